@@ -2,10 +2,14 @@ package handler
 
 import (
 	"net/http"
+	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/sshcollectorpro/sshcollectorpro/internal/config"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/service"
 	"github.com/sshcollectorpro/sshcollectorpro/pkg/logger"
+	"github.com/sshcollectorpro/sshcollectorpro/pkg/ssh"
 )
 
 // AdminHandler 管理相关处理器
@@ -16,11 +20,27 @@ func NewAdminHandler() *AdminHandler { return &AdminHandler{} }
 // DeviceDefaultsUpdate 可更新的设备平台默认参数（部分字段）
 type DeviceDefaultsUpdate struct {
 	PromptSuffixes    []string `json:"prompt_suffixes"`
+	PromptRegex       []string `json:"prompt_regex"`
 	DisablePagingCmds []string `json:"disable_paging_cmds"`
+	PostCommands      []string `json:"post_commands"`
+	LoginSequence     []config.LoginStepConfig `json:"login_sequence"`
 	EnableRequired    *bool    `json:"enable_required"`
 	SkipDelayedEcho   *bool    `json:"skip_delayed_echo"`
 	ConfigModeCLIs    []string `json:"config_mode_clis"`
 	ConfigExitCLI     string   `json:"config_exit_cli"`
+	CheckpointCLI     []string `json:"checkpoint_cli"`
+	Normalizers       []config.NormalizerConfig `json:"normalizers"`
+	Facts             []config.FactConfig `json:"facts"`
+	MaxLines          *int     `json:"max_lines"`
+	MaxLinesTail      *int     `json:"max_lines_tail"`
+	Netconf           *bool    `json:"netconf"`
+	NetconfPort       *int     `json:"netconf_port"`
+	SupportsExec         *bool `json:"supports_exec"`
+	SupportsMultiSession *bool `json:"supports_multisession"`
+	SupportsCommit       *bool `json:"supports_commit"`
+	NeedsCRLF            *bool `json:"needs_crlf"`
+	WideTerminal         *bool `json:"wide_terminal"`
+	LineEnding           *string `json:"line_ending"`
 }
 
 // GetDeviceDefaults 获取设备平台默认适配参数
@@ -68,9 +88,39 @@ func (h *AdminHandler) UpdateDeviceDefaults(c *gin.Context) {
 	if req.PromptSuffixes != nil {
 		dd.PromptSuffixes = req.PromptSuffixes
 	}
+	if req.PromptRegex != nil {
+		if err := ssh.ValidatePromptRegex(req.PromptRegex); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"code": "INVALID_PARAMS", "message": err.Error()})
+			return
+		}
+		dd.PromptRegex = req.PromptRegex
+	}
 	if req.DisablePagingCmds != nil {
 		dd.DisablePagingCmds = req.DisablePagingCmds
 	}
+	if req.PostCommands != nil {
+		dd.PostCommands = req.PostCommands
+	}
+	if req.CheckpointCLI != nil {
+		dd.CheckpointCLI = req.CheckpointCLI
+	}
+	if req.Normalizers != nil {
+		if err := service.ValidateNormalizers(req.Normalizers); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"code": "INVALID_PARAMS", "message": err.Error()})
+			return
+		}
+		dd.Normalizers = req.Normalizers
+	}
+	if req.Facts != nil {
+		if err := service.ValidateFacts(req.Facts); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"code": "INVALID_PARAMS", "message": err.Error()})
+			return
+		}
+		dd.Facts = req.Facts
+	}
+	if req.LoginSequence != nil {
+		dd.LoginSequence = req.LoginSequence
+	}
 	if req.EnableRequired != nil {
 		dd.EnableRequired = *req.EnableRequired
 	}
@@ -83,13 +133,171 @@ func (h *AdminHandler) UpdateDeviceDefaults(c *gin.Context) {
 	if req.ConfigExitCLI != "" {
 		dd.ConfigExitCLI = req.ConfigExitCLI
 	}
+	if (req.MaxLines != nil && *req.MaxLines < 0) || (req.MaxLinesTail != nil && *req.MaxLinesTail < 0) {
+		c.JSON(http.StatusBadRequest, gin.H{"code": "INVALID_PARAMS", "message": "max_lines 与 max_lines_tail 不能为负数"})
+		return
+	}
+	if req.MaxLines != nil {
+		dd.MaxLines = *req.MaxLines
+	}
+	if req.MaxLinesTail != nil {
+		dd.MaxLinesTail = *req.MaxLinesTail
+	}
+	if req.Netconf != nil {
+		dd.Capabilities.Netconf = *req.Netconf
+	}
+	if req.NetconfPort != nil {
+		if *req.NetconfPort < 0 || *req.NetconfPort > 65535 {
+			c.JSON(http.StatusBadRequest, gin.H{"code": "INVALID_PARAMS", "message": "netconf_port 超出范围"})
+			return
+		}
+		dd.Capabilities.NetconfPort = *req.NetconfPort
+	}
+	// 能力标记：请求中出现即视为显式配置
+	if req.SupportsExec != nil {
+		dd.Capabilities.SupportsExec = req.SupportsExec
+	}
+	if req.SupportsMultiSession != nil {
+		dd.Capabilities.SupportsMultiSession = req.SupportsMultiSession
+	}
+	if req.SupportsCommit != nil {
+		dd.Capabilities.SupportsCommit = req.SupportsCommit
+	}
+	if req.NeedsCRLF != nil {
+		dd.Capabilities.NeedsCRLF = req.NeedsCRLF
+	}
+	if req.WideTerminal != nil {
+		dd.Capabilities.WideTerminal = req.WideTerminal
+	}
+	if req.LineEnding != nil {
+		le := strings.ToLower(strings.TrimSpace(*req.LineEnding))
+		if le != "" && le != config.LineEndingCRLF && le != config.LineEndingLF {
+			c.JSON(http.StatusBadRequest, gin.H{"code": "INVALID_PARAMS", "message": "line_ending 仅支持 crlf 或 lf"})
+			return
+		}
+		dd.LineEnding = le
+	}
 
-	cfg.Collector.DeviceDefaults[platform] = dd
+	// 写时复制：复制平台映射后整体替换配置快照，进行中的任务继续使用旧快照
+	config.Default().Update("runtime", func(next *config.Config) {
+		defaults := make(map[string]config.PlatformDefaultsConfig, len(next.Collector.DeviceDefaults)+1)
+		for k, v := range next.Collector.DeviceDefaults {
+			defaults[k] = v
+		}
+		defaults[platform] = dd
+		next.Collector.DeviceDefaults = defaults
+	})
 
 	logger.Info("Device defaults updated", "platform", platform)
 	c.JSON(http.StatusOK, gin.H{
 		"code":    "SUCCESS",
 		"message": "更新成功（仅运行时生效）",
-		"data":    cfg.Collector.DeviceDefaults[platform],
+		"data":    dd,
 	})
-}
\ No newline at end of file
+}
+
+// ListCapabilities 查看所有已配置平台生效的能力标记
+func (h *AdminHandler) ListCapabilities(c *gin.Context) {
+	cfg := config.Get()
+	if cfg == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"code": "CONFIG_MISSING", "message": "配置未初始化"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"code":    "SUCCESS",
+		"message": "获取平台能力成功",
+		"data":    service.ListCapabilities(cfg),
+	})
+}
+
+// GetCapabilities 查看指定平台生效的能力标记（未配置的平台返回引擎默认值）
+func (h *AdminHandler) GetCapabilities(c *gin.Context) {
+	platform := strings.TrimSpace(c.Param("platform"))
+	if platform == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"code": "INVALID_PLATFORM", "message": "平台名不能为空"})
+		return
+	}
+	cfg := config.Get()
+	if cfg == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"code": "CONFIG_MISSING", "message": "配置未初始化"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"code":    "SUCCESS",
+		"message": "获取平台能力成功",
+		"data":    service.CapabilitiesFor(cfg, platform),
+	})
+}
+
+// ReadOnlyUpdate 只读模式切换请求
+type ReadOnlyUpdate struct {
+	ReadOnly *bool `json:"read_only"`
+}
+
+// GetConfigChangelog 查看最近的配置变更记录（配置文件热更新与管理接口修改，仅字段路径不含值）
+func (h *AdminHandler) GetConfigChangelog(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"code":    "SUCCESS",
+		"message": "获取配置变更记录成功",
+		"data":    config.Default().Changelog(),
+	})
+}
+
+// GetReadOnly 查询只读模式状态
+func (h *AdminHandler) GetReadOnly(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"code":    "SUCCESS",
+		"message": "获取只读模式状态成功",
+		"data":    gin.H{"read_only": service.IsReadOnly()},
+	})
+}
+
+// UpdateReadOnly 切换只读模式（运行时生效，配置热加载时以 policy.read_only 为准）；需管理令牌
+func (h *AdminHandler) UpdateReadOnly(c *gin.Context) {
+	if !authorizeAdmin(c, config.Get().Debug.Token) {
+		return
+	}
+	var req ReadOnlyUpdate
+	if err := c.ShouldBindJSON(&req); err != nil || req.ReadOnly == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": "INVALID_PARAMS", "message": "需要提供 read_only 布尔值"})
+		return
+	}
+	service.SetReadOnly(*req.ReadOnly)
+	logger.Warn("Read-only mode switched", "read_only", *req.ReadOnly, "client_ip", c.ClientIP())
+	c.JSON(http.StatusOK, gin.H{
+		"code":    "SUCCESS",
+		"message": "更新成功（仅运行时生效）",
+		"data":    gin.H{"read_only": service.IsReadOnly()},
+	})
+}
+
+// GetSSHPool 查看 SSH 连接池（统计与连接列表）
+func (h *AdminHandler) GetSSHPool(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"code":    "SUCCESS",
+		"message": "获取连接池信息成功",
+		"data":    service.SSHPoolsSnapshot(),
+	})
+}
+
+// EvictSSHPool 手动驱逐连接：?host=&port=&force=true，host 为空表示全部
+func (h *AdminHandler) EvictSSHPool(c *gin.Context) {
+	host := strings.TrimSpace(c.Query("host"))
+	port := 0
+	if v := strings.TrimSpace(c.Query("port")); v != "" {
+		p, err := strconv.Atoi(v)
+		if err != nil || p <= 0 || p > 65535 {
+			c.JSON(http.StatusBadRequest, gin.H{"code": "INVALID_PARAMS", "message": "port 无效"})
+			return
+		}
+		port = p
+	}
+	force := c.Query("force") == "true"
+	n := service.EvictSSHConnections(host, port, force)
+	logger.Info("SSH pool connections evicted", "host", host, "port", port, "force", force, "count", n)
+	c.JSON(http.StatusOK, gin.H{
+		"code":    "SUCCESS",
+		"message": "驱逐完成",
+		"data":    gin.H{"evicted": n},
+	})
+}
@@ -0,0 +1,105 @@
+package handler
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/config"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/database"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/model"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/service"
+	"gorm.io/gorm"
+)
+
+// AnonymizeRepository 基于 SQLite 的匿名化映射表（anonymize_mappings 表）
+type AnonymizeRepository struct{}
+
+// NewAnonymizeRepository 创建匿名化映射仓库
+func NewAnonymizeRepository() *AnonymizeRepository { return &AnonymizeRepository{} }
+
+// Entries 映射表全部记录
+func (AnonymizeRepository) Entries(mapID string) ([]service.AnonymizeEntry, error) {
+	var rows []model.AnonymizeMapping
+	if err := database.GetDB().Where("map_id = ?", mapID).Order("id").Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	out := make([]service.AnonymizeEntry, 0, len(rows))
+	for _, r := range rows {
+		out = append(out, service.AnonymizeEntry{Kind: r.Kind, Digest: r.Digest, Pseudonym: r.Pseudonym, OriginalEnc: r.OriginalEnc})
+	}
+	return out, nil
+}
+
+// Append 批量写入新增映射
+func (AnonymizeRepository) Append(mapID string, entries []service.AnonymizeEntry) error {
+	rows := make([]model.AnonymizeMapping, 0, len(entries))
+	for _, e := range entries {
+		rows = append(rows, model.AnonymizeMapping{MapID: mapID, Kind: e.Kind, Digest: e.Digest, Pseudonym: e.Pseudonym, OriginalEnc: e.OriginalEnc})
+	}
+	return database.WithRetry(func(tx *gorm.DB) error {
+		return tx.CreateInBatches(&rows, 200).Error
+	}, 3, 100*time.Millisecond)
+}
+
+// DeleteMap 删除映射表，返回删除的记录数
+func (AnonymizeRepository) DeleteMap(mapID string) (int64, error) {
+	res := database.GetDB().Where("map_id = ?", mapID).Delete(&model.AnonymizeMapping{})
+	return res.RowsAffected, res.Error
+}
+
+// AnonymizeHandler 匿名化接口处理器
+type AnonymizeHandler struct {
+	svc *service.AnonymizeService
+}
+
+// NewAnonymizeHandler 创建匿名化处理器
+func NewAnonymizeHandler(svc *service.AnonymizeService) *AnonymizeHandler {
+	return &AnonymizeHandler{svc: svc}
+}
+
+// Anonymize 替换文本或已存储对象中的 IP/MAC/主机名/用户名/团体字
+// @Router /api/v1/anonymize [post]
+func (h *AnonymizeHandler) Anonymize(c *gin.Context) {
+	var req service.AnonymizeRequest
+	if err := bindJSON(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Code: "INVALID_PARAMS", Message: "请求参数无效: " + err.Error(), Errors: fieldErrors(err)})
+		return
+	}
+	resp, err := h.svc.Anonymize(c.Request.Context(), &req)
+	if err != nil {
+		c.Error(err).SetMeta("ANONYMIZE_FAILED")
+		return
+	}
+	c.JSON(http.StatusOK, SuccessResponse{Code: "SUCCESS", Message: "匿名化完成", Data: resp})
+}
+
+// Deanonymize 按映射表将假名还原为原值（需要管理令牌）
+// @Router /api/v1/anonymize/reverse [post]
+func (h *AnonymizeHandler) Deanonymize(c *gin.Context) {
+	if !authorizeAdmin(c, config.Get().Debug.Token) {
+		return
+	}
+	var req service.DeanonymizeRequest
+	if err := bindJSON(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Code: "INVALID_PARAMS", Message: "请求参数无效: " + err.Error(), Errors: fieldErrors(err)})
+		return
+	}
+	resp, err := h.svc.Deanonymize(c.Request.Context(), &req)
+	if err != nil {
+		c.Error(err).SetMeta("DEANONYMIZE_FAILED")
+		return
+	}
+	c.JSON(http.StatusOK, SuccessResponse{Code: "SUCCESS", Message: "还原完成", Data: resp})
+}
+
+// DeleteMap 删除映射表
+// @Router /api/v1/anonymize/maps/{id} [delete]
+func (h *AnonymizeHandler) DeleteMap(c *gin.Context) {
+	n, err := h.svc.DeleteMap(c.Param("id"))
+	if err != nil {
+		c.Error(err).SetMeta("DELETE_FAILED")
+		return
+	}
+	c.JSON(http.StatusOK, SuccessResponse{Code: "SUCCESS", Message: "映射表已删除", Data: gin.H{"map_id": c.Param("id"), "deleted": n}})
+}
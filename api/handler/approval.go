@@ -0,0 +1,394 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/config"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/database"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/model"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/service"
+	"github.com/sshcollectorpro/sshcollectorpro/pkg/logger"
+	"gorm.io/gorm"
+)
+
+var (
+	errApprovalNotFound = errors.New("approval not found")
+	errApprovalDecided  = errors.New("approval already decided")
+	errApprovalExpired  = errors.New("approval expired")
+)
+
+// ApprovalHandler 下发审批：审批人通过企业微信/钉钉卡片链接或接口批准/驳回
+type ApprovalHandler struct {
+	deploy *service.DeployService
+}
+
+// NewApprovalHandler 创建审批处理器
+func NewApprovalHandler(deploy *service.DeployService) *ApprovalHandler {
+	return &ApprovalHandler{deploy: deploy}
+}
+
+// ApprovalDecisionRequest 接口审批参数；审批人取自凭据，不接受请求体指定
+type ApprovalDecisionRequest struct {
+	Comment string `json:"comment,omitempty"`
+}
+
+// DeployApprovalView 审批返回结构（审计事件已解码）
+type DeployApprovalView struct {
+	model.DeployApproval
+	Events []service.ApprovalEvent `json:"events,omitempty"`
+}
+
+// submitDeployApproval 保存待审批的下发请求并推送审批卡片，返回 202
+func submitDeployApproval(c *gin.Context, req *service.DeployFastRequest) {
+	cfg := config.Get()
+	enc, err := service.EncryptBatchRequest(cfg, req)
+	if err != nil {
+		if errors.Is(err, service.ErrVaultNotConfigured) {
+			c.JSON(http.StatusServiceUnavailable, ErrorResponse{Code: "VAULT_NOT_CONFIGURED", Message: "下发审批需配置 vault.master_key 以加密保存请求"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Code: "ENCRYPT_FAILED", Message: err.Error()})
+		return
+	}
+	ttl := cfg.Approval.TTL
+	if ttl <= 0 {
+		ttl = 24 * time.Hour
+	}
+	row := model.DeployApproval{
+		ID:        uuid.NewString(),
+		TaskID:    req.TaskID,
+		TaskName:  req.TaskName,
+		Status:    service.ApprovalPending,
+		Summary:   service.SummarizeDeploy(cfg, req),
+		Request:   enc,
+		Devices:   len(req.Devices),
+		ExpiresAt: time.Now().Add(ttl),
+	}
+	events := []service.ApprovalEvent{service.NewApprovalEvent("created", "", service.ApprovalViaAPI, "")}
+	events[0].Remote = c.ClientIP()
+	row.Events = encodeApprovalEvents(events)
+	if err := database.WithRetry(func(db *gorm.DB) error { return db.Create(&row).Error }, 3, 0); err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Code: "CREATE_FAILED", Message: err.Error()})
+		return
+	}
+
+	title := "下发审批: " + approvalName(&row)
+	sent := service.SendApprovalCards(c.Request.Context(), cfg.Approval, service.ApprovalCard{ID: row.ID, Title: title, Summary: row.Summary, ExpiresAt: row.ExpiresAt})
+	if len(sent) == 0 {
+		logger.Warn("Deploy approval created without notification channel", "approval_id", row.ID)
+	}
+	_ = appendApprovalEvents(row.ID, sent...)
+	events = append(events, sent...)
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"code":    "PENDING_APPROVAL",
+		"message": "下发已提交审批",
+		"data":    DeployApprovalView{DeployApproval: row, Events: events},
+	})
+}
+
+// ListApprovals GET /api/v1/deploy-approvals
+func (h *ApprovalHandler) ListApprovals(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	size, _ := strconv.Atoi(c.DefaultQuery("size", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if size < 1 || size > 200 {
+		size = 20
+	}
+	query := database.GetDB().Model(&model.DeployApproval{})
+	if v := strings.TrimSpace(c.Query("status")); v != "" {
+		query = query.Where("status = ?", v)
+	}
+	if v := strings.TrimSpace(c.Query("task_id")); v != "" {
+		query = query.Where("task_id = ?", v)
+	}
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Code: "COUNT_FAILED", Message: "获取审批总数失败: " + err.Error()})
+		return
+	}
+	var rows []model.DeployApproval
+	if err := query.Omit("request", "events").Order("created_at DESC").Offset((page - 1) * size).Limit(size).Find(&rows).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Code: "LIST_FAILED", Message: "获取审批列表失败: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"code":    "SUCCESS",
+		"message": "获取审批列表成功",
+		"data": gin.H{
+			"approvals": rows,
+			"pagination": gin.H{
+				"page":  page,
+				"size":  size,
+				"total": total,
+				"pages": (total + int64(size) - 1) / int64(size),
+			},
+		},
+	})
+}
+
+// GetApproval GET /api/v1/deploy-approvals/:id（含审计事件）
+func (h *ApprovalHandler) GetApproval(c *gin.Context) {
+	var row model.DeployApproval
+	if err := database.GetDB().Where("id = ?", c.Param("id")).First(&row).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, ErrorResponse{Code: "NOT_FOUND", Message: "审批不存在"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Code: "QUERY_FAILED", Message: err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, SuccessResponse{Code: "SUCCESS", Message: "获取审批成功", Data: approvalView(&row)})
+}
+
+// Approve POST /api/v1/deploy-approvals/:id/approve
+func (h *ApprovalHandler) Approve(c *gin.Context) {
+	h.decideByAPI(c, service.ApprovalActionApprove)
+}
+
+// Reject POST /api/v1/deploy-approvals/:id/reject
+func (h *ApprovalHandler) Reject(c *gin.Context) {
+	h.decideByAPI(c, service.ApprovalActionReject)
+}
+
+// decideByAPI 接口审批：须携带审批链接签名（via/expires/sig 查询参数）或管理令牌
+// 审批人由凭据确定：签名链接记为 "<via>-link"，管理令牌记为 "admin"
+func (h *ApprovalHandler) decideByAPI(c *gin.Context, action string) {
+	var req ApprovalDecisionRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Code: "BAD_REQUEST", Message: err.Error()})
+			return
+		}
+	}
+	id := c.Param("id")
+	cfg := config.Get()
+	actor, via := "admin", service.ApprovalViaAPI
+	if sig := c.Query("sig"); sig != "" {
+		via = c.Query("via")
+		if err := service.VerifyApproval(cfg.Approval.Secret, id, action, via, c.Query("expires"), sig, time.Now()); err != nil {
+			c.JSON(http.StatusForbidden, ErrorResponse{Code: "APPROVAL_LINK_INVALID", Message: "审批链接无效: " + err.Error()})
+			return
+		}
+		actor = via + "-link"
+	} else if !authorizeAdmin(c, cfg.Debug.Token) {
+		return
+	}
+	ev := service.NewApprovalEvent("", actor, via, req.Comment)
+	ev.Remote = c.ClientIP()
+	row, err := h.decide(id, action, ev)
+	if err != nil {
+		writeApprovalError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, SuccessResponse{Code: "SUCCESS", Message: "审批已处理", Data: approvalView(row)})
+}
+
+// Decision GET/POST /api/v1/deploy-approvals/:id/decision
+// 卡片按钮链接（签名）：GET 返回确认页，避免聊天工具预览链接时误触发；POST 提交审批
+func (h *ApprovalHandler) Decision(c *gin.Context) {
+	id := c.Param("id")
+	action := c.Query("action")
+	via := c.Query("via")
+	if action != service.ApprovalActionApprove && action != service.ApprovalActionReject {
+		approvalPage(c, http.StatusBadRequest, "无效的审批动作")
+		return
+	}
+	if err := service.VerifyApproval(config.Get().Approval.Secret, id, action, via, c.Query("expires"), c.Query("sig"), time.Now()); err != nil {
+		approvalPage(c, http.StatusForbidden, "审批链接无效: "+err.Error())
+		return
+	}
+	label := map[string]string{service.ApprovalActionApprove: "批准", service.ApprovalActionReject: "驳回"}[action]
+
+	if c.Request.Method == http.MethodGet {
+		var row model.DeployApproval
+		if err := database.GetDB().Omit("request").Where("id = ?", id).First(&row).Error; err != nil {
+			approvalPage(c, http.StatusNotFound, "审批不存在")
+			return
+		}
+		if row.Status != service.ApprovalPending {
+			approvalPage(c, http.StatusConflict, "审批已处理，当前状态: "+row.Status)
+			return
+		}
+		body := fmt.Sprintf(`<pre>%s</pre><form method="post"><label>审批人 <input name="approver" required></label> <label>备注 <input name="comment"></label> <button type="submit">确认%s</button></form>`,
+			html.EscapeString(row.Summary), label)
+		approvalPage(c, http.StatusOK, body)
+		return
+	}
+
+	approver := strings.TrimSpace(c.PostForm("approver"))
+	if approver == "" {
+		approvalPage(c, http.StatusBadRequest, "请填写审批人")
+		return
+	}
+	ev := service.NewApprovalEvent("", approver, via, c.PostForm("comment"))
+	ev.Remote = c.ClientIP()
+	if _, err := h.decide(id, action, ev); err != nil {
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, errApprovalNotFound):
+			status = http.StatusNotFound
+		case errors.Is(err, errApprovalDecided), errors.Is(err, errApprovalExpired):
+			status = http.StatusConflict
+		}
+		approvalPage(c, status, html.EscapeString(err.Error()))
+		return
+	}
+	approvalPage(c, http.StatusOK, "已"+label)
+}
+
+// decide 记录审批结论；批准后后台执行下发，驳回/过期时清空保存的请求
+func (h *ApprovalHandler) decide(id, action string, ev service.ApprovalEvent) (*model.DeployApproval, error) {
+	db := database.GetDB()
+	var row model.DeployApproval
+	if err := db.Where("id = ?", id).First(&row).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errApprovalNotFound
+		}
+		return nil, err
+	}
+	if row.Status != service.ApprovalPending {
+		return nil, fmt.Errorf("%w: %s", errApprovalDecided, row.Status)
+	}
+	if time.Now().After(row.ExpiresAt) {
+		res := db.Model(&model.DeployApproval{}).Where("id = ? AND status = ?", id, service.ApprovalPending).
+			Updates(map[string]interface{}{"status": service.ApprovalExpired, "request": ""})
+		if res.Error == nil && res.RowsAffected > 0 {
+			_ = appendApprovalEvents(id, service.NewApprovalEvent(service.ApprovalExpired, "", "", ""))
+		}
+		return nil, errApprovalExpired
+	}
+
+	now := time.Now()
+	status := service.ApprovalRejected
+	updates := map[string]interface{}{"decided_by": ev.Actor, "decided_via": ev.Via, "decided_at": &now}
+	if action == service.ApprovalActionApprove {
+		status = service.ApprovalApproved
+	} else {
+		updates["request"] = ""
+	}
+	updates["status"] = status
+	// 条件更新保证同一审批只被处理一次
+	res := db.Model(&model.DeployApproval{}).Where("id = ? AND status = ?", id, service.ApprovalPending).Updates(updates)
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	if res.RowsAffected == 0 {
+		return nil, errApprovalDecided
+	}
+	ev.Event = status
+	_ = appendApprovalEvents(id, ev)
+	logger.Info("Deploy approval decided", "approval_id", id, "status", status, "approver", ev.Actor, "via", ev.Via)
+
+	cfg := config.Get()
+	title := "下发审批" + map[string]string{service.ApprovalApproved: "已批准", service.ApprovalRejected: "已驳回"}[status] + ": " + approvalName(&row)
+	if status == service.ApprovalRejected {
+		go func() {
+			_ = appendApprovalEvents(id, service.SendApprovalNotice(context.Background(), cfg.Approval, title, "审批人: "+ev.Actor)...)
+		}()
+	} else {
+		go h.execute(cfg, row, title, ev.Actor)
+	}
+
+	row.Status, row.DecidedBy, row.DecidedVia, row.DecidedAt = status, ev.Actor, ev.Via, &now
+	row.Request = ""
+	return &row, nil
+}
+
+// execute 执行已批准的下发，记录结果并推送结果通知与任务回调
+func (h *ApprovalHandler) execute(cfg *config.Config, row model.DeployApproval, title, approver string) {
+	var req service.DeployFastRequest
+	var resp *service.DeployFastResponse
+	err := service.DecryptBatchRequest(row.Request, &req)
+	if err == nil {
+		if h.deploy == nil {
+			err = fmt.Errorf("deploy service not available")
+		} else {
+			resp, err = h.deploy.ExecuteFast(service.WithApprovalGranted(context.Background(), row.ID), &req)
+		}
+	}
+	code, detail := "FAILED", ""
+	if err != nil {
+		detail = err.Error()
+	} else {
+		code, detail = resp.Code, resp.Message
+		service.NotifyCallback(cfg, req.CallbackURL, service.NewDeployCallbackPayload(&req, resp))
+	}
+	_ = database.WithRetry(func(db *gorm.DB) error {
+		return db.Model(&model.DeployApproval{}).Where("id = ?", row.ID).
+			Updates(map[string]interface{}{"status": service.ApprovalExecuted, "result_code": code, "request": ""}).Error
+	}, 3, 0)
+	events := []service.ApprovalEvent{service.NewApprovalEvent(service.ApprovalExecuted, "", "", code+" "+detail)}
+	events = append(events, service.SendApprovalNotice(context.Background(), cfg.Approval, title, fmt.Sprintf("审批人: %s\n\n执行结果: %s %s", approver, code, detail))...)
+	_ = appendApprovalEvents(row.ID, events...)
+	logger.Info("Approved deploy finished", "approval_id", row.ID, "task_id", req.TaskID, "code", code)
+}
+
+// appendApprovalEvents 追加审计事件（事务内读改写）
+func appendApprovalEvents(id string, events ...service.ApprovalEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+	return database.TransactionWithRetry(func(tx *gorm.DB) error {
+		var row model.DeployApproval
+		if err := tx.Select("id", "events").Where("id = ?", id).First(&row).Error; err != nil {
+			return err
+		}
+		var all []service.ApprovalEvent
+		_ = json.Unmarshal([]byte(row.Events), &all)
+		all = append(all, events...)
+		return tx.Model(&model.DeployApproval{}).Where("id = ?", id).Update("events", encodeApprovalEvents(all)).Error
+	}, 3, 0)
+}
+
+// approvalName 卡片标题中的任务名称
+func approvalName(row *model.DeployApproval) string {
+	for _, v := range []string{row.TaskName, row.TaskID} {
+		if strings.TrimSpace(v) != "" {
+			return v
+		}
+	}
+	return row.ID
+}
+
+func encodeApprovalEvents(events []service.ApprovalEvent) string {
+	b, _ := json.Marshal(events)
+	return string(b)
+}
+
+func approvalView(row *model.DeployApproval) DeployApprovalView {
+	v := DeployApprovalView{DeployApproval: *row}
+	_ = json.Unmarshal([]byte(row.Events), &v.Events)
+	return v
+}
+
+func writeApprovalError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, errApprovalNotFound):
+		c.JSON(http.StatusNotFound, ErrorResponse{Code: "NOT_FOUND", Message: "审批不存在"})
+	case errors.Is(err, errApprovalDecided):
+		c.JSON(http.StatusConflict, ErrorResponse{Code: "APPROVAL_DECIDED", Message: err.Error()})
+	case errors.Is(err, errApprovalExpired):
+		c.JSON(http.StatusConflict, ErrorResponse{Code: "APPROVAL_EXPIRED", Message: err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Code: "APPROVAL_FAILED", Message: err.Error()})
+	}
+}
+
+// approvalPage 审批链接的简单 HTML 页面（审批人在聊天工具内置浏览器中打开）
+func approvalPage(c *gin.Context, status int, body string) {
+	page := `<!DOCTYPE html><html><head><meta charset="utf-8"><meta name="viewport" content="width=device-width,initial-scale=1"><title>下发审批</title></head><body>` + body + `</body></html>`
+	c.Data(status, "text/html; charset=utf-8", []byte(page))
+}
@@ -2,9 +2,14 @@ package handler
 
 import (
 	"net/http"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/config"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/model"
 	"github.com/sshcollectorpro/sshcollectorpro/internal/service"
+	"github.com/sshcollectorpro/sshcollectorpro/pkg/logger"
 )
 
 // BackupHandler 备份接口处理器
@@ -17,19 +22,81 @@ func NewBackupHandler(svc *service.BackupService) *BackupHandler { return &Backu
 // BatchBackup 批量备份接口
 func (h *BackupHandler) BatchBackup(c *gin.Context) {
 	var req service.BackupBatchRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"code": "INVALID_REQUEST", "message": err.Error()})
+	if err := bindJSON(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": "INVALID_REQUEST", "message": err.Error(), "errors": fieldErrors(err)})
 		return
 	}
 	if req.TaskID == "" || len(req.Devices) == 0 {
 		c.JSON(http.StatusBadRequest, gin.H{"code": "INVALID_PARAMS", "message": "task_id and devices are required"})
 		return
 	}
+	if err := service.ValidateOutputEncoding(req.OutputEncoding); err != nil {
+		c.Error(err)
+		return
+	}
+	ro, err := responseOutput(c, req.RawOutputMode, req.MaxOutputKB)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	req.RawOutputMode, req.MaxOutputKB = ro.Mode, ro.MaxKB
+	if err := service.ValidateCallbackURL(req.CallbackURL); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": "INVALID_PARAMS", "message": err.Error()})
+		return
+	}
+	// 执行日历：节假日、封网等非执行日不执行，返回判定结果
+	if strings.TrimSpace(req.Calendar) != "" {
+		cal, err := loadCalendar(req.Calendar)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"code": "CALENDAR_INVALID", "message": err.Error()})
+			return
+		}
+		if d := cal.Evaluate(time.Now()); !d.Allowed {
+			logger.Info("Batch backup skipped by calendar", "task_id", req.TaskID, "calendar", cal.Name, "date", d.Date, "reason", d.Reason)
+			c.JSON(http.StatusOK, gin.H{"code": "SKIPPED_BY_CALENDAR", "message": d.SkipMessage(cal.Name), "data": d, "next_allowed": cal.NextAllowed(time.Now())})
+			return
+		}
+	}
+	playbooks := newPlaybookResolver()
+	for i := range req.Devices {
+		d := &req.Devices[i]
+		if err := resolveCredential(d.CredentialID, &d.UserName, &d.Password, &d.EnablePassword); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"code": "CREDENTIAL_INVALID", "message": err.Error()})
+			return
+		}
+		cli, err := playbooks.expand(req.Playbook, d.DevicePlatform, d.CliList)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"code": "PLAYBOOK_INVALID", "message": err.Error()})
+			return
+		}
+		d.CliList = cli
+	}
+
+	// 断点记录：进程中断后可仅对未完成设备续跑
+	total := service.BatchDeviceTotal(len(req.Devices), func(i int) (string, int) { return req.Devices[i].DeviceIP, req.Devices[i].Port })
+	journal := service.StartBatchJournal(model.BatchJobKindBackup, req.TaskID, req.TaskName, total, &req, "")
+	resp, err := h.svc.ExecuteBatchStream(c.Request.Context(), &req, journal.BackupDone)
+	journal.Finish()
+	if err != nil {
+		c.Error(err).SetMeta("ERROR")
+		return
+	}
+	service.NotifyCallback(config.Get(), req.CallbackURL, service.NewBackupCallbackPayload(&req, resp))
+	ro.ApplyBackup(resp.Data)
+	c.JSON(service.BatchHTTPStatus(resp.Code), service.SanitizeOutput(resp, outputEncoding(c, req.OutputEncoding)))
+}
 
-	resp, err := h.svc.ExecuteBatch(c.Request.Context(), &req)
+// DiffBackup 对比同一设备/命令的两份备份对象，返回统一 diff 与变更摘要
+func (h *BackupHandler) DiffBackup(c *gin.Context) {
+	var req service.BackupDiffRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": "INVALID_REQUEST", "message": err.Error()})
+		return
+	}
+	resp, err := h.svc.Diff(c.Request.Context(), &req)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"code": "ERROR", "message": err.Error()})
+		c.Error(err).SetMeta("DIFF_FAILED")
 		return
 	}
-	c.JSON(http.StatusOK, resp)
+	c.JSON(http.StatusOK, gin.H{"code": "SUCCESS", "message": "backup diff generated", "data": resp})
 }
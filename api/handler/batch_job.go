@@ -0,0 +1,259 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/config"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/database"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/model"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/service"
+	"github.com/sshcollectorpro/sshcollectorpro/pkg/logger"
+	"gorm.io/gorm"
+)
+
+// BatchJobRepository 基于 SQLite 的批量任务记录仓库（batch_jobs 表）
+type BatchJobRepository struct{}
+
+// NewBatchJobRepository 创建批量任务记录仓库
+func NewBatchJobRepository() *BatchJobRepository { return &BatchJobRepository{} }
+
+// ReplaceBatchJob 新建记录，相同 ID 的旧记录被覆盖
+func (BatchJobRepository) ReplaceBatchJob(job *model.BatchJob) error {
+	return database.WithRetry(func(tx *gorm.DB) error {
+		if err := tx.Where("id = ?", job.ID).Delete(&model.BatchJob{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(job).Error
+	}, 3, 0)
+}
+
+// BatchJob 按 ID 读取完整记录
+func (BatchJobRepository) BatchJob(id string) (*model.BatchJob, error) {
+	var job model.BatchJob
+	if err := database.GetDB().Where("id = ?", id).First(&job).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, service.ErrBatchJobNotFound
+		}
+		return nil, err
+	}
+	return &job, nil
+}
+
+// ListBatchJobs 按创建时间倒序分页列出，不含请求与设备列表
+func (BatchJobRepository) ListBatchJobs(status string, offset, limit int) ([]model.BatchJob, int64, error) {
+	query := database.GetDB().Model(&model.BatchJob{})
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	rows := []model.BatchJob{}
+	if err := query.Omit("request", "done", "failed_list").Order("created_at DESC").Offset(offset).Limit(limit).Find(&rows).Error; err != nil {
+		return nil, 0, err
+	}
+	return rows, total, nil
+}
+
+// BatchJobRetries 原任务的失败重试记录，按创建时间升序
+func (BatchJobRepository) BatchJobRetries(rootID string) ([]model.BatchJob, error) {
+	var rows []model.BatchJob
+	err := database.GetDB().Omit("request").Where("retry_of = ?", rootID).Order("created_at ASC").Find(&rows).Error
+	return rows, err
+}
+
+// MarkBatchJobResumed 标记为运行中并累加续跑次数
+func (BatchJobRepository) MarkBatchJobResumed(id string) error {
+	return database.WithRetry(func(tx *gorm.DB) error {
+		return tx.Model(&model.BatchJob{}).Where("id = ?", id).Updates(map[string]interface{}{
+			"status":     model.BatchJobRunning,
+			"resumes":    gorm.Expr("resumes + 1"),
+			"updated_at": time.Now(),
+		}).Error
+	}, 3, 0)
+}
+
+// UpdateBatchJobProgress 更新已完成与失败设备
+func (BatchJobRepository) UpdateBatchJobProgress(id string, p service.BatchJobProgress) error {
+	return database.WithRetry(func(tx *gorm.DB) error {
+		return tx.Model(&model.BatchJob{}).Where("id = ?", id).Updates(map[string]interface{}{
+			"done":        p.Done,
+			"completed":   p.Completed,
+			"failed_list": p.FailedList,
+			"failed":      p.Failed,
+			"updated_at":  time.Now(),
+		}).Error
+	}, 3, 0)
+}
+
+// SetBatchJobStatus 更新状态，clearRequest 为 true 时清除原请求
+func (BatchJobRepository) SetBatchJobStatus(id, status string, clearRequest bool) error {
+	updates := map[string]interface{}{"status": status, "updated_at": time.Now()}
+	if clearRequest {
+		updates["request"] = ""
+	}
+	return database.WithRetry(func(tx *gorm.DB) error {
+		return tx.Model(&model.BatchJob{}).Where("id = ?", id).Updates(updates).Error
+	}, 3, 0)
+}
+
+// InterruptRunningBatchJobs 将 exclude 以外的运行中记录标记为 interrupted
+func (BatchJobRepository) InterruptRunningBatchJobs(exclude []string) (int64, error) {
+	q := database.GetDB().Model(&model.BatchJob{}).Where("status = ?", model.BatchJobRunning)
+	if len(exclude) > 0 {
+		q = q.Where("id NOT IN ?", exclude)
+	}
+	res := q.Updates(map[string]interface{}{"status": model.BatchJobInterrupted, "updated_at": time.Now()})
+	return res.RowsAffected, res.Error
+}
+
+// InterruptedBatchJobs 已中断的记录，按创建时间升序
+func (BatchJobRepository) InterruptedBatchJobs() ([]model.BatchJob, error) {
+	var rows []model.BatchJob
+	err := database.GetDB().Where("status = ?", model.BatchJobInterrupted).Order("created_at ASC").Find(&rows).Error
+	return rows, err
+}
+
+// BatchJobHandler 批量任务断点续跑
+type BatchJobHandler struct {
+	svc *service.BatchJobService
+}
+
+// NewBatchJobHandler 创建批量任务处理器；自定义批量采集经 collector 执行
+func NewBatchJobHandler(collector *CollectorHandler, backup *service.BackupService) *BatchJobHandler {
+	svc := service.NewBatchJobService(backup)
+	svc.RegisterLoader(model.BatchJobKindCollectCustom, func(enc string) (service.BatchJobRequest, error) {
+		req := &CustomerBatchRequest{}
+		if err := service.DecryptBatchRequest(enc, req); err != nil {
+			return nil, err
+		}
+		return &customerBatchJob{collector: collector, req: req}, nil
+	})
+	return &BatchJobHandler{svc: svc}
+}
+
+// ResumeBatchJobRequest 续跑参数
+type ResumeBatchJobRequest struct {
+	Deadline *time.Time `json:"deadline,omitempty"` // 替换原请求的执行窗口截止时间
+}
+
+// ListBatchJobs GET /api/v1/batch-jobs?status=interrupted
+func (h *BatchJobHandler) ListBatchJobs(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	size, _ := strconv.Atoi(c.DefaultQuery("size", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if size < 1 || size > 200 {
+		size = 20
+	}
+	rows, total, err := h.svc.List(strings.TrimSpace(c.Query("status")), page, size)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Code: "LIST_FAILED", Message: "获取批量任务失败: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"code":    "SUCCESS",
+		"message": "获取批量任务成功",
+		"data": gin.H{
+			"jobs": rows,
+			"pagination": gin.H{
+				"page":  page,
+				"size":  size,
+				"total": total,
+				"pages": (total + int64(size) - 1) / int64(size),
+			},
+		},
+	})
+}
+
+// ResumeBatchJob POST /api/v1/batch-jobs/:id/resume
+// 仅执行未完成的设备；异步执行，结果经 callback_url、任务历史与存储获取
+func (h *BatchJobHandler) ResumeBatchJob(c *gin.Context) {
+	var req ResumeBatchJobRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Code: "INVALID_PARAMS", Message: "请求参数无效: " + err.Error()})
+			return
+		}
+	}
+	job, remaining, err := h.svc.Resume(c.Param("id"), req.Deadline)
+	switch {
+	case errors.Is(err, service.ErrBatchJobNotFound):
+		c.JSON(http.StatusNotFound, ErrorResponse{Code: "BATCH_JOB_NOT_FOUND", Message: "批量任务不存在"})
+	case errors.Is(err, service.ErrBatchJobRunning):
+		c.JSON(http.StatusConflict, ErrorResponse{Code: "BATCH_JOB_RUNNING", Message: "批量任务正在执行"})
+	case errors.Is(err, service.ErrBatchJobCompleted):
+		c.JSON(http.StatusConflict, ErrorResponse{Code: "BATCH_JOB_COMPLETED", Message: "批量任务已完成"})
+	case errors.Is(err, service.ErrVaultNotConfigured):
+		c.JSON(http.StatusBadRequest, ErrorResponse{Code: "VAULT_NOT_CONFIGURED", Message: err.Error()})
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Code: "RESUME_FAILED", Message: "续跑失败: " + err.Error()})
+	default:
+		c.JSON(http.StatusAccepted, SuccessResponse{Code: "SUCCESS", Message: "批量任务已开始续跑", Data: gin.H{"id": job.ID, "kind": job.Kind, "remaining": remaining}})
+	}
+}
+
+// RecoverBatchJobs 将未结束且不在本进程执行的批量任务标记为 interrupted；auto 为 true 时逐个后台续跑
+// 启动时（须在 HTTP 服务开始接收请求前）与接任 leader 时调用
+func (h *BatchJobHandler) RecoverBatchJobs(auto bool) {
+	h.svc.Recover(auto)
+}
+
+// customerBatchJob 已登记的自定义批量采集请求
+type customerBatchJob struct {
+	collector *CollectorHandler
+	req       *CustomerBatchRequest
+}
+
+func (j *customerBatchJob) RetainDevices(keep func(key string) bool) int {
+	pending := j.req.Devices[:0:0]
+	for _, d := range j.req.Devices {
+		if keep(service.BatchDeviceKey(d.DeviceIP, d.Port)) {
+			pending = append(pending, d)
+		}
+	}
+	j.req.Devices = pending
+	return len(pending)
+}
+
+func (j *customerBatchJob) SetDeadline(deadline *time.Time) { j.req.Deadline = deadline }
+func (j *customerBatchJob) TaskName() string                { return j.req.TaskName }
+func (j *customerBatchJob) Payload() interface{}            { return j.req }
+
+// ForRetry 设备任务记录附带 retry_of，供原任务合并汇总
+func (j *customerBatchJob) ForRetry(taskID, retryOf string) {
+	j.req.TaskID = taskID
+	j.req.Metadata = service.MergeTaskMetadata(j.req.Metadata, map[string]interface{}{"retry_of": retryOf})
+}
+
+// Run 后台执行自定义批量采集（续跑或失败重试），action 用于结果文案与日志
+func (j *customerBatchJob) Run(journal *service.BatchJournal, action string) {
+	req := j.req
+	all := j.collector.runCustomerBatch(context.Background(), req, journal)
+	responses := make([]map[string]interface{}, 0, len(all))
+	successCount := 0
+	for _, r := range all {
+		if r == nil {
+			continue
+		}
+		responses = append(responses, r)
+		if s, ok := r["success"].(bool); ok && s {
+			successCount++
+		}
+	}
+	outcome := service.NewBatchOutcome("自定义批量任务（"+action+"）", len(responses), successCount)
+	if req.CallbackURL != "" && len(responses) > 0 {
+		payload := service.NewCollectCallbackPayload(req.TaskID, req.TaskName, "collect_custom", responses)
+		payload.Message = outcome.Message
+		service.NotifyCallback(config.Get(), req.CallbackURL, payload)
+	}
+	logger.Info("Background custom batch finished", "task_id", req.TaskID, "action", action, "code", outcome.Code, "devices", len(responses))
+}
@@ -0,0 +1,50 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/service"
+)
+
+// RetryFailedRequest 失败重试参数（请求体可选）
+type RetryFailedRequest struct {
+	TaskID   string     `json:"task_id,omitempty"`  // 重试任务 ID，默认 {id}-retry-{n}
+	Deadline *time.Time `json:"deadline,omitempty"` // 替换原请求的执行窗口截止时间
+}
+
+// RetryFailed POST /api/v1/tasks/:id/retry-failed
+// 按原批量请求仅重新执行失败的设备；{id} 为原批量 task_id 或其重试任务 ID（均按原任务计算失败设备）
+// 异步执行，重试任务以 retry_of 关联原任务，汇总见 /api/v1/tasks/{id}/summary?combined=true
+func (h *BatchJobHandler) RetryFailed(c *gin.Context) {
+	var req RetryFailedRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Code: "INVALID_PARAMS", Message: "请求参数无效: " + err.Error()})
+			return
+		}
+	}
+	retry, err := h.svc.RetryFailed(c.Param("id"), req.TaskID, req.Deadline)
+	switch {
+	case errors.Is(err, service.ErrBatchJobNotFound):
+		c.JSON(http.StatusNotFound, ErrorResponse{Code: "BATCH_JOB_NOT_FOUND", Message: "批量任务不存在（需开启 batch_resume 记录原请求）"})
+	case errors.Is(err, service.ErrBatchJobRunning):
+		c.JSON(http.StatusConflict, ErrorResponse{Code: "BATCH_JOB_RUNNING", Message: "批量任务或其重试任务正在执行"})
+	case errors.Is(err, service.ErrNoFailedDevices):
+		c.JSON(http.StatusConflict, ErrorResponse{Code: "NO_FAILED_DEVICES", Message: "批量任务没有失败的设备"})
+	case errors.Is(err, service.ErrRetryNotAvailable):
+		c.JSON(http.StatusConflict, ErrorResponse{Code: "RETRY_NOT_AVAILABLE", Message: "原批量请求已清除，无法重试"})
+	case errors.Is(err, service.ErrRetryTaskIDExists):
+		c.JSON(http.StatusConflict, ErrorResponse{Code: "TASK_ID_CONFLICT", Message: "重试任务 ID 已存在: " + retry.ID})
+	case errors.Is(err, service.ErrBatchResumeDisabled):
+		c.JSON(http.StatusBadRequest, ErrorResponse{Code: "BATCH_RESUME_DISABLED", Message: "未开启 batch_resume，无法记录重试任务"})
+	case errors.Is(err, service.ErrVaultNotConfigured):
+		c.JSON(http.StatusBadRequest, ErrorResponse{Code: "VAULT_NOT_CONFIGURED", Message: err.Error()})
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Code: "RETRY_FAILED", Message: "失败重试启动失败: " + err.Error()})
+	default:
+		c.JSON(http.StatusAccepted, SuccessResponse{Code: "SUCCESS", Message: "失败设备已开始重试", Data: retry})
+	}
+}
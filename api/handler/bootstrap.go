@@ -0,0 +1,49 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/service"
+)
+
+// BootstrapHandler 首次初始化处理器
+type BootstrapHandler struct {
+	service *service.BootstrapService
+}
+
+// NewBootstrapHandler 创建首次初始化处理器
+func NewBootstrapHandler(s *service.BootstrapService) *BootstrapHandler {
+	return &BootstrapHandler{service: s}
+}
+
+// GetBootstrap GET /api/v1/bootstrap 初始化状态与初始化接口是否可用
+func (h *BootstrapHandler) GetBootstrap(c *gin.Context) {
+	st, err := h.service.Status()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Code: "QUERY_FAILED", Message: err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, SuccessResponse{Code: "SUCCESS", Message: "获取初始化状态成功", Data: st})
+}
+
+// RunBootstrap POST /api/v1/bootstrap 执行首次初始化，成功后接口锁定；管理令牌仅在本次响应中返回
+func (h *BootstrapHandler) RunBootstrap(c *gin.Context) {
+	var req service.BootstrapRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Code: "INVALID_PARAMS", Message: "请求参数无效: " + err.Error()})
+		return
+	}
+	res, err := h.service.Run(c.Request.Context(), &req, c.ClientIP())
+	if err != nil {
+		status, code := ErrorStatus(err, "BOOTSTRAP_FAILED")
+		if errors.Is(err, service.ErrBootstrapStorage) {
+			c.JSON(status, gin.H{"code": code, "message": "存储连通性校验失败，未完成初始化: " + err.Error(), "data": res})
+			return
+		}
+		c.JSON(status, ErrorResponse{Code: code, Message: err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, SuccessResponse{Code: "SUCCESS", Message: "初始化完成，请妥善保存管理令牌", Data: res})
+}
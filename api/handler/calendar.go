@@ -0,0 +1,224 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/database"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/model"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/service"
+	"github.com/sshcollectorpro/sshcollectorpro/pkg/logger"
+	"gorm.io/gorm"
+)
+
+// CalendarHandler 执行日历处理器
+type CalendarHandler struct{}
+
+// NewCalendarHandler 创建执行日历处理器
+func NewCalendarHandler() *CalendarHandler {
+	return &CalendarHandler{}
+}
+
+// CalendarView 执行日历返回结构（附当天判定）
+type CalendarView struct {
+	ID uint `json:"id"`
+	service.Calendar
+	Today     service.CalendarDecision `json:"today"`
+	CreatedAt time.Time                `json:"created_at"`
+	UpdatedAt time.Time                `json:"updated_at"`
+}
+
+// calendarRules 表字段 rules 的内容
+type calendarRules struct {
+	WorkingDays   []string                `json:"working_days,omitempty"`
+	Holidays      []service.CalendarDay   `json:"holidays,omitempty"`
+	ExtraWorkdays []service.CalendarDay   `json:"extra_workdays,omitempty"`
+	Blackouts     []service.CalendarRange `json:"blackouts,omitempty"`
+}
+
+// ListCalendars GET /api/v1/calendars
+func (h *CalendarHandler) ListCalendars(c *gin.Context) {
+	var rows []model.Calendar
+	if err := database.GetDB().Order("name ASC").Find(&rows).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Code: "QUERY_FAILED", Message: err.Error()})
+		return
+	}
+	items := make([]CalendarView, 0, len(rows))
+	for i := range rows {
+		items = append(items, calendarView(&rows[i]))
+	}
+	c.JSON(http.StatusOK, gin.H{"code": "SUCCESS", "message": "获取执行日历列表成功", "data": items, "total": len(items)})
+}
+
+// GetCalendar GET /api/v1/calendars/:name
+func (h *CalendarHandler) GetCalendar(c *gin.Context) {
+	var row model.Calendar
+	if err := database.GetDB().Where("name = ?", c.Param("name")).First(&row).Error; err != nil {
+		c.JSON(http.StatusNotFound, ErrorResponse{Code: "CALENDAR_NOT_FOUND", Message: "执行日历不存在"})
+		return
+	}
+	c.JSON(http.StatusOK, SuccessResponse{Code: "SUCCESS", Message: "获取执行日历成功", Data: calendarView(&row)})
+}
+
+// CreateCalendar POST /api/v1/calendars
+func (h *CalendarHandler) CreateCalendar(c *gin.Context) {
+	var cal service.Calendar
+	if err := c.ShouldBindJSON(&cal); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Code: "INVALID_PARAMS", Message: "请求参数无效: " + err.Error()})
+		return
+	}
+	cal.Name = strings.TrimSpace(cal.Name)
+	if err := cal.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Code: "INVALID_PARAMS", Message: err.Error()})
+		return
+	}
+	db := database.GetDB()
+	var count int64
+	if err := db.Model(&model.Calendar{}).Where("name = ?", cal.Name).Count(&count).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Code: "QUERY_FAILED", Message: err.Error()})
+		return
+	}
+	if count > 0 {
+		c.JSON(http.StatusConflict, ErrorResponse{Code: "CALENDAR_EXISTS", Message: "执行日历名称已存在"})
+		return
+	}
+	row := model.Calendar{Name: cal.Name}
+	if err := encodeCalendar(&row, &cal); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Code: "INVALID_PARAMS", Message: err.Error()})
+		return
+	}
+	if err := database.WithRetry(func(tx *gorm.DB) error { return tx.Create(&row).Error }, 3, 0); err != nil {
+		logger.Error("Failed to create calendar", "name", cal.Name, "error", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Code: "CREATE_FAILED", Message: "创建执行日历失败: " + err.Error()})
+		return
+	}
+	logger.Info("Calendar created", "name", row.Name)
+	c.JSON(http.StatusCreated, SuccessResponse{Code: "SUCCESS", Message: "执行日历创建成功", Data: calendarView(&row)})
+}
+
+// UpdateCalendar PUT /api/v1/calendars/:name（整体替换规则，名称不可修改）
+func (h *CalendarHandler) UpdateCalendar(c *gin.Context) {
+	var cal service.Calendar
+	if err := c.ShouldBindJSON(&cal); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Code: "INVALID_PARAMS", Message: "请求参数无效: " + err.Error()})
+		return
+	}
+	db := database.GetDB()
+	var row model.Calendar
+	if err := db.Where("name = ?", c.Param("name")).First(&row).Error; err != nil {
+		c.JSON(http.StatusNotFound, ErrorResponse{Code: "CALENDAR_NOT_FOUND", Message: "执行日历不存在"})
+		return
+	}
+	cal.Name = row.Name
+	if err := cal.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Code: "INVALID_PARAMS", Message: err.Error()})
+		return
+	}
+	if err := encodeCalendar(&row, &cal); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Code: "INVALID_PARAMS", Message: err.Error()})
+		return
+	}
+	if err := database.WithRetry(func(tx *gorm.DB) error { return tx.Save(&row).Error }, 3, 0); err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Code: "UPDATE_FAILED", Message: "更新执行日历失败: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, SuccessResponse{Code: "SUCCESS", Message: "执行日历更新成功", Data: calendarView(&row)})
+}
+
+// DeleteCalendar DELETE /api/v1/calendars/:name
+func (h *CalendarHandler) DeleteCalendar(c *gin.Context) {
+	res := database.GetDB().Where("name = ?", c.Param("name")).Delete(&model.Calendar{})
+	if res.Error != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Code: "DELETE_FAILED", Message: res.Error.Error()})
+		return
+	}
+	if res.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, ErrorResponse{Code: "CALENDAR_NOT_FOUND", Message: "执行日历不存在"})
+		return
+	}
+	c.JSON(http.StatusOK, SuccessResponse{Code: "SUCCESS", Message: "执行日历删除成功"})
+}
+
+// EvaluateCalendar GET /api/v1/calendars/:name/evaluate?date=2026-10-01&days=7
+// 从 date（默认今天）起连续 days 天（默认 7，最大 366）的执行判定
+func (h *CalendarHandler) EvaluateCalendar(c *gin.Context) {
+	cal, err := loadCalendar(c.Param("name"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, ErrorResponse{Code: "CALENDAR_NOT_FOUND", Message: err.Error()})
+		return
+	}
+	from := time.Now()
+	if v := strings.TrimSpace(c.Query("date")); v != "" {
+		if from, err = cal.ParseDate(v); err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Code: "INVALID_PARAMS", Message: "date 格式应为 YYYY-MM-DD"})
+			return
+		}
+	}
+	days, _ := strconv.Atoi(c.DefaultQuery("days", "7"))
+	if days < 1 || days > 366 {
+		days = 7
+	}
+	c.JSON(http.StatusOK, SuccessResponse{Code: "SUCCESS", Message: "日历判定成功", Data: gin.H{
+		"calendar":     cal.Name,
+		"timezone":     cal.Timezone,
+		"days":         cal.Upcoming(from, days),
+		"next_allowed": cal.NextAllowed(from),
+	}})
+}
+
+// loadCalendar 按名称加载执行日历
+func loadCalendar(name string) (*service.Calendar, error) {
+	var row model.Calendar
+	if err := database.GetDB().Where("name = ?", strings.TrimSpace(name)).First(&row).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("calendar %s not found", name)
+		}
+		return nil, fmt.Errorf("load calendar %s: %w", name, err)
+	}
+	return decodeCalendar(&row)
+}
+
+// encodeCalendar 规则以 JSON 存入表字段
+func encodeCalendar(row *model.Calendar, cal *service.Calendar) error {
+	b, err := json.Marshal(calendarRules{
+		WorkingDays: cal.WorkingDays, Holidays: cal.Holidays, ExtraWorkdays: cal.ExtraWorkdays, Blackouts: cal.Blackouts,
+	})
+	if err != nil {
+		return err
+	}
+	row.Description = cal.Description
+	row.Timezone = strings.TrimSpace(cal.Timezone)
+	row.Rules = string(b)
+	return nil
+}
+
+// decodeCalendar 表记录解码为服务层日历
+func decodeCalendar(row *model.Calendar) (*service.Calendar, error) {
+	cal := &service.Calendar{Name: row.Name, Description: row.Description, Timezone: row.Timezone}
+	if strings.TrimSpace(row.Rules) != "" {
+		var r calendarRules
+		if err := json.Unmarshal([]byte(row.Rules), &r); err != nil {
+			return nil, fmt.Errorf("decode calendar %s rules: %w", row.Name, err)
+		}
+		cal.WorkingDays, cal.Holidays, cal.ExtraWorkdays, cal.Blackouts = r.WorkingDays, r.Holidays, r.ExtraWorkdays, r.Blackouts
+	}
+	return cal, nil
+}
+
+func calendarView(row *model.Calendar) CalendarView {
+	v := CalendarView{ID: row.ID, CreatedAt: row.CreatedAt, UpdatedAt: row.UpdatedAt}
+	if cal, err := decodeCalendar(row); err == nil {
+		v.Calendar = *cal
+	} else {
+		logger.Warn("Invalid calendar record", "name", row.Name, "error", err)
+		v.Calendar = service.Calendar{Name: row.Name, Description: row.Description, Timezone: row.Timezone}
+	}
+	v.Today = v.Calendar.Evaluate(time.Now())
+	return v
+}
@@ -0,0 +1,33 @@
+package handler
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/service"
+)
+
+// ListCircuitBreakers GET /api/v1/circuit-breakers
+// 返回各设备的连续建连失败次数与熔断状态（closed/open/half_open）
+func ListCircuitBreakers(c *gin.Context) {
+	c.JSON(http.StatusOK, SuccessResponse{Code: "SUCCESS", Message: "获取设备熔断状态成功", Data: service.CircuitBreakerSnapshot()})
+}
+
+// ResetCircuitBreaker DELETE /api/v1/circuit-breakers/:ip
+// 清除指定设备的失败计数与熔断状态，下次请求立即拨号
+func ResetCircuitBreaker(c *gin.Context) {
+	ip := strings.TrimSpace(c.Param("ip"))
+	if service.ResetCircuitBreaker(ip) == 0 {
+		c.JSON(http.StatusNotFound, ErrorResponse{Code: "NOT_FOUND", Message: "设备未记录熔断状态: " + ip})
+		return
+	}
+	c.JSON(http.StatusOK, SuccessResponse{Code: "SUCCESS", Message: "设备熔断已重置", Data: gin.H{"device_ip": ip, "reset": 1}})
+}
+
+// ResetCircuitBreakers DELETE /api/v1/circuit-breakers
+// 清除全部设备的熔断状态
+func ResetCircuitBreakers(c *gin.Context) {
+	n := service.ResetCircuitBreaker("")
+	c.JSON(http.StatusOK, SuccessResponse{Code: "SUCCESS", Message: "设备熔断已全部重置", Data: gin.H{"reset": n}})
+}
@@ -1,13 +1,16 @@
 package handler
 
 import (
-	"encoding/json"
+	"context"
+	"errors"
 	"fmt"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/config"
 	"github.com/sshcollectorpro/sshcollectorpro/internal/service"
 	"github.com/sshcollectorpro/sshcollectorpro/pkg/logger"
 	"golang.org/x/sync/errgroup"
@@ -53,29 +56,45 @@ type FastCollectRequest struct {
 	DevicePlatform  string   `json:"device_platform,omitempty"`
 	CollectProtocol string   `json:"collect_protocol,omitempty"`
 	RetryFlag       *int     `json:"retry_flag,omitempty"`
-	Timeout         *int     `json:"timeout,omitempty"`       // 兼容示例中的 timeout
-	TaskTimeout     *int     `json:"task_timeout,omitempty"`  // 同义字段
+	RetryPolicy     *service.RetryPolicy `json:"retry_policy,omitempty"` // 退避与重试条件
+	TaskTimeout     *int     `json:"task_timeout,omitempty"`  // 旧名 timeout 由兼容层映射
 	UserName        string   `json:"user_name"`
 	Password        string   `json:"password"`
 	EnablePassword  string   `json:"enable_password,omitempty"`
-	CliList         []string `json:"cli_list"`
+	CredentialID    string   `json:"credential_id,omitempty"` // 引用已登记凭据，替代明文密码
+	CliList         service.CLIList `json:"cli_list"`
+	Playbook        string   `json:"playbook,omitempty"` // 引用命令集，命令集命令在 cli_list 之前执行
 	DeviceTimeout   *int     `json:"device_timeout,omitempty"`
+	OutputEncoding  string   `json:"output_encoding,omitempty"` // 控制字符编码：raw | strip | escape | base64
+	XPaths          map[string]string `json:"xpaths,omitempty"` // collect_protocol=netconf 时按路径提取字段
+	SNMP            *service.SNMPOptions `json:"snmp,omitempty"` // collect_protocol=snmp 时的版本与凭据
+	SimulateRecord  *service.SimulateRecordOptions `json:"simulate_record,omitempty"` // 录制回显到模拟器目录
+	PortCandidates  []int    `json:"port_candidates,omitempty"` // 备用端口：device_port 拒绝连接时按顺序尝试
+	service.Deprecations `json:"-"` // 请求中使用的旧字段名（兼容层填充）
 }
 
 func (h *CollectorHandler) FastCollect(c *gin.Context) {
 	var req FastCollectRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, ErrorResponse{Code: "INVALID_PARAMS", Message: "请求参数无效: " + err.Error()})
+	if err := bindJSON(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Code: "INVALID_PARAMS", Message: "请求参数无效: " + err.Error(), Errors: fieldErrors(err)})
+		return
+	}
+	if err := service.ValidateOutputEncoding(req.OutputEncoding); err != nil {
+		c.Error(err)
 		return
 	}
 
-	// 组装服务层请求，兼容 timeout / task_timeout
-	var effTimeout *int
-	if req.Timeout != nil && *req.Timeout > 0 {
-		effTimeout = req.Timeout
-	} else if req.TaskTimeout != nil && *req.TaskTimeout > 0 {
-		effTimeout = req.TaskTimeout
+	if err := resolveCredential(req.CredentialID, &req.UserName, &req.Password, &req.EnablePassword); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Code: "CREDENTIAL_INVALID", Message: err.Error()})
+		return
 	}
+	cli, err := newPlaybookResolver().expand(req.Playbook, req.DevicePlatform, req.CliList)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Code: "PLAYBOOK_INVALID", Message: err.Error()})
+		return
+	}
+
+	// 组装服务层请求
 	// 默认协议为 ssh
 	proto := strings.TrimSpace(strings.ToLower(req.CollectProtocol))
 	if proto == "" { proto = "ssh" }
@@ -91,10 +110,15 @@ func (h *CollectorHandler) FastCollect(c *gin.Context) {
 		UserName:        req.UserName,
 		Password:        req.Password,
 		EnablePassword:  req.EnablePassword,
-		CliList:         req.CliList,
+		CliList:         cli,
 		RetryFlag:       req.RetryFlag,
-		TaskTimeout:     effTimeout,
+		RetryPolicy:     req.RetryPolicy,
+		TaskTimeout:     req.TaskTimeout,
 		DeviceTimeout:   req.DeviceTimeout,
+		XPaths:          req.XPaths,
+		SNMP:            req.SNMP,
+		SimulateRecord:  req.SimulateRecord,
+		PortCandidates:  req.PortCandidates,
 		Metadata:        map[string]interface{}{ "collect_mode": "fast" },
 	}
 
@@ -107,16 +131,12 @@ func (h *CollectorHandler) FastCollect(c *gin.Context) {
 	// 调用采集服务：服务层已暂停任务写库；任务上下文在执行后移除，不保留记录
 	resp, err := h.collectorService.ExecuteTask(c.Request.Context(), &r)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, ErrorResponse{Code: "EXEC_FAILED", Message: err.Error()})
+		c.Error(err).SetMeta("EXEC_FAILED")
 		return
 	}
 
 	// 返回结果，关闭HTML转义以保留原始设备输出
-	c.Header("Content-Type", "application/json")
-	c.Status(http.StatusOK)
-	enc := json.NewEncoder(c.Writer)
-	enc.SetEscapeHTML(false)
-	_ = enc.Encode(gin.H{
+	writeOutputJSON(c, http.StatusOK, outputEncoding(c, req.OutputEncoding), gin.H{
 		"code":    "SUCCESS",
 		"message": "快速采集完成",
 		"data":    resp,
@@ -147,10 +167,7 @@ func (h *CollectorHandler) GetTaskStatus(c *gin.Context) {
 	taskContext, err := h.collectorService.GetTaskStatus(taskID)
 	if err != nil {
 		logger.Error("Failed to get task status", "task_id", taskID, "error", err)
-		c.JSON(http.StatusNotFound, ErrorResponse{
-			Code:    "TASK_NOT_FOUND",
-			Message: "任务不存在: " + taskID,
-		})
+		c.Error(err)
 		return
 	}
 
@@ -186,10 +203,7 @@ func (h *CollectorHandler) CancelTask(c *gin.Context) {
 	err := h.collectorService.CancelTask(taskID)
 	if err != nil {
 		logger.Error("Failed to cancel task", "task_id", taskID, "error", err)
-		c.JSON(http.StatusNotFound, ErrorResponse{
-			Code:    "TASK_NOT_FOUND",
-			Message: "任务不存在: " + taskID,
-		})
+		c.Error(err)
 		return
 	}
 
@@ -210,6 +224,26 @@ func (h *CollectorHandler) CancelTask(c *gin.Context) {
 // @Router /api/v1/collector/stats [get]
 func (h *CollectorHandler) GetStats(c *gin.Context) {
 	stats := h.collectorService.GetStats()
+	// 各服务 worker 占用、排队深度与等待分位数（含当前并发档位）
+	if pools := workerPools(); len(pools) > 0 {
+		stats["worker_pools"] = pools
+	} else {
+		stats["worker_pools"] = []service.WorkerUtilization{h.collectorService.WorkerUtilization()}
+	}
+	// days>0 时附带持久化的按天汇总（metrics.persist_daily），platform 可选过滤
+	if v := strings.TrimSpace(c.Query("days")); v != "" {
+		days, err := strconv.Atoi(v)
+		if err != nil || days <= 0 || days > 366 {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Code: "INVALID_PARAMS", Message: "days 须为 1-366 的整数"})
+			return
+		}
+		rows, err := service.ListDailyCommandStats(c.Request.Context(), days, c.Query("platform"))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Code: "INTERNAL_ERROR", Message: "查询命令统计日汇总失败: " + err.Error()})
+			return
+		}
+		stats["command_stats_daily"] = rows
+	}
 	c.JSON(http.StatusOK, gin.H{
 		"code":    "SUCCESS",
 		"message": "获取统计信息成功",
@@ -253,20 +287,33 @@ func (h *CollectorHandler) Health(c *gin.Context) {
 // @Produce json
 // @Param requests body []service.CollectRequest true "批量采集请求"
 // @Success 200 {object} []service.CollectResponse "批量采集结果"
+// @Success 207 {object} map[string]interface{} "部分设备失败（PARTIAL_SUCCESS）"
+// @Failure 502 {object} map[string]interface{} "全部设备失败（FAILED）"
 // @Failure 400 {object} ErrorResponse "请求参数错误"
 // @Failure 500 {object} ErrorResponse "服务器内部错误"
 // @Router /api/v1/collector/batch [post]
 func (h *CollectorHandler) BatchExecute(c *gin.Context) {
 	var requests []service.CollectRequest
-	if err := c.ShouldBindJSON(&requests); err != nil {
+	if err := bindJSON(c, &requests); err != nil {
 		logger.Error("Invalid batch request parameters", "error", err)
 		c.JSON(http.StatusBadRequest, ErrorResponse{
 			Code:    "INVALID_PARAMS",
 			Message: "批量请求参数无效: " + err.Error(),
+			Errors:  fieldErrors(err),
 		})
 		return
 	}
 
+	if err := service.ValidateOutputEncoding(c.Query("output_encoding")); err != nil {
+		c.Error(err)
+		return
+	}
+	ro, err := responseOutput(c, "", 0)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
 	if len(requests) == 0 {
 		c.JSON(http.StatusBadRequest, ErrorResponse{
 			Code:    "EMPTY_REQUESTS",
@@ -284,11 +331,17 @@ func (h *CollectorHandler) BatchExecute(c *gin.Context) {
 	}
 
 	responses := make([]*service.CollectResponse, 0, len(requests))
+	playbooks := newPlaybookResolver()
 
 	// 并发执行任务
 	for i, request := range requests {
-		// 参数验证
-		if err := h.validateCollectRequest(&request); err != nil {
+		// 展开命令集后参数验证
+		cli, err := playbooks.expand(request.Playbook, request.DevicePlatform, request.CliList)
+		if err == nil {
+			request.CliList = cli
+			err = h.validateCollectRequest(&request)
+		}
+		if err != nil {
 			responses = append(responses, &service.CollectResponse{
 				TaskID:    request.TaskID,
 				Success:   false,
@@ -300,6 +353,9 @@ func (h *CollectorHandler) BatchExecute(c *gin.Context) {
 
 		// 同步执行；超时在服务层根据插件默认或传入参数处理
 		ctx := c.Request.Context()
+		if ro.RequiresStore() {
+			request.Store = true
+		}
 		response, err := h.collectorService.ExecuteTask(ctx, &request)
 		if err != nil {
 			response = &service.CollectResponse{
@@ -309,21 +365,26 @@ func (h *CollectorHandler) BatchExecute(c *gin.Context) {
 				Timestamp: time.Now(),
 			}
 		}
+		ro.ApplyCollect(response.Results)
 
 		responses = append(responses, response)
 
 		logger.Info("Batch task completed", "index", i+1, "task_id", request.TaskID, "success", response.Success)
 	}
 
+	successCount := 0
+	for _, r := range responses {
+		if r != nil && r.Success {
+			successCount++
+		}
+	}
+	outcome := service.NewBatchOutcome("批量任务", len(responses), successCount)
+
 	// 使用自定义编码器关闭 HTML 转义，避免 \u003c/\u003e 转义影响原始设备输出可读性
-	c.Header("Content-Type", "application/json")
-	c.Status(http.StatusOK)
-	enc := json.NewEncoder(c.Writer)
-	enc.SetEscapeHTML(false)
 	encodeStart := time.Now()
-	_ = enc.Encode(gin.H{
-		"code":    "SUCCESS",
-		"message": "批量任务执行完成",
+	writeOutputJSON(c, service.BatchHTTPStatus(outcome.Code), outputEncoding(c, ""), gin.H{
+		"code":    outcome.Code,
+		"message": outcome.Message,
 		"data":    responses,
 		"total":   len(responses),
 	})
@@ -336,8 +397,22 @@ type CustomerBatchRequest struct {
 	TaskID      string           `json:"task_id"`
 	TaskName    string           `json:"task_name,omitempty"`
 	RetryFlag   *int             `json:"retry_flag,omitempty"`
+	RetryPolicy *service.RetryPolicy `json:"retry_policy,omitempty"` // 退避与重试条件
 	TaskTimeout *int             `json:"task_timeout,omitempty"`
+	CallbackURL string           `json:"callback_url,omitempty"` // 批次完成后推送设备摘要
+	Deadline    *time.Time       `json:"deadline,omitempty"`     // 执行窗口截止时间（RFC3339），到点后不再派发新设备
+	OutputEncoding string        `json:"output_encoding,omitempty"` // 控制字符编码：raw | strip | escape | base64
+	RawOutputMode  string        `json:"raw_output_mode,omitempty"` // 响应中原始输出：full | omit | truncate | uri
+	MaxOutputKB    int           `json:"max_output_kb,omitempty"`   // truncate 模式单条命令保留的 KB 数
+	Metadata    map[string]interface{} `json:"metadata,omitempty"` // 随任务记录持久化，可在任务历史中过滤
+	Store          bool        `json:"store,omitempty"`           // 落盘每条命令输出，结果返回 stored_objects
+	SaveDir        string      `json:"save_dir,omitempty"`        // 落盘目录（同备份 save_dir）
+	StorageBackend string      `json:"storage_backend,omitempty"` // local | minio | s3 | azure
+	FreshTTL       int         `json:"fresh_ttl,omitempty"`       // 默认新鲜度（秒）：store=true 时最近落盘结果未过期的命令跳过执行
+	Playbook       string      `json:"playbook,omitempty"`        // 引用命令集，对每台设备按平台展开
+	DuplicateMode  string      `json:"duplicate_mode,omitempty"`  // 批内重复设备处理：reject | merge | copy（默认读取配置）
 	Devices     []CustomerDevice `json:"devices"`
+	service.Deprecations `json:"-"` // 请求中使用的旧字段名（兼容层填充）
 }
 
 // CustomerDevice 自定义采集设备参数
@@ -350,8 +425,13 @@ type CustomerDevice struct {
 	UserName        string   `json:"user_name"`
 	Password        string   `json:"password"`
 	EnablePassword  string   `json:"enable_password,omitempty"`
-	CliList         []string `json:"cli_list,omitempty"`
+	CredentialID    string   `json:"credential_id,omitempty"` // 引用已登记凭据，替代明文密码
+	CliList         service.CLIList `json:"cli_list,omitempty"`
 	DeviceTimeout   *int     `json:"device_timeout,omitempty"`
+	XPaths          map[string]string `json:"xpaths,omitempty"` // collect_protocol=netconf 时按路径提取字段
+	SNMP            *service.SNMPOptions `json:"snmp,omitempty"` // collect_protocol=snmp 时的版本与凭据
+	SimulateRecord  *service.SimulateRecordOptions `json:"simulate_record,omitempty"` // 录制回显到模拟器目录
+	PortCandidates  []int    `json:"port_candidates,omitempty"` // 备用端口：device_port 拒绝连接时按顺序尝试
 }
 
 // SystemBatchRequest 系统预制采集批量请求
@@ -359,8 +439,21 @@ type SystemBatchRequest struct {
 	TaskID      string         `json:"task_id"`
 	TaskName    string         `json:"task_name,omitempty"`
 	RetryFlag   *int           `json:"retry_flag,omitempty"`
+	RetryPolicy *service.RetryPolicy `json:"retry_policy,omitempty"` // 退避与重试条件
 	TaskTimeout *int           `json:"task_timeout,omitempty"`
+	CallbackURL string         `json:"callback_url,omitempty"` // 批次完成后推送设备摘要
+	Deadline    *time.Time     `json:"deadline,omitempty"`     // 执行窗口截止时间（RFC3339），到点后不再派发新设备
+	OutputEncoding string      `json:"output_encoding,omitempty"` // 控制字符编码：raw | strip | escape | base64
+	RawOutputMode  string      `json:"raw_output_mode,omitempty"` // 响应中原始输出：full | omit | truncate | uri
+	MaxOutputKB    int         `json:"max_output_kb,omitempty"`   // truncate 模式单条命令保留的 KB 数
+	Metadata    map[string]interface{} `json:"metadata,omitempty"` // 随任务记录持久化，可在任务历史中过滤
+	Store          bool        `json:"store,omitempty"`           // 落盘每条命令输出，结果返回 stored_objects
+	SaveDir        string      `json:"save_dir,omitempty"`        // 落盘目录（同备份 save_dir）
+	StorageBackend string      `json:"storage_backend,omitempty"` // local | minio | s3 | azure
+	FreshTTL       int         `json:"fresh_ttl,omitempty"`       // 默认新鲜度（秒）：store=true 时最近落盘结果未过期的命令跳过执行
+	DuplicateMode  string      `json:"duplicate_mode,omitempty"`  // 批内重复设备处理：reject | merge | copy（默认读取配置）
 	DeviceList  []SystemDevice `json:"device_list"`
+	service.Deprecations `json:"-"` // 请求中使用的旧字段名（兼容层填充）
 }
 
 // SystemDevice 系统预制采集设备参数（cli_list 可选扩展）
@@ -373,8 +466,12 @@ type SystemDevice struct {
 	UserName        string   `json:"user_name"`
 	Password        string   `json:"password"`
 	EnablePassword  string   `json:"enable_password,omitempty"`
-	CliList         []string `json:"cli_list,omitempty"`
+	CredentialID    string   `json:"credential_id,omitempty"` // 引用已登记凭据，替代明文密码
+	CliList         service.CLIList `json:"cli_list,omitempty"`
 	DeviceTimeout   *int     `json:"device_timeout,omitempty"`
+	SNMP            *service.SNMPOptions `json:"snmp,omitempty"` // collect_protocol=snmp 时的版本与凭据
+	SimulateRecord  *service.SimulateRecordOptions `json:"simulate_record,omitempty"` // 录制回显到模拟器目录
+	PortCandidates  []int    `json:"port_candidates,omitempty"` // 备用端口：device_port 拒绝连接时按顺序尝试
 }
 
 // BatchExecuteCustomer 自定义采集批量接口
@@ -385,14 +482,16 @@ type SystemDevice struct {
 // @Produce json
 // @Param request body CustomerBatchRequest true "自定义批量采集请求"
 // @Success 200 {object} map[string]interface{} "批量采集结果，按设备组织"
+// @Success 207 {object} map[string]interface{} "部分设备失败（PARTIAL_SUCCESS）"
+// @Failure 502 {object} map[string]interface{} "全部设备失败（FAILED）"
 // @Failure 400 {object} ErrorResponse "请求参数错误"
 // @Failure 500 {object} ErrorResponse "服务器内部错误"
 // @Router /api/v1/collector/batch/custom [post]
 func (h *CollectorHandler) BatchExecuteCustomer(c *gin.Context) {
 	var req CustomerBatchRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
+	if err := bindJSON(c, &req); err != nil {
 		logger.Error("Invalid custom batch request", "error", err)
-		c.JSON(http.StatusBadRequest, ErrorResponse{Code: "INVALID_PARAMS", Message: "请求参数无效: " + err.Error()})
+		c.JSON(http.StatusBadRequest, ErrorResponse{Code: "INVALID_PARAMS", Message: "请求参数无效: " + err.Error(), Errors: fieldErrors(err)})
 		return
 	}
 
@@ -408,7 +507,102 @@ func (h *CollectorHandler) BatchExecuteCustomer(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, ErrorResponse{Code: "TOO_MANY_DEVICES", Message: "批量设备数量不能超过200"})
 		return
 	}
+	if err := service.ValidateOutputEncoding(req.OutputEncoding); err != nil {
+		c.Error(err)
+		return
+	}
+	ro, err := responseOutput(c, req.RawOutputMode, req.MaxOutputKB)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	// 解析结果写回请求（续跑沿用）；omit/uri 需落盘
+	req.RawOutputMode, req.MaxOutputKB = ro.Mode, ro.MaxKB
+	if ro.RequiresStore() {
+		req.Store = true
+	}
+	if err := service.ValidateCallbackURL(req.CallbackURL); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Code: "INVALID_PARAMS", Message: err.Error()})
+		return
+	}
+	// 按 credential_id 解析登录凭据，按设备平台展开命令集
+	playbooks := newPlaybookResolver()
+	for i := range req.Devices {
+		d := &req.Devices[i]
+		if err := resolveCredential(d.CredentialID, &d.UserName, &d.Password, &d.EnablePassword); err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Code: "CREDENTIAL_INVALID", Message: err.Error()})
+			return
+		}
+		cli, err := playbooks.expand(req.Playbook, d.DevicePlatform, d.CliList)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Code: "PLAYBOOK_INVALID", Message: err.Error()})
+			return
+		}
+		d.CliList = cli
+	}
+	// 批内重复设备：命令并入首次出现的设备；merge 移除重复项，copy 保留位置并在执行后复制结果
+	dedup, ok := dedupBatchDevices(c, req.DuplicateMode, len(req.Devices), func(i int) (string, int, string) {
+		return req.Devices[i].DeviceIP, req.Devices[i].Port, req.Devices[i].UserName
+	})
+	if !ok {
+		return
+	}
+	req.DuplicateMode = dedup.Mode
+	for _, dup := range dedup.Duplicates {
+		p := &req.Devices[dup.DuplicateOf]
+		p.CliList = service.MergeCLIList(p.CliList, req.Devices[dup.Index].CliList)
+	}
+	if dedup.Mode == service.DuplicateModeMerge && dedup.HasDuplicates() {
+		kept := make([]CustomerDevice, 0, len(req.Devices))
+		for _, i := range dedup.Kept() {
+			kept = append(kept, req.Devices[i])
+		}
+		req.Devices = kept
+	}
+
+	// 断点记录：进程中断后可仅对未完成设备续跑
+	total := service.BatchDeviceTotal(len(req.Devices), func(i int) (string, int) { return req.Devices[i].DeviceIP, req.Devices[i].Port })
+	journal := service.StartBatchJournal(model.BatchJobKindCollectCustom, req.TaskID, req.TaskName, total, &req, "")
+	responses := h.runCustomerBatch(c.Request.Context(), &req, journal)
+	journal.Finish()
+
+	// 汇总成功/失败以确定顶层返回码与 HTTP 状态（各批量接口统一）
+	successCount := 0
+	for _, r := range responses {
+		if s, ok := r["success"].(bool); ok && s {
+			successCount++
+		}
+	}
+
+	outcome := service.NewBatchOutcome("自定义批量任务", len(responses), successCount)
+	respCode, respMsg := outcome.Code, outcome.Message
+
+	// 批次完成回调（异步，不影响响应）
+	if req.CallbackURL != "" {
+		payload := service.NewCollectCallbackPayload(req.TaskID, req.TaskName, "collect_custom", responses)
+		payload.Message = respMsg
+		service.NotifyCallback(config.Get(), req.CallbackURL, payload)
+	}
+
+	body := gin.H{
+		"code":    respCode,
+		"message": respMsg,
+		"data":    responses,
+		"total":   len(responses),
+	}
+	if dedup.HasDuplicates() {
+		body["duplicates"] = dedup.Duplicates
+	}
+	// 使用自定义编码器关闭 HTML 转义，避免 \u003c/\u003e 等转义影响原始输出可读性
+	encodeStart := time.Now()
+	writeOutputJSON(c, service.BatchHTTPStatus(respCode), outputEncoding(c, req.OutputEncoding), body)
+	encodeDur := time.Since(encodeStart)
+	logger.Info("BatchExecuteCustomer response encoded", "path", c.FullPath(), "size_bytes", c.Writer.Size(), "duration_ms", encodeDur.Milliseconds(), "count", len(responses))
+}
 
+// runCustomerBatch 并发执行自定义采集批量请求，返回按设备顺序的响应
+// journal 记录逐设备完成状态；续跑时已完成的设备跳过（对应位置为 nil）
+func (h *CollectorHandler) runCustomerBatch(ctx context.Context, req *CustomerBatchRequest, journal *service.BatchJournal) []map[string]interface{} {
 	// 基于服务的最大 worker 数控制批内并发度
 	stats := h.collectorService.GetStats()
 	maxWorkers := 4
@@ -425,21 +619,36 @@ func (h *CollectorHandler) BatchExecuteCustomer(c *gin.Context) {
 	}
 
 	responses := make([]map[string]interface{}, len(req.Devices))
-	reqCtx := c.Request.Context()
+	ro := service.ResponseOutput{Mode: req.RawOutputMode, MaxKB: req.MaxOutputKB}
+	// copy 模式：重复设备不执行，结束后复制首次出现设备的结果
+	var dedup *service.DeviceDedup
+	if req.DuplicateMode == service.DuplicateModeCopy {
+		dedup, _ = service.DedupDevices(nil, req.DuplicateMode, len(req.Devices), func(i int) (string, int, string) {
+			return req.Devices[i].DeviceIP, req.Devices[i].Port, req.Devices[i].UserName
+		})
+	}
 	sem := make(chan struct{}, k)
-	g, ctx := errgroup.WithContext(reqCtx)
+	ctx, endBatch := service.StartBatchSpan(ctx, "collect", req.TaskID, len(req.Devices))
+	g, ctx := errgroup.WithContext(ctx)
 
 	for i, d := range req.Devices {
 		i, d := i, d // capture loop vars
+		if journal.IsDone(d.DeviceIP, d.Port) || dedup.IsDuplicate(i) {
+			continue
+		}
 		g.Go(func() error {
-			// 并发控制
-			select {
-			case sem <- struct{}{}:
-				defer func() { <-sem }()
-			case <-ctx.Done():
-				// 请求已取消
+			// 并发控制（受执行窗口约束）；排队耗时计入设备时间线
+			devCtx, timings := service.WithTimings(ctx)
+			queued := time.Now()
+			if err := service.AcquireDispatchSlot(ctx, sem, req.Deadline); err != nil {
+				if errors.Is(err, service.ErrWindowClosed) {
+					responses[i] = windowClosedResponse(fmt.Sprintf("%s-%d", req.TaskID, i+1), d.DeviceIP, d.Port, d.DeviceName, d.DevicePlatform)
+				}
+				// 请求已取消或窗口关闭
 				return nil
 			}
+			defer func() { <-sem }()
+			timings.AddQueueWait(time.Since(queued))
 
 			// 组装单设备请求（customer）
 			r := service.CollectRequest{
@@ -456,9 +665,18 @@ func (h *CollectorHandler) BatchExecuteCustomer(c *gin.Context) {
 				EnablePassword:  d.EnablePassword,
 				CliList:         d.CliList,
 				RetryFlag:       req.RetryFlag,
+				RetryPolicy:     req.RetryPolicy,
 				TaskTimeout:     req.TaskTimeout,
 				DeviceTimeout:   d.DeviceTimeout,
-				Metadata:        map[string]interface{}{"batch_task_id": req.TaskID, "collect_mode": "customer"},
+				XPaths:          d.XPaths,
+				SNMP:            d.SNMP,
+				SimulateRecord:  d.SimulateRecord,
+				PortCandidates:  d.PortCandidates,
+				Metadata:        service.MergeTaskMetadata(req.Metadata, map[string]interface{}{"batch_task_id": req.TaskID, "collect_mode": "customer"}),
+				Store:           req.Store,
+				SaveDir:         req.SaveDir,
+				StorageBackend:  req.StorageBackend,
+				FreshTTL:        req.FreshTTL,
 			}
 
 			if err := h.validateCollectRequest(&r); err != nil {
@@ -472,18 +690,21 @@ func (h *CollectorHandler) BatchExecuteCustomer(c *gin.Context) {
 					"task_id":         r.TaskID,
 					"timestamp":       time.Now(),
 				}
+				journal.DeviceDone(d.DeviceIP, d.Port, false)
 				return nil
 			}
 
-			resp, err := h.collectorService.ExecuteTask(ctx, &r)
+			resp, err := h.collectorService.ExecuteTask(devCtx, &r)
 			if err != nil {
 				resp = &service.CollectResponse{
 					TaskID:    r.TaskID,
 					Success:   false,
 					Error:     err.Error(),
 					Timestamp: time.Now(),
+					Timings:   timings,
 				}
 			}
+			ro.ApplyCollect(resp.Results)
 
 			responses[i] = map[string]interface{}{
 				"device_ip":       d.DeviceIP,
@@ -495,49 +716,24 @@ func (h *CollectorHandler) BatchExecuteCustomer(c *gin.Context) {
 				"results":         resp.Results,
 				"error":           resp.Error,
 				"duration_ms":     resp.DurationMS,
+				"timings":         resp.Timings,
 				"timestamp":       resp.Timestamp,
 			}
+			if len(resp.Attempts) > 0 {
+				responses[i]["attempts"] = resp.Attempts
+			}
+			if resp.SkippedFresh > 0 {
+				responses[i]["skipped_fresh"] = resp.SkippedFresh
+			}
+			journal.DeviceDone(d.DeviceIP, d.Port, resp.Success)
 			return nil
 		})
 	}
 
 	_ = g.Wait()
-
-	// 汇总成功/失败以确定顶层返回码（与备份接口保持一致）
-	successCount := 0
-	for _, r := range responses {
-		if s, ok := r["success"].(bool); ok && s {
-			successCount++
-		}
-	}
-
-	respCode := "SUCCESS"
-	respMsg := "自定义批量任务执行完成"
-	// 与备份接口对齐：全部或部分失败均返回 PARTIAL_SUCCESS，不返回 FAILED
-	if successCount < len(responses) {
-		// 包括 successCount == 0（全部失败）和部分成功
-		respCode = "PARTIAL_SUCCESS"
-		if successCount == 0 {
-			respMsg = "自定义批量任务全部失败"
-		} else {
-			respMsg = "自定义批量任务部分成功"
-		}
-	}
-
-	// 使用自定义编码器关闭 HTML 转义，避免 \u003c/\u003e 等转义影响原始输出可读性
-	c.Header("Content-Type", "application/json")
-	c.Status(http.StatusOK)
-	enc := json.NewEncoder(c.Writer)
-	enc.SetEscapeHTML(false)
-	encodeStart := time.Now()
-	_ = enc.Encode(gin.H{
-		"code":    respCode,
-		"message": respMsg,
-		"data":    responses,
-		"total":   len(responses),
-	})
-	encodeDur := time.Since(encodeStart)
-	logger.Info("BatchExecuteCustomer response encoded", "path", c.FullPath(), "size_bytes", c.Writer.Size(), "duration_ms", encodeDur.Milliseconds(), "count", len(responses))
+	copyDuplicateResponses(responses, dedup, func(i int) string { return req.Devices[i].DeviceName })
+	endBatch(countSucceeded(responses))
+	return responses
 }
 
 // BatchExecuteSystem 系统预制采集批量接口
@@ -548,14 +744,16 @@ func (h *CollectorHandler) BatchExecuteCustomer(c *gin.Context) {
 // @Produce json
 // @Param request body SystemBatchRequest true "系统预制批量采集请求"
 // @Success 200 {object} map[string]interface{} "批量采集结果，按设备组织"
+// @Success 207 {object} map[string]interface{} "部分设备失败（PARTIAL_SUCCESS）"
+// @Failure 502 {object} map[string]interface{} "全部设备失败（FAILED）"
 // @Failure 400 {object} ErrorResponse "请求参数错误"
 // @Failure 500 {object} ErrorResponse "服务器内部错误"
 // @Router /api/v1/collector/batch/system [post]
 func (h *CollectorHandler) BatchExecuteSystem(c *gin.Context) {
 	var req SystemBatchRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
+	if err := bindJSON(c, &req); err != nil {
 		logger.Error("Invalid system batch request", "error", err)
-		c.JSON(http.StatusBadRequest, ErrorResponse{Code: "INVALID_PARAMS", Message: "请求参数无效: " + err.Error()})
+		c.JSON(http.StatusBadRequest, ErrorResponse{Code: "INVALID_PARAMS", Message: "请求参数无效: " + err.Error(), Errors: fieldErrors(err)})
 		return
 	}
 
@@ -571,6 +769,51 @@ func (h *CollectorHandler) BatchExecuteSystem(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, ErrorResponse{Code: "TOO_MANY_DEVICES", Message: "批量设备数量不能超过200"})
 		return
 	}
+	if err := service.ValidateOutputEncoding(req.OutputEncoding); err != nil {
+		c.Error(err)
+		return
+	}
+	ro, err := responseOutput(c, req.RawOutputMode, req.MaxOutputKB)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	// 解析结果写回请求（续跑沿用）；omit/uri 需落盘
+	req.RawOutputMode, req.MaxOutputKB = ro.Mode, ro.MaxKB
+	if ro.RequiresStore() {
+		req.Store = true
+	}
+	if err := service.ValidateCallbackURL(req.CallbackURL); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Code: "INVALID_PARAMS", Message: err.Error()})
+		return
+	}
+	// 按 credential_id 解析登录凭据
+	for i := range req.DeviceList {
+		d := &req.DeviceList[i]
+		if err := resolveCredential(d.CredentialID, &d.UserName, &d.Password, &d.EnablePassword); err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Code: "CREDENTIAL_INVALID", Message: err.Error()})
+			return
+		}
+	}
+	// 批内重复设备：命令并入首次出现的设备；merge 移除重复项，copy 保留位置并在执行后复制结果
+	dedup, ok := dedupBatchDevices(c, req.DuplicateMode, len(req.DeviceList), func(i int) (string, int, string) {
+		return req.DeviceList[i].DeviceIP, req.DeviceList[i].Port, req.DeviceList[i].UserName
+	})
+	if !ok {
+		return
+	}
+	for _, dup := range dedup.Duplicates {
+		p := &req.DeviceList[dup.DuplicateOf]
+		p.CliList = service.MergeCLIList(p.CliList, req.DeviceList[dup.Index].CliList)
+	}
+	if dedup.Mode == service.DuplicateModeMerge && dedup.HasDuplicates() {
+		kept := make([]SystemDevice, 0, len(req.DeviceList))
+		for _, i := range dedup.Kept() {
+			kept = append(kept, req.DeviceList[i])
+		}
+		req.DeviceList = kept
+		dedup = &service.DeviceDedup{Mode: dedup.Mode, Duplicates: dedup.Duplicates}
+	}
 
 	// 基于服务的最大 worker 数控制批内并发度
 	stats := h.collectorService.GetStats()
@@ -587,20 +830,27 @@ func (h *CollectorHandler) BatchExecuteSystem(c *gin.Context) {
 	}
 
 	responses := make([]map[string]interface{}, len(req.DeviceList))
-	reqCtx := c.Request.Context()
+	reqCtx, endBatch := service.StartBatchSpan(c.Request.Context(), "collect", req.TaskID, len(req.DeviceList))
 	sem := make(chan struct{}, k)
 	g, ctx := errgroup.WithContext(reqCtx)
 
 	for i, d := range req.DeviceList {
 		i, d := i, d // capture loop vars
+		if dedup.IsDuplicate(i) {
+			continue
+		}
 		g.Go(func() error {
-			// 并发控制
-			select {
-			case sem <- struct{}{}:
-				defer func() { <-sem }()
-			case <-ctx.Done():
+			// 并发控制（受执行窗口约束）；排队耗时计入设备时间线
+			devCtx, timings := service.WithTimings(ctx)
+			queued := time.Now()
+			if err := service.AcquireDispatchSlot(ctx, sem, req.Deadline); err != nil {
+				if errors.Is(err, service.ErrWindowClosed) {
+					responses[i] = windowClosedResponse(fmt.Sprintf("%s-%d", req.TaskID, i+1), d.DeviceIP, d.Port, d.DeviceName, d.DevicePlatform)
+				}
 				return nil
 			}
+			defer func() { <-sem }()
+			timings.AddQueueWait(time.Since(queued))
 
 			// 校验平台必填
 			if strings.TrimSpace(d.DevicePlatform) == "" {
@@ -617,7 +867,7 @@ func (h *CollectorHandler) BatchExecuteSystem(c *gin.Context) {
 			}
 
 			// 仅使用用户提供的命令列表（不再注入平台默认命令）
-			cliCombined := make([]string, 0, len(d.CliList))
+			cliCombined := make(service.CLIList, 0, len(d.CliList))
 			if len(d.CliList) > 0 {
 				cliCombined = append(cliCombined, d.CliList...)
 			}
@@ -637,9 +887,17 @@ func (h *CollectorHandler) BatchExecuteSystem(c *gin.Context) {
 				EnablePassword:  d.EnablePassword,
 				CliList:         cliCombined, // 预组装系统命令 + 扩展命令
 				RetryFlag:       req.RetryFlag,
+				RetryPolicy:     req.RetryPolicy,
 				TaskTimeout:     req.TaskTimeout,
 				DeviceTimeout:   d.DeviceTimeout,
-				Metadata:        map[string]interface{}{"batch_task_id": req.TaskID, "collect_mode": "system"},
+				SNMP:            d.SNMP,
+				SimulateRecord:  d.SimulateRecord,
+				PortCandidates:  d.PortCandidates,
+				Metadata:        service.MergeTaskMetadata(req.Metadata, map[string]interface{}{"batch_task_id": req.TaskID, "collect_mode": "system"}),
+				Store:           req.Store,
+				SaveDir:         req.SaveDir,
+				StorageBackend:  req.StorageBackend,
+				FreshTTL:        req.FreshTTL,
 			}
 
 			if err := h.validateCollectRequest(&r); err != nil {
@@ -655,15 +913,17 @@ func (h *CollectorHandler) BatchExecuteSystem(c *gin.Context) {
 				return nil
 			}
 
-			resp, err := h.collectorService.ExecuteTask(ctx, &r)
+			resp, err := h.collectorService.ExecuteTask(devCtx, &r)
 			if err != nil {
 				resp = &service.CollectResponse{
 					TaskID:    r.TaskID,
 					Success:   false,
 					Error:     err.Error(),
 					Timestamp: time.Now(),
+					Timings:   timings,
 				}
 			}
+			ro.ApplyCollect(resp.Results)
 
 			responses[i] = map[string]interface{}{
 				"device_ip":       d.DeviceIP,
@@ -675,49 +935,80 @@ func (h *CollectorHandler) BatchExecuteSystem(c *gin.Context) {
 				"results":         resp.Results,
 				"error":           resp.Error,
 				"duration_ms":     resp.DurationMS,
+				"timings":         resp.Timings,
 				"timestamp":       resp.Timestamp,
 			}
+			if len(resp.Attempts) > 0 {
+				responses[i]["attempts"] = resp.Attempts
+			}
+			if resp.SkippedFresh > 0 {
+				responses[i]["skipped_fresh"] = resp.SkippedFresh
+			}
 			return nil
 		})
 	}
 
 	_ = g.Wait()
-
-	// 汇总成功/失败以确定顶层返回码（与备份接口保持一致）
-	successCount := 0
-	for _, r := range responses {
-		if s, ok := r["success"].(bool); ok && s {
-			successCount++
-		}
+	if dedup.Mode == service.DuplicateModeCopy {
+		copyDuplicateResponses(responses, dedup, func(i int) string { return req.DeviceList[i].DeviceName })
 	}
 
-	respCode := "SUCCESS"
-	respMsg := "系统预制批量任务执行完成"
-	if successCount < len(responses) {
-		respCode = "PARTIAL_SUCCESS"
-		if successCount == 0 {
-			respMsg = "系统预制批量任务全部失败"
-		} else {
-			respMsg = "系统预制批量任务部分成功"
-		}
+	// 汇总成功/失败以确定顶层返回码与 HTTP 状态（各批量接口统一）
+	successCount := countSucceeded(responses)
+	endBatch(successCount)
+
+	outcome := service.NewBatchOutcome("系统预制批量任务", len(responses), successCount)
+	respCode, respMsg := outcome.Code, outcome.Message
+
+	// 批次完成回调（异步，不影响响应）
+	if req.CallbackURL != "" {
+		payload := service.NewCollectCallbackPayload(req.TaskID, req.TaskName, "collect_system", responses)
+		payload.Message = respMsg
+		service.NotifyCallback(config.Get(), req.CallbackURL, payload)
 	}
 
-	// 使用自定义编码器关闭 HTML 转义，保持原始输出可读性（如 <, > 不被 \u003c/\u003e）
-	c.Header("Content-Type", "application/json")
-	c.Status(http.StatusOK)
-	enc := json.NewEncoder(c.Writer)
-	enc.SetEscapeHTML(false)
-	encodeStart := time.Now()
-	_ = enc.Encode(gin.H{
+	body := gin.H{
 		"code":    respCode,
 		"message": respMsg,
 		"data":    responses,
 		"total":   len(responses),
-	})
+	}
+	if dedup.HasDuplicates() {
+		body["duplicates"] = dedup.Duplicates
+	}
+	// 使用自定义编码器关闭 HTML 转义，保持原始输出可读性（如 <, > 不被 \u003c/\u003e）
+	encodeStart := time.Now()
+	writeOutputJSON(c, service.BatchHTTPStatus(respCode), outputEncoding(c, req.OutputEncoding), body)
 	encodeDur := time.Since(encodeStart)
 	logger.Info("BatchExecuteSystem response encoded", "path", c.FullPath(), "size_bytes", c.Writer.Size(), "duration_ms", encodeDur.Milliseconds(), "count", len(responses))
 }
 
+// countSucceeded 统计批量响应中成功的设备数
+func countSucceeded(responses []map[string]interface{}) int {
+	n := 0
+	for _, r := range responses {
+		if s, ok := r["success"].(bool); ok && s {
+			n++
+		}
+	}
+	return n
+}
+
+// windowClosedResponse 执行窗口关闭后未派发设备的结果
+func windowClosedResponse(taskID, ip string, port int, name, platform string) map[string]interface{} {
+	return map[string]interface{}{
+		"device_ip":       ip,
+		"port":            port,
+		"device_name":     name,
+		"device_platform": platform,
+		"task_id":         taskID,
+		"success":         false,
+		"status":          service.StatusNotAttemptedWindowClosed,
+		"error":           "执行窗口已关闭，设备未执行",
+		"timestamp":       time.Now(),
+	}
+}
+
 // validateCollectRequest 验证采集请求参数
 func (h *CollectorHandler) validateCollectRequest(request *service.CollectRequest) error {
 	if strings.TrimSpace(request.TaskID) == "" {
@@ -726,17 +1017,25 @@ func (h *CollectorHandler) validateCollectRequest(request *service.CollectReques
 	if strings.TrimSpace(request.DeviceIP) == "" {
 		return fmt.Errorf("设备IP不能为空")
 	}
-	if strings.TrimSpace(request.UserName) == "" {
-		return fmt.Errorf("用户名不能为空")
-	}
-	// 密码可为空？通常必须；若未来支持密钥则可为空。此处仍要求密码
-	if strings.TrimSpace(request.Password) == "" {
-		return fmt.Errorf("密码不能为空")
-	}
 	// collect_protocol 校验
-	if p := strings.TrimSpace(strings.ToLower(request.CollectProtocol)); p != "" && p != "ssh" {
+	proto := strings.TrimSpace(strings.ToLower(request.CollectProtocol))
+	if proto != "" && proto != "ssh" && proto != "netconf" && proto != "snmp" {
 		return fmt.Errorf("不支持的采集协议: %s", request.CollectProtocol)
 	}
+	// SNMP 使用 snmp 字段中的 community/v3 凭据，由服务层校验
+	if proto != "snmp" {
+		if strings.TrimSpace(request.UserName) == "" {
+			return fmt.Errorf("用户名不能为空")
+		}
+		// 密码可为空？通常必须；若未来支持密钥则可为空。此处仍要求密码
+		if strings.TrimSpace(request.Password) == "" {
+			return fmt.Errorf("密码不能为空")
+		}
+	}
+	// 落盘后端校验（仅 store=true 时生效）
+	if !service.IsSupportedStorageBackend(request.StorageBackend) {
+		return fmt.Errorf("不支持的存储后端: %s", request.StorageBackend)
+	}
 	// 不再基于 origin 进行校验；平台校验在具体路由中处理
 	// 端口（可选）范围校验
 	if request.Port != 0 && (request.Port < 1 || request.Port > 65535) {
@@ -753,13 +1052,15 @@ func (h *CollectorHandler) validateCollectRequest(request *service.CollectReques
 	if request.RetryFlag != nil && *request.RetryFlag < 0 {
 		return fmt.Errorf("重试次数不能为负数")
 	}
-	return nil
+	return request.RetryPolicy.Validate()
 }
 
 // ErrorResponse 错误响应
 type ErrorResponse struct {
 	Code    string `json:"code"`
 	Message string `json:"message"`
+	// Errors 请求体字段错误（JSON 指针），仅请求体解码失败时返回
+	Errors []FieldError `json:"errors,omitempty"`
 }
 
 // SuccessResponse 成功响应
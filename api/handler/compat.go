@@ -0,0 +1,64 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/service"
+)
+
+// deprecated 由兼容层解码、携带弃用提示的请求
+type deprecated interface {
+	DeprecationWarnings() []service.Warning
+}
+
+// bindJSON 解码请求体（兼容旧字段名），弃用提示记入请求告警，随响应 warnings 返回；
+// 解码失败返回带 JSON 指针的字段错误，严格模式（见 strictJSON）下未知字段同样报错
+func bindJSON(c *gin.Context, obj interface{}) error {
+	body, err := c.GetRawData()
+	if err != nil {
+		return err
+	}
+	if err := decodeRequestBody(body, obj, strictJSON(c)); err != nil {
+		return err
+	}
+	var warnings []service.Warning
+	switch v := obj.(type) {
+	case deprecated:
+		warnings = v.DeprecationWarnings()
+	case *[]service.CollectRequest:
+		for _, r := range *v {
+			warnings = append(warnings, r.DeprecationWarnings()...)
+		}
+	}
+	ctx := c.Request.Context()
+	for _, w := range warnings {
+		service.AddWarning(ctx, w)
+	}
+	return nil
+}
+
+// GetDeprecationUsage GET /api/v1/admin/deprecations 旧字段使用次数（自启动以来）
+func (h *AdminHandler) GetDeprecationUsage(c *gin.Context) {
+	c.JSON(http.StatusOK, SuccessResponse{Code: "SUCCESS", Message: "获取旧字段使用统计成功", Data: gin.H{
+		"removal_version": service.LegacyFieldRemovalVersion,
+		"usage":           service.DeprecationUsage(),
+	}})
+}
+
+// 接口层请求类型的兼容解码
+
+func (r *FastCollectRequest) UnmarshalJSON(data []byte) error {
+	type plain FastCollectRequest
+	return service.UnmarshalCompat(data, (*plain)(r), &r.Deprecations, "collect_fast", "", true)
+}
+
+func (r *CustomerBatchRequest) UnmarshalJSON(data []byte) error {
+	type plain CustomerBatchRequest
+	return service.UnmarshalCompat(data, (*plain)(r), &r.Deprecations, "collect_batch_custom", "devices", false)
+}
+
+func (r *SystemBatchRequest) UnmarshalJSON(data []byte) error {
+	type plain SystemBatchRequest
+	return service.UnmarshalCompat(data, (*plain)(r), &r.Deprecations, "collect_batch_system", "device_list", false)
+}
@@ -0,0 +1,260 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/database"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/model"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/service"
+	"github.com/sshcollectorpro/sshcollectorpro/pkg/logger"
+	"gorm.io/gorm"
+)
+
+// ComplianceHandler 合规规则管理与合规检查
+type ComplianceHandler struct {
+	svc *service.ComplianceService
+}
+
+// NewComplianceHandler 创建合规处理器
+func NewComplianceHandler(svc *service.ComplianceService) *ComplianceHandler {
+	return &ComplianceHandler{svc: svc}
+}
+
+// ComplianceRuleRequest 规则创建/更新请求
+type ComplianceRuleRequest struct {
+	Name        string `json:"name"`
+	Platform    string `json:"platform,omitempty"`
+	Command     string `json:"command"`
+	RuleType    string `json:"rule_type"`
+	Pattern     string `json:"pattern"`
+	Severity    string `json:"severity,omitempty"`
+	Description string `json:"description,omitempty"`
+	Enabled     *bool  `json:"enabled,omitempty"`
+}
+
+// ComplianceRepository 规则查询与报告持久化（实现 service.ComplianceRuleStore / ComplianceReportStore）
+type ComplianceRepository struct{}
+
+// NewComplianceRepository 创建合规数据访问
+func NewComplianceRepository() *ComplianceRepository { return &ComplianceRepository{} }
+
+// Rules 返回启用的规则；ids 非空时仅返回指定规则
+func (ComplianceRepository) Rules(ids []uint) ([]service.ComplianceRule, error) {
+	q := database.GetDB().Where("enabled = ?", true)
+	if len(ids) > 0 {
+		q = q.Where("id IN ?", ids)
+	}
+	var rows []model.ComplianceRule
+	if err := q.Order("id ASC").Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	out := make([]service.ComplianceRule, 0, len(rows))
+	for _, r := range rows {
+		out = append(out, service.ComplianceRule{
+			ID:       r.ID,
+			Name:     r.Name,
+			Platform: r.Platform,
+			Command:  r.Command,
+			RuleType: r.RuleType,
+			Pattern:  r.Pattern,
+			Severity: r.Severity,
+		})
+	}
+	return out, nil
+}
+
+// SaveReport 保存报告摘要与完整 JSON
+func (ComplianceRepository) SaveReport(report *service.ComplianceReport, raw []byte) (uint, error) {
+	row := model.ComplianceReport{
+		TaskID:       report.TaskID,
+		TaskName:     report.TaskName,
+		Code:         report.Code,
+		Total:        report.Total,
+		Compliant:    report.Compliant,
+		NonCompliant: report.NonCompliant,
+		Report:       string(raw),
+	}
+	if report.Stored != nil {
+		row.StorageURI = report.Stored.URI
+	}
+	if err := database.WithRetry(func(tx *gorm.DB) error { return tx.Create(&row).Error }, 3, 0); err != nil {
+		return 0, err
+	}
+	return row.ID, nil
+}
+
+// RunCompliance POST /api/v1/compliance/run
+func (h *ComplianceHandler) RunCompliance(c *gin.Context) {
+	var req service.ComplianceRequest
+	if err := bindJSON(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Code: "INVALID_PARAMS", Message: "请求参数无效: " + err.Error(), Errors: fieldErrors(err)})
+		return
+	}
+	for i := range req.Devices {
+		d := &req.Devices[i]
+		if err := resolveCredential(d.CredentialID, &d.UserName, &d.Password, &d.EnablePassword); err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Code: "CREDENTIAL_INVALID", Message: err.Error()})
+			return
+		}
+	}
+	report, err := h.svc.Run(c.Request.Context(), &req)
+	if err != nil {
+		c.Error(err).SetMeta("COMPLIANCE_FAILED")
+		return
+	}
+	c.JSON(service.BatchHTTPStatus(report.Code), report)
+}
+
+// ListRules GET /api/v1/compliance/rules?platform=&enabled=
+func (h *ComplianceHandler) ListRules(c *gin.Context) {
+	q := database.GetDB().Model(&model.ComplianceRule{})
+	if p := strings.ToLower(strings.TrimSpace(c.Query("platform"))); p != "" {
+		q = q.Where("platform = ? OR platform = ''", p)
+	}
+	if e := strings.TrimSpace(c.Query("enabled")); e != "" {
+		q = q.Where("enabled = ?", e == "true" || e == "1")
+	}
+	var items []model.ComplianceRule
+	if err := q.Order("id ASC").Find(&items).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Code: "QUERY_FAILED", Message: err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"code": "SUCCESS", "message": "获取规则列表成功", "data": items, "total": len(items)})
+}
+
+// CreateRule POST /api/v1/compliance/rules
+func (h *ComplianceHandler) CreateRule(c *gin.Context) {
+	var req ComplianceRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Code: "INVALID_PARAMS", Message: "请求参数无效: " + err.Error()})
+		return
+	}
+	rule := model.ComplianceRule{Enabled: true, Severity: "medium"}
+	applyRuleRequest(&rule, &req)
+	if !validateRule(c, &rule) {
+		return
+	}
+	var count int64
+	if err := database.GetDB().Model(&model.ComplianceRule{}).Where("name = ?", rule.Name).Count(&count).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Code: "QUERY_FAILED", Message: err.Error()})
+		return
+	}
+	if count > 0 {
+		c.JSON(http.StatusConflict, ErrorResponse{Code: "RULE_EXISTS", Message: "规则名称已存在"})
+		return
+	}
+	if err := database.WithRetry(func(tx *gorm.DB) error { return tx.Create(&rule).Error }, 3, 0); err != nil {
+		logger.Error("Failed to create compliance rule", "error", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Code: "CREATE_FAILED", Message: "创建规则失败: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, SuccessResponse{Code: "SUCCESS", Message: "规则创建成功", Data: rule})
+}
+
+// UpdateRule PUT /api/v1/compliance/rules/:id（字段为空表示不修改）
+func (h *ComplianceHandler) UpdateRule(c *gin.Context) {
+	var req ComplianceRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Code: "INVALID_PARAMS", Message: "请求参数无效: " + err.Error()})
+		return
+	}
+	var rule model.ComplianceRule
+	if err := database.GetDB().Where("id = ?", c.Param("id")).First(&rule).Error; err != nil {
+		c.JSON(http.StatusNotFound, ErrorResponse{Code: "RULE_NOT_FOUND", Message: "规则不存在"})
+		return
+	}
+	applyRuleRequest(&rule, &req)
+	if !validateRule(c, &rule) {
+		return
+	}
+	if err := database.WithRetry(func(tx *gorm.DB) error { return tx.Save(&rule).Error }, 3, 0); err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Code: "UPDATE_FAILED", Message: "更新规则失败: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, SuccessResponse{Code: "SUCCESS", Message: "规则更新成功", Data: rule})
+}
+
+// DeleteRule DELETE /api/v1/compliance/rules/:id
+func (h *ComplianceHandler) DeleteRule(c *gin.Context) {
+	var rule model.ComplianceRule
+	if err := database.GetDB().Where("id = ?", c.Param("id")).First(&rule).Error; err != nil {
+		c.JSON(http.StatusNotFound, ErrorResponse{Code: "RULE_NOT_FOUND", Message: "规则不存在"})
+		return
+	}
+	if err := database.WithRetry(func(tx *gorm.DB) error { return tx.Delete(&rule).Error }, 3, 0); err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Code: "DELETE_FAILED", Message: err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, SuccessResponse{Code: "SUCCESS", Message: "规则删除成功", Data: gin.H{"id": rule.ID}})
+}
+
+// ListReports GET /api/v1/compliance/reports?task_id=（仅摘要）
+func (h *ComplianceHandler) ListReports(c *gin.Context) {
+	q := database.GetDB().Model(&model.ComplianceReport{})
+	if tid := strings.TrimSpace(c.Query("task_id")); tid != "" {
+		q = q.Where("task_id = ?", tid)
+	}
+	var items []model.ComplianceReport
+	if err := q.Order("id DESC").Limit(200).Find(&items).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Code: "QUERY_FAILED", Message: err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"code": "SUCCESS", "message": "获取报告列表成功", "data": items, "total": len(items)})
+}
+
+// GetReport GET /api/v1/compliance/reports/:id（完整报告）
+func (h *ComplianceHandler) GetReport(c *gin.Context) {
+	var row model.ComplianceReport
+	if err := database.GetDB().Where("id = ?", c.Param("id")).First(&row).Error; err != nil {
+		c.JSON(http.StatusNotFound, ErrorResponse{Code: "REPORT_NOT_FOUND", Message: "报告不存在"})
+		return
+	}
+	var report service.ComplianceReport
+	if err := json.Unmarshal([]byte(row.Report), &report); err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Code: "READ_FAILED", Message: "解析报告失败: " + err.Error()})
+		return
+	}
+	report.ReportID = row.ID
+	c.JSON(http.StatusOK, SuccessResponse{Code: "SUCCESS", Message: "获取报告成功", Data: report})
+}
+
+// applyRuleRequest 合并请求字段（空值不覆盖）
+func applyRuleRequest(rule *model.ComplianceRule, req *ComplianceRuleRequest) {
+	if v := strings.TrimSpace(req.Name); v != "" {
+		rule.Name = v
+	}
+	if v := strings.ToLower(strings.TrimSpace(req.Platform)); v != "" {
+		rule.Platform = v
+	}
+	if v := strings.TrimSpace(req.Command); v != "" {
+		rule.Command = v
+	}
+	if v := strings.ToLower(strings.TrimSpace(req.RuleType)); v != "" {
+		rule.RuleType = v
+	}
+	if req.Pattern != "" {
+		rule.Pattern = req.Pattern
+	}
+	if v := strings.ToLower(strings.TrimSpace(req.Severity)); v != "" {
+		rule.Severity = v
+	}
+	if req.Description != "" {
+		rule.Description = req.Description
+	}
+	if req.Enabled != nil {
+		rule.Enabled = *req.Enabled
+	}
+}
+
+// validateRule 校验失败时写出 400 并返回 false
+func validateRule(c *gin.Context, rule *model.ComplianceRule) bool {
+	err := service.ValidateComplianceRule(&service.ComplianceRule{Name: rule.Name, Command: rule.Command, RuleType: rule.RuleType, Pattern: rule.Pattern})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Code: "INVALID_RULE", Message: err.Error()})
+		return false
+	}
+	return true
+}
@@ -0,0 +1,125 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/config"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/service"
+	"github.com/sshcollectorpro/sshcollectorpro/pkg/logger"
+)
+
+// 运行时可修改的配置键
+const (
+	configKeyLogLevel           = "log.level"
+	configKeyConcurrencyProfile = "collector.concurrency_profile"
+)
+
+var patchableConfigKeys = []string{configKeyLogLevel, configKeyConcurrencyProfile}
+
+// ConfigHandler 运行时配置查看与修改
+type ConfigHandler struct {
+	services []service.Reloadable
+}
+
+// NewConfigHandler 创建配置处理器；services 为运行时修改后需要通知的服务
+func NewConfigHandler(services ...service.Reloadable) *ConfigHandler {
+	return &ConfigHandler{services: services}
+}
+
+// GetConfig GET /api/v1/config
+// 返回当前生效的配置（已应用并发档位、环境变量替换与叠加文件），密钥、密码等敏感项脱敏
+func (h *ConfigHandler) GetConfig(c *gin.Context) {
+	settings, err := service.RedactedSettings(config.Get())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Code: "INTERNAL_ERROR", Message: "获取配置失败: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, SuccessResponse{Code: "SUCCESS", Message: "获取配置成功", Data: settings})
+}
+
+// PatchConfig PATCH /api/v1/config
+// 运行时修改安全子集（log.level、collector.concurrency_profile），立即生效；仅修改内存中的配置，配置文件重载后以文件为准；需管理令牌
+func (h *ConfigHandler) PatchConfig(c *gin.Context) {
+	if !authorizeAdmin(c, config.Get().Debug.Token) {
+		return
+	}
+	var req map[string]interface{}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Code: "INVALID_PARAMS", Message: "参数解析失败: " + err.Error()})
+		return
+	}
+	if len(req) == 0 {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Code: "INVALID_PARAMS", Message: "未指定修改项，可修改: " + strings.Join(patchableConfigKeys, ", ")})
+		return
+	}
+
+	values := make(map[string]string, len(req))
+	for k, raw := range req {
+		key := strings.ToLower(strings.TrimSpace(k))
+		if key != configKeyLogLevel && key != configKeyConcurrencyProfile {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Code: "INVALID_PARAMS", Message: fmt.Sprintf("配置项 %s 不支持运行时修改，可修改: %s", k, strings.Join(patchableConfigKeys, ", "))})
+			return
+		}
+		s, ok := raw.(string)
+		if !ok || strings.TrimSpace(s) == "" {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Code: "INVALID_PARAMS", Message: fmt.Sprintf("配置项 %s 须为非空字符串", k)})
+			return
+		}
+		values[key] = strings.TrimSpace(s)
+	}
+
+	old := config.Get()
+	if old == nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Code: "INTERNAL_ERROR", Message: "配置未加载"})
+		return
+	}
+	// 先在副本上校验，全部通过后再替换快照
+	probe := *old
+	if lv, ok := values[configKeyLogLevel]; ok {
+		if _, err := logrus.ParseLevel(lv); err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Code: "INVALID_PARAMS", Message: "日志级别无效: " + lv})
+			return
+		}
+		probe.Log.Level = strings.ToLower(lv)
+	}
+	if prof, ok := values[configKeyConcurrencyProfile]; ok {
+		if err := config.ApplyConcurrencyProfile(&probe, prof); err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Code: "INVALID_PARAMS", Message: err.Error()})
+			return
+		}
+	}
+
+	cfg := config.Default().Update("runtime", func(next *config.Config) {
+		next.Log.Level = probe.Log.Level
+		next.Collector.ConcurrencyProfile = probe.Collector.ConcurrencyProfile
+		next.Collector.Concurrent = probe.Collector.Concurrent
+		next.Collector.Threads = probe.Collector.Threads
+	})
+	if cfg.Log.Level != old.Log.Level {
+		_ = logger.SetLevel(cfg.Log.Level)
+	}
+	service.ApplyReload(old, cfg, h.services...)
+
+	changed := make([]string, 0, len(values))
+	for k := range values {
+		changed = append(changed, k)
+	}
+	sort.Strings(changed)
+	logger.Infof("Runtime config updated via API: %s", strings.Join(changed, ", "))
+	c.JSON(http.StatusOK, SuccessResponse{Code: "SUCCESS", Message: "配置已更新", Data: gin.H{
+		"changed": changed,
+		"log": gin.H{
+			"level": cfg.Log.Level,
+		},
+		"collector": gin.H{
+			"concurrency_profile": cfg.Collector.ConcurrencyProfile,
+			"concurrent":          cfg.Collector.Concurrent,
+			"threads":             cfg.Collector.Threads,
+		},
+	}})
+}
@@ -0,0 +1,61 @@
+package handler
+
+import (
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/config"
+)
+
+// maxConfigValidateBody 试运行校验的配置文件大小上限
+const maxConfigValidateBody = 4 << 20
+
+// ConfigValidateRequest JSON 方式提交的待校验配置
+type ConfigValidateRequest struct {
+	Content string `json:"content"`
+}
+
+// ValidateConfig POST /api/v1/config/validate
+// 试运行校验编辑后的配置：请求体为 YAML 原文（或 JSON {"content": "..."}），不影响当前生效配置
+func ValidateConfig(c *gin.Context) {
+	var data []byte
+	if strings.HasPrefix(c.ContentType(), "application/json") {
+		var req ConfigValidateRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Code: "INVALID_PARAMS", Message: "参数解析失败: " + err.Error()})
+			return
+		}
+		data = []byte(req.Content)
+	} else {
+		body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxConfigValidateBody))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Code: "INVALID_PARAMS", Message: "读取请求体失败: " + err.Error()})
+			return
+		}
+		data = body
+	}
+	if strings.TrimSpace(string(data)) == "" {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Code: "INVALID_PARAMS", Message: "配置内容不能为空"})
+		return
+	}
+
+	report, err := config.ValidateYAML(data)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Code: "INTERNAL_ERROR", Message: "配置校验失败: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, SuccessResponse{Code: "SUCCESS", Message: "配置校验完成", Data: gin.H{
+		"valid":    !report.HasErrors(),
+		"errors":   nonNilIssues(report.Errors()),
+		"warnings": nonNilIssues(report.Warnings()),
+	}})
+}
+
+func nonNilIssues(issues []config.ValidationIssue) []config.ValidationIssue {
+	if issues == nil {
+		return []config.ValidationIssue{}
+	}
+	return issues
+}
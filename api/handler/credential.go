@@ -0,0 +1,206 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/config"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/database"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/model"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/service"
+	"github.com/sshcollectorpro/sshcollectorpro/pkg/logger"
+	"gorm.io/gorm"
+)
+
+// CredentialHandler 设备凭据处理器
+type CredentialHandler struct{}
+
+// NewCredentialHandler 创建凭据处理器
+func NewCredentialHandler() *CredentialHandler {
+	return &CredentialHandler{}
+}
+
+// CredentialRequest 凭据创建/更新请求
+type CredentialRequest struct {
+	Name           string `json:"name"`
+	Username       string `json:"username"`
+	Password       string `json:"password"`
+	EnablePassword string `json:"enable_password,omitempty"`
+	Remarks        string `json:"remarks,omitempty"`
+}
+
+// CreateCredential POST /api/v1/credentials
+func (h *CredentialHandler) CreateCredential(c *gin.Context) {
+	var req CredentialRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Code: "INVALID_PARAMS", Message: "请求参数无效: " + err.Error()})
+		return
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	req.Username = strings.TrimSpace(req.Username)
+	if req.Name == "" || req.Username == "" || req.Password == "" {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Code: "INVALID_PARAMS", Message: "name、username、password 不能为空"})
+		return
+	}
+	cc, err := service.NewCredentialCipher(config.Get())
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, ErrorResponse{Code: "VAULT_NOT_CONFIGURED", Message: err.Error()})
+		return
+	}
+
+	db := database.GetDB()
+	var count int64
+	if err := db.Model(&model.Credential{}).Where("name = ?", req.Name).Count(&count).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Code: "QUERY_FAILED", Message: err.Error()})
+		return
+	}
+	if count > 0 {
+		c.JSON(http.StatusConflict, ErrorResponse{Code: "CREDENTIAL_EXISTS", Message: "凭据名称已存在"})
+		return
+	}
+
+	cred := model.Credential{ID: uuid.NewString(), Name: req.Name, Username: req.Username, Remarks: req.Remarks}
+	if cred.PasswordEnc, err = cc.Encrypt(req.Password); err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Code: "ENCRYPT_FAILED", Message: err.Error()})
+		return
+	}
+	if cred.EnablePasswordEnc, err = cc.Encrypt(req.EnablePassword); err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Code: "ENCRYPT_FAILED", Message: err.Error()})
+		return
+	}
+	if err := database.WithRetry(func(tx *gorm.DB) error { return tx.Create(&cred).Error }, 3, 0); err != nil {
+		logger.Error("Failed to create credential", "error", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Code: "CREATE_FAILED", Message: "创建凭据失败: " + err.Error()})
+		return
+	}
+	logger.Info("Credential created", "credential_id", cred.ID, "name", cred.Name)
+	c.JSON(http.StatusCreated, SuccessResponse{Code: "SUCCESS", Message: "凭据创建成功", Data: cred})
+}
+
+// ListCredentials GET /api/v1/credentials（不返回密码）
+func (h *CredentialHandler) ListCredentials(c *gin.Context) {
+	var items []model.Credential
+	if err := database.GetDB().Order("name ASC").Find(&items).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Code: "QUERY_FAILED", Message: err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"code": "SUCCESS", "message": "获取凭据列表成功", "data": items, "total": len(items)})
+}
+
+// GetCredential GET /api/v1/credentials/:id（不返回密码）
+func (h *CredentialHandler) GetCredential(c *gin.Context) {
+	var cred model.Credential
+	if err := database.GetDB().Where("id = ?", c.Param("id")).First(&cred).Error; err != nil {
+		c.JSON(http.StatusNotFound, ErrorResponse{Code: "CREDENTIAL_NOT_FOUND", Message: "凭据不存在"})
+		return
+	}
+	c.JSON(http.StatusOK, SuccessResponse{Code: "SUCCESS", Message: "获取凭据成功", Data: cred})
+}
+
+// UpdateCredential PUT /api/v1/credentials/:id（密码字段为空表示不修改）
+func (h *CredentialHandler) UpdateCredential(c *gin.Context) {
+	var req CredentialRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Code: "INVALID_PARAMS", Message: "请求参数无效: " + err.Error()})
+		return
+	}
+	cc, err := service.NewCredentialCipher(config.Get())
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, ErrorResponse{Code: "VAULT_NOT_CONFIGURED", Message: err.Error()})
+		return
+	}
+	db := database.GetDB()
+	var cred model.Credential
+	if err := db.Where("id = ?", c.Param("id")).First(&cred).Error; err != nil {
+		c.JSON(http.StatusNotFound, ErrorResponse{Code: "CREDENTIAL_NOT_FOUND", Message: "凭据不存在"})
+		return
+	}
+	if n := strings.TrimSpace(req.Name); n != "" && n != cred.Name {
+		// 改名时与其他凭据重名返回冲突，避免唯一索引报错被当作内部错误
+		var count int64
+		if err := db.Model(&model.Credential{}).Where("name = ? AND id <> ?", n, cred.ID).Count(&count).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Code: "QUERY_FAILED", Message: err.Error()})
+			return
+		}
+		if count > 0 {
+			c.JSON(http.StatusConflict, ErrorResponse{Code: "CREDENTIAL_EXISTS", Message: "凭据名称已存在"})
+			return
+		}
+		cred.Name = n
+	}
+	if u := strings.TrimSpace(req.Username); u != "" {
+		cred.Username = u
+	}
+	if req.Remarks != "" {
+		cred.Remarks = req.Remarks
+	}
+	if req.Password != "" {
+		if cred.PasswordEnc, err = cc.Encrypt(req.Password); err != nil {
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Code: "ENCRYPT_FAILED", Message: err.Error()})
+			return
+		}
+	}
+	if req.EnablePassword != "" {
+		if cred.EnablePasswordEnc, err = cc.Encrypt(req.EnablePassword); err != nil {
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Code: "ENCRYPT_FAILED", Message: err.Error()})
+			return
+		}
+	}
+	if err := database.WithRetry(func(tx *gorm.DB) error { return tx.Save(&cred).Error }, 3, 0); err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Code: "UPDATE_FAILED", Message: "更新凭据失败: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, SuccessResponse{Code: "SUCCESS", Message: "凭据更新成功", Data: cred})
+}
+
+// DeleteCredential DELETE /api/v1/credentials/:id
+func (h *CredentialHandler) DeleteCredential(c *gin.Context) {
+	res := database.GetDB().Where("id = ?", c.Param("id")).Delete(&model.Credential{})
+	if res.Error != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Code: "DELETE_FAILED", Message: res.Error.Error()})
+		return
+	}
+	if res.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, ErrorResponse{Code: "CREDENTIAL_NOT_FOUND", Message: "凭据不存在"})
+		return
+	}
+	c.JSON(http.StatusOK, SuccessResponse{Code: "SUCCESS", Message: "凭据删除成功"})
+}
+
+// resolveCredential 按 credential_id 填充设备登录信息
+// 请求中显式给出的用户名/密码优先；仅填充空字段
+func resolveCredential(credentialID string, user, password, enablePassword *string) error {
+	credentialID = strings.TrimSpace(credentialID)
+	if credentialID == "" {
+		return nil
+	}
+	var cred model.Credential
+	if err := database.GetDB().Where("id = ?", credentialID).First(&cred).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("credential %s not found", credentialID)
+		}
+		return fmt.Errorf("load credential %s: %w", credentialID, err)
+	}
+	cc, err := service.NewCredentialCipher(config.Get())
+	if err != nil {
+		return err
+	}
+	if strings.TrimSpace(*user) == "" {
+		*user = cred.Username
+	}
+	if *password == "" {
+		if *password, err = cc.Decrypt(cred.PasswordEnc); err != nil {
+			return err
+		}
+	}
+	if *enablePassword == "" {
+		if *enablePassword, err = cc.Decrypt(cred.EnablePasswordEnc); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,543 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/config"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/database"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/model"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/service"
+	"github.com/sshcollectorpro/sshcollectorpro/pkg/logger"
+	"gorm.io/gorm"
+)
+
+// 轮换验证并发：默认与上限
+const (
+	defaultRotationConcurrency = 10
+	maxRotationConcurrency     = 100
+)
+
+// activeRotations 本进程内正在验证的轮换（id -> *rotationRun）
+var activeRotations sync.Map
+
+// rotationRun 验证中的轮换：结果先写入内存，验证结束后统一落库
+type rotationRun struct {
+	mu      sync.Mutex
+	targets []*service.RotationTarget
+}
+
+// snapshot 复制当前验证结果
+func (r *rotationRun) snapshot() []service.RotationTarget {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	out := make([]service.RotationTarget, len(r.targets))
+	for i, t := range r.targets {
+		out[i] = *t
+	}
+	return out
+}
+
+// CredentialRotationHandler 凭据轮换处理器
+type CredentialRotationHandler struct {
+	verify service.LoginVerifier
+}
+
+// NewCredentialRotationHandler 创建凭据轮换处理器；verify 为设备登录验证方式
+func NewCredentialRotationHandler(verify service.LoginVerifier) *CredentialRotationHandler {
+	return &CredentialRotationHandler{verify: verify}
+}
+
+// CredentialRotationRequest 发起轮换请求
+// 目标设备：设备表中已启用、用户名匹配且当前密码为原密码的设备，可再按类型/来源/IP 过滤
+type CredentialRotationRequest struct {
+	CredentialID      string   `json:"credential_id,omitempty"`
+	Username          string   `json:"username,omitempty"`
+	OldPassword       string   `json:"old_password,omitempty"`
+	NewPassword       string   `json:"new_password"`
+	NewEnablePassword string   `json:"new_enable_password,omitempty"`
+	DeviceTypes       []string `json:"device_types,omitempty"`
+	Sources           []string `json:"sources,omitempty"` // manual 表示手工登记
+	IPs               []string `json:"ips,omitempty"`
+	Concurrency       int      `json:"concurrency,omitempty"`
+}
+
+// RotationVerifyRequest 重新验证请求（可选）
+type RotationVerifyRequest struct {
+	Concurrency int `json:"concurrency,omitempty"`
+}
+
+// RotationFinalizeRequest 确认轮换请求
+// include_unverified=true 时未通过验证的设备也写入新密码（默认仅写入新密码可登录的设备）
+type RotationFinalizeRequest struct {
+	IncludeUnverified bool `json:"include_unverified,omitempty"`
+}
+
+// credentialRotationView 轮换详情：记录字段 + 目标设备（不含密文）
+type credentialRotationView struct {
+	model.CredentialRotation
+	Running bool                     `json:"running"`
+	Targets []service.RotationTarget `json:"targets,omitempty"`
+}
+
+func rotationConcurrency(n int) int {
+	if n <= 0 {
+		return defaultRotationConcurrency
+	}
+	if n > maxRotationConcurrency {
+		return maxRotationConcurrency
+	}
+	return n
+}
+
+func loadRotationTargets(rot *model.CredentialRotation) []*service.RotationTarget {
+	var targets []*service.RotationTarget
+	_ = json.Unmarshal([]byte(rot.Targets), &targets)
+	return targets
+}
+
+// encodeRotation 目标设备序列化并更新统计
+func encodeRotation(rot *model.CredentialRotation, targets []*service.RotationTarget) error {
+	b, err := json.Marshal(targets)
+	if err != nil {
+		return err
+	}
+	rot.Targets = string(b)
+	rot.Total = len(targets)
+	rot.NewOK, rot.RequiresOld, rot.Failed = service.RotationCounts(targets)
+	rot.Updated = 0
+	for _, t := range targets {
+		if t.Updated {
+			rot.Updated++
+		}
+	}
+	return nil
+}
+
+// saveRotation 写回目标设备与统计
+func saveRotation(tx *gorm.DB, rot *model.CredentialRotation, targets []*service.RotationTarget) error {
+	if err := encodeRotation(rot, targets); err != nil {
+		return err
+	}
+	return tx.Save(rot).Error
+}
+
+// rotationView 组装详情；验证中时返回内存中的最新结果。result 非空时仅返回该结果的设备
+func rotationView(rot *model.CredentialRotation, result string) credentialRotationView {
+	view := credentialRotationView{CredentialRotation: *rot}
+	var targets []service.RotationTarget
+	if v, ok := activeRotations.Load(rot.ID); ok && rot.Status == model.RotationVerifying {
+		view.Running = true
+		targets = v.(*rotationRun).snapshot()
+		ptrs := make([]*service.RotationTarget, len(targets))
+		for i := range targets {
+			ptrs[i] = &targets[i]
+		}
+		view.NewOK, view.RequiresOld, view.Failed = service.RotationCounts(ptrs)
+	} else {
+		for _, t := range loadRotationTargets(rot) {
+			targets = append(targets, *t)
+		}
+	}
+	view.Targets = make([]service.RotationTarget, 0, len(targets))
+	for _, t := range targets {
+		if result != "" && t.Result != result {
+			continue
+		}
+		t.OldPasswordEnc, t.OldEnablePasswordEnc = "", ""
+		view.Targets = append(view.Targets, t)
+	}
+	return view
+}
+
+// CreateRotation POST /api/v1/credential-rotations
+// 保存新密码并异步验证目标设备，返回 202；验证结果经 GET 查询
+func (h *CredentialRotationHandler) CreateRotation(c *gin.Context) {
+	var req CredentialRotationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Code: "INVALID_PARAMS", Message: "请求参数无效: " + err.Error()})
+		return
+	}
+	if req.NewPassword == "" {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Code: "INVALID_PARAMS", Message: "new_password 不能为空"})
+		return
+	}
+	cc, err := service.NewCredentialCipher(config.Get())
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, ErrorResponse{Code: "VAULT_NOT_CONFIGURED", Message: err.Error()})
+		return
+	}
+	db := database.GetDB()
+	rot := &model.CredentialRotation{
+		ID:           uuid.NewString(),
+		CredentialID: strings.TrimSpace(req.CredentialID),
+		Username:     strings.TrimSpace(req.Username),
+		Status:       model.RotationVerifying,
+		Concurrency:  rotationConcurrency(req.Concurrency),
+	}
+	if rot.CredentialID != "" {
+		var cred model.Credential
+		if err := db.Where("id = ?", rot.CredentialID).First(&cred).Error; err != nil {
+			c.JSON(http.StatusNotFound, ErrorResponse{Code: "CREDENTIAL_NOT_FOUND", Message: "凭据不存在"})
+			return
+		}
+		if rot.Username == "" {
+			rot.Username = cred.Username
+		}
+		if req.OldPassword == "" {
+			if req.OldPassword, err = cc.Decrypt(cred.PasswordEnc); err != nil {
+				c.JSON(http.StatusInternalServerError, ErrorResponse{Code: "DECRYPT_FAILED", Message: err.Error()})
+				return
+			}
+		}
+		rot.OldPasswordEnc, rot.OldEnablePasswordEnc = cred.PasswordEnc, cred.EnablePasswordEnc
+	}
+	if rot.Username == "" {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Code: "INVALID_PARAMS", Message: "username 或 credential_id 不能为空"})
+		return
+	}
+	if req.OldPassword == req.NewPassword {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Code: "INVALID_PARAMS", Message: "新密码与原密码相同"})
+		return
+	}
+
+	query := db.Model(&model.DeviceInfo{}).Where("enabled = ? AND username = ?", true, rot.Username)
+	if req.OldPassword != "" {
+		query = query.Where("password = ?", req.OldPassword)
+	}
+	if len(req.DeviceTypes) > 0 {
+		query = query.Where("device_type IN ?", req.DeviceTypes)
+	}
+	if len(req.Sources) > 0 {
+		sources := make([]string, len(req.Sources))
+		for i, s := range req.Sources {
+			if strings.EqualFold(strings.TrimSpace(s), "manual") {
+				s = ""
+			}
+			sources[i] = s
+		}
+		query = query.Where("source IN ?", sources)
+	}
+	if len(req.IPs) > 0 {
+		query = query.Where("ip IN ?", req.IPs)
+	}
+	var devices []model.DeviceInfo
+	if err := query.Order("ip ASC, port ASC").Find(&devices).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Code: "QUERY_FAILED", Message: err.Error()})
+		return
+	}
+	if len(devices) == 0 {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Code: "NO_TARGETS", Message: "没有匹配的设备（需已启用、用户名与原密码一致）"})
+		return
+	}
+
+	targets := make([]*service.RotationTarget, 0, len(devices))
+	for _, d := range devices {
+		t := &service.RotationTarget{DeviceID: d.ID, Name: d.Name, IP: d.IP, Port: d.Port, DeviceType: d.DeviceType, Result: service.RotationPending}
+		if t.OldPasswordEnc, err = cc.Encrypt(d.Password); err == nil {
+			t.OldEnablePasswordEnc, err = cc.Encrypt(d.EnablePassword)
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Code: "ENCRYPT_FAILED", Message: err.Error()})
+			return
+		}
+		targets = append(targets, t)
+	}
+	if rot.NewPasswordEnc, err = cc.Encrypt(req.NewPassword); err == nil {
+		rot.NewEnablePasswordEnc, err = cc.Encrypt(req.NewEnablePassword)
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Code: "ENCRYPT_FAILED", Message: err.Error()})
+		return
+	}
+	if err := encodeRotation(rot, targets); err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Code: "CREATE_FAILED", Message: err.Error()})
+		return
+	}
+	if err := database.WithRetry(func(tx *gorm.DB) error { return tx.Create(rot).Error }, 3, 0); err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Code: "CREATE_FAILED", Message: "创建轮换失败: " + err.Error()})
+		return
+	}
+	logger.Info("Credential rotation started", "rotation_id", rot.ID, "username", rot.Username, "devices", len(targets))
+	view := rotationView(rot, "")
+	view.Running = true
+	h.startVerification(rot, targets, targets)
+	c.JSON(http.StatusAccepted, SuccessResponse{Code: "SUCCESS", Message: "轮换已创建，正在验证", Data: view})
+}
+
+// startVerification 后台验证 pending 中的设备，结束后落库并标记为 verified
+func (h *CredentialRotationHandler) startVerification(rot *model.CredentialRotation, targets, pending []*service.RotationTarget) {
+	run := &rotationRun{targets: targets}
+	activeRotations.Store(rot.ID, run)
+	go func() {
+		defer activeRotations.CompareAndDelete(rot.ID, run)
+		cc, err := service.NewCredentialCipher(config.Get())
+		var newPassword string
+		if err == nil {
+			newPassword, err = cc.Decrypt(rot.NewPasswordEnc)
+		}
+		if err != nil {
+			logger.Error("Credential rotation verify aborted", "rotation_id", rot.ID, "error", err)
+			return
+		}
+		oldPassword := func(t *service.RotationTarget) (string, error) { return cc.Decrypt(t.OldPasswordEnc) }
+		service.VerifyRotation(context.Background(), pending, rot.Username, newPassword, oldPassword, rot.Concurrency, h.verify,
+			func(t *service.RotationTarget, result, message string) {
+				now := time.Now()
+				run.mu.Lock()
+				t.Result, t.Error, t.CheckedAt = result, message, &now
+				run.mu.Unlock()
+			})
+		rot.Status = model.RotationVerified
+		if err := database.WithRetry(func(tx *gorm.DB) error { return saveRotation(tx, rot, targets) }, 3, 0); err != nil {
+			logger.Error("Failed to save credential rotation", "rotation_id", rot.ID, "error", err)
+			return
+		}
+		logger.Info("Credential rotation verified", "rotation_id", rot.ID, "new_ok", rot.NewOK, "requires_old", rot.RequiresOld, "failed", rot.Failed)
+	}()
+}
+
+// findRotation 读取轮换记录；不存在时写入 404
+func findRotation(c *gin.Context) (*model.CredentialRotation, bool) {
+	var rot model.CredentialRotation
+	if err := database.GetDB().Where("id = ?", c.Param("id")).First(&rot).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, ErrorResponse{Code: "ROTATION_NOT_FOUND", Message: "轮换记录不存在"})
+		} else {
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Code: "QUERY_FAILED", Message: err.Error()})
+		}
+		return nil, false
+	}
+	return &rot, true
+}
+
+// ListRotations GET /api/v1/credential-rotations（不含目标设备明细）
+func (h *CredentialRotationHandler) ListRotations(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	size, _ := strconv.Atoi(c.DefaultQuery("size", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if size < 1 || size > 200 {
+		size = 20
+	}
+	query := database.GetDB().Model(&model.CredentialRotation{})
+	if v := strings.TrimSpace(c.Query("status")); v != "" {
+		query = query.Where("status = ?", v)
+	}
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Code: "COUNT_FAILED", Message: "获取轮换总数失败: " + err.Error()})
+		return
+	}
+	var rows []model.CredentialRotation
+	if err := query.Omit("targets").Order("created_at DESC").Offset((page - 1) * size).Limit(size).Find(&rows).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Code: "LIST_FAILED", Message: "获取轮换记录失败: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"code":    "SUCCESS",
+		"message": "获取轮换记录成功",
+		"data": gin.H{
+			"rotations": rows,
+			"pagination": gin.H{
+				"page":  page,
+				"size":  size,
+				"total": total,
+				"pages": (total + int64(size) - 1) / int64(size),
+			},
+		},
+	})
+}
+
+// GetRotation GET /api/v1/credential-rotations/:id（result 过滤设备，如 requires_old）
+func (h *CredentialRotationHandler) GetRotation(c *gin.Context) {
+	rot, ok := findRotation(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, SuccessResponse{Code: "SUCCESS", Message: "获取轮换记录成功", Data: rotationView(rot, strings.TrimSpace(c.Query("result")))})
+}
+
+// VerifyRotation POST /api/v1/credential-rotations/:id/verify
+// 仅重新验证新密码尚未通过的设备；验证中断（进程重启）的轮换也可由此继续
+func (h *CredentialRotationHandler) VerifyRotation(c *gin.Context) {
+	var req RotationVerifyRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Code: "INVALID_PARAMS", Message: "请求参数无效: " + err.Error()})
+			return
+		}
+	}
+	rot, ok := findRotation(c)
+	if !ok {
+		return
+	}
+	if _, running := activeRotations.Load(rot.ID); running && rot.Status == model.RotationVerifying {
+		c.JSON(http.StatusConflict, ErrorResponse{Code: "ROTATION_RUNNING", Message: "轮换正在验证中"})
+		return
+	}
+	if rot.Status != model.RotationVerified && rot.Status != model.RotationVerifying {
+		c.JSON(http.StatusConflict, ErrorResponse{Code: "ROTATION_CLOSED", Message: "轮换已" + rot.Status + "，不能重新验证"})
+		return
+	}
+	if req.Concurrency > 0 {
+		rot.Concurrency = rotationConcurrency(req.Concurrency)
+	}
+	targets := loadRotationTargets(rot)
+	var pending []*service.RotationTarget
+	for _, t := range targets {
+		if t.Result != service.RotationNewOK {
+			t.Result, t.Error = service.RotationPending, ""
+			pending = append(pending, t)
+		}
+	}
+	rot.Status = model.RotationVerifying
+	if err := database.WithRetry(func(tx *gorm.DB) error { return saveRotation(tx, rot, targets) }, 3, 0); err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Code: "UPDATE_FAILED", Message: err.Error()})
+		return
+	}
+	h.startVerification(rot, targets, pending)
+	c.JSON(http.StatusAccepted, gin.H{"code": "SUCCESS", "message": "正在重新验证", "data": gin.H{"id": rot.ID, "pending": len(pending)}})
+}
+
+// FinalizeRotation POST /api/v1/credential-rotations/:id/finalize
+// 将新密码写入设备表（默认仅新密码可登录的设备）并更新关联凭据；原密码保留用于回滚
+func (h *CredentialRotationHandler) FinalizeRotation(c *gin.Context) {
+	var req RotationFinalizeRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Code: "INVALID_PARAMS", Message: "请求参数无效: " + err.Error()})
+			return
+		}
+	}
+	rot, ok := findRotation(c)
+	if !ok {
+		return
+	}
+	if rot.Status != model.RotationVerified {
+		c.JSON(http.StatusConflict, ErrorResponse{Code: "ROTATION_NOT_VERIFIED", Message: "仅验证完成的轮换可以确认（当前状态 " + rot.Status + "）"})
+		return
+	}
+	cc, err := service.NewCredentialCipher(config.Get())
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, ErrorResponse{Code: "VAULT_NOT_CONFIGURED", Message: err.Error()})
+		return
+	}
+	newPassword, err := cc.Decrypt(rot.NewPasswordEnc)
+	var newEnable string
+	if err == nil {
+		newEnable, err = cc.Decrypt(rot.NewEnablePasswordEnc)
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Code: "DECRYPT_FAILED", Message: err.Error()})
+		return
+	}
+
+	targets := loadRotationTargets(rot)
+	err = database.TransactionWithRetry(func(tx *gorm.DB) error {
+		for _, t := range targets {
+			if t.Result != service.RotationNewOK && !req.IncludeUnverified {
+				continue
+			}
+			updates := map[string]interface{}{"password": newPassword}
+			if newEnable != "" {
+				updates["enable_password"] = newEnable
+			}
+			if err := tx.Model(&model.DeviceInfo{}).Where("id = ?", t.DeviceID).Updates(updates).Error; err != nil {
+				return err
+			}
+			t.Updated = true
+		}
+		if rot.CredentialID != "" {
+			updates := map[string]interface{}{"password_enc": rot.NewPasswordEnc}
+			if rot.NewEnablePasswordEnc != "" {
+				updates["enable_password_enc"] = rot.NewEnablePasswordEnc
+			}
+			if err := tx.Model(&model.Credential{}).Where("id = ?", rot.CredentialID).Updates(updates).Error; err != nil {
+				return err
+			}
+		}
+		now := time.Now()
+		rot.Status, rot.FinalizedAt = model.RotationFinalized, &now
+		return saveRotation(tx, rot, targets)
+	}, 3, 0)
+	if err != nil {
+		for _, t := range targets {
+			t.Updated = false
+		}
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Code: "FINALIZE_FAILED", Message: "确认轮换失败: " + err.Error()})
+		return
+	}
+	logger.Info("Credential rotation finalized", "rotation_id", rot.ID, "updated", rot.Updated, "total", rot.Total)
+	c.JSON(http.StatusOK, SuccessResponse{Code: "SUCCESS", Message: "轮换已确认", Data: rotationView(rot, "")})
+}
+
+// RollbackRotation POST /api/v1/credential-rotations/:id/rollback
+// 已确认的轮换恢复设备表与凭据的原密码；未确认的轮换直接放弃
+func (h *CredentialRotationHandler) RollbackRotation(c *gin.Context) {
+	rot, ok := findRotation(c)
+	if !ok {
+		return
+	}
+	if _, running := activeRotations.Load(rot.ID); running && rot.Status == model.RotationVerifying {
+		c.JSON(http.StatusConflict, ErrorResponse{Code: "ROTATION_RUNNING", Message: "轮换正在验证中"})
+		return
+	}
+	if rot.Status == model.RotationRolledBack {
+		c.JSON(http.StatusConflict, ErrorResponse{Code: "ROTATION_CLOSED", Message: "轮换已回滚"})
+		return
+	}
+	cc, err := service.NewCredentialCipher(config.Get())
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, ErrorResponse{Code: "VAULT_NOT_CONFIGURED", Message: err.Error()})
+		return
+	}
+	targets := loadRotationTargets(rot)
+	var restore []*service.RotationTarget
+	for _, t := range targets {
+		if t.Updated {
+			restore = append(restore, t)
+		}
+	}
+	err = database.TransactionWithRetry(func(tx *gorm.DB) error {
+		for _, t := range restore {
+			password, err := cc.Decrypt(t.OldPasswordEnc)
+			if err != nil {
+				return err
+			}
+			enable, err := cc.Decrypt(t.OldEnablePasswordEnc)
+			if err != nil {
+				return err
+			}
+			if err := tx.Model(&model.DeviceInfo{}).Where("id = ?", t.DeviceID).
+				Updates(map[string]interface{}{"password": password, "enable_password": enable}).Error; err != nil {
+				return err
+			}
+			t.Updated = false
+		}
+		if rot.Status == model.RotationFinalized && rot.CredentialID != "" {
+			if err := tx.Model(&model.Credential{}).Where("id = ?", rot.CredentialID).
+				Updates(map[string]interface{}{"password_enc": rot.OldPasswordEnc, "enable_password_enc": rot.OldEnablePasswordEnc}).Error; err != nil {
+				return err
+			}
+		}
+		now := time.Now()
+		rot.Status, rot.RolledBackAt = model.RotationRolledBack, &now
+		return saveRotation(tx, rot, targets)
+	}, 3, 0)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Code: "ROLLBACK_FAILED", Message: "回滚失败: " + err.Error()})
+		return
+	}
+	logger.Info("Credential rotation rolled back", "rotation_id", rot.ID)
+	c.JSON(http.StatusOK, SuccessResponse{Code: "SUCCESS", Message: "轮换已回滚", Data: rotationView(rot, "")})
+}
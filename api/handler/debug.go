@@ -0,0 +1,172 @@
+package handler
+
+import (
+	"bytes"
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/config"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/database"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/model"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/service"
+	"github.com/sshcollectorpro/sshcollectorpro/pkg/logger"
+)
+
+// maxDebugWindow 诊断包可回溯的最长时间
+const maxDebugWindow = 24 * time.Hour
+
+// DebugHandler 诊断包处理器
+type DebugHandler struct {
+	collectorService *service.CollectorService
+}
+
+// NewDebugHandler 创建诊断包处理器
+func NewDebugHandler(collectorService *service.CollectorService) *DebugHandler {
+	return &DebugHandler{collectorService: collectorService}
+}
+
+// FailedTaskSummary 失败任务摘要（不含凭据与输出）
+type FailedTaskSummary struct {
+	ID        string    `json:"id"`
+	Type      string    `json:"type"`
+	TaskName  string    `json:"task_name,omitempty"`
+	DeviceIP  string    `json:"device_ip"`
+	Port      int       `json:"device_port"`
+	ErrorMsg  string    `json:"error_msg"`
+	StartTime time.Time `json:"start_time"`
+	Duration  int64     `json:"duration"`
+}
+
+// Bundle GET /api/v1/debug/bundle?since=2h 下载诊断包（zip）
+// 需配置 debug.token 并通过 X-Admin-Token 或 Authorization: Bearer 提供
+func (h *DebugHandler) Bundle(c *gin.Context) {
+	cfg := config.Get()
+	if cfg == nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Code: "CONFIG_MISSING", Message: "配置未初始化"})
+		return
+	}
+	if !authorizeAdmin(c, cfg.Debug.Token) {
+		return
+	}
+	window := cfg.Debug.Window
+	if window <= 0 {
+		window = time.Hour
+	}
+	if v := strings.TrimSpace(c.Query("since")); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Code: "INVALID_PARAMS", Message: "since 无效，示例：30m、2h"})
+			return
+		}
+		window = d
+	}
+	if window > maxDebugWindow {
+		window = maxDebugWindow
+	}
+	since := time.Now().Add(-window)
+
+	b := service.NewDebugBundle()
+	h.addLogs(b, cfg, since)
+	if redacted, err := service.RedactConfig(cfg); err != nil {
+		b.AddError("config.json", err)
+	} else {
+		b.AddJSON("config.json", redacted)
+	}
+	b.AddJSON("runtime.json", service.RuntimeStats())
+	if h.collectorService != nil {
+		b.AddJSON("collector_stats.json", h.collectorService.GetStats())
+	}
+	b.AddJSON("ssh_pools.json", service.SSHPoolsSnapshot())
+	addFailedTasks(b, cfg, since)
+	b.CaptureProfiles()
+	b.AddJSON("manifest.json", b.Manifest(cfg, since))
+
+	var buf bytes.Buffer
+	if err := b.WriteZip(&buf); err != nil {
+		c.Error(err).SetMeta("BUNDLE_FAILED")
+		return
+	}
+	name := fmt.Sprintf("debug-bundle-%s.zip", time.Now().Format("20060102-150405"))
+	logger.Info("Debug bundle generated", "client_ip", c.ClientIP(), "since", since.Format(time.RFC3339), "size", buf.Len())
+	c.Header("Content-Disposition", "attachment; filename="+name)
+	c.Data(http.StatusOK, "application/zip", buf.Bytes())
+}
+
+// authorizeAdmin 校验管理令牌：debug.token 或首次初始化生成的令牌；两者均未配置时接口关闭
+func authorizeAdmin(c *gin.Context, token string) bool {
+	if service.BootstrapAdminTokenValid(presentedAdminToken(c.Request)) {
+		return true
+	}
+	if strings.TrimSpace(token) == "" && !service.BootstrapInitialized() {
+		c.JSON(http.StatusForbidden, ErrorResponse{Code: "DEBUG_DISABLED", Message: "未配置 debug.token，诊断接口不可用"})
+		return false
+	}
+	if !adminTokenValid(c.Request, token) {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Code: "UNAUTHORIZED", Message: "管理令牌无效"})
+		return false
+	}
+	return true
+}
+
+// presentedAdminToken 请求头 X-Admin-Token 或 Authorization: Bearer 中的令牌
+func presentedAdminToken(r *http.Request) string {
+	got := r.Header.Get("X-Admin-Token")
+	if got == "" {
+		got = strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	}
+	return got
+}
+
+// adminTokenValid 请求中的令牌与 token 一致
+func adminTokenValid(r *http.Request, token string) bool {
+	return token != "" && subtle.ConstantTimeCompare([]byte(presentedAdminToken(r)), []byte(token)) == 1
+}
+
+// addLogs 日志文件中 since 之后的行
+func (h *DebugHandler) addLogs(b *service.DebugBundle, cfg *config.Config, since time.Time) {
+	path := strings.TrimSpace(cfg.Log.FilePath)
+	if path == "" {
+		b.AddError("logs.txt", fmt.Errorf("log.file_path not configured"))
+		return
+	}
+	lines, err := readAllLines(path)
+	if err != nil {
+		b.AddError("logs.txt", err)
+		return
+	}
+	recent := service.RecentLogLines(lines, since, cfg.Debug.LogLines)
+	b.Add("logs.txt", []byte(strings.Join(recent, "\n")+"\n"))
+}
+
+// addFailedTasks 最近失败任务摘要（按开始时间倒序）
+func addFailedTasks(b *service.DebugBundle, cfg *config.Config, since time.Time) {
+	db := database.GetDB()
+	if db == nil {
+		b.AddError("failed_tasks.json", fmt.Errorf("database not initialized"))
+		return
+	}
+	limit := cfg.Debug.FailedTasks
+	if limit <= 0 {
+		limit = 100
+	}
+	var rows []model.Task
+	err := db.Select("id", "type", "task_name", "device_ip", "device_port", "error_msg", "start_time", "duration").
+		Where("status = ? AND created_at >= ?", model.TaskStatusFailed, since).
+		Order("created_at DESC").Limit(limit).Find(&rows).Error
+	if err != nil {
+		b.AddError("failed_tasks.json", err)
+		return
+	}
+	out := make([]FailedTaskSummary, 0, len(rows))
+	for _, t := range rows {
+		out = append(out, FailedTaskSummary{
+			ID: t.ID, Type: t.Type, TaskName: t.TaskName, DeviceIP: t.DeviceIP, Port: t.DevicePort,
+			ErrorMsg: t.ErrorMsg, StartTime: t.StartTime, Duration: t.Duration,
+		})
+	}
+	b.AddJSON("failed_tasks.json", out)
+}
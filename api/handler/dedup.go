@@ -0,0 +1,48 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/config"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/service"
+)
+
+// dedupBatchDevices 识别批内重复设备；reject 模式存在重复或 duplicate_mode 无效时写入 400 响应并返回 false
+func dedupBatchDevices(c *gin.Context, mode string, n int, key func(i int) (string, int, string)) (*service.DeviceDedup, bool) {
+	dedup, err := service.DedupDevices(config.Get(), mode, n, key)
+	if errors.Is(err, service.ErrDuplicateDevices) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    "DUPLICATE_DEVICES",
+			"message": "批内存在重复设备: " + err.Error(),
+			"data":    gin.H{"duplicates": dedup.Duplicates},
+		})
+		return nil, false
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Code: "INVALID_PARAMS", Message: err.Error()})
+		return nil, false
+	}
+	return dedup, true
+}
+
+// copyDuplicateResponses copy 模式：重复位置复用首次出现设备的结果（task_id 指向实际执行的子任务），设备名按各自位置填写
+func copyDuplicateResponses(responses []map[string]interface{}, dedup *service.DeviceDedup, name func(i int) string) {
+	if dedup == nil {
+		return
+	}
+	for _, dup := range dedup.Duplicates {
+		src := responses[dup.DuplicateOf]
+		if src == nil || dup.Index >= len(responses) {
+			continue
+		}
+		r := make(map[string]interface{}, len(src)+1)
+		for k, v := range src {
+			r[k] = v
+		}
+		r["device_name"] = name(dup.Index)
+		r["duplicate_of"] = dup.DuplicateOf
+		responses[dup.Index] = r
+	}
+}
@@ -20,11 +20,28 @@ func NewDeployHandler(svc *service.DeployService) *DeployHandler {
 // FastDeploy 处理 api/v1/deploy/fast
 func (h *DeployHandler) FastDeploy(c *gin.Context) {
     var req service.DeployFastRequest
-    if err := c.ShouldBindJSON(&req); err != nil {
+    if err := bindJSON(c, &req); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"code": "BAD_REQUEST", "message": err.Error(), "errors": fieldErrors(err)})
+        return
+    }
+
+    if err := service.ValidateOutputEncoding(req.OutputEncoding); err != nil {
+        c.Error(err)
+        return
+    }
+    if err := service.ValidateCallbackURL(req.CallbackURL); err != nil {
         c.JSON(http.StatusBadRequest, gin.H{"code": "BAD_REQUEST", "message": err.Error()})
         return
     }
 
+    for i := range req.Devices {
+        d := &req.Devices[i]
+        if err := resolveCredential(d.CredentialID, &d.UserName, &d.Password, &d.EnablePassword); err != nil {
+            c.JSON(http.StatusBadRequest, gin.H{"code": "CREDENTIAL_INVALID", "message": err.Error()})
+            return
+        }
+    }
+
     // 默认 task_type 为 exec
     if strings.TrimSpace(req.TaskType) == "" {
         req.TaskType = "exec"
@@ -39,10 +56,22 @@ func (h *DeployHandler) FastDeploy(c *gin.Context) {
 		}
 	}
 
+    // 下发审批：需审批时保存请求并推送卡片，批准后由审批接口执行
+    required, err := service.ApprovalRequired(config.Get(), &req)
+    if err != nil {
+        c.Error(err)
+        return
+    }
+    if required {
+        submitDeployApproval(c, &req)
+        return
+    }
+
     resp, err := h.svc.ExecuteFast(c.Request.Context(), &req)
     if err != nil {
-        c.JSON(http.StatusInternalServerError, gin.H{"code": "DEPLOY_FAILED", "message": err.Error()})
+        c.Error(err).SetMeta("DEPLOY_FAILED")
         return
     }
-    c.JSON(http.StatusOK, resp)
+    service.NotifyCallback(config.Get(), req.CallbackURL, service.NewDeployCallbackPayload(&req, resp))
+    c.JSON(service.BatchHTTPStatus(resp.Code), service.SanitizeOutput(resp, outputEncoding(c, req.OutputEncoding)))
 }
\ No newline at end of file
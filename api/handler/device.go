@@ -454,4 +454,59 @@ func (h *DeviceHandler) SetEnabled(c *gin.Context) {
 	}
 
 	c.JSON(http.StatusOK, SuccessResponse{ Code: "SUCCESS", Message: "设备启用状态已更新", Data: gin.H{ "id": device.ID, "enabled": req.Enabled } })
-}
\ No newline at end of file
+}
+// DeviceInventory 基于 device_info 表的设备资产查询（下发变量 ${inventory.*}）
+type DeviceInventory struct{}
+
+// NewDeviceInventory 创建设备资产查询
+func NewDeviceInventory() *DeviceInventory { return &DeviceInventory{} }
+
+// Attributes 按 IP 与端口查询设备属性；同一 IP/端口登记多个用户名时取最早创建的一条
+func (DeviceInventory) Attributes(deviceIP string, port int) (map[string]string, bool) {
+	db := database.GetDB()
+	if db == nil {
+		return nil, false
+	}
+	var device model.DeviceInfo
+	if err := db.Where("ip = ? AND port = ?", deviceIP, port).Order("created_at ASC").First(&device).Error; err != nil {
+		return nil, false
+	}
+	return map[string]string{
+		"name":        device.Name,
+		"device_type": device.DeviceType,
+		"vendor":      device.Vendor,
+		"model":       device.Model,
+		"version":     device.Version,
+	}, true
+}
+
+// PortCandidates 按 IP 与端口查询登记的备用端口：最近连接成功的端口在前，其后为 port_candidates
+func (DeviceInventory) PortCandidates(deviceIP string, port int) []int {
+	db := database.GetDB()
+	if db == nil {
+		return nil
+	}
+	var device model.DeviceInfo
+	if err := db.Where("ip = ? AND port = ?", deviceIP, port).Order("created_at ASC").First(&device).Error; err != nil {
+		return nil
+	}
+	out := make([]int, 0, 4)
+	if device.ConnectedPort > 0 {
+		out = append(out, device.ConnectedPort)
+	}
+	for _, f := range strings.Split(device.PortCandidates, ",") {
+		if p, err := strconv.Atoi(strings.TrimSpace(f)); err == nil && p > 0 && p <= 65535 {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// RecordConnectedPort 回写该 IP/端口全部登记最近连接成功的端口
+func (DeviceInventory) RecordConnectedPort(deviceIP string, port, connected int) error {
+	db := database.GetDB()
+	if db == nil {
+		return nil
+	}
+	return db.Model(&model.DeviceInfo{}).Where("ip = ? AND port = ?", deviceIP, port).Update("connected_port", connected).Error
+}
@@ -0,0 +1,35 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/service"
+)
+
+// FactsRequest 设备事实发现请求（接口层：支持引用已登记凭据）
+type FactsRequest struct {
+	service.FactsRequest
+	CredentialID string `json:"credential_id,omitempty"`
+}
+
+// DiscoverFacts 登录设备返回规范化事实（厂商、型号、系统版本、序列号、运行时长）；
+// device_platform 为空时按版本输出识别平台
+// @Router /api/v1/collector/facts [post]
+func (h *CollectorHandler) DiscoverFacts(c *gin.Context) {
+	var req FactsRequest
+	if err := bindJSON(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Code: "INVALID_PARAMS", Message: "请求参数无效: " + err.Error(), Errors: fieldErrors(err)})
+		return
+	}
+	if err := resolveCredential(req.CredentialID, &req.UserName, &req.Password, &req.EnablePassword); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Code: "CREDENTIAL_INVALID", Message: err.Error()})
+		return
+	}
+	facts, err := h.collectorService.DiscoverFacts(c.Request.Context(), &req.FactsRequest)
+	if err != nil {
+		c.Error(err).SetMeta("EXEC_FAILED")
+		return
+	}
+	c.JSON(http.StatusOK, SuccessResponse{Code: "SUCCESS", Message: "设备事实获取成功", Data: facts})
+}
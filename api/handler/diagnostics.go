@@ -0,0 +1,60 @@
+package handler
+
+import (
+	"encoding/json"
+	"expvar"
+	"net/http"
+	"net/http/pprof"
+	"strings"
+	"sync"
+
+	"github.com/sshcollectorpro/sshcollectorpro/internal/service"
+)
+
+// publishOnce expvar 变量只能登记一次
+var publishOnce sync.Once
+
+// NewDiagnosticsHandler 诊断监听器的处理器：/debug/pprof/*、/debug/vars（expvar）与 /debug/runtime
+// 所有路径均需管理令牌；token 每次请求时调用，配置热加载后立即生效
+func NewDiagnosticsHandler(token func() string, services ...service.WorkerReporter) http.Handler {
+	publishOnce.Do(func() {
+		expvar.Publish("workers", expvar.Func(func() interface{} {
+			out := make([]service.WorkerUtilization, 0, len(services))
+			for _, s := range services {
+				out = append(out, s.WorkerUtilization())
+			}
+			return out
+		}))
+		expvar.Publish("ssh_pools", expvar.Func(func() interface{} { return service.SSHPoolsSnapshot() }))
+	})
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	mux.Handle("/debug/vars", expvar.Handler())
+	mux.HandleFunc("/debug/runtime", func(w http.ResponseWriter, r *http.Request) {
+		writeDiagnosticsJSON(w, http.StatusOK, SuccessResponse{Code: "SUCCESS", Message: "获取运行时诊断成功", Data: service.RuntimeDiagnostics(services...)})
+	})
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		expected := strings.TrimSpace(token())
+		if expected == "" {
+			writeDiagnosticsJSON(w, http.StatusForbidden, ErrorResponse{Code: "DEBUG_DISABLED", Message: "未配置 debug.token，诊断接口不可用"})
+			return
+		}
+		if !adminTokenValid(r, expected) {
+			writeDiagnosticsJSON(w, http.StatusUnauthorized, ErrorResponse{Code: "UNAUTHORIZED", Message: "管理令牌无效"})
+			return
+		}
+		mux.ServeHTTP(w, r)
+	})
+}
+
+func writeDiagnosticsJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,24 @@
+package handler
+
+import (
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/config"
+	"github.com/sshcollectorpro/sshcollectorpro/pkg/ssh"
+)
+
+// EgressTenantMiddleware 读取 ssh.egress.tenant_header 指定的请求头，将租户标识挂载到请求上下文，
+// 设备连接时按该租户的出站规则校验；后台续跑等无请求上下文的执行仅适用全局规则
+func EgressTenantMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		header := "X-Tenant-ID"
+		if cfg := config.Get(); cfg != nil && strings.TrimSpace(cfg.SSH.Egress.TenantHeader) != "" {
+			header = strings.TrimSpace(cfg.SSH.Egress.TenantHeader)
+		}
+		if tenant := strings.TrimSpace(c.GetHeader(header)); tenant != "" {
+			c.Request = c.Request.WithContext(ssh.WithEgressTenant(c.Request.Context(), tenant))
+		}
+		c.Next()
+	}
+}
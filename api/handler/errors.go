@@ -0,0 +1,69 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/service"
+)
+
+// errorMapping 服务层错误到 HTTP 状态码与业务码的映射，按顺序匹配（具体错误在前）
+var errorMapping = []struct {
+	err    error
+	status int
+	code   string
+}{
+	{service.ErrObjectNotFound, http.StatusNotFound, "OBJECT_NOT_FOUND"},
+	{service.ErrTaskNotFound, http.StatusNotFound, "TASK_NOT_FOUND"},
+	{service.ErrDuplicateDevices, http.StatusBadRequest, "DUPLICATE_DEVICES"},
+	{service.ErrValidation, http.StatusBadRequest, "INVALID_PARAMS"},
+	{service.ErrNotFound, http.StatusNotFound, "NOT_FOUND"},
+	{service.ErrReadOnly, http.StatusForbidden, "READ_ONLY"},
+	{service.ErrApprovalRequired, http.StatusForbidden, "APPROVAL_REQUIRED"},
+	{service.ErrServiceStopped, http.StatusServiceUnavailable, "SERVICE_NOT_READY"},
+	{service.ErrVaultNotConfigured, http.StatusServiceUnavailable, "VAULT_NOT_CONFIGURED"},
+	{service.ErrInventorySyncRunning, http.StatusConflict, "SYNC_RUNNING"},
+	{service.ErrBootstrapLocked, http.StatusConflict, "BOOTSTRAP_LOCKED"},
+	{service.ErrBootstrapStorage, http.StatusBadGateway, "STORAGE_UNAVAILABLE"},
+	{service.ErrFactsUnavailable, http.StatusUnprocessableEntity, "FACTS_UNAVAILABLE"},
+	{service.ErrEgressBlocked, http.StatusForbidden, "EGRESS_BLOCKED"},
+	{service.ErrCredentialThrottled, http.StatusLocked, "CREDENTIAL_THROTTLED"},
+	{service.ErrDeviceCircuitOpen, http.StatusServiceUnavailable, "DEVICE_CIRCUIT_OPEN"},
+	{service.ErrHostKeyRejected, http.StatusBadGateway, "HOST_KEY_REJECTED"},
+	{service.ErrAuthFailed, http.StatusBadGateway, "AUTHENTICATION_FAILED"},
+	{service.ErrDeviceUnreachable, http.StatusBadGateway, "DEVICE_UNREACHABLE"},
+	{service.ErrTimeout, http.StatusGatewayTimeout, "COMMAND_TIMEOUT"},
+	{service.ErrCanceled, statusClientClosedRequest, "REQUEST_CANCELED"},
+}
+
+// statusClientClosedRequest 请求在完成前被取消（客户端断开或服务停止），沿用 nginx 的 499
+const statusClientClosedRequest = 499
+
+// ErrorStatus 返回错误对应的 HTTP 状态码与业务码；未分类错误返回 500 与 fallback
+func ErrorStatus(err error, fallback string) (int, string) {
+	for _, m := range errorMapping {
+		if errors.Is(err, m.err) {
+			return m.status, m.code
+		}
+	}
+	if fallback == "" {
+		fallback = "INTERNAL_ERROR"
+	}
+	return http.StatusInternalServerError, fallback
+}
+
+// ErrorMiddleware 统一错误响应：处理器通过 c.Error(err) 上报服务层错误，
+// 可用 SetMeta("CODE") 指定未分类错误的业务码；已写出响应时不做处理
+func ErrorMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+		if c.Writer.Written() || len(c.Errors) == 0 {
+			return
+		}
+		last := c.Errors.Last()
+		fallback, _ := last.Meta.(string)
+		status, code := ErrorStatus(last.Err, fallback)
+		c.JSON(status, ErrorResponse{Code: code, Message: last.Err.Error()})
+	}
+}
@@ -0,0 +1,318 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/service"
+)
+
+// RequestExample 任务接口的规范请求示例；示例请求体按严格模式（拒绝未知字段）可通过校验
+type RequestExample struct {
+	Endpoint    string          `json:"endpoint"`
+	Method      string          `json:"method"`
+	Path        string          `json:"path"`
+	Description string          `json:"description"`
+	Body        json.RawMessage `json:"body,omitempty"`
+
+	newRequest func() interface{} // 接口解码使用的请求类型
+}
+
+var requestExamples = []RequestExample{
+	{
+		Endpoint: "collector-fast", Method: http.MethodPost, Path: "/api/v1/collector/fast",
+		Description: "单设备快速采集",
+		Body: json.RawMessage(`{
+  "device_ip": "192.168.1.1",
+  "device_port": 22,
+  "device_name": "core-sw-01",
+  "device_platform": "huawei",
+  "user_name": "admin",
+  "password": "password",
+  "cli_list": ["display version", {"cli": "display current-configuration", "timeout": 120}],
+  "task_timeout": 60,
+  "retry_flag": 1
+}`),
+		newRequest: func() interface{} { return &FastCollectRequest{} },
+	},
+	{
+		Endpoint: "collector-facts", Method: http.MethodPost, Path: "/api/v1/collector/facts",
+		Description: "采集设备事实（型号、版本、序列号等）",
+		Body: json.RawMessage(`{
+  "device_ip": "192.168.1.1",
+  "device_platform": "cisco_ios",
+  "user_name": "admin",
+  "password": "password",
+  "task_timeout": 30
+}`),
+		newRequest: func() interface{} { return &FactsRequest{} },
+	},
+	{
+		Endpoint: "collector-stream", Method: http.MethodPost, Path: "/api/v1/collector/stream",
+		Description: "单设备采集，按命令以 SSE 推送结果",
+		Body: json.RawMessage(`{
+  "device_ip": "192.168.1.1",
+  "device_platform": "cisco_ios",
+  "user_name": "admin",
+  "password": "password",
+  "cli_list": ["show version", "show ip interface brief"]
+}`),
+		newRequest: func() interface{} { return &FastCollectRequest{} },
+	},
+	{
+		Endpoint: "collector-batch", Method: http.MethodPost, Path: "/api/v1/collector/batch",
+		Description: "批量采集（请求数组，每项一台设备）",
+		Body: json.RawMessage(`[
+  {
+    "task_id": "batch-001",
+    "device_ip": "192.168.1.1",
+    "device_platform": "cisco_ios",
+    "user_name": "admin",
+    "password": "password",
+    "cli_list": ["show version"]
+  },
+  {
+    "task_id": "batch-001",
+    "device_ip": "192.168.1.2",
+    "device_platform": "huawei",
+    "user_name": "admin",
+    "password": "password",
+    "cli_list": ["display version"]
+  }
+]`),
+		newRequest: func() interface{} { return &[]service.CollectRequest{} },
+	},
+	{
+		Endpoint: "collector-batch-custom", Method: http.MethodPost, Path: "/api/v1/collector/batch/custom",
+		Description: "自定义命令批量采集",
+		Body: json.RawMessage(`{
+  "task_id": "custom-001",
+  "task_name": "接口状态巡检",
+  "task_timeout": 120,
+  "raw_output_mode": "truncate",
+  "max_output_kb": 64,
+  "metadata": {"ticket": "CHG-1024"},
+  "devices": [
+    {
+      "device_ip": "192.168.1.1",
+      "device_platform": "cisco_ios",
+      "user_name": "admin",
+      "password": "password",
+      "cli_list": ["show interfaces status"]
+    }
+  ]
+}`),
+		newRequest: func() interface{} { return &CustomerBatchRequest{} },
+	},
+	{
+		Endpoint: "collector-batch-system", Method: http.MethodPost, Path: "/api/v1/collector/batch/system",
+		Description: "系统预置命令批量采集（按平台取默认命令）",
+		Body: json.RawMessage(`{
+  "task_id": "system-001",
+  "task_name": "日常巡检",
+  "device_list": [
+    {
+      "device_ip": "192.168.1.1",
+      "device_name": "core-sw-01",
+      "device_platform": "huawei",
+      "user_name": "admin",
+      "password": "password"
+    }
+  ]
+}`),
+		newRequest: func() interface{} { return &SystemBatchRequest{} },
+	},
+	{
+		Endpoint: "backup-batch", Method: http.MethodPost, Path: "/api/v1/backup/batch",
+		Description: "批量配置备份",
+		Body: json.RawMessage(`{
+  "task_id": "backup-001",
+  "task_name": "每日配置备份",
+  "save_dir": "backup/daily",
+  "storage_backend": "local",
+  "deadline": "2030-01-01T06:00:00+08:00",
+  "devices": [
+    {
+      "device_ip": "192.168.1.1",
+      "device_name": "core-sw-01",
+      "device_platform": "huawei",
+      "user_name": "admin",
+      "password": "password",
+      "cli_list": ["display current-configuration"]
+    }
+  ]
+}`),
+		newRequest: func() interface{} { return &service.BackupBatchRequest{} },
+	},
+	{
+		Endpoint: "formatted-batch", Method: http.MethodPost, Path: "/api/v1/formatted/batch",
+		Description: "批量采集并按 TextFSM 模板解析",
+		Body: json.RawMessage(`{
+  "task_id": "format-001",
+  "save_dir": "formatted/daily",
+  "fsm_templates": [
+    {
+      "device_platform": "cisco_ios",
+      "templates_values": [
+        {"cli_name": "show version", "fsm_value": "Value VERSION (\\S+)\n\nStart\n  ^.*Version ${VERSION}, -> Record\n"}
+      ]
+    }
+  ],
+  "devices": [
+    {
+      "device_ip": "192.168.1.1",
+      "device_name": "core-sw-01",
+      "device_platform": "cisco_ios",
+      "user_name": "admin",
+      "password": "password",
+      "cli_list": ["show version"]
+    }
+  ]
+}`),
+		newRequest: func() interface{} { return &service.FormatBatchRequest{} },
+	},
+	{
+		Endpoint: "formatted-fast", Method: http.MethodPost, Path: "/api/v1/formatted/fast",
+		Description: "单设备采集并解析，结果直接返回",
+		Body: json.RawMessage(`{
+  "task_id": "format-fast-001",
+  "device": [
+    {
+      "device_ip": "192.168.1.1",
+      "device_name": "core-sw-01",
+      "device_platform": "cisco_ios",
+      "user_name": "admin",
+      "password": "password",
+      "cli_list": ["show version"]
+    }
+  ]
+}`),
+		newRequest: func() interface{} { return &service.FormatFastRequest{} },
+	},
+	{
+		Endpoint: "deploy-fast", Method: http.MethodPost, Path: "/api/v1/deploy/fast",
+		Description: "配置下发（dry_run 仅渲染与校验，不执行）",
+		Body: json.RawMessage(`{
+  "task_id": "deploy-001",
+  "task_name": "NTP 配置",
+  "task_type": "dry_run",
+  "task_timeout": 120,
+  "status_check_enable": 1,
+  "devices": [
+    {
+      "device_ip": "192.168.1.1",
+      "device_name": "core-sw-01",
+      "device_platform": "cisco_ios",
+      "device_port": 22,
+      "collect_protocol": "ssh",
+      "user_name": "admin",
+      "password": "password",
+      "enable_password": "enable",
+      "cli_list": ["ntp server 10.0.0.1"],
+      "status_check_list": ["show running-config | include ntp"]
+    }
+  ]
+}`),
+		newRequest: func() interface{} { return &service.DeployFastRequest{} },
+	},
+	{
+		Endpoint: "compliance-run", Method: http.MethodPost, Path: "/api/v1/compliance/run",
+		Description: "按合规规则检查设备配置",
+		Body: json.RawMessage(`{
+  "task_id": "compliance-001",
+  "rule_ids": [1, 2],
+  "devices": [
+    {
+      "device_ip": "192.168.1.1",
+      "device_platform": "huawei",
+      "user_name": "admin",
+      "password": "password"
+    }
+  ]
+}`),
+		newRequest: func() interface{} { return &service.ComplianceRequest{} },
+	},
+	{
+		Endpoint: "runbook-run", Method: http.MethodPost, Path: "/api/v1/runbooks/{name}/runs",
+		Description: "按设备执行运行手册",
+		Body: json.RawMessage(`{
+  "devices": [
+    {
+      "device_ip": "192.168.1.1",
+      "device_platform": "cisco_ios",
+      "user_name": "admin",
+      "password": "password"
+    }
+  ]
+}`),
+		newRequest: func() interface{} { return &StartRunbookRequest{} },
+	},
+	{
+		Endpoint: "anonymize", Method: http.MethodPost, Path: "/api/v1/anonymize",
+		Description: "替换文本中的 IP、主机名等敏感信息",
+		Body: json.RawMessage(`{
+  "map_id": "default",
+  "text": "hostname core-sw-01\ninterface Vlan10\n ip address 10.1.1.1 255.255.255.0",
+  "hostnames": ["core-sw-01"]
+}`),
+		newRequest: func() interface{} { return &service.AnonymizeRequest{} },
+	},
+	{
+		Endpoint: "anonymize-reverse", Method: http.MethodPost, Path: "/api/v1/anonymize/reverse",
+		Description: "按映射表还原假名",
+		Body: json.RawMessage(`{
+  "map_id": "default",
+  "text": "hostname host-1"
+}`),
+		newRequest: func() interface{} { return &service.DeanonymizeRequest{} },
+	},
+}
+
+func findRequestExample(endpoint string) (RequestExample, bool) {
+	for _, ex := range requestExamples {
+		if ex.Endpoint == endpoint {
+			return ex, true
+		}
+	}
+	return RequestExample{}, false
+}
+
+// ListRequestExamples GET /api/v1/examples 可用的请求示例（不含请求体）
+func ListRequestExamples(c *gin.Context) {
+	out := make([]RequestExample, 0, len(requestExamples))
+	for _, ex := range requestExamples {
+		ex.Body = nil
+		out = append(out, ex)
+	}
+	c.JSON(http.StatusOK, SuccessResponse{Code: "SUCCESS", Message: "获取请求示例成功", Data: out})
+}
+
+// GetRequestExample GET /api/v1/examples/:endpoint 指定接口的规范请求示例
+func GetRequestExample(c *gin.Context) {
+	ex, ok := findRequestExample(c.Param("endpoint"))
+	if !ok {
+		c.JSON(http.StatusNotFound, ErrorResponse{Code: "EXAMPLE_NOT_FOUND", Message: "未找到请求示例: " + c.Param("endpoint")})
+		return
+	}
+	c.JSON(http.StatusOK, SuccessResponse{Code: "SUCCESS", Message: "获取请求示例成功", Data: ex})
+}
+
+// ValidateRequestExample POST /api/v1/examples/:endpoint/validate 按严格模式校验请求体（不执行任务）
+func ValidateRequestExample(c *gin.Context) {
+	ex, ok := findRequestExample(c.Param("endpoint"))
+	if !ok {
+		c.JSON(http.StatusNotFound, ErrorResponse{Code: "EXAMPLE_NOT_FOUND", Message: "未找到请求示例: " + c.Param("endpoint")})
+		return
+	}
+	body, err := c.GetRawData()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Code: "INVALID_PARAMS", Message: "读取请求体失败: " + err.Error()})
+		return
+	}
+	if err := decodeRequestBody(body, ex.newRequest(), true); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Code: "INVALID_PARAMS", Message: "请求参数无效: " + err.Error(), Errors: fieldErrors(err)})
+		return
+	}
+	c.JSON(http.StatusOK, SuccessResponse{Code: "SUCCESS", Message: "请求体校验通过"})
+}
@@ -2,8 +2,11 @@ package handler
 
 import (
 	"net/http"
+	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/config"
 	"github.com/sshcollectorpro/sshcollectorpro/internal/service"
 	"github.com/sshcollectorpro/sshcollectorpro/pkg/logger"
 )
@@ -26,14 +29,16 @@ func NewFormattedHandler(formatService *service.FormatService) *FormattedHandler
 // @Produce json
 // @Param request body service.FormatBatchRequest true "批量格式化请求"
 // @Success 200 {object} service.FormatBatchResponse "批量格式化结果"
+// @Success 207 {object} service.FormatBatchResponse "部分设备失败（PARTIAL_SUCCESS）"
+// @Failure 502 {object} service.FormatBatchResponse "全部设备失败（FAILED）"
 // @Failure 400 {object} ErrorResponse "请求参数错误"
 // @Failure 500 {object} ErrorResponse "服务器内部错误"
 // @Router /api/v1/formatted/batch [post]
 func (h *FormattedHandler) BatchFormatted(c *gin.Context) {
 	var req service.FormatBatchRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
+	if err := bindJSON(c, &req); err != nil {
 		logger.Error("Invalid formatted batch request", "error", err)
-		c.JSON(http.StatusBadRequest, ErrorResponse{Code: "INVALID_PARAMS", Message: "请求参数无效: " + err.Error()})
+		c.JSON(http.StatusBadRequest, ErrorResponse{Code: "INVALID_PARAMS", Message: "请求参数无效: " + err.Error(), Errors: fieldErrors(err)})
 		return
 	}
 
@@ -41,15 +46,39 @@ func (h *FormattedHandler) BatchFormatted(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, ErrorResponse{Code: "SERVICE_NOT_READY", Message: "格式化服务未初始化"})
 		return
 	}
+	if err := service.ValidateCallbackURL(req.CallbackURL); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Code: "INVALID_PARAMS", Message: err.Error()})
+		return
+	}
+	if err := service.ValidateOutputEncoding(req.OutputEncoding); err != nil {
+		c.Error(err)
+		return
+	}
+	if err := service.ValidateExportFormat(req.ExportFormat); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Code: "INVALID_PARAMS", Message: err.Error()})
+		return
+	}
+	if err := service.ValidateMetricSelectors(config.Get(), req.Metrics); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Code: "INVALID_PARAMS", Message: err.Error()})
+		return
+	}
+	for i := range req.Devices {
+		d := &req.Devices[i]
+		if err := resolveCredential(d.CredentialID, &d.UserName, &d.Password, &d.EnablePassword); err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Code: "CREDENTIAL_INVALID", Message: err.Error()})
+			return
+		}
+	}
 
 	resp, err := h.formatService.ExecuteBatch(c.Request.Context(), &req)
 	if err != nil {
 		logger.Error("Formatted batch execution failed", "error", err)
-		c.JSON(http.StatusInternalServerError, ErrorResponse{Code: "EXEC_FAILED", Message: "批量格式化执行失败: " + err.Error()})
+		c.Error(err).SetMeta("EXEC_FAILED")
 		return
 	}
+	service.NotifyCallback(config.Get(), req.CallbackURL, service.NewFormatCallbackPayload(&req, resp))
 
-	c.JSON(http.StatusOK, resp)
+	c.JSON(service.BatchHTTPStatus(resp.Code), service.SanitizeOutput(resp, outputEncoding(c, req.OutputEncoding)))
 }
 
 // FastFormatted 单设备快速格式化接口
@@ -65,9 +94,9 @@ func (h *FormattedHandler) BatchFormatted(c *gin.Context) {
 // @Router /api/v1/formatted/fast [post]
 func (h *FormattedHandler) FastFormatted(c *gin.Context) {
 	var req service.FormatFastRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
+	if err := bindJSON(c, &req); err != nil {
 		logger.Error("Invalid formatted fast request", "error", err)
-		c.JSON(http.StatusBadRequest, ErrorResponse{Code: "INVALID_PARAMS", Message: "请求参数无效: " + err.Error()})
+		c.JSON(http.StatusBadRequest, ErrorResponse{Code: "INVALID_PARAMS", Message: "请求参数无效: " + err.Error(), Errors: fieldErrors(err)})
 		return
 	}
 
@@ -75,13 +104,78 @@ func (h *FormattedHandler) FastFormatted(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, ErrorResponse{Code: "SERVICE_NOT_READY", Message: "格式化服务未初始化"})
 		return
 	}
+	if err := service.ValidateOutputEncoding(req.OutputEncoding); err != nil {
+		c.Error(err)
+		return
+	}
+
+	for i := range req.Device {
+		d := &req.Device[i]
+		if err := resolveCredential(d.CredentialID, &d.UserName, &d.Password, &d.EnablePassword); err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Code: "CREDENTIAL_INVALID", Message: err.Error()})
+			return
+		}
+	}
 
 	resp, err := h.formatService.ExecuteFast(c.Request.Context(), &req)
 	if err != nil {
 		logger.Error("Formatted fast execution failed", "error", err)
-		c.JSON(http.StatusInternalServerError, ErrorResponse{Code: "EXEC_FAILED", Message: "快速格式化执行失败: " + err.Error()})
+		c.Error(err).SetMeta("EXEC_FAILED")
 		return
 	}
 
-	c.JSON(http.StatusOK, resp)
+	c.JSON(http.StatusOK, service.SanitizeOutput(resp, outputEncoding(c, req.OutputEncoding)))
+}
+
+// ExportTask 导出任务格式化结果为表格
+// @Summary 导出格式化结果（CSV/XLSX）
+// @Description 读取任务已存储的聚合 JSON，按平台/命令展开为表格：xlsx 每个平台/命令一个工作表；csv 多张表时打包为 zip。store=true 时写回 MinIO 并返回对象信息
+// @Tags formatted
+// @Produce octet-stream
+// @Param task_id path string true "任务ID"
+// @Param format query string false "csv（默认）| xlsx"
+// @Param save_dir query string false "批量格式化时的 save_dir"
+// @Param batch query int false "仅导出指定批次"
+// @Param platform query string false "仅导出指定平台"
+// @Param command query string false "仅导出指定命令"
+// @Param store query bool false "写回 MinIO 而非直接下载"
+// @Success 200 {file} file "导出文件"
+// @Failure 400 {object} ErrorResponse "请求参数错误"
+// @Failure 404 {object} ErrorResponse "任务无格式化结果"
+// @Failure 503 {object} ErrorResponse "MinIO 未配置"
+// @Router /api/v1/format/tasks/{task_id}/export [get]
+func (h *FormattedHandler) ExportTask(c *gin.Context) {
+	if h.formatService == nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Code: "SERVICE_NOT_READY", Message: "格式化服务未初始化"})
+		return
+	}
+	req := service.FormatExportRequest{
+		TaskID:   c.Param("task_id"),
+		SaveDir:  c.Query("save_dir"),
+		Format:   c.DefaultQuery("format", service.ExportFormatCSV),
+		Platform: c.Query("platform"),
+		Command:  c.Query("command"),
+	}
+	if v := strings.TrimSpace(c.Query("batch")); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Code: "INVALID_PARAMS", Message: "batch 无效: " + v})
+			return
+		}
+		req.Batch = n
+	}
+	req.Store, _ = strconv.ParseBool(c.Query("store"))
+
+	out, err := h.formatService.ExportTask(c.Request.Context(), &req)
+	if err != nil {
+		logger.Error("Formatted export failed", "task_id", req.TaskID, "error", err)
+		c.Error(err).SetMeta("EXPORT_FAILED")
+		return
+	}
+	if req.Store {
+		c.JSON(http.StatusOK, SuccessResponse{Code: "SUCCESS", Message: "导出成功", Data: out})
+		return
+	}
+	c.Header("Content-Disposition", `attachment; filename="`+out.Filename+`"`)
+	c.Data(http.StatusOK, out.ContentType, out.Data)
 }
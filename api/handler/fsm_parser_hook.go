@@ -0,0 +1,158 @@
+package handler
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/database"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/model"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/service"
+	"gorm.io/gorm"
+)
+
+// ParserHookRequest 外部解析登记创建/更新请求（更新时空值不修改）
+type ParserHookRequest struct {
+	Platform   string `json:"platform"`
+	Command    string `json:"command"`
+	URL        string `json:"url"`
+	TimeoutMS  *int   `json:"timeout_ms,omitempty"`
+	Retries    *int   `json:"retries,omitempty"`
+	NoFallback *bool  `json:"no_fallback,omitempty"`
+	Enabled    *bool  `json:"enabled,omitempty"`
+}
+
+// LookupParser 按平台与完整命令查询已启用的外部解析登记（实现 service.ParserHookStore）
+func (TemplateRepository) LookupParser(platform, cli string) (service.ExternalParser, bool) {
+	db := database.GetDB()
+	if db == nil {
+		return service.ExternalParser{}, false
+	}
+	var hook model.FSMParserHook
+	if err := db.Where("platform = ? AND command = ? AND enabled = ?", platform, cli, true).First(&hook).Error; err != nil {
+		return service.ExternalParser{}, false
+	}
+	return service.ExternalParser{
+		URL:      hook.URL,
+		Timeout:  time.Duration(hook.TimeoutMS) * time.Millisecond,
+		Retries:  hook.Retries,
+		Fallback: !hook.NoFallback,
+	}, true
+}
+
+// validParserURL 仅允许 http/https 地址
+func validParserURL(raw string) bool {
+	u, err := url.Parse(raw)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// applyParserHookRequest 合并请求字段（空值不覆盖）
+func applyParserHookRequest(hook *model.FSMParserHook, req *ParserHookRequest) {
+	if v := strings.ToLower(strings.TrimSpace(req.Platform)); v != "" {
+		hook.Platform = v
+	}
+	if v := strings.ToLower(strings.TrimSpace(req.Command)); v != "" {
+		hook.Command = v
+	}
+	if v := strings.TrimSpace(req.URL); v != "" {
+		hook.URL = v
+	}
+	if req.TimeoutMS != nil {
+		hook.TimeoutMS = *req.TimeoutMS
+	}
+	if req.Retries != nil {
+		hook.Retries = *req.Retries
+	}
+	if req.NoFallback != nil {
+		hook.NoFallback = *req.NoFallback
+	}
+	if req.Enabled != nil {
+		hook.Enabled = *req.Enabled
+	}
+}
+
+// ListParserHooks GET /api/v1/format/parsers?platform=
+func (h *TemplateHandler) ListParserHooks(c *gin.Context) {
+	q := database.GetDB().Model(&model.FSMParserHook{})
+	if p := strings.ToLower(strings.TrimSpace(c.Query("platform"))); p != "" {
+		q = q.Where("platform = ?", p)
+	}
+	var items []model.FSMParserHook
+	if err := q.Order("platform ASC, command ASC").Find(&items).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Code: "QUERY_FAILED", Message: err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"code": "SUCCESS", "message": "获取外部解析列表成功", "data": items, "total": len(items)})
+}
+
+// CreateParserHook POST /api/v1/format/parsers
+func (h *TemplateHandler) CreateParserHook(c *gin.Context) {
+	var req ParserHookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Code: "INVALID_PARAMS", Message: "请求参数无效: " + err.Error()})
+		return
+	}
+	hook := model.FSMParserHook{Enabled: true}
+	applyParserHookRequest(&hook, &req)
+	if hook.Platform == "" || hook.Command == "" || !validParserURL(hook.URL) {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Code: "INVALID_PARAMS", Message: "platform、command 不能为空，url 须为 http/https 地址"})
+		return
+	}
+	db := database.GetDB()
+	var count int64
+	if err := db.Model(&model.FSMParserHook{}).Where("platform = ? AND command = ?", hook.Platform, hook.Command).Count(&count).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Code: "QUERY_FAILED", Message: err.Error()})
+		return
+	}
+	if count > 0 {
+		c.JSON(http.StatusConflict, ErrorResponse{Code: "PARSER_EXISTS", Message: "同平台同命令的外部解析已存在"})
+		return
+	}
+	if err := database.WithRetry(func(tx *gorm.DB) error { return tx.Create(&hook).Error }, 3, 0); err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Code: "CREATE_FAILED", Message: "创建外部解析失败: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, SuccessResponse{Code: "SUCCESS", Message: "外部解析创建成功", Data: hook})
+}
+
+// UpdateParserHook PUT /api/v1/format/parsers/:id
+func (h *TemplateHandler) UpdateParserHook(c *gin.Context) {
+	var req ParserHookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Code: "INVALID_PARAMS", Message: "请求参数无效: " + err.Error()})
+		return
+	}
+	db := database.GetDB()
+	var hook model.FSMParserHook
+	if err := db.Where("id = ?", c.Param("id")).First(&hook).Error; err != nil {
+		c.JSON(http.StatusNotFound, ErrorResponse{Code: "PARSER_NOT_FOUND", Message: "外部解析不存在"})
+		return
+	}
+	applyParserHookRequest(&hook, &req)
+	if !validParserURL(hook.URL) {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Code: "INVALID_PARAMS", Message: "url 须为 http/https 地址"})
+		return
+	}
+	if err := database.WithRetry(func(tx *gorm.DB) error { return tx.Save(&hook).Error }, 3, 0); err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Code: "UPDATE_FAILED", Message: "更新外部解析失败: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, SuccessResponse{Code: "SUCCESS", Message: "外部解析更新成功", Data: hook})
+}
+
+// DeleteParserHook DELETE /api/v1/format/parsers/:id
+func (h *TemplateHandler) DeleteParserHook(c *gin.Context) {
+	db := database.GetDB()
+	var hook model.FSMParserHook
+	if err := db.Where("id = ?", c.Param("id")).First(&hook).Error; err != nil {
+		c.JSON(http.StatusNotFound, ErrorResponse{Code: "PARSER_NOT_FOUND", Message: "外部解析不存在"})
+		return
+	}
+	if err := database.WithRetry(func(tx *gorm.DB) error { return tx.Delete(&hook).Error }, 3, 0); err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Code: "DELETE_FAILED", Message: err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, SuccessResponse{Code: "SUCCESS", Message: "外部解析删除成功", Data: gin.H{"id": hook.ID}})
+}
@@ -0,0 +1,539 @@
+package handler
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/config"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/database"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/model"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/service"
+	"github.com/sshcollectorpro/sshcollectorpro/pkg/logger"
+	"gorm.io/gorm"
+)
+
+// maxTemplateArchiveSize 导入压缩包大小上限
+const maxTemplateArchiveSize = 64 << 20
+
+// templateExt 模板文件扩展名，落盘文件一律使用该扩展名
+const templateExt = ".textfsm"
+
+// templateNamePattern 平台名与模板文件名（去扩展名）仅允许小写字母数字与 _ -，避免越出模板目录
+var templateNamePattern = regexp.MustCompile(`^[a-z0-9_-]+$`)
+
+// templateNameUnsafe 自动生成文件名时替换命令中不允许的字符
+var templateNameUnsafe = regexp.MustCompile(`[^a-z0-9_-]+`)
+
+// TemplateHandler TextFSM 模板库处理器
+type TemplateHandler struct{}
+
+// NewTemplateHandler 创建模板库处理器
+func NewTemplateHandler() *TemplateHandler {
+	return &TemplateHandler{}
+}
+
+// TemplateRequest 模板创建/更新请求
+type TemplateRequest struct {
+	Name     string `json:"name,omitempty"` // 为空时按 {platform}_{command}.textfsm 生成
+	Platform string `json:"platform"`
+	Command  string `json:"command"` // 可使用 ntc-templates 缩写写法，如 sh[[ow]] ver[[sion]]
+	Content  string `json:"content"`
+}
+
+// TemplateImportRequest ntc-templates 导入选项（multipart 表单字段）
+type TemplateImportRequest struct {
+	Platforms []string `json:"platforms,omitempty"` // 仅导入指定平台，为空表示全部
+	Overwrite bool     `json:"overwrite,omitempty"` // 覆盖同名模板
+}
+
+// TemplateImportResult 导入结果
+type TemplateImportResult struct {
+	Imported int      `json:"imported"`
+	Updated  int      `json:"updated"`
+	Skipped  int      `json:"skipped"`
+	Errors   []string `json:"errors,omitempty"`
+}
+
+// TemplateView 模板详情（含内容）
+type TemplateView struct {
+	model.FSMTemplate
+	Content string `json:"content"`
+}
+
+// TemplateRepository 模板库查询（实现 service.TemplateStore）
+type TemplateRepository struct{}
+
+// NewTemplateRepository 创建模板库查询
+func NewTemplateRepository() *TemplateRepository { return &TemplateRepository{} }
+
+// Lookup 先按完整命令精确匹配，未命中再按命令写法（含缩写）匹配
+func (TemplateRepository) Lookup(platform, cli string) []string {
+	db := database.GetDB()
+	if db == nil {
+		return nil
+	}
+	var rows []model.FSMTemplate
+	if err := db.Where("platform = ? AND command = ?", platform, cli).Order("id ASC").Find(&rows).Error; err != nil {
+		return nil
+	}
+	if len(rows) == 0 {
+		var all []model.FSMTemplate
+		if err := db.Where("platform = ?", platform).Order("id ASC").Find(&all).Error; err != nil {
+			return nil
+		}
+		for _, r := range all {
+			re, err := service.NTCCommandRegexp(r.CommandPattern)
+			if err == nil && re.MatchString(cli) {
+				rows = append(rows, r)
+			}
+		}
+	}
+	out := make([]string, 0, len(rows))
+	for _, r := range rows {
+		b, err := os.ReadFile(r.FilePath)
+		if err != nil {
+			logger.Warn("Read FSM template file failed", "name", r.Name, "path", r.FilePath, "error", err)
+			continue
+		}
+		out = append(out, string(b))
+	}
+	return out
+}
+
+// ListTemplates GET /api/v1/format/templates?platform=&command=&source=
+func (h *TemplateHandler) ListTemplates(c *gin.Context) {
+	q := database.GetDB().Model(&model.FSMTemplate{})
+	if p := strings.ToLower(strings.TrimSpace(c.Query("platform"))); p != "" {
+		q = q.Where("platform = ?", p)
+	}
+	if cmd := strings.ToLower(strings.TrimSpace(c.Query("command"))); cmd != "" {
+		q = q.Where("command LIKE ?", "%"+cmd+"%")
+	}
+	if src := strings.TrimSpace(c.Query("source")); src != "" {
+		q = q.Where("source = ?", src)
+	}
+	var items []model.FSMTemplate
+	if err := q.Order("platform ASC, command ASC").Find(&items).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Code: "QUERY_FAILED", Message: err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"code": "SUCCESS", "message": "获取模板列表成功", "data": items, "total": len(items)})
+}
+
+// GetTemplate GET /api/v1/format/templates/:id（含模板内容）
+func (h *TemplateHandler) GetTemplate(c *gin.Context) {
+	var tpl model.FSMTemplate
+	if err := database.GetDB().Where("id = ?", c.Param("id")).First(&tpl).Error; err != nil {
+		c.JSON(http.StatusNotFound, ErrorResponse{Code: "TEMPLATE_NOT_FOUND", Message: "模板不存在"})
+		return
+	}
+	b, err := os.ReadFile(tpl.FilePath)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Code: "READ_FAILED", Message: "读取模板文件失败: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, SuccessResponse{Code: "SUCCESS", Message: "获取模板成功", Data: TemplateView{FSMTemplate: tpl, Content: string(b)}})
+}
+
+// CreateTemplate POST /api/v1/format/templates
+func (h *TemplateHandler) CreateTemplate(c *gin.Context) {
+	var req TemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Code: "INVALID_PARAMS", Message: "请求参数无效: " + err.Error()})
+		return
+	}
+	req.Platform = strings.ToLower(strings.TrimSpace(req.Platform))
+	req.Command = strings.TrimSpace(req.Command)
+	if req.Platform == "" || req.Command == "" {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Code: "INVALID_PARAMS", Message: "platform、command 不能为空"})
+		return
+	}
+	if !templateNamePattern.MatchString(req.Platform) {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Code: "INVALID_PARAMS", Message: "platform 仅允许小写字母、数字、_ 与 -"})
+		return
+	}
+	if err := service.ValidateTextFSMTemplate(req.Content); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Code: "INVALID_TEMPLATE", Message: err.Error()})
+		return
+	}
+	if _, err := service.NTCCommandRegexp(req.Command); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Code: "INVALID_PARAMS", Message: "command 写法无效: " + err.Error()})
+		return
+	}
+	name, err := templateFileName(req.Name, req.Platform, req.Command)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Code: "INVALID_PARAMS", Message: err.Error()})
+		return
+	}
+
+	db := database.GetDB()
+	var count int64
+	if err := db.Model(&model.FSMTemplate{}).Where("platform = ? AND name = ?", req.Platform, name).Count(&count).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Code: "QUERY_FAILED", Message: err.Error()})
+		return
+	}
+	if count > 0 {
+		c.JSON(http.StatusConflict, ErrorResponse{Code: "TEMPLATE_EXISTS", Message: "同平台下模板名称已存在"})
+		return
+	}
+
+	tpl := model.FSMTemplate{
+		Platform:       req.Platform,
+		Name:           name,
+		Command:        service.ExpandNTCCommand(req.Command),
+		CommandPattern: req.Command,
+		Source:         "manual",
+	}
+	if tpl.FilePath, tpl.Checksum, err = writeTemplateFile(req.Platform, name, []byte(req.Content)); err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Code: "WRITE_FAILED", Message: "写入模板文件失败: " + err.Error()})
+		return
+	}
+	if err := database.WithRetry(func(tx *gorm.DB) error { return tx.Create(&tpl).Error }, 3, 0); err != nil {
+		logger.Error("Failed to create FSM template", "error", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Code: "CREATE_FAILED", Message: "创建模板失败: " + err.Error()})
+		return
+	}
+	logger.Info("FSM template created", "platform", tpl.Platform, "name", tpl.Name)
+	c.JSON(http.StatusCreated, SuccessResponse{Code: "SUCCESS", Message: "模板创建成功", Data: tpl})
+}
+
+// UpdateTemplate PUT /api/v1/format/templates/:id（command/content 为空表示不修改；不支持改名与改平台）
+func (h *TemplateHandler) UpdateTemplate(c *gin.Context) {
+	var req TemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Code: "INVALID_PARAMS", Message: "请求参数无效: " + err.Error()})
+		return
+	}
+	db := database.GetDB()
+	var tpl model.FSMTemplate
+	if err := db.Where("id = ?", c.Param("id")).First(&tpl).Error; err != nil {
+		c.JSON(http.StatusNotFound, ErrorResponse{Code: "TEMPLATE_NOT_FOUND", Message: "模板不存在"})
+		return
+	}
+	if cmd := strings.TrimSpace(req.Command); cmd != "" {
+		if _, err := service.NTCCommandRegexp(cmd); err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Code: "INVALID_PARAMS", Message: "command 写法无效: " + err.Error()})
+			return
+		}
+		tpl.Command = service.ExpandNTCCommand(cmd)
+		tpl.CommandPattern = cmd
+	}
+	if req.Content != "" {
+		if err := service.ValidateTextFSMTemplate(req.Content); err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Code: "INVALID_TEMPLATE", Message: err.Error()})
+			return
+		}
+		var err error
+		if tpl.FilePath, tpl.Checksum, err = writeTemplateFile(tpl.Platform, tpl.Name, []byte(req.Content)); err != nil {
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Code: "WRITE_FAILED", Message: "写入模板文件失败: " + err.Error()})
+			return
+		}
+	}
+	if err := database.WithRetry(func(tx *gorm.DB) error { return tx.Save(&tpl).Error }, 3, 0); err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Code: "UPDATE_FAILED", Message: "更新模板失败: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, SuccessResponse{Code: "SUCCESS", Message: "模板更新成功", Data: tpl})
+}
+
+// DeleteTemplate DELETE /api/v1/format/templates/:id（同时删除模板文件）
+func (h *TemplateHandler) DeleteTemplate(c *gin.Context) {
+	db := database.GetDB()
+	var tpl model.FSMTemplate
+	if err := db.Where("id = ?", c.Param("id")).First(&tpl).Error; err != nil {
+		c.JSON(http.StatusNotFound, ErrorResponse{Code: "TEMPLATE_NOT_FOUND", Message: "模板不存在"})
+		return
+	}
+	if err := database.WithRetry(func(tx *gorm.DB) error { return tx.Delete(&tpl).Error }, 3, 0); err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Code: "DELETE_FAILED", Message: err.Error()})
+		return
+	}
+	if err := os.Remove(tpl.FilePath); err != nil && !os.IsNotExist(err) {
+		logger.Warn("Remove FSM template file failed", "path", tpl.FilePath, "error", err)
+	}
+	c.JSON(http.StatusOK, SuccessResponse{Code: "SUCCESS", Message: "模板删除成功", Data: gin.H{"id": tpl.ID}})
+}
+
+// ImportTemplates POST /api/v1/format/templates/import
+// multipart：file 为 ntc-templates 的 zip 包，platforms（逗号分隔）与 overwrite 为表单字段；不支持读取服务器本地目录
+func (h *TemplateHandler) ImportTemplates(c *gin.Context) {
+	if !strings.HasPrefix(c.ContentType(), "multipart/") {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Code: "INVALID_PARAMS", Message: "仅支持以 multipart 上传 zip 文件（字段 file）"})
+		return
+	}
+	fh, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Code: "INVALID_PARAMS", Message: "缺少 zip 文件: " + err.Error()})
+		return
+	}
+	if fh.Size > maxTemplateArchiveSize {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Code: "INVALID_PARAMS", Message: "压缩包过大"})
+		return
+	}
+	f, err := fh.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Code: "INVALID_PARAMS", Message: err.Error()})
+		return
+	}
+	data, err := io.ReadAll(io.LimitReader(f, maxTemplateArchiveSize))
+	f.Close()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Code: "INVALID_PARAMS", Message: err.Error()})
+		return
+	}
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Code: "INVALID_PARAMS", Message: "无效的 zip 文件: " + err.Error()})
+		return
+	}
+	var req TemplateImportRequest
+	for _, p := range strings.Split(c.PostForm("platforms"), ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			req.Platforms = append(req.Platforms, p)
+		}
+	}
+	req.Overwrite = strings.EqualFold(c.PostForm("overwrite"), "true")
+
+	res, err := importNTCTemplates(zr, req.Platforms, req.Overwrite)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Code: "IMPORT_FAILED", Message: err.Error()})
+		return
+	}
+	logger.Info("FSM templates imported", "imported", res.Imported, "updated", res.Updated, "skipped", res.Skipped, "errors", len(res.Errors))
+	c.JSON(http.StatusOK, SuccessResponse{Code: "SUCCESS", Message: "模板导入完成", Data: res})
+}
+
+// importNTCTemplates 读取 index 并逐个导入模板文件；单个模板失败记录在结果中，不中断导入
+func importNTCTemplates(fsys fs.FS, platforms []string, overwrite bool) (*TemplateImportResult, error) {
+	indexPath := ""
+	_ = fs.WalkDir(fsys, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || d.Name() != "index" {
+			return nil
+		}
+		if indexPath == "" || len(p) < len(indexPath) {
+			indexPath = p
+		}
+		return nil
+	})
+	if indexPath == "" {
+		return nil, fmt.Errorf("index file not found")
+	}
+	f, err := fsys.Open(indexPath)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := service.ParseNTCIndex(f)
+	f.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	want := make(map[string]bool)
+	for _, p := range platforms {
+		want[strings.ToLower(strings.TrimSpace(p))] = true
+	}
+	baseDir := path.Dir(indexPath)
+	res := &TemplateImportResult{}
+	db := database.GetDB()
+	for _, e := range entries {
+		if len(want) > 0 && !want[e.Platform] {
+			continue
+		}
+		if !templateNamePattern.MatchString(e.Platform) {
+			res.Errors = append(res.Errors, fmt.Sprintf("%q: invalid platform name", e.Platform))
+			continue
+		}
+		if _, err := service.NTCCommandRegexp(e.Command); err != nil {
+			res.Errors = append(res.Errors, fmt.Sprintf("%s %q: invalid command: %v", e.Platform, e.Command, err))
+			continue
+		}
+		for _, name := range e.Templates {
+			if !validTemplateFileName(name) {
+				res.Errors = append(res.Errors, fmt.Sprintf("%q: invalid template name (want [a-z0-9_-]+%s)", name, templateExt))
+				continue
+			}
+			content, err := fs.ReadFile(fsys, path.Join(baseDir, name))
+			if err != nil {
+				res.Errors = append(res.Errors, fmt.Sprintf("%s: %v", name, err))
+				continue
+			}
+			if err := service.ValidateTextFSMTemplate(string(content)); err != nil {
+				res.Errors = append(res.Errors, fmt.Sprintf("%s: %v", name, err))
+				continue
+			}
+			var existing model.FSMTemplate
+			found := db.Where("platform = ? AND name = ?", e.Platform, name).First(&existing).Error == nil
+			if found && !overwrite {
+				res.Skipped++
+				continue
+			}
+			tpl := existing
+			tpl.Platform, tpl.Name, tpl.Source = e.Platform, name, "ntc"
+			tpl.Command, tpl.CommandPattern = service.ExpandNTCCommand(e.Command), e.Command
+			if tpl.FilePath, tpl.Checksum, err = writeTemplateFile(e.Platform, name, content); err != nil {
+				res.Errors = append(res.Errors, fmt.Sprintf("%s: %v", name, err))
+				continue
+			}
+			if err := database.WithRetry(func(tx *gorm.DB) error { return tx.Save(&tpl).Error }, 3, 0); err != nil {
+				res.Errors = append(res.Errors, fmt.Sprintf("%s: %v", name, err))
+				continue
+			}
+			if found {
+				res.Updated++
+			} else {
+				res.Imported++
+			}
+		}
+	}
+	return res, nil
+}
+
+// templateFileName 规范模板文件名：补齐 .textfsm 扩展名；name 为空时按 {platform}_{command} 生成
+func templateFileName(name, platform, command string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		cmd := templateNameUnsafe.ReplaceAllString(strings.ToLower(service.ExpandNTCCommand(command)), "_")
+		name = platform + "_" + strings.Trim(cmd, "_")
+	}
+	name = strings.TrimSuffix(name, templateExt) + templateExt
+	if !validTemplateFileName(name) {
+		return "", fmt.Errorf("name 仅允许小写字母、数字、_ 与 -（扩展名 %s）", templateExt)
+	}
+	return name, nil
+}
+
+// validTemplateFileName 文件名须为 [a-z0-9_-]+.textfsm，不含路径
+func validTemplateFileName(name string) bool {
+	stem, ok := strings.CutSuffix(name, templateExt)
+	return ok && templateNamePattern.MatchString(stem)
+}
+
+// writeTemplateFile 写入 {template_dir}/{platform}/{name}，返回路径与 sha256；平台名与文件名不合法时拒绝写入
+func writeTemplateFile(platform, name string, content []byte) (string, string, error) {
+	if !templateNamePattern.MatchString(platform) || !validTemplateFileName(name) {
+		return "", "", fmt.Errorf("invalid template path %q/%q", platform, name)
+	}
+	dir := "./data/templates"
+	if cfg := config.Get(); cfg != nil && strings.TrimSpace(cfg.DataFormat.TemplateDir) != "" {
+		dir = cfg.DataFormat.TemplateDir
+	}
+	dir = filepath.Join(dir, platform)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", "", err
+	}
+	p := filepath.Join(dir, name)
+	if err := os.WriteFile(p, content, 0o644); err != nil {
+		return "", "", err
+	}
+	sum := sha256.Sum256(content)
+	return p, hex.EncodeToString(sum[:]), nil
+}
+
+// 模板影响评估：默认与最大样本数、单次扫描的任务记录上限
+const (
+	defaultImpactSamples = 20
+	maxImpactSamples     = 200
+	maxImpactScanTasks   = 2000
+)
+
+// TemplateImpactRequest 模板影响评估请求：content 为待启用的新版本
+type TemplateImpactRequest struct {
+	Content string `json:"content"`
+	Samples int    `json:"samples,omitempty"` // 最近 N 条原始输出，默认 20
+}
+
+// TemplateImpact POST /api/v1/format/templates/:id/impact
+// 以最近 N 条同 平台+命令 的历史原始输出分别运行当前模板与新版本，返回记录数与字段级差异；不修改模板
+func (h *TemplateHandler) TemplateImpact(c *gin.Context) {
+	var req TemplateImpactRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Code: "INVALID_PARAMS", Message: "请求参数无效: " + err.Error()})
+		return
+	}
+	if err := service.ValidateTextFSMTemplate(req.Content); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Code: "INVALID_TEMPLATE", Message: err.Error()})
+		return
+	}
+	if req.Samples <= 0 {
+		req.Samples = defaultImpactSamples
+	}
+	if req.Samples > maxImpactSamples {
+		req.Samples = maxImpactSamples
+	}
+	var tpl model.FSMTemplate
+	if err := database.GetDB().Where("id = ?", c.Param("id")).First(&tpl).Error; err != nil {
+		c.JSON(http.StatusNotFound, ErrorResponse{Code: "TEMPLATE_NOT_FOUND", Message: "模板不存在"})
+		return
+	}
+	current, err := os.ReadFile(tpl.FilePath)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Code: "READ_FAILED", Message: "读取模板文件失败: " + err.Error()})
+		return
+	}
+	samples, err := templateSamples(&tpl, req.Samples)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Code: "QUERY_FAILED", Message: err.Error()})
+		return
+	}
+	impact := service.CompareTemplateVersions(string(current), req.Content, samples)
+	c.JSON(http.StatusOK, SuccessResponse{Code: "SUCCESS", Message: "模板影响评估完成", Data: gin.H{
+		"template": tpl,
+		"impact":   impact,
+	}})
+}
+
+// templateSamples 从成功的采集任务记录中取最近 limit 条命中该模板的原始输出
+// 任务按 metadata.device_platform 筛选平台，命令按模板的完整命令或缩写写法匹配
+func templateSamples(tpl *model.FSMTemplate, limit int) ([]service.TemplateSample, error) {
+	re, _ := service.NTCCommandRegexp(tpl.CommandPattern)
+	platformTag := fmt.Sprintf(`%%"device_platform":%q%%`, tpl.Platform)
+	var tasks []model.Task
+	err := database.GetDB().Select("id", "device_ip", "result", "end_time", "created_at").
+		Where("status = ? AND metadata LIKE ?", model.TaskStatusSuccess, platformTag).
+		Order("created_at DESC").Limit(maxImpactScanTasks).Find(&tasks).Error
+	if err != nil {
+		return nil, err
+	}
+	samples := make([]service.TemplateSample, 0, limit)
+	for _, t := range tasks {
+		var results []struct {
+			Command   string `json:"command"`
+			RawOutput string `json:"raw_output"`
+			Error     string `json:"error"`
+		}
+		if json.Unmarshal([]byte(t.Result), &results) != nil {
+			continue
+		}
+		at := t.EndTime
+		if at.IsZero() {
+			at = t.CreatedAt
+		}
+		for _, r := range results {
+			cmd := strings.ToLower(strings.Join(strings.Fields(r.Command), " "))
+			if r.Error != "" || strings.TrimSpace(r.RawOutput) == "" {
+				continue
+			}
+			if cmd != tpl.Command && (re == nil || !re.MatchString(cmd)) {
+				continue
+			}
+			samples = append(samples, service.TemplateSample{TaskID: t.ID, DeviceIP: t.DeviceIP, Command: r.Command, CapturedAt: at, Raw: r.RawOutput})
+			if len(samples) >= limit {
+				return samples, nil
+			}
+		}
+	}
+	return samples, nil
+}
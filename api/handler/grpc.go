@@ -0,0 +1,547 @@
+package handler
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	pb "github.com/sshcollectorpro/sshcollectorpro/api/proto/sshcollector/v1"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/config"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/service"
+	"golang.org/x/sync/errgroup"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// GRPCServer gRPC 接口实现：与 REST 处理器复用相同的服务与参数校验
+type GRPCServer struct {
+	pb.UnimplementedCollectorServiceServer
+	pb.UnimplementedBackupServiceServer
+	pb.UnimplementedFormatServiceServer
+	pb.UnimplementedDeployServiceServer
+
+	collector *CollectorHandler
+	backup    *service.BackupService
+	format    *service.FormatService
+	deploy    *service.DeployService
+}
+
+// NewGRPCServer 创建 gRPC 接口实现
+func NewGRPCServer(collectorService *service.CollectorService, backupService *service.BackupService, formatService *service.FormatService, deployService *service.DeployService) *GRPCServer {
+	return &GRPCServer{
+		collector: NewCollectorHandler(collectorService),
+		backup:    backupService,
+		format:    formatService,
+		deploy:    deployService,
+	}
+}
+
+// Register 在 gRPC 服务器上注册全部服务
+func (s *GRPCServer) Register(gs *grpc.Server) {
+	pb.RegisterCollectorServiceServer(gs, s)
+	pb.RegisterBackupServiceServer(gs, s)
+	pb.RegisterFormatServiceServer(gs, s)
+	pb.RegisterDeployServiceServer(gs, s)
+}
+
+// grpcError 服务层错误映射为 gRPC 状态码
+func grpcError(err error) error {
+	if err == nil {
+		return nil
+	}
+	code := codes.Internal
+	switch {
+	case errors.Is(err, service.ErrValidation):
+		code = codes.InvalidArgument
+	case errors.Is(err, service.ErrNotFound):
+		code = codes.NotFound
+	case errors.Is(err, service.ErrTimeout):
+		code = codes.DeadlineExceeded
+	case errors.Is(err, service.ErrServiceStopped):
+		code = codes.Unavailable
+	case errors.Is(err, service.ErrReadOnly), errors.Is(err, service.ErrApprovalRequired):
+		code = codes.FailedPrecondition
+	case errors.Is(err, service.ErrEgressBlocked):
+		code = codes.PermissionDenied
+	case errors.Is(err, service.ErrCredentialThrottled):
+		code = codes.ResourceExhausted
+	case errors.Is(err, service.ErrDeviceCircuitOpen):
+		code = codes.Unavailable
+	case errors.Is(err, service.ErrHostKeyRejected):
+		code = codes.FailedPrecondition
+	case errors.Is(err, service.ErrDeviceUnreachable), errors.Is(err, service.ErrAuthFailed):
+		code = codes.Unavailable
+	case errors.Is(err, service.ErrCanceled), errors.Is(err, context.Canceled):
+		code = codes.Canceled
+	}
+	return status.Error(code, err.Error())
+}
+
+// Collect 单设备采集（对应 /api/v1/collector/fast）
+func (s *GRPCServer) Collect(ctx context.Context, req *pb.CollectBatchRequest) (*pb.CollectResult, error) {
+	if len(req.GetDevices()) != 1 {
+		return nil, status.Error(codes.InvalidArgument, "exactly one device is required")
+	}
+	taskID := req.GetTaskId()
+	if strings.TrimSpace(taskID) == "" {
+		taskID = fmt.Sprintf("fast-%d", time.Now().UnixNano())
+	}
+	r, err := s.collectRequest(req, req.Devices[0], taskID, "fast")
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	resp, err := s.collector.collectorService.ExecuteTask(ctx, r)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return collectResultPB(r, resp), nil
+}
+
+// CollectBatch 批量自定义采集（对应 /api/v1/collector/batch/custom），设备完成即推送
+func (s *GRPCServer) CollectBatch(req *pb.CollectBatchRequest, stream grpc.ServerStreamingServer[pb.CollectResult]) error {
+	if strings.TrimSpace(req.GetTaskId()) == "" {
+		return status.Error(codes.InvalidArgument, "task_id is required")
+	}
+	if len(req.GetDevices()) == 0 || len(req.GetDevices()) > 200 {
+		return status.Error(codes.InvalidArgument, "devices must contain 1-200 entries")
+	}
+	// 批内并发度与 REST 接口一致：不超过服务 worker 数与设备数
+	k := 4
+	if v, ok := s.collector.collectorService.GetStats()["max_workers"].(int); ok && v > 0 {
+		k = v
+	}
+	if k > len(req.Devices) {
+		k = len(req.Devices)
+	}
+	sem := make(chan struct{}, k)
+	var sendMu sync.Mutex
+	send := func(r *pb.CollectResult) error {
+		sendMu.Lock()
+		defer sendMu.Unlock()
+		return stream.Send(r)
+	}
+	g, ctx := errgroup.WithContext(stream.Context())
+	for i, d := range req.Devices {
+		i, d := i, d
+		g.Go(func() error {
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+			defer func() { <-sem }()
+			taskID := fmt.Sprintf("%s-%d", req.TaskId, i+1)
+			r, err := s.collectRequest(req, d, taskID, "customer")
+			if err != nil {
+				return send(&pb.CollectResult{
+					TaskId:         taskID,
+					DeviceIp:       d.GetDeviceIp(),
+					DevicePort:     d.GetDevicePort(),
+					DeviceName:     d.GetDeviceName(),
+					DevicePlatform: d.GetDevicePlatform(),
+					Error:          "参数验证失败: " + err.Error(),
+				})
+			}
+			resp, err := s.collector.collectorService.ExecuteTask(ctx, r)
+			if err != nil {
+				resp = &service.CollectResponse{TaskID: taskID, Error: err.Error()}
+			}
+			return send(collectResultPB(r, resp))
+		})
+	}
+	return g.Wait()
+}
+
+// collectRequest 组装并校验单设备采集请求
+func (s *GRPCServer) collectRequest(req *pb.CollectBatchRequest, d *pb.Device, taskID, mode string) (*service.CollectRequest, error) {
+	user, password, enable := d.GetUserName(), d.GetPassword(), d.GetEnablePassword()
+	if err := resolveCredential(d.GetCredentialId(), &user, &password, &enable); err != nil {
+		return nil, err
+	}
+	proto := strings.ToLower(strings.TrimSpace(d.GetCollectProtocol()))
+	if proto == "" {
+		proto = "ssh"
+	}
+	meta := map[string]interface{}{"collect_mode": mode}
+	if mode != "fast" {
+		meta["batch_task_id"] = req.GetTaskId()
+	}
+	r := &service.CollectRequest{
+		TaskID:          taskID,
+		TaskName:        req.GetTaskName(),
+		DeviceIP:        d.GetDeviceIp(),
+		Port:            int(d.GetDevicePort()),
+		DeviceName:      d.GetDeviceName(),
+		DevicePlatform:  d.GetDevicePlatform(),
+		CollectProtocol: proto,
+		UserName:        user,
+		Password:        password,
+		EnablePassword:  enable,
+		CliList:         cliListFromPB(d.GetCliList()),
+		RetryFlag:       optionalInt(req.RetryFlag),
+		TaskTimeout:     optionalInt(req.TaskTimeout),
+		DeviceTimeout:   optionalInt(d.DeviceTimeout),
+		Metadata:        service.MergeTaskMetadata(req.GetMetadata().AsMap(), meta),
+		Store:           req.GetStore(),
+		SaveDir:         req.GetSaveDir(),
+		StorageBackend:  req.GetStorageBackend(),
+	}
+	if err := service.CheckCLIText(r.CliList.Commands()...); err != nil {
+		return nil, err
+	}
+	if err := s.collector.validateCollectRequest(r); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// BackupBatch 批量备份（对应 /api/v1/backup/batch），设备完成即推送
+func (s *GRPCServer) BackupBatch(req *pb.BackupBatchRequest, stream grpc.ServerStreamingServer[pb.BackupResult]) error {
+	if strings.TrimSpace(req.GetTaskId()) == "" || len(req.GetDevices()) == 0 {
+		return status.Error(codes.InvalidArgument, "task_id and devices are required")
+	}
+	sreq := &service.BackupBatchRequest{
+		TaskID:         req.GetTaskId(),
+		TaskName:       req.GetTaskName(),
+		TaskBatch:      int(req.GetTaskBatch()),
+		SaveDir:        req.GetSaveDir(),
+		StorageBackend: req.GetStorageBackend(),
+		RetryFlag:      optionalInt(req.RetryFlag),
+		TaskTimeout:    optionalInt(req.TaskTimeout),
+		FreshTTL:       int(req.GetFreshTtl()),
+	}
+	for _, d := range req.Devices {
+		bd := service.BackupDevice{
+			DeviceIP:        d.GetDeviceIp(),
+			Port:            int(d.GetDevicePort()),
+			DeviceName:      d.GetDeviceName(),
+			DevicePlatform:  d.GetDevicePlatform(),
+			CollectProtocol: d.GetCollectProtocol(),
+			UserName:        d.GetUserName(),
+			Password:        d.GetPassword(),
+			EnablePassword:  d.GetEnablePassword(),
+			CliList:         cliListFromPB(d.GetCliList()),
+			DeviceTimeout:   optionalInt(d.DeviceTimeout),
+		}
+		if err := service.CheckCLIText(bd.CliList.Commands()...); err != nil {
+			return status.Error(codes.InvalidArgument, err.Error())
+		}
+		if err := resolveCredential(d.GetCredentialId(), &bd.UserName, &bd.Password, &bd.EnablePassword); err != nil {
+			return status.Error(codes.InvalidArgument, err.Error())
+		}
+		sreq.Devices = append(sreq.Devices, bd)
+	}
+	var sendErr error
+	_, err := s.backup.ExecuteBatchStream(stream.Context(), sreq, func(r service.DeviceBackupResponse) {
+		if sendErr == nil {
+			sendErr = stream.Send(backupResultPB(r))
+		}
+	})
+	if err != nil {
+		return grpcError(err)
+	}
+	return sendErr
+}
+
+// FormatBatch 批量格式化（对应 /api/v1/formatted/batch）
+func (s *GRPCServer) FormatBatch(ctx context.Context, req *pb.FormatBatchRequest) (*pb.FormatBatchResponse, error) {
+	if s.format == nil {
+		return nil, status.Error(codes.Unavailable, "format service not initialized")
+	}
+	sreq := &service.FormatBatchRequest{
+		TaskID:       req.GetTaskId(),
+		TaskName:     req.GetTaskName(),
+		TaskBatch:    int(req.GetTaskBatch()),
+		RetryFlag:    optionalInt(req.RetryFlag),
+		SaveDir:      req.GetSaveDir(),
+		TaskTimeout:  optionalInt(req.TaskTimeout),
+		ExportFormat: req.GetExportFormat(),
+	}
+	if err := service.ValidateExportFormat(sreq.ExportFormat); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	for _, t := range req.GetFsmTemplates() {
+		def := service.FSMTemplateDef{DevicePlatform: t.GetDevicePlatform()}
+		for _, v := range t.GetTemplates() {
+			def.TemplateValues = append(def.TemplateValues, service.FSMTemplateValue{CLIName: v.GetCliName(), FSMValue: v.GetFsmValue()})
+		}
+		sreq.FSMTemplates = append(sreq.FSMTemplates, def)
+	}
+	for _, d := range req.GetDevices() {
+		fd := service.FormatDevice{
+			DeviceIP:        d.GetDeviceIp(),
+			DevicePort:      int(d.GetDevicePort()),
+			DeviceName:      d.GetDeviceName(),
+			DevicePlatform:  d.GetDevicePlatform(),
+			CollectProtocol: d.GetCollectProtocol(),
+			UserName:        d.GetUserName(),
+			Password:        d.GetPassword(),
+			EnablePassword:  d.GetEnablePassword(),
+			CliList:         cliListFromPB(d.GetCliList()),
+			DeviceTimeout:   optionalInt(d.DeviceTimeout),
+		}
+		if err := service.CheckCLIText(fd.CliList.Commands()...); err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+		if err := resolveCredential(d.GetCredentialId(), &fd.UserName, &fd.Password, &fd.EnablePassword); err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+		sreq.Devices = append(sreq.Devices, fd)
+	}
+	resp, err := s.format.ExecuteBatch(ctx, sreq)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	out := &pb.FormatBatchResponse{
+		Code:          resp.Code,
+		Message:       resp.Message,
+		JsonPrefix:    resp.JSONPrefix,
+		DateTime:      resp.DateTime,
+		StoredObjects: storedObjectsPB(resp.Stored),
+		PostgresRows:  int32(resp.PostgresRows),
+		PostgresError: resp.PostgresError,
+	}
+	for _, d := range service.NewFormatCallbackPayload(sreq, resp).Devices {
+		out.Devices = append(out.Devices, &pb.DeviceOutcome{
+			DeviceIp:       d.DeviceIP,
+			DeviceName:     d.DeviceName,
+			DevicePlatform: d.DevicePlatform,
+			Success:        d.Success,
+			Error:          validUTF8(d.Error),
+		})
+	}
+	return out, nil
+}
+
+// Deploy 配置下发（对应 /api/v1/deploy/fast），设备完成即推送
+func (s *GRPCServer) Deploy(req *pb.DeployRequest, stream grpc.ServerStreamingServer[pb.DeployResult]) error {
+	sreq := &service.DeployFastRequest{
+		TaskID:            req.GetTaskId(),
+		TaskName:          req.GetTaskName(),
+		RetryFlag:         int(req.GetRetryFlag()),
+		TaskType:          req.GetTaskType(),
+		TaskTimeout:       int(req.GetTaskTimeout()),
+		StatusCheckEnable: int(req.GetStatusCheckEnable()),
+		ConfigTemplate:    req.GetConfigTemplate(),
+		Variables:         req.GetVariables().AsMap(),
+		AutoRollback:      req.GetAutoRollback(),
+	}
+	if strings.TrimSpace(sreq.TaskType) == "" {
+		sreq.TaskType = "exec"
+	}
+	if sreq.TaskTimeout <= 0 {
+		if cfg := config.Get(); cfg != nil && cfg.SSH.Timeout > 0 {
+			sreq.TaskTimeout = int(cfg.SSH.Timeout.Seconds())
+		} else {
+			sreq.TaskTimeout = 15
+		}
+	}
+	for _, d := range req.GetDevices() {
+		dd := service.DeployDevice{
+			DeviceIP:        d.GetDeviceIp(),
+			DevicePort:      int(d.GetDevicePort()),
+			DeviceName:      d.GetDeviceName(),
+			DevicePlatform:  d.GetDevicePlatform(),
+			UserName:        d.GetUserName(),
+			Password:        d.GetPassword(),
+			EnablePassword:  d.GetEnablePassword(),
+			CliList:         service.NewDeployCLIList(d.GetCliList()...),
+			StatusCheckList: d.GetStatusCheckList(),
+			ConfigDeploy:    d.GetConfigDeploy(),
+			DeviceTimeout:   optionalInt(d.DeviceTimeout),
+			Variables:       d.GetVariables().AsMap(),
+			RollbackCliList: service.NewDeployCLIList(d.GetRollbackCliList()...),
+		}
+		if err := service.CheckCLIText(append(d.GetCliList(), d.GetRollbackCliList()...)...); err != nil {
+			return status.Error(codes.InvalidArgument, err.Error())
+		}
+		if err := resolveCredential(d.GetCredentialId(), &dd.UserName, &dd.Password, &dd.EnablePassword); err != nil {
+			return status.Error(codes.InvalidArgument, err.Error())
+		}
+		sreq.Devices = append(sreq.Devices, dd)
+	}
+	var sendErr error
+	_, err := s.deploy.DeployStream(stream.Context(), sreq, func(r service.DeployDeviceResult) {
+		if sendErr == nil {
+			sendErr = stream.Send(deployResultPB(r))
+		}
+	})
+	if err != nil {
+		return grpcError(err)
+	}
+	return sendErr
+}
+
+// ===== 类型转换 =====
+
+func optionalInt(v *int32) *int {
+	if v == nil {
+		return nil
+	}
+	n := int(*v)
+	return &n
+}
+
+// validUTF8 protobuf string 字段要求合法 UTF-8，设备输出中的非法字节替换为 U+FFFD
+func validUTF8(s string) string {
+	return strings.ToValidUTF8(s, "�")
+}
+
+func cliListFromPB(cmds []*pb.Command) service.CLIList {
+	out := make(service.CLIList, 0, len(cmds))
+	for _, c := range cmds {
+		out = append(out, service.CLIItem{
+			CLI:             c.GetCli(),
+			Timeout:         int(c.GetTimeout()),
+			FreshTTL:        int(c.GetFreshTtl()),
+			IncludeRawBytes: c.GetIncludeRawBytes(),
+		})
+	}
+	return out
+}
+
+func storedObjectsPB(objs []service.StoredObject) []*pb.StoredObject {
+	out := make([]*pb.StoredObject, 0, len(objs))
+	for _, o := range objs {
+		out = append(out, storedObjectPB(o))
+	}
+	return out
+}
+
+func storedObjectPB(o service.StoredObject) *pb.StoredObject {
+	return &pb.StoredObject{Uri: o.URI, Size: o.Size, Checksum: o.Checksum, ContentType: o.ContentType}
+}
+
+// formatOutputPB 格式化行经 JSON 转为 ListValue
+func formatOutputPB(v interface{}) *structpb.ListValue {
+	if v == nil {
+		return nil
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil
+	}
+	var rows []interface{}
+	if err := json.Unmarshal(b, &rows); err != nil || len(rows) == 0 {
+		return nil
+	}
+	lv, err := structpb.NewList(rows)
+	if err != nil {
+		return nil
+	}
+	return lv
+}
+
+func collectResultPB(r *service.CollectRequest, resp *service.CollectResponse) *pb.CollectResult {
+	out := &pb.CollectResult{
+		TaskId:         resp.TaskID,
+		DeviceIp:       r.DeviceIP,
+		DevicePort:     int32(r.Port),
+		DeviceName:     r.DeviceName,
+		DevicePlatform: r.DevicePlatform,
+		Success:        resp.Success,
+		Error:          validUTF8(resp.Error),
+		DurationMs:     resp.DurationMS,
+	}
+	for _, v := range resp.Results {
+		if v == nil {
+			continue
+		}
+		cr := &pb.CommandResult{
+			Command:       validUTF8(v.Command),
+			RawOutput:     validUTF8(v.RawOutput),
+			FormatOutput:  formatOutputPB(v.FormatOutput),
+			Error:         validUTF8(v.Error),
+			ExitCode:      int32(v.ExitCode),
+			DurationMs:    v.DurationMS,
+			StoredObjects: storedObjectsPB(v.StoredObjects),
+			StoreError:    v.StoreError,
+		}
+		if v.RawBytes != "" {
+			cr.RawBytes, _ = base64.StdEncoding.DecodeString(v.RawBytes)
+		}
+		out.Results = append(out.Results, cr)
+	}
+	return out
+}
+
+func backupResultPB(r service.DeviceBackupResponse) *pb.BackupResult {
+	out := &pb.BackupResult{
+		TaskId:         r.TaskID,
+		DeviceIp:       r.DeviceIP,
+		DevicePort:     int32(r.Port),
+		DeviceName:     r.DeviceName,
+		DevicePlatform: r.DevicePlatform,
+		Success:        r.Success,
+		Status:         r.Status,
+		Error:          validUTF8(r.Error),
+		DurationMs:     r.DurationMS,
+		SkippedFresh:   int32(r.SkippedFresh),
+	}
+	for _, c := range r.Results {
+		br := &pb.BackupCommandResult{
+			Command:       validUTF8(c.Command),
+			RawOutput:     validUTF8(c.RawOutput),
+			StoredObjects: storedObjectsPB(c.StoredObjects),
+			ExitCode:      int32(c.ExitCode),
+			DurationMs:    c.DurationMS,
+			Error:         validUTF8(c.Error),
+			SkippedFresh:  c.SkippedFresh,
+		}
+		if c.RawObject != nil {
+			br.RawObject = storedObjectPB(*c.RawObject)
+		}
+		out.Results = append(out.Results, br)
+	}
+	return out
+}
+
+func deployLogsPB(logs []service.CommandResult) []*pb.DeployCommandLog {
+	out := make([]*pb.DeployCommandLog, 0, len(logs))
+	for _, l := range logs {
+		out = append(out, &pb.DeployCommandLog{
+			Command:  validUTF8(l.Command),
+			Output:   validUTF8(l.Output),
+			Error:    validUTF8(l.Error),
+			Elapsed:  l.Elapsed,
+			ExitCode: int32(l.ExitCode),
+		})
+	}
+	return out
+}
+
+func validUTF8Map(m map[string]string) map[string]string {
+	if len(m) == 0 {
+		return nil
+	}
+	out := make(map[string]string, len(m))
+	for k, v := range m {
+		out[validUTF8(k)] = validUTF8(v)
+	}
+	return out
+}
+
+func deployResultPB(r service.DeployDeviceResult) *pb.DeployResult {
+	ok, errMsg := r.Outcome()
+	return &pb.DeployResult{
+		DeviceIp:           r.DeviceIP,
+		DeviceName:         r.DeviceName,
+		DevicePlatform:     r.DevicePlatform,
+		Success:            ok,
+		Status:             r.Status,
+		Error:              validUTF8(errMsg),
+		DeviceStatusBefore: validUTF8Map(r.DeviceStatusBefore),
+		DeviceStatusAfter:  validUTF8Map(r.DeviceStatusAfter),
+		DeployLogExec:      deployLogsPB(r.DeployLogExec),
+		RenderedConfig:     validUTF8(r.RenderedConfig),
+		RollbackLogExec:    deployLogsPB(r.RollbackLogExec),
+		RollbackStatus:     r.RollbackStatus,
+	}
+}
@@ -0,0 +1,14 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/service"
+)
+
+// HAStatus GET /api/v1/ha/status
+// 返回本实例角色（leader/follower/standalone）、当前租约持有者与到期时间；两个实例均可查询
+func HAStatus(c *gin.Context) {
+	c.JSON(http.StatusOK, SuccessResponse{Code: "SUCCESS", Message: "获取高可用状态成功", Data: service.CurrentHAStatus()})
+}
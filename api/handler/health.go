@@ -0,0 +1,35 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/service"
+)
+
+// HealthHandler 聚合健康检查（就绪/存活探针）
+type HealthHandler struct {
+	checker *service.HealthChecker
+}
+
+// NewHealthHandler 创建健康检查处理器
+func NewHealthHandler(checker *service.HealthChecker) *HealthHandler {
+	return &HealthHandler{checker: checker}
+}
+
+// Ready GET /api/v1/health/ready
+// 检查各服务、SQLite、MinIO、SSH 连接池与模拟服务；关键依赖异常返回 503，非关键依赖异常为 degraded 仍返回 200
+func (h *HealthHandler) Ready(c *gin.Context) {
+	report := h.checker.Check(c.Request.Context())
+	if report.Status == service.HealthDown {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"code": "SERVICE_UNAVAILABLE", "message": "关键依赖异常", "data": report})
+		return
+	}
+	c.JSON(http.StatusOK, SuccessResponse{Code: "SUCCESS", Message: "服务就绪", Data: report})
+}
+
+// Live GET /api/v1/health/live
+// 进程存活即返回 200，不检查依赖（避免依赖故障导致容器被反复重启）
+func (h *HealthHandler) Live(c *gin.Context) {
+	c.JSON(http.StatusOK, SuccessResponse{Code: "SUCCESS", Message: "alive", Data: gin.H{"status": service.HealthOK}})
+}
@@ -0,0 +1,115 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/database"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/model"
+	"github.com/sshcollectorpro/sshcollectorpro/pkg/logger"
+	"github.com/sshcollectorpro/sshcollectorpro/pkg/ssh"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// HostKeyRepository 基于 SQLite 的主机密钥指纹仓库（host_keys 表）
+type HostKeyRepository struct{}
+
+// NewHostKeyRepository 创建主机密钥指纹仓库
+func NewHostKeyRepository() *HostKeyRepository { return &HostKeyRepository{} }
+
+// HostKeys 设备已记录的主机密钥
+func (HostKeyRepository) HostKeys(host string, port int) ([]ssh.HostKeyRecord, error) {
+	var rows []model.HostKey
+	if err := database.GetDB().Where("host = ? AND port = ?", host, port).Order("id").Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	out := make([]ssh.HostKeyRecord, 0, len(rows))
+	for _, r := range rows {
+		out = append(out, ssh.HostKeyRecord{Host: r.Host, Port: r.Port, KeyType: r.KeyType, Fingerprint: r.Fingerprint, PublicKey: r.PublicKey})
+	}
+	return out, nil
+}
+
+// SaveHostKey 首次记录指纹；已存在时仅刷新最近校验时间，不覆盖指纹
+func (HostKeyRepository) SaveHostKey(rec ssh.HostKeyRecord) error {
+	row := model.HostKey{Host: rec.Host, Port: rec.Port, KeyType: rec.KeyType, Fingerprint: rec.Fingerprint, PublicKey: rec.PublicKey, LastSeenAt: time.Now()}
+	return database.WithRetry(func(tx *gorm.DB) error {
+		return tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "host"}, {Name: "port"}, {Name: "key_type"}},
+			DoUpdates: clause.AssignmentColumns([]string{"last_seen_at"}),
+		}).Create(&row).Error
+	}, 3, 100*time.Millisecond)
+}
+
+// ListHostKeys GET /api/v1/admin/host-keys?host=&port=
+// 列出已记录的设备主机密钥指纹
+func ListHostKeys(c *gin.Context) {
+	query := database.GetDB().Model(&model.HostKey{})
+	if host := strings.TrimSpace(c.Query("host")); host != "" {
+		query = query.Where("host = ?", host)
+	}
+	if v := strings.TrimSpace(c.Query("port")); v != "" {
+		port, err := strconv.Atoi(v)
+		if err != nil || port <= 0 || port > 65535 {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Code: "INVALID_PARAMS", Message: "port 无效"})
+			return
+		}
+		query = query.Where("port = ?", port)
+	}
+	rows := []model.HostKey{}
+	if err := query.Order("host, port, key_type").Find(&rows).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Code: "DB_ERROR", Message: "查询主机密钥失败: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, SuccessResponse{Code: "SUCCESS", Message: "获取主机密钥成功", Data: rows})
+}
+
+// DeleteHostKey DELETE /api/v1/admin/host-keys/:id
+func DeleteHostKey(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Code: "INVALID_PARAMS", Message: "id 无效"})
+		return
+	}
+	res := database.GetDB().Delete(&model.HostKey{}, id)
+	if res.Error != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Code: "DB_ERROR", Message: "删除主机密钥失败: " + res.Error.Error()})
+		return
+	}
+	if res.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, ErrorResponse{Code: "NOT_FOUND", Message: "主机密钥不存在"})
+		return
+	}
+	logger.Info("Host key removed", "id", id)
+	c.JSON(http.StatusOK, SuccessResponse{Code: "SUCCESS", Message: "删除成功", Data: gin.H{"deleted": res.RowsAffected}})
+}
+
+// DeleteHostKeys DELETE /api/v1/admin/host-keys?host=&port=
+// 删除设备的全部指纹（设备更换后重新登记）；host 必填，port 为空表示不限端口
+func DeleteHostKeys(c *gin.Context) {
+	host := strings.TrimSpace(c.Query("host"))
+	if host == "" {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Code: "INVALID_PARAMS", Message: "host 不能为空"})
+		return
+	}
+	query := database.GetDB().Where("host = ?", host)
+	if v := strings.TrimSpace(c.Query("port")); v != "" {
+		port, err := strconv.Atoi(v)
+		if err != nil || port <= 0 || port > 65535 {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Code: "INVALID_PARAMS", Message: "port 无效"})
+			return
+		}
+		query = query.Where("port = ?", port)
+	}
+	res := query.Delete(&model.HostKey{})
+	if res.Error != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Code: "DB_ERROR", Message: "删除主机密钥失败: " + res.Error.Error()})
+		return
+	}
+	logger.Info("Host keys removed", "host", host, "count", res.RowsAffected)
+	c.JSON(http.StatusOK, SuccessResponse{Code: "SUCCESS", Message: "删除成功", Data: gin.H{"deleted": res.RowsAffected}})
+}
@@ -0,0 +1,126 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/database"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/model"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/service"
+)
+
+// InventoryStore 基于 device_info 表的资产同步读写
+type InventoryStore struct{}
+
+// NewInventoryStore 创建资产同步存储
+func NewInventoryStore() *InventoryStore { return &InventoryStore{} }
+
+// ListInventory 列出全部设备；同一 IP/端口登记多个用户名时取最早创建的一条
+func (InventoryStore) ListInventory() ([]service.InventoryRecord, error) {
+	var rows []model.DeviceInfo
+	if err := database.GetDB().Order("created_at ASC").Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	seen := map[string]bool{}
+	out := make([]service.InventoryRecord, 0, len(rows))
+	for _, d := range rows {
+		key := fmt.Sprintf("%s:%d", d.IP, d.Port)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		out = append(out, service.InventoryRecord{
+			IP:       d.IP,
+			Port:     d.Port,
+			Name:     d.Name,
+			Platform: d.DeviceType,
+			Vendor:   d.Vendor,
+			Model:    d.Model,
+			Source:   d.Source,
+			Enabled:  d.Enabled,
+		})
+	}
+	return out, nil
+}
+
+// UpsertInventory 按 ip+port 更新全部登记（不修改凭据与启用状态），不存在时新建
+func (InventoryStore) UpsertInventory(rec service.InventoryRecord) error {
+	db := database.GetDB()
+	updates := map[string]interface{}{"name": rec.Name, "source": rec.Source}
+	if rec.Platform != "" {
+		updates["device_type"] = rec.Platform
+	}
+	if rec.Vendor != "" {
+		updates["vendor"] = rec.Vendor
+	}
+	if rec.Model != "" {
+		updates["model"] = rec.Model
+	}
+	res := db.Model(&model.DeviceInfo{}).Where("ip = ? AND port = ?", rec.IP, rec.Port).Updates(updates)
+	if res.Error != nil || res.RowsAffected > 0 {
+		return res.Error
+	}
+	return db.Create(&model.DeviceInfo{
+		ID:         uuid.NewString(),
+		Name:       rec.Name,
+		IP:         rec.IP,
+		Port:       rec.Port,
+		DeviceType: rec.Platform,
+		Vendor:     rec.Vendor,
+		Model:      rec.Model,
+		Enabled:    true,
+		Status:     "unknown",
+		Source:     rec.Source,
+	}).Error
+}
+
+// DisableInventory 禁用指定 IP/端口的设备
+func (InventoryStore) DisableInventory(ip string, port int) error {
+	return database.GetDB().Model(&model.DeviceInfo{}).Where("ip = ? AND port = ?", ip, port).Update("enabled", false).Error
+}
+
+// InventoryHandler 资产同步接口
+type InventoryHandler struct {
+	svc *service.InventorySyncService
+}
+
+// NewInventoryHandler 创建资产同步处理器
+func NewInventoryHandler(svc *service.InventorySyncService) *InventoryHandler {
+	return &InventoryHandler{svc: svc}
+}
+
+// SyncNetBox POST /api/v1/inventory/netbox/sync 立即执行一次同步，请求体可选 {"dry_run": true}
+func (h *InventoryHandler) SyncNetBox(c *gin.Context) {
+	var req struct {
+		DryRun bool `json:"dry_run"`
+	}
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Code: "INVALID_PARAMS", Message: "请求参数无效: " + err.Error()})
+			return
+		}
+	}
+	report, err := h.svc.Sync(c.Request.Context(), req.DryRun)
+	if err != nil {
+		// 拉取或写入阶段失败时仍返回已产生的部分报告
+		if report != nil {
+			c.JSON(http.StatusBadGateway, gin.H{"code": "SYNC_FAILED", "message": err.Error(), "data": report})
+			return
+		}
+		c.Error(err).SetMeta("SYNC_FAILED")
+		return
+	}
+	c.JSON(http.StatusOK, SuccessResponse{Code: "SUCCESS", Message: "同步完成", Data: report})
+}
+
+// GetNetBoxSync GET /api/v1/inventory/netbox/sync 最近一次同步报告
+func (h *InventoryHandler) GetNetBoxSync(c *gin.Context) {
+	report := h.svc.LastReport()
+	if report == nil {
+		c.JSON(http.StatusNotFound, ErrorResponse{Code: "NOT_FOUND", Message: "尚未执行同步"})
+		return
+	}
+	c.JSON(http.StatusOK, SuccessResponse{Code: "SUCCESS", Message: "获取同步报告成功", Data: report})
+}
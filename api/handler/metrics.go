@@ -0,0 +1,52 @@
+package handler
+
+import (
+	"net/http"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/config"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/service"
+)
+
+// workerReporters 输出 worker 排队指标的服务（/metrics 与 /collector/stats 的 worker_pools）
+var workerReporters struct {
+	mu       sync.RWMutex
+	services []service.WorkerReporter
+}
+
+// SetWorkerReporters 登记需输出 worker 占用与排队指标的服务
+func SetWorkerReporters(services ...service.WorkerReporter) {
+	workerReporters.mu.Lock()
+	defer workerReporters.mu.Unlock()
+	workerReporters.services = services
+}
+
+// workerPools 已登记服务的 worker 占用与排队指标
+func workerPools() []service.WorkerUtilization {
+	workerReporters.mu.RLock()
+	defer workerReporters.mu.RUnlock()
+	out := make([]service.WorkerUtilization, 0, len(workerReporters.services))
+	for _, s := range workerReporters.services {
+		if s != nil {
+			out = append(out, s.WorkerUtilization())
+		}
+	}
+	return out
+}
+
+// Metrics GET /metrics
+// Prometheus 文本格式：按平台的命令耗时与输出大小直方图、各服务 worker 占用与排队指标；metrics.enable=false 时返回 404
+func Metrics(c *gin.Context) {
+	if cfg := config.Get(); cfg == nil || !cfg.Metrics.Enable {
+		c.Status(http.StatusNotFound)
+		return
+	}
+	c.Header("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	c.Status(http.StatusOK)
+	service.WriteCommandMetrics(c.Writer)
+	workerReporters.mu.RLock()
+	services := workerReporters.services
+	workerReporters.mu.RUnlock()
+	service.WriteWorkerMetrics(c.Writer, services...)
+}
@@ -0,0 +1,241 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/config"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/database"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/model"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/service"
+	"github.com/sshcollectorpro/sshcollectorpro/pkg/logger"
+	"gorm.io/gorm"
+)
+
+// PlaybookHandler 命令集处理器
+type PlaybookHandler struct{}
+
+// NewPlaybookHandler 创建命令集处理器
+func NewPlaybookHandler() *PlaybookHandler {
+	return &PlaybookHandler{}
+}
+
+// PlaybookRequest 命令集创建/更新请求
+type PlaybookRequest struct {
+	Name        string                     `json:"name"`
+	Description string                     `json:"description,omitempty"`
+	Commands    service.CLIList            `json:"commands"`
+	Variants    map[string]service.CLIList `json:"variants,omitempty"` // 平台 -> 命令列表
+}
+
+// PlaybookView 命令集返回结构（命令列表已解码）
+type PlaybookView struct {
+	ID uint `json:"id"`
+	service.Playbook
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// ListPlaybooks GET /api/v1/playbooks
+func (h *PlaybookHandler) ListPlaybooks(c *gin.Context) {
+	var rows []model.Playbook
+	if err := database.GetDB().Order("name ASC").Find(&rows).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Code: "QUERY_FAILED", Message: err.Error()})
+		return
+	}
+	items := make([]PlaybookView, 0, len(rows))
+	for i := range rows {
+		items = append(items, playbookView(&rows[i]))
+	}
+	c.JSON(http.StatusOK, gin.H{"code": "SUCCESS", "message": "获取命令集列表成功", "data": items, "total": len(items)})
+}
+
+// GetPlaybook GET /api/v1/playbooks/:name
+func (h *PlaybookHandler) GetPlaybook(c *gin.Context) {
+	var row model.Playbook
+	if err := database.GetDB().Where("name = ?", c.Param("name")).First(&row).Error; err != nil {
+		c.JSON(http.StatusNotFound, ErrorResponse{Code: "PLAYBOOK_NOT_FOUND", Message: "命令集不存在"})
+		return
+	}
+	c.JSON(http.StatusOK, SuccessResponse{Code: "SUCCESS", Message: "获取命令集成功", Data: playbookView(&row)})
+}
+
+// CreatePlaybook POST /api/v1/playbooks
+func (h *PlaybookHandler) CreatePlaybook(c *gin.Context) {
+	var req PlaybookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Code: "INVALID_PARAMS", Message: "请求参数无效: " + err.Error()})
+		return
+	}
+	pb := req.playbook(strings.TrimSpace(req.Name))
+	if err := pb.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Code: "INVALID_PARAMS", Message: err.Error()})
+		return
+	}
+	db := database.GetDB()
+	var count int64
+	if err := db.Model(&model.Playbook{}).Where("name = ?", pb.Name).Count(&count).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Code: "QUERY_FAILED", Message: err.Error()})
+		return
+	}
+	if count > 0 {
+		c.JSON(http.StatusConflict, ErrorResponse{Code: "PLAYBOOK_EXISTS", Message: "命令集名称已存在"})
+		return
+	}
+	row := model.Playbook{Name: pb.Name, Description: pb.Description}
+	if err := encodePlaybook(&row, pb); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Code: "INVALID_PARAMS", Message: err.Error()})
+		return
+	}
+	if err := database.WithRetry(func(tx *gorm.DB) error { return tx.Create(&row).Error }, 3, 0); err != nil {
+		logger.Error("Failed to create playbook", "name", pb.Name, "error", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Code: "CREATE_FAILED", Message: "创建命令集失败: " + err.Error()})
+		return
+	}
+	logger.Info("Playbook created", "name", row.Name)
+	c.JSON(http.StatusCreated, SuccessResponse{Code: "SUCCESS", Message: "命令集创建成功", Data: playbookView(&row)})
+}
+
+// UpdatePlaybook PUT /api/v1/playbooks/:name（整体替换命令与变体，名称不可修改）
+func (h *PlaybookHandler) UpdatePlaybook(c *gin.Context) {
+	var req PlaybookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Code: "INVALID_PARAMS", Message: "请求参数无效: " + err.Error()})
+		return
+	}
+	db := database.GetDB()
+	var row model.Playbook
+	if err := db.Where("name = ?", c.Param("name")).First(&row).Error; err != nil {
+		c.JSON(http.StatusNotFound, ErrorResponse{Code: "PLAYBOOK_NOT_FOUND", Message: "命令集不存在"})
+		return
+	}
+	pb := req.playbook(row.Name)
+	if err := pb.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Code: "INVALID_PARAMS", Message: err.Error()})
+		return
+	}
+	row.Description = pb.Description
+	if err := encodePlaybook(&row, pb); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Code: "INVALID_PARAMS", Message: err.Error()})
+		return
+	}
+	if err := database.WithRetry(func(tx *gorm.DB) error { return tx.Save(&row).Error }, 3, 0); err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Code: "UPDATE_FAILED", Message: "更新命令集失败: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, SuccessResponse{Code: "SUCCESS", Message: "命令集更新成功", Data: playbookView(&row)})
+}
+
+// DeletePlaybook DELETE /api/v1/playbooks/:name
+func (h *PlaybookHandler) DeletePlaybook(c *gin.Context) {
+	res := database.GetDB().Where("name = ?", c.Param("name")).Delete(&model.Playbook{})
+	if res.Error != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Code: "DELETE_FAILED", Message: res.Error.Error()})
+		return
+	}
+	if res.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, ErrorResponse{Code: "PLAYBOOK_NOT_FOUND", Message: "命令集不存在"})
+		return
+	}
+	c.JSON(http.StatusOK, SuccessResponse{Code: "SUCCESS", Message: "命令集删除成功"})
+}
+
+// playbook 请求转为服务层命令集；变体平台名统一小写
+func (r *PlaybookRequest) playbook(name string) *service.Playbook {
+	pb := &service.Playbook{Name: name, Description: r.Description, Commands: r.Commands}
+	if len(r.Variants) > 0 {
+		pb.Variants = make(map[string]service.CLIList, len(r.Variants))
+		for k, v := range r.Variants {
+			pb.Variants[strings.ToLower(strings.TrimSpace(k))] = v
+		}
+	}
+	return pb
+}
+
+// encodePlaybook 命令与变体以 JSON 存入表字段
+func encodePlaybook(row *model.Playbook, pb *service.Playbook) error {
+	cmds := pb.Commands
+	if cmds == nil {
+		cmds = service.CLIList{}
+	}
+	b, err := json.Marshal(cmds)
+	if err != nil {
+		return err
+	}
+	row.Commands = string(b)
+	row.Variants = ""
+	if len(pb.Variants) > 0 {
+		if b, err = json.Marshal(pb.Variants); err != nil {
+			return err
+		}
+		row.Variants = string(b)
+	}
+	return nil
+}
+
+// decodePlaybook 表记录解码为服务层命令集
+func decodePlaybook(row *model.Playbook) (*service.Playbook, error) {
+	pb := &service.Playbook{Name: row.Name, Description: row.Description}
+	if strings.TrimSpace(row.Commands) != "" {
+		if err := json.Unmarshal([]byte(row.Commands), &pb.Commands); err != nil {
+			return nil, fmt.Errorf("decode playbook %s commands: %w", row.Name, err)
+		}
+	}
+	if strings.TrimSpace(row.Variants) != "" {
+		if err := json.Unmarshal([]byte(row.Variants), &pb.Variants); err != nil {
+			return nil, fmt.Errorf("decode playbook %s variants: %w", row.Name, err)
+		}
+	}
+	return pb, nil
+}
+
+func playbookView(row *model.Playbook) PlaybookView {
+	v := PlaybookView{ID: row.ID, CreatedAt: row.CreatedAt, UpdatedAt: row.UpdatedAt}
+	if pb, err := decodePlaybook(row); err == nil {
+		v.Playbook = *pb
+	} else {
+		logger.Warn("Invalid playbook record", "name", row.Name, "error", err)
+		v.Playbook = service.Playbook{Name: row.Name, Description: row.Description}
+	}
+	return v
+}
+
+// playbookResolver 单次请求内按名称加载命令集，同名命令集只查询一次
+type playbookResolver struct {
+	cache map[string]*service.Playbook
+}
+
+func newPlaybookResolver() *playbookResolver {
+	return &playbookResolver{cache: map[string]*service.Playbook{}}
+}
+
+// expand 未引用命令集时原样返回 cli；否则按设备平台展开命令集并追加 cli 中的额外命令
+func (r *playbookResolver) expand(name, platform string, cli service.CLIList) (service.CLIList, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return cli, nil
+	}
+	pb, ok := r.cache[name]
+	if !ok {
+		var row model.Playbook
+		if err := database.GetDB().Where("name = ?", name).First(&row).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, fmt.Errorf("playbook %s not found", name)
+			}
+			return nil, fmt.Errorf("load playbook %s: %w", name, err)
+		}
+		var err error
+		if pb, err = decodePlaybook(&row); err != nil {
+			return nil, err
+		}
+		r.cache[name] = pb
+	}
+	// 平台字符串按 platform_mappings 归一后匹配剧本平台
+	p, _ := service.NormalizePlatform(config.Get(), platform)
+	return pb.Expand(p, cli), nil
+}
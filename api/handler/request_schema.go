@@ -0,0 +1,285 @@
+package handler
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/config"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/service"
+)
+
+// StrictJSONHeader 请求级严格解码开关（true/false），优先于 server.strict_json 配置
+const StrictJSONHeader = "X-Strict-JSON"
+
+// FieldError 请求体字段错误，Pointer 为 JSON 指针（RFC 6901），如 /devices/0/device_port；空串表示整个请求体
+type FieldError struct {
+	Pointer string `json:"pointer"`
+	Message string `json:"message"`
+}
+
+// requestSchemaError 请求体与接口结构不符（未知字段、类型错误、JSON 语法错误）
+type requestSchemaError struct {
+	fields []FieldError
+}
+
+func (e *requestSchemaError) Error() string {
+	parts := make([]string, 0, len(e.fields))
+	for _, f := range e.fields {
+		if f.Pointer == "" {
+			parts = append(parts, f.Message)
+			continue
+		}
+		parts = append(parts, f.Pointer+": "+f.Message)
+	}
+	return strings.Join(parts, "; ")
+}
+
+func (e *requestSchemaError) Unwrap() error { return service.ErrValidation }
+
+// fieldErrors 请求体解码错误的逐字段明细，供错误响应的 errors 数组使用
+func fieldErrors(err error) []FieldError {
+	var se *requestSchemaError
+	if errors.As(err, &se) {
+		return se.fields
+	}
+	if err == nil {
+		return nil
+	}
+	return []FieldError{{Message: err.Error()}}
+}
+
+// strictJSON 是否拒绝未知字段：请求头 X-Strict-JSON 或查询参数 strict_json 优先，其次 server.strict_json
+func strictJSON(c *gin.Context) bool {
+	for _, v := range []string{c.GetHeader(StrictJSONHeader), c.Query("strict_json")} {
+		if b, err := strconv.ParseBool(strings.TrimSpace(v)); err == nil {
+			return b
+		}
+	}
+	cfg := config.Get()
+	return cfg != nil && cfg.Server.StrictJSON
+}
+
+// decodeRequestBody 解码请求体；strict 时先拒绝未知字段，解码失败时尽量定位到具体字段
+func decodeRequestBody(body []byte, obj interface{}, strict bool) error {
+	if strict {
+		if fields := checkRequestSchema(body, obj, true); len(fields) > 0 {
+			return &requestSchemaError{fields: fields}
+		}
+	}
+	if err := binding.JSON.BindBody(body, obj); err != nil {
+		fields := checkRequestSchema(body, obj, false)
+		if len(fields) == 0 {
+			fields = []FieldError{{Message: err.Error()}}
+		}
+		return &requestSchemaError{fields: fields}
+	}
+	return nil
+}
+
+// checkRequestSchema 按 obj 的结构逐字段检查请求体，返回类型不符（strict 时含未知字段）的 JSON 指针；
+// 旧字段名按兼容层映射视为已知字段，自定义解码的非对象值（如 cli_list 中的字符串）不做检查
+func checkRequestSchema(body []byte, obj interface{}, strict bool) []FieldError {
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil
+	}
+	var out []FieldError
+	walkRequestSchema(v, reflect.TypeOf(obj), "", strict, &out)
+	return out
+}
+
+var (
+	jsonUnmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+	rawMessageType      = reflect.TypeOf(json.RawMessage(nil))
+)
+
+func walkRequestSchema(v interface{}, t reflect.Type, ptr string, strict bool, out *[]FieldError) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if v == nil || t == rawMessageType || t.Kind() == reflect.Interface {
+		return
+	}
+	obj, isObj := v.(map[string]interface{})
+	custom := reflect.PointerTo(t).Implements(jsonUnmarshalerType) || reflect.PointerTo(t).Implements(textUnmarshalerType)
+	if custom && (t.Kind() != reflect.Struct || !isObj) {
+		return
+	}
+
+	mismatch := func(want string) {
+		*out = append(*out, FieldError{Pointer: ptr, Message: fmt.Sprintf("expected %s, got %s", want, jsonKind(v))})
+	}
+	switch t.Kind() {
+	case reflect.Struct:
+		if !isObj {
+			mismatch("object")
+			return
+		}
+		fields := jsonFields(t)
+		for _, k := range sortedKeys(obj) {
+			ft, ok := lookupJSONField(fields, k)
+			if !ok {
+				if strict {
+					*out = append(*out, FieldError{Pointer: ptr + "/" + escapePointer(k), Message: "unknown field"})
+				}
+				continue
+			}
+			walkRequestSchema(obj[k], ft, ptr+"/"+escapePointer(k), strict, out)
+		}
+	case reflect.Map:
+		if !isObj {
+			mismatch("object")
+			return
+		}
+		for _, k := range sortedKeys(obj) {
+			walkRequestSchema(obj[k], t.Elem(), ptr+"/"+escapePointer(k), strict, out)
+		}
+	case reflect.Slice, reflect.Array:
+		if _, ok := v.(string); ok && t.Elem().Kind() == reflect.Uint8 {
+			return // []byte 以 base64 字符串表示
+		}
+		items, ok := v.([]interface{})
+		if !ok {
+			mismatch("array")
+			return
+		}
+		for i, it := range items {
+			walkRequestSchema(it, t.Elem(), ptr+"/"+strconv.Itoa(i), strict, out)
+		}
+	case reflect.String:
+		if _, ok := v.(string); !ok {
+			mismatch("string")
+		}
+	case reflect.Bool:
+		if _, ok := v.(bool); !ok {
+			mismatch("boolean")
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if n, ok := v.(json.Number); !ok {
+			mismatch("integer")
+		} else if _, err := n.Int64(); err != nil {
+			*out = append(*out, FieldError{Pointer: ptr, Message: fmt.Sprintf("expected integer, got %s", n)})
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if n, ok := v.(json.Number); !ok {
+			mismatch("non-negative integer")
+		} else if _, err := strconv.ParseUint(n.String(), 10, 64); err != nil {
+			*out = append(*out, FieldError{Pointer: ptr, Message: fmt.Sprintf("expected non-negative integer, got %s", n)})
+		}
+	case reflect.Float32, reflect.Float64:
+		if _, ok := v.(json.Number); !ok {
+			mismatch("number")
+		}
+	}
+}
+
+// jsonField 结构体字段的 JSON 名与类型；quoted 为 ",string" 选项（数值以字符串传入，不检查类型）
+type jsonField struct {
+	name   string
+	typ    reflect.Type
+	quoted bool
+}
+
+// jsonFields 按 encoding/json 规则展开结构体字段（含匿名嵌入），json:"-" 与未导出字段除外
+func jsonFields(t reflect.Type) []jsonField {
+	var out []jsonField
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		ft := f.Type
+		for ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		if f.Anonymous && name == "" && ft.Kind() == reflect.Struct {
+			out = append(out, jsonFields(ft)...)
+			continue
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		out = append(out, jsonField{name: name, typ: f.Type, quoted: strings.Contains(","+opts+",", ",string,")})
+	}
+	return out
+}
+
+// lookupJSONField 精确匹配优先，其次按 encoding/json 的大小写不敏感匹配；旧字段名映射到现名
+func lookupJSONField(fields []jsonField, key string) (reflect.Type, bool) {
+	find := func(k string) (reflect.Type, bool) {
+		for _, f := range fields {
+			if f.name == k {
+				return f.fieldType(), true
+			}
+		}
+		for _, f := range fields {
+			if strings.EqualFold(f.name, k) {
+				return f.fieldType(), true
+			}
+		}
+		return nil, false
+	}
+	if t, ok := find(key); ok {
+		return t, true
+	}
+	for _, current := range service.LegacyFieldReplacements(key) {
+		if t, ok := find(current); ok {
+			return t, true
+		}
+	}
+	return nil, false
+}
+
+func (f jsonField) fieldType() reflect.Type {
+	if f.quoted {
+		return reflect.TypeOf((*interface{})(nil)).Elem()
+	}
+	return f.typ
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// escapePointer 按 RFC 6901 转义指针片段
+func escapePointer(s string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(s)
+}
+
+func jsonKind(v interface{}) string {
+	switch x := v.(type) {
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case json.Number:
+		return "number " + x.String()
+	}
+	return "null"
+}
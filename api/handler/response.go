@@ -0,0 +1,39 @@
+package handler
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/config"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/service"
+)
+
+// outputEncoding 请求体未指定时回退查询参数 output_encoding
+func outputEncoding(c *gin.Context, fromBody string) string {
+	if v := strings.TrimSpace(fromBody); v != "" {
+		return v
+	}
+	return strings.TrimSpace(c.Query("output_encoding"))
+}
+
+// writeOutputJSON 按 output_encoding 处理控制字符后输出 JSON（关闭 HTML 转义以保留原始设备输出）
+func writeOutputJSON(c *gin.Context, status int, encoding string, payload interface{}) {
+	c.Header("Content-Type", "application/json")
+	c.Status(status)
+	enc := json.NewEncoder(c.Writer)
+	enc.SetEscapeHTML(false)
+	_ = enc.Encode(service.SanitizeOutput(payload, encoding))
+}
+
+// responseOutput 批量响应原始输出控制：请求体优先，其次查询参数 raw_output_mode、max_output_kb，最后为 batch_response 配置
+func responseOutput(c *gin.Context, mode string, maxKB int) (service.ResponseOutput, error) {
+	if strings.TrimSpace(mode) == "" {
+		mode = c.Query("raw_output_mode")
+	}
+	if maxKB <= 0 {
+		maxKB, _ = strconv.Atoi(c.Query("max_output_kb"))
+	}
+	return service.ResolveResponseOutput(config.Get(), mode, maxKB)
+}
@@ -0,0 +1,491 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/config"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/database"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/model"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/service"
+	"github.com/sshcollectorpro/sshcollectorpro/pkg/logger"
+	"gorm.io/gorm"
+)
+
+// activeRunbookRuns 本进程内正在执行的运行手册，避免同一执行记录并发续跑
+var activeRunbookRuns sync.Map
+
+// activeIDs 本进程内正在执行的记录编号
+func activeIDs(m *sync.Map) []string {
+	var ids []string
+	m.Range(func(k, _ interface{}) bool {
+		ids = append(ids, k.(string))
+		return true
+	})
+	return ids
+}
+
+// RunbookHandler 运行手册管理与执行
+type RunbookHandler struct {
+	svc *service.RunbookService
+}
+
+// NewRunbookHandler 创建运行手册处理器
+func NewRunbookHandler(svc *service.RunbookService) *RunbookHandler {
+	return &RunbookHandler{svc: svc}
+}
+
+// RunbookRequest 运行手册创建/更新请求
+type RunbookRequest struct {
+	Name        string                `json:"name"`
+	Description string                `json:"description,omitempty"`
+	Steps       []service.RunbookStep `json:"steps"`
+}
+
+// RunbookView 运行手册返回结构（步骤已解码）
+type RunbookView struct {
+	ID          uint                  `json:"id"`
+	Name        string                `json:"name"`
+	Description string                `json:"description,omitempty"`
+	Steps       []service.RunbookStep `json:"steps"`
+	CreatedAt   time.Time             `json:"created_at"`
+	UpdatedAt   time.Time             `json:"updated_at"`
+}
+
+// StartRunbookRequest 执行参数：设备列表为各步骤共用的目标
+type StartRunbookRequest struct {
+	RunID   string                  `json:"run_id,omitempty"` // 为空自动生成
+	Devices []service.RunbookDevice `json:"devices"`
+}
+
+// RunbookRunView 执行记录返回结构
+type RunbookRunView struct {
+	model.RunbookRun
+	Resumable bool                        `json:"resumable"`
+	Results   []service.RunbookStepResult `json:"results,omitempty"`
+}
+
+// ListRunbooks GET /api/v1/runbooks
+func (h *RunbookHandler) ListRunbooks(c *gin.Context) {
+	var rows []model.Runbook
+	if err := database.GetDB().Order("name ASC").Find(&rows).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Code: "QUERY_FAILED", Message: err.Error()})
+		return
+	}
+	items := make([]RunbookView, 0, len(rows))
+	for i := range rows {
+		items = append(items, runbookView(&rows[i]))
+	}
+	c.JSON(http.StatusOK, gin.H{"code": "SUCCESS", "message": "获取运行手册列表成功", "data": items, "total": len(items)})
+}
+
+// GetRunbook GET /api/v1/runbooks/:name
+func (h *RunbookHandler) GetRunbook(c *gin.Context) {
+	var row model.Runbook
+	if err := database.GetDB().Where("name = ?", c.Param("name")).First(&row).Error; err != nil {
+		c.JSON(http.StatusNotFound, ErrorResponse{Code: "RUNBOOK_NOT_FOUND", Message: "运行手册不存在"})
+		return
+	}
+	c.JSON(http.StatusOK, SuccessResponse{Code: "SUCCESS", Message: "获取运行手册成功", Data: runbookView(&row)})
+}
+
+// CreateRunbook POST /api/v1/runbooks
+func (h *RunbookHandler) CreateRunbook(c *gin.Context) {
+	var req RunbookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Code: "INVALID_PARAMS", Message: "请求参数无效: " + err.Error()})
+		return
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Code: "INVALID_PARAMS", Message: "name is required"})
+		return
+	}
+	row := model.Runbook{Name: req.Name, Description: req.Description}
+	if !encodeRunbook(c, &row, req.Steps) {
+		return
+	}
+	db := database.GetDB()
+	var count int64
+	if err := db.Model(&model.Runbook{}).Where("name = ?", row.Name).Count(&count).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Code: "QUERY_FAILED", Message: err.Error()})
+		return
+	}
+	if count > 0 {
+		c.JSON(http.StatusConflict, ErrorResponse{Code: "RUNBOOK_EXISTS", Message: "运行手册名称已存在"})
+		return
+	}
+	if err := database.WithRetry(func(tx *gorm.DB) error { return tx.Create(&row).Error }, 3, 0); err != nil {
+		logger.Error("Failed to create runbook", "name", row.Name, "error", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Code: "CREATE_FAILED", Message: "创建运行手册失败: " + err.Error()})
+		return
+	}
+	logger.Info("Runbook created", "name", row.Name, "steps", len(req.Steps))
+	c.JSON(http.StatusCreated, SuccessResponse{Code: "SUCCESS", Message: "运行手册创建成功", Data: runbookView(&row)})
+}
+
+// UpdateRunbook PUT /api/v1/runbooks/:name（整体替换步骤，名称不可修改；已启动的执行沿用原步骤）
+func (h *RunbookHandler) UpdateRunbook(c *gin.Context) {
+	var req RunbookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Code: "INVALID_PARAMS", Message: "请求参数无效: " + err.Error()})
+		return
+	}
+	var row model.Runbook
+	if err := database.GetDB().Where("name = ?", c.Param("name")).First(&row).Error; err != nil {
+		c.JSON(http.StatusNotFound, ErrorResponse{Code: "RUNBOOK_NOT_FOUND", Message: "运行手册不存在"})
+		return
+	}
+	row.Description = req.Description
+	if !encodeRunbook(c, &row, req.Steps) {
+		return
+	}
+	if err := database.WithRetry(func(tx *gorm.DB) error { return tx.Save(&row).Error }, 3, 0); err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Code: "UPDATE_FAILED", Message: "更新运行手册失败: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, SuccessResponse{Code: "SUCCESS", Message: "运行手册更新成功", Data: runbookView(&row)})
+}
+
+// DeleteRunbook DELETE /api/v1/runbooks/:name（执行记录保留）
+func (h *RunbookHandler) DeleteRunbook(c *gin.Context) {
+	res := database.GetDB().Where("name = ?", c.Param("name")).Delete(&model.Runbook{})
+	if res.Error != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Code: "DELETE_FAILED", Message: res.Error.Error()})
+		return
+	}
+	if res.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, ErrorResponse{Code: "RUNBOOK_NOT_FOUND", Message: "运行手册不存在"})
+		return
+	}
+	c.JSON(http.StatusOK, SuccessResponse{Code: "SUCCESS", Message: "运行手册删除成功"})
+}
+
+// StartRunbook POST /api/v1/runbooks/:name/runs
+// 异步执行，返回执行记录；进度与各步骤结果经 GET /api/v1/runbook-runs/:id 查询
+func (h *RunbookHandler) StartRunbook(c *gin.Context) {
+	var rb model.Runbook
+	if err := database.GetDB().Where("name = ?", c.Param("name")).First(&rb).Error; err != nil {
+		c.JSON(http.StatusNotFound, ErrorResponse{Code: "RUNBOOK_NOT_FOUND", Message: "运行手册不存在"})
+		return
+	}
+	steps, err := decodeRunbookSteps(rb.Steps)
+	if err == nil {
+		err = service.ValidateRunbookSteps(steps)
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Code: "RUNBOOK_INVALID", Message: fmt.Sprintf("运行手册 %s 步骤无效: %v", rb.Name, err)})
+		return
+	}
+	var req StartRunbookRequest
+	if err := bindJSON(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Code: "INVALID_PARAMS", Message: "请求参数无效: " + err.Error(), Errors: fieldErrors(err)})
+		return
+	}
+	if len(req.Devices) == 0 {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Code: "INVALID_PARAMS", Message: "devices is empty"})
+		return
+	}
+	if len(req.Devices) > 200 {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Code: "TOO_MANY_DEVICES", Message: "批量设备数量不能超过200"})
+		return
+	}
+	for i := range req.Devices {
+		d := &req.Devices[i]
+		if strings.TrimSpace(d.DeviceIP) == "" {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Code: "INVALID_PARAMS", Message: fmt.Sprintf("devices[%d]: device_ip is required", i)})
+			return
+		}
+		if err := resolveCredential(d.CredentialID, &d.UserName, &d.Password, &d.EnablePassword); err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Code: "CREDENTIAL_INVALID", Message: err.Error()})
+			return
+		}
+	}
+	id := strings.TrimSpace(req.RunID)
+	if id == "" {
+		id = uuid.NewString()
+	}
+	// 设备凭据加密保存以便续跑；未配置 vault 主密钥时执行记录不可续跑
+	enc, err := service.EncryptBatchRequest(config.Get(), req.Devices)
+	if err != nil && !errors.Is(err, service.ErrVaultNotConfigured) {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Code: "ENCRYPT_FAILED", Message: err.Error()})
+		return
+	}
+	results := service.NewRunbookResults(steps)
+	stepsJSON, _ := json.Marshal(steps)
+	resultsJSON, _ := json.Marshal(results)
+	run := model.RunbookRun{
+		ID:          id,
+		RunbookID:   rb.ID,
+		RunbookName: rb.Name,
+		Status:      service.RunbookRunRunning,
+		Steps:       string(stepsJSON),
+		Results:     string(resultsJSON),
+		Request:     enc,
+		Devices:     len(req.Devices),
+	}
+	if _, loaded := activeRunbookRuns.LoadOrStore(id, struct{}{}); loaded {
+		c.JSON(http.StatusConflict, ErrorResponse{Code: "RUNBOOK_RUN_EXISTS", Message: "执行记录已存在"})
+		return
+	}
+	err = database.WithRetry(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Model(&model.RunbookRun{}).Where("id = ?", id).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return errRunbookRunExists
+		}
+		return tx.Create(&run).Error
+	}, 3, 0)
+	if err != nil {
+		activeRunbookRuns.Delete(id)
+		if errors.Is(err, errRunbookRunExists) {
+			c.JSON(http.StatusConflict, ErrorResponse{Code: "RUNBOOK_RUN_EXISTS", Message: "执行记录已存在"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Code: "CREATE_FAILED", Message: "创建执行记录失败: " + err.Error()})
+		return
+	}
+	logger.Info("Runbook run started", "run_id", id, "runbook", rb.Name, "steps", len(steps), "devices", len(req.Devices))
+	go h.execute(id, rb.Name, steps, req.Devices, results)
+	c.JSON(http.StatusAccepted, SuccessResponse{Code: "SUCCESS", Message: "运行手册已开始执行", Data: runbookRunView(&run, true)})
+}
+
+// ListRunbookRuns GET /api/v1/runbook-runs?runbook=&status=
+func (h *RunbookHandler) ListRunbookRuns(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	size, _ := strconv.Atoi(c.DefaultQuery("size", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if size < 1 || size > 200 {
+		size = 20
+	}
+	query := database.GetDB().Model(&model.RunbookRun{})
+	if v := strings.TrimSpace(c.Query("runbook")); v != "" {
+		query = query.Where("runbook_name = ?", v)
+	}
+	if v := strings.TrimSpace(c.Query("status")); v != "" {
+		query = query.Where("status = ?", v)
+	}
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Code: "COUNT_FAILED", Message: "获取执行记录总数失败: " + err.Error()})
+		return
+	}
+	var rows []model.RunbookRun
+	if err := query.Omit("steps", "results").Order("created_at DESC").Offset((page - 1) * size).Limit(size).Find(&rows).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Code: "LIST_FAILED", Message: "获取执行记录失败: " + err.Error()})
+		return
+	}
+	items := make([]RunbookRunView, 0, len(rows))
+	for i := range rows {
+		items = append(items, runbookRunView(&rows[i], false))
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"code":    "SUCCESS",
+		"message": "获取执行记录成功",
+		"data": gin.H{
+			"runs": items,
+			"pagination": gin.H{
+				"page":  page,
+				"size":  size,
+				"total": total,
+				"pages": (total + int64(size) - 1) / int64(size),
+			},
+		},
+	})
+}
+
+// GetRunbookRun GET /api/v1/runbook-runs/:id（含各步骤结果）
+func (h *RunbookHandler) GetRunbookRun(c *gin.Context) {
+	var run model.RunbookRun
+	if err := database.GetDB().Where("id = ?", c.Param("id")).First(&run).Error; err != nil {
+		c.JSON(http.StatusNotFound, ErrorResponse{Code: "RUNBOOK_RUN_NOT_FOUND", Message: "执行记录不存在"})
+		return
+	}
+	c.JSON(http.StatusOK, SuccessResponse{Code: "SUCCESS", Message: "获取执行记录成功", Data: runbookRunView(&run, true)})
+}
+
+// ResumeRunbookRun POST /api/v1/runbook-runs/:id/resume
+// 已结束的步骤沿用原结果，从首个未结束的步骤继续执行（中断时正在执行的步骤整体重跑）
+func (h *RunbookHandler) ResumeRunbookRun(c *gin.Context) {
+	var run model.RunbookRun
+	if err := database.GetDB().Where("id = ?", c.Param("id")).First(&run).Error; err != nil {
+		c.JSON(http.StatusNotFound, ErrorResponse{Code: "RUNBOOK_RUN_NOT_FOUND", Message: "执行记录不存在"})
+		return
+	}
+	switch run.Status {
+	case service.RunbookRunCompleted:
+		c.JSON(http.StatusConflict, ErrorResponse{Code: "RUNBOOK_RUN_COMPLETED", Message: "执行记录已完成"})
+		return
+	case service.RunbookRunRunning:
+		c.JSON(http.StatusConflict, ErrorResponse{Code: "RUNBOOK_RUN_RUNNING", Message: "运行手册正在执行"})
+		return
+	}
+	if run.Request == "" {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Code: "RUNBOOK_RUN_NOT_RESUMABLE", Message: "执行记录未保存设备凭据（未配置 vault 主密钥），无法续跑"})
+		return
+	}
+	var devices []service.RunbookDevice
+	if err := service.DecryptBatchRequest(run.Request, &devices); err != nil {
+		if errors.Is(err, service.ErrVaultNotConfigured) {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Code: "VAULT_NOT_CONFIGURED", Message: err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Code: "RESUME_FAILED", Message: "续跑失败: " + err.Error()})
+		return
+	}
+	steps, err := decodeRunbookSteps(run.Steps)
+	var results []service.RunbookStepResult
+	if err == nil {
+		err = json.Unmarshal([]byte(run.Results), &results)
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Code: "RESUME_FAILED", Message: "执行记录损坏: " + err.Error()})
+		return
+	}
+	if _, loaded := activeRunbookRuns.LoadOrStore(run.ID, struct{}{}); loaded {
+		c.JSON(http.StatusConflict, ErrorResponse{Code: "RUNBOOK_RUN_RUNNING", Message: "运行手册正在执行"})
+		return
+	}
+	err = database.WithRetry(func(tx *gorm.DB) error {
+		return tx.Model(&model.RunbookRun{}).Where("id = ?", run.ID).Updates(map[string]interface{}{
+			"status":     service.RunbookRunRunning,
+			"resumes":    gorm.Expr("resumes + 1"),
+			"updated_at": time.Now(),
+		}).Error
+	}, 3, 0)
+	if err != nil {
+		activeRunbookRuns.Delete(run.ID)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Code: "RESUME_FAILED", Message: "续跑失败: " + err.Error()})
+		return
+	}
+	remaining := 0
+	for _, r := range results {
+		if r.Status == service.RunbookPending || r.Status == service.RunbookRunning {
+			remaining++
+		}
+	}
+	logger.Info("Runbook run resumed", "run_id", run.ID, "runbook", run.RunbookName, "remaining_steps", remaining)
+	go h.execute(run.ID, run.RunbookName, steps, devices, results)
+	c.JSON(http.StatusAccepted, SuccessResponse{Code: "SUCCESS", Message: "运行手册已开始续跑", Data: gin.H{"id": run.ID, "remaining_steps": remaining}})
+}
+
+// RecoverRunbookRuns 将未结束且不在本进程执行的记录标记为 interrupted；启动时（须在接收请求前）与接任 leader 时调用
+func RecoverRunbookRuns() {
+	db := database.GetDB()
+	if db == nil {
+		return
+	}
+	q := db.Model(&model.RunbookRun{}).Where("status = ?", service.RunbookRunRunning)
+	if active := activeIDs(&activeRunbookRuns); len(active) > 0 {
+		q = q.Where("id NOT IN ?", active)
+	}
+	res := q.Updates(map[string]interface{}{"status": service.RunbookRunInterrupted, "updated_at": time.Now()})
+	if res.Error != nil {
+		logger.Warn("Failed to mark interrupted runbook runs", "error", res.Error)
+		return
+	}
+	if res.RowsAffected > 0 {
+		logger.Info("Interrupted runbook runs found", "count", res.RowsAffected)
+	}
+}
+
+var errRunbookRunExists = errors.New("runbook run already exists")
+
+// execute 后台执行并逐步骤保存结果；全部步骤结束后标记 completed 并清除设备凭据
+func (h *RunbookHandler) execute(id, name string, steps []service.RunbookStep, devices []service.RunbookDevice, results []service.RunbookStepResult) {
+	defer activeRunbookRuns.Delete(id)
+	results = h.svc.Execute(context.Background(), id, name, steps, devices, results, func(r []service.RunbookStepResult) {
+		saveRunbookRun(id, r, nil)
+	})
+	status := service.RunbookRunStatus(results)
+	updates := map[string]interface{}{"status": status}
+	if status == service.RunbookRunCompleted {
+		now := time.Now()
+		updates["finished_at"] = &now
+		updates["request"] = ""
+	}
+	saveRunbookRun(id, results, updates)
+	logger.Info("Runbook run finished", "run_id", id, "runbook", name, "status", status)
+}
+
+// saveRunbookRun 保存步骤结果及附加字段
+func saveRunbookRun(id string, results []service.RunbookStepResult, updates map[string]interface{}) {
+	b, err := json.Marshal(results)
+	if err != nil {
+		logger.Warn("Failed to encode runbook results", "run_id", id, "error", err)
+		return
+	}
+	if updates == nil {
+		updates = map[string]interface{}{}
+	}
+	updates["results"] = string(b)
+	updates["updated_at"] = time.Now()
+	err = database.WithRetry(func(tx *gorm.DB) error {
+		return tx.Model(&model.RunbookRun{}).Where("id = ?", id).Updates(updates).Error
+	}, 3, 0)
+	if err != nil {
+		logger.Warn("Failed to save runbook run", "run_id", id, "error", err)
+	}
+}
+
+// encodeRunbook 校验并以 JSON 存入步骤；失败时写入 400 响应
+func encodeRunbook(c *gin.Context, row *model.Runbook, steps []service.RunbookStep) bool {
+	if err := service.ValidateRunbookSteps(steps); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Code: "INVALID_PARAMS", Message: err.Error()})
+		return false
+	}
+	b, err := json.Marshal(steps)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Code: "INVALID_PARAMS", Message: err.Error()})
+		return false
+	}
+	row.Steps = string(b)
+	return true
+}
+
+func decodeRunbookSteps(raw string) ([]service.RunbookStep, error) {
+	var steps []service.RunbookStep
+	if strings.TrimSpace(raw) == "" {
+		return steps, nil
+	}
+	if err := json.Unmarshal([]byte(raw), &steps); err != nil {
+		return nil, fmt.Errorf("decode runbook steps: %w", err)
+	}
+	return steps, nil
+}
+
+func runbookView(row *model.Runbook) RunbookView {
+	v := RunbookView{ID: row.ID, Name: row.Name, Description: row.Description, CreatedAt: row.CreatedAt, UpdatedAt: row.UpdatedAt}
+	steps, err := decodeRunbookSteps(row.Steps)
+	if err != nil {
+		logger.Warn("Invalid runbook record", "name", row.Name, "error", err)
+	}
+	v.Steps = steps
+	if v.Steps == nil {
+		v.Steps = []service.RunbookStep{}
+	}
+	return v
+}
+
+func runbookRunView(run *model.RunbookRun, withResults bool) RunbookRunView {
+	v := RunbookRunView{RunbookRun: *run, Resumable: run.Request != "" && run.Status != service.RunbookRunCompleted}
+	if withResults && run.Results != "" {
+		if err := json.Unmarshal([]byte(run.Results), &v.Results); err != nil {
+			logger.Warn("Invalid runbook run results", "run_id", run.ID, "error", err)
+		}
+	}
+	return v
+}
@@ -0,0 +1,114 @@
+package handler
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sshcollectorpro/sshcollectorpro/pkg/logger"
+	"github.com/sshcollectorpro/sshcollectorpro/simulate"
+)
+
+// simulateConfigPath 模拟服务加载与热更新的配置文件
+var simulateConfigPath = filepath.Join("simulate", "simulate.yaml")
+
+// ExportBundle GET /api/v1/simulate/bundles/:namespace?hostkey=true
+// 导出命名空间的配置片段、设备回显文件与 SQLite 回显为 tar.gz，hostkey=true 时附带 host key
+func (h *SimulateConfigHandler) ExportBundle(c *gin.Context) {
+	ns := c.Param("namespace")
+	cfg, err := simulate.LoadConfig(simulateConfigPath)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"code": "READ_FAILED", "message": "读取模拟配置失败: " + err.Error()})
+		return
+	}
+	withKey, _ := strconv.ParseBool(c.Query("hostkey"))
+	// 先写入内存，出错时仍可返回 JSON 错误
+	var buf bytes.Buffer
+	if err := simulate.ExportBundle(&buf, cfg, ns, withKey); err != nil {
+		if errors.Is(err, simulate.ErrNamespaceNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"code": "NAMESPACE_NOT_FOUND", "message": "命名空间不存在: " + ns})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"code": "EXPORT_FAILED", "message": "导出失败: " + err.Error()})
+		return
+	}
+	name := fmt.Sprintf("simulate-%s-%s.tar.gz", ns, time.Now().Format("20060102150405"))
+	c.Header("Content-Disposition", `attachment; filename="`+name+`"`)
+	c.Data(http.StatusOK, "application/gzip", buf.Bytes())
+}
+
+// ImportBundle POST /api/v1/simulate/bundles
+// 请求体为 tar.gz（或 multipart 字段 file）；查询参数：namespace 目标命名空间、port 目标端口、
+// device_map 设备改名（old=new,old2=new2）、overwrite 覆盖已存在配置、hostkey 使用包内 host key
+// 导入后合并写入 simulate.yaml，模拟服务运行中时立即热更新
+func (h *SimulateConfigHandler) ImportBundle(c *gin.Context) {
+	opts := simulate.ImportOptions{Namespace: strings.TrimSpace(c.Query("namespace")), DeviceMap: map[string]string{}}
+	if v := strings.TrimSpace(c.Query("port")); v != "" {
+		port, err := strconv.Atoi(v)
+		if err != nil || port <= 0 || port > 65535 {
+			c.JSON(http.StatusBadRequest, gin.H{"code": "INVALID_PARAMS", "message": "port 无效: " + v})
+			return
+		}
+		opts.Port = port
+	}
+	for _, pair := range strings.Split(c.Query("device_map"), ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		from, to, ok := strings.Cut(pair, "=")
+		if !ok || strings.TrimSpace(from) == "" || strings.TrimSpace(to) == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"code": "INVALID_PARAMS", "message": "device_map 格式应为 old=new,old2=new2"})
+			return
+		}
+		opts.DeviceMap[strings.TrimSpace(from)] = strings.TrimSpace(to)
+	}
+	opts.Overwrite, _ = strconv.ParseBool(c.Query("overwrite"))
+	opts.HostKey, _ = strconv.ParseBool(c.Query("hostkey"))
+
+	var body io.Reader = c.Request.Body
+	if strings.HasPrefix(c.ContentType(), "multipart/") {
+		fh, err := c.FormFile("file")
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"code": "INVALID_PARAMS", "message": "缺少上传文件 file: " + err.Error()})
+			return
+		}
+		f, err := fh.Open()
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"code": "INVALID_PARAMS", "message": "读取上传文件失败: " + err.Error()})
+			return
+		}
+		defer f.Close()
+		body = f
+	}
+
+	res, err := simulate.ImportBundle(body, simulateConfigPath, opts)
+	switch {
+	case errors.Is(err, simulate.ErrBundleInvalid):
+		c.JSON(http.StatusBadRequest, gin.H{"code": "INVALID_BUNDLE", "message": err.Error()})
+		return
+	case errors.Is(err, simulate.ErrBundleConflict):
+		c.JSON(http.StatusConflict, gin.H{"code": "BUNDLE_CONFLICT", "message": err.Error()})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"code": "IMPORT_FAILED", "message": "导入失败: " + err.Error()})
+		return
+	}
+	reloaded := false
+	if m := simulate.Current(); m != nil {
+		if sc, err := simulate.LoadConfig(simulateConfigPath); err != nil {
+			logger.Warn("Simulate: reload after bundle import failed", "error", err)
+		} else if err := m.Reload(sc); err != nil {
+			logger.Warn("Simulate: reload after bundle import failed", "error", err)
+		} else {
+			reloaded = true
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{"code": "SUCCESS", "message": "导入成功", "data": gin.H{"result": res, "reloaded": reloaded}})
+}
@@ -1,14 +1,17 @@
 package handler
 
 import (
+	"encoding/json"
 	"net/http"
 	"os"
 	"path/filepath"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/sshcollectorpro/sshcollectorpro/pkg/logger"
+	"github.com/sshcollectorpro/sshcollectorpro/simulate"
 	// 新增：数据库与模型
 	"github.com/sshcollectorpro/sshcollectorpro/internal/database"
 	"github.com/sshcollectorpro/sshcollectorpro/internal/model"
@@ -21,11 +24,23 @@ type SimulateConfigHandler struct{}
 func NewSimulateConfigHandler() *SimulateConfigHandler { return &SimulateConfigHandler{} }
 
 // 命名空间配置
-// 表结构：name(string, unique), port(int), idle_seconds(int), max_conn(int)
+// 表结构：name(string, unique), port(int), idle_seconds(int), max_conn(int), latency_ms/jitter_ms(int), drop_rate(float)，
+// server_version/banner 与 kex/cipher/mac/host key 算法列表
 type NamespaceConf struct {
-	Port        int `yaml:"port" json:"port"`
-	IdleSeconds int `yaml:"idle_seconds" json:"idle_seconds"`
-	MaxConn     int `yaml:"max_conn" json:"max_conn"`
+	Port        int     `yaml:"port" json:"port"`
+	IdleSeconds int     `yaml:"idle_seconds" json:"idle_seconds"`
+	MaxConn     int     `yaml:"max_conn" json:"max_conn"`
+	LatencyMS   int     `yaml:"latency_ms,omitempty" json:"latency_ms,omitempty"`
+	JitterMS    int     `yaml:"jitter_ms,omitempty" json:"jitter_ms,omitempty"`
+	DropRate    float64 `yaml:"drop_rate,omitempty" json:"drop_rate,omitempty"`
+	TelnetPort  int     `yaml:"telnet_port,omitempty" json:"telnet_port,omitempty"`
+	// 握手参数
+	ServerVersion     string   `yaml:"server_version,omitempty" json:"server_version,omitempty"`
+	Banner            string   `yaml:"banner,omitempty" json:"banner,omitempty"`
+	KeyExchanges      []string `yaml:"key_exchanges,omitempty" json:"key_exchanges,omitempty"`
+	Ciphers           []string `yaml:"ciphers,omitempty" json:"ciphers,omitempty"`
+	MACs              []string `yaml:"macs,omitempty" json:"macs,omitempty"`
+	HostKeyAlgorithms []string `yaml:"host_key_algorithms,omitempty" json:"host_key_algorithms,omitempty"`
 }
 
 type DeviceTypeConf struct {
@@ -33,11 +48,23 @@ type DeviceTypeConf struct {
 	EnableModeRequired bool   `yaml:"enable_mode_required" json:"enable_mode_required"`
 	EnableModeSuffixe  string `yaml:"enable_mode_suffixe,omitempty" json:"enable_mode_suffixe,omitempty"`
 	ConfigModeSuffixe  string `yaml:"config_mode_suffixe,omitempty" json:"config_mode_suffixe,omitempty"`
+	// 分页与确认提示
+	PageLines             int                            `yaml:"page_lines,omitempty" json:"page_lines,omitempty"`
+	MorePrompt            string                         `yaml:"more_prompt,omitempty" json:"more_prompt,omitempty"`
+	PagingDisableCommands []string                       `yaml:"paging_disable_commands,omitempty" json:"paging_disable_commands,omitempty"`
+	ConfirmPrompts        []simulate.ConfirmPromptConfig `yaml:"confirm_prompts,omitempty" json:"confirm_prompts,omitempty"`
 }
 
 // 新增：模拟设备名称到类型的映射
 type DeviceNameConf struct {
 	DeviceType string `yaml:"device_type" json:"device_type"`
+	// 登录认证与故障模拟
+	Password       string   `yaml:"password,omitempty" json:"password,omitempty"`
+	AuthorizedKeys []string `yaml:"authorized_keys,omitempty" json:"authorized_keys,omitempty"`
+	MaxAuthTries   int      `yaml:"max_auth_tries,omitempty" json:"max_auth_tries,omitempty"`
+	AuthFailure    string   `yaml:"auth_failure,omitempty" json:"auth_failure,omitempty"`
+	AuthDelayMS    int      `yaml:"auth_delay_ms,omitempty" json:"auth_delay_ms,omitempty"`
+	Banner         string   `yaml:"banner,omitempty" json:"banner,omitempty"`
 }
 
 type SimulateConfig struct {
@@ -69,24 +96,46 @@ func (h *SimulateConfigHandler) GetSimulateConfig(c *gin.Context) {
 					"port": n.Port,
 					"idle_seconds": n.IdleSeconds,
 					"max_conn": n.MaxConn,
+					"latency_ms": n.LatencyMS,
+					"jitter_ms": n.JitterMS,
+					"drop_rate": n.DropRate,
+					"telnet_port": n.TelnetPort,
+					"server_version": n.ServerVersion,
+					"banner": n.Banner,
+					"key_exchanges": splitKeyLines(n.KeyExchanges),
+					"ciphers": splitKeyLines(n.Ciphers),
+					"macs": splitKeyLines(n.MACs),
+					"host_key_algorithms": splitKeyLines(n.HostKeyAlgorithms),
 				})
 			}
 			if !defaultPresent {
 				namespaces = append(namespaces, gin.H{"name": "default", "port": 22001, "idle_seconds": 180, "max_conn": 5})
 			}
 			for _, d := range dtRows {
+				var confirms []simulate.ConfirmPromptConfig
+				_ = json.Unmarshal([]byte(d.ConfirmPrompts), &confirms)
 				deviceTypes = append(deviceTypes, gin.H{
 					"type": d.Type,
 					"prompt_suffixe": d.PromptSuffixe,
 					"enable_mode_required": d.EnableModeRequired,
 					"enable_mode_suffixe": d.EnableModeSuffixe,
 					"config_mode_suffixe": d.ConfigModeSuffixe,
+					"page_lines": d.PageLines,
+					"more_prompt": d.MorePrompt,
+					"paging_disable_commands": splitKeyLines(d.PagingDisableCommands),
+					"confirm_prompts": confirms,
 				})
 			}
 			for _, dn := range dnRows {
 				deviceNames = append(deviceNames, gin.H{
 					"name": dn.Name,
 					"device_type": dn.DeviceType,
+					"password": dn.Password,
+					"authorized_keys": splitKeyLines(dn.AuthorizedKeys),
+					"max_auth_tries": dn.MaxAuthTries,
+					"auth_failure": dn.AuthFailure,
+					"auth_delay_ms": dn.AuthDelayMS,
+					"banner": dn.Banner,
 				})
 			}
 			c.JSON(http.StatusOK, gin.H{"code": "SUCCESS", "message": "OK", "data": gin.H{"namespaces": namespaces, "device_types": deviceTypes, "device_names": deviceNames}})
@@ -120,28 +169,48 @@ func (h *SimulateConfigHandler) GetSimulateConfig(c *gin.Context) {
 	namespaces := make([]gin.H, 0, len(sc.Namespace))
 	for name, n := range sc.Namespace {
 		namespaces = append(namespaces, gin.H{
-			"name":         name,
-			"port":         n.Port,
-			"idle_seconds": n.IdleSeconds,
-			"max_conn":     n.MaxConn,
+			"name":                name,
+			"port":                n.Port,
+			"idle_seconds":        n.IdleSeconds,
+			"max_conn":            n.MaxConn,
+			"latency_ms":          n.LatencyMS,
+			"jitter_ms":           n.JitterMS,
+			"drop_rate":           n.DropRate,
+			"telnet_port":         n.TelnetPort,
+			"server_version":      n.ServerVersion,
+			"banner":              n.Banner,
+			"key_exchanges":       n.KeyExchanges,
+			"ciphers":             n.Ciphers,
+			"macs":                n.MACs,
+			"host_key_algorithms": n.HostKeyAlgorithms,
 		})
 	}
 	deviceTypes := make([]gin.H, 0, len(sc.DeviceType))
 	for typ, d := range sc.DeviceType {
 		deviceTypes = append(deviceTypes, gin.H{
-			"type":                 typ,
-			"prompt_suffixe":       d.PromptSuffixe,
-			"enable_mode_required": d.EnableModeRequired,
-			"enable_mode_suffixe":  d.EnableModeSuffixe,
-			"config_mode_suffixe":  d.ConfigModeSuffixe,
+			"type":                    typ,
+			"prompt_suffixe":          d.PromptSuffixe,
+			"enable_mode_required":    d.EnableModeRequired,
+			"enable_mode_suffixe":     d.EnableModeSuffixe,
+			"config_mode_suffixe":     d.ConfigModeSuffixe,
+			"page_lines":              d.PageLines,
+			"more_prompt":             d.MorePrompt,
+			"paging_disable_commands": d.PagingDisableCommands,
+			"confirm_prompts":         d.ConfirmPrompts,
 		})
 	}
 	// 新增：设备名称映射数组
 	deviceNames := make([]gin.H, 0, len(sc.DeviceName))
 	for name, dn := range sc.DeviceName {
 		deviceNames = append(deviceNames, gin.H{
-			"name":        name,
-			"device_type": dn.DeviceType,
+			"name":            name,
+			"device_type":     dn.DeviceType,
+			"password":        dn.Password,
+			"authorized_keys": dn.AuthorizedKeys,
+			"max_auth_tries":  dn.MaxAuthTries,
+			"auth_failure":    dn.AuthFailure,
+			"auth_delay_ms":   dn.AuthDelayMS,
+			"banner":          dn.Banner,
 		})
 	}
 	c.JSON(http.StatusOK, gin.H{"code": "SUCCESS", "message": "OK", "data": gin.H{"namespaces": namespaces, "device_types": deviceTypes, "device_names": deviceNames}})
@@ -163,6 +232,26 @@ func (h *SimulateConfigHandler) SaveSimulateConfig(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"code": "INVALID_PARAMS", "message": "device_type 不能为空"})
 		return
 	}
+	// 故障注入参数校验
+	for name, n := range payload.Namespace {
+		if n.LatencyMS < 0 || n.JitterMS < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"code": "INVALID_PARAMS", "message": fmt.Sprintf("命名空间 %s 的 latency_ms/jitter_ms 不能为负数", name)})
+			return
+		}
+		if n.DropRate < 0 || n.DropRate > 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"code": "INVALID_PARAMS", "message": fmt.Sprintf("命名空间 %s 的 drop_rate 须在 0~1 之间", name)})
+			return
+		}
+		if n.TelnetPort < 0 || (n.TelnetPort > 0 && n.TelnetPort == n.Port) {
+			c.JSON(http.StatusBadRequest, gin.H{"code": "INVALID_PARAMS", "message": fmt.Sprintf("命名空间 %s 的 telnet_port 不能为负数或与 port 相同", name)})
+			return
+		}
+	}
+	// 握手参数校验（与模拟服务加载时一致）
+	if err := simulate.ValidateNamespaceHandshake(simulateNamespaces(payload.Namespace)); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": "INVALID_PARAMS", "message": err.Error()})
+		return
+	}
 	// device_name 可为空；如有则在后续引用检查中校验
 	// 设备名称引用检查
 	for name, dn := range payload.DeviceName {
@@ -175,21 +264,36 @@ func (h *SimulateConfigHandler) SaveSimulateConfig(c *gin.Context) {
 			return
 		}
 	}
+	// 认证配置校验（与模拟服务加载时一致）
+	if err := simulate.ValidateDeviceAuth(simulateDeviceAuth(payload.DeviceName)); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": "INVALID_PARAMS", "message": err.Error()})
+		return
+	}
+	// 分页与确认提示校验
+	for typ, d := range payload.DeviceType {
+		if err := simulate.ValidateDeviceTypes(map[string]simulate.DeviceTypeConfig{typ: {PageLines: d.PageLines, ConfirmPrompts: d.ConfirmPrompts}}); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"code": "INVALID_PARAMS", "message": err.Error()})
+			return
+		}
+	}
 	// 先写入SQLite（事务替换整个配置集，带重试）
 	if err := database.TransactionWithRetry(func(tx *gorm.DB) error {
 		if err := tx.Exec("DELETE FROM sim_device_names").Error; err != nil { return err }
 		if err := tx.Exec("DELETE FROM sim_device_types").Error; err != nil { return err }
 		if err := tx.Exec("DELETE FROM sim_namespaces").Error; err != nil { return err }
 		for name, n := range payload.Namespace {
-			row := model.SimNamespace{ Name: name, Port: n.Port, IdleSeconds: n.IdleSeconds, MaxConn: n.MaxConn }
+			row := model.SimNamespace{ Name: name, Port: n.Port, IdleSeconds: n.IdleSeconds, MaxConn: n.MaxConn, LatencyMS: n.LatencyMS, JitterMS: n.JitterMS, DropRate: n.DropRate, TelnetPort: n.TelnetPort,
+				ServerVersion: n.ServerVersion, Banner: n.Banner, KeyExchanges: strings.Join(n.KeyExchanges, "\n"), Ciphers: strings.Join(n.Ciphers, "\n"), MACs: strings.Join(n.MACs, "\n"), HostKeyAlgorithms: strings.Join(n.HostKeyAlgorithms, "\n") }
 			if err := tx.Create(&row).Error; err != nil { return err }
 		}
 		for typ, d := range payload.DeviceType {
-			row := model.SimDeviceType{ Type: typ, PromptSuffixe: d.PromptSuffixe, EnableModeRequired: d.EnableModeRequired, EnableModeSuffixe: d.EnableModeSuffixe, ConfigModeSuffixe: d.ConfigModeSuffixe }
+			confirms, _ := json.Marshal(d.ConfirmPrompts)
+			row := model.SimDeviceType{ Type: typ, PromptSuffixe: d.PromptSuffixe, EnableModeRequired: d.EnableModeRequired, EnableModeSuffixe: d.EnableModeSuffixe, ConfigModeSuffixe: d.ConfigModeSuffixe,
+				PageLines: d.PageLines, MorePrompt: d.MorePrompt, PagingDisableCommands: strings.Join(d.PagingDisableCommands, "\n"), ConfirmPrompts: string(confirms) }
 			if err := tx.Create(&row).Error; err != nil { return err }
 		}
 		for name, dn := range payload.DeviceName {
-			row := model.SimDeviceName{ Name: name, DeviceType: dn.DeviceType }
+			row := model.SimDeviceName{ Name: name, DeviceType: dn.DeviceType, Password: dn.Password, AuthorizedKeys: strings.Join(dn.AuthorizedKeys, "\n"), MaxAuthTries: dn.MaxAuthTries, AuthFailure: dn.AuthFailure, AuthDelayMS: dn.AuthDelayMS, Banner: dn.Banner }
 			if err := tx.Create(&row).Error; err != nil { return err }
 		}
 		return nil
@@ -217,4 +321,32 @@ func (h *SimulateConfigHandler) SaveSimulateConfig(c *gin.Context) {
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"code": "SUCCESS", "message": "生成成功", "data": gin.H{"path": path}})
-}
\ No newline at end of file
+}
+// simulateNamespaces 提取命名空间握手配置用于校验
+func simulateNamespaces(in map[string]NamespaceConf) map[string]simulate.NamespaceConfig {
+	out := make(map[string]simulate.NamespaceConfig, len(in))
+	for name, n := range in {
+		out[name] = simulate.NamespaceConfig{ServerVersion: n.ServerVersion, KeyExchanges: n.KeyExchanges, Ciphers: n.Ciphers, MACs: n.MACs, HostKeyAlgorithms: n.HostKeyAlgorithms}
+	}
+	return out
+}
+
+// simulateDeviceAuth 提取设备认证配置用于校验
+func simulateDeviceAuth(in map[string]DeviceNameConf) map[string]simulate.DeviceNameConfig {
+	out := make(map[string]simulate.DeviceNameConfig, len(in))
+	for name, dn := range in {
+		out[name] = simulate.DeviceNameConfig{DeviceType: dn.DeviceType, Password: dn.Password, AuthorizedKeys: dn.AuthorizedKeys, MaxAuthTries: dn.MaxAuthTries, AuthFailure: dn.AuthFailure, AuthDelayMS: dn.AuthDelayMS, Banner: dn.Banner}
+	}
+	return out
+}
+
+// splitKeyLines 按行拆分数据库中的多行字段（公钥、关闭分页命令）
+func splitKeyLines(s string) []string {
+	out := make([]string, 0)
+	for _, l := range strings.Split(s, "\n") {
+		if strings.TrimSpace(l) != "" {
+			out = append(out, l)
+		}
+	}
+	return out
+}
@@ -0,0 +1,52 @@
+package handler
+
+import (
+	"time"
+
+	"github.com/sshcollectorpro/sshcollectorpro/internal/database"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/model"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/service"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// SnapshotIndex 基于 SQLite 的命令结果索引（command_snapshots 表）
+type SnapshotIndex struct{}
+
+// NewSnapshotIndex 创建命令结果索引
+func NewSnapshotIndex() *SnapshotIndex { return &SnapshotIndex{} }
+
+// Latest 查询设备命令最近一次落盘结果
+func (SnapshotIndex) Latest(deviceIP string, port int, command string) (service.StoredObject, time.Time, bool) {
+	db := database.GetDB()
+	if db == nil {
+		return service.StoredObject{}, time.Time{}, false
+	}
+	var snap model.CommandSnapshot
+	if err := db.Where("device_ip = ? AND port = ? AND command = ?", deviceIP, port, command).First(&snap).Error; err != nil {
+		return service.StoredObject{}, time.Time{}, false
+	}
+	obj := service.StoredObject{URI: snap.URI, Size: snap.Size, Checksum: snap.Checksum, ContentType: snap.ContentType}
+	return obj, snap.CollectedAt, true
+}
+
+// Record 覆盖写入设备命令最近一次落盘结果
+func (SnapshotIndex) Record(deviceIP string, port int, command, taskID string, obj service.StoredObject, at time.Time) error {
+	snap := model.CommandSnapshot{
+		DeviceIP:    deviceIP,
+		Port:        port,
+		Command:     command,
+		TaskID:      taskID,
+		URI:         obj.URI,
+		Size:        obj.Size,
+		Checksum:    obj.Checksum,
+		ContentType: obj.ContentType,
+		CollectedAt: at,
+	}
+	return database.WithRetry(func(tx *gorm.DB) error {
+		return tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "device_ip"}, {Name: "port"}, {Name: "command"}},
+			DoUpdates: clause.AssignmentColumns([]string{"task_id", "uri", "size", "checksum", "content_type", "collected_at", "updated_at"}),
+		}).Create(&snap).Error
+	}, 3, 100*time.Millisecond)
+}
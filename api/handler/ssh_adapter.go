@@ -7,6 +7,7 @@ import (
 	"os"
 	"path/filepath"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
 
@@ -41,19 +42,111 @@ type UpdatePlatformRequest struct {
 // UpdateParamsRequest 更新平台适配参数请求（任意JSON对象）
 type UpdateParamsRequest map[string]interface{}
 
-// ListPlatforms 列出所有平台（不含大字段处理）
+// PlatformSummary 平台概要（不含 params 正文，详情通过 GET /platforms/:id/params 获取）
+type PlatformSummary struct {
+	ID           uint      `json:"id"`
+	Type         string    `json:"ssh_type"`
+	Vendor       string    `json:"vendor"`
+	System       string    `json:"system"`
+	Remark       string    `json:"remark"`
+	ParamsFields int       `json:"params_fields"` // params 顶层字段数
+	ParamsKeys   []string  `json:"params_keys"`   // params 顶层字段名（排序）
+	ParamsBytes  int       `json:"params_bytes"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+func newPlatformSummary(p *model.SSHPlatform) PlatformSummary {
+	out := PlatformSummary{ID: p.ID, Type: p.Type, Vendor: p.Vendor, System: p.System, Remark: p.Remark,
+		ParamsKeys: []string{}, ParamsBytes: len(p.Params), CreatedAt: p.CreatedAt, UpdatedAt: p.UpdatedAt}
+	var params map[string]json.RawMessage
+	if p.Params != "" && json.Unmarshal([]byte(p.Params), &params) == nil {
+		for k := range params {
+			out.ParamsKeys = append(out.ParamsKeys, k)
+		}
+		sort.Strings(out.ParamsKeys)
+		out.ParamsFields = len(out.ParamsKeys)
+	}
+	return out
+}
+
+// ListPlatforms 列出平台
+// 过滤：vendor=&system=（忽略大小写）；传 page 或 size 时分页，返回 {platforms, pagination}，否则返回完整数组；
+// summary=true 时不返回 params 正文，仅返回字段统计与更新时间
 func (h *SSHAdapterHandler) ListPlatforms(c *gin.Context) {
 	// 保证 default 的ID为1（如存在则调整，占位冲突时顺序置换）
 	ensureDefaultIDOne()
 
 	db := database.GetDB()
+	importYAMLPlatforms(db)
+
+	query := db.Model(&model.SSHPlatform{})
+	for _, field := range []string{"vendor", "system"} {
+		if v := strings.TrimSpace(c.Query(field)); v != "" {
+			query = query.Where("LOWER("+field+") = LOWER(?)", v)
+		}
+	}
+	summary, _ := strconv.ParseBool(c.DefaultQuery("summary", "false"))
+	_, hasPage := c.GetQuery("page")
+	_, hasSize := c.GetQuery("size")
+	paged := hasPage || hasSize
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	size, _ := strconv.Atoi(c.DefaultQuery("size", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if size < 1 || size > 200 {
+		size = 20
+	}
+
+	var total int64
+	if paged {
+		if err := query.Count(&total).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Code: "DB_ERROR", Message: "查询平台总数失败: " + err.Error()})
+			return
+		}
+		query = query.Offset((page - 1) * size).Limit(size)
+	}
 	var list []model.SSHPlatform
-	if err := db.Order("id asc").Find(&list).Error; err != nil {
+	if err := query.Order("id asc").Find(&list).Error; err != nil {
 		logger.Error("List SSH platforms failed", "error", err)
 		c.JSON(http.StatusInternalServerError, ErrorResponse{Code: "DB_ERROR", Message: "查询平台列表失败: " + err.Error()})
 		return
 	}
 
+	var items interface{} = list
+	if summary {
+		sums := make([]PlatformSummary, 0, len(list))
+		for i := range list {
+			sums = append(sums, newPlatformSummary(&list[i]))
+		}
+		items = sums
+	} else if list == nil {
+		items = []model.SSHPlatform{}
+	}
+	if !paged {
+		c.JSON(http.StatusOK, SuccessResponse{Code: "SUCCESS", Message: "OK", Data: items})
+		return
+	}
+	c.JSON(http.StatusOK, SuccessResponse{Code: "SUCCESS", Message: "OK", Data: gin.H{
+		"platforms": items,
+		"pagination": gin.H{
+			"page":  page,
+			"size":  size,
+			"total": total,
+			"pages": (total + int64(size) - 1) / int64(size),
+		},
+	}})
+}
+
+// importYAMLPlatforms 读取 configs/auto-ssh.yaml 补全数据库中缺失的平台
+func importYAMLPlatforms(db *gorm.DB) {
+	var list []model.SSHPlatform
+	if err := db.Select("ssh_type").Find(&list).Error; err != nil {
+		logger.Error("List SSH platforms failed", "error", err)
+		return
+	}
+
 	// 读取 configs/auto-ssh.yaml 的 collector.device_defaults，按平台补全缺失条目
 	// 数据源优先级：数据库 > auto-ssh.yaml（仅用于补全，不覆盖已存在条目）
 	present := map[string]struct{}{}
@@ -89,14 +182,11 @@ func (h *SSHAdapterHandler) ListPlatforms(c *gin.Context) {
 				logger.Error("Auto import platform from YAML failed", "type", e.Type, "error", err)
 				continue
 			}
-			list = append(list, p)
 			present[e.Type] = struct{}{}
 		}
 	} else {
 		logger.Debug("auto-ssh.yaml not loaded for platform import", "error", err)
 	}
-
-	c.JSON(http.StatusOK, SuccessResponse{Code: "SUCCESS", Message: "OK", Data: list})
 }
 
 // 保证 default 的ID为1；如已被占用则让占用者移至最大ID+1
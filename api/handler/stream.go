@@ -0,0 +1,205 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/config"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/service"
+	"github.com/sshcollectorpro/sshcollectorpro/pkg/logger"
+	"github.com/sshcollectorpro/sshcollectorpro/pkg/ssh"
+	"golang.org/x/net/websocket"
+)
+
+// StreamEvent 流式采集事件
+// type: command_start | output | command_end | done
+type StreamEvent struct {
+	Type       string `json:"type"`
+	Command    string `json:"command,omitempty"`
+	Line       string `json:"line,omitempty"`
+	ExitCode   *int   `json:"exit_code,omitempty"`
+	Error      string `json:"error,omitempty"`
+	DurationMS int64  `json:"duration_ms,omitempty"`
+	Success    *bool  `json:"success,omitempty"`
+}
+
+// StreamCollect 流式采集：POST 以 SSE 返回；GET + Upgrade: websocket 以 WebSocket 返回（首条消息为请求 JSON）
+// @Summary 流式采集设备命令输出
+// @Description 实时转发交互会话输出，按命令给出开始/结束标记与最终状态
+// @Tags collector
+// @Accept json
+// @Produce text/event-stream
+// @Param request body FastCollectRequest true "采集请求（与快速采集一致）"
+// @Router /api/v1/collector/stream [post]
+func (h *CollectorHandler) StreamCollect(c *gin.Context) {
+	if strings.EqualFold(c.GetHeader("Upgrade"), "websocket") {
+		h.streamWebSocket(c)
+		return
+	}
+	if c.Request.Method != http.MethodPost {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Code: "INVALID_PARAMS", Message: "SSE 需使用 POST，WebSocket 需携带 Upgrade 头"})
+		return
+	}
+
+	var req FastCollectRequest
+	if err := bindJSON(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Code: "INVALID_PARAMS", Message: "请求参数无效: " + err.Error(), Errors: fieldErrors(err)})
+		return
+	}
+	r, err := h.buildStreamRequest(&req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Code: "INVALID_PARAMS", Message: err.Error()})
+		return
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+
+	events := h.runStream(c.Request.Context(), r)
+	c.Stream(func(w io.Writer) bool {
+		ev, ok := <-events
+		if !ok {
+			return false
+		}
+		c.SSEvent(ev.Type, ev)
+		return true
+	})
+}
+
+// streamWebSocket WebSocket 模式：读取首条请求消息后逐条发送事件
+func (h *CollectorHandler) streamWebSocket(c *gin.Context) {
+	srv := websocket.Server{Handshake: checkStreamOrigin, Handler: func(ws *websocket.Conn) {
+		defer ws.Close()
+		var req FastCollectRequest
+		if err := websocket.JSON.Receive(ws, &req); err != nil {
+			_ = websocket.JSON.Send(ws, ErrorResponse{Code: "INVALID_PARAMS", Message: "请求参数无效: " + err.Error()})
+			return
+		}
+		r, err := h.buildStreamRequest(&req)
+		if err != nil {
+			_ = websocket.JSON.Send(ws, ErrorResponse{Code: "INVALID_PARAMS", Message: err.Error()})
+			return
+		}
+		ctx, cancel := context.WithCancel(c.Request.Context())
+		defer cancel()
+		events := h.runStream(ctx, r)
+		for ev := range events {
+			if err := websocket.JSON.Send(ws, ev); err != nil {
+				// 客户端断开：取消执行，等待执行协程退出
+				cancel()
+				for range events {
+				}
+				return
+			}
+		}
+	}}
+	srv.ServeHTTP(c.Writer, c.Request)
+}
+
+// checkStreamOrigin WebSocket 握手 Origin 校验，拒绝时返回 403。
+// 不带 Origin 的非浏览器客户端与同源页面允许；跨站页面须在 server.stream_allowed_origins 中，
+// 防止任意网页借用户浏览器连接并发起采集。
+func checkStreamOrigin(_ *websocket.Config, r *http.Request) error {
+	origin := strings.TrimSpace(r.Header.Get("Origin"))
+	if origin == "" {
+		return nil
+	}
+	u, err := url.Parse(origin)
+	if err != nil || u.Host == "" {
+		return fmt.Errorf("invalid origin %q", origin)
+	}
+	if strings.EqualFold(u.Host, r.Host) {
+		return nil
+	}
+	if cfg := config.Get(); cfg != nil {
+		for _, allowed := range cfg.Server.StreamAllowedOrigins {
+			allowed = strings.TrimRight(strings.TrimSpace(allowed), "/")
+			if allowed == "*" || strings.EqualFold(allowed, origin) {
+				return nil
+			}
+		}
+	}
+	logger.Warn("Stream websocket origin rejected", "origin", origin, "host", r.Host)
+	return fmt.Errorf("origin %q not allowed", origin)
+}
+
+// buildStreamRequest 解析凭据并组装服务层请求
+func (h *CollectorHandler) buildStreamRequest(req *FastCollectRequest) (*service.CollectRequest, error) {
+	if err := resolveCredential(req.CredentialID, &req.UserName, &req.Password, &req.EnablePassword); err != nil {
+		return nil, err
+	}
+	cli, err := newPlaybookResolver().expand(req.Playbook, req.DevicePlatform, req.CliList)
+	if err != nil {
+		return nil, err
+	}
+	proto := strings.TrimSpace(strings.ToLower(req.CollectProtocol))
+	if proto == "" {
+		proto = "ssh"
+	}
+	r := &service.CollectRequest{
+		TaskID:          fmt.Sprintf("stream-%d", time.Now().UnixNano()),
+		CollectOrigin:   "stream",
+		DeviceIP:        req.DeviceIP,
+		Port:            req.DevicePort,
+		DeviceName:      req.DeviceName,
+		DevicePlatform:  req.DevicePlatform,
+		CollectProtocol: proto,
+		UserName:        req.UserName,
+		Password:        req.Password,
+		EnablePassword:  req.EnablePassword,
+		CliList:         cli,
+		TaskTimeout:     req.TaskTimeout,
+		DeviceTimeout:   req.DeviceTimeout,
+	}
+	if err := h.validateCollectRequest(r); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// runStream 在独立协程执行采集，事件经通道按序输出；执行结束后发送 done 并关闭通道
+func (h *CollectorHandler) runStream(ctx context.Context, r *service.CollectRequest) <-chan StreamEvent {
+	events := make(chan StreamEvent, 256)
+	send := func(ev StreamEvent) {
+		select {
+		case events <- ev:
+		case <-ctx.Done():
+		}
+	}
+	hooks := &ssh.StreamHooks{
+		OnCommandStart: func(cmd string) { send(StreamEvent{Type: "command_start", Command: cmd}) },
+		OnOutputLine:   func(cmd, line string) { send(StreamEvent{Type: "output", Command: cmd, Line: line}) },
+		OnCommandEnd: func(res *ssh.CommandResult) {
+			code := res.ExitCode
+			send(StreamEvent{Type: "command_end", Command: res.Command, ExitCode: &code, Error: res.Error, DurationMS: res.Duration.Milliseconds()})
+		},
+	}
+	go func() {
+		defer close(events)
+		results, err := h.collectorService.ExecuteStream(ctx, r, hooks)
+		ok := err == nil
+		done := StreamEvent{Type: "done", Success: &ok}
+		if err != nil {
+			done.Error = err.Error()
+			logger.Warn("Stream collect failed", "device_ip", r.DeviceIP, "error", err)
+		} else {
+			for _, res := range results {
+				if res != nil && res.ExitCode != 0 {
+					ok = false
+					break
+				}
+			}
+		}
+		send(done)
+	}()
+	return events
+}
@@ -0,0 +1,414 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/database"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/model"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/service"
+	"github.com/sshcollectorpro/sshcollectorpro/pkg/logger"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// TaskHandler 任务历史与标注
+type TaskHandler struct{}
+
+// NewTaskHandler 创建任务历史处理器
+func NewTaskHandler() *TaskHandler { return &TaskHandler{} }
+
+// TaskRepository 任务记录持久化（实现 service.TaskStore）
+type TaskRepository struct{}
+
+// NewTaskRepository 创建任务记录存储
+func NewTaskRepository() *TaskRepository { return &TaskRepository{} }
+
+// SaveTask 按任务 ID 插入或覆盖，保留已有标注
+func (TaskRepository) SaveTask(task *model.Task) error {
+	return database.WithRetry(func(tx *gorm.DB) error {
+		return tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"collector_id", "type", "device_ip", "device_port", "username", "password", "commands", "status", "result", "error_msg", "start_time", "end_time", "duration", "task_name", "metadata", "updated_at"}),
+		}).Create(task).Error
+	}, 3, 0)
+}
+
+// UpdateTask 更新运行结果字段
+func (TaskRepository) UpdateTask(task *model.Task) error {
+	return database.WithRetry(func(tx *gorm.DB) error {
+		return tx.Model(&model.Task{}).Where("id = ?", task.ID).Updates(map[string]interface{}{
+			"status":     task.Status,
+			"result":     task.Result,
+			"error_msg":  task.ErrorMsg,
+			"duration":   task.Duration,
+			"updated_at": time.Now(),
+		}).Error
+	}, 3, 0)
+}
+
+// InterruptRunningTasks 将本采集器遗留的运行中任务标记为失败（实现 service.TaskRecoveryStore）
+func (TaskRepository) InterruptRunningTasks(collectorID string, before time.Time, reason string) (int64, error) {
+	if database.GetDB() == nil {
+		return 0, nil
+	}
+	var n int64
+	err := database.WithRetry(func(tx *gorm.DB) error {
+		res := tx.Model(&model.Task{}).
+			Where("collector_id = ? AND status = ? AND start_time < ?", collectorID, model.TaskStatusRunning, before).
+			Updates(map[string]interface{}{"status": model.TaskStatusFailed, "error_msg": reason, "updated_at": time.Now()})
+		n = res.RowsAffected
+		return res.Error
+	}, 3, 0)
+	return n, err
+}
+
+// SaveTaskLog 写入任务日志
+func (TaskRepository) SaveTaskLog(entry *model.TaskLog) error {
+	return database.WithRetry(func(tx *gorm.DB) error { return tx.Create(entry).Error }, 3, 0)
+}
+
+// PurgeTasks 删除 before 之前创建的任务及其日志（同时清理已结束的批量任务断点记录）
+func (TaskRepository) PurgeTasks(before time.Time) (int64, int64, error) {
+	var tasks, logs int64
+	err := database.WithRetry(func(tx *gorm.DB) error {
+		res := tx.Where("created_at < ?", before).Delete(&model.TaskLog{})
+		if res.Error != nil {
+			return res.Error
+		}
+		logs = res.RowsAffected
+		// 已结束的批量任务断点记录随任务历史一并清理
+		if err := tx.Where("created_at < ? AND status <> ?", before, model.BatchJobRunning).Delete(&model.BatchJob{}).Error; err != nil {
+			return err
+		}
+		res = tx.Where("created_at < ?", before).Delete(&model.Task{})
+		tasks = res.RowsAffected
+		return res.Error
+	}, 3, 0)
+	return tasks, logs, err
+}
+
+// TaskView 任务历史视图（不含密码与结果正文）
+type TaskView struct {
+	ID          string                 `json:"id"`
+	TaskName    string                 `json:"task_name,omitempty"`
+	Type        string                 `json:"type"`
+	DeviceIP    string                 `json:"device_ip"`
+	DevicePort  int                    `json:"device_port"`
+	Commands    string                 `json:"commands"`
+	Status      string                 `json:"status"`
+	ErrorMsg    string                 `json:"error_msg,omitempty"`
+	Duration    int64                  `json:"duration"`
+	Metadata    map[string]interface{} `json:"metadata"`
+	Annotations map[string]interface{} `json:"annotations"`
+	Result      json.RawMessage        `json:"result,omitempty"`
+	CreatedAt   time.Time              `json:"created_at"`
+	UpdatedAt   time.Time              `json:"updated_at"`
+}
+
+// taskFilterKey 允许的 metadata/annotation 过滤键
+var taskFilterKey = regexp.MustCompile(`^[A-Za-z0-9_\-]{1,64}$`)
+
+// ListTasks GET /api/v1/tasks（同 /api/v1/collector/tasks）
+// 过滤：status=&device_ip=（或 device=）&from=&to=&metadata.<key>=&annotations.<key>=
+// from/to 按创建时间过滤，格式 RFC3339 或 2006-01-02 15:04:05
+func (h *TaskHandler) ListTasks(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	size, _ := strconv.Atoi(c.DefaultQuery("size", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if size < 1 || size > 200 {
+		size = 20
+	}
+
+	query := database.GetDB().Model(&model.Task{})
+	if v := strings.TrimSpace(c.Query("status")); v != "" {
+		query = query.Where("status = ?", v)
+	}
+	device := strings.TrimSpace(c.Query("device_ip"))
+	if device == "" {
+		device = strings.TrimSpace(c.Query("device"))
+	}
+	if device != "" {
+		query = query.Where("device_ip = ?", device)
+	}
+	for _, p := range []struct{ param, cond string }{{"from", "created_at >= ?"}, {"to", "created_at <= ?"}} {
+		v := strings.TrimSpace(c.Query(p.param))
+		if v == "" {
+			continue
+		}
+		t, err := parseTaskTime(v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Code: "INVALID_PARAMS", Message: "无效的时间参数 " + p.param + ": " + v})
+			return
+		}
+		query = query.Where(p.cond, t)
+	}
+	// metadata.<key>=<value> / annotations.<key>=<value>：按 JSON 字段等值过滤
+	for param, values := range c.Request.URL.Query() {
+		column, key, ok := strings.Cut(param, ".")
+		if !ok || (column != "metadata" && column != "annotations") || len(values) == 0 {
+			continue
+		}
+		if !taskFilterKey.MatchString(key) {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Code: "INVALID_PARAMS", Message: "无效的过滤键: " + param})
+			return
+		}
+		query = query.Where("CAST(json_extract("+column+", ?) AS TEXT) = ?", "$."+key, values[0])
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Code: "COUNT_FAILED", Message: "获取任务总数失败: " + err.Error()})
+		return
+	}
+	var rows []model.Task
+	if err := query.Omit("result").Order("created_at DESC").Offset((page - 1) * size).Limit(size).Find(&rows).Error; err != nil {
+		logger.Error("Failed to list tasks", "error", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Code: "LIST_FAILED", Message: "获取任务列表失败: " + err.Error()})
+		return
+	}
+	items := make([]TaskView, 0, len(rows))
+	for i := range rows {
+		items = append(items, newTaskView(&rows[i], false))
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"code":    "SUCCESS",
+		"message": "获取任务列表成功",
+		"data": gin.H{
+			"tasks": items,
+			"pagination": gin.H{
+				"page":  page,
+				"size":  size,
+				"total": total,
+				"pages": (total + int64(size) - 1) / int64(size),
+			},
+		},
+	})
+}
+
+// GetTask GET /api/v1/tasks/:id（含结果）
+func (h *TaskHandler) GetTask(c *gin.Context) {
+	var row model.Task
+	if err := database.GetDB().Where("id = ?", c.Param("id")).First(&row).Error; err != nil {
+		c.JSON(http.StatusNotFound, ErrorResponse{Code: "TASK_NOT_FOUND", Message: "任务不存在"})
+		return
+	}
+	c.JSON(http.StatusOK, SuccessResponse{Code: "SUCCESS", Message: "获取任务成功", Data: newTaskView(&row, true)})
+}
+
+// GetTaskLogs GET /api/v1/tasks/:id/logs?level=&page=&size=（按时间升序）
+func (h *TaskHandler) GetTaskLogs(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	size, _ := strconv.Atoi(c.DefaultQuery("size", "100"))
+	if page < 1 {
+		page = 1
+	}
+	if size < 1 || size > 1000 {
+		size = 100
+	}
+	db := database.GetDB()
+	var count int64
+	if err := db.Model(&model.Task{}).Where("id = ?", c.Param("id")).Count(&count).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Code: "QUERY_FAILED", Message: err.Error()})
+		return
+	}
+	if count == 0 {
+		c.JSON(http.StatusNotFound, ErrorResponse{Code: "TASK_NOT_FOUND", Message: "任务不存在"})
+		return
+	}
+
+	query := db.Model(&model.TaskLog{}).Where("task_id = ?", c.Param("id"))
+	if v := strings.TrimSpace(c.Query("level")); v != "" {
+		query = query.Where("level = ?", strings.ToUpper(v))
+	}
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Code: "COUNT_FAILED", Message: "获取日志总数失败: " + err.Error()})
+		return
+	}
+	var rows []model.TaskLog
+	if err := query.Order("created_at ASC").Offset((page - 1) * size).Limit(size).Find(&rows).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Code: "LIST_FAILED", Message: "获取任务日志失败: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"code":    "SUCCESS",
+		"message": "获取任务日志成功",
+		"data": gin.H{
+			"logs": rows,
+			"pagination": gin.H{
+				"page":  page,
+				"size":  size,
+				"total": total,
+				"pages": (total + int64(size) - 1) / int64(size),
+			},
+		},
+	})
+}
+
+// GetTaskSummary GET /api/v1/tasks/:id/summary?top=10&combined=true
+// id 为批次 task_id 时汇总 metadata.batch_task_id 相同的设备任务，为单个设备任务 ID 时仅汇总该任务
+// combined=true 时合并失败重试任务（metadata.retry_of），每台设备取最近一次执行结果
+func (h *TaskHandler) GetTaskSummary(c *gin.Context) {
+	top, _ := strconv.Atoi(c.DefaultQuery("top", "10"))
+	if top < 1 || top > 100 {
+		top = 10
+	}
+	id := c.Param("id")
+	combined, _ := strconv.ParseBool(c.DefaultQuery("combined", "false"))
+	query := database.GetDB().
+		Select("id", "device_ip", "device_port", "status", "error_msg", "duration", "result", "created_at").
+		Where("id = ? OR CAST(json_extract(metadata, '$.batch_task_id') AS TEXT) = ?", id, id)
+	if combined {
+		query = query.Or("CAST(json_extract(metadata, '$.retry_of') AS TEXT) = ?", id)
+	}
+	var rows []model.Task
+	err := query.Order("created_at ASC").Find(&rows).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Code: "QUERY_FAILED", Message: err.Error()})
+		return
+	}
+	if len(rows) == 0 {
+		c.JSON(http.StatusNotFound, ErrorResponse{Code: "TASK_NOT_FOUND", Message: "任务不存在"})
+		return
+	}
+	if combined {
+		rows = service.LatestTaskPerDevice(rows)
+	}
+	c.JSON(http.StatusOK, SuccessResponse{Code: "SUCCESS", Message: "获取任务汇总成功", Data: service.SummarizeTasks(id, rows, top)})
+}
+
+// CompareTasks GET /api/v1/tasks/compare?base=&target=&context=3&changed_only=false&combined=false
+// base/target 为批次 task_id 或单个设备任务 ID；按设备（ip:port）与命令对齐，返回文本差异与解析字段差异
+func (h *TaskHandler) CompareTasks(c *gin.Context) {
+	baseID, targetID := strings.TrimSpace(c.Query("base")), strings.TrimSpace(c.Query("target"))
+	if baseID == "" || targetID == "" {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Code: "INVALID_PARAMS", Message: "base 与 target 均不能为空"})
+		return
+	}
+	opts := service.TaskCompareOptions{}
+	opts.Context, _ = strconv.Atoi(c.DefaultQuery("context", "3"))
+	if opts.Context < 0 || opts.Context > 100 {
+		opts.Context = 3
+	}
+	opts.ChangedOnly, _ = strconv.ParseBool(c.DefaultQuery("changed_only", "false"))
+	combined, _ := strconv.ParseBool(c.DefaultQuery("combined", "false"))
+	sets := make([][]model.Task, 2)
+	for i, id := range []string{baseID, targetID} {
+		query := database.GetDB().
+			Select("id", "device_ip", "device_port", "status", "result", "created_at").
+			Where("id = ? OR CAST(json_extract(metadata, '$.batch_task_id') AS TEXT) = ?", id, id)
+		if combined {
+			query = query.Or("CAST(json_extract(metadata, '$.retry_of') AS TEXT) = ?", id)
+		}
+		if err := query.Order("created_at ASC").Find(&sets[i]).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Code: "QUERY_FAILED", Message: err.Error()})
+			return
+		}
+		if len(sets[i]) == 0 {
+			c.JSON(http.StatusNotFound, ErrorResponse{Code: "TASK_NOT_FOUND", Message: "任务不存在: " + id})
+			return
+		}
+	}
+	c.JSON(http.StatusOK, SuccessResponse{Code: "SUCCESS", Message: "任务对比完成", Data: service.CompareTasks(baseID, targetID, sets[0], sets[1], opts)})
+}
+
+// UpdateAnnotations PATCH /api/v1/tasks/:id/annotations
+// 请求体为 JSON 对象，与已有标注合并；值为 null 时删除该键
+func (h *TaskHandler) UpdateAnnotations(c *gin.Context) {
+	var patch map[string]interface{}
+	if err := c.ShouldBindJSON(&patch); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Code: "INVALID_PARAMS", Message: "请求参数无效: " + err.Error()})
+		return
+	}
+	for k := range patch {
+		if !taskFilterKey.MatchString(k) {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Code: "INVALID_PARAMS", Message: "无效的标注键: " + k})
+			return
+		}
+	}
+
+	var row model.Task
+	err := database.WithRetry(func(tx *gorm.DB) error {
+		if err := tx.Where("id = ?", c.Param("id")).First(&row).Error; err != nil {
+			return err
+		}
+		annotations := decodeJSONObject(row.Annotations)
+		for k, v := range patch {
+			if v == nil {
+				delete(annotations, k)
+				continue
+			}
+			annotations[k] = v
+		}
+		b, err := json.Marshal(annotations)
+		if err != nil {
+			return err
+		}
+		row.Annotations = string(b)
+		return tx.Model(&model.Task{}).Where("id = ?", row.ID).Update("annotations", row.Annotations).Error
+	}, 3, 0)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, ErrorResponse{Code: "TASK_NOT_FOUND", Message: "任务不存在"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Code: "UPDATE_FAILED", Message: "更新标注失败: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, SuccessResponse{Code: "SUCCESS", Message: "标注更新成功", Data: newTaskView(&row, false)})
+}
+
+// newTaskView 解析 JSON 字段；withResult 为 true 时附带结果正文
+func newTaskView(t *model.Task, withResult bool) TaskView {
+	v := TaskView{
+		ID:          t.ID,
+		TaskName:    t.TaskName,
+		Type:        t.Type,
+		DeviceIP:    t.DeviceIP,
+		DevicePort:  t.DevicePort,
+		Commands:    t.Commands,
+		Status:      t.Status,
+		ErrorMsg:    t.ErrorMsg,
+		Duration:    t.Duration,
+		Metadata:    decodeJSONObject(t.Metadata),
+		Annotations: decodeJSONObject(t.Annotations),
+		CreatedAt:   t.CreatedAt,
+		UpdatedAt:   t.UpdatedAt,
+	}
+	if withResult && json.Valid([]byte(t.Result)) {
+		v.Result = json.RawMessage(t.Result)
+	}
+	return v
+}
+
+// parseTaskTime 解析时间过滤参数：RFC3339 或本地时间 2006-01-02 15:04:05 / 2006-01-02
+func parseTaskTime(v string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02 15:04:05", v, time.Local); err == nil {
+		return t, nil
+	}
+	return time.ParseInLocation("2006-01-02", v, time.Local)
+}
+
+// decodeJSONObject 解析 JSON 对象文本；为空或非法时返回空 map
+func decodeJSONObject(s string) map[string]interface{} {
+	out := map[string]interface{}{}
+	if strings.TrimSpace(s) != "" {
+		_ = json.Unmarshal([]byte(s), &out)
+	}
+	if out == nil {
+		out = map[string]interface{}{}
+	}
+	return out
+}
@@ -0,0 +1,91 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/service"
+)
+
+// warningsWriter 请求产生告警时缓冲 JSON 响应，结束后追加 warnings 字段
+type warningsWriter struct {
+	gin.ResponseWriter
+	ws  *service.Warnings
+	buf *bytes.Buffer
+}
+
+func (w *warningsWriter) buffering() bool {
+	if w.buf != nil {
+		return true
+	}
+	if w.ws.Len() == 0 || w.ResponseWriter.Written() {
+		return false
+	}
+	if !strings.Contains(w.Header().Get("Content-Type"), "application/json") {
+		return false
+	}
+	w.buf = &bytes.Buffer{}
+	return true
+}
+
+func (w *warningsWriter) Write(b []byte) (int, error) {
+	if w.buffering() {
+		return w.buf.Write(b)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *warningsWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+func (w *warningsWriter) Written() bool {
+	return w.buf != nil || w.ResponseWriter.Written()
+}
+
+func (w *warningsWriter) Size() int {
+	if w.buf != nil {
+		return w.buf.Len()
+	}
+	return w.ResponseWriter.Size()
+}
+
+// flush 向 JSON 对象追加 warnings 后写出；响应不是对象时原样写出
+func (w *warningsWriter) flush() {
+	if w.buf == nil {
+		return
+	}
+	body := w.buf.Bytes()
+	w.buf = nil
+	if warnings, err := json.Marshal(w.ws.List()); err == nil {
+		trimmed := bytes.TrimRight(body, " \r\n\t")
+		if n := len(trimmed); n > 0 && trimmed[n-1] == '}' {
+			head := bytes.TrimRight(trimmed[:n-1], " \r\n\t")
+			out := make([]byte, 0, len(body)+len(warnings)+16)
+			out = append(out, head...)
+			if len(head) > 0 && head[len(head)-1] != '{' {
+				out = append(out, ',')
+			}
+			out = append(out, `"warnings":`...)
+			out = append(out, warnings...)
+			out = append(out, '}', '\n')
+			body = out
+		}
+	}
+	_, _ = w.ResponseWriter.Write(body)
+}
+
+// WarningsMiddleware 为请求挂载告警收集器；处理过程中产生非致命告警（旧字段、存储回退、
+// 平台默认参数、结果截断等）时在 JSON 响应对象中附加 warnings 数组（流式响应不处理）
+func WarningsMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, ws := service.WithWarnings(c.Request.Context())
+		c.Request = c.Request.WithContext(ctx)
+		w := &warningsWriter{ResponseWriter: c.Writer, ws: ws}
+		c.Writer = w
+		c.Next()
+		w.flush()
+	}
+}
@@ -3,16 +3,23 @@ package router
 import (
 	"fmt"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/sshcollectorpro/sshcollectorpro/api/handler"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/config"
 	"github.com/sshcollectorpro/sshcollectorpro/internal/service"
 	"github.com/sshcollectorpro/sshcollectorpro/pkg/logger"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // SetupRouter 设置路由
-func SetupRouter(collectorService *service.CollectorService, backupService *service.BackupService, formatService *service.FormatService, deployService *service.DeployService) *gin.Engine {
+func SetupRouter(collectorService *service.CollectorService, backupService *service.BackupService, formatService *service.FormatService, deployService *service.DeployService, inventoryService *service.InventorySyncService) *gin.Engine {
 	// 设置Gin模式
 	gin.SetMode(gin.ReleaseMode)
 
@@ -24,7 +31,16 @@ func SetupRouter(collectorService *service.CollectorService, backupService *serv
 	r.Use(gin.Recovery())
 	r.Use(CORSMiddleware())
 	r.Use(RequestIDMiddleware())
+	r.Use(TracingMiddleware())
 	r.Use(LoggingMiddleware())
+	// 非致命告警（旧字段名、存储回退等）：JSON 响应附加 warnings
+	r.Use(handler.WarningsMiddleware())
+	r.Use(handler.ErrorMiddleware())
+	// 出站策略租户标识（ssh.egress.tenant_header）
+	r.Use(handler.EgressTenantMiddleware())
+
+	// Prometheus 指标（metrics.enable）
+	r.GET("/metrics", handler.Metrics)
 
 	// 静态资源与管理页入口
 	r.Static("/static", "./web/static")
@@ -43,16 +59,59 @@ func SetupRouter(collectorService *service.CollectorService, backupService *serv
 
 	// 创建处理器
 	collectorHandler := handler.NewCollectorHandler(collectorService)
+	// worker 排队指标：/metrics 与 /collector/stats 的 worker_pools
+	handler.SetWorkerReporters(collectorService, backupService, formatService)
+	// 任务记录：持久化请求 metadata，支持历史查询与事后标注
+	collectorService.SetTaskStore(handler.NewTaskRepository())
+	// 主机密钥指纹：ssh.host_key.mode=tofu/strict 时读写
+	service.SetHostKeyStore(handler.NewHostKeyRepository())
+	// 批量任务断点记录：batch_resume 开启时登记进度，支持续跑与失败重试
+	service.SetBatchJobStore(handler.NewBatchJobRepository())
+	// 采集结果落盘（store=true）：复用备份存储写入器
+	collectorService.SetStorageWriter(backupService.StorageWriter())
+	taskHandler := handler.NewTaskHandler()
 	deviceHandler := handler.NewDeviceHandler()
+	// 命令结果索引：备份、采集落盘与格式化原始数据共用，支持按新鲜度跳过命令
+	snapshots := handler.NewSnapshotIndex()
+	backupService.SetResultIndex(snapshots)
+	collectorService.SetResultIndex(snapshots)
+	formatService.SetResultIndex(snapshots, backupService.StorageReader())
 	backupHandler := handler.NewBackupHandler(backupService)
 	formattedHandler := handler.NewFormattedHandler(formatService)
+	// 模板库：格式化请求未提供模板时回退
+	formatService.SetTemplateStore(handler.NewTemplateRepository())
+	templateHandler := handler.NewTemplateHandler()
+	// 设备资产：下发命令中 ${inventory.*} 变量来源
+	deployService.SetInventory(handler.NewDeviceInventory())
+	// 采集连接被拒绝时尝试资产登记的备用端口，并回写连接成功的端口
+	collectorService.SetPortInventory(handler.NewDeviceInventory())
 	deployHandler := handler.NewDeployHandler(deployService)
+	approvalHandler := handler.NewApprovalHandler(deployService)
+	// 合规检查：复用备份服务的连接池与存储
+	complianceRepo := handler.NewComplianceRepository()
+	complianceService := service.NewComplianceService(backupService, complianceRepo, complianceRepo)
+	complianceHandler := handler.NewComplianceHandler(complianceService)
 	adminHandler := handler.NewAdminHandler()
+	debugHandler := handler.NewDebugHandler(collectorService)
+	bootstrapHandler := handler.NewBootstrapHandler(service.NewBootstrapService(backupService))
 	simCmdHandler := handler.NewSimCmdHandler()
 	simDeviceCmdHandler := handler.NewSimDeviceCmdHandler()
 	logsHandler := handler.NewLogsHandler()
 	sshAdapterHandler := handler.NewSSHAdapterHandler()
 	simulateConfigHandler := handler.NewSimulateConfigHandler()
+	credentialHandler := handler.NewCredentialHandler()
+	playbookHandler := handler.NewPlaybookHandler()
+	inventoryHandler := handler.NewInventoryHandler(inventoryService)
+	batchJobHandler := handler.NewBatchJobHandler(collectorHandler, backupService)
+	batchDefinitionHandler := handler.NewBatchDefinitionHandler(collectorHandler, backupHandler)
+	calendarHandler := handler.NewCalendarHandler()
+	credentialRotationHandler := handler.NewCredentialRotationHandler(service.NewSSHLoginVerifier(config.Get()))
+	// 运行手册：按步骤编排采集、合规检查、下发与通知
+	runbookHandler := handler.NewRunbookHandler(service.NewRunbookService(config.Get(), collectorService, complianceService, deployService))
+	configHandler := handler.NewConfigHandler(collectorService, backupService, formatService)
+	// 原始输出匿名化：映射表加密存储，可读取备份对象
+	anonymizeHandler := handler.NewAnonymizeHandler(service.NewAnonymizeService(config.Get(), handler.NewAnonymizeRepository(), backupService.StorageReader()))
+	healthHandler := handler.NewHealthHandler(service.NewHealthChecker(collectorService, backupService, formatService, deployService))
 
 	// 根路径
 	r.GET("/", func(c *gin.Context) {
@@ -68,16 +127,38 @@ func SetupRouter(collectorService *service.CollectorService, backupService *serv
 	{
 		// 健康检查
 		v1.GET("/health", collectorHandler.Health)
+		// 聚合健康检查：ready 用于就绪探针（关键依赖异常返回 503），live 用于存活探针
+		v1.GET("/health/ready", healthHandler.Ready)
+		v1.GET("/health/live", healthHandler.Live)
+		// 高可用选主状态
+		v1.GET("/ha/status", handler.HAStatus)
+		// 设备熔断：查看与重置
+		v1.GET("/circuit-breakers", handler.ListCircuitBreakers)
+		v1.DELETE("/circuit-breakers", handler.ResetCircuitBreakers)
+		v1.DELETE("/circuit-breakers/:ip", handler.ResetCircuitBreaker)
+		// 配置试运行校验
+		v1.POST("/config/validate", handler.ValidateConfig)
+		// 生效配置（脱敏）与运行时修改
+		v1.GET("/config", configHandler.GetConfig)
+		v1.PATCH("/config", configHandler.PatchConfig)
 
 		// 采集器相关路由
 		collector := v1.Group("/collector")
 		{
 			collector.POST("/fast", collectorHandler.FastCollect)
+			// 设备事实发现（未指定平台时按版本输出识别）
+			collector.POST("/facts", collectorHandler.DiscoverFacts)
+			// 流式采集：POST 为 SSE，GET + Upgrade 为 WebSocket
+			collector.POST("/stream", collectorHandler.StreamCollect)
+			collector.GET("/stream", collectorHandler.StreamCollect)
 			collector.POST("/batch", collectorHandler.BatchExecute)
 			// 新增拆封后的批量接口
 			collector.POST("/batch/custom", collectorHandler.BatchExecuteCustomer)
 			collector.POST("/batch/system", collectorHandler.BatchExecuteSystem)
 			collector.GET("/task/:task_id/status", collectorHandler.GetTaskStatus)
+			// 任务历史查询（同 /api/v1/tasks）
+			collector.GET("/tasks", taskHandler.ListTasks)
+			collector.GET("/tasks/:id/logs", taskHandler.GetTaskLogs)
 			collector.POST("/task/:task_id/cancel", collectorHandler.CancelTask)
 			collector.GET("/stats", collectorHandler.GetStats)
 			// 新增：快速采集设置（sqlite）
@@ -85,6 +166,18 @@ func SetupRouter(collectorService *service.CollectorService, backupService *serv
 			collector.POST("/settings", collectorHandler.UpdateCollectorSettings)
 		}
 
+		// 任务历史与标注
+		tasks := v1.Group("/tasks")
+		{
+			tasks.GET("", taskHandler.ListTasks)
+			tasks.GET("/compare", taskHandler.CompareTasks)
+			tasks.GET("/:id", taskHandler.GetTask)
+			tasks.GET("/:id/logs", taskHandler.GetTaskLogs)
+			tasks.GET("/:id/summary", taskHandler.GetTaskSummary)
+			tasks.PATCH("/:id/annotations", taskHandler.UpdateAnnotations)
+			tasks.POST("/:id/retry-failed", batchJobHandler.RetryFailed)
+		}
+
 		// 设备管理路由
 		devices := v1.Group("/devices")
 		{
@@ -97,8 +190,116 @@ func SetupRouter(collectorService *service.CollectorService, backupService *serv
 			devices.POST("/:id/enabled", deviceHandler.SetEnabled)
 		}
 
+		// 资产同步：从 NetBox 拉取设备写入设备表
+		v1.POST("/inventory/netbox/sync", inventoryHandler.SyncNetBox)
+		v1.GET("/inventory/netbox/sync", inventoryHandler.GetNetBoxSync)
+
+		// 设备凭据管理（密码加密存储，批量接口通过 credential_id 引用）
+		creds := v1.Group("/credentials")
+		{
+			creds.POST("", credentialHandler.CreateCredential)
+			creds.GET("", credentialHandler.ListCredentials)
+			creds.GET("/:id", credentialHandler.GetCredential)
+			creds.PUT("/:id", credentialHandler.UpdateCredential)
+			creds.DELETE("/:id", credentialHandler.DeleteCredential)
+		}
+
+		// 凭据轮换：新密码逐设备验证后确认写入设备表，可回滚
+		rotations := v1.Group("/credential-rotations")
+		{
+			rotations.POST("", credentialRotationHandler.CreateRotation)
+			rotations.GET("", credentialRotationHandler.ListRotations)
+			rotations.GET("/:id", credentialRotationHandler.GetRotation)
+			rotations.POST("/:id/verify", credentialRotationHandler.VerifyRotation)
+			rotations.POST("/:id/finalize", credentialRotationHandler.FinalizeRotation)
+			rotations.POST("/:id/rollback", credentialRotationHandler.RollbackRotation)
+		}
+
+		// 命令集管理（采集/备份请求通过 playbook 引用）
+		playbooks := v1.Group("/playbooks")
+		{
+			playbooks.POST("", playbookHandler.CreatePlaybook)
+			playbooks.GET("", playbookHandler.ListPlaybooks)
+			playbooks.GET("/:name", playbookHandler.GetPlaybook)
+			playbooks.PUT("/:name", playbookHandler.UpdatePlaybook)
+			playbooks.DELETE("/:name", playbookHandler.DeletePlaybook)
+		}
+
+		// 运行手册与执行记录
+		runbooks := v1.Group("/runbooks")
+		{
+			runbooks.POST("", runbookHandler.CreateRunbook)
+			runbooks.GET("", runbookHandler.ListRunbooks)
+			runbooks.GET("/:name", runbookHandler.GetRunbook)
+			runbooks.PUT("/:name", runbookHandler.UpdateRunbook)
+			runbooks.DELETE("/:name", runbookHandler.DeleteRunbook)
+			runbooks.POST("/:name/runs", runbookHandler.StartRunbook)
+		}
+		runbookRuns := v1.Group("/runbook-runs")
+		{
+			runbookRuns.GET("", runbookHandler.ListRunbookRuns)
+			runbookRuns.GET("/:id", runbookHandler.GetRunbookRun)
+			runbookRuns.POST("/:id/resume", runbookHandler.ResumeRunbookRun)
+		}
+
+		// 下发审批（企业微信/钉钉卡片链接为签名 URL）
+		approvals := v1.Group("/deploy-approvals")
+		{
+			approvals.GET("", approvalHandler.ListApprovals)
+			approvals.GET("/:id", approvalHandler.GetApproval)
+			approvals.POST("/:id/approve", approvalHandler.Approve)
+			approvals.POST("/:id/reject", approvalHandler.Reject)
+			approvals.GET("/:id/decision", approvalHandler.Decision)
+			approvals.POST("/:id/decision", approvalHandler.Decision)
+		}
+
+		// 执行日历（批量备份通过 calendar 引用，节假日与封网日跳过）
+		calendars := v1.Group("/calendars")
+		{
+			calendars.POST("", calendarHandler.CreateCalendar)
+			calendars.GET("", calendarHandler.ListCalendars)
+			calendars.GET("/:name", calendarHandler.GetCalendar)
+			calendars.GET("/:name/evaluate", calendarHandler.EvaluateCalendar)
+			calendars.PUT("/:name", calendarHandler.UpdateCalendar)
+			calendars.DELETE("/:name", calendarHandler.DeleteCalendar)
+		}
+
+		// 批量任务断点续跑
+		batchJobs := v1.Group("/batch-jobs")
+		{
+			batchJobs.GET("", batchJobHandler.ListBatchJobs)
+			batchJobs.POST("/:id/resume", batchJobHandler.ResumeBatchJob)
+		}
+
+		// 批量设备定义：CSV/XLSX 导入，按定义执行批量备份或自定义批量采集
+		batchDefs := v1.Group("/batch-definitions")
+		{
+			batchDefs.POST("/import", batchDefinitionHandler.ImportBatchDefinition)
+			batchDefs.GET("", batchDefinitionHandler.ListBatchDefinitions)
+			batchDefs.GET("/:name", batchDefinitionHandler.GetBatchDefinition)
+			batchDefs.DELETE("/:name", batchDefinitionHandler.DeleteBatchDefinition)
+			batchDefs.POST("/:name/run", batchDefinitionHandler.RunBatchDefinition)
+		}
+
+		// 请求示例与严格模式校验（不执行任务）
+		examples := v1.Group("/examples")
+		{
+			examples.GET("", handler.ListRequestExamples)
+			examples.GET("/:endpoint", handler.GetRequestExample)
+			examples.POST("/:endpoint/validate", handler.ValidateRequestExample)
+		}
+
 		// 备份路由
 		v1.POST("/backup/batch", backupHandler.BatchBackup)
+		v1.POST("/backup/diff", backupHandler.DiffBackup)
+
+		// 匿名化：一致性假名替换与还原
+		anonymize := v1.Group("/anonymize")
+		{
+			anonymize.POST("", anonymizeHandler.Anonymize)
+			anonymize.POST("/reverse", anonymizeHandler.Deanonymize)
+			anonymize.DELETE("/maps/:id", anonymizeHandler.DeleteMap)
+		}
 
 		// 数据格式化路由
 		formatted := v1.Group("/formatted")
@@ -107,16 +308,78 @@ func SetupRouter(collectorService *service.CollectorService, backupService *serv
 			formatted.POST("/fast", formattedHandler.FastFormatted)
 		}
 
+		// 按任务导出格式化结果（CSV/XLSX）
+		v1.GET("/format/tasks/:task_id/export", formattedHandler.ExportTask)
+
+		// TextFSM 模板库管理
+		templates := v1.Group("/format/templates")
+		{
+			templates.GET("", templateHandler.ListTemplates)
+			templates.POST("", templateHandler.CreateTemplate)
+			templates.POST("/import", templateHandler.ImportTemplates)
+			templates.GET("/:id", templateHandler.GetTemplate)
+			templates.PUT("/:id", templateHandler.UpdateTemplate)
+			templates.DELETE("/:id", templateHandler.DeleteTemplate)
+			templates.POST("/:id/impact", templateHandler.TemplateImpact)
+		}
+		// 兼容路径：模板影响评估
+		v1.POST("/fsm-templates/:id/impact", templateHandler.TemplateImpact)
+
+		// 外部解析服务登记（平台+命令交由外部 HTTP 服务解析）
+		parsers := v1.Group("/format/parsers")
+		{
+			parsers.GET("", templateHandler.ListParserHooks)
+			parsers.POST("", templateHandler.CreateParserHook)
+			parsers.PUT("/:id", templateHandler.UpdateParserHook)
+			parsers.DELETE("/:id", templateHandler.DeleteParserHook)
+		}
+
 		// 部署路由
 		v1.POST("/deploy/fast", deployHandler.FastDeploy)
 
+		// 配置合规检查
+		compliance := v1.Group("/compliance")
+		{
+			compliance.POST("/run", complianceHandler.RunCompliance)
+			compliance.GET("/rules", complianceHandler.ListRules)
+			compliance.POST("/rules", complianceHandler.CreateRule)
+			compliance.PUT("/rules/:id", complianceHandler.UpdateRule)
+			compliance.DELETE("/rules/:id", complianceHandler.DeleteRule)
+			compliance.GET("/reports", complianceHandler.ListReports)
+			compliance.GET("/reports/:id", complianceHandler.GetReport)
+		}
+
 		// 管理路由：设备类型默认参数
 		admin := v1.Group("/admin")
 		{
 			admin.GET("/device-defaults", adminHandler.GetDeviceDefaults)
 			admin.PUT("/device-defaults/:platform", adminHandler.UpdateDeviceDefaults)
+			// 平台能力标记（生效值与来源）
+			admin.GET("/capabilities", adminHandler.ListCapabilities)
+			admin.GET("/capabilities/:platform", adminHandler.GetCapabilities)
+			// 配置变更记录
+			admin.GET("/config/changelog", adminHandler.GetConfigChangelog)
+			// 只读模式开关
+			admin.GET("/read-only", adminHandler.GetReadOnly)
+			admin.PUT("/read-only", adminHandler.UpdateReadOnly)
+			// SSH 连接池查看与驱逐
+			admin.GET("/ssh-pool", adminHandler.GetSSHPool)
+			admin.DELETE("/ssh-pool/connections", adminHandler.EvictSSHPool)
+			// 设备主机密钥指纹
+			admin.GET("/host-keys", handler.ListHostKeys)
+			admin.DELETE("/host-keys", handler.DeleteHostKeys)
+			admin.DELETE("/host-keys/:id", handler.DeleteHostKey)
+			// 旧字段使用统计
+			admin.GET("/deprecations", adminHandler.GetDeprecationUsage)
 		}
 
+		// 诊断包（需 debug.token 管理令牌）
+		v1.GET("/debug/bundle", debugHandler.Bundle)
+
+		// 首次初始化（完成后锁定）
+		v1.GET("/bootstrap", bootstrapHandler.GetBootstrap)
+		v1.POST("/bootstrap", bootstrapHandler.RunBootstrap)
+
 		// SSH适配管理
 		ssh := v1.Group("/ssh-adapter")
 		{
@@ -171,6 +434,9 @@ func SetupRouter(collectorService *service.CollectorService, backupService *serv
 		// 兼容前端已存在路径：/simulate/config
 		v1.GET("/simulate/config", simulateConfigHandler.GetSimulateConfig)
 		v1.POST("/simulate/config", simulateConfigHandler.SaveSimulateConfig)
+		// 模拟夹具包：按命名空间导出/导入配置与回显
+		v1.GET("/simulate/bundles/:namespace", simulateConfigHandler.ExportBundle)
+		v1.POST("/simulate/bundles", simulateConfigHandler.ImportBundle)
 
 		// 日志查询
 		v1.GET("/logs/tail", logsHandler.TailLogs)
@@ -193,8 +459,8 @@ func CORSMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		c.Header("Access-Control-Allow-Origin", "*")
 		c.Header("Access-Control-Allow-Credentials", "true")
-		c.Header("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With, X-Request-ID")
-		c.Header("Access-Control-Allow-Methods", "POST, OPTIONS, GET, PUT, DELETE")
+		c.Header("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With, X-Request-ID, X-Tenant-ID")
+		c.Header("Access-Control-Allow-Methods", "POST, OPTIONS, GET, PUT, PATCH, DELETE")
 
 		if c.Request.Method == "OPTIONS" {
 			c.AbortWithStatus(204)
@@ -218,6 +484,37 @@ func RequestIDMiddleware() gin.HandlerFunc {
 	}
 }
 
+// TracingMiddleware 为 API 请求创建服务端 span，并承接上游 traceparent；未启用追踪时为 noop
+func TracingMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !strings.HasPrefix(c.Request.URL.Path, "/api/") {
+			c.Next()
+			return
+		}
+		ctx := otel.GetTextMapPropagator().Extract(c.Request.Context(), propagation.HeaderCarrier(c.Request.Header))
+		route := c.FullPath()
+		if route == "" {
+			route = c.Request.URL.Path
+		}
+		ctx, span := otel.Tracer("github.com/sshcollectorpro/sshcollectorpro/api").Start(ctx, c.Request.Method+" "+route,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				attribute.String("http.request.method", c.Request.Method),
+				attribute.String("http.route", route),
+				attribute.String("request.id", c.GetString("request_id")),
+			),
+		)
+		defer span.End()
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+		status := c.Writer.Status()
+		span.SetAttributes(attribute.Int("http.response.status_code", status))
+		if status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(status))
+		}
+	}
+}
+
 // LoggingMiddleware 日志中间件
 func LoggingMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"fmt"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
@@ -11,7 +12,9 @@ import (
 	"time"
 
 	"github.com/fsnotify/fsnotify"
+	"google.golang.org/grpc"
 
+	"github.com/sshcollectorpro/sshcollectorpro/api/handler"
 	"github.com/sshcollectorpro/sshcollectorpro/api/router"
 	"github.com/sshcollectorpro/sshcollectorpro/internal/config"
 	"github.com/sshcollectorpro/sshcollectorpro/internal/database"
@@ -44,6 +47,12 @@ func main() {
 	}
 
 	logger.Info("Starting SSH Collector Pro Server", "version", "1.0.0")
+	if files := config.OverlayFiles("configs/config.yaml"); len(files) > 0 {
+		logger.Info("Config overlays applied", "files", files, "env", os.Getenv(config.EnvName))
+	}
+	for _, w := range config.LastValidation().Warnings() {
+		logger.Warn("Config validation warning", "path", w.Path, "message", w.Message)
+	}
 
 	// 打印并发档位应用情况（按实际 workers 与 threads 输出）
 	workers := cfg.Collector.Concurrent
@@ -61,6 +70,40 @@ func main() {
 	}
 	defer database.Close()
 
+	// 初始化执行策略（只读模式等）
+	service.InitPolicy(cfg)
+
+	// 初始化消息总线事件发布（可选）
+	if err := service.InitEvents(cfg); err != nil {
+		logger.Warn("Failed to initialize event publisher", "error", err)
+	}
+	defer service.CloseEvents()
+
+	// 初始化健康监控导出（Zabbix/HTTP，可选）
+	if err := service.InitMonitor(cfg); err != nil {
+		logger.Warn("Failed to initialize monitor exporter", "error", err)
+	}
+	defer service.CloseMonitor()
+
+	// 初始化链路追踪（OTLP，可选）
+	if err := service.InitTracing(cfg); err != nil {
+		logger.Warn("Failed to initialize tracing", "error", err)
+	}
+	defer service.ShutdownTracing(context.Background())
+
+	// 高可用选主（可选）：仅 leader 执行定时任务
+	if err := service.InitHA(cfg); err != nil {
+		logger.Fatal("Failed to initialize HA leader election", "error", err)
+	}
+	defer service.CloseHA()
+
+	// 命令耗时与输出大小日汇总（可选）
+	service.InitCommandStats(cfg)
+	defer service.CloseCommandStats()
+
+	// 共用 SSH 连接池（ssh.connection_cache）归进程所有：在所有服务停止后关闭一次
+	defer service.CloseSharedSSHPool()
+
 	// 创建采集器服务
 	collectorService := service.NewCollectorService(cfg)
 	ctx := context.Background()
@@ -90,6 +133,13 @@ func main() {
 	}
 	defer deployService.Stop()
 
+	// 创建 NetBox 资产同步服务（启用且配置 interval 时定时同步）
+	inventoryService := service.NewInventorySyncService(cfg, handler.NewInventoryStore())
+	if err := inventoryService.Start(ctx); err != nil {
+		logger.Fatal("Failed to start inventory sync service", "error", err)
+	}
+	defer inventoryService.Stop()
+
 	// 启动模拟服务（可选）
 	var simMgr *simulate.Manager
 	if cfg.Server.SimulateEnable {
@@ -124,7 +174,19 @@ func main() {
 	}()
 
 	// 设置路由
-	r := router.SetupRouter(collectorService, backupService, formatService, deployService)
+	r := router.SetupRouter(collectorService, backupService, formatService, deployService, inventoryService)
+
+	// 中断任务恢复由 leader 执行：启动时已是 leader 立即执行（须在接收请求前），之后每次接任 leader 时再执行，接管故障实例遗留的记录
+	// - 批量任务：未结束的标记为 interrupted，按配置自动续跑
+	// - 运行手册：未结束的执行记录标记为 interrupted，可经接口续跑
+	batchJobHandler := handler.NewBatchJobHandler(handler.NewCollectorHandler(collectorService), backupService)
+	service.OnLeaderElected(func() {
+		// 接任 leader 可能发生在配置热加载之后，按当前配置决定是否续跑
+		if c := config.Get(); c != nil && c.BatchResume.Enable {
+			batchJobHandler.RecoverBatchJobs(c.BatchResume.Auto)
+		}
+		handler.RecoverRunbookRuns()
+	})
 
 	// 创建HTTP服务器
 	server := &http.Server{
@@ -143,6 +205,38 @@ func main() {
 		}
 	}()
 
+	// 启动 gRPC 服务器（可选）
+	var grpcServer *grpc.Server
+	if cfg.Server.GRPC.Enable {
+		lis, err := net.Listen("tcp", fmt.Sprintf(":%d", cfg.Server.GRPC.Port))
+		if err != nil {
+			logger.Fatal("Failed to listen for gRPC", "port", cfg.Server.GRPC.Port, "error", err)
+		}
+		grpcServer = grpc.NewServer()
+		handler.NewGRPCServer(collectorService, backupService, formatService, deployService).Register(grpcServer)
+		go func() {
+			logger.Info("gRPC server starting", "port", cfg.Server.GRPC.Port)
+			if err := grpcServer.Serve(lis); err != nil {
+				logger.Error("gRPC server stopped", "error", err)
+			}
+		}()
+	}
+
+	// 启动诊断监听器（可选）：pprof、expvar 与运行时诊断，需 debug.token
+	var debugServer *http.Server
+	if addr := strings.TrimSpace(cfg.Debug.Listen); addr != "" {
+		debugServer = &http.Server{
+			Addr:    addr,
+			Handler: handler.NewDiagnosticsHandler(func() string { return config.Get().Debug.Token }, collectorService, backupService, formatService),
+		}
+		go func() {
+			logger.Info("Diagnostics server starting", "listen", addr)
+			if err := debugServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				logger.Error("Diagnostics server stopped", "error", err)
+			}
+		}()
+	}
+
 	// 配置文件监听与热更新
 	go func() {
 		watcher, err := fsnotify.NewWatcher()
@@ -156,16 +250,25 @@ func main() {
 			logger.Warn("Config watch add failed", "error", err)
 			return
 		}
+		// 启动时已存在的叠加文件一并监听（新增叠加文件需重启或修改基础文件后生效）
+		for _, p := range config.OverlayFiles(path) {
+			if err := watcher.Add(p); err != nil {
+				logger.Warn("Config overlay watch add failed", "path", p, "error", err)
+			}
+		}
 		var debounce *time.Timer
 		debounceInterval := 300 * time.Millisecond
 		trigger := func() {
-			newCfg, err := config.Load(path)
+			// 整体替换配置快照（不原地覆盖，避免并发读取到更新一半的结构）
+			old := config.Get()
+			cfg, changed, err := config.Reload(path)
 			if err != nil {
 				logger.Warn("Config reload failed", "error", err)
 				return
 			}
-			// 原地覆盖，保持指针不变
-			*cfg = *newCfg
+			for _, w := range config.LastValidation().Warnings() {
+				logger.Warn("Config validation warning", "path", w.Path, "message", w.Message)
+			}
 			// 刷新日志配置
 			_ = logger.Init(logger.Config{
 				Level:      cfg.Log.Level,
@@ -177,7 +280,22 @@ func main() {
 				MaxAge:     cfg.Log.MaxAge,
 				Compress:   cfg.Log.Compress,
 			})
-			logger.Info("Config reloaded")
+			logger.Info("Config reloaded", "changed", changed)
+			// 同步执行策略（运行时切换的只读状态以配置文件为准重新初始化）
+			service.InitPolicy(cfg)
+			// 重建事件发布器（消息总线地址或 topic 可能变化）
+			if err := service.InitEvents(cfg); err != nil {
+				logger.Warn("Failed to reinitialize event publisher", "error", err)
+			}
+			if err := service.InitMonitor(cfg); err != nil {
+				logger.Warn("Failed to reinitialize monitor exporter", "error", err)
+			}
+			if err := service.InitTracing(cfg); err != nil {
+				logger.Warn("Failed to reinitialize tracing", "error", err)
+			}
+			service.InitCommandStats(cfg)
+			// 运行中的服务：调整并发名额与连接池，存储配置变化时重建客户端
+			service.ApplyReload(old, cfg, collectorService, backupService, formatService)
 			// 模拟开关变化时动态启停
 			if cfg.Server.SimulateEnable && simMgr == nil {
 				simPath := "simulate/simulate.yaml"
@@ -239,7 +357,7 @@ func main() {
 				logger.Warn("Simulate: reload simulate.yaml failed", "error", err)
 				return
 			}
-			if !cfg.Server.SimulateEnable {
+			if !config.Get().Server.SimulateEnable {
 				logger.Info("Simulate: reload ignored, simulate disabled")
 				return
 			}
@@ -285,6 +403,22 @@ func main() {
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
+	if grpcServer != nil {
+		// 等待进行中的流式调用结束，超时后强制关闭
+		stopped := make(chan struct{})
+		go func() {
+			grpcServer.GracefulStop()
+			close(stopped)
+		}()
+		select {
+		case <-stopped:
+		case <-ctx.Done():
+			grpcServer.Stop()
+		}
+	}
+	if debugServer != nil {
+		_ = debugServer.Shutdown(ctx)
+	}
 	if err := server.Shutdown(ctx); err != nil {
 		logger.Error("Server forced to shutdown", "error", err)
 	} else {
@@ -22,6 +22,20 @@ type Config struct {
 	Backup     BackupConfig     `mapstructure:"backup"`
 	DataFormat DataFormatConfig `mapstructure:"data_format"`
 	Deploy     DeployConfig     `mapstructure:"deploy"`
+	Callback   CallbackConfig   `mapstructure:"callback"`
+	Vault      VaultConfig      `mapstructure:"vault"`
+	Policy     PolicyConfig     `mapstructure:"policy"`
+	Events     EventsConfig     `mapstructure:"events"`
+	NetBox     NetBoxConfig     `mapstructure:"netbox"`
+	Monitor    MonitorConfig    `mapstructure:"monitor"`
+	Debug      DebugConfig      `mapstructure:"debug"`
+	BatchResume BatchResumeConfig `mapstructure:"batch_resume"`
+	SNMP       SNMPConfig       `mapstructure:"snmp"`
+	BatchResponse BatchResponseConfig `mapstructure:"batch_response"`
+	Approval   ApprovalConfig   `mapstructure:"approval"`
+	Tracing    TracingConfig    `mapstructure:"tracing"`
+	HA         HAConfig         `mapstructure:"ha"`
+	Metrics    MetricsConfig    `mapstructure:"metrics"`
 }
 
 // ServerConfig 服务器配置
@@ -32,6 +46,25 @@ type ServerConfig struct {
 	ReadTimeout  time.Duration `mapstructure:"read_timeout"`
 	WriteTimeout time.Duration `mapstructure:"write_timeout"`
 	SimulateEnable bool        `mapstructure:"simulate_enable"`
+	SimulateRecord SimulateRecordConfig `mapstructure:"simulate_record"`
+	GRPC         GRPCConfig    `mapstructure:"grpc"`
+	// StrictJSON 采集、备份、格式化等任务接口拒绝请求体中的未知字段（请求头 X-Strict-JSON 可按请求覆盖）
+	StrictJSON   bool          `mapstructure:"strict_json"`
+	// StreamAllowedOrigins 流式采集 WebSocket 允许的跨站 Origin（如 https://noc.example.com，"*" 为不限制）；同源与不带 Origin 的客户端始终允许
+	StreamAllowedOrigins []string `mapstructure:"stream_allowed_origins"`
+}
+
+// SimulateRecordConfig 采集录制：将真实设备的命令回显写入模拟器目录（simulate/namespace/<ns>/<device>）
+type SimulateRecordConfig struct {
+	Enable bool `mapstructure:"enable"`
+	// RedactPatterns 额外脱敏正则（在内置密码/密钥规则之外）；含捕获组时保留第 1 组，其余替换为 ******
+	RedactPatterns []string `mapstructure:"redact_patterns"`
+}
+
+// GRPCConfig gRPC 接口配置（与 HTTP 接口并行提供）
+type GRPCConfig struct {
+	Enable bool `mapstructure:"enable"`
+	Port   int  `mapstructure:"port"`
 }
 
 // CollectorConfig 采集器配置
@@ -55,6 +88,59 @@ type CollectorConfig struct {
 	Interact InteractConfig `mapstructure:"interact"`
 	// DeviceDefaults 按设备平台加载的交互/适配参数（提示符、分页、enable、自动交互）
 	DeviceDefaults map[string]PlatformDefaultsConfig `mapstructure:"device_defaults"`
+	// PlatformMappings 请求平台字符串归一规则（如 "Cisco IOS-XE 17.3" -> cisco_ios），按顺序匹配首条
+	PlatformMappings []PlatformMappingConfig `mapstructure:"platform_mappings"`
+	// UnknownPrompt 未知确认提示检测：未被自动交互应答的确认提示按安全应答中断
+	UnknownPrompt UnknownPromptConfig `mapstructure:"unknown_prompt"`
+	// PlatformDetect 请求未指定平台时登录探测厂商平台
+	PlatformDetect PlatformDetectConfig `mapstructure:"platform_detect"`
+	// DuplicateDevices 批内重复设备（IP+端口+用户名相同）处理方式：reject | merge | copy，请求 duplicate_mode 优先
+	DuplicateDevices string `mapstructure:"duplicate_devices"`
+	// RetryPolicy 设备级重试的退避与重试条件（次数仍由 retry_flag / retry_flags 决定），平台 retry_policy 可逐项覆盖
+	RetryPolicy RetryPolicyConfig `mapstructure:"retry_policy"`
+}
+
+// PlatformDetectConfig 平台自动探测：device_platform 为 "auto" 时总是探测，为空时由 enable 控制
+type PlatformDetectConfig struct {
+	Enable bool `mapstructure:"enable"`
+	// CacheTTL 探测结果按 设备IP:端口 缓存的时长；0 表示不缓存
+	CacheTTL time.Duration `mapstructure:"cache_ttl"`
+}
+
+// 重试退避方式与重试条件取值
+const (
+	RetryBackoffConstant    = "constant"
+	RetryBackoffLinear      = "linear"
+	RetryBackoffExponential = "exponential"
+
+	RetryOnAny     = "any"     // 任意错误均重试
+	RetryOnConnect = "connect" // 仅设备不可达/建连超时重试（认证失败、命令错误不重试）
+)
+
+// RetryPolicyConfig 重试策略；平台配置中未设置（零值）的字段沿用全局配置
+type RetryPolicyConfig struct {
+	Backoff      string        `mapstructure:"backoff"`       // constant | linear | exponential
+	InitialDelay time.Duration `mapstructure:"initial_delay"` // 首次重试前的等待
+	MaxDelay     time.Duration `mapstructure:"max_delay"`     // 单次等待上限，0 不限制
+	Multiplier   float64       `mapstructure:"multiplier"`    // exponential 每次等待的倍数
+	Jitter       float64       `mapstructure:"jitter"`        // 等待时长随机浮动比例（0~1）
+	MaxElapsed   time.Duration `mapstructure:"max_elapsed"`   // 自首次尝试起的总时长上限，超过后不再重试；0 不限制
+	RetryOn      string        `mapstructure:"retry_on"`      // any | connect
+}
+
+// PlatformMappingConfig 平台映射规则：pattern 为正则（大小写不敏感），platform 为 device_defaults 平台键
+type PlatformMappingConfig struct {
+	Pattern  string `mapstructure:"pattern"`
+	Platform string `mapstructure:"platform"`
+}
+
+// UnknownPromptConfig 未知确认提示检测配置
+type UnknownPromptConfig struct {
+	Enable bool `mapstructure:"enable"`
+	// Patterns 输出末行匹配的正则（大小写不敏感）；为空时使用内置模式
+	Patterns []string `mapstructure:"patterns"`
+	// Response 安全应答：默认 N；ctrl-c 表示发送 Ctrl-C
+	Response string `mapstructure:"response"`
 }
 
 // ConcurrencyProfileConfig 并发档位配置：并发与线程数
@@ -66,6 +152,8 @@ type ConcurrencyProfileConfig struct {
 // DatabaseConfig 数据库配置
 type DatabaseConfig struct {
 	SQLite SQLiteConfig `mapstructure:"sqlite"`
+	// TaskRetention 任务记录与任务日志保留时长，超期记录定期清理；0 表示不清理
+	TaskRetention time.Duration `mapstructure:"task_retention"`
 }
 
 // SQLiteConfig SQLite配置
@@ -79,6 +167,8 @@ type SQLiteConfig struct {
 // StorageConfig 采集数据存储配置（用于原始与格式化数据）
 type StorageConfig struct {
 	Minio    MinioConfig    `mapstructure:"minio"`
+	S3       S3Config       `mapstructure:"s3"`
+	Azure    AzureConfig    `mapstructure:"azure"`
 	Postgres PostgresConfig `mapstructure:"postgres"`
 }
 
@@ -86,6 +176,44 @@ type StorageConfig struct {
 type DataFormatConfig struct {
 	// MinioPrefix 用于格式化数据在 MinIO 中的顶层路径（不含 bucket）
 	MinioPrefix string `mapstructure:"minio_prefix"`
+	// TemplateDir 模板库文件目录（/api/v1/format/templates 管理的 .textfsm 文件）
+	TemplateDir string `mapstructure:"template_dir"`
+	// Timeseries 解析后数值字段的时序库输出（可选）
+	Timeseries TimeseriesConfig `mapstructure:"timeseries"`
+	// ExternalParser 外部解析服务调用参数（平台+命令的登记在模板库中管理）
+	ExternalParser ExternalParserConfig `mapstructure:"external_parser"`
+	// FSM 本地模板解析的并行度与编译缓存
+	FSM FSMParseConfig `mapstructure:"fsm"`
+}
+
+// FSMParseConfig 本地 FSM 解析参数
+type FSMParseConfig struct {
+	// Workers 解析 worker 数（CPU 密集，独立于设备并发）；0 表示 CPU 核数
+	Workers int `mapstructure:"workers"`
+	// CacheSize 已编译模板缓存条数（按模板内容哈希）
+	CacheSize int `mapstructure:"cache_size"`
+}
+
+// ExternalParserConfig 外部 HTTP 解析服务的默认超时、重试与熔断参数
+type ExternalParserConfig struct {
+	// Timeout 单次请求超时；登记项未指定时使用
+	Timeout time.Duration `mapstructure:"timeout"`
+	// Retries 网络错误、5xx 或 429 时的重试次数；登记项未指定时使用
+	Retries int `mapstructure:"retries"`
+	// FailureThreshold 连续失败达到该次数后熔断；Cooldown 熔断持续时间，期间直接回退本地模板
+	FailureThreshold int           `mapstructure:"failure_threshold"`
+	Cooldown         time.Duration `mapstructure:"cooldown"`
+}
+
+// TimeseriesConfig 时序库输出配置
+type TimeseriesConfig struct {
+	Enable   bool          `mapstructure:"enable"`
+	Backend  string        `mapstructure:"backend"` // influxdb | prometheus
+	URL      string        `mapstructure:"url"`     // 完整写入地址（InfluxDB /api/v2/write?... 或 Prometheus remote-write 地址）
+	Token    string        `mapstructure:"token"`   // InfluxDB Token 或 Bearer Token
+	Username string        `mapstructure:"username"`
+	Password string        `mapstructure:"password"`
+	Timeout  time.Duration `mapstructure:"timeout"`
 }
 
 // DeployConfig 部署相关配置
@@ -94,15 +222,227 @@ type DeployConfig struct {
 	DeployWaitMS int `mapstructure:"deploy_wait_ms"`
 }
 
+// CallbackConfig 任务完成回调配置（批量接口 callback_url）
+type CallbackConfig struct {
+	// Secret HMAC-SHA256 签名密钥；为空时不附加签名头，支持 ${ENV} 引用
+	Secret     string        `mapstructure:"secret"`
+	Retries    int           `mapstructure:"retries"`
+	Timeout    time.Duration `mapstructure:"timeout"`
+	RetryDelay time.Duration `mapstructure:"retry_delay"`
+}
+
+// EventsConfig 消息总线事件发布配置：设备级采集/备份/下发完成后推送事件
+type EventsConfig struct {
+	Enable bool `mapstructure:"enable"`
+	// Backend 消息总线类型：kafka | nats
+	Backend string `mapstructure:"backend"`
+	// Brokers kafka 为 host:port 列表；nats 为 nats://host:4222 列表
+	Brokers []string `mapstructure:"brokers"`
+	// Topics 各类事件的 topic（nats 为 subject）；为空表示不发布该类事件
+	Topics EventTopicsConfig `mapstructure:"topics"`
+	// NATS 认证（可选），支持 ${ENV} 引用
+	Username string `mapstructure:"username"`
+	Password string `mapstructure:"password"`
+	Token    string `mapstructure:"token"`
+	// Timeout 单条事件发送超时
+	Timeout time.Duration `mapstructure:"timeout"`
+	// QueueSize 待发送事件队列长度，队列满时丢弃并记录日志
+	QueueSize int `mapstructure:"queue_size"`
+}
+
+// EventTopicsConfig 各类事件的 topic
+type EventTopicsConfig struct {
+	Collect string `mapstructure:"collect"`
+	Backup  string `mapstructure:"backup"`
+	Deploy  string `mapstructure:"deploy"`
+}
+
+// MonitorConfig 设备健康监控导出：将设备级采集/备份/下发结果推送到 Zabbix 或 HTTP 接收端
+type MonitorConfig struct {
+	Enable bool `mapstructure:"enable"`
+	// Backend 导出方式：zabbix（sender 协议）| http（JSON POST）
+	Backend string `mapstructure:"backend"`
+	// Zabbix server/proxy 地址 host:port（默认端口 10051）
+	ZabbixServer string `mapstructure:"zabbix_server"`
+	// HTTP 推送地址与附加请求头
+	URL     string            `mapstructure:"url"`
+	Headers map[string]string `mapstructure:"headers"`
+	// HostTemplate 监控主机名模板；KeyTemplate 监控项 key 模板
+	// 可用占位符：{device_ip} {device_name} {device_platform} {type} {metric} {task_id} {collector_id}
+	HostTemplate string `mapstructure:"host_template"`
+	KeyTemplate  string `mapstructure:"key_template"`
+	// BatchSize 单次推送的最大监控值数；FlushInterval 未满批时的最长等待
+	BatchSize     int           `mapstructure:"batch_size"`
+	FlushInterval time.Duration `mapstructure:"flush_interval"`
+	Timeout       time.Duration `mapstructure:"timeout"`
+	QueueSize     int           `mapstructure:"queue_size"`
+}
+
+// TracingConfig OpenTelemetry 链路追踪配置（OTLP/HTTP 导出）
+type TracingConfig struct {
+	Enable bool `mapstructure:"enable"`
+	// Endpoint OTLP/HTTP 接收地址 host:port（如 localhost:4318）；Insecure 使用 http 而非 https
+	Endpoint string `mapstructure:"endpoint"`
+	Insecure bool   `mapstructure:"insecure"`
+	// ServiceName 上报的 service.name；为空时使用 collector.id
+	ServiceName string `mapstructure:"service_name"`
+	// SampleRatio 根 span 采样比例（0~1）；上游携带 traceparent 时跟随上游采样决定
+	SampleRatio float64           `mapstructure:"sample_ratio"`
+	Headers     map[string]string `mapstructure:"headers"`
+	Timeout     time.Duration     `mapstructure:"timeout"`
+}
+
+// HAConfig 双实例高可用：按租约选主，仅 leader 执行定时任务，两个实例均提供接口
+type HAConfig struct {
+	Enable bool `mapstructure:"enable"`
+	// Backend 租约存储：db（共享 SQLite 库的 ha_leases 表）| file（共享目录下的租约文件）
+	Backend string `mapstructure:"backend"`
+	// LeaseName 租约名，同组实例须一致；FilePath file 后端的租约文件路径
+	LeaseName string `mapstructure:"lease_name"`
+	FilePath  string `mapstructure:"file_path"`
+	// InstanceID 本实例标识；为空时使用 主机名-进程号
+	InstanceID string `mapstructure:"instance_id"`
+	// LeaseDuration 租约有效期（leader 失联后的最长切换时间）；RenewInterval 续约/抢占间隔，须小于 LeaseDuration
+	LeaseDuration time.Duration `mapstructure:"lease_duration"`
+	RenewInterval time.Duration `mapstructure:"renew_interval"`
+}
+
+// MetricsConfig 按平台统计命令耗时与输出大小分布（内存直方图，/api/v1/collector/stats 与 /metrics 输出）
+type MetricsConfig struct {
+	// Enable 提供 Prometheus 文本格式的 /metrics 接口
+	Enable bool `mapstructure:"enable"`
+	// PersistDaily 按天汇总写入数据库（command_stats_daily），FlushInterval 为写入间隔
+	PersistDaily  bool          `mapstructure:"persist_daily"`
+	FlushInterval time.Duration `mapstructure:"flush_interval"`
+	// RetentionDays 日汇总保留天数（leader 清理），0 表示不清理
+	RetentionDays int `mapstructure:"retention_days"`
+}
+
+// DebugConfig 诊断包（/api/v1/debug/bundle）配置
+type DebugConfig struct {
+	// Token 管理令牌（请求头 X-Admin-Token 或 Authorization: Bearer），支持 ${ENV} 引用；为空时接口不可用
+	Token string `mapstructure:"token"`
+	// Window 默认采集最近多长时间内的日志与失败任务；请求可用 since 缩短或延长（最长 24h）
+	Window time.Duration `mapstructure:"window"`
+	// LogLines 日志最多保留的末尾行数；FailedTasks 失败任务摘要最大条数
+	LogLines    int `mapstructure:"log_lines"`
+	FailedTasks int `mapstructure:"failed_tasks"`
+	// Listen 诊断监听地址（如 127.0.0.1:6060），提供 pprof、expvar 与运行时诊断；为空时不启动
+	Listen string `mapstructure:"listen"`
+}
+
+// BatchResumeConfig 批量任务断点续跑：请求以 vault 主密钥加密后写入 batch_jobs，逐设备记录完成状态
+type BatchResumeConfig struct {
+	Enable bool `mapstructure:"enable"`
+	// Auto 启动时自动续跑上次进程中断的批量任务；否则通过 /api/v1/batch-jobs/{id}/resume 手动续跑
+	Auto bool `mapstructure:"auto"`
+}
+
+// BatchResponseConfig 批量响应中原始输出的默认返回方式（请求 raw_output_mode/max_output_kb 优先）
+type BatchResponseConfig struct {
+	RawOutputMode string `mapstructure:"raw_output_mode"` // full | omit | truncate | uri
+	MaxOutputKB   int    `mapstructure:"max_output_kb"`   // truncate 模式单条命令保留的 KB 数
+}
+
+// ApprovalConfig 下发审批：待审批的下发推送企业微信/钉钉卡片，审批人点击签名链接批准或驳回
+type ApprovalConfig struct {
+	Enable bool `mapstructure:"enable"`
+	// DeployRequired 所有 exec 下发均需审批；为 false 时仅请求 require_approval=true 的下发需要审批
+	DeployRequired bool `mapstructure:"deploy_required"`
+	// Secret 审批链接 HMAC-SHA256 签名密钥，支持 ${ENV} 引用；为空时审批链接不可用
+	Secret string `mapstructure:"secret"`
+	// BaseURL 本服务对审批人可达的地址（如 https://collector.example.com），用于生成卡片按钮链接
+	BaseURL string `mapstructure:"base_url"`
+	// TTL 审批有效期，超时未处理的审批作废
+	TTL      time.Duration         `mapstructure:"ttl"`
+	WeCom    ApprovalChannelConfig `mapstructure:"wecom"`
+	DingTalk ApprovalChannelConfig `mapstructure:"dingtalk"`
+}
+
+// ApprovalChannelConfig 群机器人 webhook；钉钉机器人开启加签时填写 secret
+type ApprovalChannelConfig struct {
+	WebhookURL string `mapstructure:"webhook_url"`
+	Secret     string `mapstructure:"secret"`
+}
+
+// SNMPConfig SNMP 采集配置（collect_protocol=snmp）
+type SNMPConfig struct {
+	Port           int           `mapstructure:"port"`
+	Timeout        time.Duration `mapstructure:"timeout"` // 单次请求等待应答的超时
+	Retries        int           `mapstructure:"retries"`
+	MaxRepetitions int           `mapstructure:"max_repetitions"` // GetBulk 每次返回的行数
+	// OIDSets 具名 OID 集合，覆盖或补充内置的 system、interfaces
+	OIDSets map[string][]SNMPOIDConfig `mapstructure:"oid_sets"`
+}
+
+// SNMPOIDConfig OID 集合中的一项；walk 表示遍历表列
+type SNMPOIDConfig struct {
+	Name string `mapstructure:"name"`
+	OID  string `mapstructure:"oid"`
+	Walk bool   `mapstructure:"walk"`
+}
+
+// NetBoxConfig NetBox 资产同步配置：拉取设备、平台与主 IP 写入 device_info
+type NetBoxConfig struct {
+	Enable bool `mapstructure:"enable"`
+	// URL NetBox 地址，如 https://netbox.example.com
+	URL string `mapstructure:"url"`
+	// Token API Token，支持 ${ENV} 引用
+	Token string `mapstructure:"token"`
+	// Interval 定时同步周期；0 表示仅按需同步
+	Interval time.Duration `mapstructure:"interval"`
+	// Timeout 单次 API 请求超时
+	Timeout            time.Duration `mapstructure:"timeout"`
+	PageSize           int           `mapstructure:"page_size"`
+	InsecureSkipVerify bool          `mapstructure:"insecure_skip_verify"`
+	// Filters 设备查询过滤参数，如 status: active、site: dc1
+	Filters map[string]string `mapstructure:"filters"`
+	// PlatformMap NetBox 平台 slug -> 本地平台名；RoleMap 设备角色 slug -> 本地平台名（平台未映射时使用）
+	PlatformMap map[string]string `mapstructure:"platform_map"`
+	RoleMap     map[string]string `mapstructure:"role_map"`
+	// DefaultPlatform 平台与角色均未映射时使用；为空则跳过该设备
+	DefaultPlatform string `mapstructure:"default_platform"`
+	DefaultPort     int    `mapstructure:"default_port"`
+	// DisableMissing 由 NetBox 同步创建、但本次未拉取到的设备置为禁用
+	DisableMissing bool `mapstructure:"disable_missing"`
+}
+
+// VaultConfig 凭据保险箱配置
+type VaultConfig struct {
+	// MasterKey 主密钥（任意长度，经 SHA-256 派生为 AES-256 密钥）；支持 ${ENV} 引用，
+	// 也可通过环境变量 SSH_COLLECTOR_VAULT_MASTER_KEY 提供
+	MasterKey string `mapstructure:"master_key"`
+}
+
+// PolicyConfig 执行策略配置
+type PolicyConfig struct {
+	// ReadOnly 只读模式：禁止配置下发与进入配置模式，仍允许采集/备份/格式化
+	ReadOnly bool `mapstructure:"read_only"`
+}
+
 // BackupConfig 备份服务配置
 type BackupConfig struct {
-	// StorageBackend 默认存储后端：local | minio
+	// StorageBackend 默认存储后端：local | minio | s3 | azure
 	StorageBackend string `mapstructure:"storage_backend"`
 	// Prefix 顶层保存目录前缀（与请求中的 save_dir 组合）
 	Prefix string            `mapstructure:"prefix"`
 	Local  LocalBackupConfig `mapstructure:"local"`
 	// Aggregate 聚合配置（是否将所有 CLI 输出写入单一文件）
 	Aggregate AggregateConfig `mapstructure:"aggregate"`
+	// PathTemplate 设备目录模板（save_dir 之下，可含 /），为空时为 设备/日期_时间/task_id；
+	// 变量：${device_name} ${device_ip} ${platform} ${task_id} ${date} ${time}，以及平台 facts 中定义的事实（如 ${hostname} ${serial}）
+	PathTemplate string `mapstructure:"path_template"`
+	// FilenameTemplate 文件名模板，为空时为命令名；额外支持 ${command}，无扩展名时追加 .txt
+	FilenameTemplate string `mapstructure:"filename_template"`
+	// Transcript 会话转录：记录每台设备交互会话收发的全部字节，随备份保存
+	Transcript BackupTranscriptConfig `mapstructure:"transcript"`
+}
+
+// BackupTranscriptConfig 会话转录配置（请求中的 transcript 可覆盖 Enable）
+type BackupTranscriptConfig struct {
+	Enable bool `mapstructure:"enable"`
+	// MaxBytes 单台设备转录上限，超出后截断；0 为默认 8MB
+	MaxBytes int `mapstructure:"max_bytes"`
 }
 
 // LocalBackupConfig 本地存储配置
@@ -131,13 +471,44 @@ type MinioConfig struct {
 	Secure    bool   `mapstructure:"secure"`
 }
 
+// S3Config 通用 S3 兼容对象存储配置（AWS S3 及其他 S3 兼容服务）
+type S3Config struct {
+	// Endpoint 服务地址（可带端口），默认 s3.amazonaws.com
+	Endpoint string `mapstructure:"endpoint"`
+	Region   string `mapstructure:"region"`
+	Bucket   string `mapstructure:"bucket"`
+	// AccessKey/SecretKey 为空时依次读取 AWS 环境变量、~/.aws/credentials 与实例角色
+	AccessKey    string `mapstructure:"access_key"`
+	SecretKey    string `mapstructure:"secret_key"`
+	SessionToken string `mapstructure:"session_token"`
+	Secure       bool   `mapstructure:"secure"`
+	// PathStyle 使用路径风格访问（endpoint/bucket/key），多数自建 S3 兼容服务需要开启
+	PathStyle bool `mapstructure:"path_style"`
+}
+
+// AzureConfig Azure Blob 存储配置
+type AzureConfig struct {
+	AccountName string `mapstructure:"account_name"`
+	// AccountKey 共享密钥（Base64）；与 SASToken 二选一，SASToken 优先
+	AccountKey string `mapstructure:"account_key"`
+	SASToken   string `mapstructure:"sas_token"`
+	Container  string `mapstructure:"container"`
+	// Endpoint 服务地址，默认 https://{account_name}.blob.core.windows.net
+	Endpoint string `mapstructure:"endpoint"`
+}
+
 // PostgresConfig 格式化数据存储配置（PostgreSQL）
 type PostgresConfig struct {
+	// Enable 启用后批量格式化的解析记录写入 PostgreSQL（JSONB）
+	Enable   bool   `mapstructure:"enable"`
 	Host     string `mapstructure:"host"`
 	Port     int    `mapstructure:"port"`
 	Username string `mapstructure:"username"`
 	Password string `mapstructure:"password"`
 	Database string `mapstructure:"database"`
+	SSLMode  string `mapstructure:"sslmode"` // disable | require | verify-full 等
+	// Table 记录表名（可带 schema），不存在时自动创建
+	Table string `mapstructure:"table"`
 }
 
 // SSHConfig SSH配置
@@ -148,6 +519,102 @@ type SSHConfig struct {
 	KeepAliveInterval time.Duration `mapstructure:"keep_alive_interval"`
 	CleanupInterval   time.Duration `mapstructure:"cleanup_interval"`
 	MaxSessions       int           `mapstructure:"max_sessions"`
+	ConnectionCache   ConnectionCacheConfig `mapstructure:"connection_cache"`
+	Proxy             SSHProxyConfig        `mapstructure:"proxy"`
+	Pool              SSHPoolConfig         `mapstructure:"pool"`
+	Egress            SSHEgressConfig       `mapstructure:"egress"`
+	AuthThrottle      SSHAuthThrottleConfig `mapstructure:"auth_throttle"`
+	CircuitBreaker    SSHCircuitBreakerConfig `mapstructure:"circuit_breaker"`
+	HostKey           SSHHostKeyConfig      `mapstructure:"host_key"`
+}
+
+// 主机密钥校验模式
+const (
+	HostKeyInsecure   = "insecure"
+	HostKeyKnownHosts = "known_hosts"
+	HostKeyTOFU       = "tofu"
+	HostKeyStrict     = "strict"
+)
+
+// SSHHostKeyConfig 主机密钥校验：insecure 不校验（默认）；known_hosts 仅按 known_hosts 文件校验；
+// tofu 首次连接记录指纹（SQLite host_keys 表），之后必须一致；strict 仅接受 known_hosts 文件或已记录的指纹
+type SSHHostKeyConfig struct {
+	Mode       string                 `mapstructure:"mode"`
+	KnownHosts []string               `mapstructure:"known_hosts"` // known_hosts 文件路径，tofu/strict 下同样参与校验
+	Rules      []SSHHostKeyRuleConfig `mapstructure:"rules"`       // 按平台/设备覆盖 mode，按顺序匹配首条
+}
+
+// SSHHostKeyRuleConfig 校验模式规则：platform 与 match 均为空时匹配所有设备
+type SSHHostKeyRuleConfig struct {
+	Platform string `mapstructure:"platform"`
+	Match    string `mapstructure:"match"` // IP、CIDR、主机名或通配（如 core-*）
+	Mode     string `mapstructure:"mode"`
+}
+
+// SSHCircuitBreakerConfig 设备熔断：同一设备 IP 连续建连失败（不可达、超时）达到 failure_threshold 后，
+// cooldown 内不再拨号并直接返回 DEVICE_CIRCUIT_OPEN；冷却结束后放行一次试探连接，成功即恢复
+type SSHCircuitBreakerConfig struct {
+	Enable           bool          `mapstructure:"enable"`
+	FailureThreshold int           `mapstructure:"failure_threshold"`
+	Cooldown         time.Duration `mapstructure:"cooldown"`
+}
+
+// SSHAuthThrottleConfig 认证失败限流：同一凭据（用户名+密码）连续认证失败达到 max_failures 后，
+// 各服务在 cooldown 内暂停使用该凭据（CREDENTIAL_THROTTLED），避免 TACACS/设备账号被锁定
+type SSHAuthThrottleConfig struct {
+	Enable      bool          `mapstructure:"enable"`
+	MaxFailures int           `mapstructure:"max_failures"`
+	Cooldown    time.Duration `mapstructure:"cooldown"`
+	NotifyURL   string        `mapstructure:"notify_url"` // 凭据进入冷却期时推送回调（可选）
+}
+
+// SSHEgressConfig 出站连接策略：拨号前校验设备地址，deny 命中或不在 allow 内（allow 非空时）即拒绝（EGRESS_BLOCKED）
+type SSHEgressConfig struct {
+	Allow        []string                 `mapstructure:"allow"`         // IP、CIDR 或主机名
+	Deny         []string                 `mapstructure:"deny"`          // 优先于 allow
+	TenantHeader string                   `mapstructure:"tenant_header"` // 请求头中的租户标识
+	Tenants      map[string]SSHEgressRule `mapstructure:"tenants"`       // 租户规则，与全局规则同时生效
+}
+
+// SSHEgressRule 租户出站规则
+type SSHEgressRule struct {
+	Allow []string `mapstructure:"allow"`
+	Deny  []string `mapstructure:"deny"`
+}
+
+// SSHPoolConfig 连接池空闲策略：按设备限制空闲连接数，避免长时间空闲的连接占用设备 VTY
+type SSHPoolConfig struct {
+	MaxIdlePerHost int                 `mapstructure:"max_idle_per_host"` // 每台设备保留的空闲连接上限，0 不限
+	IdleRules      []SSHIdleRuleConfig `mapstructure:"idle_rules"`        // 按平台/主机覆盖，按顺序匹配首条
+	// MaxConnectionAge 连接最大存活时长，0 不限；超龄连接不再复用，由清理协程关闭（使用中的在释放时关闭）
+	MaxConnectionAge time.Duration `mapstructure:"max_connection_age"`
+}
+
+// SSHIdleRuleConfig 空闲规则：platform 与 match 均为空时匹配所有连接
+type SSHIdleRuleConfig struct {
+	Platform    string        `mapstructure:"platform"`     // 平台键
+	Match       string        `mapstructure:"match"`        // IP、CIDR、主机名或通配（如 core-*）
+	MaxIdle     *int          `mapstructure:"max_idle"`     // 每台设备空闲连接上限，0 表示用完即关闭；未设置沿用 max_idle_per_host
+	IdleTimeout time.Duration `mapstructure:"idle_timeout"` // 空闲时长，未设置沿用连接池默认
+}
+
+// SSHProxyConfig SSH 拨号代理：socks5://[user:pass@]host:port 或 http://[user:pass@]host:port（CONNECT）
+type SSHProxyConfig struct {
+	URL       string             `mapstructure:"url"`       // 全局代理，为空直连
+	Overrides []SSHProxyOverride `mapstructure:"overrides"` // 按设备覆盖，按顺序匹配
+}
+
+// SSHProxyOverride 设备级代理覆盖：match 为设备 IP 或 CIDR，url 为 direct 表示直连
+type SSHProxyOverride struct {
+	Match string `mapstructure:"match"`
+	URL   string `mapstructure:"url"`
+}
+
+// ConnectionCacheConfig 持久连接缓存：各服务共用连接池，按设备跨请求复用已认证连接
+type ConnectionCacheConfig struct {
+	Enable         bool          `mapstructure:"enable"`
+	TTL            time.Duration `mapstructure:"ttl"`             // 空闲连接保活时长
+	MaxConnections int           `mapstructure:"max_connections"` // 最多保留的空闲连接数
 }
 
 // LogConfig 日志配置
@@ -162,44 +629,88 @@ type LogConfig struct {
 	Compress   bool   `mapstructure:"compress"`
 }
 
-var globalConfig *Config
-
-// Load 加载配置文件
+// Load 加载配置文件，并发布为全局配置快照
 func Load(configPath string) (*Config, error) {
-	viper.SetConfigType("yaml")
+	cfg, err := parse(configPath)
+	if err != nil {
+		return nil, err
+	}
+	global.Swap(cfg, "file")
+	return cfg, nil
+}
+
+// Reload 重新加载配置文件并整体替换全局快照（不修改已发布的配置），返回新配置与变更字段
+func Reload(configPath string) (*Config, []string, error) {
+	cfg, err := parse(configPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	return cfg, global.Swap(cfg, "file"), nil
+}
+
+// parse 读取并解析配置文件；校验未通过时返回汇总全部问题的错误
+func parse(configPath string) (*Config, error) {
+	cfg, report, err := parseWith(viper.New(), configPath)
+	if err != nil {
+		return nil, err
+	}
+	if err := report.Err(); err != nil {
+		return nil, err
+	}
+	lastValidation.Store(report)
+	return cfg, nil
+}
+
+// parseWith 使用独立的 viper 实例读取、解析并校验配置（不影响全局配置，供试运行校验复用）；
+// 读取或解析失败返回 error，配置问题记录在报告中
+func parseWith(v *viper.Viper, configPath string) (*Config, *ValidationReport, error) {
+	report := &ValidationReport{}
+	v.SetConfigType("yaml")
 
 	// 设置默认值
-	setDefaults()
+	setDefaults(v)
 
 	if configPath != "" {
-		viper.SetConfigFile(configPath)
+		v.SetConfigFile(configPath)
 	} else {
 		// 默认配置文件路径
-		viper.SetConfigName("config")
-		viper.AddConfigPath("./configs")
-		viper.AddConfigPath("../configs")
-		viper.AddConfigPath("../../configs")
+		v.SetConfigName("config")
+		v.AddConfigPath("./configs")
+		v.AddConfigPath("../configs")
+		v.AddConfigPath("../../configs")
 	}
 
 	// 设置环境变量前缀
-	viper.SetEnvPrefix("SSH_COLLECTOR")
-	viper.AutomaticEnv()
-	viper.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
+	v.SetEnvPrefix("SSH_COLLECTOR")
+	v.AutomaticEnv()
+	v.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
 
 	// 读取配置文件
-	if err := viper.ReadInConfig(); err != nil {
-		return nil, fmt.Errorf("failed to read config file: %w", err)
+	if err := v.ReadInConfig(); err != nil {
+		return nil, nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+	// 叠加环境与站点本地配置（config.<env>.yaml、config.local.yaml），按顺序深度合并
+	baseFile := v.ConfigFileUsed()
+	overlays := OverlayFiles(baseFile)
+	if err := mergeOverlays(v, baseFile, overlays); err != nil {
+		return nil, nil, err
+	}
+
+	// 时长字段先行校验，避免解码阶段的报错难以定位
+	checkDurationValues(v, report)
+	if report.HasErrors() {
+		return nil, report, nil
 	}
 
 	var config Config
-	if err := viper.Unmarshal(&config); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal config: %w", err)
+	if err := v.Unmarshal(&config); err != nil {
+		return nil, nil, fmt.Errorf("failed to unmarshal config: %w", err)
 	}
 
 	// 兼容旧键名：backup.backup_backend -> backup.storage_backend
 	if strings.TrimSpace(config.Backup.StorageBackend) == "" {
-		if viper.IsSet("backup.backup_backend") {
-			bb := strings.TrimSpace(viper.GetString("backup.backup_backend"))
+		if v.IsSet("backup.backup_backend") {
+			bb := strings.TrimSpace(v.GetString("backup.backup_backend"))
 			if bb != "" {
 				config.Backup.StorageBackend = bb
 			}
@@ -208,8 +719,8 @@ func Load(configPath string) (*Config, error) {
 
 	// 兼容旧顶层键：deploy_wait_ms -> deploy.deploy_wait_ms
 	if config.Deploy.DeployWaitMS <= 0 {
-		if viper.IsSet("deploy_wait_ms") {
-			val := viper.GetInt("deploy_wait_ms")
+		if v.IsSet("deploy_wait_ms") {
+			val := v.GetInt("deploy_wait_ms")
 			if val > 0 {
 				config.Deploy.DeployWaitMS = val
 			}
@@ -217,25 +728,25 @@ func Load(configPath string) (*Config, error) {
 	}
 
 	// 兼容新嵌套：ssh.timeout.*（若存在则覆盖旧字段）
-	if viper.IsSet("ssh.timeout.timeout_all") {
-		to := viper.GetInt("ssh.timeout.timeout_all")  // 改为GetInt
+	if v.IsSet("ssh.timeout.timeout_all") {
+		to := v.GetInt("ssh.timeout.timeout_all")  // 改为GetInt
 		if to > 0 {
 			config.SSH.Timeout = time.Duration(to) * time.Second  // 转换为time.Duration
 		}
 	}
 	// 兼容旧顶层：ssh.timeout（若仍为时长字符串则生效；嵌套块不影响）
 	if config.SSH.Timeout <= 0 {
-		if to := viper.GetDuration("ssh.timeout"); to > 0 {
+		if to := v.GetDuration("ssh.timeout"); to > 0 {
 			config.SSH.Timeout = to
 		}
 	}
 	// 支持拆分的握手超时（dial/auth）；若设置则合并为 ConnectTimeout
 	var dialSec, authSec int
-	if viper.IsSet("ssh.timeout.dial_timeout") {
-		dialSec = viper.GetInt("ssh.timeout.dial_timeout")
+	if v.IsSet("ssh.timeout.dial_timeout") {
+		dialSec = v.GetInt("ssh.timeout.dial_timeout")
 	}
-	if viper.IsSet("ssh.timeout.auth_timeout") {
-		authSec = viper.GetInt("ssh.timeout.auth_timeout")
+	if v.IsSet("ssh.timeout.auth_timeout") {
+		authSec = v.GetInt("ssh.timeout.auth_timeout")
 	}
 	if dialSec > 0 || authSec > 0 {
 		merged := time.Duration(dialSec+authSec) * time.Second
@@ -245,90 +756,248 @@ func Load(configPath string) (*Config, error) {
 	// 环境变量替换
 	config = replaceEnvVars(config)
 
-	// 读取 auto-ssh.yaml 的设备平台默认项并覆盖
+	// 读取 auto-ssh.yaml 的设备平台默认项并覆盖；叠加文件中的 device_defaults 再合并到其上
+	rawDefaults, _ := v.Get("collector.device_defaults").(map[string]interface{})
 	autoPath := filepath.Join("configs", "auto-ssh.yaml")
-	if dd, err := loadAutoSSHDeviceDefaults(autoPath); err == nil && len(dd) > 0 {
-		config.Collector.DeviceDefaults = dd
+	if raw, err := loadAutoSSHDeviceDefaults(autoPath); err == nil && len(raw) > 0 {
+		for _, p := range overlays {
+			if od, err := overlayDeviceDefaults(p); err == nil {
+				deepMerge(raw, od)
+			}
+		}
+		if dd, err := decodeDeviceDefaults(raw); err == nil {
+			config.Collector.DeviceDefaults = dd
+			rawDefaults = raw
+		}
 	}
 
+	checkUnknownPlatformKeys(rawDefaults, report)
+
 	// 应用并发档位配置（若设置了 concurrency_profile 则覆盖 concurrent 数值）
-	applyConcurrencyProfile(&config)
+	explicitConcurrent := v.InConfig("collector.concurrent")
+	before := config.Collector.Concurrent
+	profileFound := applyConcurrencyProfile(&config, v)
+	checkConcurrency(&config, explicitConcurrent, before, profileFound, report)
 
-	globalConfig = &config
-	return &config, nil
+	validateConfig(&config, report)
+	return &config, report, nil
 }
 
-func setDefaults() {
+func setDefaults(v *viper.Viper) {
 	// 默认输出过滤规则：大小写不敏感，去除首尾空格
-	viper.SetDefault("collector.output_filter.case_insensitive", true)
-	viper.SetDefault("collector.output_filter.trim_space", true)
+	v.SetDefault("collector.output_filter.case_insensitive", true)
+	v.SetDefault("collector.output_filter.trim_space", true)
 	// 默认前缀匹配：H3C/Huawei 页提示与纯 more 行
-	viper.SetDefault("collector.output_filter.prefixes", []string{"---- More ----", "more"})
+	v.SetDefault("collector.output_filter.prefixes", []string{"---- More ----", "more"})
 	// 默认包含匹配：Cisco --more-- 提示
-	viper.SetDefault("collector.output_filter.contains", []string{"--more--"})
+	v.SetDefault("collector.output_filter.contains", []string{"--more--"})
 
 	// 默认交互配置
-	viper.SetDefault("collector.interact.case_insensitive", true)
-	viper.SetDefault("collector.interact.trim_space", true)
+	v.SetDefault("collector.interact.case_insensitive", true)
+	v.SetDefault("collector.interact.trim_space", true)
 	// 默认自动交互为空（由各平台插件提供）
-	viper.SetDefault("collector.interact.auto_interactions", []map[string]string{})
+	v.SetDefault("collector.interact.auto_interactions", []map[string]string{})
 	// 默认错误提示前缀（可按需调整或清空）
-	viper.SetDefault("collector.interact.error_hints", []string{"ERROR:", "invalid parameters detect"})
+	v.SetDefault("collector.interact.error_hints", []string{"ERROR:", "invalid parameters detect"})
+	// 默认启用未知确认提示检测，安全应答为 N
+	v.SetDefault("collector.unknown_prompt.enable", true)
+	v.SetDefault("collector.unknown_prompt.response", "N")
+	// 默认对未指定平台的 SSH 请求探测平台，结果缓存 1 小时
+	v.SetDefault("collector.platform_detect.enable", true)
+	v.SetDefault("collector.platform_detect.cache_ttl", time.Hour)
+	// 批内重复设备默认只执行一次并复制结果
+	v.SetDefault("collector.duplicate_devices", "copy")
+	// 默认线性退避（150ms、300ms…），任意错误均重试
+	v.SetDefault("collector.retry_policy.backoff", RetryBackoffLinear)
+	v.SetDefault("collector.retry_policy.initial_delay", 150*time.Millisecond)
+	v.SetDefault("collector.retry_policy.max_delay", 10*time.Second)
+	v.SetDefault("collector.retry_policy.multiplier", 2.0)
+	v.SetDefault("collector.retry_policy.retry_on", RetryOnAny)
 
 	// 不预设设备平台默认项：完全由配置文件控制。
 	// 若需要兜底，可在配置文件中提供 collector.device_defaults.default 项。
 	// 这里不设置 viper 默认，避免内置平台行为。
 
 	// 默认并发档位配置（包含并发与线程数）
-	viper.SetDefault("collector.concurrency_profile", "S")
-	viper.SetDefault("collector.concurrency_profiles", map[string]map[string]int{
+	v.SetDefault("collector.concurrency_profile", "S")
+	v.SetDefault("collector.concurrency_profiles", map[string]map[string]int{
 		"S":  {"concurrent": 8, "threads": 32},   // 2c4g
 		"M":  {"concurrent": 16, "threads": 64},  // 4c8g
 		"L":  {"concurrent": 32, "threads": 128}, // 8c16g
 		"XL": {"concurrent": 64, "threads": 256}, // 16c32g
 	})
 	// 默认重试次数（接口未指定时使用）。若配置文件未设置，则使用 1。
-	viper.SetDefault("collector.retry_flags", 1)
+	v.SetDefault("collector.retry_flags", 1)
 
 	// 备份服务默认配置
-	viper.SetDefault("backup.storage_backend", "local")
+	v.SetDefault("backup.storage_backend", "local")
+	v.SetDefault("storage.s3.endpoint", "s3.amazonaws.com")
+	v.SetDefault("storage.s3.secure", true)
+	v.SetDefault("storage.postgres.enable", false)
+	v.SetDefault("storage.postgres.port", 5432)
+	v.SetDefault("storage.postgres.sslmode", "disable")
+	v.SetDefault("storage.postgres.table", "formatted_records")
 	// 顶层前缀默认用于在 base_dir 下分组，如 "configs"
-	viper.SetDefault("backup.prefix", "configs")
-	viper.SetDefault("backup.local.base_dir", "./data/backups")
+	v.SetDefault("backup.prefix", "configs")
+	v.SetDefault("backup.local.base_dir", "./data/backups")
 	// 可选：局部覆盖的前缀，默认空串，最终路径 prefix/local.prefix/save_dir
-	viper.SetDefault("backup.local.prefix", "")
-	viper.SetDefault("backup.local.mkdir_if_missing", true)
-	viper.SetDefault("backup.local.compress", false)
+	v.SetDefault("backup.local.prefix", "")
+	v.SetDefault("backup.local.mkdir_if_missing", true)
+	v.SetDefault("backup.local.compress", false)
 	// 聚合写入默认开启，聚合文件名默认为 all_cli.txt
-	viper.SetDefault("backup.aggregate.enabled", true)
-	viper.SetDefault("backup.aggregate.filename", "all_cli.txt")
+	v.SetDefault("backup.aggregate.enabled", true)
+	v.SetDefault("backup.aggregate.filename", "all_cli.txt")
 	// 聚合仅写入模式默认关闭（false 表示仍写入逐命令文件）
-	viper.SetDefault("backup.aggregate.aggregate_only", false)
+	v.SetDefault("backup.aggregate.aggregate_only", false)
+	v.SetDefault("backup.path_template", "")
+	v.SetDefault("backup.filename_template", "")
+	v.SetDefault("backup.transcript.enable", false)
+	v.SetDefault("backup.transcript.max_bytes", 8<<20)
 
 	// 格式化数据默认配置
 	// 仅定义 MinIO 路径前缀，最终对象路径为 /{minio_prefix}/{save_dir}/{task_id}/...
-	viper.SetDefault("data_format.minio_prefix", "data-formats")
+	v.SetDefault("data_format.minio_prefix", "data-formats")
+	v.SetDefault("data_format.template_dir", "./data/templates")
+	v.SetDefault("data_format.timeseries.enable", false)
+	v.SetDefault("data_format.timeseries.backend", "influxdb")
+	v.SetDefault("data_format.timeseries.timeout", 10*time.Second)
+	v.SetDefault("data_format.external_parser.timeout", 10*time.Second)
+	v.SetDefault("data_format.external_parser.retries", 1)
+	v.SetDefault("data_format.external_parser.failure_threshold", 5)
+	v.SetDefault("data_format.external_parser.cooldown", 30*time.Second)
+	v.SetDefault("data_format.fsm.workers", 0)
+	v.SetDefault("data_format.fsm.cache_size", 1024)
 
 	// SSH 超时新默认（替换旧的 connect_timeout 与顶层 timeout）
 	// 全局执行窗口（接口未指定时可参考此值）
-	viper.SetDefault("ssh.timeout.timeout_all", 60)  // 改为int类型，单位秒
+	v.SetDefault("ssh.timeout.timeout_all", 60)  // 改为int类型，单位秒
 	// 拨号与握手阶段拆分默认（合并为 ConnectTimeout 使用）
-	viper.SetDefault("ssh.timeout.dial_timeout", 2)
-	viper.SetDefault("ssh.timeout.auth_timeout", 5)
+	v.SetDefault("ssh.timeout.dial_timeout", 2)
+	v.SetDefault("ssh.timeout.auth_timeout", 5)
 
 	// 新增：连接池清理周期默认 30s（可通过 ssh.cleanup_interval 覆盖）
-	viper.SetDefault("ssh.cleanup_interval", 30*time.Second)
+	v.SetDefault("ssh.cleanup_interval", 30*time.Second)
+	// 持久连接缓存默认关闭
+	v.SetDefault("ssh.connection_cache.enable", false)
+	v.SetDefault("ssh.connection_cache.ttl", 10*time.Minute)
+	v.SetDefault("ssh.connection_cache.max_connections", 100)
+	v.SetDefault("ssh.pool.max_idle_per_host", 0)
+	v.SetDefault("ssh.pool.max_connection_age", 0)
+	v.SetDefault("ssh.egress.tenant_header", "X-Tenant-ID")
+	// 认证失败限流默认开启：同一凭据连续失败 2 次后暂停 15 分钟
+	v.SetDefault("ssh.auth_throttle.enable", true)
+	v.SetDefault("ssh.auth_throttle.max_failures", 2)
+	v.SetDefault("ssh.auth_throttle.cooldown", 15*time.Minute)
+	// 设备熔断默认关闭：开启后同一设备连续建连失败 3 次熔断 5 分钟
+	v.SetDefault("ssh.circuit_breaker.enable", false)
+	v.SetDefault("ssh.circuit_breaker.failure_threshold", 3)
+	v.SetDefault("ssh.circuit_breaker.cooldown", 5*time.Minute)
+	// 主机密钥默认不校验（兼容旧行为）
+	v.SetDefault("ssh.host_key.mode", HostKeyInsecure)
 
 	// 新增：模拟服务开关默认关闭
-	viper.SetDefault("server.simulate_enable", false)
+	v.SetDefault("server.simulate_enable", false)
+	v.SetDefault("server.simulate_record.enable", false)
+	// gRPC 接口默认关闭，端口 9090
+	v.SetDefault("server.grpc.enable", false)
+	v.SetDefault("server.grpc.port", 9090)
+	v.SetDefault("server.strict_json", false)
 
 	// 新增：日志默认级别为 info（可通过 log.level 覆盖为 debug/warn/error 等）
-	viper.SetDefault("log.level", "info")
+	v.SetDefault("log.level", "info")
+
+	// 任务完成回调默认：失败重试 3 次，单次超时 10s，重试间隔 2s 递增
+	v.SetDefault("callback.retries", 3)
+	v.SetDefault("callback.timeout", 10*time.Second)
+	v.SetDefault("callback.retry_delay", 2*time.Second)
+	v.SetDefault("events.enable", false)
+	v.SetDefault("events.backend", "nats")
+	v.SetDefault("events.topics.collect", "sshcollector.collect")
+	v.SetDefault("events.topics.backup", "sshcollector.backup")
+	v.SetDefault("events.topics.deploy", "sshcollector.deploy")
+	v.SetDefault("events.timeout", 5*time.Second)
+	v.SetDefault("events.queue_size", 1000)
+
+	// 健康监控导出默认关闭
+	v.SetDefault("monitor.enable", false)
+	v.SetDefault("monitor.backend", "zabbix")
+	v.SetDefault("monitor.host_template", "{device_name}")
+	v.SetDefault("monitor.key_template", "sshcollector.{type}.{metric}")
+	v.SetDefault("monitor.batch_size", 100)
+	v.SetDefault("monitor.flush_interval", 5*time.Second)
+	v.SetDefault("monitor.timeout", 5*time.Second)
+	v.SetDefault("monitor.queue_size", 5000)
+
+	// 链路追踪默认关闭
+	v.SetDefault("tracing.enable", false)
+	v.SetDefault("tracing.endpoint", "localhost:4318")
+	v.SetDefault("tracing.insecure", true)
+	v.SetDefault("tracing.service_name", "")
+	v.SetDefault("tracing.sample_ratio", 1.0)
+	v.SetDefault("tracing.timeout", 10*time.Second)
+
+	// 高可用选主默认关闭
+	v.SetDefault("ha.enable", false)
+	v.SetDefault("ha.backend", "db")
+	v.SetDefault("ha.lease_name", "sshcollector")
+	v.SetDefault("ha.file_path", "data/ha.lease")
+	v.SetDefault("ha.instance_id", "")
+	v.SetDefault("ha.lease_duration", 15*time.Second)
+	v.SetDefault("ha.renew_interval", 5*time.Second)
+
+	// 命令耗时与输出大小分布
+	v.SetDefault("metrics.enable", true)
+	v.SetDefault("metrics.persist_daily", false)
+	v.SetDefault("metrics.flush_interval", time.Minute)
+	v.SetDefault("metrics.retention_days", 90)
+
+	// 任务历史默认保留 30 天
+	v.SetDefault("database.task_retention", 30*24*time.Hour)
+
+	// 诊断包默认配置
+	v.SetDefault("debug.token", "")
+	v.SetDefault("debug.window", time.Hour)
+	v.SetDefault("debug.log_lines", 5000)
+	v.SetDefault("debug.failed_tasks", 100)
+	v.SetDefault("debug.listen", "")
+
+	// 批量任务断点续跑默认关闭
+	v.SetDefault("batch_resume.enable", false)
+	v.SetDefault("batch_resume.auto", false)
+
+	// SNMP 采集默认参数
+	v.SetDefault("snmp.port", 161)
+	v.SetDefault("snmp.timeout", 3*time.Second)
+	v.SetDefault("snmp.retries", 1)
+	v.SetDefault("snmp.max_repetitions", 10)
+
+	// 批量响应原始输出：默认完整返回
+	v.SetDefault("batch_response.raw_output_mode", "full")
+	v.SetDefault("batch_response.max_output_kb", 64)
+
+	// 下发审批默认关闭，审批有效期 24 小时
+	v.SetDefault("approval.enable", false)
+	v.SetDefault("approval.deploy_required", false)
+	v.SetDefault("approval.ttl", 24*time.Hour)
+
+	// NetBox 资产同步默认关闭；启用后默认仅按需同步
+	v.SetDefault("netbox.enable", false)
+	v.SetDefault("netbox.interval", time.Duration(0))
+	v.SetDefault("netbox.timeout", 30*time.Second)
+	v.SetDefault("netbox.page_size", 200)
+	v.SetDefault("netbox.default_port", 22)
+	v.SetDefault("netbox.disable_missing", false)
+
+	// 凭据主密钥默认空（未配置时凭据接口不可用）；设置默认值以便环境变量覆盖生效
+	v.SetDefault("vault.master_key", "")
+
+	// 只读模式默认关闭
+	v.SetDefault("policy.read_only", false)
 }
 
-// Get 获取全局配置
+// Get 获取全局配置的当前快照；热更新后返回新快照，调用方不得修改
 func Get() *Config {
-	return globalConfig
+	return global.Get()
 }
 
 // replaceEnvVars 替换配置中的环境变量
@@ -341,14 +1010,104 @@ func replaceEnvVars(config Config) Config {
 		}
 	}
 
+	// 替换凭据主密钥
+	if strings.HasPrefix(config.Vault.MasterKey, "${") && strings.HasSuffix(config.Vault.MasterKey, "}") {
+		envVar := strings.TrimSuffix(strings.TrimPrefix(config.Vault.MasterKey, "${"), "}")
+		config.Vault.MasterKey = os.Getenv(envVar)
+	}
+
+	// 替换回调签名密钥
+	if strings.HasPrefix(config.Callback.Secret, "${") && strings.HasSuffix(config.Callback.Secret, "}") {
+		envVar := strings.TrimSuffix(strings.TrimPrefix(config.Callback.Secret, "${"), "}")
+		config.Callback.Secret = os.Getenv(envVar)
+	}
+
+	// 替换消息总线认证信息
+	if strings.HasPrefix(config.Events.Password, "${") && strings.HasSuffix(config.Events.Password, "}") {
+		envVar := strings.TrimSuffix(strings.TrimPrefix(config.Events.Password, "${"), "}")
+		config.Events.Password = os.Getenv(envVar)
+	}
+	if strings.HasPrefix(config.Events.Token, "${") && strings.HasSuffix(config.Events.Token, "}") {
+		envVar := strings.TrimSuffix(strings.TrimPrefix(config.Events.Token, "${"), "}")
+		config.Events.Token = os.Getenv(envVar)
+	}
+
+	// 替换 SSH 代理地址（可含认证信息）
+	if strings.HasPrefix(config.SSH.Proxy.URL, "${") && strings.HasSuffix(config.SSH.Proxy.URL, "}") {
+		envVar := strings.TrimSuffix(strings.TrimPrefix(config.SSH.Proxy.URL, "${"), "}")
+		config.SSH.Proxy.URL = os.Getenv(envVar)
+	}
+	for i, o := range config.SSH.Proxy.Overrides {
+		if strings.HasPrefix(o.URL, "${") && strings.HasSuffix(o.URL, "}") {
+			envVar := strings.TrimSuffix(strings.TrimPrefix(o.URL, "${"), "}")
+			config.SSH.Proxy.Overrides[i].URL = os.Getenv(envVar)
+		}
+	}
+
+	// 替换 NetBox Token
+	if strings.HasPrefix(config.NetBox.Token, "${") && strings.HasSuffix(config.NetBox.Token, "}") {
+		envVar := strings.TrimSuffix(strings.TrimPrefix(config.NetBox.Token, "${"), "}")
+		config.NetBox.Token = os.Getenv(envVar)
+	}
+
+	// 替换时序库认证信息
+	if strings.HasPrefix(config.DataFormat.Timeseries.Token, "${") && strings.HasSuffix(config.DataFormat.Timeseries.Token, "}") {
+		envVar := strings.TrimSuffix(strings.TrimPrefix(config.DataFormat.Timeseries.Token, "${"), "}")
+		config.DataFormat.Timeseries.Token = os.Getenv(envVar)
+	}
+	if strings.HasPrefix(config.DataFormat.Timeseries.Password, "${") && strings.HasSuffix(config.DataFormat.Timeseries.Password, "}") {
+		envVar := strings.TrimSuffix(strings.TrimPrefix(config.DataFormat.Timeseries.Password, "${"), "}")
+		config.DataFormat.Timeseries.Password = os.Getenv(envVar)
+	}
+
+	// 替换对象存储密钥
+	if strings.HasPrefix(config.Storage.S3.SecretKey, "${") && strings.HasSuffix(config.Storage.S3.SecretKey, "}") {
+		envVar := strings.TrimSuffix(strings.TrimPrefix(config.Storage.S3.SecretKey, "${"), "}")
+		config.Storage.S3.SecretKey = os.Getenv(envVar)
+	}
+	if strings.HasPrefix(config.Storage.Azure.AccountKey, "${") && strings.HasSuffix(config.Storage.Azure.AccountKey, "}") {
+		envVar := strings.TrimSuffix(strings.TrimPrefix(config.Storage.Azure.AccountKey, "${"), "}")
+		config.Storage.Azure.AccountKey = os.Getenv(envVar)
+	}
+	if strings.HasPrefix(config.Storage.Postgres.Password, "${") && strings.HasSuffix(config.Storage.Postgres.Password, "}") {
+		envVar := strings.TrimSuffix(strings.TrimPrefix(config.Storage.Postgres.Password, "${"), "}")
+		config.Storage.Postgres.Password = os.Getenv(envVar)
+	}
+	if strings.HasPrefix(config.Storage.Azure.SASToken, "${") && strings.HasSuffix(config.Storage.Azure.SASToken, "}") {
+		envVar := strings.TrimSuffix(strings.TrimPrefix(config.Storage.Azure.SASToken, "${"), "}")
+		config.Storage.Azure.SASToken = os.Getenv(envVar)
+	}
+	if strings.HasPrefix(config.Debug.Token, "${") && strings.HasSuffix(config.Debug.Token, "}") {
+		envVar := strings.TrimSuffix(strings.TrimPrefix(config.Debug.Token, "${"), "}")
+		config.Debug.Token = os.Getenv(envVar)
+	}
+
+	// 替换审批签名密钥与钉钉加签密钥
+	if strings.HasPrefix(config.Approval.Secret, "${") && strings.HasSuffix(config.Approval.Secret, "}") {
+		envVar := strings.TrimSuffix(strings.TrimPrefix(config.Approval.Secret, "${"), "}")
+		config.Approval.Secret = os.Getenv(envVar)
+	}
+	if strings.HasPrefix(config.Approval.DingTalk.Secret, "${") && strings.HasSuffix(config.Approval.DingTalk.Secret, "}") {
+		envVar := strings.TrimSuffix(strings.TrimPrefix(config.Approval.DingTalk.Secret, "${"), "}")
+		config.Approval.DingTalk.Secret = os.Getenv(envVar)
+	}
+
+	// 替换链路追踪请求头中的环境变量引用（如 Authorization: ${OTLP_TOKEN}）
+	for k, v := range config.Tracing.Headers {
+		if strings.HasPrefix(v, "${") && strings.HasSuffix(v, "}") {
+			config.Tracing.Headers[k] = os.Getenv(strings.TrimSuffix(strings.TrimPrefix(v, "${"), "}"))
+		}
+	}
+
 	return config
 }
 
-// applyConcurrencyProfile 根据并发档位设置并发数（覆盖 Collector.Concurrent）
-func applyConcurrencyProfile(cfg *Config) {
+// applyConcurrencyProfile 根据并发档位设置并发数（覆盖 Collector.Concurrent）；档位未设置时返回 true，
+// 设置了但未在档位映射中找到时返回 false
+func applyConcurrencyProfile(cfg *Config, v *viper.Viper) bool {
 	prof := strings.TrimSpace(cfg.Collector.ConcurrencyProfile)
 	if prof == "" {
-		return
+		return true
 	}
 	// 兼容大小写与可能的前缀（例如 "Concurrency-S"）
 	p := strings.ToUpper(prof)
@@ -364,7 +1123,7 @@ func applyConcurrencyProfile(cfg *Config) {
 			mapping[strings.ToUpper(k)] = v
 		}
 	} else {
-		raw := viper.Get("collector.concurrency_profiles")
+		raw := v.Get("collector.concurrency_profiles")
 		switch rm := raw.(type) {
 		case map[string]interface{}:
 			for k, v := range rm {
@@ -424,32 +1183,24 @@ func applyConcurrencyProfile(cfg *Config) {
 		if profConf.Threads > 0 {
 			cfg.Collector.Threads = profConf.Threads
 		}
+		return true
 	}
+	return false
 }
 
-// 从 auto-ssh.yaml 加载设备平台默认项（collector.device_defaults 或顶层 device_defaults）
-func loadAutoSSHDeviceDefaults(path string) (map[string]PlatformDefaultsConfig, error) {
+// 从 auto-ssh.yaml 加载设备平台默认项原始映射（collector.device_defaults 或顶层 device_defaults）
+func loadAutoSSHDeviceDefaults(path string) (map[string]interface{}, error) {
 	v := viper.New()
 	v.SetConfigFile(path)
 	v.SetConfigType("yaml")
 	if err := v.ReadInConfig(); err != nil {
 		return nil, err
 	}
-	type collectorWrapper struct {
-		DeviceDefaults map[string]PlatformDefaultsConfig `mapstructure:"device_defaults"`
-	}
-	var root struct {
-		Collector      collectorWrapper                    `mapstructure:"collector"`
-		DeviceDefaults map[string]PlatformDefaultsConfig   `mapstructure:"device_defaults"`
-	}
-	if err := v.Unmarshal(&root); err != nil {
-		return nil, err
-	}
-	if len(root.Collector.DeviceDefaults) > 0 {
-		return root.Collector.DeviceDefaults, nil
+	if dd, ok := v.Get("collector.device_defaults").(map[string]interface{}); ok && len(dd) > 0 {
+		return dd, nil
 	}
-	if len(root.DeviceDefaults) > 0 {
-		return root.DeviceDefaults, nil
+	if dd, ok := v.Get("device_defaults").(map[string]interface{}); ok && len(dd) > 0 {
+		return dd, nil
 	}
 	return nil, fmt.Errorf("device_defaults not found in %s", path)
 }
@@ -490,6 +1241,30 @@ type OutputFilterConfig struct {
 	TrimSpace       bool    `mapstructure:"trim_space"`
 }
 
+// 内置输出规整类型
+const (
+	NormalizerStripANSI       = "strip_ansi"           // 去除 ANSI 控制序列与退格
+	NormalizerDropBanner      = "drop_banner"          // 删除横幅/MOTD：pattern 为起始行正则，end 为结束行正则（未配置时仅删除匹配行）
+	NormalizerCollapseBlank   = "collapse_blank_lines" // 连续空行合并为一行并去除首尾空行
+	NormalizerStripTimestamps = "strip_timestamps"     // 删除易变时间戳：pattern 未配置时使用内置格式
+	NormalizerRegexReplace    = "regex_replace"        // 按 pattern 替换为 replace
+)
+
+// NormalizerConfig 输出规整步骤：按配置顺序作用于原始输出（存储与解析之前）
+type NormalizerConfig struct {
+	Type    string `mapstructure:"type" json:"type"`
+	Pattern string `mapstructure:"pattern" json:"pattern,omitempty"`
+	End     string `mapstructure:"end" json:"end,omitempty"`
+	Replace string `mapstructure:"replace" json:"replace,omitempty"`
+}
+
+// FactConfig 设备事实：name 为模板变量名，command 的输出按 pattern（多行模式）首个捕获组取值
+type FactConfig struct {
+	Name    string `mapstructure:"name" json:"name"`
+	Command string `mapstructure:"command" json:"command"`
+	Pattern string `mapstructure:"pattern" json:"pattern"`
+}
+
 // InteractConfig 交互配置（提示符、自动交互与错误提示）
 type InteractConfig struct {
 	AutoInteractions []AutoInteractionConfig `mapstructure:"auto_interactions"`
@@ -504,6 +1279,13 @@ type AutoInteractionConfig struct {
 	AutoSend     string `mapstructure:"command_auto_send"`
 }
 
+// LoginStepConfig 登录序列步骤：发送 send 后等待输出包含 expect（为空则不等待）
+type LoginStepConfig struct {
+	Send       string `mapstructure:"send" json:"send"`
+	Expect     string `mapstructure:"expect" json:"expect"`
+	TimeoutSec int    `mapstructure:"timeout_sec" json:"timeout_sec"`
+}
+
 // InteractTimingConfig 交互时序相关配置（毫秒与秒，以平台覆盖全局）
 type InteractTimingConfig struct {
 	CommandIntervalMS        int `mapstructure:"command_interval_ms"`
@@ -527,13 +1309,18 @@ type PlatformTimeoutConfig struct {
 // PlatformDefaultsConfig 平台默认交互/适配参数
 type PlatformDefaultsConfig struct {
 	PromptSuffixes    []string                `mapstructure:"prompt_suffixes"`
+	PromptRegex       []string                `mapstructure:"prompt_regex"` // 提示符正则（匹配整行），配置后替代 prompt_suffixes 判定
 	DisablePagingCmds []string                `mapstructure:"disable_paging_cmds"`
+	PostCommands      []string                `mapstructure:"post_commands"` // 会话结束前执行的清理命令（如 terminal no monitor），不计入结果
+	LoginSequence     []LoginStepConfig       `mapstructure:"login_sequence"` // 首个提示符后按序执行的登录步骤（每会话一次）
 	AutoInteractions  []AutoInteractionConfig `mapstructure:"auto_interactions"`
 	ErrorHints        []string                `mapstructure:"error_hints"`
 	SkipDelayedEcho   bool                    `mapstructure:"skip_delayed_echo"`
 	EnableRequired    bool                    `mapstructure:"enable_required"`
 
 	OutputFilter OutputFilterConfig `mapstructure:"output_filter"`
+	// Normalizers 输出规整流水线（去除 ANSI、横幅、多余空行、易变时间戳），先于 output_filter 执行
+	Normalizers []NormalizerConfig `mapstructure:"normalizers"`
 
 	Interact InteractConfig `mapstructure:"interact"`
 
@@ -544,6 +1331,15 @@ type PlatformDefaultsConfig struct {
 
 	ConfigExitCLI string `mapstructure:"config_exit_cli"`
 
+	// Facts 备份命名使用的设备事实：在同一会话中执行命令并以正则首个捕获组提取（如 hostname、serial）
+	Facts []FactConfig `mapstructure:"facts"`
+
+	// CheckpointCLI 下发前在设备上生成本地还原点的命令（特权模式执行，请求 checkpoint=true 时生效），支持 ${task_id}、${timestamp}
+	CheckpointCLI []string `mapstructure:"checkpoint_cli"`
+
+	// LineEnding 交互与 exec 执行使用的命令结束符：crlf|lf，未配置时按 capabilities.needs_crlf（默认 crlf）
+	LineEnding string `mapstructure:"line_ending"`
+
 	CommandIntervalMS         int `mapstructure:"command_interval_ms"`
 	CommandTimeoutSec         int `mapstructure:"command_timeout_sec"`
 	QuietAfterMS              int `mapstructure:"quiet_after_ms"`
@@ -553,5 +1349,36 @@ type PlatformDefaultsConfig struct {
 	PromptInducerMaxCount     int `mapstructure:"prompt_inducer_max_count"`
 	ExitPauseMS               int `mapstructure:"exit_pause_ms"`
 
+	// MaxLines 响应中单条命令输出的最大行数（0 不限制），超出时保留前部与末尾 MaxLinesTail 行
+	MaxLines     int `mapstructure:"max_lines"`
+	MaxLinesTail int `mapstructure:"max_lines_tail"`
+
+	// Capabilities 平台能力标记
+	Capabilities PlatformCapabilitiesConfig `mapstructure:"capabilities"`
+	// SNMPOIDSets collect_protocol=snmp 且未指定 cli_list 时采集的 OID 集合（默认 system）
+	SNMPOIDSets []string `mapstructure:"snmp_oid_sets"`
+
 	Timeout PlatformTimeoutConfig `mapstructure:"timeout"`
+
+	// RetryPolicy 平台重试策略，覆盖 collector.retry_policy 中已设置的字段
+	RetryPolicy RetryPolicyConfig `mapstructure:"retry_policy"`
+}
+
+// 平台命令结束符取值
+const (
+	LineEndingCRLF = "crlf"
+	LineEndingLF   = "lf"
+)
+
+// PlatformCapabilitiesConfig 平台能力标记
+type PlatformCapabilitiesConfig struct {
+	Netconf     bool `mapstructure:"netconf"`      // 允许 collect_protocol=netconf
+	NetconfPort int  `mapstructure:"netconf_port"` // 请求未指定 device_port 时使用的 NETCONF 端口（默认 830）
+
+	// 以下标记影响 SSH 执行引擎；未配置（nil）时使用引擎默认值
+	SupportsExec         *bool `mapstructure:"supports_exec"`         // 交互失败时允许回退 exec 通道（默认 true）
+	SupportsMultiSession *bool `mapstructure:"supports_multisession"` // 允许同一连接上开启多个会话并复用连接（默认 true）
+	SupportsCommit       *bool `mapstructure:"supports_commit"`       // 配置下发后需执行 commit（默认 false）
+	NeedsCRLF            *bool `mapstructure:"needs_crlf"`            // 交互发送使用 CRLF，false 时使用 LF（默认 true）
+	WideTerminal         *bool `mapstructure:"wide_terminal"`         // 申请宽终端避免长行折行（默认 false）
 }
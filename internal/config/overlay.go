@@ -0,0 +1,92 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// EnvName 选择环境叠加文件的环境变量：取值 prod 时叠加 config.prod.yaml
+const EnvName = "SSH_COLLECTOR_ENV"
+
+// OverlayFiles 基础配置文件的叠加文件（按合并顺序，仅返回存在的文件）：
+// 同目录下的 config.<SSH_COLLECTOR_ENV>.yaml，其后为 config.local.yaml（站点本地覆盖，不随版本发布）
+func OverlayFiles(base string) []string {
+	if strings.TrimSpace(base) == "" {
+		return nil
+	}
+	dir := filepath.Dir(base)
+	ext := filepath.Ext(base)
+	name := strings.TrimSuffix(filepath.Base(base), ext)
+	var names []string
+	if env := strings.TrimSpace(os.Getenv(EnvName)); env != "" && env != "local" {
+		names = append(names, name+"."+env+ext)
+	}
+	names = append(names, name+".local"+ext)
+
+	var out []string
+	for _, n := range names {
+		p := filepath.Join(dir, n)
+		if fi, err := os.Stat(p); err == nil && !fi.IsDir() {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// mergeOverlays 将叠加文件依次深度合并到已读取的基础配置：映射按键递归合并，列表与标量整体替换
+func mergeOverlays(v *viper.Viper, base string, overlays []string) error {
+	defer v.SetConfigFile(base)
+	for _, p := range overlays {
+		v.SetConfigFile(p)
+		if err := v.MergeInConfig(); err != nil {
+			return fmt.Errorf("failed to merge config overlay %s: %w", p, err)
+		}
+	}
+	return nil
+}
+
+// overlayDeviceDefaults 读取叠加文件中的 collector.device_defaults（原始映射），用于在 auto-ssh.yaml 之上再合并
+func overlayDeviceDefaults(path string) (map[string]interface{}, error) {
+	v := viper.New()
+	v.SetConfigFile(path)
+	v.SetConfigType("yaml")
+	if err := v.ReadInConfig(); err != nil {
+		return nil, err
+	}
+	dd, _ := v.Get("collector.device_defaults").(map[string]interface{})
+	return dd, nil
+}
+
+// deepMerge 将 src 递归合并到 dst：两侧均为映射时按键合并，否则以 src 覆盖
+func deepMerge(dst, src map[string]interface{}) {
+	for k, sv := range src {
+		if sm, ok := sv.(map[string]interface{}); ok {
+			if dm, ok := dst[k].(map[string]interface{}); ok {
+				deepMerge(dm, sm)
+				continue
+			}
+			cp := make(map[string]interface{}, len(sm))
+			deepMerge(cp, sm)
+			dst[k] = cp
+			continue
+		}
+		dst[k] = sv
+	}
+}
+
+// decodeDeviceDefaults 原始映射解码为平台默认项（与主配置相同的类型转换规则）
+func decodeDeviceDefaults(raw map[string]interface{}) (map[string]PlatformDefaultsConfig, error) {
+	v := viper.New()
+	if err := v.MergeConfigMap(map[string]interface{}{"device_defaults": raw}); err != nil {
+		return nil, err
+	}
+	var out map[string]PlatformDefaultsConfig
+	if err := v.UnmarshalKey("device_defaults", &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
@@ -0,0 +1,169 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// maxReloadRecords 保留的配置变更记录条数
+const maxReloadRecords = 50
+
+// Provider 配置快照提供者：热更新时整体替换快照，已发布的 *Config 不再修改，
+// 读取方每次拿到完整一致的配置，不会读到更新一半的结构
+type Provider struct {
+	v       atomic.Value // *Config
+	mu      sync.Mutex   // 串行化 Swap/Update
+	records []ReloadRecord
+}
+
+// ReloadRecord 一次配置替换的变更记录
+type ReloadRecord struct {
+	Time    time.Time `json:"time"`
+	Source  string    `json:"source"`  // file（配置文件热更新）| runtime（管理接口修改）
+	Changed []string  `json:"changed"` // 变更字段路径（配置键名），如 collector.concurrent
+}
+
+// NewProvider 创建提供者；cfg 为初始快照
+func NewProvider(cfg *Config) *Provider {
+	p := &Provider{}
+	if cfg != nil {
+		p.v.Store(cfg)
+	}
+	return p
+}
+
+// Get 返回当前快照（未初始化时为 nil）；调用方不得修改返回的配置
+func (p *Provider) Get() *Config {
+	if p == nil {
+		return nil
+	}
+	cfg, _ := p.v.Load().(*Config)
+	return cfg
+}
+
+// Swap 替换快照并返回变更字段；首次设置不记录变更
+func (p *Provider) Swap(cfg *Config, source string) []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.swapLocked(cfg, source)
+}
+
+// Update 基于当前快照的浅拷贝修改后替换（写时复制）；mutate 修改 map/slice 时须先复制，不能原地修改
+func (p *Provider) Update(source string, mutate func(*Config)) *Config {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	next := &Config{}
+	if cur := p.Get(); cur != nil {
+		*next = *cur
+	}
+	mutate(next)
+	p.swapLocked(next, source)
+	return next
+}
+
+func (p *Provider) swapLocked(cfg *Config, source string) []string {
+	old := p.Get()
+	p.v.Store(cfg)
+	if old == nil {
+		return nil
+	}
+	changed := Diff(old, cfg)
+	if len(changed) > 0 {
+		p.records = append(p.records, ReloadRecord{Time: time.Now(), Source: source, Changed: changed})
+		if len(p.records) > maxReloadRecords {
+			p.records = p.records[len(p.records)-maxReloadRecords:]
+		}
+	}
+	return changed
+}
+
+// Changelog 返回最近的配置变更记录（由旧到新）
+func (p *Provider) Changelog() []ReloadRecord {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]ReloadRecord(nil), p.records...)
+}
+
+// global 进程级配置提供者：Load/Reload 写入，Get 读取
+var global = &Provider{}
+
+// Default 返回进程级配置提供者
+func Default() *Provider {
+	return global
+}
+
+// ProviderFor 服务构造时使用：cfg 为当前全局快照时返回全局提供者（跟随热更新），否则返回固定该配置的提供者
+func ProviderFor(cfg *Config) *Provider {
+	if cfg != nil && cfg == global.Get() {
+		return global
+	}
+	return NewProvider(cfg)
+}
+
+// Diff 比较两份配置，返回变更字段路径（按配置键名，map 按键展开一层）；不包含字段值，避免输出密钥
+func Diff(old, new *Config) []string {
+	var out []string
+	if old == nil || new == nil {
+		return out
+	}
+	diffValue("", reflect.ValueOf(*old), reflect.ValueOf(*new), &out)
+	sort.Strings(out)
+	return out
+}
+
+func diffValue(path string, a, b reflect.Value, out *[]string) {
+	switch a.Kind() {
+	case reflect.Struct:
+		t := a.Type()
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if !f.IsExported() {
+				continue
+			}
+			diffValue(joinPath(path, fieldKey(f)), a.Field(i), b.Field(i), out)
+		}
+	case reflect.Map:
+		if a.Type().Elem().Kind() != reflect.Struct {
+			if !reflect.DeepEqual(a.Interface(), b.Interface()) {
+				*out = append(*out, path)
+			}
+			return
+		}
+		keys := map[string]reflect.Value{}
+		for _, k := range a.MapKeys() {
+			keys[fmt.Sprint(k.Interface())] = k
+		}
+		for _, k := range b.MapKeys() {
+			keys[fmt.Sprint(k.Interface())] = k
+		}
+		for name, k := range keys {
+			av, bv := a.MapIndex(k), b.MapIndex(k)
+			if !av.IsValid() || !bv.IsValid() || !reflect.DeepEqual(av.Interface(), bv.Interface()) {
+				*out = append(*out, joinPath(path, name))
+			}
+		}
+	default:
+		if !reflect.DeepEqual(a.Interface(), b.Interface()) {
+			*out = append(*out, path)
+		}
+	}
+}
+
+func fieldKey(f reflect.StructField) string {
+	if tag := strings.Split(f.Tag.Get("mapstructure"), ",")[0]; tag != "" && tag != "-" {
+		return tag
+	}
+	return strings.ToLower(f.Name)
+}
+
+func joinPath(prefix, key string) string {
+	if prefix == "" {
+		return key
+	}
+	return prefix + "." + key
+}
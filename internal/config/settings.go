@@ -0,0 +1,87 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Settings 生效配置按配置键（与 YAML 一致，键名规则同 Diff）组织为通用结构：结构体转为映射，时长输出为字符串（如 30s）
+func Settings(cfg *Config) map[string]interface{} {
+	if cfg == nil {
+		return nil
+	}
+	m, _ := settingsValue(reflect.ValueOf(*cfg)).(map[string]interface{})
+	return m
+}
+
+func settingsValue(v reflect.Value) interface{} {
+	if !v.IsValid() {
+		return nil
+	}
+	if v.Type() == durationType {
+		return time.Duration(v.Int()).String()
+	}
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+		return settingsValue(v.Elem())
+	case reflect.Struct:
+		out := make(map[string]interface{}, v.NumField())
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if !f.IsExported() {
+				continue
+			}
+			out[fieldKey(f)] = settingsValue(v.Field(i))
+		}
+		return out
+	case reflect.Map:
+		out := make(map[string]interface{}, v.Len())
+		for _, k := range v.MapKeys() {
+			out[fmt.Sprint(k.Interface())] = settingsValue(v.MapIndex(k))
+		}
+		return out
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			return []interface{}{}
+		}
+		out := make([]interface{}, v.Len())
+		for i := range out {
+			out[i] = settingsValue(v.Index(i))
+		}
+		return out
+	default:
+		return v.Interface()
+	}
+}
+
+// ApplyConcurrencyProfile 按 collector.concurrency_profiles 切换并发档位（运行时修改使用），档位不存在时返回错误
+func ApplyConcurrencyProfile(cfg *Config, profile string) error {
+	p := strings.ToUpper(strings.TrimSpace(profile))
+	p = strings.TrimPrefix(p, "CONCURRENCY-")
+	for k, pc := range cfg.Collector.ConcurrencyProfiles {
+		if strings.ToUpper(k) != p {
+			continue
+		}
+		cfg.Collector.ConcurrencyProfile = p
+		if pc.Concurrent > 0 {
+			cfg.Collector.Concurrent = pc.Concurrent
+		}
+		if pc.Threads > 0 {
+			cfg.Collector.Threads = pc.Threads
+		}
+		return nil
+	}
+	names := make([]string, 0, len(cfg.Collector.ConcurrencyProfiles))
+	for k := range cfg.Collector.ConcurrencyProfiles {
+		names = append(names, strings.ToUpper(k))
+	}
+	sort.Strings(names)
+	return fmt.Errorf("unknown concurrency profile %q (defined: %s)", profile, strings.Join(names, ", "))
+}
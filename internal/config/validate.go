@@ -0,0 +1,532 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// 校验问题级别：error 阻止加载，warning 仅提示
+const (
+	IssueError   = "error"
+	IssueWarning = "warning"
+)
+
+// ValidationIssue 单条配置问题，Path 为配置键路径（如 collector.device_defaults.huawei.prompt_regex）
+type ValidationIssue struct {
+	Level   string `json:"level"`
+	Path    string `json:"path"`
+	Message string `json:"message"`
+}
+
+// ValidationReport 配置校验报告
+type ValidationReport struct {
+	Issues []ValidationIssue `json:"issues"`
+}
+
+func (r *ValidationReport) errorf(path, format string, args ...interface{}) {
+	r.Issues = append(r.Issues, ValidationIssue{Level: IssueError, Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+func (r *ValidationReport) warnf(path, format string, args ...interface{}) {
+	r.Issues = append(r.Issues, ValidationIssue{Level: IssueWarning, Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+func (r *ValidationReport) filter(level string) []ValidationIssue {
+	if r == nil {
+		return nil
+	}
+	var out []ValidationIssue
+	for _, i := range r.Issues {
+		if i.Level == level {
+			out = append(out, i)
+		}
+	}
+	return out
+}
+
+// Errors 阻止加载的问题
+func (r *ValidationReport) Errors() []ValidationIssue { return r.filter(IssueError) }
+
+// Warnings 不阻止加载的问题
+func (r *ValidationReport) Warnings() []ValidationIssue { return r.filter(IssueWarning) }
+
+// HasErrors 是否存在阻止加载的问题
+func (r *ValidationReport) HasErrors() bool { return len(r.Errors()) > 0 }
+
+// Err 存在错误时返回逐行列出全部错误的汇总错误，否则返回 nil
+func (r *ValidationReport) Err() error {
+	errs := r.Errors()
+	if len(errs) == 0 {
+		return nil
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "invalid configuration (%d errors):", len(errs))
+	for _, e := range errs {
+		fmt.Fprintf(&b, "\n  - %s: %s", e.Path, e.Message)
+	}
+	return fmt.Errorf("%s", b.String())
+}
+
+var lastValidation atomic.Pointer[ValidationReport]
+
+// LastValidation 最近一次成功加载配置的校验报告（仅含告警）；尚未加载时返回空报告
+func LastValidation() *ValidationReport {
+	if r := lastValidation.Load(); r != nil {
+		return r
+	}
+	return &ValidationReport{}
+}
+
+// ValidateYAML 试运行校验：将 data 作为基础配置文件解析并校验，不影响当前生效配置；
+// 环境/本地叠加文件不参与，configs/auto-ssh.yaml 与环境变量仍按正常加载规则生效
+func ValidateYAML(data []byte) (*ValidationReport, error) {
+	dir, err := os.MkdirTemp("", "config-validate-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "config.yaml")
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		return nil, err
+	}
+	_, report, err := parseWith(viper.New(), path)
+	if err != nil {
+		// 读取或解码失败（如 YAML 语法错误）作为单条错误返回
+		return &ValidationReport{Issues: []ValidationIssue{{Level: IssueError, Path: "", Message: err.Error()}}}, nil
+	}
+	return report, nil
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// checkDurationValues 校验配置文件中的时长字段：字符串须可解析且不为负；
+// 不带单位的数字按纳秒解析，较小的取值通常是漏写单位，给出告警
+func checkDurationValues(v *viper.Viper, report *ValidationReport) {
+	walkDurations(reflect.TypeOf(Config{}), "", func(path string) {
+		if !v.InConfig(path) {
+			return
+		}
+		switch raw := v.Get(path).(type) {
+		case string:
+			d, err := time.ParseDuration(strings.TrimSpace(raw))
+			if err != nil {
+				report.errorf(path, "invalid duration %q (use a unit, e.g. 30s, 5m, 1h)", raw)
+			} else if d < 0 {
+				report.errorf(path, "duration must not be negative: %s", raw)
+			}
+		case int, int64, float64:
+			n := reflect.ValueOf(raw).Convert(reflect.TypeOf(float64(0))).Float()
+			if n < 0 {
+				report.errorf(path, "duration must not be negative: %v", raw)
+			} else if n > 0 && n < float64(time.Millisecond) {
+				report.warnf(path, "bare number %v is read as nanoseconds; add a unit such as %vs", raw, raw)
+			}
+		}
+	})
+}
+
+// walkDurations 遍历结构体中的 time.Duration 字段（按 mapstructure 键路径）
+func walkDurations(t reflect.Type, prefix string, fn func(path string)) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		key := strings.Split(f.Tag.Get("mapstructure"), ",")[0]
+		if !f.IsExported() || key == "" || key == "-" {
+			continue
+		}
+		path := joinPath(prefix, key)
+		switch {
+		case f.Type == durationType:
+			fn(path)
+		case f.Type.Kind() == reflect.Struct:
+			walkDurations(f.Type, path, fn)
+		}
+	}
+}
+
+// checkUnknownPlatformKeys 平台默认项中的未知键（常为拼写错误，否则会被静默忽略）
+func checkUnknownPlatformKeys(raw map[string]interface{}, report *ValidationReport) {
+	t := reflect.TypeOf(PlatformDefaultsConfig{})
+	for platform, pv := range raw {
+		if m, ok := pv.(map[string]interface{}); ok {
+			unknownKeys(t, m, "collector.device_defaults."+platform, report)
+		}
+	}
+}
+
+func unknownKeys(t reflect.Type, raw map[string]interface{}, prefix string, report *ValidationReport) {
+	fields := make(map[string]reflect.Type, t.NumField())
+	known := make([]string, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		key := strings.Split(f.Tag.Get("mapstructure"), ",")[0]
+		if f.IsExported() && key != "" && key != "-" {
+			fields[key] = f.Type
+			known = append(known, key)
+		}
+	}
+	keys := make([]string, 0, len(raw))
+	for k := range raw {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		ft, ok := fields[strings.ToLower(k)]
+		path := joinPath(prefix, k)
+		if !ok {
+			report.errorf(path, "unknown key %q%s", k, suggestKey(strings.ToLower(k), known))
+			continue
+		}
+		for ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		switch {
+		case ft.Kind() == reflect.Struct && ft != durationType:
+			if m, ok := raw[k].(map[string]interface{}); ok {
+				unknownKeys(ft, m, path, report)
+			}
+		case ft.Kind() == reflect.Slice && ft.Elem().Kind() == reflect.Struct:
+			items, _ := raw[k].([]interface{})
+			for i, it := range items {
+				if m, ok := it.(map[string]interface{}); ok {
+					unknownKeys(ft.Elem(), m, fmt.Sprintf("%s[%d]", path, i), report)
+				}
+			}
+		}
+	}
+}
+
+// suggestKey 给出与未知键相近的已知键（共同前缀或编辑距离较小）
+func suggestKey(key string, known []string) string {
+	best, bestDist := "", 3
+	for _, k := range known {
+		if d := editDistance(key, k); d < bestDist {
+			best, bestDist = k, d
+		}
+	}
+	if best == "" {
+		return ""
+	}
+	return fmt.Sprintf(" (did you mean %q?)", best)
+}
+
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur := make([]int, len(b)+1)
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(b)]
+}
+
+// checkConcurrency 档位不存在时报错；显式配置的 concurrent 被档位覆盖时告警
+func checkConcurrency(cfg *Config, explicit bool, before int, profileFound bool, report *ValidationReport) {
+	prof := strings.TrimSpace(cfg.Collector.ConcurrencyProfile)
+	if !profileFound {
+		names := make([]string, 0, len(cfg.Collector.ConcurrencyProfiles))
+		for k := range cfg.Collector.ConcurrencyProfiles {
+			names = append(names, strings.ToUpper(k))
+		}
+		sort.Strings(names)
+		report.errorf("collector.concurrency_profile", "unknown profile %q (defined: %s)", prof, strings.Join(names, ", "))
+		return
+	}
+	if explicit && prof != "" && cfg.Collector.Concurrent != before {
+		report.warnf("collector.concurrent", "value %d is overridden by concurrency_profile %s (%d); set concurrency_profile to \"\" to use it", before, prof, cfg.Collector.Concurrent)
+	}
+	if cfg.Collector.Concurrent <= 0 {
+		report.errorf("collector.concurrent", "must be greater than 0")
+	}
+}
+
+// validateConfig 解码后的结构校验
+func validateConfig(cfg *Config, report *ValidationReport) {
+	platforms := make([]string, 0, len(cfg.Collector.DeviceDefaults))
+	for p := range cfg.Collector.DeviceDefaults {
+		platforms = append(platforms, p)
+	}
+	sort.Strings(platforms)
+	for _, platform := range platforms {
+		dd := cfg.Collector.DeviceDefaults[platform]
+		base := "collector.device_defaults." + platform
+		// 平台提示符正则，避免运行时静默回退
+		for i, p := range dd.PromptRegex {
+			if _, err := regexp.Compile(p); err != nil {
+				report.errorf(fmt.Sprintf("%s.prompt_regex[%d]", base, i), "invalid regex: %v", err)
+			}
+		}
+		if len(dd.PromptSuffixes) == 0 && len(dd.PromptRegex) == 0 {
+			report.warnf(base+".prompt_suffixes", "not set; the built-in suffixes #, >, ] are used")
+		}
+		switch strings.ToLower(strings.TrimSpace(dd.LineEnding)) {
+		case "", LineEndingCRLF, LineEndingLF:
+		default:
+			report.errorf(base+".line_ending", "invalid value %q (crlf|lf)", dd.LineEnding)
+		}
+		checkNormalizers(base, dd.Normalizers, report)
+		checkFacts(base, dd.Facts, report)
+		checkRetryPolicy(base+".retry_policy", dd.RetryPolicy, report)
+	}
+	checkRetryPolicy("collector.retry_policy", cfg.Collector.RetryPolicy, report)
+
+	switch strings.ToLower(strings.TrimSpace(cfg.Collector.DuplicateDevices)) {
+	case "", "reject", "merge", "copy":
+	default:
+		report.errorf("collector.duplicate_devices", "invalid value %q (reject|merge|copy)", cfg.Collector.DuplicateDevices)
+	}
+
+	// 平台映射规则
+	for i, m := range cfg.Collector.PlatformMappings {
+		path := fmt.Sprintf("collector.platform_mappings[%d]", i)
+		if strings.TrimSpace(m.Platform) == "" {
+			report.errorf(path+".platform", "platform is required")
+		} else if _, ok := cfg.Collector.DeviceDefaults[strings.ToLower(strings.TrimSpace(m.Platform))]; !ok && len(cfg.Collector.DeviceDefaults) > 0 {
+			report.warnf(path+".platform", "platform %q is not defined in device_defaults; default platform settings are used", m.Platform)
+		}
+		if _, err := regexp.Compile("(?i)" + m.Pattern); err != nil {
+			report.errorf(path+".pattern", "invalid regex: %v", err)
+		}
+	}
+
+	// 连接池空闲规则
+	for i, r := range cfg.SSH.Pool.IdleRules {
+		path := fmt.Sprintf("ssh.pool.idle_rules[%d]", i)
+		if m := strings.TrimSpace(r.Match); strings.Contains(m, "/") {
+			if _, _, err := net.ParseCIDR(m); err != nil {
+				report.errorf(path+".match", "invalid CIDR: %v", err)
+			}
+		}
+		if (r.MaxIdle != nil && *r.MaxIdle < 0) || r.IdleTimeout < 0 {
+			report.errorf(path, "max_idle and idle_timeout must not be negative")
+		}
+	}
+	if cfg.SSH.Pool.MaxIdlePerHost < 0 {
+		report.errorf("ssh.pool.max_idle_per_host", "must not be negative")
+	}
+	if cfg.SSH.Pool.MaxConnectionAge < 0 {
+		report.errorf("ssh.pool.max_connection_age", "must not be negative")
+	}
+	if cfg.SSH.CleanupInterval < 0 {
+		report.errorf("ssh.cleanup_interval", "must not be negative")
+	}
+
+	// 出站策略
+	checkEgressRule("ssh.egress", SSHEgressRule{Allow: cfg.SSH.Egress.Allow, Deny: cfg.SSH.Egress.Deny}, report)
+	for name, r := range cfg.SSH.Egress.Tenants {
+		checkEgressRule("ssh.egress.tenants."+name, r, report)
+	}
+
+	// 认证失败限流
+	if at := cfg.SSH.AuthThrottle; at.Enable {
+		if at.MaxFailures < 1 {
+			report.errorf("ssh.auth_throttle.max_failures", "must be at least 1 when auth_throttle is enabled")
+		}
+		if at.Cooldown <= 0 {
+			report.errorf("ssh.auth_throttle.cooldown", "must be positive when auth_throttle is enabled")
+		}
+	}
+	if u := strings.TrimSpace(cfg.SSH.AuthThrottle.NotifyURL); u != "" && !strings.HasPrefix(u, "http://") && !strings.HasPrefix(u, "https://") {
+		report.errorf("ssh.auth_throttle.notify_url", "scheme must be http or https")
+	}
+
+	// 设备熔断
+	if cb := cfg.SSH.CircuitBreaker; cb.Enable {
+		if cb.FailureThreshold < 1 {
+			report.errorf("ssh.circuit_breaker.failure_threshold", "must be at least 1 when circuit_breaker is enabled")
+		}
+		if cb.Cooldown <= 0 {
+			report.errorf("ssh.circuit_breaker.cooldown", "must be positive when circuit_breaker is enabled")
+		}
+	}
+
+	// 主机密钥校验
+	checkHostKeyMode("ssh.host_key.mode", cfg.SSH.HostKey.Mode, report)
+	needFiles := strings.EqualFold(strings.TrimSpace(cfg.SSH.HostKey.Mode), HostKeyKnownHosts)
+	for i, r := range cfg.SSH.HostKey.Rules {
+		path := fmt.Sprintf("ssh.host_key.rules[%d]", i)
+		if m := strings.TrimSpace(r.Match); strings.Contains(m, "/") {
+			if _, _, err := net.ParseCIDR(m); err != nil {
+				report.errorf(path+".match", "invalid CIDR: %v", err)
+			}
+		}
+		if strings.TrimSpace(r.Mode) == "" {
+			report.errorf(path+".mode", "mode is required")
+		} else {
+			checkHostKeyMode(path+".mode", r.Mode, report)
+		}
+		needFiles = needFiles || strings.EqualFold(strings.TrimSpace(r.Mode), HostKeyKnownHosts)
+	}
+	if needFiles && len(cfg.SSH.HostKey.KnownHosts) == 0 {
+		report.errorf("ssh.host_key.known_hosts", "at least one file is required for known_hosts mode")
+	}
+	for i, f := range cfg.SSH.HostKey.KnownHosts {
+		if _, err := os.Stat(f); err != nil {
+			report.warnf(fmt.Sprintf("ssh.host_key.known_hosts[%d]", i), "file not readable: %v", err)
+		}
+	}
+
+	// 录制脱敏规则
+	for i, p := range cfg.Server.SimulateRecord.RedactPatterns {
+		if _, err := regexp.Compile(p); err != nil {
+			report.errorf(fmt.Sprintf("server.simulate_record.redact_patterns[%d]", i), "invalid regex: %v", err)
+		}
+	}
+
+	// 备份命名模板：文件名须含 ${command} 以区分各命令；引用的事实须有平台定义
+	if tpl := strings.TrimSpace(cfg.Backup.FilenameTemplate); tpl != "" && !strings.Contains(tpl, "${command}") {
+		report.errorf("backup.filename_template", "template must contain ${command}")
+	}
+	defined := map[string]bool{}
+	for _, dd := range cfg.Collector.DeviceDefaults {
+		for _, f := range dd.Facts {
+			defined[strings.ToLower(strings.TrimSpace(f.Name))] = true
+		}
+	}
+	for _, t := range [][2]string{{"path_template", cfg.Backup.PathTemplate}, {"filename_template", cfg.Backup.FilenameTemplate}} {
+		for _, m := range NameTemplateTokenRe.FindAllStringSubmatch(t[1], -1) {
+			if name := strings.ToLower(m[1]); !BuiltinNameTokens[name] && !defined[name] {
+				report.warnf("backup."+t[0], "${%s} is not defined in any platform facts; device name is used", m[1])
+			}
+		}
+	}
+
+	if cfg.Backup.Transcript.MaxBytes < 0 {
+		report.errorf("backup.transcript.max_bytes", "must not be negative")
+	}
+
+	// MinIO：配置了地址或选作备份后端时须完整
+	minio := cfg.Storage.Minio
+	minioBackend := strings.EqualFold(strings.TrimSpace(cfg.Backup.StorageBackend), "minio")
+	if strings.TrimSpace(minio.Host) != "" || minioBackend {
+		if strings.TrimSpace(minio.Host) == "" || minio.Port <= 0 {
+			report.errorf("storage.minio.host", "host and port are required when MinIO is used")
+		}
+		if strings.TrimSpace(minio.Bucket) == "" {
+			report.errorf("storage.minio.bucket", "bucket is required when MinIO is enabled")
+		}
+	}
+}
+
+// NameTemplateTokenRe 备份命名模板变量 ${name}
+var NameTemplateTokenRe = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// BuiltinNameTokens 备份命名模板的内置变量（由请求与任务信息提供，其余变量为设备事实）
+var BuiltinNameTokens = map[string]bool{
+	"device_name": true, "device_ip": true, "platform": true,
+	"task_id": true, "date": true, "time": true, "command": true,
+}
+
+// checkEgressRule 出站规则条目：含 / 的须为合法 CIDR，其余为 IP 或主机名
+func checkHostKeyMode(path, mode string, report *ValidationReport) {
+	switch strings.ToLower(strings.TrimSpace(mode)) {
+	case "", HostKeyInsecure, HostKeyKnownHosts, HostKeyTOFU, HostKeyStrict:
+	default:
+		report.errorf(path, "invalid mode %q: must be insecure, known_hosts, tofu or strict", mode)
+	}
+}
+
+func checkEgressRule(base string, r SSHEgressRule, report *ValidationReport) {
+	for _, f := range []struct {
+		name string
+		list []string
+	}{{"allow", r.Allow}, {"deny", r.Deny}} {
+		for i, m := range f.list {
+			m = strings.TrimSpace(m)
+			path := fmt.Sprintf("%s.%s[%d]", base, f.name, i)
+			if m == "" {
+				report.errorf(path, "must not be empty")
+			} else if strings.Contains(m, "/") {
+				if _, _, err := net.ParseCIDR(m); err != nil {
+					report.errorf(path, "invalid CIDR: %v", err)
+				}
+			}
+		}
+	}
+}
+
+// checkFacts 校验设备事实：name/command 必填，pattern 须可编译且含捕获组
+func checkFacts(base string, list []FactConfig, report *ValidationReport) {
+	for i, f := range list {
+		path := fmt.Sprintf("%s.facts[%d]", base, i)
+		if strings.TrimSpace(f.Name) == "" {
+			report.errorf(path+".name", "name is required")
+		}
+		if strings.TrimSpace(f.Command) == "" {
+			report.errorf(path+".command", "command is required")
+		}
+		re, err := regexp.Compile(f.Pattern)
+		if err != nil {
+			report.errorf(path+".pattern", "invalid regex: %v", err)
+		} else if re.NumSubexp() == 0 {
+			report.errorf(path+".pattern", "pattern must contain a capture group")
+		}
+	}
+}
+
+// checkRetryPolicy 校验重试策略取值；空值表示沿用上层配置
+func checkRetryPolicy(base string, p RetryPolicyConfig, report *ValidationReport) {
+	switch strings.ToLower(strings.TrimSpace(p.Backoff)) {
+	case "", RetryBackoffConstant, RetryBackoffLinear, RetryBackoffExponential:
+	default:
+		report.errorf(base+".backoff", "invalid value %q (constant|linear|exponential)", p.Backoff)
+	}
+	switch strings.ToLower(strings.TrimSpace(p.RetryOn)) {
+	case "", RetryOnAny, RetryOnConnect:
+	default:
+		report.errorf(base+".retry_on", "invalid value %q (any|connect)", p.RetryOn)
+	}
+	if p.Multiplier != 0 && p.Multiplier < 1 {
+		report.errorf(base+".multiplier", "must be at least 1")
+	}
+	if p.Jitter < 0 || p.Jitter > 1 {
+		report.errorf(base+".jitter", "must be between 0 and 1")
+	}
+}
+
+// checkNormalizers 校验输出规整步骤：正则须可编译；未知类型告警（可能由扩展注册，否则运行时忽略）
+func checkNormalizers(base string, list []NormalizerConfig, report *ValidationReport) {
+	for i, n := range list {
+		path := fmt.Sprintf("%s.normalizers[%d]", base, i)
+		typ := strings.ToLower(strings.TrimSpace(n.Type))
+		switch typ {
+		case "":
+			report.errorf(path+".type", "type is required")
+			continue
+		case NormalizerStripANSI, NormalizerCollapseBlank, NormalizerStripTimestamps:
+		case NormalizerDropBanner, NormalizerRegexReplace:
+			if strings.TrimSpace(n.Pattern) == "" {
+				report.errorf(path+".pattern", "pattern is required for %s", typ)
+			}
+		default:
+			report.warnf(path+".type", "unknown normalizer type %q; ignored unless registered", n.Type)
+		}
+		for _, f := range [][2]string{{"pattern", n.Pattern}, {"end", n.End}} {
+			if f[1] == "" {
+				continue
+			}
+			if _, err := regexp.Compile(f[1]); err != nil {
+				report.errorf(path+"."+f[0], "invalid regex: %v", err)
+			}
+		}
+	}
+}
@@ -98,6 +98,42 @@ func autoMigrate() error {
 		&model.DeviceType{},
 		// 新增：采集设置表（保存快速采集的重试与超时）
 		&model.CollectorSettings{},
+		// 新增：设备凭据表（密码加密存储）
+		&model.Credential{},
+		// 新增：命令结果索引（差异化采集）
+		&model.CommandSnapshot{},
+		// 新增：TextFSM 模板库
+		&model.FSMTemplate{},
+		// 新增：外部解析服务登记
+		&model.FSMParserHook{},
+		// 新增：命令集（playbook）
+		&model.Playbook{},
+		// 新增：批量任务断点记录
+		&model.BatchJob{},
+		// 新增：批量设备定义（CSV/XLSX 导入）
+		&model.BatchDefinition{},
+		// 新增：执行日历
+		&model.Calendar{},
+		// 新增：凭据轮换记录
+		&model.CredentialRotation{},
+		// 新增：合规规则与审计报告
+		&model.ComplianceRule{},
+		&model.ComplianceReport{},
+		// 新增：运行手册与执行记录
+		&model.Runbook{},
+		&model.RunbookRun{},
+		// 新增：下发审批
+		&model.DeployApproval{},
+		// 新增：高可用选主租约
+		&model.HALease{},
+		// 新增：命令耗时与输出大小日汇总
+		&model.CommandStatsDaily{},
+		// 新增：首次初始化状态
+		&model.BootstrapState{},
+		// 新增：匿名化映射（原值加密存储）
+		&model.AnonymizeMapping{},
+		// 新增：设备主机密钥指纹（TOFU）
+		&model.HostKey{},
 	); err != nil {
 		return err
 	}
@@ -0,0 +1,19 @@
+package model
+
+import "time"
+
+// AnonymizeMapping 匿名化映射：同一映射表（map_id）内原值与假名一一对应
+// - digest: 原值的 HMAC（以保险箱主密钥派生），用于查找已有假名，不可逆
+// - original_enc: 原值以 AES-GCM 加密后存储，仅用于还原，不对外输出；团体字等凭据类为空（不可还原）
+// 表名：anonymize_mappings
+type AnonymizeMapping struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	MapID       string    `gorm:"type:varchar(128);not null;uniqueIndex:idx_anon_digest;uniqueIndex:idx_anon_pseudonym" json:"map_id"`
+	Kind        string    `gorm:"type:varchar(32);not null;uniqueIndex:idx_anon_digest" json:"kind"`
+	Digest      string    `gorm:"type:varchar(64);not null;uniqueIndex:idx_anon_digest" json:"-"`
+	Pseudonym   string    `gorm:"type:varchar(128);not null;uniqueIndex:idx_anon_pseudonym" json:"pseudonym"`
+	OriginalEnc string    `gorm:"type:text" json:"-"`
+	CreatedAt   time.Time `json:"created_at" gorm:"autoCreateTime"`
+}
+
+func (AnonymizeMapping) TableName() string { return "anonymize_mappings" }
@@ -0,0 +1,28 @@
+package model
+
+import "time"
+
+// DeployApproval 下发审批：待审批的下发请求与审批审计记录
+// - request: 下发请求 JSON（含凭据），以 vault 主密钥加密；审批结束后清空
+// - summary: 审批卡片展示的变更摘要（设备与命令）
+// - events: 审计事件（JSON 数组）：创建、卡片推送、批准/驳回、执行结果、过期
+// 表名：deploy_approvals
+type DeployApproval struct {
+	ID         string     `json:"id" gorm:"primaryKey;type:varchar(64)"`
+	TaskID     string     `json:"task_id" gorm:"type:varchar(128);index"`
+	TaskName   string     `json:"task_name" gorm:"type:varchar(255)"`
+	Status     string     `json:"status" gorm:"type:varchar(16);not null;index"`
+	Summary    string     `json:"summary" gorm:"type:text"`
+	Request    string     `json:"-" gorm:"type:text"`
+	Devices    int        `json:"devices"`
+	DecidedBy  string     `json:"decided_by,omitempty" gorm:"type:varchar(128)"`
+	DecidedVia string     `json:"decided_via,omitempty" gorm:"type:varchar(32)"`
+	DecidedAt  *time.Time `json:"decided_at,omitempty"`
+	ResultCode string     `json:"result_code,omitempty" gorm:"type:varchar(32)"`
+	Events     string     `json:"-" gorm:"type:text"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	CreatedAt  time.Time  `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt  time.Time  `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+func (DeployApproval) TableName() string { return "deploy_approvals" }
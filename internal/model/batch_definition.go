@@ -0,0 +1,21 @@
+package model
+
+import "time"
+
+// BatchDefinition 批量设备定义：由 CSV/XLSX 导入的设备清单，可作为批量备份或自定义批量采集执行
+// - devices: 设备列表 JSON；含明文密码时以 vault 主密钥加密（encrypted=true）
+// - source: 导入的文件名
+// 表名：batch_definitions
+type BatchDefinition struct {
+	ID          uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	Name        string    `json:"name" gorm:"type:varchar(128);not null;uniqueIndex"`
+	Description string    `json:"description" gorm:"type:text"`
+	Source      string    `json:"source" gorm:"type:varchar(255)"`
+	Devices     string    `json:"-" gorm:"type:text"`
+	Encrypted   bool      `json:"encrypted"`
+	Total       int       `json:"total"`
+	CreatedAt   time.Time `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt   time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+func (BatchDefinition) TableName() string { return "batch_definitions" }
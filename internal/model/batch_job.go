@@ -0,0 +1,37 @@
+package model
+
+import "time"
+
+// BatchJob 批量任务断点记录：进程重启后仅对未完成设备续跑
+// - request: 批量请求 JSON（含凭据），以 vault 主密钥加密；任务完成后清空
+// - done: 已完成设备（ip:port，JSON 数组）；failed_list 为其中执行失败的设备
+// - retry_of: 失败重试任务指向的原批量任务 ID
+// 表名：batch_jobs
+type BatchJob struct {
+	ID         string    `json:"id" gorm:"primaryKey;type:varchar(64)"`
+	Kind       string    `json:"kind" gorm:"type:varchar(32);not null"`
+	TaskName   string    `json:"task_name" gorm:"type:varchar(128)"`
+	Status     string    `json:"status" gorm:"type:varchar(16);not null;index"`
+	Request    string    `json:"-" gorm:"type:text"`
+	Total      int       `json:"total"`
+	Completed  int       `json:"completed"`
+	Done       string    `json:"-" gorm:"type:text"`
+	Failed     int       `json:"failed"`
+	FailedList string    `json:"-" gorm:"type:text"`
+	RetryOf    string    `json:"retry_of,omitempty" gorm:"type:varchar(64);index"`
+	Resumes    int       `json:"resumes"`
+	CreatedAt  time.Time `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt  time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+func (BatchJob) TableName() string { return "batch_jobs" }
+
+// BatchJob 状态与类型
+const (
+	BatchJobRunning     = "running"
+	BatchJobInterrupted = "interrupted"
+	BatchJobCompleted   = "completed"
+
+	BatchJobKindBackup        = "backup"
+	BatchJobKindCollectCustom = "collect_custom"
+)
@@ -0,0 +1,21 @@
+package model
+
+import "time"
+
+// BootstrapState 首次初始化状态（仅一行，ID=1）
+// 表名：bootstrap_state
+// Initialized 置位后初始化接口锁定；AdminTokenHash 为初始化生成的管理令牌的 SHA-256
+
+type BootstrapState struct {
+	ID             uint       `gorm:"primaryKey" json:"-"`
+	Initialized    bool       `gorm:"not null;default:false" json:"initialized"`
+	AdminTokenHash string     `gorm:"type:varchar(64)" json:"-"`
+	StorageBackend string     `gorm:"type:varchar(16)" json:"storage_backend,omitempty"`
+	InitializedBy  string     `gorm:"type:varchar(64)" json:"initialized_by,omitempty"` // 客户端地址
+	InitializedAt  *time.Time `json:"initialized_at,omitempty"`
+	UpdatedAt      time.Time  `gorm:"autoUpdateTime" json:"updated_at"`
+}
+
+func (BootstrapState) TableName() string {
+	return "bootstrap_state"
+}
@@ -0,0 +1,18 @@
+package model
+
+import "time"
+
+// Calendar 执行日历：工作日、节假日、调休补班日与封网日期，批量备份通过 calendar 引用
+// - rules: 日历规则（JSON：working_days/holidays/extra_workdays/blackouts）
+// 表名：calendars
+type Calendar struct {
+	ID          uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	Name        string    `json:"name" gorm:"type:varchar(128);not null;uniqueIndex"`
+	Description string    `json:"description" gorm:"type:text"`
+	Timezone    string    `json:"timezone" gorm:"type:varchar(64)"`
+	Rules       string    `json:"rules" gorm:"type:text"`
+	CreatedAt   time.Time `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt   time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+func (Calendar) TableName() string { return "calendars" }
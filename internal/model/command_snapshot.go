@@ -0,0 +1,23 @@
+package model
+
+import "time"
+
+// CommandSnapshot 设备命令最近一次落盘结果的索引（用于差异化采集判断新鲜度）
+// 每个 设备IP+端口+命令 仅保留最新一条
+// 表名：command_snapshots
+
+type CommandSnapshot struct {
+	ID          uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	DeviceIP    string    `json:"device_ip" gorm:"type:varchar(64);not null;uniqueIndex:idx_snapshot_ip_port_cmd"`
+	Port        int       `json:"port" gorm:"not null;default:22;uniqueIndex:idx_snapshot_ip_port_cmd"`
+	Command     string    `json:"command" gorm:"type:varchar(512);not null;uniqueIndex:idx_snapshot_ip_port_cmd"`
+	TaskID      string    `json:"task_id" gorm:"type:varchar(128)"`
+	URI         string    `json:"uri" gorm:"type:text;not null"`
+	Size        int64     `json:"size"`
+	Checksum    string    `json:"checksum" gorm:"type:varchar(128)"`
+	ContentType string    `json:"content_type" gorm:"type:varchar(128)"`
+	CollectedAt time.Time `json:"collected_at" gorm:"index"`
+	UpdatedAt   time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+func (CommandSnapshot) TableName() string { return "command_snapshots" }
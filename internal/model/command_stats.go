@@ -0,0 +1,22 @@
+package model
+
+import "time"
+
+// CommandStatsDaily 按天、平台汇总的命令耗时与输出大小分布（多实例写入时累加）
+// 表名：command_stats_daily
+type CommandStatsDaily struct {
+	ID       uint   `json:"id" gorm:"primaryKey"`
+	Date     string `json:"date" gorm:"type:varchar(10);uniqueIndex:idx_command_stats_day_platform"`
+	Platform string `json:"platform" gorm:"type:varchar(100);uniqueIndex:idx_command_stats_day_platform"`
+	Commands int64  `json:"commands"`
+	// DurationSumMS/DurationMaxMS 命令耗时合计与最大值；DurationBuckets 为各桶计数（JSON 数组，末项为超出最大边界）
+	DurationSumMS   int64     `json:"duration_sum_ms"`
+	DurationMaxMS   int64     `json:"duration_max_ms"`
+	DurationBuckets string    `json:"duration_buckets" gorm:"type:text"`
+	OutputBytesSum  int64     `json:"output_bytes_sum"`
+	OutputBytesMax  int64     `json:"output_bytes_max"`
+	OutputBuckets   string    `json:"output_buckets" gorm:"type:text"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+func (CommandStatsDaily) TableName() string { return "command_stats_daily" }
@@ -0,0 +1,42 @@
+package model
+
+import "time"
+
+// ComplianceRule 配置合规规则（按平台与命令匹配采集输出）
+// - platform: 为空表示适用于所有平台
+// - rule_type: regex | must_contain | must_not_contain
+// 表名：compliance_rules
+
+type ComplianceRule struct {
+	ID          uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	Name        string    `json:"name" gorm:"type:varchar(255);not null;uniqueIndex"`
+	Platform    string    `json:"platform" gorm:"type:varchar(64);index"`
+	Command     string    `json:"command" gorm:"type:varchar(512);not null"`
+	RuleType    string    `json:"rule_type" gorm:"type:varchar(32);not null"`
+	Pattern     string    `json:"pattern" gorm:"type:text;not null"`
+	Severity    string    `json:"severity" gorm:"type:varchar(32);default:medium"`
+	Description string    `json:"description" gorm:"type:text"`
+	Enabled     bool      `json:"enabled" gorm:"default:true"`
+	CreatedAt   time.Time `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt   time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+func (ComplianceRule) TableName() string { return "compliance_rules" }
+
+// ComplianceReport 合规审计报告（完整报告 JSON 同时写入备份存储）
+// 表名：compliance_reports
+
+type ComplianceReport struct {
+	ID           uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	TaskID       string    `json:"task_id" gorm:"type:varchar(128);not null;index"`
+	TaskName     string    `json:"task_name" gorm:"type:varchar(255)"`
+	Code         string    `json:"code" gorm:"type:varchar(32)"`
+	Total        int       `json:"total"`
+	Compliant    int       `json:"compliant"`
+	NonCompliant int       `json:"non_compliant"`
+	StorageURI   string    `json:"storage_uri" gorm:"type:text"`
+	Report       string    `json:"-" gorm:"type:text"`
+	CreatedAt    time.Time `json:"created_at" gorm:"autoCreateTime"`
+}
+
+func (ComplianceReport) TableName() string { return "compliance_reports" }
@@ -0,0 +1,23 @@
+package model
+
+import "time"
+
+// Credential 设备登录凭据（密码类字段以 AES-GCM 加密后存储）
+// - name: 凭据名称，唯一
+// - username: 登录用户名（明文）
+// - password_enc/enable_password_enc: 加密后的密码密文，不对外输出
+//
+// 批量接口可通过 credential_id 引用凭据，避免在请求中携带明文密码。
+
+type Credential struct {
+	ID                string    `gorm:"primaryKey;type:varchar(64)" json:"id"`
+	Name              string    `gorm:"type:varchar(128);uniqueIndex;not null" json:"name"`
+	Username          string    `gorm:"type:varchar(64);not null" json:"username"`
+	PasswordEnc       string    `gorm:"type:text" json:"-"`
+	EnablePasswordEnc string    `gorm:"type:text" json:"-"`
+	Remarks           string    `gorm:"type:text" json:"remarks"`
+	CreatedAt         time.Time `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt         time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+func (Credential) TableName() string { return "credentials" }
@@ -0,0 +1,40 @@
+package model
+
+import "time"
+
+// CredentialRotation 凭据轮换记录：新密码先逐设备验证，确认后写入设备表，可回滚
+// - new_password_enc/new_enable_password_enc: 新密码密文（vault 主密钥加密）
+// - targets: 目标设备及验证结果（JSON 数组），含各设备原密码密文，用于回滚
+// - old_password_enc/old_enable_password_enc: 凭据记录的原密码密文（关联 credential_id 时）
+// 表名：credential_rotations
+type CredentialRotation struct {
+	ID                   string     `json:"id" gorm:"primaryKey;type:varchar(64)"`
+	CredentialID         string     `json:"credential_id" gorm:"type:varchar(64);index"`
+	Username             string     `json:"username" gorm:"type:varchar(64);not null"`
+	Status               string     `json:"status" gorm:"type:varchar(16);not null;index"`
+	Concurrency          int        `json:"concurrency"`
+	NewPasswordEnc       string     `json:"-" gorm:"type:text"`
+	NewEnablePasswordEnc string     `json:"-" gorm:"type:text"`
+	OldPasswordEnc       string     `json:"-" gorm:"type:text"`
+	OldEnablePasswordEnc string     `json:"-" gorm:"type:text"`
+	Targets              string     `json:"-" gorm:"type:text"`
+	Total                int        `json:"total"`
+	NewOK                int        `json:"new_ok"`
+	RequiresOld          int        `json:"requires_old"`
+	Failed               int        `json:"failed"`
+	Updated              int        `json:"updated"`
+	FinalizedAt          *time.Time `json:"finalized_at,omitempty"`
+	RolledBackAt         *time.Time `json:"rolled_back_at,omitempty"`
+	CreatedAt            time.Time  `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt            time.Time  `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+func (CredentialRotation) TableName() string { return "credential_rotations" }
+
+// CredentialRotation 状态
+const (
+	RotationVerifying  = "verifying"
+	RotationVerified   = "verified"
+	RotationFinalized  = "finalized"
+	RotationRolledBack = "rolled_back"
+)
@@ -0,0 +1,44 @@
+package model
+
+import "time"
+
+// FSMTemplate TextFSM 模板库索引（模板内容存放于 data_format.template_dir 下的文件）
+// - command: 完整命令（小写，去除缩写标记），用于精确匹配
+// - command_pattern: 原始写法，可含 ntc-templates 的 [[...]] 缩写标记
+// - source: manual | ntc
+// 表名：fsm_templates
+
+type FSMTemplate struct {
+	ID             uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	Platform       string    `json:"platform" gorm:"type:varchar(64);not null;index;uniqueIndex:idx_fsm_tpl_platform_name"`
+	Name           string    `json:"name" gorm:"type:varchar(255);not null;uniqueIndex:idx_fsm_tpl_platform_name"`
+	Command        string    `json:"command" gorm:"type:varchar(512);not null;index"`
+	CommandPattern string    `json:"command_pattern" gorm:"type:varchar(512)"`
+	FilePath       string    `json:"file_path" gorm:"type:text;not null"`
+	Checksum       string    `json:"checksum" gorm:"type:varchar(128)"`
+	Source         string    `json:"source" gorm:"type:varchar(32);default:manual"`
+	CreatedAt      time.Time `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt      time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+func (FSMTemplate) TableName() string { return "fsm_templates" }
+
+// FSMParserHook 外部解析服务登记：指定 平台+命令 的输出交由外部 HTTP 服务解析（POST 原始文本，返回 JSON）
+// - command: 完整命令（小写），精确匹配
+// - timeout_ms/retries: 为 0 时使用 data_format.external_parser 的默认值
+// - no_fallback: 外部解析失败时不回退本地模板
+// 表名：fsm_parser_hooks
+type FSMParserHook struct {
+	ID         uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	Platform   string    `json:"platform" gorm:"type:varchar(64);not null;uniqueIndex:idx_fsm_hook_platform_cmd"`
+	Command    string    `json:"command" gorm:"type:varchar(512);not null;uniqueIndex:idx_fsm_hook_platform_cmd"`
+	URL        string    `json:"url" gorm:"type:text;not null"`
+	TimeoutMS  int       `json:"timeout_ms"`
+	Retries    int       `json:"retries"`
+	NoFallback bool      `json:"no_fallback"`
+	Enabled    bool      `json:"enabled"`
+	CreatedAt  time.Time `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt  time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+func (FSMParserHook) TableName() string { return "fsm_parser_hooks" }
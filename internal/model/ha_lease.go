@@ -0,0 +1,14 @@
+package model
+
+import "time"
+
+// HALease 高可用选主租约：持有者在到期前续约，过期后其他实例可抢占
+// 表名：ha_leases
+type HALease struct {
+	Name      string    `json:"name" gorm:"primaryKey;type:varchar(64)"`
+	Holder    string    `json:"holder" gorm:"type:varchar(255);not null"`
+	ExpiresAt time.Time `json:"expires_at"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+func (HALease) TableName() string { return "ha_leases" }
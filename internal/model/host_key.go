@@ -0,0 +1,20 @@
+package model
+
+import "time"
+
+// HostKey 设备 SSH 主机密钥指纹
+// - ssh.host_key.mode=tofu 时首次连接记录，之后校验；strict 模式仅接受已记录的指纹
+// - 同一设备（host+port）每种密钥类型一条；设备更换后需删除记录才能重新登记
+// 表名：host_keys
+type HostKey struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	Host        string    `gorm:"type:varchar(255);not null;uniqueIndex:idx_host_key" json:"host"`
+	Port        int       `gorm:"not null;uniqueIndex:idx_host_key" json:"port"`
+	KeyType     string    `gorm:"type:varchar(64);not null;uniqueIndex:idx_host_key" json:"key_type"`
+	Fingerprint string    `gorm:"type:varchar(128);not null" json:"fingerprint"`
+	PublicKey   string    `gorm:"type:text" json:"public_key"`
+	CreatedAt   time.Time `json:"created_at" gorm:"autoCreateTime"`
+	LastSeenAt  time.Time `json:"last_seen_at"`
+}
+
+func (HostKey) TableName() string { return "host_keys" }
@@ -0,0 +1,19 @@
+package model
+
+import "time"
+
+// Playbook 命令集：按名称登记的一组命令，可按平台提供变体，采集/备份请求通过 playbook 引用
+// - commands: 默认命令列表（JSON 数组，元素同 cli_list）
+// - variants: 平台变体（JSON 对象，平台 -> 命令列表），命中设备平台时替代 commands
+// 表名：playbooks
+type Playbook struct {
+	ID          uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	Name        string    `json:"name" gorm:"type:varchar(128);not null;uniqueIndex"`
+	Description string    `json:"description" gorm:"type:text"`
+	Commands    string    `json:"commands" gorm:"type:text"`
+	Variants    string    `json:"variants" gorm:"type:text"`
+	CreatedAt   time.Time `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt   time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+func (Playbook) TableName() string { return "playbooks" }
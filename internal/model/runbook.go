@@ -0,0 +1,39 @@
+package model
+
+import "time"
+
+// Runbook 运行手册：有序步骤（采集、合规检查、下发、通知），步骤可按前序步骤结果决定是否执行
+// - steps: 步骤定义（JSON 数组）
+// 表名：runbooks
+type Runbook struct {
+	ID          uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	Name        string    `json:"name" gorm:"type:varchar(128);not null;uniqueIndex"`
+	Description string    `json:"description" gorm:"type:text"`
+	Steps       string    `json:"steps" gorm:"type:text"`
+	CreatedAt   time.Time `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt   time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+func (Runbook) TableName() string { return "runbooks" }
+
+// RunbookRun 运行手册执行记录：逐步骤保存结果，中断后从未完成的步骤续跑
+// - steps: 启动时的步骤定义快照（JSON 数组），运行手册修改不影响已启动的执行
+// - results: 各步骤结果（JSON 数组）
+// - request: 设备列表 JSON（含凭据），以 vault 主密钥加密；执行结束后清空，未配置主密钥时为空（不可续跑）
+// 表名：runbook_runs
+type RunbookRun struct {
+	ID          string     `json:"id" gorm:"primaryKey;type:varchar(64)"`
+	RunbookID   uint       `json:"runbook_id" gorm:"index"`
+	RunbookName string     `json:"runbook_name" gorm:"type:varchar(128)"`
+	Status      string     `json:"status" gorm:"type:varchar(16);not null;index"`
+	Steps       string     `json:"-" gorm:"type:text"`
+	Results     string     `json:"-" gorm:"type:text"`
+	Request     string     `json:"-" gorm:"type:text"`
+	Devices     int        `json:"devices"`
+	Resumes     int        `json:"resumes"`
+	FinishedAt  *time.Time `json:"finished_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt   time.Time  `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+func (RunbookRun) TableName() string { return "runbook_runs" }
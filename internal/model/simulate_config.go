@@ -11,6 +11,17 @@ type SimNamespace struct {
 	Port        int       `json:"port"`
 	IdleSeconds int       `json:"idle_seconds"`
 	MaxConn     int       `json:"max_conn"`
+	LatencyMS   int       `json:"latency_ms"`  // 故障注入：命令回显延迟（毫秒）
+	JitterMS    int       `json:"jitter_ms"`   // 故障注入：延迟随机抖动（毫秒）
+	DropRate    float64   `json:"drop_rate"`   // 故障注入：每条命令断开连接的概率（0~1）
+	TelnetPort  int       `json:"telnet_port"` // Telnet 监听端口（0 不开启）
+	// 握手参数（算法列表按行存储）
+	ServerVersion     string `json:"server_version"`
+	Banner            string `json:"banner" gorm:"type:text"`
+	KeyExchanges      string `json:"key_exchanges" gorm:"type:text"`
+	Ciphers           string `json:"ciphers" gorm:"type:text"`
+	MACs              string `json:"macs" gorm:"type:text"`
+	HostKeyAlgorithms string `json:"host_key_algorithms" gorm:"type:text"`
 	CreatedAt   time.Time `json:"created_at" gorm:"autoCreateTime"`
 	UpdatedAt   time.Time `json:"updated_at" gorm:"autoUpdateTime"`
 }
@@ -27,6 +38,11 @@ type SimDeviceType struct {
 	EnableModeRequired bool      `json:"enable_mode_required"`
 	EnableModeSuffixe  string    `json:"enable_mode_suffixe"`
 	ConfigModeSuffixe  string    `json:"config_mode_suffixe"`
+	// 分页与确认提示（paging_disable_commands 按行存储，confirm_prompts 为 JSON）
+	PageLines             int    `json:"page_lines"`
+	MorePrompt            string `json:"more_prompt"`
+	PagingDisableCommands string `json:"paging_disable_commands" gorm:"type:text"`
+	ConfirmPrompts        string `json:"confirm_prompts" gorm:"type:text"`
 	CreatedAt          time.Time `json:"created_at" gorm:"autoCreateTime"`
 	UpdatedAt          time.Time `json:"updated_at" gorm:"autoUpdateTime"`
 }
@@ -40,6 +56,13 @@ type SimDeviceName struct {
 	ID         uint      `json:"id" gorm:"primaryKey;autoIncrement"`
 	Name       string    `json:"name" gorm:"type:varchar(128);uniqueIndex;not null"`
 	DeviceType string    `json:"device_type" gorm:"type:varchar(64);not null"`
+	// 登录认证与故障模拟（authorized_keys 按行存储）
+	Password       string    `json:"password"`
+	AuthorizedKeys string    `json:"authorized_keys" gorm:"type:text"`
+	MaxAuthTries   int       `json:"max_auth_tries"`
+	AuthFailure    string    `json:"auth_failure" gorm:"type:varchar(16)"`
+	AuthDelayMS    int       `json:"auth_delay_ms"`
+	Banner         string    `json:"banner" gorm:"type:text"`
 	CreatedAt  time.Time `json:"created_at" gorm:"autoCreateTime"`
 	UpdatedAt  time.Time `json:"updated_at" gorm:"autoUpdateTime"`
 }
@@ -9,7 +9,7 @@ import "time"
 // - UpdatedAt: 最近更新时间，用于页面展示
 //
 // 说明：params 字段建议为JSON对象结构，键集合参考用户提供的示例：
-//  prompt_suffixes、disable_paging_cmds、config_mode_clis、config_exit_cli、enable_required、
+//  prompt_suffixes、disable_paging_cmds、post_commands、login_sequence、config_mode_clis、config_exit_cli、enable_required、
 //  enable_cli、enable_except_output、skip_delayed_echo、timeout（含子字段）、
 //  output_filter（含 prefixes/contains/case_insensitive/trim_space）、
 //  interact（含 auto_interactions[{except_output,command_auto_send}], error_hints[], case_insensitive, trim_space）
@@ -20,6 +20,9 @@ type Task struct {
 	StartTime   time.Time `json:"start_time"`
 	EndTime     time.Time `json:"end_time"`
 	Duration    int64     `json:"duration"` // 执行时长，毫秒
+	TaskName    string    `json:"task_name" gorm:"type:varchar(128)"`
+	Metadata    string    `json:"-" gorm:"type:text"`    // 请求 metadata（JSON）
+	Annotations string    `json:"-" gorm:"type:text"`    // 事后标注（JSON），如工单号
 	CreatedAt   time.Time `json:"created_at" gorm:"autoCreateTime"`
 	UpdatedAt   time.Time `json:"updated_at" gorm:"autoUpdateTime"`
 }
@@ -74,6 +77,12 @@ type DeviceInfo struct {
 	Enabled    bool      `json:"enabled" gorm:"not null;default:true"`
 	Status     string    `json:"status" gorm:"type:varchar(16);default:'unknown'"`
 	Remarks    string    `json:"remarks" gorm:"type:text"`
+	// Source 设备来源：空为手工登记，netbox 为资产同步创建
+	Source     string    `json:"source" gorm:"type:varchar(32);index"`
+	// PortCandidates 备用端口（逗号分隔，如 "2222,830"），登记端口拒绝连接时按顺序尝试
+	PortCandidates string `json:"port_candidates" gorm:"type:varchar(128)"`
+	// ConnectedPort 最近一次通过备用端口连接成功的端口，下次优先尝试
+	ConnectedPort int `json:"connected_port"`
 	LastCheck  time.Time `json:"last_check"`
 	CreatedAt  time.Time `json:"created_at" gorm:"autoCreateTime"`
 	UpdatedAt  time.Time `json:"updated_at" gorm:"autoUpdateTime"`
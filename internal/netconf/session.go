@@ -0,0 +1,413 @@
+package netconf
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/sshcollectorpro/sshcollectorpro/pkg/ssh"
+)
+
+// 基础能力
+const (
+	CapBase10 = "urn:ietf:params:netconf:base:1.0"
+	CapBase11 = "urn:ietf:params:netconf:base:1.1"
+
+	baseNamespace = "urn:ietf:params:xml:ns:netconf:base:1.0"
+	// endOfMessage NETCONF 1.0 消息分隔符
+	endOfMessage = "]]>]]>"
+	// maxChunkSize RFC 6242 规定的最大分块长度
+	maxChunkSize = 4294967295
+)
+
+// ErrSessionClosed 会话已关闭
+var ErrSessionClosed = errors.New("netconf session closed")
+
+// RPCError 设备返回的 rpc-error
+type RPCError struct {
+	Type     string `xml:"error-type"`
+	Tag      string `xml:"error-tag"`
+	Severity string `xml:"error-severity"`
+	Message  string `xml:"error-message"`
+}
+
+func (e *RPCError) Error() string {
+	msg := strings.TrimSpace(e.Message)
+	if msg == "" {
+		msg = e.Tag
+	}
+	return fmt.Sprintf("netconf rpc-error (%s/%s): %s", e.Type, e.Tag, msg)
+}
+
+// Session NETCONF 会话：hello 交换后按协商的帧格式收发 RPC（1.1 使用分块帧，否则使用 ]]>]]> 分隔）
+type Session struct {
+	r      *bufio.Reader
+	w      io.Writer
+	closer io.Closer
+
+	// SessionID 设备分配的会话 ID
+	SessionID string
+	// ServerCapabilities 设备 hello 中声明的能力
+	ServerCapabilities []string
+
+	chunked bool
+	msgID   int
+	mu      sync.Mutex
+	closed  bool
+}
+
+// Dial 建立 SSH 连接并打开 netconf 子系统
+func Dial(ctx context.Context, cfg *ssh.Config, info *ssh.ConnectionInfo) (*Session, error) {
+	client := ssh.NewClient(cfg)
+	if err := client.Connect(ctx, info); err != nil {
+		return nil, err
+	}
+	stdin, stdout, err := client.Subsystem("netconf")
+	if err != nil {
+		client.Close()
+		return nil, err
+	}
+	sess, err := NewSession(ctx, stdout, stdin, client)
+	if err != nil {
+		client.Close()
+		return nil, err
+	}
+	return sess, nil
+}
+
+// NewSession 在已建立的传输上完成 hello 交换；closer 在会话关闭或上下文取消时关闭底层传输
+func NewSession(ctx context.Context, r io.Reader, w io.Writer, closer io.Closer) (*Session, error) {
+	s := &Session{r: bufio.NewReader(r), w: w, closer: closer}
+	hello := `<?xml version="1.0" encoding="UTF-8"?><hello xmlns="` + baseNamespace + `"><capabilities>` +
+		`<capability>` + CapBase10 + `</capability><capability>` + CapBase11 + `</capability></capabilities></hello>`
+	if err := s.withContext(ctx, func() error {
+		if _, err := io.WriteString(s.w, hello+endOfMessage); err != nil {
+			return fmt.Errorf("send hello: %w", err)
+		}
+		msg, err := s.readEOM()
+		if err != nil {
+			return fmt.Errorf("read server hello: %w", err)
+		}
+		return s.parseHello(msg)
+	}); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *Session) parseHello(msg []byte) error {
+	var h struct {
+		XMLName      xml.Name `xml:"hello"`
+		Capabilities []string `xml:"capabilities>capability"`
+		SessionID    string   `xml:"session-id"`
+	}
+	if err := xml.Unmarshal(msg, &h); err != nil {
+		return fmt.Errorf("parse server hello: %w", err)
+	}
+	s.SessionID = strings.TrimSpace(h.SessionID)
+	for _, c := range h.Capabilities {
+		c = strings.TrimSpace(c)
+		s.ServerCapabilities = append(s.ServerCapabilities, c)
+		if c == CapBase11 {
+			s.chunked = true
+		}
+	}
+	return nil
+}
+
+// HasCapability 设备是否声明了指定能力（按前缀匹配，忽略 ?module= 等参数）
+func (s *Session) HasCapability(uri string) bool {
+	for _, c := range s.ServerCapabilities {
+		if c == uri || strings.HasPrefix(c, uri+"?") {
+			return true
+		}
+	}
+	return false
+}
+
+// Call 发送一个 RPC（body 为 <rpc> 内部的操作元素），返回完整的 rpc-reply
+// 设备返回 rpc-error（severity 为 error）时同时返回 reply 与 *RPCError
+func (s *Session) Call(ctx context.Context, body string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return "", ErrSessionClosed
+	}
+	s.msgID++
+	rpc := fmt.Sprintf(`<rpc message-id="%d" xmlns="%s">%s</rpc>`, s.msgID, baseNamespace, body)
+	var reply []byte
+	err := s.withContext(ctx, func() error {
+		if err := s.writeMessage([]byte(rpc)); err != nil {
+			return fmt.Errorf("send rpc: %w", err)
+		}
+		var err error
+		if reply, err = s.readMessage(); err != nil {
+			return fmt.Errorf("read rpc-reply: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return string(reply), replyError(reply)
+}
+
+// Get 执行 <get>，filter 为子树过滤内容（可为空）
+func (s *Session) Get(ctx context.Context, filter string) (string, error) {
+	return s.Call(ctx, "<get>"+subtreeFilter(filter)+"</get>")
+}
+
+// GetConfig 执行 <get-config>，source 默认 running
+func (s *Session) GetConfig(ctx context.Context, source, filter string) (string, error) {
+	if source == "" {
+		source = "running"
+	}
+	return s.Call(ctx, "<get-config><source><"+source+"/></source>"+subtreeFilter(filter)+"</get-config>")
+}
+
+// Close 发送 close-session 并关闭底层传输
+func (s *Session) Close() error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+	s.msgID++
+	_ = s.writeMessage([]byte(fmt.Sprintf(`<rpc message-id="%d" xmlns="%s"><close-session/></rpc>`, s.msgID, baseNamespace)))
+	s.closed = true
+	s.mu.Unlock()
+	if s.closer != nil {
+		return s.closer.Close()
+	}
+	return nil
+}
+
+// withContext 执行阻塞读写；上下文取消时关闭传输以解除阻塞
+func (s *Session) withContext(ctx context.Context, fn func() error) error {
+	done := make(chan error, 1)
+	go func() { done <- fn() }()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		s.closed = true
+		if s.closer != nil {
+			_ = s.closer.Close()
+		}
+		<-done
+		return ctx.Err()
+	}
+}
+
+func (s *Session) writeMessage(msg []byte) error {
+	if !s.chunked {
+		_, err := s.w.Write(append(msg, endOfMessage...))
+		return err
+	}
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "\n#%d\n", len(msg))
+	buf.Write(msg)
+	buf.WriteString("\n##\n")
+	_, err := s.w.Write(buf.Bytes())
+	return err
+}
+
+func (s *Session) readMessage() ([]byte, error) {
+	if s.chunked {
+		return s.readChunked()
+	}
+	return s.readEOM()
+}
+
+// readEOM 读取到 ]]>]]> 为止
+func (s *Session) readEOM() ([]byte, error) {
+	var buf bytes.Buffer
+	for {
+		b, err := s.r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		buf.WriteByte(b)
+		if b == '>' && bytes.HasSuffix(buf.Bytes(), []byte(endOfMessage)) {
+			return bytes.TrimSpace(buf.Bytes()[:buf.Len()-len(endOfMessage)]), nil
+		}
+	}
+}
+
+// readChunked 读取分块帧：\n#<len>\n<data>...\n##\n
+func (s *Session) readChunked() ([]byte, error) {
+	var msg bytes.Buffer
+	for {
+		if err := s.expect("\n#"); err != nil {
+			return nil, err
+		}
+		line, err := s.r.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		line = strings.TrimSuffix(line, "\n")
+		if line == "#" {
+			return msg.Bytes(), nil
+		}
+		n, err := strconv.ParseUint(line, 10, 64)
+		if err != nil || n == 0 || n > maxChunkSize {
+			return nil, fmt.Errorf("invalid chunk size %q", line)
+		}
+		if _, err := io.CopyN(&msg, s.r, int64(n)); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (s *Session) expect(want string) error {
+	got := make([]byte, len(want))
+	if _, err := io.ReadFull(s.r, got); err != nil {
+		return err
+	}
+	if string(got) != want {
+		return fmt.Errorf("invalid chunk framing: got %q, want %q", got, want)
+	}
+	return nil
+}
+
+// replyError 提取首个 severity=error 的 rpc-error
+func replyError(reply []byte) error {
+	var r struct {
+		Errors []RPCError `xml:"rpc-error"`
+	}
+	if err := xml.Unmarshal(reply, &r); err != nil {
+		return fmt.Errorf("parse rpc-reply: %w", err)
+	}
+	for i := range r.Errors {
+		if sev := strings.TrimSpace(r.Errors[i].Severity); sev == "" || sev == "error" {
+			e := r.Errors[i]
+			e.Type, e.Tag, e.Severity = strings.TrimSpace(e.Type), strings.TrimSpace(e.Tag), strings.TrimSpace(e.Severity)
+			return &e
+		}
+	}
+	return nil
+}
+
+func subtreeFilter(filter string) string {
+	filter = strings.TrimSpace(filter)
+	if filter == "" {
+		return ""
+	}
+	if strings.HasPrefix(filter, "<filter") {
+		return filter
+	}
+	return `<filter type="subtree">` + filter + `</filter>`
+}
+
+// readOperations 采集允许的只读 RPC 操作；edit-config、commit、delete-config 等写操作一律拒绝
+var readOperations = map[string]bool{"get": true, "get-config": true, "get-schema": true}
+
+// BuildRPC 将采集命令转换为 RPC 操作元素，仅允许只读操作：
+//
+//	get [<子树过滤>]
+//	get-config [running|candidate|startup] [<子树过滤>]
+//	get-schema <identifier> [version]
+//	<get .../> | <get-config .../> | <get-schema .../>  以 < 开头时原样发送
+func BuildRPC(cli string) (string, error) {
+	cli = strings.TrimSpace(cli)
+	body, err := buildRPC(cli)
+	if err != nil {
+		return "", err
+	}
+	if err := checkReadOperation(body); err != nil {
+		return "", err
+	}
+	return body, nil
+}
+
+// checkReadOperation 操作元素须为单个只读操作（过滤内容拼接出的额外元素同样拒绝）
+func checkReadOperation(body string) error {
+	dec := xml.NewDecoder(strings.NewReader(body))
+	depth, roots := 0, 0
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("invalid rpc xml: %w", err)
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if depth == 0 {
+				roots++
+				if roots > 1 {
+					return fmt.Errorf("exactly one netconf operation is allowed")
+				}
+				if !readOperations[t.Name.Local] {
+					return fmt.Errorf("netconf operation %q is not allowed (expected get, get-config or get-schema)", t.Name.Local)
+				}
+			}
+			depth++
+		case xml.EndElement:
+			depth--
+		case xml.CharData:
+			if depth == 0 && len(bytes.TrimSpace(t)) > 0 {
+				return fmt.Errorf("invalid rpc xml: text outside operation element")
+			}
+		}
+	}
+	if roots == 0 {
+		return fmt.Errorf("empty netconf operation")
+	}
+	return nil
+}
+
+// buildRPC 展开命令简写，以 < 开头时原样返回
+func buildRPC(cli string) (string, error) {
+	if strings.HasPrefix(cli, "<") {
+		return cli, nil
+	}
+	op, rest := cli, ""
+	if i := strings.IndexAny(cli, " \t"); i >= 0 {
+		op, rest = cli[:i], strings.TrimSpace(cli[i+1:])
+	}
+	switch strings.ToLower(op) {
+	case "get":
+		return "<get>" + subtreeFilter(rest) + "</get>", nil
+	case "get-config":
+		source := "running"
+		if rest != "" && !strings.HasPrefix(rest, "<") {
+			source, rest = rest, ""
+			if i := strings.IndexAny(source, " \t"); i >= 0 {
+				source, rest = source[:i], strings.TrimSpace(source[i+1:])
+			}
+		}
+		switch source = strings.ToLower(source); source {
+		case "running", "candidate", "startup":
+		default:
+			return "", fmt.Errorf("invalid get-config source %q", source)
+		}
+		return "<get-config><source><" + source + "/></source>" + subtreeFilter(rest) + "</get-config>", nil
+	case "get-schema":
+		f := strings.Fields(rest)
+		if len(f) == 0 || len(f) > 2 {
+			return "", fmt.Errorf("get-schema requires an identifier and optional version")
+		}
+		var b strings.Builder
+		b.WriteString(`<get-schema xmlns="urn:ietf:params:xml:ns:yang:ietf-netconf-monitoring"><identifier>`)
+		xml.EscapeText(&b, []byte(f[0]))
+		b.WriteString("</identifier>")
+		if len(f) == 2 {
+			b.WriteString("<version>")
+			xml.EscapeText(&b, []byte(f[1]))
+			b.WriteString("</version>")
+		}
+		b.WriteString("</get-schema>")
+		return b.String(), nil
+	default:
+		return "", fmt.Errorf("unsupported netconf operation %q (expected get, get-config, get-schema or raw XML of these)", op)
+	}
+}
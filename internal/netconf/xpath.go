@@ -0,0 +1,352 @@
+package netconf
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Node XML 元素节点（名称为去掉前缀的本地名，命名空间不参与匹配）
+type Node struct {
+	Name     string
+	Attrs    map[string]string
+	Children []*Node
+	text     strings.Builder
+}
+
+// Text 节点及其后代的文本（去首尾空白）
+func (n *Node) Text() string {
+	var b strings.Builder
+	n.collectText(&b)
+	return strings.TrimSpace(b.String())
+}
+
+func (n *Node) collectText(b *strings.Builder) {
+	b.WriteString(n.text.String())
+	for _, c := range n.Children {
+		c.collectText(b)
+	}
+}
+
+// ParseDocument 解析 XML 文档，返回虚拟根节点（其子节点为文档根元素）
+func ParseDocument(doc string) (*Node, error) {
+	root := &Node{}
+	stack := []*Node{root}
+	dec := xml.NewDecoder(strings.NewReader(doc))
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("parse xml: %w", err)
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			n := &Node{Name: t.Name.Local, Attrs: map[string]string{}}
+			for _, a := range t.Attr {
+				n.Attrs[a.Name.Local] = a.Value
+			}
+			parent := stack[len(stack)-1]
+			parent.Children = append(parent.Children, n)
+			stack = append(stack, n)
+		case xml.EndElement:
+			stack = stack[:len(stack)-1]
+		case xml.CharData:
+			stack[len(stack)-1].text.Write(t)
+		}
+	}
+	return root, nil
+}
+
+// XPath 编译后的路径表达式，支持 XPath 的常用子集：
+//
+//	/a/b      子元素路径（从文档根开始）
+//	//b, a//b 后代元素
+//	*         任意元素
+//	p:name    前缀被忽略，按本地名匹配
+//	[n]       位置（从 1 开始）
+//	[c='v']   子元素文本等于 v；[@x='v'] 属性等于 v
+//	.../@x    末尾取属性值；.../text() 取文本
+//
+// 不以 / 开头的表达式按 // 处理（在整个文档中查找）
+type XPath struct {
+	expr  string
+	steps []xpathStep
+	attr  string // 末尾 @attr
+}
+
+type xpathStep struct {
+	descendant bool
+	name       string // * 表示任意
+	preds      []xpathPred
+}
+
+type xpathPred struct {
+	pos   int    // >0 位置谓词
+	child string // 子元素名
+	attr  string // 属性名
+	value string
+}
+
+// CompileXPath 编译路径表达式
+func CompileXPath(expr string) (*XPath, error) {
+	src := strings.TrimSpace(expr)
+	if src == "" {
+		return nil, fmt.Errorf("empty xpath")
+	}
+	if !strings.HasPrefix(src, "/") {
+		src = "//" + src
+	}
+	parts, err := splitXPath(src)
+	if err != nil {
+		return nil, fmt.Errorf("invalid xpath %q: %w", expr, err)
+	}
+	x := &XPath{expr: expr}
+	descendant := false
+	for i, p := range parts {
+		if p == "" {
+			// 连续的 / 表示后代轴；开头的单个 / 产生的空段忽略
+			if i > 0 {
+				descendant = true
+			}
+			continue
+		}
+		last := i == len(parts)-1
+		if last && p == "text()" {
+			break
+		}
+		if last && strings.HasPrefix(p, "@") {
+			x.attr = localName(p[1:])
+			if x.attr == "" {
+				return nil, fmt.Errorf("invalid xpath %q: empty attribute name", expr)
+			}
+			break
+		}
+		step, err := parseStep(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid xpath %q: %w", expr, err)
+		}
+		step.descendant = descendant
+		descendant = false
+		x.steps = append(x.steps, step)
+	}
+	if len(x.steps) == 0 {
+		return nil, fmt.Errorf("invalid xpath %q: no element step", expr)
+	}
+	return x, nil
+}
+
+// String 原始表达式
+func (x *XPath) String() string { return x.expr }
+
+// Select 返回匹配节点的文本（或属性值），按文档顺序
+func (x *XPath) Select(root *Node) []string {
+	ctx := []*Node{root}
+	for _, st := range x.steps {
+		var next []*Node
+		seen := map[*Node]bool{}
+		for _, n := range ctx {
+			for _, m := range st.match(n) {
+				if !seen[m] {
+					seen[m] = true
+					next = append(next, m)
+				}
+			}
+		}
+		ctx = next
+		if len(ctx) == 0 {
+			break
+		}
+	}
+	out := make([]string, 0, len(ctx))
+	for _, n := range ctx {
+		if x.attr != "" {
+			if v, ok := n.Attrs[x.attr]; ok {
+				out = append(out, v)
+			}
+			continue
+		}
+		out = append(out, n.Text())
+	}
+	return out
+}
+
+// match 在上下文节点下按轴与名称匹配，再依次应用谓词
+func (st xpathStep) match(n *Node) []*Node {
+	var cands []*Node
+	var walk func(*Node)
+	walk = func(p *Node) {
+		for _, c := range p.Children {
+			if st.name == "*" || c.Name == st.name {
+				cands = append(cands, c)
+			}
+			if st.descendant {
+				walk(c)
+			}
+		}
+	}
+	walk(n)
+	for _, pr := range st.preds {
+		if pr.pos > 0 {
+			if pr.pos > len(cands) {
+				return nil
+			}
+			cands = cands[pr.pos-1 : pr.pos]
+			continue
+		}
+		kept := cands[:0:0]
+		for _, c := range cands {
+			if pr.test(c) {
+				kept = append(kept, c)
+			}
+		}
+		cands = kept
+	}
+	return cands
+}
+
+func (pr xpathPred) test(n *Node) bool {
+	if pr.attr != "" {
+		v, ok := n.Attrs[pr.attr]
+		return ok && v == pr.value
+	}
+	for _, c := range n.Children {
+		if c.Name == pr.child && c.Text() == pr.value {
+			return true
+		}
+	}
+	return false
+}
+
+// splitXPath 按 / 切分，忽略谓词与引号内的 /
+func splitXPath(s string) ([]string, error) {
+	var parts []string
+	depth := 0
+	var quote byte
+	start := 0
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case c == '[':
+			depth++
+		case c == ']':
+			depth--
+			if depth < 0 {
+				return nil, fmt.Errorf("unbalanced ]")
+			}
+		case c == '/' && depth == 0:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	if quote != 0 || depth != 0 {
+		return nil, fmt.Errorf("unterminated predicate or string")
+	}
+	return append(parts, s[start:]), nil
+}
+
+func parseStep(p string) (xpathStep, error) {
+	st := xpathStep{}
+	name := p
+	if i := strings.IndexByte(p, '['); i >= 0 {
+		name = p[:i]
+		rest := p[i:]
+		for rest != "" {
+			if rest[0] != '[' {
+				return st, fmt.Errorf("unexpected %q after predicate", rest)
+			}
+			end := predicateEnd(rest)
+			if end < 0 {
+				return st, fmt.Errorf("unterminated predicate in %q", p)
+			}
+			pr, err := parsePredicate(strings.TrimSpace(rest[1:end]))
+			if err != nil {
+				return st, err
+			}
+			st.preds = append(st.preds, pr)
+			rest = rest[end+1:]
+		}
+	}
+	name = strings.TrimSpace(name)
+	if name != "*" {
+		name = localName(name)
+	}
+	if name == "" || strings.ContainsAny(name, "()@=") {
+		return st, fmt.Errorf("unsupported step %q", p)
+	}
+	st.name = name
+	return st, nil
+}
+
+// predicateEnd 返回与开头 [ 匹配的 ] 下标
+func predicateEnd(s string) int {
+	var quote byte
+	for i := 1; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case c == ']':
+			return i
+		}
+	}
+	return -1
+}
+
+func parsePredicate(s string) (xpathPred, error) {
+	if n, err := strconv.Atoi(s); err == nil {
+		if n < 1 {
+			return xpathPred{}, fmt.Errorf("position predicate must be >= 1: [%s]", s)
+		}
+		return xpathPred{pos: n}, nil
+	}
+	eq := strings.IndexByte(s, '=')
+	if eq <= 0 {
+		return xpathPred{}, fmt.Errorf("unsupported predicate [%s]", s)
+	}
+	lhs := strings.TrimSpace(s[:eq])
+	rhs := strings.TrimSpace(s[eq+1:])
+	if len(rhs) < 2 || (rhs[0] != '\'' && rhs[0] != '"') || rhs[len(rhs)-1] != rhs[0] {
+		return xpathPred{}, fmt.Errorf("predicate value must be quoted: [%s]", s)
+	}
+	pr := xpathPred{value: rhs[1 : len(rhs)-1]}
+	if strings.HasPrefix(lhs, "@") {
+		pr.attr = localName(lhs[1:])
+	} else {
+		pr.child = localName(lhs)
+	}
+	if pr.attr == "" && pr.child == "" {
+		return xpathPred{}, fmt.Errorf("unsupported predicate [%s]", s)
+	}
+	return pr, nil
+}
+
+func localName(s string) string {
+	s = strings.TrimSpace(s)
+	if i := strings.LastIndexByte(s, ':'); i >= 0 {
+		return s[i+1:]
+	}
+	return s
+}
+
+// Extract 按名称对文档执行多条路径表达式
+func Extract(root *Node, paths map[string]*XPath) map[string][]string {
+	out := make(map[string][]string, len(paths))
+	for name, x := range paths {
+		out[name] = x.Select(root)
+	}
+	return out
+}
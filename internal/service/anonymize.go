@@ -0,0 +1,610 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/sshcollectorpro/sshcollectorpro/internal/config"
+	"github.com/sshcollectorpro/sshcollectorpro/pkg/logger"
+)
+
+// 匿名化数据类型
+const (
+	AnonymizeIP        = "ip"
+	AnonymizeIPv6      = "ipv6"
+	AnonymizeMAC       = "mac"
+	AnonymizeHostname  = "hostname"
+	AnonymizeUsername  = "username"
+	AnonymizeCommunity = "community"
+)
+
+// anonymizeKinds 全部类型；请求未指定 kinds 时全部处理
+var anonymizeKinds = []string{AnonymizeIP, AnonymizeIPv6, AnonymizeMAC, AnonymizeHostname, AnonymizeUsername, AnonymizeCommunity}
+
+// anonymizeOneWayKinds 凭据类取值只保存 HMAC 与假名、不保存原值密文，不可还原
+var anonymizeOneWayKinds = map[string]bool{AnonymizeCommunity: true}
+
+// ErrAnonymizeMapNotFound 还原时映射表不存在
+var ErrAnonymizeMapNotFound = withKind(ErrNotFound, errors.New("anonymize map not found"))
+
+// AnonymizeEntry 映射记录：digest 为原值 HMAC（查找用），original_enc 为原值密文（还原用，一次性类型为空）
+type AnonymizeEntry struct {
+	Kind        string
+	Digest      string
+	Pseudonym   string
+	OriginalEnc string
+}
+
+// AnonymizeStore 匿名化映射持久化
+type AnonymizeStore interface {
+	Entries(mapID string) ([]AnonymizeEntry, error)
+	Append(mapID string, entries []AnonymizeEntry) error
+	DeleteMap(mapID string) (int64, error)
+}
+
+// AnonymizeRequest 匿名化请求：text 与 uris 至少提供一项
+type AnonymizeRequest struct {
+	// MapID 已有映射表；同一映射表内同一原值总是得到同一假名。为空时新建映射表并在响应中返回随机生成的 map_id
+	MapID string   `json:"map_id,omitempty"`
+	Text  string   `json:"text,omitempty"`
+	URIs  []string `json:"uris,omitempty"` // 已存储对象（file://、minio://、s3://、azure://）
+	Kinds []string `json:"kinds,omitempty"`
+	// Hostnames/Usernames 额外需要替换的主机名与用户名（输出中无法识别时补充）
+	Hostnames []string `json:"hostnames,omitempty"`
+	Usernames []string `json:"usernames,omitempty"`
+}
+
+// AnonymizedObject 已存储对象的匿名化内容
+type AnonymizedObject struct {
+	URI     string `json:"uri"`
+	Content string `json:"content"`
+}
+
+// AnonymizeResponse 匿名化结果
+type AnonymizeResponse struct {
+	MapID   string             `json:"map_id"`
+	Text    string             `json:"text,omitempty"`
+	Objects []AnonymizedObject `json:"objects,omitempty"`
+	// Replaced 各类型的替换次数；NewMappings 本次新增的映射数
+	Replaced    map[string]int `json:"replaced"`
+	NewMappings int            `json:"new_mappings"`
+}
+
+// DeanonymizeRequest 按映射表还原文本
+type DeanonymizeRequest struct {
+	MapID string `json:"map_id"`
+	Text  string `json:"text"`
+}
+
+// DeanonymizeResponse 还原结果
+type DeanonymizeResponse struct {
+	MapID    string `json:"map_id"`
+	Text     string `json:"text"`
+	Restored int    `json:"restored"`
+}
+
+// AnonymizeService 原始输出匿名化：IP/MAC/主机名/用户名/团体字一致性假名替换，映射加密存储可还原
+type AnonymizeService struct {
+	provider *config.Provider
+	store    AnonymizeStore
+	reader   StorageReader
+	// mu 串行化映射表的读取-分配-写入，保证同一原值只分配一个假名
+	mu sync.Mutex
+}
+
+// NewAnonymizeService 创建匿名化服务；reader 为空时不支持 uris
+func NewAnonymizeService(cfg *config.Config, store AnonymizeStore, reader StorageReader) *AnonymizeService {
+	return &AnonymizeService{provider: config.ProviderFor(cfg), store: store, reader: reader}
+}
+
+var anonymizeMapIDRe = regexp.MustCompile(`^[\w.\-]{1,128}$`)
+
+// newAnonymizeMapID 随机映射表编号（128 位），不可猜测
+func newAnonymizeMapID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "am-" + hex.EncodeToString(b), nil
+}
+
+func normalizeMapID(id string) (string, error) {
+	id = strings.TrimSpace(id)
+	if id == "" {
+		return "", validationErrorf("map_id is required")
+	}
+	if !anonymizeMapIDRe.MatchString(id) {
+		return "", validationErrorf("map_id must match %s", anonymizeMapIDRe.String())
+	}
+	return id, nil
+}
+
+// Anonymize 替换文本与已存储对象中的敏感值；新分配的假名在返回前写入映射表
+func (s *AnonymizeService) Anonymize(ctx context.Context, req *AnonymizeRequest) (*AnonymizeResponse, error) {
+	if req == nil || (req.Text == "" && len(req.URIs) == 0) {
+		return nil, validationErrorf("text or uris is required")
+	}
+	created := strings.TrimSpace(req.MapID) == ""
+	var mapID string
+	var err error
+	if created {
+		mapID, err = newAnonymizeMapID()
+	} else {
+		mapID, err = normalizeMapID(req.MapID)
+	}
+	if err != nil {
+		return nil, err
+	}
+	kinds := make(map[string]bool, len(anonymizeKinds))
+	for _, k := range req.Kinds {
+		k = strings.ToLower(strings.TrimSpace(k))
+		if !slices.Contains(anonymizeKinds, k) {
+			return nil, validationErrorf("unsupported kind: %s (expected %s)", k, strings.Join(anonymizeKinds, ", "))
+		}
+		kinds[k] = true
+	}
+	if len(kinds) == 0 {
+		for _, k := range anonymizeKinds {
+			kinds[k] = true
+		}
+	}
+	cc, err := NewCredentialCipher(s.conf())
+	if err != nil {
+		return nil, err
+	}
+
+	// 先读取对象，避免持锁等待远端存储
+	objects := make([]AnonymizedObject, 0, len(req.URIs))
+	for _, uri := range req.URIs {
+		if s.reader == nil {
+			return nil, fmt.Errorf("storage backend does not support reading")
+		}
+		data, err := s.reader.Read(ctx, uri)
+		if err != nil {
+			return nil, fmt.Errorf("read %s: %w", uri, err)
+		}
+		objects = append(objects, AnonymizedObject{URI: uri, Content: string(data)})
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entries, err := s.store.Entries(mapID)
+	if err != nil {
+		return nil, err
+	}
+	// 映射表只能由服务端创建，指定的 map_id 须为已有映射表
+	if !created && len(entries) == 0 {
+		return nil, fmt.Errorf("%w: %s (omit map_id to create a new map)", ErrAnonymizeMapNotFound, mapID)
+	}
+	a := newAnonymizer(cc, s.conf().Vault.MasterKey, kinds, entries)
+	// 主机名等取值先从全部输入中识别，保证在每份输入中都被替换
+	a.discover(AnonymizeHostname, req.Hostnames...)
+	a.discover(AnonymizeUsername, req.Usernames...)
+	a.discoverIn(req.Text)
+	for _, o := range objects {
+		a.discoverIn(o.Content)
+	}
+
+	resp := &AnonymizeResponse{MapID: mapID}
+	if req.Text != "" {
+		if resp.Text, err = a.apply(req.Text); err != nil {
+			return nil, err
+		}
+	}
+	for i := range objects {
+		if objects[i].Content, err = a.apply(objects[i].Content); err != nil {
+			return nil, err
+		}
+	}
+	if len(a.added) > 0 {
+		if err := s.store.Append(mapID, a.added); err != nil {
+			return nil, fmt.Errorf("save anonymize map: %w", err)
+		}
+		logger.Info("Anonymize map updated", "map_id", mapID, "new_mappings", len(a.added))
+	}
+	resp.Objects = objects
+	resp.Replaced = a.replaced
+	resp.NewMappings = len(a.added)
+	return resp, nil
+}
+
+// Deanonymize 将文本中的假名还原为原值（如厂商回复中引用的地址）
+func (s *AnonymizeService) Deanonymize(ctx context.Context, req *DeanonymizeRequest) (*DeanonymizeResponse, error) {
+	if req == nil {
+		return nil, validationErrorf("map_id is required")
+	}
+	mapID, err := normalizeMapID(req.MapID)
+	if err != nil {
+		return nil, err
+	}
+	cc, err := NewCredentialCipher(s.conf())
+	if err != nil {
+		return nil, err
+	}
+	entries, err := s.store.Entries(mapID)
+	if err != nil {
+		return nil, err
+	}
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrAnonymizeMapNotFound, mapID)
+	}
+	literals := make(map[string]string, len(entries))
+	macs := make(map[string]string)
+	for _, e := range entries {
+		if anonymizeOneWayKinds[e.Kind] || e.OriginalEnc == "" {
+			continue
+		}
+		orig, err := cc.Decrypt(e.OriginalEnc)
+		if err != nil {
+			return nil, err
+		}
+		if e.Kind == AnonymizeMAC {
+			macs[macHex(e.Pseudonym)] = orig
+			continue
+		}
+		literals[e.Pseudonym] = orig
+	}
+	restored := 0
+	text := replaceLiterals(req.Text, literals, false, func(string) { restored++ })
+	text = anonymizeMACRe.ReplaceAllStringFunc(text, func(m string) string {
+		if orig, ok := macs[macHex(m)]; ok {
+			restored++
+			return renderMAC(orig, m)
+		}
+		return m
+	})
+	return &DeanonymizeResponse{MapID: mapID, Text: text, Restored: restored}, nil
+}
+
+// DeleteMap 删除映射表（删除后已分享的数据不可再还原）
+func (s *AnonymizeService) DeleteMap(mapID string) (int64, error) {
+	mapID, err := normalizeMapID(mapID)
+	if err != nil {
+		return 0, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n, err := s.store.DeleteMap(mapID)
+	if err == nil && n == 0 {
+		return 0, fmt.Errorf("%w: %s", ErrAnonymizeMapNotFound, mapID)
+	}
+	return n, err
+}
+
+// ====== 替换逻辑 ======
+
+// anonymizer 单次请求的替换状态：已有映射 + 本次新增
+type anonymizer struct {
+	cipher   *CredentialCipher
+	key      []byte
+	kinds    map[string]bool
+	known    map[string]string // kind + "\x00" + digest -> 假名
+	counts   map[string]int
+	found    map[string][]string // 已识别的主机名/用户名/团体字
+	added    []AnonymizeEntry
+	replaced map[string]int
+}
+
+func newAnonymizer(cc *CredentialCipher, masterKey string, kinds map[string]bool, entries []AnonymizeEntry) *anonymizer {
+	key := sha256.Sum256([]byte("anonymize:" + masterKey))
+	a := &anonymizer{
+		cipher:   cc,
+		key:      key[:],
+		kinds:    kinds,
+		known:    make(map[string]string, len(entries)),
+		counts:   map[string]int{},
+		found:    map[string][]string{},
+		replaced: map[string]int{},
+	}
+	for _, e := range entries {
+		a.known[e.Kind+"\x00"+e.Digest] = e.Pseudonym
+		a.counts[e.Kind]++
+	}
+	return a
+}
+
+// pseudonym 查找或分配假名；norm 为规范化后的原值（MAC 为 12 位小写十六进制）
+func (a *anonymizer) pseudonym(kind, norm string) (string, error) {
+	mac := hmac.New(sha256.New, a.key)
+	mac.Write([]byte(kind + "\x00" + norm))
+	digest := hex.EncodeToString(mac.Sum(nil))
+	if p, ok := a.known[kind+"\x00"+digest]; ok {
+		return p, nil
+	}
+	p, err := makePseudonym(kind, a.counts[kind]+1)
+	if err != nil {
+		return "", err
+	}
+	enc := ""
+	if !anonymizeOneWayKinds[kind] {
+		if enc, err = a.cipher.Encrypt(norm); err != nil {
+			return "", err
+		}
+	}
+	a.counts[kind]++
+	a.known[kind+"\x00"+digest] = p
+	a.added = append(a.added, AnonymizeEntry{Kind: kind, Digest: digest, Pseudonym: p, OriginalEnc: enc})
+	return p, nil
+}
+
+// makePseudonym 第 n 个假名：地址取文档/测试保留段（198.18.0.0/15、2001:db8::/32、本地管理 MAC 02:00:00），名称按序编号
+func makePseudonym(kind string, n int) (string, error) {
+	switch kind {
+	case AnonymizeIP:
+		if n >= 1<<17-1 {
+			return "", fmt.Errorf("ip pseudonym range exhausted")
+		}
+		ip := make(net.IP, 4)
+		binary.BigEndian.PutUint32(ip, 198<<24|18<<16+uint32(n))
+		return ip.String(), nil
+	case AnonymizeIPv6:
+		ip := make(net.IP, 16)
+		copy(ip, []byte{0x20, 0x01, 0x0d, 0xb8})
+		binary.BigEndian.PutUint32(ip[12:], uint32(n))
+		return ip.String(), nil
+	case AnonymizeMAC:
+		if n >= 1<<24 {
+			return "", fmt.Errorf("mac pseudonym range exhausted")
+		}
+		return fmt.Sprintf("02:00:00:%02x:%02x:%02x", byte(n>>16), byte(n>>8), byte(n)), nil
+	case AnonymizeHostname:
+		return fmt.Sprintf("host-%04d", n), nil
+	case AnonymizeUsername:
+		return fmt.Sprintf("user-%04d", n), nil
+	case AnonymizeCommunity:
+		return fmt.Sprintf("community-%04d", n), nil
+	}
+	return "", fmt.Errorf("unsupported kind: %s", kind)
+}
+
+// anonymizeLiteralPatterns 从输出中识别主机名、用户名与团体字（第 1 组为取值）
+var anonymizeLiteralPatterns = []struct {
+	kind string
+	re   *regexp.Regexp
+}{
+	{AnonymizeHostname, regexp.MustCompile(`(?im)^\s*(?:hostname|sysname|host-name)\s+"?([^\s";]+)`)},
+	{AnonymizeHostname, regexp.MustCompile(`(?m)^\s*<([\w.\-]+)>`)},
+	{AnonymizeHostname, regexp.MustCompile(`(?m)^([A-Za-z][\w.\-]*)(?:\([\w\-]+\))?#`)},
+	{AnonymizeUsername, regexp.MustCompile(`(?im)^\s*(?:username|local-user)\s+(\S+)`)},
+	{AnonymizeCommunity, regexp.MustCompile(`(?i)\bcommunity\s+(?:(?:read|write)\s+)?` + recordSecretModifiers + `(\S+)`)},
+}
+
+// anonymizedLiteralRe 已是假名的取值（重复匿名化时保持不变）
+var anonymizedLiteralRe = regexp.MustCompile(`^(?:host|user|community)-\d{4,}$`)
+
+var (
+	anonymizeMACRe  = regexp.MustCompile(`(?i)\b(?:[0-9a-f]{2}:){5}[0-9a-f]{2}\b|\b(?:[0-9a-f]{2}-){5}[0-9a-f]{2}\b|\b[0-9a-f]{4}([.\-])[0-9a-f]{4}[.\-][0-9a-f]{4}\b`)
+	anonymizeIPv4Re = regexp.MustCompile(`\b(?:\d{1,3}\.){3}\d{1,3}\b`)
+	anonymizeWordRe = regexp.MustCompile(`[0-9A-Za-z_:.]+`)
+	pseudoIPv4Net   = &net.IPNet{IP: net.IPv4(198, 18, 0, 0), Mask: net.CIDRMask(15, 32)}
+	pseudoIPv6Net   = &net.IPNet{IP: net.ParseIP("2001:db8::"), Mask: net.CIDRMask(32, 128)}
+)
+
+// discoverIn 按内置模式从输出中识别主机名、用户名与团体字
+func (a *anonymizer) discoverIn(text string) {
+	for _, p := range anonymizeLiteralPatterns {
+		for _, m := range p.re.FindAllStringSubmatch(text, -1) {
+			a.discover(p.kind, m[1])
+		}
+	}
+}
+
+// discover 登记待替换的取值；过短或已是假名的取值忽略
+func (a *anonymizer) discover(kind string, vals ...string) {
+	if !a.kinds[kind] {
+		return
+	}
+	for _, v := range vals {
+		if v = strings.TrimSpace(v); len(v) >= 2 && !anonymizedLiteralRe.MatchString(v) {
+			a.found[kind] = append(a.found[kind], v)
+		}
+	}
+}
+
+// apply 依次替换主机名/用户名/团体字、MAC、IPv6、IPv4
+func (a *anonymizer) apply(text string) (string, error) {
+	var ferr error
+	fail := func(err error) {
+		if ferr == nil {
+			ferr = err
+		}
+	}
+
+	for _, kind := range []string{AnonymizeHostname, AnonymizeUsername, AnonymizeCommunity} {
+		fold := kind == AnonymizeHostname
+		repl := map[string]string{}
+		for _, v := range a.found[kind] {
+			norm := v
+			if fold {
+				norm = strings.ToLower(v)
+			}
+			p, err := a.pseudonym(kind, norm)
+			if err != nil {
+				return "", err
+			}
+			repl[norm] = p
+		}
+		text = replaceLiterals(text, repl, fold, func(string) { a.replaced[kind]++ })
+	}
+
+	if a.kinds[AnonymizeMAC] {
+		text = anonymizeMACRe.ReplaceAllStringFunc(text, func(m string) string {
+			h := macHex(m)
+			if h == "000000000000" || h == "ffffffffffff" || strings.HasPrefix(h, "020000") {
+				return m
+			}
+			p, err := a.pseudonym(AnonymizeMAC, h)
+			if err != nil {
+				fail(err)
+				return m
+			}
+			a.replaced[AnonymizeMAC]++
+			return renderMAC(macHex(p), m)
+		})
+	}
+
+	if a.kinds[AnonymizeIPv6] {
+		text = anonymizeWordRe.ReplaceAllStringFunc(text, func(w string) string {
+			cand := strings.TrimRight(w, ".")
+			if strings.Count(cand, ":") < 2 {
+				return w
+			}
+			ip := net.ParseIP(cand)
+			if ip == nil || ip.To4() != nil || keepIPv6(ip) {
+				return w
+			}
+			p, err := a.pseudonym(AnonymizeIPv6, ip.String())
+			if err != nil {
+				fail(err)
+				return w
+			}
+			a.replaced[AnonymizeIPv6]++
+			return p + w[len(cand):]
+		})
+	}
+
+	if a.kinds[AnonymizeIP] {
+		var b strings.Builder
+		last := 0
+		for _, loc := range anonymizeIPv4Re.FindAllStringIndex(text, -1) {
+			start, end := loc[0], loc[1]
+			// OID、版本号等更长的点分数字串不处理
+			if start > 0 && text[start-1] == '.' || end+1 < len(text) && text[end] == '.' && isDigit(text[end+1]) {
+				continue
+			}
+			ip := net.ParseIP(text[start:end]).To4()
+			if ip == nil || keepIPv4(ip) {
+				continue
+			}
+			p, err := a.pseudonym(AnonymizeIP, ip.String())
+			if err != nil {
+				return "", err
+			}
+			a.replaced[AnonymizeIP]++
+			b.WriteString(text[last:start])
+			b.WriteString(p)
+			last = end
+		}
+		b.WriteString(text[last:])
+		text = b.String()
+	}
+	return text, ferr
+}
+
+// keepIPv4 不替换的地址：未指定、环回、组播、掩码与反掩码、假名段
+func keepIPv4(ip net.IP) bool {
+	v := binary.BigEndian.Uint32(ip)
+	if ip.IsUnspecified() || ip.IsLoopback() || ip.IsMulticast() || pseudoIPv4Net.Contains(ip) {
+		return true
+	}
+	return v&(v+1) == 0 || ^v&(^v+1) == 0
+}
+
+func keepIPv6(ip net.IP) bool {
+	return ip.IsUnspecified() || ip.IsLoopback() || ip.IsMulticast() || pseudoIPv6Net.Contains(ip)
+}
+
+// macHex MAC 规范化为 12 位小写十六进制
+func macHex(s string) string {
+	return strings.ToLower(strings.NewReplacer(":", "", "-", "", ".", "").Replace(s))
+}
+
+// renderMAC 按样例的分隔格式与大小写输出 MAC（12 位十六进制输入）
+func renderMAC(h, sample string) string {
+	h = macHex(h)
+	if len(h) != 12 {
+		return h
+	}
+	var out string
+	switch {
+	case strings.Contains(sample, "."):
+		out = h[0:4] + "." + h[4:8] + "." + h[8:12]
+	case len(sample) == 14:
+		out = h[0:4] + "-" + h[4:8] + "-" + h[8:12]
+	default:
+		sep := ":"
+		if strings.Contains(sample, "-") {
+			sep = "-"
+		}
+		parts := make([]string, 6)
+		for i := range parts {
+			parts[i] = h[i*2 : i*2+2]
+		}
+		out = strings.Join(parts, sep)
+	}
+	if strings.ToLower(sample) != sample {
+		out = strings.ToUpper(out)
+	}
+	return out
+}
+
+// replaceLiterals 整词替换：按长度降序匹配，前后不能紧邻字母、数字、下划线或连字符；
+// 地址类取值（含 . 或 :）另要求前后不能紧邻 ./: 加字母数字（避免替换更长地址的前缀）
+func replaceLiterals(text string, repl map[string]string, fold bool, onHit func(string)) string {
+	if len(repl) == 0 || text == "" {
+		return text
+	}
+	keys := make([]string, 0, len(repl))
+	for k := range repl {
+		keys = append(keys, regexp.QuoteMeta(k))
+	}
+	sort.Slice(keys, func(i, j int) bool { return len(keys[i]) > len(keys[j]) })
+	pattern := strings.Join(keys, "|")
+	if fold {
+		pattern = "(?i)" + pattern
+	}
+	re := regexp.MustCompile(pattern)
+	var b strings.Builder
+	last := 0
+	for _, loc := range re.FindAllStringIndex(text, -1) {
+		start, end := loc[0], loc[1]
+		m := text[start:end]
+		addr := strings.ContainsAny(m, ".:")
+		if !literalBoundary(text, start-1, -1, addr) || !literalBoundary(text, end, 1, addr) {
+			continue
+		}
+		key := m
+		if fold {
+			key = strings.ToLower(m)
+		}
+		b.WriteString(text[last:start])
+		b.WriteString(repl[key])
+		onHit(key)
+		last = end
+	}
+	b.WriteString(text[last:])
+	return b.String()
+}
+
+func literalBoundary(text string, i, dir int, addr bool) bool {
+	if i < 0 || i >= len(text) {
+		return true
+	}
+	c := text[i]
+	if isWordByte(c) || c == '-' {
+		return false
+	}
+	if addr && (c == '.' || c == ':') {
+		j := i + dir
+		return j < 0 || j >= len(text) || !isWordByte(text[j])
+	}
+	return true
+}
+
+func isWordByte(c byte) bool {
+	return c == '_' || isDigit(c) || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+func isDigit(c byte) bool { return c >= '0' && c <= '9' }
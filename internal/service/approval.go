@@ -0,0 +1,336 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/sshcollectorpro/sshcollectorpro/internal/config"
+)
+
+// 下发审批状态
+const (
+	ApprovalPending  = "pending"
+	ApprovalApproved = "approved" // 已批准，下发执行中
+	ApprovalExecuted = "executed"
+	ApprovalRejected = "rejected"
+	ApprovalExpired  = "expired"
+)
+
+// 审批动作与来源
+const (
+	ApprovalActionApprove = "approve"
+	ApprovalActionReject  = "reject"
+
+	ApprovalViaWeCom    = "wecom"
+	ApprovalViaDingTalk = "dingtalk"
+	ApprovalViaAPI      = "api"
+)
+
+// ApprovalEvent 审批审计事件
+type ApprovalEvent struct {
+	Time   time.Time `json:"time"`
+	Event  string    `json:"event"` // created | notified | notify_failed | approved | rejected | expired | executed
+	Actor  string    `json:"actor,omitempty"`
+	Via    string    `json:"via,omitempty"`
+	Remote string    `json:"remote,omitempty"` // 审批请求来源地址
+	Detail string    `json:"detail,omitempty"`
+}
+
+// NewApprovalEvent 生成当前时间的审计事件
+func NewApprovalEvent(event, actor, via, detail string) ApprovalEvent {
+	return ApprovalEvent{Time: time.Now(), Event: event, Actor: actor, Via: via, Detail: detail}
+}
+
+// ErrApprovalRequired 下发需审批：须经审批流程批准后执行（REST 提交审批，gRPC/运行手册直接拒绝）
+var ErrApprovalRequired = errors.New("deploy requires approval")
+
+type approvalGrantKey struct{}
+
+// WithApprovalGranted 标记下发已获批准，仅审批执行流程使用
+func WithApprovalGranted(ctx context.Context, approvalID string) context.Context {
+	return context.WithValue(ctx, approvalGrantKey{}, approvalID)
+}
+
+// approvalGranted 上下文是否携带审批批准标记
+func approvalGranted(ctx context.Context) bool {
+	id, _ := ctx.Value(approvalGrantKey{}).(string)
+	return id != ""
+}
+
+// checkDeployApproval 未获批准且需审批的下发返回 ErrApprovalRequired；所有下发入口经 DeployStream 统一校验
+func checkDeployApproval(ctx context.Context, cfg *config.Config, req *DeployFastRequest) error {
+	if approvalGranted(ctx) {
+		return nil
+	}
+	required, err := ApprovalRequired(cfg, req)
+	if err != nil {
+		return err
+	}
+	if required {
+		return fmt.Errorf("%w: task %q must be approved before execution", ErrApprovalRequired, req.TaskID)
+	}
+	return nil
+}
+
+// ApprovalRequired 判断下发是否需要审批；仅 exec 下发受审批约束
+func ApprovalRequired(cfg *config.Config, req *DeployFastRequest) (bool, error) {
+	if req == nil || !strings.EqualFold(strings.TrimSpace(req.TaskType), "exec") {
+		return false, nil
+	}
+	enabled := cfg != nil && cfg.Approval.Enable
+	if req.RequireApproval && !enabled {
+		return false, validationErrorf("require_approval is set but approval is not enabled")
+	}
+	if !enabled {
+		return false, nil
+	}
+	if strings.TrimSpace(cfg.Approval.Secret) == "" || strings.TrimSpace(cfg.Approval.BaseURL) == "" {
+		return false, fmt.Errorf("approval.secret and approval.base_url must be configured")
+	}
+	return cfg.Approval.DeployRequired || req.RequireApproval, nil
+}
+
+// SummarizeDeploy 生成审批卡片展示的变更摘要：每台设备列出下发命令（cli_list、config_deploy 或模板渲染结果）、
+// 回滚命令与检查点命令，每类最多展示前 5 条；cfg 用于查找平台 checkpoint_cli
+func SummarizeDeploy(cfg *config.Config, req *DeployFastRequest) string {
+	const maxDevices = 10
+	var b strings.Builder
+	fmt.Fprintf(&b, "任务: %s (%s)\n设备数: %d\n", req.TaskName, req.TaskID, len(req.Devices))
+	// 模板语法错误时仅展示占位符，提交后由下发校验报错
+	var configTpl *template.Template
+	if strings.TrimSpace(req.ConfigTemplate) != "" {
+		configTpl, _ = parseConfigTemplate("config_template", req.ConfigTemplate)
+	}
+	for i, d := range req.Devices {
+		if i == maxDevices {
+			fmt.Fprintf(&b, "... 其余 %d 台设备\n", len(req.Devices)-maxDevices)
+			break
+		}
+		fmt.Fprintf(&b, "- %s %s", d.DeviceIP, d.DevicePlatform)
+		if d.DeviceName != "" {
+			fmt.Fprintf(&b, " (%s)", d.DeviceName)
+		}
+		b.WriteString("\n")
+		if req.Checkpoint {
+			dd, _ := deployDefaults(cfg, d.DevicePlatform)
+			writeSummaryLines(&b, "检查点命令:", checkpointCommands(dd, req.TaskID, time.Now()))
+		}
+		writeSummaryLines(&b, "", summaryDeployLines(configTpl, req, d))
+		writeSummaryLines(&b, "回滚命令:", d.RollbackCliList.Lines())
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// summaryDeployLines 设备下发命令：与执行时一致，cli_list 优先，其次模板渲染结果，最后 config_deploy
+func summaryDeployLines(configTpl *template.Template, req *DeployFastRequest, d DeployDevice) []string {
+	if lines := d.CliList.Lines(); len(lines) > 0 {
+		return lines
+	}
+	raw := d.ConfigDeploy
+	if strings.TrimSpace(req.ConfigTemplate) != "" {
+		if configTpl == nil {
+			return []string{"<config_template>"}
+		}
+		rendered, err := renderDeviceConfig(configTpl, req.Variables, d)
+		if err != nil {
+			return []string{"<config_template: " + err.Error() + ">"}
+		}
+		raw = rendered
+	}
+	var lines []string
+	for _, ln := range strings.Split(strings.ReplaceAll(raw, "\r\n", "\n"), "\n") {
+		if t := strings.TrimSpace(ln); t != "" {
+			lines = append(lines, t)
+		}
+	}
+	return lines
+}
+
+// writeSummaryLines 写入一组命令（最多 5 条），title 非空时作为小标题；无命令时不输出
+func writeSummaryLines(b *strings.Builder, title string, lines []string) {
+	const maxLines = 5
+	if len(lines) == 0 {
+		return
+	}
+	if title != "" {
+		fmt.Fprintf(b, "  %s\n", title)
+	}
+	for j, l := range lines {
+		if j == maxLines {
+			fmt.Fprintf(b, "    ... 共 %d 条\n", len(lines))
+			break
+		}
+		fmt.Fprintf(b, "    %s\n", l)
+	}
+}
+
+// SignApproval 计算审批链接签名：HMAC-SHA256(secret, id.action.via.expires)，十六进制编码
+func SignApproval(secret, id, action, via string, expires int64) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(id + "." + action + "." + via + "." + strconv.FormatInt(expires, 10)))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyApproval 校验审批链接签名与有效期
+func VerifyApproval(secret, id, action, via, expires, sig string, now time.Time) error {
+	if strings.TrimSpace(secret) == "" {
+		return fmt.Errorf("approval secret not configured")
+	}
+	exp, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid expires")
+	}
+	want := SignApproval(secret, id, action, via, exp)
+	if !hmac.Equal([]byte(want), []byte(strings.ToLower(sig))) {
+		return fmt.Errorf("invalid signature")
+	}
+	if now.Unix() > exp {
+		return fmt.Errorf("approval link expired")
+	}
+	return nil
+}
+
+// ApprovalLink 生成审批人点击的签名链接
+func ApprovalLink(cfg config.ApprovalConfig, id, action, via string, expiresAt time.Time) string {
+	exp := expiresAt.Unix()
+	q := url.Values{}
+	q.Set("action", action)
+	q.Set("via", via)
+	q.Set("expires", strconv.FormatInt(exp, 10))
+	q.Set("sig", SignApproval(cfg.Secret, id, action, via, exp))
+	return strings.TrimRight(cfg.BaseURL, "/") + "/api/v1/deploy-approvals/" + url.PathEscape(id) + "/decision?" + q.Encode()
+}
+
+// ApprovalCard 待审批卡片内容
+type ApprovalCard struct {
+	ID        string
+	Title     string
+	Summary   string
+	ExpiresAt time.Time
+}
+
+// SendApprovalCards 向已配置的企业微信/钉钉群机器人推送审批卡片，返回推送审计事件
+func SendApprovalCards(ctx context.Context, cfg config.ApprovalConfig, card ApprovalCard) []ApprovalEvent {
+	var events []ApprovalEvent
+	deadline := "有效期至 " + card.ExpiresAt.Format("2006-01-02 15:04:05")
+	if u := strings.TrimSpace(cfg.WeCom.WebhookURL); u != "" {
+		body := map[string]interface{}{
+			"msgtype": "template_card",
+			"template_card": map[string]interface{}{
+				"card_type":      "text_notice",
+				"main_title":     map[string]string{"title": card.Title, "desc": deadline},
+				"sub_title_text": card.Summary,
+				"jump_list": []map[string]interface{}{
+					{"type": 1, "title": "批准", "url": ApprovalLink(cfg, card.ID, ApprovalActionApprove, ApprovalViaWeCom, card.ExpiresAt)},
+					{"type": 1, "title": "驳回", "url": ApprovalLink(cfg, card.ID, ApprovalActionReject, ApprovalViaWeCom, card.ExpiresAt)},
+				},
+				// 卡片整体点击仅查看审批详情
+				"card_action": map[string]interface{}{"type": 1, "url": strings.TrimRight(cfg.BaseURL, "/") + "/api/v1/deploy-approvals/" + url.PathEscape(card.ID)},
+			},
+		}
+		events = append(events, robotEvent(ApprovalViaWeCom, postRobot(ctx, u, body)))
+	}
+	if u := strings.TrimSpace(cfg.DingTalk.WebhookURL); u != "" {
+		body := map[string]interface{}{
+			"msgtype": "actionCard",
+			"actionCard": map[string]interface{}{
+				"title":          card.Title,
+				"text":           "### " + card.Title + "\n\n" + deadline + "\n\n```\n" + card.Summary + "\n```",
+				"btnOrientation": "1",
+				"btns": []map[string]string{
+					{"title": "批准", "actionURL": ApprovalLink(cfg, card.ID, ApprovalActionApprove, ApprovalViaDingTalk, card.ExpiresAt)},
+					{"title": "驳回", "actionURL": ApprovalLink(cfg, card.ID, ApprovalActionReject, ApprovalViaDingTalk, card.ExpiresAt)},
+				},
+			},
+		}
+		events = append(events, robotEvent(ApprovalViaDingTalk, postRobot(ctx, dingTalkSignedURL(u, cfg.DingTalk.Secret, time.Now()), body)))
+	}
+	return events
+}
+
+// SendApprovalNotice 向已配置的群机器人推送审批结果（markdown），失败仅返回审计事件
+func SendApprovalNotice(ctx context.Context, cfg config.ApprovalConfig, title, text string) []ApprovalEvent {
+	var events []ApprovalEvent
+	if u := strings.TrimSpace(cfg.WeCom.WebhookURL); u != "" {
+		body := map[string]interface{}{"msgtype": "markdown", "markdown": map[string]string{"content": "**" + title + "**\n" + text}}
+		if err := postRobot(ctx, u, body); err != nil {
+			events = append(events, robotEvent(ApprovalViaWeCom, err))
+		}
+	}
+	if u := strings.TrimSpace(cfg.DingTalk.WebhookURL); u != "" {
+		body := map[string]interface{}{"msgtype": "markdown", "markdown": map[string]string{"title": title, "text": "### " + title + "\n\n" + text}}
+		if err := postRobot(ctx, dingTalkSignedURL(u, cfg.DingTalk.Secret, time.Now()), body); err != nil {
+			events = append(events, robotEvent(ApprovalViaDingTalk, err))
+		}
+	}
+	return events
+}
+
+func robotEvent(via string, err error) ApprovalEvent {
+	if err != nil {
+		return NewApprovalEvent("notify_failed", "", via, err.Error())
+	}
+	return NewApprovalEvent("notified", "", via, "")
+}
+
+// dingTalkSignedURL 钉钉机器人加签：sign = base64(HMAC-SHA256(secret, timestamp + "\n" + secret))
+func dingTalkSignedURL(webhook, secret string, now time.Time) string {
+	if strings.TrimSpace(secret) == "" {
+		return webhook
+	}
+	ts := strconv.FormatInt(now.UnixMilli(), 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(ts + "\n" + secret))
+	sign := base64.StdEncoding.EncodeToString(mac.Sum(nil))
+	sep := "?"
+	if strings.Contains(webhook, "?") {
+		sep = "&"
+	}
+	return webhook + sep + "timestamp=" + ts + "&sign=" + url.QueryEscape(sign)
+}
+
+// postRobot 发送群机器人消息；企业微信与钉钉均以 errcode 非 0 表示失败
+func postRobot(ctx context.Context, webhook string, payload interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	var r struct {
+		ErrCode int    `json:"errcode"`
+		ErrMsg  string `json:"errmsg"`
+	}
+	if json.Unmarshal(data, &r) == nil && r.ErrCode != 0 {
+		return fmt.Errorf("errcode %d: %s", r.ErrCode, r.ErrMsg)
+	}
+	return nil
+}
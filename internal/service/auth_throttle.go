@@ -0,0 +1,186 @@
+package service
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sshcollectorpro/sshcollectorpro/internal/config"
+	"github.com/sshcollectorpro/sshcollectorpro/pkg/logger"
+)
+
+// ErrCredentialThrottled 凭据连续认证失败达到阈值，冷却期内暂停使用，避免设备/TACACS 账号被锁定
+var ErrCredentialThrottled = errors.New("CREDENTIAL_THROTTLED")
+
+// StatusCredentialThrottled 设备因凭据处于冷却期未执行
+const StatusCredentialThrottled = "CREDENTIAL_THROTTLED"
+
+// AuthThrottleEntry 单个凭据的失败计数与锁定状态（仅展示用户名与最近失败设备，不含密码或其派生值）
+type AuthThrottleEntry struct {
+	Username    string     `json:"username"`
+	Failures    int        `json:"failures"`
+	Locked      bool       `json:"locked"`
+	LockedUntil *time.Time `json:"locked_until,omitempty"`
+	LastDevice  string     `json:"last_device,omitempty"`
+	LastError   string     `json:"last_error,omitempty"`
+}
+
+// AuthThrottleStats 认证失败限流快照，供 /collector/stats 展示
+type AuthThrottleStats struct {
+	Enable      bool                `json:"enable"`
+	MaxFailures int                 `json:"max_failures"`
+	Cooldown    string              `json:"cooldown"`
+	Locked      int                 `json:"locked"`
+	Credentials []AuthThrottleEntry `json:"credentials"`
+}
+
+type authThrottleState struct {
+	username    string
+	failures    int
+	lockedUntil time.Time
+	lastDevice  string
+	lastError   string
+}
+
+// authThrottle 进程内共享：各服务使用同一凭据的失败次数合并计算
+var authThrottle = struct {
+	mu      sync.Mutex
+	entries map[string]*authThrottleState
+}{entries: make(map[string]*authThrottleState)}
+
+// credentialKeySecret 进程级随机密钥，密码指纹只在本进程内可比较，无法离线撞库
+var credentialKeySecret = func() []byte {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		panic(fmt.Sprintf("auth throttle: read random key: %v", err))
+	}
+	return b
+}()
+
+// credentialKey 凭据标识：用户名（不区分大小写）+ 密码的 HMAC-SHA256；仅用于进程内计数，不对外输出
+func credentialKey(username, password string) string {
+	mac := hmac.New(sha256.New, credentialKeySecret)
+	mac.Write([]byte(password))
+	return normalizeThrottleUser(username) + "|" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func normalizeThrottleUser(username string) string {
+	return strings.ToLower(strings.TrimSpace(username))
+}
+
+// authThrottleConfig 读取当前全局配置（跟随热更新）；未加载配置或阈值无效时视为关闭
+func authThrottleConfig() (config.SSHAuthThrottleConfig, bool) {
+	cfg := config.Get()
+	if cfg == nil {
+		return config.SSHAuthThrottleConfig{}, false
+	}
+	tc := cfg.SSH.AuthThrottle
+	return tc, tc.Enable && tc.MaxFailures > 0 && tc.Cooldown > 0
+}
+
+// checkAuthThrottle 建连前调用：凭据处于冷却期时返回 ErrCredentialThrottled，冷却结束后清零计数
+func checkAuthThrottle(username, password string) error {
+	if _, on := authThrottleConfig(); !on {
+		return nil
+	}
+	authThrottle.mu.Lock()
+	defer authThrottle.mu.Unlock()
+	key := credentialKey(username, password)
+	st, ok := authThrottle.entries[key]
+	if !ok || st.lockedUntil.IsZero() {
+		return nil
+	}
+	if time.Now().Before(st.lockedUntil) {
+		return fmt.Errorf("%w: credential for user %s paused after %d consecutive authentication failures until %s",
+			ErrCredentialThrottled, username, st.failures, st.lockedUntil.Format(time.RFC3339))
+	}
+	delete(authThrottle.entries, key)
+	return nil
+}
+
+// recordAuthResult 建连后调用：认证失败累加计数并在达到阈值时锁定；成功清零；其它错误（不可达、超时等）不计入
+func recordAuthResult(username, password, device string, err error) {
+	tc, on := authThrottleConfig()
+	if !on || errors.Is(err, ErrCredentialThrottled) || errors.Is(err, ErrEgressBlocked) {
+		return
+	}
+	key := credentialKey(username, password)
+	authThrottle.mu.Lock()
+	if err == nil {
+		delete(authThrottle.entries, key)
+		authThrottle.mu.Unlock()
+		return
+	}
+	if !isAuthFailure(err) {
+		authThrottle.mu.Unlock()
+		return
+	}
+	st, ok := authThrottle.entries[key]
+	if !ok {
+		st = &authThrottleState{username: normalizeThrottleUser(username)}
+		authThrottle.entries[key] = st
+	}
+	st.failures++
+	st.lastDevice = device
+	st.lastError = err.Error()
+	locked := st.failures >= tc.MaxFailures && st.lockedUntil.IsZero()
+	if locked {
+		st.lockedUntil = time.Now().Add(tc.Cooldown)
+	}
+	failures, until := st.failures, st.lockedUntil
+	authThrottle.mu.Unlock()
+
+	if locked {
+		notifyCredentialThrottled(tc, username, device, failures, until)
+	}
+}
+
+// notifyCredentialThrottled 凭据进入冷却期：记录日志，并按 ssh.auth_throttle.notify_url 推送回调
+func notifyCredentialThrottled(tc config.SSHAuthThrottleConfig, username, device string, failures int, until time.Time) {
+	logger.Warn("Credential paused after consecutive authentication failures",
+		"username", username, "failures", failures, "device_ip", device, "locked_until", until)
+	if strings.TrimSpace(tc.NotifyURL) == "" {
+		return
+	}
+	NotifyCallback(config.Get(), tc.NotifyURL, &CallbackPayload{
+		TaskID:      fmt.Sprintf("auth-throttle-%s-%s-%d", normalizeThrottleUser(username), device, until.Unix()),
+		TaskType:    "auth_throttle",
+		Code:        StatusCredentialThrottled,
+		Message:     fmt.Sprintf("credential for user %s paused after %d consecutive authentication failures until %s", username, failures, until.Format(time.RFC3339)),
+		Total:       1,
+		FailedCount: 1,
+		Devices:     []CallbackDeviceSummary{{DeviceIP: device, Success: false, Error: StatusCredentialThrottled}},
+	})
+}
+
+// AuthThrottleSnapshot 返回当前失败计数与锁定中的凭据（已过冷却期的锁定不再计为锁定）
+func AuthThrottleSnapshot() AuthThrottleStats {
+	tc, on := authThrottleConfig()
+	out := AuthThrottleStats{Enable: on, MaxFailures: tc.MaxFailures, Cooldown: tc.Cooldown.String(), Credentials: []AuthThrottleEntry{}}
+	now := time.Now()
+	authThrottle.mu.Lock()
+	for _, st := range authThrottle.entries {
+		e := AuthThrottleEntry{Username: st.username, Failures: st.failures, LastDevice: st.lastDevice, LastError: st.lastError}
+		if !st.lockedUntil.IsZero() && now.Before(st.lockedUntil) {
+			until := st.lockedUntil
+			e.Locked, e.LockedUntil = true, &until
+			out.Locked++
+		}
+		out.Credentials = append(out.Credentials, e)
+	}
+	authThrottle.mu.Unlock()
+	sort.Slice(out.Credentials, func(i, j int) bool {
+		if out.Credentials[i].Username != out.Credentials[j].Username {
+			return out.Credentials[i].Username < out.Credentials[j].Username
+		}
+		return out.Credentials[i].LastDevice < out.Credentials[j].LastDevice
+	})
+	return out
+}
@@ -5,6 +5,7 @@ import (
 	"context"
 	"crypto/sha256"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"net"
 	"net/http"
@@ -21,6 +22,8 @@ import (
 	"github.com/sshcollectorpro/sshcollectorpro/internal/config"
 	"github.com/sshcollectorpro/sshcollectorpro/pkg/logger"
 	"github.com/sshcollectorpro/sshcollectorpro/pkg/ssh"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // ==== 合并自 backup_types.go：请求/响应/模型类型定义 ====
@@ -31,24 +34,37 @@ type BackupBatchRequest struct {
 	TaskName       string         `json:"task_name,omitempty"`
 	TaskBatch      int            `json:"task_batch,omitempty"`
 	SaveDir        string         `json:"save_dir,omitempty"`
-	StorageBackend string         `json:"storage_backend,omitempty"` // local | minio（默认读取配置）
+	StorageBackend string         `json:"storage_backend,omitempty"` // local | minio | s3 | azure（默认读取配置）
 	RetryFlag      *int           `json:"retry_flag,omitempty"`
+	RetryPolicy    *RetryPolicy   `json:"retry_policy,omitempty"` // 退避与重试条件，未设置的字段沿用平台/全局配置
 	TaskTimeout    *int           `json:"task_timeout,omitempty"`
+	CallbackURL    string         `json:"callback_url,omitempty"`    // 批次完成后推送设备摘要
+	Deadline       *time.Time     `json:"deadline,omitempty"`        // 执行窗口截止时间，到点后不再派发新设备
+	FreshTTL       int            `json:"fresh_ttl,omitempty"`       // 默认新鲜度（秒）：最近落盘结果未过期的命令跳过执行
+	OutputEncoding string         `json:"output_encoding,omitempty"` // 响应中控制字符编码：raw | strip | escape | base64
+	RawOutputMode  string         `json:"raw_output_mode,omitempty"` // 响应中原始输出：full | omit | truncate | uri
+	MaxOutputKB    int            `json:"max_output_kb,omitempty"`   // truncate 模式单条命令保留的 KB 数
+	Playbook       string         `json:"playbook,omitempty"`        // 引用命令集，接口层按设备平台展开到 cli_list
+	Calendar       string         `json:"calendar,omitempty"`        // 引用执行日历，非执行日（节假日、封网等）接口层直接跳过
+	DuplicateMode  string         `json:"duplicate_mode,omitempty"`  // 批内重复设备处理：reject | merge | copy（默认读取配置）
+	Transcript     *bool          `json:"transcript,omitempty"`      // 保存设备会话转录（收发原始字节与时间戳），默认读取 backup.transcript.enable
 	Devices        []BackupDevice `json:"devices"`
+	Deprecations   `json:"-"`     // 请求中使用的旧字段名（兼容层填充）
 }
 
 // BackupDevice 备份的设备信息与命令
 type BackupDevice struct {
-	DeviceIP        string   `json:"device_ip"`
-	Port            int      `json:"device_port,omitempty"`
-	DeviceName      string   `json:"device_name,omitempty"`
-	DevicePlatform  string   `json:"device_platform,omitempty"`
-	CollectProtocol string   `json:"collect_protocol,omitempty"` // ssh
-	UserName        string   `json:"user_name"`
-	Password        string   `json:"password"`
-	EnablePassword  string   `json:"enable_password,omitempty"`
-	CliList         []string `json:"cli_list"`
-	DeviceTimeout   *int     `json:"device_timeout,omitempty"`
+	DeviceIP        string  `json:"device_ip"`
+	Port            int     `json:"device_port,omitempty"`
+	DeviceName      string  `json:"device_name,omitempty"`
+	DevicePlatform  string  `json:"device_platform,omitempty"`
+	CollectProtocol string  `json:"collect_protocol,omitempty"` // ssh
+	UserName        string  `json:"user_name"`
+	Password        string  `json:"password"`
+	EnablePassword  string  `json:"enable_password,omitempty"`
+	CredentialID    string  `json:"credential_id,omitempty"` // 引用已登记凭据（接口层解析）
+	CliList         CLIList `json:"cli_list"`
+	DeviceTimeout   *int    `json:"device_timeout,omitempty"`
 }
 
 // StoredObject 存储的对象信息
@@ -61,36 +77,51 @@ type StoredObject struct {
 
 // CommandBackupResult 命令备份结果
 type CommandBackupResult struct {
-	Command        string         `json:"command"`
-	RawOutput      string         `json:"raw_output"`
-	RawOutputLines []string       `json:"raw_output_lines"`
-	StoredObjects  []StoredObject `json:"stored_objects"`
-	ExitCode       int            `json:"exit_code"`
-	DurationMS     int64          `json:"duration_ms"`
-	Error          string         `json:"error"`
+	Command            string         `json:"command"`
+	RawOutput          string         `json:"raw_output"`
+	RawOutputLines     []string       `json:"raw_output_lines"`
+	StoredObjects      []StoredObject `json:"stored_objects"`
+	ExitCode           int            `json:"exit_code"`
+	DurationMS         int64          `json:"duration_ms"`
+	Error              string         `json:"error"`
+	RawObject          *StoredObject  `json:"raw_object,omitempty"`           // include_raw_bytes 时另存的原始字节对象
+	SkippedFresh       bool           `json:"skipped_fresh,omitempty"`        // 结果仍新鲜，未执行，引用缓存对象
+	CollectedAt        *time.Time     `json:"collected_at,omitempty"`         // 缓存对象的采集时间
+	OmittedLines       int            `json:"omitted_lines,omitempty"`        // 超出 max_lines 被省略的行数（存储对象为完整输出）
+	RawOutputOmitted   bool           `json:"raw_output_omitted,omitempty"`   // raw_output_mode=omit/uri：响应不含原始输出
+	RawOutputTruncated bool           `json:"raw_output_truncated,omitempty"` // 超出 max_output_kb 被截断
+	RawOutputSize      int            `json:"raw_output_size,omitempty"`      // 省略或截断前的字节数
+	RawOutputURI       string         `json:"raw_output_uri,omitempty"`       // raw_output_mode=uri：存储对象地址
 }
 
 // DeviceBackupResponse 设备备份响应
 type DeviceBackupResponse struct {
-	DeviceIP       string                `json:"device_ip"`
-	Port           int                   `json:"port"`
-	DeviceName     string                `json:"device_name,omitempty"`
-	DevicePlatform string                `json:"device_platform,omitempty"`
-	TaskID         string                `json:"task_id"`
-	TaskBatch      int                   `json:"task_batch,omitempty"`
-	Success        bool                  `json:"success"`
-	Results        []CommandBackupResult `json:"results"`
-	Error          string                `json:"error"`
-	DurationMS     int64                 `json:"duration_ms"`
-	Timestamp      time.Time             `json:"timestamp"`
+	DeviceIP         string                `json:"device_ip"`
+	Port             int                   `json:"port"`
+	DeviceName       string                `json:"device_name,omitempty"`
+	DevicePlatform   string                `json:"device_platform,omitempty"`
+	TaskID           string                `json:"task_id"`
+	TaskBatch        int                   `json:"task_batch,omitempty"`
+	Success          bool                  `json:"success"`
+	Status           string                `json:"status,omitempty"` // 如 NOT_ATTEMPTED_WINDOW_CLOSED
+	Results          []CommandBackupResult `json:"results"`
+	SkippedFresh     int                   `json:"skipped_fresh"` // 因结果新鲜而跳过的命令数
+	Error            string                `json:"error"`
+	DurationMS       int64                 `json:"duration_ms"`
+	Timestamp        time.Time             `json:"timestamp"`
+	Timings          *DeviceTimings        `json:"timings,omitempty"`
+	DuplicateOf      *int                  `json:"duplicate_of,omitempty"`      // copy 模式下复制自请求中该位置设备的结果
+	Attempts         []RetryAttempt        `json:"attempts,omitempty"`          // 尝试记录，仅在发生失败尝试时返回
+	TranscriptObject *StoredObject         `json:"transcript_object,omitempty"` // 会话转录对象（启用 transcript 时）
 }
 
 // BackupBatchResponse 批量备份响应
 type BackupBatchResponse struct {
-	Code    string                 `json:"code"`
-	Message string                 `json:"message"`
-	Data    []DeviceBackupResponse `json:"data"`
-	Total   int                    `json:"total"`
+	Code       string                 `json:"code"`
+	Message    string                 `json:"message"`
+	Data       []DeviceBackupResponse `json:"data"`
+	Total      int                    `json:"total"`
+	Duplicates []DuplicateDevice      `json:"duplicates,omitempty"`
 }
 
 // ==== 合并自 storage_writer.go：存储写入器实现 ====
@@ -111,119 +142,172 @@ type StorageMeta struct {
 	DeviceIP       string
 	DevicePlatform string
 	CommandSlug    string
-	Backend        string // local|minio
+	Backend        string // local|minio|s3|azure
+	SkipFilter     bool   // 跳过输出行过滤（如结构化报告）
+	// Facts 同一任务中解析的设备事实（小写变量名 -> 取值），供 path_template/filename_template 使用
+	Facts map[string]string
 }
 
-// NewStorageWriter 根据配置创建写入器（委派到本地或 MinIO）
+// NewStorageWriter 根据配置创建写入器（委派到本地、MinIO、S3 或 Azure Blob）
 func NewStorageWriter(cfg *config.Config) StorageWriter {
 	// 委派写入器：根据 meta.Backend 路由
-	dw := &DelegatingStorageWriter{cfg: cfg, local: &LocalStorageWriter{cfg: cfg}}
+	p := config.ProviderFor(cfg)
+	dw := &DelegatingStorageWriter{provider: p, local: &LocalStorageWriter{provider: p}}
 	// 初始化 MinIO 写入器（统一文件实现）
 	dw.minio = initMinioWriter(cfg)
+	// 初始化 S3 与 Azure Blob 写入器（未配置时为空）
+	dw.s3 = initS3Writer(cfg)
+	dw.azure = initAzureBlobWriter(cfg)
 	return dw
 }
 
 // DelegatingStorageWriter 按后端路由写入
 type DelegatingStorageWriter struct {
-	cfg   *config.Config
-	local *LocalStorageWriter
+	provider *config.Provider
+	local    *LocalStorageWriter
+	// mu 保护远端写入器（配置热更新时重建）
+	mu    sync.RWMutex
 	minio *MinioStorageWriter
+	s3    *MinioStorageWriter
+	azure *AzureBlobStorageWriter
 }
 
+// remotes 当前远端写入器（未配置的为 nil）
+func (w *DelegatingStorageWriter) remotes() (mw, s3 *MinioStorageWriter, az *AzureBlobStorageWriter) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.minio, w.s3, w.azure
+}
+
+// reloadRemotes 按新配置重建 MinIO/S3/Azure 写入器；进行中的写入继续使用旧客户端
+func (w *DelegatingStorageWriter) reloadRemotes(cfg *config.Config) {
+	mw, s3, az := initMinioWriter(cfg), initS3Writer(cfg), initAzureBlobWriter(cfg)
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.minio, w.s3, w.azure = mw, s3, az
+}
+
+// Write 写入对象并记录 storage.write span（远端写入另记 storage.remote_write，便于区分回退耗时）
 func (w *DelegatingStorageWriter) Write(ctx context.Context, meta StorageMeta, content string, contentType string) (StoredObject, error) {
+	ctx, span := startSpan(ctx, "storage.write",
+		attribute.String("storage.backend", strings.ToLower(strings.TrimSpace(meta.Backend))),
+		attribute.String("storage.object", meta.CommandSlug),
+		attribute.Int("storage.bytes", len(content)),
+	)
+	start := time.Now()
+	obj, err := w.write(ctx, meta, content, contentType)
+	recordStorageWrite(ctx, time.Since(start))
+	span.SetAttributes(attribute.String("storage.uri", obj.URI))
+	endSpan(span, err)
+	return obj, err
+}
+
+func (w *DelegatingStorageWriter) write(ctx context.Context, meta StorageMeta, content string, contentType string) (StoredObject, error) {
 	backend := strings.ToLower(strings.TrimSpace(meta.Backend))
-	if backend == "minio" {
-		if w.minio == nil {
-			// MinIO 未初始化：记录预警并回退到本地
-			logger.Warn("MinIO backend selected but client not initialized; falling back to local")
-			obj, lerr := w.local.Write(ctx, meta, content, contentType)
-			if lerr != nil {
-				return StoredObject{}, fmt.Errorf("minio client not initialized; local fallback failed: %w", lerr)
-			}
-			// 返回对象同时返回预警错误，便于上层记录但不中断流程
-			return obj, fmt.Errorf("minio client not initialized; wrote to local instead")
+	if backend == "local" || backend == "" {
+		return w.local.Write(ctx, meta, content, contentType)
+	}
+	remote := w.remote(backend)
+	if remote == nil {
+		// 远端未初始化：记录预警并回退到本地
+		logger.Warn("Storage backend selected but client not initialized; falling back to local", "backend", backend)
+		obj, lerr := w.local.Write(ctx, meta, content, contentType)
+		if lerr != nil {
+			return StoredObject{}, fmt.Errorf("%s client not initialized; local fallback failed: %w", backend, lerr)
 		}
-		// 先尝试 MinIO 写入
-		obj, err := w.minio.Write(ctx, meta, content, contentType)
-		if err != nil {
-			// 失败则记录预警并回退到本地
-			logger.Warn("MinIO write failed; falling back to local", "error", err)
-			objLocal, lerr := w.local.Write(ctx, meta, content, contentType)
-			if lerr != nil {
-				return StoredObject{}, fmt.Errorf("minio write failed: %v; local fallback failed: %w", err, lerr)
-			}
-			// 返回本地对象，并携带预警错误说明
-			return objLocal, fmt.Errorf("minio write failed: %w; fell back to local successfully", err)
+		AddWarning(ctx, Warning{
+			Code:    WarnStorageFallback,
+			Message: fmt.Sprintf("%s storage not initialized, objects written to local storage", backend),
+			Context: map[string]interface{}{"backend": backend},
+		})
+		// 返回对象同时返回预警错误，便于上层记录但不中断流程
+		return obj, fmt.Errorf("%s client not initialized; wrote to local instead", backend)
+	}
+	// 先尝试远端写入
+	_, remoteSpan := startSpan(ctx, "storage.remote_write", attribute.String("storage.backend", backend))
+	obj, err := remote.Write(ctx, meta, content, contentType)
+	endSpan(remoteSpan, err)
+	if err != nil {
+		// 失败则记录预警并回退到本地
+		logger.Warn("Storage backend write failed; falling back to local", "backend", backend, "error", err)
+		objLocal, lerr := w.local.Write(ctx, meta, content, contentType)
+		if lerr != nil {
+			return StoredObject{}, fmt.Errorf("%s write failed: %v; local fallback failed: %w", backend, err, lerr)
+		}
+		AddWarning(ctx, Warning{
+			Code:    WarnStorageFallback,
+			Message: fmt.Sprintf("%s write failed, objects written to local storage", backend),
+			Context: map[string]interface{}{"backend": backend, "error": err.Error()},
+		})
+		// 返回本地对象，并携带预警错误说明
+		return objLocal, fmt.Errorf("%s write failed: %w; fell back to local successfully", backend, err)
+	}
+	return obj, nil
+}
+
+// remote 返回远端写入器；未知或未初始化时返回 nil
+func (w *DelegatingStorageWriter) remote(backend string) StorageWriter {
+	mw, s3, az := w.remotes()
+	switch backend {
+	case "minio":
+		if mw != nil {
+			return mw
+		}
+	case "s3":
+		if s3 != nil {
+			return s3
+		}
+	case "azure":
+		if az != nil {
+			return az
 		}
-		return obj, nil
 	}
-	// 默认走本地
-	return w.local.Write(ctx, meta, content, contentType)
+	return nil
 }
 
 // LocalStorageWriter 本地文件写入
 type LocalStorageWriter struct {
-	cfg *config.Config
+	provider *config.Provider
 }
 
 func (w *LocalStorageWriter) Write(ctx context.Context, meta StorageMeta, content string, contentType string) (StoredObject, error) {
-	baseDir := strings.TrimSpace(w.cfg.Backup.Local.BaseDir)
+	baseDir := strings.TrimSpace(w.conf().Backup.Local.BaseDir)
 	if baseDir == "" {
 		baseDir = "./data/backups"
 	}
 
 	// 层级：baseDir / backup.prefix / local.prefix / save_dir / device / date / taskID
 	parts := []string{baseDir}
-	if p := strings.TrimSpace(w.cfg.Backup.Prefix); p != "" {
+	if p := strings.TrimSpace(w.conf().Backup.Prefix); p != "" {
 		parts = append(parts, p)
 	}
-	if p := strings.TrimSpace(w.cfg.Backup.Local.Prefix); p != "" {
+	if p := strings.TrimSpace(w.conf().Backup.Local.Prefix); p != "" {
 		parts = append(parts, p)
 	}
 	if sd := strings.TrimSpace(meta.SaveDir); sd != "" {
 		parts = append(parts, sd)
 	}
 
-	deviceLabel := strings.TrimSpace(meta.DeviceName)
-	if deviceLabel == "" {
-		deviceLabel = strings.TrimSpace(meta.DeviceIP)
-	}
-	deviceLabel = slug(deviceLabel)
-
-	parts = append(parts, deviceLabel)
-	// 目录层增加统一的设备任务开始时间，例如 20251016_145830
-	datePart := strings.TrimSpace(meta.DateYYYYMMDD)
-	if datePart == "" {
-		datePart = time.Now().Format("20060102")
-	}
-	timePart := strings.TrimSpace(meta.TimeHHMMSS)
-	if timePart == "" {
-		timePart = time.Now().Format("150405")
-	}
-	parts = append(parts, fmt.Sprintf("%s_%s", datePart, timePart))
-	if tid := strings.TrimSpace(meta.TaskID); tid != "" {
-		parts = append(parts, tid)
-	}
+	// 设备目录层级：默认 设备/日期_时间（统一的设备任务开始时间，例如 20251016_145830）/taskID，可由 path_template 定制
+	parts = append(parts, deviceDirParts(w.conf(), meta)...)
 
 	dirPath := filepath.Join(parts...)
 
-	if w.cfg.Backup.Local.MkdirIfMissing {
+	if w.conf().Backup.Local.MkdirIfMissing {
 		if err := os.MkdirAll(dirPath, 0o755); err != nil {
 			return StoredObject{}, fmt.Errorf("failed to create dir: %w", err)
 		}
 	}
 
 	// 过滤输出（按平台配置优先，回退到全局配置）
-	filtered := applyPlatformLineFilter(w.cfg, meta.DevicePlatform, content)
+	filtered := content
+	if !meta.SkipFilter {
+		filtered = applyPlatformLineFilter(w.conf(), meta.DevicePlatform, content)
+	}
 
-	// 文件名：命令 slug 或显式文件名（目录已带时分秒避免覆盖）
+	// 文件名：命令 slug 或显式文件名（目录已带时分秒避免覆盖），可由 filename_template 定制
 	// 若传入已包含扩展名，则不再追加 .txt
-	base := slug(meta.CommandSlug)
-	filename := base
-	if !strings.Contains(base, ".") {
-		filename = base + ".txt"
-	}
-	fullPath := filepath.Join(dirPath, filename)
+	fullPath := filepath.Join(dirPath, storageFileName(w.conf(), meta))
 
 	// 写入文件
 	data := []byte(filtered)
@@ -250,11 +334,14 @@ func (w *LocalStorageWriter) Write(ctx context.Context, meta StorageMeta, conten
 	}, nil
 }
 
-// MinioStorageWriter MinIO 对象存储写入（统一文件实现）
+// MinioStorageWriter MinIO 对象存储写入（统一文件实现，S3 兼容后端复用）
 type MinioStorageWriter struct {
-	cfg           *config.Config
+	provider      *config.Provider
 	client        *minio.Client
 	endpoint      string
+	bucket        string
+	region        string
+	scheme        string // 对象 URI 前缀：minio | s3
 	bucketEnsured bool
 }
 
@@ -289,7 +376,7 @@ func initMinioWriter(cfg *config.Config) *MinioStorageWriter {
 		return nil
 	}
 
-	w := &MinioStorageWriter{cfg: cfg, client: client, endpoint: endpoint}
+	w := &MinioStorageWriter{provider: config.ProviderFor(cfg), client: client, endpoint: endpoint, bucket: strings.TrimSpace(cfg.Storage.Minio.Bucket), scheme: "minio"}
 
 	// 进行一次轻量连通性与 bucket 校验（不影响整体初始化）
 	bucket := strings.TrimSpace(cfg.Storage.Minio.Bucket)
@@ -312,51 +399,19 @@ func (w *MinioStorageWriter) Write(ctx context.Context, meta StorageMeta, conten
 	if w == nil || w.client == nil {
 		return StoredObject{}, fmt.Errorf("minio client not initialized")
 	}
-	bucket := strings.TrimSpace(w.cfg.Storage.Minio.Bucket)
+	bucket := w.bucket
 	if bucket == "" {
-		return StoredObject{}, fmt.Errorf("minio bucket not configured")
+		return StoredObject{}, fmt.Errorf("%s bucket not configured", w.scheme)
 	}
 
 	// 过滤输出（按平台配置优先，回退到全局配置）
-	filtered := applyPlatformLineFilter(w.cfg, meta.DevicePlatform, content)
-
-	// 构造对象路径（使用 POSIX 风格，与本地一致）
-	parts := []string{}
-	if p := strings.TrimSpace(w.cfg.Backup.Prefix); p != "" {
-		parts = append(parts, p)
-	}
-	if p := strings.TrimSpace(w.cfg.Backup.Local.Prefix); p != "" {
-		parts = append(parts, p)
-	}
-	if sd := strings.TrimSpace(meta.SaveDir); sd != "" {
-		parts = append(parts, sd)
-	}
-	deviceLabel := strings.TrimSpace(meta.DeviceName)
-	if deviceLabel == "" {
-		deviceLabel = strings.TrimSpace(meta.DeviceIP)
-	}
-	deviceLabel = slug(deviceLabel)
-	parts = append(parts, deviceLabel)
-	datePart := strings.TrimSpace(meta.DateYYYYMMDD)
-	if datePart == "" {
-		datePart = time.Now().Format("20060102")
-	}
-	timePart := strings.TrimSpace(meta.TimeHHMMSS)
-	if timePart == "" {
-		timePart = time.Now().Format("150405")
-	}
-	parts = append(parts, fmt.Sprintf("%s_%s", datePart, timePart))
-	if tid := strings.TrimSpace(meta.TaskID); tid != "" {
-		parts = append(parts, tid)
+	filtered := content
+	if !meta.SkipFilter {
+		filtered = applyPlatformLineFilter(w.conf(), meta.DevicePlatform, content)
 	}
 
-	// 文件名：命令 slug 或显式文件名（与本地规则一致）
-	base := slug(meta.CommandSlug)
-	filename := base
-	if !strings.Contains(base, ".") {
-		filename = base + ".txt"
-	}
-	objectName := path.Join(strings.Join(parts, "/"), filename)
+	// 构造对象路径（使用 POSIX 风格，与本地一致）
+	objectName := storageObjectName(w.conf(), meta)
 
 	data := []byte(filtered)
 	ct := contentType
@@ -366,13 +421,13 @@ func (w *MinioStorageWriter) Write(ctx context.Context, meta StorageMeta, conten
 
 	// 写入前快速连通性探测（失败则尽早返回明确错误）
 	if err := w.fastConnectivityCheck(ctx); err != nil {
-		return StoredObject{}, fmt.Errorf("minio connectivity failed to %s: %w", w.endpoint, err)
+		return StoredObject{}, fmt.Errorf("%s connectivity failed to %s: %w", w.scheme, w.endpoint, err)
 	}
 
 	// 需要时确保 bucket（有限重试）
 	if !w.bucketEnsured {
 		if err := w.ensureBucket(ctx, bucket, 3); err != nil {
-			return StoredObject{}, fmt.Errorf("minio ensure bucket failed: %w", err)
+			return StoredObject{}, fmt.Errorf("%s ensure bucket failed: %w", w.scheme, err)
 		}
 		w.bucketEnsured = true
 	}
@@ -393,7 +448,7 @@ func (w *MinioStorageWriter) Write(ctx context.Context, meta StorageMeta, conten
 		time.Sleep(attempts[i])
 	}
 	if lastErr != nil {
-		return StoredObject{}, fmt.Errorf("minio put object failed after retries: %w", lastErr)
+		return StoredObject{}, fmt.Errorf("%s put object failed after retries: %w", w.scheme, lastErr)
 	}
 
 	// 计算校验
@@ -401,7 +456,7 @@ func (w *MinioStorageWriter) Write(ctx context.Context, meta StorageMeta, conten
 	chk := "sha256:" + hex.EncodeToString(sum[:])
 
 	// 返回对象信息
-	uri := "minio://" + path.Join(bucket, objectName)
+	uri := w.scheme + "://" + path.Join(bucket, objectName)
 	return StoredObject{
 		URI:         uri,
 		Size:        int64(len(data)),
@@ -437,7 +492,7 @@ func (w *MinioStorageWriter) ensureBucket(parent context.Context, bucket string,
 			return nil
 		}
 		ctx2, cancel2 := w.attemptContext(parent, 10*time.Second)
-		if mkErr := w.client.MakeBucket(ctx2, bucket, minio.MakeBucketOptions{}); mkErr != nil {
+		if mkErr := w.client.MakeBucket(ctx2, bucket, minio.MakeBucketOptions{Region: w.region}); mkErr != nil {
 			lastErr = mkErr
 			cancel2()
 			time.Sleep(time.Duration(i+1) * time.Second)
@@ -551,9 +606,9 @@ func getOutputFilterForPlatform(cfg *config.Config, platform string) config.Outp
 	return config.OutputFilterConfig{}
 }
 
-// applyPlatformLineFilter 根据设备平台选择过滤规则并应用
+// applyPlatformLineFilter 根据设备平台先执行输出规整流水线，再选择过滤规则并应用
 func applyPlatformLineFilter(cfg *config.Config, platform string, s string) string {
-	return applyLineFilter(getOutputFilterForPlatform(cfg, platform), s)
+	return applyLineFilter(getOutputFilterForPlatform(cfg, platform), NormalizeOutput(cfg, platform, s))
 }
 
 var slugRe = regexp.MustCompile(`[^a-z0-9._-]+`)
@@ -575,12 +630,13 @@ func slug(s string) string {
 // 交互说明：设备命令执行统一走 InteractBasic（交互优先、失败回退非交互逻辑已内联到 InteractBasic），包含平台预命令注入与结果过滤。
 // 职责边界：本服务仅做任务编排与存储写入；不参与预命令注入或输出过滤。
 type BackupService struct {
-	config        *config.Config
+	provider      *config.Provider
 	sshPool       *ssh.Pool
 	running       bool
-	workers       chan struct{}
+	workers       *workerSlots
 	interact      *InteractBasic
 	storageWriter StorageWriter
+	resultIndex   CommandResultIndex
 }
 
 // NewBackupService 创建备份服务
@@ -589,33 +645,21 @@ func NewBackupService(cfg *config.Config) *BackupService {
 	if conc <= 0 {
 		conc = 1
 	}
-	threads := cfg.Collector.Threads
-	if threads <= 0 {
-		threads = cfg.SSH.MaxSessions
-	}
-	poolConfig := &ssh.PoolConfig{
-		MaxIdle:         10,
-		MaxActive:       conc,
-		IdleTimeout:     5 * time.Minute,
-		CleanupInterval: cfg.SSH.CleanupInterval,
-		SSHConfig: &ssh.Config{
-			Timeout:        cfg.SSH.Timeout,
-			ConnectTimeout: cfg.SSH.ConnectTimeout,
-			KeepAlive:      cfg.SSH.KeepAliveInterval,
-			MaxSessions:    threads,
-		},
-	}
-
-	pool := ssh.NewPool(poolConfig)
+	pool := newServicePool(cfg, "backup")
 	return &BackupService{
-		config:        cfg,
+		provider:      config.ProviderFor(cfg),
 		sshPool:       pool,
-		workers:       make(chan struct{}, conc),
+		workers:       newWorkerSlots(conc),
 		interact:      NewInteractBasic(cfg, pool),
 		storageWriter: NewStorageWriter(cfg),
 	}
 }
 
+// SetResultIndex 注入命令结果索引（启用差异化备份与落盘记录）
+func (s *BackupService) SetResultIndex(idx CommandResultIndex) {
+	s.resultIndex = idx
+}
+
 // Start 启动服务
 func (s *BackupService) Start(ctx context.Context) error {
 	if s.running {
@@ -632,7 +676,7 @@ func (s *BackupService) Stop() error {
 		return nil
 	}
 	s.running = false
-	if err := s.sshPool.Close(); err != nil {
+	if err := closeServicePool(s.sshPool); err != nil {
 		logger.Error("Failed to close SSH pool (backup)", "error", err)
 	}
 	logger.Info("Backup service stopped")
@@ -641,26 +685,129 @@ func (s *BackupService) Stop() error {
 
 // ExecuteBatch 执行批量备份
 func (s *BackupService) ExecuteBatch(ctx context.Context, req *BackupBatchRequest) (*BackupBatchResponse, error) {
+	return s.ExecuteBatchStream(ctx, req, nil)
+}
+
+// ExecuteBatchStream 执行批量备份，每台设备完成时回调 onDevice（串行调用，均在返回前完成）
+// 批内重复设备按 duplicate_mode 处理：reject 拒绝请求；merge/copy 合并命令只执行一次，copy 将结果复制到各重复位置
+func (s *BackupService) ExecuteBatchStream(ctx context.Context, req *BackupBatchRequest, onDevice func(DeviceBackupResponse)) (*BackupBatchResponse, error) {
+	emit := func(_ int, r DeviceBackupResponse) { onDevice(r) }
+	if onDevice == nil {
+		emit = nil
+	}
+	if req == nil || len(req.Devices) < 2 {
+		return s.executeBatchStream(ctx, req, emit)
+	}
+	dedup, err := DedupDevices(s.conf(), req.DuplicateMode, len(req.Devices), func(i int) (string, int, string) {
+		return req.Devices[i].DeviceIP, req.Devices[i].Port, req.Devices[i].UserName
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !dedup.HasDuplicates() {
+		return s.executeBatchStream(ctx, req, emit)
+	}
+
+	// 仅执行首次出现的设备，重复设备的命令并入首次出现的位置
+	kept := dedup.Kept()
+	run := *req
+	run.Devices = make([]BackupDevice, 0, len(kept))
+	at := make(map[int]int, len(kept)) // 请求位置 -> 执行位置
+	for _, i := range kept {
+		at[i] = len(run.Devices)
+		run.Devices = append(run.Devices, req.Devices[i])
+	}
+	for _, dup := range dedup.Duplicates {
+		p := &run.Devices[at[dup.DuplicateOf]]
+		p.CliList = MergeCLIList(p.CliList, req.Devices[dup.Index].CliList)
+	}
+	copyOf := func(i int, r DeviceBackupResponse) DeviceBackupResponse {
+		r.DeviceName = req.Devices[i].DeviceName
+		primary := dedup.Primary(i)
+		r.DuplicateOf = &primary
+		return r
+	}
+	if emit != nil && dedup.Mode == DuplicateModeCopy {
+		// 首次出现的设备完成时一并推送其重复位置的复制结果
+		emit = func(idx int, r DeviceBackupResponse) {
+			onDevice(r)
+			for _, dup := range dedup.Duplicates {
+				if at[dup.DuplicateOf] == idx {
+					onDevice(copyOf(dup.Index, r))
+				}
+			}
+		}
+	}
+	resp, err := s.executeBatchStream(ctx, &run, emit)
+	if err != nil {
+		return nil, err
+	}
+	resp.Duplicates = dedup.Duplicates
+	if dedup.Mode != DuplicateModeCopy {
+		return resp, nil
+	}
+	data := make([]DeviceBackupResponse, 0, len(req.Devices))
+	succeeded := 0
+	for i := range req.Devices {
+		r := resp.Data[at[dedup.Primary(i)]]
+		if dedup.IsDuplicate(i) {
+			r = copyOf(i, r)
+		}
+		if r.Success {
+			succeeded++
+		}
+		data = append(data, r)
+	}
+	outcome := NewBatchOutcome("批量备份任务", len(data), succeeded)
+	resp.Data, resp.Total, resp.Code, resp.Message = data, len(data), outcome.Code, outcome.Message
+	return resp, nil
+}
+
+// executeBatchStream 执行批量备份，onDevice 附带设备在 req.Devices 中的位置
+func (s *BackupService) executeBatchStream(ctx context.Context, req *BackupBatchRequest, onDevice func(int, DeviceBackupResponse)) (*BackupBatchResponse, error) {
+	cfg := s.conf() // 本任务使用的配置快照
 	if !s.running {
-		return nil, fmt.Errorf("backup service is not running")
+		return nil, serviceStopped("backup")
 	}
 	if req == nil {
-		return nil, fmt.Errorf("nil request")
+		return nil, validationErrorf("nil request")
 	}
 	if strings.TrimSpace(req.TaskID) == "" {
-		return nil, fmt.Errorf("task_id is required")
+		return nil, validationErrorf("task_id is required")
 	}
 	if len(req.Devices) == 0 {
-		return nil, fmt.Errorf("devices is empty")
+		return nil, validationErrorf("devices is empty")
 	}
+	if err := req.RetryPolicy.Validate(); err != nil {
+		return nil, err
+	}
+	for i := range req.Devices {
+		req.Devices[i].DevicePlatform = normalizeRequestPlatform(ctx, cfg, req.Devices[i].DevicePlatform, req.Devices[i].DeviceIP)
+	}
+	ctx, batchSpan := startSpan(ctx, "backup.batch", attribute.String("task.id", req.TaskID), attribute.Int("task.batch", req.TaskBatch), attribute.Int("batch.devices", len(req.Devices)))
+	defer batchSpan.End()
 
 	// 并发执行各设备备份
 	type item struct {
 		resp DeviceBackupResponse
 	}
 	out := make([]item, len(req.Devices))
+	// 设备 span 在结果回调前结束，避免晚于批次 span
+	spans := make([]trace.Span, len(req.Devices))
 	var wg sync.WaitGroup
 	wg.Add(len(req.Devices))
+	var notifyMu sync.Mutex
+	done := func(idx int) {
+		if spans[idx] != nil {
+			endSpan(spans[idx], resultError(out[idx].resp.Success, out[idx].resp.Error))
+		}
+		if onDevice != nil {
+			notifyMu.Lock()
+			onDevice(idx, out[idx].resp)
+			notifyMu.Unlock()
+		}
+		wg.Done()
+	}
 
 	for i := range req.Devices {
 		idx := i
@@ -670,12 +817,19 @@ func (s *BackupService) ExecuteBatch(ctx context.Context, req *BackupBatchReques
 		go func() {
 			// 采用有效超时作为队列等待窗口
 			effTimeout := s.effectiveTimeout(req.TaskTimeout, dev.DevicePlatform)
+			devCtx, timings := WithTimings(ctx)
+			queued := time.Now()
 			waitCtx, waitCancel := context.WithTimeout(context.Background(), time.Duration(effTimeout)*time.Second)
 			defer waitCancel()
-			select {
-			case s.workers <- struct{}{}:
-				defer func() { <-s.workers }()
-			case <-waitCtx.Done():
+			slots, slotErr := s.workers.acquire(waitCtx, req.Deadline)
+			timings.AddQueueWait(time.Since(queued))
+			if slotErr != nil {
+				errMsg := fmt.Sprintf("queue wait timeout after %ds", effTimeout)
+				status := ""
+				if errors.Is(slotErr, ErrWindowClosed) {
+					errMsg = "execution window closed before dispatch"
+					status = StatusNotAttemptedWindowClosed
+				}
 				out[idx].resp = DeviceBackupResponse{
 					DeviceIP: dev.DeviceIP,
 					Port: func() int {
@@ -689,13 +843,18 @@ func (s *BackupService) ExecuteBatch(ctx context.Context, req *BackupBatchReques
 					TaskID:         req.TaskID,
 					TaskBatch:      req.TaskBatch,
 					Success:        false,
-					Error:          fmt.Sprintf("queue wait timeout after %ds", effTimeout),
+					Status:         status,
+					Error:          errMsg,
 					DurationMS:     0,
 					Timestamp:      time.Now(),
+					Timings:        timings,
 				}
-				wg.Done()
+				done(idx)
 				return
 			}
+			defer func() { <-slots }()
+			ctx, span := startSpan(devCtx, "backup.device", append(deviceSpanAttrs(dev.DeviceIP, dev.DeviceName, dev.DevicePlatform), attribute.String("task.id", req.TaskID))...)
+			spans[idx] = span
 
 			start := time.Now()
 			resp := DeviceBackupResponse{
@@ -711,6 +870,17 @@ func (s *BackupService) ExecuteBatch(ctx context.Context, req *BackupBatchReques
 				TaskID:         req.TaskID,
 				TaskBatch:      req.TaskBatch,
 				Timestamp:      start,
+				Timings:        timings,
+			}
+
+			// 出站策略：范围外的设备地址不执行
+			if err := checkEgress(ctx, cfg, dev.DeviceIP); err != nil {
+				resp.Status = StatusEgressBlocked
+				resp.Error = err.Error()
+				resp.DurationMS = time.Since(start).Milliseconds()
+				out[idx].resp = resp
+				done(idx)
+				return
 			}
 
 			// 执行命令
@@ -730,41 +900,69 @@ func (s *BackupService) ExecuteBatch(ctx context.Context, req *BackupBatchReques
 					}
 					return s.effectiveTimeout(req.TaskTimeout, dev.DevicePlatform)
 				}(),
+				CommandTimeouts: dev.CliList.Timeouts(),
+				RawCommands:     dev.CliList.RawCommands(),
+				Transcript:      backupTranscript(cfg, req, &dev),
 			}
 
-			// 支持有限重试（请求优先，平台默认回退）
+			// 差异化备份：结果仍新鲜的命令直接引用缓存对象
+			runList, hits := splitFreshCommands(s.resultIndex, dev.DeviceIP, resp.Port, dev.CliList, req.FreshTTL, start)
+			skipped := make([]CommandBackupResult, 0, len(hits))
+			for _, h := range hits {
+				skipped = append(skipped, h.backupResult())
+			}
+			resp.SkippedFresh = len(skipped)
+			if len(runList) == 0 {
+				resp.Results = skipped
+				resp.Success = true
+				resp.DurationMS = time.Since(start).Milliseconds()
+				out[idx].resp = resp
+				done(idx)
+				return
+			}
+
+			// 命名模板引用设备事实时，事实命令在同一会话中追加执行
+			facts := factCommands(cfg, dev.DevicePlatform)
+			cmds, factExtra := withFactCommands(runList.Commands(), facts)
+
+			// 支持有限重试（请求优先，平台默认回退），退避与重试条件见 retry_policy
 			var results []*ssh.CommandResult
-			var err error
 			retries := s.effectiveRetries(req.RetryFlag, dev.DevicePlatform)
-			for attempt := 0; attempt <= retries; attempt++ {
-				results, err = s.interact.Execute(ctx, execReq, dev.CliList)
-				if err == nil {
-					break
-				}
-				if attempt < retries {
-					time.Sleep(300 * time.Millisecond)
-				}
-			}
+			attempts, err := resolveRetryPolicy(cfg, dev.DevicePlatform, req.RetryPolicy).do(ctx, retries, nil, func() error {
+				var err error
+				results, err = s.interact.Execute(ctx, execReq, cmds)
+				return err
+			})
+			resp.Attempts = failedAttempts(attempts)
 			if err != nil {
 				resp.Success = false
 				resp.Error = err.Error()
+				if errors.Is(err, ErrEgressBlocked) {
+					resp.Status = StatusEgressBlocked
+				} else if errors.Is(err, ErrCredentialThrottled) {
+					resp.Status = StatusCredentialThrottled
+				} else if errors.Is(err, ErrDeviceCircuitOpen) {
+					resp.Status = StatusDeviceCircuitOpen
+				} else if errors.Is(err, ErrHostKeyRejected) {
+					resp.Status = StatusHostKeyRejected
+				}
+				resp.TranscriptObject = s.storeTranscript(ctx, cfg, req, &dev, start, nil, execReq.Transcript)
 				resp.DurationMS = time.Since(start).Milliseconds()
 				out[idx].resp = resp
-				wg.Done()
+				done(idx)
 				return
 			}
 
+			// 提取事实后移除仅为事实追加的命令结果（不落盘、不返回）
+			devFacts := resolveFacts(ctx, facts, results, dev.DeviceIP)
+			results = dropFactResults(results, factExtra)
+
 			// 写入存储并组装响应
 			date := time.Now().Format("20060102")
-			backend := strings.TrimSpace(req.StorageBackend)
-			if backend == "" {
-				backend = strings.TrimSpace(s.config.Backup.StorageBackend)
-			}
-			if backend == "" {
-				backend = "local"
-			}
+			backend := backupBackend(cfg, req)
 
-			resp.Results = make([]CommandBackupResult, 0, len(results))
+			resp.Results = make([]CommandBackupResult, 0, len(results)+len(skipped))
+			resp.Results = append(resp.Results, skipped...)
 			for _, r := range results {
 				// 预处理命令不落盘，仅记录输出（例如 enable、关闭分页等）
 				isPre := s.isPreCommand(dev.DevicePlatform, r.Command)
@@ -772,7 +970,7 @@ func (s *BackupService) ExecuteBatch(ctx context.Context, req *BackupBatchReques
 				stored := []StoredObject{}
 				storeErrMsg := ""
 				// 当 aggregate_only 启用时，跳过逐命令写入，仅生成聚合文件
-				if !isPre && !s.config.Backup.Aggregate.AggregateOnly {
+				if !isPre && !cfg.Backup.Aggregate.AggregateOnly {
 					// 仅对采集命令进行存储
 					meta := StorageMeta{
 						SaveDir:        req.SaveDir,
@@ -784,6 +982,7 @@ func (s *BackupService) ExecuteBatch(ctx context.Context, req *BackupBatchReques
 						DevicePlatform: dev.DevicePlatform,
 						CommandSlug:    r.Command,
 						Backend:        backend,
+						Facts:          devFacts,
 					}
 					obj, werr := s.storageWriter.Write(ctx, meta, r.Output, "text/plain; charset=utf-8")
 					if obj.URI != "" {
@@ -791,6 +990,33 @@ func (s *BackupService) ExecuteBatch(ctx context.Context, req *BackupBatchReques
 					}
 					if werr != nil {
 						storeErrMsg = werr.Error()
+					} else if r.ExitCode == 0 && r.Error == "" {
+						recordFreshResult(s.resultIndex, dev.DeviceIP, resp.Port, r.Command, req.TaskID, obj, start)
+					}
+				}
+
+				// 原始字节另存为 {命令}.raw，不做行过滤
+				var rawObj *StoredObject
+				if !isPre && r.Raw != nil {
+					meta := StorageMeta{
+						SaveDir:        req.SaveDir,
+						DateYYYYMMDD:   date,
+						TimeHHMMSS:     start.Format("150405"),
+						TaskID:         req.TaskID,
+						DeviceName:     dev.DeviceName,
+						DeviceIP:       dev.DeviceIP,
+						DevicePlatform: dev.DevicePlatform,
+						CommandSlug:    r.Command + ".raw",
+						Backend:        backend,
+						Facts:          devFacts,
+						SkipFilter:     true,
+					}
+					obj, werr := s.storageWriter.Write(ctx, meta, string(r.Raw), "application/octet-stream")
+					if obj.URI != "" {
+						rawObj = &obj
+					}
+					if werr != nil && storeErrMsg == "" {
+						storeErrMsg = werr.Error()
 					}
 				}
 
@@ -804,6 +1030,7 @@ func (s *BackupService) ExecuteBatch(ctx context.Context, req *BackupBatchReques
 						return strings.Split(r.Output, "\n")
 					}(),
 					StoredObjects: stored,
+					RawObject:     rawObj,
 					ExitCode:      r.ExitCode,
 					DurationMS:    r.Duration.Milliseconds(),
 					Error: func() string {
@@ -817,7 +1044,7 @@ func (s *BackupService) ExecuteBatch(ctx context.Context, req *BackupBatchReques
 
 			// 聚合写入：受配置控制，将所有采集命令输出汇总到单一文件（不包含预处理命令）
 			// 当 aggregate_only=true 时，即便未显式开启 enabled，也生成聚合文件
-			if s.config.Backup.Aggregate.Enabled || s.config.Backup.Aggregate.AggregateOnly {
+			if cfg.Backup.Aggregate.Enabled || cfg.Backup.Aggregate.AggregateOnly {
 				var aggBuilder strings.Builder
 				// 统一的设备与时间，用于段落标识
 				devName := strings.TrimSpace(dev.DeviceName)
@@ -826,7 +1053,7 @@ func (s *BackupService) ExecuteBatch(ctx context.Context, req *BackupBatchReques
 				}
 				ts := start.Format("2006-01-02 15:04:05")
 				for _, r := range resp.Results {
-					if s.isPreCommand(dev.DevicePlatform, r.Command) {
+					if r.SkippedFresh || s.isPreCommand(dev.DevicePlatform, r.Command) {
 						continue
 					}
 					cmdTitle := strings.TrimSpace(r.Command)
@@ -853,7 +1080,7 @@ func (s *BackupService) ExecuteBatch(ctx context.Context, req *BackupBatchReques
 				aggContent := aggBuilder.String()
 				if strings.TrimSpace(aggContent) != "" {
 					// 聚合文件名可配置，允许带扩展名
-					aggName := strings.TrimSpace(s.config.Backup.Aggregate.Filename)
+					aggName := strings.TrimSpace(cfg.Backup.Aggregate.Filename)
 					if aggName == "" {
 						aggName = "all_cli.txt"
 					}
@@ -867,6 +1094,7 @@ func (s *BackupService) ExecuteBatch(ctx context.Context, req *BackupBatchReques
 						DevicePlatform: dev.DevicePlatform,
 						CommandSlug:    aggName,
 						Backend:        backend,
+						Facts:          devFacts,
 					}
 					obj, werr := s.storageWriter.Write(ctx, metaAll, aggContent, "text/plain; charset=utf-8")
 					storedList := []StoredObject{}
@@ -889,11 +1117,16 @@ func (s *BackupService) ExecuteBatch(ctx context.Context, req *BackupBatchReques
 				}
 			}
 
+			resp.TranscriptObject = s.storeTranscript(ctx, cfg, req, &dev, start, devFacts, execReq.Transcript)
+
+			// 行数上限在写入存储与聚合之后应用，存储对象保留完整输出
+			s.capBackupResults(ctx, &dev, resp.Results)
+
 			// 成功条件：至少有结果且不含致命错误
 			resp.Success = len(resp.Results) > 0 && resp.Error == ""
 			resp.DurationMS = time.Since(start).Milliseconds()
 			out[idx].resp = resp
-			wg.Done()
+			done(idx)
 		}()
 	}
 
@@ -901,22 +1134,20 @@ func (s *BackupService) ExecuteBatch(ctx context.Context, req *BackupBatchReques
 
 	// 汇总响应
 	final := &BackupBatchResponse{
-		Code:    "SUCCESS",
-		Message: "batch backup executed",
-		Data:    make([]DeviceBackupResponse, 0, len(out)),
-		Total:   len(out),
+		Data:  make([]DeviceBackupResponse, 0, len(out)),
+		Total: len(out),
 	}
-	anyFail := false
+	succeeded := 0
 	for _, it := range out {
 		final.Data = append(final.Data, it.resp)
-		if !it.resp.Success {
-			anyFail = true
+		publishBackupEvent(cfg, req, it.resp)
+		if it.resp.Success {
+			succeeded++
 		}
 	}
-	if anyFail {
-		final.Code = "PARTIAL_SUCCESS"
-		final.Message = "some devices failed"
-	}
+	outcome := NewBatchOutcome("批量备份任务", len(out), succeeded)
+	final.Code, final.Message = outcome.Code, outcome.Message
+	batchSpan.SetAttributes(attribute.Int("batch.succeeded", succeeded))
 	return final, nil
 }
 
@@ -950,8 +1181,8 @@ func (s *BackupService) effectiveRetries(reqRetries *int, platform string) int {
 	if d.Retries > 0 {
 		return d.Retries
 	}
-	if s.config != nil && s.config.Collector.RetryFlags > 0 {
-		return s.config.Collector.RetryFlags
+	if s.conf() != nil && s.conf().Collector.RetryFlags > 0 {
+		return s.conf().Collector.RetryFlags
 	}
 	return 0
 }
@@ -964,19 +1195,19 @@ func (s *BackupService) isPreCommand(platform, cmd string) bool {
 	}
 	p := strings.ToLower(strings.TrimSpace(platform))
 
-	dd, ok := s.config.Collector.DeviceDefaults[p]
+	dd, ok := s.conf().Collector.DeviceDefaults[p]
 	if !ok {
 		if strings.HasPrefix(p, "huawei") {
-			dd, ok = s.config.Collector.DeviceDefaults["huawei"]
+			dd, ok = s.conf().Collector.DeviceDefaults["huawei"]
 		}
 		if !ok && strings.HasPrefix(p, "h3c") {
-			dd, ok = s.config.Collector.DeviceDefaults["h3c"]
+			dd, ok = s.conf().Collector.DeviceDefaults["h3c"]
 		}
 		if !ok && strings.HasPrefix(p, "cisco") {
-			dd, ok = s.config.Collector.DeviceDefaults["cisco_ios"]
+			dd, ok = s.conf().Collector.DeviceDefaults["cisco_ios"]
 		}
 		if !ok && strings.HasPrefix(p, "linux") {
-			dd, ok = s.config.Collector.DeviceDefaults["linux"]
+			dd, ok = s.conf().Collector.DeviceDefaults["linux"]
 		}
 	}
 	if ok {
@@ -0,0 +1,291 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	minio "github.com/minio/minio-go/v7"
+	"github.com/pmezard/go-difflib/difflib"
+)
+
+// maxDiffObjectSize 参与对比的单个对象大小上限
+const maxDiffObjectSize = 32 << 20
+
+// 对比错误：请求无效（URI 不合法或不属于同一设备/命令）、对象不存在
+var (
+	ErrInvalidDiffRequest = withKind(ErrValidation, errors.New("invalid diff request"))
+	ErrObjectNotFound     = withKind(ErrNotFound, errors.New("stored object not found"))
+)
+
+// BackupDiffRequest 两份备份对象的对比请求
+type BackupDiffRequest struct {
+	BaseURI        string   `json:"base_uri"`                  // 旧版本，如 file://... 或 minio://bucket/...
+	TargetURI      string   `json:"target_uri"`                // 新版本
+	Context        *int     `json:"context,omitempty"`         // 统一 diff 上下文行数，默认 3
+	IgnorePatterns []string `json:"ignore_patterns,omitempty"` // 对比前剔除匹配的行（正则），如时间戳注释
+	AllowMismatch  bool     `json:"allow_mismatch,omitempty"`  // 允许对比不同设备或命令的对象
+}
+
+// BackupDiffSummary 变更摘要
+type BackupDiffSummary struct {
+	Identical       bool     `json:"identical"`
+	LinesAdded      int      `json:"lines_added"`
+	LinesRemoved    int      `json:"lines_removed"`
+	SectionsChanged []string `json:"sections_changed"` // 发生变化的顶层配置段（无缩进行）
+}
+
+// BackupDiffResponse 对比结果
+type BackupDiffResponse struct {
+	BaseURI   string            `json:"base_uri"`
+	TargetURI string            `json:"target_uri"`
+	Device    string            `json:"device"`
+	Command   string            `json:"command"`
+	Diff      string            `json:"diff"` // 统一 diff 文本
+	Summary   BackupDiffSummary `json:"summary"`
+}
+
+// StorageReader 按 URI 读取已存储对象
+type StorageReader interface {
+	Read(ctx context.Context, uri string) ([]byte, error)
+}
+
+// Read 读取 file://、minio://、s3:// 或 azure:// 对象；本地路径须位于备份根目录内，远端须为配置的 bucket/容器
+func (w *DelegatingStorageWriter) Read(ctx context.Context, uri string) ([]byte, error) {
+	mw, s3, _ := w.remotes()
+	switch {
+	case strings.HasPrefix(uri, "file://"):
+		return w.readLocal(strings.TrimPrefix(uri, "file://"))
+	case strings.HasPrefix(uri, "minio://"):
+		return w.readMinio(ctx, mw, strings.TrimPrefix(uri, "minio://"))
+	case strings.HasPrefix(uri, "s3://"):
+		return w.readMinio(ctx, s3, strings.TrimPrefix(uri, "s3://"))
+	case strings.HasPrefix(uri, "azure://"):
+		return w.readAzure(ctx, strings.TrimPrefix(uri, "azure://"))
+	}
+	return nil, fmt.Errorf("%w: unsupported uri scheme: %s", ErrInvalidDiffRequest, uri)
+}
+
+func (w *DelegatingStorageWriter) readLocal(p string) ([]byte, error) {
+	baseDir := strings.TrimSpace(w.conf().Backup.Local.BaseDir)
+	if baseDir == "" {
+		baseDir = "./data/backups"
+	}
+	absBase, err := filepath.Abs(baseDir)
+	if err != nil {
+		return nil, err
+	}
+	absPath, err := filepath.Abs(p)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(absPath, absBase+string(filepath.Separator)) {
+		return nil, fmt.Errorf("%w: path outside backup base dir", ErrInvalidDiffRequest)
+	}
+	data, err := readLimited(os.Open(absPath))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s", ErrObjectNotFound, p)
+	}
+	return data, err
+}
+
+func (w *DelegatingStorageWriter) readMinio(ctx context.Context, mw *MinioStorageWriter, p string) ([]byte, error) {
+	if mw == nil || mw.client == nil {
+		return nil, fmt.Errorf("object storage client not initialized")
+	}
+	bucket := mw.bucket
+	idx := strings.Index(p, "/")
+	if idx <= 0 || p[:idx] != bucket {
+		return nil, fmt.Errorf("%w: object not in configured bucket", ErrInvalidDiffRequest)
+	}
+	obj, err := mw.client.GetObject(ctx, bucket, p[idx+1:], minio.GetObjectOptions{})
+	if err != nil {
+		return nil, err
+	}
+	data, err := readLimited(obj, nil)
+	if err != nil && minio.ToErrorResponse(err).Code == "NoSuchKey" {
+		return nil, fmt.Errorf("%w: %s", ErrObjectNotFound, p)
+	}
+	return data, err
+}
+
+func (w *DelegatingStorageWriter) readAzure(ctx context.Context, p string) ([]byte, error) {
+	_, _, az := w.remotes()
+	if az == nil {
+		return nil, fmt.Errorf("azure client not initialized")
+	}
+	idx := strings.Index(p, "/")
+	if idx <= 0 || p[:idx] != az.container {
+		return nil, fmt.Errorf("%w: object not in configured container", ErrInvalidDiffRequest)
+	}
+	return az.getBlob(ctx, p[idx+1:])
+}
+
+// readLimited 读取并限制大小
+func readLimited(r io.ReadCloser, err error) ([]byte, error) {
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	data, err := io.ReadAll(io.LimitReader(r, maxDiffObjectSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxDiffObjectSize {
+		return nil, fmt.Errorf("object exceeds %d bytes", maxDiffObjectSize)
+	}
+	return data, nil
+}
+
+// backupObjectKey 从 URI 解析设备目录与命令文件名：.../{device}/{date_time}/{task_id}/{cmd}.txt
+func backupObjectKey(uri string) (device, file string, ok bool) {
+	p := uri
+	if i := strings.Index(p, "://"); i >= 0 {
+		p = p[i+3:]
+	}
+	segs := strings.Split(path.Clean(filepath.ToSlash(p)), "/")
+	if len(segs) < 4 {
+		return "", "", false
+	}
+	return segs[len(segs)-4], segs[len(segs)-1], true
+}
+
+// Diff 对比同一设备/命令的两份备份对象
+func (s *BackupService) Diff(ctx context.Context, req *BackupDiffRequest) (*BackupDiffResponse, error) {
+	if req == nil || strings.TrimSpace(req.BaseURI) == "" || strings.TrimSpace(req.TargetURI) == "" {
+		return nil, fmt.Errorf("%w: base_uri and target_uri are required", ErrInvalidDiffRequest)
+	}
+	baseDev, baseFile, ok1 := backupObjectKey(req.BaseURI)
+	targetDev, targetFile, ok2 := backupObjectKey(req.TargetURI)
+	if !ok1 || !ok2 {
+		return nil, fmt.Errorf("%w: uri is not a backup object path", ErrInvalidDiffRequest)
+	}
+	if !req.AllowMismatch && (baseDev != targetDev || baseFile != targetFile) {
+		return nil, fmt.Errorf("%w: objects belong to different device/command (%s/%s vs %s/%s)", ErrInvalidDiffRequest, baseDev, baseFile, targetDev, targetFile)
+	}
+	ignore := make([]*regexp.Regexp, 0, len(req.IgnorePatterns))
+	for _, p := range req.IgnorePatterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, fmt.Errorf("%w: ignore_patterns %q: %v", ErrInvalidDiffRequest, p, err)
+		}
+		ignore = append(ignore, re)
+	}
+	ctxLines := 3
+	if req.Context != nil && *req.Context >= 0 {
+		ctxLines = *req.Context
+	}
+
+	reader, ok := s.storageWriter.(StorageReader)
+	if !ok {
+		return nil, fmt.Errorf("storage backend does not support reading")
+	}
+	baseData, err := reader.Read(ctx, req.BaseURI)
+	if err != nil {
+		return nil, fmt.Errorf("read base object: %w", err)
+	}
+	targetData, err := reader.Read(ctx, req.TargetURI)
+	if err != nil {
+		return nil, fmt.Errorf("read target object: %w", err)
+	}
+
+	a := diffLines(string(baseData), ignore)
+	b := diffLines(string(targetData), ignore)
+	text, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        a,
+		B:        b,
+		FromFile: req.BaseURI,
+		ToFile:   req.TargetURI,
+		Context:  ctxLines,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &BackupDiffResponse{
+		BaseURI:   req.BaseURI,
+		TargetURI: req.TargetURI,
+		Device:    targetDev,
+		Command:   strings.TrimSuffix(targetFile, ".txt"),
+		Diff:      text,
+		Summary:   summarizeDiff(a, b),
+	}, nil
+}
+
+// diffLines 按行拆分（统一换行符），剔除匹配忽略规则的行；每行保留结尾换行以符合 difflib 约定
+func diffLines(s string, ignore []*regexp.Regexp) []string {
+	s = strings.TrimSuffix(strings.ReplaceAll(s, "\r\n", "\n"), "\n")
+	if s == "" {
+		return []string{}
+	}
+	out := make([]string, 0, strings.Count(s, "\n")+1)
+	for _, l := range difflib.SplitLines(s) {
+		skip := false
+		for _, re := range ignore {
+			if re.MatchString(strings.TrimRight(l, "\n")) {
+				skip = true
+				break
+			}
+		}
+		if !skip {
+			out = append(out, l)
+		}
+	}
+	return out
+}
+
+// summarizeDiff 统计增删行数与涉及的顶层配置段
+func summarizeDiff(a, b []string) BackupDiffSummary {
+	sum := BackupDiffSummary{SectionsChanged: []string{}}
+	aSec, bSec := sectionHeaders(a), sectionHeaders(b)
+	seen := make(map[string]bool)
+	mark := func(sec string) {
+		if sec != "" && !seen[sec] {
+			seen[sec] = true
+			sum.SectionsChanged = append(sum.SectionsChanged, sec)
+		}
+	}
+	for _, op := range difflib.NewMatcher(a, b).GetOpCodes() {
+		switch op.Tag {
+		case 'r', 'd', 'i':
+			for i := op.I1; i < op.I2; i++ {
+				sum.LinesRemoved++
+				mark(aSec[i])
+			}
+			for j := op.J1; j < op.J2; j++ {
+				sum.LinesAdded++
+				mark(bSec[j])
+			}
+		}
+	}
+	sort.Strings(sum.SectionsChanged)
+	sum.Identical = sum.LinesAdded == 0 && sum.LinesRemoved == 0
+	return sum
+}
+
+// sectionHeaders 计算每行所属的顶层配置段：最近的无缩进行；! 与 # 分隔行不作为段头
+func sectionHeaders(lines []string) []string {
+	out := make([]string, len(lines))
+	cur := ""
+	for i, l := range lines {
+		t := strings.TrimRight(l, "\r\n")
+		trimmed := strings.TrimSpace(t)
+		switch {
+		case trimmed == "" || trimmed == "!" || trimmed == "#":
+			out[i] = cur
+			continue
+		case t[0] != ' ' && t[0] != '\t':
+			cur = trimmed
+		}
+		out[i] = cur
+	}
+	return out
+}
@@ -0,0 +1,216 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/sshcollectorpro/sshcollectorpro/internal/config"
+	"github.com/sshcollectorpro/sshcollectorpro/pkg/logger"
+	"github.com/sshcollectorpro/sshcollectorpro/pkg/ssh"
+)
+
+// nameTemplateFacts 备份命名模板引用的事实变量（去重，按出现顺序）
+func nameTemplateFacts(cfg *config.Config) []string {
+	seen := map[string]bool{}
+	out := make([]string, 0)
+	for _, tpl := range []string{cfg.Backup.PathTemplate, cfg.Backup.FilenameTemplate} {
+		for _, m := range config.NameTemplateTokenRe.FindAllStringSubmatch(tpl, -1) {
+			if name := strings.ToLower(m[1]); !config.BuiltinNameTokens[name] && !seen[name] {
+				seen[name] = true
+				out = append(out, name)
+			}
+		}
+	}
+	return out
+}
+
+// factCommands 平台中命名模板所需事实的定义
+func factCommands(cfg *config.Config, platform string) []config.FactConfig {
+	needed := nameTemplateFacts(cfg)
+	if len(needed) == 0 {
+		return nil
+	}
+	dd, ok := cfg.Collector.DeviceDefaults[strings.ToLower(strings.TrimSpace(platform))]
+	if !ok {
+		return nil
+	}
+	out := make([]config.FactConfig, 0, len(dd.Facts))
+	for _, f := range dd.Facts {
+		for _, n := range needed {
+			if strings.EqualFold(strings.TrimSpace(f.Name), n) {
+				out = append(out, f)
+				break
+			}
+		}
+	}
+	return out
+}
+
+// ValidateFacts 校验设备事实定义：name/command 必填，pattern 须可编译且含捕获组
+func ValidateFacts(list []config.FactConfig) error {
+	for i, f := range list {
+		if strings.TrimSpace(f.Name) == "" || strings.TrimSpace(f.Command) == "" {
+			return fmt.Errorf("facts[%d]: name and command are required", i)
+		}
+		re, err := regexp.Compile(f.Pattern)
+		if err != nil {
+			return fmt.Errorf("facts[%d]: invalid pattern: %w", i, err)
+		}
+		if re.NumSubexp() == 0 {
+			return fmt.Errorf("facts[%d]: pattern must contain a capture group", i)
+		}
+	}
+	return nil
+}
+
+// withFactCommands 在命令列表后追加尚未包含的事实命令；返回执行列表与追加的命令集合
+func withFactCommands(cmds []string, facts []config.FactConfig) ([]string, map[string]bool) {
+	have := make(map[string]bool, len(cmds))
+	for _, c := range cmds {
+		have[canonical(c)] = true
+	}
+	extra := map[string]bool{}
+	out := append([]string{}, cmds...)
+	for _, f := range facts {
+		c := strings.TrimSpace(f.Command)
+		if c == "" || have[canonical(c)] {
+			continue
+		}
+		have[canonical(c)] = true
+		extra[canonical(c)] = true
+		out = append(out, c)
+	}
+	return out, extra
+}
+
+// resolveFacts 从同一会话的命令输出中提取事实；未解析的事实记录告警，命名时回退到设备名或 IP
+func resolveFacts(ctx context.Context, facts []config.FactConfig, results []*ssh.CommandResult, deviceIP string) map[string]string {
+	if len(facts) == 0 {
+		return nil
+	}
+	outputs := make(map[string]string, len(results))
+	for _, r := range results {
+		if r != nil && r.Error == "" {
+			outputs[canonical(r.Command)] = r.Output
+		}
+	}
+	out := make(map[string]string, len(facts))
+	for _, f := range facts {
+		name := strings.ToLower(strings.TrimSpace(f.Name))
+		if _, done := out[name]; done {
+			continue
+		}
+		re, err := regexp.Compile("(?m)" + f.Pattern)
+		if err == nil {
+			if m := re.FindStringSubmatch(outputs[canonical(f.Command)]); len(m) > 1 && strings.TrimSpace(m[1]) != "" {
+				out[name] = strings.TrimSpace(m[1])
+				continue
+			}
+		}
+		logger.Warnf("Fact %s unresolved for %s (command %q), falling back to device name", name, deviceIP, f.Command)
+		AddWarning(ctx, Warning{
+			Code:    WarnFactUnresolved,
+			Message: fmt.Sprintf("fact %s could not be resolved; backup name falls back to device name", name),
+			Context: map[string]interface{}{"device_ip": deviceIP, "fact": name, "command": f.Command},
+		})
+	}
+	return out
+}
+
+// dropFactResults 移除仅为提取事实而追加的命令结果
+func dropFactResults(results []*ssh.CommandResult, extra map[string]bool) []*ssh.CommandResult {
+	if len(extra) == 0 {
+		return results
+	}
+	out := results[:0:0]
+	for _, r := range results {
+		if r != nil && extra[canonical(r.Command)] {
+			continue
+		}
+		out = append(out, r)
+	}
+	return out
+}
+
+// expandNameTemplate 替换命名变量（取值经 slug 处理）；事实缺失时回退到设备名，再回退到设备 IP
+func expandNameTemplate(tpl string, meta StorageMeta) string {
+	label := strings.TrimSpace(meta.DeviceName)
+	if label == "" {
+		label = strings.TrimSpace(meta.DeviceIP)
+	}
+	return config.NameTemplateTokenRe.ReplaceAllStringFunc(tpl, func(tok string) string {
+		name := strings.ToLower(config.NameTemplateTokenRe.FindStringSubmatch(tok)[1])
+		var v string
+		switch name {
+		case "device_name":
+			v = label
+		case "device_ip":
+			v = meta.DeviceIP
+		case "platform":
+			v = meta.DevicePlatform
+		case "task_id":
+			v = meta.TaskID
+		case "date":
+			v = meta.DateYYYYMMDD
+		case "time":
+			v = meta.TimeHHMMSS
+		case "command":
+			v = meta.CommandSlug
+		default:
+			if v = meta.Facts[name]; v == "" {
+				v = label
+			}
+		}
+		return slug(v)
+	})
+}
+
+// deviceDirParts save_dir 之下的设备目录层级：配置 path_template 时按模板展开，否则为 设备/日期_时间/task_id
+func deviceDirParts(cfg *config.Config, meta StorageMeta) []string {
+	meta.DateYYYYMMDD, meta.TimeHHMMSS = strings.TrimSpace(meta.DateYYYYMMDD), strings.TrimSpace(meta.TimeHHMMSS)
+	if meta.DateYYYYMMDD == "" {
+		meta.DateYYYYMMDD = time.Now().Format("20060102")
+	}
+	if meta.TimeHHMMSS == "" {
+		meta.TimeHHMMSS = time.Now().Format("150405")
+	}
+	if tpl := strings.TrimSpace(cfg.Backup.PathTemplate); tpl != "" {
+		parts := make([]string, 0)
+		for _, seg := range strings.Split(tpl, "/") {
+			if seg = strings.TrimSpace(seg); seg == "" || seg == "." || seg == ".." {
+				continue
+			}
+			parts = append(parts, expandNameTemplate(seg, meta))
+		}
+		if len(parts) > 0 {
+			return parts
+		}
+	}
+	label := strings.TrimSpace(meta.DeviceName)
+	if label == "" {
+		label = strings.TrimSpace(meta.DeviceIP)
+	}
+	parts := []string{slug(label), meta.DateYYYYMMDD + "_" + meta.TimeHHMMSS}
+	if tid := strings.TrimSpace(meta.TaskID); tid != "" {
+		parts = append(parts, tid)
+	}
+	return parts
+}
+
+// storageFileName 文件名：配置 filename_template 时按模板展开，否则为命令 slug；
+// 命令与模板字面文本均无扩展名时追加 .txt（变量取值中的点号如 IP 不视为扩展名）
+func storageFileName(cfg *config.Config, meta StorageMeta) string {
+	base := slug(meta.CommandSlug)
+	name, literal := base, ""
+	if tpl := strings.TrimSpace(cfg.Backup.FilenameTemplate); tpl != "" {
+		name = expandNameTemplate(tpl, meta)
+		literal = config.NameTemplateTokenRe.ReplaceAllString(tpl, "")
+	}
+	if !strings.Contains(base, ".") && !strings.Contains(literal, ".") {
+		name += ".txt"
+	}
+	return name
+}
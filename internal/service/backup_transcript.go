@@ -0,0 +1,67 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/sshcollectorpro/sshcollectorpro/internal/config"
+	"github.com/sshcollectorpro/sshcollectorpro/pkg/logger"
+	"github.com/sshcollectorpro/sshcollectorpro/pkg/ssh"
+)
+
+// TranscriptObjectName 会话转录在设备备份目录中的文件名
+const TranscriptObjectName = "session.transcript"
+
+// backupBackend 备份存储后端：请求优先，其次 backup.storage_backend，默认 local
+func backupBackend(cfg *config.Config, req *BackupBatchRequest) string {
+	backend := strings.TrimSpace(req.StorageBackend)
+	if backend == "" {
+		backend = strings.TrimSpace(cfg.Backup.StorageBackend)
+	}
+	if backend == "" {
+		backend = "local"
+	}
+	return backend
+}
+
+// backupTranscript 按请求 transcript（优先）与 backup.transcript.enable 为设备创建会话转录；未启用时返回 nil。
+// 登录与 enable 密码在转录中替换为 ******
+func backupTranscript(cfg *config.Config, req *BackupBatchRequest, dev *BackupDevice) *ssh.Transcript {
+	enable := cfg.Backup.Transcript.Enable
+	if req.Transcript != nil {
+		enable = *req.Transcript
+	}
+	if !enable {
+		return nil
+	}
+	return ssh.NewTranscript(cfg.Backup.Transcript.MaxBytes, dev.Password, dev.EnablePassword)
+}
+
+// storeTranscript 将会话转录保存为 session.transcript，与该设备的备份文件同目录；成功与失败的设备均保存
+func (s *BackupService) storeTranscript(ctx context.Context, cfg *config.Config, req *BackupBatchRequest, dev *BackupDevice, start time.Time, facts map[string]string, t *ssh.Transcript) *StoredObject {
+	if t == nil || t.Len() == 0 {
+		return nil
+	}
+	meta := StorageMeta{
+		SaveDir:        req.SaveDir,
+		DateYYYYMMDD:   time.Now().Format("20060102"),
+		TimeHHMMSS:     start.Format("150405"),
+		TaskID:         req.TaskID,
+		DeviceName:     dev.DeviceName,
+		DeviceIP:       dev.DeviceIP,
+		DevicePlatform: dev.DevicePlatform,
+		CommandSlug:    TranscriptObjectName,
+		Backend:        backupBackend(cfg, req),
+		Facts:          facts,
+		SkipFilter:     true,
+	}
+	obj, err := s.storageWriter.Write(ctx, meta, string(t.Bytes()), "text/plain; charset=utf-8")
+	if err != nil {
+		logger.Warn("Session transcript write failed", "device_ip", dev.DeviceIP, "error", err)
+	}
+	if obj.URI == "" {
+		return nil
+	}
+	return &obj
+}
@@ -0,0 +1,566 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sshcollectorpro/sshcollectorpro/internal/config"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/model"
+	"github.com/sshcollectorpro/sshcollectorpro/pkg/logger"
+)
+
+// 批量任务断点续跑与失败重试错误
+var (
+	ErrBatchJobNotFound    = withKind(ErrNotFound, errors.New("batch job not found"))
+	ErrBatchJobRunning     = errors.New("batch job is already running")
+	ErrBatchJobCompleted   = errors.New("batch job already completed")
+	ErrNoFailedDevices     = errors.New("no failed devices to retry")
+	ErrRetryNotAvailable   = errors.New("batch request not retained")
+	ErrRetryTaskIDExists   = errors.New("retry task_id already exists")
+	ErrBatchResumeDisabled = errors.New("batch_resume is not enabled")
+)
+
+// BatchJobStore 批量任务记录持久化（batch_jobs 表）
+type BatchJobStore interface {
+	// ReplaceBatchJob 新建记录，相同 ID 的旧记录被覆盖
+	ReplaceBatchJob(job *model.BatchJob) error
+	// BatchJob 按 ID 读取完整记录，不存在时返回 ErrBatchJobNotFound
+	BatchJob(id string) (*model.BatchJob, error)
+	// ListBatchJobs 按创建时间倒序分页列出（不含请求与设备列表），status 为空时不过滤
+	ListBatchJobs(status string, offset, limit int) ([]model.BatchJob, int64, error)
+	// BatchJobRetries 原任务的失败重试记录（不含请求），按创建时间升序
+	BatchJobRetries(rootID string) ([]model.BatchJob, error)
+	// MarkBatchJobResumed 标记为运行中并累加续跑次数
+	MarkBatchJobResumed(id string) error
+	// UpdateBatchJobProgress 更新已完成与失败设备
+	UpdateBatchJobProgress(id string, p BatchJobProgress) error
+	// SetBatchJobStatus 更新状态；clearRequest 为 true 时清除原请求（含凭据）
+	SetBatchJobStatus(id, status string, clearRequest bool) error
+	// InterruptRunningBatchJobs 将 exclude 以外的运行中记录标记为 interrupted，返回更新行数
+	InterruptRunningBatchJobs(exclude []string) (int64, error)
+	// InterruptedBatchJobs 已中断的记录，按创建时间升序
+	InterruptedBatchJobs() ([]model.BatchJob, error)
+}
+
+// BatchJobProgress 批量任务执行进度，设备列表为有序 JSON 数组
+type BatchJobProgress struct {
+	Done       string
+	Completed  int
+	FailedList string
+	Failed     int
+}
+
+// batchJobStore 批量任务记录仓库，各批量接口共用；由路由初始化时注入
+var batchJobStore struct {
+	mu    sync.RWMutex
+	store BatchJobStore
+}
+
+// SetBatchJobStore 设置批量任务记录仓库（batch_resume 开启时读写）
+func SetBatchJobStore(store BatchJobStore) {
+	batchJobStore.mu.Lock()
+	batchJobStore.store = store
+	batchJobStore.mu.Unlock()
+}
+
+func currentBatchJobStore() BatchJobStore {
+	batchJobStore.mu.RLock()
+	defer batchJobStore.mu.RUnlock()
+	return batchJobStore.store
+}
+
+// activeBatchJobs 本进程内正在执行的批量任务，避免同一任务并发续跑
+var activeBatchJobs sync.Map
+
+// activeBatchJobIDs 本进程内正在执行的批量任务 ID
+func activeBatchJobIDs() []string {
+	var ids []string
+	activeBatchJobs.Range(func(k, _ interface{}) bool {
+		ids = append(ids, k.(string))
+		return true
+	})
+	return ids
+}
+
+// BatchJournal 批量任务断点记录；未启用续跑或未配置 vault 主密钥时为 nil，方法均可在 nil 上调用
+type BatchJournal struct {
+	id     string
+	store  BatchJobStore
+	mu     sync.Mutex
+	done   map[string]bool
+	failed map[string]bool
+}
+
+// BatchDeviceKey 设备标识 ip:port（端口缺省按 22）
+func BatchDeviceKey(ip string, port int) string {
+	if port < 1 || port > 65535 {
+		port = 22
+	}
+	return fmt.Sprintf("%s:%d", strings.TrimSpace(ip), port)
+}
+
+// BatchDeviceTotal 按设备标识去重后的设备数（批内重复设备只登记一次进度）
+func BatchDeviceTotal(n int, key func(i int) (string, int)) int {
+	seen := make(map[string]bool, n)
+	for i := 0; i < n; i++ {
+		seen[BatchDeviceKey(key(i))] = true
+	}
+	return len(seen)
+}
+
+// StartBatchJournal 登记新的批量任务；相同 task_id 的旧记录被覆盖；retryOf 为失败重试的原任务 ID
+// 未开启 batch_resume 或登记失败时返回 nil，批量任务照常执行但不可续跑
+func StartBatchJournal(kind, id, name string, total int, req interface{}, retryOf string) *BatchJournal {
+	j, err := startBatchJournal(kind, id, name, total, req, retryOf)
+	if err != nil {
+		logger.Warn("Batch job journal disabled", "task_id", id, "error", err)
+	}
+	return j
+}
+
+// startBatchJournal 同 StartBatchJournal，登记失败时返回错误；未开启 batch_resume 时返回 nil, nil
+func startBatchJournal(kind, id, name string, total int, req interface{}, retryOf string) (*BatchJournal, error) {
+	cfg := config.Get()
+	store := currentBatchJobStore()
+	if cfg == nil || !cfg.BatchResume.Enable || store == nil {
+		return nil, nil
+	}
+	if _, loaded := activeBatchJobs.LoadOrStore(id, struct{}{}); loaded {
+		return nil, ErrBatchJobRunning
+	}
+	enc, err := EncryptBatchRequest(cfg, req)
+	if err != nil {
+		activeBatchJobs.Delete(id)
+		return nil, err
+	}
+	job := &model.BatchJob{ID: id, Kind: kind, TaskName: name, Status: model.BatchJobRunning, Request: enc, Total: total, Done: "[]", FailedList: "[]", RetryOf: retryOf}
+	if err := store.ReplaceBatchJob(job); err != nil {
+		activeBatchJobs.Delete(id)
+		return nil, fmt.Errorf("save batch job: %w", err)
+	}
+	return &BatchJournal{id: id, store: store, done: map[string]bool{}, failed: map[string]bool{}}, nil
+}
+
+// resumeBatchJournal 续跑已登记的任务：载入已完成设备并标记为运行中
+func resumeBatchJournal(store BatchJobStore, job *model.BatchJob) (*BatchJournal, error) {
+	if _, loaded := activeBatchJobs.LoadOrStore(job.ID, struct{}{}); loaded {
+		return nil, ErrBatchJobRunning
+	}
+	if err := store.MarkBatchJobResumed(job.ID); err != nil {
+		activeBatchJobs.Delete(job.ID)
+		return nil, err
+	}
+	return &BatchJournal{id: job.ID, store: store, done: decodeDeviceKeys(job.Done), failed: decodeDeviceKeys(job.FailedList)}, nil
+}
+
+// IsDone 设备是否已执行完成
+func (j *BatchJournal) IsDone(ip string, port int) bool {
+	if j == nil {
+		return false
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.done[BatchDeviceKey(ip, port)]
+}
+
+// DeviceDone 记录设备已执行完成（成功或失败均不再续跑）；失败设备另行记录，供失败重试
+func (j *BatchJournal) DeviceDone(ip string, port int, ok bool) {
+	if j == nil {
+		return
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	key := BatchDeviceKey(ip, port)
+	if j.done[key] {
+		return
+	}
+	j.done[key] = true
+	if !ok {
+		j.failed[key] = true
+	}
+	err := j.store.UpdateBatchJobProgress(j.id, BatchJobProgress{
+		Done:       encodeDeviceKeys(j.done),
+		Completed:  len(j.done),
+		FailedList: encodeDeviceKeys(j.failed),
+		Failed:     len(j.failed),
+	})
+	if err != nil {
+		logger.Warn("Failed to update batch job progress", "task_id", j.id, "device", key, "error", err)
+	}
+}
+
+// BackupDone 备份设备回调；执行窗口关闭未派发的设备留待续跑
+func (j *BatchJournal) BackupDone(r DeviceBackupResponse) {
+	if r.Status == StatusNotAttemptedWindowClosed {
+		return
+	}
+	j.DeviceDone(r.DeviceIP, r.Port, r.Success)
+}
+
+// Finish 全部设备完成时标记 completed，否则标记 interrupted 以便续跑；
+// 完成且无失败设备时清除请求（含凭据），有失败设备时保留请求供失败重试
+func (j *BatchJournal) Finish() {
+	if j == nil {
+		return
+	}
+	defer activeBatchJobs.Delete(j.id)
+	job, err := j.store.BatchJob(j.id)
+	if err == nil {
+		status, clear := model.BatchJobInterrupted, false
+		if job.Completed >= job.Total {
+			status, clear = model.BatchJobCompleted, job.Failed == 0
+		}
+		err = j.store.SetBatchJobStatus(j.id, status, clear)
+	}
+	if err != nil {
+		logger.Warn("Failed to finish batch job", "task_id", j.id, "error", err)
+	}
+}
+
+// decodeDeviceKeys 解析设备标识 JSON 数组
+func decodeDeviceKeys(s string) map[string]bool {
+	out := map[string]bool{}
+	var keys []string
+	_ = json.Unmarshal([]byte(s), &keys)
+	for _, k := range keys {
+		out[k] = true
+	}
+	return out
+}
+
+// encodeDeviceKeys 设备标识序列化为有序 JSON 数组
+func encodeDeviceKeys(m map[string]bool) string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	b, _ := json.Marshal(keys)
+	return string(b)
+}
+
+// EncryptBatchRequest 请求序列化后以 vault 主密钥加密（含设备凭据）
+func EncryptBatchRequest(cfg *config.Config, req interface{}) (string, error) {
+	cc, err := NewCredentialCipher(cfg)
+	if err != nil {
+		return "", err
+	}
+	b, err := json.Marshal(req)
+	if err != nil {
+		return "", err
+	}
+	return cc.Encrypt(string(b))
+}
+
+// DecryptBatchRequest 解密 EncryptBatchRequest 保存的请求
+func DecryptBatchRequest(enc string, req interface{}) error {
+	if enc == "" {
+		return fmt.Errorf("batch request not available")
+	}
+	cc, err := NewCredentialCipher(config.Get())
+	if err != nil {
+		return err
+	}
+	plain, err := cc.Decrypt(enc)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal([]byte(plain), req)
+}
+
+// BatchJobRequest 已登记的批量请求，由各类批量任务适配
+type BatchJobRequest interface {
+	// RetainDevices 仅保留 keep 返回 true 的设备（按设备标识），返回保留的设备数
+	RetainDevices(keep func(key string) bool) int
+	// SetDeadline 替换执行窗口截止时间
+	SetDeadline(deadline *time.Time)
+	// ForRetry 改写为失败重试任务
+	ForRetry(taskID, retryOf string)
+	// TaskName 任务名称
+	TaskName() string
+	// Payload 加密登记的原请求
+	Payload() interface{}
+	// Run 执行设备（同步），action 用于结果文案与日志
+	Run(journal *BatchJournal, action string)
+}
+
+// BatchJobLoader 解密某类批量任务的原请求
+type BatchJobLoader func(enc string) (BatchJobRequest, error)
+
+// BatchJobService 批量任务查询、断点续跑与失败重试
+type BatchJobService struct {
+	mu      sync.RWMutex
+	loaders map[string]BatchJobLoader
+}
+
+// NewBatchJobService 创建批量任务服务；backup 非 nil 时可续跑批量备份
+func NewBatchJobService(backup *BackupService) *BatchJobService {
+	s := &BatchJobService{loaders: map[string]BatchJobLoader{}}
+	s.RegisterLoader(model.BatchJobKindBackup, func(enc string) (BatchJobRequest, error) {
+		req := &BackupBatchRequest{}
+		if err := DecryptBatchRequest(enc, req); err != nil {
+			return nil, err
+		}
+		return &backupBatchJob{svc: backup, req: req}, nil
+	})
+	return s
+}
+
+// RegisterLoader 注册批量任务类型（如接口层的自定义批量采集）
+func (s *BatchJobService) RegisterLoader(kind string, loader BatchJobLoader) {
+	s.mu.Lock()
+	s.loaders[kind] = loader
+	s.mu.Unlock()
+}
+
+// load 按任务类型解密原请求
+func (s *BatchJobService) load(job *model.BatchJob) (BatchJobRequest, error) {
+	s.mu.RLock()
+	loader := s.loaders[job.Kind]
+	s.mu.RUnlock()
+	if loader == nil {
+		return nil, fmt.Errorf("unsupported batch job kind %q", job.Kind)
+	}
+	return loader(job.Request)
+}
+
+// store 当前仓库；未注入时批量任务不可用
+func (s *BatchJobService) store() (BatchJobStore, error) {
+	store := currentBatchJobStore()
+	if store == nil {
+		return nil, ErrBatchResumeDisabled
+	}
+	return store, nil
+}
+
+// List 分页列出批量任务
+func (s *BatchJobService) List(status string, page, size int) ([]model.BatchJob, int64, error) {
+	store, err := s.store()
+	if err != nil {
+		return nil, 0, err
+	}
+	return store.ListBatchJobs(status, (page-1)*size, size)
+}
+
+// Resume 后台执行未完成设备，返回任务记录与待执行设备数
+func (s *BatchJobService) Resume(id string, deadline *time.Time) (*model.BatchJob, int, error) {
+	store, err := s.store()
+	if err != nil {
+		return nil, 0, err
+	}
+	job, err := store.BatchJob(id)
+	if err != nil {
+		return nil, 0, err
+	}
+	remaining, err := s.resume(store, job, deadline)
+	return job, remaining, err
+}
+
+// resume 解密原请求并后台执行未完成设备，返回待执行设备数
+func (s *BatchJobService) resume(store BatchJobStore, job *model.BatchJob, deadline *time.Time) (int, error) {
+	if job.Status == model.BatchJobCompleted {
+		return 0, ErrBatchJobCompleted
+	}
+	if job.Status == model.BatchJobRunning {
+		return 0, ErrBatchJobRunning
+	}
+	req, err := s.load(job)
+	if err != nil {
+		return 0, err
+	}
+	if deadline != nil {
+		req.SetDeadline(deadline)
+	}
+	journal, err := resumeBatchJournal(store, job)
+	if err != nil {
+		return 0, err
+	}
+	remaining := req.RetainDevices(func(key string) bool { return !journal.done[key] })
+	go runBatchJob(req, journal, remaining, "续跑")
+	return remaining, nil
+}
+
+// runBatchJob 后台执行批量请求，结束时更新记录状态；无待执行设备时直接收尾
+func runBatchJob(req BatchJobRequest, journal *BatchJournal, devices int, action string) {
+	defer journal.Finish()
+	if devices == 0 {
+		return
+	}
+	req.Run(journal, action)
+}
+
+// Recover 将未结束且不在本进程执行的批量任务标记为 interrupted；auto 为 true 时逐个后台续跑
+// 启动时（须在 HTTP 服务开始接收请求前）与接任 leader 时调用
+func (s *BatchJobService) Recover(auto bool) {
+	store := currentBatchJobStore()
+	if store == nil {
+		return
+	}
+	// 本进程正在执行的任务（如由 follower 接任 leader 时）不标记
+	n, err := store.InterruptRunningBatchJobs(activeBatchJobIDs())
+	if err != nil {
+		logger.Warn("Failed to mark interrupted batch jobs", "error", err)
+		return
+	}
+	if n > 0 {
+		logger.Info("Interrupted batch jobs found", "count", n, "auto_resume", auto)
+	}
+	if !auto {
+		return
+	}
+	jobs, err := store.InterruptedBatchJobs()
+	if err != nil {
+		logger.Warn("Failed to load interrupted batch jobs", "error", err)
+		return
+	}
+	for i := range jobs {
+		if _, running := activeBatchJobs.Load(jobs[i].ID); running {
+			continue
+		}
+		if remaining, err := s.resume(store, &jobs[i], nil); err != nil {
+			logger.Warn("Failed to resume batch job", "task_id", jobs[i].ID, "error", err)
+		} else {
+			logger.Info("Batch job resumed", "task_id", jobs[i].ID, "kind", jobs[i].Kind, "remaining", remaining)
+		}
+	}
+}
+
+// BatchRetry 失败重试任务
+type BatchRetry struct {
+	ID      string `json:"id"`
+	RetryOf string `json:"retry_of"`
+	Kind    string `json:"kind"`
+	Devices int    `json:"devices"`
+}
+
+// RetryFailed 按原批量请求仅重新执行失败的设备，登记为新的批量任务后台执行；
+// id 为原任务或其重试任务（均按原任务计算失败设备），taskID 为空时按 {原任务}-retry-{n} 生成
+func (s *BatchJobService) RetryFailed(id, taskID string, deadline *time.Time) (*BatchRetry, error) {
+	if cfg := config.Get(); cfg == nil || !cfg.BatchResume.Enable {
+		return nil, ErrBatchResumeDisabled
+	}
+	store, err := s.store()
+	if err != nil {
+		return nil, err
+	}
+	root, err := store.BatchJob(id)
+	if err != nil {
+		return nil, err
+	}
+	if root.RetryOf != "" {
+		if root, err = store.BatchJob(root.RetryOf); err != nil {
+			return nil, err
+		}
+	}
+	// 整个调用期间占用原任务 ID：并发的续跑与重试请求返回运行中，避免失败设备被重复执行；
+	// 重试任务登记后以其自身 ID 标记运行中
+	if _, running := activeBatchJobs.LoadOrStore(root.ID, struct{}{}); running {
+		return nil, ErrBatchJobRunning
+	}
+	defer activeBatchJobs.Delete(root.ID)
+	if root, err = store.BatchJob(root.ID); err != nil {
+		return nil, err
+	}
+	if root.Status == model.BatchJobRunning {
+		return nil, ErrBatchJobRunning
+	}
+	retries, err := store.BatchJobRetries(root.ID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range retries {
+		if _, running := activeBatchJobs.Load(retries[i].ID); running || retries[i].Status == model.BatchJobRunning {
+			return nil, ErrBatchJobRunning
+		}
+	}
+	failed := retryFailedDevices(root, retries)
+	if len(failed) == 0 {
+		return nil, ErrNoFailedDevices
+	}
+	if root.Request == "" {
+		return nil, ErrRetryNotAvailable
+	}
+	retry := &BatchRetry{ID: strings.TrimSpace(taskID), RetryOf: root.ID, Kind: root.Kind}
+	if retry.ID == "" {
+		retry.ID = fmt.Sprintf("%s-retry-%d", root.ID, len(retries)+1)
+	}
+	if _, err := store.BatchJob(retry.ID); err == nil {
+		return retry, ErrRetryTaskIDExists
+	} else if !errors.Is(err, ErrBatchJobNotFound) {
+		return retry, err
+	}
+
+	req, err := s.load(root)
+	if err != nil {
+		return nil, err
+	}
+	retry.Devices = req.RetainDevices(func(key string) bool { return failed[key] })
+	req.ForRetry(retry.ID, root.ID)
+	if deadline != nil {
+		req.SetDeadline(deadline)
+	}
+	journal, err := startBatchJournal(root.Kind, retry.ID, req.TaskName(), retry.Devices, req.Payload(), root.ID)
+	if err != nil {
+		return nil, err
+	}
+	if journal == nil {
+		return nil, ErrBatchResumeDisabled
+	}
+	go runBatchJob(req, journal, retry.Devices, "失败重试")
+	return retry, nil
+}
+
+// retryFailedDevices 原任务失败设备按重试结果（按创建时间依次）更新，返回仍处于失败状态的设备
+func retryFailedDevices(root *model.BatchJob, retries []model.BatchJob) map[string]bool {
+	state := decodeDeviceKeys(root.FailedList)
+	for i := range retries {
+		failed := decodeDeviceKeys(retries[i].FailedList)
+		for k := range decodeDeviceKeys(retries[i].Done) {
+			state[k] = failed[k]
+		}
+	}
+	for k, v := range state {
+		if !v {
+			delete(state, k)
+		}
+	}
+	return state
+}
+
+// backupBatchJob 已登记的批量备份请求
+type backupBatchJob struct {
+	svc *BackupService
+	req *BackupBatchRequest
+}
+
+func (b *backupBatchJob) RetainDevices(keep func(key string) bool) int {
+	pending := b.req.Devices[:0:0]
+	for _, d := range b.req.Devices {
+		if keep(BatchDeviceKey(d.DeviceIP, d.Port)) {
+			pending = append(pending, d)
+		}
+	}
+	b.req.Devices = pending
+	return len(pending)
+}
+
+func (b *backupBatchJob) SetDeadline(deadline *time.Time) { b.req.Deadline = deadline }
+func (b *backupBatchJob) ForRetry(taskID, _ string)       { b.req.TaskID = taskID }
+func (b *backupBatchJob) TaskName() string                { return b.req.TaskName }
+func (b *backupBatchJob) Payload() interface{}            { return b.req }
+
+func (b *backupBatchJob) Run(journal *BatchJournal, action string) {
+	resp, err := b.svc.ExecuteBatchStream(context.Background(), b.req, journal.BackupDone)
+	if err != nil {
+		logger.Error("Background batch backup failed", "task_id", b.req.TaskID, "action", action, "error", err)
+		return
+	}
+	NotifyCallback(config.Get(), b.req.CallbackURL, NewBackupCallbackPayload(b.req, resp))
+	logger.Info("Background batch backup finished", "task_id", b.req.TaskID, "action", action, "code", resp.Code, "devices", resp.Total)
+}
@@ -0,0 +1,413 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/config"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/database"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/model"
+	"github.com/sshcollectorpro/sshcollectorpro/pkg/logger"
+	"github.com/sshcollectorpro/sshcollectorpro/simulate"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrBootstrapLocked 已完成首次初始化或已配置 debug.token，初始化接口不再可用
+	ErrBootstrapLocked = errors.New("bootstrap is locked")
+	// ErrBootstrapStorage 初始化时存储连通性校验失败
+	ErrBootstrapStorage = errors.New("storage connectivity check failed")
+)
+
+// BootstrapSimulatePath 初始化启动内置模拟器时读取的配置文件
+var BootstrapSimulatePath = "simulate/simulate.yaml"
+
+// 初始化步骤状态
+const (
+	BootstrapStepOK      = "ok"
+	BootstrapStepSkipped = "skipped"
+	BootstrapStepFailed  = "failed"
+)
+
+// bootstrapStateID 初始化状态表的唯一行
+const bootstrapStateID = 1
+
+// BootstrapRequest 首次初始化请求
+type BootstrapRequest struct {
+	AdminToken     string               `json:"admin_token,omitempty"`     // 管理令牌（至少 16 个字符），为空时自动生成
+	StorageBackend string               `json:"storage_backend,omitempty"` // 校验的存储后端，默认 backup.storage_backend
+	SeedPlatforms  *bool                `json:"seed_platforms,omitempty"`  // 按 collector.device_defaults 补全平台适配，默认 true
+	Simulator      bool                 `json:"simulator,omitempty"`       // 启动内置模拟器；未指定 test_device 时以模拟设备作为测试设备
+	TestDevice     *BootstrapTestDevice `json:"test_device,omitempty"`
+}
+
+// BootstrapTestDevice 登记到设备表的测试设备
+type BootstrapTestDevice struct {
+	DeviceIP       string `json:"device_ip"`
+	Port           int    `json:"device_port,omitempty"`
+	DeviceName     string `json:"device_name,omitempty"`
+	DevicePlatform string `json:"device_platform,omitempty"`
+	UserName       string `json:"user_name"`
+	Password       string `json:"password,omitempty"`
+}
+
+// BootstrapStep 初始化步骤结果
+type BootstrapStep struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// BootstrapResult 初始化结果；AdminToken 只在本次响应中返回，服务端仅保存其摘要
+type BootstrapResult struct {
+	Initialized  bool            `json:"initialized"`
+	AdminToken   string          `json:"admin_token,omitempty"`
+	TestDeviceID string          `json:"test_device_id,omitempty"`
+	Steps        []BootstrapStep `json:"steps"`
+}
+
+// BootstrapStatus 初始化状态
+type BootstrapStatus struct {
+	Available bool `json:"available"` // 初始化接口是否可用
+	model.BootstrapState
+	Reason string `json:"reason,omitempty"` // 不可用原因
+}
+
+// BootstrapService 首次初始化：生成管理令牌、校验存储连通性、补全平台适配、可选启动模拟器与登记测试设备，完成后锁定
+type BootstrapService struct {
+	mu     sync.Mutex
+	backup *BackupService
+}
+
+// NewBootstrapService 创建初始化服务；backup 为空时按当前配置创建存储写入器
+func NewBootstrapService(backup *BackupService) *BootstrapService {
+	return &BootstrapService{backup: backup}
+}
+
+// loadBootstrapState 读取初始化状态，表中无记录时返回零值
+func loadBootstrapState() (model.BootstrapState, error) {
+	var st model.BootstrapState
+	db := database.GetDB()
+	if db == nil {
+		return st, fmt.Errorf("database not initialized")
+	}
+	err := db.First(&st, bootstrapStateID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return model.BootstrapState{}, nil
+	}
+	return st, err
+}
+
+// Status 当前初始化状态
+func (s *BootstrapService) Status() (BootstrapStatus, error) {
+	st, err := loadBootstrapState()
+	if err != nil {
+		return BootstrapStatus{}, err
+	}
+	out := BootstrapStatus{BootstrapState: st, Available: true}
+	switch {
+	case st.Initialized:
+		out.Available, out.Reason = false, "already initialized"
+	case bootstrapTokenConfigured():
+		out.Available, out.Reason = false, "debug.token is configured"
+	}
+	return out, nil
+}
+
+// bootstrapTokenConfigured 已通过配置提供管理令牌的部署视为已初始化
+func bootstrapTokenConfigured() bool {
+	cfg := config.Get()
+	return cfg != nil && strings.TrimSpace(cfg.Debug.Token) != ""
+}
+
+// BootstrapInitialized 是否已完成首次初始化
+func BootstrapInitialized() bool {
+	if database.GetDB() == nil {
+		return false
+	}
+	st, err := loadBootstrapState()
+	return err == nil && st.Initialized
+}
+
+// BootstrapAdminTokenValid 令牌与初始化生成的管理令牌一致
+func BootstrapAdminTokenValid(token string) bool {
+	if strings.TrimSpace(token) == "" || database.GetDB() == nil {
+		return false
+	}
+	st, err := loadBootstrapState()
+	if err != nil || !st.Initialized || st.AdminTokenHash == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(hashAdminToken(token)), []byte(st.AdminTokenHash)) == 1
+}
+
+func hashAdminToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// Validate 校验初始化请求
+func (r *BootstrapRequest) Validate() error {
+	if t := r.AdminToken; t != "" && len(strings.TrimSpace(t)) < 16 {
+		return validationErrorf("admin_token must be at least 16 characters")
+	}
+	if d := r.TestDevice; d != nil {
+		if strings.TrimSpace(d.DeviceIP) == "" {
+			return validationErrorf("test_device.device_ip is required")
+		}
+		if d.Port < 0 || d.Port > 65535 {
+			return validationErrorf("test_device.device_port must be between 1 and 65535")
+		}
+		if strings.TrimSpace(d.UserName) == "" {
+			return validationErrorf("test_device.user_name is required")
+		}
+	}
+	return nil
+}
+
+// Run 执行初始化；存储校验失败时中止且不锁定，其余步骤失败仅记录在结果中
+func (s *BootstrapService) Run(ctx context.Context, req *BootstrapRequest, clientIP string) (*BootstrapResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	status, err := s.Status()
+	if err != nil {
+		return nil, err
+	}
+	if !status.Available {
+		return nil, fmt.Errorf("%w: %s", ErrBootstrapLocked, status.Reason)
+	}
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+	cfg := config.Get()
+	if cfg == nil {
+		return nil, fmt.Errorf("config not loaded")
+	}
+
+	res := &BootstrapResult{}
+	backend := backupBackend(cfg, &BackupBatchRequest{StorageBackend: req.StorageBackend})
+	if err := s.probeStorage(ctx, cfg, backend); err != nil {
+		res.Steps = append(res.Steps, BootstrapStep{Name: "storage", Status: BootstrapStepFailed, Detail: err.Error()})
+		return res, fmt.Errorf("%w: %v", ErrBootstrapStorage, err)
+	}
+	res.Steps = append(res.Steps, BootstrapStep{Name: "storage", Status: BootstrapStepOK, Detail: "backend " + backend + " writable"})
+
+	if req.SeedPlatforms == nil || *req.SeedPlatforms {
+		res.Steps = append(res.Steps, seedPlatforms(cfg))
+	} else {
+		res.Steps = append(res.Steps, BootstrapStep{Name: "platforms", Status: BootstrapStepSkipped})
+	}
+
+	dev := req.TestDevice
+	if req.Simulator {
+		step, simDev := startBootstrapSimulator()
+		res.Steps = append(res.Steps, step)
+		if dev == nil {
+			dev = simDev
+		}
+	}
+	if dev != nil {
+		var step BootstrapStep
+		step, res.TestDeviceID = registerTestDevice(dev)
+		res.Steps = append(res.Steps, step)
+	} else {
+		res.Steps = append(res.Steps, BootstrapStep{Name: "test_device", Status: BootstrapStepSkipped})
+	}
+
+	token := strings.TrimSpace(req.AdminToken)
+	if token == "" {
+		buf := make([]byte, 24)
+		if _, err := rand.Read(buf); err != nil {
+			return nil, fmt.Errorf("generate admin token: %w", err)
+		}
+		token = hex.EncodeToString(buf)
+	}
+	now := time.Now()
+	st := model.BootstrapState{
+		ID:             bootstrapStateID,
+		Initialized:    true,
+		AdminTokenHash: hashAdminToken(token),
+		StorageBackend: backend,
+		InitializedBy:  clientIP,
+		InitializedAt:  &now,
+	}
+	if err := database.WithRetry(func(d *gorm.DB) error { return d.Save(&st).Error }, 6, 100*time.Millisecond); err != nil {
+		return nil, fmt.Errorf("save bootstrap state: %w", err)
+	}
+	res.Steps = append(res.Steps, BootstrapStep{Name: "admin_token", Status: BootstrapStepOK})
+	res.Initialized, res.AdminToken = true, token
+	logger.Info("Bootstrap completed", "client_ip", clientIP, "storage_backend", backend)
+	return res, nil
+}
+
+// probeStorage 向存储后端写入探测对象；远端不可用时写入器回退本地并返回错误，此处视为失败
+func (s *BootstrapService) probeStorage(ctx context.Context, cfg *config.Config, backend string) error {
+	var w StorageWriter
+	if s.backup != nil {
+		w = s.backup.storageWriter
+	} else {
+		w = NewStorageWriter(cfg)
+	}
+	now := time.Now()
+	meta := StorageMeta{
+		SaveDir:      "bootstrap",
+		DateYYYYMMDD: now.Format("20060102"),
+		TimeHHMMSS:   now.Format("150405"),
+		TaskID:       "bootstrap",
+		DeviceName:   "storage-probe",
+		CommandSlug:  "probe",
+		Backend:      backend,
+		SkipFilter:   true,
+	}
+	_, err := w.Write(ctx, meta, "bootstrap storage probe "+now.Format(time.RFC3339)+"\n", "text/plain; charset=utf-8")
+	return err
+}
+
+// seedPlatforms 按 collector.device_defaults 补全平台适配表中缺失的平台（已存在的不覆盖）
+func seedPlatforms(cfg *config.Config) BootstrapStep {
+	step := BootstrapStep{Name: "platforms", Status: BootstrapStepOK}
+	collector, _ := config.Settings(cfg)["collector"].(map[string]interface{})
+	defaults, _ := collector["device_defaults"].(map[string]interface{})
+	if len(defaults) == 0 {
+		step.Status, step.Detail = BootstrapStepSkipped, "collector.device_defaults is empty"
+		return step
+	}
+	names := make([]string, 0, len(defaults))
+	for name := range defaults {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	db := database.GetDB()
+	var created, existing int
+	var failed []string
+	for _, name := range names {
+		var n int64
+		if err := db.Model(&model.SSHPlatform{}).Where("ssh_type = ?", name).Count(&n).Error; err == nil && n > 0 {
+			existing++
+			continue
+		}
+		params, _ := json.Marshal(defaults[name])
+		p := model.SSHPlatform{Type: name, Remark: "seeded by bootstrap", Params: string(params)}
+		if err := database.WithRetry(func(d *gorm.DB) error { return d.Create(&p).Error }, 6, 100*time.Millisecond); err != nil {
+			logger.Warn("Bootstrap: seed platform failed", "type", name, "error", err)
+			failed = append(failed, name)
+			continue
+		}
+		created++
+	}
+	step.Detail = fmt.Sprintf("created %d, existing %d", created, existing)
+	if len(failed) > 0 {
+		step.Status = BootstrapStepFailed
+		step.Detail += ", failed: " + strings.Join(failed, ", ")
+	}
+	return step
+}
+
+// startBootstrapSimulator 启动内置模拟器（已在运行时复用），返回首个命名空间中的首台模拟设备作为测试设备
+func startBootstrapSimulator() (BootstrapStep, *BootstrapTestDevice) {
+	step := BootstrapStep{Name: "simulator", Status: BootstrapStepOK}
+	if _, err := os.Stat(BootstrapSimulatePath); err != nil {
+		step.Status, step.Detail = BootstrapStepFailed, err.Error()
+		return step, nil
+	}
+	sc, err := simulate.LoadConfig(BootstrapSimulatePath)
+	if err != nil {
+		step.Status, step.Detail = BootstrapStepFailed, err.Error()
+		return step, nil
+	}
+	if simulate.Current() == nil {
+		if _, err := simulate.Start(sc); err != nil {
+			step.Status, step.Detail = BootstrapStepFailed, err.Error()
+			return step, nil
+		}
+		step.Detail = "started from " + BootstrapSimulatePath
+	} else {
+		step.Detail = "already running"
+	}
+
+	nsName := "default"
+	if _, ok := sc.Namespace[nsName]; !ok {
+		nsName = ""
+		for name := range sc.Namespace {
+			if nsName == "" || name < nsName {
+				nsName = name
+			}
+		}
+	}
+	var devName string
+	for name := range sc.DeviceName {
+		if devName == "" || name < devName {
+			devName = name
+		}
+	}
+	if nsName == "" || devName == "" {
+		return step, nil
+	}
+	dn := sc.DeviceName[devName]
+	return step, &BootstrapTestDevice{
+		DeviceIP:       "127.0.0.1",
+		Port:           sc.Namespace[nsName].Port,
+		DeviceName:     devName,
+		DevicePlatform: dn.DeviceType,
+		UserName:       devName, // 模拟器以用户名匹配设备名称
+		Password:       dn.Password,
+	}
+}
+
+// registerTestDevice 登记测试设备（IP + 端口 + 用户名已存在时复用）并探测 TCP 可达性；不可达不影响初始化
+func registerTestDevice(d *BootstrapTestDevice) (BootstrapStep, string) {
+	step := BootstrapStep{Name: "test_device", Status: BootstrapStepOK}
+	port := d.Port
+	if port == 0 {
+		port = 22
+	}
+	ip := strings.TrimSpace(d.DeviceIP)
+	db := database.GetDB()
+	var dev model.DeviceInfo
+	err := db.Where("ip = ? AND port = ? AND username = ?", ip, port, d.UserName).First(&dev).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		dev = model.DeviceInfo{
+			ID:         uuid.NewString(),
+			Name:       d.DeviceName,
+			IP:         ip,
+			Port:       port,
+			DeviceType: d.DevicePlatform,
+			Username:   d.UserName,
+			Password:   d.Password,
+			Enabled:    true,
+			Status:     "unknown",
+			Remarks:    "registered by bootstrap",
+		}
+		err = database.WithRetry(func(g *gorm.DB) error { return g.Create(&dev).Error }, 6, 100*time.Millisecond)
+	}
+	if err != nil {
+		step.Status, step.Detail = BootstrapStepFailed, err.Error()
+		return step, ""
+	}
+
+	addr := net.JoinHostPort(ip, strconv.Itoa(port))
+	conn, derr := net.DialTimeout("tcp", addr, 3*time.Second)
+	if derr != nil {
+		step.Detail = addr + " unreachable: " + derr.Error()
+		return step, dev.ID
+	}
+	conn.Close()
+	step.Detail = addr + " reachable"
+	return step, dev.ID
+}
@@ -0,0 +1,185 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// calendarDateLayout 日历日期格式
+const calendarDateLayout = "2006-01-02"
+
+// 非执行日原因
+const (
+	CalendarReasonNonWorkingDay = "non_working_day"
+	CalendarReasonHoliday       = "holiday"
+	CalendarReasonBlackout      = "blackout"
+)
+
+// weekdayNames 工作日写法（小写三字母）
+var weekdayNames = []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}
+
+// Calendar 执行日历：工作日、节假日、调休补班日与封网（变更冻结）日期
+// 批量备份通过 calendar 引用，非执行日直接跳过
+type Calendar struct {
+	Name          string          `json:"name"`
+	Description   string          `json:"description,omitempty"`
+	Timezone      string          `json:"timezone,omitempty"`       // IANA 时区，如 Asia/Shanghai；为空使用服务本地时区
+	WorkingDays   []string        `json:"working_days,omitempty"`   // mon..sun；为空表示每天均为工作日
+	Holidays      []CalendarDay   `json:"holidays,omitempty"`       // 节假日，不执行
+	ExtraWorkdays []CalendarDay   `json:"extra_workdays,omitempty"` // 调休补班日：即使不在 working_days 中也执行
+	Blackouts     []CalendarRange `json:"blackouts,omitempty"`      // 封网期间（含首尾日期），优先级最高
+}
+
+// CalendarDay 单日
+type CalendarDay struct {
+	Date string `json:"date"` // 2006-01-02
+	Name string `json:"name,omitempty"`
+}
+
+// CalendarRange 日期区间（含首尾）
+type CalendarRange struct {
+	From   string `json:"from"`
+	To     string `json:"to"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// CalendarDecision 某日的判定结果
+type CalendarDecision struct {
+	Date    string `json:"date"`
+	Weekday string `json:"weekday"`
+	Allowed bool   `json:"allowed"`
+	Reason  string `json:"reason,omitempty"` // non_working_day | holiday | blackout
+	Detail  string `json:"detail,omitempty"` // 节假日名称或封网原因
+}
+
+// Validate 校验名称、时区、工作日与日期格式
+func (c *Calendar) Validate() error {
+	if !playbookNamePattern.MatchString(c.Name) {
+		return validationErrorf("invalid calendar name: %q", c.Name)
+	}
+	if _, err := c.location(); err != nil {
+		return validationErrorf("invalid calendar timezone %q: %v", c.Timezone, err)
+	}
+	for _, d := range c.WorkingDays {
+		if weekdayIndex(d) < 0 {
+			return validationErrorf("invalid working day %q, expected one of %s", d, strings.Join(weekdayNames, ","))
+		}
+	}
+	for _, days := range [][]CalendarDay{c.Holidays, c.ExtraWorkdays} {
+		for _, d := range days {
+			if _, err := time.Parse(calendarDateLayout, d.Date); err != nil {
+				return validationErrorf("invalid calendar date %q, expected YYYY-MM-DD", d.Date)
+			}
+		}
+	}
+	for _, r := range c.Blackouts {
+		from, err1 := time.Parse(calendarDateLayout, r.From)
+		to, err2 := time.Parse(calendarDateLayout, r.To)
+		if err1 != nil || err2 != nil {
+			return validationErrorf("invalid blackout range %q - %q, expected YYYY-MM-DD", r.From, r.To)
+		}
+		if to.Before(from) {
+			return validationErrorf("blackout range %s - %s ends before it starts", r.From, r.To)
+		}
+	}
+	return nil
+}
+
+func (c *Calendar) location() (*time.Location, error) {
+	if strings.TrimSpace(c.Timezone) == "" {
+		return time.Local, nil
+	}
+	return time.LoadLocation(strings.TrimSpace(c.Timezone))
+}
+
+func weekdayIndex(name string) int {
+	n := strings.ToLower(strings.TrimSpace(name))
+	if len(n) > 3 {
+		n = n[:3]
+	}
+	for i, w := range weekdayNames {
+		if w == n {
+			return i
+		}
+	}
+	return -1
+}
+
+// Evaluate 判定 t 所在日期（按日历时区）是否允许执行
+// 优先级：封网 > 节假日 > 调休补班日 > 工作日
+func (c *Calendar) Evaluate(t time.Time) CalendarDecision {
+	if loc, err := c.location(); err == nil {
+		t = t.In(loc)
+	}
+	date := t.Format(calendarDateLayout)
+	d := CalendarDecision{Date: date, Weekday: weekdayNames[t.Weekday()], Allowed: true}
+	for _, r := range c.Blackouts {
+		if date >= r.From && date <= r.To {
+			d.Allowed, d.Reason, d.Detail = false, CalendarReasonBlackout, r.Reason
+			return d
+		}
+	}
+	for _, h := range c.Holidays {
+		if h.Date == date {
+			d.Allowed, d.Reason, d.Detail = false, CalendarReasonHoliday, h.Name
+			return d
+		}
+	}
+	for _, w := range c.ExtraWorkdays {
+		if w.Date == date {
+			d.Detail = w.Name
+			return d
+		}
+	}
+	if len(c.WorkingDays) > 0 {
+		for _, wd := range c.WorkingDays {
+			if weekdayIndex(wd) == int(t.Weekday()) {
+				return d
+			}
+		}
+		d.Allowed, d.Reason = false, CalendarReasonNonWorkingDay
+	}
+	return d
+}
+
+// ParseDate 解析日历时区中的日期（取当天中午，避免时区换算到相邻日期）
+func (c *Calendar) ParseDate(v string) (time.Time, error) {
+	loc, err := c.location()
+	if err != nil {
+		return time.Time{}, err
+	}
+	t, err := time.ParseInLocation(calendarDateLayout, strings.TrimSpace(v), loc)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return t.Add(12 * time.Hour), nil
+}
+
+// Upcoming 从 from 起连续 days 天的判定
+func (c *Calendar) Upcoming(from time.Time, days int) []CalendarDecision {
+	out := make([]CalendarDecision, 0, days)
+	for i := 0; i < days; i++ {
+		out = append(out, c.Evaluate(from.AddDate(0, 0, i)))
+	}
+	return out
+}
+
+// NextAllowed from 之后（含当天）一年内首个允许执行的日期；不存在时返回空串
+func (c *Calendar) NextAllowed(from time.Time) string {
+	for i := 0; i < 366; i++ {
+		if d := c.Evaluate(from.AddDate(0, 0, i)); d.Allowed {
+			return d.Date
+		}
+	}
+	return ""
+}
+
+// SkipMessage 非执行日的说明文本
+func (d CalendarDecision) SkipMessage(calendar string) string {
+	msg := fmt.Sprintf("%s is not an execution day in calendar %s (%s)", d.Date, calendar, d.Reason)
+	if d.Detail != "" {
+		msg += ": " + d.Detail
+	}
+	return msg
+}
@@ -0,0 +1,243 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sshcollectorpro/sshcollectorpro/internal/config"
+	"github.com/sshcollectorpro/sshcollectorpro/pkg/logger"
+)
+
+// 回调签名相关请求头
+const (
+	CallbackHeaderTimestamp = "X-Callback-Timestamp"
+	CallbackHeaderSignature = "X-Callback-Signature"
+)
+
+// CallbackDeviceSummary 回调中的单设备摘要（不含原始输出，避免回调体过大）
+type CallbackDeviceSummary struct {
+	DeviceIP       string `json:"device_ip"`
+	DeviceName     string `json:"device_name,omitempty"`
+	DevicePlatform string `json:"device_platform,omitempty"`
+	Success        bool   `json:"success"`
+	Error          string `json:"error,omitempty"`
+}
+
+// CallbackPayload 批量任务完成后推送给上游的内容
+type CallbackPayload struct {
+	TaskID       string                  `json:"task_id"`
+	TaskName     string                  `json:"task_name,omitempty"`
+	TaskType     string                  `json:"task_type"` // collect_custom | collect_system | backup | format | deploy | runbook
+	Code         string                  `json:"code"`
+	Message      string                  `json:"message,omitempty"`
+	Total        int                     `json:"total"`
+	SuccessCount int                     `json:"success_count"`
+	FailedCount  int                     `json:"failed_count"`
+	Devices      []CallbackDeviceSummary `json:"devices"`
+	FinishedAt   time.Time               `json:"finished_at"`
+}
+
+// ValidateCallbackURL 校验回调地址：为空视为不回调；仅支持 http/https
+func ValidateCallbackURL(raw string) error {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return nil
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return fmt.Errorf("invalid callback_url: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("invalid callback_url: scheme must be http or https")
+	}
+	if strings.TrimSpace(u.Host) == "" {
+		return fmt.Errorf("invalid callback_url: host is required")
+	}
+	return nil
+}
+
+// SignCallback 计算回调签名：HMAC-SHA256(secret, timestamp + "." + body)，十六进制编码
+func SignCallback(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// NotifyCallback 异步推送批量任务摘要；callbackURL 为空时直接返回
+// 回调在独立 goroutine 中执行，不阻塞接口响应，失败按配置重试后仅记录日志
+func NotifyCallback(cfg *config.Config, callbackURL string, payload *CallbackPayload) {
+	callbackURL = strings.TrimSpace(callbackURL)
+	if callbackURL == "" || payload == nil {
+		return
+	}
+	if payload.FinishedAt.IsZero() {
+		payload.FinishedAt = time.Now()
+	}
+	cbCfg := config.CallbackConfig{}
+	if cfg != nil {
+		cbCfg = cfg.Callback
+	}
+	go func() {
+		if err := sendCallback(context.Background(), cbCfg, callbackURL, payload); err != nil {
+			logger.Warn("Task callback failed", "task_id", payload.TaskID, "url", callbackURL, "error", err)
+			return
+		}
+		logger.Info("Task callback delivered", "task_id", payload.TaskID, "url", callbackURL)
+	}()
+}
+
+// sendCallback 同步发送回调（含重试）
+func sendCallback(ctx context.Context, cbCfg config.CallbackConfig, callbackURL string, payload *CallbackPayload) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("marshal callback payload: %w", err)
+	}
+	timeout := cbCfg.Timeout
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+	retries := cbCfg.Retries
+	if retries < 0 {
+		retries = 0
+	}
+	delay := cbCfg.RetryDelay
+	if delay <= 0 {
+		delay = 2 * time.Second
+	}
+	client := &http.Client{Timeout: timeout}
+
+	var lastErr error
+	for attempt := 0; attempt <= retries; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(delay * time.Duration(attempt)):
+			}
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, callbackURL, bytes.NewReader(body))
+		if err != nil {
+			return fmt.Errorf("build callback request: %w", err)
+		}
+		ts := strconv.FormatInt(time.Now().Unix(), 10)
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(CallbackHeaderTimestamp, ts)
+		if secret := strings.TrimSpace(cbCfg.Secret); secret != "" {
+			req.Header.Set(CallbackHeaderSignature, SignCallback(secret, ts, body))
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		resp.Body.Close()
+		if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+			return nil
+		}
+		lastErr = fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return fmt.Errorf("after %d attempts: %w", retries+1, lastErr)
+}
+
+// newCallbackPayload 根据设备摘要计算统计与返回码
+func newCallbackPayload(taskID, taskName, taskType string, devices []CallbackDeviceSummary) *CallbackPayload {
+	p := &CallbackPayload{
+		TaskID:   taskID,
+		TaskName: taskName,
+		TaskType: taskType,
+		Total:    len(devices),
+		Devices:  devices,
+	}
+	for _, d := range devices {
+		if d.Success {
+			p.SuccessCount++
+		}
+	}
+	p.FailedCount = p.Total - p.SuccessCount
+	p.Code = NewBatchOutcome("", p.Total, p.SuccessCount).Code
+	return p
+}
+
+// NewCollectCallbackPayload 由采集批量接口的设备结果构造回调内容
+func NewCollectCallbackPayload(taskID, taskName, taskType string, responses []map[string]interface{}) *CallbackPayload {
+	devices := make([]CallbackDeviceSummary, 0, len(responses))
+	for _, r := range responses {
+		if r == nil {
+			continue
+		}
+		d := CallbackDeviceSummary{}
+		d.DeviceIP, _ = r["device_ip"].(string)
+		d.DeviceName, _ = r["device_name"].(string)
+		d.DevicePlatform, _ = r["device_platform"].(string)
+		d.Success, _ = r["success"].(bool)
+		d.Error, _ = r["error"].(string)
+		devices = append(devices, d)
+	}
+	return newCallbackPayload(taskID, taskName, taskType, devices)
+}
+
+// NewBackupCallbackPayload 由批量备份结果构造回调内容
+func NewBackupCallbackPayload(req *BackupBatchRequest, resp *BackupBatchResponse) *CallbackPayload {
+	devices := make([]CallbackDeviceSummary, 0, len(resp.Data))
+	for _, d := range resp.Data {
+		devices = append(devices, CallbackDeviceSummary{
+			DeviceIP:       d.DeviceIP,
+			DeviceName:     d.DeviceName,
+			DevicePlatform: d.DevicePlatform,
+			Success:        d.Success,
+			Error:          d.Error,
+		})
+	}
+	p := newCallbackPayload(req.TaskID, req.TaskName, "backup", devices)
+	p.Message = resp.Message
+	return p
+}
+
+// NewFormatCallbackPayload 由批量格式化结果构造回调内容（登录、采集或解析失败均视为设备失败）
+func NewFormatCallbackPayload(req *FormatBatchRequest, resp *FormatBatchResponse) *CallbackPayload {
+	failed := resp.failedDevices()
+	devices := make([]CallbackDeviceSummary, 0, len(req.Devices))
+	for _, d := range req.Devices {
+		errMsg, bad := failed[formatDeviceKey(d.DeviceIP, d.DeviceName)]
+		devices = append(devices, CallbackDeviceSummary{
+			DeviceIP:       d.DeviceIP,
+			DeviceName:     d.DeviceName,
+			DevicePlatform: d.DevicePlatform,
+			Success:        !bad,
+			Error:          errMsg,
+		})
+	}
+	p := newCallbackPayload(req.TaskID, req.TaskName, "format", devices)
+	p.Message = resp.Message
+	return p
+}
+
+// NewDeployCallbackPayload 由配置下发结果构造回调内容（存在错误或任一命令失败即视为设备失败）
+func NewDeployCallbackPayload(req *DeployFastRequest, resp *DeployFastResponse) *CallbackPayload {
+	devices := make([]CallbackDeviceSummary, 0, len(resp.Results))
+	for _, r := range resp.Results {
+		ok, errMsg := r.Outcome()
+		devices = append(devices, CallbackDeviceSummary{
+			DeviceIP:       r.DeviceIP,
+			DeviceName:     r.DeviceName,
+			DevicePlatform: r.DevicePlatform,
+			Success:        ok,
+			Error:          errMsg,
+		})
+	}
+	p := newCallbackPayload(req.TaskID, req.TaskName, "deploy", devices)
+	p.Message = resp.Message
+	return p
+}
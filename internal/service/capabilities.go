@@ -0,0 +1,124 @@
+package service
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/sshcollectorpro/sshcollectorpro/internal/config"
+	"github.com/sshcollectorpro/sshcollectorpro/pkg/ssh"
+)
+
+// 能力取值来源
+const (
+	CapabilitySourceConfigured = "configured"
+	CapabilitySourceDefault    = "default"
+)
+
+// CapabilityFlag 生效的能力取值及来源（configured 为平台显式配置，default 为引擎默认）
+type CapabilityFlag struct {
+	Value  bool   `json:"value"`
+	Source string `json:"source"`
+}
+
+// PlatformCapabilities 平台生效的能力标记
+type PlatformCapabilities struct {
+	Platform string `json:"platform"`
+	// Configured 平台是否存在于 device_defaults；未配置的平台全部取引擎默认值
+	Configured           bool           `json:"configured"`
+	Netconf              bool           `json:"netconf"`
+	NetconfPort          int            `json:"netconf_port"`
+	SupportsExec         CapabilityFlag `json:"supports_exec"`
+	SupportsMultiSession CapabilityFlag `json:"supports_multisession"`
+	SupportsCommit       CapabilityFlag `json:"supports_commit"`
+	NeedsCRLF            CapabilityFlag `json:"needs_crlf"`
+	WideTerminal         CapabilityFlag `json:"wide_terminal"`
+	// LineEnding 生效的命令结束符（crlf|lf），由 line_ending 或 needs_crlf 决定
+	LineEnding string `json:"line_ending"`
+}
+
+func capabilityFlag(v *bool, def bool) CapabilityFlag {
+	if v == nil {
+		return CapabilityFlag{Value: def, Source: CapabilitySourceDefault}
+	}
+	return CapabilityFlag{Value: *v, Source: CapabilitySourceConfigured}
+}
+
+// resolveCapabilities 合并平台配置与引擎默认值；line_ending 优先于 capabilities.needs_crlf
+func resolveCapabilities(platform string, dd config.PlatformDefaultsConfig, configured bool) PlatformCapabilities {
+	c := dd.Capabilities
+	port := c.NetconfPort
+	if port <= 0 || port > 65535 {
+		port = defaultNetconfPort
+	}
+	out := PlatformCapabilities{
+		Platform:             platform,
+		Configured:           configured,
+		Netconf:              c.Netconf,
+		NetconfPort:          port,
+		SupportsExec:         capabilityFlag(c.SupportsExec, true),
+		SupportsMultiSession: capabilityFlag(c.SupportsMultiSession, true),
+		SupportsCommit:       capabilityFlag(c.SupportsCommit, false),
+		NeedsCRLF:            capabilityFlag(c.NeedsCRLF, true),
+		WideTerminal:         capabilityFlag(c.WideTerminal, false),
+	}
+	if le := strings.ToLower(strings.TrimSpace(dd.LineEnding)); le != "" {
+		out.NeedsCRLF = CapabilityFlag{Value: le != config.LineEndingLF, Source: CapabilitySourceConfigured}
+	}
+	out.LineEnding = config.LineEndingCRLF
+	if !out.NeedsCRLF.Value {
+		out.LineEnding = config.LineEndingLF
+	}
+	return out
+}
+
+// CapabilitiesFor 指定平台生效的能力标记（平台键小写精确匹配）
+func CapabilitiesFor(cfg *config.Config, platform string) PlatformCapabilities {
+	p := strings.ToLower(strings.TrimSpace(platform))
+	var dd config.PlatformDefaultsConfig
+	ok := false
+	if cfg != nil {
+		dd, ok = cfg.Collector.DeviceDefaults[p]
+	}
+	return resolveCapabilities(p, dd, ok)
+}
+
+// ListCapabilities 所有已配置平台生效的能力标记（按平台名排序）
+func ListCapabilities(cfg *config.Config) []PlatformCapabilities {
+	if cfg == nil {
+		return nil
+	}
+	out := make([]PlatformCapabilities, 0, len(cfg.Collector.DeviceDefaults))
+	for p, dd := range cfg.Collector.DeviceDefaults {
+		out = append(out, resolveCapabilities(p, dd, true))
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Platform < out[j].Platform })
+	return out
+}
+
+// applyConnection 不支持多会话的平台释放时关闭连接
+func (c PlatformCapabilities) applyConnection(info *ssh.ConnectionInfo) {
+	info.SingleSession = !c.SupportsMultiSession.Value
+}
+
+// applyInteractive 按能力设置交互会话的行结束符与终端宽度
+func (c PlatformCapabilities) applyInteractive(opts *ssh.InteractiveOptions) {
+	if !c.NeedsCRLF.Value {
+		opts.LineEnding = "\n"
+	}
+	if c.WideTerminal.Value {
+		opts.TerminalWidth = ssh.WideTerminalWidth
+	}
+}
+
+// commitCommand 支持 commit 的平台在配置命令后追加 commit（命令中已包含时不重复）
+func (c PlatformCapabilities) commitCommand(cmds []string) []string {
+	if !c.SupportsCommit.Value || len(cmds) == 0 {
+		return cmds
+	}
+	for _, cmd := range cmds {
+		if k := canonical(cmd); k == "commit" || strings.HasPrefix(k, "commit ") {
+			return cmds
+		}
+	}
+	return append(append([]string{}, cmds...), "commit")
+}
@@ -0,0 +1,169 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sshcollectorpro/sshcollectorpro/internal/config"
+	"github.com/sshcollectorpro/sshcollectorpro/pkg/logger"
+)
+
+// ErrDeviceCircuitOpen 设备连续建连失败已熔断，冷却期内不再拨号
+var ErrDeviceCircuitOpen = errors.New("DEVICE_CIRCUIT_OPEN")
+
+// StatusDeviceCircuitOpen 设备因熔断未执行
+const StatusDeviceCircuitOpen = "DEVICE_CIRCUIT_OPEN"
+
+// 熔断器状态
+const (
+	CircuitClosed   = "closed"
+	CircuitOpen     = "open"
+	CircuitHalfOpen = "half_open" // 冷却结束，等待试探连接结果
+)
+
+// CircuitBreakerEntry 单台设备的熔断状态
+type CircuitBreakerEntry struct {
+	DeviceIP  string     `json:"device_ip"`
+	State     string     `json:"state"`
+	Failures  int        `json:"failures"`
+	OpenUntil *time.Time `json:"open_until,omitempty"`
+	LastError string     `json:"last_error,omitempty"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+// CircuitBreakerStats 设备熔断快照，供 /collector/stats 与熔断管理接口展示
+type CircuitBreakerStats struct {
+	Enable           bool                  `json:"enable"`
+	FailureThreshold int                   `json:"failure_threshold"`
+	Cooldown         string                `json:"cooldown"`
+	Open             int                   `json:"open"`
+	Devices          []CircuitBreakerEntry `json:"devices"`
+}
+
+type circuitState struct {
+	failures  int
+	openUntil time.Time
+	probing   bool // 冷却结束后已放行一次试探连接
+	lastError string
+	updatedAt time.Time
+}
+
+// circuitBreakers 进程内共享：各服务对同一设备的建连失败合并计算
+var circuitBreakers = struct {
+	mu      sync.Mutex
+	entries map[string]*circuitState
+}{entries: make(map[string]*circuitState)}
+
+func circuitKey(ip string) string { return strings.ToLower(strings.TrimSpace(ip)) }
+
+// circuitBreakerConfig 读取当前全局配置（跟随热更新）；未加载配置或阈值无效时视为关闭
+func circuitBreakerConfig() (config.SSHCircuitBreakerConfig, bool) {
+	cfg := config.Get()
+	if cfg == nil {
+		return config.SSHCircuitBreakerConfig{}, false
+	}
+	cb := cfg.SSH.CircuitBreaker
+	return cb, cb.Enable && cb.FailureThreshold > 0 && cb.Cooldown > 0
+}
+
+// checkCircuitBreaker 建连前调用：熔断中返回 ErrDeviceCircuitOpen；冷却结束后仅放行一次试探连接
+func checkCircuitBreaker(ip string) error {
+	if _, on := circuitBreakerConfig(); !on {
+		return nil
+	}
+	circuitBreakers.mu.Lock()
+	defer circuitBreakers.mu.Unlock()
+	st, ok := circuitBreakers.entries[circuitKey(ip)]
+	if !ok || st.openUntil.IsZero() {
+		return nil
+	}
+	if time.Now().Before(st.openUntil) || st.probing {
+		return fmt.Errorf("%w: device %s skipped after %d consecutive connection failures (open until %s): %s",
+			ErrDeviceCircuitOpen, ip, st.failures, st.openUntil.Format(time.RFC3339), st.lastError)
+	}
+	st.probing = true
+	return nil
+}
+
+// recordCircuitResult 建连后调用：成功关闭熔断；设备不可达、建连超时累加计数，达到阈值（或试探失败）时熔断；
+// 认证失败、调用方取消等其他错误不计入但结束试探
+func recordCircuitResult(ip string, err error) {
+	cb, on := circuitBreakerConfig()
+	if !on || errors.Is(err, ErrDeviceCircuitOpen) {
+		return
+	}
+	key := circuitKey(ip)
+	circuitBreakers.mu.Lock()
+	defer circuitBreakers.mu.Unlock()
+	st, ok := circuitBreakers.entries[key]
+	if err == nil {
+		delete(circuitBreakers.entries, key)
+		return
+	}
+	if kind := classifyConnectError(err); !errors.Is(kind, ErrDeviceUnreachable) && !errors.Is(kind, ErrTimeout) {
+		if ok {
+			st.probing = false
+		}
+		return
+	}
+	if !ok {
+		st = &circuitState{}
+		circuitBreakers.entries[key] = st
+	}
+	st.failures++
+	st.lastError = err.Error()
+	st.updatedAt = time.Now()
+	if st.failures >= cb.FailureThreshold {
+		if st.openUntil.IsZero() {
+			logger.Warn("Device circuit opened after consecutive connection failures", "device_ip", ip, "failures", st.failures, "cooldown", cb.Cooldown)
+		}
+		st.openUntil = st.updatedAt.Add(cb.Cooldown)
+	}
+	st.probing = false
+}
+
+// CircuitBreakerSnapshot 返回各设备的失败计数与熔断状态
+func CircuitBreakerSnapshot() CircuitBreakerStats {
+	cb, on := circuitBreakerConfig()
+	out := CircuitBreakerStats{Enable: on, FailureThreshold: cb.FailureThreshold, Cooldown: cb.Cooldown.String(), Devices: []CircuitBreakerEntry{}}
+	now := time.Now()
+	circuitBreakers.mu.Lock()
+	for key, st := range circuitBreakers.entries {
+		e := CircuitBreakerEntry{DeviceIP: key, State: CircuitClosed, Failures: st.failures, LastError: st.lastError, UpdatedAt: st.updatedAt}
+		if !st.openUntil.IsZero() {
+			until := st.openUntil
+			e.OpenUntil = &until
+			if now.Before(until) {
+				e.State = CircuitOpen
+				out.Open++
+			} else {
+				e.State = CircuitHalfOpen
+			}
+		}
+		out.Devices = append(out.Devices, e)
+	}
+	circuitBreakers.mu.Unlock()
+	sort.Slice(out.Devices, func(i, j int) bool { return out.Devices[i].DeviceIP < out.Devices[j].DeviceIP })
+	return out
+}
+
+// ResetCircuitBreaker 清除指定设备的失败计数与熔断状态；ip 为空时清除全部，返回清除的条目数
+func ResetCircuitBreaker(ip string) int {
+	circuitBreakers.mu.Lock()
+	defer circuitBreakers.mu.Unlock()
+	if strings.TrimSpace(ip) == "" {
+		n := len(circuitBreakers.entries)
+		circuitBreakers.entries = make(map[string]*circuitState)
+		return n
+	}
+	key := circuitKey(ip)
+	if _, ok := circuitBreakers.entries[key]; !ok {
+		return 0
+	}
+	delete(circuitBreakers.entries, key)
+	return 1
+}
@@ -0,0 +1,127 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// CLIItem 命令项：JSON 中可写为字符串，或写为 {"cli": "...", "timeout": 300} 为慢命令单独指定超时（秒）
+// fresh_ttl（秒）用于差异化备份：最近一次落盘结果未超过该时长时跳过执行
+// include_raw_bytes 保留未经归一化的原始字节（采集返回 base64，备份另存对象），便于编写 FSM 模板
+// max_lines/tail_lines 限制响应中的输出行数（保留首尾），落盘对象仍为完整输出
+type CLIItem struct {
+	CLI             string `json:"cli"`
+	Timeout         int    `json:"timeout,omitempty"`
+	FreshTTL        int    `json:"fresh_ttl,omitempty"`
+	IncludeRawBytes bool   `json:"include_raw_bytes,omitempty"`
+	MaxLines        int    `json:"max_lines,omitempty"`
+	TailLines       int    `json:"tail_lines,omitempty"`
+}
+
+// UnmarshalJSON 兼容字符串与对象两种写法
+func (c *CLIItem) UnmarshalJSON(b []byte) error {
+	b = bytes.TrimSpace(b)
+	if len(b) > 0 && b[0] == '"' {
+		c.Timeout, c.FreshTTL, c.IncludeRawBytes, c.MaxLines, c.TailLines = 0, 0, false, 0, 0
+		if err := json.Unmarshal(b, &c.CLI); err != nil {
+			return err
+		}
+		return checkCLIText(c.CLI)
+	}
+	var obj struct {
+		CLI             string `json:"cli"`
+		Timeout         int    `json:"timeout"`
+		FreshTTL        int    `json:"fresh_ttl"`
+		IncludeRawBytes bool   `json:"include_raw_bytes"`
+		MaxLines        int    `json:"max_lines"`
+		TailLines       int    `json:"tail_lines"`
+	}
+	if err := json.Unmarshal(b, &obj); err != nil {
+		return fmt.Errorf("cli_list item must be a string or {\"cli\",\"timeout\"}: %w", err)
+	}
+	if obj.Timeout < 0 || obj.FreshTTL < 0 || obj.MaxLines < 0 || obj.TailLines < 0 {
+		return fmt.Errorf("cli_list item %q: timeout, fresh_ttl, max_lines and tail_lines must be >= 0", obj.CLI)
+	}
+	if obj.TailLines > obj.MaxLines && obj.MaxLines > 0 {
+		return fmt.Errorf("cli_list item %q: tail_lines must not exceed max_lines", obj.CLI)
+	}
+	c.CLI, c.Timeout, c.FreshTTL, c.IncludeRawBytes = obj.CLI, obj.Timeout, obj.FreshTTL, obj.IncludeRawBytes
+	c.MaxLines, c.TailLines = obj.MaxLines, obj.TailLines
+	return checkCLIText(c.CLI)
+}
+
+// checkCLIText 命令文本不得以 NUL 开头（非合法设备命令）
+func checkCLIText(cmd string) error {
+	if strings.HasPrefix(cmd, "\x00") {
+		return fmt.Errorf("cli_list item %q: command must not start with a NUL character", cmd)
+	}
+	return nil
+}
+
+// MarshalJSON 未指定超时、新鲜度、原始字节与行数上限时输出为字符串，保持原有格式
+func (c CLIItem) MarshalJSON() ([]byte, error) {
+	if c.Timeout <= 0 && c.FreshTTL <= 0 && !c.IncludeRawBytes && c.MaxLines <= 0 {
+		return json.Marshal(c.CLI)
+	}
+	return json.Marshal(struct {
+		CLI             string `json:"cli"`
+		Timeout         int    `json:"timeout,omitempty"`
+		FreshTTL        int    `json:"fresh_ttl,omitempty"`
+		IncludeRawBytes bool   `json:"include_raw_bytes,omitempty"`
+		MaxLines        int    `json:"max_lines,omitempty"`
+		TailLines       int    `json:"tail_lines,omitempty"`
+	}{c.CLI, c.Timeout, c.FreshTTL, c.IncludeRawBytes, c.MaxLines, c.TailLines})
+}
+
+// CLIList 命令列表
+type CLIList []CLIItem
+
+// NewCLIList 由纯命令构造列表
+func NewCLIList(cmds ...string) CLIList {
+	out := make(CLIList, 0, len(cmds))
+	for _, c := range cmds {
+		out = append(out, CLIItem{CLI: c})
+	}
+	return out
+}
+
+// Commands 返回命令文本
+func (l CLIList) Commands() []string {
+	out := make([]string, 0, len(l))
+	for _, it := range l {
+		out = append(out, it.CLI)
+	}
+	return out
+}
+
+// Timeouts 返回单条命令超时（秒），键为小写去空白的命令；无覆盖时返回 nil
+func (l CLIList) Timeouts() map[string]int {
+	var out map[string]int
+	for _, it := range l {
+		if it.Timeout <= 0 {
+			continue
+		}
+		if out == nil {
+			out = make(map[string]int)
+		}
+		out[strings.ToLower(strings.TrimSpace(it.CLI))] = it.Timeout
+	}
+	return out
+}
+
+// RawCommands 返回需要捕获原始字节的命令，键为小写去空白的命令；无选中时返回 nil
+func (l CLIList) RawCommands() map[string]bool {
+	var out map[string]bool
+	for _, it := range l {
+		if !it.IncludeRawBytes {
+			continue
+		}
+		if out == nil {
+			out = make(map[string]bool)
+		}
+		out[strings.ToLower(strings.TrimSpace(it.CLI))] = true
+	}
+	return out
+}
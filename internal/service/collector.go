@@ -2,6 +2,7 @@ package service
 
 import (
 	"context"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"strings"
@@ -12,17 +13,28 @@ import (
 	"github.com/sshcollectorpro/sshcollectorpro/internal/model"
 	"github.com/sshcollectorpro/sshcollectorpro/pkg/logger"
 	"github.com/sshcollectorpro/sshcollectorpro/pkg/ssh"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 // CollectorService 采集器服务
 type CollectorService struct {
-	config   *config.Config
+	provider *config.Provider // 配置快照提供者，热更新时整体替换
 	sshPool  *ssh.Pool
 	interact *InteractBasic
 	mutex    sync.RWMutex
 	running  bool
 	tasks    map[string]*TaskContext
-	workers  chan struct{}
+	workers  *workerSlots
+	// taskStore 任务记录持久化（为空时仅输出日志）
+	taskStore TaskStore
+	// storageWriter 复用备份存储写入器（store=true 时落盘命令输出）
+	storageWriter StorageWriter
+	// resultIndex 命令结果索引（store=true 时记录落盘对象并按 fresh_ttl 跳过命令）
+	resultIndex CommandResultIndex
+	// portInventory 资产登记的备用端口与回写（为空时仅使用请求中的 port_candidates）
+	portInventory PortInventory
+	// detected 平台探测结果缓存：设备IP:端口 -> detectedPlatform
+	detected sync.Map
 }
 
 // TaskContext 任务上下文
@@ -43,16 +55,31 @@ type CollectRequest struct {
 	DeviceIP        string                 `json:"device_ip"`
 	DeviceName      string                 `json:"device_name,omitempty"`
 	DevicePlatform  string                 `json:"device_platform,omitempty"`
-	CollectProtocol string                 `json:"collect_protocol,omitempty"` // ssh
+	CollectProtocol string                 `json:"collect_protocol,omitempty"` // ssh | netconf | snmp
 	Port            int                    `json:"device_port,omitempty"`
 	UserName        string                 `json:"user_name"`
 	Password        string                 `json:"password"`
 	EnablePassword  string                 `json:"enable_password,omitempty"`
-	CliList         []string               `json:"cli_list"`
+	CliList         CLIList                `json:"cli_list"`
+	Playbook        string                 `json:"playbook,omitempty"` // 引用命令集（接口层展开到 cli_list）
 	RetryFlag       *int                   `json:"retry_flag,omitempty"`
+	RetryPolicy     *RetryPolicy           `json:"retry_policy,omitempty"` // 退避与重试条件，未设置的字段沿用平台/全局配置
 	TaskTimeout     *int                   `json:"task_timeout,omitempty"`
 	DeviceTimeout   *int                   `json:"device_timeout,omitempty"`
 	Metadata        map[string]interface{} `json:"metadata"`
+	Store           bool                   `json:"store,omitempty"`           // 落盘每条命令输出并返回 stored_objects
+	SaveDir         string                 `json:"save_dir,omitempty"`        // 落盘目录（同备份 save_dir）
+	StorageBackend  string                 `json:"storage_backend,omitempty"` // local | minio（默认读取 backup 配置）
+	FreshTTL        int                    `json:"fresh_ttl,omitempty"`       // 默认新鲜度（秒）：store=true 时最近落盘结果未过期的命令跳过执行
+	XPaths          map[string]string      `json:"xpaths,omitempty"`          // netconf：字段名 -> 路径表达式，结果写入 fields
+	SNMP            *SNMPOptions           `json:"snmp,omitempty"`            // snmp：版本与凭据
+	SimulateRecord  *SimulateRecordOptions `json:"simulate_record,omitempty"` // 录制回显到模拟器目录
+	PortCandidates  []int                  `json:"port_candidates,omitempty"` // 备用端口：device_port 拒绝连接时按顺序尝试
+	Deprecations    `json:"-"` // 请求中使用的旧字段名（兼容层填充）
+
+	internal      bool           // 内部编排采集（如下发前后状态采集），不发布设备事件
+	connectedPort int            // 实际连接成功的端口（SSH 采集填充）
+	attempts      []RetryAttempt // 建连/执行的尝试记录（SSH 与 NETCONF 采集填充）
 }
 
 // CollectResponse 采集响应
@@ -65,6 +92,15 @@ type CollectResponse struct {
 	DurationMS int64                  `json:"duration_ms"`
 	Timestamp  time.Time              `json:"timestamp"`
 	Metadata   map[string]interface{} `json:"metadata"`
+	Timings    *DeviceTimings         `json:"timings,omitempty"`
+	// ConnectedPort 实际连接成功的端口（SSH 采集）
+	ConnectedPort int `json:"connected_port,omitempty"`
+	// DetectedPlatform 请求未指定平台时探测得到的平台键
+	DetectedPlatform string `json:"detected_platform,omitempty"`
+	// Attempts 尝试记录，仅在发生失败尝试时返回
+	Attempts []RetryAttempt `json:"attempts,omitempty"`
+	// SkippedFresh 因结果新鲜而跳过的命令数
+	SkippedFresh int `json:"skipped_fresh,omitempty"`
 }
 
 // 内置交互默认值结构（替代原 addone/interact）
@@ -74,6 +110,7 @@ type platformInteractDefaults struct {
 	Threads           int
 	Concurrent        int
 	PromptSuffixes    []string
+	PromptRegex       []string
 	CommandIntervalMS int
 	AutoInteractions  []struct{ ExpectOutput, AutoSend string }
 	ErrorHints        []string
@@ -104,6 +141,7 @@ func getPlatformDefaults(platform string) platformInteractDefaults {
 			if len(dd.PromptSuffixes) > 0 {
 				base.PromptSuffixes = dd.PromptSuffixes
 			}
+			base.PromptRegex = dd.PromptRegex
 			base.SkipDelayedEcho = dd.SkipDelayedEcho
 			// 优先使用平台嵌套 interact，其次兼容旧字段
 			if len(dd.Interact.ErrorHints) > 0 {
@@ -176,6 +214,7 @@ func getPlatformDefaults(platform string) platformInteractDefaults {
 			if len(dd.PromptSuffixes) > 0 {
 				base.PromptSuffixes = dd.PromptSuffixes
 			}
+			base.PromptRegex = dd.PromptRegex
 			base.SkipDelayedEcho = dd.SkipDelayedEcho
 			if len(dd.Interact.ErrorHints) > 0 {
 				base.ErrorHints = dd.Interact.ErrorHints
@@ -267,6 +306,17 @@ type CommandResultView struct {
 	Error        string      `json:"error"`
 	ExitCode     int         `json:"exit_code"`
 	DurationMS   int64       `json:"duration_ms"`
+	RawBytes     string      `json:"raw_bytes,omitempty"` // include_raw_bytes 时的原始字节（base64）
+	StoredObjects []StoredObject `json:"stored_objects,omitempty"` // store=true 时的落盘对象（输出与 .raw）
+	StoreError    string          `json:"store_error,omitempty"`
+	OmittedLines  int             `json:"omitted_lines,omitempty"` // 超出 max_lines 被省略的行数（落盘对象为完整输出）
+	Fields        map[string][]string `json:"fields,omitempty"`     // netconf：按 xpaths 提取的字段
+	RawOutputOmitted   bool   `json:"raw_output_omitted,omitempty"`   // raw_output_mode=omit/uri：响应不含原始输出
+	RawOutputTruncated bool   `json:"raw_output_truncated,omitempty"` // 超出 max_output_kb 被截断
+	RawOutputSize      int    `json:"raw_output_size,omitempty"`      // 省略或截断前的字节数
+	RawOutputURI       string `json:"raw_output_uri,omitempty"`       // raw_output_mode=uri：落盘对象地址
+	SkippedFresh       bool       `json:"skipped_fresh,omitempty"` // 结果仍新鲜，未执行，引用缓存对象
+	CollectedAt        *time.Time `json:"collected_at,omitempty"`  // 缓存对象的采集时间
 }
 
 // NewCollectorService 创建采集器服务
@@ -277,29 +327,13 @@ func NewCollectorService(cfg *config.Config) *CollectorService {
 	if conc <= 0 {
 		conc = 1
 	}
-	threads := cfg.Collector.Threads
-	if threads <= 0 {
-		threads = cfg.SSH.MaxSessions
-	}
-	poolConfig := &ssh.PoolConfig{
-		MaxIdle:         10,
-		MaxActive:       conc,
-		IdleTimeout:     5 * time.Minute,
-		CleanupInterval: cfg.SSH.CleanupInterval,
-		SSHConfig: &ssh.Config{
-			Timeout:        cfg.SSH.Timeout,
-			ConnectTimeout: cfg.SSH.ConnectTimeout,
-			KeepAlive:      cfg.SSH.KeepAliveInterval,
-			MaxSessions:    threads,
-		},
-	}
-	pool := ssh.NewPool(poolConfig)
+	pool := newServicePool(cfg, "collector")
 	return &CollectorService{
-		config:   cfg,
+		provider: config.ProviderFor(cfg),
 		sshPool:  pool,
 		interact: NewInteractBasic(cfg, pool),
 		tasks:    make(map[string]*TaskContext),
-		workers:  make(chan struct{}, conc),
+		workers:  newWorkerSlots(conc),
 	}
 }
 
@@ -313,6 +347,7 @@ func (s *CollectorService) Start(ctx context.Context) error {
 	}
 
 	s.running = true
+	s.recoverInterruptedTasks(time.Now())
 	logger.Info("Collector service started")
 
 	// 启动任务清理协程
@@ -340,7 +375,7 @@ func (s *CollectorService) Stop() error {
 	}
 
 	// 关闭SSH连接池
-	if err := s.sshPool.Close(); err != nil {
+	if err := closeServicePool(s.sshPool); err != nil {
 		logger.Error("Failed to close SSH pool", "error", err)
 	}
 
@@ -348,12 +383,39 @@ func (s *CollectorService) Stop() error {
 	return nil
 }
 
-// ExecuteTask 执行采集任务
+// ExecuteTask 执行采集任务（设备级 span：collector.device）
 func (s *CollectorService) ExecuteTask(ctx context.Context, request *CollectRequest) (*CollectResponse, error) {
+	ctx, span := startSpan(ctx, "collector.device", append(deviceSpanAttrs(request.DeviceIP, request.DeviceName, request.DevicePlatform), attribute.String("task.id", request.TaskID))...)
+	resp, err := s.executeTask(ctx, request)
+	spanErr := err
+	if err == nil && resp != nil {
+		spanErr = resultError(resp.Success, resp.Error)
+	}
+	endSpan(span, spanErr)
+	return resp, err
+}
+
+func (s *CollectorService) executeTask(ctx context.Context, request *CollectRequest) (*CollectResponse, error) {
+	cfg := s.conf() // 本任务使用的配置快照
 	if !s.running {
-		return nil, fmt.Errorf("collector service is not running")
+		return nil, serviceStopped("collector")
+	}
+	// 出站策略：拨号前拒绝范围外的设备地址（含 SNMP）
+	if err := checkEgress(ctx, cfg, request.DeviceIP); err != nil {
+		return nil, err
 	}
 
+	// 平台为空或 auto：先登录探测厂商平台，再据此注入预命令
+	detected := ""
+	if wantsPlatformDetect(cfg, request) {
+		detected = s.detectPlatform(ctx, cfg, request)
+		request.DevicePlatform = detected
+	} else if strings.EqualFold(strings.TrimSpace(request.DevicePlatform), PlatformAuto) {
+		request.DevicePlatform = ""
+	}
+	// 平台字符串归一为 device_defaults 平台键（platform_mappings）
+	request.DevicePlatform = normalizeRequestPlatform(ctx, cfg, request.DevicePlatform, request.DeviceIP)
+
 	// 在进入工作协程前先解析平台默认与有效超时/重试，用于队列等待控制
 	platform := strings.TrimSpace(strings.ToLower(request.DevicePlatform))
 	if platform == "" {
@@ -363,14 +425,56 @@ func (s *CollectorService) ExecuteTask(ctx context.Context, request *CollectRequ
 	if proto := strings.TrimSpace(strings.ToLower(request.CollectProtocol)); proto == "" {
 		request.CollectProtocol = "ssh"
 	}
-	if request.CollectProtocol != "ssh" {
-		return nil, fmt.Errorf("unsupported collect_protocol: %s", request.CollectProtocol)
+	request.CollectProtocol = strings.TrimSpace(strings.ToLower(request.CollectProtocol))
+	switch request.CollectProtocol {
+	case "ssh":
+		if len(request.XPaths) > 0 {
+			return nil, validationErrorf("xpaths requires collect_protocol netconf")
+		}
+	case "netconf":
+		if err := validateNetconfRequest(cfg, platform, request); err != nil {
+			return nil, err
+		}
+	case "snmp":
+		if err := validateSNMPRequest(cfg, request); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, validationErrorf("unsupported collect_protocol: %s", request.CollectProtocol)
+	}
+	if err := request.RetryPolicy.Validate(); err != nil {
+		return nil, err
+	}
+	if request.SimulateRecord != nil {
+		if _, _, err := simulateRecordTarget(cfg, request); err != nil {
+			return nil, err
+		}
+	}
+
+	// 差异化采集：落盘模式下结果仍新鲜的命令直接引用缓存对象，全部新鲜时不登录设备
+	runList, hits := request.CliList, []freshHit(nil)
+	if request.Store && request.CollectProtocol == "ssh" {
+		runList, hits = splitFreshCommands(s.resultIndex, request.DeviceIP, request.Port, request.CliList, request.FreshTTL, time.Now())
+	}
+	skipped := make([]*CommandResultView, 0, len(hits))
+	for _, h := range hits {
+		skipped = append(skipped, h.collectView())
+	}
+	if len(skipped) > 0 && len(runList) == 0 {
+		return &CollectResponse{
+			TaskID:       request.TaskID,
+			Success:      true,
+			Results:      skipped,
+			Timestamp:    time.Now(),
+			Metadata:     request.Metadata,
+			SkippedFresh: len(skipped),
+		}, nil
 	}
 
 	interactDefaults := getPlatformDefaults(platform)
 	
 	// 获取timeout_all配置（系统强制中断超时）
-	timeoutAll := s.config.GetTimeoutAll(platform)
+	timeoutAll := cfg.GetTimeoutAll(platform)
 	
 	// 计算有效超时与重试（用于队列等待与任务上下文）
 	effTimeout := 30
@@ -384,26 +488,39 @@ func (s *CollectorService) ExecuteTask(ctx context.Context, request *CollectRequ
 		effRetries = *request.RetryFlag
 	} else if interactDefaults.Retries > 0 {
 		effRetries = interactDefaults.Retries
-	} else if s.config != nil && s.config.Collector.RetryFlags > 0 {
-		effRetries = s.config.Collector.RetryFlags
+	} else if cfg != nil && cfg.Collector.RetryFlags > 0 {
+		effRetries = cfg.Collector.RetryFlags
+	}
+
+	// 执行时间线：沿用接口层已挂载的记录（含批次排队），内部编排采集单独记录
+	timings := timingsFrom(ctx)
+	if timings == nil || request.internal {
+		ctx, timings = WithTimings(ctx)
 	}
 
 	// 获取工作协程：使用基于有效超时的内部等待上下文，避免HTTP上下文过早结束
+	queued := time.Now()
 	waitCtx, waitCancel := context.WithTimeout(context.Background(), time.Duration(effTimeout)*time.Second)
 	defer waitCancel()
-	select {
-	case s.workers <- struct{}{}:
-		defer func() { <-s.workers }()
-	case <-waitCtx.Done():
-		return nil, fmt.Errorf("task queue wait timeout after %ds: %w", effTimeout, waitCtx.Err())
+	slots, slotErr := s.workers.acquire(waitCtx, nil)
+	if slotErr != nil {
+		return nil, withKind(ErrTimeout, fmt.Errorf("task queue wait timeout after %ds: %w", effTimeout, slotErr))
 	}
+	defer func() { <-slots }()
+	timings.AddQueueWait(time.Since(queued))
 
 	startTime := time.Now()
 	response := &CollectResponse{
 		TaskID:    request.TaskID,
 		Timestamp: startTime,
 		Metadata:  request.Metadata,
+		Timings:   timings,
+
+		DetectedPlatform: detected,
+		SkippedFresh:     len(skipped),
 	}
+	// 采集完成后发布设备事件（消息总线未启用时忽略）
+	defer func() { publishCollectEvent(cfg, request, response) }()
 
 	// 以上已解析平台与有效超时/重试
 
@@ -414,18 +531,18 @@ func (s *CollectorService) ExecuteTask(ctx context.Context, request *CollectRequ
 	preCmds := func() []string {
 		out := make([]string, 0, 4)
 		p := strings.TrimSpace(strings.ToLower(request.DevicePlatform))
-		if p == "" {
+		if p == "" || request.CollectProtocol != "ssh" {
 			return out
 		}
 		// 查找设备默认配置
-		dd, ok := s.config.Collector.DeviceDefaults[p]
+		dd, ok := cfg.Collector.DeviceDefaults[p]
 		if !ok {
 			if strings.HasPrefix(p, "huawei") {
-				dd, ok = s.config.Collector.DeviceDefaults["huawei"]
+				dd, ok = cfg.Collector.DeviceDefaults["huawei"]
 			} else if strings.HasPrefix(p, "h3c") {
-				dd, ok = s.config.Collector.DeviceDefaults["h3c"]
+				dd, ok = cfg.Collector.DeviceDefaults["h3c"]
 			} else if strings.HasPrefix(p, "cisco") {
-				dd, ok = s.config.Collector.DeviceDefaults["cisco_ios"]
+				dd, ok = cfg.Collector.DeviceDefaults["cisco_ios"]
 			}
 		}
 		if !ok {
@@ -436,7 +553,7 @@ func (s *CollectorService) ExecuteTask(ctx context.Context, request *CollectRequ
 		hasCmd := func(cmd string) bool {
 			key := strings.ToLower(strings.TrimSpace(cmd))
 			for _, c := range request.CliList {
-				if strings.ToLower(strings.TrimSpace(c)) == key {
+				if strings.ToLower(strings.TrimSpace(c.CLI)) == key {
 					return true
 				}
 			}
@@ -468,8 +585,10 @@ func (s *CollectorService) ExecuteTask(ctx context.Context, request *CollectRequ
 	if len(preCmds) > 0 {
 		commands = append(commands, preCmds...)
 	}
-	if len(request.CliList) > 0 {
-		commands = append(commands, request.CliList...)
+	if len(runList) > 0 {
+		commands = append(commands, runList.Commands()...)
+	} else if request.CollectProtocol == "snmp" {
+		commands = snmpDefaultCommands(cfg, platform)
 	}
 	// 命令为空：允许继续（将返回空结果）
 
@@ -482,10 +601,16 @@ func (s *CollectorService) ExecuteTask(ctx context.Context, request *CollectRequ
 	if port <= 0 || port > 65535 {
 		port = 22
 	}
+	switch request.CollectProtocol {
+	case "netconf":
+		port = netconfPort(cfg, platform, request.Port)
+	case "snmp":
+		port = snmpPort(cfg, request.Port)
+	}
 
 	task := &model.Task{
 		ID:          request.TaskID,
-		CollectorID: s.config.Collector.ID,
+		CollectorID: cfg.Collector.ID,
 		Type:        model.TaskTypeSimple,
 		DeviceIP:    request.DeviceIP,
 		DevicePort:  port,
@@ -494,6 +619,8 @@ func (s *CollectorService) ExecuteTask(ctx context.Context, request *CollectRequ
 		Commands:    strings.Join(commands, ";"),
 		Status:      model.TaskStatusRunning,
 		StartTime:   startTime,
+		TaskName:    request.TaskName,
+		Metadata:    encodeTaskMetadata(taskMetadataWithPlatform(request.Metadata, request.DevicePlatform)),
 		CreatedAt:   startTime,
 		UpdatedAt:   startTime,
 	}
@@ -520,11 +647,21 @@ func (s *CollectorService) ExecuteTask(ctx context.Context, request *CollectRequ
 	deviceInteractStart := time.Now()
 	s.logTaskInfo(request.TaskID, fmt.Sprintf("Device interaction started with timeout_all=%ds", timeoutAll))
 
-	// 执行SSH采集
+	// 执行采集（SSH 交互或 NETCONF RPC）
 	execStart := time.Now()
-	results, err := s.executeSSHCollection(taskCtx, request, commands, effRetries)
+	var results []*CommandResultView
+	var err error
+	switch request.CollectProtocol {
+	case "netconf":
+		results, err = s.executeNetconfCollection(taskCtx, request, commands, port, effRetries)
+	case "snmp":
+		results, err = s.executeSNMPCollection(taskCtx, request, commands, port)
+	default:
+		results, err = s.executeSSHCollection(taskCtx, request, commands, effRetries)
+	}
 	response.Duration = time.Since(execStart)
 	response.DurationMS = response.Duration.Milliseconds()
+	response.Attempts = failedAttempts(request.attempts)
 
 	// 记录设备交互时长
 	deviceInteractDuration := time.Since(deviceInteractStart)
@@ -568,11 +705,20 @@ func (s *CollectorService) ExecuteTask(ctx context.Context, request *CollectRequ
 		s.logTaskError(request.TaskID, err.Error())
 	} else {
 		response.Success = true
-		response.Results = results
 		task.Status = model.TaskStatusSuccess
+		response.ConnectedPort = request.connectedPort
+		if request.Store {
+			s.storeResults(ctx, request, results, startTime)
+			s.recordStoredResults(request, runList, results, startTime)
+		}
+		if request.SimulateRecord != nil {
+			s.recordSimulate(ctx, cfg, request, results)
+		}
+		s.capCollectResults(ctx, request, results)
+		response.Results = append(skipped, results...)
 
 		// 序列化结果
-		if resultData, err := json.Marshal(results); err == nil {
+		if resultData, err := json.Marshal(response.Results); err == nil {
 			task.Result = string(resultData)
 		}
 	}
@@ -599,24 +745,7 @@ func (s *CollectorService) executeSSHCollection(ctx context.Context, request *Co
 	s.logTaskInfo(request.TaskID, fmt.Sprintf("Starting SSH collection for %s:%d", request.DeviceIP, port))
 
 	// 计算有效超时（与 ExecuteTask 逻辑保持一致）
-	effTimeoutSec := 30
-	if request.TaskTimeout != nil && *request.TaskTimeout > 0 {
-		effTimeoutSec = *request.TaskTimeout
-	} else {
-		p := strings.TrimSpace(strings.ToLower(request.DevicePlatform))
-		platformKey := p
-		if platformKey == "" {
-			platformKey = "default"
-		}
-		d := getPlatformDefaults(platformKey)
-		if d.Timeout > 0 {
-			effTimeoutSec = d.Timeout
-		}
-	}
-	devTimeoutSec := effTimeoutSec
-	if request.DeviceTimeout != nil && *request.DeviceTimeout > 0 {
-		devTimeoutSec = *request.DeviceTimeout
-	}
+	effTimeoutSec, devTimeoutSec := s.effectiveTimeouts(request)
 	// 统一交互入口：通过 InteractBasic 执行并完成预命令与行过滤
 	execReq := &ExecRequest{
 		DeviceIP:         request.DeviceIP,
@@ -629,35 +758,30 @@ func (s *CollectorService) executeSSHCollection(ctx context.Context, request *Co
 		EnablePassword:   request.EnablePassword,
 		TaskTimeoutSec:   effTimeoutSec,
 		DeviceTimeoutSec: devTimeoutSec,
+		CommandTimeouts:  request.CliList.Timeouts(),
+		RawCommands:      request.CliList.RawCommands(),
+		PortCandidates:   s.portCandidates(request, port),
 	}
 
-	// 使用请求中的 retries 参数进行重试（至少执行一次）
-	attempts := retries
-	if attempts < 0 {
-		attempts = 0
-	}
-	maxAttempts := attempts + 1
+	// 使用请求中的 retries 参数进行重试（至少执行一次），退避与重试条件见 retry_policy
 	var rawResults []*ssh.CommandResult
-	var err error
-	for i := 0; i < maxAttempts; i++ {
+	policy := resolveRetryPolicy(s.conf(), request.DevicePlatform, request.RetryPolicy)
+	attempts, err := policy.do(ctx, retries, func(attempt, total int, err error) {
+		s.logTaskWarn(request.TaskID, fmt.Sprintf("Attempt %d/%d failed: %v", attempt, total, err))
+	}, func() error {
+		var err error
 		rawResults, err = s.interact.Execute(ctx, execReq, commands)
-		if err == nil {
-			if i > 0 {
-				s.logTaskInfo(request.TaskID, fmt.Sprintf("Retry successful on attempt %d/%d", i+1, maxAttempts))
-			}
-			break
-		}
-		s.logTaskWarn(request.TaskID, fmt.Sprintf("Attempt %d/%d failed: %v", i+1, maxAttempts, err))
-		// 若上下文已取消或达到最大重试次数则退出
-		if ctx.Err() != nil || i >= attempts {
-			break
-		}
-		// 轻微退避，避免立即重试造成设备压力
-		time.Sleep(time.Duration(150*(i+1)) * time.Millisecond)
-	}
+		return err
+	})
+	request.attempts = attempts
 	if err != nil {
 		return nil, err
 	}
+	if len(attempts) > 1 {
+		s.logTaskInfo(request.TaskID, fmt.Sprintf("Retry successful on attempt %d/%d", len(attempts), retries+1))
+	}
+	request.connectedPort = execReq.ConnectedPort
+	s.recordConnectedPort(request, port, execReq.ConnectedPort)
 	// 记录成功日志
 	s.logTaskInfo(request.TaskID, fmt.Sprintf("SSH collection completed, executed %d commands", len(rawResults)))
 
@@ -759,6 +883,9 @@ func (s *CollectorService) executeSSHCollection(ctx context.Context, request *Co
 			ExitCode:     exitCodeVal,
 			DurationMS:   durationMsVal,
 		}
+		if r != nil && r.Raw != nil {
+			view.RawBytes = base64.StdEncoding.EncodeToString(r.Raw)
+		}
 		logger.Debugf("Collector output filter: cmd=%q lines_before=%d lines_after=%d exit=%d dur_ms=%d error_propagated=%v", displayCmd, beforeLines, afterLines, exitCodeVal, durationMsVal, propagated)
 		out = append(out, view)
 	}
@@ -766,6 +893,29 @@ func (s *CollectorService) executeSSHCollection(ctx context.Context, request *Co
 	return out, nil
 }
 
+// effectiveTimeouts 计算任务与设备登录超时（秒）：请求值优先，其次平台默认，兜底 30s
+func (s *CollectorService) effectiveTimeouts(request *CollectRequest) (int, int) {
+	effTimeoutSec := 30
+	if request.TaskTimeout != nil && *request.TaskTimeout > 0 {
+		effTimeoutSec = *request.TaskTimeout
+	} else {
+		p := strings.TrimSpace(strings.ToLower(request.DevicePlatform))
+		platformKey := p
+		if platformKey == "" {
+			platformKey = "default"
+		}
+		d := getPlatformDefaults(platformKey)
+		if d.Timeout > 0 {
+			effTimeoutSec = d.Timeout
+		}
+	}
+	devTimeoutSec := effTimeoutSec
+	if request.DeviceTimeout != nil && *request.DeviceTimeout > 0 {
+		devTimeoutSec = *request.DeviceTimeout
+	}
+	return effTimeoutSec, devTimeoutSec
+}
+
 // GetTaskStatus 获取任务状态
 func (s *CollectorService) GetTaskStatus(taskID string) (*TaskContext, error) {
 	s.mutex.RLock()
@@ -773,7 +923,7 @@ func (s *CollectorService) GetTaskStatus(taskID string) (*TaskContext, error) {
 
 	taskCtx, exists := s.tasks[taskID]
 	if !exists {
-		return nil, fmt.Errorf("task not found: %s", taskID)
+		return nil, fmt.Errorf("%w: %s", ErrTaskNotFound, taskID)
 	}
 
 	return taskCtx, nil
@@ -792,7 +942,7 @@ func (s *CollectorService) CancelTask(taskID string) error {
 		return nil
 	}
 
-	return fmt.Errorf("task not found: %s", taskID)
+	return fmt.Errorf("%w: %s", ErrTaskNotFound, taskID)
 }
 
 // GetStats 获取采集器统计信息
@@ -800,12 +950,19 @@ func (s *CollectorService) GetStats() map[string]interface{} {
 	s.mutex.RLock()
 	defer s.mutex.RUnlock()
 
+	slots := s.workers.get()
 	stats := map[string]interface{}{
 		"running":      s.running,
 		"active_tasks": len(s.tasks),
-		"max_workers":  cap(s.workers),
-		"busy_workers": len(s.workers),
+		"max_workers":  cap(slots),
+		"busy_workers": len(slots),
 		"ssh_pool":     s.sshPool.GetStats(),
+		// 按平台的命令耗时与输出大小分布（进程启动以来）
+		"command_stats": CommandStatsSnapshot(),
+		// 认证失败计数与冷却中的凭据（各服务共享）
+		"auth_throttle": AuthThrottleSnapshot(),
+		// 连续建连失败的设备与熔断状态（各服务共享）
+		"circuit_breaker": CircuitBreakerSnapshot(),
 	}
 
 	// 添加设备交互时长统计
@@ -857,10 +1014,12 @@ func (s *CollectorService) removeTaskContext(taskID string) {
 	delete(s.tasks, taskID)
 }
 
-// cleanupTasks 清理过期任务
+// cleanupTasks 清理过期任务；按 taskPurgeInterval 清理超期任务历史
 func (s *CollectorService) cleanupTasks(ctx context.Context) {
 	ticker := time.NewTicker(1 * time.Minute)
 	defer ticker.Stop()
+	purgeTicker := time.NewTicker(taskPurgeInterval)
+	defer purgeTicker.Stop()
 
 	for {
 		select {
@@ -868,6 +1027,8 @@ func (s *CollectorService) cleanupTasks(ctx context.Context) {
 			return
 		case <-ticker.C:
 			s.cleanupExpiredTasks()
+		case <-purgeTicker.C:
+			s.purgeTaskHistory()
 		}
 	}
 }
@@ -894,16 +1055,20 @@ func (s *CollectorService) cleanupExpiredTasks() {
 
 // saveTask 保存任务到数据库
 func (s *CollectorService) saveTask(task *model.Task) error {
-	// 暂停任务信息写库：仅输出日志用于排查
-	logger.Info("Skip task DB write", "task_id", task.ID)
-	return nil
+	if !s.persistTask(task) {
+		logger.Info("Skip task DB write", "task_id", task.ID)
+		return nil
+	}
+	return s.taskStore.SaveTask(taskRecord(task))
 }
 
 // updateTask 更新任务状态
 func (s *CollectorService) updateTask(task *model.Task) error {
-	// 暂停任务信息写库：仅输出日志用于排查
-	logger.Info("Skip task DB update", "task_id", task.ID, "status", task.Status, "duration_ms", task.Duration)
-	return nil
+	if !s.persistTask(task) {
+		logger.Info("Skip task DB update", "task_id", task.ID, "status", task.Status, "duration_ms", task.Duration)
+		return nil
+	}
+	return s.taskStore.UpdateTask(taskRecord(task))
 }
 
 // 已移除 Redis 缓存函数（保留数据库写入版本的任务日志函数）
@@ -928,8 +1093,3 @@ func (s *CollectorService) logTaskWarn(taskID, message string) {
 	s.saveTaskLog(taskID, "WARN", message)
 }
 
-// saveTaskLog 保存任务日志
-func (s *CollectorService) saveTaskLog(taskID, level, message string) {
-	// 暂停任务日志入库：保留日志输出（logTask* 已记录），此处不重复写日志避免噪声
-	// 删除冗余的 return 语句
-}
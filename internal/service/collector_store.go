@@ -0,0 +1,107 @@
+package service
+
+import (
+	"context"
+	"encoding/base64"
+	"strings"
+	"time"
+
+	"github.com/sshcollectorpro/sshcollectorpro/pkg/logger"
+)
+
+// SetStorageWriter 注入存储写入器（通常复用备份服务的写入器）
+func (s *CollectorService) SetStorageWriter(w StorageWriter) {
+	s.storageWriter = w
+}
+
+// SetResultIndex 注入命令结果索引（store=true 时记录落盘对象并支持 fresh_ttl）
+func (s *CollectorService) SetResultIndex(idx CommandResultIndex) {
+	s.resultIndex = idx
+}
+
+// StorageWriter 返回备份服务的存储写入器，供其他服务复用
+func (s *BackupService) StorageWriter() StorageWriter {
+	return s.storageWriter
+}
+
+// StorageReader 按 URI 读取已存储对象；存储后端不支持读取时返回 nil
+func (s *BackupService) StorageReader() StorageReader {
+	r, _ := s.storageWriter.(StorageReader)
+	return r
+}
+
+// storeResults 按备份目录规则落盘每条命令输出；失败记录在对应命令的 store_error，不影响采集结果
+func (s *CollectorService) storeResults(ctx context.Context, request *CollectRequest, results []*CommandResultView, start time.Time) {
+	if s.storageWriter == nil {
+		for _, v := range results {
+			if v != nil {
+				v.StoreError = "storage writer not configured"
+			}
+		}
+		return
+	}
+	backend := strings.TrimSpace(request.StorageBackend)
+	if backend == "" {
+		backend = strings.TrimSpace(s.conf().Backup.StorageBackend)
+	}
+	if backend == "" {
+		backend = "local"
+	}
+	base := StorageMeta{
+		SaveDir:        request.SaveDir,
+		DateYYYYMMDD:   start.Format("20060102"),
+		TimeHHMMSS:     start.Format("150405"),
+		TaskID:         request.TaskID,
+		DeviceName:     request.DeviceName,
+		DeviceIP:       request.DeviceIP,
+		DevicePlatform: request.DevicePlatform,
+		Backend:        backend,
+	}
+	for _, v := range results {
+		if v == nil {
+			continue
+		}
+		meta := base
+		meta.CommandSlug = v.Command
+		obj, err := s.storageWriter.Write(ctx, meta, v.RawOutput, "text/plain; charset=utf-8")
+		if err != nil {
+			v.StoreError = err.Error()
+			logger.Warn("Store collector output failed", "task_id", request.TaskID, "command", v.Command, "error", err)
+			continue
+		}
+		v.StoredObjects = append(v.StoredObjects, obj)
+		// 原始字节另存为 {命令}.raw，不做行过滤
+		if v.RawBytes == "" {
+			continue
+		}
+		raw, err := base64.StdEncoding.DecodeString(v.RawBytes)
+		if err != nil {
+			continue
+		}
+		meta.CommandSlug = v.Command + ".raw"
+		meta.SkipFilter = true
+		rawObj, err := s.storageWriter.Write(ctx, meta, string(raw), "application/octet-stream")
+		if err != nil {
+			v.StoreError = err.Error()
+			continue
+		}
+		v.StoredObjects = append(v.StoredObjects, rawObj)
+	}
+}
+
+// recordStoredResults 记录本次执行且成功落盘的用户命令（预命令不记录），供后续任务按新鲜度跳过
+func (s *CollectorService) recordStoredResults(request *CollectRequest, runList CLIList, results []*CommandResultView, start time.Time) {
+	if s.resultIndex == nil {
+		return
+	}
+	wanted := make(map[string]bool, len(runList))
+	for _, it := range runList {
+		wanted[CommandKey(it.CLI)] = true
+	}
+	for _, v := range results {
+		if v == nil || v.Error != "" || v.ExitCode != 0 || len(v.StoredObjects) == 0 || !wanted[CommandKey(v.Command)] {
+			continue
+		}
+		recordFreshResult(s.resultIndex, request.DeviceIP, request.Port, v.Command, request.TaskID, v.StoredObjects[0], start)
+	}
+}
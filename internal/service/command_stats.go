@@ -0,0 +1,364 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sshcollectorpro/sshcollectorpro/internal/config"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/database"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/model"
+	"github.com/sshcollectorpro/sshcollectorpro/pkg/logger"
+	"github.com/sshcollectorpro/sshcollectorpro/pkg/ssh"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 直方图桶上界：命令耗时（秒）与输出大小（字节）
+var (
+	commandDurationBuckets = []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 120}
+	commandOutputBuckets   = []float64{256, 1 << 10, 4 << 10, 16 << 10, 64 << 10, 256 << 10, 1 << 20, 4 << 20, 16 << 20}
+)
+
+// histogram 固定桶直方图；counts 比 bounds 多一项（超出最大上界）
+type histogram struct {
+	bounds []float64
+	counts []uint64
+	count  uint64
+	sum    float64
+	max    float64
+}
+
+func newHistogram(bounds []float64) *histogram {
+	return &histogram{bounds: bounds, counts: make([]uint64, len(bounds)+1)}
+}
+
+func (h *histogram) observe(v float64) {
+	i := sort.SearchFloat64s(h.bounds, v)
+	h.counts[i]++
+	h.count++
+	h.sum += v
+	if v > h.max {
+		h.max = v
+	}
+}
+
+// quantile 按桶估算分位数：取累计计数首次达到 q 的桶上界，落在最后一桶时取最大值
+func (h *histogram) quantile(q float64) float64 {
+	if h.count == 0 {
+		return 0
+	}
+	target := uint64(math.Ceil(q * float64(h.count)))
+	var cum uint64
+	for i, c := range h.counts {
+		cum += c
+		if cum >= target {
+			if i < len(h.bounds) {
+				return math.Min(h.bounds[i], h.max)
+			}
+			break
+		}
+	}
+	return h.max
+}
+
+// HistogramBucket 单个桶的累计计数（le 为上界，+Inf 为全部）
+type HistogramBucket struct {
+	Le    string `json:"le"`
+	Count uint64 `json:"count"`
+}
+
+// HistogramSnapshot 直方图快照；分位数按桶上界估算
+type HistogramSnapshot struct {
+	Count   uint64            `json:"count"`
+	Sum     float64           `json:"sum"`
+	Avg     float64           `json:"avg"`
+	Max     float64           `json:"max"`
+	P50     float64           `json:"p50"`
+	P95     float64           `json:"p95"`
+	P99     float64           `json:"p99"`
+	Buckets []HistogramBucket `json:"buckets"`
+}
+
+func (h *histogram) snapshot() HistogramSnapshot {
+	s := HistogramSnapshot{Count: h.count, Sum: h.sum, Max: h.max, P50: h.quantile(0.5), P95: h.quantile(0.95), P99: h.quantile(0.99)}
+	if h.count > 0 {
+		s.Avg = h.sum / float64(h.count)
+	}
+	var cum uint64
+	for i, c := range h.counts {
+		cum += c
+		le := "+Inf"
+		if i < len(h.bounds) {
+			le = strconv.FormatFloat(h.bounds[i], 'g', -1, 64)
+		}
+		s.Buckets = append(s.Buckets, HistogramBucket{Le: le, Count: cum})
+	}
+	return s
+}
+
+// platformCommandStats 单个平台的命令耗时与输出大小分布
+type platformCommandStats struct {
+	duration *histogram
+	output   *histogram
+}
+
+func newPlatformCommandStats() *platformCommandStats {
+	return &platformCommandStats{duration: newHistogram(commandDurationBuckets), output: newHistogram(commandOutputBuckets)}
+}
+
+// PlatformCommandStats 平台命令分布快照（/api/v1/collector/stats 的 command_stats）
+type PlatformCommandStats struct {
+	Platform        string            `json:"platform"`
+	Commands        uint64            `json:"commands"`
+	DurationSeconds HistogramSnapshot `json:"duration_seconds"`
+	OutputBytes     HistogramSnapshot `json:"output_bytes"`
+}
+
+// commandStatsRegistry 进程内累计分布；启用日汇总时另记待写入的增量（按日期、平台）
+type commandStatsRegistry struct {
+	mu        sync.Mutex
+	platforms map[string]*platformCommandStats
+	persist   bool
+	pending   map[string]map[string]*platformCommandStats
+}
+
+var commandStats = &commandStatsRegistry{platforms: map[string]*platformCommandStats{}}
+
+// observeCommandResults 记录一次执行的命令结果（耗时与输出字节数）；平台为空时记为 default
+func observeCommandResults(platform string, results []*ssh.CommandResult) {
+	p := strings.ToLower(strings.TrimSpace(platform))
+	if p == "" {
+		p = "default"
+	}
+	r := commandStats
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	ps := r.platforms[p]
+	if ps == nil {
+		ps = newPlatformCommandStats()
+		r.platforms[p] = ps
+	}
+	var day *platformCommandStats
+	if r.persist {
+		date := time.Now().Format("2006-01-02")
+		if r.pending[date] == nil {
+			r.pending[date] = map[string]*platformCommandStats{}
+		}
+		if day = r.pending[date][p]; day == nil {
+			day = newPlatformCommandStats()
+			r.pending[date][p] = day
+		}
+	}
+	for _, res := range results {
+		if res == nil {
+			continue
+		}
+		d, size := res.Duration.Seconds(), float64(len(res.Output))
+		ps.duration.observe(d)
+		ps.output.observe(size)
+		if day != nil {
+			day.duration.observe(d)
+			day.output.observe(size)
+		}
+	}
+}
+
+// CommandStatsSnapshot 按平台名排序的命令分布快照
+func CommandStatsSnapshot() []PlatformCommandStats {
+	r := commandStats
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	out := make([]PlatformCommandStats, 0, len(r.platforms))
+	for p, ps := range r.platforms {
+		out = append(out, PlatformCommandStats{
+			Platform:        p,
+			Commands:        ps.duration.count,
+			DurationSeconds: ps.duration.snapshot(),
+			OutputBytes:     ps.output.snapshot(),
+		})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Platform < out[j].Platform })
+	return out
+}
+
+// WriteCommandMetrics 以 Prometheus 文本格式输出命令耗时与输出大小直方图
+func WriteCommandMetrics(w io.Writer) {
+	stats := CommandStatsSnapshot()
+	write := func(name, help string, pick func(PlatformCommandStats) HistogramSnapshot) {
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s histogram\n", name, help, name)
+		for _, ps := range stats {
+			h := pick(ps)
+			label := strconv.Quote(ps.Platform)
+			for _, b := range h.Buckets {
+				fmt.Fprintf(w, "%s_bucket{platform=%s,le=%q} %d\n", name, label, b.Le, b.Count)
+			}
+			fmt.Fprintf(w, "%s_sum{platform=%s} %s\n", name, label, strconv.FormatFloat(h.Sum, 'g', -1, 64))
+			fmt.Fprintf(w, "%s_count{platform=%s} %d\n", name, label, h.Count)
+		}
+	}
+	write("sshcollector_command_duration_seconds", "Command execution duration by device platform.",
+		func(ps PlatformCommandStats) HistogramSnapshot { return ps.DurationSeconds })
+	write("sshcollector_command_output_bytes", "Command output size by device platform.",
+		func(ps PlatformCommandStats) HistogramSnapshot { return ps.OutputBytes })
+}
+
+var (
+	statsFlushMu   sync.Mutex
+	statsFlushStop chan struct{}
+	statsFlushDone chan struct{}
+)
+
+// InitCommandStats 按配置启动日汇总写入；未启用 metrics.persist_daily 时仅保留内存统计。重复调用会先停止旧的写入
+func InitCommandStats(cfg *config.Config) {
+	CloseCommandStats()
+	if cfg == nil || !cfg.Metrics.PersistDaily {
+		return
+	}
+	interval := cfg.Metrics.FlushInterval
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	commandStats.mu.Lock()
+	commandStats.persist = true
+	commandStats.pending = map[string]map[string]*platformCommandStats{}
+	commandStats.mu.Unlock()
+
+	stop, done := make(chan struct{}), make(chan struct{})
+	statsFlushMu.Lock()
+	statsFlushStop, statsFlushDone = stop, done
+	statsFlushMu.Unlock()
+	retention := cfg.Metrics.RetentionDays
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				flushCommandStats()
+				return
+			case <-ticker.C:
+				flushCommandStats()
+				if retention > 0 && IsLeader() {
+					purgeCommandStats(retention)
+				}
+			}
+		}
+	}()
+}
+
+// CloseCommandStats 停止日汇总写入并写入剩余增量
+func CloseCommandStats() {
+	statsFlushMu.Lock()
+	stop, done := statsFlushStop, statsFlushDone
+	statsFlushStop, statsFlushDone = nil, nil
+	statsFlushMu.Unlock()
+	if stop == nil {
+		return
+	}
+	close(stop)
+	<-done
+	commandStats.mu.Lock()
+	commandStats.persist = false
+	commandStats.pending = nil
+	commandStats.mu.Unlock()
+}
+
+// flushCommandStats 将待写入的增量累加到 command_stats_daily；写入失败的增量丢弃并记录告警
+func flushCommandStats() {
+	commandStats.mu.Lock()
+	pending := commandStats.pending
+	if len(pending) > 0 {
+		commandStats.pending = map[string]map[string]*platformCommandStats{}
+	}
+	commandStats.mu.Unlock()
+	if len(pending) == 0 {
+		return
+	}
+	db := database.GetDB()
+	if db == nil {
+		return
+	}
+	for date, platforms := range pending {
+		for p, ps := range platforms {
+			if err := mergeDailyStats(db, date, p, ps); err != nil {
+				logger.Warn("Command stats rollup write failed", "date", date, "platform", p, "error", err)
+			}
+		}
+	}
+}
+
+func mergeDailyStats(db *gorm.DB, date, platform string, ps *platformCommandStats) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		row := model.CommandStatsDaily{Date: date, Platform: platform}
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&row).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("date = ? AND platform = ?", date, platform).First(&row).Error; err != nil {
+			return err
+		}
+		row.Commands += int64(ps.duration.count)
+		row.DurationSumMS += int64(ps.duration.sum * 1000)
+		if ms := int64(ps.duration.max * 1000); ms > row.DurationMaxMS {
+			row.DurationMaxMS = ms
+		}
+		row.OutputBytesSum += int64(ps.output.sum)
+		if b := int64(ps.output.max); b > row.OutputBytesMax {
+			row.OutputBytesMax = b
+		}
+		row.DurationBuckets = addBucketCounts(row.DurationBuckets, ps.duration.counts)
+		row.OutputBuckets = addBucketCounts(row.OutputBuckets, ps.output.counts)
+		return tx.Save(&row).Error
+	})
+}
+
+// addBucketCounts 累加桶计数（JSON 数组）；桶数不一致（桶边界调整过）时以本次为准
+func addBucketCounts(stored string, counts []uint64) string {
+	var prev []uint64
+	_ = json.Unmarshal([]byte(stored), &prev)
+	out := append([]uint64{}, counts...)
+	if len(prev) == len(out) {
+		for i := range out {
+			out[i] += prev[i]
+		}
+	}
+	b, _ := json.Marshal(out)
+	return string(b)
+}
+
+// purgeCommandStats 删除超过保留天数的日汇总
+func purgeCommandStats(days int) {
+	db := database.GetDB()
+	if db == nil {
+		return
+	}
+	cutoff := time.Now().AddDate(0, 0, -days).Format("2006-01-02")
+	if err := db.Where("date < ?", cutoff).Delete(&model.CommandStatsDaily{}).Error; err != nil {
+		logger.Warn("Command stats rollup purge failed", "error", err)
+	}
+}
+
+// ListDailyCommandStats 最近 days 天的日汇总（按日期倒序、平台名排序）；platform 为空表示全部平台
+func ListDailyCommandStats(ctx context.Context, days int, platform string) ([]model.CommandStatsDaily, error) {
+	db := database.GetDB()
+	if db == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+	q := db.WithContext(ctx).Where("date >= ?", time.Now().AddDate(0, 0, -(days-1)).Format("2006-01-02"))
+	if p := strings.ToLower(strings.TrimSpace(platform)); p != "" {
+		q = q.Where("platform = ?", p)
+	}
+	var rows []model.CommandStatsDaily
+	if err := q.Order("date desc, platform asc").Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
@@ -0,0 +1,215 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+
+	"github.com/sshcollectorpro/sshcollectorpro/pkg/logger"
+)
+
+// LegacyFieldRemovalVersion 已更名字段的旧名计划移除的 API 版本
+const LegacyFieldRemovalVersion = "v2"
+
+// taskLegacyFields 任务级已更名字段：旧名 -> 现名
+var taskLegacyFields = map[string]string{
+	"timeout": "task_timeout",
+	"retry":   "retry_flag",
+}
+
+// deviceLegacyFields 设备级已更名字段：旧名 -> 现名
+var deviceLegacyFields = map[string]string{
+	"ip":       "device_ip",
+	"port":     "device_port",
+	"platform": "device_platform",
+	"protocol": "collect_protocol",
+	"username": "user_name",
+	"timeout":  "device_timeout",
+}
+
+// Deprecations 请求中使用的已弃用字段提示（由 UnmarshalJSON 填充，不参与序列化）
+type Deprecations struct {
+	warnings []Warning
+}
+
+// DeprecationWarnings 返回弃用提示，供接口层写入响应 warnings
+func (d Deprecations) DeprecationWarnings() []Warning { return d.warnings }
+
+// deprecationUsage 旧字段使用次数：请求类型.字段 -> *int64
+var deprecationUsage sync.Map
+
+// DeprecationUsage 各旧字段自启动以来的使用次数，用于评估移除时机
+func DeprecationUsage() map[string]int64 {
+	out := map[string]int64{}
+	deprecationUsage.Range(func(k, v interface{}) bool {
+		out[k.(string)] = atomic.LoadInt64(v.(*int64))
+		return true
+	})
+	return out
+}
+
+// recordDeprecation 累计使用次数；首次及每 100 次记录一条日志
+func recordDeprecation(key string) {
+	v, _ := deprecationUsage.LoadOrStore(key, new(int64))
+	n := atomic.AddInt64(v.(*int64), 1)
+	if n == 1 || n%100 == 0 {
+		logger.Warn("Deprecated request field used", "field", key, "count", n)
+	}
+}
+
+// UnmarshalCompat 改写旧字段名后解码到 v，并记录弃用提示
+// listKey 为设备列表字段（如 devices），flat 表示设备字段直接位于顶层（单设备请求）
+// 同时出现新旧字段时以新字段为准
+func UnmarshalCompat(data []byte, v interface{}, d *Deprecations, typeName, listKey string, flat bool) error {
+	data, warnings, err := normalizeLegacyJSON(data, typeName, listKey, flat)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return err
+	}
+	d.warnings = warnings
+	return nil
+}
+
+// normalizeLegacyJSON 将旧字段名改写为现名；未使用旧字段时原样返回
+func normalizeLegacyJSON(data []byte, typeName, listKey string, flat bool) ([]byte, []Warning, error) {
+	var obj map[string]json.RawMessage
+	if err := json.Unmarshal(data, &obj); err != nil || obj == nil {
+		// 非对象交由标准解码报告错误
+		return data, nil, nil
+	}
+	used := map[string]string{}
+	changed := false
+
+	top := taskLegacyFields
+	if flat {
+		top = make(map[string]string, len(taskLegacyFields)+len(deviceLegacyFields))
+		for k, v := range deviceLegacyFields {
+			top[k] = v
+		}
+		// 单设备请求中 timeout 沿用历史含义（任务超时）
+		for k, v := range taskLegacyFields {
+			top[k] = v
+		}
+	}
+	if renameLegacyKeys(obj, top, "", used) {
+		changed = true
+	}
+
+	if raw, ok := obj[listKey]; ok && listKey != "" {
+		var items []json.RawMessage
+		if json.Unmarshal(raw, &items) == nil {
+			itemsChanged := false
+			for i, it := range items {
+				var dev map[string]json.RawMessage
+				if json.Unmarshal(it, &dev) != nil || dev == nil {
+					continue
+				}
+				if renameLegacyKeys(dev, deviceLegacyFields, listKey+"[].", used) {
+					b, err := json.Marshal(dev)
+					if err != nil {
+						return nil, nil, err
+					}
+					items[i] = b
+					itemsChanged = true
+				}
+			}
+			if itemsChanged {
+				b, err := json.Marshal(items)
+				if err != nil {
+					return nil, nil, err
+				}
+				obj[listKey] = b
+				changed = true
+			}
+		}
+	}
+	if !changed {
+		return data, nil, nil
+	}
+
+	fields := make([]string, 0, len(used))
+	for f := range used {
+		fields = append(fields, f)
+	}
+	sort.Strings(fields)
+	warnings := make([]Warning, 0, len(fields))
+	for _, f := range fields {
+		recordDeprecation(typeName + "." + f)
+		warnings = append(warnings, Warning{
+			Code:    WarnDeprecatedField,
+			Message: fmt.Sprintf("field %q is deprecated and will be removed in API %s, use %q instead", f, LegacyFieldRemovalVersion, used[f]),
+			Context: map[string]interface{}{"field": f, "replacement": used[f], "removal_version": LegacyFieldRemovalVersion},
+		})
+	}
+	out, err := json.Marshal(obj)
+	if err != nil {
+		return nil, nil, err
+	}
+	return out, warnings, nil
+}
+
+// LegacyFieldReplacements 旧字段名对应的现名（任务级与设备级），非旧字段返回 nil
+func LegacyFieldReplacements(name string) []string {
+	var out []string
+	if v, ok := taskLegacyFields[name]; ok {
+		out = append(out, v)
+	}
+	if v, ok := deviceLegacyFields[name]; ok {
+		out = append(out, v)
+	}
+	return out
+}
+
+// renameLegacyKeys 就地改写对象中的旧字段名；记录 used[前缀+旧名] = 前缀+现名
+func renameLegacyKeys(obj map[string]json.RawMessage, aliases map[string]string, prefix string, used map[string]string) bool {
+	changed := false
+	for legacy, current := range aliases {
+		raw, ok := obj[legacy]
+		if !ok {
+			continue
+		}
+		delete(obj, legacy)
+		if _, exists := obj[current]; !exists {
+			obj[current] = raw
+		}
+		used[prefix+legacy] = prefix + current
+		changed = true
+	}
+	return changed
+}
+
+// 各请求类型的兼容解码：plain 类型避免递归调用 UnmarshalJSON
+
+func (r *CollectRequest) UnmarshalJSON(data []byte) error {
+	type plain CollectRequest
+	return UnmarshalCompat(data, (*plain)(r), &r.Deprecations, "collect", "", true)
+}
+
+func (r *BackupBatchRequest) UnmarshalJSON(data []byte) error {
+	type plain BackupBatchRequest
+	return UnmarshalCompat(data, (*plain)(r), &r.Deprecations, "backup_batch", "devices", false)
+}
+
+func (r *FormatBatchRequest) UnmarshalJSON(data []byte) error {
+	type plain FormatBatchRequest
+	return UnmarshalCompat(data, (*plain)(r), &r.Deprecations, "format_batch", "devices", false)
+}
+
+func (r *FormatFastRequest) UnmarshalJSON(data []byte) error {
+	type plain FormatFastRequest
+	return UnmarshalCompat(data, (*plain)(r), &r.Deprecations, "format_fast", "device", false)
+}
+
+func (r *DeployFastRequest) UnmarshalJSON(data []byte) error {
+	type plain DeployFastRequest
+	return UnmarshalCompat(data, (*plain)(r), &r.Deprecations, "deploy_fast", "devices", false)
+}
+
+func (r *ComplianceRequest) UnmarshalJSON(data []byte) error {
+	type plain ComplianceRequest
+	return UnmarshalCompat(data, (*plain)(r), &r.Deprecations, "compliance", "devices", false)
+}
@@ -0,0 +1,421 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sshcollectorpro/sshcollectorpro/pkg/logger"
+)
+
+// 合规规则类型
+const (
+	RuleTypeRegex          = "regex"
+	RuleTypeMustContain    = "must_contain"
+	RuleTypeMustNotContain = "must_not_contain"
+)
+
+// maxEvidenceLines 单条规则返回的证据行上限
+const maxEvidenceLines = 20
+
+// ComplianceRule 合规规则：对指定平台、命令的输出做正则/包含/不包含判断
+type ComplianceRule struct {
+	ID       uint   `json:"id"`
+	Name     string `json:"name"`
+	Platform string `json:"platform,omitempty"` // 为空表示所有平台
+	Command  string `json:"command"`
+	RuleType string `json:"rule_type"` // regex | must_contain | must_not_contain
+	Pattern  string `json:"pattern"`
+	Severity string `json:"severity,omitempty"`
+
+	re *regexp.Regexp // CompileRules 预编译的正则，批次内各设备共用
+}
+
+// CompileRules 预编译 regex 规则，评估多台设备时每条规则只编译一次；
+// 编译失败的规则保持未编译，评估时报告 invalid pattern
+func CompileRules(rules []ComplianceRule) []ComplianceRule {
+	for i := range rules {
+		if rules[i].RuleType == RuleTypeRegex && rules[i].re == nil {
+			rules[i].re, _ = regexp.Compile(rules[i].Pattern)
+		}
+	}
+	return rules
+}
+
+// ComplianceRuleStore 合规规则查询（由接口层注入数据库实现）
+type ComplianceRuleStore interface {
+	// Rules 返回启用的规则；ids 非空时仅返回指定规则
+	Rules(ids []uint) ([]ComplianceRule, error)
+}
+
+// ComplianceReportStore 审计报告持久化（由接口层注入数据库实现）
+type ComplianceReportStore interface {
+	SaveReport(report *ComplianceReport, raw []byte) (uint, error)
+}
+
+// ComplianceRequest 合规检查请求：按规则涉及的命令采集并逐设备评估
+type ComplianceRequest struct {
+	TaskID         string             `json:"task_id"`
+	TaskName       string             `json:"task_name,omitempty"`
+	RuleIDs        []uint             `json:"rule_ids,omitempty"` // 为空表示全部启用规则
+	SaveDir        string             `json:"save_dir,omitempty"`
+	StorageBackend string             `json:"storage_backend,omitempty"` // local | minio（默认读取配置）
+	RetryFlag      *int               `json:"retry_flag,omitempty"`
+	TaskTimeout    *int               `json:"task_timeout,omitempty"`
+	Devices        []ComplianceDevice `json:"devices"`
+	Deprecations   `json:"-"`         // 请求中使用的旧字段名（兼容层填充）
+}
+
+// ComplianceDevice 参与合规检查的设备
+type ComplianceDevice struct {
+	DeviceIP        string `json:"device_ip"`
+	Port            int    `json:"device_port,omitempty"`
+	DeviceName      string `json:"device_name,omitempty"`
+	DevicePlatform  string `json:"device_platform"`
+	CollectProtocol string `json:"collect_protocol,omitempty"`
+	UserName        string `json:"user_name"`
+	Password        string `json:"password"`
+	EnablePassword  string `json:"enable_password,omitempty"`
+	CredentialID    string `json:"credential_id,omitempty"` // 引用已登记凭据（接口层解析）
+	DeviceTimeout   *int   `json:"device_timeout,omitempty"`
+}
+
+// RuleResult 单条规则的评估结果
+type RuleResult struct {
+	RuleID   uint     `json:"rule_id"`
+	RuleName string   `json:"rule_name"`
+	Command  string   `json:"command"`
+	RuleType string   `json:"rule_type"`
+	Severity string   `json:"severity,omitempty"`
+	Passed   bool     `json:"passed"`
+	Evidence []string `json:"evidence"` // 命中行（must_not_contain 为违规行）
+	Error    string   `json:"error,omitempty"`
+
+	matched int // 命中行总数（证据最多保留 maxEvidenceLines 行）
+}
+
+// DeviceComplianceResult 单设备合规结果
+type DeviceComplianceResult struct {
+	DeviceIP       string       `json:"device_ip"`
+	Port           int          `json:"port"`
+	DeviceName     string       `json:"device_name,omitempty"`
+	DevicePlatform string       `json:"device_platform,omitempty"`
+	Success        bool         `json:"success"`   // 采集是否成功
+	Compliant      bool         `json:"compliant"` // 全部规则通过
+	Passed         int          `json:"passed"`
+	Failed         int          `json:"failed"`
+	Rules          []RuleResult `json:"rules"`
+	Error          string       `json:"error,omitempty"`
+	DurationMS     int64        `json:"duration_ms"`
+}
+
+// ComplianceReport 合规审计报告
+type ComplianceReport struct {
+	ReportID     uint                     `json:"report_id,omitempty"`
+	Code         string                   `json:"code"`
+	Message      string                   `json:"message"`
+	TaskID       string                   `json:"task_id"`
+	TaskName     string                   `json:"task_name,omitempty"`
+	Total        int                      `json:"total"`
+	Compliant    int                      `json:"compliant"`
+	NonCompliant int                      `json:"non_compliant"`
+	Devices      []DeviceComplianceResult `json:"devices"`
+	Stored       *StoredObject            `json:"stored_object,omitempty"`
+	StoreError   string                   `json:"store_error,omitempty"`
+	CreatedAt    time.Time                `json:"created_at"`
+}
+
+// ValidateComplianceRule 校验规则字段与正则语法
+func ValidateComplianceRule(r *ComplianceRule) error {
+	if strings.TrimSpace(r.Name) == "" || strings.TrimSpace(r.Command) == "" || r.Pattern == "" {
+		return validationErrorf("name, command and pattern are required")
+	}
+	switch r.RuleType {
+	case RuleTypeRegex:
+		if _, err := regexp.Compile(r.Pattern); err != nil {
+			return validationErrorf("invalid pattern: %v", err)
+		}
+	case RuleTypeMustContain, RuleTypeMustNotContain:
+	default:
+		return validationErrorf("unsupported rule_type: %s", r.RuleType)
+	}
+	return nil
+}
+
+// EvaluateRule 对命令输出评估单条规则，返回是否通过与证据行
+func EvaluateRule(r ComplianceRule, output string) RuleResult {
+	res := RuleResult{RuleID: r.ID, RuleName: r.Name, Command: r.Command, RuleType: r.RuleType, Severity: r.Severity, Evidence: []string{}}
+	match := func(string) bool { return false }
+	switch r.RuleType {
+	case RuleTypeRegex:
+		re := r.re
+		if re == nil {
+			var err error
+			if re, err = regexp.Compile(r.Pattern); err != nil {
+				res.Error = "invalid pattern: " + err.Error()
+				return res
+			}
+		}
+		match = re.MatchString
+	case RuleTypeMustContain, RuleTypeMustNotContain:
+		match = func(ln string) bool { return strings.Contains(ln, r.Pattern) }
+	default:
+		res.Error = "unsupported rule_type: " + r.RuleType
+		return res
+	}
+	for _, ln := range strings.Split(strings.ReplaceAll(output, "\r\n", "\n"), "\n") {
+		if !match(ln) {
+			continue
+		}
+		res.matched++
+		if len(res.Evidence) < maxEvidenceLines {
+			res.Evidence = append(res.Evidence, ln)
+		}
+	}
+	if r.RuleType == RuleTypeMustNotContain {
+		res.Passed = len(res.Evidence) == 0
+	} else {
+		res.Passed = len(res.Evidence) > 0
+	}
+	return res
+}
+
+// rulesForPlatform 选出适用于平台的规则
+func rulesForPlatform(rules []ComplianceRule, platform string) []ComplianceRule {
+	p := strings.ToLower(strings.TrimSpace(platform))
+	out := make([]ComplianceRule, 0, len(rules))
+	for _, r := range rules {
+		rp := strings.ToLower(strings.TrimSpace(r.Platform))
+		if rp == "" || rp == p {
+			out = append(out, r)
+		}
+	}
+	return out
+}
+
+// ComplianceService 合规检查：复用备份服务的连接池、并发令牌与存储写入
+type ComplianceService struct {
+	backup  *BackupService
+	rules   ComplianceRuleStore
+	reports ComplianceReportStore
+}
+
+// NewComplianceService 创建合规检查服务
+func NewComplianceService(backup *BackupService, rules ComplianceRuleStore, reports ComplianceReportStore) *ComplianceService {
+	return &ComplianceService{backup: backup, rules: rules, reports: reports}
+}
+
+// Run 执行采集并评估规则，生成审计报告（写入存储并持久化索引）
+func (s *ComplianceService) Run(ctx context.Context, req *ComplianceRequest) (*ComplianceReport, error) {
+	if s.backup == nil || !s.backup.running {
+		return nil, serviceStopped("compliance")
+	}
+	if req == nil {
+		return nil, validationErrorf("nil request")
+	}
+	if strings.TrimSpace(req.TaskID) == "" {
+		return nil, validationErrorf("task_id is required")
+	}
+	if len(req.Devices) == 0 {
+		return nil, validationErrorf("devices is empty")
+	}
+	if s.rules == nil {
+		return nil, fmt.Errorf("compliance rule store is not configured")
+	}
+	rules, err := s.rules.Rules(req.RuleIDs)
+	if err != nil {
+		return nil, fmt.Errorf("load compliance rules: %w", err)
+	}
+	if len(rules) == 0 {
+		return nil, validationErrorf("no enabled compliance rules")
+	}
+	rules = CompileRules(rules)
+
+	start := time.Now()
+	results := make([]DeviceComplianceResult, len(req.Devices))
+	var wg sync.WaitGroup
+	for i := range req.Devices {
+		wg.Add(1)
+		go func(idx int, dev ComplianceDevice) {
+			defer wg.Done()
+			results[idx] = s.checkDevice(ctx, req, dev, rulesForPlatform(rules, dev.DevicePlatform))
+		}(i, req.Devices[i])
+	}
+	wg.Wait()
+
+	report := &ComplianceReport{TaskID: req.TaskID, TaskName: req.TaskName, Total: len(results), Devices: results, CreatedAt: start}
+	succeeded := 0
+	for _, d := range results {
+		if d.Compliant {
+			report.Compliant++
+		}
+		if d.Success {
+			succeeded++
+		}
+	}
+	report.NonCompliant = report.Total - report.Compliant
+	outcome := NewBatchOutcome("合规检查任务", report.Total, succeeded)
+	report.Code, report.Message = outcome.Code, outcome.Message
+
+	s.storeReport(ctx, req, report)
+	logger.Info("Compliance check finished", "task_id", req.TaskID, "total", report.Total, "compliant", report.Compliant, "duration", time.Since(start))
+	return report, nil
+}
+
+// checkDevice 采集规则涉及的命令并逐条评估
+func (s *ComplianceService) checkDevice(ctx context.Context, req *ComplianceRequest, dev ComplianceDevice, rules []ComplianceRule) DeviceComplianceResult {
+	port := dev.Port
+	if port < 1 || port > 65535 {
+		port = 22
+	}
+	res := DeviceComplianceResult{DeviceIP: dev.DeviceIP, Port: port, DeviceName: dev.DeviceName, DevicePlatform: dev.DevicePlatform, Rules: []RuleResult{}}
+	start := time.Now()
+	defer func() { res.DurationMS = time.Since(start).Milliseconds() }()
+	if len(rules) == 0 {
+		// 无适用规则视为合规
+		res.Success, res.Compliant = true, true
+		return res
+	}
+
+	effTimeout := s.backup.effectiveTimeout(req.TaskTimeout, dev.DevicePlatform)
+	waitCtx, waitCancel := context.WithTimeout(context.Background(), time.Duration(effTimeout)*time.Second)
+	defer waitCancel()
+	slots, slotErr := s.backup.workers.acquire(waitCtx, nil)
+	if slotErr != nil {
+		res.Error = fmt.Sprintf("queue wait timeout after %ds", effTimeout)
+		return res
+	}
+	defer func() { <-slots }()
+
+	// 规则命令去重，保持首次出现顺序
+	cmds := make([]string, 0, len(rules))
+	seen := map[string]bool{}
+	for _, r := range rules {
+		k := strings.ToLower(strings.TrimSpace(r.Command))
+		if !seen[k] {
+			seen[k] = true
+			cmds = append(cmds, strings.TrimSpace(r.Command))
+		}
+	}
+	execReq := &ExecRequest{
+		DeviceIP:        dev.DeviceIP,
+		Port:            dev.Port,
+		DeviceName:      dev.DeviceName,
+		DevicePlatform:  dev.DevicePlatform,
+		CollectProtocol: dev.CollectProtocol,
+		UserName:        dev.UserName,
+		Password:        dev.Password,
+		EnablePassword:  dev.EnablePassword,
+		TaskTimeoutSec:  effTimeout,
+		DeviceTimeoutSec: func() int {
+			if dev.DeviceTimeout != nil && *dev.DeviceTimeout > 0 {
+				return *dev.DeviceTimeout
+			}
+			return effTimeout
+		}(),
+	}
+	outputs := map[string]string{}
+	failures := map[string]string{}
+	retries := s.backup.effectiveRetries(req.RetryFlag, dev.DevicePlatform)
+	_, err := resolveRetryPolicy(s.backup.conf(), dev.DevicePlatform, nil).do(ctx, retries, nil, func() error {
+		results, err := s.backup.interact.Execute(ctx, execReq, cmds)
+		if err != nil {
+			return err
+		}
+		for _, r := range results {
+			k := strings.ToLower(strings.TrimSpace(r.Command))
+			outputs[k] = r.Output
+			if r.Error != "" {
+				failures[k] = r.Error
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		res.Error = err.Error()
+		return res
+	}
+	res.Success = true
+
+	for _, r := range rules {
+		k := strings.ToLower(strings.TrimSpace(r.Command))
+		var rr RuleResult
+		out, ok := outputs[k]
+		switch {
+		case !ok:
+			rr = RuleResult{RuleID: r.ID, RuleName: r.Name, Command: r.Command, RuleType: r.RuleType, Severity: r.Severity, Evidence: []string{}, Error: "command output missing"}
+		case failures[k] != "":
+			rr = RuleResult{RuleID: r.ID, RuleName: r.Name, Command: r.Command, RuleType: r.RuleType, Severity: r.Severity, Evidence: []string{}, Error: failures[k]}
+		default:
+			rr = EvaluateRule(r, out)
+			if rr.matched > len(rr.Evidence) {
+				AddWarning(ctx, Warning{
+					Code:    WarnTruncated,
+					Message: fmt.Sprintf("device %s rule %q evidence truncated to %d lines", dev.DeviceIP, r.Name, maxEvidenceLines),
+					Context: map[string]interface{}{"device_ip": dev.DeviceIP, "rule_id": r.ID, "matched": rr.matched},
+				})
+			}
+		}
+		if rr.Passed {
+			res.Passed++
+		} else {
+			res.Failed++
+		}
+		res.Rules = append(res.Rules, rr)
+	}
+	res.Compliant = res.Failed == 0
+	return res
+}
+
+// storeReport 报告 JSON 写入备份存储，并记录到报告索引；失败仅记录在报告中
+func (s *ComplianceService) storeReport(ctx context.Context, req *ComplianceRequest, report *ComplianceReport) {
+	backend := strings.TrimSpace(req.StorageBackend)
+	if backend == "" {
+		backend = strings.TrimSpace(s.backup.conf().Backup.StorageBackend)
+	}
+	if backend == "" {
+		backend = "local"
+	}
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		report.StoreError = err.Error()
+		return
+	}
+	meta := StorageMeta{
+		SaveDir:      req.SaveDir,
+		DateYYYYMMDD: report.CreatedAt.Format("20060102"),
+		TimeHHMMSS:   report.CreatedAt.Format("150405"),
+		TaskID:       req.TaskID,
+		DeviceName:   "compliance",
+		CommandSlug:  "audit_report.json",
+		Backend:      backend,
+		SkipFilter:   true,
+	}
+	obj, werr := s.backup.storageWriter.Write(ctx, meta, string(data), "application/json; charset=utf-8")
+	if obj.URI != "" {
+		report.Stored = &obj
+	}
+	if werr != nil {
+		report.StoreError = werr.Error()
+	}
+	if s.reports == nil {
+		return
+	}
+	// 索引中保存包含存储位置的最终报告
+	if data, err = json.Marshal(report); err != nil {
+		return
+	}
+	id, err := s.reports.SaveReport(report, data)
+	if err != nil {
+		logger.Error("Failed to save compliance report", "task_id", req.TaskID, "error", err)
+		if report.StoreError == "" {
+			report.StoreError = err.Error()
+		}
+		return
+	}
+	report.ReportID = id
+}
@@ -0,0 +1,65 @@
+package service
+
+import "github.com/sshcollectorpro/sshcollectorpro/internal/config"
+
+// 配置读取统一经由 conf() 获取当前快照：热更新整体替换快照而非原地修改，
+// 任务入口处取一次快照并在整个任务内使用，避免同一任务前后读到不同配置。
+
+func (s *CollectorService) conf() *config.Config {
+	if s == nil {
+		return nil
+	}
+	return s.provider.Get()
+}
+
+func (s *BackupService) conf() *config.Config {
+	if s == nil {
+		return nil
+	}
+	return s.provider.Get()
+}
+
+func (s *DeployService) conf() *config.Config {
+	if s == nil {
+		return nil
+	}
+	return s.provider.Get()
+}
+
+func (s *FormatService) conf() *config.Config {
+	if s == nil {
+		return nil
+	}
+	return s.provider.Get()
+}
+
+func (s *RunbookService) conf() *config.Config {
+	if s == nil {
+		return nil
+	}
+	return s.provider.Get()
+}
+
+func (s *AnonymizeService) conf() *config.Config {
+	if s == nil {
+		return nil
+	}
+	return s.provider.Get()
+}
+
+func (b *InteractBasic) conf() *config.Config {
+	if b == nil {
+		return nil
+	}
+	return b.provider.Get()
+}
+
+func (w *DelegatingStorageWriter) conf() *config.Config { return w.provider.Get() }
+
+func (w *LocalStorageWriter) conf() *config.Config { return w.provider.Get() }
+
+func (w *MinioStorageWriter) conf() *config.Config { return w.provider.Get() }
+
+func (w *FormatMinioWriter) conf() *config.Config { return w.provider.Get() }
+
+func (w *AzureBlobStorageWriter) conf() *config.Config { return w.provider.Get() }
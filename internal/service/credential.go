@@ -0,0 +1,80 @@
+package service
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/sshcollectorpro/sshcollectorpro/internal/config"
+)
+
+// 密文前缀：便于后续升级加密格式
+const credentialCipherPrefix = "v1:"
+
+// ErrVaultNotConfigured 未配置主密钥
+var ErrVaultNotConfigured = errors.New("vault master key is not configured")
+
+// CredentialCipher 凭据加解密（AES-256-GCM，主密钥经 SHA-256 派生）
+type CredentialCipher struct {
+	aead cipher.AEAD
+}
+
+// NewCredentialCipher 根据配置中的主密钥创建加解密器
+func NewCredentialCipher(cfg *config.Config) (*CredentialCipher, error) {
+	if cfg == nil || strings.TrimSpace(cfg.Vault.MasterKey) == "" {
+		return nil, ErrVaultNotConfigured
+	}
+	key := sha256.Sum256([]byte(cfg.Vault.MasterKey))
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, fmt.Errorf("init cipher: %w", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("init gcm: %w", err)
+	}
+	return &CredentialCipher{aead: aead}, nil
+}
+
+// Encrypt 加密明文；空串直接返回空串
+func (c *CredentialCipher) Encrypt(plain string) (string, error) {
+	if plain == "" {
+		return "", nil
+	}
+	nonce := make([]byte, c.aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", fmt.Errorf("generate nonce: %w", err)
+	}
+	sealed := c.aead.Seal(nonce, nonce, []byte(plain), nil)
+	return credentialCipherPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt 解密密文；空串直接返回空串
+func (c *CredentialCipher) Decrypt(enc string) (string, error) {
+	if enc == "" {
+		return "", nil
+	}
+	raw, ok := strings.CutPrefix(enc, credentialCipherPrefix)
+	if !ok {
+		return "", fmt.Errorf("unsupported ciphertext format")
+	}
+	data, err := base64.StdEncoding.DecodeString(raw)
+	if err != nil {
+		return "", fmt.Errorf("decode ciphertext: %w", err)
+	}
+	ns := c.aead.NonceSize()
+	if len(data) < ns {
+		return "", fmt.Errorf("ciphertext too short")
+	}
+	plain, err := c.aead.Open(nil, data[:ns], data[ns:], nil)
+	if err != nil {
+		return "", fmt.Errorf("decrypt credential (wrong master key?): %w", err)
+	}
+	return string(plain), nil
+}
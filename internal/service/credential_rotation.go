@@ -0,0 +1,127 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/sshcollectorpro/sshcollectorpro/internal/config"
+	"github.com/sshcollectorpro/sshcollectorpro/pkg/ssh"
+)
+
+// 轮换验证结果
+const (
+	RotationPending     = "pending"      // 尚未验证
+	RotationNewOK       = "new_ok"       // 新密码登录成功
+	RotationRequiresOld = "requires_old" // 新密码被拒绝，原密码仍可登录
+	RotationAuthFailed  = "auth_failed"  // 新旧密码均被拒绝
+	RotationUnreachable = "unreachable"  // 连接失败或超时，未能验证
+)
+
+// RotationTarget 轮换目标设备；old_*_enc 为设备原密码密文，用于回滚
+type RotationTarget struct {
+	DeviceID             string     `json:"device_id"`
+	Name                 string     `json:"name"`
+	IP                   string     `json:"ip"`
+	Port                 int        `json:"port"`
+	DeviceType           string     `json:"device_type"`
+	OldPasswordEnc       string     `json:"old_password_enc,omitempty"`
+	OldEnablePasswordEnc string     `json:"old_enable_password_enc,omitempty"`
+	Result               string     `json:"result"`
+	Error                string     `json:"error,omitempty"`
+	Updated              bool       `json:"updated"`
+	CheckedAt            *time.Time `json:"checked_at,omitempty"`
+}
+
+// LoginVerifier 以给定用户名与密码登录设备，仅完成认证即返回
+type LoginVerifier func(ctx context.Context, ip string, port int, username, password string) error
+
+// NewSSHLoginVerifier 基于 SSH 握手与认证的登录验证（不打开会话、不执行命令）
+func NewSSHLoginVerifier(cfg *config.Config) LoginVerifier {
+	return func(ctx context.Context, ip string, port int, username, password string) error {
+		sshCfg := &ssh.Config{
+			Timeout:        cfg.SSH.Timeout,
+			ConnectTimeout: cfg.SSH.ConnectTimeout,
+			Proxy:          sshProxyConfig(cfg.SSH.Proxy),
+			Egress:         sshEgressPolicy(cfg.SSH.Egress),
+			HostKey:        sshHostKeyPolicy(cfg.SSH.HostKey),
+		}
+		if err := checkAuthThrottle(username, password); err != nil {
+			return err
+		}
+		if err := checkCircuitBreaker(ip); err != nil {
+			return err
+		}
+		client := ssh.NewClient(sshCfg)
+		err := client.Connect(ctx, &ssh.ConnectionInfo{Host: ip, Port: port, Username: username, Password: password})
+		recordAuthResult(username, password, ip, err)
+		recordCircuitResult(ip, err)
+		if err != nil {
+			return err
+		}
+		return client.Close()
+	}
+}
+
+// VerifyRotation 按并发上限逐设备验证：先用新密码登录，认证被拒时再用原密码判断设备是否仍需旧密码
+// oldPassword 返回设备原密码（解密失败时返回错误，结果记为 auth_failed）；
+// 结果经 done 回调交给调用方写入（可能并发调用，调用方负责加锁），VerifyRotation 不修改 targets
+func VerifyRotation(ctx context.Context, targets []*RotationTarget, username, newPassword string, oldPassword func(*RotationTarget) (string, error), concurrency int, verify LoginVerifier, done func(t *RotationTarget, result, message string)) {
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for _, t := range targets {
+		if ctx.Err() != nil {
+			break
+		}
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(t *RotationTarget) {
+			defer func() { <-sem; wg.Done() }()
+			result, msg := verifyRotationTarget(ctx, t, username, newPassword, oldPassword, verify)
+			done(t, result, msg)
+		}(t)
+	}
+	wg.Wait()
+}
+
+func verifyRotationTarget(ctx context.Context, t *RotationTarget, username, newPassword string, oldPassword func(*RotationTarget) (string, error), verify LoginVerifier) (string, string) {
+	err := verify(ctx, t.IP, t.Port, username, newPassword)
+	if err == nil {
+		return RotationNewOK, ""
+	}
+	if ClassifyErrorMessage(err.Error()) != ErrorClassAuth {
+		return RotationUnreachable, err.Error()
+	}
+	old, derr := oldPassword(t)
+	if derr != nil {
+		return RotationAuthFailed, derr.Error()
+	}
+	if old == "" || old == newPassword {
+		return RotationAuthFailed, err.Error()
+	}
+	if oerr := verify(ctx, t.IP, t.Port, username, old); oerr != nil {
+		if ClassifyErrorMessage(oerr.Error()) == ErrorClassAuth {
+			return RotationAuthFailed, err.Error()
+		}
+		return RotationUnreachable, oerr.Error()
+	}
+	return RotationRequiresOld, "new password rejected, old password still accepted"
+}
+
+// RotationCounts 按验证结果统计：新密码可用、仍需旧密码、失败（含未验证）
+func RotationCounts(targets []*RotationTarget) (newOK, requiresOld, failed int) {
+	for _, t := range targets {
+		switch t.Result {
+		case RotationNewOK:
+			newOK++
+		case RotationRequiresOld:
+			requiresOld++
+		default:
+			failed++
+		}
+	}
+	return
+}
@@ -0,0 +1,257 @@
+package service
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"regexp"
+	"runtime"
+	"runtime/pprof"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sshcollectorpro/sshcollectorpro/internal/config"
+)
+
+// processStart 进程启动时间（诊断包中计算运行时长）
+var processStart = time.Now()
+
+// DebugBundle 诊断包：按文件名收集各部分内容，最终写为 zip
+// 单个部分采集失败时记录到 errors.txt，不影响其余部分
+type DebugBundle struct {
+	names  []string
+	files  map[string][]byte
+	errors []string
+}
+
+// NewDebugBundle 创建空诊断包
+func NewDebugBundle() *DebugBundle {
+	return &DebugBundle{files: map[string][]byte{}}
+}
+
+// Add 添加文件；同名文件覆盖
+func (b *DebugBundle) Add(name string, data []byte) {
+	if _, ok := b.files[name]; !ok {
+		b.names = append(b.names, name)
+	}
+	b.files[name] = data
+}
+
+// AddJSON 以缩进 JSON 添加文件
+func (b *DebugBundle) AddJSON(name string, v interface{}) {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		b.AddError(name, err)
+		return
+	}
+	b.Add(name, data)
+}
+
+// AddError 记录某部分的采集错误
+func (b *DebugBundle) AddError(section string, err error) {
+	b.errors = append(b.errors, fmt.Sprintf("%s: %v", section, err))
+}
+
+// WriteZip 写出 zip；存在采集错误时附带 errors.txt
+func (b *DebugBundle) WriteZip(w io.Writer) error {
+	zw := zip.NewWriter(w)
+	now := time.Now()
+	write := func(name string, data []byte) error {
+		fw, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: now})
+		if err != nil {
+			return err
+		}
+		_, err = fw.Write(data)
+		return err
+	}
+	for _, name := range b.names {
+		if err := write(name, b.files[name]); err != nil {
+			return err
+		}
+	}
+	if len(b.errors) > 0 {
+		if err := write("errors.txt", []byte(strings.Join(b.errors, "\n")+"\n")); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+// CaptureProfiles 添加 goroutine 栈（文本）与 heap profile（pprof 格式，可用 go tool pprof 打开）
+func (b *DebugBundle) CaptureProfiles() {
+	var buf bytes.Buffer
+	if err := pprof.Lookup("goroutine").WriteTo(&buf, 2); err != nil {
+		b.AddError("goroutines.txt", err)
+	} else {
+		b.Add("goroutines.txt", buf.Bytes())
+	}
+	var heap bytes.Buffer
+	if err := pprof.Lookup("heap").WriteTo(&heap, 0); err != nil {
+		b.AddError("heap.pprof", err)
+	} else {
+		b.Add("heap.pprof", heap.Bytes())
+	}
+}
+
+// RuntimeStats Go 运行时概况
+func RuntimeStats() map[string]interface{} {
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+	return map[string]interface{}{
+		"go_version":     runtime.Version(),
+		"goos":           runtime.GOOS,
+		"goarch":         runtime.GOARCH,
+		"num_cpu":        runtime.NumCPU(),
+		"gomaxprocs":     runtime.GOMAXPROCS(0),
+		"goroutines":     runtime.NumGoroutine(),
+		"uptime_seconds": int64(time.Since(processStart).Seconds()),
+		"memory": map[string]interface{}{
+			"alloc_bytes":       m.Alloc,
+			"total_alloc_bytes": m.TotalAlloc,
+			"sys_bytes":         m.Sys,
+			"heap_inuse_bytes":  m.HeapInuse,
+			"heap_objects":      m.HeapObjects,
+			"num_gc":            m.NumGC,
+			"pause_total_ns":    m.PauseTotalNs,
+		},
+	}
+}
+
+// sensitiveKey 需脱敏的配置项与请求头名称（比较前去除 _ 与 -）
+var sensitiveKey = regexp.MustCompile(`(?i)(password|secret|token|masterkey|accesskey|accountkey|apikey|authorization|cookie)`)
+
+// redactedValue 脱敏后的占位值
+const redactedValue = "******"
+
+// sensitiveQueryParam URL 查询参数中的凭据（群机器人 webhook 的 key、access_token、加签 sign 等）
+var sensitiveQueryParam = regexp.MustCompile(`(?i)^(key|token|access_token|secret|sign)$`)
+
+// RedactConfig 配置转为通用结构并脱敏：密码、密钥、令牌类字段与请求头替换为占位值，URL 中的用户信息去除密码
+func RedactConfig(cfg *config.Config) (interface{}, error) {
+	if cfg == nil {
+		return nil, fmt.Errorf("config not loaded")
+	}
+	data, err := json.Marshal(cfg)
+	if err != nil {
+		return nil, err
+	}
+	var v interface{}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return nil, err
+	}
+	return redactValue("", v), nil
+}
+
+// redactURL 去除 URL 用户信息中的密码，并将凭据类查询参数替换为占位值（保留参数顺序）
+func redactURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return raw
+	}
+	changed := false
+	if u.User != nil {
+		if _, ok := u.User.Password(); ok {
+			u.User = url.UserPassword(u.User.Username(), "xxxxx")
+			changed = true
+		}
+	}
+	if u.RawQuery != "" {
+		parts := strings.Split(u.RawQuery, "&")
+		for i, p := range parts {
+			name, _, _ := strings.Cut(p, "=")
+			if n, err := url.QueryUnescape(name); err == nil && sensitiveQueryParam.MatchString(n) {
+				parts[i] = name + "=" + redactedValue
+				changed = true
+			}
+		}
+		u.RawQuery = strings.Join(parts, "&")
+	}
+	if !changed {
+		return raw
+	}
+	return u.String()
+}
+
+// RedactedSettings 按配置键组织的生效配置并脱敏（GET /api/v1/config）
+func RedactedSettings(cfg *config.Config) (interface{}, error) {
+	if cfg == nil {
+		return nil, fmt.Errorf("config not loaded")
+	}
+	return redactValue("", config.Settings(cfg)), nil
+}
+
+func redactValue(key string, v interface{}) interface{} {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		for k, item := range t {
+			t[k] = redactValue(k, item)
+		}
+		return t
+	case []interface{}:
+		for i, item := range t {
+			t[i] = redactValue(key, item)
+		}
+		return t
+	case string:
+		if t == "" {
+			return t
+		}
+		if sensitiveKey.MatchString(strings.NewReplacer("_", "", "-", "").Replace(key)) {
+			return redactedValue
+		}
+		if strings.Contains(t, "://") {
+			return redactURL(t)
+		}
+		return t
+	default:
+		return v
+	}
+}
+
+// logTimestamp 日志行时间（text 与 json 两种格式，均为 2006-01-02 15:04:05 本地时间）
+var logTimestamp = regexp.MustCompile(`time"?[:=]"(\d{4}-\d{2}-\d{2} \d{2}:\d{2}:\d{2})"`)
+
+// RecentLogLines 保留 since 之后的日志行，最多 max 行（取末尾）
+// 无时间戳的行（如多行堆栈）跟随前一行的判定
+func RecentLogLines(lines []string, since time.Time, max int) []string {
+	keep := false
+	out := make([]string, 0, len(lines))
+	for _, ln := range lines {
+		if m := logTimestamp.FindStringSubmatch(ln); m != nil {
+			ts, err := time.ParseInLocation("2006-01-02 15:04:05", m[1], time.Local)
+			keep = err != nil || !ts.Before(since)
+		}
+		if keep {
+			out = append(out, ln)
+		}
+	}
+	if max > 0 && len(out) > max {
+		out = out[len(out)-max:]
+	}
+	return out
+}
+
+// DebugBundleManifest 诊断包说明（manifest.json）
+type DebugBundleManifest struct {
+	GeneratedAt time.Time `json:"generated_at"`
+	Since       time.Time `json:"since"`
+	CollectorID string    `json:"collector_id,omitempty"`
+	Version     string    `json:"version,omitempty"`
+	Files       []string  `json:"files"`
+}
+
+// Manifest 生成当前已添加文件的说明
+func (b *DebugBundle) Manifest(cfg *config.Config, since time.Time) DebugBundleManifest {
+	files := append([]string(nil), b.names...)
+	sort.Strings(files)
+	m := DebugBundleManifest{GeneratedAt: time.Now(), Since: since, Files: files}
+	if cfg != nil {
+		m.CollectorID = cfg.Collector.ID
+		m.Version = cfg.Collector.Version
+	}
+	return m
+}
@@ -0,0 +1,129 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/sshcollectorpro/sshcollectorpro/internal/config"
+)
+
+// 批内重复设备（device_ip + 端口 + 用户名相同）的处理方式
+const (
+	DuplicateModeReject = "reject" // 拒绝整个请求
+	DuplicateModeMerge  = "merge"  // 合并为一台设备执行（命令取并集），响应只保留首次出现的位置
+	DuplicateModeCopy   = "copy"   // 合并执行一次，结果复制到每个重复位置（响应与请求一一对应）
+)
+
+// ErrDuplicateDevices 批内存在重复设备且处理方式为 reject
+var ErrDuplicateDevices = withKind(ErrValidation, errors.New("duplicate devices in batch"))
+
+// DuplicateDevice 重复设备条目
+type DuplicateDevice struct {
+	Index       int    `json:"index"`        // 在请求设备列表中的位置（从 0 开始）
+	DuplicateOf int    `json:"duplicate_of"` // 首次出现的位置
+	DeviceIP    string `json:"device_ip"`
+	Port        int    `json:"device_port"`
+	UserName    string `json:"user_name"`
+}
+
+// DeviceDedup 批内去重结果
+type DeviceDedup struct {
+	Mode       string
+	Duplicates []DuplicateDevice
+	primary    []int // primary[i] 为第 i 台设备首次出现的位置
+}
+
+// ValidateDuplicateMode 校验重复设备处理方式：为空表示使用配置默认
+func ValidateDuplicateMode(mode string) error {
+	switch strings.ToLower(strings.TrimSpace(mode)) {
+	case "", DuplicateModeReject, DuplicateModeMerge, DuplicateModeCopy:
+		return nil
+	}
+	return validationErrorf("invalid duplicate_mode %q: must be reject, merge or copy", mode)
+}
+
+// resolveDuplicateMode 请求未指定时取 collector.duplicate_devices，均未配置时为 copy
+func resolveDuplicateMode(cfg *config.Config, mode string) string {
+	mode = strings.ToLower(strings.TrimSpace(mode))
+	if mode == "" && cfg != nil {
+		mode = strings.ToLower(strings.TrimSpace(cfg.Collector.DuplicateDevices))
+	}
+	if mode == "" {
+		mode = DuplicateModeCopy
+	}
+	return mode
+}
+
+// DedupDevices 按 device_ip + 端口（缺省 22）+ 用户名识别重复设备；reject 模式存在重复时返回 ErrDuplicateDevices（结果仍含重复列表）
+func DedupDevices(cfg *config.Config, mode string, n int, key func(i int) (ip string, port int, user string)) (*DeviceDedup, error) {
+	if err := ValidateDuplicateMode(mode); err != nil {
+		return nil, err
+	}
+	d := &DeviceDedup{Mode: resolveDuplicateMode(cfg, mode), primary: make([]int, n)}
+	first := make(map[string]int, n)
+	for i := 0; i < n; i++ {
+		ip, port, user := key(i)
+		if port < 1 || port > 65535 {
+			port = 22
+		}
+		k := strings.ToLower(strings.TrimSpace(ip)) + ":" + strconv.Itoa(port) + "@" + strings.TrimSpace(user)
+		p, seen := first[k]
+		if !seen {
+			first[k] = i
+			d.primary[i] = i
+			continue
+		}
+		d.primary[i] = p
+		d.Duplicates = append(d.Duplicates, DuplicateDevice{Index: i, DuplicateOf: p, DeviceIP: strings.TrimSpace(ip), Port: port, UserName: strings.TrimSpace(user)})
+	}
+	if d.Mode == DuplicateModeReject && len(d.Duplicates) > 0 {
+		parts := make([]string, 0, len(d.Duplicates))
+		for _, dup := range d.Duplicates {
+			parts = append(parts, fmt.Sprintf("devices[%d] duplicates devices[%d] (%s:%d %s)", dup.Index, dup.DuplicateOf, dup.DeviceIP, dup.Port, dup.UserName))
+		}
+		return d, fmt.Errorf("%w: %s", ErrDuplicateDevices, strings.Join(parts, "; "))
+	}
+	return d, nil
+}
+
+// HasDuplicates 是否存在重复设备
+func (d *DeviceDedup) HasDuplicates() bool { return d != nil && len(d.Duplicates) > 0 }
+
+// Primary 第 i 台设备首次出现的位置；无重复时为 i
+func (d *DeviceDedup) Primary(i int) int {
+	if d == nil || i < 0 || i >= len(d.primary) {
+		return i
+	}
+	return d.primary[i]
+}
+
+// IsDuplicate 第 i 台设备是否为重复出现
+func (d *DeviceDedup) IsDuplicate(i int) bool { return d.Primary(i) != i }
+
+// Kept 首次出现的设备位置（按请求顺序）
+func (d *DeviceDedup) Kept() []int {
+	out := make([]int, 0, len(d.primary))
+	for i, p := range d.primary {
+		if p == i {
+			out = append(out, i)
+		}
+	}
+	return out
+}
+
+// MergeCLIList 将 src 中 dst 尚未包含的命令（按命令文本）追加到 dst
+func MergeCLIList(dst, src CLIList) CLIList {
+	seen := make(map[string]bool, len(dst))
+	for _, it := range dst {
+		seen[strings.TrimSpace(it.CLI)] = true
+	}
+	for _, it := range src {
+		if k := strings.TrimSpace(it.CLI); !seen[k] {
+			seen[k] = true
+			dst = append(dst, it)
+		}
+	}
+	return dst
+}
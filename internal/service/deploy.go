@@ -3,37 +3,42 @@ package service
 import (
 	"context"
 	"strings"
+	"text/template"
 	"time"
 
 	"github.com/sshcollectorpro/sshcollectorpro/internal/config"
 	"github.com/sshcollectorpro/sshcollectorpro/pkg/logger"
 	"github.com/sshcollectorpro/sshcollectorpro/pkg/ssh"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // DeployService 提供设备配置快速下发与状态采集能力
 type DeployService struct {
-	cfg       *config.Config
+	provider  *config.Provider
 	collector *CollectorService
 	sshPool   *ssh.Pool
+	inventory DeviceInventory
 }
 
 func NewDeployService(cfg *config.Config, collector *CollectorService) *DeployService {
-	return &DeployService{cfg: cfg, collector: collector, sshPool: collector.sshPool}
+	return &DeployService{provider: config.ProviderFor(cfg), collector: collector, sshPool: collector.sshPool}
 }
 
 func (s *DeployService) Start(ctx context.Context) error {
 	// 输出配置下发服务启动信息与关键 SSH 参数，便于现场定位
-	if s == nil || s.cfg == nil {
+	cfg := s.conf()
+	if cfg == nil {
 		logger.Info("Deploy service started")
 		return nil
 	}
 	logger.Info(
 		"Deploy service started",
-		"ssh_timeout_all", s.cfg.SSH.Timeout,
-		"ssh_connect_timeout", s.cfg.SSH.ConnectTimeout,
-		"ssh_keep_alive_interval", s.cfg.SSH.KeepAliveInterval,
-		"ssh_max_sessions", s.cfg.SSH.MaxSessions,
-		"deploy_wait_ms", s.cfg.Deploy.DeployWaitMS,
+		"ssh_timeout_all", cfg.SSH.Timeout,
+		"ssh_connect_timeout", cfg.SSH.ConnectTimeout,
+		"ssh_keep_alive_interval", cfg.SSH.KeepAliveInterval,
+		"ssh_max_sessions", cfg.SSH.MaxSessions,
+		"deploy_wait_ms", cfg.Deploy.DeployWaitMS,
 	)
 	return nil
 }
@@ -50,7 +55,16 @@ type DeployFastRequest struct {
 	TaskType          string         `json:"task_type"` // exec/dry_run
 	TaskTimeout       int            `json:"task_timeout"`
 	StatusCheckEnable int            `json:"status_check_enable"` // 1 开启/0 关闭
+	CallbackURL       string         `json:"callback_url,omitempty"` // 下发完成后推送设备摘要
+	Deadline          *time.Time     `json:"deadline,omitempty"`     // 变更窗口截止时间，到点后不再下发新设备
+	OutputEncoding    string         `json:"output_encoding,omitempty"` // 响应中控制字符编码：raw | strip | escape | base64
+	ConfigTemplate    string                 `json:"config_template,omitempty"` // 黄金配置模板（Go text/template），按设备渲染后下发
+	Variables         map[string]interface{} `json:"variables,omitempty"`       // 模板公共变量，设备级 variables 覆盖同名键
+	AutoRollback      bool                   `json:"auto_rollback,omitempty"`   // 下发失败且未提供 rollback_cli_list 时自动生成回滚命令
+	RequireApproval   bool                   `json:"require_approval,omitempty"` // 下发前需经审批（approval.enable 开启时生效）
+	Checkpoint        bool                   `json:"checkpoint,omitempty"`       // 下发前执行平台 checkpoint_cli 生成设备本地还原点，失败则不下发
 	Devices           []DeployDevice `json:"devices"`
+	Deprecations `json:"-"` // 请求中使用的旧字段名（兼容层填充）
 }
 
 // DeployDevice 单设备参数
@@ -63,14 +77,20 @@ type DeployDevice struct {
 	UserName        string   `json:"user_name"`
 	Password        string   `json:"password"`
 	EnablePassword  string   `json:"enable_password"`
-	CliList         []string `json:"cli_list"`
+	CredentialID    string   `json:"credential_id,omitempty"` // 引用已登记凭据（接口层解析）
+	CliList         DeployCLIList `json:"cli_list"` // 配置行与脚本步骤（send_raw/sleep_ms）混排
 	StatusCheckList []string `json:"status_check_list"`
 	ConfigDeploy    string   `json:"config_deploy"`
 	DeviceTimeout   *int     `json:"device_timeout,omitempty"`
+	Variables       map[string]interface{} `json:"variables,omitempty"` // 设备级模板变量
+	RollbackCliList DeployCLIList `json:"rollback_cli_list,omitempty"` // 下发失败时执行的回滚命令
+	Assertions      []DeployAssertion `json:"assertions,omitempty"` // 下发前后状态断言
 }
 
 // DeployFastResponse 响应
 type DeployFastResponse struct {
+	Code     string               `json:"code"` // SUCCESS | PARTIAL_SUCCESS | FAILED
+	Message  string               `json:"message"`
 	TaskID   string               `json:"task_id"`
 	TaskName string               `json:"task_name"`
 	Results  []DeployDeviceResult `json:"results"`
@@ -86,7 +106,32 @@ type DeployDeviceResult struct {
 	DeviceStatusAfter    map[string]string `json:"device_status_after,omitempty"`
 	DeployLogExec        []CommandResult   `json:"deploy_log_exec"`
 	DeployLogsAggregated []CommandResult   `json:"deploy_logs_aggregated,omitempty"`
+	Status               string            `json:"status,omitempty"` // 如 NOT_ATTEMPTED_WINDOW_CLOSED
+	RenderedConfig       string            `json:"rendered_config,omitempty"` // config_template 渲染结果
+	RollbackLogExec      []CommandResult   `json:"rollback_log_exec,omitempty"`
+	RollbackStatus       string            `json:"rollback_status,omitempty"` // ROLLED_BACK | ROLLBACK_FAILED
+	CheckpointLogExec    []CommandResult   `json:"checkpoint_log_exec,omitempty"`
+	CheckpointStatus     string            `json:"checkpoint_status,omitempty"` // SAVED | FAILED | UNSUPPORTED
+	Assertions           []AssertionResult `json:"assertions,omitempty"`
+	AssertionsPassed     *bool             `json:"assertions_passed,omitempty"`
 	Error                string            `json:"error,omitempty"`
+	Timings              *DeviceTimings    `json:"timings,omitempty"` // 状态采集不计入，见各自采集结果
+}
+
+// Outcome 设备是否下发成功：存在错误或任一命令失败即视为失败，返回失败原因
+func (r DeployDeviceResult) Outcome() (bool, string) {
+	if strings.TrimSpace(r.Error) != "" {
+		return false, r.Error
+	}
+	for _, l := range r.DeployLogExec {
+		if l.ExitCode != 0 {
+			return false, strings.TrimSpace(l.Command + ": " + l.Error)
+		}
+	}
+	if msg := firstFailedAssertion(r.Assertions); msg != "" {
+		return false, msg
+	}
+	return true, ""
 }
 
 func canonical(cmd string) string {
@@ -103,17 +148,22 @@ func canonical(cmd string) string {
 
 // 读取平台默认配置（设备默认）
 func (s *DeployService) getDefaults(platform string) (config.PlatformDefaultsConfig, bool) {
+	return deployDefaults(s.conf(), platform)
+}
+
+// deployDefaults 按平台查找设备默认配置：精确匹配优先，其次平台前缀
+func deployDefaults(cfg *config.Config, platform string) (config.PlatformDefaultsConfig, bool) {
 	p := strings.TrimSpace(strings.ToLower(platform))
 	if p == "" {
 		p = "default"
 	}
 	// 优先精确匹配
-	if s.cfg != nil && s.cfg.Collector.DeviceDefaults != nil {
-		if dd, ok := s.cfg.Collector.DeviceDefaults[p]; ok {
+	if cfg != nil && cfg.Collector.DeviceDefaults != nil {
+		if dd, ok := cfg.Collector.DeviceDefaults[p]; ok {
 			return dd, true
 		}
 		// 前缀兜底：当 key 为平台前缀时也可匹配（如 huawei、h3c、cisco_ios、linux）
-		for key, v := range s.cfg.Collector.DeviceDefaults {
+		for key, v := range cfg.Collector.DeviceDefaults {
 			kk := strings.TrimSpace(strings.ToLower(key))
 			if kk == "" {
 				continue
@@ -128,19 +178,97 @@ func (s *DeployService) getDefaults(platform string) (config.PlatformDefaultsCon
 
 // Deploy 执行下发
 func (s *DeployService) Deploy(ctx context.Context, req *DeployFastRequest) (*DeployFastResponse, error) {
+	return s.DeployStream(ctx, req, nil)
+}
+
+// DeployStream 同 Deploy，每台设备完成时回调 onDevice
+func (s *DeployService) DeployStream(ctx context.Context, req *DeployFastRequest, onDevice func(DeployDeviceResult)) (*DeployFastResponse, error) {
+	cfg := s.conf() // 本任务使用的配置快照
+	// 策略校验：只读模式下仅允许 dry_run
+	if strings.EqualFold(strings.TrimSpace(req.TaskType), "exec") {
+		if err := CheckDeployAllowed(); err != nil {
+			return nil, err
+		}
+	}
+	if err := checkDeployApproval(ctx, cfg, req); err != nil {
+		return nil, err
+	}
+	if err := ValidateDeployAssertions(req.Devices); err != nil {
+		return nil, err
+	}
+	for i := range req.Devices {
+		req.Devices[i].DevicePlatform = normalizeRequestPlatform(ctx, cfg, req.Devices[i].DevicePlatform, req.Devices[i].DeviceIP)
+	}
+	// ${var} 变量替换：任务级 variables、设备级 variables 与资产属性
+	if err := s.applyDeployVars(req); err != nil {
+		return nil, err
+	}
+	// 黄金配置模板：先整体校验语法，避免部分设备下发后才发现模板错误
+	var configTpl *template.Template
+	if strings.TrimSpace(req.ConfigTemplate) != "" {
+		tpl, err := parseConfigTemplate("config_template", req.ConfigTemplate)
+		if err != nil {
+			return nil, err
+		}
+		configTpl = tpl
+	}
 	start := time.Now()
 	resp := &DeployFastResponse{TaskID: req.TaskID, TaskName: req.TaskName, Results: make([]DeployDeviceResult, 0, len(req.Devices))}
 	statusEnable := req.StatusCheckEnable
+	ctx, batchSpan := startSpan(ctx, "deploy.batch", attribute.String("task.id", req.TaskID), attribute.String("task.type", req.TaskType), attribute.Int("batch.devices", len(req.Devices)))
+	defer batchSpan.End()
+	var devSpan trace.Span
+
+	// finish 记录设备结果并发布下发事件
+	finish := func(r DeployDeviceResult, devStart time.Time) {
+		if devSpan != nil {
+			ok, msg := r.Outcome()
+			endSpan(devSpan, resultError(ok, msg))
+			devSpan = nil
+		}
+		resp.Results = append(resp.Results, r)
+		publishDeployEvent(cfg, req, r, time.Since(devStart).Milliseconds())
+		if onDevice != nil {
+			onDevice(r)
+		}
+	}
 
 	// 设备循环
 	for _, d := range req.Devices {
-		r := DeployDeviceResult{DeviceIP: d.DeviceIP, DeviceName: d.DeviceName, DevicePlatform: d.DevicePlatform, DeviceStatusBefore: map[string]string{}, DeviceStatusAfter: map[string]string{}}
+		devStart := time.Now()
+		ctx, span := startSpan(ctx, "deploy.device", append(deviceSpanAttrs(d.DeviceIP, d.DeviceName, d.DevicePlatform), attribute.String("task.id", req.TaskID))...)
+		devSpan = span
+		// 设备串行下发：排队时长为批次开始至本设备开始
+		ctx, timings := WithTimings(ctx)
+		timings.AddQueueWait(devStart.Sub(start))
+		r := DeployDeviceResult{DeviceIP: d.DeviceIP, DeviceName: d.DeviceName, DevicePlatform: d.DevicePlatform, DeviceStatusBefore: map[string]string{}, DeviceStatusAfter: map[string]string{}, Timings: timings}
+
+		// 变更窗口关闭：不再开始新设备，已下发设备不受影响
+		if windowClosed(req.Deadline) {
+			r.Status = StatusNotAttemptedWindowClosed
+			r.Error = "execution window closed before dispatch"
+			finish(r, devStart)
+			continue
+		}
+
+		// 模板渲染：未显式提供 cli_list 时以渲染结果作为 config_deploy；渲染失败则跳过该设备
+		if configTpl != nil && len(d.CliList) == 0 {
+			rendered, err := renderDeviceConfig(configTpl, req.Variables, d)
+			if err != nil {
+				r.Error = err.Error()
+				r.DeployLogExec = make([]CommandResult, 0)
+				finish(r, devStart)
+				continue
+			}
+			d.ConfigDeploy = rendered
+			r.RenderedConfig = rendered
+		}
 
 		// 计算有效超时：优先设备级，其次任务级，再次全局，最后回退 15s
 		effTimeout := req.TaskTimeout
 		if effTimeout <= 0 {
-			if s.cfg != nil && s.cfg.SSH.Timeout > 0 {
-				effTimeout = int(s.cfg.SSH.Timeout.Seconds())
+			if cfg != nil && cfg.SSH.Timeout > 0 {
+				effTimeout = int(cfg.SSH.Timeout.Seconds())
 			} else {
 				effTimeout = 15
 			}
@@ -151,9 +279,11 @@ func (s *DeployService) Deploy(ctx context.Context, req *DeployFastRequest) (*De
 		}
 		sshTimeout := time.Duration(devTimeout) * time.Second
 		// 步骤控制标志与执行间隔
-		needsStatus := (statusEnable == 1) && (len(d.StatusCheckList) > 0) && (s.collector != nil)
+		// 配置了断言时无论 status_check_enable 均采集状态
+		statusCmds := statusCheckCommands(d)
+		needsStatus := (statusEnable == 1 || len(d.Assertions) > 0) && (len(statusCmds) > 0) && (s.collector != nil)
 		doDeploy := strings.EqualFold(strings.TrimSpace(req.TaskType), "exec")
-		wait := s.cfg.Deploy.DeployWaitMS
+		wait := cfg.Deploy.DeployWaitMS
 		if wait <= 0 {
 			wait = 2000
 		}
@@ -163,8 +293,8 @@ func (s *DeployService) Deploy(ctx context.Context, req *DeployFastRequest) (*De
 			cTimeout := req.TaskTimeout
 			if cTimeout <= 0 {
 				// 使用全局 ssh.timeout.timeout_all 作为默认值（秒），回退 15s
-				if s.cfg != nil && s.cfg.SSH.Timeout > 0 {
-					cTimeout = int(s.cfg.SSH.Timeout.Seconds())
+				if cfg != nil && cfg.SSH.Timeout > 0 {
+					cTimeout = int(cfg.SSH.Timeout.Seconds())
 				} else {
 					cTimeout = 15
 				}
@@ -182,11 +312,12 @@ func (s *DeployService) Deploy(ctx context.Context, req *DeployFastRequest) (*De
 				UserName:        d.UserName,
 				Password:        d.Password,
 				EnablePassword:  d.EnablePassword,
-				CliList:         d.StatusCheckList,
+				CliList:         NewCLIList(statusCmds...),
 				RetryFlag:       &rf,
 				TaskTimeout:     &cTimeout,
 				DeviceTimeout:   d.DeviceTimeout,
 				Metadata:        map[string]interface{}{"collect_mode": "customer"},
+				internal:        true,
 			}
 			if cresp, err := s.collector.ExecuteTask(ctx, creq); err == nil && cresp != nil {
 				for _, v := range cresp.Results {
@@ -201,12 +332,21 @@ func (s *DeployService) Deploy(ctx context.Context, req *DeployFastRequest) (*De
 			time.Sleep(time.Duration(wait) * time.Millisecond)
 		}
 
+		// 前置断言：任一未通过则不下发
+		if len(d.Assertions) > 0 {
+			r.Assertions = evaluateAssertions(d, AssertPhasePre, r.DeviceStatusBefore)
+			if msg := firstFailedAssertion(r.Assertions); msg != "" && doDeploy {
+				doDeploy = false
+				r.Error = msg
+			}
+		}
+
 		// 配置下发阶段：仅当 task_type=exec 执行
 		if doDeploy {
 			// 建立设备连接并准备交互选项
 			if s.sshPool == nil {
 				r.Error = "ssh pool not initialized"
-				resp.Results = append(resp.Results, r)
+				finish(r, devStart)
 				continue
 			}
 			info := &ssh.ConnectionInfo{
@@ -214,13 +354,19 @@ func (s *DeployService) Deploy(ctx context.Context, req *DeployFastRequest) (*De
 				Port:     d.DevicePort,
 				Username: d.UserName,
 				Password: d.Password,
+				Platform: d.DevicePlatform,
 			}
+			dd, configured := s.getDefaults(d.DevicePlatform)
+			caps := resolveCapabilities(strings.ToLower(strings.TrimSpace(d.DevicePlatform)), dd, configured)
+			caps.applyConnection(info)
 			connCtx, cancel := context.WithTimeout(ctx, sshTimeout)
-			cli, err := s.sshPool.GetConnection(connCtx, info)
+			_, connSpan := startSpan(ctx, "ssh.connect", attribute.String("net.peer.name", info.Host), attribute.Int("net.peer.port", info.Port))
+			cli, err := getTracedConnection(connCtx, s.sshPool, info)
+			endSpan(connSpan, err)
 			cancel()
 			if err != nil {
 				r.Error = "connect failed: " + err.Error()
-				resp.Results = append(resp.Results, r)
+				finish(r, devStart)
 				continue
 			}
 			// 平台交互默认与节奏
@@ -250,18 +396,36 @@ func (s *DeployService) Deploy(ctx context.Context, req *DeployFastRequest) (*De
 				// 新增：设备平台用于区分不同平台的处理逻辑
 				DevicePlatform: strings.TrimSpace(d.DevicePlatform),
 				PromptSuffixes: p.PromptSuffixes,
+				PromptRegex:    p.PromptRegex,
 			}
+			caps.applyInteractive(opts)
 			// 用户下发序列（预命令 + 进入配置模式 + 用户命令 + 退出配置模式）
 			pre := s.getPreCommands(d.DevicePlatform)
 			configEnter := s.getConfigModeCmds(d.DevicePlatform)
 			exitCmd := s.getConfigExitCmd(d.DevicePlatform)
-			// 将 config_deploy 兼容为用户命令列表（当 cli_list 为空时）
-			userCmds := make([]string, 0, len(d.CliList))
-			for _, c := range d.CliList {
-				if t := strings.TrimSpace(c); t != "" {
-					userCmds = append(userCmds, t)
+			// 设备本地检查点：在配置模式外执行，失败时不下发
+			if req.Checkpoint {
+				ckCmds := checkpointCommands(dd, req.TaskID, time.Now())
+				if len(ckCmds) == 0 {
+					r.CheckpointStatus = CheckpointStatusUnsupported
+				} else {
+					ckLogs := filterLogs(ckCmds, s.runCommandsDetailed(ctx, cli, deploySequence(pre, nil, ckCmds, ""), nil, p.PromptSuffixes, opts))
+					markErrorHints(p.ErrorHints, ckLogs)
+					r.CheckpointLogExec = ckLogs
+					r.CheckpointStatus = CheckpointStatusSaved
+					if deployFailed(ckCmds, ckLogs) {
+						r.CheckpointStatus = CheckpointStatusFailed
+						s.sshPool.ReleaseConnection(info)
+						r.Error = "checkpoint failed, deployment skipped"
+						r.DeployLogExec = make([]CommandResult, 0)
+						logger.Warn("Deploy checkpoint failed", "device_ip", d.DeviceIP, "task_id", req.TaskID)
+						finish(r, devStart)
+						continue
+					}
 				}
 			}
+			// 将 config_deploy 兼容为用户命令列表（当 cli_list 为空时）
+			userCmds, userSteps := d.CliList.script()
 			if len(userCmds) == 0 && strings.TrimSpace(d.ConfigDeploy) != "" {
 				raw := strings.ReplaceAll(d.ConfigDeploy, "\r\n", "\n")
 				for _, ln := range strings.Split(raw, "\n") {
@@ -271,86 +435,46 @@ func (s *DeployService) Deploy(ctx context.Context, req *DeployFastRequest) (*De
 				}
 			}
 			// 保留原始用户命令（不进行规范化/映射）
+			// 支持 commit 的平台在退出配置模式前提交
+			userCmds = caps.commitCommand(userCmds)
 			// 条件退出配置模式：在 SSH 交互中根据提示符判定是否需要执行退出
 			opts.ConfigExitCLI = exitCmd
 			opts.ConfigExitConditional = true
-			deploySeq := append([]string{}, pre...)
-			deploySeq = append(deploySeq, configEnter...)
-			deploySeq = append(deploySeq, userCmds...)
-			// 保护：若用户已包含退出命令（如 end/quit），则不再附加平台退出命令
-			userHasExit := false
-			if strings.TrimSpace(exitCmd) != "" {
-				ce := canonical(exitCmd)
-				for _, u := range userCmds {
-					if canonical(u) == ce {
-						userHasExit = true
-						break
-					}
-				}
-			}
-			if !userHasExit && strings.TrimSpace(exitCmd) != "" {
-				deploySeq = append(deploySeq, exitCmd)
-			}
+			deploySeq := deploySequence(pre, configEnter, userCmds, exitCmd)
 
 			// 执行详细日志（逐条）
-			sessionLogs := s.runCommandsDetailed(ctx, cli, deploySeq, p.PromptSuffixes, opts)
-			// 释放连接到全局池（每台设备完成后立即释放，避免 defer 堆积）
-			s.sshPool.ReleaseConnection(info)
+			sessionLogs := s.runCommandsDetailed(ctx, cli, deploySeq, shiftSteps(userSteps, len(pre)+len(configEnter)), p.PromptSuffixes, opts)
 
 			// 仅保留用户命令对应的回显作为 deploy_log_exec
-			include := map[string]struct{}{}
-			for _, c := range userCmds {
-				k := canonical(c)
-				if k != "" {
-					include[k] = struct{}{}
-				}
-			}
-			filteredLogs := make([]CommandResult, 0, len(userCmds))
-			for _, lr := range sessionLogs {
-				key := canonical(lr.Command)
-				if _, ok := include[key]; ok {
-					filteredLogs = append(filteredLogs, lr)
-				}
-			}
+			filteredLogs := filterLogs(userCmds, sessionLogs)
 			// 新增：根据平台错误提示调整 ExitCode 与错误字段，便于定位下发失败
-			if len(filteredLogs) > 0 {
-				// 读取平台错误提示集合
-				pi := s.getPlatformInteract(d.DevicePlatform)
-				for i := range filteredLogs {
-					outLower := strings.ToLower(filteredLogs[i].Output)
-					// 命中任一错误提示则认为下发失败，标记 ExitCode=-1
-					for _, hint := range pi.ErrorHints {
-						h := strings.ToLower(strings.TrimSpace(hint))
-						if h == "" {
-							continue
-						}
-						if strings.Contains(outLower, h) {
-							if filteredLogs[i].ExitCode == 0 {
-								filteredLogs[i].ExitCode = -1
-							}
-							if strings.TrimSpace(filteredLogs[i].Error) == "" {
-								filteredLogs[i].Error = "deployment command error detected"
-							}
-							break
-						}
+			markErrorHints(p.ErrorHints, filteredLogs)
+			// 下发失败时回滚：rollback_cli_list 优先，其次 auto_rollback 自动生成
+			if deployFailed(userCmds, filteredLogs) {
+				if rbCmds, rbSteps := rollbackCommands(req, d, filteredLogs); len(rbCmds) > 0 {
+					rbCmds = caps.commitCommand(rbCmds)
+					rbLogs := filterLogs(rbCmds, s.runCommandsDetailed(ctx, cli, deploySequence(pre, configEnter, rbCmds, exitCmd), shiftSteps(rbSteps, len(pre)+len(configEnter)), p.PromptSuffixes, opts))
+					markErrorHints(p.ErrorHints, rbLogs)
+					r.RollbackLogExec = rbLogs
+					r.RollbackStatus = RollbackStatusRolledBack
+					if deployFailed(rbCmds, rbLogs) {
+						r.RollbackStatus = RollbackStatusFailed
 					}
+					logger.Warn("Deploy failed, rollback executed", "device_ip", d.DeviceIP, "task_id", req.TaskID, "rollback_status", r.RollbackStatus)
 				}
 			}
+			// 释放连接到全局池（每台设备完成后立即释放，避免 defer 堆积）
+			s.sshPool.ReleaseConnection(info)
 			r.DeployLogExec = filteredLogs
 			// 组装聚合输出（模拟粘贴式整体回显）
-			agg := s.aggregateDeployLogs(userCmds, filteredLogs)
+			agg := s.aggregateDeployLogs(userCmds, r.DeployLogExec)
 			r.DeployLogsAggregated = []CommandResult{agg}
 		} else {
 			// 跳过真实下发：构造空执行日志与聚合
 			filteredLogs := make([]CommandResult, 0)
 			r.DeployLogExec = filteredLogs
 			// 使用 config_deploy 或 cli_list 构造聚合命令行，便于前端显示
-			userCmds := make([]string, 0, len(d.CliList))
-			for _, c := range d.CliList {
-				if t := strings.TrimSpace(c); t != "" {
-					userCmds = append(userCmds, t)
-				}
-			}
+			userCmds := d.CliList.Lines()
 			if len(userCmds) == 0 && strings.TrimSpace(d.ConfigDeploy) != "" {
 				raw := strings.ReplaceAll(d.ConfigDeploy, "\r\n", "\n")
 				for _, ln := range strings.Split(raw, "\n") {
@@ -372,8 +496,8 @@ func (s *DeployService) Deploy(ctx context.Context, req *DeployFastRequest) (*De
 		if needsStatus {
 			cTimeout := req.TaskTimeout
 			if cTimeout <= 0 {
-				if s.cfg != nil && s.cfg.SSH.Timeout > 0 {
-					cTimeout = int(s.cfg.SSH.Timeout.Seconds())
+				if cfg != nil && cfg.SSH.Timeout > 0 {
+					cTimeout = int(cfg.SSH.Timeout.Seconds())
 				} else {
 					cTimeout = 15
 				}
@@ -391,11 +515,12 @@ func (s *DeployService) Deploy(ctx context.Context, req *DeployFastRequest) (*De
 				UserName:        d.UserName,
 				Password:        d.Password,
 				EnablePassword:  d.EnablePassword,
-				CliList:         d.StatusCheckList,
+				CliList:         NewCLIList(statusCmds...),
 				RetryFlag:       &rf,
 				TaskTimeout:     &cTimeout,
 				DeviceTimeout:   d.DeviceTimeout,
 				Metadata:        map[string]interface{}{"collect_mode": "customer"},
+				internal:        true,
 			}
 			if cresp, err := s.collector.ExecuteTask(ctx, creq); err == nil && cresp != nil {
 				for _, v := range cresp.Results {
@@ -408,15 +533,56 @@ func (s *DeployService) Deploy(ctx context.Context, req *DeployFastRequest) (*De
 			}
 		}
 
-		resp.Results = append(resp.Results, r)
+		// 后置断言：前置断言阻止下发时不再评估
+		if len(d.Assertions) > 0 {
+			if r.Error == "" || firstFailedAssertion(r.Assertions) == "" {
+				r.Assertions = append(r.Assertions, evaluateAssertions(d, AssertPhasePost, r.DeviceStatusAfter)...)
+			}
+			passed := firstFailedAssertion(r.Assertions) == ""
+			r.AssertionsPassed = &passed
+		}
+
+		finish(r, devStart)
 	}
 	resp.Duration = time.Since(start).String()
+	succeeded := 0
+	for _, r := range resp.Results {
+		if ok, _ := r.Outcome(); ok {
+			succeeded++
+		}
+	}
+	outcome := NewBatchOutcome("配置下发任务", len(resp.Results), succeeded)
+	resp.Code, resp.Message = outcome.Code, outcome.Message
+	batchSpan.SetAttributes(attribute.Int("batch.succeeded", succeeded))
 	return resp, nil
 }
 
+// markErrorHints 输出命中平台错误提示的命令标记为失败（ExitCode=-1）
+func markErrorHints(hints []string, logs []CommandResult) {
+	for i := range logs {
+		outLower := strings.ToLower(logs[i].Output)
+		for _, hint := range hints {
+			h := strings.ToLower(strings.TrimSpace(hint))
+			if h == "" {
+				continue
+			}
+			if strings.Contains(outLower, h) {
+				if logs[i].ExitCode == 0 {
+					logs[i].ExitCode = -1
+				}
+				if strings.TrimSpace(logs[i].Error) == "" {
+					logs[i].Error = "deployment command error detected"
+				}
+				break
+			}
+		}
+	}
+}
+
 // getPlatformInteract 读取平台交互默认，避免与其他服务深耦合，这里做最小复制
 type platformInteract struct {
 	PromptSuffixes           []string
+	PromptRegex              []string
 	AutoInteractions         []ssh.AutoInteraction
 	SkipDelayedEcho          bool
 	EnableCLI                string
@@ -439,6 +605,7 @@ func (s *DeployService) getPlatformInteract(platform string) platformInteract {
 		return p
 	}
 	p.PromptSuffixes = append([]string{}, dd.PromptSuffixes...)
+	p.PromptRegex = append([]string{}, dd.PromptRegex...)
 	// 转换配置中的自动交互项到 SSH 类型
 	p.AutoInteractions = make([]ssh.AutoInteraction, 0, len(dd.Interact.AutoInteractions))
 	for _, ai := range dd.Interact.AutoInteractions {
@@ -547,12 +714,19 @@ func (s *DeployService) getConfigExitCmd(platform string) string {
 }
 
 // runCommandsDetailed 返回详细执行日志（逐条）
-func (s *DeployService) runCommandsDetailed(ctx context.Context, cli *ssh.Client, cmds []string, promptSuffixes []string, opts *ssh.InteractiveOptions) []CommandResult {
+// steps 为按序列下标关联的脚本步骤，仅对本次执行生效
+func (s *DeployService) runCommandsDetailed(ctx context.Context, cli *ssh.Client, cmds []string, steps map[int]ssh.ScriptStep, promptSuffixes []string, opts *ssh.InteractiveOptions) []CommandResult {
 	logs := make([]CommandResult, 0, len(cmds))
 	if len(cmds) == 0 {
 		return logs
 	}
-	results, err := cli.ExecuteInteractiveCommands(ctx, cmds, promptSuffixes, opts)
+	traced := *opts
+	traced.Steps = steps
+	var finishTrace func(error)
+	traced.Stream, finishTrace = traceCommandHooks(ctx, opts.Stream, []string{opts.LoginPassword, opts.EnablePassword})
+	results, err := cli.ExecuteInteractiveCommands(ctx, cmds, promptSuffixes, &traced)
+	finishTrace(err)
+	recordCommandTimings(ctx, results, []string{opts.LoginPassword, opts.EnablePassword})
 	if err != nil {
 		// 即使出错（如上下文超时），客户端也会返回部分结果；继续写入
 		for _, cr := range results {
@@ -0,0 +1,132 @@
+package service
+
+import (
+	"strings"
+)
+
+// 断言阶段
+const (
+	AssertPhasePre  = "pre"  // 评估 device_status_before；失败时不下发
+	AssertPhasePost = "post" // 评估 device_status_after
+)
+
+// DeployAssertion 下发前后状态断言（规则类型与合规检查一致）
+type DeployAssertion struct {
+	Name     string `json:"name"`
+	Phase    string `json:"phase"`     // pre | post，默认 post
+	Command  string `json:"command"`   // 状态采集命令，未在 status_check_list 中时自动追加
+	RuleType string `json:"rule_type"` // regex | must_contain | must_not_contain
+	Pattern  string `json:"pattern"`
+}
+
+// AssertionResult 单条断言结果
+type AssertionResult struct {
+	Name     string   `json:"name"`
+	Phase    string   `json:"phase"`
+	Command  string   `json:"command"`
+	RuleType string   `json:"rule_type"`
+	Passed   bool     `json:"passed"`
+	Evidence []string `json:"evidence"`
+	Error    string   `json:"error,omitempty"`
+}
+
+// assertionPhase 归一化阶段，空值视为 post
+func assertionPhase(a DeployAssertion) string {
+	if strings.EqualFold(strings.TrimSpace(a.Phase), AssertPhasePre) {
+		return AssertPhasePre
+	}
+	return AssertPhasePost
+}
+
+// ValidateDeployAssertions 校验断言定义：阶段、命令与规则
+func ValidateDeployAssertions(devices []DeployDevice) error {
+	for _, d := range devices {
+		for i, a := range d.Assertions {
+			if p := strings.ToLower(strings.TrimSpace(a.Phase)); p != "" && p != AssertPhasePre && p != AssertPhasePost {
+				return validationErrorf("device %s assertion %d: unsupported phase: %s", d.DeviceIP, i+1, a.Phase)
+			}
+			name := a.Name
+			if strings.TrimSpace(name) == "" {
+				name = a.Command
+			}
+			rule := &ComplianceRule{Name: name, Command: a.Command, RuleType: strings.ToLower(strings.TrimSpace(a.RuleType)), Pattern: a.Pattern}
+			if err := ValidateComplianceRule(rule); err != nil {
+				return validationErrorf("device %s assertion %d: %v", d.DeviceIP, i+1, err)
+			}
+		}
+	}
+	return nil
+}
+
+// statusCheckCommands 状态采集命令：status_check_list 与断言命令去重合并
+func statusCheckCommands(d DeployDevice) []string {
+	out := make([]string, 0, len(d.StatusCheckList)+len(d.Assertions))
+	seen := map[string]struct{}{}
+	add := func(c string) {
+		t := strings.TrimSpace(c)
+		if t == "" {
+			return
+		}
+		if _, ok := seen[canonical(t)]; ok {
+			return
+		}
+		seen[canonical(t)] = struct{}{}
+		out = append(out, t)
+	}
+	for _, c := range d.StatusCheckList {
+		add(c)
+	}
+	for _, a := range d.Assertions {
+		add(a.Command)
+	}
+	return out
+}
+
+// evaluateAssertions 对指定阶段的断言按采集输出评估
+func evaluateAssertions(d DeployDevice, phase string, status map[string]string) []AssertionResult {
+	out := make([]AssertionResult, 0, len(d.Assertions))
+	for _, a := range d.Assertions {
+		if assertionPhase(a) != phase {
+			continue
+		}
+		name := strings.TrimSpace(a.Name)
+		if name == "" {
+			name = strings.TrimSpace(a.Command)
+		}
+		res := AssertionResult{Name: name, Phase: phase, Command: strings.TrimSpace(a.Command), RuleType: strings.ToLower(strings.TrimSpace(a.RuleType)), Evidence: []string{}}
+		output, ok := lookupStatus(status, a.Command)
+		if !ok {
+			res.Error = "no status output collected for command"
+			out = append(out, res)
+			continue
+		}
+		rr := EvaluateRule(ComplianceRule{Name: name, Command: res.Command, RuleType: res.RuleType, Pattern: a.Pattern}, output)
+		res.Passed, res.Evidence, res.Error = rr.Passed, rr.Evidence, rr.Error
+		out = append(out, res)
+	}
+	return out
+}
+
+// lookupStatus 按命令查找状态输出（忽略大小写与多余空白）
+func lookupStatus(status map[string]string, cmd string) (string, bool) {
+	if v, ok := status[strings.TrimSpace(cmd)]; ok {
+		return v, true
+	}
+	key := canonical(cmd)
+	for k, v := range status {
+		if canonical(k) == key {
+			return v, true
+		}
+	}
+	return "", false
+}
+
+// firstFailedAssertion 返回首条未通过断言的描述，全部通过返回空串
+func firstFailedAssertion(results []AssertionResult) string {
+	for _, a := range results {
+		if !a.Passed {
+			return a.Phase + "-check assertion failed: " + a.Name
+		}
+	}
+	return ""
+}
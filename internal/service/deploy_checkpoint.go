@@ -0,0 +1,28 @@
+package service
+
+import (
+	"strings"
+	"time"
+
+	"github.com/sshcollectorpro/sshcollectorpro/internal/config"
+)
+
+// 设备本地检查点状态
+const (
+	CheckpointStatusSaved       = "SAVED"
+	CheckpointStatusFailed      = "FAILED"
+	CheckpointStatusUnsupported = "UNSUPPORTED" // 平台未配置 checkpoint_cli，继续下发
+)
+
+// checkpointCommands 平台检查点命令（如 archive config、save checkpoint），
+// 替换 ${task_id} 与 ${timestamp}（20060102150405），便于按任务定位设备上的还原点
+func checkpointCommands(dd config.PlatformDefaultsConfig, taskID string, now time.Time) []string {
+	rep := strings.NewReplacer("${task_id}", taskID, "${timestamp}", now.Format("20060102150405"))
+	cmds := make([]string, 0, len(dd.CheckpointCLI))
+	for _, c := range dd.CheckpointCLI {
+		if t := strings.TrimSpace(c); t != "" {
+			cmds = append(cmds, rep.Replace(t))
+		}
+	}
+	return cmds
+}
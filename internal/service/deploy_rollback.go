@@ -0,0 +1,202 @@
+package service
+
+import (
+	"strings"
+
+	"github.com/sshcollectorpro/sshcollectorpro/pkg/ssh"
+)
+
+// 回滚结果状态
+const (
+	RollbackStatusRolledBack = "ROLLED_BACK"     // 回滚命令全部执行成功
+	RollbackStatusFailed     = "ROLLBACK_FAILED" // 回滚命令存在失败
+)
+
+// contextPrefixes 进入子视图的命令前缀：自动回滚时原样重放以进入上下文
+var contextPrefixes = []string{
+	"interface ", "router ", "vlan ", "line ", "vrf ", "ip vrf ", "ip vpn-instance ",
+	"route-map ", "policy-map ", "class-map ", "bgp ", "ospf ", "isis ", "acl ",
+	"ip access-list ", "user-interface ", "vlan-interface ",
+}
+
+// skipRollback 不参与回滚生成的命令（退出/提交/保存类）
+var skipRollback = map[string]struct{}{
+	"exit": {}, "end": {}, "quit": {}, "return": {}, "commit": {}, "save": {}, "write": {}, "write memory": {},
+}
+
+// isContextCommand 判断命令是否进入子视图
+func isContextCommand(cmd string) bool {
+	lc := strings.ToLower(strings.TrimSpace(cmd))
+	for _, p := range contextPrefixes {
+		if strings.HasPrefix(lc, p) || lc == strings.TrimSpace(p) {
+			return true
+		}
+	}
+	return false
+}
+
+// negateCommand 按平台生成命令的反向形式；已是否定形式时去掉否定前缀
+func negateCommand(platform, cmd string) string {
+	p := strings.ToLower(strings.TrimSpace(platform))
+	c := strings.TrimSpace(cmd)
+	lc := strings.ToLower(c)
+	switch {
+	case strings.HasPrefix(p, "juniper"):
+		if strings.HasPrefix(lc, "set ") {
+			return "delete " + c[4:]
+		}
+		if strings.HasPrefix(lc, "delete ") {
+			return "set " + c[7:]
+		}
+		return ""
+	case strings.HasPrefix(p, "huawei"), strings.HasPrefix(p, "h3c"):
+		if strings.HasPrefix(lc, "undo ") {
+			return strings.TrimSpace(c[5:])
+		}
+		return "undo " + c
+	default:
+		if strings.HasPrefix(lc, "no ") {
+			return strings.TrimSpace(c[3:])
+		}
+		return "no " + c
+	}
+}
+
+// GenerateRollbackCommands 根据已成功下发的命令自动生成回滚命令
+// 规则：按块逆序回滚；子视图命令原样重放后逆序否定其下的配置，再退出子视图
+// 无法生成（如 linux 平台）时返回空
+func GenerateRollbackCommands(platform string, applied []string) []string {
+	p := strings.ToLower(strings.TrimSpace(platform))
+	if p == "linux" {
+		return nil
+	}
+	exitCmd := "exit"
+	if strings.HasPrefix(p, "huawei") || strings.HasPrefix(p, "h3c") {
+		exitCmd = "quit"
+	}
+
+	type block struct {
+		ctx   string
+		items []string
+	}
+	blocks := make([]block, 0, len(applied))
+	inCtx := false
+	for _, raw := range applied {
+		c := strings.TrimSpace(raw)
+		if c == "" {
+			continue
+		}
+		if _, skip := skipRollback[strings.ToLower(c)]; skip {
+			// 退出类命令结束当前子视图
+			inCtx = false
+			continue
+		}
+		if isContextCommand(c) && !strings.HasPrefix(p, "juniper") {
+			blocks = append(blocks, block{ctx: c})
+			inCtx = true
+			continue
+		}
+		// 子视图内的命令归入当前块，直到遇到退出命令或下一个子视图
+		if inCtx {
+			blocks[len(blocks)-1].items = append(blocks[len(blocks)-1].items, c)
+			continue
+		}
+		blocks = append(blocks, block{items: []string{c}})
+	}
+
+	out := make([]string, 0, len(applied)+len(blocks))
+	for i := len(blocks) - 1; i >= 0; i-- {
+		b := blocks[i]
+		if b.ctx != "" {
+			// 仅进入了子视图而无子配置：整体否定该子视图
+			if len(b.items) == 0 {
+				if n := negateCommand(platform, b.ctx); n != "" {
+					out = append(out, n)
+				}
+				continue
+			}
+			out = append(out, b.ctx)
+		}
+		for j := len(b.items) - 1; j >= 0; j-- {
+			if n := negateCommand(platform, b.items[j]); n != "" {
+				out = append(out, n)
+			}
+		}
+		if b.ctx != "" {
+			out = append(out, exitCmd)
+		}
+	}
+	return out
+}
+
+// appliedDeployCommands 首条失败命令之前已成功执行的用户命令（脚本步骤不参与回滚生成）
+func appliedDeployCommands(cli DeployCLIList, logs []CommandResult) []string {
+	out := make([]string, 0, len(logs))
+	for _, l := range logs {
+		if l.ExitCode != 0 {
+			break
+		}
+		if cli.isStep(l.Command) {
+			continue
+		}
+		out = append(out, l.Command)
+	}
+	return out
+}
+
+// deployFailed 下发是否失败：存在失败命令或会话提前中断（部分命令无回显）
+func deployFailed(userCmds []string, logs []CommandResult) bool {
+	if len(logs) < len(userCmds) {
+		return true
+	}
+	for _, l := range logs {
+		if l.ExitCode != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// rollbackCommands 确定回滚命令及其脚本步骤：优先使用设备 rollback_cli_list，其次 auto_rollback 自动生成
+func rollbackCommands(req *DeployFastRequest, d DeployDevice, logs []CommandResult) ([]string, map[int]ssh.ScriptStep) {
+	cmds, steps := d.RollbackCliList.script()
+	if len(cmds) > 0 || !req.AutoRollback {
+		return cmds, steps
+	}
+	return GenerateRollbackCommands(d.DevicePlatform, appliedDeployCommands(d.CliList, logs)), nil
+}
+
+// deploySequence 组装下发序列：预命令 + 进入配置模式 + 用户命令 + 退出配置模式
+// 用户命令已包含退出命令（如 end/quit）时不再附加平台退出命令
+func deploySequence(pre, configEnter, cmds []string, exitCmd string) []string {
+	seq := append([]string{}, pre...)
+	seq = append(seq, configEnter...)
+	seq = append(seq, cmds...)
+	if strings.TrimSpace(exitCmd) == "" {
+		return seq
+	}
+	ce := canonical(exitCmd)
+	for _, u := range cmds {
+		if canonical(u) == ce {
+			return seq
+		}
+	}
+	return append(seq, exitCmd)
+}
+
+// filterLogs 仅保留指定命令对应的日志
+func filterLogs(cmds []string, logs []CommandResult) []CommandResult {
+	include := map[string]struct{}{}
+	for _, c := range cmds {
+		if k := canonical(c); k != "" {
+			include[k] = struct{}{}
+		}
+	}
+	out := make([]CommandResult, 0, len(cmds))
+	for _, l := range logs {
+		if _, ok := include[canonical(l.Command)]; ok {
+			out = append(out, l)
+		}
+	}
+	return out
+}
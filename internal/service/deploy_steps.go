@@ -0,0 +1,186 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/sshcollectorpro/sshcollectorpro/pkg/ssh"
+)
+
+// maxDeploySleepMS 单个 sleep_ms 步骤上限（10 分钟）
+const maxDeploySleepMS = 600000
+
+// DeployCLIItem 下发命令项：配置行或脚本步骤（二者其一）
+type DeployCLIItem struct {
+	CLI  string
+	Step *ssh.ScriptStep
+}
+
+// DeployCLIList 下发命令列表：元素可为配置行字符串，或脚本步骤对象
+// {"send_raw": "\x1a"} 原样发送控制字符（不追加回车，支持 \xNN、\e、\r、\n、\t 转义）
+// {"sleep_ms": 500} 固定等待
+// {"cli": "..."} 与字符串写法等价
+type DeployCLIList []DeployCLIItem
+
+// NewDeployCLIList 由纯配置行构造列表
+func NewDeployCLIList(cmds ...string) DeployCLIList {
+	out := make(DeployCLIList, 0, len(cmds))
+	for _, c := range cmds {
+		out = append(out, DeployCLIItem{CLI: c})
+	}
+	return out
+}
+
+// Lines 可读命令序列：配置行去空白，脚本步骤为可读形式，空行忽略
+func (l DeployCLIList) Lines() []string {
+	out := make([]string, 0, len(l))
+	for _, it := range l {
+		if it.Step != nil {
+			out = append(out, it.Step.String())
+		} else if t := strings.TrimSpace(it.CLI); t != "" {
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+// script 命令序列与按下标关联的脚本步骤（下标与 Lines 一致）
+func (l DeployCLIList) script() ([]string, map[int]ssh.ScriptStep) {
+	lines := l.Lines()
+	var steps map[int]ssh.ScriptStep
+	i := 0
+	for _, it := range l {
+		if it.Step == nil && strings.TrimSpace(it.CLI) == "" {
+			continue
+		}
+		if it.Step != nil {
+			if steps == nil {
+				steps = make(map[int]ssh.ScriptStep)
+			}
+			steps[i] = *it.Step
+		}
+		i++
+	}
+	return lines, steps
+}
+
+// isStep 可读命令是否对应列表中的脚本步骤
+func (l DeployCLIList) isStep(line string) bool {
+	for _, it := range l {
+		if it.Step != nil && it.Step.String() == line {
+			return true
+		}
+	}
+	return false
+}
+
+// shiftSteps 步骤下标整体偏移（用户命令前插入预命令与进入配置模式命令时）
+func shiftSteps(steps map[int]ssh.ScriptStep, offset int) map[int]ssh.ScriptStep {
+	if len(steps) == 0 {
+		return nil
+	}
+	out := make(map[int]ssh.ScriptStep, len(steps))
+	for i, st := range steps {
+		out[i+offset] = st
+	}
+	return out
+}
+
+// CheckCLIText 拒绝以 NUL 开头的命令文本：此类内容不是合法的设备命令，仅可能来自伪造的内部编码
+func CheckCLIText(cmds ...string) error {
+	for i, c := range cmds {
+		if err := checkCLIItem(i, c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func checkCLIItem(i int, cmd string) error {
+	if checkCLIText(cmd) != nil {
+		return fmt.Errorf("cli_list item %d: command must not start with a NUL character", i+1)
+	}
+	return nil
+}
+
+// UnmarshalJSON 兼容字符串与步骤对象混排
+func (l *DeployCLIList) UnmarshalJSON(b []byte) error {
+	var items []json.RawMessage
+	if err := json.Unmarshal(b, &items); err != nil {
+		return err
+	}
+	out := make(DeployCLIList, 0, len(items))
+	for i, raw := range items {
+		raw = bytes.TrimSpace(raw)
+		if len(raw) > 0 && raw[0] == '"' {
+			var s string
+			if err := json.Unmarshal(raw, &s); err != nil {
+				return err
+			}
+			if err := checkCLIItem(i, s); err != nil {
+				return err
+			}
+			out = append(out, DeployCLIItem{CLI: s})
+			continue
+		}
+		var obj struct {
+			CLI     *string `json:"cli"`
+			SendRaw *string `json:"send_raw"`
+			SleepMS *int    `json:"sleep_ms"`
+		}
+		if err := json.Unmarshal(raw, &obj); err != nil {
+			return fmt.Errorf("cli_list item %d must be a string or {\"send_raw\"}/{\"sleep_ms\"}: %w", i+1, err)
+		}
+		set := 0
+		for _, ok := range []bool{obj.CLI != nil, obj.SendRaw != nil, obj.SleepMS != nil} {
+			if ok {
+				set++
+			}
+		}
+		if set != 1 {
+			return fmt.Errorf("cli_list item %d: exactly one of cli, send_raw, sleep_ms is required", i+1)
+		}
+		switch {
+		case obj.CLI != nil:
+			if err := checkCLIItem(i, *obj.CLI); err != nil {
+				return err
+			}
+			out = append(out, DeployCLIItem{CLI: *obj.CLI})
+		case obj.SendRaw != nil:
+			if *obj.SendRaw == "" {
+				return fmt.Errorf("cli_list item %d: send_raw must not be empty", i+1)
+			}
+			st := ssh.SendRawStep(*obj.SendRaw)
+			out = append(out, DeployCLIItem{Step: &st})
+		default:
+			if *obj.SleepMS <= 0 || *obj.SleepMS > maxDeploySleepMS {
+				return fmt.Errorf("cli_list item %d: sleep_ms must be between 1 and %d", i+1, maxDeploySleepMS)
+			}
+			st := ssh.SleepStep(*obj.SleepMS)
+			out = append(out, DeployCLIItem{Step: &st})
+		}
+	}
+	*l = out
+	return nil
+}
+
+// MarshalJSON 脚本步骤还原为对象写法
+func (l DeployCLIList) MarshalJSON() ([]byte, error) {
+	if l == nil {
+		return []byte("null"), nil
+	}
+	items := make([]interface{}, 0, len(l))
+	for _, it := range l {
+		switch {
+		case it.Step == nil:
+			items = append(items, it.CLI)
+		case it.Step.Kind == ssh.StepKindSleepMS:
+			items = append(items, map[string]int{"sleep_ms": it.Step.SleepMS})
+		default:
+			items = append(items, map[string]string{"send_raw": string(it.Step.Raw)})
+		}
+	}
+	return json.Marshal(items)
+}
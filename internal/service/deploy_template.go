@@ -0,0 +1,61 @@
+package service
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"text/template"
+)
+
+// configTemplateFuncs 配置模板可用的辅助函数
+var configTemplateFuncs = template.FuncMap{
+	"upper": strings.ToUpper,
+	"lower": strings.ToLower,
+	"trim":  strings.TrimSpace,
+	// default 变量为空时使用默认值：{{ default "1500" .mtu }}
+	"default": func(def, v interface{}) interface{} {
+		if v == nil {
+			return def
+		}
+		if s, ok := v.(string); ok && strings.TrimSpace(s) == "" {
+			return def
+		}
+		return v
+	},
+}
+
+// parseConfigTemplate 解析 Go text/template 配置模板；引用未定义变量时渲染报错
+func parseConfigTemplate(name, src string) (*template.Template, error) {
+	tpl, err := template.New(name).Funcs(configTemplateFuncs).Option("missingkey=error").Parse(src)
+	if err != nil {
+		return nil, validationErrorf("invalid config_template: %v", err)
+	}
+	return tpl, nil
+}
+
+// templateData 合并任务级与设备级变量（设备级优先），并注入内置 device 变量
+func templateData(global, device map[string]interface{}, d DeployDevice) map[string]interface{} {
+	data := make(map[string]interface{}, len(global)+len(device)+1)
+	for k, v := range global {
+		data[k] = v
+	}
+	for k, v := range device {
+		data[k] = v
+	}
+	data["device"] = map[string]interface{}{
+		"ip":       d.DeviceIP,
+		"name":     d.DeviceName,
+		"platform": d.DevicePlatform,
+		"port":     d.DevicePort,
+	}
+	return data
+}
+
+// renderDeviceConfig 按设备变量渲染配置，返回去除首尾空行的配置文本
+func renderDeviceConfig(tpl *template.Template, global map[string]interface{}, d DeployDevice) (string, error) {
+	var buf bytes.Buffer
+	if err := tpl.Execute(&buf, templateData(global, d.Variables, d)); err != nil {
+		return "", fmt.Errorf("render config_template: %w", err)
+	}
+	return strings.Trim(buf.String(), "\r\n"), nil
+}
@@ -0,0 +1,133 @@
+package service
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// deployVarPattern 匹配 ${name}；$${name} 为转义，输出字面量 ${name}
+var deployVarPattern = regexp.MustCompile(`\$(\$?)\{\s*([A-Za-z_][A-Za-z0-9_.\-]*)\s*\}`)
+
+// DeviceInventory 设备资产属性查询（${inventory.*} 变量来源，由接口层基于 device_info 表实现）
+type DeviceInventory interface {
+	Attributes(deviceIP string, port int) (map[string]string, bool)
+}
+
+// SetInventory 注入设备资产查询
+func (s *DeployService) SetInventory(inv DeviceInventory) {
+	s.inventory = inv
+}
+
+// deployVarScope 变量作用域：设备级覆盖任务级；device.* 为内置设备字段，inventory.* 为资产属性
+func deployVarScope(global, device map[string]interface{}, d DeployDevice, inv map[string]string) map[string]string {
+	scope := make(map[string]string, len(global)+len(device)+len(inv)+4)
+	for k, v := range global {
+		scope[k] = varString(v)
+	}
+	for k, v := range device {
+		scope[k] = varString(v)
+	}
+	scope["device.ip"] = d.DeviceIP
+	scope["device.name"] = d.DeviceName
+	scope["device.platform"] = d.DevicePlatform
+	scope["device.port"] = strconv.Itoa(d.DevicePort)
+	for k, v := range inv {
+		scope["inventory."+k] = v
+	}
+	return scope
+}
+
+// varString 变量值转文本；JSON 数字按整数/小数原样输出（避免 1e+06 形式）
+func varString(v interface{}) string {
+	switch t := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return t
+	case float64:
+		return strconv.FormatFloat(t, 'f', -1, 64)
+	default:
+		return fmt.Sprint(t)
+	}
+}
+
+// substituteVars 替换文本中的 ${name}，未定义的变量记入 undefined 并保留原文
+func substituteVars(s string, scope map[string]string, undefined map[string]struct{}) string {
+	if !strings.Contains(s, "${") {
+		return s
+	}
+	return deployVarPattern.ReplaceAllStringFunc(s, func(m string) string {
+		sub := deployVarPattern.FindStringSubmatch(m)
+		if sub[1] != "" {
+			return "${" + sub[2] + "}"
+		}
+		v, ok := scope[sub[2]]
+		if !ok {
+			undefined[sub[2]] = struct{}{}
+			return m
+		}
+		return v
+	})
+}
+
+// hasVarRef 命令中是否存在变量引用
+func hasVarRef(d DeployDevice) bool {
+	if strings.Contains(d.ConfigDeploy, "${") {
+		return true
+	}
+	for _, l := range []DeployCLIList{d.CliList, d.RollbackCliList} {
+		for _, c := range l {
+			if c.Step == nil && strings.Contains(c.CLI, "${") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// applyDeployVars 下发前替换各设备 cli_list、rollback_cli_list 与 config_deploy 中的变量
+// 任一设备引用未定义变量时整个请求校验失败，避免部分设备已下发
+func (s *DeployService) applyDeployVars(req *DeployFastRequest) error {
+	for i := range req.Devices {
+		d := &req.Devices[i]
+		if !hasVarRef(*d) {
+			continue
+		}
+		var inv map[string]string
+		if s.inventory != nil {
+			port := d.DevicePort
+			if port <= 0 {
+				port = 22
+			}
+			inv, _ = s.inventory.Attributes(d.DeviceIP, port)
+		}
+		scope := deployVarScope(req.Variables, d.Variables, *d, inv)
+		undefined := map[string]struct{}{}
+		subList := func(l DeployCLIList) DeployCLIList {
+			out := make(DeployCLIList, len(l))
+			for j, c := range l {
+				out[j] = c
+				// 脚本步骤（send_raw/sleep_ms）原样保留
+				if c.Step == nil {
+					out[j].CLI = substituteVars(c.CLI, scope, undefined)
+				}
+			}
+			return out
+		}
+		d.CliList = subList(d.CliList)
+		d.RollbackCliList = subList(d.RollbackCliList)
+		d.ConfigDeploy = substituteVars(d.ConfigDeploy, scope, undefined)
+		if len(undefined) > 0 {
+			names := make([]string, 0, len(undefined))
+			for n := range undefined {
+				names = append(names, "${"+n+"}")
+			}
+			sort.Strings(names)
+			return validationErrorf("devices[%d] (%s): undefined variable %s", i, d.DeviceIP, strings.Join(names, ", "))
+		}
+	}
+	return nil
+}
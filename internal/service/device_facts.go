@@ -0,0 +1,264 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/sshcollectorpro/sshcollectorpro/pkg/ssh"
+)
+
+// ErrFactsUnavailable 版本输出为空或未匹配任何厂商特征
+var ErrFactsUnavailable = errors.New("device facts unavailable")
+
+// FactsRequest 设备事实发现请求；device_platform 为空时探测平台
+type FactsRequest struct {
+	DeviceIP        string `json:"device_ip"`
+	Port            int    `json:"device_port,omitempty"`
+	DeviceName      string `json:"device_name,omitempty"`
+	DevicePlatform  string `json:"device_platform,omitempty"`
+	CollectProtocol string `json:"collect_protocol,omitempty"`
+	UserName        string `json:"user_name"`
+	Password        string `json:"password"`
+	EnablePassword  string `json:"enable_password,omitempty"`
+	TaskTimeout     *int   `json:"task_timeout,omitempty"`
+	PortCandidates  []int  `json:"port_candidates,omitempty"`
+}
+
+// DeviceFacts 规范化的设备事实
+type DeviceFacts struct {
+	DeviceIP       string `json:"device_ip"`
+	DeviceName     string `json:"device_name,omitempty"`
+	DevicePlatform string `json:"device_platform"`
+	// PlatformDetected 平台由版本输出的厂商特征识别（请求未指定或无内置解析器）
+	PlatformDetected bool   `json:"platform_detected"`
+	Vendor           string `json:"vendor"`
+	Model            string `json:"model"`
+	OSVersion        string `json:"os_version"`
+	Serial           string `json:"serial"`
+	Uptime           string `json:"uptime"`
+	Hostname         string `json:"hostname,omitempty"`
+	// Command 解析事实所用的命令
+	Command    string `json:"command,omitempty"`
+	RawOutput  string `json:"raw_output,omitempty"`
+	DurationMS int64  `json:"duration_ms"`
+}
+
+// factsParser 内置版本输出解析器：signature 识别厂商，字段取各正则的首个捕获组
+type factsParser struct {
+	platform  string
+	vendor    string
+	command   string
+	signature *regexp.Regexp
+	model     *regexp.Regexp
+	version   *regexp.Regexp
+	serial    *regexp.Regexp
+	uptime    *regexp.Regexp
+	hostname  *regexp.Regexp
+}
+
+// factsProbeCommands 未知平台时依次探测的版本命令（同一会话执行）
+var factsProbeCommands = []string{"show version", "display version"}
+
+// builtinFactsParsers 按特征匹配顺序排列：更具体的系统（NX-OS、IOS XR）先于 IOS
+var builtinFactsParsers = []factsParser{
+	{
+		platform: "cisco_nxos", vendor: "Cisco", command: "show version",
+		signature: regexp.MustCompile(`(?i)Cisco Nexus Operating System|NX-OS`),
+		model:     regexp.MustCompile(`(?m)^\s*cisco (.+?) [Cc]hassis`),
+		version:   regexp.MustCompile(`(?m)^\s*(?:NXOS|system):\s+version\s+(\S+)`),
+		serial:    regexp.MustCompile(`(?m)^\s*Processor Board ID\s+(\S+)`),
+		uptime:    regexp.MustCompile(`(?m)^Kernel uptime is (.+)$`),
+		hostname:  regexp.MustCompile(`(?m)^\s*Device name:\s+(\S+)`),
+	},
+	{
+		platform: "cisco_xr", vendor: "Cisco", command: "show version",
+		signature: regexp.MustCompile(`Cisco IOS XR Software`),
+		model:     regexp.MustCompile(`(?m)^cisco (\S+)`),
+		version:   regexp.MustCompile(`Cisco IOS XR Software, Version ([^\s\[,]+)`),
+		serial:    regexp.MustCompile(`(?m)Processor board ID\s+(\S+)`),
+		uptime:    regexp.MustCompile(`(?m)^\S+ uptime is (.+)$`),
+		hostname:  regexp.MustCompile(`(?m)^(\S+) uptime is`),
+	},
+	{
+		platform: "cisco_ios", vendor: "Cisco", command: "show version",
+		signature: regexp.MustCompile(`Cisco IOS Software|Cisco Internetwork Operating System|IOS-XE Software|Cisco IOS XE Software`),
+		model:     regexp.MustCompile(`(?m)^[Cc]isco (\S+) \(.*\) processor`),
+		version:   regexp.MustCompile(`Version ([^\s,]+)`),
+		serial:    regexp.MustCompile(`(?m)Processor board ID\s+(\S+)`),
+		uptime:    regexp.MustCompile(`(?m)^\S+ uptime is (.+)$`),
+		hostname:  regexp.MustCompile(`(?m)^(\S+) uptime is`),
+	},
+	{
+		platform: "arista_eos", vendor: "Arista", command: "show version",
+		signature: regexp.MustCompile(`Arista`),
+		model:     regexp.MustCompile(`(?m)^Arista (\S+)`),
+		version:   regexp.MustCompile(`(?m)^Software image version:\s+(\S+)`),
+		serial:    regexp.MustCompile(`(?m)^Serial number:\s+(\S+)`),
+		uptime:    regexp.MustCompile(`(?m)^Uptime:\s+(.+)$`),
+	},
+	{
+		platform: "juniper_junos", vendor: "Juniper", command: "show version",
+		signature: regexp.MustCompile(`(?m)^Junos:|JUNOS `),
+		model:     regexp.MustCompile(`(?m)^Model:\s+(\S+)`),
+		version:   regexp.MustCompile(`(?m)^Junos:\s+(\S+)|JUNOS .*\[(\S+)\]`),
+		hostname:  regexp.MustCompile(`(?m)^Hostname:\s+(\S+)`),
+	},
+	{
+		platform: "huawei", vendor: "Huawei", command: "display version",
+		signature: regexp.MustCompile(`Huawei Versatile Routing Platform|HUAWEI`),
+		model:     regexp.MustCompile(`(?m)^(?:HUAWEI|Huawei) (\S+) (?:uptime|Routing Switch|Router)`),
+		version:   regexp.MustCompile(`VRP \(R\) software, Version ([^\s(]+(?: \([^)]*\))?)`),
+		serial:    regexp.MustCompile(`(?m)^\s*(?:ESN|Serial Number)\s*:\s*(\S+)`),
+		uptime:    regexp.MustCompile(`(?m)uptime is (.+)$`),
+	},
+	{
+		platform: "h3c", vendor: "H3C", command: "display version",
+		signature: regexp.MustCompile(`H3C Comware|H3C`),
+		model:     regexp.MustCompile(`(?m)^H3C (\S+) uptime`),
+		version:   regexp.MustCompile(`Comware Software, Version ([^,\s]+(?:, Release \S+)?)`),
+		serial:    regexp.MustCompile(`(?m)^\s*DEVICE_SERIAL_NUMBER\s*:\s*(\S+)`),
+		uptime:    regexp.MustCompile(`(?m)uptime is (.+)$`),
+	},
+}
+
+// factsParserFor 按平台键查找内置解析器：精确匹配，其次按前缀（如 huawei_vrp -> huawei）
+func factsParserFor(platform string) *factsParser {
+	p := strings.ToLower(strings.TrimSpace(platform))
+	if p == "" {
+		return nil
+	}
+	for i := range builtinFactsParsers {
+		if builtinFactsParsers[i].platform == p {
+			return &builtinFactsParsers[i]
+		}
+	}
+	for i := range builtinFactsParsers {
+		if strings.HasPrefix(p, builtinFactsParsers[i].platform) {
+			return &builtinFactsParsers[i]
+		}
+	}
+	return nil
+}
+
+// detectFactsParser 按厂商特征识别版本输出
+func detectFactsParser(output string) *factsParser {
+	for i := range builtinFactsParsers {
+		if builtinFactsParsers[i].signature.MatchString(output) {
+			return &builtinFactsParsers[i]
+		}
+	}
+	return nil
+}
+
+// firstGroup 正则首个非空捕获组
+func firstGroup(re *regexp.Regexp, s string) string {
+	if re == nil {
+		return ""
+	}
+	m := re.FindStringSubmatch(s)
+	for _, g := range m[min(1, len(m)):] {
+		if g = strings.TrimSpace(g); g != "" {
+			return g
+		}
+	}
+	return ""
+}
+
+func (p *factsParser) parse(f *DeviceFacts, output string) {
+	f.Vendor = p.vendor
+	f.Model = firstGroup(p.model, output)
+	f.OSVersion = firstGroup(p.version, output)
+	f.Serial = firstGroup(p.serial, output)
+	f.Uptime = firstGroup(p.uptime, output)
+	f.Hostname = firstGroup(p.hostname, output)
+}
+
+// DiscoverFacts 登录设备执行版本命令并解析规范化事实；未指定平台（或平台无内置解析器）时
+// 依次执行 show version / display version，按厂商特征识别平台
+func (s *CollectorService) DiscoverFacts(ctx context.Context, req *FactsRequest) (*DeviceFacts, error) {
+	cfg := s.conf()
+	if !s.running {
+		return nil, serviceStopped("collector")
+	}
+	if req == nil || strings.TrimSpace(req.DeviceIP) == "" {
+		return nil, validationErrorf("device_ip is required")
+	}
+	platform := ""
+	if strings.TrimSpace(req.DevicePlatform) != "" {
+		platform = normalizeRequestPlatform(ctx, cfg, req.DevicePlatform, req.DeviceIP)
+	}
+	port := req.Port
+	if port < 1 || port > 65535 {
+		port = 22
+	}
+
+	parser := factsParserFor(platform)
+	commands := factsProbeCommands
+	if parser != nil {
+		commands = []string{parser.command}
+	}
+
+	slots, err := s.workers.acquire(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { <-slots }()
+
+	start := time.Now()
+	timeout, devTimeout := s.effectiveTimeouts(&CollectRequest{DevicePlatform: platform, TaskTimeout: req.TaskTimeout})
+	execReq := &ExecRequest{
+		DeviceIP:         req.DeviceIP,
+		Port:             port,
+		DeviceName:       req.DeviceName,
+		DevicePlatform:   platform,
+		CollectProtocol:  req.CollectProtocol,
+		UserName:         req.UserName,
+		Password:         req.Password,
+		EnablePassword:   req.EnablePassword,
+		TaskTimeoutSec:   timeout,
+		DeviceTimeoutSec: devTimeout,
+		PortCandidates:   req.PortCandidates,
+	}
+	results, err := s.interact.Execute(ctx, execReq, commands)
+	if err != nil {
+		return nil, err
+	}
+
+	facts := &DeviceFacts{DeviceIP: req.DeviceIP, DeviceName: req.DeviceName, DevicePlatform: platform}
+	var matched *ssh.CommandResult
+	for _, r := range results {
+		if r == nil || r.Error != "" {
+			continue
+		}
+		if parser != nil {
+			if canonical(r.Command) == canonical(parser.command) {
+				matched = r
+				break
+			}
+			continue
+		}
+		if p := detectFactsParser(r.Output); p != nil {
+			parser, matched = p, r
+			facts.PlatformDetected = true
+			if platform == "" {
+				facts.DevicePlatform, _ = NormalizePlatform(cfg, p.platform)
+			}
+			break
+		}
+	}
+	if matched == nil {
+		if parser != nil {
+			return nil, fmt.Errorf("%w: %s failed", ErrFactsUnavailable, parser.command)
+		}
+		return nil, fmt.Errorf("%w: no vendor signature matched %s", ErrFactsUnavailable, strings.Join(commands, " / "))
+	}
+	facts.Command = matched.Command
+	facts.RawOutput = matched.Output
+	parser.parse(facts, matched.Output)
+	facts.DurationMS = time.Since(start).Milliseconds()
+	return facts, nil
+}
@@ -0,0 +1,352 @@
+package service
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/sshcollectorpro/sshcollectorpro/internal/config"
+)
+
+// ErrImportFormat 上传文件无法解析为设备表（非 CSV/XLSX、缺少表头或必需列）
+var ErrImportFormat = errors.New("invalid device sheet")
+
+// ImportDevice 设备表中的一行；password/enable_password 仅在未引用 credential_id 时使用
+type ImportDevice struct {
+	Row             int     `json:"row"`
+	DeviceIP        string  `json:"device_ip"`
+	Port            int     `json:"device_port,omitempty"`
+	DeviceName      string  `json:"device_name,omitempty"`
+	DevicePlatform  string  `json:"device_platform,omitempty"`
+	CollectProtocol string  `json:"collect_protocol,omitempty"`
+	UserName        string  `json:"user_name,omitempty"`
+	Password        string  `json:"password,omitempty"`
+	EnablePassword  string  `json:"enable_password,omitempty"`
+	CredentialID    string  `json:"credential_id,omitempty"`
+	CliProfile      string  `json:"cli_profile,omitempty"` // 引用命令集（playbook）
+	CliList         CLIList `json:"cli_list,omitempty"`
+}
+
+// ImportRowError 行级校验错误；row 为表格行号（表头为第 1 行）
+type ImportRowError struct {
+	Row     int    `json:"row"`
+	Column  string `json:"column,omitempty"`
+	Value   string `json:"value,omitempty"`
+	Message string `json:"message"`
+}
+
+// importColumns 列名别名（小写，空格与连字符视同下划线）-> 规范列名
+var importColumns = map[string]string{
+	"device_ip": "device_ip", "ip": "device_ip", "host": "device_ip", "address": "device_ip",
+	"name": "device_name", "device_name": "device_name", "hostname": "device_name",
+	"platform": "device_platform", "device_platform": "device_platform",
+	"port": "device_port", "device_port": "device_port",
+	"protocol": "collect_protocol", "collect_protocol": "collect_protocol",
+	"user_name": "user_name", "username": "user_name", "user": "user_name",
+	"password":        "password",
+	"enable_password": "enable_password", "enable": "enable_password",
+	"credential_id": "credential_id", "credential": "credential_id",
+	"cli_profile": "cli_profile", "profile": "cli_profile", "playbook": "cli_profile",
+	"cli_list": "cli_list", "commands": "cli_list",
+}
+
+var hostnameRe = regexp.MustCompile(`^[A-Za-z0-9]([A-Za-z0-9-]*[A-Za-z0-9])?(\.[A-Za-z0-9]([A-Za-z0-9-]*[A-Za-z0-9])?)*$`)
+
+// ReadDeviceSheet 按文件扩展名（或 ZIP 文件头）读取 CSV / XLSX 首个工作表为二维表
+func ReadDeviceSheet(filename string, data []byte) ([][]string, error) {
+	ext := strings.ToLower(path.Ext(filename))
+	if ext == ".xlsx" || (ext != ".csv" && bytes.HasPrefix(data, []byte("PK\x03\x04"))) {
+		return readXLSX(data)
+	}
+	if ext != "" && ext != ".csv" && ext != ".txt" {
+		return nil, fmt.Errorf("%w: unsupported file type %s (expect .csv or .xlsx)", ErrImportFormat, ext)
+	}
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+	r := csv.NewReader(bytes.NewReader(data))
+	r.FieldsPerRecord = -1
+	r.TrimLeadingSpace = true
+	rows, err := r.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrImportFormat, err)
+	}
+	return rows, nil
+}
+
+// ParseDeviceSheet 按表头映射列并逐行校验：device_ip 必填且为 IP 或主机名，端口 1-65535，
+// 须提供 credential_id 或 user_name+password，平台按 platform_mappings 归一（auto 表示探测），
+// 协议仅支持 ssh，同一 设备IP:端口 不可重复。空行忽略；返回有效行与全部行级错误
+func ParseDeviceSheet(cfg *config.Config, rows [][]string) ([]ImportDevice, []ImportRowError, error) {
+	header := -1
+	for i, r := range rows {
+		if !blankRow(r) {
+			header = i
+			break
+		}
+	}
+	if header < 0 {
+		return nil, nil, fmt.Errorf("%w: file is empty", ErrImportFormat)
+	}
+	cols := map[string]int{}
+	for i, h := range rows[header] {
+		key := strings.NewReplacer(" ", "_", "-", "_").Replace(strings.ToLower(strings.TrimSpace(h)))
+		if name, ok := importColumns[key]; ok {
+			if _, dup := cols[name]; !dup {
+				cols[name] = i
+			}
+		}
+	}
+	if _, ok := cols["device_ip"]; !ok {
+		return nil, nil, fmt.Errorf("%w: header must contain device_ip column", ErrImportFormat)
+	}
+
+	devices := make([]ImportDevice, 0, len(rows)-header-1)
+	errs := make([]ImportRowError, 0)
+	seen := map[string]int{}
+	for i := header + 1; i < len(rows); i++ {
+		r := rows[i]
+		if blankRow(r) {
+			continue
+		}
+		line := i + 1
+		get := func(name string) string {
+			if idx, ok := cols[name]; ok && idx < len(r) {
+				return strings.TrimSpace(r[idx])
+			}
+			return ""
+		}
+		fail := func(column, value, format string, args ...interface{}) {
+			errs = append(errs, ImportRowError{Row: line, Column: column, Value: value, Message: fmt.Sprintf(format, args...)})
+		}
+		before := len(errs)
+		d := ImportDevice{
+			Row:            line,
+			DeviceIP:       get("device_ip"),
+			DeviceName:     get("device_name"),
+			UserName:       get("user_name"),
+			Password:       get("password"),
+			EnablePassword: get("enable_password"),
+			CredentialID:   get("credential_id"),
+			CliProfile:     get("cli_profile"),
+		}
+		if d.DeviceIP == "" {
+			fail("device_ip", "", "device_ip is required")
+		} else if net.ParseIP(d.DeviceIP) == nil && !hostnameRe.MatchString(d.DeviceIP) {
+			fail("device_ip", d.DeviceIP, "device_ip must be an IP address or hostname")
+		}
+		if v := get("device_port"); v != "" {
+			port, err := strconv.Atoi(strings.TrimSuffix(v, ".0"))
+			if err != nil || port < 1 || port > 65535 {
+				fail("device_port", v, "device_port must be an integer between 1 and 65535")
+			}
+			d.Port = port
+		}
+		if v := get("device_platform"); v != "" {
+			if strings.EqualFold(v, PlatformAuto) {
+				d.DevicePlatform = PlatformAuto
+			} else if p, ok := NormalizePlatform(cfg, v); ok {
+				d.DevicePlatform = p
+			} else {
+				fail("device_platform", v, "platform does not match any device_defaults key or platform mapping")
+			}
+		}
+		if v := strings.ToLower(get("collect_protocol")); v != "" && v != "ssh" {
+			fail("collect_protocol", v, "unsupported collect_protocol for imported devices (only ssh)")
+		} else {
+			d.CollectProtocol = v
+		}
+		if d.CredentialID == "" && (d.UserName == "" || d.Password == "") {
+			fail("credential_id", "", "credential_id or user_name and password are required")
+		}
+		for _, cmd := range strings.FieldsFunc(get("cli_list"), func(r rune) bool { return r == ';' || r == '\n' }) {
+			if cmd = strings.TrimSpace(cmd); cmd != "" {
+				d.CliList = append(d.CliList, CLIItem{CLI: cmd})
+			}
+		}
+		if d.DeviceIP != "" {
+			port := d.Port
+			if port == 0 {
+				port = 22
+			}
+			key := net.JoinHostPort(d.DeviceIP, strconv.Itoa(port))
+			if prev, dup := seen[key]; dup {
+				fail("device_ip", d.DeviceIP, "duplicate device %s (first seen on row %d)", key, prev)
+			} else {
+				seen[key] = line
+			}
+		}
+		if len(errs) == before {
+			devices = append(devices, d)
+		}
+	}
+	return devices, errs, nil
+}
+
+func blankRow(r []string) bool {
+	for _, v := range r {
+		if strings.TrimSpace(v) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// xlsx 部件结构（仅解析所需字段）
+type xlsxWorkbook struct {
+	Sheets []struct {
+		RID string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+	} `xml:"sheets>sheet"`
+}
+
+type xlsxRels struct {
+	Rels []struct {
+		ID     string `xml:"Id,attr"`
+		Target string `xml:"Target,attr"`
+	} `xml:"Relationship"`
+}
+
+type xlsxText struct {
+	T    string `xml:"t"`
+	Runs []struct {
+		T string `xml:"t"`
+	} `xml:"r"`
+}
+
+func (t xlsxText) String() string {
+	if len(t.Runs) == 0 {
+		return t.T
+	}
+	var b strings.Builder
+	for _, r := range t.Runs {
+		b.WriteString(r.T)
+	}
+	return b.String()
+}
+
+type xlsxSharedStrings struct {
+	Items []xlsxText `xml:"si"`
+}
+
+type xlsxSheet struct {
+	Rows []struct {
+		Num   int `xml:"r,attr"`
+		Cells []struct {
+			Ref    string   `xml:"r,attr"`
+			Type   string   `xml:"t,attr"`
+			Value  string   `xml:"v"`
+			Inline xlsxText `xml:"is"`
+		} `xml:"c"`
+	} `xml:"sheetData>row"`
+}
+
+// readXLSX 读取工作簿首个工作表；单元格支持共享字符串、内联字符串、公式字符串与数值
+func readXLSX(data []byte) ([][]string, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrImportFormat, err)
+	}
+	files := make(map[string]*zip.File, len(zr.File))
+	for _, f := range zr.File {
+		files[strings.TrimPrefix(f.Name, "/")] = f
+	}
+	decode := func(name string, v interface{}) (bool, error) {
+		f, ok := files[name]
+		if !ok {
+			return false, nil
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return true, err
+		}
+		defer rc.Close()
+		return true, xml.NewDecoder(io.LimitReader(rc, 64<<20)).Decode(v)
+	}
+
+	sheetPath := "xl/worksheets/sheet1.xml"
+	var wb xlsxWorkbook
+	var rels xlsxRels
+	if ok, err := decode("xl/workbook.xml", &wb); ok && err == nil && len(wb.Sheets) > 0 {
+		if ok, err := decode("xl/_rels/workbook.xml.rels", &rels); ok && err == nil {
+			for _, r := range rels.Rels {
+				if r.ID == wb.Sheets[0].RID {
+					if strings.HasPrefix(r.Target, "/") {
+						sheetPath = strings.TrimPrefix(r.Target, "/")
+					} else {
+						sheetPath = path.Join("xl", r.Target)
+					}
+					break
+				}
+			}
+		}
+	}
+
+	var shared xlsxSharedStrings
+	if _, err := decode("xl/sharedStrings.xml", &shared); err != nil {
+		return nil, fmt.Errorf("%w: shared strings: %v", ErrImportFormat, err)
+	}
+	var sheet xlsxSheet
+	ok, err := decode(sheetPath, &sheet)
+	if !ok {
+		return nil, fmt.Errorf("%w: worksheet %s not found", ErrImportFormat, sheetPath)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: worksheet: %v", ErrImportFormat, err)
+	}
+
+	out := make([][]string, 0, len(sheet.Rows))
+	for _, row := range sheet.Rows {
+		// 省略的空行补齐，保证行号与表格一致
+		for row.Num > len(out)+1 {
+			out = append(out, nil)
+		}
+		cells := make([]string, 0, len(row.Cells))
+		for i, c := range row.Cells {
+			col := xlsxColumnIndex(c.Ref)
+			if col < 0 {
+				col = i
+			}
+			for len(cells) <= col {
+				cells = append(cells, "")
+			}
+			switch c.Type {
+			case "s":
+				idx, err := strconv.Atoi(strings.TrimSpace(c.Value))
+				if err != nil || idx < 0 || idx >= len(shared.Items) {
+					return nil, fmt.Errorf("%w: cell %s references missing shared string", ErrImportFormat, c.Ref)
+				}
+				cells[col] = shared.Items[idx].String()
+			case "inlineStr":
+				cells[col] = c.Inline.String()
+			default:
+				cells[col] = c.Value
+			}
+		}
+		out = append(out, cells)
+	}
+	return out, nil
+}
+
+// xlsxColumnIndex 单元格引用（如 "C7"）的列序号，从 0 开始（xlsxColumn 的逆运算）；无法解析时返回 -1
+func xlsxColumnIndex(ref string) int {
+	col := 0
+	n := 0
+	for _, ch := range strings.ToUpper(ref) {
+		if ch < 'A' || ch > 'Z' {
+			break
+		}
+		col = col*26 + int(ch-'A'+1)
+		n++
+	}
+	if n == 0 {
+		return -1
+	}
+	return col - 1
+}
@@ -0,0 +1,116 @@
+package service
+
+import (
+	"runtime"
+	"runtime/debug"
+	"time"
+)
+
+// WorkerUtilization 服务 worker 占用情况
+type WorkerUtilization struct {
+	Service     string  `json:"service"`
+	Profile     string  `json:"concurrency_profile,omitempty"` // 当前并发档位 S/M/L/XL
+	Busy        int     `json:"busy"`
+	Max         int     `json:"max"`
+	Utilization float64 `json:"utilization"` // busy/max，0~1
+
+	// 排队指标（进程启动以来）：等待名额的请求数、获取结果计数与等待耗时分位数
+	QueueDepth     int                 `json:"queue_depth"`
+	PeakQueueDepth int                 `json:"peak_queue_depth"`
+	Acquired       int64               `json:"acquired"`
+	Rejected       int64               `json:"rejected"`  // 执行窗口关闭
+	TimedOut       int64               `json:"timed_out"` // 排队超时
+	Cancelled      int64               `json:"cancelled"`
+	WaitP50MS      float64             `json:"wait_p50_ms"`
+	WaitP95MS      float64             `json:"wait_p95_ms"`
+	WaitMaxMS      float64             `json:"wait_max_ms"`
+	WaitSumSeconds float64             `json:"-"`
+	QueueHistory   []WorkerQueueSample `json:"queue_history"` // 最近 60 分钟
+}
+
+// WorkerReporter 可报告 worker 占用的服务
+type WorkerReporter interface {
+	WorkerUtilization() WorkerUtilization
+}
+
+func workerUtilization(name string, w *workerSlots) WorkerUtilization {
+	workers := w.get()
+	u := WorkerUtilization{Service: name, Profile: currentConcurrencyProfile(), Busy: len(workers), Max: cap(workers)}
+	if u.Max > 0 {
+		u.Utilization = float64(u.Busy) / float64(u.Max)
+	}
+	w.fill(&u)
+	return u
+}
+
+// WorkerUtilization 采集 worker 占用（配置下发复用采集 worker）
+func (s *CollectorService) WorkerUtilization() WorkerUtilization {
+	return workerUtilization("collector", s.workers)
+}
+
+// WorkerUtilization 备份 worker 占用
+func (s *BackupService) WorkerUtilization() WorkerUtilization {
+	return workerUtilization("backup", s.workers)
+}
+
+// WorkerUtilization 格式化 worker 占用
+func (s *FormatService) WorkerUtilization() WorkerUtilization {
+	return workerUtilization("format", s.workers)
+}
+
+// pauseQuantileNames GC 停顿分位点名称（与 PauseQuantiles 长度 5 对应）
+var pauseQuantileNames = []string{"min", "p25", "p50", "p75", "max"}
+
+// RuntimeDiagnostics goroutine 数、GC 统计与各服务 worker 占用，用于在线性能排查
+func RuntimeDiagnostics(services ...WorkerReporter) map[string]interface{} {
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+	gc := debug.GCStats{PauseQuantiles: make([]time.Duration, 5)}
+	debug.ReadGCStats(&gc)
+
+	// 最近 10 次 GC 停顿（由近及远）
+	recent := gc.Pause
+	if len(recent) > 10 {
+		recent = recent[:10]
+	}
+	pauses := make([]string, 0, len(recent))
+	for _, p := range recent {
+		pauses = append(pauses, p.String())
+	}
+	quantiles := make(map[string]string, len(gc.PauseQuantiles))
+	for i, q := range gc.PauseQuantiles {
+		quantiles[pauseQuantileNames[i]] = q.String()
+	}
+
+	workers := make([]WorkerUtilization, 0, len(services))
+	for _, s := range services {
+		if s != nil {
+			workers = append(workers, s.WorkerUtilization())
+		}
+	}
+	out := map[string]interface{}{
+		"goroutines":     runtime.NumGoroutine(),
+		"gomaxprocs":     runtime.GOMAXPROCS(0),
+		"uptime_seconds": int64(time.Since(processStart).Seconds()),
+		"gc": map[string]interface{}{
+			"num_gc":          gc.NumGC,
+			"pause_total":     gc.PauseTotal.String(),
+			"recent_pauses":   pauses,
+			"pause_quantiles": quantiles,
+			"cpu_fraction":    m.GCCPUFraction,
+			"next_gc_bytes":   m.NextGC,
+		},
+		"memory": map[string]interface{}{
+			"heap_alloc_bytes": m.HeapAlloc,
+			"heap_inuse_bytes": m.HeapInuse,
+			"heap_objects":     m.HeapObjects,
+			"sys_bytes":        m.Sys,
+		},
+		"workers":   workers,
+		"fsm_cache": CurrentFSMCacheStats(),
+	}
+	if !gc.LastGC.IsZero() {
+		out["gc"].(map[string]interface{})["last_gc"] = gc.LastGC
+	}
+	return out
+}
@@ -0,0 +1,40 @@
+package service
+
+import (
+	"context"
+	"strings"
+
+	"github.com/sshcollectorpro/sshcollectorpro/internal/config"
+	"github.com/sshcollectorpro/sshcollectorpro/pkg/logger"
+	"github.com/sshcollectorpro/sshcollectorpro/pkg/ssh"
+)
+
+// ErrEgressBlocked 设备地址被 ssh.egress 出站策略拒绝
+var ErrEgressBlocked = ssh.ErrEgressBlocked
+
+// StatusEgressBlocked 设备因出站策略未执行
+const StatusEgressBlocked = "EGRESS_BLOCKED"
+
+// sshEgressPolicy 转换 ssh.egress 配置；未配置任何规则时返回 nil（不限制）
+func sshEgressPolicy(ec config.SSHEgressConfig) *ssh.EgressPolicy {
+	if len(ec.Allow) == 0 && len(ec.Deny) == 0 && len(ec.Tenants) == 0 {
+		return nil
+	}
+	out := &ssh.EgressPolicy{EgressRule: ssh.EgressRule{Allow: ec.Allow, Deny: ec.Deny}}
+	if len(ec.Tenants) > 0 {
+		out.Tenants = make(map[string]ssh.EgressRule, len(ec.Tenants))
+		for name, r := range ec.Tenants {
+			out.Tenants[strings.ToLower(strings.TrimSpace(name))] = ssh.EgressRule{Allow: r.Allow, Deny: r.Deny}
+		}
+	}
+	return out
+}
+
+// checkEgress 执行前按出站策略校验设备地址（各协议通用），被拒绝时记录日志
+func checkEgress(ctx context.Context, cfg *config.Config, host string) error {
+	err := sshEgressPolicy(cfg.SSH.Egress).Check(ctx, host)
+	if err != nil {
+		logger.Warn("Egress blocked", "device_ip", host, "tenant", ssh.EgressTenantFrom(ctx), "error", err)
+	}
+	return err
+}
@@ -0,0 +1,81 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// 服务层错误分类：调用方通过 errors.Is 判断类别，不再依赖错误文本匹配
+var (
+	ErrServiceStopped    = errors.New("service is not running")
+	ErrValidation        = errors.New("validation failed")
+	ErrNotFound          = errors.New("not found")
+	ErrDeviceUnreachable = errors.New("device unreachable")
+	ErrAuthFailed        = errors.New("device authentication failed")
+	ErrTimeout           = errors.New("operation timed out")
+	ErrCanceled          = errors.New("operation canceled")
+)
+
+// ErrTaskNotFound 采集任务不存在
+var ErrTaskNotFound = withKind(ErrNotFound, errors.New("task not found"))
+
+// kindError 为具体错误附加分类，Error() 保持原始文本不变
+type kindError struct {
+	kind error
+	err  error
+}
+
+func (e *kindError) Error() string   { return e.err.Error() }
+func (e *kindError) Unwrap() []error { return []error{e.kind, e.err} }
+
+// withKind 为 err 附加分类；err 为 nil 时返回 nil
+func withKind(kind, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &kindError{kind: kind, err: err}
+}
+
+// validationErrorf 构造参数校验错误
+func validationErrorf(format string, args ...interface{}) error {
+	return withKind(ErrValidation, fmt.Errorf(format, args...))
+}
+
+// serviceStopped 构造服务未运行错误，如 "backup service is not running"
+func serviceStopped(name string) error {
+	return withKind(ErrServiceStopped, fmt.Errorf("%s service is not running", name))
+}
+
+// classifyConnectError 对建连失败分类：调用方取消、登录超时、认证失败或设备不可达；出站策略拒绝、凭据冷却与设备熔断原样返回
+func classifyConnectError(err error) error {
+	if errors.Is(err, ErrEgressBlocked) || errors.Is(err, ErrCredentialThrottled) || errors.Is(err, ErrDeviceCircuitOpen) || errors.Is(err, ErrHostKeyRejected) {
+		return err
+	}
+	// 上下文结束先于其他判断：请求取消或服务停止不归咎于设备；超时（含拨号 i/o timeout）归为登录超时而非不可达
+	if errors.Is(err, context.Canceled) {
+		return withKind(ErrCanceled, fmt.Errorf("SSH connection canceled: %w", err))
+	}
+	if errors.Is(err, context.DeadlineExceeded) || isLoginTimeout(err) {
+		// 设备登陆阶段的超时错误，统一标注为“设备登陆失败”
+		return withKind(ErrTimeout, fmt.Errorf("设备登陆失败"))
+	}
+	wrapped := fmt.Errorf("failed to create SSH connection: %w", err)
+	if isAuthFailure(err) {
+		return withKind(ErrAuthFailed, wrapped)
+	}
+	return withKind(ErrDeviceUnreachable, wrapped)
+}
+
+// isAuthFailure 判断 SSH 建连错误是否为设备拒绝认证
+func isAuthFailure(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, ErrAuthFailed) {
+		return true
+	}
+	msg := strings.ToLower(err.Error())
+	return strings.Contains(msg, "unable to authenticate") || strings.Contains(msg, "permission denied")
+}
@@ -0,0 +1,310 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/config"
+	"github.com/sshcollectorpro/sshcollectorpro/pkg/logger"
+)
+
+// 设备事件类型
+const (
+	EventTypeCollect = "collect"
+	EventTypeBackup  = "backup"
+	EventTypeDeploy  = "deploy"
+)
+
+// 设备事件状态（窗口关闭等未执行状态沿用结果中的 status）
+const (
+	EventStatusSuccess = "success"
+	EventStatusFailed  = "failed"
+)
+
+// DeviceEvent 单设备采集/备份/下发完成事件
+type DeviceEvent struct {
+	Type           string    `json:"type"` // collect | backup | deploy
+	TaskID         string    `json:"task_id"`
+	TaskName       string    `json:"task_name,omitempty"`
+	CollectorID    string    `json:"collector_id,omitempty"`
+	DeviceIP       string    `json:"device_ip"`
+	DeviceName     string    `json:"device_name,omitempty"`
+	DevicePlatform string    `json:"device_platform,omitempty"`
+	Status         string    `json:"status"`
+	Error          string    `json:"error,omitempty"`
+	ObjectURIs     []string  `json:"object_uris,omitempty"`
+	DurationMS     int64     `json:"duration_ms"`
+	Timestamp      time.Time `json:"timestamp"`
+}
+
+// eventSink 消息总线发送端
+type eventSink interface {
+	Publish(ctx context.Context, topic string, key, value []byte) error
+	Close() error
+}
+
+// eventPublisher 异步事件发布器：事件先入队，由单个协程顺序发送
+type eventPublisher struct {
+	cfg     config.EventsConfig
+	sink    eventSink
+	queue   chan DeviceEvent
+	done    chan struct{}
+	closeMu sync.Once
+}
+
+var (
+	eventsMu  sync.RWMutex
+	publisher *eventPublisher
+)
+
+// InitEvents 按配置初始化事件发布；未启用时不做任何事。重复调用会先关闭旧的发布器
+func InitEvents(cfg *config.Config) error {
+	CloseEvents()
+	if cfg == nil || !cfg.Events.Enable {
+		return nil
+	}
+	ec := cfg.Events
+	if len(ec.Brokers) == 0 {
+		return fmt.Errorf("events.brokers is required")
+	}
+	if ec.Timeout <= 0 {
+		ec.Timeout = 5 * time.Second
+	}
+	if ec.QueueSize <= 0 {
+		ec.QueueSize = 1000
+	}
+	var sink eventSink
+	var err error
+	switch strings.ToLower(strings.TrimSpace(ec.Backend)) {
+	case "kafka":
+		sink, err = newKafkaEventSink(ec.Brokers, cfg.Collector.ID, ec.Timeout)
+	case "nats", "":
+		sink, err = newNATSEventSink(ec)
+	default:
+		return fmt.Errorf("unsupported events.backend: %s", ec.Backend)
+	}
+	if err != nil {
+		return err
+	}
+	p := &eventPublisher{
+		cfg:   ec,
+		sink:  sink,
+		queue: make(chan DeviceEvent, ec.QueueSize),
+		done:  make(chan struct{}),
+	}
+	go p.loop()
+	eventsMu.Lock()
+	publisher = p
+	eventsMu.Unlock()
+	logger.Info("Event publisher started", "backend", ec.Backend, "brokers", strings.Join(ec.Brokers, ","))
+	return nil
+}
+
+// CloseEvents 停止事件发布：等待队列中已有事件发送完毕后关闭连接
+func CloseEvents() {
+	eventsMu.Lock()
+	p := publisher
+	publisher = nil
+	eventsMu.Unlock()
+	if p != nil {
+		p.close()
+	}
+}
+
+// PublishDeviceEvent 投递设备事件（同时交给健康监控导出）；未启用、该类型未配置 topic 或队列已满时直接返回
+func PublishDeviceEvent(ev DeviceEvent) {
+	if ev.Timestamp.IsZero() {
+		ev.Timestamp = time.Now()
+	}
+	exportMonitorEvent(ev)
+
+	eventsMu.RLock()
+	defer eventsMu.RUnlock()
+	p := publisher
+	if p == nil || p.topic(ev.Type) == "" {
+		return
+	}
+	select {
+	case p.queue <- ev:
+	default:
+		logger.Warn("Event queue full, event dropped", "type", ev.Type, "task_id", ev.TaskID, "device_ip", ev.DeviceIP)
+	}
+}
+
+// topic 事件类型对应的 topic
+func (p *eventPublisher) topic(typ string) string {
+	switch typ {
+	case EventTypeCollect:
+		return strings.TrimSpace(p.cfg.Topics.Collect)
+	case EventTypeBackup:
+		return strings.TrimSpace(p.cfg.Topics.Backup)
+	case EventTypeDeploy:
+		return strings.TrimSpace(p.cfg.Topics.Deploy)
+	}
+	return ""
+}
+
+// loop 顺序发送队列中的事件，失败仅记录日志
+func (p *eventPublisher) loop() {
+	defer close(p.done)
+	for ev := range p.queue {
+		body, err := json.Marshal(ev)
+		if err != nil {
+			logger.Warn("Encode event failed", "task_id", ev.TaskID, "error", err)
+			continue
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), p.cfg.Timeout)
+		err = p.sink.Publish(ctx, p.topic(ev.Type), []byte(ev.DeviceIP), body)
+		cancel()
+		if err != nil {
+			logger.Warn("Publish event failed", "type", ev.Type, "task_id", ev.TaskID, "device_ip", ev.DeviceIP, "error", err)
+		}
+	}
+}
+
+// close 关闭队列并等待发送协程退出
+func (p *eventPublisher) close() {
+	p.closeMu.Do(func() {
+		close(p.queue)
+		<-p.done
+		if err := p.sink.Close(); err != nil {
+			logger.Warn("Close event publisher failed", "error", err)
+		}
+	})
+}
+
+// natsEventSink 基于 NATS 核心发布（at-most-once），每条事件发送后 flush 确认服务端已接收
+type natsEventSink struct {
+	conn    *nats.Conn
+	timeout time.Duration
+}
+
+func newNATSEventSink(ec config.EventsConfig) (*natsEventSink, error) {
+	opts := []nats.Option{
+		nats.Name("sshcollectorpro"),
+		nats.Timeout(ec.Timeout),
+		nats.MaxReconnects(-1),
+		// 启动时服务端不可达也继续运行，由后台自动重连
+		nats.RetryOnFailedConnect(true),
+	}
+	if ec.Username != "" {
+		opts = append(opts, nats.UserInfo(ec.Username, ec.Password))
+	}
+	if ec.Token != "" {
+		opts = append(opts, nats.Token(ec.Token))
+	}
+	conn, err := nats.Connect(strings.Join(ec.Brokers, ","), opts...)
+	if err != nil {
+		return nil, fmt.Errorf("nats connect: %w", err)
+	}
+	return &natsEventSink{conn: conn, timeout: ec.Timeout}, nil
+}
+
+func (s *natsEventSink) Publish(ctx context.Context, topic string, key, value []byte) error {
+	if err := s.conn.Publish(topic, value); err != nil {
+		return err
+	}
+	return s.conn.FlushWithContext(ctx)
+}
+
+func (s *natsEventSink) Close() error {
+	if err := s.conn.Drain(); err != nil {
+		s.conn.Close()
+	}
+	return nil
+}
+
+// eventStatus 由成功标志与结果状态得出事件状态
+func eventStatus(success bool, status string) string {
+	if strings.TrimSpace(status) != "" {
+		return status
+	}
+	if success {
+		return EventStatusSuccess
+	}
+	return EventStatusFailed
+}
+
+// publishCollectEvent 单设备采集完成事件（对象 URI 来自 store=true 的落盘结果）
+func publishCollectEvent(cfg *config.Config, req *CollectRequest, resp *CollectResponse) {
+	if req == nil || resp == nil || req.internal {
+		return
+	}
+	var uris []string
+	for _, r := range resp.Results {
+		if r == nil {
+			continue
+		}
+		for _, o := range r.StoredObjects {
+			uris = append(uris, o.URI)
+		}
+	}
+	PublishDeviceEvent(DeviceEvent{
+		Type:           EventTypeCollect,
+		TaskID:         req.TaskID,
+		TaskName:       req.TaskName,
+		CollectorID:    collectorID(cfg),
+		DeviceIP:       req.DeviceIP,
+		DeviceName:     req.DeviceName,
+		DevicePlatform: req.DevicePlatform,
+		Status:         eventStatus(resp.Success, ""),
+		Error:          resp.Error,
+		ObjectURIs:     uris,
+		DurationMS:     resp.DurationMS,
+	})
+}
+
+// publishBackupEvent 单设备备份完成事件（含原始字节对象与聚合文件 URI）
+func publishBackupEvent(cfg *config.Config, req *BackupBatchRequest, d DeviceBackupResponse) {
+	var uris []string
+	for _, r := range d.Results {
+		for _, o := range r.StoredObjects {
+			uris = append(uris, o.URI)
+		}
+		if r.RawObject != nil {
+			uris = append(uris, r.RawObject.URI)
+		}
+	}
+	PublishDeviceEvent(DeviceEvent{
+		Type:           EventTypeBackup,
+		TaskID:         req.TaskID,
+		TaskName:       req.TaskName,
+		CollectorID:    collectorID(cfg),
+		DeviceIP:       d.DeviceIP,
+		DeviceName:     d.DeviceName,
+		DevicePlatform: d.DevicePlatform,
+		Status:         eventStatus(d.Success, d.Status),
+		Error:          d.Error,
+		ObjectURIs:     uris,
+		DurationMS:     d.DurationMS,
+	})
+}
+
+// publishDeployEvent 单设备下发完成事件
+func publishDeployEvent(cfg *config.Config, req *DeployFastRequest, r DeployDeviceResult, durationMS int64) {
+	ok, errMsg := r.Outcome()
+	PublishDeviceEvent(DeviceEvent{
+		Type:           EventTypeDeploy,
+		TaskID:         req.TaskID,
+		TaskName:       req.TaskName,
+		CollectorID:    collectorID(cfg),
+		DeviceIP:       r.DeviceIP,
+		DeviceName:     r.DeviceName,
+		DevicePlatform: r.DevicePlatform,
+		Status:         eventStatus(ok, r.Status),
+		Error:          errMsg,
+		DurationMS:     durationMS,
+	})
+}
+
+func collectorID(cfg *config.Config) string {
+	if cfg == nil {
+		return ""
+	}
+	return cfg.Collector.ID
+}
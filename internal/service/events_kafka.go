@@ -0,0 +1,46 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/twmb/franz-go/pkg/kgo"
+)
+
+// kafkaEventSink Kafka 生产者（franz-go）：按 key 哈希选择分区，acks=1
+type kafkaEventSink struct {
+	client *kgo.Client
+}
+
+func newKafkaEventSink(brokers []string, clientID string, timeout time.Duration) (*kafkaEventSink, error) {
+	if clientID == "" {
+		clientID = "sshcollectorpro"
+	}
+	client, err := kgo.NewClient(
+		kgo.SeedBrokers(brokers...),
+		kgo.ClientID(clientID),
+		kgo.DialTimeout(timeout),
+		kgo.ProduceRequestTimeout(timeout),
+		kgo.RecordDeliveryTimeout(timeout),
+		kgo.RequiredAcks(kgo.LeaderAck()),
+		kgo.DisableIdempotentWrite(),
+		kgo.ProducerLinger(0),
+		kgo.ProducerBatchCompression(kgo.NoCompression()),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("kafka client: %w", err)
+	}
+	return &kafkaEventSink{client: client}, nil
+}
+
+// Publish 同步发送单条消息，等待 leader 确认
+func (k *kafkaEventSink) Publish(ctx context.Context, topic string, key, value []byte) error {
+	return k.client.ProduceSync(ctx, &kgo.Record{Topic: topic, Key: key, Value: value}).FirstErr()
+}
+
+// Close 关闭全部 broker 连接
+func (k *kafkaEventSink) Close() error {
+	k.client.Close()
+	return nil
+}
@@ -0,0 +1,307 @@
+package service
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// 格式化结果导出格式
+const (
+	ExportFormatCSV  = "csv"
+	ExportFormatXLSX = "xlsx"
+)
+
+// ValidateExportFormat 校验导出格式：为空表示不导出
+func ValidateExportFormat(f string) error {
+	switch strings.ToLower(strings.TrimSpace(f)) {
+	case "", ExportFormatCSV, ExportFormatXLSX:
+		return nil
+	}
+	return validationErrorf("invalid export_format %q: must be csv or xlsx", f)
+}
+
+// exportContentType 导出文件的 Content-Type
+func exportContentType(f string) string {
+	if f == ExportFormatXLSX {
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	}
+	return "text/csv; charset=utf-8"
+}
+
+var templateValueLineRe = regexp.MustCompile(`^\s*Value\s+(?:(?:Required|Filldown|List|Fillup|Key)\s+)*([A-Za-z_][A-Za-z0-9_]*)\s*\(`)
+
+// templateColumnOrder 按模板中 Value 定义（TTP 为 {{ var }}，正则为命名分组）的先后顺序提取列名（多个模板按出现顺序合并去重）
+func templateColumnOrder(templates []string) []string {
+	seen := map[string]struct{}{}
+	cols := make([]string, 0)
+	add := func(name string) {
+		if _, ok := seen[name]; ok {
+			return
+		}
+		seen[name] = struct{}{}
+		cols = append(cols, name)
+	}
+	for _, tpl := range templates {
+		switch typ, body := templateTypeOf(tpl); typ {
+		case TemplateTypeTTP:
+			for _, f := range compileTTPTemplate(body).fields {
+				add(f)
+			}
+			continue
+		case TemplateTypeRegex:
+			if re, err := compileNamedRegex(body); err == nil {
+				for _, n := range namedGroups(re) {
+					add(n)
+				}
+			}
+			continue
+		}
+		for _, ln := range strings.Split(tpl, "\n") {
+			if m := templateValueLineRe.FindStringSubmatch(ln); m != nil {
+				add(m[1])
+			}
+		}
+	}
+	return cols
+}
+
+// flattenFormattedItems 将同一平台/命令下的解析记录展开为表格
+// 首列为 device_name，其后为模板变量顺序，模板外的字段按字母序追加
+func flattenFormattedItems(items []FormattedItem, varOrder []string) ([]string, [][]string) {
+	records := make([]struct {
+		device string
+		rec    map[string]interface{}
+	}, 0)
+	extra := map[string]struct{}{}
+	known := map[string]struct{}{}
+	for _, v := range varOrder {
+		known[v] = struct{}{}
+	}
+	for _, it := range items {
+		for _, rec := range parsedRecords(it.InfoFormatted) {
+			for k := range rec {
+				if _, ok := known[k]; !ok {
+					extra[k] = struct{}{}
+				}
+			}
+			records = append(records, struct {
+				device string
+				rec    map[string]interface{}
+			}{it.DeviceName, rec})
+		}
+	}
+	extraCols := make([]string, 0, len(extra))
+	for k := range extra {
+		extraCols = append(extraCols, k)
+	}
+	sort.Strings(extraCols)
+
+	header := append([]string{"device_name"}, varOrder...)
+	header = append(header, extraCols...)
+	rows := make([][]string, 0, len(records))
+	for _, r := range records {
+		row := make([]string, len(header))
+		row[0] = r.device
+		for i, col := range header[1:] {
+			row[i+1] = exportCell(r.rec[col])
+		}
+		rows = append(rows, row)
+	}
+	return header, rows
+}
+
+// parsedRecords 取出 applyFSM 结果中的 parsed 记录
+func parsedRecords(v interface{}) []map[string]interface{} {
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	switch recs := m["parsed"].(type) {
+	case []map[string]interface{}:
+		return recs
+	case []interface{}:
+		out := make([]map[string]interface{}, 0, len(recs))
+		for _, r := range recs {
+			if rm, ok := r.(map[string]interface{}); ok {
+				out = append(out, rm)
+			}
+		}
+		return out
+	}
+	return nil
+}
+
+// exportCell 单元格取值：列表以 "; " 连接，其他复杂类型序列化为 JSON
+func exportCell(v interface{}) string {
+	switch t := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return t
+	case []string:
+		return strings.Join(t, "; ")
+	case int, int64, float64, bool:
+		return fmt.Sprint(t)
+	default:
+		b, err := json.Marshal(t)
+		if err != nil {
+			return fmt.Sprint(t)
+		}
+		return string(b)
+	}
+}
+
+// encodeExport 按导出格式编码表格
+func encodeExport(format, sheet string, header []string, rows [][]string) ([]byte, error) {
+	if format == ExportFormatXLSX {
+		return encodeXLSX(sheet, header, rows)
+	}
+	return encodeCSV(header, rows)
+}
+
+// encodeCSV 编码为带 UTF-8 BOM 的 CSV（便于 Excel 直接打开中文）
+func encodeCSV(header []string, rows [][]string) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString("\xEF\xBB\xBF")
+	w := csv.NewWriter(&buf)
+	if err := w.Write(header); err != nil {
+		return nil, err
+	}
+	if err := w.WriteAll(rows); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// encodeXLSX 生成单工作表的最小 XLSX（内联字符串，无样式）
+func encodeXLSX(sheet string, header []string, rows [][]string) ([]byte, error) {
+	return encodeXLSXSheets([]exportSheet{{name: sheet, header: header, rows: rows}})
+}
+
+// exportSheet 工作簿中的一个工作表
+type exportSheet struct {
+	name   string
+	header []string
+	rows   [][]string
+}
+
+// encodeXLSXSheets 生成多工作表的最小 XLSX；工作表名按 xlsxSheetName 处理，重名时追加序号
+func encodeXLSXSheets(sheets []exportSheet) ([]byte, error) {
+	contentTypes := xml.Header + `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+		`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+		`<Default Extension="xml" ContentType="application/xml"/>` +
+		`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>`
+	workbook := xml.Header + `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets>`
+	rels := xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">`
+	var sheetFiles []struct{ name, body string }
+	used := make(map[string]bool, len(sheets))
+	for n, sh := range sheets {
+		id := strconv.Itoa(n + 1)
+		part := "worksheets/sheet" + id + ".xml"
+		contentTypes += `<Override PartName="/xl/` + part + `" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>`
+		var name bytes.Buffer
+		xml.EscapeText(&name, []byte(uniqueSheetName(sh.name, used)))
+		workbook += `<sheet name="` + name.String() + `" sheetId="` + id + `" r:id="rId` + id + `"/>`
+		rels += `<Relationship Id="rId` + id + `" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="` + part + `"/>`
+		sheetFiles = append(sheetFiles, struct{ name, body string }{"xl/" + part, xlsxSheetXML(sh.header, sh.rows)})
+	}
+
+	files := append([]struct{ name, body string }{
+		{"[Content_Types].xml", contentTypes + `</Types>`},
+		{"_rels/.rels", xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+			`</Relationships>`},
+		{"xl/workbook.xml", workbook + `</sheets></workbook>`},
+		{"xl/_rels/workbook.xml.rels", rels + `</Relationships>`},
+	}, sheetFiles...)
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, f := range files {
+		w, err := zw.Create(f.name)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := w.Write([]byte(f.body)); err != nil {
+			return nil, err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// xlsxSheetXML 工作表内容：首行为表头，单元格均为内联字符串
+func xlsxSheetXML(header []string, rows [][]string) string {
+	var sheetXML bytes.Buffer
+	sheetXML.WriteString(xml.Header)
+	sheetXML.WriteString(`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`)
+	writeRow := func(idx int, cells []string) {
+		r := strconv.Itoa(idx)
+		sheetXML.WriteString(`<row r="` + r + `">`)
+		for i, v := range cells {
+			sheetXML.WriteString(`<c r="` + xlsxColumn(i) + r + `" t="inlineStr"><is><t xml:space="preserve">`)
+			xml.EscapeText(&sheetXML, []byte(v))
+			sheetXML.WriteString(`</t></is></c>`)
+		}
+		sheetXML.WriteString(`</row>`)
+	}
+	writeRow(1, header)
+	for i, row := range rows {
+		writeRow(i+2, row)
+	}
+	sheetXML.WriteString(`</sheetData></worksheet>`)
+	return sheetXML.String()
+}
+
+// uniqueSheetName 工作表名去重（Excel 不区分大小写）：重名时截断并追加 ~2、~3
+func uniqueSheetName(s string, used map[string]bool) string {
+	base := xlsxSheetName(s)
+	name := base
+	for i := 2; used[strings.ToLower(name)]; i++ {
+		suffix := "~" + strconv.Itoa(i)
+		rs := []rune(base)
+		if len(rs)+len(suffix) > 31 {
+			rs = rs[:31-len(suffix)]
+		}
+		name = string(rs) + suffix
+	}
+	used[strings.ToLower(name)] = true
+	return name
+}
+
+// xlsxColumn 列序号（从 0 开始）转列名：0->A, 26->AA
+func xlsxColumn(i int) string {
+	name := ""
+	for i >= 0 {
+		name = string(rune('A'+i%26)) + name
+		i = i/26 - 1
+	}
+	return name
+}
+
+// xlsxSheetName 工作表名：去除非法字符并截断至 31 字符
+func xlsxSheetName(s string) string {
+	s = strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`[]:*?/\`, r) {
+			return '_'
+		}
+		return r
+	}, strings.TrimSpace(s))
+	if s == "" {
+		s = "Sheet1"
+	}
+	if rs := []rune(s); len(rs) > 31 {
+		s = string(rs[:31])
+	}
+	return s
+}
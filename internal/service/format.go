@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net"
 	"net/http"
@@ -18,6 +19,7 @@ import (
 	"github.com/sshcollectorpro/sshcollectorpro/internal/config"
 	"github.com/sshcollectorpro/sshcollectorpro/pkg/logger"
 	"github.com/sshcollectorpro/sshcollectorpro/pkg/ssh"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 // ====== 请求/响应类型定义 ======
@@ -30,7 +32,14 @@ type FormatBatchRequest struct {
 	SaveDir      string           `json:"save_dir"`
 	TaskTimeout  *int             `json:"task_timeout,omitempty"`
 	FSMTemplates []FSMTemplateDef `json:"fsm_templates"`
+	CallbackURL  string           `json:"callback_url,omitempty"` // 批次完成后推送设备摘要
+	Deadline     *time.Time       `json:"deadline,omitempty"`     // 执行窗口截止时间，到点后不再派发新设备
+	ExportFormat string           `json:"export_format,omitempty"` // 额外导出表格：csv | xlsx
+	Metrics      []MetricSelector `json:"metrics,omitempty"`       // 选中数值字段写入时序库
+	OutputEncoding string         `json:"output_encoding,omitempty"` // 响应中控制字符编码：raw | strip | escape | base64
+	FreshTTL     int              `json:"fresh_ttl,omitempty"`       // 默认新鲜度（秒）：最近落盘结果未过期的命令读取缓存对象解析，不再执行
 	Devices      []FormatDevice   `json:"devices"`
+	Deprecations `json:"-"` // 请求中使用的旧字段名（兼容层填充）
 }
 
 type FormatDevice struct {
@@ -42,7 +51,8 @@ type FormatDevice struct {
 	UserName        string   `json:"user_name"`
 	Password        string   `json:"password"`
 	EnablePassword  string   `json:"enable_password,omitempty"`
-	CliList         []string `json:"cli_list"`
+	CredentialID    string   `json:"credential_id,omitempty"` // 引用已登记凭据（接口层解析）
+	CliList         CLIList  `json:"cli_list"`
 	DeviceTimeout   *int     `json:"device_timeout,omitempty"`
 }
 
@@ -53,12 +63,14 @@ type FSMTemplateValue struct {
 }
 type FSMTemplateDef struct {
 	DevicePlatform string             `json:"device_platform"`
+	TemplateType   string             `json:"template_type,omitempty"` // 解析引擎：textfsm（默认）| ttp | regex
 	TemplateValues []FSMTemplateValue `json:"templates_values"`
 }
 
 // 聚合后的格式化条目
 type FormattedItem struct {
 	DeviceName    string      `json:"device_name"`
+	DeviceIP      string      `json:"-"` // 仅用于 PostgreSQL 记录，不写入 JSON 文件
 	InfoFormatted interface{} `json:"info_formatted"`
 }
 
@@ -77,6 +89,15 @@ type DeviceCommandFailures struct {
 	FailedRatio    string   `json:"failed_ratio,omitempty"`
 }
 
+// DeviceFreshSkipped 因结果新鲜而未执行的命令（解析使用缓存对象）
+type DeviceFreshSkipped struct {
+	DeviceIP       string   `json:"device_ip"`
+	DeviceName     string   `json:"device_name"`
+	DevicePlatform string   `json:"device_platform"`
+	SkippedFresh   int      `json:"skipped_fresh"`
+	Commands       []string `json:"commands"`
+}
+
 // FSM 模版未匹配信息
 type DeviceTemplateNotFound struct {
 	DeviceName       string   `json:"device_name"`
@@ -95,14 +116,49 @@ type FormatBatchResponse struct {
 	CollectFailures []DeviceCommandFailures  `json:"collect_failures"`
 	FormatFailures  []DeviceCommandFailures  `json:"failed_commands"`
 	FSMNotFound     []DeviceTemplateNotFound `json:"fsm_notfound"`
-	Stats           struct {
+	// NotAttempted 执行窗口关闭后未派发的设备（error 为 NOT_ATTEMPTED_WINDOW_CLOSED）
+	NotAttempted []DeviceFailure `json:"not_attempted,omitempty"`
+	// SkippedFresh 按设备统计因 fresh_ttl 跳过执行的命令
+	SkippedFresh []DeviceFreshSkipped `json:"skipped_fresh,omitempty"`
+	Stats        struct {
 		TotalDevices  int `json:"total_devices"`
 		FullySuccess  int `json:"fully_success_devices"`
 		LoginFailed   int `json:"login_failed_devices"`
 		CollectFailed int `json:"collect_failed_devices"`
 		ParseFailed   int `json:"parse_failed_devices"`
+		NotAttempted  int `json:"not_attempted_devices"`
 	} `json:"stats"`
 	Stored []StoredObject `json:"stored_objects,omitempty"`
+	// 时序库输出：写入点数与错误（仅请求携带 metrics 时返回）
+	MetricsWritten int    `json:"metrics_written,omitempty"`
+	MetricsError   string `json:"metrics_error,omitempty"`
+	// PostgreSQL 输出：写入记录数与错误（仅启用 storage.postgres 时返回）
+	PostgresRows  int    `json:"postgres_rows,omitempty"`
+	PostgresError string `json:"postgres_error,omitempty"`
+}
+
+func formatDeviceKey(ip, name string) string { return ip + "|" + name }
+
+// failedDevices 汇总失败设备及原因（登录、未派发、采集、解析失败依次优先）
+func (resp *FormatBatchResponse) failedDevices() map[string]string {
+	failed := make(map[string]string)
+	for _, f := range resp.LoginFailures {
+		failed[formatDeviceKey(f.DeviceIP, f.DeviceName)] = f.Error
+	}
+	for _, f := range resp.NotAttempted {
+		failed[formatDeviceKey(f.DeviceIP, f.DeviceName)] = f.Error
+	}
+	for _, f := range resp.CollectFailures {
+		if _, ok := failed[formatDeviceKey(f.DeviceIP, f.DeviceName)]; !ok {
+			failed[formatDeviceKey(f.DeviceIP, f.DeviceName)] = "collect failed: " + strings.Join(f.FailedCommands, ", ")
+		}
+	}
+	for _, f := range resp.FormatFailures {
+		if _, ok := failed[formatDeviceKey(f.DeviceIP, f.DeviceName)]; !ok {
+			failed[formatDeviceKey(f.DeviceIP, f.DeviceName)] = "parse failed: " + strings.Join(f.FailedCommands, ", ")
+		}
+	}
+	return failed
 }
 
 // ====== 快速格式化请求/响应 ======
@@ -116,6 +172,8 @@ type FormatFastRequest struct {
 	TaskTimeout  *int               `json:"task_timeout,omitempty"`
 	Device       []FormatFastDevice `json:"device"` // 允许传入一个设备（数组便于扩展）
 	FSMTemplates []FSMTemplateDef   `json:"fsm_templates,omitempty"`
+	OutputEncoding string           `json:"output_encoding,omitempty"` // 响应中控制字符编码：raw | strip | escape | base64
+	Deprecations `json:"-"` // 请求中使用的旧字段名（兼容层填充）
 }
 
 // FormatFastDevice 快速格式化设备参数（支持单条命令或命令列表）
@@ -128,8 +186,9 @@ type FormatFastDevice struct {
 	UserName        string   `json:"user_name"`
 	Password        string   `json:"password"`
 	EnablePassword  string   `json:"enable_password,omitempty"`
+	CredentialID    string   `json:"credential_id,omitempty"` // 引用已登记凭据（接口层解析）
 	Cli             string   `json:"cli,omitempty"`
-	CliList         []string `json:"cli_list,omitempty"`
+	CliList         CLIList  `json:"cli_list,omitempty"`
 	DeviceTimeout   *int     `json:"device_timeout,omitempty"`
 }
 
@@ -148,6 +207,7 @@ type FormatFastResponse struct {
 	} `json:"device"`
 	Raw       []CommandResultView    `json:"raw"`
 	Formatted map[string]interface{} `json:"formatted_json"`
+	Timings   *DeviceTimings         `json:"timings,omitempty"`
 }
 
 // ====== 服务定义 ======
@@ -158,13 +218,23 @@ type FormatFastResponse struct {
 // 作用：负责并发调度、结果聚合与写入，不直接操作 SSH 客户端。
 
 type FormatService struct {
-	cfg         *config.Config
+	provider    *config.Provider
 	sshPool     *ssh.Pool
-	workers     chan struct{}
+	workers     *workerSlots
 	interact    *InteractBasic
 	minioWriter *FormatMinioWriter
+	pgWriter    *FormatPostgresWriter
 	running     bool
 	mutex       sync.RWMutex
+	// templateStore 模板库：请求未提供模板时回退
+	templateStore TemplateStore
+	// parserBreakers 外部解析地址 -> 熔断状态
+	parserBreakers sync.Map
+	// parseSlots 本地 FSM 解析 worker 名额（data_format.fsm.workers）
+	parseSlots *workerSlots
+	// resultIndex 命令结果索引；resultReader 读取缓存对象用于解析（为空时不跳过命令）
+	resultIndex  CommandResultIndex
+	resultReader StorageReader
 }
 
 func NewFormatService(cfg *config.Config) *FormatService {
@@ -172,30 +242,16 @@ func NewFormatService(cfg *config.Config) *FormatService {
 	if conc <= 0 {
 		conc = 1
 	}
-	threads := cfg.Collector.Threads
-	if threads <= 0 {
-		threads = cfg.SSH.MaxSessions
-	}
-	poolConfig := &ssh.PoolConfig{
-		MaxIdle:     10,
-		MaxActive:   conc,
-		IdleTimeout: 5 * time.Minute,
-		CleanupInterval: cfg.SSH.CleanupInterval,
-		SSHConfig: &ssh.Config{
-			Timeout:        cfg.SSH.Timeout,
-			ConnectTimeout: cfg.SSH.ConnectTimeout,
-			KeepAlive:      cfg.SSH.KeepAliveInterval,
-			MaxSessions:    threads,
-		},
-	}
-
-	pool := ssh.NewPool(poolConfig)
+	pool := newServicePool(cfg, "format")
+	fsmCache.setLimit(cfg.DataFormat.FSM.CacheSize)
 	return &FormatService{
-		cfg:         cfg,
+		provider:    config.ProviderFor(cfg),
 		sshPool:     pool,
-		workers:     make(chan struct{}, conc),
+		workers:     newWorkerSlots(conc),
 		interact:    NewInteractBasic(cfg, pool),
 		minioWriter: NewFormatMinioWriter(cfg),
+		pgWriter:    NewFormatPostgresWriter(cfg),
+		parseSlots:  newWorkerSlots(fsmParseWorkers(cfg)),
 	}
 }
 
@@ -217,27 +273,106 @@ func (s *FormatService) Stop() error {
 		return nil
 	}
 	s.running = false
-	if err := s.sshPool.Close(); err != nil {
+	if err := closeServicePool(s.sshPool); err != nil {
 		logger.Error("Failed to close SSH pool (format)", "error", err)
 	}
+	if err := s.pgWriter.Close(); err != nil {
+		logger.Error("Failed to close PostgreSQL writer (format)", "error", err)
+	}
 	logger.Info("Format service stopped")
 	return nil
 }
 
+// formatMinio 当前格式化结果写入器（配置热更新时重建）
+func (s *FormatService) formatMinio() *FormatMinioWriter {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return s.minioWriter
+}
+
+// SetResultIndex 注入命令结果索引与对象读取器：记录原始数据落盘结果，并按 fresh_ttl 复用缓存输出
+func (s *FormatService) SetResultIndex(idx CommandResultIndex, reader StorageReader) {
+	s.resultIndex = idx
+	s.resultReader = reader
+}
+
+// loadFreshOutputs 读取结果仍新鲜命令的缓存输出（键为 CommandKey），返回仍需执行的命令；读取失败的命令照常执行
+func (s *FormatService) loadFreshOutputs(ctx context.Context, dev FormatDevice, defTTL int, now time.Time) (CLIList, map[string]string) {
+	if s.resultReader == nil {
+		return dev.CliList, nil
+	}
+	run, hits := splitFreshCommands(s.resultIndex, dev.DeviceIP, dev.DevicePort, dev.CliList, defTTL, now)
+	if len(hits) == 0 {
+		return run, nil
+	}
+	cached := make(map[string]string, len(hits))
+	for _, h := range hits {
+		data, err := s.resultReader.Read(ctx, h.Object.URI)
+		if err != nil {
+			logger.Warn("Read cached command output failed, executing instead", "device_ip", dev.DeviceIP, "command", h.Command, "uri", h.Object.URI, "error", err)
+			continue
+		}
+		cached[CommandKey(h.Command)] = string(data)
+	}
+	// 按请求中的命令顺序保留需执行的命令（含缓存读取失败的命令）
+	ordered := make(CLIList, 0, len(dev.CliList)-len(cached))
+	for _, it := range dev.CliList {
+		if _, ok := cached[CommandKey(it.CLI)]; !ok {
+			ordered = append(ordered, it)
+		}
+	}
+	return ordered, cached
+}
+
+// mergeFreshResults 按请求命令顺序合并执行结果与缓存输出
+func mergeFreshResults(cliList []string, executed []*ssh.CommandResult, cached map[string]string) []*ssh.CommandResult {
+	if len(cached) == 0 {
+		return executed
+	}
+	out := make([]*ssh.CommandResult, 0, len(cliList))
+	next := 0
+	for _, cmd := range cliList {
+		if output, ok := cached[CommandKey(cmd)]; ok {
+			out = append(out, &ssh.CommandResult{Command: cmd, Output: output})
+			continue
+		}
+		var r *ssh.CommandResult
+		if next < len(executed) {
+			r = executed[next]
+		}
+		next++
+		out = append(out, r)
+	}
+	return out
+}
+
 // ExecuteBatch 执行批量格式化流程
 func (s *FormatService) ExecuteBatch(ctx context.Context, req *FormatBatchRequest) (*FormatBatchResponse, error) {
+	cfg := s.conf() // 本任务使用的配置快照
 	if !s.running {
-		return nil, fmt.Errorf("format service is not running")
+		return nil, serviceStopped("format")
 	}
 	if req == nil {
-		return nil, fmt.Errorf("nil request")
+		return nil, validationErrorf("nil request")
 	}
 	if strings.TrimSpace(req.TaskID) == "" {
-		return nil, fmt.Errorf("task_id is required")
+		return nil, validationErrorf("task_id is required")
 	}
 	if len(req.Devices) == 0 {
-		return nil, fmt.Errorf("devices is empty")
+		return nil, validationErrorf("devices is empty")
 	}
+	for i := range req.Devices {
+		req.Devices[i].DevicePlatform = normalizeRequestPlatform(ctx, cfg, req.Devices[i].DevicePlatform, req.Devices[i].DeviceIP)
+	}
+	if err := ValidateExportFormat(req.ExportFormat); err != nil {
+		return nil, err
+	}
+	if err := ValidateMetricSelectors(cfg, req.Metrics); err != nil {
+		return nil, err
+	}
+	exportFormat := strings.ToLower(strings.TrimSpace(req.ExportFormat))
+	ctx, batchSpan := startSpan(ctx, "format.batch", attribute.String("task.id", req.TaskID), attribute.Int("task.batch", req.TaskBatch), attribute.Int("batch.devices", len(req.Devices)))
+	defer batchSpan.End()
 
 	start := time.Now()
 	date := start.Format("20060102")
@@ -245,22 +380,15 @@ func (s *FormatService) ExecuteBatch(ctx context.Context, req *FormatBatchReques
 	dateTime := fmt.Sprintf("%s_%s", date, timeStr)
 
 	// 构造模板查找表：platform -> cli -> []fsm_value
-	tmpl := make(map[string]map[string][]string)
-	for _, d := range req.FSMTemplates {
-		p := strings.ToLower(strings.TrimSpace(d.DevicePlatform))
-		if p == "" {
-			continue
-		}
-		if _, ok := tmpl[p]; !ok {
-			tmpl[p] = make(map[string][]string)
-		}
-		for _, tv := range d.TemplateValues {
-			cli := strings.ToLower(strings.TrimSpace(tv.CLIName))
-			if cli == "" {
-				continue
-			}
-			tmpl[p][cli] = append(tmpl[p][cli], tv.FSMValue)
-		}
+	tmpl, err := buildTemplateTable(req.FSMTemplates)
+	if err != nil {
+		return nil, err
+	}
+	// 请求未提供的模板从模板库补齐，并查询外部解析登记
+	hooks := make(map[string]map[string]*ExternalParser)
+	for _, dev := range req.Devices {
+		s.fillStoredTemplates(tmpl, dev.DevicePlatform, dev.CliList.Commands())
+		s.fillParserHooks(hooks, dev.DevicePlatform, dev.CliList.Commands())
 	}
 
 	// 聚合：platform -> cli -> []FormattedItem
@@ -271,9 +399,11 @@ func (s *FormatService) ExecuteBatch(ctx context.Context, req *FormatBatchReques
 	collectFailures := make([]DeviceCommandFailures, 0)
 	formatFailures := make([]DeviceCommandFailures, 0)
 	fsmNotFound := make([]DeviceTemplateNotFound, 0)
+	notAttempted := make([]DeviceFailure, 0)
+	freshSkipped := make([]DeviceFreshSkipped, 0)
 
 	// 并发控制
-	k := s.cfg.Collector.Concurrent
+	k := cfg.Collector.Concurrent
 	if k <= 0 {
 		k = 1
 	}
@@ -286,13 +416,45 @@ func (s *FormatService) ExecuteBatch(ctx context.Context, req *FormatBatchReques
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
-			// 限制并发
-			select {
-			case sem <- struct{}{}:
-				defer func() { <-sem }()
-			case <-ctx.Done():
+			// 限制并发（受执行窗口约束）
+			if err := s.workers.observe(sem, func() error { return AcquireDispatchSlot(ctx, sem, req.Deadline) }); err != nil {
+				if errors.Is(err, ErrWindowClosed) {
+					muAgg.Lock()
+					notAttempted = append(notAttempted, DeviceFailure{
+						DeviceIP:       dev.DeviceIP,
+						DeviceName:     dev.DeviceName,
+						DevicePlatform: dev.DevicePlatform,
+						Error:          StatusNotAttemptedWindowClosed,
+					})
+					muAgg.Unlock()
+				}
 				return
 			}
+			defer func() { <-sem }()
+			ctx, span := startSpan(ctx, "format.device", append(deviceSpanAttrs(dev.DeviceIP, dev.DeviceName, dev.DevicePlatform), attribute.String("task.id", req.TaskID))...)
+			var devErr error
+			defer func() { endSpan(span, devErr) }()
+			cliList := dev.CliList.Commands()
+
+			// 差异化格式化：结果仍新鲜的命令解析缓存对象，全部新鲜时不登录设备
+			runList, cached := s.loadFreshOutputs(ctx, dev, req.FreshTTL, start)
+			if len(cached) > 0 {
+				skippedCmds := make([]string, 0, len(cached))
+				for _, cmd := range cliList {
+					if _, ok := cached[CommandKey(cmd)]; ok {
+						skippedCmds = append(skippedCmds, cmd)
+					}
+				}
+				muAgg.Lock()
+				freshSkipped = append(freshSkipped, DeviceFreshSkipped{
+					DeviceIP:       dev.DeviceIP,
+					DeviceName:     dev.DeviceName,
+					DevicePlatform: dev.DevicePlatform,
+					SkippedFresh:   len(skippedCmds),
+					Commands:       skippedCmds,
+				})
+				muAgg.Unlock()
+			}
 
 			// 执行采集（仅采集重试，解析仅在成功采集后进行一次）
 			timeout := s.effectiveTimeout(req.TaskTimeout, dev.DevicePlatform)
@@ -303,6 +465,9 @@ func (s *FormatService) ExecuteBatch(ctx context.Context, req *FormatBatchReques
 			// 默认回退：平台默认 -> collector.retry_flags
 			retries := s.effectiveRetries(req.RetryFlag, dev.DevicePlatform)
 			attempts := retries + 1
+			if len(runList) == 0 {
+				attempts = 0
+			}
 			var res []*ssh.CommandResult
 			var err error
 			for try := 0; try < attempts; try++ {
@@ -317,12 +482,14 @@ func (s *FormatService) ExecuteBatch(ctx context.Context, req *FormatBatchReques
 					EnablePassword:  dev.EnablePassword,
 					TaskTimeoutSec:   timeout,
 					DeviceTimeoutSec: devTimeout,
-				}, dev.CliList)
+					CommandTimeouts:  dev.CliList.Timeouts(),
+				}, runList.Commands())
 				if err == nil {
 					break
 				}
 				// 若还有剩余重试次数则继续；否则记录失败并结束
 				if try+1 >= attempts {
+					devErr = err
 					loginFailures = append(loginFailures, DeviceFailure{
 						DeviceIP:       dev.DeviceIP,
 						DeviceName:     dev.DeviceName,
@@ -334,7 +501,7 @@ func (s *FormatService) ExecuteBatch(ctx context.Context, req *FormatBatchReques
 			}
 
 			// 统一交互层已过滤预命令与应用行过滤，此处直接使用结果
-			filtered := res
+			filtered := mergeFreshResults(cliList, res, cached)
 
 			// 统计/聚合失败命令
 			failedCmds := make([]string, 0)
@@ -345,22 +512,29 @@ func (s *FormatService) ExecuteBatch(ctx context.Context, req *FormatBatchReques
 					continue
 				}
 				if r.ExitCode != 0 || strings.TrimSpace(r.Error) != "" {
-					name := safeDisplayCmd(dev.CliList, i)
+					name := safeDisplayCmd(cliList, i)
 					if strings.TrimSpace(name) == "" {
 						name = strings.TrimSpace(r.Command)
 					}
 					failedCmds = append(failedCmds, name)
 				}
 				// 原始数据对象路径：/{minio_prefix}/{save_dir}/{task_id}/raw/{batch_id}/{device_name}/formatted/{cli_name}.txt
-				disp := strings.TrimSpace(safeDisplayCmd(dev.CliList, i))
+				disp := strings.TrimSpace(safeDisplayCmd(cliList, i))
 				if disp == "" {
 					disp = strings.TrimSpace(r.Command)
 				}
 				cli := strings.ToLower(disp)
+				// 缓存输出已有落盘对象，不重复写入
+				if _, ok := cached[CommandKey(cli)]; ok {
+					continue
+				}
 				obj := s.buildRawObjectPath(req.SaveDir, req.TaskID, req.TaskBatch, dev.DeviceName, cli)
 				if obj != "" {
-					if _, werr := s.minioWriter.PutObject(ctx, obj, []byte(r.Output), "text/plain; charset=utf-8"); werr != nil {
+					so, werr := s.formatMinio().PutObject(ctx, obj, []byte(r.Output), "text/plain; charset=utf-8")
+					if werr != nil {
 						logger.Warn("Write raw to MinIO failed", "device", dev.DeviceName, "cmd", cli, "error", werr)
+					} else if r.ExitCode == 0 && strings.TrimSpace(r.Error) == "" {
+						recordFreshResult(s.resultIndex, dev.DeviceIP, dev.DevicePort, cli, req.TaskID, so, start)
 					}
 				}
 			}
@@ -378,29 +552,31 @@ func (s *FormatService) ExecuteBatch(ctx context.Context, req *FormatBatchReques
 			totalCmds := len(filtered)
 			notfoundCmds := make([]string, 0)
 			parseFailedCmds := make([]string, 0)
+			parsed := s.parseCommands(ctx, p, cliList, filtered, tmpl, hooks)
 			for i, r := range filtered {
 				if r == nil {
 					continue
 				}
-				disp := strings.TrimSpace(safeDisplayCmd(dev.CliList, i))
+				disp := strings.TrimSpace(safeDisplayCmd(cliList, i))
 				if disp == "" {
 					disp = strings.TrimSpace(r.Command)
 				}
 				cli := strings.ToLower(disp)
 				// 模板列表
 				tvals := tmpl[p][cli]
-				formatted, ferr := s.applyFSM(tvals, r.Output)
+				hook := hooks[p][cli]
+				formatted, ferr := parsed[i].formatted, parsed[i].err
 				if ferr != nil {
-					// 区分未匹配模板与解析失败
-					if len(tvals) == 0 || strings.Contains(strings.ToLower(ferr.Error()), "no matched fsm template") {
-						name := safeDisplayCmd(dev.CliList, i)
+					// 区分未匹配模板与解析失败（已登记外部解析的命令视为解析失败）
+					if (len(tvals) == 0 && hook == nil) || strings.Contains(strings.ToLower(ferr.Error()), "no matched fsm template") {
+						name := safeDisplayCmd(cliList, i)
 						if strings.TrimSpace(name) == "" {
 							name = strings.TrimSpace(r.Command)
 						}
 						notfoundCmds = append(notfoundCmds, name)
 						formatted = map[string]interface{}{"parsed": []interface{}{}}
 					} else {
-						name := safeDisplayCmd(dev.CliList, i)
+						name := safeDisplayCmd(cliList, i)
 						if strings.TrimSpace(name) == "" {
 							name = strings.TrimSpace(r.Command)
 						}
@@ -412,7 +588,7 @@ func (s *FormatService) ExecuteBatch(ctx context.Context, req *FormatBatchReques
 				if _, ok := agg[p]; !ok {
 					agg[p] = make(map[string][]FormattedItem)
 				}
-				agg[p][cli] = append(agg[p][cli], FormattedItem{DeviceName: dev.DeviceName, InfoFormatted: formatted})
+				agg[p][cli] = append(agg[p][cli], FormattedItem{DeviceName: dev.DeviceName, DeviceIP: dev.DeviceIP, InfoFormatted: formatted})
 				muAgg.Unlock()
 			}
 			// 聚合：未匹配模板统计
@@ -450,18 +626,55 @@ func (s *FormatService) ExecuteBatch(ctx context.Context, req *FormatBatchReques
 			if obj == "" {
 				continue
 			}
-			if so, err := s.minioWriter.PutObject(ctx, obj, data, "application/json; charset=utf-8"); err != nil {
+			if so, err := s.formatMinio().PutObject(ctx, obj, data, "application/json; charset=utf-8"); err != nil {
 				logger.Warn("Write formatted JSON failed", "obj", obj, "error", err)
 			} else {
 				stored = append(stored, so)
 			}
+			// 导出表格：与 JSON 同目录同名，扩展名为导出格式；列顺序取模板变量定义顺序
+			if exportFormat == "" {
+				continue
+			}
+			header, rows := flattenFormattedItems(items, templateColumnOrder(tmpl[platform][cli]))
+			table, err := encodeExport(exportFormat, cli, header, rows)
+			if err != nil {
+				logger.Warn("Encode formatted export failed", "platform", platform, "cli", cli, "error", err)
+				continue
+			}
+			expObj := strings.TrimSuffix(obj, ".json") + "." + exportFormat
+			if so, err := s.formatMinio().PutObject(ctx, expObj, table, exportContentType(exportFormat)); err != nil {
+				logger.Warn("Write formatted export failed", "obj", expObj, "error", err)
+			} else {
+				stored = append(stored, so)
+			}
+		}
+	}
+
+	// 时序库输出：失败仅记录，不影响批次结果
+	metricsWritten, metricsErr := 0, ""
+	if len(req.Metrics) > 0 {
+		points := collectMetricPoints(req.Metrics, agg, start)
+		if err := NewTimeseriesWriter(cfg).Write(ctx, points); err != nil {
+			logger.Warn("Write timeseries failed", "task_id", req.TaskID, "points", len(points), "error", err)
+			metricsErr = err.Error()
+		} else {
+			metricsWritten = len(points)
 		}
 	}
 
+	// PostgreSQL 输出：失败仅记录，不影响批次结果
+	pgRows, pgErr := 0, ""
+	if s.pgWriter != nil {
+		n, err := s.pgWriter.WriteRecords(ctx, req.TaskID, req.TaskBatch, agg, start)
+		if err != nil {
+			logger.Warn("Write formatted records to PostgreSQL failed", "task_id", req.TaskID, "error", err)
+			pgErr = err.Error()
+		}
+		pgRows = n
+	}
+
 	// 统计与响应
 	resp := &FormatBatchResponse{
-		Code:            "SUCCESS",
-		Message:         "批量格式化处理完成",
 		JSONPrefix:      s.buildJSONPrefix(req.SaveDir, req.TaskID),
 		DateTime:        dateTime,
 		LoginFailures:   loginFailures,
@@ -474,8 +687,24 @@ func (s *FormatService) ExecuteBatch(ctx context.Context, req *FormatBatchReques
 	resp.Stats.CollectFailed = uniqueDeviceCount(collectFailures)
 	// 解析失败设备数：未匹配模板与解析失败的并集
 	resp.Stats.ParseFailed = unionParseFailedDevicesCount(formatFailures, fsmNotFound)
-	resp.Stats.FullySuccess = resp.Stats.TotalDevices - resp.Stats.LoginFailed - resp.Stats.ParseFailed
+	resp.Stats.NotAttempted = len(notAttempted)
 	resp.FSMNotFound = fsmNotFound
+	resp.NotAttempted = notAttempted
+	resp.SkippedFresh = freshSkipped
+	// 完全成功设备：未出现在登录、采集、解析失败及未派发任一列表中
+	failed := resp.failedDevices()
+	for _, d := range req.Devices {
+		if _, bad := failed[formatDeviceKey(d.DeviceIP, d.DeviceName)]; !bad {
+			resp.Stats.FullySuccess++
+		}
+	}
+	outcome := NewBatchOutcome("批量格式化任务", resp.Stats.TotalDevices, resp.Stats.FullySuccess)
+	resp.Code, resp.Message = outcome.Code, outcome.Message
+	batchSpan.SetAttributes(attribute.Int("batch.succeeded", resp.Stats.FullySuccess))
+	resp.MetricsWritten = metricsWritten
+	resp.MetricsError = metricsErr
+	resp.PostgresRows = pgRows
+	resp.PostgresError = pgErr
 
 	return resp, nil
 }
@@ -483,17 +712,21 @@ func (s *FormatService) ExecuteBatch(ctx context.Context, req *FormatBatchReques
 // ExecuteFast 针对单台设备的快速格式化流程
 // 仅在采集成功后进行一次解析；采集阶段按 retry_flag 进行重试
 func (s *FormatService) ExecuteFast(ctx context.Context, req *FormatFastRequest) (*FormatFastResponse, error) {
+	cfg := s.conf() // 本任务使用的配置快照
 	if !s.running {
-		return nil, fmt.Errorf("format service is not running")
+		return nil, serviceStopped("format")
 	}
 	if req == nil {
-		return nil, fmt.Errorf("nil request")
+		return nil, validationErrorf("nil request")
 	}
 	if strings.TrimSpace(req.TaskID) == "" {
-		return nil, fmt.Errorf("task_id is required")
+		return nil, validationErrorf("task_id is required")
 	}
 	if len(req.Device) == 0 {
-		return nil, fmt.Errorf("device is empty")
+		return nil, validationErrorf("device is empty")
+	}
+	for i := range req.Device {
+		req.Device[i].DevicePlatform = normalizeRequestPlatform(ctx, cfg, req.Device[i].DevicePlatform, req.Device[i].DeviceIP)
 	}
 
 	start := time.Now()
@@ -507,30 +740,24 @@ func (s *FormatService) ExecuteFast(ctx context.Context, req *FormatFastRequest)
 	if strings.TrimSpace(dev.Cli) != "" {
 		userCmds = []string{dev.Cli}
 	} else if len(dev.CliList) > 0 {
-		userCmds = append(userCmds, dev.CliList...)
+		userCmds = append(userCmds, dev.CliList.Commands()...)
 	}
 	if len(userCmds) == 0 {
-		return nil, fmt.Errorf("cli or cli_list is required")
+		return nil, validationErrorf("cli or cli_list is required")
 	}
 
 	// 构造模板查找表：platform -> cli -> []fsm_value
-	tmpl := make(map[string]map[string][]string)
-	for _, d := range req.FSMTemplates {
-		p := strings.ToLower(strings.TrimSpace(d.DevicePlatform))
-		if p == "" {
-			continue
-		}
-		if _, ok := tmpl[p]; !ok {
-			tmpl[p] = make(map[string][]string)
-		}
-		for _, tv := range d.TemplateValues {
-			cli := strings.ToLower(strings.TrimSpace(tv.CLIName))
-			if cli == "" {
-				continue
-			}
-			tmpl[p][cli] = append(tmpl[p][cli], tv.FSMValue)
-		}
-	}
+	tmpl, terr := buildTemplateTable(req.FSMTemplates)
+	if terr != nil {
+		return nil, terr
+	}
+	s.fillStoredTemplates(tmpl, dev.DevicePlatform, userCmds)
+	hooks := make(map[string]map[string]*ExternalParser)
+	s.fillParserHooks(hooks, dev.DevicePlatform, userCmds)
+	ctx, span := startSpan(ctx, "format.device", append(deviceSpanAttrs(dev.DeviceIP, dev.DeviceName, dev.DevicePlatform), attribute.String("task.id", req.TaskID))...)
+	var devErr error
+	defer func() { endSpan(span, devErr) }()
+	ctx, timings := WithTimings(ctx)
 
 	// 执行采集（仅采集重试，解析仅在成功采集后进行一次）
 	timeout := s.effectiveTimeout(req.TaskTimeout, dev.DevicePlatform)
@@ -555,16 +782,19 @@ func (s *FormatService) ExecuteFast(ctx context.Context, req *FormatFastRequest)
 			EnablePassword:  dev.EnablePassword,
 			TaskTimeoutSec:   timeout,
 			DeviceTimeoutSec: devTimeout,
+			CommandTimeouts:  dev.CliList.Timeouts(),
 		}, userCmds)
 		if err == nil {
 			break
 		}
 		if try+1 >= attempts {
+			devErr = err
 			// 采集失败：返回 collect_failed
 			resp := &FormatFastResponse{Code: "SUCCESS", Message: "快速格式化处理完成", TaskID: req.TaskID, DateTime: dateTime, Result: "collect_failed"}
 			resp.Device.DeviceIP = dev.DeviceIP
 			resp.Device.DeviceName = dev.DeviceName
 			resp.Device.DevicePlatform = dev.DevicePlatform
+			resp.Timings = timings
 			resp.Raw = []CommandResultView{}
 			resp.Formatted = map[string]interface{}{}
 			return resp, nil
@@ -606,6 +836,7 @@ func (s *FormatService) ExecuteFast(ctx context.Context, req *FormatFastRequest)
 		resp.Device.DeviceIP = dev.DeviceIP
 		resp.Device.DeviceName = dev.DeviceName
 		resp.Device.DevicePlatform = dev.DevicePlatform
+		resp.Timings = timings
 		resp.Raw = rawViews
 		resp.Formatted = map[string]interface{}{}
 		return resp, nil
@@ -613,6 +844,7 @@ func (s *FormatService) ExecuteFast(ctx context.Context, req *FormatFastRequest)
 	p := strings.ToLower(strings.TrimSpace(dev.DevicePlatform))
 	formatted := make(map[string]interface{})
 	emptyCount := 0
+	parsed := s.parseCommands(ctx, p, userCmds, filtered, tmpl, hooks)
 	for i, r := range filtered {
 		if r == nil {
 			continue
@@ -622,8 +854,7 @@ func (s *FormatService) ExecuteFast(ctx context.Context, req *FormatFastRequest)
 			disp = strings.TrimSpace(r.Command)
 		}
 		cli := strings.ToLower(disp)
-		tvals := tmpl[p][cli]
-		f, ferr := s.applyFSM(tvals, r.Output)
+		f, ferr := parsed[i].formatted, parsed[i].err
 		if ferr != nil {
 			// 无匹配模板或解析失败，统一按空 parsed 输出
 			f = map[string]interface{}{"parsed": []interface{}{}}
@@ -653,6 +884,7 @@ func (s *FormatService) ExecuteFast(ctx context.Context, req *FormatFastRequest)
 	resp.Device.DeviceIP = dev.DeviceIP
 	resp.Device.DeviceName = dev.DeviceName
 	resp.Device.DevicePlatform = dev.DevicePlatform
+	resp.Timings = timings
 	resp.Raw = rawViews
 	resp.Formatted = formatted
 	return resp, nil
@@ -687,8 +919,8 @@ func (s *FormatService) effectiveRetries(reqRetries *int, platform string) int {
 	if d.Retries > 0 {
 		return d.Retries
 	}
-	if s.cfg != nil && s.cfg.Collector.RetryFlags > 0 {
-		return s.cfg.Collector.RetryFlags
+	if s.conf() != nil && s.conf().Collector.RetryFlags > 0 {
+		return s.conf().Collector.RetryFlags
 	}
 	return 0
 }
@@ -705,17 +937,31 @@ func (s *FormatService) applyFSM(templates []string, raw string) (interface{}, e
 	}
 
 	for _, tpl := range templates {
+		// 编译产物按模板内容缓存，避免每设备每命令重复编译
+		ct := fsmCache.get(tpl)
+		// TTP 与命名分组正则模板只使用各自引擎，未产出记录时尝试下一个模板
+		switch ct.engine {
+		case TemplateTypeTTP:
+			if recs := parseTTP(ct.ttp, raw); len(recs) > 0 {
+				return map[string]interface{}{"parsed": recs}, nil
+			}
+			continue
+		case TemplateTypeRegex:
+			if recs := parseNamedRegex(ct.named, raw); len(recs) > 0 {
+				return map[string]interface{}{"parsed": recs}, nil
+			}
+			continue
+		}
 		// 优先尝试 TextFSM 风格：完整状态机语义
-		if looksLikeTextFSM(tpl) {
-			if tmpl := parseTextFSMTemplate(tpl); tmpl != nil && len(tmpl.states) > 0 {
+		if ct.textFSM {
+			if tmpl := ct.machine; tmpl != nil && len(tmpl.states) > 0 {
 				recs := runTextFSM(tmpl, strings.Split(raw, "\n"))
 				if len(recs) > 0 {
 					return map[string]interface{}{"parsed": recs}, nil
 				}
 			}
 			// 次优：简化版规则（单行匹配）
-			rules := compileTextFSMRules(tpl)
-			if len(rules) > 0 {
+			if rules := ct.rules; len(rules) > 0 {
 				out := parseWithTextFSM(rules, raw)
 				if len(out) > 0 {
 					return map[string]interface{}{"parsed": out}, nil
@@ -725,7 +971,7 @@ func (s *FormatService) applyFSM(templates []string, raw string) (interface{}, e
 		}
 
 		// 回退：逐行正则匹配
-		regs := compileFSMTemplateRegexes(tpl)
+		regs := ct.regexes
 		if len(regs) == 0 {
 			continue
 		}
@@ -799,12 +1045,17 @@ func looksLikeTextFSM(tpl string) bool {
 	return strings.Contains(t, "value ") || strings.Contains(t, "${")
 }
 
+var (
+	textFSMValueRe       = regexp.MustCompile(`^\s*Value\s+([A-Za-z_][A-Za-z0-9_]*)\s*\((.+)\)\s*$`)
+	textFSMPlaceholderRe = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+)
+
 // compileTextFSMRules 将 TextFSM 模板编译为规则（支持 Value 定义与 ${VAR} 占位符）
 func compileTextFSMRules(tpl string) []textFSMRule {
 	lines := strings.Split(tpl, "\n")
 	// 解析变量定义：Value NAME (REGEX)
 	vars := map[string]string{}
-	valRe := regexp.MustCompile(`^\s*Value\s+([A-Za-z_][A-Za-z0-9_]*)\s*\((.+)\)\s*$`)
+	valRe := textFSMValueRe
 	for _, ln := range lines {
 		l := strings.TrimSpace(ln)
 		if l == "" || strings.HasPrefix(l, "#") {
@@ -823,7 +1074,7 @@ func compileTextFSMRules(tpl string) []textFSMRule {
 
 	// 解析规则行：形如 "^... ${VAR} ... -> ..."，仅取箭头左侧
 	rules := make([]textFSMRule, 0)
-	phRe := regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+	phRe := textFSMPlaceholderRe
 	for _, ln := range lines {
 		raw := strings.TrimSpace(ln)
 		if raw == "" || strings.HasPrefix(raw, "#") {
@@ -1199,7 +1450,7 @@ func safeDisplayCmd(cliList []string, idx int) string {
 // ====== MinIO 写入器（格式化路径语义） ======
 
 type FormatMinioWriter struct {
-	cfg      *config.Config
+	provider *config.Provider
 	client   *minio.Client
 	endpoint string
 	ensured  bool
@@ -1231,7 +1482,7 @@ func NewFormatMinioWriter(cfg *config.Config) *FormatMinioWriter {
 		logger.Error("MinIO client init failed (format)", "error", err)
 		return nil
 	}
-	w := &FormatMinioWriter{cfg: cfg, client: cli, endpoint: endpoint}
+	w := &FormatMinioWriter{provider: config.ProviderFor(cfg), client: cli, endpoint: endpoint}
 	// 尝试确保 bucket
 	bucket := strings.TrimSpace(cfg.Storage.Minio.Bucket)
 	if bucket != "" {
@@ -1246,11 +1497,21 @@ func NewFormatMinioWriter(cfg *config.Config) *FormatMinioWriter {
 	return w
 }
 
+// PutObject 写入 MinIO 对象并记录 storage.write span
 func (w *FormatMinioWriter) PutObject(parent context.Context, objectName string, data []byte, contentType string) (StoredObject, error) {
+	ctx, span := startSpan(parent, "storage.write", attribute.String("storage.backend", "minio"), attribute.String("storage.object", objectName), attribute.Int("storage.bytes", len(data)))
+	start := time.Now()
+	obj, err := w.putObject(ctx, objectName, data, contentType)
+	recordStorageWrite(ctx, time.Since(start))
+	endSpan(span, err)
+	return obj, err
+}
+
+func (w *FormatMinioWriter) putObject(parent context.Context, objectName string, data []byte, contentType string) (StoredObject, error) {
 	if w == nil || w.client == nil {
 		return StoredObject{}, fmt.Errorf("minio client not initialized")
 	}
-	bucket := strings.TrimSpace(w.cfg.Storage.Minio.Bucket)
+	bucket := strings.TrimSpace(w.conf().Storage.Minio.Bucket)
 	if bucket == "" {
 		return StoredObject{}, fmt.Errorf("minio bucket not configured")
 	}
@@ -1348,7 +1609,7 @@ func (w *FormatMinioWriter) attemptContext(parent context.Context, prefer time.D
 // ====== 路径构造工具 ======
 
 func (s *FormatService) buildJSONPrefix(saveDir, taskID string) string {
-	prefix := strings.TrimSpace(s.cfg.DataFormat.MinioPrefix)
+	prefix := strings.TrimSpace(s.conf().DataFormat.MinioPrefix)
 	if prefix == "" {
 		prefix = "data-formats"
 	}
@@ -1364,7 +1625,7 @@ func (s *FormatService) buildJSONPrefix(saveDir, taskID string) string {
 }
 
 func (s *FormatService) buildFormattedJSONPath(saveDir, taskID, platform, cli string, batchID int) string {
-	prefix := strings.TrimSpace(s.cfg.DataFormat.MinioPrefix)
+	prefix := strings.TrimSpace(s.conf().DataFormat.MinioPrefix)
 	if prefix == "" {
 		prefix = "data-formats"
 	}
@@ -1388,7 +1649,7 @@ func (s *FormatService) buildFormattedJSONPath(saveDir, taskID, platform, cli st
 }
 
 func (s *FormatService) buildRawObjectPath(saveDir, taskID string, batchID int, deviceName, cli string) string {
-	prefix := strings.TrimSpace(s.cfg.DataFormat.MinioPrefix)
+	prefix := strings.TrimSpace(s.conf().DataFormat.MinioPrefix)
 	if prefix == "" {
 		prefix = "data-formats"
 	}
@@ -0,0 +1,200 @@
+package service
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	minio "github.com/minio/minio-go/v7"
+)
+
+// FormatExportRequest 按任务导出已存储的格式化结果
+type FormatExportRequest struct {
+	TaskID   string `json:"task_id"`
+	SaveDir  string `json:"save_dir,omitempty"`
+	Format   string `json:"format,omitempty"`   // csv（默认）| xlsx
+	Batch    int    `json:"batch,omitempty"`    // 仅导出指定批次，0 表示全部批次
+	Platform string `json:"platform,omitempty"` // 仅导出指定平台
+	Command  string `json:"command,omitempty"`  // 仅导出指定命令
+	Store    bool   `json:"store,omitempty"`    // 写回 MinIO（任务目录 export/ 下）
+}
+
+// FormattedTable 同一平台/命令的聚合结果（多批次合并）
+type FormattedTable struct {
+	Platform string          `json:"platform"`
+	Command  string          `json:"command"` // 存储路径中的命令名（slug）
+	Batches  []int           `json:"batches"`
+	Items    []FormattedItem `json:"-"`
+}
+
+// FormatExport 导出文件
+type FormatExport struct {
+	Filename    string           `json:"filename"`
+	ContentType string           `json:"content_type"`
+	Tables      int              `json:"tables"`
+	Rows        int              `json:"rows"`
+	Data        []byte           `json:"-"`
+	Stored      *StoredObject    `json:"stored,omitempty"`
+	Sources     []FormattedTable `json:"sources"`
+}
+
+// EncodeFormattedExport 将聚合结果展开为表格：xlsx 每个平台/命令一个工作表；
+// csv 仅一张表时直接输出，多张表时打包为 zip（{platform}/{command}.csv）
+func EncodeFormattedExport(format, name string, tables []FormattedTable) (*FormatExport, error) {
+	format = strings.ToLower(strings.TrimSpace(format))
+	if format == "" {
+		format = ExportFormatCSV
+	}
+	if err := ValidateExportFormat(format); err != nil {
+		return nil, withKind(ErrValidation, err)
+	}
+	out := &FormatExport{Tables: len(tables), Sources: tables}
+	sheets := make([]exportSheet, 0, len(tables))
+	for _, t := range tables {
+		header, rows := flattenFormattedItems(t.Items, nil)
+		out.Rows += len(rows)
+		sheets = append(sheets, exportSheet{name: t.Platform + "-" + t.Command, header: header, rows: rows})
+	}
+
+	var err error
+	switch {
+	case format == ExportFormatXLSX:
+		out.Filename, out.ContentType = name+".xlsx", exportContentType(ExportFormatXLSX)
+		out.Data, err = encodeXLSXSheets(sheets)
+	case len(sheets) == 1:
+		out.Filename, out.ContentType = name+".csv", exportContentType(ExportFormatCSV)
+		out.Data, err = encodeCSV(sheets[0].header, sheets[0].rows)
+	default:
+		out.Filename, out.ContentType = name+".zip", "application/zip"
+		var buf bytes.Buffer
+		zw := zip.NewWriter(&buf)
+		for i, sh := range sheets {
+			data, cerr := encodeCSV(sh.header, sh.rows)
+			if cerr != nil {
+				return nil, cerr
+			}
+			w, cerr := zw.Create(path.Join(slug(tables[i].Platform), slug(tables[i].Command)+".csv"))
+			if cerr != nil {
+				return nil, cerr
+			}
+			if _, cerr = w.Write(data); cerr != nil {
+				return nil, cerr
+			}
+		}
+		if err = zw.Close(); err == nil {
+			out.Data = buf.Bytes()
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ExportTask 读取任务已存储的聚合 JSON（{prefix}/{save_dir}/{task_id}/formatted/{platform}/{cli}/formatted_{batch}.json），
+// 按平台/命令展开为表格；Store 为 true 时写入 {prefix}/{save_dir}/{task_id}/export/
+func (s *FormatService) ExportTask(ctx context.Context, req *FormatExportRequest) (*FormatExport, error) {
+	if req == nil || strings.TrimSpace(req.TaskID) == "" {
+		return nil, validationErrorf("task_id is required")
+	}
+	if err := ValidateExportFormat(req.Format); err != nil {
+		return nil, withKind(ErrValidation, err)
+	}
+	if req.Batch < 0 {
+		return nil, validationErrorf("batch must not be negative")
+	}
+	mw := s.formatMinio()
+	if mw == nil || mw.client == nil {
+		return nil, withKind(ErrServiceStopped, fmt.Errorf("format storage (minio) is not configured"))
+	}
+	bucket := strings.TrimSpace(mw.conf().Storage.Minio.Bucket)
+	if bucket == "" {
+		return nil, withKind(ErrServiceStopped, fmt.Errorf("minio bucket not configured"))
+	}
+
+	jsonPrefix := s.buildJSONPrefix(req.SaveDir, req.TaskID)
+	listPrefix := strings.TrimPrefix(jsonPrefix, "/")
+	platform := strings.ToLower(strings.TrimSpace(req.Platform))
+	command := ""
+	if strings.TrimSpace(req.Command) != "" {
+		command = slug(req.Command)
+	}
+
+	byKey := map[string]*FormattedTable{}
+	for obj := range mw.client.ListObjects(ctx, bucket, minio.ListObjectsOptions{Prefix: listPrefix, Recursive: true}) {
+		if obj.Err != nil {
+			return nil, fmt.Errorf("list formatted results: %w", obj.Err)
+		}
+		p, cli, batch, ok := parseFormattedObject(strings.TrimPrefix(strings.TrimPrefix(obj.Key, "/"), listPrefix))
+		if !ok || (platform != "" && p != platform) || (command != "" && cli != command) || (req.Batch > 0 && batch != req.Batch) {
+			continue
+		}
+		data, err := readLimited(mw.client.GetObject(ctx, bucket, obj.Key, minio.GetObjectOptions{}))
+		if err != nil {
+			return nil, fmt.Errorf("read %s: %w", obj.Key, err)
+		}
+		var items []FormattedItem
+		if err := json.Unmarshal(data, &items); err != nil {
+			return nil, fmt.Errorf("decode %s: %w", obj.Key, err)
+		}
+		t, ok := byKey[p+"/"+cli]
+		if !ok {
+			t = &FormattedTable{Platform: p, Command: cli}
+			byKey[p+"/"+cli] = t
+		}
+		t.Batches = append(t.Batches, batch)
+		t.Items = append(t.Items, items...)
+	}
+	if len(byKey) == 0 {
+		return nil, withKind(ErrNotFound, fmt.Errorf("no formatted results for task %s", req.TaskID))
+	}
+	tables := make([]FormattedTable, 0, len(byKey))
+	for _, t := range byKey {
+		sort.Ints(t.Batches)
+		tables = append(tables, *t)
+	}
+	sort.Slice(tables, func(i, j int) bool {
+		if tables[i].Platform != tables[j].Platform {
+			return tables[i].Platform < tables[j].Platform
+		}
+		return tables[i].Command < tables[j].Command
+	})
+
+	name := slug(req.TaskID) + "_formatted"
+	if req.Batch > 0 {
+		name += "_" + strconv.Itoa(req.Batch)
+	}
+	out, err := EncodeFormattedExport(req.Format, name, tables)
+	if err != nil {
+		return nil, err
+	}
+	if req.Store {
+		obj := path.Join(strings.TrimSuffix(jsonPrefix, "formatted/"), "export", time.Now().Format("20060102_150405")+"_"+out.Filename)
+		so, err := mw.PutObject(ctx, obj, out.Data, out.ContentType)
+		if err != nil {
+			return nil, fmt.Errorf("store export: %w", err)
+		}
+		out.Stored = &so
+	}
+	return out, nil
+}
+
+// parseFormattedObject 解析任务 formatted/ 下的相对路径 {platform}/{cli}/formatted_{batch}.json
+func parseFormattedObject(rel string) (platform, cli string, batch int, ok bool) {
+	parts := strings.Split(rel, "/")
+	if len(parts) != 3 || !strings.HasPrefix(parts[2], "formatted_") || !strings.HasSuffix(parts[2], ".json") {
+		return "", "", 0, false
+	}
+	n, err := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(parts[2], "formatted_"), ".json"))
+	if err != nil || n <= 0 {
+		return "", "", 0, false
+	}
+	return parts[0], parts[1], n, true
+}
@@ -0,0 +1,209 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sshcollectorpro/sshcollectorpro/pkg/logger"
+)
+
+// ExternalParser 外部解析服务登记（由模板库提供）
+type ExternalParser struct {
+	URL      string
+	Timeout  time.Duration // 0 使用 data_format.external_parser.timeout
+	Retries  int           // 0 使用 data_format.external_parser.retries
+	Fallback bool          // 外部解析失败时回退本地模板
+}
+
+// ParserHookStore 外部解析登记查询；模板库实现该接口时生效
+type ParserHookStore interface {
+	LookupParser(platform, cli string) (ExternalParser, bool)
+}
+
+// errParserCircuitOpen 熔断期间不调用外部服务
+var errParserCircuitOpen = errors.New("circuit open")
+
+// parserBreaker 单个外部解析地址的熔断状态：连续失败达到阈值后在冷却期内拒绝调用，
+// 冷却结束后放行请求试探，成功即恢复
+type parserBreaker struct {
+	mu        sync.Mutex
+	failures  int
+	openUntil time.Time
+}
+
+func (b *parserBreaker) allow(now time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return !now.Before(b.openUntil)
+}
+
+func (b *parserBreaker) record(err error, threshold int, cooldown time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err == nil {
+		b.failures = 0
+		b.openUntil = time.Time{}
+		return
+	}
+	b.failures++
+	if threshold > 0 && b.failures >= threshold {
+		b.openUntil = time.Now().Add(cooldown)
+	}
+}
+
+// fillParserHooks 对 平台+命令 查询外部解析登记；须在派发设备协程前调用，执行期间 hooks 只读
+func (s *FormatService) fillParserHooks(hooks map[string]map[string]*ExternalParser, platform string, clis []string) {
+	store, ok := s.templateStore.(ParserHookStore)
+	if !ok {
+		return
+	}
+	p := strings.ToLower(strings.TrimSpace(platform))
+	if p == "" {
+		return
+	}
+	for _, c := range clis {
+		cli := strings.ToLower(strings.TrimSpace(c))
+		if cli == "" {
+			continue
+		}
+		if _, seen := hooks[p][cli]; seen {
+			continue
+		}
+		if _, ok := hooks[p]; !ok {
+			hooks[p] = make(map[string]*ExternalParser)
+		}
+		if h, found := store.LookupParser(p, cli); found {
+			hooks[p][cli] = &h
+		} else {
+			hooks[p][cli] = nil
+		}
+	}
+}
+
+// parseOutput 命中外部解析登记时先调用外部服务；失败且允许回退时使用本地模板
+func (s *FormatService) parseOutput(ctx context.Context, hook *ExternalParser, platform, cli string, templates []string, raw string) (interface{}, error) {
+	if hook != nil {
+		rows, err := s.callExternalParser(ctx, hook, platform, cli, raw)
+		if err == nil {
+			return map[string]interface{}{"parsed": rows}, nil
+		}
+		logger.Warn("External parser failed", "platform", platform, "cli", cli, "url", hook.URL, "fallback", hook.Fallback, "error", err)
+		if !hook.Fallback || len(templates) == 0 {
+			return nil, fmt.Errorf("external parser: %w", err)
+		}
+	}
+	return s.parseLocal(ctx, templates, raw)
+}
+
+// callExternalParser POST 原始输出（text/plain），期望返回 JSON 数组或 {"parsed": [...]}
+func (s *FormatService) callExternalParser(ctx context.Context, hook *ExternalParser, platform, cli, raw string) ([]map[string]interface{}, error) {
+	ec := s.conf().DataFormat.ExternalParser
+	timeout := hook.Timeout
+	if timeout <= 0 {
+		timeout = ec.Timeout
+	}
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+	retries := hook.Retries
+	if retries <= 0 {
+		retries = ec.Retries
+	}
+	cooldown := ec.Cooldown
+	if cooldown <= 0 {
+		cooldown = 30 * time.Second
+	}
+
+	v, _ := s.parserBreakers.LoadOrStore(hook.URL, &parserBreaker{})
+	breaker := v.(*parserBreaker)
+	if !breaker.allow(time.Now()) {
+		return nil, errParserCircuitOpen
+	}
+
+	var lastErr error
+	for attempt := 0; attempt <= retries; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(200 * time.Millisecond * time.Duration(attempt)):
+			}
+		}
+		rows, retryable, err := s.postExternalParser(ctx, hook.URL, timeout, platform, cli, raw)
+		if err == nil {
+			breaker.record(nil, ec.FailureThreshold, cooldown)
+			return rows, nil
+		}
+		lastErr = err
+		if !retryable {
+			break
+		}
+	}
+	breaker.record(lastErr, ec.FailureThreshold, cooldown)
+	return nil, lastErr
+}
+
+// postExternalParser 单次调用；返回错误是否可重试（网络错误、5xx、429）
+func (s *FormatService) postExternalParser(ctx context.Context, url string, timeout time.Duration, platform, cli, raw string) ([]map[string]interface{}, bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, strings.NewReader(raw))
+	if err != nil {
+		return nil, false, err
+	}
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("X-Device-Platform", platform)
+	req.Header.Set("X-Command", cli)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, true, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 32<<20))
+	if err != nil {
+		return nil, true, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		retryable := resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests
+		msg := strings.TrimSpace(string(body))
+		if len(msg) > 256 {
+			msg = msg[:256]
+		}
+		return nil, retryable, fmt.Errorf("status %d: %s", resp.StatusCode, msg)
+	}
+	rows, err := decodeExternalRows(body)
+	return rows, false, err
+}
+
+// decodeExternalRows 解析外部服务返回的记录列表，每条记录须为 JSON 对象
+func decodeExternalRows(body []byte) ([]map[string]interface{}, error) {
+	var wrapped struct {
+		Parsed *[]map[string]interface{} `json:"parsed"`
+	}
+	trimmed := strings.TrimSpace(string(body))
+	if strings.HasPrefix(trimmed, "{") {
+		if err := json.Unmarshal(body, &wrapped); err != nil {
+			return nil, fmt.Errorf("decode response: %w", err)
+		}
+		if wrapped.Parsed == nil {
+			return nil, fmt.Errorf("response has no parsed field")
+		}
+		return *wrapped.Parsed, nil
+	}
+	var rows []map[string]interface{}
+	if err := json.Unmarshal(body, &rows); err != nil {
+		return nil, fmt.Errorf("decode response: %w", err)
+	}
+	if rows == nil {
+		rows = []map[string]interface{}{}
+	}
+	return rows, nil
+}
@@ -0,0 +1,172 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	_ "github.com/lib/pq"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/config"
+)
+
+// pgTableName 允许的表名（可带 schema）
+var pgTableName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)?$`)
+
+// FormatPostgresWriter 将解析后的 FSM 记录写入 PostgreSQL（每条记录一行，payload 为 JSONB）
+type FormatPostgresWriter struct {
+	cfg   config.PostgresConfig
+	table string
+
+	mu    sync.Mutex
+	db    *sql.DB
+	ready bool
+}
+
+// NewFormatPostgresWriter 未启用或未配置主机时返回 nil；连接在首次写入时建立
+func NewFormatPostgresWriter(cfg *config.Config) *FormatPostgresWriter {
+	if cfg == nil || !cfg.Storage.Postgres.Enable || strings.TrimSpace(cfg.Storage.Postgres.Host) == "" {
+		return nil
+	}
+	table := strings.TrimSpace(cfg.Storage.Postgres.Table)
+	if table == "" {
+		table = "formatted_records"
+	}
+	return &FormatPostgresWriter{cfg: cfg.Storage.Postgres, table: table}
+}
+
+// dsn 连接串（key=value 形式，值按规范转义）
+func (w *FormatPostgresWriter) dsn() string {
+	quote := func(v string) string {
+		return "'" + strings.ReplaceAll(strings.ReplaceAll(v, `\`, `\\`), "'", `\'`) + "'"
+	}
+	port := w.cfg.Port
+	if port <= 0 {
+		port = 5432
+	}
+	sslmode := strings.TrimSpace(w.cfg.SSLMode)
+	if sslmode == "" {
+		sslmode = "disable"
+	}
+	parts := []string{
+		"host=" + quote(w.cfg.Host),
+		fmt.Sprintf("port=%d", port),
+		"dbname=" + quote(w.cfg.Database),
+		"sslmode=" + quote(sslmode),
+		"connect_timeout=10",
+	}
+	if w.cfg.Username != "" {
+		parts = append(parts, "user="+quote(w.cfg.Username))
+	}
+	if w.cfg.Password != "" {
+		parts = append(parts, "password="+quote(w.cfg.Password))
+	}
+	return strings.Join(parts, " ")
+}
+
+// open 建立连接并确保表与索引存在
+func (w *FormatPostgresWriter) open(ctx context.Context) (*sql.DB, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.ready {
+		return w.db, nil
+	}
+	if !pgTableName.MatchString(w.table) {
+		return nil, fmt.Errorf("invalid postgres table name: %s", w.table)
+	}
+	if w.db == nil {
+		db, err := sql.Open("postgres", w.dsn())
+		if err != nil {
+			return nil, err
+		}
+		db.SetMaxOpenConns(4)
+		db.SetConnMaxLifetime(30 * time.Minute)
+		w.db = db
+	}
+	idx := strings.ReplaceAll(w.table, ".", "_")
+	stmts := []string{
+		`CREATE TABLE IF NOT EXISTS ` + w.table + ` (
+			id BIGSERIAL PRIMARY KEY,
+			task_id TEXT NOT NULL,
+			task_batch INTEGER NOT NULL DEFAULT 0,
+			device_name TEXT NOT NULL,
+			device_ip TEXT NOT NULL DEFAULT '',
+			device_platform TEXT NOT NULL,
+			cli TEXT NOT NULL,
+			payload JSONB NOT NULL,
+			collected_at TIMESTAMPTZ NOT NULL
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_` + idx + `_task ON ` + w.table + ` (task_id)`,
+		`CREATE INDEX IF NOT EXISTS idx_` + idx + `_device_cli ON ` + w.table + ` (device_name, cli)`,
+	}
+	for _, q := range stmts {
+		if _, err := w.db.ExecContext(ctx, q); err != nil {
+			return nil, fmt.Errorf("ensure postgres table: %w", err)
+		}
+	}
+	w.ready = true
+	return w.db, nil
+}
+
+// WriteRecords 在单个事务内写入聚合结果中的全部解析记录，返回写入行数
+func (w *FormatPostgresWriter) WriteRecords(ctx context.Context, taskID string, batch int, agg map[string]map[string][]FormattedItem, ts time.Time) (int, error) {
+	if w == nil {
+		return 0, fmt.Errorf("postgres writer not configured")
+	}
+	db, err := w.open(ctx)
+	if err != nil {
+		return 0, err
+	}
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+	stmt, err := tx.PrepareContext(ctx, `INSERT INTO `+w.table+
+		` (task_id, task_batch, device_name, device_ip, device_platform, cli, payload, collected_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`)
+	if err != nil {
+		return 0, err
+	}
+	defer stmt.Close()
+
+	n := 0
+	for platform, byCmd := range agg {
+		for cli, items := range byCmd {
+			for _, it := range items {
+				for _, rec := range parsedRecords(it.InfoFormatted) {
+					payload, err := json.Marshal(rec)
+					if err != nil {
+						return 0, fmt.Errorf("encode record: %w", err)
+					}
+					if _, err := stmt.ExecContext(ctx, taskID, batch, it.DeviceName, it.DeviceIP, platform, cli, string(payload), ts); err != nil {
+						return 0, fmt.Errorf("insert record: %w", err)
+					}
+					n++
+				}
+			}
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+// Close 关闭连接池
+func (w *FormatPostgresWriter) Close() error {
+	if w == nil {
+		return nil
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.db == nil {
+		return nil
+	}
+	err := w.db.Close()
+	w.db, w.ready = nil, false
+	return err
+}
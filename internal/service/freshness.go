@@ -0,0 +1,97 @@
+package service
+
+import (
+	"strings"
+	"time"
+
+	"github.com/sshcollectorpro/sshcollectorpro/pkg/logger"
+)
+
+// CommandResultIndex 命令最近一次落盘结果的索引（数据库实现由接口层注入）
+type CommandResultIndex interface {
+	// Latest 返回设备命令最近一次落盘的对象与采集时间
+	Latest(deviceIP string, port int, command string) (StoredObject, time.Time, bool)
+	// Record 记录（覆盖）设备命令最近一次落盘结果
+	Record(deviceIP string, port int, command, taskID string, obj StoredObject, at time.Time) error
+}
+
+// CommandKey 结果索引使用的命令键：小写去空白
+func CommandKey(cmd string) string {
+	return strings.ToLower(strings.TrimSpace(cmd))
+}
+
+// freshHit 结果仍新鲜、无需执行的命令及其缓存对象
+type freshHit struct {
+	Command string
+	Object  StoredObject
+	At      time.Time
+}
+
+// freshPort 索引使用的端口：未指定时按 SSH 默认端口，备份、采集、格式化共用同一条记录
+func freshPort(port int) int {
+	if port <= 0 || port > 65535 {
+		return 22
+	}
+	return port
+}
+
+// splitFreshCommands 按新鲜度拆分命令：返回仍需执行的命令，以及可引用缓存对象的命令
+// 单条命令 fresh_ttl 优先，未设置时使用任务级 defTTL；TTL<=0 表示始终执行
+func splitFreshCommands(idx CommandResultIndex, deviceIP string, port int, list CLIList, defTTL int, now time.Time) (CLIList, []freshHit) {
+	if idx == nil {
+		return list, nil
+	}
+	run := make(CLIList, 0, len(list))
+	var hits []freshHit
+	for _, it := range list {
+		ttl := defTTL
+		if it.FreshTTL > 0 {
+			ttl = it.FreshTTL
+		}
+		if ttl <= 0 {
+			run = append(run, it)
+			continue
+		}
+		obj, at, ok := idx.Latest(deviceIP, freshPort(port), CommandKey(it.CLI))
+		if !ok || now.Sub(at) >= time.Duration(ttl)*time.Second {
+			run = append(run, it)
+			continue
+		}
+		hits = append(hits, freshHit{Command: it.CLI, Object: obj, At: at})
+	}
+	return run, hits
+}
+
+// backupResult 跳过执行的备份命令结果，引用缓存对象
+func (h freshHit) backupResult() CommandBackupResult {
+	at := h.At
+	return CommandBackupResult{
+		Command:        h.Command,
+		RawOutputLines: []string{},
+		StoredObjects:  []StoredObject{h.Object},
+		SkippedFresh:   true,
+		CollectedAt:    &at,
+	}
+}
+
+// collectView 跳过执行的采集命令结果，引用缓存对象（响应不含输出）
+func (h freshHit) collectView() *CommandResultView {
+	at := h.At
+	return &CommandResultView{
+		Command:       h.Command,
+		FormatOutput:  []map[string]interface{}{},
+		StoredObjects: []StoredObject{h.Object},
+		SkippedFresh:  true,
+		CollectedAt:   &at,
+	}
+}
+
+// recordFreshResult 记录成功落盘的命令结果；失败仅告警，不影响任务结果
+func recordFreshResult(idx CommandResultIndex, deviceIP string, port int, command, taskID string, obj StoredObject, at time.Time) {
+	if idx == nil || obj.URI == "" {
+		return
+	}
+	if err := idx.Record(deviceIP, freshPort(port), CommandKey(command), taskID, obj, at); err != nil {
+		logger.Warn("Failed to record command snapshot", "device_ip", deviceIP, "command", command, "error", err)
+	}
+}
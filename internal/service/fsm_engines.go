@@ -0,0 +1,326 @@
+package service
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// 模板解析引擎（FSMTemplateDef.template_type）
+const (
+	TemplateTypeTextFSM = "textfsm"
+	TemplateTypeTTP     = "ttp"
+	TemplateTypeRegex   = "regex"
+)
+
+// templateTypeDirective 模板首行的引擎声明；对 TextFSM 与逐行正则回退均为注释行
+const templateTypeDirective = "#template_type:"
+
+// normalizeTemplateType 校验并规范化模板引擎，空值为 textfsm
+func normalizeTemplateType(t string) (string, error) {
+	switch v := strings.ToLower(strings.TrimSpace(t)); v {
+	case "":
+		return TemplateTypeTextFSM, nil
+	case TemplateTypeTextFSM, TemplateTypeTTP, TemplateTypeRegex:
+		return v, nil
+	default:
+		return "", validationErrorf("unsupported template_type: %s (expected textfsm, ttp or regex)", t)
+	}
+}
+
+// tagTemplate 非 TextFSM 模板在首行写入引擎声明，模板在查找表与编译缓存中仍以字符串传递
+func tagTemplate(typ, body string) string {
+	if typ == TemplateTypeTextFSM {
+		return body
+	}
+	return templateTypeDirective + " " + typ + "\n" + body
+}
+
+// templateTypeOf 读取首行引擎声明，返回引擎与去掉声明后的模板内容
+func templateTypeOf(tpl string) (string, string) {
+	first, rest, _ := strings.Cut(tpl, "\n")
+	if v, ok := strings.CutPrefix(strings.TrimSpace(first), templateTypeDirective); ok {
+		if typ, err := normalizeTemplateType(v); err == nil {
+			return typ, rest
+		}
+	}
+	return TemplateTypeTextFSM, tpl
+}
+
+// buildTemplateTable 构造模板查找表：platform -> cli -> []模板；同一命令可混用不同引擎，按顺序尝试
+func buildTemplateTable(defs []FSMTemplateDef) (map[string]map[string][]string, error) {
+	tmpl := make(map[string]map[string][]string)
+	for _, d := range defs {
+		p := strings.ToLower(strings.TrimSpace(d.DevicePlatform))
+		if p == "" {
+			continue
+		}
+		typ, err := normalizeTemplateType(d.TemplateType)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := tmpl[p]; !ok {
+			tmpl[p] = make(map[string][]string)
+		}
+		for _, tv := range d.TemplateValues {
+			cli := strings.ToLower(strings.TrimSpace(tv.CLIName))
+			if cli == "" {
+				continue
+			}
+			if typ != TemplateTypeTextFSM {
+				if err := validateTemplateBody(typ, tv.FSMValue); err != nil {
+					return nil, validationErrorf("fsm_templates %s/%s: %v", p, cli, err)
+				}
+			}
+			tmpl[p][cli] = append(tmpl[p][cli], tagTemplate(typ, tv.FSMValue))
+		}
+	}
+	return tmpl, nil
+}
+
+// ParseWithTemplateType 以指定引擎的单个模板解析原始输出（与格式化接口使用相同的解析逻辑）
+func ParseWithTemplateType(templateType, tpl, raw string) ([]map[string]interface{}, error) {
+	typ, err := normalizeTemplateType(templateType)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateTemplateBody(typ, tpl); err != nil {
+		return nil, err
+	}
+	return ParseWithTemplate(tagTemplate(typ, tpl), raw)
+}
+
+// validateTemplateBody 校验 TTP 与正则模板可编译且至少定义一个字段
+func validateTemplateBody(typ, body string) error {
+	switch typ {
+	case TemplateTypeTTP:
+		if t := compileTTPTemplate(body); len(t.fields) == 0 {
+			return fmt.Errorf("ttp template has no {{ variable }} placeholders")
+		}
+	case TemplateTypeRegex:
+		if _, err := compileNamedRegex(body); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ====== 命名分组正则 ======
+
+// compileNamedRegex 整个模板为一条正则（多行模式），至少包含一个命名分组 (?P<name>...)
+func compileNamedRegex(body string) (*regexp.Regexp, error) {
+	p := strings.TrimSpace(body)
+	if p == "" {
+		return nil, fmt.Errorf("regex template is empty")
+	}
+	re, err := regexp.Compile("(?m)" + p)
+	if err != nil {
+		return nil, fmt.Errorf("invalid regex template: %w", err)
+	}
+	if len(namedGroups(re)) == 0 {
+		return nil, fmt.Errorf("regex template has no named groups")
+	}
+	return re, nil
+}
+
+func namedGroups(re *regexp.Regexp) []string {
+	out := make([]string, 0)
+	for _, n := range re.SubexpNames() {
+		if n != "" {
+			out = append(out, n)
+		}
+	}
+	return out
+}
+
+// parseNamedRegex 每个匹配产出一条记录，键为命名分组
+func parseNamedRegex(re *regexp.Regexp, raw string) []map[string]interface{} {
+	if re == nil {
+		return nil
+	}
+	names := re.SubexpNames()
+	out := make([]map[string]interface{}, 0)
+	for _, m := range re.FindAllStringSubmatch(raw, -1) {
+		rec := make(map[string]interface{})
+		for i, n := range names {
+			if n != "" && i < len(m) {
+				rec[n] = strings.TrimSpace(m[i])
+			}
+		}
+		out = append(out, rec)
+	}
+	return out
+}
+
+// ====== TTP 风格模板 ======
+
+// ttpLine 模板行：整行正则 + 捕获组对应的变量名
+type ttpLine struct {
+	re    *regexp.Regexp
+	vars  []string
+	start bool
+}
+
+// ttpGroup <group> 块；组外的行构成一个隐式组
+type ttpGroup struct {
+	lines []ttpLine
+}
+
+type ttpTemplate struct {
+	groups []ttpGroup
+	fields []string
+}
+
+var (
+	ttpPlaceholderRe = regexp.MustCompile(`\{\{\s*(.*?)\s*\}\}`)
+	ttpGroupOpenRe   = regexp.MustCompile(`^<group(\s[^>]*)?>$`)
+	ttpReFuncRe      = regexp.MustCompile(`^re\(\s*["'](.*)["']\s*\)$`)
+	ttpWhitespaceRe  = regexp.MustCompile(`\s+`)
+)
+
+// ttpPatterns TTP 内置匹配模式；未声明时为 WORD
+var ttpPatterns = map[string]string{
+	"WORD":     `\S+`,
+	"PHRASE":   `\S+(?: \S+)+`,
+	"ORPHRASE": `\S+(?: \S+)*`,
+	"DIGIT":    `\d+`,
+	"IP":       `(?:[0-9]{1,3}\.){3}[0-9]{1,3}`,
+	"PREFIX":   `(?:[0-9]{1,3}\.){3}[0-9]{1,3}/[0-9]{1,2}`,
+	"IPV6":     `[0-9a-fA-F:]*:[0-9a-fA-F:.]+`,
+	"MAC":      `(?:[0-9a-fA-F]{2}[:-]){5}[0-9a-fA-F]{2}|(?:[0-9a-fA-F]{4}\.){2}[0-9a-fA-F]{4}`,
+	"_line_":   `.+`,
+}
+
+// compileTTPTemplate 编译 TTP 模板：每个含 {{ var }} 的行为一条匹配规则；
+// 组内首行（或标记 _start_ 的行）开始一条新记录，其余行向当前记录填充字段。
+// 支持 WORD/PHRASE/ORPHRASE/DIGIT/IP/PREFIX/IPV6/MAC/_line_ 与 re("...")，其他函数忽略；{{ ignore }} 匹配但不记录
+func compileTTPTemplate(body string) *ttpTemplate {
+	t := &ttpTemplate{}
+	seen := map[string]bool{}
+	cur := ttpGroup{}
+	flush := func() {
+		if len(cur.lines) > 0 {
+			markTTPStart(&cur)
+			t.groups = append(t.groups, cur)
+		}
+		cur = ttpGroup{}
+	}
+	for _, ln := range strings.Split(body, "\n") {
+		line := strings.TrimSpace(ln)
+		switch {
+		case line == "" || strings.HasPrefix(line, "##"):
+			continue
+		case ttpGroupOpenRe.MatchString(line), line == "</group>":
+			flush()
+			continue
+		}
+		tl, ok := compileTTPLine(line)
+		if !ok {
+			continue
+		}
+		for _, v := range tl.vars {
+			if !seen[v] {
+				seen[v] = true
+				t.fields = append(t.fields, v)
+			}
+		}
+		cur.lines = append(cur.lines, tl)
+	}
+	flush()
+	return t
+}
+
+// markTTPStart 组内未显式标记 _start_ 时首行为记录起始行
+func markTTPStart(g *ttpGroup) {
+	for _, l := range g.lines {
+		if l.start {
+			return
+		}
+	}
+	g.lines[0].start = true
+}
+
+func compileTTPLine(line string) (ttpLine, bool) {
+	locs := ttpPlaceholderRe.FindAllStringSubmatchIndex(line, -1)
+	if len(locs) == 0 {
+		return ttpLine{}, false
+	}
+	var b strings.Builder
+	b.WriteString(`^\s*`)
+	out := ttpLine{}
+	prev := 0
+	for _, loc := range locs {
+		b.WriteString(ttpLiteral(line[prev:loc[0]]))
+		prev = loc[1]
+		parts := strings.Split(line[loc[2]:loc[3]], "|")
+		name := strings.TrimSpace(parts[0])
+		pattern := ttpPatterns["WORD"]
+		for _, f := range parts[1:] {
+			f = strings.TrimSpace(f)
+			if f == "_start_" {
+				out.start = true
+				continue
+			}
+			if p, ok := ttpPatterns[f]; ok {
+				pattern = p
+				continue
+			}
+			if m := ttpReFuncRe.FindStringSubmatch(f); m != nil {
+				if _, err := regexp.Compile(m[1]); err == nil {
+					pattern = m[1]
+				}
+			}
+		}
+		if name == "ignore" {
+			b.WriteString("(?:" + pattern + ")")
+			continue
+		}
+		b.WriteString("(" + pattern + ")")
+		out.vars = append(out.vars, name)
+	}
+	b.WriteString(ttpLiteral(line[prev:]))
+	b.WriteString(`\s*$`)
+	re, err := regexp.Compile(b.String())
+	if err != nil {
+		return ttpLine{}, false
+	}
+	out.re = re
+	return out, true
+}
+
+// ttpLiteral 模板中的字面文本：转义正则元字符，连续空白匹配任意空白
+func ttpLiteral(s string) string {
+	return ttpWhitespaceRe.ReplaceAllString(regexp.QuoteMeta(s), `\s+`)
+}
+
+// parseTTP 逐行匹配：每行取模板顺序中第一条命中的规则；记录按起始行出现顺序输出
+func parseTTP(t *ttpTemplate, raw string) []map[string]interface{} {
+	if t == nil || len(t.groups) == 0 {
+		return nil
+	}
+	out := make([]map[string]interface{}, 0)
+	current := make([]map[string]interface{}, len(t.groups))
+	for _, line := range strings.Split(raw, "\n") {
+		line = strings.TrimRight(line, "\r")
+	groups:
+		for gi, g := range t.groups {
+			for _, l := range g.lines {
+				m := l.re.FindStringSubmatch(line)
+				if m == nil {
+					continue
+				}
+				if l.start {
+					current[gi] = make(map[string]interface{})
+					out = append(out, current[gi])
+				}
+				if rec := current[gi]; rec != nil {
+					for i, v := range l.vars {
+						rec[v] = strings.TrimSpace(m[i+1])
+					}
+				}
+				break groups
+			}
+		}
+	}
+	return out
+}
@@ -0,0 +1,148 @@
+package service
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"time"
+)
+
+// maxImpactExamples 每个样本返回的字段变化示例上限
+const maxImpactExamples = 5
+
+// TemplateSample 历史原始输出样本（来自采集任务记录）
+type TemplateSample struct {
+	TaskID     string    `json:"task_id"`
+	DeviceIP   string    `json:"device_ip"`
+	Command    string    `json:"command"`
+	CapturedAt time.Time `json:"captured_at"`
+	Raw        string    `json:"-"`
+}
+
+// TemplateFieldChange 同一序号记录中字段取值的变化
+type TemplateFieldChange struct {
+	Record    int         `json:"record"`
+	Field     string      `json:"field"`
+	Current   interface{} `json:"current"`
+	Candidate interface{} `json:"candidate"`
+}
+
+// TemplateSampleImpact 单个样本的新旧解析结果对比
+type TemplateSampleImpact struct {
+	TemplateSample
+	CurrentRecords   int                   `json:"current_records"`
+	CandidateRecords int                   `json:"candidate_records"`
+	CurrentError     string                `json:"current_error,omitempty"`
+	CandidateError   string                `json:"candidate_error,omitempty"`
+	AddedFields      []string              `json:"added_fields,omitempty"`
+	RemovedFields    []string              `json:"removed_fields,omitempty"`
+	ChangedFields    map[string]int        `json:"changed_fields,omitempty"` // 字段 -> 取值变化的记录数
+	Examples         []TemplateFieldChange `json:"examples,omitempty"`
+	Changed          bool                  `json:"changed"`
+}
+
+// TemplateImpact 模板新版本对历史样本的影响汇总
+type TemplateImpact struct {
+	Samples           int                    `json:"samples"`
+	Changed           int                    `json:"changed"`
+	CurrentRecords    int                    `json:"current_records"`
+	CandidateRecords  int                    `json:"candidate_records"`
+	CurrentFailures   int                    `json:"current_failures"`
+	CandidateFailures int                    `json:"candidate_failures"`
+	AddedFields       []string               `json:"added_fields"`
+	RemovedFields     []string               `json:"removed_fields"`
+	ChangedFields     map[string]int         `json:"changed_fields"`
+	Details           []TemplateSampleImpact `json:"details"`
+}
+
+// ParseWithTemplate 以单个模板解析原始输出（与格式化接口使用相同的解析逻辑）
+func ParseWithTemplate(tpl, raw string) ([]map[string]interface{}, error) {
+	out, err := (&FormatService{}).applyFSM([]string{tpl}, raw)
+	if err != nil {
+		return nil, err
+	}
+	return parsedRecords(out), nil
+}
+
+// CompareTemplateVersions 用当前模板与候选模板分别解析样本，按记录数与字段逐一对比
+// 记录按序号对齐：同序号记录逐字段比较取值，多出或缺少的记录只计入记录数差异
+func CompareTemplateVersions(current, candidate string, samples []TemplateSample) *TemplateImpact {
+	res := &TemplateImpact{Samples: len(samples), ChangedFields: map[string]int{}, Details: make([]TemplateSampleImpact, 0, len(samples))}
+	added, removed := map[string]bool{}, map[string]bool{}
+	for _, s := range samples {
+		d := TemplateSampleImpact{TemplateSample: s, ChangedFields: map[string]int{}}
+		cur, cerr := ParseWithTemplate(current, s.Raw)
+		cand, nerr := ParseWithTemplate(candidate, s.Raw)
+		if cerr != nil {
+			d.CurrentError = cerr.Error()
+			res.CurrentFailures++
+		}
+		if nerr != nil {
+			d.CandidateError = nerr.Error()
+			res.CandidateFailures++
+		}
+		d.CurrentRecords, d.CandidateRecords = len(cur), len(cand)
+		res.CurrentRecords += len(cur)
+		res.CandidateRecords += len(cand)
+
+		curFields, candFields := recordFields(cur), recordFields(cand)
+		for f := range candFields {
+			if !curFields[f] {
+				d.AddedFields = append(d.AddedFields, f)
+				added[f] = true
+			}
+		}
+		for f := range curFields {
+			if !candFields[f] {
+				d.RemovedFields = append(d.RemovedFields, f)
+				removed[f] = true
+			}
+		}
+		sort.Strings(d.AddedFields)
+		sort.Strings(d.RemovedFields)
+
+		for i := 0; i < len(cur) && i < len(cand); i++ {
+			for _, f := range fieldNames(curFields) {
+				if !candFields[f] {
+					continue
+				}
+				a, b := cur[i][f], cand[i][f]
+				if reflect.DeepEqual(a, b) || fmt.Sprint(a) == fmt.Sprint(b) {
+					continue
+				}
+				d.ChangedFields[f]++
+				res.ChangedFields[f]++
+				if len(d.Examples) < maxImpactExamples {
+					d.Examples = append(d.Examples, TemplateFieldChange{Record: i, Field: f, Current: a, Candidate: b})
+				}
+			}
+		}
+		d.Changed = d.CurrentRecords != d.CandidateRecords || len(d.AddedFields) > 0 || len(d.RemovedFields) > 0 ||
+			len(d.ChangedFields) > 0 || (d.CurrentError == "") != (d.CandidateError == "")
+		if d.Changed {
+			res.Changed++
+		}
+		res.Details = append(res.Details, d)
+	}
+	res.AddedFields, res.RemovedFields = fieldNames(added), fieldNames(removed)
+	return res
+}
+
+func recordFields(recs []map[string]interface{}) map[string]bool {
+	out := map[string]bool{}
+	for _, r := range recs {
+		for k := range r {
+			out[k] = true
+		}
+	}
+	return out
+}
+
+func fieldNames(m map[string]bool) []string {
+	out := make([]string, 0, len(m))
+	for k := range m {
+		out = append(out, k)
+	}
+	sort.Strings(out)
+	return out
+}
@@ -0,0 +1,170 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"regexp"
+	"runtime"
+	"strings"
+	"sync"
+
+	"github.com/sshcollectorpro/sshcollectorpro/internal/config"
+	"github.com/sshcollectorpro/sshcollectorpro/pkg/ssh"
+)
+
+// defaultFSMCacheSize 未配置 data_format.fsm.cache_size 时的编译缓存条数
+const defaultFSMCacheSize = 1024
+
+// compiledFSMTemplate 模板编译产物（只读，可并发使用）
+type compiledFSMTemplate struct {
+	engine  string
+	textFSM bool
+	machine *textFSMTemplate
+	rules   []textFSMRule
+	regexes []*regexp.Regexp
+	ttp     *ttpTemplate
+	named   *regexp.Regexp
+}
+
+func compileFSMTemplate(tpl string) *compiledFSMTemplate {
+	engine, body := templateTypeOf(tpl)
+	switch engine {
+	case TemplateTypeTTP:
+		return &compiledFSMTemplate{engine: engine, ttp: compileTTPTemplate(body)}
+	case TemplateTypeRegex:
+		re, _ := compileNamedRegex(body)
+		return &compiledFSMTemplate{engine: engine, named: re}
+	}
+	c := &compiledFSMTemplate{engine: engine, textFSM: looksLikeTextFSM(tpl)}
+	if c.textFSM {
+		c.machine = parseTextFSMTemplate(tpl)
+		c.rules = compileTextFSMRules(tpl)
+	}
+	c.regexes = compileFSMTemplateRegexes(tpl)
+	return c
+}
+
+// FSMCacheStats 模板编译缓存统计
+type FSMCacheStats struct {
+	Size   int    `json:"size"`
+	Limit  int    `json:"limit"`
+	Hits   uint64 `json:"hits"`
+	Misses uint64 `json:"misses"`
+}
+
+// fsmTemplateCache 按模板内容哈希缓存编译产物；超出上限时淘汰任意一条
+type fsmTemplateCache struct {
+	mu      sync.Mutex
+	limit   int
+	entries map[[sha256.Size]byte]*compiledFSMTemplate
+	hits    uint64
+	misses  uint64
+}
+
+var fsmCache = &fsmTemplateCache{limit: defaultFSMCacheSize, entries: map[[sha256.Size]byte]*compiledFSMTemplate{}}
+
+func (c *fsmTemplateCache) get(tpl string) *compiledFSMTemplate {
+	key := sha256.Sum256([]byte(tpl))
+	c.mu.Lock()
+	if ct, ok := c.entries[key]; ok {
+		c.hits++
+		c.mu.Unlock()
+		return ct
+	}
+	c.misses++
+	c.mu.Unlock()
+
+	// 编译在锁外进行，并发编译同一模板时以先写入者为准
+	ct := compileFSMTemplate(tpl)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if existing, ok := c.entries[key]; ok {
+		return existing
+	}
+	if c.limit > 0 {
+		for len(c.entries) >= c.limit {
+			for k := range c.entries {
+				delete(c.entries, k)
+				break
+			}
+		}
+		c.entries[key] = ct
+	}
+	return ct
+}
+
+func (c *fsmTemplateCache) setLimit(n int) {
+	if n <= 0 {
+		n = defaultFSMCacheSize
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.limit = n
+	for k := range c.entries {
+		if len(c.entries) <= n {
+			break
+		}
+		delete(c.entries, k)
+	}
+}
+
+// CurrentFSMCacheStats 当前模板编译缓存统计
+func CurrentFSMCacheStats() FSMCacheStats {
+	fsmCache.mu.Lock()
+	defer fsmCache.mu.Unlock()
+	return FSMCacheStats{Size: len(fsmCache.entries), Limit: fsmCache.limit, Hits: fsmCache.hits, Misses: fsmCache.misses}
+}
+
+// fsmParseWorkers 解析 worker 数：data_format.fsm.workers，未配置时为 CPU 核数
+func fsmParseWorkers(cfg *config.Config) int {
+	if cfg != nil && cfg.DataFormat.FSM.Workers > 0 {
+		return cfg.DataFormat.FSM.Workers
+	}
+	return runtime.NumCPU()
+}
+
+// fsmParseResult 单条命令的解析结果
+type fsmParseResult struct {
+	formatted interface{}
+	err       error
+}
+
+// parseCommands 并行解析一台设备的命令输出，结果与 results 下标对应；
+// 本地模板解析占用解析 worker，避免大批量格式化时与设备交互争用或串行
+func (s *FormatService) parseCommands(ctx context.Context, platform string, cliList []string, results []*ssh.CommandResult, tmpl map[string]map[string][]string, hooks map[string]map[string]*ExternalParser) []fsmParseResult {
+	out := make([]fsmParseResult, len(results))
+	var wg sync.WaitGroup
+	for i, r := range results {
+		if r == nil {
+			continue
+		}
+		disp := strings.TrimSpace(safeDisplayCmd(cliList, i))
+		if disp == "" {
+			disp = strings.TrimSpace(r.Command)
+		}
+		cli := strings.ToLower(disp)
+		wg.Add(1)
+		go func(i int, cli, raw string) {
+			defer wg.Done()
+			f, err := s.parseOutput(ctx, hooks[platform][cli], platform, cli, tmpl[platform][cli], raw)
+			out[i] = fsmParseResult{formatted: f, err: err}
+		}(i, cli, r.Output)
+	}
+	wg.Wait()
+	return out
+}
+
+// parseLocal 占用一个解析 worker 执行本地模板解析
+func (s *FormatService) parseLocal(ctx context.Context, templates []string, raw string) (interface{}, error) {
+	if s.parseSlots == nil {
+		return s.applyFSM(templates, raw)
+	}
+	slots := s.parseSlots.get()
+	select {
+	case slots <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	defer func() { <-slots }()
+	return s.applyFSM(templates, raw)
+}
@@ -0,0 +1,151 @@
+package service
+
+import (
+	"bufio"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// TemplateStore 已登记的 TextFSM 模板库（SQLite 索引 + 文件内容，由接口层注入）
+type TemplateStore interface {
+	// Lookup 按平台与命令返回模板内容；支持 ntc-templates 的命令缩写匹配
+	Lookup(platform, cli string) []string
+}
+
+// SetTemplateStore 注入模板库：请求未提供对应模板时回退使用
+func (s *FormatService) SetTemplateStore(store TemplateStore) {
+	s.templateStore = store
+}
+
+// fillStoredTemplates 对请求中未提供模板的 平台+命令 从模板库补齐
+// 未命中时记录空列表，同一批次不重复查询；须在派发设备协程前调用，执行期间 tmpl 只读
+func (s *FormatService) fillStoredTemplates(tmpl map[string]map[string][]string, platform string, clis []string) {
+	if s.templateStore == nil {
+		return
+	}
+	p := strings.ToLower(strings.TrimSpace(platform))
+	if p == "" {
+		return
+	}
+	for _, c := range clis {
+		cli := strings.ToLower(strings.TrimSpace(c))
+		if cli == "" {
+			continue
+		}
+		if _, seen := tmpl[p][cli]; seen {
+			continue
+		}
+		if _, ok := tmpl[p]; !ok {
+			tmpl[p] = make(map[string][]string)
+		}
+		tmpl[p][cli] = append([]string{}, s.templateStore.Lookup(p, cli)...)
+	}
+}
+
+// ValidateTextFSMTemplate 校验模板至少包含一个 Value 与一个状态
+func ValidateTextFSMTemplate(content string) error {
+	if strings.TrimSpace(content) == "" {
+		return fmt.Errorf("template content is empty")
+	}
+	t := parseTextFSMTemplate(content)
+	if t == nil || len(t.vars) == 0 {
+		return fmt.Errorf("template has no Value definitions")
+	}
+	if len(t.states) == 0 {
+		return fmt.Errorf("template has no states")
+	}
+	return nil
+}
+
+// ====== ntc-templates 索引 ======
+
+// NTCIndexEntry ntc-templates index 文件中的一行
+// Templates 可含多个模板（以冒号分隔），Command 为带 [[...]] 缩写标记的原始写法
+type NTCIndexEntry struct {
+	Templates []string
+	Platform  string
+	Command   string
+}
+
+// ParseNTCIndex 解析 ntc-templates 的 index 文件（# 注释，首个非注释行为表头）
+func ParseNTCIndex(r io.Reader) ([]NTCIndexEntry, error) {
+	var header []string
+	entries := make([]NTCIndexEntry, 0)
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64*1024), 1024*1024)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		cr := csv.NewReader(strings.NewReader(line))
+		cr.TrimLeadingSpace = true
+		fields, err := cr.Read()
+		if err != nil {
+			return nil, fmt.Errorf("index line %q: %w", line, err)
+		}
+		if header == nil {
+			header = make([]string, len(fields))
+			for i, f := range fields {
+				header[i] = strings.ToLower(strings.TrimSpace(f))
+			}
+			continue
+		}
+		var e NTCIndexEntry
+		for i, f := range fields {
+			if i >= len(header) {
+				break
+			}
+			f = strings.TrimSpace(f)
+			switch header[i] {
+			case "template":
+				for _, t := range strings.Split(f, ":") {
+					if t = strings.TrimSpace(t); t != "" {
+						e.Templates = append(e.Templates, t)
+					}
+				}
+			case "platform":
+				e.Platform = strings.ToLower(f)
+			case "command":
+				e.Command = f
+			}
+		}
+		if len(e.Templates) == 0 || e.Platform == "" || e.Command == "" {
+			continue
+		}
+		entries = append(entries, e)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	if header == nil {
+		return nil, fmt.Errorf("index has no header")
+	}
+	return entries, nil
+}
+
+var ntcOptionalPattern = regexp.MustCompile(`\[\[([^\]]*)\]\]`)
+
+// ExpandNTCCommand 去除缩写标记得到完整命令：sh[[ow]] ver[[sion]] -> show version
+func ExpandNTCCommand(pattern string) string {
+	return strings.ToLower(strings.Join(strings.Fields(ntcOptionalPattern.ReplaceAllString(pattern, "$1")), " "))
+}
+
+// NTCCommandRegexp 将索引中的命令写法转为匹配正则（与 textfsm clitable 一致，其余部分按正则处理）
+// 缩写标记逐字符可选：sh[[ow]] -> sh(o(w)?)?
+func NTCCommandRegexp(pattern string) (*regexp.Regexp, error) {
+	expr := ntcOptionalPattern.ReplaceAllStringFunc(pattern, func(m string) string {
+		opt := []rune(m[2 : len(m)-2])
+		var b strings.Builder
+		for _, r := range opt {
+			b.WriteString("(" + regexp.QuoteMeta(string(r)))
+		}
+		b.WriteString(strings.Repeat(")?", len(opt)))
+		return b.String()
+	})
+	expr = strings.Join(strings.Fields(expr), `\s+`)
+	return regexp.Compile(`(?i)^\s*` + expr + `\s*$`)
+}
@@ -0,0 +1,473 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/sshcollectorpro/sshcollectorpro/internal/config"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/database"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/model"
+	"github.com/sshcollectorpro/sshcollectorpro/pkg/logger"
+)
+
+// 实例角色
+const (
+	HARoleStandalone = "standalone" // 未启用高可用
+	HARoleLeader     = "leader"
+	HARoleFollower   = "follower"
+)
+
+// haLease 租约当前状态
+type haLease struct {
+	Holder    string
+	ExpiresAt time.Time
+}
+
+// leaseStore 租约存储：租约空闲、已过期或已由 holder 持有时获取/续约，返回操作后的租约
+type leaseStore interface {
+	Acquire(ctx context.Context, name, holder string, ttl time.Duration) (haLease, error)
+	Release(name, holder string) error
+}
+
+// HAStatus 高可用状态（/api/v1/ha/status）
+type HAStatus struct {
+	Enabled        bool       `json:"enabled"`
+	Backend        string     `json:"backend,omitempty"`
+	InstanceID     string     `json:"instance_id,omitempty"`
+	Role           string     `json:"role"`
+	Leader         string     `json:"leader,omitempty"`
+	LeaseExpiresAt *time.Time `json:"lease_expires_at,omitempty"`
+	Since          *time.Time `json:"since,omitempty"` // 进入当前角色的时间
+	Transitions    int        `json:"transitions"`
+	LastError      string     `json:"last_error,omitempty"`
+}
+
+// LeaderElector 租约选主：按 renew_interval 续约或抢占；续约失败且租约到期后自动降级为 follower
+type LeaderElector struct {
+	store   leaseStore
+	backend string
+	name    string
+	id      string
+	ttl     time.Duration
+	renew   time.Duration
+
+	mu          sync.RWMutex
+	leader      bool
+	term        int64 // 本次成为 leader 的任期，用于同一任期只执行一次回调
+	holder      string
+	expiresAt   time.Time
+	since       time.Time
+	transitions int
+	lastErr     string
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// NewLeaderElector 按配置创建选主器（未启动）
+func NewLeaderElector(hc config.HAConfig) (*LeaderElector, error) {
+	if hc.LeaseDuration <= 0 {
+		hc.LeaseDuration = 15 * time.Second
+	}
+	if hc.RenewInterval <= 0 {
+		hc.RenewInterval = hc.LeaseDuration / 3
+	}
+	if hc.RenewInterval >= hc.LeaseDuration {
+		return nil, fmt.Errorf("ha.renew_interval (%s) must be less than ha.lease_duration (%s)", hc.RenewInterval, hc.LeaseDuration)
+	}
+	name := strings.TrimSpace(hc.LeaseName)
+	if name == "" {
+		name = "sshcollector"
+	}
+	id := strings.TrimSpace(hc.InstanceID)
+	if id == "" {
+		host, _ := os.Hostname()
+		id = fmt.Sprintf("%s-%d", host, os.Getpid())
+	}
+	backend := strings.ToLower(strings.TrimSpace(hc.Backend))
+	var store leaseStore
+	switch backend {
+	case "db", "":
+		backend = "db"
+		store = dbLeaseStore{}
+	case "file":
+		if strings.TrimSpace(hc.FilePath) == "" {
+			return nil, fmt.Errorf("ha.file_path is required")
+		}
+		store = fileLeaseStore{path: hc.FilePath}
+	default:
+		return nil, fmt.Errorf("unsupported ha.backend: %s", hc.Backend)
+	}
+	return &LeaderElector{store: store, backend: backend, name: name, id: id, ttl: hc.LeaseDuration, renew: hc.RenewInterval, since: time.Now()}, nil
+}
+
+// Start 立即尝试一次获取租约，之后后台定时续约/抢占
+func (e *LeaderElector) Start(ctx context.Context) {
+	ctx, e.cancel = context.WithCancel(ctx)
+	e.done = make(chan struct{})
+	e.tick(ctx)
+	go func() {
+		defer close(e.done)
+		ticker := time.NewTicker(e.renew)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				e.tick(ctx)
+			}
+		}
+	}()
+}
+
+// Stop 停止续约；为 leader 时主动释放租约，便于 follower 立即接管
+func (e *LeaderElector) Stop() {
+	if e.cancel == nil {
+		return
+	}
+	e.cancel()
+	<-e.done
+	e.cancel = nil
+	e.mu.Lock()
+	wasLeader := e.leader
+	e.setRole(false)
+	e.mu.Unlock()
+	if wasLeader {
+		if err := e.store.Release(e.name, e.id); err != nil {
+			logger.Warn("HA: release lease failed", "lease", e.name, "error", err)
+		}
+	}
+}
+
+// tick 续约或抢占一次
+func (e *LeaderElector) tick(ctx context.Context) {
+	ctx, cancel := context.WithTimeout(ctx, e.renew)
+	defer cancel()
+	lease, err := e.store.Acquire(ctx, e.name, e.id, e.ttl)
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if err != nil {
+		e.lastErr = err.Error()
+		logger.Warn("HA: lease renew failed", "lease", e.name, "instance", e.id, "error", err)
+		// 无法确认租约时，到期即降级，避免与新 leader 同时执行
+		if e.leader && !time.Now().Before(e.expiresAt) {
+			e.setRole(false)
+		}
+		return
+	}
+	e.lastErr = ""
+	e.holder, e.expiresAt = lease.Holder, lease.ExpiresAt
+	e.setRole(lease.Holder == e.id)
+}
+
+// setRole 切换角色并记录；调用方持有 e.mu
+func (e *LeaderElector) setRole(leader bool) {
+	if e.leader == leader {
+		return
+	}
+	e.leader = leader
+	e.since = time.Now()
+	e.transitions++
+	if leader {
+		e.term = leaderTerms.Add(1) + standaloneLeaderTerm
+		logger.Info("HA: became leader", "lease", e.name, "instance", e.id)
+		go fireLeaderHooks(e)
+	} else {
+		logger.Warn("HA: stepped down to follower", "lease", e.name, "instance", e.id, "leader", e.holder)
+	}
+}
+
+// IsLeader 本实例是否持有未过期的租约
+func (e *LeaderElector) IsLeader() bool {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.leader && time.Now().Before(e.expiresAt)
+}
+
+// leaderTerm 当前任期；非 leader 返回 0
+func (e *LeaderElector) leaderTerm() int64 {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	if e.leader && time.Now().Before(e.expiresAt) {
+		return e.term
+	}
+	return 0
+}
+
+// Status 当前选主状态
+func (e *LeaderElector) Status() HAStatus {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	st := HAStatus{Enabled: true, Backend: e.backend, InstanceID: e.id, Role: HARoleFollower, Leader: e.holder, Transitions: e.transitions, LastError: e.lastErr}
+	if e.leader && time.Now().Before(e.expiresAt) {
+		st.Role = HARoleLeader
+	}
+	if !e.expiresAt.IsZero() {
+		exp := e.expiresAt
+		st.LeaseExpiresAt = &exp
+	}
+	since := e.since
+	st.Since = &since
+	return st
+}
+
+var (
+	haMu    sync.RWMutex
+	elector *LeaderElector
+)
+
+// InitHA 按配置启动选主；未启用时所有实例均视为 leader。重复调用会先停止旧的选主器
+func InitHA(cfg *config.Config) error {
+	CloseHA()
+	if cfg == nil || !cfg.HA.Enable {
+		return nil
+	}
+	e, err := NewLeaderElector(cfg.HA)
+	if err != nil {
+		return err
+	}
+	e.Start(context.Background())
+	haMu.Lock()
+	elector = e
+	haMu.Unlock()
+	logger.Info("HA leader election started", "backend", e.backend, "lease", e.name, "instance", e.id, "leader", e.IsLeader())
+	// 首次续约在登记前完成，已注册的回调在此补执行
+	fireLeaderHooks(e)
+	return nil
+}
+
+// CloseHA 停止选主并释放租约
+func CloseHA() {
+	haMu.Lock()
+	e := elector
+	elector = nil
+	haMu.Unlock()
+	if e != nil {
+		e.Stop()
+	}
+}
+
+// IsLeader 是否应执行定时任务（NetBox 定时同步、任务历史清理、中断任务恢复）；未启用高可用时恒为 true
+func IsLeader() bool {
+	haMu.RLock()
+	defer haMu.RUnlock()
+	return elector == nil || elector.IsLeader()
+}
+
+// standaloneLeaderTerm 未启用高可用时的固定任期；选主产生的任期从其后递增
+const standaloneLeaderTerm = 1
+
+// leaderTerms 进程内成为 leader 的次数
+var leaderTerms atomic.Int64
+
+// leaderHook 成为 leader 时执行的回调；term 为最近一次执行的任期
+type leaderHook struct {
+	mu   sync.Mutex
+	fn   func()
+	term int64
+}
+
+// run 每个任期只执行一次；term 为 0 表示当前不是 leader
+func (h *leaderHook) run(term int64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if term == 0 || term <= h.term {
+		return
+	}
+	h.term = term
+	h.fn()
+}
+
+var (
+	leaderHooksMu sync.Mutex
+	leaderHooks   []*leaderHook
+)
+
+// OnLeaderElected 注册成为 leader 时执行的回调（中断任务恢复等）：注册时已是 leader 则同步执行一次，
+// 之后每次由 follower 切换为 leader 时在后台执行，接管故障实例遗留的工作。返回取消注册的函数
+func OnLeaderElected(fn func()) func() {
+	h := &leaderHook{fn: fn}
+	leaderHooksMu.Lock()
+	leaderHooks = append(leaderHooks, h)
+	leaderHooksMu.Unlock()
+	h.run(currentLeaderTerm())
+	return func() {
+		leaderHooksMu.Lock()
+		defer leaderHooksMu.Unlock()
+		leaderHooks = slices.DeleteFunc(leaderHooks, func(x *leaderHook) bool { return x == h })
+	}
+}
+
+// currentLeaderTerm 当前任期；未启用高可用时为 standaloneLeaderTerm，follower 为 0
+func currentLeaderTerm() int64 {
+	haMu.RLock()
+	defer haMu.RUnlock()
+	if elector == nil {
+		return standaloneLeaderTerm
+	}
+	return elector.leaderTerm()
+}
+
+// fireLeaderHooks e 为当前生效的选主器且为 leader 时执行已注册的回调
+func fireLeaderHooks(e *LeaderElector) {
+	haMu.RLock()
+	current := elector == e
+	haMu.RUnlock()
+	if !current {
+		return
+	}
+	term := e.leaderTerm()
+	leaderHooksMu.Lock()
+	hooks := slices.Clone(leaderHooks)
+	leaderHooksMu.Unlock()
+	for _, h := range hooks {
+		h.run(term)
+	}
+}
+
+// CurrentHAStatus 当前实例的高可用状态
+func CurrentHAStatus() HAStatus {
+	haMu.RLock()
+	defer haMu.RUnlock()
+	if elector == nil {
+		return HAStatus{Role: HARoleStandalone}
+	}
+	return elector.Status()
+}
+
+// dbLeaseStore 共享数据库租约：依赖 SQLite 写事务串行化保证抢占原子性
+type dbLeaseStore struct{}
+
+func (dbLeaseStore) Acquire(ctx context.Context, name, holder string, ttl time.Duration) (haLease, error) {
+	db := database.GetDB()
+	if db == nil {
+		return haLease{}, errors.New("database not initialized")
+	}
+	var row model.HALease
+	now := time.Now()
+	err := db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).
+			Create(&model.HALease{Name: name, Holder: holder, ExpiresAt: now.Add(ttl)}).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&model.HALease{}).
+			Where("name = ? AND (holder = ? OR expires_at < ?)", name, holder, now).
+			Updates(map[string]interface{}{"holder": holder, "expires_at": now.Add(ttl)}).Error; err != nil {
+			return err
+		}
+		return tx.First(&row, "name = ?", name).Error
+	})
+	if err != nil {
+		return haLease{}, err
+	}
+	return haLease{Holder: row.Holder, ExpiresAt: row.ExpiresAt}, nil
+}
+
+func (dbLeaseStore) Release(name, holder string) error {
+	db := database.GetDB()
+	if db == nil {
+		return nil
+	}
+	return db.Model(&model.HALease{}).Where("name = ? AND holder = ?", name, holder).
+		Update("expires_at", time.Now()).Error
+}
+
+// fileLeaseStore 共享目录租约文件：读改写期间以 <path>.lock（O_EXCL 创建）互斥，写入经临时文件 rename
+type fileLeaseStore struct {
+	path string
+}
+
+// fileLease 租约文件内容（按租约名区分）
+type fileLease struct {
+	Holder    string    `json:"holder"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+func (s fileLeaseStore) Acquire(ctx context.Context, name, holder string, ttl time.Duration) (haLease, error) {
+	var out haLease
+	err := s.withLock(ctx, ttl, func(leases map[string]fileLease) bool {
+		now := time.Now()
+		cur, ok := leases[name]
+		if !ok || cur.Holder == holder || now.After(cur.ExpiresAt) {
+			cur = fileLease{Holder: holder, ExpiresAt: now.Add(ttl)}
+			leases[name] = cur
+			out = haLease{Holder: cur.Holder, ExpiresAt: cur.ExpiresAt}
+			return true
+		}
+		out = haLease{Holder: cur.Holder, ExpiresAt: cur.ExpiresAt}
+		return false
+	})
+	return out, err
+}
+
+func (s fileLeaseStore) Release(name, holder string) error {
+	return s.withLock(context.Background(), time.Second, func(leases map[string]fileLease) bool {
+		if cur, ok := leases[name]; ok && cur.Holder == holder {
+			delete(leases, name)
+			return true
+		}
+		return false
+	})
+}
+
+// withLock 获取锁文件后读取租约表，fn 返回 true 时写回；持锁超过 staleAfter 的锁文件视为残留并清除
+func (s fileLeaseStore) withLock(ctx context.Context, staleAfter time.Duration, fn func(map[string]fileLease) bool) error {
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o755); err != nil {
+		return err
+	}
+	lock := s.path + ".lock"
+	for {
+		f, err := os.OpenFile(lock, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+		if err == nil {
+			f.Close()
+			break
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return err
+		}
+		if fi, err := os.Stat(lock); err == nil && time.Since(fi.ModTime()) > staleAfter {
+			_ = os.Remove(lock)
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(20 * time.Millisecond):
+		}
+	}
+	defer os.Remove(lock)
+
+	leases := map[string]fileLease{}
+	if data, err := os.ReadFile(s.path); err == nil && len(data) > 0 {
+		if err := json.Unmarshal(data, &leases); err != nil {
+			return fmt.Errorf("parse lease file: %w", err)
+		}
+	} else if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	if !fn(leases) {
+		return nil
+	}
+	data, err := json.Marshal(leases)
+	if err != nil {
+		return err
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
@@ -0,0 +1,248 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sshcollectorpro/sshcollectorpro/internal/config"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/database"
+	"github.com/sshcollectorpro/sshcollectorpro/simulate"
+)
+
+// 依赖检查状态
+const (
+	DependencyUp       = "up"
+	DependencyDown     = "down"
+	DependencyDisabled = "disabled" // 未配置或未开启，不参与整体状态
+)
+
+// 整体健康状态
+const (
+	HealthOK       = "ok"       // 所有依赖正常
+	HealthDegraded = "degraded" // 非关键依赖异常，仍可接收请求
+	HealthDown     = "down"     // 关键依赖异常
+)
+
+// 单项依赖检查超时
+const dependencyCheckTimeout = 3 * time.Second
+
+// DependencyHealth 单项依赖检查结果
+type DependencyHealth struct {
+	Name      string      `json:"name"`
+	Status    string      `json:"status"`
+	Critical  bool        `json:"critical"` // 关键依赖异常时整体为 down
+	LatencyMS float64     `json:"latency_ms"`
+	Error     string      `json:"error,omitempty"`
+	Details   interface{} `json:"details,omitempty"`
+}
+
+// HealthReport 聚合健康检查结果
+type HealthReport struct {
+	Status    string             `json:"status"`
+	CheckedAt time.Time          `json:"checked_at"`
+	Checks    []DependencyHealth `json:"checks"`
+}
+
+// HealthChecker 聚合各服务与外部依赖的健康检查
+type HealthChecker struct {
+	collector *CollectorService
+	backup    *BackupService
+	format    *FormatService
+	deploy    *DeployService
+}
+
+// NewHealthChecker 创建健康检查器；未提供的服务视为未运行
+func NewHealthChecker(collector *CollectorService, backup *BackupService, format *FormatService, deploy *DeployService) *HealthChecker {
+	return &HealthChecker{collector: collector, backup: backup, format: format, deploy: deploy}
+}
+
+// healthCheck 单项检查：返回状态、详情与错误
+type healthCheck struct {
+	name     string
+	critical bool
+	run      func(ctx context.Context) (string, interface{}, error)
+}
+
+// Check 并发执行所有检查（单项超时 3s），按固定顺序返回
+func (h *HealthChecker) Check(ctx context.Context) HealthReport {
+	checks := []healthCheck{
+		{name: "collector", critical: true, run: h.checkCollector},
+		{name: "backup", critical: true, run: h.checkBackup},
+		{name: "format", critical: true, run: h.checkFormat},
+		{name: "deploy", critical: true, run: h.checkDeploy},
+		{name: "sqlite", critical: true, run: checkSQLite},
+		{name: "minio", run: h.checkMinio},
+		{name: "ssh_pool", run: checkSSHPools},
+		{name: "simulate", run: checkSimulate},
+	}
+	results := make([]DependencyHealth, len(checks))
+	var wg sync.WaitGroup
+	for i, c := range checks {
+		wg.Add(1)
+		go func(i int, c healthCheck) {
+			defer wg.Done()
+			results[i] = runHealthCheck(ctx, c)
+		}(i, c)
+	}
+	wg.Wait()
+
+	report := HealthReport{Status: HealthOK, CheckedAt: time.Now(), Checks: results}
+	for _, r := range results {
+		if r.Status != DependencyDown {
+			continue
+		}
+		if r.Critical {
+			report.Status = HealthDown
+			break
+		}
+		report.Status = HealthDegraded
+	}
+	return report
+}
+
+// runHealthCheck 执行单项检查并记录耗时；超时视为 down
+func runHealthCheck(parent context.Context, c healthCheck) DependencyHealth {
+	ctx, cancel := context.WithTimeout(parent, dependencyCheckTimeout)
+	defer cancel()
+	type result struct {
+		status  string
+		details interface{}
+		err     error
+	}
+	start := time.Now()
+	ch := make(chan result, 1)
+	go func() {
+		status, details, err := c.run(ctx)
+		ch <- result{status, details, err}
+	}()
+	out := DependencyHealth{Name: c.name, Critical: c.critical}
+	select {
+	case r := <-ch:
+		out.Status, out.Details = r.status, r.details
+		if r.err != nil {
+			out.Error = r.err.Error()
+		}
+	case <-ctx.Done():
+		out.Status, out.Error = DependencyDown, "check timed out: "+ctx.Err().Error()
+	}
+	out.LatencyMS = float64(time.Since(start).Microseconds()) / 1000
+	return out
+}
+
+func (h *HealthChecker) checkCollector(ctx context.Context) (string, interface{}, error) {
+	if h.collector == nil {
+		return DependencyDown, nil, fmt.Errorf("collector service not configured")
+	}
+	stats := h.collector.GetStats()
+	details := map[string]interface{}{"active_tasks": stats["active_tasks"], "busy_workers": stats["busy_workers"], "max_workers": stats["max_workers"]}
+	if running, _ := stats["running"].(bool); !running {
+		return DependencyDown, details, serviceStopped("collector")
+	}
+	return DependencyUp, details, nil
+}
+
+func (h *HealthChecker) checkBackup(ctx context.Context) (string, interface{}, error) {
+	if h.backup == nil || !h.backup.running {
+		return DependencyDown, nil, serviceStopped("backup")
+	}
+	return DependencyUp, h.backup.WorkerUtilization(), nil
+}
+
+func (h *HealthChecker) checkFormat(ctx context.Context) (string, interface{}, error) {
+	if h.format == nil {
+		return DependencyDown, nil, serviceStopped("format")
+	}
+	h.format.mutex.RLock()
+	running := h.format.running
+	h.format.mutex.RUnlock()
+	if !running {
+		return DependencyDown, nil, serviceStopped("format")
+	}
+	return DependencyUp, h.format.WorkerUtilization(), nil
+}
+
+// checkDeploy 下发复用采集服务的 worker 与连接池，采集服务停止时不可用
+func (h *HealthChecker) checkDeploy(ctx context.Context) (string, interface{}, error) {
+	if h.deploy == nil || h.deploy.collector == nil {
+		return DependencyDown, nil, serviceStopped("deploy")
+	}
+	h.deploy.collector.mutex.RLock()
+	running := h.deploy.collector.running
+	h.deploy.collector.mutex.RUnlock()
+	if !running {
+		return DependencyDown, nil, serviceStopped("deploy")
+	}
+	return DependencyUp, nil, nil
+}
+
+func checkSQLite(ctx context.Context) (string, interface{}, error) {
+	if err := database.Health(); err != nil {
+		return DependencyDown, nil, err
+	}
+	return DependencyUp, database.GetStats(), nil
+}
+
+// checkMinio TCP 探测 MinIO 端点；未配置 host/port 时为 disabled
+func (h *HealthChecker) checkMinio(ctx context.Context) (string, interface{}, error) {
+	var w *MinioStorageWriter
+	if h.backup != nil {
+		if dw, ok := h.backup.storageWriter.(*DelegatingStorageWriter); ok {
+			w, _, _ = dw.remotes()
+		}
+	}
+	if w == nil {
+		return DependencyDisabled, nil, nil
+	}
+	details := map[string]interface{}{"endpoint": w.endpoint, "bucket": w.bucket}
+	if err := w.fastConnectivityCheck(ctx); err != nil {
+		return DependencyDown, details, err
+	}
+	return DependencyUp, details, nil
+}
+
+// checkSSHPools 各连接池统计；存在连接但全部断开时为 down
+func checkSSHPools(ctx context.Context) (string, interface{}, error) {
+	poolsMu.Lock()
+	var unhealthy []string
+	for name, p := range pools {
+		if err := p.Health(); err != nil {
+			unhealthy = append(unhealthy, name+": "+err.Error())
+		}
+	}
+	poolsMu.Unlock()
+
+	infos := SSHPoolsSnapshot()
+	details := make([]map[string]interface{}, 0, len(infos))
+	for _, info := range infos {
+		details = append(details, map[string]interface{}{"services": info.Services, "shared": info.Shared, "stats": info.Stats})
+	}
+	if len(unhealthy) > 0 {
+		return DependencyDown, details, fmt.Errorf("%s", strings.Join(unhealthy, "; "))
+	}
+	return DependencyUp, details, nil
+}
+
+// checkSimulate 开启模拟服务时检查各命名空间端口是否在监听
+func checkSimulate(ctx context.Context) (string, interface{}, error) {
+	if cfg := config.Get(); cfg == nil || !cfg.Server.SimulateEnable {
+		return DependencyDisabled, nil, nil
+	}
+	m := simulate.Current()
+	if m == nil {
+		return DependencyDown, nil, fmt.Errorf("simulate manager not running")
+	}
+	status := m.Status()
+	var down []string
+	for _, ns := range status {
+		if !ns.Listening {
+			down = append(down, fmt.Sprintf("%s:%d", ns.Namespace, ns.Port))
+		}
+	}
+	if len(down) > 0 {
+		return DependencyDown, status, fmt.Errorf("namespaces not listening: %s", strings.Join(down, ", "))
+	}
+	return DependencyUp, status, nil
+}
@@ -0,0 +1,67 @@
+package service
+
+import (
+	"errors"
+	"strings"
+	"sync"
+
+	"github.com/sshcollectorpro/sshcollectorpro/internal/config"
+	"github.com/sshcollectorpro/sshcollectorpro/pkg/ssh"
+)
+
+// ErrHostKeyRejected 设备主机密钥未通过 ssh.host_key 校验
+var ErrHostKeyRejected = ssh.ErrHostKeyRejected
+
+// StatusHostKeyRejected 设备因主机密钥校验失败未执行
+const StatusHostKeyRejected = "HOST_KEY_REJECTED"
+
+// hostKeyStore 主机密钥指纹仓库，各服务共用；由路由初始化时注入
+var hostKeyStore struct {
+	mu    sync.RWMutex
+	store ssh.HostKeyStore
+}
+
+// SetHostKeyStore 设置主机密钥指纹仓库（tofu/strict 模式读写）
+func SetHostKeyStore(store ssh.HostKeyStore) {
+	hostKeyStore.mu.Lock()
+	hostKeyStore.store = store
+	hostKeyStore.mu.Unlock()
+}
+
+// sharedHostKeyStore 建连时读取当前仓库，服务创建早于仓库注入也能生效
+type sharedHostKeyStore struct{}
+
+var errHostKeyStoreMissing = errors.New("host key store is not configured")
+
+func (sharedHostKeyStore) HostKeys(host string, port int) ([]ssh.HostKeyRecord, error) {
+	hostKeyStore.mu.RLock()
+	s := hostKeyStore.store
+	hostKeyStore.mu.RUnlock()
+	if s == nil {
+		return nil, errHostKeyStoreMissing
+	}
+	return s.HostKeys(host, port)
+}
+
+func (sharedHostKeyStore) SaveHostKey(rec ssh.HostKeyRecord) error {
+	hostKeyStore.mu.RLock()
+	s := hostKeyStore.store
+	hostKeyStore.mu.RUnlock()
+	if s == nil {
+		return errHostKeyStoreMissing
+	}
+	return s.SaveHostKey(rec)
+}
+
+// sshHostKeyPolicy 转换 ssh.host_key 配置；全部为 insecure 时返回 nil（不校验）
+func sshHostKeyPolicy(hc config.SSHHostKeyConfig) *ssh.HostKeyPolicy {
+	mode := strings.ToLower(strings.TrimSpace(hc.Mode))
+	if (mode == "" || mode == config.HostKeyInsecure) && len(hc.Rules) == 0 {
+		return nil
+	}
+	out := &ssh.HostKeyPolicy{Mode: mode, KnownHosts: hc.KnownHosts, Store: sharedHostKeyStore{}}
+	for _, r := range hc.Rules {
+		out.Rules = append(out.Rules, ssh.HostKeyRule{Platform: r.Platform, Match: r.Match, Mode: r.Mode})
+	}
+	return out
+}
@@ -6,10 +6,13 @@ import (
 	"fmt"
 	"net"
 	"strings"
+	"syscall"
 	"time"
 
 	"github.com/sshcollectorpro/sshcollectorpro/internal/config"
+	"github.com/sshcollectorpro/sshcollectorpro/pkg/logger"
 	"github.com/sshcollectorpro/sshcollectorpro/pkg/ssh"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 // ExecRequest 执行器输入参数（设备连接信息）
@@ -24,6 +27,18 @@ type ExecRequest struct {
 	EnablePassword  string
 	TaskTimeoutSec   int
 	DeviceTimeoutSec int
+	// CommandTimeouts 单条命令超时覆盖（秒），键为小写命令
+	CommandTimeouts map[string]int
+	// Stream 流式输出回调（可选），仅转发用户命令
+	Stream *ssh.StreamHooks
+	// RawCommands 需要保留原始字节的命令，键为小写命令
+	RawCommands map[string]bool
+	// Transcript 会话转录（可选），记录交互会话收发的全部字节
+	Transcript *ssh.Transcript
+	// PortCandidates 备用端口：Port 连接被拒绝时按顺序尝试
+	PortCandidates []int
+	// ConnectedPort 实际连接成功的端口（由 Execute 填充）
+	ConnectedPort int
 }
 
 // InteractBasic 统一的设备基础交互入口：
@@ -31,12 +46,12 @@ type ExecRequest struct {
 // - 标准化输出：去除内部预命令（enable/关闭分页），应用统一的行过滤
 // - 面向服务层暴露统一交互入口，避免重复注入与过滤
 type InteractBasic struct {
-	cfg  *config.Config
-	pool *ssh.Pool
+	provider *config.Provider
+	pool     *ssh.Pool
 }
 
 func NewInteractBasic(cfg *config.Config, pool *ssh.Pool) *InteractBasic {
-	return &InteractBasic{cfg: cfg, pool: pool}
+	return &InteractBasic{provider: config.ProviderFor(cfg), pool: pool}
 }
 
 // Execute 执行用户命令：
@@ -49,7 +64,11 @@ func (b *InteractBasic) Execute(ctx context.Context, req *ExecRequest, userComma
 		req.CollectProtocol = "ssh"
 	}
 	if strings.ToLower(req.CollectProtocol) != "ssh" {
-		return nil, fmt.Errorf("unsupported protocol: %s", req.CollectProtocol)
+		return nil, validationErrorf("unsupported protocol: %s", req.CollectProtocol)
+	}
+	// 策略校验：只读模式下禁止进入配置模式的命令
+	if err := CheckCommandsAllowed(b.conf(), req.DevicePlatform, userCommands); err != nil {
+		return nil, err
 	}
 
 	// 端口校正
@@ -63,7 +82,10 @@ func (b *InteractBasic) Execute(ctx context.Context, req *ExecRequest, userComma
 		Port:     port,
 		Username: req.UserName,
 		Password: req.Password,
+		Platform: req.DevicePlatform,
 	}
+	caps := CapabilitiesFor(b.conf(), req.DevicePlatform)
+	caps.applyConnection(conn)
 
 	// 任务超时控制（用于整个执行窗口）
 	effTaskTimeout := req.TaskTimeoutSec
@@ -95,15 +117,32 @@ func (b *InteractBasic) Execute(ctx context.Context, req *ExecRequest, userComma
 		}
 	}
 
-	client, err := b.pool.GetConnection(loginCtx, conn)
-	if err != nil {
-		// 设备登陆阶段的超时错误，统一标注为“设备登陆失败”
-		if isLoginTimeout(err) {
-			return nil, fmt.Errorf("设备登陆失败")
+	// 连接被拒绝时依次尝试备用端口
+	ports := candidatePorts(port, req.PortCandidates)
+	var client *ssh.Client
+	var err error
+	for i, p := range ports {
+		conn.Port = p
+		_, connSpan := startSpan(ctx, "ssh.connect", attribute.String("net.peer.name", req.DeviceIP), attribute.Int("net.peer.port", p))
+		client, err = getTracedConnection(loginCtx, b.pool, conn)
+		endSpan(connSpan, err)
+		if err == nil || i == len(ports)-1 || !isConnectionRefused(err) {
+			break
 		}
-		return nil, fmt.Errorf("failed to create SSH connection: %w", err)
+		logger.Warn("SSH port refused, trying next candidate", "device_ip", req.DeviceIP, "port", p, "next_port", ports[i+1])
+	}
+	if err != nil {
+		return nil, classifyConnectError(err)
 	}
 	defer b.pool.ReleaseConnection(conn)
+	req.ConnectedPort = conn.Port
+	if conn.Port != port {
+		AddWarning(ctx, Warning{
+			Code:    WarnPortFallback,
+			Message: fmt.Sprintf("port %d refused, connected on port %d", port, conn.Port),
+			Context: map[string]interface{}{"requested_port": port, "connected_port": conn.Port},
+		})
+	}
 
 	// 注入平台级预命令（enable 与分页关闭）
 	commands := make([]string, 0, len(userCommands)+4)
@@ -114,6 +153,20 @@ func (b *InteractBasic) Execute(ctx context.Context, req *ExecRequest, userComma
 	if len(userCommands) > 0 {
 		commands = append(commands, userCommands...)
 	}
+	// 会话结束前的清理命令（尽力而为，结果由统一过滤剔除）
+	post := b.getPostCommands(req.DevicePlatform, userCommands)
+	commands = append(commands, post...)
+
+	// 平台未配置时使用 default 平台参数，告知调用方
+	if pl := strings.ToLower(strings.TrimSpace(req.DevicePlatform)); pl != "" {
+		if _, ok := b.conf().Collector.DeviceDefaults[pl]; !ok {
+			AddWarning(ctx, Warning{
+				Code:    WarnConfigDefault,
+				Message: fmt.Sprintf("platform %q is not configured, using default platform settings", pl),
+				Context: map[string]interface{}{"device_platform": pl},
+			})
+		}
+	}
 
 	// 交互默认与提示符后缀
 	defaults := getPlatformDefaults(strings.ToLower(strings.TrimSpace(func() string {
@@ -134,9 +187,10 @@ func (b *InteractBasic) Execute(ctx context.Context, req *ExecRequest, userComma
 	// 新增：设备平台用于区分不同平台的处理逻辑
 	interactive.DevicePlatform = strings.TrimSpace(req.DevicePlatform)
 	interactive.PromptSuffixes = promptSuffixes
+	interactive.PromptRegex = defaults.PromptRegex
 	// enable 配置
 	p := strings.ToLower(strings.TrimSpace(req.DevicePlatform))
-	if dd, ok := b.cfg.Collector.DeviceDefaults[p]; ok && dd.EnableRequired {
+	if dd, ok := b.conf().Collector.DeviceDefaults[p]; ok && dd.EnableRequired {
 		interactive.EnableCLI = strings.TrimSpace(dd.EnableCLI)
 		interactive.EnableExpectOutput = strings.TrimSpace(dd.EnableExceptOutput)
 		if strings.TrimSpace(req.EnablePassword) != "" {
@@ -197,52 +251,122 @@ func (b *InteractBasic) Execute(ctx context.Context, req *ExecRequest, userComma
 		interactive.AutoInteractions = mapped
 	}
 	// 不再叠加全局交互；交互配置由平台/device_defaults.interact 提供
+	interactive.CommandTimeouts = req.CommandTimeouts
+	interactive.RawCommands = req.RawCommands
+	interactive.Transcript = req.Transcript
+	interactive.Stream = userCommandHooks(req.Stream, userCommands)
+	var finishTrace func(error)
+	interactive.Stream, finishTrace = traceCommandHooks(ctx, interactive.Stream, []string{req.Password, req.EnablePassword})
+	interactive.LoginSequence = b.getLoginSequence(req.DevicePlatform)
+	if up := b.conf().Collector.UnknownPrompt; up.Enable {
+		interactive.UnknownPromptPatterns = up.Patterns
+		if len(interactive.UnknownPromptPatterns) == 0 {
+			interactive.UnknownPromptPatterns = ssh.DefaultUnknownPromptPatterns
+		}
+		interactive.UnknownPromptResponse = up.Response
+	}
+	caps.applyInteractive(interactive)
 
 	// 交互优先执行
 	res, err := client.ExecuteInteractiveCommands(execCtx, commands, promptSuffixes, interactive)
+	finishTrace(err)
+	if err != nil && len(post) > 0 && userCommandsCompleted(res, userCommands) {
+		// 清理命令失败不影响采集结果
+		logger.Warn("Post commands failed after collection", "device_ip", req.DeviceIP, "platform", req.DevicePlatform, "error", err)
+		err = nil
+	}
+	if errors.Is(err, ssh.ErrLoginSequence) {
+		// 未完成登录步骤的会话命令不生效，回退 exec 通道会得到错误结果
+		_ = b.pool.CloseConnection(conn)
+		return nil, err
+	}
+	if err != nil && !caps.SupportsExec.Value {
+		// 平台不支持 exec 通道，不做非交互回退
+		return nil, fmt.Errorf("interactive failed (exec fallback disabled by platform capabilities): %w", err)
+	}
 	if err != nil {
 		// 回退前重置连接，避免复用异常会话
 		_ = b.pool.CloseConnection(conn)
 		// 重连使用与登录相同的限时窗口
-		client2, errConn := b.pool.GetConnection(loginCtx, conn)
+		client2, errConn := getTracedConnection(loginCtx, b.pool, conn)
 		if errConn != nil {
 			// 若重连失败，保留原始错误以便定位
 			return nil, fmt.Errorf("interactive failed: %v; fallback reconnect failed: %w", err, errConn)
 		}
 		defer b.pool.ReleaseConnection(conn)
-		// 回退非交互（保证尽力而为）
-		res2, err2 := client2.ExecuteCommands(execCtx, commands)
+		// 回退非交互（保证尽力而为）；无逐条命令回调，整体记为一个 span
+		_, fbSpan := startSpan(ctx, "ssh.fallback_exec", attribute.Int("ssh.commands", len(commands)))
+		res2, err2 := client2.ExecuteCommandsWithOptions(execCtx, commands, interactive)
+		endSpan(fbSpan, err2)
 		if err2 != nil {
 			return nil, fmt.Errorf("interactive failed: %v; non-interactive failed: %w", err, err2)
 		}
+		recordCommandTimings(ctx, res2, []string{req.Password, req.EnablePassword})
 		// 回退结果继续走统一过滤流程
-		filtered := filterInternalPreCommandsBase(b.cfg, req.DevicePlatform, userCommands, res2)
+		filtered := filterInternalPreCommandsBase(b.conf(), req.DevicePlatform, userCommands, res2)
 		out := make([]*ssh.CommandResult, 0, len(filtered))
 		for _, r := range filtered {
 			if r == nil {
 				continue
 			}
 			nr := *r
-			nr.Output = applyPlatformLineFilter(b.cfg, req.DevicePlatform, r.Output)
+			nr.Output = applyPlatformLineFilter(b.conf(), req.DevicePlatform, r.Output)
 			out = append(out, &nr)
 		}
+		// 非交互回退无法实时输出，按结果补发流式事件
+		replayStream(req.Stream, out)
+		observeCommandResults(req.DevicePlatform, out)
 		return out, nil
 	}
 
 	// 正常交互结果：统一过滤与输出处理
-	filtered := filterInternalPreCommandsBase(b.cfg, req.DevicePlatform, userCommands, res)
+	recordCommandTimings(ctx, res, []string{req.Password, req.EnablePassword})
+	filtered := filterInternalPreCommandsBase(b.conf(), req.DevicePlatform, userCommands, res)
 	out := make([]*ssh.CommandResult, 0, len(filtered))
 	for _, r := range filtered {
 		if r == nil {
 			continue
 		}
 		nr := *r
-		nr.Output = applyPlatformLineFilter(b.cfg, req.DevicePlatform, r.Output)
+		nr.Output = applyPlatformLineFilter(b.conf(), req.DevicePlatform, r.Output)
 		out = append(out, &nr)
 	}
+	observeCommandResults(req.DevicePlatform, out)
 	return out, nil
 }
 
+// candidatePorts 连接尝试的端口顺序：首选端口在前，其后为去重后的有效备用端口
+func candidatePorts(port int, alternates []int) []int {
+	out := []int{port}
+	for _, p := range alternates {
+		if p < 1 || p > 65535 {
+			continue
+		}
+		dup := false
+		for _, q := range out {
+			if q == p {
+				dup = true
+				break
+			}
+		}
+		if !dup {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// isConnectionRefused 判断是否为端口拒绝连接（未监听），此时可尝试备用端口
+func isConnectionRefused(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, syscall.ECONNREFUSED) {
+		return true
+	}
+	return strings.Contains(strings.ToLower(err.Error()), "connection refused")
+}
+
 // isLoginTimeout 判断连接/握手阶段是否为典型超时错误
 func isLoginTimeout(err error) bool {
 	if err == nil {
@@ -332,19 +456,19 @@ func (b *InteractBasic) getPreCommands(platform string, user []string) []string
 	if p == "" {
 		return out
 	}
-	dd, ok := b.cfg.Collector.DeviceDefaults[p]
+	dd, ok := b.conf().Collector.DeviceDefaults[p]
 	if !ok {
 		if strings.HasPrefix(p, "huawei") {
-			dd, ok = b.cfg.Collector.DeviceDefaults["huawei"]
+			dd, ok = b.conf().Collector.DeviceDefaults["huawei"]
 		}
 		if !ok && strings.HasPrefix(p, "h3c") {
-			dd, ok = b.cfg.Collector.DeviceDefaults["h3c"]
+			dd, ok = b.conf().Collector.DeviceDefaults["h3c"]
 		}
 		if !ok && strings.HasPrefix(p, "cisco") {
-			dd, ok = b.cfg.Collector.DeviceDefaults["cisco_ios"]
+			dd, ok = b.conf().Collector.DeviceDefaults["cisco_ios"]
 		}
 		if !ok && strings.HasPrefix(p, "linux") {
-			dd, ok = b.cfg.Collector.DeviceDefaults["linux"]
+			dd, ok = b.conf().Collector.DeviceDefaults["linux"]
 		}
 	}
 	has := func(cmd string) bool {
@@ -376,24 +500,107 @@ func (b *InteractBasic) getPreCommands(platform string, user []string) []string
 	return out
 }
 
+// getPostCommands 生成平台清理命令（用户已显式执行的不重复追加）
+func (b *InteractBasic) getPostCommands(platform string, user []string) []string {
+	p := strings.ToLower(strings.TrimSpace(platform))
+	if p == "" {
+		return nil
+	}
+	dd, ok := b.conf().Collector.DeviceDefaults[p]
+	if !ok {
+		if strings.HasPrefix(p, "huawei") {
+			dd = b.conf().Collector.DeviceDefaults["huawei"]
+		} else if strings.HasPrefix(p, "h3c") {
+			dd = b.conf().Collector.DeviceDefaults["h3c"]
+		} else if strings.HasPrefix(p, "cisco") {
+			dd = b.conf().Collector.DeviceDefaults["cisco_ios"]
+		} else if strings.HasPrefix(p, "linux") {
+			dd = b.conf().Collector.DeviceDefaults["linux"]
+		}
+	}
+	seen := map[string]struct{}{}
+	for _, c := range user {
+		seen[strings.ToLower(strings.TrimSpace(c))] = struct{}{}
+	}
+	out := make([]string, 0, len(dd.PostCommands))
+	for _, pc := range dd.PostCommands {
+		key := strings.ToLower(strings.TrimSpace(pc))
+		if key == "" {
+			continue
+		}
+		if _, dup := seen[key]; dup {
+			continue
+		}
+		seen[key] = struct{}{}
+		out = append(out, strings.TrimSpace(pc))
+	}
+	return out
+}
+
+// getLoginSequence 平台登录序列（忽略未配置发送内容的步骤）
+func (b *InteractBasic) getLoginSequence(platform string) []ssh.LoginStep {
+	p := strings.ToLower(strings.TrimSpace(platform))
+	if p == "" {
+		return nil
+	}
+	dd, ok := b.conf().Collector.DeviceDefaults[p]
+	if !ok {
+		if strings.HasPrefix(p, "huawei") {
+			dd = b.conf().Collector.DeviceDefaults["huawei"]
+		} else if strings.HasPrefix(p, "h3c") {
+			dd = b.conf().Collector.DeviceDefaults["h3c"]
+		} else if strings.HasPrefix(p, "cisco") {
+			dd = b.conf().Collector.DeviceDefaults["cisco_ios"]
+		} else if strings.HasPrefix(p, "linux") {
+			dd = b.conf().Collector.DeviceDefaults["linux"]
+		}
+	}
+	out := make([]ssh.LoginStep, 0, len(dd.LoginSequence))
+	for _, st := range dd.LoginSequence {
+		if strings.TrimSpace(st.Send) == "" {
+			continue
+		}
+		out = append(out, ssh.LoginStep{Send: strings.TrimSpace(st.Send), Expect: st.Expect, TimeoutSec: st.TimeoutSec})
+	}
+	return out
+}
+
+// userCommandsCompleted 用户命令是否均已返回结果（用于判定失败发生在清理阶段）
+func userCommandsCompleted(results []*ssh.CommandResult, user []string) bool {
+	done := map[string]struct{}{}
+	for _, r := range results {
+		if r != nil {
+			done[strings.ToLower(strings.TrimSpace(r.Command))] = struct{}{}
+		}
+	}
+	for _, u := range user {
+		if _, ok := done[strings.ToLower(strings.TrimSpace(u))]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
 // EnterConfigMode 统一进入配置模式：读取平台 config_mode_clis 并执行
 func (b *InteractBasic) EnterConfigMode(ctx context.Context, req *ExecRequest) ([]*ssh.CommandResult, error) {
-    if b == nil || b.cfg == nil || b.pool == nil { return nil, fmt.Errorf("InteractBasic not initialized") }
+    if b == nil || b.conf() == nil || b.pool == nil { return nil, fmt.Errorf("InteractBasic not initialized") }
+    // 策略校验：只读模式下禁止进入配置模式
+    if err := CheckDeployAllowed(); err != nil { return nil, err }
     p := strings.ToLower(strings.TrimSpace(func() string { if req.DevicePlatform == "" { return "default" }; return req.DevicePlatform }()))
-    dd, ok := b.cfg.Collector.DeviceDefaults[p]
+    dd, ok := b.conf().Collector.DeviceDefaults[p]
     if !ok {
         found := false
         if strings.HasPrefix(p, "huawei") {
-            if v, ok2 := b.cfg.Collector.DeviceDefaults["huawei"]; ok2 { dd = v; found = true }
+            if v, ok2 := b.conf().Collector.DeviceDefaults["huawei"]; ok2 { dd = v; found = true }
         }
         if !found && strings.HasPrefix(p, "h3c") {
-            if v, ok2 := b.cfg.Collector.DeviceDefaults["h3c"]; ok2 { dd = v; found = true }
+            if v, ok2 := b.conf().Collector.DeviceDefaults["h3c"]; ok2 { dd = v; found = true }
         }
         if !found && strings.HasPrefix(p, "cisco") {
-            if v, ok2 := b.cfg.Collector.DeviceDefaults["cisco_ios"]; ok2 { dd = v; found = true }
+            if v, ok2 := b.conf().Collector.DeviceDefaults["cisco_ios"]; ok2 { dd = v; found = true }
         }
         if !found && strings.HasPrefix(p, "linux") {
-            if v, ok2 := b.cfg.Collector.DeviceDefaults["linux"]; ok2 { dd = v; found = true }
+            if v, ok2 := b.conf().Collector.DeviceDefaults["linux"]; ok2 { dd = v; found = true }
         }
     }
     cmds := make([]string, 0, len(dd.ConfigModeCLIs))
@@ -410,9 +617,13 @@ func (b *InteractBasic) EnterConfigMode(ctx context.Context, req *ExecRequest) (
     } else {
         if deadline, ok := ctx.Deadline(); ok { remain := time.Until(deadline); if remain > 0 && remain < time.Duration(effTaskTimeout)*time.Second { loginCtx = ctx } }
     }
-    conn := &ssh.ConnectionInfo{ Host: req.DeviceIP, Port: func() int { if req.Port < 1 || req.Port > 65535 { return 22 }; return req.Port }(), Username: req.UserName, Password: req.Password }
-    client, err := b.pool.GetConnection(loginCtx, conn)
-    if err != nil { if isLoginTimeout(err) { return nil, fmt.Errorf("设备登陆失败") }; return nil, fmt.Errorf("failed to create SSH connection: %w", err) }
+    conn := &ssh.ConnectionInfo{ Host: req.DeviceIP, Port: func() int { if req.Port < 1 || req.Port > 65535 { return 22 }; return req.Port }(), Username: req.UserName, Password: req.Password, Platform: req.DevicePlatform }
+    caps := CapabilitiesFor(b.conf(), req.DevicePlatform)
+    caps.applyConnection(conn)
+    _, connSpan := startSpan(ctx, "ssh.connect", attribute.String("net.peer.name", conn.Host), attribute.Int("net.peer.port", conn.Port))
+    client, err := getTracedConnection(loginCtx, b.pool, conn)
+    endSpan(connSpan, err)
+    if err != nil { return nil, classifyConnectError(err) }
     defer b.pool.ReleaseConnection(conn)
 
     // 平台交互参数（与 Execute 一致）
@@ -424,6 +635,7 @@ func (b *InteractBasic) EnterConfigMode(ctx context.Context, req *ExecRequest) (
     // 新增：设备平台用于区分不同平台的处理逻辑
     interactive.DevicePlatform = strings.TrimSpace(req.DevicePlatform)
     interactive.PromptSuffixes = promptSuffixes
+    interactive.PromptRegex = defaults.PromptRegex
     if dd.EnableRequired {
         interactive.EnableCLI = strings.TrimSpace(dd.EnableCLI)
         interactive.EnableExpectOutput = strings.TrimSpace(dd.EnableExceptOutput)
@@ -438,19 +650,34 @@ func (b *InteractBasic) EnterConfigMode(ctx context.Context, req *ExecRequest) (
     if defaults.PromptInducerIntervalMS > 0 { interactive.PromptInducerIntervalMS = defaults.PromptInducerIntervalMS }
     if defaults.PromptInducerMaxCount > 0 { interactive.PromptInducerMaxCount = defaults.PromptInducerMaxCount }
     if defaults.ExitPauseMS > 0 { interactive.ExitPauseMS = defaults.ExitPauseMS }
+    interactive.LoginSequence = b.getLoginSequence(req.DevicePlatform)
     // 退出命令序列（会话结束时使用）
     if strings.HasPrefix(p, "cisco") { interactive.ExitCommands = []string{"exit"} } else if strings.HasPrefix(p, "h3c") || strings.HasPrefix(p, "huawei") { interactive.ExitCommands = []string{"quit", "exit"} } else { interactive.ExitCommands = []string{"exit", "quit"} }
 
+    var finishTrace func(error)
+    interactive.Stream, finishTrace = traceCommandHooks(ctx, nil, []string{req.Password, req.EnablePassword})
+    caps.applyInteractive(interactive)
+
     // 交互执行进入配置模式命令，失败则回退到非交互执行
     res, err := client.ExecuteInteractiveCommands(execCtx, cmds, promptSuffixes, interactive)
+    finishTrace(err)
+    if errors.Is(err, ssh.ErrLoginSequence) {
+        _ = b.pool.CloseConnection(conn)
+        return nil, err
+    }
+    if err != nil && !caps.SupportsExec.Value {
+        return nil, fmt.Errorf("interactive failed (exec fallback disabled by platform capabilities): %w", err)
+    }
     if err != nil {
         _ = b.pool.CloseConnection(conn)
-        client2, errConn := b.pool.GetConnection(loginCtx, conn)
+        client2, errConn := getTracedConnection(loginCtx, b.pool, conn)
         if errConn != nil { return nil, fmt.Errorf("interactive failed: %v; fallback reconnect failed: %w", err, errConn) }
         defer b.pool.ReleaseConnection(conn)
-        res2, err2 := client2.ExecuteCommands(execCtx, cmds)
+        res2, err2 := client2.ExecuteCommandsWithOptions(execCtx, cmds, interactive)
         if err2 != nil { return nil, fmt.Errorf("interactive failed: %v; non-interactive failed: %w", err, err2) }
+        recordCommandTimings(ctx, res2, []string{req.Password, req.EnablePassword})
         return res2, nil
     }
+    recordCommandTimings(ctx, res, []string{req.Password, req.EnablePassword})
     return res, nil
 }
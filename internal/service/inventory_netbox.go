@@ -0,0 +1,440 @@
+package service
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sshcollectorpro/sshcollectorpro/internal/config"
+	"github.com/sshcollectorpro/sshcollectorpro/pkg/logger"
+)
+
+// InventorySourceNetBox 由 NetBox 同步写入的设备来源标识
+const InventorySourceNetBox = "netbox"
+
+// ErrInventorySyncRunning 已有同步在执行
+var ErrInventorySyncRunning = errors.New("inventory sync is already running")
+
+// 资产变更动作
+const (
+	InventoryActionCreate  = "create"
+	InventoryActionUpdate  = "update"
+	InventoryActionDisable = "disable"
+	InventoryActionSkip    = "skip"
+)
+
+// InventoryRecord 设备资产记录（同步关心的 device_info 字段）
+type InventoryRecord struct {
+	IP       string `json:"ip"`
+	Port     int    `json:"port"`
+	Name     string `json:"name"`
+	Platform string `json:"device_type"`
+	Vendor   string `json:"vendor"`
+	Model    string `json:"model"`
+	Source   string `json:"source"`
+	Enabled  bool   `json:"enabled"`
+}
+
+// InventoryStore 设备资产读写（由接口层基于 device_info 表实现）
+type InventoryStore interface {
+	ListInventory() ([]InventoryRecord, error)
+	// UpsertInventory 按 ip+port 新建或更新名称、平台、厂商、型号与来源，不修改凭据
+	UpsertInventory(rec InventoryRecord) error
+	DisableInventory(ip string, port int) error
+}
+
+// FieldChange 字段变更前后值
+type FieldChange struct {
+	Old string `json:"old"`
+	New string `json:"new"`
+}
+
+// InventoryChange 单台设备的同步结果
+type InventoryChange struct {
+	Action   string                 `json:"action"`
+	IP       string                 `json:"ip,omitempty"`
+	Port     int                    `json:"port,omitempty"`
+	Name     string                 `json:"name"`
+	NetBoxID int                    `json:"netbox_id,omitempty"`
+	Fields   map[string]FieldChange `json:"fields,omitempty"`
+	Reason   string                 `json:"reason,omitempty"`
+}
+
+// InventorySyncReport 一次同步的变更报告
+type InventorySyncReport struct {
+	Source     string            `json:"source"`
+	DryRun     bool              `json:"dry_run"`
+	StartedAt  time.Time         `json:"started_at"`
+	FinishedAt time.Time         `json:"finished_at"`
+	DurationMS int64             `json:"duration_ms"`
+	Fetched    int               `json:"fetched"`
+	Created    int               `json:"created"`
+	Updated    int               `json:"updated"`
+	Unchanged  int               `json:"unchanged"`
+	Disabled   int               `json:"disabled"`
+	Skipped    int               `json:"skipped"`
+	Changes    []InventoryChange `json:"changes"`
+	Error      string            `json:"error,omitempty"`
+}
+
+// InventorySyncService NetBox 资产同步：定时或按需拉取设备并写入本地资产表
+type InventorySyncService struct {
+	cfg    config.NetBoxConfig
+	store  InventoryStore
+	client *http.Client
+
+	running sync.Mutex
+	mu      sync.RWMutex
+	last    *InventorySyncReport
+	cancel  context.CancelFunc
+	done    chan struct{}
+}
+
+// NewInventorySyncService 创建 NetBox 资产同步服务
+func NewInventorySyncService(cfg *config.Config, store InventoryStore) *InventorySyncService {
+	nc := cfg.NetBox
+	if nc.Timeout <= 0 {
+		nc.Timeout = 30 * time.Second
+	}
+	if nc.PageSize <= 0 {
+		nc.PageSize = 200
+	}
+	if nc.DefaultPort <= 0 {
+		nc.DefaultPort = 22
+	}
+	client := &http.Client{Timeout: nc.Timeout}
+	if nc.InsecureSkipVerify {
+		client.Transport = &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}}
+	}
+	return &InventorySyncService{cfg: nc, store: store, client: client}
+}
+
+// Start 启动定时同步（启用且 interval > 0 时），启动后立即执行一次
+func (s *InventorySyncService) Start(ctx context.Context) error {
+	if !s.cfg.Enable || s.cfg.Interval <= 0 {
+		return nil
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	s.cancel = cancel
+	s.done = make(chan struct{})
+	go func() {
+		defer close(s.done)
+		ticker := time.NewTicker(s.cfg.Interval)
+		defer ticker.Stop()
+		for {
+			// 高可用部署中仅 leader 定时同步
+			if !IsLeader() {
+				logger.Debug("NetBox inventory sync skipped on follower")
+			} else if _, err := s.Sync(ctx, false); err != nil && !errors.Is(err, ErrInventorySyncRunning) {
+				logger.Warn("NetBox inventory sync failed", "error", err)
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	logger.Info("NetBox inventory sync started", "url", s.cfg.URL, "interval", s.cfg.Interval.String())
+	return nil
+}
+
+// Stop 停止定时同步
+func (s *InventorySyncService) Stop() error {
+	if s.cancel != nil {
+		s.cancel()
+		<-s.done
+		s.cancel = nil
+	}
+	return nil
+}
+
+// LastReport 最近一次同步报告（未同步过时为 nil）
+func (s *InventorySyncService) LastReport() *InventorySyncReport {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.last
+}
+
+// Sync 执行一次同步；dryRun 仅计算变更不写入
+func (s *InventorySyncService) Sync(ctx context.Context, dryRun bool) (*InventorySyncReport, error) {
+	if !s.cfg.Enable {
+		return nil, validationErrorf("netbox sync is disabled")
+	}
+	if strings.TrimSpace(s.cfg.URL) == "" {
+		return nil, validationErrorf("netbox.url is required")
+	}
+	if s.store == nil {
+		return nil, fmt.Errorf("inventory store not configured")
+	}
+	if !s.running.TryLock() {
+		return nil, ErrInventorySyncRunning
+	}
+	defer s.running.Unlock()
+
+	report := &InventorySyncReport{Source: InventorySourceNetBox, DryRun: dryRun, StartedAt: time.Now(), Changes: []InventoryChange{}}
+	err := s.sync(ctx, report)
+	report.FinishedAt = time.Now()
+	report.DurationMS = report.FinishedAt.Sub(report.StartedAt).Milliseconds()
+	if err != nil {
+		report.Error = err.Error()
+	}
+	s.mu.Lock()
+	s.last = report
+	s.mu.Unlock()
+	if err != nil {
+		return report, err
+	}
+	logger.Info("NetBox inventory sync finished", "dry_run", dryRun, "fetched", report.Fetched, "created", report.Created,
+		"updated", report.Updated, "disabled", report.Disabled, "skipped", report.Skipped, "duration_ms", report.DurationMS)
+	return report, nil
+}
+
+func (s *InventorySyncService) sync(ctx context.Context, report *InventorySyncReport) error {
+	devices, err := s.fetchDevices(ctx)
+	if err != nil {
+		return err
+	}
+	report.Fetched = len(devices)
+
+	existing, err := s.store.ListInventory()
+	if err != nil {
+		return fmt.Errorf("list inventory: %w", err)
+	}
+	index := make(map[string]InventoryRecord, len(existing))
+	for _, r := range existing {
+		index[inventoryKey(r.IP, r.Port)] = r
+	}
+
+	seen := map[string]bool{}
+	for _, d := range devices {
+		rec, reason := s.mapDevice(d)
+		if reason != "" {
+			report.Skipped++
+			report.Changes = append(report.Changes, InventoryChange{Action: InventoryActionSkip, Name: d.Name, NetBoxID: d.ID, Reason: reason})
+			continue
+		}
+		key := inventoryKey(rec.IP, rec.Port)
+		if seen[key] {
+			report.Skipped++
+			report.Changes = append(report.Changes, InventoryChange{Action: InventoryActionSkip, IP: rec.IP, Port: rec.Port, Name: rec.Name, NetBoxID: d.ID, Reason: "duplicate primary ip"})
+			continue
+		}
+		seen[key] = true
+
+		old, ok := index[key]
+		change := InventoryChange{IP: rec.IP, Port: rec.Port, Name: rec.Name, NetBoxID: d.ID}
+		if !ok {
+			change.Action = InventoryActionCreate
+			rec.Enabled = true
+		} else {
+			change.Fields = diffInventory(old, rec)
+			if len(change.Fields) == 0 {
+				report.Unchanged++
+				continue
+			}
+			change.Action = InventoryActionUpdate
+		}
+		if !report.DryRun {
+			if err := s.store.UpsertInventory(rec); err != nil {
+				return fmt.Errorf("upsert %s: %w", rec.IP, err)
+			}
+		}
+		if change.Action == InventoryActionCreate {
+			report.Created++
+		} else {
+			report.Updated++
+		}
+		report.Changes = append(report.Changes, change)
+	}
+
+	if s.cfg.DisableMissing {
+		for _, r := range existing {
+			if r.Source != InventorySourceNetBox || !r.Enabled || seen[inventoryKey(r.IP, r.Port)] {
+				continue
+			}
+			if !report.DryRun {
+				if err := s.store.DisableInventory(r.IP, r.Port); err != nil {
+					return fmt.Errorf("disable %s: %w", r.IP, err)
+				}
+			}
+			report.Disabled++
+			report.Changes = append(report.Changes, InventoryChange{Action: InventoryActionDisable, IP: r.IP, Port: r.Port, Name: r.Name, Reason: "not found in netbox"})
+		}
+	}
+	return nil
+}
+
+func inventoryKey(ip string, port int) string {
+	return net.JoinHostPort(ip, strconv.Itoa(port))
+}
+
+// diffInventory 同步字段的差异；平台/厂商/型号在 NetBox 为空时不覆盖本地值
+func diffInventory(old, rec InventoryRecord) map[string]FieldChange {
+	fields := map[string]FieldChange{}
+	cmp := func(name, o, n string, keepEmpty bool) {
+		if n == "" && !keepEmpty {
+			return
+		}
+		if o != n {
+			fields[name] = FieldChange{Old: o, New: n}
+		}
+	}
+	cmp("name", old.Name, rec.Name, true)
+	cmp("device_type", old.Platform, rec.Platform, false)
+	cmp("vendor", old.Vendor, rec.Vendor, false)
+	cmp("model", old.Model, rec.Model, false)
+	cmp("source", old.Source, rec.Source, true)
+	return fields
+}
+
+// netboxRef NetBox 嵌套对象（平台、角色、厂商等）
+type netboxRef struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+	Slug string `json:"slug"`
+}
+
+// netboxDevice /api/dcim/devices/ 返回的设备（仅同步所需字段）
+type netboxDevice struct {
+	ID         int        `json:"id"`
+	Name       string     `json:"name"`
+	Role       *netboxRef `json:"role"`
+	DeviceRole *netboxRef `json:"device_role"` // NetBox 3.6 之前的字段名
+	Platform   *netboxRef `json:"platform"`
+	DeviceType *struct {
+		Model        string     `json:"model"`
+		Manufacturer *netboxRef `json:"manufacturer"`
+	} `json:"device_type"`
+	PrimaryIP *struct {
+		Address string `json:"address"`
+	} `json:"primary_ip"`
+}
+
+type netboxPage struct {
+	Count   int            `json:"count"`
+	Next    *string        `json:"next"`
+	Results []netboxDevice `json:"results"`
+}
+
+// fetchDevices 分页拉取设备列表，按 next 链接翻页
+func (s *InventorySyncService) fetchDevices(ctx context.Context) ([]netboxDevice, error) {
+	q := url.Values{}
+	keys := make([]string, 0, len(s.cfg.Filters))
+	for k := range s.cfg.Filters {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		q.Set(k, s.cfg.Filters[k])
+	}
+	q.Set("limit", strconv.Itoa(s.cfg.PageSize))
+	next := strings.TrimRight(s.cfg.URL, "/") + "/api/dcim/devices/?" + q.Encode()
+
+	var out []netboxDevice
+	for next != "" {
+		page, err := s.getPage(ctx, next)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, page.Results...)
+		next = ""
+		if page.Next != nil {
+			next = *page.Next
+		}
+	}
+	return out, nil
+}
+
+func (s *InventorySyncService) getPage(ctx context.Context, u string) (*netboxPage, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if s.cfg.Token != "" {
+		req.Header.Set("Authorization", "Token "+s.cfg.Token)
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("netbox request: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("netbox returned %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	var page netboxPage
+	if err := json.NewDecoder(resp.Body).Decode(&page); err != nil {
+		return nil, fmt.Errorf("decode netbox response: %w", err)
+	}
+	return &page, nil
+}
+
+// mapDevice NetBox 设备转本地资产记录；无法映射时返回跳过原因
+func (s *InventorySyncService) mapDevice(d netboxDevice) (InventoryRecord, string) {
+	if d.PrimaryIP == nil || strings.TrimSpace(d.PrimaryIP.Address) == "" {
+		return InventoryRecord{}, "no primary ip"
+	}
+	ip := strings.TrimSpace(d.PrimaryIP.Address)
+	if i := strings.IndexByte(ip, '/'); i >= 0 {
+		ip = ip[:i]
+	}
+	if net.ParseIP(ip) == nil {
+		return InventoryRecord{}, "invalid primary ip " + d.PrimaryIP.Address
+	}
+	role := d.Role
+	if role == nil {
+		role = d.DeviceRole
+	}
+	platform := ""
+	if d.Platform != nil {
+		platform = lookupSlug(s.cfg.PlatformMap, d.Platform.Slug)
+	}
+	if platform == "" && role != nil {
+		platform = lookupSlug(s.cfg.RoleMap, role.Slug)
+	}
+	if platform == "" {
+		platform = s.cfg.DefaultPlatform
+	}
+	if platform == "" {
+		slug := ""
+		if d.Platform != nil {
+			slug = d.Platform.Slug
+		}
+		return InventoryRecord{}, fmt.Sprintf("unmapped platform %q", slug)
+	}
+	rec := InventoryRecord{
+		IP:       ip,
+		Port:     s.cfg.DefaultPort,
+		Name:     d.Name,
+		Platform: platform,
+		Source:   InventorySourceNetBox,
+	}
+	if d.DeviceType != nil {
+		rec.Model = d.DeviceType.Model
+		if d.DeviceType.Manufacturer != nil {
+			rec.Vendor = d.DeviceType.Manufacturer.Name
+		}
+	}
+	return rec, ""
+}
+
+// lookupSlug 映射表查找（viper 会将键转为小写）
+func lookupSlug(m map[string]string, slug string) string {
+	if slug == "" {
+		return ""
+	}
+	return strings.TrimSpace(m[strings.ToLower(slug)])
+}
@@ -0,0 +1,321 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sshcollectorpro/sshcollectorpro/internal/config"
+	"github.com/sshcollectorpro/sshcollectorpro/pkg/logger"
+)
+
+// MonitorValue 单个监控值（Zabbix trapper 项）
+type MonitorValue struct {
+	Host  string `json:"host"`
+	Key   string `json:"key"`
+	Value string `json:"value"`
+	Clock int64  `json:"clock"`
+}
+
+// monitorSink 监控值发送端
+type monitorSink interface {
+	Send(ctx context.Context, values []MonitorValue) error
+}
+
+// monitorExporter 异步批量导出：设备事件转为监控值入队，满批或到达刷新间隔时发送
+type monitorExporter struct {
+	cfg     config.MonitorConfig
+	sink    monitorSink
+	queue   chan MonitorValue
+	done    chan struct{}
+	closeMu sync.Once
+}
+
+var (
+	monitorMu sync.RWMutex
+	exporter  *monitorExporter
+)
+
+// InitMonitor 按配置初始化健康监控导出；未启用时不做任何事。重复调用会先关闭旧的导出器
+func InitMonitor(cfg *config.Config) error {
+	CloseMonitor()
+	if cfg == nil || !cfg.Monitor.Enable {
+		return nil
+	}
+	mc := cfg.Monitor
+	if mc.BatchSize <= 0 {
+		mc.BatchSize = 100
+	}
+	if mc.FlushInterval <= 0 {
+		mc.FlushInterval = 5 * time.Second
+	}
+	if mc.Timeout <= 0 {
+		mc.Timeout = 5 * time.Second
+	}
+	if mc.QueueSize <= 0 {
+		mc.QueueSize = 5000
+	}
+	if strings.TrimSpace(mc.HostTemplate) == "" {
+		mc.HostTemplate = "{device_name}"
+	}
+	if strings.TrimSpace(mc.KeyTemplate) == "" {
+		mc.KeyTemplate = "sshcollector.{type}.{metric}"
+	}
+	var sink monitorSink
+	switch strings.ToLower(strings.TrimSpace(mc.Backend)) {
+	case "zabbix", "":
+		if strings.TrimSpace(mc.ZabbixServer) == "" {
+			return fmt.Errorf("monitor.zabbix_server is required")
+		}
+		addr := mc.ZabbixServer
+		if _, _, err := net.SplitHostPort(addr); err != nil {
+			addr = net.JoinHostPort(addr, "10051")
+		}
+		sink = &zabbixSender{addr: addr, timeout: mc.Timeout}
+	case "http":
+		if strings.TrimSpace(mc.URL) == "" {
+			return fmt.Errorf("monitor.url is required")
+		}
+		sink = &httpMonitorSink{url: mc.URL, headers: mc.Headers, client: &http.Client{Timeout: mc.Timeout}}
+	default:
+		return fmt.Errorf("unsupported monitor.backend: %s", mc.Backend)
+	}
+	e := &monitorExporter{
+		cfg:   mc,
+		sink:  sink,
+		queue: make(chan MonitorValue, mc.QueueSize),
+		done:  make(chan struct{}),
+	}
+	go e.loop()
+	monitorMu.Lock()
+	exporter = e
+	monitorMu.Unlock()
+	logger.Info("Monitor exporter started", "backend", mc.Backend, "batch_size", mc.BatchSize)
+	return nil
+}
+
+// CloseMonitor 停止导出：发送队列中剩余的监控值后退出
+func CloseMonitor() {
+	monitorMu.Lock()
+	e := exporter
+	exporter = nil
+	monitorMu.Unlock()
+	if e != nil {
+		e.close()
+	}
+}
+
+// exportMonitorEvent 设备事件转为监控值入队；未启用或队列已满时直接返回
+func exportMonitorEvent(ev DeviceEvent) {
+	monitorMu.RLock()
+	defer monitorMu.RUnlock()
+	e := exporter
+	if e == nil {
+		return
+	}
+	for _, v := range e.values(ev) {
+		select {
+		case e.queue <- v:
+		default:
+			logger.Warn("Monitor queue full, value dropped", "host", v.Host, "key", v.Key)
+			return
+		}
+	}
+}
+
+// monitorPlaceholder 模板占位符 {name}
+var monitorPlaceholder = regexp.MustCompile(`\{([a-z_]+)\}`)
+
+// renderMonitorTemplate 替换模板中的占位符，未知占位符原样保留
+func renderMonitorTemplate(tpl string, vars map[string]string) string {
+	return monitorPlaceholder.ReplaceAllStringFunc(tpl, func(m string) string {
+		if v, ok := vars[m[1:len(m)-1]]; ok {
+			return v
+		}
+		return m
+	})
+}
+
+// values 单设备事件展开为 status（1 成功/0 失败）、duration_ms、error 三个监控值
+func (e *monitorExporter) values(ev DeviceEvent) []MonitorValue {
+	vars := map[string]string{
+		"device_ip":       ev.DeviceIP,
+		"device_name":     ev.DeviceName,
+		"device_platform": ev.DevicePlatform,
+		"type":            ev.Type,
+		"task_id":         ev.TaskID,
+		"collector_id":    ev.CollectorID,
+	}
+	host := strings.TrimSpace(renderMonitorTemplate(e.cfg.HostTemplate, vars))
+	if host == "" {
+		host = ev.DeviceIP
+	}
+	status := "0"
+	if ev.Status == EventStatusSuccess {
+		status = "1"
+	}
+	clock := ev.Timestamp.Unix()
+	metrics := []struct{ name, value string }{
+		{"status", status},
+		{"duration_ms", strconv.FormatInt(ev.DurationMS, 10)},
+		// 成功时发送空串，便于基于 error 项的触发器恢复
+		{"error", ev.Error},
+	}
+	out := make([]MonitorValue, 0, len(metrics))
+	for _, m := range metrics {
+		vars["metric"] = m.name
+		out = append(out, MonitorValue{Host: host, Key: renderMonitorTemplate(e.cfg.KeyTemplate, vars), Value: m.value, Clock: clock})
+	}
+	return out
+}
+
+// loop 攒批发送：满 batch_size 立即发送，否则每 flush_interval 发送一次
+func (e *monitorExporter) loop() {
+	defer close(e.done)
+	ticker := time.NewTicker(e.cfg.FlushInterval)
+	defer ticker.Stop()
+	batch := make([]MonitorValue, 0, e.cfg.BatchSize)
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), e.cfg.Timeout)
+		err := e.sink.Send(ctx, batch)
+		cancel()
+		if err != nil {
+			logger.Warn("Export monitor values failed", "count", len(batch), "error", err)
+		}
+		batch = batch[:0]
+	}
+	for {
+		select {
+		case v, ok := <-e.queue:
+			if !ok {
+				flush()
+				return
+			}
+			batch = append(batch, v)
+			if len(batch) >= e.cfg.BatchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		}
+	}
+}
+
+func (e *monitorExporter) close() {
+	e.closeMu.Do(func() {
+		close(e.queue)
+		<-e.done
+	})
+}
+
+// zabbixSender Zabbix sender 协议（ZBXD 头 + JSON），与 zabbix_sender 行为一致
+type zabbixSender struct {
+	addr    string
+	timeout time.Duration
+}
+
+// zabbixProcessed 解析 info 中的 "processed: N; failed: M"
+var zabbixProcessed = regexp.MustCompile(`processed:\s*(\d+);\s*failed:\s*(\d+)`)
+
+func (s *zabbixSender) Send(ctx context.Context, values []MonitorValue) error {
+	body, err := json.Marshal(map[string]interface{}{
+		"request": "sender data",
+		"data":    values,
+		"clock":   time.Now().Unix(),
+	})
+	if err != nil {
+		return err
+	}
+	d := net.Dialer{Timeout: s.timeout}
+	conn, err := d.DialContext(ctx, "tcp", s.addr)
+	if err != nil {
+		return fmt.Errorf("zabbix connect: %w", err)
+	}
+	defer conn.Close()
+	if dl, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(dl)
+	}
+	var buf bytes.Buffer
+	buf.WriteString("ZBXD\x01")
+	_ = binary.Write(&buf, binary.LittleEndian, uint64(len(body)))
+	buf.Write(body)
+	if _, err := conn.Write(buf.Bytes()); err != nil {
+		return fmt.Errorf("zabbix write: %w", err)
+	}
+
+	header := make([]byte, 13)
+	if _, err := io.ReadFull(conn, header); err != nil {
+		return fmt.Errorf("zabbix read: %w", err)
+	}
+	if string(header[:4]) != "ZBXD" {
+		return fmt.Errorf("zabbix: invalid response header")
+	}
+	n := binary.LittleEndian.Uint64(header[5:])
+	if n > 1<<20 {
+		return fmt.Errorf("zabbix: response too large")
+	}
+	data := make([]byte, n)
+	if _, err := io.ReadFull(conn, data); err != nil {
+		return fmt.Errorf("zabbix read: %w", err)
+	}
+	var resp struct {
+		Response string `json:"response"`
+		Info     string `json:"info"`
+	}
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return fmt.Errorf("zabbix: decode response: %w", err)
+	}
+	if resp.Response != "success" {
+		return fmt.Errorf("zabbix: %s %s", resp.Response, resp.Info)
+	}
+	// 主机或监控项未配置为 trapper 时 Zabbix 仍返回 success，仅在 info 中计入 failed
+	if m := zabbixProcessed.FindStringSubmatch(resp.Info); m != nil && m[2] != "0" {
+		logger.Warn("Zabbix rejected monitor values", "info", resp.Info)
+	}
+	return nil
+}
+
+// httpMonitorSink 通用 HTTP 推送：POST {"data":[{host,key,value,clock}]}
+type httpMonitorSink struct {
+	url     string
+	headers map[string]string
+	client  *http.Client
+}
+
+func (s *httpMonitorSink) Send(ctx context.Context, values []MonitorValue) error {
+	body, err := json.Marshal(map[string]interface{}{"data": values})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range s.headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("monitor push returned %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,149 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/sshcollectorpro/sshcollectorpro/internal/config"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/netconf"
+	"github.com/sshcollectorpro/sshcollectorpro/pkg/ssh"
+)
+
+// defaultNetconfPort NETCONF over SSH 标准端口
+const defaultNetconfPort = 830
+
+// platformCapabilities 平台能力标记（未配置的平台为零值）
+func platformCapabilities(cfg *config.Config, platform string) config.PlatformCapabilitiesConfig {
+	if cfg == nil {
+		return config.PlatformCapabilitiesConfig{}
+	}
+	return cfg.Collector.DeviceDefaults[strings.ToLower(strings.TrimSpace(platform))].Capabilities
+}
+
+// netconfPort 请求端口优先，其次平台 capabilities.netconf_port，默认 830
+func netconfPort(cfg *config.Config, platform string, port int) int {
+	if port > 0 && port <= 65535 {
+		return port
+	}
+	if p := platformCapabilities(cfg, platform).NetconfPort; p > 0 && p <= 65535 {
+		return p
+	}
+	return defaultNetconfPort
+}
+
+// validateNetconfRequest 平台须声明 netconf 能力；命令与 xpaths 须可解析
+func validateNetconfRequest(cfg *config.Config, platform string, request *CollectRequest) error {
+	if !platformCapabilities(cfg, platform).Netconf {
+		return validationErrorf("platform %s does not support netconf (set device_defaults.%s.capabilities.netconf)", platform, platform)
+	}
+	for _, c := range request.CliList {
+		if _, err := netconf.BuildRPC(c.CLI); err != nil {
+			return validationErrorf("invalid netconf command %q: %v", c.CLI, err)
+		}
+	}
+	if _, err := compileXPaths(request.XPaths); err != nil {
+		return validationErrorf("%v", err)
+	}
+	return nil
+}
+
+func compileXPaths(paths map[string]string) (map[string]*netconf.XPath, error) {
+	out := make(map[string]*netconf.XPath, len(paths))
+	for name, expr := range paths {
+		if strings.TrimSpace(name) == "" {
+			return nil, fmt.Errorf("xpaths: empty field name")
+		}
+		x, err := netconf.CompileXPath(expr)
+		if err != nil {
+			return nil, fmt.Errorf("xpaths.%s: %w", name, err)
+		}
+		out[name] = x
+	}
+	return out, nil
+}
+
+// executeNetconfCollection 通过 netconf 子系统逐条执行 RPC；rpc-error 记录为命令错误，传输错误中止任务
+func (s *CollectorService) executeNetconfCollection(ctx context.Context, request *CollectRequest, commands []string, port, retries int) ([]*CommandResultView, error) {
+	s.logTaskInfo(request.TaskID, fmt.Sprintf("Starting NETCONF collection for %s:%d", request.DeviceIP, port))
+	// 与 SSH 采集一致的只读策略；RPC 仅允许只读操作，执行前再次校验
+	if err := CheckCommandsAllowed(s.conf(), request.DevicePlatform, commands); err != nil {
+		return nil, err
+	}
+	for _, cmd := range commands {
+		if _, err := netconf.BuildRPC(cmd); err != nil {
+			return nil, validationErrorf("invalid netconf command %q: %v", strings.TrimSpace(cmd), err)
+		}
+	}
+	xpaths, err := compileXPaths(request.XPaths)
+	if err != nil {
+		return nil, err
+	}
+	sshCfg := &ssh.Config{
+		Timeout:        s.conf().SSH.Timeout,
+		ConnectTimeout: s.conf().SSH.ConnectTimeout,
+		KeepAlive:      s.conf().SSH.KeepAliveInterval,
+		Proxy:          sshProxyConfig(s.conf().SSH.Proxy),
+		Egress:         sshEgressPolicy(s.conf().SSH.Egress),
+		HostKey:        sshHostKeyPolicy(s.conf().SSH.HostKey),
+	}
+	info := &ssh.ConnectionInfo{Host: request.DeviceIP, Port: port, Username: request.UserName, Password: request.Password, Platform: request.DevicePlatform}
+
+	var sess *netconf.Session
+	policy := resolveRetryPolicy(s.conf(), request.DevicePlatform, request.RetryPolicy)
+	request.attempts, err = policy.do(ctx, retries, func(attempt, total int, err error) {
+		s.logTaskWarn(request.TaskID, fmt.Sprintf("NETCONF attempt %d/%d failed: %v", attempt, total, err))
+	}, func() error {
+		if err := checkAuthThrottle(info.Username, info.Password); err != nil {
+			return err
+		}
+		if err := checkCircuitBreaker(info.Host); err != nil {
+			return err
+		}
+		var err error
+		sess, err = netconf.Dial(ctx, sshCfg, info)
+		recordAuthResult(info.Username, info.Password, info.Host, err)
+		recordCircuitResult(info.Host, err)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("netconf session to %s:%d: %w", request.DeviceIP, port, err)
+	}
+	defer sess.Close()
+	s.logTaskInfo(request.TaskID, fmt.Sprintf("NETCONF session %s established (%d capabilities)", sess.SessionID, len(sess.ServerCapabilities)))
+
+	out := make([]*CommandResultView, 0, len(commands))
+	for _, cmd := range commands {
+		body, err := netconf.BuildRPC(cmd)
+		if err != nil {
+			return nil, err
+		}
+		start := time.Now()
+		reply, err := sess.Call(ctx, body)
+		view := &CommandResultView{
+			Command:      strings.TrimSpace(cmd),
+			RawOutput:    reply,
+			FormatOutput: []map[string]interface{}{},
+			DurationMS:   time.Since(start).Milliseconds(),
+		}
+		var rpcErr *netconf.RPCError
+		if err != nil && !errors.As(err, &rpcErr) {
+			return nil, fmt.Errorf("netconf rpc %q: %w", view.Command, err)
+		}
+		if rpcErr != nil {
+			view.Error = rpcErr.Error()
+			view.ExitCode = 1
+		} else if len(xpaths) > 0 {
+			if doc, perr := netconf.ParseDocument(reply); perr == nil {
+				view.Fields = netconf.Extract(doc, xpaths)
+			} else {
+				view.Error = perr.Error()
+			}
+		}
+		out = append(out, view)
+	}
+	s.logTaskInfo(request.TaskID, fmt.Sprintf("NETCONF collection completed, executed %d rpcs", len(out)))
+	return out, nil
+}
@@ -0,0 +1,242 @@
+package service
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/sshcollectorpro/sshcollectorpro/internal/config"
+	"github.com/sshcollectorpro/sshcollectorpro/pkg/logger"
+)
+
+// OutputNormalizer 输出规整步骤：输入原始输出，返回规整后的输出
+type OutputNormalizer func(string) string
+
+// NormalizerBuilder 按步骤配置构造规整函数
+type NormalizerBuilder func(config.NormalizerConfig) (OutputNormalizer, error)
+
+var (
+	normalizerMu       sync.RWMutex
+	normalizerBuilders = map[string]NormalizerBuilder{
+		config.NormalizerStripANSI:       func(config.NormalizerConfig) (OutputNormalizer, error) { return stripANSI, nil },
+		config.NormalizerDropBanner:      buildDropBanner,
+		config.NormalizerCollapseBlank:   func(config.NormalizerConfig) (OutputNormalizer, error) { return collapseBlankLines, nil },
+		config.NormalizerStripTimestamps: buildStripTimestamps,
+		config.NormalizerRegexReplace:    buildRegexReplace,
+	}
+)
+
+// RegisterNormalizer 注册自定义规整类型（同名覆盖内置实现），平台 normalizers 中以 type 引用
+func RegisterNormalizer(name string, b NormalizerBuilder) {
+	normalizerMu.Lock()
+	defer normalizerMu.Unlock()
+	normalizerBuilders[strings.ToLower(strings.TrimSpace(name))] = b
+	normalizerPipelines.reset()
+}
+
+var (
+	ansiRe = regexp.MustCompile(`\x1b\[[0-9;?]*[ -/]*[@-~]|\x1b\][^\x07\x1b]*(\x07|\x1b\\)|\x1b[()][A-Za-z0-9]|\x1b[=>78DEHM]`)
+	// 默认时间戳：ISO 8601、Cisco 风格（10:21:33 UTC Mon Mar 1 2021）与 syslog 风格（Mar  1 10:21:33）
+	defaultTimestampRe = regexp.MustCompile(`\d{4}[-/]\d{2}[-/]\d{2}[ T]\d{2}:\d{2}:\d{2}(\.\d+)?(Z|[+-]\d{2}:?\d{2})?` +
+		`|\d{2}:\d{2}:\d{2}(\.\d+)? [A-Z]{2,5} (Mon|Tue|Wed|Thu|Fri|Sat|Sun) (Jan|Feb|Mar|Apr|May|Jun|Jul|Aug|Sep|Oct|Nov|Dec) +\d{1,2} \d{4}` +
+		`|(Jan|Feb|Mar|Apr|May|Jun|Jul|Aug|Sep|Oct|Nov|Dec) +\d{1,2} \d{2}:\d{2}:\d{2}(\.\d+)?`)
+)
+
+// stripANSI 去除 ANSI 控制序列，并按退格删除前一字符
+func stripANSI(s string) string {
+	s = ansiRe.ReplaceAllString(s, "")
+	if !strings.Contains(s, "\b") {
+		return s
+	}
+	out := make([]rune, 0, len(s))
+	for _, r := range s {
+		if r == '\b' {
+			if len(out) > 0 {
+				out = out[:len(out)-1]
+			}
+			continue
+		}
+		out = append(out, r)
+	}
+	return string(out)
+}
+
+// collapseBlankLines 连续空行合并为一行，去除首尾空行
+func collapseBlankLines(s string) string {
+	lines := strings.Split(s, "\n")
+	out := make([]string, 0, len(lines))
+	blank := false
+	for _, ln := range lines {
+		if strings.TrimSpace(ln) == "" {
+			if !blank && len(out) > 0 {
+				out = append(out, "")
+			}
+			blank = true
+			continue
+		}
+		blank = false
+		out = append(out, ln)
+	}
+	for len(out) > 0 && out[len(out)-1] == "" {
+		out = out[:len(out)-1]
+	}
+	return strings.Join(out, "\n")
+}
+
+func buildDropBanner(n config.NormalizerConfig) (OutputNormalizer, error) {
+	if strings.TrimSpace(n.Pattern) == "" {
+		return nil, fmt.Errorf("pattern is required")
+	}
+	start, err := regexp.Compile(n.Pattern)
+	if err != nil {
+		return nil, err
+	}
+	var end *regexp.Regexp
+	if strings.TrimSpace(n.End) != "" {
+		if end, err = regexp.Compile(n.End); err != nil {
+			return nil, err
+		}
+	}
+	return func(s string) string {
+		lines := strings.Split(s, "\n")
+		out := make([]string, 0, len(lines))
+		inBanner := false
+		for _, ln := range lines {
+			cmp := strings.TrimRight(ln, "\r")
+			if inBanner {
+				if end.MatchString(cmp) {
+					inBanner = false
+				}
+				continue
+			}
+			if start.MatchString(cmp) {
+				// 结束行从起始行之后开始匹配
+				inBanner = end != nil
+				continue
+			}
+			out = append(out, ln)
+		}
+		return strings.Join(out, "\n")
+	}, nil
+}
+
+func buildStripTimestamps(n config.NormalizerConfig) (OutputNormalizer, error) {
+	re := defaultTimestampRe
+	if strings.TrimSpace(n.Pattern) != "" {
+		var err error
+		if re, err = regexp.Compile(n.Pattern); err != nil {
+			return nil, err
+		}
+	}
+	return func(s string) string { return re.ReplaceAllString(s, n.Replace) }, nil
+}
+
+func buildRegexReplace(n config.NormalizerConfig) (OutputNormalizer, error) {
+	if strings.TrimSpace(n.Pattern) == "" {
+		return nil, fmt.Errorf("pattern is required")
+	}
+	re, err := regexp.Compile(n.Pattern)
+	if err != nil {
+		return nil, err
+	}
+	return func(s string) string { return re.ReplaceAllString(s, n.Replace) }, nil
+}
+
+// ValidateNormalizers 校验规整步骤：类型须已注册、正则可编译（运行时更新平台配置时使用）
+func ValidateNormalizers(list []config.NormalizerConfig) error {
+	normalizerMu.RLock()
+	defer normalizerMu.RUnlock()
+	for i, n := range list {
+		b, ok := normalizerBuilders[strings.ToLower(strings.TrimSpace(n.Type))]
+		if !ok {
+			return fmt.Errorf("normalizers[%d]: unknown type %q", i, n.Type)
+		}
+		if _, err := b(n); err != nil {
+			return fmt.Errorf("normalizers[%d]: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// buildNormalizers 按顺序构造规整流水线；无效步骤记录日志后跳过
+func buildNormalizers(platform string, list []config.NormalizerConfig) []OutputNormalizer {
+	normalizerMu.RLock()
+	defer normalizerMu.RUnlock()
+	out := make([]OutputNormalizer, 0, len(list))
+	for i, n := range list {
+		b, ok := normalizerBuilders[strings.ToLower(strings.TrimSpace(n.Type))]
+		if !ok {
+			logger.Warn("Unknown output normalizer ignored", "platform", platform, "index", i, "type", n.Type)
+			continue
+		}
+		f, err := b(n)
+		if err != nil {
+			logger.Warn("Invalid output normalizer ignored", "platform", platform, "index", i, "type", n.Type, "error", err)
+			continue
+		}
+		out = append(out, f)
+	}
+	return out
+}
+
+// normalizerCache 按配置快照缓存各平台已构造的流水线，快照替换后重建
+type normalizerCache struct {
+	mu        sync.Mutex
+	cfg       *config.Config
+	pipelines map[string][]OutputNormalizer
+}
+
+var normalizerPipelines = &normalizerCache{}
+
+func (c *normalizerCache) reset() {
+	c.mu.Lock()
+	c.cfg, c.pipelines = nil, nil
+	c.mu.Unlock()
+}
+
+func (c *normalizerCache) get(cfg *config.Config, platform string) []OutputNormalizer {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.cfg != cfg || c.pipelines == nil {
+		c.cfg, c.pipelines = cfg, map[string][]OutputNormalizer{}
+	}
+	if p, ok := c.pipelines[platform]; ok {
+		return p
+	}
+	p := buildNormalizers(platform, normalizersForPlatform(cfg, platform))
+	c.pipelines[platform] = p
+	return p
+}
+
+// normalizersForPlatform 平台规整步骤：精确匹配，其次平台族（与 output_filter 相同的回退规则），最后 default 平台
+func normalizersForPlatform(cfg *config.Config, platform string) []config.NormalizerConfig {
+	p := strings.ToLower(strings.TrimSpace(platform))
+	if p == "" {
+		p = "default"
+	}
+	if dd, ok := cfg.Collector.DeviceDefaults[p]; ok && len(dd.Normalizers) > 0 {
+		return dd.Normalizers
+	}
+	for _, family := range []string{"huawei", "h3c", "cisco_ios", "linux"} {
+		prefix := strings.SplitN(family, "_", 2)[0]
+		if strings.HasPrefix(p, prefix) {
+			if dd, ok := cfg.Collector.DeviceDefaults[family]; ok && len(dd.Normalizers) > 0 {
+				return dd.Normalizers
+			}
+			break
+		}
+	}
+	return cfg.Collector.DeviceDefaults["default"].Normalizers
+}
+
+// NormalizeOutput 按平台规整流水线处理原始输出
+func NormalizeOutput(cfg *config.Config, platform, s string) string {
+	if cfg == nil || s == "" {
+		return s
+	}
+	for _, f := range normalizerPipelines.get(cfg, strings.ToLower(strings.TrimSpace(platform))) {
+		s = f(s)
+	}
+	return s
+}
@@ -0,0 +1,38 @@
+package service
+
+import "net/http"
+
+// 批量接口统一返回码
+const (
+	BatchCodeSuccess        = "SUCCESS"
+	BatchCodePartialSuccess = "PARTIAL_SUCCESS"
+	BatchCodeFailed         = "FAILED"
+)
+
+// BatchOutcome 批量接口的整体结果（返回码与消息）
+type BatchOutcome struct {
+	Code    string
+	Message string
+}
+
+// NewBatchOutcome 按设备成功数确定整体结果，label 为消息前缀（如“自定义批量任务”）
+// 全部成功：SUCCESS；部分成功：PARTIAL_SUCCESS；全部失败：FAILED；无设备视为成功
+func NewBatchOutcome(label string, total, succeeded int) BatchOutcome {
+	switch {
+	case succeeded >= total:
+		return BatchOutcome{Code: BatchCodeSuccess, Message: label + "执行完成"}
+	case succeeded <= 0:
+		return BatchOutcome{Code: BatchCodeFailed, Message: label + "全部失败"}
+	default:
+		return BatchOutcome{Code: BatchCodePartialSuccess, Message: label + "部分成功"}
+	}
+}
+
+// BatchHTTPStatus 返回码到 HTTP 状态的映射：SUCCESS 200，PARTIAL_SUCCESS 207，FAILED 200
+// 设备全部失败不代表本服务或上游网关故障，仍返回 200，由响应体 code=FAILED 与逐设备结果表达
+func BatchHTTPStatus(code string) int {
+	if code == BatchCodePartialSuccess {
+		return http.StatusMultiStatus
+	}
+	return http.StatusOK
+}
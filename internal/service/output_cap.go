@@ -0,0 +1,108 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/sshcollectorpro/sshcollectorpro/internal/config"
+)
+
+// OutputCap 响应中单条命令输出的行数上限：超出时保留前 MaxLines-TailLines 行与末尾 TailLines 行
+type OutputCap struct {
+	MaxLines  int
+	TailLines int
+}
+
+// Enabled 是否设置了上限
+func (c OutputCap) Enabled() bool { return c.MaxLines > 0 }
+
+// Apply 截断输出，中间插入省略标记；返回截断后的输出与省略行数（未截断时为 0）
+// TailLines 未设置时首尾各保留一半
+func (c OutputCap) Apply(output string) (string, int) {
+	if !c.Enabled() || output == "" {
+		return output, 0
+	}
+	lines := strings.Split(output, "\n")
+	if len(lines) <= c.MaxLines {
+		return output, 0
+	}
+	tail := c.TailLines
+	if tail <= 0 {
+		tail = c.MaxLines / 2
+	}
+	if tail > c.MaxLines {
+		tail = c.MaxLines
+	}
+	head := c.MaxLines - tail
+	omitted := len(lines) - c.MaxLines
+	out := make([]string, 0, c.MaxLines+1)
+	out = append(out, lines[:head]...)
+	out = append(out, fmt.Sprintf("... [%d lines omitted] ...", omitted))
+	out = append(out, lines[len(lines)-tail:]...)
+	return strings.Join(out, "\n"), omitted
+}
+
+// resolveOutputCap 单条命令 max_lines 优先，其次平台 device_defaults.<platform>.max_lines
+func resolveOutputCap(cfg *config.Config, platform string, item *CLIItem) OutputCap {
+	if item != nil && item.MaxLines > 0 {
+		return OutputCap{MaxLines: item.MaxLines, TailLines: item.TailLines}
+	}
+	if cfg == nil {
+		return OutputCap{}
+	}
+	dd, ok := cfg.Collector.DeviceDefaults[strings.ToLower(strings.TrimSpace(platform))]
+	if !ok {
+		return OutputCap{}
+	}
+	return OutputCap{MaxLines: dd.MaxLines, TailLines: dd.MaxLinesTail}
+}
+
+// itemFor 按命令文本（小写去空白）查找命令项
+func (l CLIList) itemFor(cmd string) *CLIItem {
+	key := strings.ToLower(strings.TrimSpace(cmd))
+	for i := range l {
+		if strings.ToLower(strings.TrimSpace(l[i].CLI)) == key {
+			return &l[i]
+		}
+	}
+	return nil
+}
+
+// capOutput 截断输出并记录 TRUNCATED 提示
+func capOutput(ctx context.Context, oc OutputCap, deviceIP, cmd, output string) (string, int) {
+	capped, omitted := oc.Apply(output)
+	if omitted > 0 {
+		AddWarning(ctx, Warning{
+			Code:    WarnTruncated,
+			Message: fmt.Sprintf("output of %q on %s truncated: %d lines omitted", cmd, deviceIP, omitted),
+			Context: map[string]interface{}{"device_ip": deviceIP, "command": cmd, "omitted_lines": omitted, "max_lines": oc.MaxLines},
+		})
+	}
+	return capped, omitted
+}
+
+// capCollectResults 对采集结果应用行数上限（须在落盘之后调用，落盘对象保存完整输出）
+func (s *CollectorService) capCollectResults(ctx context.Context, request *CollectRequest, results []*CommandResultView) {
+	for _, v := range results {
+		if v == nil {
+			continue
+		}
+		oc := resolveOutputCap(s.conf(), request.DevicePlatform, request.CliList.itemFor(v.Command))
+		v.RawOutput, v.OmittedLines = capOutput(ctx, oc, request.DeviceIP, v.Command, v.RawOutput)
+	}
+}
+
+// capBackupResults 对备份结果应用行数上限；聚合文件不在命令列表中，使用平台上限
+func (s *BackupService) capBackupResults(ctx context.Context, dev *BackupDevice, results []CommandBackupResult) {
+	for i := range results {
+		r := &results[i]
+		oc := resolveOutputCap(s.conf(), dev.DevicePlatform, dev.CliList.itemFor(r.Command))
+		capped, omitted := capOutput(ctx, oc, dev.DeviceIP, r.Command, r.RawOutput)
+		if omitted == 0 {
+			continue
+		}
+		r.RawOutput, r.OmittedLines = capped, omitted
+		r.RawOutputLines = strings.Split(capped, "\n")
+	}
+}
@@ -0,0 +1,157 @@
+package service
+
+import (
+	"encoding/base64"
+	"fmt"
+	"reflect"
+	"strings"
+	"unicode/utf8"
+)
+
+// 响应中控制字符的编码方式（output_encoding）
+const (
+	OutputEncodingRaw    = "raw"    // 默认：原样输出（JSON 编码器仅转义 0x00-0x1F）
+	OutputEncodingStrip  = "strip"  // 移除控制字符（保留 \n \r \t）
+	OutputEncodingEscape = "escape" // 控制字符替换为可见的 \uXXXX 文本
+	OutputEncodingBase64 = "base64" // 疑似二进制的字符串整体编码为 "base64:<data>"
+)
+
+// base64Prefix 二进制输出经 base64 包装后的前缀
+const base64Prefix = "base64:"
+
+// ValidateOutputEncoding 校验 output_encoding：为空视为 raw
+func ValidateOutputEncoding(mode string) error {
+	switch strings.ToLower(strings.TrimSpace(mode)) {
+	case "", OutputEncodingRaw, OutputEncodingStrip, OutputEncodingEscape, OutputEncodingBase64:
+		return nil
+	}
+	return validationErrorf("unsupported output_encoding: %s", mode)
+}
+
+// isControlRune 需处理的控制字符：C0（除 \n \r \t）、DEL 与 C1
+func isControlRune(r rune) bool {
+	if r == '\n' || r == '\r' || r == '\t' {
+		return false
+	}
+	return r < 0x20 || (r >= 0x7f && r <= 0x9f)
+}
+
+// looksBinary 含控制字符或非法 UTF-8 视为二进制
+func looksBinary(s string) bool {
+	if !utf8.ValidString(s) {
+		return true
+	}
+	return strings.IndexFunc(s, isControlRune) >= 0
+}
+
+// EncodeControlChars 按模式处理单个字符串
+func EncodeControlChars(s, mode string) string {
+	switch strings.ToLower(strings.TrimSpace(mode)) {
+	case OutputEncodingStrip:
+		if !looksBinary(s) {
+			return s
+		}
+		return strings.Map(func(r rune) rune {
+			if isControlRune(r) {
+				return -1
+			}
+			return r
+		}, strings.ToValidUTF8(s, ""))
+	case OutputEncodingEscape:
+		if !looksBinary(s) {
+			return s
+		}
+		var b strings.Builder
+		b.Grow(len(s))
+		for i := 0; i < len(s); {
+			r, size := utf8.DecodeRuneInString(s[i:])
+			switch {
+			case r == utf8.RuneError && size == 1:
+				// 非法字节按原始字节值转义
+				fmt.Fprintf(&b, "\\u%04x", s[i])
+			case isControlRune(r):
+				fmt.Fprintf(&b, "\\u%04x", r)
+			default:
+				b.WriteString(s[i : i+size])
+			}
+			i += size
+		}
+		return b.String()
+	case OutputEncodingBase64:
+		if !looksBinary(s) {
+			return s
+		}
+		return base64Prefix + base64.StdEncoding.EncodeToString([]byte(s))
+	}
+	return s
+}
+
+// SanitizeOutput 对响应中所有字符串字段应用编码方式（原地修改），raw 模式直接返回
+func SanitizeOutput(v interface{}, mode string) interface{} {
+	mode = strings.ToLower(strings.TrimSpace(mode))
+	if v == nil || mode == "" || mode == OutputEncodingRaw {
+		return v
+	}
+	rv := reflect.New(reflect.TypeOf(v)).Elem()
+	rv.Set(reflect.ValueOf(v))
+	sanitizeValue(rv, func(s string) string { return EncodeControlChars(s, mode) })
+	return rv.Interface()
+}
+
+// sanitizeValue 递归遍历可导出字段、切片与 map 中的字符串
+func sanitizeValue(v reflect.Value, fn func(string) string) {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if !v.IsNil() {
+			sanitizeValue(v.Elem(), fn)
+		}
+	case reflect.Interface:
+		if v.IsNil() {
+			return
+		}
+		e := v.Elem()
+		switch e.Kind() {
+		case reflect.String, reflect.Struct, reflect.Array:
+			// 接口内的值不可寻址：复制后回写
+			cp := reflect.New(e.Type()).Elem()
+			cp.Set(e)
+			sanitizeValue(cp, fn)
+			if v.CanSet() {
+				v.Set(cp)
+			}
+		default:
+			sanitizeValue(e, fn)
+		}
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			if f := v.Field(i); f.CanSet() {
+				sanitizeValue(f, fn)
+			}
+		}
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return
+		}
+		for i := 0; i < v.Len(); i++ {
+			sanitizeValue(v.Index(i), fn)
+		}
+	case reflect.Array:
+		if v.CanSet() {
+			for i := 0; i < v.Len(); i++ {
+				sanitizeValue(v.Index(i), fn)
+			}
+		}
+	case reflect.Map:
+		iter := v.MapRange()
+		for iter.Next() {
+			cp := reflect.New(iter.Value().Type()).Elem()
+			cp.Set(iter.Value())
+			sanitizeValue(cp, fn)
+			v.SetMapIndex(iter.Key(), cp)
+		}
+	case reflect.String:
+		if v.CanSet() {
+			v.SetString(fn(v.String()))
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sshcollectorpro/sshcollectorpro/internal/config"
+	"github.com/sshcollectorpro/sshcollectorpro/pkg/logger"
+	"github.com/sshcollectorpro/sshcollectorpro/pkg/ssh"
+)
+
+// PlatformAuto 请求平台取值 "auto" 表示由服务探测
+const PlatformAuto = "auto"
+
+// bannerSignatures SSH 版本串与登录横幅中的厂商特征；版本命令未识别时回退使用
+var bannerSignatures = []struct {
+	platform string
+	re       *regexp.Regexp
+}{
+	{"huawei", regexp.MustCompile(`(?i)huawei|\bVRP\b`)},
+	{"h3c", regexp.MustCompile(`(?i)\bH3C\b|comware`)},
+	{"cisco_nxos", regexp.MustCompile(`(?i)NX-?OS|nexus`)},
+	{"cisco_ios", regexp.MustCompile(`(?i)cisco`)},
+	{"juniper_junos", regexp.MustCompile(`(?i)junos|juniper`)},
+	{"arista_eos", regexp.MustCompile(`(?i)arista`)},
+}
+
+// detectedPlatform 探测结果缓存项
+type detectedPlatform struct {
+	platform string
+	at       time.Time
+}
+
+// wantsPlatformDetect 平台为 "auto" 时总是探测；为空时按 collector.platform_detect.enable；仅 SSH 采集
+func wantsPlatformDetect(cfg *config.Config, request *CollectRequest) bool {
+	proto := strings.ToLower(strings.TrimSpace(request.CollectProtocol))
+	if proto != "" && proto != "ssh" {
+		return false
+	}
+	switch strings.ToLower(strings.TrimSpace(request.DevicePlatform)) {
+	case PlatformAuto:
+		return true
+	case "":
+		return cfg.Collector.PlatformDetect.Enable
+	}
+	return false
+}
+
+// detectBannerPlatform 按 SSH 版本串与登录横幅匹配厂商
+func detectBannerPlatform(serverVersion, banner string) string {
+	text := serverVersion + "\n" + banner
+	for _, sig := range bannerSignatures {
+		if sig.re.MatchString(text) {
+			return sig.platform
+		}
+	}
+	return ""
+}
+
+// detectPlatform 登录设备执行 show version / display version，按版本输出的厂商特征识别平台，
+// 未识别时回退到 SSH 版本串与登录横幅；识别结果归一为平台键并按 设备IP:端口 缓存。
+// 探测失败时记录 PLATFORM_UNDETECTED 告警并返回空平台（按 default 平台执行）
+func (s *CollectorService) detectPlatform(ctx context.Context, cfg *config.Config, request *CollectRequest) string {
+	port := request.Port
+	if port < 1 || port > 65535 {
+		port = 22
+	}
+	key := net.JoinHostPort(strings.TrimSpace(request.DeviceIP), strconv.Itoa(port))
+	ttl := cfg.Collector.PlatformDetect.CacheTTL
+	if v, ok := s.detected.Load(key); ok && ttl > 0 {
+		if d := v.(detectedPlatform); time.Since(d.at) < ttl {
+			return d.platform
+		}
+		s.detected.Delete(key)
+	}
+
+	platform, err := s.probePlatform(ctx, request, port)
+	if err != nil || platform == "" {
+		reason := "no vendor signature matched version output or ssh banner"
+		if err != nil {
+			reason = err.Error()
+		}
+		logger.Warnf("Platform detection failed for %s: %s", key, reason)
+		AddWarning(ctx, Warning{
+			Code:    WarnPlatformUndetected,
+			Message: fmt.Sprintf("platform could not be detected (%s); using default platform", reason),
+			Context: map[string]interface{}{"device_ip": request.DeviceIP, "device_port": port},
+		})
+		return ""
+	}
+	platform, _ = NormalizePlatform(cfg, platform)
+	logger.Info("Platform detected", "device", key, "platform", platform)
+	if ttl > 0 {
+		s.detected.Store(key, detectedPlatform{platform: platform, at: time.Now()})
+	}
+	return platform
+}
+
+// probePlatform 探测会话：占用一个工作协程名额，连接沿用连接池（随后的采集可复用）
+func (s *CollectorService) probePlatform(ctx context.Context, request *CollectRequest, port int) (string, error) {
+	slots, err := s.workers.acquire(ctx, nil)
+	if err != nil {
+		return "", err
+	}
+	defer func() { <-slots }()
+
+	timeout, devTimeout := s.effectiveTimeouts(&CollectRequest{TaskTimeout: request.TaskTimeout})
+	execReq := &ExecRequest{
+		DeviceIP:         request.DeviceIP,
+		Port:             port,
+		DeviceName:       request.DeviceName,
+		CollectProtocol:  "ssh",
+		UserName:         request.UserName,
+		Password:         request.Password,
+		EnablePassword:   request.EnablePassword,
+		TaskTimeoutSec:   timeout,
+		DeviceTimeoutSec: devTimeout,
+		PortCandidates:   s.portCandidates(request, port),
+	}
+	trace := &ssh.ConnectTrace{}
+	results, err := s.interact.Execute(ssh.WithConnectTrace(ctx, trace), execReq, factsProbeCommands)
+	if err != nil {
+		return "", err
+	}
+	for _, r := range results {
+		if r == nil || r.Error != "" {
+			continue
+		}
+		if p := detectFactsParser(r.Output); p != nil {
+			return p.platform, nil
+		}
+	}
+	return detectBannerPlatform(trace.ServerVersion, trace.Banner), nil
+}
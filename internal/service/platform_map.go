@@ -0,0 +1,59 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/sshcollectorpro/sshcollectorpro/internal/config"
+)
+
+// platformPatterns 已编译的映射正则（按 pattern 缓存）
+var platformPatterns sync.Map
+
+// NormalizePlatform 将请求中的平台字符串（如 NetBox/OS 名称 "Cisco IOS-XE 17.3"）归一为 device_defaults 平台键：
+// 已是平台键时返回小写键；否则按 collector.platform_mappings 顺序匹配首条规则；均未命中返回小写原值与 false
+func NormalizePlatform(cfg *config.Config, raw string) (string, bool) {
+	p := strings.ToLower(strings.TrimSpace(raw))
+	if p == "" || cfg == nil {
+		return p, true
+	}
+	if _, ok := cfg.Collector.DeviceDefaults[p]; ok {
+		return p, true
+	}
+	for _, m := range cfg.Collector.PlatformMappings {
+		re := compilePlatformPattern(m.Pattern)
+		if re != nil && re.MatchString(strings.TrimSpace(raw)) {
+			return strings.ToLower(strings.TrimSpace(m.Platform)), true
+		}
+	}
+	return p, false
+}
+
+// normalizeRequestPlatform 归一请求平台；未命中时记入请求告警（平台参数回退 default）
+func normalizeRequestPlatform(ctx context.Context, cfg *config.Config, raw, deviceIP string) string {
+	p, ok := NormalizePlatform(cfg, raw)
+	if !ok {
+		AddWarning(ctx, Warning{
+			Code:    WarnPlatformUnmapped,
+			Message: fmt.Sprintf("platform %q does not match any device_defaults key or platform mapping", strings.TrimSpace(raw)),
+			Context: map[string]interface{}{"device_platform": strings.TrimSpace(raw), "device_ip": deviceIP},
+		})
+	}
+	return p
+}
+
+func compilePlatformPattern(pattern string) *regexp.Regexp {
+	if v, ok := platformPatterns.Load(pattern); ok {
+		return v.(*regexp.Regexp)
+	}
+	// 配置加载时已校验；非法规则忽略
+	re, err := regexp.Compile("(?i)" + pattern)
+	if err != nil {
+		return nil
+	}
+	platformPatterns.Store(pattern, re)
+	return re
+}
@@ -0,0 +1,79 @@
+package service
+
+import (
+	"regexp"
+	"strings"
+)
+
+// playbookNamePattern 命令集名称：字母、数字、点、下划线与中划线
+var playbookNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]{0,127}$`)
+
+// Playbook 命令集：默认命令与按平台的变体
+type Playbook struct {
+	Name        string             `json:"name"`
+	Description string             `json:"description,omitempty"`
+	Commands    CLIList            `json:"commands"`
+	Variants    map[string]CLIList `json:"variants,omitempty"` // 平台（小写） -> 命令列表
+}
+
+// Validate 校验名称与命令：默认命令与变体至少提供一项
+func (p *Playbook) Validate() error {
+	if !playbookNamePattern.MatchString(p.Name) {
+		return validationErrorf("invalid playbook name: %q", p.Name)
+	}
+	total := countCommands(p.Commands)
+	for platform, cmds := range p.Variants {
+		if strings.TrimSpace(platform) == "" {
+			return validationErrorf("playbook variant platform is empty")
+		}
+		n := countCommands(cmds)
+		if n == 0 {
+			return validationErrorf("playbook variant %s has no commands", platform)
+		}
+		total += n
+	}
+	if total == 0 {
+		return validationErrorf("playbook %s has no commands", p.Name)
+	}
+	return nil
+}
+
+// CommandsFor 返回设备平台对应的命令：平台变体优先，否则默认命令
+func (p *Playbook) CommandsFor(platform string) CLIList {
+	if v, ok := p.Variants[strings.ToLower(strings.TrimSpace(platform))]; ok {
+		return v
+	}
+	return p.Commands
+}
+
+// Expand 命令集命令在前，请求中额外的 cli_list 追加在后（与命令集重复的命令跳过）
+func (p *Playbook) Expand(platform string, extra CLIList) CLIList {
+	base := p.CommandsFor(platform)
+	out := make(CLIList, 0, len(base)+len(extra))
+	seen := make(map[string]bool, len(base))
+	for _, it := range base {
+		if strings.TrimSpace(it.CLI) == "" {
+			continue
+		}
+		seen[strings.TrimSpace(it.CLI)] = true
+		out = append(out, it)
+	}
+	for _, it := range extra {
+		if seen[strings.TrimSpace(it.CLI)] {
+			continue
+		}
+		out = append(out, it)
+	}
+	return out
+}
+
+// countCommands 非空命令数
+func countCommands(l CLIList) int {
+	n := 0
+	for _, it := range l {
+		if strings.TrimSpace(it.CLI) != "" {
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,139 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync/atomic"
+
+	"github.com/sshcollectorpro/sshcollectorpro/internal/config"
+)
+
+// ErrReadOnly 只读模式下拒绝对设备的写操作
+var ErrReadOnly = errors.New("service is in read-only mode")
+
+// readOnly 全局只读开关（启动与配置热加载时由配置初始化，运行时可经管理接口切换）
+var readOnly atomic.Bool
+
+// configModeVerbs 进入配置模式的命令首词及设备接受的最短缩写长度
+// 首词为其缩写即拒绝，不论后续参数：conf t / config terminal / configure exclusive / sys / system / edit 等
+var configModeVerbs = []struct {
+	word string
+	min  int
+}{
+	{"configure", 4},   // Cisco/Arista/Juniper/Nokia 等：conf、config、configure [terminal|private|exclusive ...]
+	{"system-view", 3}, // 华为/H3C：sys、system、system-view
+	{"edit", 2},        // Juniper 操作模式 edit 进入配置模式
+}
+
+// InitPolicy 按配置初始化策略状态
+func InitPolicy(cfg *config.Config) {
+	if cfg == nil {
+		return
+	}
+	readOnly.Store(cfg.Policy.ReadOnly)
+}
+
+// SetReadOnly 运行时切换只读模式
+func SetReadOnly(v bool) { readOnly.Store(v) }
+
+// IsReadOnly 当前是否处于只读模式
+func IsReadOnly() bool { return readOnly.Load() }
+
+// CheckDeployAllowed 配置下发/进入配置模式前的策略校验
+func CheckDeployAllowed() error {
+	if IsReadOnly() {
+		return ErrReadOnly
+	}
+	return nil
+}
+
+// CheckCommandsAllowed 校验采集类命令：只读模式下拒绝任何进入配置模式的命令
+// 采集、备份、格式化均经 InteractBasic 执行，在此统一拦截
+func CheckCommandsAllowed(cfg *config.Config, platform string, cmds []string) error {
+	if !IsReadOnly() || len(cmds) == 0 {
+		return nil
+	}
+	var platformCLIs [][]string
+	if cfg != nil {
+		for _, c := range platformConfigModeCLIs(cfg, platform) {
+			if t := cliTokens(c); len(t) > 0 {
+				platformCLIs = append(platformCLIs, t)
+			}
+		}
+	}
+	for _, c := range cmds {
+		// 命令中的换行在发送时拆为多行，逐行校验，避免以 "show clock\nconfigure terminal" 绕过
+		for _, ln := range commandLines(c) {
+			if isConfigModeCommand(cliTokens(ln), platformCLIs) {
+				return fmt.Errorf("%w: config-mode command %q is not allowed", ErrReadOnly, strings.TrimSpace(ln))
+			}
+		}
+	}
+	return nil
+}
+
+// commandLines 按 \r、\n 拆分命令，与 SSH 发送时的行拆分一致
+func commandLines(cmd string) []string {
+	return strings.FieldsFunc(cmd, func(r rune) bool { return r == '\r' || r == '\n' })
+}
+
+// cliTokens 命令规范化为小写词序列（合并空白）
+func cliTokens(cmd string) []string {
+	return strings.Fields(strings.ToLower(cmd))
+}
+
+// isConfigModeCommand 首词为配置模式动词的缩写，或以平台 config_mode_clis 中任一命令的词序列开头
+func isConfigModeCommand(tokens []string, platformCLIs [][]string) bool {
+	if len(tokens) == 0 {
+		return false
+	}
+	for _, v := range configModeVerbs {
+		if len(tokens[0]) >= v.min && strings.HasPrefix(v.word, tokens[0]) {
+			return true
+		}
+	}
+	for _, p := range platformCLIs {
+		if len(tokens) < len(p) {
+			continue
+		}
+		match := true
+		for i := range p {
+			if tokens[i] != p[i] {
+				match = false
+				break
+			}
+		}
+		if match {
+			return true
+		}
+	}
+	return false
+}
+
+// platformConfigModeCLIs 读取平台进入配置模式命令（前缀兜底与其他服务一致）
+func platformConfigModeCLIs(cfg *config.Config, platform string) []string {
+	p := strings.ToLower(strings.TrimSpace(platform))
+	if p == "" {
+		p = "default"
+	}
+	dd, ok := cfg.Collector.DeviceDefaults[p]
+	if !ok {
+		if strings.HasPrefix(p, "huawei") {
+			dd, ok = cfg.Collector.DeviceDefaults["huawei"]
+		}
+		if !ok && strings.HasPrefix(p, "h3c") {
+			dd, ok = cfg.Collector.DeviceDefaults["h3c"]
+		}
+		if !ok && strings.HasPrefix(p, "cisco") {
+			dd, ok = cfg.Collector.DeviceDefaults["cisco_ios"]
+		}
+		if !ok && strings.HasPrefix(p, "linux") {
+			dd, ok = cfg.Collector.DeviceDefaults["linux"]
+		}
+	}
+	if !ok {
+		return nil
+	}
+	return dd.ConfigModeCLIs
+}
@@ -0,0 +1,43 @@
+package service
+
+import (
+	"fmt"
+
+	"github.com/sshcollectorpro/sshcollectorpro/pkg/logger"
+)
+
+// PortInventory 设备备用端口来源与回写（由接口层基于 device_info 表实现）
+type PortInventory interface {
+	// PortCandidates 资产登记的备用端口（最近连接成功的端口在前）
+	PortCandidates(ip string, port int) []int
+	// RecordConnectedPort 记录最近连接成功的端口，下次连接优先尝试
+	RecordConnectedPort(ip string, port, connected int) error
+}
+
+// SetPortInventory 注入资产备用端口来源
+func (s *CollectorService) SetPortInventory(inv PortInventory) {
+	s.portInventory = inv
+}
+
+// portCandidates 请求中的 port_candidates 在前，其后为资产登记的备用端口
+func (s *CollectorService) portCandidates(request *CollectRequest, port int) []int {
+	out := append([]int{}, request.PortCandidates...)
+	if s.portInventory != nil {
+		out = append(out, s.portInventory.PortCandidates(request.DeviceIP, port)...)
+	}
+	return out
+}
+
+// recordConnectedPort 使用备用端口连接成功时回写资产
+func (s *CollectorService) recordConnectedPort(request *CollectRequest, port, connected int) {
+	if connected == 0 || connected == port {
+		return
+	}
+	s.logTaskInfo(request.TaskID, fmt.Sprintf("Connected on alternate port %d (requested %d)", connected, port))
+	if s.portInventory == nil {
+		return
+	}
+	if err := s.portInventory.RecordConnectedPort(request.DeviceIP, port, connected); err != nil {
+		logger.Warn("Failed to record connected port", "device_ip", request.DeviceIP, "port", port, "connected_port", connected, "error", err)
+	}
+}
@@ -0,0 +1,111 @@
+package service
+
+import (
+	"reflect"
+	"sync"
+
+	"github.com/sshcollectorpro/sshcollectorpro/internal/config"
+	"github.com/sshcollectorpro/sshcollectorpro/pkg/logger"
+)
+
+// Reloadable 配置热更新后需调整运行时资源（并发名额、连接池、存储客户端）的服务；
+// 按任务读取的配置（device_defaults 等）经配置快照自动生效，无需在此处理
+type Reloadable interface {
+	OnReload(old, cfg *config.Config)
+}
+
+// ApplyReload 配置快照替换后依次通知各服务；old 为替换前的快照
+func ApplyReload(old, cfg *config.Config, services ...Reloadable) {
+	if old == nil || cfg == nil {
+		return
+	}
+	for _, s := range services {
+		if s != nil {
+			s.OnReload(old, cfg)
+		}
+	}
+}
+
+// workerSlots 可调整容量的并发名额：调整时换用新容量的通道，已占用的名额仍在原通道上释放，
+// 因此调整后短时间内实际并发可能为新旧上限之和
+type workerSlots struct {
+	mu    sync.RWMutex
+	ch    chan struct{}
+	stats workerStats
+}
+
+func newWorkerSlots(n int) *workerSlots {
+	if n <= 0 {
+		n = 1
+	}
+	return &workerSlots{ch: make(chan struct{}, n)}
+}
+
+// get 当前名额通道；占用与释放须使用同一次 get 的返回值
+func (w *workerSlots) get() chan struct{} {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.ch
+}
+
+// resize 调整容量，容量未变化时返回 false
+func (w *workerSlots) resize(n int) bool {
+	if n <= 0 {
+		n = 1
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if cap(w.ch) == n {
+		return false
+	}
+	w.ch = make(chan struct{}, n)
+	return true
+}
+
+// reloadWorkers 按 collector.concurrent 调整服务并发名额
+func reloadWorkers(name string, w *workerSlots, cfg *config.Config) {
+	before := cap(w.get())
+	if w.resize(cfg.Collector.Concurrent) {
+		logger.Info("Worker pool resized by config reload", "service", name, "from", before, "to", cap(w.get()))
+	}
+}
+
+// storageChanged 存储客户端相关配置（MinIO/S3/Azure）是否变化
+func storageChanged(old, cfg *config.Config) bool {
+	return !reflect.DeepEqual(old.Storage.Minio, cfg.Storage.Minio) ||
+		!reflect.DeepEqual(old.Storage.S3, cfg.Storage.S3) ||
+		!reflect.DeepEqual(old.Storage.Azure, cfg.Storage.Azure)
+}
+
+// OnReload 调整采集并发名额与连接池
+func (s *CollectorService) OnReload(old, cfg *config.Config) {
+	reloadWorkers("collector", s.workers, cfg)
+	reconfigureServicePool(cfg, s.sshPool)
+}
+
+// OnReload 调整备份并发名额与连接池，存储配置变化时重建远端存储客户端
+func (s *BackupService) OnReload(old, cfg *config.Config) {
+	reloadWorkers("backup", s.workers, cfg)
+	reconfigureServicePool(cfg, s.sshPool)
+	if dw, ok := s.storageWriter.(*DelegatingStorageWriter); ok && storageChanged(old, cfg) {
+		dw.reloadRemotes(cfg)
+		logger.Info("Storage clients recreated by config reload")
+	}
+}
+
+// OnReload 调整格式化并发名额与连接池，MinIO 配置变化时重建格式化结果写入客户端
+func (s *FormatService) OnReload(old, cfg *config.Config) {
+	reloadWorkers("format", s.workers, cfg)
+	if s.parseSlots != nil {
+		s.parseSlots.resize(fsmParseWorkers(cfg))
+	}
+	fsmCache.setLimit(cfg.DataFormat.FSM.CacheSize)
+	reconfigureServicePool(cfg, s.sshPool)
+	if !reflect.DeepEqual(old.Storage.Minio, cfg.Storage.Minio) {
+		mw := NewFormatMinioWriter(cfg)
+		s.mutex.Lock()
+		s.minioWriter = mw
+		s.mutex.Unlock()
+		logger.Info("Format MinIO client recreated by config reload")
+	}
+}
@@ -0,0 +1,129 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/sshcollectorpro/sshcollectorpro/internal/config"
+)
+
+// 批量响应中原始输出的返回方式（raw_output_mode）
+const (
+	RawOutputFull     = "full"     // 默认：完整返回
+	RawOutputOmit     = "omit"     // 仅落盘，响应不含原始输出
+	RawOutputTruncate = "truncate" // 每条命令保留前 max_output_kb KB
+	RawOutputURI      = "uri"      // 以落盘对象 URI 代替原始输出
+)
+
+// defaultMaxOutputKB truncate 模式未配置上限时保留的 KB 数
+const defaultMaxOutputKB = 64
+
+// ResponseOutput 批量响应的原始输出控制；落盘对象与任务记录始终保存完整输出
+type ResponseOutput struct {
+	Mode  string
+	MaxKB int
+}
+
+// ResolveResponseOutput 请求参数优先，其次 batch_response 配置；mode 为空视为 full
+func ResolveResponseOutput(cfg *config.Config, mode string, maxKB int) (ResponseOutput, error) {
+	o := ResponseOutput{Mode: strings.ToLower(strings.TrimSpace(mode)), MaxKB: maxKB}
+	if cfg != nil {
+		if o.Mode == "" {
+			o.Mode = strings.ToLower(strings.TrimSpace(cfg.BatchResponse.RawOutputMode))
+		}
+		if o.MaxKB <= 0 {
+			o.MaxKB = cfg.BatchResponse.MaxOutputKB
+		}
+	}
+	if o.Mode == "" {
+		o.Mode = RawOutputFull
+	}
+	if o.MaxKB <= 0 {
+		o.MaxKB = defaultMaxOutputKB
+	}
+	switch o.Mode {
+	case RawOutputFull, RawOutputOmit, RawOutputTruncate, RawOutputURI:
+		return o, nil
+	}
+	return o, validationErrorf("unsupported raw_output_mode: %s", mode)
+}
+
+// RequiresStore omit/uri 模式需落盘，否则输出无处可取
+func (o ResponseOutput) RequiresStore() bool {
+	return o.Mode == RawOutputOmit || o.Mode == RawOutputURI
+}
+
+// apply 返回响应中的原始输出与标记；uri 模式无落盘对象（如落盘失败）时退化为截断，避免输出丢失
+func (o ResponseOutput) apply(raw string, objects []StoredObject) (out, uri string, omitted, truncated bool) {
+	switch o.Mode {
+	case RawOutputOmit:
+		return "", "", raw != "", false
+	case RawOutputURI:
+		if len(objects) > 0 {
+			return "", objects[0].URI, raw != "", false
+		}
+		out, truncated = truncateOutput(raw, o.MaxKB)
+		return out, "", false, truncated
+	case RawOutputTruncate:
+		out, truncated = truncateOutput(raw, o.MaxKB)
+		return out, "", false, truncated
+	}
+	return raw, "", false, false
+}
+
+// ApplyCollect 处理采集结果（须在落盘与任务记录保存之后调用）
+func (o ResponseOutput) ApplyCollect(results []*CommandResultView) {
+	if o.Mode == "" || o.Mode == RawOutputFull {
+		return
+	}
+	for _, v := range results {
+		if v == nil {
+			continue
+		}
+		size := len(v.RawOutput)
+		v.RawOutput, v.RawOutputURI, v.RawOutputOmitted, v.RawOutputTruncated = o.apply(v.RawOutput, v.StoredObjects)
+		if v.RawOutputOmitted || v.RawOutputTruncated {
+			v.RawOutputSize = size
+		}
+		if o.RequiresStore() {
+			v.RawBytes = ""
+		}
+	}
+}
+
+// ApplyBackup 处理备份结果；raw_output_lines 与 raw_output 保持一致
+func (o ResponseOutput) ApplyBackup(devices []DeviceBackupResponse) {
+	if o.Mode == "" || o.Mode == RawOutputFull {
+		return
+	}
+	for i := range devices {
+		for j := range devices[i].Results {
+			r := &devices[i].Results[j]
+			size := len(r.RawOutput)
+			r.RawOutput, r.RawOutputURI, r.RawOutputOmitted, r.RawOutputTruncated = o.apply(r.RawOutput, r.StoredObjects)
+			if r.RawOutputOmitted || r.RawOutputTruncated {
+				r.RawOutputSize = size
+			}
+			switch {
+			case r.RawOutputTruncated:
+				r.RawOutputLines = strings.Split(r.RawOutput, "\n")
+			case r.RawOutput == "":
+				r.RawOutputLines = []string{}
+			}
+		}
+	}
+}
+
+// truncateOutput 保留前 maxKB KB（按 UTF-8 字符边界），末尾附省略标记
+func truncateOutput(s string, maxKB int) (string, bool) {
+	limit := maxKB * 1024
+	if limit <= 0 || len(s) <= limit {
+		return s, false
+	}
+	cut := limit
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	return s[:cut] + fmt.Sprintf("\n... [truncated: %d of %d bytes shown] ...", cut, len(s)), true
+}
@@ -0,0 +1,202 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/sshcollectorpro/sshcollectorpro/internal/config"
+)
+
+// RetryPolicy 请求级重试策略，未设置的字段沿用平台与全局配置（collector.retry_policy）
+type RetryPolicy struct {
+	Backoff        string   `json:"backoff,omitempty"` // constant | linear | exponential
+	InitialDelayMS int      `json:"initial_delay_ms,omitempty"`
+	MaxDelayMS     int      `json:"max_delay_ms,omitempty"`
+	Multiplier     float64  `json:"multiplier,omitempty"`
+	Jitter         *float64 `json:"jitter,omitempty"` // 0~1
+	MaxElapsedMS   int      `json:"max_elapsed_ms,omitempty"`
+	RetryOn        string   `json:"retry_on,omitempty"` // any | connect
+}
+
+// RetryAttempt 单次尝试记录
+type RetryAttempt struct {
+	Attempt    int    `json:"attempt"`            // 从 1 开始
+	DelayMS    int64  `json:"delay_ms,omitempty"` // 本次尝试前的退避等待
+	DurationMS int64  `json:"duration_ms"`
+	Error      string `json:"error,omitempty"`
+}
+
+// Validate 校验请求级重试策略
+func (p *RetryPolicy) Validate() error {
+	if p == nil {
+		return nil
+	}
+	switch strings.ToLower(strings.TrimSpace(p.Backoff)) {
+	case "", config.RetryBackoffConstant, config.RetryBackoffLinear, config.RetryBackoffExponential:
+	default:
+		return validationErrorf("invalid retry_policy.backoff %q: must be constant, linear or exponential", p.Backoff)
+	}
+	switch strings.ToLower(strings.TrimSpace(p.RetryOn)) {
+	case "", config.RetryOnAny, config.RetryOnConnect:
+	default:
+		return validationErrorf("invalid retry_policy.retry_on %q: must be any or connect", p.RetryOn)
+	}
+	if p.InitialDelayMS < 0 || p.MaxDelayMS < 0 || p.MaxElapsedMS < 0 {
+		return validationErrorf("retry_policy delays must not be negative")
+	}
+	if p.Multiplier != 0 && p.Multiplier < 1 {
+		return validationErrorf("retry_policy.multiplier must be at least 1")
+	}
+	if p.Jitter != nil && (*p.Jitter < 0 || *p.Jitter > 1) {
+		return validationErrorf("retry_policy.jitter must be between 0 and 1")
+	}
+	return nil
+}
+
+// retryPolicy 合并后的有效重试策略
+type retryPolicy struct {
+	config.RetryPolicyConfig
+}
+
+// resolveRetryPolicy 按 全局 -> 平台 -> 请求 的顺序逐项覆盖
+func resolveRetryPolicy(cfg *config.Config, platform string, req *RetryPolicy) retryPolicy {
+	p := retryPolicy{config.RetryPolicyConfig{Backoff: config.RetryBackoffLinear, InitialDelay: 150 * time.Millisecond, Multiplier: 2, RetryOn: config.RetryOnAny}}
+	if cfg != nil {
+		p.merge(cfg.Collector.RetryPolicy)
+		if dd, ok := cfg.Collector.DeviceDefaults[strings.ToLower(strings.TrimSpace(platform))]; ok {
+			p.merge(dd.RetryPolicy)
+		}
+	}
+	if req != nil {
+		o := config.RetryPolicyConfig{
+			Backoff:      req.Backoff,
+			InitialDelay: time.Duration(req.InitialDelayMS) * time.Millisecond,
+			MaxDelay:     time.Duration(req.MaxDelayMS) * time.Millisecond,
+			Multiplier:   req.Multiplier,
+			MaxElapsed:   time.Duration(req.MaxElapsedMS) * time.Millisecond,
+			RetryOn:      req.RetryOn,
+		}
+		p.merge(o)
+		if req.Jitter != nil {
+			p.Jitter = *req.Jitter
+		}
+	}
+	return p
+}
+
+func (p *retryPolicy) merge(o config.RetryPolicyConfig) {
+	if b := strings.ToLower(strings.TrimSpace(o.Backoff)); b != "" {
+		p.Backoff = b
+	}
+	if o.InitialDelay > 0 {
+		p.InitialDelay = o.InitialDelay
+	}
+	if o.MaxDelay > 0 {
+		p.MaxDelay = o.MaxDelay
+	}
+	if o.Multiplier >= 1 {
+		p.Multiplier = o.Multiplier
+	}
+	if o.Jitter > 0 {
+		p.Jitter = o.Jitter
+	}
+	if o.MaxElapsed > 0 {
+		p.MaxElapsed = o.MaxElapsed
+	}
+	if r := strings.ToLower(strings.TrimSpace(o.RetryOn)); r != "" {
+		p.RetryOn = r
+	}
+}
+
+// delay 第 n 次重试（从 1 开始）前的等待时长
+func (p retryPolicy) delay(n int) time.Duration {
+	d := float64(p.InitialDelay)
+	switch p.Backoff {
+	case config.RetryBackoffLinear:
+		d *= float64(n)
+	case config.RetryBackoffExponential:
+		d *= math.Pow(p.Multiplier, float64(n-1))
+	}
+	if p.MaxDelay > 0 && d > float64(p.MaxDelay) {
+		d = float64(p.MaxDelay)
+	}
+	if p.Jitter > 0 {
+		d *= 1 - p.Jitter + 2*p.Jitter*rand.Float64()
+	}
+	return time.Duration(d)
+}
+
+// retryable 判断失败后是否继续重试：出站策略拒绝、凭据冷却、设备熔断、主机密钥拒绝与参数错误不重试；retry_on=connect 仅重试建连失败
+func (p retryPolicy) retryable(err error) bool {
+	if errors.Is(err, ErrEgressBlocked) || errors.Is(err, ErrCredentialThrottled) || errors.Is(err, ErrDeviceCircuitOpen) ||
+		errors.Is(err, ErrHostKeyRejected) || errors.Is(err, ErrValidation) {
+		return false
+	}
+	if p.RetryOn == config.RetryOnConnect {
+		return isConnectFailure(err)
+	}
+	return true
+}
+
+// isConnectFailure 设备不可达、建连/登录超时等网络层失败；认证失败不计入
+func isConnectFailure(err error) bool {
+	if err == nil || errors.Is(err, ErrAuthFailed) || isAuthFailure(err) || errors.Is(err, ErrCanceled) || errors.Is(err, context.Canceled) {
+		return false
+	}
+	if errors.Is(err, ErrDeviceUnreachable) || errors.Is(err, ErrTimeout) {
+		return true
+	}
+	var ne net.Error
+	return errors.As(err, &ne)
+}
+
+// do 执行 fn，失败时按策略退避重试，最多 retries 次；返回每次尝试的记录与最后一次的错误
+func (p retryPolicy) do(ctx context.Context, retries int, onFail func(attempt, total int, err error), fn func() error) ([]RetryAttempt, error) {
+	if retries < 0 {
+		retries = 0
+	}
+	start := time.Now()
+	attempts := make([]RetryAttempt, 0, 1)
+	var wait time.Duration
+	for i := 0; ; i++ {
+		t := time.Now()
+		err := fn()
+		a := RetryAttempt{Attempt: i + 1, DelayMS: wait.Milliseconds(), DurationMS: time.Since(t).Milliseconds()}
+		if err == nil {
+			return append(attempts, a), nil
+		}
+		a.Error = err.Error()
+		attempts = append(attempts, a)
+		if onFail != nil {
+			onFail(i+1, retries+1, err)
+		}
+		if i >= retries || ctx.Err() != nil || !p.retryable(err) {
+			return attempts, err
+		}
+		wait = p.delay(i + 1)
+		if p.MaxElapsed > 0 && time.Since(start)+wait > p.MaxElapsed {
+			return attempts, fmt.Errorf("%w (retry_policy.max_elapsed %s reached after %d attempts)", err, p.MaxElapsed, i+1)
+		}
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return attempts, err
+		}
+	}
+}
+
+// failedAttempts 存在失败尝试时返回完整记录，首次即成功时返回 nil（响应中省略）
+func failedAttempts(attempts []RetryAttempt) []RetryAttempt {
+	for _, a := range attempts {
+		if a.Error != "" {
+			return attempts
+		}
+	}
+	return nil
+}
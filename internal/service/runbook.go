@@ -0,0 +1,514 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sshcollectorpro/sshcollectorpro/internal/config"
+	"github.com/sshcollectorpro/sshcollectorpro/pkg/logger"
+)
+
+// 运行手册步骤类型
+const (
+	RunbookStepCollect    = "collect"
+	RunbookStepCompliance = "compliance"
+	RunbookStepDeploy     = "deploy"
+	RunbookStepNotify     = "notify"
+)
+
+// 步骤状态；when.outcome 另支持 completed（已执行，不论成败）与 always（总是执行）
+const (
+	RunbookPending   = "pending"
+	RunbookRunning   = "running"
+	RunbookSucceeded = "succeeded"
+	RunbookFailed    = "failed"
+	RunbookSkipped   = "skipped"
+
+	RunbookOutcomeCompleted = "completed"
+	RunbookOutcomeAlways    = "always"
+)
+
+// 执行记录状态
+const (
+	RunbookRunRunning     = "running"
+	RunbookRunCompleted   = "completed"
+	RunbookRunInterrupted = "interrupted"
+)
+
+// 步骤目标设备：取条件步骤中成功/失败的设备
+const (
+	RunbookTargetsAll       = "all"
+	RunbookTargetsFailed    = "failed"
+	RunbookTargetsSucceeded = "succeeded"
+)
+
+// RunbookCondition 步骤执行条件：引用前序步骤的结果
+type RunbookCondition struct {
+	Step    string `json:"step,omitempty"` // 前序步骤名，为空表示上一步
+	Outcome string `json:"outcome"`        // succeeded | failed | skipped | completed | always
+}
+
+// RunbookDeploy 下发步骤参数（同 /deploy/fast，命令对所有目标设备相同）
+type RunbookDeploy struct {
+	TaskType        string                 `json:"task_type,omitempty"` // exec（默认）| dry_run
+	CliList         DeployCLIList          `json:"cli_list,omitempty"`
+	ConfigTemplate  string                 `json:"config_template,omitempty"`
+	Variables       map[string]interface{} `json:"variables,omitempty"`
+	RollbackCliList DeployCLIList          `json:"rollback_cli_list,omitempty"`
+	AutoRollback    bool                   `json:"auto_rollback,omitempty"`
+}
+
+// RunbookStep 运行手册步骤
+// when 为空时：首个步骤总是执行，其余步骤仅在上一步成功时执行
+type RunbookStep struct {
+	Name        string            `json:"name"`
+	Type        string            `json:"type"` // collect | compliance | deploy | notify
+	When        *RunbookCondition `json:"when,omitempty"`
+	Targets     string            `json:"targets,omitempty"` // all（默认）| failed | succeeded
+	TaskTimeout *int              `json:"task_timeout,omitempty"`
+	CliList     CLIList           `json:"cli_list,omitempty"`     // collect
+	Store       bool              `json:"store,omitempty"`        // collect：落盘命令输出
+	RuleIDs     []uint            `json:"rule_ids,omitempty"`     // compliance：为空表示全部启用规则
+	Deploy      *RunbookDeploy    `json:"deploy,omitempty"`       // deploy
+	CallbackURL string            `json:"callback_url,omitempty"` // notify
+	Message     string            `json:"message,omitempty"`      // notify
+}
+
+// RunbookDevice 参与执行的设备（各步骤共用）
+type RunbookDevice struct {
+	DeviceIP        string `json:"device_ip"`
+	Port            int    `json:"device_port,omitempty"`
+	DeviceName      string `json:"device_name,omitempty"`
+	DevicePlatform  string `json:"device_platform"`
+	CollectProtocol string `json:"collect_protocol,omitempty"`
+	UserName        string `json:"user_name"`
+	Password        string `json:"password"`
+	EnablePassword  string `json:"enable_password,omitempty"`
+	CredentialID    string `json:"credential_id,omitempty"` // 引用已登记凭据（接口层解析）
+	DeviceTimeout   *int   `json:"device_timeout,omitempty"`
+}
+
+// RunbookDeviceOutcome 步骤中单设备的结果
+type RunbookDeviceOutcome struct {
+	DeviceIP   string `json:"device_ip"`
+	Port       int    `json:"device_port"`
+	DeviceName string `json:"device_name,omitempty"`
+	Success    bool   `json:"success"`
+	Error      string `json:"error,omitempty"`
+}
+
+// RunbookStepResult 步骤结果；task_id 可用于查询任务历史、合规报告等
+type RunbookStepResult struct {
+	Name       string                 `json:"name"`
+	Type       string                 `json:"type"`
+	Status     string                 `json:"status"`
+	TaskID     string                 `json:"task_id,omitempty"`
+	Message    string                 `json:"message,omitempty"`
+	Succeeded  int                    `json:"succeeded"`
+	Failed     int                    `json:"failed"`
+	Devices    []RunbookDeviceOutcome `json:"devices,omitempty"`
+	StartedAt  *time.Time             `json:"started_at,omitempty"`
+	FinishedAt *time.Time             `json:"finished_at,omitempty"`
+}
+
+// ValidateRunbookSteps 校验步骤定义：名称唯一，条件只能引用前序步骤
+func ValidateRunbookSteps(steps []RunbookStep) error {
+	if len(steps) == 0 {
+		return validationErrorf("steps is empty")
+	}
+	seen := map[string]bool{}
+	for i := range steps {
+		st := &steps[i]
+		st.Name = strings.TrimSpace(st.Name)
+		st.Type = strings.ToLower(strings.TrimSpace(st.Type))
+		if st.Name == "" {
+			return validationErrorf("step %d: name is required", i+1)
+		}
+		if seen[st.Name] {
+			return validationErrorf("duplicate step name %q", st.Name)
+		}
+		if st.When != nil {
+			st.When.Step = strings.TrimSpace(st.When.Step)
+			st.When.Outcome = strings.ToLower(strings.TrimSpace(st.When.Outcome))
+			if st.When.Step != "" && !seen[st.When.Step] {
+				return validationErrorf("step %q: when.step %q must reference an earlier step", st.Name, st.When.Step)
+			}
+			switch st.When.Outcome {
+			case RunbookSucceeded, RunbookFailed, RunbookSkipped, RunbookOutcomeCompleted, RunbookOutcomeAlways:
+			default:
+				return validationErrorf("step %q: unsupported when.outcome %q", st.Name, st.When.Outcome)
+			}
+		}
+		st.Targets = strings.ToLower(strings.TrimSpace(st.Targets))
+		switch st.Targets {
+		case "", RunbookTargetsAll, RunbookTargetsFailed, RunbookTargetsSucceeded:
+		default:
+			return validationErrorf("step %q: unsupported targets %q", st.Name, st.Targets)
+		}
+		switch st.Type {
+		case RunbookStepCollect:
+			if len(st.CliList) == 0 {
+				return validationErrorf("step %q: cli_list is required", st.Name)
+			}
+		case RunbookStepCompliance:
+		case RunbookStepDeploy:
+			if st.Deploy == nil || (len(st.Deploy.CliList) == 0 && strings.TrimSpace(st.Deploy.ConfigTemplate) == "") {
+				return validationErrorf("step %q: deploy.cli_list or deploy.config_template is required", st.Name)
+			}
+		case RunbookStepNotify:
+			if strings.TrimSpace(st.CallbackURL) == "" {
+				return validationErrorf("step %q: callback_url is required", st.Name)
+			}
+			if err := ValidateCallbackURL(st.CallbackURL); err != nil {
+				return validationErrorf("step %q: %v", st.Name, err)
+			}
+		default:
+			return validationErrorf("step %q: unsupported type %q", st.Name, st.Type)
+		}
+		seen[st.Name] = true
+	}
+	return nil
+}
+
+// NewRunbookResults 为步骤生成初始（pending）结果
+func NewRunbookResults(steps []RunbookStep) []RunbookStepResult {
+	out := make([]RunbookStepResult, len(steps))
+	for i, st := range steps {
+		out[i] = RunbookStepResult{Name: st.Name, Type: st.Type, Status: RunbookPending}
+	}
+	return out
+}
+
+// RunbookRunStatus 全部步骤已结束为 completed，否则为 interrupted
+func RunbookRunStatus(results []RunbookStepResult) string {
+	for _, r := range results {
+		if r.Status == RunbookPending || r.Status == RunbookRunning {
+			return RunbookRunInterrupted
+		}
+	}
+	return RunbookRunCompleted
+}
+
+// RunbookService 运行手册执行：按顺序调用采集、合规检查、下发与回调通知
+type RunbookService struct {
+	provider   *config.Provider
+	collector  *CollectorService
+	compliance *ComplianceService
+	deploy     *DeployService
+}
+
+// NewRunbookService 创建运行手册服务
+func NewRunbookService(cfg *config.Config, collector *CollectorService, compliance *ComplianceService, deploy *DeployService) *RunbookService {
+	return &RunbookService{provider: config.ProviderFor(cfg), collector: collector, compliance: compliance, deploy: deploy}
+}
+
+// Execute 从首个未结束（pending/running）的步骤开始执行，已结束的步骤沿用原结果（续跑）
+// 每个步骤开始与结束时调用 onStep 以持久化进度；ctx 取消后不再开始新步骤
+func (s *RunbookService) Execute(ctx context.Context, runID, name string, steps []RunbookStep, devices []RunbookDevice, results []RunbookStepResult, onStep func([]RunbookStepResult)) []RunbookStepResult {
+	if len(results) != len(steps) {
+		results = NewRunbookResults(steps)
+	}
+	for i, st := range steps {
+		if r := results[i]; r.Status != RunbookPending && r.Status != RunbookRunning {
+			continue
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		now := time.Now()
+		res := RunbookStepResult{Name: st.Name, Type: st.Type, Status: RunbookRunning, StartedAt: &now}
+		ref := runbookRef(steps, results, i)
+		if ok, why := runbookConditionMet(st, ref); !ok {
+			res.Status, res.Message = RunbookSkipped, why
+		} else {
+			results[i] = res
+			if onStep != nil {
+				onStep(results)
+			}
+			res.TaskID = fmt.Sprintf("%s-s%d", runID, i+1)
+			targets := runbookTargets(st, ref, devices)
+			if len(targets) == 0 && st.Type != RunbookStepNotify {
+				res.Status, res.Message = RunbookSkipped, "no target devices"
+			} else {
+				s.runStep(ctx, name, st, targets, ref, &res)
+			}
+			if ctx.Err() != nil && res.Status == RunbookFailed {
+				// 执行中被取消：保留为 running，续跑时重新执行该步骤
+				results[i].Message = ctx.Err().Error()
+				break
+			}
+		}
+		end := time.Now()
+		res.FinishedAt = &end
+		results[i] = res
+		if onStep != nil {
+			onStep(results)
+		}
+		logger.Info("Runbook step finished", "run_id", runID, "step", st.Name, "type", st.Type, "status", res.Status, "succeeded", res.Succeeded, "failed", res.Failed)
+	}
+	return results
+}
+
+// runbookRef 条件与目标引用的步骤结果；首个步骤无引用时返回 nil
+func runbookRef(steps []RunbookStep, results []RunbookStepResult, i int) *RunbookStepResult {
+	if st := steps[i]; st.When != nil && st.When.Step != "" {
+		for j := 0; j < i; j++ {
+			if steps[j].Name == st.When.Step {
+				return &results[j]
+			}
+		}
+	}
+	if i == 0 {
+		return nil
+	}
+	return &results[i-1]
+}
+
+func runbookConditionMet(st RunbookStep, ref *RunbookStepResult) (bool, string) {
+	outcome := RunbookSucceeded
+	if st.When != nil {
+		outcome = st.When.Outcome
+	}
+	if ref == nil || outcome == RunbookOutcomeAlways {
+		return true, ""
+	}
+	switch outcome {
+	case ref.Status:
+		return true, ""
+	case RunbookOutcomeCompleted:
+		if ref.Status == RunbookSucceeded || ref.Status == RunbookFailed {
+			return true, ""
+		}
+	}
+	return false, fmt.Sprintf("condition not met: step %s is %s, requires %s", ref.Name, ref.Status, outcome)
+}
+
+// runbookTargets 按 targets 从引用步骤的设备结果中筛选；引用步骤无设备结果时 all 取全部设备
+func runbookTargets(st RunbookStep, ref *RunbookStepResult, devices []RunbookDevice) []RunbookDevice {
+	if st.Targets == "" || st.Targets == RunbookTargetsAll {
+		return devices
+	}
+	if ref == nil {
+		return nil
+	}
+	want := map[string]bool{}
+	for _, o := range ref.Devices {
+		if o.Success == (st.Targets == RunbookTargetsSucceeded) {
+			want[runbookDeviceKey(o.DeviceIP, o.Port)] = true
+		}
+	}
+	out := make([]RunbookDevice, 0, len(want))
+	for _, d := range devices {
+		if want[runbookDeviceKey(d.DeviceIP, d.Port)] {
+			out = append(out, d)
+		}
+	}
+	return out
+}
+
+func runbookDeviceKey(ip string, port int) string {
+	if port < 1 || port > 65535 {
+		port = 22
+	}
+	return fmt.Sprintf("%s:%d", strings.TrimSpace(ip), port)
+}
+
+func (d RunbookDevice) outcome(success bool, errMsg string) RunbookDeviceOutcome {
+	port := d.Port
+	if port < 1 || port > 65535 {
+		port = 22
+	}
+	return RunbookDeviceOutcome{DeviceIP: d.DeviceIP, Port: port, DeviceName: d.DeviceName, Success: success, Error: errMsg}
+}
+
+// runStep 执行单个步骤并填充结果；步骤级错误（如参数无效、服务未运行）记为全部目标设备失败
+func (s *RunbookService) runStep(ctx context.Context, name string, st RunbookStep, targets []RunbookDevice, ref *RunbookStepResult, res *RunbookStepResult) {
+	var err error
+	switch st.Type {
+	case RunbookStepCollect:
+		res.Devices = s.runCollect(ctx, name, st, targets, res.TaskID)
+	case RunbookStepCompliance:
+		res.Devices, err = s.runCompliance(ctx, name, st, targets, res.TaskID)
+	case RunbookStepDeploy:
+		res.Devices, err = s.runDeploy(ctx, name, st, targets, res.TaskID)
+	case RunbookStepNotify:
+		err = s.runNotify(ctx, name, st, ref, res.TaskID)
+	default:
+		err = fmt.Errorf("unsupported step type %q", st.Type)
+	}
+	if err != nil {
+		res.Status, res.Message = RunbookFailed, err.Error()
+		if st.Type != RunbookStepNotify {
+			res.Devices = make([]RunbookDeviceOutcome, 0, len(targets))
+			for _, d := range targets {
+				res.Devices = append(res.Devices, d.outcome(false, err.Error()))
+			}
+			res.Failed = len(targets)
+		}
+		return
+	}
+	for _, o := range res.Devices {
+		if o.Success {
+			res.Succeeded++
+		} else {
+			res.Failed++
+		}
+	}
+	res.Status = RunbookSucceeded
+	if res.Failed > 0 {
+		res.Status = RunbookFailed
+		res.Message = fmt.Sprintf("%d of %d devices failed", res.Failed, len(res.Devices))
+	}
+}
+
+func (s *RunbookService) runCollect(ctx context.Context, name string, st RunbookStep, targets []RunbookDevice, taskID string) []RunbookDeviceOutcome {
+	out := make([]RunbookDeviceOutcome, len(targets))
+	var wg sync.WaitGroup
+	for i := range targets {
+		wg.Add(1)
+		go func(i int, d RunbookDevice) {
+			defer wg.Done()
+			if s.collector == nil {
+				out[i] = d.outcome(false, serviceStopped("collector").Error())
+				return
+			}
+			req := &CollectRequest{
+				TaskID:          fmt.Sprintf("%s-%d", taskID, i+1),
+				TaskName:        name + "/" + st.Name,
+				DeviceIP:        d.DeviceIP,
+				Port:            d.Port,
+				DeviceName:      d.DeviceName,
+				DevicePlatform:  d.DevicePlatform,
+				CollectProtocol: d.CollectProtocol,
+				UserName:        d.UserName,
+				Password:        d.Password,
+				EnablePassword:  d.EnablePassword,
+				CliList:         st.CliList,
+				TaskTimeout:     st.TaskTimeout,
+				DeviceTimeout:   d.DeviceTimeout,
+				Metadata:        map[string]interface{}{"batch_task_id": taskID, "runbook_step": st.Name},
+				Store:           st.Store,
+			}
+			resp, err := s.collector.ExecuteTask(ctx, req)
+			switch {
+			case err != nil:
+				out[i] = d.outcome(false, err.Error())
+			default:
+				out[i] = d.outcome(resp.Success, resp.Error)
+			}
+		}(i, targets[i])
+	}
+	wg.Wait()
+	return out
+}
+
+func (s *RunbookService) runCompliance(ctx context.Context, name string, st RunbookStep, targets []RunbookDevice, taskID string) ([]RunbookDeviceOutcome, error) {
+	if s.compliance == nil {
+		return nil, serviceStopped("compliance")
+	}
+	req := &ComplianceRequest{TaskID: taskID, TaskName: name + "/" + st.Name, RuleIDs: st.RuleIDs, TaskTimeout: st.TaskTimeout}
+	for _, d := range targets {
+		req.Devices = append(req.Devices, ComplianceDevice{
+			DeviceIP: d.DeviceIP, Port: d.Port, DeviceName: d.DeviceName, DevicePlatform: d.DevicePlatform,
+			CollectProtocol: d.CollectProtocol, UserName: d.UserName, Password: d.Password,
+			EnablePassword: d.EnablePassword, DeviceTimeout: d.DeviceTimeout,
+		})
+	}
+	report, err := s.compliance.Run(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	// 设备合规即成功；采集失败或存在未通过的规则均视为失败
+	out := make([]RunbookDeviceOutcome, len(targets))
+	for i, d := range targets {
+		r := report.Devices[i]
+		msg := r.Error
+		if msg == "" && !r.Compliant {
+			msg = fmt.Sprintf("non-compliant: %d rules failed", r.Failed)
+		}
+		out[i] = d.outcome(r.Compliant, msg)
+	}
+	return out, nil
+}
+
+func (s *RunbookService) runDeploy(ctx context.Context, name string, st RunbookStep, targets []RunbookDevice, taskID string) ([]RunbookDeviceOutcome, error) {
+	if s.deploy == nil {
+		return nil, serviceStopped("deploy")
+	}
+	dp := st.Deploy
+	req := &DeployFastRequest{
+		TaskID:         taskID,
+		TaskName:       name + "/" + st.Name,
+		TaskType:       dp.TaskType,
+		ConfigTemplate: dp.ConfigTemplate,
+		Variables:      dp.Variables,
+		AutoRollback:   dp.AutoRollback,
+	}
+	if strings.TrimSpace(req.TaskType) == "" {
+		req.TaskType = "exec"
+	}
+	// 默认超时同 /deploy/fast：ssh.timeout，否则 15s
+	switch {
+	case st.TaskTimeout != nil && *st.TaskTimeout > 0:
+		req.TaskTimeout = *st.TaskTimeout
+	case s.conf() != nil && s.conf().SSH.Timeout > 0:
+		req.TaskTimeout = int(s.conf().SSH.Timeout.Seconds())
+	default:
+		req.TaskTimeout = 15
+	}
+	for _, d := range targets {
+		req.Devices = append(req.Devices, DeployDevice{
+			DeviceIP: d.DeviceIP, DeviceName: d.DeviceName, DevicePlatform: d.DevicePlatform, DevicePort: d.Port,
+			CollectProtocol: d.CollectProtocol, UserName: d.UserName, Password: d.Password,
+			EnablePassword: d.EnablePassword, DeviceTimeout: d.DeviceTimeout,
+			CliList: dp.CliList, RollbackCliList: dp.RollbackCliList,
+		})
+	}
+	resp, err := s.deploy.ExecuteFast(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	// 下发结果不含端口，按设备 IP 依次对应
+	used := make([]bool, len(resp.Results))
+	out := make([]RunbookDeviceOutcome, len(targets))
+	for i, d := range targets {
+		out[i] = d.outcome(false, "no deploy result")
+		for j, r := range resp.Results {
+			if used[j] || r.DeviceIP != d.DeviceIP {
+				continue
+			}
+			used[j] = true
+			ok, msg := r.Outcome()
+			out[i] = d.outcome(ok, msg)
+			break
+		}
+	}
+	return out, nil
+}
+
+// runNotify 同步推送回调：设备摘要取引用步骤中按 targets 筛选的设备结果
+func (s *RunbookService) runNotify(ctx context.Context, name string, st RunbookStep, ref *RunbookStepResult, taskID string) error {
+	devices := make([]CallbackDeviceSummary, 0)
+	if ref != nil {
+		for _, o := range ref.Devices {
+			if (st.Targets == RunbookTargetsFailed && o.Success) || (st.Targets == RunbookTargetsSucceeded && !o.Success) {
+				continue
+			}
+			devices = append(devices, CallbackDeviceSummary{DeviceIP: o.DeviceIP, DeviceName: o.DeviceName, Success: o.Success, Error: o.Error})
+		}
+	}
+	p := newCallbackPayload(taskID, name, "runbook", devices)
+	p.Message = st.Message
+	if p.Message == "" && ref != nil {
+		p.Message = fmt.Sprintf("runbook %s: step %s %s", name, ref.Name, ref.Status)
+	}
+	p.FinishedAt = time.Now()
+	cbCfg := config.CallbackConfig{}
+	if s.conf() != nil {
+		cbCfg = s.conf().Callback
+	}
+	return sendCallback(ctx, cbCfg, strings.TrimSpace(st.CallbackURL), p)
+}
@@ -0,0 +1,106 @@
+package service
+
+import (
+	"context"
+	"regexp"
+	"strings"
+
+	"github.com/sshcollectorpro/sshcollectorpro/internal/config"
+	"github.com/sshcollectorpro/sshcollectorpro/pkg/logger"
+	"github.com/sshcollectorpro/sshcollectorpro/simulate"
+)
+
+// SimulateRecordOptions 采集时将命令回显录制到模拟器目录（需开启 server.simulate_record.enable）
+type SimulateRecordOptions struct {
+	Namespace  string `json:"namespace,omitempty"`   // 默认 default
+	DeviceName string `json:"device_name,omitempty"` // 默认取请求 device_name，为空时由 device_ip 生成
+}
+
+// recordSecretModifiers 口令前可能出现的加密类型/算法修饰（如 7、md5、irreversible-cipher），跳过后替换其后的值
+const recordSecretModifiers = `(?:(?:\d+|md5|sha\S*|hmac-\S+|cipher|irreversible-cipher|simple|plain|encrypted|hash)\s+)*`
+
+// recordRedactPatterns 内置脱敏规则：第 1 组为保留的关键字前缀，其后的口令/密文替换为 ******
+var recordRedactPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`(?i)\b((?:password|secret)\s+` + recordSecretModifiers + `)\S+`),
+	regexp.MustCompile(`(?i)\b((?:irreversible-)?cipher\s+)\S+`),
+	regexp.MustCompile(`(?i)\b(community\s+(?:(?:read|write)\s+)?` + recordSecretModifiers + `)\S+`),
+	regexp.MustCompile(`(?i)\b((?:pre-shared-key|key-string|authentication-key|shared-key)\s+` + recordSecretModifiers + `)\S+`),
+	regexp.MustCompile(`(?i)\b((?:tacacs-server|radius-server)\s+key\s+` + recordSecretModifiers + `)\S+`),
+	regexp.MustCompile(`(?i)\b(md5\s+` + recordSecretModifiers + `)\S+`),
+}
+
+// SanitizeRecordedOutput 录制前脱敏：替换登录/提权口令原文，再按内置与配置规则替换配置中的口令与密文
+func SanitizeRecordedOutput(out string, secrets []string, extra []*regexp.Regexp) string {
+	for _, sec := range secrets {
+		if len(sec) >= 3 {
+			out = strings.ReplaceAll(out, sec, redactedValue)
+		}
+	}
+	for _, re := range append(append([]*regexp.Regexp(nil), recordRedactPatterns...), extra...) {
+		if re.NumSubexp() > 0 {
+			out = re.ReplaceAllString(out, "${1}"+redactedValue)
+		} else {
+			out = re.ReplaceAllString(out, redactedValue)
+		}
+	}
+	return out
+}
+
+// simulateRecordTarget 录制目标命名空间与设备名（已校验）
+func simulateRecordTarget(cfg *config.Config, request *CollectRequest) (string, string, error) {
+	opt := request.SimulateRecord
+	if cfg == nil || !cfg.Server.SimulateRecord.Enable {
+		return "", "", validationErrorf("simulate_record is set but server.simulate_record.enable is false")
+	}
+	if request.CollectProtocol != "ssh" {
+		return "", "", validationErrorf("simulate_record requires collect_protocol ssh")
+	}
+	ns := strings.TrimSpace(opt.Namespace)
+	if ns == "" {
+		ns = "default"
+	}
+	dev := strings.TrimSpace(opt.DeviceName)
+	if dev == "" {
+		dev = strings.TrimSpace(request.DeviceName)
+	}
+	if dev == "" {
+		dev = strings.NewReplacer(".", "-", ":", "-").Replace(strings.TrimSpace(request.DeviceIP))
+	}
+	if !simulate.ValidRecordName(ns) || !simulate.ValidRecordName(dev) {
+		return "", "", validationErrorf("simulate_record namespace and device_name may only contain letters, digits, '.', '_' and '-'")
+	}
+	return ns, dev, nil
+}
+
+// recordSimulate 将成功命令的回显脱敏后写入模拟器目录；失败仅记录告警，不影响采集结果
+func (s *CollectorService) recordSimulate(ctx context.Context, cfg *config.Config, request *CollectRequest, results []*CommandResultView) {
+	ns, dev, err := simulateRecordTarget(cfg, request)
+	if err != nil {
+		return
+	}
+	var extra []*regexp.Regexp
+	for _, p := range cfg.Server.SimulateRecord.RedactPatterns {
+		if re, err := regexp.Compile(p); err == nil {
+			extra = append(extra, re)
+		}
+	}
+	secrets := []string{request.Password, request.EnablePassword}
+	cmds := make([]simulate.RecordedCommand, 0, len(results))
+	for _, v := range results {
+		if v == nil || v.Error != "" {
+			continue
+		}
+		cmds = append(cmds, simulate.RecordedCommand{Command: v.Command, Output: SanitizeRecordedOutput(v.RawOutput, secrets, extra)})
+	}
+	res, err := simulate.RecordOutputs(ns, dev, cmds)
+	if err != nil {
+		logger.Warn("Simulate record failed", "task_id", request.TaskID, "namespace", ns, "device", dev, "error", err)
+		AddWarning(ctx, Warning{
+			Code:    WarnSimulateRecordFailed,
+			Message: "failed to record outputs into simulate directory",
+			Context: map[string]interface{}{"device_ip": request.DeviceIP, "namespace": ns, "device_name": dev, "error": err.Error()},
+		})
+		return
+	}
+	logger.Info("Simulate outputs recorded", "task_id", request.TaskID, "dir", res.Dir, "files", len(res.Files), "scenarios", len(res.Scenarios))
+}
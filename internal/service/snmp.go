@@ -0,0 +1,258 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sshcollectorpro/sshcollectorpro/internal/config"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/snmp"
+)
+
+// SNMPOptions collect_protocol=snmp 的凭据与版本
+type SNMPOptions struct {
+	Version      string `json:"version,omitempty"`       // v2c（默认）| v1 | v3
+	Community    string `json:"community,omitempty"`     // v1/v2c
+	User         string `json:"user,omitempty"`          // v3
+	AuthProtocol string `json:"auth_protocol,omitempty"` // md5 | sha | sha256
+	AuthPassword string `json:"auth_password,omitempty"`
+	PrivProtocol string `json:"priv_protocol,omitempty"` // des | aes
+	PrivPassword string `json:"priv_password,omitempty"`
+	ContextName  string `json:"context_name,omitempty"`
+}
+
+// builtinSNMPOIDSets 内置 OID 集合：系统信息与接口表
+var builtinSNMPOIDSets = map[string][]config.SNMPOIDConfig{
+	"system": {
+		{Name: "sysDescr", OID: "1.3.6.1.2.1.1.1.0"},
+		{Name: "sysObjectID", OID: "1.3.6.1.2.1.1.2.0"},
+		{Name: "sysUpTime", OID: "1.3.6.1.2.1.1.3.0"},
+		{Name: "sysContact", OID: "1.3.6.1.2.1.1.4.0"},
+		{Name: "sysName", OID: "1.3.6.1.2.1.1.5.0"},
+		{Name: "sysLocation", OID: "1.3.6.1.2.1.1.6.0"},
+	},
+	"interfaces": {
+		{Name: "ifDescr", OID: "1.3.6.1.2.1.2.2.1.2", Walk: true},
+		{Name: "ifName", OID: "1.3.6.1.2.1.31.1.1.1.1", Walk: true},
+		{Name: "ifAdminStatus", OID: "1.3.6.1.2.1.2.2.1.7", Walk: true},
+		{Name: "ifOperStatus", OID: "1.3.6.1.2.1.2.2.1.8", Walk: true},
+		{Name: "ifHCInOctets", OID: "1.3.6.1.2.1.31.1.1.1.6", Walk: true},
+		{Name: "ifHCOutOctets", OID: "1.3.6.1.2.1.31.1.1.1.10", Walk: true},
+		{Name: "ifInErrors", OID: "1.3.6.1.2.1.2.2.1.14", Walk: true},
+		{Name: "ifOutErrors", OID: "1.3.6.1.2.1.2.2.1.20", Walk: true},
+	},
+}
+
+// snmpOIDSet 按名称查找 OID 集合：配置 snmp.oid_sets 优先于内置
+func snmpOIDSet(cfg *config.Config, name string) ([]config.SNMPOIDConfig, bool) {
+	key := strings.ToLower(strings.TrimSpace(name))
+	if cfg != nil {
+		for k, v := range cfg.SNMP.OIDSets {
+			if strings.ToLower(k) == key {
+				return v, true
+			}
+		}
+	}
+	set, ok := builtinSNMPOIDSets[key]
+	return set, ok
+}
+
+// snmpCommandItems 解析一条采集命令：OID 集合名、get <oid>...、walk <oid>
+func snmpCommandItems(cfg *config.Config, cmd string) ([]config.SNMPOIDConfig, error) {
+	fields := strings.Fields(cmd)
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("empty snmp command")
+	}
+	switch strings.ToLower(fields[0]) {
+	case "get", "walk":
+		if len(fields) < 2 || (strings.ToLower(fields[0]) == "walk" && len(fields) != 2) {
+			return nil, fmt.Errorf("usage: get <oid> [<oid>...] | walk <oid>")
+		}
+		items := make([]config.SNMPOIDConfig, 0, len(fields)-1)
+		for _, o := range fields[1:] {
+			if _, err := snmp.ParseOID(o); err != nil {
+				return nil, err
+			}
+			oid := strings.TrimPrefix(o, ".")
+			items = append(items, config.SNMPOIDConfig{Name: oid, OID: oid, Walk: strings.ToLower(fields[0]) == "walk"})
+		}
+		return items, nil
+	}
+	if len(fields) != 1 {
+		return nil, fmt.Errorf("unknown snmp command %q", cmd)
+	}
+	set, ok := snmpOIDSet(cfg, fields[0])
+	if !ok {
+		return nil, fmt.Errorf("unknown snmp oid set %q", fields[0])
+	}
+	for _, it := range set {
+		if _, err := snmp.ParseOID(it.OID); err != nil {
+			return nil, fmt.Errorf("oid set %s: %w", fields[0], err)
+		}
+	}
+	return set, nil
+}
+
+// snmpDefaultCommands 未指定 cli_list 时按平台 snmp_oid_sets 采集，默认 system
+func snmpDefaultCommands(cfg *config.Config, platform string) []string {
+	if cfg != nil {
+		if dd, ok := cfg.Collector.DeviceDefaults[strings.ToLower(strings.TrimSpace(platform))]; ok && len(dd.SNMPOIDSets) > 0 {
+			return append([]string(nil), dd.SNMPOIDSets...)
+		}
+	}
+	return []string{"system"}
+}
+
+// snmpPort 请求端口优先，其次 snmp.port，默认 161
+func snmpPort(cfg *config.Config, port int) int {
+	if port > 0 && port <= 65535 {
+		return port
+	}
+	if cfg != nil && cfg.SNMP.Port > 0 && cfg.SNMP.Port <= 65535 {
+		return cfg.SNMP.Port
+	}
+	return 161
+}
+
+func (o *SNMPOptions) clientConfig(cfg *config.Config, target string, port int) snmp.Config {
+	c := snmp.Config{Target: target, Port: port}
+	if o != nil {
+		c.Version, c.Community, c.User = o.Version, o.Community, o.User
+		c.AuthProtocol, c.AuthPassword = o.AuthProtocol, o.AuthPassword
+		c.PrivProtocol, c.PrivPassword, c.ContextName = o.PrivProtocol, o.PrivPassword, o.ContextName
+	}
+	if cfg != nil {
+		c.Timeout, c.Retries, c.MaxRepetitions = cfg.SNMP.Timeout, cfg.SNMP.Retries, cfg.SNMP.MaxRepetitions
+	}
+	return c
+}
+
+// validateSNMPRequest 校验凭据与命令
+func validateSNMPRequest(cfg *config.Config, request *CollectRequest) error {
+	c := request.SNMP.clientConfig(cfg, request.DeviceIP, 0)
+	if err := c.Validate(); err != nil {
+		return validationErrorf("%v", err)
+	}
+	for _, it := range request.CliList {
+		if _, err := snmpCommandItems(cfg, it.CLI); err != nil {
+			return validationErrorf("invalid snmp command %q: %v", it.CLI, err)
+		}
+	}
+	return nil
+}
+
+// executeSNMPCollection 每条命令（OID 集合或 get/walk）生成一条结果：
+// raw_output 为 "名称 OID = 值" 文本，format_output 为行：标量合为一行，表列按索引合并为多行
+// 设备返回 error-status 记录为命令错误；超时等传输错误中止任务
+func (s *CollectorService) executeSNMPCollection(ctx context.Context, request *CollectRequest, commands []string, port int) ([]*CommandResultView, error) {
+	s.logTaskInfo(request.TaskID, fmt.Sprintf("Starting SNMP collection for %s:%d", request.DeviceIP, port))
+	client, err := snmp.Dial(ctx, request.SNMP.clientConfig(s.conf(), request.DeviceIP, port))
+	if err != nil {
+		return nil, err
+	}
+	defer client.Close()
+
+	out := make([]*CommandResultView, 0, len(commands))
+	for _, cmd := range commands {
+		items, err := snmpCommandItems(s.conf(), cmd)
+		if err != nil {
+			return nil, err
+		}
+		start := time.Now()
+		view := &CommandResultView{Command: strings.TrimSpace(cmd)}
+		var lines []string
+		scalar := map[string]interface{}{}
+		table := map[string]map[string]interface{}{}
+		var scalarOIDs []string
+		var scalarItems []config.SNMPOIDConfig
+		for _, it := range items {
+			if !it.Walk {
+				scalarOIDs = append(scalarOIDs, strings.TrimPrefix(it.OID, "."))
+				scalarItems = append(scalarItems, it)
+			}
+		}
+		if len(scalarOIDs) > 0 {
+			vars, gerr := client.Get(ctx, scalarOIDs...)
+			if gerr != nil {
+				err = gerr
+			}
+			for i, v := range vars {
+				if i >= len(scalarItems) {
+					break
+				}
+				lines = append(lines, fmt.Sprintf("%s %s = %s", scalarItems[i].Name, v.OID, v.String()))
+				if v.Exists() {
+					scalar[scalarItems[i].Name] = v.Value
+				}
+			}
+		}
+		for _, it := range items {
+			if !it.Walk || err != nil {
+				continue
+			}
+			vars, werr := client.Walk(ctx, it.OID)
+			if werr != nil {
+				err = werr
+			}
+			for _, v := range vars {
+				idx := snmp.IndexOf(v.OID, it.OID)
+				lines = append(lines, fmt.Sprintf("%s %s = %s", it.Name, v.OID, v.String()))
+				row, ok := table[idx]
+				if !ok {
+					row = map[string]interface{}{"index": idx}
+					table[idx] = row
+				}
+				row[it.Name] = v.Value
+			}
+		}
+		var statusErr *snmp.StatusError
+		if err != nil && !errors.As(err, &statusErr) {
+			return nil, fmt.Errorf("snmp %q: %w", view.Command, err)
+		}
+		if statusErr != nil {
+			view.Error = statusErr.Error()
+			view.ExitCode = 1
+		}
+		view.RawOutput = strings.Join(lines, "\n")
+		view.FormatOutput = snmpRows(scalar, table)
+		view.DurationMS = time.Since(start).Milliseconds()
+		out = append(out, view)
+	}
+	s.logTaskInfo(request.TaskID, fmt.Sprintf("SNMP collection completed, executed %d commands", len(out)))
+	return out, nil
+}
+
+// snmpRows 标量一行在前，表行按索引排序
+func snmpRows(scalar map[string]interface{}, table map[string]map[string]interface{}) []map[string]interface{} {
+	rows := make([]map[string]interface{}, 0, len(table)+1)
+	if len(scalar) > 0 {
+		rows = append(rows, scalar)
+	}
+	idx := make([]string, 0, len(table))
+	for k := range table {
+		idx = append(idx, k)
+	}
+	sort.Slice(idx, func(i, j int) bool { return snmpIndexLess(idx[i], idx[j]) })
+	for _, k := range idx {
+		rows = append(rows, table[k])
+	}
+	return rows
+}
+
+// snmpIndexLess 按数字逐段比较索引
+func snmpIndexLess(a, b string) bool {
+	pa, pb := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(pa) && i < len(pb); i++ {
+		if pa[i] == pb[i] {
+			continue
+		}
+		if len(pa[i]) != len(pb[i]) {
+			return len(pa[i]) < len(pb[i])
+		}
+		return pa[i] < pb[i]
+	}
+	return len(pa) < len(pb)
+}
@@ -0,0 +1,208 @@
+package service
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sshcollectorpro/sshcollectorpro/internal/config"
+	"github.com/sshcollectorpro/sshcollectorpro/pkg/logger"
+	"github.com/sshcollectorpro/sshcollectorpro/pkg/ssh"
+)
+
+// 连接池登记表：供管理接口统一查看与驱逐
+var (
+	poolsMu    sync.Mutex
+	pools      = make(map[string]*ssh.Pool)
+	sharedPool *ssh.Pool
+)
+
+// newServicePool 为服务创建 SSH 连接池
+// 开启 ssh.connection_cache 时各服务共用同一个持久连接池，已认证连接按设备跨请求复用；
+// 否则每个服务独立建池（原有行为）
+func newServicePool(cfg *config.Config, name string) *ssh.Pool {
+	poolsMu.Lock()
+	defer poolsMu.Unlock()
+
+	if cfg.SSH.ConnectionCache.Enable {
+		if sharedPool == nil {
+			sharedPool = ssh.NewPool(servicePoolConfig(cfg, true))
+		}
+		pools[name] = sharedPool
+		return sharedPool
+	}
+
+	pool := ssh.NewPool(servicePoolConfig(cfg, false))
+	pools[name] = pool
+	return pool
+}
+
+// closeServicePool 服务停止时关闭自有连接池；共用池归进程所有，由 CloseSharedSSHPool 在退出时关闭一次
+func closeServicePool(pool *ssh.Pool) error {
+	if pool == nil {
+		return nil
+	}
+	poolsMu.Lock()
+	shared := pool == sharedPool
+	poolsMu.Unlock()
+	if shared {
+		return nil
+	}
+	return pool.Close()
+}
+
+// CloseSharedSSHPool 关闭各服务共用的持久连接池（进程退出、各服务停止后调用）
+func CloseSharedSSHPool() error {
+	poolsMu.Lock()
+	p := sharedPool
+	sharedPool = nil
+	for name, sp := range pools {
+		if p != nil && sp == p {
+			delete(pools, name)
+		}
+	}
+	poolsMu.Unlock()
+	if p == nil {
+		return nil
+	}
+	return p.Close()
+}
+
+// servicePoolConfig 按配置计算连接池参数；shared 为各服务共用的持久连接池
+func servicePoolConfig(cfg *config.Config, shared bool) *ssh.PoolConfig {
+	conc := cfg.Collector.Concurrent
+	if conc <= 0 {
+		conc = 1
+	}
+	threads := cfg.Collector.Threads
+	if threads <= 0 {
+		threads = cfg.SSH.MaxSessions
+	}
+	sshCfg := &ssh.Config{
+		Timeout:        cfg.SSH.Timeout,
+		ConnectTimeout: cfg.SSH.ConnectTimeout,
+		KeepAlive:      cfg.SSH.KeepAliveInterval,
+		MaxSessions:    threads,
+		Proxy:          sshProxyConfig(cfg.SSH.Proxy),
+		Egress:         sshEgressPolicy(cfg.SSH.Egress),
+		HostKey:        sshHostKeyPolicy(cfg.SSH.HostKey),
+	}
+	pc := &ssh.PoolConfig{
+		MaxIdle:         10,
+		MaxActive:       conc,
+		IdleTimeout:     5 * time.Minute,
+		CleanupInterval: cfg.SSH.CleanupInterval,
+		SSHConfig:       sshCfg,
+		MaxIdlePerHost:  cfg.SSH.Pool.MaxIdlePerHost,
+		IdleRules:       sshIdleRules(cfg.SSH.Pool.IdleRules),
+		// 最大存活时长对共用池同样生效
+		MaxConnectionAge: cfg.SSH.Pool.MaxConnectionAge,
+	}
+	if shared {
+		cache := cfg.SSH.ConnectionCache
+		pc.IdleTimeout = cache.TTL
+		if pc.IdleTimeout <= 0 {
+			pc.IdleTimeout = 10 * time.Minute
+		}
+		pc.MaxIdle = cache.MaxConnections
+		if pc.MaxIdle <= 0 {
+			pc.MaxIdle = 100
+		}
+		// 采集/备份/格式化共用，活跃上限按三类服务并发之和
+		pc.MaxActive = conc * 3
+	}
+	return pc
+}
+
+// reconfigureServicePool 配置热更新后调整服务连接池参数；ssh.connection_cache.enable 的切换需重启生效
+func reconfigureServicePool(cfg *config.Config, pool *ssh.Pool) {
+	if pool == nil {
+		return
+	}
+	poolsMu.Lock()
+	shared := pool == sharedPool
+	poolsMu.Unlock()
+	if shared != cfg.SSH.ConnectionCache.Enable {
+		logger.Warn("ssh.connection_cache.enable changed; restart required to take effect")
+	}
+	pool.Reconfigure(servicePoolConfig(cfg, shared))
+}
+
+// sshIdleRules 转换 ssh.pool.idle_rules
+func sshIdleRules(rules []config.SSHIdleRuleConfig) []ssh.IdleRule {
+	out := make([]ssh.IdleRule, 0, len(rules))
+	for _, r := range rules {
+		out = append(out, ssh.IdleRule{Platform: r.Platform, Match: r.Match, MaxIdle: r.MaxIdle, IdleTimeout: r.IdleTimeout})
+	}
+	return out
+}
+
+// sshProxyConfig 转换 ssh.proxy 配置；地址无效时记录告警，连接时返回错误
+func sshProxyConfig(pc config.SSHProxyConfig) *ssh.ProxyConfig {
+	if strings.TrimSpace(pc.URL) == "" && len(pc.Overrides) == 0 {
+		return nil
+	}
+	out := &ssh.ProxyConfig{URL: strings.TrimSpace(pc.URL)}
+	if err := ssh.ValidateProxyURL(out.URL); err != nil {
+		logger.Warn("Invalid ssh.proxy.url", "error", err)
+	}
+	for _, o := range pc.Overrides {
+		if err := ssh.ValidateProxyURL(strings.TrimSpace(o.URL)); err != nil {
+			logger.Warn("Invalid ssh.proxy.overrides url", "match", o.Match, "error", err)
+		}
+		out.Overrides = append(out.Overrides, ssh.ProxyOverride{Match: o.Match, URL: o.URL})
+	}
+	return out
+}
+
+// SSHPoolInfo 连接池概览
+type SSHPoolInfo struct {
+	Services    []string                 `json:"services"`
+	Shared      bool                     `json:"shared"`
+	Stats       map[string]interface{}   `json:"stats"`
+	Connections []ssh.ConnectionSnapshot `json:"connections"`
+}
+
+// SSHPoolsSnapshot 返回所有已登记连接池的统计与连接列表（共用池只列一次）
+func SSHPoolsSnapshot() []SSHPoolInfo {
+	poolsMu.Lock()
+	defer poolsMu.Unlock()
+
+	idx := make(map[*ssh.Pool]int)
+	out := make([]SSHPoolInfo, 0, len(pools))
+	for _, name := range []string{"collector", "backup", "format"} {
+		p, ok := pools[name]
+		if !ok {
+			continue
+		}
+		if i, seen := idx[p]; seen {
+			out[i].Services = append(out[i].Services, name)
+			continue
+		}
+		idx[p] = len(out)
+		out = append(out, SSHPoolInfo{
+			Services:    []string{name},
+			Shared:      p == sharedPool,
+			Stats:       p.GetStats(),
+			Connections: p.Snapshot(),
+		})
+	}
+	return out
+}
+
+// EvictSSHConnections 在所有连接池中驱逐匹配的连接，返回关闭数量
+func EvictSSHConnections(host string, port int, force bool) int {
+	poolsMu.Lock()
+	defer poolsMu.Unlock()
+
+	seen := make(map[*ssh.Pool]bool)
+	n := 0
+	for _, p := range pools {
+		if seen[p] {
+			continue
+		}
+		seen[p] = true
+		n += p.Evict(host, port, force)
+	}
+	return n
+}
@@ -0,0 +1,316 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	minio "github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/config"
+	"github.com/sshcollectorpro/sshcollectorpro/pkg/logger"
+)
+
+// azureAPIVersion Blob REST API 版本
+const azureAPIVersion = "2021-08-06"
+
+// IsSupportedStorageBackend 校验存储后端名称（空值表示使用配置默认值）
+func IsSupportedStorageBackend(backend string) bool {
+	switch strings.ToLower(strings.TrimSpace(backend)) {
+	case "", "local", "minio", "s3", "azure":
+		return true
+	}
+	return false
+}
+
+// storageObjectName 对象存储键：{prefix}/{local.prefix}/{save_dir}/{device}/{date_time}/{task_id}/{command}.txt
+func storageObjectName(cfg *config.Config, meta StorageMeta) string {
+	parts := []string{}
+	if p := strings.TrimSpace(cfg.Backup.Prefix); p != "" {
+		parts = append(parts, p)
+	}
+	if p := strings.TrimSpace(cfg.Backup.Local.Prefix); p != "" {
+		parts = append(parts, p)
+	}
+	if sd := strings.TrimSpace(meta.SaveDir); sd != "" {
+		parts = append(parts, sd)
+	}
+	parts = append(parts, deviceDirParts(cfg, meta)...)
+
+	// 文件名：命令 slug 或显式文件名（与本地规则一致）
+	return path.Join(strings.Join(parts, "/"), storageFileName(cfg, meta))
+}
+
+// initS3Writer 初始化通用 S3 写入器；未配置 bucket 时返回 nil。
+// 与 MinIO 后端共用 minio-go 客户端（SigV4、虚拟主机/路径风格、AWS 凭证链均已支持），
+// 不额外引入 aws-sdk-go-v2，避免同一协议维护两套依赖与重试逻辑。
+func initS3Writer(cfg *config.Config) *MinioStorageWriter {
+	sc := cfg.Storage.S3
+	bucket := strings.TrimSpace(sc.Bucket)
+	if bucket == "" {
+		return nil
+	}
+	endpoint := strings.TrimSpace(sc.Endpoint)
+	if endpoint == "" {
+		endpoint = "s3.amazonaws.com"
+	}
+	// 连通性探测地址需带端口
+	dialAddr := endpoint
+	if _, _, err := net.SplitHostPort(endpoint); err != nil {
+		if sc.Secure {
+			dialAddr = endpoint + ":443"
+		} else {
+			dialAddr = endpoint + ":80"
+		}
+	}
+
+	creds := credentials.NewStaticV4(sc.AccessKey, sc.SecretKey, sc.SessionToken)
+	if strings.TrimSpace(sc.AccessKey) == "" {
+		creds = credentials.NewChainCredentials([]credentials.Provider{
+			&credentials.EnvAWS{},
+			&credentials.FileAWSCredentials{},
+			&credentials.IAM{Client: &http.Client{Timeout: 5 * time.Second}},
+		})
+	}
+	lookup := minio.BucketLookupAuto
+	if sc.PathStyle {
+		lookup = minio.BucketLookupPath
+	}
+	transport := &http.Transport{
+		DialContext:           (&net.Dialer{Timeout: 5 * time.Second, KeepAlive: 30 * time.Second}).DialContext,
+		TLSHandshakeTimeout:   5 * time.Second,
+		ResponseHeaderTimeout: 30 * time.Second,
+		ExpectContinueTimeout: 5 * time.Second,
+		IdleConnTimeout:       90 * time.Second,
+		MaxIdleConns:          100,
+		MaxIdleConnsPerHost:   100,
+	}
+	client, err := minio.New(endpoint, &minio.Options{
+		Creds:        creds,
+		Secure:       sc.Secure,
+		Region:       strings.TrimSpace(sc.Region),
+		BucketLookup: lookup,
+		Transport:    transport,
+	})
+	if err != nil {
+		logger.Error("S3 client initialization failed", "error", err)
+		return nil
+	}
+	return &MinioStorageWriter{provider: config.ProviderFor(cfg), client: client, endpoint: dialAddr, bucket: bucket, region: strings.TrimSpace(sc.Region), scheme: "s3"}
+}
+
+// AzureBlobStorageWriter Azure Blob 写入（REST API，共享密钥或 SAS 认证）
+type AzureBlobStorageWriter struct {
+	provider  *config.Provider
+	account   string
+	key       []byte
+	sas       string
+	container string
+	endpoint  string
+	client    *http.Client
+}
+
+// initAzureBlobWriter 初始化 Azure Blob 写入器；未配置账户或容器时返回 nil
+func initAzureBlobWriter(cfg *config.Config) *AzureBlobStorageWriter {
+	ac := cfg.Storage.Azure
+	account := strings.TrimSpace(ac.AccountName)
+	container := strings.TrimSpace(ac.Container)
+	if account == "" || container == "" {
+		return nil
+	}
+	w := &AzureBlobStorageWriter{
+		provider:  config.ProviderFor(cfg),
+		account:   account,
+		sas:       strings.TrimPrefix(strings.TrimSpace(ac.SASToken), "?"),
+		container: container,
+		endpoint:  strings.TrimRight(strings.TrimSpace(ac.Endpoint), "/"),
+		client:    &http.Client{Timeout: 30 * time.Second},
+	}
+	if w.endpoint == "" {
+		w.endpoint = "https://" + account + ".blob.core.windows.net"
+	}
+	if w.sas == "" {
+		key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(ac.AccountKey))
+		if err != nil || len(key) == 0 {
+			logger.Warn("Azure Blob account_key missing or invalid and no sas_token configured")
+			return nil
+		}
+		w.key = key
+	}
+	return w
+}
+
+// Write 将内容写入 Azure Blob（BlockBlob，带重试）
+func (w *AzureBlobStorageWriter) Write(ctx context.Context, meta StorageMeta, content string, contentType string) (StoredObject, error) {
+	filtered := content
+	if !meta.SkipFilter {
+		filtered = applyPlatformLineFilter(w.conf(), meta.DevicePlatform, content)
+	}
+	objectName := storageObjectName(w.conf(), meta)
+	data := []byte(filtered)
+	ct := contentType
+	if ct == "" {
+		ct = "text/plain; charset=utf-8"
+	}
+
+	var lastErr error
+	backoff := []time.Duration{time.Second, 2 * time.Second}
+	for i := 0; ; i++ {
+		lastErr = w.putBlob(ctx, objectName, data, ct)
+		if lastErr == nil || i == len(backoff) {
+			break
+		}
+		// 退避等待可被取消，避免任务取消后仍阻塞在重试间隔
+		timer := time.NewTimer(backoff[i])
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return StoredObject{}, fmt.Errorf("azure put blob canceled: %w", ctx.Err())
+		case <-timer.C:
+		}
+	}
+	if lastErr != nil {
+		return StoredObject{}, fmt.Errorf("azure put blob failed after retries: %w", lastErr)
+	}
+
+	sum := sha256.Sum256(data)
+	return StoredObject{
+		URI:         "azure://" + path.Join(w.container, objectName),
+		Size:        int64(len(data)),
+		Checksum:    "sha256:" + hex.EncodeToString(sum[:]),
+		ContentType: ct,
+	}, nil
+}
+
+// putBlob 单次上传
+func (w *AzureBlobStorageWriter) putBlob(ctx context.Context, name string, data []byte, ct string) error {
+	req, err := w.newRequest(ctx, http.MethodPut, name, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.ContentLength = int64(len(data))
+	req.Header.Set("Content-Type", ct)
+	req.Header.Set("x-ms-blob-type", "BlockBlob")
+	w.sign(req)
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
+
+// getBlob 读取 Blob 内容（用于备份对比）
+func (w *AzureBlobStorageWriter) getBlob(ctx context.Context, name string) ([]byte, error) {
+	req, err := w.newRequest(ctx, http.MethodGet, name, nil)
+	if err != nil {
+		return nil, err
+	}
+	w.sign(req)
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		resp.Body.Close()
+		return nil, fmt.Errorf("%w: %s", ErrObjectNotFound, name)
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("azure get blob status %d", resp.StatusCode)
+	}
+	return readLimited(resp.Body, nil)
+}
+
+// newRequest 构造 Blob 请求：{endpoint}/{container}/{blob}[?sas]
+func (w *AzureBlobStorageWriter) newRequest(ctx context.Context, method, name string, body io.Reader) (*http.Request, error) {
+	segs := strings.Split(name, "/")
+	for i, s := range segs {
+		segs[i] = url.PathEscape(s)
+	}
+	u := w.endpoint + "/" + url.PathEscape(w.container) + "/" + strings.Join(segs, "/")
+	if w.sas != "" {
+		u += "?" + w.sas
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("x-ms-date", time.Now().UTC().Format(http.TimeFormat))
+	req.Header.Set("x-ms-version", azureAPIVersion)
+	return req, nil
+}
+
+// sign 共享密钥签名（SAS 认证时跳过）
+func (w *AzureBlobStorageWriter) sign(req *http.Request) {
+	if len(w.key) == 0 {
+		return
+	}
+	mac := hmac.New(sha256.New, w.key)
+	mac.Write([]byte(azureStringToSign(w.account, req)))
+	req.Header.Set("Authorization", "SharedKey "+w.account+":"+base64.StdEncoding.EncodeToString(mac.Sum(nil)))
+}
+
+// azureStringToSign 按 Shared Key 规范构造待签名串
+func azureStringToSign(account string, req *http.Request) string {
+	length := ""
+	if req.ContentLength > 0 {
+		length = strconv.FormatInt(req.ContentLength, 10)
+	}
+	h := req.Header
+	var b strings.Builder
+	b.WriteString(req.Method + "\n")
+	b.WriteString(h.Get("Content-Encoding") + "\n")
+	b.WriteString(h.Get("Content-Language") + "\n")
+	b.WriteString(length + "\n")
+	b.WriteString(h.Get("Content-MD5") + "\n")
+	b.WriteString(h.Get("Content-Type") + "\n")
+	b.WriteString("\n") // Date：使用 x-ms-date
+	b.WriteString(h.Get("If-Modified-Since") + "\n")
+	b.WriteString(h.Get("If-Match") + "\n")
+	b.WriteString(h.Get("If-None-Match") + "\n")
+	b.WriteString(h.Get("If-Unmodified-Since") + "\n")
+	b.WriteString(h.Get("Range") + "\n")
+
+	msHeaders := make([]string, 0, 4)
+	for k := range h {
+		if lk := strings.ToLower(k); strings.HasPrefix(lk, "x-ms-") {
+			msHeaders = append(msHeaders, lk)
+		}
+	}
+	sort.Strings(msHeaders)
+	for _, k := range msHeaders {
+		b.WriteString(k + ":" + strings.TrimSpace(h.Get(k)) + "\n")
+	}
+
+	b.WriteString("/" + account + req.URL.EscapedPath())
+	q := req.URL.Query()
+	keys := make([]string, 0, len(q))
+	for k := range q {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		vals := append([]string{}, q[k]...)
+		sort.Strings(vals)
+		b.WriteString("\n" + strings.ToLower(k) + ":" + strings.Join(vals, ","))
+	}
+	return b.String()
+}
@@ -0,0 +1,82 @@
+package service
+
+import (
+	"context"
+	"strings"
+
+	"github.com/sshcollectorpro/sshcollectorpro/pkg/ssh"
+)
+
+// userCommandHooks 包装流式回调，过滤内部预命令（enable、关闭分页等）的事件
+func userCommandHooks(h *ssh.StreamHooks, userCommands []string) *ssh.StreamHooks {
+	if h == nil {
+		return nil
+	}
+	idx := make(map[string]struct{}, len(userCommands))
+	for _, u := range userCommands {
+		idx[strings.ToLower(strings.TrimSpace(u))] = struct{}{}
+	}
+	isUser := func(cmd string) bool {
+		_, ok := idx[strings.ToLower(strings.TrimSpace(cmd))]
+		return ok
+	}
+	return &ssh.StreamHooks{
+		OnCommandStart: func(cmd string) {
+			if isUser(cmd) && h.OnCommandStart != nil {
+				h.OnCommandStart(cmd)
+			}
+		},
+		OnOutputLine: func(cmd, line string) {
+			if isUser(cmd) && h.OnOutputLine != nil {
+				h.OnOutputLine(cmd, line)
+			}
+		},
+		OnCommandEnd: func(res *ssh.CommandResult) {
+			if isUser(res.Command) && h.OnCommandEnd != nil {
+				h.OnCommandEnd(res)
+			}
+		},
+	}
+}
+
+// replayStream 按最终结果补发流式事件（用于非交互回退）
+func replayStream(h *ssh.StreamHooks, results []*ssh.CommandResult) {
+	if h == nil {
+		return
+	}
+	for _, r := range results {
+		if r == nil {
+			continue
+		}
+		if h.OnCommandStart != nil {
+			h.OnCommandStart(r.Command)
+		}
+		if h.OnOutputLine != nil {
+			for _, ln := range strings.Split(strings.TrimRight(r.Output, "\n"), "\n") {
+				h.OnOutputLine(r.Command, ln)
+			}
+		}
+		if h.OnCommandEnd != nil {
+			h.OnCommandEnd(r)
+		}
+	}
+}
+
+// ExecuteStream 单设备流式执行：不重试、不记录任务，输出经 hooks 实时转发
+func (s *CollectorService) ExecuteStream(ctx context.Context, request *CollectRequest, hooks *ssh.StreamHooks) ([]*ssh.CommandResult, error) {
+	taskTO, devTO := s.effectiveTimeouts(request)
+	return s.interact.Execute(ctx, &ExecRequest{
+		DeviceIP:         request.DeviceIP,
+		Port:             request.Port,
+		DeviceName:       request.DeviceName,
+		DevicePlatform:   request.DevicePlatform,
+		CollectProtocol:  request.CollectProtocol,
+		UserName:         request.UserName,
+		Password:         request.Password,
+		EnablePassword:   request.EnablePassword,
+		TaskTimeoutSec:   taskTO,
+		DeviceTimeoutSec: devTO,
+		CommandTimeouts:  request.CliList.Timeouts(),
+		Stream:           hooks,
+	}, request.CliList.Commands())
+}
@@ -0,0 +1,261 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/pmezard/go-difflib/difflib"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/model"
+)
+
+// 对比结果状态
+const (
+	CompareUnchanged  = "unchanged"
+	CompareChanged    = "changed"
+	CompareOnlyBase   = "only_base"
+	CompareOnlyTarget = "only_target"
+)
+
+// TaskCompareOptions 任务对比选项
+type TaskCompareOptions struct {
+	Context     int  // 文本差异上下文行数
+	ChangedOnly bool // 仅返回有变化的设备与命令
+}
+
+// TaskFieldChange 解析字段差异；路径形如 format_output[0].VERSION 或 fields.hostname
+type TaskFieldChange struct {
+	Path   string `json:"path"`
+	Change string `json:"change"` // added | removed | changed
+	Base   string `json:"base,omitempty"`
+	Target string `json:"target,omitempty"`
+}
+
+// CommandComparison 同一设备同一命令在两次任务中的差异
+type CommandComparison struct {
+	Command      string             `json:"command"`
+	Status       string             `json:"status"`
+	Diff         string             `json:"diff,omitempty"` // unified diff（raw_output）
+	Summary      *BackupDiffSummary `json:"summary,omitempty"`
+	FieldChanges []TaskFieldChange  `json:"field_changes,omitempty"`
+	BaseError    string             `json:"base_error,omitempty"`
+	TargetError  string             `json:"target_error,omitempty"`
+}
+
+// DeviceComparison 同一设备（ip:port）在两次任务中的差异
+type DeviceComparison struct {
+	DeviceIP     string              `json:"device_ip"`
+	DevicePort   int                 `json:"device_port"`
+	Status       string              `json:"status"`
+	BaseTaskID   string              `json:"base_task_id,omitempty"`
+	TargetTaskID string              `json:"target_task_id,omitempty"`
+	BaseStatus   string              `json:"base_status,omitempty"`
+	TargetStatus string              `json:"target_status,omitempty"`
+	Commands     []CommandComparison `json:"commands"`
+}
+
+// TaskCompareSummary 对比计数
+type TaskCompareSummary struct {
+	Devices         int `json:"devices"`
+	Changed         int `json:"changed"`
+	Unchanged       int `json:"unchanged"`
+	OnlyBase        int `json:"only_base"`
+	OnlyTarget      int `json:"only_target"`
+	CommandsChanged int `json:"commands_changed"`
+}
+
+// TaskComparison 两次任务的对比结果
+type TaskComparison struct {
+	Base    string             `json:"base"`
+	Target  string             `json:"target"`
+	Summary TaskCompareSummary `json:"summary"`
+	Devices []DeviceComparison `json:"devices"`
+}
+
+// compareResult 对比时关心的命令结果字段
+type compareResult struct {
+	Command      string              `json:"command"`
+	RawOutput    string              `json:"raw_output"`
+	FormatOutput interface{}         `json:"format_output"`
+	Fields       map[string][]string `json:"fields"`
+	Error        string              `json:"error"`
+}
+
+// CompareTasks 按设备（ip:port）与命令对齐两次任务的结果（同一设备多条任务取最后一条，tasks 按创建时间升序），
+// 返回每条命令的文本差异与解析字段差异；设备顺序按 base 出现顺序，target 新增设备在后
+func CompareTasks(baseID, targetID string, base, target []model.Task, opts TaskCompareOptions) TaskComparison {
+	if opts.Context <= 0 {
+		opts.Context = 3
+	}
+	out := TaskComparison{Base: baseID, Target: targetID, Devices: []DeviceComparison{}}
+	base, target = LatestTaskPerDevice(base), LatestTaskPerDevice(target)
+	targets := make(map[string]*model.Task, len(target))
+	for i := range target {
+		targets[fmt.Sprintf("%s:%d", target[i].DeviceIP, target[i].DevicePort)] = &target[i]
+	}
+	add := func(d DeviceComparison) {
+		out.Summary.Devices++
+		switch d.Status {
+		case CompareChanged:
+			out.Summary.Changed++
+		case CompareUnchanged:
+			out.Summary.Unchanged++
+		case CompareOnlyBase:
+			out.Summary.OnlyBase++
+		case CompareOnlyTarget:
+			out.Summary.OnlyTarget++
+		}
+		if opts.ChangedOnly && d.Status == CompareUnchanged {
+			return
+		}
+		out.Devices = append(out.Devices, d)
+	}
+	for i := range base {
+		b := &base[i]
+		key := fmt.Sprintf("%s:%d", b.DeviceIP, b.DevicePort)
+		t, ok := targets[key]
+		if !ok {
+			add(DeviceComparison{DeviceIP: b.DeviceIP, DevicePort: b.DevicePort, Status: CompareOnlyBase, BaseTaskID: b.ID, BaseStatus: b.Status, Commands: []CommandComparison{}})
+			continue
+		}
+		delete(targets, key)
+		d := compareDevice(b, t, opts)
+		for _, c := range d.Commands {
+			if c.Status != CompareUnchanged {
+				out.Summary.CommandsChanged++
+			}
+		}
+		add(d)
+	}
+	for i := range target {
+		t := &target[i]
+		if _, ok := targets[fmt.Sprintf("%s:%d", t.DeviceIP, t.DevicePort)]; ok {
+			add(DeviceComparison{DeviceIP: t.DeviceIP, DevicePort: t.DevicePort, Status: CompareOnlyTarget, TargetTaskID: t.ID, TargetStatus: t.Status, Commands: []CommandComparison{}})
+		}
+	}
+	return out
+}
+
+// compareDevice 按命令文本（忽略大小写与多余空白，重复命令按出现次序）对齐命令结果
+func compareDevice(b, t *model.Task, opts TaskCompareOptions) DeviceComparison {
+	d := DeviceComparison{DeviceIP: b.DeviceIP, DevicePort: b.DevicePort, Status: CompareUnchanged,
+		BaseTaskID: b.ID, TargetTaskID: t.ID, BaseStatus: b.Status, TargetStatus: t.Status, Commands: []CommandComparison{}}
+	bres, tres := decodeCompareResults(b.Result), decodeCompareResults(t.Result)
+	_, tIdx := compareKeys(tres)
+	matched := make([]bool, len(tres))
+	bKeys, _ := compareKeys(bres)
+	for i := range bres {
+		c := CommandComparison{Command: bres[i].Command}
+		j, ok := tIdx[bKeys[i]]
+		if !ok {
+			c.Status, c.BaseError = CompareOnlyBase, bres[i].Error
+		} else {
+			matched[j] = true
+			c = compareCommand(&bres[i], &tres[j], opts.Context)
+		}
+		if !opts.ChangedOnly || c.Status != CompareUnchanged {
+			d.Commands = append(d.Commands, c)
+		}
+		if c.Status != CompareUnchanged {
+			d.Status = CompareChanged
+		}
+	}
+	for j := range tres {
+		if !matched[j] {
+			d.Commands = append(d.Commands, CommandComparison{Command: tres[j].Command, Status: CompareOnlyTarget, TargetError: tres[j].Error})
+			d.Status = CompareChanged
+		}
+	}
+	if b.Status != t.Status {
+		d.Status = CompareChanged
+	}
+	return d
+}
+
+func decodeCompareResults(s string) []compareResult {
+	var out []compareResult
+	if strings.TrimSpace(s) != "" {
+		_ = json.Unmarshal([]byte(s), &out)
+	}
+	return out
+}
+
+// compareKeys 命令对齐键：规范化命令文本 + 出现次序
+func compareKeys(results []compareResult) ([]string, map[string]int) {
+	keys := make([]string, len(results))
+	idx := make(map[string]int, len(results))
+	seen := map[string]int{}
+	for i, r := range results {
+		norm := strings.ToLower(strings.Join(strings.Fields(r.Command), " "))
+		seen[norm]++
+		keys[i] = fmt.Sprintf("%s#%d", norm, seen[norm])
+		idx[keys[i]] = i
+	}
+	return keys, idx
+}
+
+func compareCommand(b, t *compareResult, ctxLines int) CommandComparison {
+	c := CommandComparison{Command: t.Command, Status: CompareUnchanged, BaseError: b.Error, TargetError: t.Error}
+	a, bl := diffLines(b.RawOutput, nil), diffLines(t.RawOutput, nil)
+	sum := summarizeDiff(a, bl)
+	if !sum.Identical {
+		c.Diff, _ = difflib.GetUnifiedDiffString(difflib.UnifiedDiff{A: a, B: bl, FromFile: "base", ToFile: "target", Context: ctxLines})
+		c.Summary = &sum
+	}
+	c.FieldChanges = diffFields(flattenCompareFields(b), flattenCompareFields(t))
+	if !sum.Identical || len(c.FieldChanges) > 0 || b.Error != t.Error {
+		c.Status = CompareChanged
+	}
+	return c
+}
+
+// flattenCompareFields 展开解析结果为 路径 -> 值（非标量以 JSON 表示）
+func flattenCompareFields(r *compareResult) map[string]string {
+	out := map[string]string{}
+	var walk func(path string, v interface{})
+	walk = func(path string, v interface{}) {
+		switch x := v.(type) {
+		case map[string]interface{}:
+			for k, vv := range x {
+				walk(path+"."+k, vv)
+			}
+		case []interface{}:
+			for i, vv := range x {
+				walk(fmt.Sprintf("%s[%d]", path, i), vv)
+			}
+		case nil:
+		case string:
+			out[path] = x
+		default:
+			data, _ := json.Marshal(x)
+			out[path] = string(data)
+		}
+	}
+	walk("format_output", r.FormatOutput)
+	for k, vals := range r.Fields {
+		data, _ := json.Marshal(vals)
+		out["fields."+k] = string(data)
+	}
+	return out
+}
+
+func diffFields(b, t map[string]string) []TaskFieldChange {
+	var out []TaskFieldChange
+	for p, bv := range b {
+		tv, ok := t[p]
+		switch {
+		case !ok:
+			out = append(out, TaskFieldChange{Path: p, Change: "removed", Base: bv})
+		case tv != bv:
+			out = append(out, TaskFieldChange{Path: p, Change: "changed", Base: bv, Target: tv})
+		}
+	}
+	for p, tv := range t {
+		if _, ok := b[p]; !ok {
+			out = append(out, TaskFieldChange{Path: p, Change: "added", Target: tv})
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Path < out[j].Path })
+	return out
+}
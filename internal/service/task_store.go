@@ -0,0 +1,153 @@
+package service
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/model"
+	"github.com/sshcollectorpro/sshcollectorpro/pkg/logger"
+)
+
+// TaskStore 任务记录持久化（由接口层提供数据库实现）
+type TaskStore interface {
+	// SaveTask 新建或覆盖任务记录（不覆盖已有标注）
+	SaveTask(task *model.Task) error
+	// UpdateTask 更新任务状态与结果
+	UpdateTask(task *model.Task) error
+}
+
+// TaskLogStore 任务日志持久化；TaskStore 实现该接口时写入任务日志
+type TaskLogStore interface {
+	SaveTaskLog(entry *model.TaskLog) error
+}
+
+// TaskRetentionStore 任务历史清理；TaskStore 实现该接口时按 database.task_retention 定期清理
+type TaskRetentionStore interface {
+	// PurgeTasks 删除 before 之前创建的任务与任务日志，返回删除行数
+	PurgeTasks(before time.Time) (tasks int64, logs int64, err error)
+}
+
+// TaskRecoveryStore 启动时收尾上次进程遗留的运行中任务；TaskStore 实现该接口时生效
+type TaskRecoveryStore interface {
+	// InterruptRunningTasks 将本采集器 before 之前开始、仍为运行中的任务标记为失败，返回更新行数
+	InterruptRunningTasks(collectorID string, before time.Time, reason string) (int64, error)
+}
+
+// taskInterruptedReason 进程退出时仍在运行的任务的错误信息（未保存密码，重启后无法原样重跑）
+const taskInterruptedReason = "interrupted: collector restarted before the task finished"
+
+// taskPurgeInterval 任务历史清理周期
+const taskPurgeInterval = time.Hour
+
+// SetTaskStore 注入任务记录存储；为空时不写库
+func (s *CollectorService) SetTaskStore(store TaskStore) {
+	s.taskStore = store
+}
+
+// persistTask 快速采集（collect_mode=fast）不记录任务
+func (s *CollectorService) persistTask(task *model.Task) bool {
+	if s.taskStore == nil || task == nil {
+		return false
+	}
+	var meta map[string]interface{}
+	if task.Metadata != "" && json.Unmarshal([]byte(task.Metadata), &meta) == nil {
+		if mode, _ := meta["collect_mode"].(string); strings.EqualFold(mode, "fast") {
+			return false
+		}
+	}
+	return true
+}
+
+// recoverInterruptedTasks 将上次进程遗留的运行中任务标记为失败，避免历史中永久显示 running；
+// 同一 task_id 重新下发后按原记录继续更新，已有标注保留
+func (s *CollectorService) recoverInterruptedTasks(before time.Time) {
+	store, ok := s.taskStore.(TaskRecoveryStore)
+	if !ok || s.conf() == nil {
+		return
+	}
+	n, err := store.InterruptRunningTasks(s.conf().Collector.ID, before, taskInterruptedReason)
+	if err != nil {
+		logger.Warn("Failed to mark interrupted tasks", "error", err)
+		return
+	}
+	if n > 0 {
+		logger.Info("Interrupted tasks marked failed", "count", n)
+	}
+}
+
+// saveTaskLog 写入任务日志：仅记录执行中且入库的任务（快速采集不记录）
+func (s *CollectorService) saveTaskLog(taskID, level, message string) {
+	store, ok := s.taskStore.(TaskLogStore)
+	if !ok {
+		return
+	}
+	s.mutex.RLock()
+	taskCtx := s.tasks[taskID]
+	s.mutex.RUnlock()
+	if taskCtx == nil || !s.persistTask(taskCtx.Task) {
+		return
+	}
+	entry := &model.TaskLog{ID: uuid.NewString(), TaskID: taskID, Level: level, Message: message, CreatedAt: time.Now()}
+	if err := store.SaveTaskLog(entry); err != nil {
+		logger.Warn("Failed to save task log", "task_id", taskID, "error", err)
+	}
+}
+
+// purgeTaskHistory 删除超过保留时长的任务记录与任务日志
+func (s *CollectorService) purgeTaskHistory() {
+	store, ok := s.taskStore.(TaskRetentionStore)
+	if !ok || s.conf() == nil || s.conf().Database.TaskRetention <= 0 || !IsLeader() {
+		return
+	}
+	before := time.Now().Add(-s.conf().Database.TaskRetention)
+	tasks, logs, err := store.PurgeTasks(before)
+	if err != nil {
+		logger.Warn("Failed to purge task history", "before", before.Format(time.RFC3339), "error", err)
+		return
+	}
+	if tasks > 0 || logs > 0 {
+		logger.Info("Task history purged", "before", before.Format(time.RFC3339), "tasks", tasks, "logs", logs)
+	}
+}
+
+// taskRecord 写库副本：不落盘设备密码
+func taskRecord(task *model.Task) *model.Task {
+	cp := *task
+	cp.Password = ""
+	return &cp
+}
+
+// encodeTaskMetadata 请求 metadata 序列化为 JSON 文本，空值返回空串
+func encodeTaskMetadata(meta map[string]interface{}) string {
+	if len(meta) == 0 {
+		return ""
+	}
+	b, err := json.Marshal(meta)
+	if err != nil {
+		return ""
+	}
+	return string(b)
+}
+
+// taskMetadataWithPlatform 任务记录附带 device_platform，供按平台检索历史输出（如模板影响评估）
+func taskMetadataWithPlatform(meta map[string]interface{}, platform string) map[string]interface{} {
+	platform = strings.ToLower(strings.TrimSpace(platform))
+	if platform == "" {
+		return meta
+	}
+	return MergeTaskMetadata(meta, map[string]interface{}{"device_platform": platform})
+}
+
+// MergeTaskMetadata 合并调用方 metadata 与内部字段（内部字段优先，避免覆盖 collect_mode 等）
+func MergeTaskMetadata(user, internal map[string]interface{}) map[string]interface{} {
+	out := make(map[string]interface{}, len(user)+len(internal))
+	for k, v := range user {
+		out[k] = v
+	}
+	for k, v := range internal {
+		out[k] = v
+	}
+	return out
+}
@@ -0,0 +1,196 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/sshcollectorpro/sshcollectorpro/internal/model"
+)
+
+// 错误类别（任务摘要按类别计数）
+const (
+	ErrorClassAuth        = "auth_failed"
+	ErrorClassLogin       = "login_failed"
+	ErrorClassTimeout     = "timeout"
+	ErrorClassUnreachable = "unreachable"
+	ErrorClassPrompt      = "prompt_interrupted"
+	ErrorClassCancelled   = "cancelled"
+	ErrorClassCommand     = "command_failed"
+	ErrorClassOther       = "other"
+)
+
+// ClassifyErrorMessage 按持久化的错误文本归类（任务记录只保存文本，无法使用 errors.Is）
+func ClassifyErrorMessage(msg string) string {
+	m := strings.ToLower(msg)
+	switch {
+	case m == "":
+		return ""
+	case strings.Contains(m, "unable to authenticate"), strings.Contains(m, "permission denied"), strings.Contains(m, "authentication failed"):
+		return ErrorClassAuth
+	case strings.Contains(m, "设备登陆失败"):
+		return ErrorClassLogin
+	case strings.Contains(m, "interrupted_prompt"):
+		return ErrorClassPrompt
+	case strings.Contains(m, "context canceled"), strings.Contains(m, "cancelled"):
+		return ErrorClassCancelled
+	case strings.Contains(m, "timeout"), strings.Contains(m, "timed out"), strings.Contains(m, "deadline exceeded"), strings.Contains(m, "超时"):
+		return ErrorClassTimeout
+	case strings.Contains(m, "connection refused"), strings.Contains(m, "no route to host"), strings.Contains(m, "network is unreachable"),
+		strings.Contains(m, "failed to create ssh connection"), strings.Contains(m, "unreachable"):
+		return ErrorClassUnreachable
+	default:
+		return ErrorClassOther
+	}
+}
+
+// TaskFailure 失败设备
+type TaskFailure struct {
+	TaskID     string `json:"task_id"`
+	DeviceIP   string `json:"device_ip"`
+	DevicePort int    `json:"device_port"`
+	Status     string `json:"status"`
+	ErrorClass string `json:"error_class"`
+	Error      string `json:"error"`
+}
+
+// DeviceDuration 设备执行耗时
+type DeviceDuration struct {
+	TaskID     string `json:"task_id"`
+	DeviceIP   string `json:"device_ip"`
+	DurationMS int64  `json:"duration_ms"`
+}
+
+// CommandFailureCount 命令失败次数
+type CommandFailureCount struct {
+	Command  string `json:"command"`
+	Failures int    `json:"failures"`
+}
+
+// TaskSummary 批量任务汇总：状态与错误类别计数、失败列表、最慢设备、存储量与命令失败排行
+type TaskSummary struct {
+	TaskID          string                `json:"task_id"`
+	Total           int                   `json:"total"`
+	Succeeded       int                   `json:"succeeded"`
+	Failed          int                   `json:"failed"`
+	StatusCounts    map[string]int        `json:"status_counts"`
+	ErrorClasses    map[string]int        `json:"error_classes"`
+	Failures        []TaskFailure         `json:"failures"`
+	SlowestDevices  []DeviceDuration      `json:"slowest_devices"`
+	StoredObjects   int                   `json:"stored_objects"`
+	StoredBytes     int64                 `json:"stored_bytes"`
+	CommandFailures []CommandFailureCount `json:"command_failures"`
+	TotalDurationMS int64                 `json:"total_duration_ms"`
+}
+
+// summaryResult 解析任务结果时只关心的字段
+type summaryResult struct {
+	Command       string `json:"command"`
+	Error         string `json:"error"`
+	StoredObjects []struct {
+		Size int64 `json:"size"`
+	} `json:"stored_objects"`
+}
+
+// LatestTaskPerDevice 每台设备（ip:port）仅保留最后一条任务（tasks 按创建时间升序），保持设备首次出现的顺序
+func LatestTaskPerDevice(tasks []model.Task) []model.Task {
+	idx := make(map[string]int, len(tasks))
+	out := make([]model.Task, 0, len(tasks))
+	for _, t := range tasks {
+		key := fmt.Sprintf("%s:%d", t.DeviceIP, t.DevicePort)
+		if i, ok := idx[key]; ok {
+			out[i] = t
+			continue
+		}
+		idx[key] = len(out)
+		out = append(out, t)
+	}
+	return out
+}
+
+// SummarizeTasks 汇总同一批次的设备任务（失败列表保持 tasks 顺序）；top 限制最慢设备与命令失败排行条数
+func SummarizeTasks(taskID string, tasks []model.Task, top int) TaskSummary {
+	if top <= 0 {
+		top = 10
+	}
+	sum := TaskSummary{
+		TaskID:          taskID,
+		Total:           len(tasks),
+		StatusCounts:    map[string]int{},
+		ErrorClasses:    map[string]int{},
+		Failures:        []TaskFailure{},
+		SlowestDevices:  []DeviceDuration{},
+		CommandFailures: []CommandFailureCount{},
+	}
+	cmdFailures := map[string]int{}
+	for i := range tasks {
+		t := &tasks[i]
+		sum.StatusCounts[t.Status]++
+		sum.TotalDurationMS += t.Duration
+		sum.SlowestDevices = append(sum.SlowestDevices, DeviceDuration{TaskID: t.ID, DeviceIP: t.DeviceIP, DurationMS: t.Duration})
+
+		var results []summaryResult
+		if strings.TrimSpace(t.Result) != "" {
+			_ = json.Unmarshal([]byte(t.Result), &results)
+		}
+		for _, r := range results {
+			for _, o := range r.StoredObjects {
+				sum.StoredObjects++
+				sum.StoredBytes += o.Size
+			}
+			if r.Error != "" {
+				cmdFailures[strings.TrimSpace(r.Command)]++
+			}
+		}
+
+		switch t.Status {
+		case model.TaskStatusSuccess:
+			sum.Succeeded++
+		case model.TaskStatusFailed, model.TaskStatusTimeout, model.TaskStatusCancelled:
+			sum.Failed++
+			class := ClassifyErrorMessage(t.ErrorMsg)
+			if class == "" {
+				class = ErrorClassOther
+			}
+			if t.Status == model.TaskStatusTimeout {
+				class = ErrorClassTimeout
+			}
+			sum.ErrorClasses[class]++
+			sum.Failures = append(sum.Failures, TaskFailure{
+				TaskID: t.ID, DeviceIP: t.DeviceIP, DevicePort: t.DevicePort,
+				Status: t.Status, ErrorClass: class, Error: t.ErrorMsg,
+			})
+		}
+		// 任务成功但存在命令级错误时单独计数
+		if t.Status == model.TaskStatusSuccess {
+			for _, r := range results {
+				if r.Error != "" {
+					sum.ErrorClasses[ErrorClassCommand]++
+					break
+				}
+			}
+		}
+	}
+
+	sort.SliceStable(sum.SlowestDevices, func(i, j int) bool {
+		return sum.SlowestDevices[i].DurationMS > sum.SlowestDevices[j].DurationMS
+	})
+	if len(sum.SlowestDevices) > top {
+		sum.SlowestDevices = sum.SlowestDevices[:top]
+	}
+	for cmd, n := range cmdFailures {
+		sum.CommandFailures = append(sum.CommandFailures, CommandFailureCount{Command: cmd, Failures: n})
+	}
+	sort.Slice(sum.CommandFailures, func(i, j int) bool {
+		a, b := sum.CommandFailures[i], sum.CommandFailures[j]
+		if a.Failures != b.Failures {
+			return a.Failures > b.Failures
+		}
+		return a.Command < b.Command
+	})
+	if len(sum.CommandFailures) > top {
+		sum.CommandFailures = sum.CommandFailures[:top]
+	}
+	return sum
+}
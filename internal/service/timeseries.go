@@ -0,0 +1,286 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/klauspost/compress/snappy"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/config"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// 时序库后端
+const (
+	TimeseriesInfluxDB   = "influxdb"
+	TimeseriesPrometheus = "prometheus"
+)
+
+// MetricSelector 选择写入时序库的解析字段（按平台与命令匹配）
+type MetricSelector struct {
+	DevicePlatform string   `json:"device_platform"`
+	CLIName        string   `json:"cli_name"`
+	Measurement    string   `json:"measurement"`          // 指标名；Prometheus 下为 {measurement}_{field}
+	Fields         []string `json:"fields"`               // 数值字段（模板变量名）
+	TagFields      []string `json:"tag_fields,omitempty"` // 作为标签的字段，如 INTERFACE
+}
+
+// MetricPoint 单个时序点
+type MetricPoint struct {
+	Measurement string
+	Tags        map[string]string
+	Fields      map[string]float64
+	Time        time.Time
+}
+
+// ValidateMetricSelectors 校验指标选择：需开启时序输出且字段完整
+func ValidateMetricSelectors(cfg *config.Config, selectors []MetricSelector) error {
+	if len(selectors) == 0 {
+		return nil
+	}
+	if cfg == nil || !cfg.DataFormat.Timeseries.Enable || strings.TrimSpace(cfg.DataFormat.Timeseries.URL) == "" {
+		return validationErrorf("metrics requested but data_format.timeseries is not configured")
+	}
+	for i, s := range selectors {
+		if strings.TrimSpace(s.DevicePlatform) == "" || strings.TrimSpace(s.CLIName) == "" {
+			return validationErrorf("metrics[%d]: device_platform and cli_name are required", i)
+		}
+		if strings.TrimSpace(s.Measurement) == "" || len(s.Fields) == 0 {
+			return validationErrorf("metrics[%d]: measurement and fields are required", i)
+		}
+	}
+	return nil
+}
+
+// collectMetricPoints 从聚合结果中抽取选中的数值字段；非数值字段跳过
+func collectMetricPoints(selectors []MetricSelector, agg map[string]map[string][]FormattedItem, ts time.Time) []MetricPoint {
+	points := make([]MetricPoint, 0)
+	for _, sel := range selectors {
+		p := strings.ToLower(strings.TrimSpace(sel.DevicePlatform))
+		cli := strings.ToLower(strings.TrimSpace(sel.CLIName))
+		for _, it := range agg[p][cli] {
+			for _, rec := range parsedRecords(it.InfoFormatted) {
+				pt := MetricPoint{
+					Measurement: sel.Measurement,
+					Tags:        map[string]string{"device": it.DeviceName, "platform": p},
+					Fields:      map[string]float64{},
+					Time:        ts,
+				}
+				for _, tf := range sel.TagFields {
+					if v := strings.TrimSpace(exportCell(rec[tf])); v != "" {
+						pt.Tags[strings.ToLower(tf)] = v
+					}
+				}
+				for _, f := range sel.Fields {
+					if v, ok := metricValue(rec[f]); ok {
+						pt.Fields[strings.ToLower(f)] = v
+					}
+				}
+				if len(pt.Fields) > 0 {
+					points = append(points, pt)
+				}
+			}
+		}
+	}
+	return points
+}
+
+// metricValue 将解析值转为数值（兼容千分位逗号）
+func metricValue(v interface{}) (float64, bool) {
+	switch t := v.(type) {
+	case float64:
+		return t, true
+	case int:
+		return float64(t), true
+	case int64:
+		return float64(t), true
+	case string:
+		s := strings.ReplaceAll(strings.TrimSpace(t), ",", "")
+		if s == "" {
+			return 0, false
+		}
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+			return 0, false
+		}
+		return f, true
+	}
+	return 0, false
+}
+
+// TimeseriesWriter 时序库写入器
+type TimeseriesWriter struct {
+	cfg    config.TimeseriesConfig
+	client *http.Client
+}
+
+// NewTimeseriesWriter 创建时序库写入器；未开启时返回 nil
+func NewTimeseriesWriter(cfg *config.Config) *TimeseriesWriter {
+	if cfg == nil || !cfg.DataFormat.Timeseries.Enable {
+		return nil
+	}
+	to := cfg.DataFormat.Timeseries.Timeout
+	if to <= 0 {
+		to = 10 * time.Second
+	}
+	return &TimeseriesWriter{cfg: cfg.DataFormat.Timeseries, client: &http.Client{Timeout: to}}
+}
+
+// Write 按后端类型编码并写入
+func (w *TimeseriesWriter) Write(ctx context.Context, points []MetricPoint) error {
+	if w == nil {
+		return fmt.Errorf("timeseries writer not configured")
+	}
+	if len(points) == 0 {
+		return nil
+	}
+	var (
+		body    []byte
+		headers = map[string]string{}
+	)
+	switch strings.ToLower(strings.TrimSpace(w.cfg.Backend)) {
+	case "", TimeseriesInfluxDB:
+		body = encodeInfluxLines(points)
+		headers["Content-Type"] = "text/plain; charset=utf-8"
+		if w.cfg.Token != "" {
+			headers["Authorization"] = "Token " + w.cfg.Token
+		}
+	case TimeseriesPrometheus:
+		body = snappy.Encode(nil, encodeRemoteWrite(points))
+		headers["Content-Type"] = "application/x-protobuf"
+		headers["Content-Encoding"] = "snappy"
+		headers["X-Prometheus-Remote-Write-Version"] = "0.1.0"
+		if w.cfg.Token != "" {
+			headers["Authorization"] = "Bearer " + w.cfg.Token
+		}
+	default:
+		return fmt.Errorf("unsupported timeseries backend: %s", w.cfg.Backend)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.cfg.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	if w.cfg.Username != "" {
+		req.SetBasicAuth(w.cfg.Username, w.cfg.Password)
+	}
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("timeseries write status %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
+
+// ====== InfluxDB 行协议 ======
+
+var (
+	influxMeasurementEscaper = strings.NewReplacer(",", `\,`, " ", `\ `)
+	influxTagEscaper         = strings.NewReplacer(",", `\,`, "=", `\=`, " ", `\ `)
+)
+
+// encodeInfluxLines 编码为行协议：measurement,tag=v field=v ts(ns)
+func encodeInfluxLines(points []MetricPoint) []byte {
+	var buf bytes.Buffer
+	for _, pt := range points {
+		buf.WriteString(influxMeasurementEscaper.Replace(pt.Measurement))
+		for _, k := range sortedKeys(pt.Tags) {
+			if pt.Tags[k] == "" {
+				continue
+			}
+			buf.WriteString("," + influxTagEscaper.Replace(k) + "=" + influxTagEscaper.Replace(pt.Tags[k]))
+		}
+		buf.WriteByte(' ')
+		fields := make([]string, 0, len(pt.Fields))
+		for k, v := range pt.Fields {
+			fields = append(fields, influxTagEscaper.Replace(k)+"="+strconv.FormatFloat(v, 'f', -1, 64))
+		}
+		sort.Strings(fields)
+		buf.WriteString(strings.Join(fields, ","))
+		buf.WriteString(" " + strconv.FormatInt(pt.Time.UnixNano(), 10) + "\n")
+	}
+	return buf.Bytes()
+}
+
+// ====== Prometheus remote-write（prompb.WriteRequest 手工编码） ======
+
+var promNameSanitizer = regexp.MustCompile(`[^a-zA-Z0-9_:]`)
+
+// promName 指标/标签名规范化
+func promName(s string) string {
+	s = promNameSanitizer.ReplaceAllString(s, "_")
+	if s != "" && s[0] >= '0' && s[0] <= '9' {
+		s = "_" + s
+	}
+	return s
+}
+
+// encodeRemoteWrite 每个字段生成一条时间序列：{measurement}_{field}
+// WriteRequest{timeseries=1}; TimeSeries{labels=1, samples=2}; Label{name=1, value=2}; Sample{value=1, timestamp=2}
+func encodeRemoteWrite(points []MetricPoint) []byte {
+	var out []byte
+	for _, pt := range points {
+		for _, field := range sortedFloatKeys(pt.Fields) {
+			labels := map[string]string{"__name__": promName(pt.Measurement + "_" + field)}
+			for k, v := range pt.Tags {
+				if v != "" {
+					labels[promName(k)] = v
+				}
+			}
+			var ts []byte
+			for _, k := range sortedKeys(labels) { // remote-write 要求标签按名称排序
+				var lb []byte
+				lb = protowire.AppendTag(lb, 1, protowire.BytesType)
+				lb = protowire.AppendString(lb, k)
+				lb = protowire.AppendTag(lb, 2, protowire.BytesType)
+				lb = protowire.AppendString(lb, labels[k])
+				ts = protowire.AppendTag(ts, 1, protowire.BytesType)
+				ts = protowire.AppendBytes(ts, lb)
+			}
+			var sample []byte
+			sample = protowire.AppendTag(sample, 1, protowire.Fixed64Type)
+			sample = protowire.AppendFixed64(sample, math.Float64bits(pt.Fields[field]))
+			sample = protowire.AppendTag(sample, 2, protowire.VarintType)
+			sample = protowire.AppendVarint(sample, uint64(pt.Time.UnixMilli()))
+			ts = protowire.AppendTag(ts, 2, protowire.BytesType)
+			ts = protowire.AppendBytes(ts, sample)
+
+			out = protowire.AppendTag(out, 1, protowire.BytesType)
+			out = protowire.AppendBytes(out, ts)
+		}
+	}
+	return out
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func sortedFloatKeys(m map[string]float64) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,127 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/sshcollectorpro/sshcollectorpro/pkg/ssh"
+)
+
+// DeviceTimings 单设备执行时间线（毫秒），随设备响应的 timings 返回，用于区分设备侧耗时与采集器开销：
+// queue_wait 为等待并发名额；connect 为拨号与密钥交换（复用池内连接时为取连接耗时）；auth 为 SSH 认证；
+// commands 为会话内逐条命令耗时（含注入的预命令与清理命令）；storage_write 为结果写入存储的累计耗时
+type DeviceTimings struct {
+	QueueWaitMS      int64           `json:"queue_wait_ms"`
+	ConnectMS        int64           `json:"connect_ms"`
+	AuthMS           int64           `json:"auth_ms"`
+	ConnectionReused bool            `json:"connection_reused,omitempty"`
+	Commands         []CommandTiming `json:"commands,omitempty"`
+	StorageWriteMS   int64           `json:"storage_write_ms"`
+
+	mu sync.Mutex
+}
+
+// CommandTiming 单条命令耗时；命令文本按录制规则脱敏
+type CommandTiming struct {
+	Command    string `json:"command"`
+	DurationMS int64  `json:"duration_ms"`
+}
+
+type timingsKey struct{}
+
+// WithTimings 为设备执行上下文挂载时间线记录；嵌套调用（如下发前后的状态采集）各自独立
+func WithTimings(ctx context.Context) (context.Context, *DeviceTimings) {
+	t := &DeviceTimings{}
+	return context.WithValue(ctx, timingsKey{}, t), t
+}
+
+func timingsFrom(ctx context.Context) *DeviceTimings {
+	if ctx == nil {
+		return nil
+	}
+	t, _ := ctx.Value(timingsKey{}).(*DeviceTimings)
+	return t
+}
+
+// AddQueueWait 累加等待并发名额的时长（接口层批次限流与服务层工作协程可各记一次）
+func (t *DeviceTimings) AddQueueWait(d time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.QueueWaitMS += d.Milliseconds()
+}
+
+// getTracedConnection 从连接池获取连接，并将耗时计入上下文中的时间线（重试时累加）；
+// 凭据处于认证失败冷却期或设备已熔断时不建连，建连结果计入认证失败限流与设备熔断
+func getTracedConnection(ctx context.Context, pool *ssh.Pool, info *ssh.ConnectionInfo) (*ssh.Client, error) {
+	if err := checkAuthThrottle(info.Username, info.Password); err != nil {
+		return nil, err
+	}
+	if err := checkCircuitBreaker(info.Host); err != nil {
+		return nil, err
+	}
+	client, err := tracedPoolConnection(ctx, pool, info)
+	recordAuthResult(info.Username, info.Password, info.Host, err)
+	recordCircuitResult(info.Host, err)
+	return client, err
+}
+
+// tracedPoolConnection 从连接池获取连接并记录建连耗时
+func tracedPoolConnection(ctx context.Context, pool *ssh.Pool, info *ssh.ConnectionInfo) (*ssh.Client, error) {
+	t := timingsFrom(ctx)
+	if t == nil {
+		return pool.GetConnection(ctx, info)
+	}
+	// 调用方已挂载连接记录（如平台探测读取横幅）时沿用
+	ct := ssh.ConnectTraceFrom(ctx)
+	if ct == nil {
+		ct = &ssh.ConnectTrace{}
+		ctx = ssh.WithConnectTrace(ctx, ct)
+	}
+	ct.Dial, ct.KeyExchange, ct.Auth = 0, 0, 0
+	start := time.Now()
+	client, err := pool.GetConnection(ctx, info)
+	elapsed := time.Since(start)
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if ct.Dial == 0 {
+		// 复用池内连接
+		t.ConnectionReused = err == nil
+		t.ConnectMS += elapsed.Milliseconds()
+		return client, err
+	}
+	t.ConnectionReused = false
+	t.ConnectMS += (elapsed - ct.Auth).Milliseconds()
+	t.AuthMS += ct.Auth.Milliseconds()
+	return client, err
+}
+
+// recordCommandTimings 追加会话内逐条命令耗时
+func recordCommandTimings(ctx context.Context, results []*ssh.CommandResult, secrets []string) {
+	t := timingsFrom(ctx)
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, r := range results {
+		if r == nil {
+			continue
+		}
+		t.Commands = append(t.Commands, CommandTiming{
+			Command:    SanitizeRecordedOutput(r.Command, secrets, nil),
+			DurationMS: r.Duration.Milliseconds(),
+		})
+	}
+}
+
+// recordStorageWrite 累加存储写入耗时
+func recordStorageWrite(ctx context.Context, d time.Duration) {
+	t := timingsFrom(ctx)
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.StorageWriteMS += d.Milliseconds()
+}
@@ -0,0 +1,215 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sshcollectorpro/sshcollectorpro/internal/config"
+	"github.com/sshcollectorpro/sshcollectorpro/pkg/ssh"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+// tracerName 本服务创建 span 使用的 instrumentation 名称
+const tracerName = "github.com/sshcollectorpro/sshcollectorpro"
+
+var (
+	tracingMu       sync.Mutex
+	tracingProvider *sdktrace.TracerProvider
+)
+
+// InitTracing 按配置初始化 OpenTelemetry 链路追踪（OTLP/HTTP 导出）；未启用时使用 noop。重复调用会先关闭旧的 provider
+func InitTracing(cfg *config.Config) error {
+	tracingMu.Lock()
+	defer tracingMu.Unlock()
+	shutdownTracingLocked(context.Background())
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	if cfg == nil || !cfg.Tracing.Enable {
+		otel.SetTracerProvider(noop.NewTracerProvider())
+		return nil
+	}
+	tc := cfg.Tracing
+	endpoint := strings.TrimSpace(tc.Endpoint)
+	if endpoint == "" {
+		otel.SetTracerProvider(noop.NewTracerProvider())
+		return fmt.Errorf("tracing.endpoint is required")
+	}
+	if tc.Timeout <= 0 {
+		tc.Timeout = 10 * time.Second
+	}
+	opts := []otlptracehttp.Option{otlptracehttp.WithTimeout(tc.Timeout)}
+	if strings.HasPrefix(endpoint, "http://") || strings.HasPrefix(endpoint, "https://") {
+		opts = append(opts, otlptracehttp.WithEndpointURL(endpoint))
+	} else {
+		opts = append(opts, otlptracehttp.WithEndpoint(endpoint))
+		if tc.Insecure {
+			opts = append(opts, otlptracehttp.WithInsecure())
+		}
+	}
+	if len(tc.Headers) > 0 {
+		opts = append(opts, otlptracehttp.WithHeaders(tc.Headers))
+	}
+	exp, err := otlptracehttp.New(context.Background(), opts...)
+	if err != nil {
+		otel.SetTracerProvider(noop.NewTracerProvider())
+		return fmt.Errorf("create otlp exporter: %w", err)
+	}
+
+	name := strings.TrimSpace(tc.ServiceName)
+	if name == "" {
+		name = strings.TrimSpace(cfg.Collector.ID)
+	}
+	if name == "" {
+		name = "sshcollectorpro"
+	}
+	res := resource.NewSchemaless(
+		attribute.String("service.name", name),
+		attribute.String("service.version", cfg.Collector.Version),
+		attribute.String("collector.id", cfg.Collector.ID),
+		attribute.String("collector.type", cfg.Collector.Type),
+	)
+	ratio := tc.SampleRatio
+	if ratio < 0 || ratio > 1 {
+		ratio = 1
+	}
+	tracingProvider = sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exp),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(ratio))),
+	)
+	otel.SetTracerProvider(tracingProvider)
+	return nil
+}
+
+// ShutdownTracing 刷新未导出的 span 并关闭 provider
+func ShutdownTracing(ctx context.Context) {
+	tracingMu.Lock()
+	defer tracingMu.Unlock()
+	shutdownTracingLocked(ctx)
+}
+
+func shutdownTracingLocked(ctx context.Context) {
+	if tracingProvider == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	_ = tracingProvider.Shutdown(ctx)
+	tracingProvider = nil
+}
+
+// startSpan 以全局 provider 创建 span（未启用追踪时为 noop）
+func startSpan(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(tracerName).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// StartBatchSpan 创建批次 span（<kind>.batch）；返回的 end 记录成功设备数并结束 span
+func StartBatchSpan(ctx context.Context, kind, taskID string, devices int) (context.Context, func(succeeded int)) {
+	ctx, span := startSpan(ctx, kind+".batch", attribute.String("task.id", taskID), attribute.Int("batch.devices", devices))
+	return ctx, func(succeeded int) {
+		span.SetAttributes(attribute.Int("batch.succeeded", succeeded))
+		span.End()
+	}
+}
+
+// endSpan 记录错误并结束 span
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// resultError 结果标记失败但未返回 error 时，以失败信息作为 span 错误
+func resultError(success bool, msg string) error {
+	if success {
+		return nil
+	}
+	if strings.TrimSpace(msg) == "" {
+		msg = "failed"
+	}
+	return errors.New(msg)
+}
+
+// deviceSpanAttrs 设备 span 通用属性
+func deviceSpanAttrs(ip, name, platform string) []attribute.KeyValue {
+	return []attribute.KeyValue{
+		attribute.String("device.ip", ip),
+		attribute.String("device.name", name),
+		attribute.String("device.platform", platform),
+	}
+}
+
+// traceCommandHooks 包装流式回调：首条命令开始前的会话建立与提示符等待记为 ssh.prompt_wait，
+// 每条命令（含内部预命令）记为 ssh.command；命令文本按录制规则脱敏。返回的 finish 结束仍未关闭的 span
+func traceCommandHooks(ctx context.Context, inner *ssh.StreamHooks, secrets []string) (*ssh.StreamHooks, func(error)) {
+	if !trace.SpanFromContext(ctx).IsRecording() {
+		return inner, func(error) {}
+	}
+	var mu sync.Mutex
+	_, wait := startSpan(ctx, "ssh.prompt_wait")
+	var cmdSpan trace.Span
+	hooks := &ssh.StreamHooks{
+		OnCommandStart: func(cmd string) {
+			mu.Lock()
+			if wait != nil {
+				wait.End()
+				wait = nil
+			}
+			if cmdSpan != nil {
+				cmdSpan.End()
+			}
+			_, cmdSpan = startSpan(ctx, "ssh.command", attribute.String("ssh.command", SanitizeRecordedOutput(cmd, secrets, nil)))
+			mu.Unlock()
+			if inner != nil && inner.OnCommandStart != nil {
+				inner.OnCommandStart(cmd)
+			}
+		},
+		OnOutputLine: func(cmd, line string) {
+			if inner != nil && inner.OnOutputLine != nil {
+				inner.OnOutputLine(cmd, line)
+			}
+		},
+		OnCommandEnd: func(res *ssh.CommandResult) {
+			mu.Lock()
+			if cmdSpan != nil {
+				cmdSpan.SetAttributes(attribute.Int("ssh.exit_code", res.ExitCode), attribute.Int("ssh.output_bytes", len(res.Output)))
+				var err error
+				if res.Error != "" {
+					err = fmt.Errorf("%s", res.Error)
+				}
+				endSpan(cmdSpan, err)
+				cmdSpan = nil
+			}
+			mu.Unlock()
+			if inner != nil && inner.OnCommandEnd != nil {
+				inner.OnCommandEnd(res)
+			}
+		},
+	}
+	finish := func(err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if wait != nil {
+			endSpan(wait, err)
+			wait = nil
+		}
+		if cmdSpan != nil {
+			endSpan(cmdSpan, err)
+			cmdSpan = nil
+		}
+	}
+	return hooks, finish
+}
@@ -0,0 +1,81 @@
+package service
+
+import (
+	"context"
+	"sync"
+)
+
+// 非致命告警码：请求仍成功完成，但存在降级或需调用方关注的情况
+const (
+	WarnDeprecatedField      = "DEPRECATED_FIELD"       // 使用了已更名的旧字段
+	WarnStorageFallback      = "STORAGE_FALLBACK"       // 远端存储不可用，已写入本地
+	WarnConfigDefault        = "CONFIG_DEFAULT"         // 平台未配置，使用默认参数
+	WarnTruncated            = "TRUNCATED"              // 结果超出上限被截断
+	WarnPlatformUnmapped     = "PLATFORM_UNMAPPED"      // 平台字符串未匹配平台键或映射规则
+	WarnSimulateRecordFailed = "SIMULATE_RECORD_FAILED" // 采集回显录制到模拟器目录失败
+	WarnPortFallback         = "PORT_FALLBACK"          // 首选端口拒绝连接，已使用备用端口
+	WarnFactUnresolved       = "FACT_UNRESOLVED"        // 备份命名所需的设备事实未解析，已回退到设备名
+	WarnPlatformUndetected   = "PLATFORM_UNDETECTED"    // 平台自动探测未识别厂商，按 default 平台执行
+)
+
+// maxWarnings 单个请求保留的告警上限
+const maxWarnings = 100
+
+// Warning 非致命告警，随接口响应的 warnings 数组返回
+type Warning struct {
+	Code    string                 `json:"code"`
+	Message string                 `json:"message"`
+	Context map[string]interface{} `json:"context,omitempty"`
+}
+
+// Warnings 请求级告警收集器，并发安全；相同 code+message 只保留首条
+type Warnings struct {
+	mu    sync.Mutex
+	items []Warning
+	seen  map[string]bool
+}
+
+type warningsKey struct{}
+
+// WithWarnings 为请求上下文挂载告警收集器
+func WithWarnings(ctx context.Context) (context.Context, *Warnings) {
+	w := &Warnings{seen: map[string]bool{}}
+	return context.WithValue(ctx, warningsKey{}, w), w
+}
+
+// AddWarning 记录告警；上下文未挂载收集器时忽略（日志仍由调用方输出）
+func AddWarning(ctx context.Context, w Warning) {
+	if ctx == nil {
+		return
+	}
+	ws, _ := ctx.Value(warningsKey{}).(*Warnings)
+	if ws == nil {
+		return
+	}
+	ws.add(w)
+}
+
+func (ws *Warnings) add(w Warning) {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	key := w.Code + "\x00" + w.Message
+	if ws.seen[key] || len(ws.items) >= maxWarnings {
+		return
+	}
+	ws.seen[key] = true
+	ws.items = append(ws.items, w)
+}
+
+// List 返回已记录的告警副本
+func (ws *Warnings) List() []Warning {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	return append([]Warning(nil), ws.items...)
+}
+
+// Len 已记录的告警数
+func (ws *Warnings) Len() int {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	return len(ws.items)
+}
@@ -0,0 +1,46 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// StatusNotAttemptedWindowClosed 执行窗口（deadline）关闭后未派发的设备状态
+const StatusNotAttemptedWindowClosed = "NOT_ATTEMPTED_WINDOW_CLOSED"
+
+// ErrWindowClosed 批次执行窗口已关闭
+var ErrWindowClosed = errors.New("execution window closed")
+
+// windowClosed 判断截止时间是否已到（nil 表示不限制）
+func windowClosed(deadline *time.Time) bool {
+	return deadline != nil && !deadline.IsZero() && !time.Now().Before(*deadline)
+}
+
+// AcquireDispatchSlot 在执行窗口内等待并发令牌。
+// 窗口关闭返回 ErrWindowClosed；ctx 结束返回 ctx.Err()；成功后调用方负责归还令牌。
+// 已派发的设备不受截止时间影响，允许其命令执行完毕。
+func AcquireDispatchSlot(ctx context.Context, slots chan struct{}, deadline *time.Time) error {
+	if windowClosed(deadline) {
+		return ErrWindowClosed
+	}
+	var expired <-chan time.Time
+	if deadline != nil && !deadline.IsZero() {
+		t := time.NewTimer(time.Until(*deadline))
+		defer t.Stop()
+		expired = t.C
+	}
+	select {
+	case slots <- struct{}{}:
+		// 令牌与截止时间同时就绪时以窗口为准
+		if windowClosed(deadline) {
+			<-slots
+			return ErrWindowClosed
+		}
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-expired:
+		return ErrWindowClosed
+	}
+}
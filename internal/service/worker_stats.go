@@ -0,0 +1,193 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/sshcollectorpro/sshcollectorpro/internal/config"
+)
+
+const (
+	// workerWaitSamples 计算排队等待分位数保留的最近样本数
+	workerWaitSamples = 1024
+	// workerDepthMinutes 排队深度历史保留的分钟数
+	workerDepthMinutes = 60
+)
+
+// WorkerQueueSample 每分钟的排队深度与占用峰值
+type WorkerQueueSample struct {
+	Minute        time.Time `json:"minute"`
+	MaxQueueDepth int       `json:"max_queue_depth"`
+	MaxBusy       int       `json:"max_busy"`
+}
+
+// workerStats 名额等待统计：当前排队数、等待耗时样本、各结果计数与按分钟的深度历史
+type workerStats struct {
+	mu        sync.Mutex
+	waiting   int
+	peak      int
+	acquired  int64
+	rejected  int64 // 执行窗口已关闭
+	timedOut  int64 // 排队超时
+	cancelled int64 // 调用方取消
+	waitSum   time.Duration
+	waits     []time.Duration // 环形缓冲
+	next      int
+	history   []WorkerQueueSample // 按分钟，最多 workerDepthMinutes 条
+}
+
+// sampleLocked 记录当前分钟的深度与占用峰值
+func (st *workerStats) sampleLocked(depth, busy int) {
+	minute := time.Now().Truncate(time.Minute)
+	if n := len(st.history); n == 0 || !st.history[n-1].Minute.Equal(minute) {
+		st.history = append(st.history, WorkerQueueSample{Minute: minute})
+		if len(st.history) > workerDepthMinutes {
+			st.history = st.history[len(st.history)-workerDepthMinutes:]
+		}
+	}
+	cur := &st.history[len(st.history)-1]
+	if depth > cur.MaxQueueDepth {
+		cur.MaxQueueDepth = depth
+	}
+	if busy > cur.MaxBusy {
+		cur.MaxBusy = busy
+	}
+}
+
+func (st *workerStats) enqueue(busy int) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.waiting++
+	if st.waiting > st.peak {
+		st.peak = st.waiting
+	}
+	st.sampleLocked(st.waiting, busy)
+}
+
+func (st *workerStats) dequeue(wait time.Duration, busy int, err error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.waiting--
+	switch {
+	case err == nil:
+		st.acquired++
+		st.waitSum += wait
+		if len(st.waits) < workerWaitSamples {
+			st.waits = append(st.waits, wait)
+		} else {
+			st.waits[st.next] = wait
+			st.next = (st.next + 1) % workerWaitSamples
+		}
+	case errors.Is(err, ErrWindowClosed):
+		st.rejected++
+	case errors.Is(err, context.DeadlineExceeded):
+		st.timedOut++
+	default:
+		st.cancelled++
+	}
+	st.sampleLocked(st.waiting, busy)
+}
+
+// observe 统计一次名额等待；wait 阻塞至获得名额或失败，slots 用于记录占用
+func (w *workerSlots) observe(slots chan struct{}, wait func() error) error {
+	w.stats.enqueue(len(slots))
+	start := time.Now()
+	err := wait()
+	w.stats.dequeue(time.Since(start), len(slots), err)
+	return err
+}
+
+// acquire 等待一个名额（受执行窗口约束）并记录排队指标；成功后调用方从返回的通道归还名额
+func (w *workerSlots) acquire(ctx context.Context, deadline *time.Time) (chan struct{}, error) {
+	slots := w.get()
+	return slots, w.observe(slots, func() error { return AcquireDispatchSlot(ctx, slots, deadline) })
+}
+
+// fill 填充排队指标；分位数基于最近 workerWaitSamples 次成功获取名额的等待耗时
+func (w *workerSlots) fill(u *WorkerUtilization) {
+	st := &w.stats
+	st.mu.Lock()
+	u.QueueDepth, u.PeakQueueDepth = st.waiting, st.peak
+	u.Acquired, u.Rejected, u.TimedOut, u.Cancelled = st.acquired, st.rejected, st.timedOut, st.cancelled
+	u.WaitSumSeconds = st.waitSum.Seconds()
+	waits := append([]time.Duration(nil), st.waits...)
+	cutoff := time.Now().Truncate(time.Minute).Add(-(workerDepthMinutes - 1) * time.Minute)
+	u.QueueHistory = make([]WorkerQueueSample, 0, len(st.history))
+	for _, s := range st.history {
+		if !s.Minute.Before(cutoff) {
+			u.QueueHistory = append(u.QueueHistory, s)
+		}
+	}
+	st.mu.Unlock()
+
+	if len(waits) == 0 {
+		return
+	}
+	sort.Slice(waits, func(i, j int) bool { return waits[i] < waits[j] })
+	ms := func(d time.Duration) float64 { return float64(d) / float64(time.Millisecond) }
+	u.WaitP50MS = ms(waits[(len(waits)-1)*50/100])
+	u.WaitP95MS = ms(waits[(len(waits)-1)*95/100])
+	u.WaitMaxMS = ms(waits[len(waits)-1])
+}
+
+// currentConcurrencyProfile 当前并发档位（S/M/L/XL），未使用档位时为空
+func currentConcurrencyProfile() string {
+	if cfg := config.Get(); cfg != nil {
+		return cfg.Collector.ConcurrencyProfile
+	}
+	return ""
+}
+
+// WriteWorkerMetrics 以 Prometheus 文本格式输出各服务的 worker 占用、排队深度、等待分位数与名额获取结果计数
+func WriteWorkerMetrics(w io.Writer, services ...WorkerReporter) {
+	stats := make([]WorkerUtilization, 0, len(services))
+	for _, s := range services {
+		if s != nil {
+			stats = append(stats, s.WorkerUtilization())
+		}
+	}
+	if len(stats) == 0 {
+		return
+	}
+	gauge := func(name, help string, pick func(WorkerUtilization) int) {
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s gauge\n", name, help, name)
+		for _, u := range stats {
+			fmt.Fprintf(w, "%s{service=%q} %d\n", name, u.Service, pick(u))
+		}
+	}
+	gauge("sshcollector_worker_busy", "Worker slots currently in use.", func(u WorkerUtilization) int { return u.Busy })
+	gauge("sshcollector_worker_max", "Worker slot capacity.", func(u WorkerUtilization) int { return u.Max })
+	gauge("sshcollector_worker_queue_depth", "Requests currently waiting for a worker slot.", func(u WorkerUtilization) int { return u.QueueDepth })
+
+	const wait = "sshcollector_worker_queue_wait_seconds"
+	fmt.Fprintf(w, "# HELP %s Time spent waiting for a worker slot (quantiles over recent acquisitions).\n# TYPE %s summary\n", wait, wait)
+	for _, u := range stats {
+		sec := func(ms float64) string { return strconv.FormatFloat(ms/1000, 'g', -1, 64) }
+		fmt.Fprintf(w, "%s{service=%q,quantile=\"0.5\"} %s\n", wait, u.Service, sec(u.WaitP50MS))
+		fmt.Fprintf(w, "%s{service=%q,quantile=\"0.95\"} %s\n", wait, u.Service, sec(u.WaitP95MS))
+		fmt.Fprintf(w, "%s_sum{service=%q} %s\n", wait, u.Service, strconv.FormatFloat(u.WaitSumSeconds, 'g', -1, 64))
+		fmt.Fprintf(w, "%s_count{service=%q} %d\n", wait, u.Service, u.Acquired)
+	}
+
+	const acq = "sshcollector_worker_acquisitions_total"
+	fmt.Fprintf(w, "# HELP %s Worker slot acquisitions by result.\n# TYPE %s counter\n", acq, acq)
+	for _, u := range stats {
+		for _, r := range []struct {
+			result string
+			n      int64
+		}{{"acquired", u.Acquired}, {"rejected", u.Rejected}, {"timed_out", u.TimedOut}, {"cancelled", u.Cancelled}} {
+			fmt.Fprintf(w, "%s{service=%q,result=%q} %d\n", acq, u.Service, r.result, r.n)
+		}
+	}
+
+	if profile := stats[0].Profile; profile != "" {
+		const info = "sshcollector_concurrency_profile"
+		fmt.Fprintf(w, "# HELP %s Active concurrency profile.\n# TYPE %s gauge\n%s{profile=%q} 1\n", info, info, info, profile)
+	}
+}
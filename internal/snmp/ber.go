@@ -0,0 +1,386 @@
+package snmp
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// BER 标签
+const (
+	tagInteger     = 0x02
+	tagOctetString = 0x04
+	tagNull        = 0x05
+	tagOID         = 0x06
+	tagSequence    = 0x30
+
+	tagIPAddress = 0x40
+	tagCounter32 = 0x41
+	tagGauge32   = 0x42
+	tagTimeTicks = 0x43
+	tagOpaque    = 0x44
+	tagCounter64 = 0x46
+
+	tagNoSuchObject   = 0x80
+	tagNoSuchInstance = 0x81
+	tagEndOfMibView   = 0x82
+
+	pduGet      = 0xa0
+	pduGetNext  = 0xa1
+	pduResponse = 0xa2
+	pduGetBulk  = 0xa5
+	pduReport   = 0xa8
+)
+
+// 值类型名称
+const (
+	TypeInteger        = "integer"
+	TypeOctetString    = "octet_string"
+	TypeNull           = "null"
+	TypeOID            = "oid"
+	TypeIPAddress      = "ip_address"
+	TypeCounter32      = "counter32"
+	TypeGauge32        = "gauge32"
+	TypeTimeTicks      = "timeticks"
+	TypeOpaque         = "opaque"
+	TypeCounter64      = "counter64"
+	TypeNoSuchObject   = "no_such_object"
+	TypeNoSuchInstance = "no_such_instance"
+	TypeEndOfMibView   = "end_of_mib_view"
+)
+
+var errTruncated = errors.New("snmp: truncated message")
+
+// Variable 变量绑定
+type Variable struct {
+	OID   string      `json:"oid"`
+	Type  string      `json:"type"`
+	Value interface{} `json:"value"`
+}
+
+// Exists 是否为实际值（非 noSuchObject/noSuchInstance/endOfMibView）
+func (v Variable) Exists() bool {
+	switch v.Type {
+	case TypeNoSuchObject, TypeNoSuchInstance, TypeEndOfMibView:
+		return false
+	}
+	return true
+}
+
+// String 值的文本形式
+func (v Variable) String() string {
+	if !v.Exists() || v.Value == nil {
+		return v.Type
+	}
+	return fmt.Sprintf("%v", v.Value)
+}
+
+func encLength(n int) []byte {
+	if n < 0x80 {
+		return []byte{byte(n)}
+	}
+	var b []byte
+	for v := n; v > 0; v >>= 8 {
+		b = append([]byte{byte(v)}, b...)
+	}
+	return append([]byte{0x80 | byte(len(b))}, b...)
+}
+
+func tlv(tag byte, val []byte) []byte {
+	out := append([]byte{tag}, encLength(len(val))...)
+	return append(out, val...)
+}
+
+func encInt(v int64) []byte {
+	b := []byte{byte(v)}
+	for v > 127 || v < -128 {
+		v >>= 8
+		b = append([]byte{byte(v)}, b...)
+	}
+	return tlv(tagInteger, b)
+}
+
+func encOctets(b []byte) []byte { return tlv(tagOctetString, b) }
+
+func encSeq(parts ...[]byte) []byte {
+	var body []byte
+	for _, p := range parts {
+		body = append(body, p...)
+	}
+	return tlv(tagSequence, body)
+}
+
+// ParseOID 校验并解析点分 OID（允许前导点）
+func ParseOID(oid string) ([]uint32, error) {
+	s := strings.TrimPrefix(strings.TrimSpace(oid), ".")
+	parts := strings.Split(s, ".")
+	if len(parts) < 2 {
+		return nil, fmt.Errorf("invalid oid %q", oid)
+	}
+	arcs := make([]uint32, len(parts))
+	for i, p := range parts {
+		n, err := strconv.ParseUint(p, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid oid %q", oid)
+		}
+		arcs[i] = uint32(n)
+	}
+	if arcs[0] > 2 || (arcs[0] < 2 && arcs[1] >= 40) {
+		return nil, fmt.Errorf("invalid oid %q", oid)
+	}
+	return arcs, nil
+}
+
+func encOID(oid string) ([]byte, error) {
+	arcs, err := ParseOID(oid)
+	if err != nil {
+		return nil, err
+	}
+	body := encBase128(arcs[0]*40 + arcs[1])
+	for _, a := range arcs[2:] {
+		body = append(body, encBase128(a)...)
+	}
+	return tlv(tagOID, body), nil
+}
+
+func encBase128(v uint32) []byte {
+	b := []byte{byte(v & 0x7f)}
+	for v >>= 7; v > 0; v >>= 7 {
+		b = append([]byte{byte(v&0x7f) | 0x80}, b...)
+	}
+	return b
+}
+
+// readTLV 读取一个 TLV；val 与 rest 为 b 的子切片
+func readTLV(b []byte) (tag byte, val, rest []byte, err error) {
+	if len(b) < 2 {
+		return 0, nil, nil, errTruncated
+	}
+	tag = b[0]
+	n := int(b[1])
+	off := 2
+	if n&0x80 != 0 {
+		k := n & 0x7f
+		if k == 0 || k > 4 || len(b) < 2+k {
+			return 0, nil, nil, errTruncated
+		}
+		n = 0
+		for _, c := range b[2 : 2+k] {
+			n = n<<8 | int(c)
+		}
+		off += k
+	}
+	if n < 0 || len(b)-off < n {
+		return 0, nil, nil, errTruncated
+	}
+	return tag, b[off : off+n], b[off+n:], nil
+}
+
+// expectTLV 读取指定标签的 TLV
+func expectTLV(b []byte, want byte) (val, rest []byte, err error) {
+	tag, val, rest, err := readTLV(b)
+	if err != nil {
+		return nil, nil, err
+	}
+	if tag != want {
+		return nil, nil, fmt.Errorf("snmp: unexpected tag 0x%02x, want 0x%02x", tag, want)
+	}
+	return val, rest, nil
+}
+
+func decInt(val []byte) int64 {
+	var v int64
+	for i, c := range val {
+		if i == 0 && c&0x80 != 0 {
+			v = -1
+		}
+		v = v<<8 | int64(c)
+	}
+	return v
+}
+
+func decUint(val []byte) uint64 {
+	var v uint64
+	for _, c := range val {
+		v = v<<8 | uint64(c)
+	}
+	return v
+}
+
+func readInt(b []byte) (int64, []byte, error) {
+	val, rest, err := expectTLV(b, tagInteger)
+	if err != nil {
+		return 0, nil, err
+	}
+	return decInt(val), rest, nil
+}
+
+func decOID(val []byte) string {
+	if len(val) == 0 {
+		return ""
+	}
+	var arcs []string
+	var v uint64
+	first := true
+	for _, c := range val {
+		v = v<<7 | uint64(c&0x7f)
+		if c&0x80 != 0 {
+			continue
+		}
+		if first {
+			a := v / 40
+			if a > 2 {
+				a = 2
+			}
+			arcs = append(arcs, strconv.FormatUint(a, 10), strconv.FormatUint(v-a*40, 10))
+			first = false
+		} else {
+			arcs = append(arcs, strconv.FormatUint(v, 10))
+		}
+		v = 0
+	}
+	return strings.Join(arcs, ".")
+}
+
+// decValue 解码变量值
+func decValue(tag byte, val []byte) (string, interface{}) {
+	switch tag {
+	case tagInteger:
+		return TypeInteger, decInt(val)
+	case tagOctetString:
+		return TypeOctetString, octetString(val)
+	case tagNull:
+		return TypeNull, nil
+	case tagOID:
+		return TypeOID, decOID(val)
+	case tagIPAddress:
+		if len(val) == 4 {
+			return TypeIPAddress, net.IP(val).String()
+		}
+		return TypeIPAddress, hex.EncodeToString(val)
+	case tagCounter32:
+		return TypeCounter32, decUint(val)
+	case tagGauge32:
+		return TypeGauge32, decUint(val)
+	case tagTimeTicks:
+		return TypeTimeTicks, decUint(val)
+	case tagCounter64:
+		return TypeCounter64, decUint(val)
+	case tagOpaque:
+		return TypeOpaque, hex.EncodeToString(val)
+	case tagNoSuchObject:
+		return TypeNoSuchObject, nil
+	case tagNoSuchInstance:
+		return TypeNoSuchInstance, nil
+	case tagEndOfMibView:
+		return TypeEndOfMibView, nil
+	default:
+		return fmt.Sprintf("tag_0x%02x", tag), hex.EncodeToString(val)
+	}
+}
+
+// octetString 可打印文本原样返回（去掉末尾 NUL），否则返回 0x 前缀的十六进制
+func octetString(val []byte) string {
+	s := strings.TrimRight(string(val), "\x00")
+	if utf8.ValidString(s) {
+		printable := true
+		for _, r := range s {
+			if r < 0x20 && r != '\n' && r != '\r' && r != '\t' {
+				printable = false
+				break
+			}
+		}
+		if printable {
+			return s
+		}
+	}
+	return "0x" + hex.EncodeToString(val)
+}
+
+// pdu 解码后的 PDU
+type pdu struct {
+	tag         byte
+	requestID   int64
+	errorStatus int64
+	errorIndex  int64
+	vars        []Variable
+}
+
+// encPDU 编码请求 PDU；GetBulk 时 a、b 为 non-repeaters 与 max-repetitions
+func encPDU(tag byte, requestID int64, a, b int, oids []string) ([]byte, error) {
+	vbs := make([][]byte, 0, len(oids))
+	for _, o := range oids {
+		eo, err := encOID(o)
+		if err != nil {
+			return nil, err
+		}
+		vbs = append(vbs, encSeq(eo, tlv(tagNull, nil)))
+	}
+	body := append(encInt(requestID), encInt(int64(a))...)
+	body = append(body, encInt(int64(b))...)
+	body = append(body, encSeq(vbs...)...)
+	return tlv(tag, body), nil
+}
+
+func decPDU(b []byte) (*pdu, error) {
+	tag, body, _, err := readTLV(b)
+	if err != nil {
+		return nil, err
+	}
+	p := &pdu{tag: tag}
+	if p.requestID, body, err = readInt(body); err != nil {
+		return nil, err
+	}
+	if p.errorStatus, body, err = readInt(body); err != nil {
+		return nil, err
+	}
+	if p.errorIndex, body, err = readInt(body); err != nil {
+		return nil, err
+	}
+	vbs, _, err := expectTLV(body, tagSequence)
+	if err != nil {
+		return nil, err
+	}
+	for len(vbs) > 0 {
+		var vb []byte
+		if vb, vbs, err = expectTLV(vbs, tagSequence); err != nil {
+			return nil, err
+		}
+		oid, rest, err := expectTLV(vb, tagOID)
+		if err != nil {
+			return nil, err
+		}
+		vtag, vval, _, err := readTLV(rest)
+		if err != nil {
+			return nil, err
+		}
+		typ, value := decValue(vtag, vval)
+		p.vars = append(p.vars, Variable{OID: decOID(oid), Type: typ, Value: value})
+	}
+	return p, nil
+}
+
+// errorStatusNames RFC 3416 error-status
+var errorStatusNames = []string{
+	"noError", "tooBig", "noSuchName", "badValue", "readOnly", "genErr", "noAccess", "wrongType",
+	"wrongLength", "wrongEncoding", "wrongValue", "noCreation", "inconsistentValue", "resourceUnavailable",
+	"commitFailed", "undoFailed", "authorizationError", "notWritable", "inconsistentName",
+}
+
+// StatusError 设备返回的 error-status
+type StatusError struct {
+	Status int64
+	Index  int64
+}
+
+func (e *StatusError) Error() string {
+	name := strconv.FormatInt(e.Status, 10)
+	if e.Status >= 0 && int(e.Status) < len(errorStatusNames) {
+		name = errorStatusNames[e.Status]
+	}
+	return fmt.Sprintf("snmp error-status %s (index %d)", name, e.Index)
+}
@@ -0,0 +1,334 @@
+package snmp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 协议版本
+const (
+	Version1  = "v1"
+	Version2c = "v2c"
+	Version3  = "v3"
+)
+
+// maxWalkVariables 单次遍历的变量上限，防止设备返回异常数据时无限遍历
+const maxWalkVariables = 100000
+
+// ErrTimeout 请求在重试后仍无应答
+var ErrTimeout = errors.New("snmp: request timeout")
+
+// Config 客户端参数
+type Config struct {
+	Target         string
+	Port           int           // 默认 161
+	Version        string        // v1 | v2c（默认）| v3
+	Community      string        // v1/v2c
+	Timeout        time.Duration // 单次请求等待应答的超时，默认 3s
+	Retries        int           // 超时重发次数
+	MaxRepetitions int           // GetBulk 每次返回的行数，默认 10
+
+	// v3（USM）：设置 AuthProtocol 启用认证，再设置 PrivProtocol 启用加密
+	User         string
+	AuthProtocol string // md5 | sha | sha256
+	AuthPassword string
+	PrivProtocol string // des | aes
+	PrivPassword string
+	ContextName  string
+}
+
+// Validate 校验版本与凭据
+func (c *Config) Validate() error {
+	switch strings.ToLower(c.Version) {
+	case "", Version2c, "2c", "2", Version1, "1":
+		if c.Community == "" {
+			return fmt.Errorf("snmp community is required for %s", c.version())
+		}
+		return nil
+	case Version3, "3":
+	default:
+		return fmt.Errorf("unsupported snmp version %q", c.Version)
+	}
+	if strings.TrimSpace(c.User) == "" {
+		return fmt.Errorf("snmp v3 user is required")
+	}
+	if c.AuthProtocol != "" {
+		if _, err := authFor(c.AuthProtocol); err != nil {
+			return err
+		}
+		if len(c.AuthPassword) < 8 {
+			return fmt.Errorf("snmp v3 auth password must be at least 8 characters")
+		}
+	}
+	if c.PrivProtocol != "" {
+		if c.AuthProtocol == "" {
+			return fmt.Errorf("snmp v3 privacy requires authentication")
+		}
+		if p := strings.ToLower(c.PrivProtocol); p != "des" && p != "aes" {
+			return fmt.Errorf("unsupported snmp privacy protocol %q (expected des or aes)", c.PrivProtocol)
+		}
+		if len(c.PrivPassword) < 8 {
+			return fmt.Errorf("snmp v3 privacy password must be at least 8 characters")
+		}
+	}
+	return nil
+}
+
+// version 归一化后的版本
+func (c *Config) version() string {
+	switch strings.ToLower(c.Version) {
+	case Version1, "1":
+		return Version1
+	case Version3, "3":
+		return Version3
+	default:
+		return Version2c
+	}
+}
+
+// Client SNMP 客户端（UDP，单连接串行请求）
+type Client struct {
+	cfg       Config
+	conn      net.Conn
+	requestID int64
+	usm       *usm
+}
+
+// Dial 建立 UDP 连接；v3 同时完成引擎发现
+func Dial(ctx context.Context, cfg Config) (*Client, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	if cfg.Port <= 0 {
+		cfg.Port = 161
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 3 * time.Second
+	}
+	if cfg.Retries < 0 {
+		cfg.Retries = 0
+	}
+	if cfg.MaxRepetitions <= 0 {
+		cfg.MaxRepetitions = 10
+	}
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "udp", net.JoinHostPort(cfg.Target, strconv.Itoa(cfg.Port)))
+	if err != nil {
+		return nil, fmt.Errorf("snmp dial: %w", err)
+	}
+	c := &Client{cfg: cfg, conn: conn, requestID: int64(rand.Int31n(1 << 30))}
+	if cfg.version() == Version3 {
+		c.usm = newUSM(&cfg)
+		if err := c.discover(ctx); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// Close 关闭连接
+func (c *Client) Close() error { return c.conn.Close() }
+
+// Get 读取指定 OID
+func (c *Client) Get(ctx context.Context, oids ...string) ([]Variable, error) {
+	p, err := c.request(ctx, pduGet, 0, 0, oids)
+	if err != nil {
+		return nil, err
+	}
+	if p.errorStatus != 0 {
+		return nil, &StatusError{Status: p.errorStatus, Index: p.errorIndex}
+	}
+	return p.vars, nil
+}
+
+// Walk 遍历 root 子树（v2c/v3 使用 GetBulk，v1 使用 GetNext）
+func (c *Client) Walk(ctx context.Context, root string) ([]Variable, error) {
+	rootArcs, err := ParseOID(root)
+	if err != nil {
+		return nil, err
+	}
+	prefix := joinArcs(rootArcs)
+	var out []Variable
+	cur := prefix
+	for len(out) < maxWalkVariables {
+		var p *pdu
+		if c.cfg.version() == Version1 {
+			p, err = c.request(ctx, pduGetNext, 0, 0, []string{cur})
+		} else {
+			p, err = c.request(ctx, pduGetBulk, 0, c.cfg.MaxRepetitions, []string{cur})
+		}
+		if err != nil {
+			return out, err
+		}
+		if p.errorStatus == 2 && c.cfg.version() == Version1 {
+			// v1 以 noSuchName 表示已到 MIB 末尾
+			return out, nil
+		}
+		if p.errorStatus != 0 {
+			return out, &StatusError{Status: p.errorStatus, Index: p.errorIndex}
+		}
+		if len(p.vars) == 0 {
+			return out, nil
+		}
+		for _, v := range p.vars {
+			if v.Type == TypeEndOfMibView || !inSubtree(v.OID, prefix) {
+				return out, nil
+			}
+			if compareOID(v.OID, cur) <= 0 {
+				return out, fmt.Errorf("snmp: oid not increasing during walk (%s after %s)", v.OID, cur)
+			}
+			out = append(out, v)
+			cur = v.OID
+		}
+	}
+	return out, fmt.Errorf("snmp: walk of %s exceeded %d variables", root, maxWalkVariables)
+}
+
+// request 发送请求并等待匹配的应答，超时按 Retries 重发
+func (c *Client) request(ctx context.Context, tag byte, a, b int, oids []string) (*pdu, error) {
+	notInTimeRetried := false
+	for attempt := 0; attempt <= c.cfg.Retries; attempt++ {
+		c.requestID = (c.requestID + 1) & 0x7fffffff
+		id := c.requestID
+		pduBytes, err := encPDU(tag, id, a, b, oids)
+		if err != nil {
+			return nil, err
+		}
+		var msg []byte
+		if c.usm != nil {
+			msg, err = c.usm.encode(id, pduBytes, true)
+		} else {
+			msg = c.encodeCommunity(pduBytes)
+		}
+		if err != nil {
+			return nil, err
+		}
+		p, err := c.roundTrip(ctx, id, msg)
+		if errors.Is(err, ErrTimeout) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if p.tag == pduReport {
+			// 时间窗口不一致：report 中已携带最新的 boots/time，重发一次
+			if c.usm != nil && reportIs(p, oidNotInTimeWindows) && !notInTimeRetried {
+				notInTimeRetried = true
+				attempt--
+				continue
+			}
+			return nil, reportError(p)
+		}
+		return p, nil
+	}
+	return nil, fmt.Errorf("%w after %d attempts (%s:%d)", ErrTimeout, c.cfg.Retries+1, c.cfg.Target, c.cfg.Port)
+}
+
+// roundTrip 发送并读取 request-id 匹配的应答；超时返回 ErrTimeout
+func (c *Client) roundTrip(ctx context.Context, id int64, msg []byte) (*pdu, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if _, err := c.conn.Write(msg); err != nil {
+		return nil, fmt.Errorf("snmp write: %w", err)
+	}
+	deadline := time.Now().Add(c.cfg.Timeout)
+	if dl, ok := ctx.Deadline(); ok && dl.Before(deadline) {
+		deadline = dl
+	}
+	_ = c.conn.SetReadDeadline(deadline)
+	buf := make([]byte, 65535)
+	for {
+		n, err := c.conn.Read(buf)
+		if err != nil {
+			var ne net.Error
+			if errors.As(err, &ne) && ne.Timeout() {
+				if ctx.Err() != nil {
+					return nil, ctx.Err()
+				}
+				return nil, ErrTimeout
+			}
+			return nil, fmt.Errorf("snmp read: %w", err)
+		}
+		var p *pdu
+		if c.usm != nil {
+			p, err = c.usm.decode(buf[:n])
+		} else {
+			p, err = c.decodeCommunity(buf[:n])
+		}
+		if err != nil {
+			return nil, err
+		}
+		// 忽略迟到的旧应答
+		if p.requestID == id {
+			return p, nil
+		}
+	}
+}
+
+func (c *Client) encodeCommunity(pduBytes []byte) []byte {
+	ver := int64(1)
+	if c.cfg.version() == Version1 {
+		ver = 0
+	}
+	return encSeq(encInt(ver), encOctets([]byte(c.cfg.Community)), pduBytes)
+}
+
+func (c *Client) decodeCommunity(msg []byte) (*pdu, error) {
+	body, _, err := expectTLV(msg, tagSequence)
+	if err != nil {
+		return nil, err
+	}
+	if _, body, err = readInt(body); err != nil {
+		return nil, err
+	}
+	if _, body, err = expectTLV(body, tagOctetString); err != nil {
+		return nil, err
+	}
+	return decPDU(body)
+}
+
+func joinArcs(arcs []uint32) string {
+	s := make([]string, len(arcs))
+	for i, a := range arcs {
+		s[i] = strconv.FormatUint(uint64(a), 10)
+	}
+	return strings.Join(s, ".")
+}
+
+// inSubtree oid 是否位于 prefix 之下
+func inSubtree(oid, prefix string) bool {
+	return strings.HasPrefix(oid, prefix+".")
+}
+
+// compareOID 按子标识逐级比较
+func compareOID(a, b string) int {
+	pa, pb := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(pa) && i < len(pb); i++ {
+		x, _ := strconv.ParseUint(pa[i], 10, 64)
+		y, _ := strconv.ParseUint(pb[i], 10, 64)
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+	return len(pa) - len(pb)
+}
+
+// IndexOf 返回 oid 相对 base 的索引后缀（如 ifDescr.3 的 "3"）；不在子树下时返回空串
+func IndexOf(oid, base string) string {
+	base = strings.TrimPrefix(strings.TrimSpace(base), ".")
+	if !inSubtree(oid, base) {
+		return ""
+	}
+	return oid[len(base)+1:]
+}
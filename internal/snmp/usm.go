@@ -0,0 +1,475 @@
+package snmp
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/des"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"strings"
+	"sync"
+	"time"
+)
+
+// USM 统计 OID（report 原因）
+const (
+	oidUnsupportedSecLevels = "1.3.6.1.6.3.15.1.1.1.0"
+	oidNotInTimeWindows     = "1.3.6.1.6.3.15.1.1.2.0"
+	oidUnknownUserNames     = "1.3.6.1.6.3.15.1.1.3.0"
+	oidUnknownEngineIDs     = "1.3.6.1.6.3.15.1.1.4.0"
+	oidWrongDigests         = "1.3.6.1.6.3.15.1.1.5.0"
+	oidDecryptionErrors     = "1.3.6.1.6.3.15.1.1.6.0"
+)
+
+var reportNames = map[string]string{
+	oidUnsupportedSecLevels: "unsupported security level",
+	oidNotInTimeWindows:     "not in time window",
+	oidUnknownUserNames:     "unknown user name",
+	oidUnknownEngineIDs:     "unknown engine id",
+	oidWrongDigests:         "wrong digest (check auth protocol/password)",
+	oidDecryptionErrors:     "decryption error (check privacy protocol/password)",
+}
+
+const (
+	msgFlagAuth       = 0x01
+	msgFlagPriv       = 0x02
+	msgFlagReportable = 0x04
+	securityModelUSM  = 3
+	maxMessageSize    = 65507
+)
+
+// ErrWrongDigest 应答的认证摘要不匹配
+var ErrWrongDigest = errors.New("snmp: response authentication failed")
+
+type authProto struct {
+	hash   func() hash.Hash
+	digest int // 截断后的摘要长度
+}
+
+func authFor(name string) (authProto, error) {
+	switch strings.ToLower(name) {
+	case "md5":
+		return authProto{md5.New, 12}, nil
+	case "sha", "sha1":
+		return authProto{sha1.New, 12}, nil
+	case "sha256":
+		return authProto{sha256.New, 24}, nil
+	default:
+		return authProto{}, fmt.Errorf("unsupported snmp auth protocol %q (expected md5, sha or sha256)", name)
+	}
+}
+
+// usm 用户安全模型状态：引擎参数、本地化密钥与加密盐
+type usm struct {
+	cfg  *Config
+	auth *authProto
+	priv string
+
+	mu         sync.Mutex
+	engineID   []byte
+	boots      int64
+	engineTime int64
+	syncedAt   time.Time
+	authKey    []byte
+	privKey    []byte
+	salt       uint64
+}
+
+func newUSM(cfg *Config) *usm {
+	u := &usm{cfg: cfg, priv: strings.ToLower(cfg.PrivProtocol), salt: uint64(time.Now().UnixNano())}
+	if cfg.AuthProtocol != "" {
+		a, _ := authFor(cfg.AuthProtocol)
+		u.auth = &a
+	}
+	return u
+}
+
+// discover 发送空请求获取 authoritative engine 参数并本地化密钥
+func (c *Client) discover(ctx context.Context) error {
+	for attempt := 0; attempt <= c.cfg.Retries; attempt++ {
+		c.requestID = (c.requestID + 1) & 0x7fffffff
+		id := c.requestID
+		pduBytes, err := encPDU(pduGet, id, 0, 0, nil)
+		if err != nil {
+			return err
+		}
+		msg, err := c.usm.encode(id, pduBytes, false)
+		if err != nil {
+			return err
+		}
+		p, err := c.roundTrip(ctx, id, msg)
+		if errors.Is(err, ErrTimeout) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("snmp engine discovery: %w", err)
+		}
+		if len(c.usm.engineID) == 0 {
+			return fmt.Errorf("snmp engine discovery: empty engine id (report %v)", p.vars)
+		}
+		c.usm.localize()
+		return nil
+	}
+	return fmt.Errorf("snmp engine discovery: %w (%s:%d)", ErrTimeout, c.cfg.Target, c.cfg.Port)
+}
+
+func (u *usm) localize() {
+	if u.auth == nil {
+		return
+	}
+	u.authKey = localizeKey(u.auth.hash, u.cfg.AuthPassword, u.engineID)
+	if u.priv != "" {
+		u.privKey = localizeKey(u.auth.hash, u.cfg.PrivPassword, u.engineID)
+	}
+}
+
+// LocalizeKey 按认证协议（md5 | sha | sha256）将口令转换为 engineID 本地化密钥（RFC 3414 A.2），认证与加密密钥均由此生成
+func LocalizeKey(authProtocol, password string, engineID []byte) ([]byte, error) {
+	a, err := authFor(authProtocol)
+	if err != nil {
+		return nil, err
+	}
+	if password == "" {
+		return nil, fmt.Errorf("snmp: empty password")
+	}
+	return localizeKey(a.hash, password, engineID), nil
+}
+
+// localizeKey RFC 3414 A.2：口令扩展为 1MB 求摘要，再与 engineID 本地化
+func localizeKey(h func() hash.Hash, password string, engineID []byte) []byte {
+	hh := h()
+	pw := []byte(password)
+	buf := make([]byte, 64)
+	idx := 0
+	for count := 0; count < 1048576; count += 64 {
+		for i := range buf {
+			buf[i] = pw[idx%len(pw)]
+			idx++
+		}
+		hh.Write(buf)
+	}
+	ku := hh.Sum(nil)
+	hh.Reset()
+	hh.Write(ku)
+	hh.Write(engineID)
+	hh.Write(ku)
+	return hh.Sum(nil)
+}
+
+// encode 组装 v3 消息；secure=false 用于引擎发现（noAuthNoPriv、空用户）
+func (u *usm) encode(id int64, pduBytes []byte, secure bool) ([]byte, error) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	flags := byte(msgFlagReportable)
+	user, engineID := "", []byte{}
+	var boots, etime int64
+	var authParams, privParams []byte
+	scoped := encSeq(encOctets(u.engineID), encOctets([]byte(u.cfg.ContextName)), pduBytes)
+	if secure {
+		user, engineID = u.cfg.User, u.engineID
+		boots = u.boots
+		etime = u.engineTime + int64(time.Since(u.syncedAt)/time.Second)
+		if u.auth != nil {
+			flags |= msgFlagAuth
+			authParams = make([]byte, u.auth.digest)
+		}
+		if u.auth != nil && u.priv != "" {
+			flags |= msgFlagPriv
+			enc, salt, err := u.encrypt(scoped, boots, etime)
+			if err != nil {
+				return nil, err
+			}
+			scoped, privParams = encOctets(enc), salt
+		}
+	}
+	secParams := encSeq(encOctets(engineID), encInt(boots), encInt(etime), encOctets([]byte(user)), encOctets(authParams), encOctets(privParams))
+	msg := encSeq(
+		encInt(3),
+		encSeq(encInt(id), encInt(maxMessageSize), encOctets([]byte{flags}), encInt(securityModelUSM)),
+		encOctets(secParams),
+		scoped,
+	)
+	if flags&msgFlagAuth != 0 {
+		slot, err := authParamsSlot(msg)
+		if err != nil {
+			return nil, err
+		}
+		copy(slot, u.sign(msg))
+	}
+	return msg, nil
+}
+
+// sign 对 authParams 置零的整条消息计算截断 HMAC
+func (u *usm) sign(msg []byte) []byte {
+	mac := hmac.New(u.auth.hash, u.authKey)
+	mac.Write(msg)
+	return mac.Sum(nil)[:u.auth.digest]
+}
+
+// v3Message 解码后的 v3 消息头与安全参数
+type v3Message struct {
+	msgID      int64
+	flags      byte
+	engineID   []byte
+	boots      int64
+	engineTime int64
+	authParams []byte // 原消息中的子切片
+	privParams []byte
+	data       []byte // scopedPDU 或加密后的 OCTET STRING 内容
+	encrypted  bool
+}
+
+func parseV3(msg []byte) (*v3Message, error) {
+	body, _, err := expectTLV(msg, tagSequence)
+	if err != nil {
+		return nil, err
+	}
+	var ver int64
+	if ver, body, err = readInt(body); err != nil {
+		return nil, err
+	}
+	if ver != 3 {
+		return nil, fmt.Errorf("snmp: unexpected message version %d", ver)
+	}
+	header, body, err := expectTLV(body, tagSequence)
+	if err != nil {
+		return nil, err
+	}
+	m := &v3Message{}
+	if m.msgID, header, err = readInt(header); err != nil {
+		return nil, err
+	}
+	if _, header, err = readInt(header); err != nil {
+		return nil, err
+	}
+	flags, _, err := expectTLV(header, tagOctetString)
+	if err != nil || len(flags) != 1 {
+		return nil, fmt.Errorf("snmp: invalid msgFlags")
+	}
+	m.flags = flags[0]
+	sp, body, err := expectTLV(body, tagOctetString)
+	if err != nil {
+		return nil, err
+	}
+	usmSeq, _, err := expectTLV(sp, tagSequence)
+	if err != nil {
+		return nil, err
+	}
+	if m.engineID, usmSeq, err = expectTLV(usmSeq, tagOctetString); err != nil {
+		return nil, err
+	}
+	if m.boots, usmSeq, err = readInt(usmSeq); err != nil {
+		return nil, err
+	}
+	if m.engineTime, usmSeq, err = readInt(usmSeq); err != nil {
+		return nil, err
+	}
+	if _, usmSeq, err = expectTLV(usmSeq, tagOctetString); err != nil {
+		return nil, err
+	}
+	if m.authParams, usmSeq, err = expectTLV(usmSeq, tagOctetString); err != nil {
+		return nil, err
+	}
+	if m.privParams, _, err = expectTLV(usmSeq, tagOctetString); err != nil {
+		return nil, err
+	}
+	tag, data, _, err := readTLV(body)
+	if err != nil {
+		return nil, err
+	}
+	m.encrypted = tag == tagOctetString
+	if m.encrypted {
+		m.data = data
+	} else {
+		m.data = body
+	}
+	return m, nil
+}
+
+// authParamsSlot 返回消息中 authParams 的子切片（用于写入或校验摘要）
+func authParamsSlot(msg []byte) ([]byte, error) {
+	m, err := parseV3(msg)
+	if err != nil {
+		return nil, err
+	}
+	return m.authParams, nil
+}
+
+// decode 校验摘要、解密并解出 PDU；同步引擎参数
+func (u *usm) decode(msg []byte) (*pdu, error) {
+	cp := append([]byte(nil), msg...)
+	m, err := parseV3(cp)
+	if err != nil {
+		return nil, err
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	// 应答携带 authoritative engine 的最新 boots/time，用于后续请求的时间窗口
+	if len(m.engineID) > 0 {
+		if len(u.engineID) == 0 {
+			u.engineID = append([]byte(nil), m.engineID...)
+		}
+		u.boots, u.engineTime, u.syncedAt = m.boots, m.engineTime, time.Now()
+	}
+	if m.flags&msgFlagAuth != 0 && u.auth != nil && len(u.authKey) > 0 {
+		got := append([]byte(nil), m.authParams...)
+		for i := range m.authParams {
+			m.authParams[i] = 0
+		}
+		if !hmac.Equal(got, u.sign(cp)) {
+			return nil, ErrWrongDigest
+		}
+	}
+	data := m.data
+	if m.encrypted {
+		if m.flags&msgFlagPriv == 0 || len(u.privKey) == 0 {
+			return nil, fmt.Errorf("snmp: unexpected encrypted response")
+		}
+		if data, err = u.decrypt(data, m.privParams, m.boots, m.engineTime); err != nil {
+			return nil, err
+		}
+	}
+	scoped, _, err := expectTLV(data, tagSequence)
+	if err != nil {
+		return nil, err
+	}
+	if _, scoped, err = expectTLV(scoped, tagOctetString); err != nil {
+		return nil, err
+	}
+	if _, scoped, err = expectTLV(scoped, tagOctetString); err != nil {
+		return nil, err
+	}
+	p, err := decPDU(scoped)
+	if err != nil {
+		return nil, err
+	}
+	// 无法解密等情况下 report 的 request-id 不可靠，按 msgID 匹配（请求使用相同编号）
+	if p.tag == pduReport {
+		p.requestID = m.msgID
+	}
+	return p, nil
+}
+
+// encrypt DES-CBC（RFC 3414）或 AES-128-CFB（RFC 3826），返回密文与 privParams（salt）
+func (u *usm) encrypt(plain []byte, boots, etime int64) ([]byte, []byte, error) {
+	u.salt++
+	switch u.priv {
+	case "des":
+		salt := make([]byte, 8)
+		binary.BigEndian.PutUint32(salt[:4], uint32(boots))
+		binary.BigEndian.PutUint32(salt[4:], uint32(u.salt))
+		block, err := des.NewCipher(u.privKey[:8])
+		if err != nil {
+			return nil, nil, err
+		}
+		iv := make([]byte, 8)
+		for i := range iv {
+			iv[i] = u.privKey[8+i] ^ salt[i]
+		}
+		if pad := len(plain) % 8; pad != 0 {
+			plain = append(append([]byte(nil), plain...), make([]byte, 8-pad)...)
+		}
+		out := make([]byte, len(plain))
+		cipher.NewCBCEncrypter(block, iv).CryptBlocks(out, plain)
+		return out, salt, nil
+	case "aes":
+		salt := make([]byte, 8)
+		binary.BigEndian.PutUint64(salt, u.salt)
+		block, err := aes.NewCipher(u.privKey[:16])
+		if err != nil {
+			return nil, nil, err
+		}
+		return cfb(block, aesIV(boots, etime, salt), plain, true), salt, nil
+	}
+	return nil, nil, fmt.Errorf("unsupported snmp privacy protocol %q", u.priv)
+}
+
+func (u *usm) decrypt(data, salt []byte, boots, etime int64) ([]byte, error) {
+	if len(salt) != 8 {
+		return nil, fmt.Errorf("snmp: invalid privacy parameters")
+	}
+	switch u.priv {
+	case "des":
+		if len(data)%8 != 0 {
+			return nil, fmt.Errorf("snmp: invalid DES ciphertext length %d", len(data))
+		}
+		block, err := des.NewCipher(u.privKey[:8])
+		if err != nil {
+			return nil, err
+		}
+		iv := make([]byte, 8)
+		for i := range iv {
+			iv[i] = u.privKey[8+i] ^ salt[i]
+		}
+		out := make([]byte, len(data))
+		cipher.NewCBCDecrypter(block, iv).CryptBlocks(out, data)
+		return out, nil
+	case "aes":
+		block, err := aes.NewCipher(u.privKey[:16])
+		if err != nil {
+			return nil, err
+		}
+		return cfb(block, aesIV(boots, etime, salt), data, false), nil
+	}
+	return nil, fmt.Errorf("unsupported snmp privacy protocol %q", u.priv)
+}
+
+func aesIV(boots, etime int64, salt []byte) []byte {
+	iv := make([]byte, 16)
+	binary.BigEndian.PutUint32(iv[:4], uint32(boots))
+	binary.BigEndian.PutUint32(iv[4:8], uint32(etime))
+	copy(iv[8:], salt)
+	return iv
+}
+
+// cfb 128 位分组的 CFB 模式
+func cfb(block cipher.Block, iv, in []byte, encrypt bool) []byte {
+	out := make([]byte, len(in))
+	reg := append([]byte(nil), iv...)
+	ks := make([]byte, block.BlockSize())
+	for off := 0; off < len(in); off += len(ks) {
+		block.Encrypt(ks, reg)
+		end := off + len(ks)
+		if end > len(in) {
+			end = len(in)
+		}
+		for i := off; i < end; i++ {
+			out[i] = in[i] ^ ks[i-off]
+		}
+		if encrypt {
+			copy(reg, out[off:end])
+		} else {
+			copy(reg, in[off:end])
+		}
+	}
+	return out
+}
+
+func reportIs(p *pdu, oid string) bool {
+	for _, v := range p.vars {
+		if v.OID == oid {
+			return true
+		}
+	}
+	return false
+}
+
+// reportError report PDU 转换为错误
+func reportError(p *pdu) error {
+	for _, v := range p.vars {
+		if name, ok := reportNames[v.OID]; ok {
+			return fmt.Errorf("snmp report: %s", name)
+		}
+	}
+	if len(p.vars) > 0 {
+		return fmt.Errorf("snmp report: %s = %s", p.vars[0].OID, p.vars[0].String())
+	}
+	return fmt.Errorf("snmp report")
+}
@@ -143,4 +143,13 @@ func WithField(key string, value interface{}) *logrus.Entry {
 // WithFields 添加多个字段
 func WithFields(fields logrus.Fields) *logrus.Entry {
 	return GetLogger().WithFields(fields)
-}
\ No newline at end of file
+}
+// SetLevel 运行时调整日志级别（不重建输出）
+func SetLevel(level string) error {
+	lv, err := logrus.ParseLevel(level)
+	if err != nil {
+		return err
+	}
+	GetLogger().SetLevel(lv)
+	return nil
+}
@@ -3,7 +3,10 @@ package ssh
 import (
 	"context"
 	"fmt"
+	"io"
 	"net"
+	"os"
+	"regexp"
 	"strconv"
 	"strings"
 	"sync"
@@ -22,6 +25,12 @@ type Config struct {
 	ConnectTimeout time.Duration `yaml:"connect_timeout"`
 	KeepAlive      time.Duration `yaml:"keep_alive"`
 	MaxSessions    int           `yaml:"max_sessions"`
+	// Proxy 拨号代理（为空直连）
+	Proxy *ProxyConfig `yaml:"-"`
+	// Egress 出站策略（为空不限制），拨号前校验目标地址
+	Egress *EgressPolicy `yaml:"-"`
+	// HostKey 主机密钥校验策略（为空不校验）
+	HostKey *HostKeyPolicy `yaml:"-"`
 }
 
 // Client SSH客户端
@@ -41,6 +50,36 @@ type ConnectionInfo struct {
 	Username string `json:"username"`
 	Password string `json:"password"`
 	KeyFile  string `json:"key_file,omitempty"`
+	// Proxy 覆盖配置中的代理（direct 表示直连）
+	Proxy string `json:"proxy,omitempty"`
+	// Platform 设备平台，用于匹配连接池空闲规则（不参与连接复用键）
+	Platform string `json:"platform,omitempty"`
+	// SingleSession 设备不支持同一连接上的多个会话：释放时关闭连接而不放回池中
+	SingleSession bool `json:"single_session,omitempty"`
+}
+
+// ConnectTrace 新建连接各阶段耗时；通过 WithConnectTrace 挂载到 Connect 的上下文，复用池内连接时不填充
+type ConnectTrace struct {
+	Dial        time.Duration // TCP 拨号（含代理）
+	KeyExchange time.Duration // 版本交换与密钥交换（至校验主机密钥）
+	Auth        time.Duration // 用户认证
+	// ServerVersion 服务端版本串（如 SSH-2.0-HUAWEI-1.5），握手成功后填充
+	ServerVersion string
+	// Banner 认证阶段服务端下发的登录横幅
+	Banner string
+}
+
+type connectTraceKey struct{}
+
+// WithConnectTrace 为上下文挂载连接阶段耗时记录
+func WithConnectTrace(ctx context.Context, t *ConnectTrace) context.Context {
+	return context.WithValue(ctx, connectTraceKey{}, t)
+}
+
+// ConnectTraceFrom 读取上下文中已挂载的连接记录
+func ConnectTraceFrom(ctx context.Context) *ConnectTrace {
+	t, _ := ctx.Value(connectTraceKey{}).(*ConnectTrace)
+	return t
 }
 
 // CommandResult 命令执行结果
@@ -50,6 +89,8 @@ type CommandResult struct {
 	Error    string        `json:"error"`
 	ExitCode int           `json:"exit_code"`
 	Duration time.Duration `json:"duration"`
+	// Raw 未经换行归一化与清洗的原始字节（仅在 RawCommands 选中时填充）
+	Raw []byte `json:"-"`
 }
 
 // InteractiveOptions 交互会话选项
@@ -57,6 +98,8 @@ type CommandResult struct {
 type InteractiveOptions struct {
 	DisablePagingCmds []string
 	PromptSuffixes    []string
+	// PromptRegex 提示符正则（匹配清洗后的整行）；配置后替代后缀判定
+	PromptRegex       []string
 	EnableCmd         string
 	EnablePassword    string
 	ConfigExitCLI     string
@@ -84,6 +127,98 @@ type InteractiveOptions struct {
 	PromptInducerMaxCount    int
 	// 条件退出配置模式
 	ConfigExitConditional bool
+	// 单条命令超时覆盖（秒），键为小写去空白的命令；优先于 PerCommandTimeoutSec
+	CommandTimeouts map[string]int
+	// 流式输出回调（可选），用于实时转发命令输出
+	Stream *StreamHooks
+	// 需要捕获原始字节的命令，键为小写去空白的命令
+	RawCommands map[string]bool
+	// 会话转录（可选），记录会话收发的全部字节及时间戳
+	Transcript *Transcript
+	// 脚本步骤（仅下发路径设置），键为 commands 中的下标；对应命令文本仅用于展示
+	Steps map[int]ScriptStep
+	// 未知确认提示检测：末行匹配任一正则且输出静默后发送安全应答，并将命令标记为 INTERRUPTED_PROMPT
+	UnknownPromptPatterns []string
+	UnknownPromptResponse string
+	// 登录序列：首个提示符之后、执行命令之前按序执行（每会话一次）
+	LoginSequence []LoginStep
+	// LineEnding 行结束符（空为 CRLF，平台 needs_crlf=false 时为 LF）
+	LineEnding string
+	// TerminalWidth 伪终端列数（0 为 80）
+	TerminalWidth int
+}
+
+// 平台能力相关的终端参数
+const (
+	defaultTerminalWidth = 80
+	// WideTerminalWidth 宽终端列数，避免长行被设备折行
+	WideTerminalWidth = 512
+)
+
+// lineEnding 发送命令与应答时使用的行结束符，默认 CRLF
+func (o *InteractiveOptions) lineEnding() string {
+	if o == nil || o.LineEnding == "" {
+		return "\r\n"
+	}
+	return o.LineEnding
+}
+
+// normalizeCommand 统一命令内部的换行为 LineEnding 并去除末尾换行（末尾结束符由发送方追加）；
+// 避免 LF 平台收到嵌入的 CR
+func (o *InteractiveOptions) normalizeCommand(cmd string) string {
+	s := strings.ReplaceAll(cmd, "\r\n", "\n")
+	s = strings.TrimRight(s, "\r\n")
+	if eol := o.lineEnding(); eol != "\n" {
+		return strings.ReplaceAll(s, "\n", eol)
+	}
+	return strings.ReplaceAll(s, "\r", "")
+}
+
+// terminalWidth 申请伪终端的列数，默认 80
+func (o *InteractiveOptions) terminalWidth() int {
+	if o == nil || o.TerminalWidth <= 0 {
+		return defaultTerminalWidth
+	}
+	return o.TerminalWidth
+}
+
+// commandTimeout 计算单条命令超时：命令级覆盖 > PerCommandTimeoutSec > def
+func (o *InteractiveOptions) commandTimeout(cmd string, def time.Duration) time.Duration {
+	if o == nil {
+		return def
+	}
+	if sec, ok := o.CommandTimeouts[strings.ToLower(strings.TrimSpace(cmd))]; ok && sec > 0 {
+		return time.Duration(sec) * time.Second
+	}
+	if o.PerCommandTimeoutSec > 0 {
+		return time.Duration(o.PerCommandTimeoutSec) * time.Second
+	}
+	return def
+}
+
+// StreamHooks 交互执行过程中的流式回调，均在执行协程中同步调用，实现方不应阻塞
+type StreamHooks struct {
+	OnCommandStart func(cmd string)
+	OnOutputLine   func(cmd, line string)
+	OnCommandEnd   func(res *CommandResult)
+}
+
+func (h *StreamHooks) commandStart(cmd string) {
+	if h != nil && h.OnCommandStart != nil {
+		h.OnCommandStart(cmd)
+	}
+}
+
+func (h *StreamHooks) outputLine(cmd, line string) {
+	if h != nil && h.OnOutputLine != nil {
+		h.OnOutputLine(cmd, line)
+	}
+}
+
+func (h *StreamHooks) commandEnd(res *CommandResult) {
+	if h != nil && h.OnCommandEnd != nil && res != nil {
+		h.OnCommandEnd(res)
+	}
 }
 
 // AutoInteraction 自动交互对
@@ -109,11 +244,25 @@ func (c *Client) Connect(ctx context.Context, info *ConnectionInfo) error {
 	// 记录连接参数以便后续自动重连
 	c.info = info
 
+	// 阶段耗时：主机密钥回调发生在密钥交换末尾，以此划分握手与认证
+	trace, _ := ctx.Value(connectTraceKey{}).(*ConnectTrace)
+	var phaseStart, kexDone time.Time
+	dialHost := connectHost(info.Host)
+
 	// 构建SSH配置
 	sshConfig := &ssh.ClientConfig{
-		User:            info.Username,
-		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
-		Timeout:         c.config.ConnectTimeout,
+		User: info.Username,
+		HostKeyCallback: func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+			kexDone = time.Now()
+			return c.config.HostKey.Verify(info, dialHost, remote, key)
+		},
+		BannerCallback: func(message string) error {
+			if trace != nil {
+				trace.Banner += message
+			}
+			return nil
+		},
+		Timeout: c.config.ConnectTimeout,
 		Config: ssh.Config{
 			// 支持旧版本的密钥交换算法
 			KeyExchanges: []string{
@@ -174,21 +323,28 @@ func (c *Client) Connect(ctx context.Context, info *ConnectionInfo) error {
 	}
 
 	if info.KeyFile != "" {
-		// TODO: 实现密钥文件认证
+		// 私钥认证优先于口令
+		bs, err := os.ReadFile(info.KeyFile)
+		if err != nil {
+			return fmt.Errorf("failed to read key file: %w", err)
+		}
+		signer, err := ssh.ParsePrivateKey(bs)
+		if err != nil {
+			return fmt.Errorf("failed to parse key file: %w", err)
+		}
+		sshConfig.Auth = append([]ssh.AuthMethod{ssh.PublicKeys(signer)}, sshConfig.Auth...)
 	}
 
 	// 连接SSH服务器
 	// 构造地址（兼容 IPv6，处理 0.0.0.0/:: 映射到本地回环）
-	host := strings.TrimSpace(info.Host)
-	if host == "" {
-		host = "127.0.0.1"
-	}
-	lhost := strings.ToLower(host)
-	if lhost == "0.0.0.0" || lhost == "::" {
-		host = "127.0.0.1"
-	}
+	host := dialHost
 	address := net.JoinHostPort(host, strconv.Itoa(info.Port))
 
+	if err := c.config.Egress.Check(ctx, host); err != nil {
+		logger.Warn("SSH egress blocked", "address", address, "tenant", EgressTenantFrom(ctx), "error", err)
+		return err
+	}
+
 	// 使用context控制连接超时
 	dialer := &net.Dialer{Timeout: c.config.ConnectTimeout}
 
@@ -199,12 +355,42 @@ func (c *Client) Connect(ctx context.Context, info *ConnectionInfo) error {
 		logger.Debugf("SSH Connect: dial start address=%s timeout=%s ctx_deadline=none", address, c.config.Timeout)
 	}
 
-	conn, err := dialer.DialContext(ctx, "tcp", address)
-	if err != nil {
-		return fmt.Errorf("failed to dial: %w", err)
+	var conn net.Conn
+	var err error
+	defer func() {
+		if trace == nil {
+			return
+		}
+		switch {
+		case !kexDone.IsZero():
+			trace.KeyExchange = kexDone.Sub(phaseStart)
+			trace.Auth = time.Since(kexDone)
+		case trace.Dial > 0:
+			trace.KeyExchange = time.Since(phaseStart)
+		default:
+			// 拨号失败
+			trace.Dial = time.Since(phaseStart)
+		}
+	}()
+	phaseStart = time.Now()
+	if proxyURL := c.config.Proxy.resolve(info); proxyURL != "" {
+		logger.Debugf("SSH Connect: dial via proxy address=%s proxy=%s", address, redactProxyURL(proxyURL))
+		conn, err = dialViaProxy(ctx, dialer, proxyURL, address)
+		if err != nil {
+			return fmt.Errorf("failed to dial via proxy %s: %w", redactProxyURL(proxyURL), err)
+		}
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", address)
+		if err != nil {
+			return fmt.Errorf("failed to dial: %w", err)
+		}
 	}
 
 	logger.Debugf("SSH Connect: tcp connected address=%s", address)
+	if trace != nil {
+		trace.Dial = time.Since(phaseStart)
+	}
+	phaseStart = time.Now()
 
 	// 为握手阶段添加截止时间，避免在某些设备上握手卡死
 	// 优先使用任务上下文的截止时间，其次使用全局 SSH 超时
@@ -236,6 +422,9 @@ func (c *Client) Connect(ctx context.Context, info *ConnectionInfo) error {
 	}
 
 	c.connection = ssh.NewClient(sshConn, chans, reqs)
+	if trace != nil {
+		trace.ServerVersion = string(sshConn.ServerVersion())
+	}
 
 	// 握手完成，清除截止时间
 	_ = conn.SetDeadline(time.Time{})
@@ -247,6 +436,19 @@ func (c *Client) Connect(ctx context.Context, info *ConnectionInfo) error {
 	return nil
 }
 
+// connectHost 实际连接的主机：空地址与 0.0.0.0/:: 映射到本地回环
+func connectHost(h string) string {
+	host := strings.TrimSpace(h)
+	if host == "" {
+		host = "127.0.0.1"
+	}
+	lhost := strings.ToLower(host)
+	if lhost == "0.0.0.0" || lhost == "::" {
+		host = "127.0.0.1"
+	}
+	return host
+}
+
 // newSessionWithRetry 创建会话（带重试）
 // 针对部分网络设备首次或快速连续打开会话通道可能返回
 // "ssh: rejected: administratively prohibited (open failed)" 的情况，
@@ -304,8 +506,39 @@ func (c *Client) newSessionWithRetry() (*ssh.Session, error) {
 	return nil, lastErr
 }
 
+// Subsystem 打开 SSH 子系统会话（如 netconf），返回会话的标准输入与标准输出；会话随 Close 一并关闭
+func (c *Client) Subsystem(name string) (io.WriteCloser, io.Reader, error) {
+	sess, err := c.newSessionWithRetry()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create session: %w", err)
+	}
+	stdin, err := sess.StdinPipe()
+	if err != nil {
+		sess.Close()
+		return nil, nil, fmt.Errorf("failed to get stdin pipe: %w", err)
+	}
+	stdout, err := sess.StdoutPipe()
+	if err != nil {
+		sess.Close()
+		return nil, nil, fmt.Errorf("failed to get stdout pipe: %w", err)
+	}
+	if err := sess.RequestSubsystem(name); err != nil {
+		sess.Close()
+		return nil, nil, fmt.Errorf("failed to request subsystem %s: %w", name, err)
+	}
+	c.mutex.Lock()
+	c.sessions["subsystem:"+name] = sess
+	c.mutex.Unlock()
+	return stdin, stdout, nil
+}
+
 // ExecuteCommand 执行单个命令
 func (c *Client) ExecuteCommand(ctx context.Context, command string) (*CommandResult, error) {
+	return c.executeCommand(ctx, command, false)
+}
+
+// executeCommand 非交互执行单条命令；keepRaw 时保留未转换的原始字节
+func (c *Client) executeCommand(ctx context.Context, command string, keepRaw bool) (*CommandResult, error) {
 	if c == nil {
 		return nil, fmt.Errorf("SSH client is nil")
 	}
@@ -340,6 +573,9 @@ func (c *Client) ExecuteCommand(ctx context.Context, command string) (*CommandRe
 	case <-done:
 		result.Duration = time.Since(startTime)
 		result.Output = util.EnsureUTF8Bytes(output)
+		if keepRaw {
+			result.Raw = output
+		}
 		
 		// Debug日志：记录命令回显的head/tail-lines
 		logger.DebugCommandOutput(command, result.Output, 5)
@@ -399,6 +635,32 @@ func (c *Client) ExecuteCommands(ctx context.Context, commands []string) ([]*Com
 	return results, nil
 }
 
+// ExecuteCommandsWithOptions 非交互批量执行，按 opts 为每条命令施加超时（命令级覆盖 > PerCommandTimeoutSec），
+// 命令内换行按 LineEnding 统一
+// opts 为空或未配置超时时与 ExecuteCommands 一致，仅受 ctx 约束
+func (c *Client) ExecuteCommandsWithOptions(ctx context.Context, commands []string, opts *InteractiveOptions) ([]*CommandResult, error) {
+	if c == nil {
+		return nil, fmt.Errorf("SSH client is nil")
+	}
+	results := make([]*CommandResult, 0, len(commands))
+	for _, command := range commands {
+		if ctx.Err() != nil {
+			return results, ctx.Err()
+		}
+		cmdCtx, cancel := ctx, context.CancelFunc(func() {})
+		if to := opts.commandTimeout(command, 0); to > 0 {
+			cmdCtx, cancel = context.WithTimeout(ctx, to)
+		}
+		result, _ := c.executeCommand(cmdCtx, opts.normalizeCommand(command), opts.captureRaw(command))
+		cancel()
+		if result != nil {
+			result.Command = command
+			results = append(results, result)
+		}
+	}
+	return results, nil
+}
+
 // ExecuteInteractiveCommand 执行交互式命令
 func (c *Client) ExecuteInteractiveCommand(ctx context.Context, command string, responses []string) (*CommandResult, error) {
 	if c == nil {
@@ -533,6 +795,7 @@ func (c *Client) ExecuteInteractiveCommands(ctx context.Context, commands []stri
 	if c.connection == nil {
 		return nil, fmt.Errorf("SSH connection not established")
 	}
+	eol := opts.lineEnding()
 
 	// 创建会话（带重试）
 	session, err := c.newSessionWithRetry()
@@ -553,7 +816,7 @@ func (c *Client) ExecuteInteractiveCommands(ctx context.Context, commands []stri
 	{
 		var lastErr error
 		for _, term := range []string{"vt100", "xterm", "ansi", "dumb"} {
-			if ptyErr := session.RequestPty(term, 80, 24, modes); ptyErr == nil {
+			if ptyErr := session.RequestPty(term, opts.terminalWidth(), 24, modes); ptyErr == nil {
 				lastErr = nil
 				break
 			} else {
@@ -570,8 +833,9 @@ func (c *Client) ExecuteInteractiveCommands(ctx context.Context, commands []stri
 	logger.Debugf("SSH Interactive: session created; commands=%d", len(commands))
 	// 选项摘要，便于现场定位交互行为差异（提示符后缀、退出命令、回显跳过、提权参数、自动交互项数）
 	if opts != nil {
-		logger.Debugf("SSH Interactive options: prompt_suffixes=%v exit_cmds=%v skip_delayed_echo=%v cmd_interval_ms=%d enable_cli=%q expect=%q auto_interactions=%d",
+		logger.Debugf("SSH Interactive options: prompt_suffixes=%v prompt_regex=%q exit_cmds=%v skip_delayed_echo=%v cmd_interval_ms=%d enable_cli=%q expect=%q auto_interactions=%d",
 			promptSuffixes,
+			opts.PromptRegex,
 			opts.ExitCommands,
 			opts.SkipDelayedEcho,
 			opts.CommandIntervalMS,
@@ -598,6 +862,12 @@ func (c *Client) ExecuteInteractiveCommands(ctx context.Context, commands []stri
 		session.Close()
 		return nil, fmt.Errorf("failed to get stderr: %w", err)
 	}
+	// 会话转录（按需）：此后经 stdin/stdout/stderr 的字节均写入转录
+	if t := opts.transcript(); t != nil {
+		c.beginTranscript(t)
+		stdin, stdout, stderr = t.tapSession(stdin, stdout, stderr)
+		defer t.note("session closed")
+	}
 
 	// 启动交互式Shell
 	if err := session.Shell(); err != nil {
@@ -607,8 +877,8 @@ func (c *Client) ExecuteInteractiveCommands(ctx context.Context, commands []stri
 
 	logger.Debug("SSH Interactive: shell started; sending CRLF to elicit prompt")
 
-	// 发送 CRLF 促使设备输出当前提示符，便于后续检测（网络设备通常期望 CRLF）
-	stdin.Write([]byte("\r\n"))
+	// 发送换行促使设备输出当前提示符，便于后续检测（网络设备通常期望 CRLF，见 LineEnding）
+	stdin.Write([]byte(eol))
 
 	// 提示符诱发参数
 	piInterval := 1000 * time.Millisecond
@@ -636,12 +906,18 @@ func (c *Client) ExecuteInteractiveCommands(ctx context.Context, commands []stri
 				if count >= piMax {
 					return
 				}
-				stdin.Write([]byte("\r\n"))
+				stdin.Write([]byte(eol))
 				count++
 			}
 		}
 	}()
 
+	// 原始字节旁路（按需）
+	tap := newRawTap(opts)
+	// 未知确认提示检测器与读取协程中尚未换行的末行
+	unknown := opts.unknownPrompt()
+	var pending pendingLine
+
 	// 读取输出的协程，将数据按行推送到通道
 	lineCh := make(chan string, 4096)
 	doneCh := make(chan struct{})
@@ -652,6 +928,7 @@ func (c *Client) ExecuteInteractiveCommands(ctx context.Context, commands []stri
 		for {
 			n, err := stdout.Read(buf)
 			if n > 0 {
+				tap.write(buf[:n])
 				acc.Write(buf[:n])
 				s := acc.String()
 				// 统一换行符：仅将 CRLF -> \n；保留孤立 CR 作为行续行（去除），避免将回车误判为换行
@@ -664,6 +941,7 @@ func (c *Client) ExecuteInteractiveCommands(ctx context.Context, commands []stri
 				if len(lines) > 0 {
 					acc.WriteString(lines[len(lines)-1])
 				}
+				pending.set(acc.String())
 				for i := 0; i < len(lines)-1; i++ {
 					line := lines[i]
 					// 阻塞推送，避免丢失关键信息（例如提示符）
@@ -683,6 +961,7 @@ func (c *Client) ExecuteInteractiveCommands(ctx context.Context, commands []stri
 		for {
 			n, err := stderr.Read(buf)
 			if n > 0 {
+				tap.write(buf[:n])
 				acc.Write(buf[:n])
 				s := acc.String()
 				// 统一换行符：仅将 CRLF -> \n；孤立 CR 去除，避免命令回显被拆成多行
@@ -738,12 +1017,21 @@ func (c *Client) ExecuteInteractiveCommands(ctx context.Context, commands []stri
 	// 当进入 sudo 提权阶段时，放宽提示符前缀要求（用户->root 提示符前缀会变化）
 	var relaxPromptPrefix bool
 
+	// 平台提示符正则：配置后替代后缀启发式，避免横幅中的 > / # 误判
+	var promptRes []*regexp.Regexp
+	if opts != nil && len(opts.PromptRegex) > 0 {
+		promptRes = compilePromptRegex(opts.PromptRegex)
+	}
+
 	// 辅助函数：判断行是否是提示符（先清洗再匹配后缀；若已捕获前缀，且未放宽，则要求包含前缀）
 	isPrompt := func(line string) bool {
 		trimmed := strings.TrimSpace(sanitize(line))
 		if trimmed == "" {
 			return false
 		}
+		if len(promptRes) > 0 {
+			return matchPromptRegex(promptRes, trimmed)
+		}
 		for _, suf := range promptSuffixes {
 			if strings.HasSuffix(trimmed, suf) {
 				// 如已捕获前缀，则进一步校验；sudo 提权阶段放宽前缀检查
@@ -824,6 +1112,25 @@ Ready:
 	}
 
 StartCommands:
+	// 平台登录序列（如 OLT 需 enable/config/清屏后命令才生效）
+	if opts != nil && len(opts.LoginSequence) > 0 {
+		if err := runLoginSequence(ctx, stdin, lineCh, &pending, sanitize, opts.LoginSequence); err != nil {
+			stdin.Close()
+			session.Close()
+			return nil, fmt.Errorf("%w: %w", ErrLoginSequence, err)
+		}
+		// 丢弃登录序列的残留输出，避免计入第一条命令
+		time.Sleep(loginStepSettle)
+	DrainLogin:
+		for {
+			select {
+			case <-lineCh:
+			default:
+				break DrainLogin
+			}
+		}
+	}
+
 	results := make([]*CommandResult, 0, len(commands))
 	// 记录上一条已发送命令，用于跳过其延迟回显（常见于网络设备在提示符后一并回显上一条命令）
 	prevCmd := ""
@@ -833,7 +1140,30 @@ StartCommands:
 	eq := func(a, b string) bool { return strings.EqualFold(strings.TrimSpace(a), strings.TrimSpace(b)) }
 	// 使用客户端方法，结合设备名与提示符后缀进行精确判定
 	isConfigPromptLine := func(line string) bool { return c.isConfigPromptLine(line, opts) }
-	for _, cmd := range commands {
+	var stream *StreamHooks
+	if opts != nil {
+		stream = opts.Stream
+	}
+	for i, cmd := range commands {
+		// 脚本步骤（原样发送控制字符 / 固定等待）：不追加回车，不等待提示符
+		if step, ok := opts.scriptStep(i); ok {
+			logger.Debugf("SSH Interactive: run script step: %s", step)
+			settle := time.Duration(0)
+			if opts != nil && opts.CommandIntervalMS > 0 {
+				settle = time.Duration(opts.CommandIntervalMS) * time.Millisecond
+			}
+			stream.commandStart(step.String())
+			result, err := runScriptStep(ctx, stdin, lineCh, sanitize, step, settle)
+			if result != nil {
+				results = append(results, result)
+				stream.commandEnd(result)
+			}
+			if err != nil {
+				return results, err
+			}
+			prevCmd = ""
+			continue
+		}
 		logger.Debugf("SSH Interactive: send command: %s", cmd)
 		// 写入命令；若写入失败，认为会话已不可用，返回错误以触发上层回退
 		if opts != nil && opts.ConfigExitConditional && opts.ConfigExitCLI != "" && eq(cmd, opts.ConfigExitCLI) {
@@ -847,6 +1177,8 @@ StartCommands:
 					Duration: 0,
 				}
 				results = append(results, result)
+				stream.commandStart(cmd)
+				stream.commandEnd(result)
 				// 添加debug日志，记录设备回显信息
 				logger.DebugCommandOutput(cmd, result.Output, 5)
 				// 对齐后续节奏：记录上一条命令并应用命令间隔
@@ -857,7 +1189,10 @@ StartCommands:
 				continue
 			}
 		}
-		if _, err := stdin.Write([]byte(cmd + "\r\n")); err != nil {
+		if opts.captureRaw(cmd) {
+			tap.start()
+		}
+		if _, err := stdin.Write([]byte(opts.normalizeCommand(cmd) + eol)); err != nil {
 			// 关闭输入并等待读取协程结束，避免资源泄露
 			stdin.Close()
 			select {
@@ -866,6 +1201,7 @@ StartCommands:
 			}
 			return nil, fmt.Errorf("failed to write command: %w", err)
 		}
+		stream.commandStart(cmd)
 
 		// 收集输出直到下一个提示符
 		var out strings.Builder
@@ -886,6 +1222,9 @@ StartCommands:
 		}
 		// 自动交互仅命中一次（每条命令），触发后不再重复执行
 		autoInteractDone := false
+		// 已由自动交互应答的行，以及被安全应答中断的确认提示行
+		aiLine := ""
+		interruptedLine := ""
 		// 针对提权命令的密码输入增加超时回退：若未检测到提示，按时发送一次
 		enableFallbackSent := false
 		var enableFallback <-chan time.Time
@@ -944,11 +1283,8 @@ StartCommands:
 		if opts != nil && opts.QuietPollIntervalMS > 0 {
 			quietPoll = time.Duration(opts.QuietPollIntervalMS) * time.Millisecond
 		}
-		// 单条命令超时（可调）
-		perCmdTimeout := 30 * time.Second
-		if opts != nil && opts.PerCommandTimeoutSec > 0 {
-			perCmdTimeout = time.Duration(opts.PerCommandTimeoutSec) * time.Second
-		}
+		// 单条命令超时（可调）：命令级覆盖 > 平台 PerCommandTimeoutSec > 30s
+		perCmdTimeout := opts.commandTimeout(cmd, 30*time.Second)
 		for {
 			select {
 			case <-ctx.Done():
@@ -963,6 +1299,7 @@ StartCommands:
 					Duration: time.Since(cmdStart),
 				}
 				results = append(results, result)
+				stream.commandEnd(result)
 				// 添加debug日志，记录设备回显信息
 				logger.DebugCommandOutput(cmd, result.Output, 5)
 				// 返回上层错误以触发服务层的非交互回退逻辑，避免只返回预命令导致结果为空
@@ -1010,12 +1347,14 @@ StartCommands:
 					// 4) 其他情况：认为回显已结束，从此行计入输出
 					echoRemain = ""
 				}
+				// 确认提示（如 "[Y/N]"）可能以提示符后缀结尾，不作为命令结束标志
+				confirmLine := unknown.match(clean)
 				// 若尚未看到内容且遇到提示符，认为是前序残留提示符，跳过
-				if isPrompt(clean) && !sawContent {
+				if isPrompt(clean) && !sawContent && !confirmLine {
 					continue
 				}
 				// 若是提示符行（命令结束标志），不要写入输出，直接结束该命令
-				if isPrompt(clean) {
+				if isPrompt(clean) && !confirmLine {
 					// 更新最近提示符行，用于后续条件退出判断
 					lastPromptLine = clean
 					// 针对提权命令：校验提示符是否进入特权模式（以 '#' 结尾）
@@ -1052,6 +1391,7 @@ StartCommands:
 				// 写入正常内容
 				out.WriteString(clean)
 				out.WriteString("\n")
+				stream.outputLine(cmd, clean)
 				outLineCount++
 				if strings.TrimSpace(clean) != "" {
 					sawContent = true
@@ -1083,7 +1423,7 @@ StartCommands:
 									pwdToSend = lp
 								}
 							}
-							stdin.Write([]byte(pwdToSend + "\r\n"))
+							stdin.Write([]byte(pwdToSend + eol))
 							enableFallbackSent = true
 							// 标记提权完成并取消回退通道，避免密码被误当作下一条命令
 							enableDone = true
@@ -1104,7 +1444,7 @@ StartCommands:
 									pwdToSend = lp
 								}
 							}
-							stdin.Write([]byte(pwdToSend + "\r\n"))
+							stdin.Write([]byte(pwdToSend + eol))
 							enableFallbackSent = true
 							// 标记提权完成并取消回退通道，避免密码被误当作下一条命令
 							enableDone = true
@@ -1129,7 +1469,7 @@ StartCommands:
 							if !sorryRetryDone {
 								lp := strings.TrimSpace(opts.LoginPassword)
 								if lp != "" {
-									stdin.Write([]byte(lp + "\r\n"))
+									stdin.Write([]byte(lp + eol))
 									sorryRetryDone = true
 									// 不立即结束，继续等待提示符，以确保进入特权模式
 									continue
@@ -1146,9 +1486,10 @@ StartCommands:
 							continue
 						}
 						if strings.Contains(lower, strings.ToLower(ai.ExpectOutput)) {
-							stdin.Write([]byte(ai.AutoSend + "\r\n"))
+							stdin.Write([]byte(ai.AutoSend + eol))
 							// 命中后标记不再重复自动执行
 							autoInteractDone = true
+							aiLine = clean
 							break
 						}
 					}
@@ -1164,7 +1505,7 @@ StartCommands:
 							pwdToSend = lp
 						}
 					}
-					stdin.Write([]byte(pwdToSend + "\r\n"))
+					stdin.Write([]byte(pwdToSend + eol))
 					enableFallbackSent = true
 					// 密码发送后等待足够时间让设备处理，避免与下一条命令时序冲突
 					time.Sleep(500 * time.Millisecond)
@@ -1177,6 +1518,22 @@ StartCommands:
 			// 静默完成检测：在已经读取到内容(sawContent)的情况下，如果持续一段时间未再收到输出，认为命令已完成
 			// 该逻辑可以避免因提示符识别失败导致的“总是等到整体超时”问题
 			case <-time.After(quietPoll):
+				// 未知确认提示：末行（含未换行部分）匹配且静默，且未被自动交互处理时发送安全应答
+				if unknown != nil && interruptedLine == "" {
+					tail, tailAt := pending.get()
+					candidate := sanitize(tail)
+					if strings.TrimSpace(candidate) == "" {
+						candidate = lastCleanLine
+						tailAt = lastRecvAt
+					}
+					if candidate != aiLine && time.Since(tailAt) >= quietAfter && time.Since(lastRecvAt) >= quietAfter && unknown.match(candidate) {
+						logger.Warnf("SSH Interactive: unanswered confirmation prompt, sending safe response; cmd=%q line=%q", cmd, candidate)
+						stdin.Write(unknown.response)
+						interruptedLine = strings.TrimSpace(candidate)
+						lastRecvAt = time.Now()
+						continue
+					}
+				}
 				// 修复：对于无输出命令（如terminal length 0），在命令启动后足够时间内未收到任何输出，也认为完成
 				timeSinceStart := time.Since(cmdStart)
 				timeSinceLastRecv := time.Since(lastRecvAt)
@@ -1234,6 +1591,17 @@ StartCommands:
 			}
 		}
 	NextCmd:
+		if raw := tap.stop(); raw != nil && len(results) > 0 {
+			results[len(results)-1].Raw = raw
+		}
+		if interruptedLine != "" && len(results) > 0 {
+			last := results[len(results)-1]
+			last.Error = StatusInterruptedPrompt + ": " + interruptedLine
+			last.ExitCode = ExitCodeInterruptedPrompt
+		}
+		if len(results) > 0 {
+			stream.commandEnd(results[len(results)-1])
+		}
 		logger.Debugf("SSH Interactive: command finished: %s; duration=%s; bytes=%d", cmd, time.Since(cmdStart), len(out.String()))
 		// 离开当前命令后恢复提示符前缀检查
 		relaxPromptPrefix = false
@@ -1252,7 +1620,7 @@ StartCommands:
 		exitSeq = opts.ExitCommands
 	}
 	for _, ec := range exitSeq {
-		stdin.Write([]byte(ec + "\r\n"))
+		stdin.Write([]byte(ec + eol))
 		// 退出命令发送间隔（可调）
 		exitPause := 150 * time.Millisecond
 		if opts != nil && opts.ExitPauseMS > 0 {
@@ -1365,12 +1733,17 @@ func (c *Client) DetectPrompt(ctx context.Context, promptSuffixes []string, opts
 	if c.connection == nil {
 		return "", fmt.Errorf("SSH connection not established")
 	}
+	eol := opts.lineEnding()
 
 	session, err := c.newSessionWithRetry()
 	if err != nil {
 		return "", fmt.Errorf("failed to create session: %w", err)
 	}
 	defer session.Close()
+	var promptRes []*regexp.Regexp
+	if opts != nil {
+		promptRes = compilePromptRegex(opts.PromptRegex)
+	}
 
 	modes := ssh.TerminalModes{
 		ssh.ECHO:          1,
@@ -1380,7 +1753,7 @@ func (c *Client) DetectPrompt(ctx context.Context, promptSuffixes []string, opts
 	{
 		var lastErr error
 		for _, term := range []string{"vt100", "xterm", "ansi", "dumb"} {
-			if ptyErr := session.RequestPty(term, 80, 24, modes); ptyErr == nil {
+			if ptyErr := session.RequestPty(term, opts.terminalWidth(), 24, modes); ptyErr == nil {
 				lastErr = nil
 				break
 			} else {
@@ -1435,7 +1808,7 @@ func (c *Client) DetectPrompt(ctx context.Context, promptSuffixes []string, opts
 				if count >= piMax {
 					return
 				}
-				stdin.Write([]byte("\r\n"))
+				stdin.Write([]byte(eol))
 				count++
 			}
 		}
@@ -1532,17 +1905,15 @@ func (c *Client) DetectPrompt(ctx context.Context, promptSuffixes []string, opts
 			if trimmed == "" {
 				continue
 			}
-			for _, suf := range promptSuffixes {
-				if strings.HasSuffix(trimmed, suf) {
-					close(stop)
-					stdin.Close()
-					// 等待读取结束片刻
-					select {
-					case <-doneCh:
-					case <-time.After(200 * time.Millisecond):
-					}
-					return trimmed, nil
+			if isPromptLine(promptRes, promptSuffixes, trimmed) {
+				close(stop)
+				stdin.Close()
+				// 等待读取结束片刻
+				select {
+				case <-doneCh:
+				case <-time.After(200 * time.Millisecond):
 				}
+				return trimmed, nil
 			}
 		case <-time.After(3 * time.Second):
 			if time.Since(start) > 10*time.Second {
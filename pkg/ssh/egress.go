@@ -0,0 +1,117 @@
+package ssh
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+)
+
+// ErrEgressBlocked 目标地址不在出站策略允许范围内，拨号前拒绝
+var ErrEgressBlocked = errors.New("EGRESS_BLOCKED")
+
+// EgressRule 出站规则：Deny 命中即拒绝；Allow 非空时目标须命中其一。条目为 IP、CIDR 或主机名
+type EgressRule struct {
+	Allow []string
+	Deny  []string
+}
+
+// EgressPolicy 出站策略：全局规则对所有连接生效；上下文带租户（WithEgressTenant）且配置了该租户时，
+// 须同时满足租户规则
+type EgressPolicy struct {
+	EgressRule
+	Tenants map[string]EgressRule // 键为小写租户标识
+}
+
+type egressTenantKey struct{}
+
+// WithEgressTenant 为上下文挂载租户标识，连接时按租户规则校验（不区分大小写）
+func WithEgressTenant(ctx context.Context, tenant string) context.Context {
+	if tenant = strings.TrimSpace(tenant); tenant == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, egressTenantKey{}, tenant)
+}
+
+// EgressTenantFrom 读取上下文中的租户标识
+func EgressTenantFrom(ctx context.Context) string {
+	t, _ := ctx.Value(egressTenantKey{}).(string)
+	return t
+}
+
+// Check 校验目标主机；主机名解析出的每个地址都须允许，策略含 allow 时无法解析的主机名直接拒绝
+func (p *EgressPolicy) Check(ctx context.Context, host string) error {
+	if p == nil {
+		return nil
+	}
+	host = strings.TrimSpace(host)
+	if h := strings.ToLower(host); h == "" || h == "0.0.0.0" || h == "::" {
+		host = "127.0.0.1" // 与 Connect 的地址映射一致
+	}
+	tenant := strings.ToLower(EgressTenantFrom(ctx))
+	rules := []struct {
+		scope string
+		rule  EgressRule
+	}{{"ssh.egress", p.EgressRule}}
+	if tr, ok := p.Tenants[tenant]; ok && tenant != "" {
+		rules = append(rules, struct {
+			scope string
+			rule  EgressRule
+		}{"ssh.egress.tenants." + tenant, tr})
+	}
+
+	ips := []net.IP{net.ParseIP(host)}
+	if ips[0] == nil {
+		addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+		if err != nil {
+			for _, r := range rules {
+				if len(r.rule.Allow) > 0 {
+					return fmt.Errorf("%w: %s cannot be resolved to check %s allow list", ErrEgressBlocked, host, r.scope)
+				}
+			}
+		}
+		ips = ips[:0]
+		for _, a := range addrs {
+			ips = append(ips, a.IP)
+		}
+	}
+	for _, r := range rules {
+		if reason := r.rule.denied(host, ips); reason != "" {
+			return fmt.Errorf("%w: %s %s by %s", ErrEgressBlocked, host, reason, r.scope)
+		}
+	}
+	return nil
+}
+
+// denied 返回拒绝原因，允许时为空串
+func (r EgressRule) denied(host string, ips []net.IP) string {
+	targets := ips
+	if len(targets) == 0 {
+		targets = []net.IP{nil} // 未解析：仅按主机名匹配
+	}
+	for _, ip := range targets {
+		for _, d := range r.Deny {
+			if matchProxyTarget(d, host, ip) {
+				return "matches deny " + strings.TrimSpace(d)
+			}
+		}
+		if len(r.Allow) == 0 {
+			continue
+		}
+		allowed := false
+		for _, a := range r.Allow {
+			if matchProxyTarget(a, host, ip) {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			if ip != nil && ip.String() != host {
+				return "(" + ip.String() + ") is not in allow list"
+			}
+			return "is not in allow list"
+		}
+	}
+	return ""
+}
@@ -0,0 +1,142 @@
+package ssh
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// ErrHostKeyRejected 设备主机密钥未通过校验（与记录不一致或严格模式下未登记）
+var ErrHostKeyRejected = errors.New("HOST_KEY_REJECTED")
+
+// 主机密钥校验模式
+const (
+	HostKeyInsecure   = "insecure"    // 不校验（默认，兼容旧行为）
+	HostKeyKnownHosts = "known_hosts" // 仅按 known_hosts 文件校验，未登记的设备拒绝
+	HostKeyTOFU       = "tofu"        // 首次连接记录指纹，之后必须一致
+	HostKeyStrict     = "strict"      // 仅接受 known_hosts 文件或已记录的指纹
+)
+
+// HostKeyRecord 已记录的设备主机密钥
+type HostKeyRecord struct {
+	Host        string
+	Port        int
+	KeyType     string
+	Fingerprint string // SHA256:...
+	PublicKey   string // authorized_keys 格式
+}
+
+// HostKeyStore 主机密钥指纹持久化（TOFU 首次记录、strict 校验）
+type HostKeyStore interface {
+	HostKeys(host string, port int) ([]HostKeyRecord, error)
+	// SaveHostKey 新增记录或刷新最近校验时间
+	SaveHostKey(rec HostKeyRecord) error
+}
+
+// HostKeyRule 按平台/设备覆盖校验模式，按顺序匹配首条
+type HostKeyRule struct {
+	Platform string // 平台键，为空匹配所有平台
+	Match    string // IP、CIDR、主机名或通配，为空匹配所有主机
+	Mode     string
+}
+
+// HostKeyPolicy 主机密钥校验策略；为空时不校验
+type HostKeyPolicy struct {
+	Mode       string
+	KnownHosts []string // known_hosts 文件，tofu/strict 下同样参与校验
+	Rules      []HostKeyRule
+	Store      HostKeyStore
+}
+
+// ModeFor 设备生效的校验模式
+func (p *HostKeyPolicy) ModeFor(info *ConnectionInfo) string {
+	if p == nil {
+		return HostKeyInsecure
+	}
+	for _, r := range p.Rules {
+		if r.Platform != "" && !strings.EqualFold(strings.TrimSpace(r.Platform), strings.TrimSpace(info.Platform)) {
+			continue
+		}
+		if r.Match != "" && !MatchHostPattern(r.Match, info.Host) {
+			continue
+		}
+		return normalizeHostKeyMode(r.Mode)
+	}
+	return normalizeHostKeyMode(p.Mode)
+}
+
+func normalizeHostKeyMode(m string) string {
+	if m = strings.ToLower(strings.TrimSpace(m)); m == "" {
+		return HostKeyInsecure
+	}
+	return m
+}
+
+// Verify 在密钥交换末尾校验设备主机密钥（host 为实际连接的地址）
+func (p *HostKeyPolicy) Verify(info *ConnectionInfo, host string, remote net.Addr, key ssh.PublicKey) error {
+	mode := p.ModeFor(info)
+	if mode == HostKeyInsecure {
+		return nil
+	}
+	address := net.JoinHostPort(host, strconv.Itoa(info.Port))
+	fp := ssh.FingerprintSHA256(key)
+
+	// known_hosts 文件：命中即通过，密钥不符直接拒绝
+	if len(p.KnownHosts) > 0 {
+		cb, err := knownhosts.New(p.KnownHosts...)
+		if err != nil {
+			return fmt.Errorf("%w: load known_hosts: %v", ErrHostKeyRejected, err)
+		}
+		if ip := net.ParseIP(host); ip != nil {
+			remote = &net.TCPAddr{IP: ip, Port: info.Port}
+		}
+		err = cb(address, remote, key)
+		if err == nil {
+			return nil
+		}
+		var ke *knownhosts.KeyError
+		if !errors.As(err, &ke) {
+			return fmt.Errorf("%w: %v", ErrHostKeyRejected, err)
+		}
+		if len(ke.Want) > 0 {
+			return fmt.Errorf("%w: %s presented %s %s which does not match known_hosts (possible man-in-the-middle)", ErrHostKeyRejected, address, key.Type(), fp)
+		}
+	}
+	if mode == HostKeyKnownHosts {
+		return fmt.Errorf("%w: %s (%s %s) is not in known_hosts", ErrHostKeyRejected, address, key.Type(), fp)
+	}
+	if mode != HostKeyTOFU && mode != HostKeyStrict {
+		return fmt.Errorf("%w: unknown host key mode %q", ErrHostKeyRejected, mode)
+	}
+	if p.Store == nil {
+		return fmt.Errorf("%w: host key store is not configured for %s mode", ErrHostKeyRejected, mode)
+	}
+
+	recs, err := p.Store.HostKeys(host, info.Port)
+	if err != nil {
+		return fmt.Errorf("%w: load stored host keys: %v", ErrHostKeyRejected, err)
+	}
+	rec := HostKeyRecord{Host: host, Port: info.Port, KeyType: key.Type(), Fingerprint: fp, PublicKey: strings.TrimSpace(string(ssh.MarshalAuthorizedKey(key)))}
+	for _, r := range recs {
+		if r.KeyType == rec.KeyType && r.Fingerprint == fp {
+			_ = p.Store.SaveHostKey(rec)
+			return nil
+		}
+	}
+	if len(recs) > 0 {
+		return fmt.Errorf("%w: %s presented %s %s but stored fingerprint is %s %s (possible man-in-the-middle; remove the stored key if the device was replaced)",
+			ErrHostKeyRejected, address, key.Type(), fp, recs[0].KeyType, recs[0].Fingerprint)
+	}
+	if mode == HostKeyStrict {
+		return fmt.Errorf("%w: %s (%s %s) has no known_hosts entry or stored fingerprint", ErrHostKeyRejected, address, key.Type(), fp)
+	}
+	if err := p.Store.SaveHostKey(rec); err != nil {
+		return fmt.Errorf("%w: store host key: %v", ErrHostKeyRejected, err)
+	}
+	return nil
+}
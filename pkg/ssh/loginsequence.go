@@ -0,0 +1,70 @@
+package ssh
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/sshcollectorpro/sshcollectorpro/pkg/logger"
+)
+
+// 登录序列单步默认等待时间
+const (
+	defaultLoginStepTimeout = 5 * time.Second
+	loginStepSettle         = 300 * time.Millisecond
+)
+
+// ErrLoginSequence 登录序列失败；exec 通道无法执行登录步骤，调用方不应回退非交互执行
+var ErrLoginSequence = errors.New("login sequence failed")
+
+// LoginStep 登录后附加步骤：发送 Send，并等待输出包含 Expect（大小写不敏感）
+// Expect 为空时仅发送并短暂等待输出平稳
+type LoginStep struct {
+	Send       string
+	Expect     string
+	TimeoutSec int
+}
+
+// runLoginSequence 在首个提示符之后按序执行登录步骤；任一步骤等待超时即失败
+func runLoginSequence(ctx context.Context, stdin io.Writer, lineCh <-chan string, pending *pendingLine, sanitize func(string) string, steps []LoginStep) error {
+	for i, st := range steps {
+		if _, err := stdin.Write([]byte(st.Send + "\r\n")); err != nil {
+			return fmt.Errorf("login step %d: write failed: %w", i+1, err)
+		}
+		expect := strings.ToLower(strings.TrimSpace(st.Expect))
+		timeout := defaultLoginStepTimeout
+		if st.TimeoutSec > 0 {
+			timeout = time.Duration(st.TimeoutSec) * time.Second
+		}
+		deadline := time.After(timeout)
+		if expect == "" {
+			deadline = time.After(loginStepSettle)
+		}
+	Wait:
+		for {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case line := <-lineCh:
+				if expect != "" && strings.Contains(strings.ToLower(sanitize(line)), expect) {
+					break Wait
+				}
+			case <-time.After(100 * time.Millisecond):
+				// 期望文本常为不带换行的提示符，检查未完成的末行
+				if tail, _ := pending.get(); expect != "" && strings.Contains(strings.ToLower(sanitize(tail)), expect) {
+					break Wait
+				}
+			case <-deadline:
+				if expect == "" {
+					break Wait
+				}
+				return fmt.Errorf("login step %d (%q): timed out after %s waiting for %q", i+1, st.Send, timeout, st.Expect)
+			}
+		}
+		logger.Debugf("SSH Interactive: login step %d done: %s", i+1, st.Send)
+	}
+	return nil
+}
@@ -2,7 +2,12 @@ package ssh
 
 import (
     "context"
+    "crypto/hmac"
+    "crypto/rand"
+    "crypto/sha256"
+    "encoding/hex"
     "fmt"
+    "sort"
     "sync"
     "time"
 
@@ -18,6 +23,12 @@ type Pool struct {
 	maxActive   int
 	idleTimeout time.Duration
 	cleanupInterval time.Duration
+	maxIdlePerHost int
+	idleRules      []IdleRule
+	maxAge         time.Duration
+	evictions      map[string]int64
+	lastReap       time.Time
+	reapStop       chan struct{}
 }
 
 // pooledConnection 池化的连接
@@ -27,6 +38,7 @@ type pooledConnection struct {
 	lastUsed   time.Time
 	inUse      bool
 	created    time.Time
+	policy     idlePolicy
 }
 
 // PoolConfig 连接池配置
@@ -36,6 +48,11 @@ type PoolConfig struct {
 	IdleTimeout    time.Duration `yaml:"idle_timeout"`
 	CleanupInterval time.Duration `yaml:"cleanup_interval"`
 	SSHConfig      *Config       `yaml:"ssh"`
+	// MaxIdlePerHost 每台设备保留的空闲连接上限（0 不限），IdleRules 可按平台/主机覆盖
+	MaxIdlePerHost int        `yaml:"max_idle_per_host"`
+	IdleRules      []IdleRule `yaml:"-"`
+	// MaxConnectionAge 连接最大存活时长（0 不限），超龄连接不再复用，空闲时由清理协程关闭
+	MaxConnectionAge time.Duration `yaml:"max_connection_age"`
 }
 
 // NewPool 创建SSH连接池
@@ -46,6 +63,10 @@ func NewPool(config *PoolConfig) *Pool {
 		maxIdle:     config.MaxIdle,
 		maxActive:   config.MaxActive,
 		idleTimeout: config.IdleTimeout,
+		maxIdlePerHost: config.MaxIdlePerHost,
+		idleRules:      config.IdleRules,
+		maxAge:         config.MaxConnectionAge,
+		evictions:      make(map[string]int64),
 	}
 	ci := config.CleanupInterval
 	if ci <= 0 {
@@ -54,7 +75,7 @@ func NewPool(config *PoolConfig) *Pool {
 	pool.cleanupInterval = ci
 
 	// 启动清理协程
-	go pool.cleanup()
+	pool.startReaperLocked()
 
 	return pool
 }
@@ -62,22 +83,43 @@ func NewPool(config *PoolConfig) *Pool {
 // GetConnection 获取SSH连接
 func (p *Pool) GetConnection(ctx context.Context, info *ConnectionInfo) (*Client, error) {
     key := p.getConnectionKey(info)
+    target := info.logTarget()
+
+    // 出站策略在复用池内连接前同样校验（策略收紧或租户不同时不得复用）
+    p.mutex.RLock()
+    egress := p.config.Egress
+    p.mutex.RUnlock()
+    if err := egress.Check(ctx, info.Host); err != nil {
+        logger.Warn("SSH pool: egress blocked", "target", target, "tenant", EgressTenantFrom(ctx), "error", err)
+        return nil, err
+    }
 
     p.mutex.Lock()
     defer p.mutex.Unlock()
 
-    logger.Debugf("SSH pool: GetConnection start key=%s", key)
+    logger.Debugf("SSH pool: GetConnection start target=%s", target)
+    p.startReaperLocked()
+    // 空闲超时或超龄的连接不再复用（清理协程按周期运行，期间设备侧可能已因空闲断开）
+    if conn, exists := p.connections[key]; exists {
+        if reason := p.expiredReasonLocked(conn, time.Now()); reason != "" {
+            p.removeLocked(key, reason)
+        }
+    }
     // 查找现有连接
     if conn, exists := p.connections[key]; exists {
         if !conn.inUse && conn.client.IsConnected() {
             conn.inUse = true
             conn.lastUsed = time.Now()
-            logger.Debugf("SSH pool: reuse connection key=%s created=%s", key, conn.created.Format(time.RFC3339))
+            logger.Debugf("SSH pool: reuse connection target=%s created=%s", target, conn.created.Format(time.RFC3339))
             return conn.client, nil
         }
         // 连接已断开或正在使用，删除
-        logger.Debugf("SSH pool: drop stale/busy connection key=%s in_use=%v alive=%v", key, conn.inUse, conn.client.IsConnected())
-        delete(p.connections, key)
+        logger.Debugf("SSH pool: drop stale/busy connection target=%s in_use=%v alive=%v", target, conn.inUse, conn.client.IsConnected())
+        if !conn.client.IsConnected() {
+            p.removeLocked(key, EvictBroken)
+        } else {
+            delete(p.connections, key)
+        }
     }
 
 	// 检查连接数限制
@@ -90,7 +132,7 @@ func (p *Pool) GetConnection(ctx context.Context, info *ConnectionInfo) (*Client
 	// 创建新连接
     client := NewClient(p.config)
     if err := client.Connect(ctx, info); err != nil {
-        logger.Error("SSH pool: connect failed", "key", key, "error", err)
+        logger.Error("SSH pool: connect failed", "target", target, "error", err)
         return nil, fmt.Errorf("failed to create SSH connection: %w", err)
     }
 
@@ -101,9 +143,10 @@ func (p *Pool) GetConnection(ctx context.Context, info *ConnectionInfo) (*Client
         lastUsed: time.Now(),
         inUse:    true,
         created:  time.Now(),
+        policy:   p.resolveIdlePolicy(info),
     }
 
-    logger.Debugf("SSH pool: new connection established key=%s", key)
+    logger.Debugf("SSH pool: new connection established target=%s", target)
     return client, nil
 }
 
@@ -117,14 +160,25 @@ func (p *Pool) ReleaseConnection(info *ConnectionInfo) {
     if conn, exists := p.connections[key]; exists {
         // 若连接已失效，立即关闭并从池中移除，避免后续复用导致 EOF
         if !conn.client.IsConnected() {
+            p.removeLocked(key, EvictBroken)
+            return
+        }
+        // 不支持多会话的设备不复用连接
+        if info.SingleSession {
             conn.client.Close()
             delete(p.connections, key)
-            logger.Debugf("SSH pool: release and remove dead connection key=%s", key)
+            logger.Debugf("SSH pool: release and close single-session connection target=%s", info.logTarget())
             return
         }
         conn.inUse = false
         conn.lastUsed = time.Now()
-        logger.Debugf("SSH pool: release connection key=%s", key)
+        // 超过最大存活时长的连接用完即关闭
+        if p.maxAgeExpired(conn, conn.lastUsed) {
+            p.removeLocked(key, EvictMaxAge)
+            return
+        }
+        logger.Debugf("SSH pool: release connection target=%s", info.logTarget())
+        p.enforceHostIdleLocked(conn.info.Host, conn.policy.maxIdlePerHost)
     }
 }
 
@@ -182,6 +236,7 @@ func (p *Pool) Close() error {
 	p.mutex.Lock()
 	defer p.mutex.Unlock()
 
+	p.stopReaperLocked()
 	var lastErr error
 	for key, conn := range p.connections {
 		if err := conn.client.Close(); err != nil {
@@ -193,6 +248,24 @@ func (p *Pool) Close() error {
 	return lastErr
 }
 
+// Reconfigure 配置热更新：调整连接数上限、空闲策略与新建连接使用的 SSH 参数
+// 已建立的连接保持原参数，空闲策略按新规则重新匹配，最大存活时长对已有连接同样生效；CleanupInterval 需重启生效
+func (p *Pool) Reconfigure(config *PoolConfig) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.config = config.SSHConfig
+	p.maxIdle = config.MaxIdle
+	p.maxActive = config.MaxActive
+	p.idleTimeout = config.IdleTimeout
+	p.maxIdlePerHost = config.MaxIdlePerHost
+	p.idleRules = config.IdleRules
+	p.maxAge = config.MaxConnectionAge
+	for _, conn := range p.connections {
+		conn.policy = p.resolveIdlePolicy(conn.info)
+	}
+}
+
 // GetStats 获取连接池统计信息
 func (p *Pool) GetStats() map[string]interface{} {
 	p.mutex.RLock()
@@ -204,13 +277,38 @@ func (p *Pool) GetStats() map[string]interface{} {
 		"idle_connections":   p.getIdleCount(),
 		"max_idle":          p.maxIdle,
 		"max_active":        p.maxActive,
+		"max_idle_per_host": p.maxIdlePerHost,
+		"hosts":             p.hostStatsLocked(),
+		"max_connection_age_sec": int(p.maxAge.Seconds()),
+		"cleanup_interval_sec":   int(p.cleanupInterval.Seconds()),
+	}
+	stats["evictions"], stats["evictions_total"] = p.evictionStatsLocked()
+	if !p.lastReap.IsZero() {
+		stats["last_cleanup"] = p.lastReap
 	}
 
 	return stats
 }
 
-// getConnectionKey 生成连接键
+// connKeySecret 连接键摘要的进程级随机密钥：摘要无法离线猜测口令，重启后失效
+var connKeySecret = func() []byte {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		panic(fmt.Sprintf("ssh pool: failed to generate connection key secret: %v", err))
+	}
+	return b
+}()
+
+// getConnectionKey 生成连接键（仅用于池内索引，不得写入日志）
+// 附带凭据摘要：连接跨请求复用时，口令不同的请求不能复用他人已认证的连接（摘要含密钥文件与代理覆盖）
 func (p *Pool) getConnectionKey(info *ConnectionInfo) string {
+	mac := hmac.New(sha256.New, connKeySecret)
+	mac.Write([]byte(info.Password + "\x00" + info.KeyFile + "\x00" + info.Proxy))
+	return fmt.Sprintf("%s:%d@%s#%s", info.Host, info.Port, info.Username, hex.EncodeToString(mac.Sum(nil)))
+}
+
+// logTarget 日志中的连接标识：仅 host:port@user
+func (info *ConnectionInfo) logTarget() string {
 	return fmt.Sprintf("%s:%d@%s", info.Host, info.Port, info.Username)
 }
 
@@ -236,66 +334,6 @@ func (p *Pool) getIdleCount() int {
 	return count
 }
 
-// cleanup 清理过期连接
-func (p *Pool) cleanup() {
-	// 使用可配置清理周期（默认 30s）
-	ticker := time.NewTicker(p.cleanupInterval)
-	defer ticker.Stop()
-
-	for range ticker.C {
-		p.cleanupExpiredConnections()
-	}
-}
-
-// cleanupExpiredConnections 清理过期连接
-func (p *Pool) cleanupExpiredConnections() {
-    p.mutex.Lock()
-    defer p.mutex.Unlock()
-
-    now := time.Now()
-    toDelete := make([]string, 0)
-
-	for key, conn := range p.connections {
-		// 清理超时的空闲连接
-		if !conn.inUse && now.Sub(conn.lastUsed) > p.idleTimeout {
-			toDelete = append(toDelete, key)
-			continue
-		}
-
-		// 清理断开的连接
-		if !conn.client.IsConnected() {
-			toDelete = append(toDelete, key)
-			continue
-		}
-	}
-
-    // 删除过期连接
-    for _, key := range toDelete {
-        if conn, exists := p.connections[key]; exists {
-            conn.client.Close()
-            delete(p.connections, key)
-            logger.Debugf("SSH pool: cleanup remove key=%s", key)
-        }
-    }
-
-	// 如果空闲连接过多，关闭一些
-	idleCount := p.getIdleCount()
-    if idleCount > p.maxIdle {
-        excess := idleCount - p.maxIdle
-        for key, conn := range p.connections {
-            if excess <= 0 {
-                break
-            }
-            if !conn.inUse {
-                conn.client.Close()
-                delete(p.connections, key)
-                excess--
-                logger.Debugf("SSH pool: reduce idle remove key=%s", key)
-            }
-        }
-    }
-}
-
 // Health 健康检查
 func (p *Pool) Health() error {
 	p.mutex.RLock()
@@ -318,4 +356,66 @@ func (p *Pool) Health() error {
 	}
 
 	return nil
-}
\ No newline at end of file
+}
+// ConnectionSnapshot 连接池内单个连接的快照（不含口令）
+type ConnectionSnapshot struct {
+	Host     string    `json:"host"`
+	Port     int       `json:"port"`
+	Username string    `json:"username"`
+	InUse    bool      `json:"in_use"`
+	Alive    bool      `json:"alive"`
+	Created  time.Time `json:"created"`
+	LastUsed time.Time `json:"last_used"`
+}
+
+// Snapshot 列出池内连接，按 host/port/username 排序
+func (p *Pool) Snapshot() []ConnectionSnapshot {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+
+	out := make([]ConnectionSnapshot, 0, len(p.connections))
+	for _, conn := range p.connections {
+		out = append(out, ConnectionSnapshot{
+			Host:     conn.info.Host,
+			Port:     conn.info.Port,
+			Username: conn.info.Username,
+			InUse:    conn.inUse,
+			Alive:    conn.client.IsConnected(),
+			Created:  conn.created,
+			LastUsed: conn.lastUsed,
+		})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Host != out[j].Host {
+			return out[i].Host < out[j].Host
+		}
+		if out[i].Port != out[j].Port {
+			return out[i].Port < out[j].Port
+		}
+		return out[i].Username < out[j].Username
+	})
+	return out
+}
+
+// Evict 手动驱逐连接：host 为空表示全部，port<=0 表示不限端口
+// force=false 时跳过使用中的连接；返回关闭的连接数
+func (p *Pool) Evict(host string, port int, force bool) int {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	n := 0
+	for key, conn := range p.connections {
+		if host != "" && conn.info.Host != host {
+			continue
+		}
+		if port > 0 && conn.info.Port != port {
+			continue
+		}
+		if conn.inUse && !force {
+			continue
+		}
+		p.removeLocked(key, EvictManual)
+		n++
+	}
+	return n
+}
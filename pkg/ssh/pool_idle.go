@@ -0,0 +1,131 @@
+package ssh
+
+import (
+	"net"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sshcollectorpro/sshcollectorpro/pkg/logger"
+)
+
+// IdleRule 按平台/主机覆盖连接池空闲策略，按顺序匹配首条
+type IdleRule struct {
+	Platform string // 平台键，为空匹配所有平台
+	Match    string // IP、CIDR、主机名或通配（如 core-*），为空匹配所有主机
+	// MaxIdle 每台设备保留的空闲连接上限；0 表示用完即关闭，nil 沿用连接池 MaxIdlePerHost
+	MaxIdle     *int
+	IdleTimeout time.Duration // 0 沿用连接池 IdleTimeout
+}
+
+// idlePolicy 单个连接生效的空闲策略（建连时确定）
+type idlePolicy struct {
+	maxIdlePerHost int // <0 不限
+	idleTimeout    time.Duration
+}
+
+// HostPoolStats 单台设备的连接占用
+type HostPoolStats struct {
+	Host           string `json:"host"`
+	Total          int    `json:"total"`
+	InUse          int    `json:"in_use"`
+	Idle           int    `json:"idle"`
+	MaxIdle        int    `json:"max_idle"` // -1 不限
+	IdleTimeoutSec int    `json:"idle_timeout_sec"`
+}
+
+// resolveIdlePolicy 按平台与主机匹配空闲规则，未命中时使用连接池默认值
+func (p *Pool) resolveIdlePolicy(info *ConnectionInfo) idlePolicy {
+	pol := idlePolicy{maxIdlePerHost: -1, idleTimeout: p.idleTimeout}
+	if p.maxIdlePerHost > 0 {
+		pol.maxIdlePerHost = p.maxIdlePerHost
+	}
+	for _, r := range p.idleRules {
+		if r.Platform != "" && !strings.EqualFold(strings.TrimSpace(r.Platform), strings.TrimSpace(info.Platform)) {
+			continue
+		}
+		if r.Match != "" && !MatchHostPattern(r.Match, info.Host) {
+			continue
+		}
+		if r.MaxIdle != nil {
+			pol.maxIdlePerHost = *r.MaxIdle
+		}
+		if r.IdleTimeout > 0 {
+			pol.idleTimeout = r.IdleTimeout
+		}
+		break
+	}
+	return pol
+}
+
+// MatchHostPattern 主机匹配：IP、CIDR、主机名（忽略大小写）或含 * 的通配
+func MatchHostPattern(pattern, host string) bool {
+	pattern = strings.TrimSpace(pattern)
+	if strings.Contains(pattern, "*") {
+		ok, err := path.Match(strings.ToLower(pattern), strings.ToLower(strings.TrimSpace(host)))
+		return err == nil && ok
+	}
+	return matchProxyTarget(pattern, host, net.ParseIP(strings.TrimSpace(host)))
+}
+
+// idleExpired 空闲连接是否已超过空闲时长（超时连接不再复用，设备侧 VTY 可能已断开）
+func (c *pooledConnection) idleExpired(now time.Time) bool {
+	return !c.inUse && c.policy.idleTimeout > 0 && now.Sub(c.lastUsed) > c.policy.idleTimeout
+}
+
+// enforceHostIdleLocked 按连接策略限制该设备的空闲连接数，超出时优先关闭最久未用的连接；调用方持有锁
+func (p *Pool) enforceHostIdleLocked(host string, limit int) {
+	if limit < 0 {
+		return
+	}
+	idle := p.idleKeysLocked(func(c *pooledConnection) bool { return c.info.Host == host })
+	for i := 0; i < len(idle)-limit; i++ {
+		logger.Debugf("SSH pool: host idle cap remove target=%s max_idle=%d", p.connections[idle[i]].info.logTarget(), limit)
+		p.removeLocked(idle[i], EvictIdleCap)
+	}
+}
+
+// idleKeysLocked 满足条件的空闲连接键，按最近使用时间由旧到新排序
+func (p *Pool) idleKeysLocked(filter func(*pooledConnection) bool) []string {
+	keys := make([]string, 0)
+	for key, conn := range p.connections {
+		if !conn.inUse && filter(conn) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return p.connections[keys[i]].lastUsed.Before(p.connections[keys[j]].lastUsed)
+	})
+	return keys
+}
+
+// HostStats 按设备统计连接占用，按 host 排序
+func (p *Pool) HostStats() []HostPoolStats {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+	return p.hostStatsLocked()
+}
+
+func (p *Pool) hostStatsLocked() []HostPoolStats {
+	byHost := make(map[string]*HostPoolStats)
+	for _, conn := range p.connections {
+		h := byHost[conn.info.Host]
+		if h == nil {
+			h = &HostPoolStats{Host: conn.info.Host, MaxIdle: conn.policy.maxIdlePerHost, IdleTimeoutSec: int(conn.policy.idleTimeout.Seconds())}
+			byHost[conn.info.Host] = h
+		}
+		h.Total++
+		if conn.inUse {
+			h.InUse++
+		} else {
+			h.Idle++
+		}
+	}
+	out := make([]HostPoolStats, 0, len(byHost))
+	for _, h := range byHost {
+		out = append(out, *h)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Host < out[j].Host })
+	return out
+}
@@ -0,0 +1,121 @@
+package ssh
+
+import (
+	"time"
+
+	"github.com/sshcollectorpro/sshcollectorpro/pkg/logger"
+)
+
+// 连接驱逐原因，GetStats 的 evictions 按原因计数
+const (
+	EvictIdleTimeout = "idle_timeout" // 空闲超时
+	EvictBroken      = "broken"       // 连接已断开
+	EvictMaxAge      = "max_age"      // 超过最大存活时长
+	EvictIdleCap     = "idle_cap"     // 空闲连接数超出上限（全局或按设备）
+	EvictManual      = "manual"       // 管理接口驱逐
+)
+
+var evictReasons = []string{EvictIdleTimeout, EvictBroken, EvictMaxAge, EvictIdleCap, EvictManual}
+
+// startReaperLocked 启动后台清理协程；调用方持有锁（或池尚未共享）
+func (p *Pool) startReaperLocked() {
+	if p.reapStop != nil {
+		return
+	}
+	stop := make(chan struct{})
+	p.reapStop = stop
+	go p.reaper(p.cleanupInterval, stop)
+}
+
+// stopReaperLocked 停止后台清理协程；池关闭后再次取连接时重新启动
+func (p *Pool) stopReaperLocked() {
+	if p.reapStop != nil {
+		close(p.reapStop)
+		p.reapStop = nil
+	}
+}
+
+// reaper 按清理周期（ssh.cleanup_interval，默认 30s）回收过期连接
+func (p *Pool) reaper(interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			p.Reap()
+		case <-stop:
+			return
+		}
+	}
+}
+
+// Reap 立即执行一次清理：关闭空闲超时、已断开、超过最大存活时长的空闲连接，并将空闲连接数压到上限内；
+// 使用中的连接仅在已断开时移除，超龄的使用中连接在释放时关闭。返回关闭的连接数
+func (p *Pool) Reap() int {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	now := time.Now()
+	p.lastReap = now
+	n := 0
+	for key, conn := range p.connections {
+		reason := p.expiredReasonLocked(conn, now)
+		if reason == "" && !conn.client.IsConnected() {
+			reason = EvictBroken
+		}
+		if reason != "" {
+			p.removeLocked(key, reason)
+			n++
+		}
+	}
+
+	// 如果空闲连接过多，优先关闭最久未用的连接
+	idle := p.idleKeysLocked(func(*pooledConnection) bool { return true })
+	for i := 0; i < len(idle)-p.maxIdle; i++ {
+		p.removeLocked(idle[i], EvictIdleCap)
+		n++
+	}
+	return n
+}
+
+// expiredReasonLocked 空闲连接是否应回收：空闲超时或超过最大存活时长；使用中的连接返回空
+func (p *Pool) expiredReasonLocked(conn *pooledConnection, now time.Time) string {
+	if conn.inUse {
+		return ""
+	}
+	if conn.idleExpired(now) {
+		return EvictIdleTimeout
+	}
+	if p.maxAgeExpired(conn, now) {
+		return EvictMaxAge
+	}
+	return ""
+}
+
+// maxAgeExpired 连接自建立起是否已超过最大存活时长（0 不限）
+func (p *Pool) maxAgeExpired(conn *pooledConnection, now time.Time) bool {
+	return p.maxAge > 0 && now.Sub(conn.created) > p.maxAge
+}
+
+// removeLocked 关闭并移除连接，按原因累计驱逐次数；调用方持有锁
+func (p *Pool) removeLocked(key, reason string) {
+	conn, ok := p.connections[key]
+	if !ok {
+		return
+	}
+	conn.client.Close()
+	delete(p.connections, key)
+	p.evictions[reason]++
+	logger.Debugf("SSH pool: evict target=%s reason=%s in_use=%v", conn.info.logTarget(), reason, conn.inUse)
+}
+
+// evictionStatsLocked 各原因的驱逐次数（含 0）
+func (p *Pool) evictionStatsLocked() (map[string]int64, int64) {
+	out := make(map[string]int64, len(evictReasons))
+	var total int64
+	for _, r := range evictReasons {
+		out[r] = p.evictions[r]
+		total += p.evictions[r]
+	}
+	return out, total
+}
@@ -0,0 +1,73 @@
+package ssh
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/sshcollectorpro/sshcollectorpro/pkg/logger"
+)
+
+// promptRegexCache 已编译的提示符正则，按模式串缓存
+var promptRegexCache sync.Map
+
+// ValidatePromptRegex 校验提示符正则是否可编译
+func ValidatePromptRegex(patterns []string) error {
+	for _, p := range patterns {
+		if _, err := regexp.Compile(p); err != nil {
+			return fmt.Errorf("invalid prompt_regex %q: %w", p, err)
+		}
+	}
+	return nil
+}
+
+// compilePromptRegex 编译提示符正则，无效模式记录告警后跳过
+func compilePromptRegex(patterns []string) []*regexp.Regexp {
+	out := make([]*regexp.Regexp, 0, len(patterns))
+	for _, p := range patterns {
+		if p == "" {
+			continue
+		}
+		if v, ok := promptRegexCache.Load(p); ok {
+			out = append(out, v.(*regexp.Regexp))
+			continue
+		}
+		re, err := regexp.Compile(p)
+		if err != nil {
+			logger.Warn("Invalid prompt_regex ignored", "pattern", p, "error", err)
+			continue
+		}
+		promptRegexCache.Store(p, re)
+		out = append(out, re)
+	}
+	return out
+}
+
+// matchPromptRegex 清洗后的整行是否匹配任一提示符正则
+func matchPromptRegex(res []*regexp.Regexp, line string) bool {
+	for _, re := range res {
+		if re.MatchString(line) {
+			return true
+		}
+	}
+	return false
+}
+
+// isPromptLine 配置了提示符正则时仅按正则判定，否则按后缀判定
+func isPromptLine(res []*regexp.Regexp, suffixes []string, line string) bool {
+	if len(res) > 0 {
+		return matchPromptRegex(res, line)
+	}
+	for _, suf := range suffixes {
+		if strings.HasSuffix(line, suf) {
+			return true
+		}
+	}
+	return false
+}
+
+// MatchPrompt 判断清洗后的行是否为提示符：regex 非空时按正则，否则按后缀
+func MatchPrompt(regex, suffixes []string, line string) bool {
+	return isPromptLine(compilePromptRegex(regex), suffixes, strings.TrimSpace(line))
+}
@@ -0,0 +1,180 @@
+package ssh
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"golang.org/x/net/proxy"
+)
+
+// ProxyDirect 代理设置为该值时直连（用于在全局代理下排除个别设备）
+const ProxyDirect = "direct"
+
+// ProxyConfig SSH 拨号代理：全局代理与按设备 IP/CIDR 的覆盖
+type ProxyConfig struct {
+	URL       string
+	Overrides []ProxyOverride
+}
+
+// ProxyOverride 设备级代理覆盖，Match 为 IP 或 CIDR
+type ProxyOverride struct {
+	Match string
+	URL   string
+}
+
+// resolve 返回设备使用的代理地址，空串表示直连
+// 优先级：连接参数 Proxy > 首个匹配的覆盖项 > 全局 URL
+func (p *ProxyConfig) resolve(info *ConnectionInfo) string {
+	u := strings.TrimSpace(info.Proxy)
+	if u == "" && p != nil {
+		u = strings.TrimSpace(p.URL)
+		ip := net.ParseIP(strings.TrimSpace(info.Host))
+		for _, o := range p.Overrides {
+			if matchProxyTarget(o.Match, info.Host, ip) {
+				u = strings.TrimSpace(o.URL)
+				break
+			}
+		}
+	}
+	if strings.EqualFold(u, ProxyDirect) {
+		return ""
+	}
+	return u
+}
+
+func matchProxyTarget(match, host string, ip net.IP) bool {
+	match = strings.TrimSpace(match)
+	if match == "" {
+		return false
+	}
+	if strings.Contains(match, "/") {
+		_, n, err := net.ParseCIDR(match)
+		return err == nil && ip != nil && n.Contains(ip)
+	}
+	if m := net.ParseIP(match); m != nil && ip != nil {
+		return m.Equal(ip)
+	}
+	return strings.EqualFold(match, strings.TrimSpace(host))
+}
+
+// ValidateProxyURL 校验代理地址：支持 socks5、socks5h 与 http（CONNECT）
+func ValidateProxyURL(raw string) error {
+	if raw == "" || strings.EqualFold(raw, ProxyDirect) {
+		return nil
+	}
+	_, err := parseProxyURL(raw)
+	return err
+}
+
+func parseProxyURL(raw string) (*url.URL, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy url: %w", err)
+	}
+	if u.Hostname() == "" {
+		return nil, fmt.Errorf("invalid proxy url: missing host")
+	}
+	switch strings.ToLower(u.Scheme) {
+	case "socks5", "socks5h":
+		if u.Port() == "" {
+			u.Host = net.JoinHostPort(u.Hostname(), "1080")
+		}
+	case "http":
+		if u.Port() == "" {
+			u.Host = net.JoinHostPort(u.Hostname(), "8080")
+		}
+	default:
+		return nil, fmt.Errorf("unsupported proxy scheme: %s", u.Scheme)
+	}
+	return u, nil
+}
+
+// dialViaProxy 经代理建立到 address 的 TCP 连接
+func dialViaProxy(ctx context.Context, dialer *net.Dialer, proxyURL, address string) (net.Conn, error) {
+	u, err := parseProxyURL(proxyURL)
+	if err != nil {
+		return nil, err
+	}
+	if strings.ToLower(u.Scheme) == "http" {
+		return dialHTTPConnect(ctx, dialer, u, address)
+	}
+	var auth *proxy.Auth
+	if u.User != nil {
+		pass, _ := u.User.Password()
+		auth = &proxy.Auth{User: u.User.Username(), Password: pass}
+	}
+	d, err := proxy.SOCKS5("tcp", u.Host, auth, dialer)
+	if err != nil {
+		return nil, err
+	}
+	return d.(proxy.ContextDialer).DialContext(ctx, "tcp", address)
+}
+
+// dialHTTPConnect 通过 HTTP CONNECT 隧道连接目标
+func dialHTTPConnect(ctx context.Context, dialer *net.Dialer, u *url.URL, address string) (net.Conn, error) {
+	conn, err := dialer.DialContext(ctx, "tcp", u.Host)
+	if err != nil {
+		return nil, err
+	}
+	if dl, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(dl)
+	} else if dialer.Timeout > 0 {
+		_ = conn.SetDeadline(time.Now().Add(dialer.Timeout))
+	}
+	req := &http.Request{
+		Method: http.MethodConnect,
+		URL:    &url.URL{Opaque: address},
+		Host:   address,
+		Header: make(http.Header),
+	}
+	if u.User != nil {
+		pass, _ := u.User.Password()
+		cred := base64.StdEncoding.EncodeToString([]byte(u.User.Username() + ":" + pass))
+		req.Header.Set("Proxy-Authorization", "Basic "+cred)
+	}
+	if err := req.Write(conn); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("proxy connect: %w", err)
+	}
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, req)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("proxy connect: %w", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		conn.Close()
+		return nil, fmt.Errorf("proxy connect: %s", resp.Status)
+	}
+	_ = conn.SetDeadline(time.Time{})
+	// 代理可能已转发设备的 SSH 版本行，保留已缓冲的数据
+	if br.Buffered() > 0 {
+		return &bufferedConn{Conn: conn, r: br}, nil
+	}
+	return conn, nil
+}
+
+// bufferedConn 先读取缓冲区中剩余数据的连接
+type bufferedConn struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+func (c *bufferedConn) Read(b []byte) (int, error) { return c.r.Read(b) }
+
+// redactProxyURL 日志与错误中隐藏代理口令
+func redactProxyURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "invalid"
+	}
+	return u.Redacted()
+}
@@ -0,0 +1,78 @@
+package ssh
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+)
+
+// maxRawCapture 单条命令原始字节捕获上限，超出部分丢弃
+const maxRawCapture = 16 << 20
+
+// rawTap 交互会话的原始字节旁路：在换行归一化与清洗之前记录设备回传的字节流
+type rawTap struct {
+	mu  sync.Mutex
+	on  bool
+	buf bytes.Buffer
+}
+
+// newRawTap 仅当选项要求捕获原始字节时创建
+func newRawTap(opts *InteractiveOptions) *rawTap {
+	if opts == nil || len(opts.RawCommands) == 0 {
+		return nil
+	}
+	return &rawTap{}
+}
+
+// write 记录读取到的字节（未开始捕获时忽略）
+func (t *rawTap) write(p []byte) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !t.on {
+		return
+	}
+	if room := maxRawCapture - t.buf.Len(); room > 0 {
+		if len(p) > room {
+			p = p[:room]
+		}
+		t.buf.Write(p)
+	}
+}
+
+// start 开始捕获（发送命令前调用）
+func (t *rawTap) start() {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	t.on = true
+	t.buf.Reset()
+	t.mu.Unlock()
+}
+
+// stop 结束捕获并返回本次字节副本
+func (t *rawTap) stop() []byte {
+	if t == nil {
+		return nil
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !t.on {
+		return nil
+	}
+	t.on = false
+	out := append([]byte(nil), t.buf.Bytes()...)
+	t.buf.Reset()
+	return out
+}
+
+// captureRaw 判断命令是否需要捕获原始字节
+func (o *InteractiveOptions) captureRaw(cmd string) bool {
+	if o == nil || len(o.RawCommands) == 0 {
+		return false
+	}
+	return o.RawCommands[strings.ToLower(strings.TrimSpace(cmd))]
+}
@@ -0,0 +1,140 @@
+package ssh
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 脚本步骤：仅由下发路径构造，经 InteractiveOptions.Steps 按命令下标传入交互引擎
+// 命令文本本身从不被解析为步骤，采集/备份/格式化路径无法通过命令内容触发
+const (
+	StepKindSendRaw  = "send_raw"
+	StepKindSleepMS  = "sleep_ms"
+	scriptStepSettle = 300 * time.Millisecond
+	maxScriptSleepMS = 600000
+)
+
+// ScriptStep 脚本步骤：send_raw 原样发送字节（不追加回车），sleep_ms 固定等待
+type ScriptStep struct {
+	Kind    string
+	Raw     []byte
+	SleepMS int
+}
+
+// SendRawStep 构造原样发送步骤，raw 支持 \xNN、\e、\r、\n、\t 转义
+func SendRawStep(raw string) ScriptStep {
+	return ScriptStep{Kind: StepKindSendRaw, Raw: DecodeRawEscapes(raw)}
+}
+
+// SleepStep 构造固定等待步骤（毫秒）
+func SleepStep(ms int) ScriptStep {
+	return ScriptStep{Kind: StepKindSleepMS, SleepMS: ms}
+}
+
+// String 步骤的可读形式（用于日志与结果展示）
+func (s ScriptStep) String() string {
+	if s.Kind == StepKindSendRaw {
+		return fmt.Sprintf("<send_raw %q>", string(s.Raw))
+	}
+	return "<sleep_ms " + strconv.Itoa(s.SleepMS) + ">"
+}
+
+// scriptStep 返回第 i 条命令对应的脚本步骤
+func (o *InteractiveOptions) scriptStep(i int) (ScriptStep, bool) {
+	if o == nil || len(o.Steps) == 0 {
+		return ScriptStep{}, false
+	}
+	st, ok := o.Steps[i]
+	return st, ok
+}
+
+// DecodeRawEscapes 解析 \xNN、\e(ESC)、\r、\n、\t、\\ 转义；无法识别的转义原样保留
+func DecodeRawEscapes(s string) []byte {
+	out := make([]byte, 0, len(s))
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i+1 >= len(s) {
+			out = append(out, s[i])
+			continue
+		}
+		switch s[i+1] {
+		case 'x', 'X':
+			if i+4 <= len(s) {
+				if v, err := strconv.ParseUint(s[i+2:i+4], 16, 8); err == nil {
+					out = append(out, byte(v))
+					i += 3
+					continue
+				}
+			}
+			out = append(out, s[i])
+			continue
+		case 'e':
+			out = append(out, 0x1b)
+		case 'r':
+			out = append(out, '\r')
+		case 'n':
+			out = append(out, '\n')
+		case 't':
+			out = append(out, '\t')
+		case '\\':
+			out = append(out, '\\')
+		default:
+			out = append(out, s[i])
+			continue
+		}
+		i++
+	}
+	return out
+}
+
+// runScriptStep 执行步骤：send_raw 原样写入后短暂收集输出；sleep_ms 等待期间收集输出，避免计入下一条命令
+func runScriptStep(ctx context.Context, stdin io.Writer, lineCh <-chan string, sanitize func(string) string, step ScriptStep, settle time.Duration) (*CommandResult, error) {
+	start := time.Now()
+	res := &CommandResult{Command: step.String()}
+	wait := settle
+	if wait <= 0 {
+		wait = scriptStepSettle
+	}
+	switch step.Kind {
+	case StepKindSendRaw:
+		if _, err := stdin.Write(step.Raw); err != nil {
+			return nil, fmt.Errorf("failed to write raw step: %w", err)
+		}
+	case StepKindSleepMS:
+		if step.SleepMS < 0 || step.SleepMS > maxScriptSleepMS {
+			res.Error = "invalid sleep_ms: " + strconv.Itoa(step.SleepMS)
+			res.ExitCode = -1
+			res.Duration = time.Since(start)
+			return res, nil
+		}
+		wait = time.Duration(step.SleepMS) * time.Millisecond
+	default:
+		res.Error = "unknown script step: " + step.Kind
+		res.ExitCode = -1
+		res.Duration = time.Since(start)
+		return res, nil
+	}
+	var out strings.Builder
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			res.Output = out.String()
+			res.Error = ctx.Err().Error()
+			res.ExitCode = -1
+			res.Duration = time.Since(start)
+			return res, ctx.Err()
+		case line := <-lineCh:
+			out.WriteString(sanitize(line))
+			out.WriteString("\n")
+		case <-timer.C:
+			res.Output = out.String()
+			res.Duration = time.Since(start)
+			return res, nil
+		}
+	}
+}
@@ -0,0 +1,186 @@
+package ssh
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultTranscriptMaxBytes 会话转录默认上限
+const DefaultTranscriptMaxBytes = 8 << 20
+
+// 转录方向标记
+const (
+	transcriptSent   = ">"
+	transcriptStdout = "<"
+	transcriptStderr = "!"
+)
+
+// Transcript 交互会话的完整 PTY 转录：按时间顺序记录发送与接收的原始字节（含相对时间戳），
+// 用于排查提示符识别与自动交互问题。每行格式为 `+秒.微秒 方向 "转义后的字节"`，
+// 方向 > 为发送、< 为 stdout、! 为 stderr；字节以 Go 字符串字面量转义，可无损还原
+type Transcript struct {
+	mu        sync.Mutex
+	start     time.Time
+	buf       bytes.Buffer
+	max       int
+	truncated bool
+	sessions  int
+	secrets   []string
+}
+
+// NewTranscript 创建转录；maxBytes<=0 时使用 DefaultTranscriptMaxBytes，secrets 中的非空值在转录中替换为 ******
+func NewTranscript(maxBytes int, secrets ...string) *Transcript {
+	if maxBytes <= 0 {
+		maxBytes = DefaultTranscriptMaxBytes
+	}
+	t := &Transcript{start: time.Now(), max: maxBytes}
+	for _, s := range secrets {
+		if s != "" {
+			t.secrets = append(t.secrets, s)
+		}
+	}
+	return t
+}
+
+// Bytes 转录内容副本
+func (t *Transcript) Bytes() []byte {
+	if t == nil {
+		return nil
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]byte(nil), t.buf.Bytes()...)
+}
+
+// Len 已记录的字节数
+func (t *Transcript) Len() int {
+	if t == nil {
+		return 0
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.buf.Len()
+}
+
+// Truncated 是否因超出上限丢弃了后续内容
+func (t *Transcript) Truncated() bool {
+	if t == nil {
+		return false
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.truncated
+}
+
+// beginSession 记录一次交互会话的开始（重试或回退时同一转录包含多个会话）
+func (t *Transcript) beginSession(host string, port int, platform string) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.sessions++
+	t.appendLocked(fmt.Sprintf("# session %d %s:%d platform=%s started=%s\n",
+		t.sessions, host, port, platform, time.Now().Format(time.RFC3339Nano)))
+}
+
+// note 追加注释行（会话结束、错误等）
+func (t *Transcript) note(format string, args ...interface{}) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.appendLocked(fmt.Sprintf("%s # %s\n", t.elapsedLocked(), fmt.Sprintf(format, args...)))
+}
+
+func (t *Transcript) record(dir string, p []byte) {
+	if t == nil || len(p) == 0 {
+		return
+	}
+	data := string(p)
+	for _, s := range t.secrets {
+		data = strings.ReplaceAll(data, s, "******")
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.appendLocked(t.elapsedLocked() + " " + dir + " " + strconv.Quote(data) + "\n")
+}
+
+func (t *Transcript) elapsedLocked() string {
+	return fmt.Sprintf("+%.6f", time.Since(t.start).Seconds())
+}
+
+// appendLocked 超出上限时写入截断标记并丢弃后续内容
+func (t *Transcript) appendLocked(line string) {
+	if t.truncated {
+		return
+	}
+	if t.buf.Len()+len(line) > t.max {
+		t.truncated = true
+		t.buf.WriteString(fmt.Sprintf("# truncated at %d bytes\n", t.max))
+		return
+	}
+	t.buf.WriteString(line)
+}
+
+// transcriptWriter 记录写入会话的字节
+type transcriptWriter struct {
+	io.WriteCloser
+	t *Transcript
+}
+
+func (w transcriptWriter) Write(p []byte) (int, error) {
+	n, err := w.WriteCloser.Write(p)
+	w.t.record(transcriptSent, p[:n])
+	return n, err
+}
+
+// transcriptReader 记录从会话读取的字节
+type transcriptReader struct {
+	io.Reader
+	t   *Transcript
+	dir string
+}
+
+func (r transcriptReader) Read(p []byte) (int, error) {
+	n, err := r.Reader.Read(p)
+	r.t.record(r.dir, p[:n])
+	return n, err
+}
+
+// transcript 选项中的会话转录（可为空）
+func (o *InteractiveOptions) transcript() *Transcript {
+	if o == nil {
+		return nil
+	}
+	return o.Transcript
+}
+
+// beginTranscript 在转录中标记新会话的开始，目标取自最近一次连接参数
+func (c *Client) beginTranscript(t *Transcript) {
+	if t == nil {
+		return
+	}
+	var host, platform string
+	var port int
+	if c.info != nil {
+		host, port, platform = c.info.Host, c.info.Port, c.info.Platform
+	}
+	t.beginSession(host, port, platform)
+}
+
+// tapSession 为会话的 stdin/stdout/stderr 挂载转录；t 为空时原样返回
+func (t *Transcript) tapSession(stdin io.WriteCloser, stdout, stderr io.Reader) (io.WriteCloser, io.Reader, io.Reader) {
+	if t == nil {
+		return stdin, stdout, stderr
+	}
+	return transcriptWriter{WriteCloser: stdin, t: t},
+		transcriptReader{Reader: stdout, t: t, dir: transcriptStdout},
+		transcriptReader{Reader: stderr, t: t, dir: transcriptStderr}
+}
@@ -0,0 +1,110 @@
+package ssh
+
+import (
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 未知确认提示被安全应答中断的命令标记
+const (
+	StatusInterruptedPrompt   = "INTERRUPTED_PROMPT"
+	ExitCodeInterruptedPrompt = -3
+)
+
+// DefaultUnknownPromptPatterns 默认确认提示正则（大小写不敏感，匹配输出末行）
+var DefaultUnknownPromptPatterns = []string{
+	`[\[(]\s*(y|yes)\s*/\s*(n|no)\s*[\])]\s*[:?]?\s*$`,
+	`\[confirm\]\s*$`,
+	`(continue|proceed)\s*\?\s*$`,
+	`是否继续\s*[?？]?\s*$`,
+}
+
+// unknownPromptDetector 未知确认提示检测：末行匹配且输出静默后发送安全应答
+type unknownPromptDetector struct {
+	patterns []*regexp.Regexp
+	response []byte
+}
+
+// unknownPrompt 按选项构建检测器；未配置模式时返回 nil（不检测）
+func (o *InteractiveOptions) unknownPrompt() *unknownPromptDetector {
+	if o == nil || len(o.UnknownPromptPatterns) == 0 {
+		return nil
+	}
+	d := &unknownPromptDetector{}
+	for _, p := range o.UnknownPromptPatterns {
+		if strings.TrimSpace(p) == "" {
+			continue
+		}
+		re, err := regexp.Compile("(?i)" + p)
+		if err != nil {
+			continue
+		}
+		d.patterns = append(d.patterns, re)
+	}
+	if len(d.patterns) == 0 {
+		return nil
+	}
+	d.response = UnknownPromptResponseBytes(o.UnknownPromptResponse)
+	return d
+}
+
+// UnknownPromptResponseBytes 应答内容：ctrl-c/^c 发送 Ctrl-C，空值默认 N，其余原样加回车
+func UnknownPromptResponseBytes(resp string) []byte {
+	r := strings.TrimSpace(resp)
+	switch strings.ToLower(r) {
+	case "ctrl-c", "ctrl+c", "^c":
+		return []byte{0x03}
+	case "":
+		r = "N"
+	}
+	return []byte(r + "\r\n")
+}
+
+// match 判断行是否为确认提示
+func (d *unknownPromptDetector) match(line string) bool {
+	if d == nil {
+		return false
+	}
+	t := strings.TrimSpace(line)
+	if t == "" {
+		return false
+	}
+	for _, re := range d.patterns {
+		if re.MatchString(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// MatchUnknownPrompt 使用给定模式判断行是否为确认提示（供配置校验与测试）
+func MatchUnknownPrompt(patterns []string, line string) bool {
+	o := &InteractiveOptions{UnknownPromptPatterns: patterns}
+	return o.unknownPrompt().match(line)
+}
+
+// pendingLine 读取协程中尚未以换行结束的末行（确认提示通常不带换行）
+type pendingLine struct {
+	mu sync.Mutex
+	s  string
+	at time.Time
+}
+
+// set 记录末行内容与更新时间
+func (p *pendingLine) set(s string) {
+	p.mu.Lock()
+	if s != p.s {
+		p.s = s
+		p.at = time.Now()
+	}
+	p.mu.Unlock()
+}
+
+// get 返回末行内容与最近更新时间
+func (p *pendingLine) get() (string, time.Time) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.s, p.at
+}
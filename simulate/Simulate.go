@@ -9,6 +9,7 @@ import (
 	"encoding/pem"
 	"fmt"
 	"io"
+	mathrand "math/rand"
 	"net"
 	"os"
 	"path/filepath"
@@ -29,25 +30,81 @@ import (
 // Simulate.yaml 配置结构
 // 注意：根据需求使用 prompt_suffixe/enable_mode_suffixe 键名（带 e）
 type Config struct {
-	Namespace  map[string]NamespaceConfig  `mapstructure:"namespace"`
-	DeviceType map[string]DeviceTypeConfig `mapstructure:"device_type"`
-	DeviceName map[string]DeviceNameConfig `mapstructure:"device_name"`
+	Namespace  map[string]NamespaceConfig  `mapstructure:"namespace" yaml:"namespace"`
+	DeviceType map[string]DeviceTypeConfig `mapstructure:"device_type" yaml:"device_type"`
+	DeviceName map[string]DeviceNameConfig `mapstructure:"device_name" yaml:"device_name"`
+	// Scenarios 场景化回显（按调用次数变化），见 scenario.go
+	Scenarios []ScenarioConfig `mapstructure:"scenarios" yaml:"scenarios,omitempty"`
 }
 
 type NamespaceConfig struct {
-	Port        int `mapstructure:"port"`
-	IdleSeconds int `mapstructure:"idle_seconds"`
-	MaxConn     int `mapstructure:"max_conn"`
+	Port        int `mapstructure:"port" yaml:"port"`
+	IdleSeconds int `mapstructure:"idle_seconds" yaml:"idle_seconds,omitempty"`
+	MaxConn     int `mapstructure:"max_conn" yaml:"max_conn,omitempty"`
+	// 故障注入：每条命令回显前等待 latency_ms ± jitter_ms 毫秒；drop_rate 为每条命令直接断开连接的概率（0~1）
+	LatencyMS int     `mapstructure:"latency_ms" yaml:"latency_ms,omitempty"`
+	JitterMS  int     `mapstructure:"jitter_ms" yaml:"jitter_ms,omitempty"`
+	DropRate  float64 `mapstructure:"drop_rate" yaml:"drop_rate,omitempty"`
+	// 握手参数：server_version 为 SSH 版本串（未以 SSH- 开头时补 SSH-2.0-）；banner 为认证前横幅（设备未单独配置时使用）；
+	// 算法列表为空时使用 x/crypto/ssh 默认值，用于复现旧设备的握手问题
+	ServerVersion     string   `mapstructure:"server_version" yaml:"server_version,omitempty"`
+	Banner            string   `mapstructure:"banner" yaml:"banner,omitempty"`
+	KeyExchanges      []string `mapstructure:"key_exchanges" yaml:"key_exchanges,omitempty"`
+	Ciphers           []string `mapstructure:"ciphers" yaml:"ciphers,omitempty"`
+	MACs              []string `mapstructure:"macs" yaml:"macs,omitempty"`
+	HostKeyAlgorithms []string `mapstructure:"host_key_algorithms" yaml:"host_key_algorithms,omitempty"`
+	// telnet_port 大于 0 时额外开启 Telnet 监听（见 telnet.go），与 SSH 共用设备回显、提示符逻辑与 max_conn
+	TelnetPort int `mapstructure:"telnet_port" yaml:"telnet_port,omitempty"`
+}
+
+// listenPorts SSH 端口与已开启的 Telnet 端口
+func (c NamespaceConfig) listenPorts() []int {
+	if c.TelnetPort > 0 {
+		return []int{c.Port, c.TelnetPort}
+	}
+	return []int{c.Port}
+}
+
+// commandDelay 本条命令回显前的等待时长：latency_ms 加上 [-jitter_ms, jitter_ms] 内的随机抖动，不小于 0
+func (c NamespaceConfig) commandDelay() time.Duration {
+	ms := c.LatencyMS
+	if c.JitterMS > 0 {
+		ms += mathrand.Intn(2*c.JitterMS+1) - c.JitterMS
+	}
+	if ms <= 0 {
+		return 0
+	}
+	return time.Duration(ms) * time.Millisecond
+}
+
+// shouldDrop 按 drop_rate 判定本条命令是否断开连接
+func (c NamespaceConfig) shouldDrop() bool {
+	return c.DropRate > 0 && mathrand.Float64() < c.DropRate
 }
 
 type DeviceTypeConfig struct {
-	PromptSuffix       string `mapstructure:"prompt_suffixe"`
-	EnableModeRequired bool   `mapstructure:"enable_mode_required"`
-	EnableModeSuffix   string `mapstructure:"enable_mode_suffixe"`
+	PromptSuffix       string `mapstructure:"prompt_suffixe" yaml:"prompt_suffixe"`
+	EnableModeRequired bool   `mapstructure:"enable_mode_required" yaml:"enable_mode_required,omitempty"`
+	EnableModeSuffix   string `mapstructure:"enable_mode_suffixe" yaml:"enable_mode_suffixe,omitempty"`
+	// 分页与确认提示（见 paging.go）：超过 page_lines 行的回显按页输出并显示 more_prompt；
+	// paging_disable_commands 中的命令在本会话内关闭分页；confirm_prompts 在回显前要求确认
+	PageLines             int                   `mapstructure:"page_lines" yaml:"page_lines,omitempty"`
+	MorePrompt            string                `mapstructure:"more_prompt" yaml:"more_prompt,omitempty"`
+	PagingDisableCommands []string              `mapstructure:"paging_disable_commands" yaml:"paging_disable_commands,omitempty"`
+	ConfirmPrompts        []ConfirmPromptConfig `mapstructure:"confirm_prompts" yaml:"confirm_prompts,omitempty"`
 }
 
 type DeviceNameConfig struct {
-	DeviceType string `mapstructure:"device_type"`
+	DeviceType string `mapstructure:"device_type" yaml:"device_type"`
+	// 登录认证（见 auth.go）：password 为空时使用 nova；authorized_keys 为 authorized_keys 格式公钥，配置后允许公钥登录
+	Password       string   `mapstructure:"password" yaml:"password,omitempty"`
+	AuthorizedKeys []string `mapstructure:"authorized_keys" yaml:"authorized_keys,omitempty"`
+	// 故障模拟：认证失败 max_auth_tries 次后断开（0 不限）；auth_failure 为 reject（始终拒绝）或 disconnect（认证时断开）；
+	// auth_delay_ms 为认证失败响应前的等待；banner 为认证前横幅
+	MaxAuthTries int    `mapstructure:"max_auth_tries" yaml:"max_auth_tries,omitempty"`
+	AuthFailure  string `mapstructure:"auth_failure" yaml:"auth_failure,omitempty"`
+	AuthDelayMS  int    `mapstructure:"auth_delay_ms" yaml:"auth_delay_ms,omitempty"`
+	Banner       string `mapstructure:"banner" yaml:"banner,omitempty"`
 }
 
 // Manager 管理多个 namespace 的 SSH 模拟服务
@@ -67,10 +124,13 @@ type namespaceServer struct {
 	cfg      NamespaceConfig
 	simCfg   *Config
 	listener net.Listener
+	telnetListener net.Listener
 	hostKey  ssh.Signer
 	active   int
 	mu       sync.Mutex
 	wg       sync.WaitGroup
+	// 场景调用计数
+	scenarios scenarioState
 }
 
 // LoadConfig 读取 simulate/simulate.yaml
@@ -85,6 +145,18 @@ func LoadConfig(path string) (*Config, error) {
 	if err := v.Unmarshal(&cfg); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal simulate config: %w", err)
 	}
+	if err := validateScenarios(cfg.Scenarios); err != nil {
+		return nil, fmt.Errorf("invalid simulate scenarios: %w", err)
+	}
+	if err := ValidateNamespaceHandshake(cfg.Namespace); err != nil {
+		return nil, fmt.Errorf("invalid simulate namespace: %w", err)
+	}
+	if err := ValidateDeviceAuth(cfg.DeviceName); err != nil {
+		return nil, fmt.Errorf("invalid simulate device auth: %w", err)
+	}
+	if err := ValidateDeviceTypes(cfg.DeviceType); err != nil {
+		return nil, fmt.Errorf("invalid simulate device type: %w", err)
+	}
 	return &cfg, nil
 }
 
@@ -137,6 +209,7 @@ func Start(simCfg *Config) (*Manager, error) {
 		logger.Info("Simulate: namespace server started", "namespace", ns, "port", nsCfg.Port)
 	}
 
+	current.Store(m)
 	return m, nil
 }
 
@@ -166,10 +239,11 @@ func (m *Manager) Reload(newCfg *Config) error {
 	// 2) 新增或更新现有命名空间（端口变化则重启）
 	for ns, nsCfg := range newCfg.Namespace {
 		if srv, ok := m.nsServers[ns]; ok {
-			portChanged := srv.cfg.Port != nsCfg.Port
-			// 更新运行时配置
+			portChanged := srv.cfg.Port != nsCfg.Port || srv.cfg.TelnetPort != nsCfg.TelnetPort
+			// 更新运行时配置；场景计数从头开始
 			srv.cfg = nsCfg
 			srv.simCfg = newCfg
+			srv.scenarios.reset()
 			if portChanged {
 				srv.stop()
 				if err := srv.start(); err != nil {
@@ -211,6 +285,7 @@ func (m *Manager) Stop() {
 		srv.stop()
 		logger.Info("Simulate: namespace server stopped", "namespace", ns)
 	}
+	current.CompareAndSwap(m, nil)
 }
 
 func newNamespaceServer(nsName string, nsCfg NamespaceConfig, simCfg *Config) (*namespaceServer, error) {
@@ -294,85 +369,75 @@ func (s *namespaceServer) start() error {
 	s.listener = ln
 	logger.Debug("Simulate: listener started", "namespace", s.nsName, "port", s.cfg.Port)
 
-	go func() {
-		for {
-			conn, err := ln.Accept()
-			if err != nil {
-				if ne, ok := err.(net.Error); ok && ne.Temporary() {
-					logger.Warn("Simulate: accept temporary error", "error", err)
-					time.Sleep(200 * time.Millisecond)
-					continue
-				}
-				// listener closed
-				return
-			}
-			logger.Debug("Simulate: accept connection", "namespace", s.nsName, "remote", conn.RemoteAddr().String())
-			// 并发限制
-			s.mu.Lock()
-			if s.cfg.MaxConn > 0 && s.active >= s.cfg.MaxConn {
-				s.mu.Unlock()
-				_ = conn.Close()
-				logger.Warn("Simulate: reject connection, max_conn exceeded", "namespace", s.nsName)
-				logger.Debug("Simulate: active", "active", s.active)
+	go s.serve(ln, s.handleConn)
+
+	if s.cfg.TelnetPort > 0 {
+		tln, err := net.Listen("tcp", fmt.Sprintf(":%d", s.cfg.TelnetPort))
+		if err != nil {
+			s.stop()
+			return fmt.Errorf("telnet listener: %w", err)
+		}
+		s.telnetListener = tln
+		logger.Debug("Simulate: telnet listener started", "namespace", s.nsName, "port", s.cfg.TelnetPort)
+		go s.serve(tln, s.handleTelnetConn)
+	}
+	return nil
+}
+
+// serve 接受连接并按 max_conn 限制并发（SSH 与 Telnet 共用计数）
+func (s *namespaceServer) serve(ln net.Listener, handle func(net.Conn)) {
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			if ne, ok := err.(net.Error); ok && ne.Temporary() {
+				logger.Warn("Simulate: accept temporary error", "error", err)
+				time.Sleep(200 * time.Millisecond)
 				continue
 			}
-			s.active++
+			// listener closed
+			return
+		}
+		logger.Debug("Simulate: accept connection", "namespace", s.nsName, "remote", conn.RemoteAddr().String())
+		// 并发限制
+		s.mu.Lock()
+		if s.cfg.MaxConn > 0 && s.active >= s.cfg.MaxConn {
 			s.mu.Unlock()
-
-			s.wg.Add(1)
-			go func(c net.Conn) {
-				defer s.wg.Done()
-				s.handleConn(c)
-				s.mu.Lock()
-				s.active--
-				s.mu.Unlock()
-			}(conn)
+			_ = conn.Close()
+			logger.Warn("Simulate: reject connection, max_conn exceeded", "namespace", s.nsName)
+			logger.Debug("Simulate: active", "active", s.active)
+			continue
 		}
-	}()
+		s.active++
+		s.mu.Unlock()
 
-	return nil
+		s.wg.Add(1)
+		go func(c net.Conn) {
+			defer s.wg.Done()
+			handle(c)
+			s.mu.Lock()
+			s.active--
+			s.mu.Unlock()
+		}(conn)
+	}
 }
 
 func (s *namespaceServer) stop() {
 	if s.listener != nil {
 		_ = s.listener.Close()
+		s.listener = nil
+	}
+	if s.telnetListener != nil {
+		_ = s.telnetListener.Close()
+		s.telnetListener = nil
 	}
 	s.wg.Wait()
 }
 
 func (s *namespaceServer) handleConn(nc net.Conn) {
-	// 构造 SSH ServerConfig：允许任意用户名（作为设备名），密码统一为 nova
+	// 构造 SSH ServerConfig：允许任意用户名（作为设备名），认证按 device_name 配置（默认密码 nova）
 	logger.Debug("Simulate: handshake start", "namespace", s.nsName, "remote", nc.RemoteAddr().String())
-	srvCfg := &ssh.ServerConfig{
-		NoClientAuth: false,
-		PasswordCallback: func(connMetadata ssh.ConnMetadata, password []byte) (*ssh.Permissions, error) {
-			user := strings.TrimSpace(connMetadata.User())
-			pass := strings.TrimSpace(string(password))
-			logger.Debug("Simulate: auth try (password)", "user", user)
-			if pass == "nova" {
-				logger.Debug("Simulate: auth success (password)", "user", user)
-				return nil, nil
-			}
-			logger.Debug("Simulate: auth failed (password)", "user", user)
-			return nil, fmt.Errorf("access denied")
-		},
-		KeyboardInteractiveCallback: func(connMetadata ssh.ConnMetadata, challenge ssh.KeyboardInteractiveChallenge) (*ssh.Permissions, error) {
-			// 兼容部分客户端默认使用 keyboard-interactive 的情况
-			logger.Debug("Simulate: auth try (keyboard-interactive)", "user", connMetadata.User())
-			answers, err := challenge(connMetadata.User(), "Authentication", []string{"Password:"}, []bool{false})
-			if err != nil {
-				logger.Debug("Simulate: auth failed (ki challenge)", "error", err)
-				return nil, err
-			}
-			if len(answers) > 0 && strings.TrimSpace(answers[0]) == "nova" {
-				logger.Debug("Simulate: auth success (keyboard-interactive)", "user", connMetadata.User())
-				return nil, nil
-			}
-			logger.Debug("Simulate: auth failed (keyboard-interactive)", "user", connMetadata.User())
-			return nil, fmt.Errorf("access denied")
-		},
-	}
-	srvCfg.AddHostKey(s.hostKey)
+	srvCfg := s.serverConfig(nc)
+	s.applyHandshake(srvCfg)
 
 	// 完成握手
 	conn, chans, reqs, err := ssh.NewServerConn(nc, srvCfg)
@@ -410,7 +475,8 @@ func (s *namespaceServer) handleConn(nc net.Conn) {
 
 		logger.Debug("Simulate: device resolved", "device", deviceName, "prompt_suffix", promptSuffix, "enable_required", enableRequired, "enable_suffix", enableSuffix)
 		// 处理请求（pty-req / shell / exec）
-		go s.handleSession(channel, requests, deviceName, promptSuffix, enableRequired, enableSuffix)
+		// 故障注入断连：直接关闭底层 TCP 连接，模拟设备或链路异常
+		go s.handleSession(channel, requests, deviceName, promptSuffix, enableRequired, enableSuffix, func() { _ = nc.Close() })
 	}
 }
 
@@ -425,7 +491,7 @@ func (s *namespaceServer) resolveDeviceType(deviceName string) DeviceTypeConfig
 	return DeviceTypeConfig{PromptSuffix: ">", EnableModeRequired: false, EnableModeSuffix: "#"}
 }
 
-func (s *namespaceServer) handleSession(channel ssh.Channel, requests <-chan *ssh.Request, deviceName, promptSuffix string, enableRequired bool, enableSuffix string) {
+func (s *namespaceServer) handleSession(channel ssh.Channel, requests <-chan *ssh.Request, deviceName, promptSuffix string, enableRequired bool, enableSuffix string, drop func()) {
 	defer channel.Close()
 
 	// 跟踪 PTY 是否已就绪
@@ -442,7 +508,7 @@ func (s *namespaceServer) handleSession(channel ssh.Channel, requests <-chan *ss
 			req.Reply(true, nil)
 			logger.Debug("Simulate: shell start", "device", deviceName)
 			// 进入交互式 shell
-			s.runInteractiveShell(channel, deviceName, promptSuffix, enableRequired, enableSuffix)
+			s.runInteractiveShell(channel, deviceName, promptSuffix, enableRequired, enableSuffix, drop)
 			return
 		case "exec":
 			// 执行单条命令并返回结果
@@ -455,6 +521,9 @@ func (s *namespaceServer) handleSession(channel ssh.Channel, requests <-chan *ss
 				logger.Debug("Simulate: exec unmatched", "cmd", cmd)
 				out = "unsupportted command\r\n"
 			}
+			if !s.injectFaults(deviceName, cmd, drop) {
+				return
+			}
 			channel.Write([]byte(out))
 			if ptyReady {
 				channel.Write([]byte(fmt.Sprintf("%s%s\r\n", deviceName, promptSuffix)))
@@ -468,7 +537,10 @@ func (s *namespaceServer) handleSession(channel ssh.Channel, requests <-chan *ss
 	}
 }
 
-func (s *namespaceServer) runInteractiveShell(channel ssh.Channel, deviceName, promptSuffix string, enableRequired bool, enableSuffix string) {
+func (s *namespaceServer) runInteractiveShell(channel io.ReadWriter, deviceName, promptSuffix string, enableRequired bool, enableSuffix string, drop func()) {
+	// 分页与确认提示按设备类型配置；关闭分页命令仅作用于本会话
+	devType := s.resolveDeviceType(deviceName)
+	pagingOff := false
 	// 初始提示符
 	currentSuffix := promptSuffix
 	printPrompt := func() {
@@ -557,6 +629,21 @@ func (s *namespaceServer) runInteractiveShell(channel ssh.Channel, deviceName, p
 			continue
 		}
 
+		// 关闭分页（如 terminal length 0）
+		if devType.disablesPaging(cmd) {
+			pagingOff = true
+			logger.Debug("Simulate: paging disabled", "device", deviceName, "cmd", cmd)
+			printPrompt()
+			continue
+		}
+
+		// 确认提示：应答不符时不输出回显
+		if cp := devType.confirmFor(cmd); cp != nil && !confirm(channel, reader, cp) {
+			logger.Debug("Simulate: confirmation declined", "device", deviceName, "cmd", cmd)
+			printPrompt()
+			continue
+		}
+
 		// 加载模拟命令输出
 		out := s.loadCommandOutput(s.nsName, deviceName, cmd)
 		if out == "" {
@@ -564,13 +651,39 @@ func (s *namespaceServer) runInteractiveShell(channel ssh.Channel, deviceName, p
 			logger.Debug("Simulate: command unmatched", "device", deviceName, "cmd", cmd)
 			out = "unsupportted command\r\n"
 		}
-		// 2) 匹配：显示 txt 文件内容（已按 CRLF 标准化）
-		channel.Write([]byte(out))
+		if !s.injectFaults(deviceName, cmd, drop) {
+			return
+		}
+		// 2) 匹配：显示 txt 文件内容（已按 CRLF 标准化），按设备类型分页
+		pageLines := devType.PageLines
+		if pagingOff {
+			pageLines = 0
+		}
+		writePaged(channel, reader, out, pageLines, devType.MorePrompt)
 		printPrompt()
 	}
 }
 
+// injectFaults 回显前按命名空间配置注入延迟与断连；断连时返回 false，会话随即结束
+func (s *namespaceServer) injectFaults(deviceName, cmd string, drop func()) bool {
+	cfg := s.cfg
+	if d := cfg.commandDelay(); d > 0 {
+		logger.Debug("Simulate: inject latency", "namespace", s.nsName, "device", deviceName, "cmd", cmd, "delay_ms", d.Milliseconds())
+		time.Sleep(d)
+	}
+	if cfg.shouldDrop() {
+		logger.Debug("Simulate: inject connection drop", "namespace", s.nsName, "device", deviceName, "cmd", cmd)
+		drop()
+		return false
+	}
+	return true
+}
+
 func (s *namespaceServer) loadCommandOutput(ns, deviceName, cmd string) string {
+	// 场景化回显优先：同一命令按调用次数返回不同输出
+	if out, ok := s.scenarioOutput(ns, deviceName, cmd); ok {
+		return out
+	}
 	// 新增：优先从 SQLite 按 namespace + device_name + command 精确匹配
 	if db := database.GetDB(); db != nil {
 		var rec model.SimDeviceCommand
@@ -0,0 +1,161 @@
+package simulate
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+
+	"github.com/sshcollectorpro/sshcollectorpro/pkg/logger"
+)
+
+// 默认登录密码（未在 device_name 配置 password 的设备）
+const defaultPassword = "nova"
+
+// 认证失败模式
+const (
+	AuthFailureReject     = "reject"     // 始终拒绝（模拟账号锁定/口令过期）
+	AuthFailureDisconnect = "disconnect" // 首次认证尝试即断开 TCP 连接
+)
+
+// ValidateDeviceAuth 校验 device_name 下的认证配置
+func ValidateDeviceAuth(devices map[string]DeviceNameConfig) error {
+	for name, d := range devices {
+		switch strings.ToLower(strings.TrimSpace(d.AuthFailure)) {
+		case "", AuthFailureReject, AuthFailureDisconnect:
+		default:
+			return fmt.Errorf("device_name %s: unknown auth_failure %q (expected reject or disconnect)", name, d.AuthFailure)
+		}
+		if d.MaxAuthTries < 0 || d.AuthDelayMS < 0 {
+			return fmt.Errorf("device_name %s: max_auth_tries/auth_delay_ms must not be negative", name)
+		}
+		if _, err := parseAuthorizedKeys(d.AuthorizedKeys); err != nil {
+			return fmt.Errorf("device_name %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// parseAuthorizedKeys 解析 authorized_keys 格式的公钥列表
+func parseAuthorizedKeys(lines []string) ([]ssh.PublicKey, error) {
+	keys := make([]ssh.PublicKey, 0, len(lines))
+	for _, l := range lines {
+		if strings.TrimSpace(l) == "" {
+			continue
+		}
+		k, _, _, _, err := ssh.ParseAuthorizedKey([]byte(l))
+		if err != nil {
+			return nil, fmt.Errorf("invalid authorized_keys entry: %w", err)
+		}
+		keys = append(keys, k)
+	}
+	return keys, nil
+}
+
+// deviceAuth 按用户名（设备名）解析的认证设置
+type deviceAuth struct {
+	password string
+	keys     []ssh.PublicKey
+	maxTries int
+	failure  string
+	delay    time.Duration
+	banner   string
+}
+
+func (s *namespaceServer) resolveDeviceAuth(user string) deviceAuth {
+	a := deviceAuth{password: defaultPassword}
+	dn, ok := s.simCfg.DeviceName[user]
+	if !ok {
+		return a
+	}
+	if dn.Password != "" {
+		a.password = dn.Password
+	}
+	// 已在加载时校验，这里忽略错误
+	a.keys, _ = parseAuthorizedKeys(dn.AuthorizedKeys)
+	a.maxTries = dn.MaxAuthTries
+	a.failure = strings.ToLower(strings.TrimSpace(dn.AuthFailure))
+	a.delay = time.Duration(dn.AuthDelayMS) * time.Millisecond
+	a.banner = dn.Banner
+	return a
+}
+
+// serverConfig 构造单个连接的 SSH ServerConfig：按用户名匹配设备认证设置，
+// 认证失败次数达到 max_auth_tries 或 auth_failure=disconnect 时关闭底层连接
+func (s *namespaceServer) serverConfig(nc net.Conn) *ssh.ServerConfig {
+	var mu sync.Mutex
+	failures := 0
+	// check 统一处理认证结果：失败模式、延迟与次数限制
+	check := func(meta ssh.ConnMetadata, method string, ok bool) (*ssh.Permissions, error) {
+		user := strings.TrimSpace(meta.User())
+		auth := s.resolveDeviceAuth(user)
+		switch auth.failure {
+		case AuthFailureDisconnect:
+			logger.Debug("Simulate: auth disconnect", "user", user, "method", method)
+			_ = nc.Close()
+			return nil, fmt.Errorf("connection closed")
+		case AuthFailureReject:
+			ok = false
+		}
+		if ok {
+			logger.Debug("Simulate: auth success", "user", user, "method", method)
+			return nil, nil
+		}
+		if auth.delay > 0 {
+			time.Sleep(auth.delay)
+		}
+		mu.Lock()
+		failures++
+		n := failures
+		mu.Unlock()
+		logger.Debug("Simulate: auth failed", "user", user, "method", method, "failures", n)
+		if auth.maxTries > 0 && n >= auth.maxTries {
+			logger.Debug("Simulate: max auth tries exceeded, disconnect", "user", user)
+			_ = nc.Close()
+		}
+		return nil, fmt.Errorf("access denied")
+	}
+
+	return &ssh.ServerConfig{
+		NoClientAuth: false,
+		PasswordCallback: func(meta ssh.ConnMetadata, password []byte) (*ssh.Permissions, error) {
+			auth := s.resolveDeviceAuth(strings.TrimSpace(meta.User()))
+			return check(meta, "password", strings.TrimSpace(string(password)) == auth.password)
+		},
+		KeyboardInteractiveCallback: func(meta ssh.ConnMetadata, challenge ssh.KeyboardInteractiveChallenge) (*ssh.Permissions, error) {
+			// 兼容部分客户端默认使用 keyboard-interactive 的情况
+			answers, err := challenge(meta.User(), "Authentication", []string{"Password:"}, []bool{false})
+			if err != nil {
+				logger.Debug("Simulate: auth failed (ki challenge)", "error", err)
+				return nil, err
+			}
+			auth := s.resolveDeviceAuth(strings.TrimSpace(meta.User()))
+			return check(meta, "keyboard-interactive", len(answers) > 0 && strings.TrimSpace(answers[0]) == auth.password)
+		},
+		PublicKeyCallback: func(meta ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+			auth := s.resolveDeviceAuth(strings.TrimSpace(meta.User()))
+			ok := false
+			for _, k := range auth.keys {
+				if bytes.Equal(k.Marshal(), key.Marshal()) {
+					ok = true
+					break
+				}
+			}
+			return check(meta, "publickey", ok)
+		},
+		BannerCallback: func(meta ssh.ConnMetadata) string {
+			b := s.resolveDeviceAuth(strings.TrimSpace(meta.User())).banner
+			if b == "" {
+				b = s.cfg.Banner
+			}
+			if b != "" && !strings.HasSuffix(b, "\n") {
+				b += "\r\n"
+			}
+			return b
+		},
+	}
+}
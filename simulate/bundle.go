@@ -0,0 +1,530 @@
+package simulate
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+	"gorm.io/gorm"
+
+	"github.com/sshcollectorpro/sshcollectorpro/internal/database"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/model"
+	"github.com/sshcollectorpro/sshcollectorpro/pkg/logger"
+)
+
+// 夹具包（tar.gz）布局：manifest.yaml、fixtures/<device>/...（设备目录原样）、可选 hostkey.pem
+const (
+	bundleVersion  = 1
+	bundleManifest = "manifest.yaml"
+	bundleFixtures = "fixtures/"
+	bundleHostKey  = "hostkey.pem"
+	// 解压后总大小上限，防止异常包占满磁盘
+	maxBundleBytes = 64 << 20
+)
+
+var (
+	ErrNamespaceNotFound = errors.New("simulate namespace not found")
+	ErrBundleInvalid     = errors.New("invalid simulate bundle")
+	ErrBundleConflict    = errors.New("simulate bundle conflicts with existing config")
+)
+
+// BundleManifest 夹具包清单：仅含导出命名空间、其设备、引用的设备类型与场景
+type BundleManifest struct {
+	Version   int             `yaml:"version"`
+	Namespace string          `yaml:"namespace"`
+	CreatedAt time.Time       `yaml:"created_at"`
+	Config    Config          `yaml:"config"`
+	Commands  []BundleCommand `yaml:"commands,omitempty"` // SQLite 中该命名空间的命令回显
+	HostKey   bool            `yaml:"hostkey,omitempty"`
+}
+
+// BundleCommand SQLite 命令回显（sim_device_commands）
+type BundleCommand struct {
+	DeviceName string `yaml:"device_name"`
+	Command    string `yaml:"command"`
+	Output     string `yaml:"output"`
+	Enabled    bool   `yaml:"enabled"`
+}
+
+// ExportBundle 导出命名空间为 tar.gz：配置片段、设备目录下的回显文件与 SQLite 回显，可选附带 host key
+func ExportBundle(w io.Writer, cfg *Config, ns string, withHostKey bool) error {
+	if cfg == nil || !ValidRecordName(ns) {
+		return fmt.Errorf("%w: %s", ErrNamespaceNotFound, ns)
+	}
+	nsCfg, ok := cfg.Namespace[ns]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrNamespaceNotFound, ns)
+	}
+	devices, err := namespaceDevices(ns)
+	if err != nil {
+		return err
+	}
+	man := BundleManifest{Version: bundleVersion, Namespace: ns, CreatedAt: time.Now(), Config: Config{
+		Namespace:  map[string]NamespaceConfig{ns: nsCfg},
+		DeviceType: map[string]DeviceTypeConfig{},
+		DeviceName: map[string]DeviceNameConfig{},
+	}}
+	inBundle := make(map[string]bool, len(devices))
+	for _, dev := range devices {
+		inBundle[dev] = true
+		dn, ok := cfg.DeviceName[dev]
+		if !ok {
+			continue
+		}
+		man.Config.DeviceName[dev] = dn
+		if dt, ok := cfg.DeviceType[dn.DeviceType]; ok {
+			man.Config.DeviceType[dn.DeviceType] = dt
+		}
+	}
+	// 全局场景仅导出限定到本包设备的
+	for _, sc := range cfg.Scenarios {
+		if sc.Namespace == ns || (sc.Namespace == "" && inBundle[sc.DeviceName]) {
+			man.Config.Scenarios = append(man.Config.Scenarios, sc)
+		}
+	}
+	if db := database.GetDB(); db != nil {
+		var rows []model.SimDeviceCommand
+		if err := db.Where("namespace = ?", ns).Order("device_name, id").Find(&rows).Error; err != nil {
+			return err
+		}
+		for _, r := range rows {
+			man.Commands = append(man.Commands, BundleCommand{DeviceName: r.DeviceName, Command: r.Command, Output: r.Output, Enabled: r.Enabled})
+		}
+	}
+	var hostKey []byte
+	if withHostKey {
+		if hostKey, err = os.ReadFile(filepath.Join("simulate", "_hostkey_rsa.pem")); err != nil {
+			return fmt.Errorf("read host key: %w", err)
+		}
+		man.HostKey = true
+	}
+
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	mb, err := yaml.Marshal(man)
+	if err != nil {
+		return err
+	}
+	if err := writeTarFile(tw, bundleManifest, mb, 0o644); err != nil {
+		return err
+	}
+	for _, dev := range devices {
+		base := DeviceDir(ns, dev)
+		err := filepath.WalkDir(base, func(p string, d fs.DirEntry, err error) error {
+			if err != nil || !d.Type().IsRegular() {
+				return err
+			}
+			rel, err := filepath.Rel(base, p)
+			if err != nil {
+				return err
+			}
+			bs, err := os.ReadFile(p)
+			if err != nil {
+				return err
+			}
+			return writeTarFile(tw, bundleFixtures+dev+"/"+filepath.ToSlash(rel), bs, 0o644)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	if hostKey != nil {
+		if err := writeTarFile(tw, bundleHostKey, hostKey, 0o600); err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// namespaceDevices 命名空间目录下的设备目录（按名称排序）
+func namespaceDevices(ns string) ([]string, error) {
+	entries, err := os.ReadDir(filepath.Join("simulate", "namespace", ns))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	out := make([]string, 0, len(entries))
+	for _, e := range entries {
+		if e.IsDir() && ValidRecordName(e.Name()) {
+			out = append(out, e.Name())
+		}
+	}
+	sort.Strings(out)
+	return out, nil
+}
+
+func writeTarFile(tw *tar.Writer, name string, data []byte, mode int64) error {
+	if err := tw.WriteHeader(&tar.Header{Name: name, Mode: mode, Size: int64(len(data)), ModTime: time.Now(), Typeflag: tar.TypeReg}); err != nil {
+		return err
+	}
+	_, err := tw.Write(data)
+	return err
+}
+
+// ImportOptions 导入选项
+type ImportOptions struct {
+	Namespace string            // 目标命名空间，默认沿用包内名称
+	Port      int               // 目标端口，默认沿用包内端口
+	DeviceMap map[string]string // 设备改名：包内名称 -> 目标名称
+	Overwrite bool              // 覆盖已存在的命名空间、设备与设备类型配置
+	HostKey   bool              // 使用包内 host key 替换全局 host key（命名空间重启后生效）
+}
+
+// ImportResult 导入结果
+type ImportResult struct {
+	Namespace string            `json:"namespace"`
+	Port      int               `json:"port"`
+	Devices   map[string]string `json:"devices"` // 包内名称 -> 目标名称
+	Files     int               `json:"files"`
+	Commands  int               `json:"commands"`
+	HostKey   bool              `json:"hostkey"`
+}
+
+// ImportBundle 导入夹具包：校验冲突后写入设备目录与 SQLite 回显，并将配置片段合并写入 configPath（保留原有注释）
+// 运行中的模拟服务需由调用方按新配置 Reload
+func ImportBundle(r io.Reader, configPath string, opts ImportOptions) (*ImportResult, error) {
+	man, files, hostKey, err := readBundle(r)
+	if err != nil {
+		return nil, err
+	}
+	var current Config
+	if bs, err := os.ReadFile(configPath); err == nil {
+		if err := yaml.Unmarshal(bs, &current); err != nil {
+			return nil, fmt.Errorf("parse %s: %w", configPath, err)
+		}
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	target := chooseNonEmpty(strings.TrimSpace(opts.Namespace), man.Namespace)
+	if !ValidRecordName(target) {
+		return nil, fmt.Errorf("%w: invalid target namespace %q", ErrBundleInvalid, target)
+	}
+	nsCfg := man.Config.Namespace[man.Namespace]
+	if opts.Port > 0 {
+		nsCfg.Port = opts.Port
+	}
+	if _, exists := current.Namespace[target]; exists && !opts.Overwrite {
+		return nil, fmt.Errorf("%w: namespace %s already exists", ErrBundleConflict, target)
+	}
+	for name, n := range current.Namespace {
+		if name == target {
+			continue
+		}
+		for _, p := range nsCfg.listenPorts() {
+			if slices.Contains(n.listenPorts(), p) {
+				return nil, fmt.Errorf("%w: port %d is used by namespace %s", ErrBundleConflict, p, name)
+			}
+		}
+	}
+
+	// 设备改名与配置片段
+	rename := func(dev string) string {
+		if to := strings.TrimSpace(opts.DeviceMap[dev]); to != "" {
+			return to
+		}
+		return dev
+	}
+	res := &ImportResult{Namespace: target, Port: nsCfg.Port, Devices: map[string]string{}, HostKey: opts.HostKey && hostKey != nil}
+	frag := Config{Namespace: map[string]NamespaceConfig{target: nsCfg}, DeviceType: map[string]DeviceTypeConfig{}, DeviceName: map[string]DeviceNameConfig{}}
+	for dev := range files {
+		res.Devices[dev] = rename(dev)
+	}
+	for dev, dn := range man.Config.DeviceName {
+		res.Devices[dev] = rename(dev)
+		frag.DeviceName[rename(dev)] = dn
+	}
+	for _, c := range man.Commands {
+		res.Devices[c.DeviceName] = rename(c.DeviceName)
+	}
+	for from, to := range res.Devices {
+		if !ValidRecordName(to) {
+			return nil, fmt.Errorf("%w: invalid device name %q (from %s)", ErrBundleInvalid, to, from)
+		}
+	}
+	for typ, dt := range man.Config.DeviceType {
+		if old, ok := current.DeviceType[typ]; ok && !opts.Overwrite && !reflect.DeepEqual(old, dt) {
+			return nil, fmt.Errorf("%w: device_type %s differs from existing config", ErrBundleConflict, typ)
+		}
+		frag.DeviceType[typ] = dt
+	}
+	for name, dn := range frag.DeviceName {
+		if old, ok := current.DeviceName[name]; ok && !opts.Overwrite && !reflect.DeepEqual(old, dn) {
+			return nil, fmt.Errorf("%w: device_name %s differs from existing config", ErrBundleConflict, name)
+		}
+	}
+	for _, sc := range man.Config.Scenarios {
+		sc.Namespace = target
+		if sc.DeviceName != "" {
+			sc.DeviceName = rename(sc.DeviceName)
+		}
+		frag.Scenarios = append(frag.Scenarios, sc)
+	}
+	if err := validateScenarios(frag.Scenarios); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrBundleInvalid, err)
+	}
+	if err := ValidateNamespaceHandshake(frag.Namespace); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrBundleInvalid, err)
+	}
+	if err := ValidateDeviceAuth(frag.DeviceName); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrBundleInvalid, err)
+	}
+	if err := ValidateDeviceTypes(frag.DeviceType); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrBundleInvalid, err)
+	}
+
+	// 写入设备目录（覆盖时先清空目标设备目录）
+	for dev, fixtures := range files {
+		base := DeviceDir(target, rename(dev))
+		if opts.Overwrite {
+			if err := os.RemoveAll(base); err != nil {
+				return nil, err
+			}
+		}
+		for rel, data := range fixtures {
+			p := filepath.Join(base, filepath.FromSlash(rel))
+			if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+				return nil, err
+			}
+			if err := os.WriteFile(p, data, 0o644); err != nil {
+				return nil, err
+			}
+			res.Files++
+		}
+	}
+	if len(man.Commands) > 0 {
+		db := database.GetDB()
+		if db == nil {
+			return nil, fmt.Errorf("database not initialized; bundle contains %d sqlite commands", len(man.Commands))
+		}
+		err := db.Transaction(func(tx *gorm.DB) error {
+			for _, dev := range res.Devices {
+				if err := tx.Where("namespace = ? AND device_name = ?", target, dev).Delete(&model.SimDeviceCommand{}).Error; err != nil {
+					return err
+				}
+			}
+			for _, c := range man.Commands {
+				row := model.SimDeviceCommand{Namespace: target, DeviceName: rename(c.DeviceName), Command: c.Command, Output: c.Output, Enabled: c.Enabled}
+				if err := tx.Create(&row).Error; err != nil {
+					return err
+				}
+				// gorm 对 bool 零值使用列默认值（true），需显式更新
+				if !c.Enabled {
+					if err := tx.Model(&row).Update("enabled", false).Error; err != nil {
+						return err
+					}
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		res.Commands = len(man.Commands)
+	}
+	if res.HostKey {
+		if err := os.WriteFile(filepath.Join("simulate", "_hostkey_rsa.pem"), hostKey, 0o600); err != nil {
+			return nil, err
+		}
+	}
+	if err := mergeConfigFile(configPath, &frag); err != nil {
+		return nil, err
+	}
+	logger.Info("Simulate: bundle imported", "namespace", target, "port", nsCfg.Port, "files", res.Files, "commands", res.Commands)
+	return res, nil
+}
+
+// readBundle 读取并校验夹具包：返回清单、按设备分组的文件（相对设备目录）与 host key
+func readBundle(r io.Reader) (*BundleManifest, map[string]map[string][]byte, []byte, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("%w: %v", ErrBundleInvalid, err)
+	}
+	defer gz.Close()
+	tr := tar.NewReader(gz)
+	var man *BundleManifest
+	var hostKey []byte
+	files := make(map[string]map[string][]byte)
+	var total int64
+	for {
+		h, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("%w: %v", ErrBundleInvalid, err)
+		}
+		if h.Typeflag != tar.TypeReg {
+			continue
+		}
+		total += h.Size
+		if total > maxBundleBytes {
+			return nil, nil, nil, fmt.Errorf("%w: exceeds %d bytes", ErrBundleInvalid, maxBundleBytes)
+		}
+		data, err := io.ReadAll(io.LimitReader(tr, h.Size))
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("%w: %v", ErrBundleInvalid, err)
+		}
+		name := path.Clean(strings.TrimPrefix(h.Name, "./"))
+		switch {
+		case name == bundleManifest:
+			man = &BundleManifest{}
+			if err := yaml.Unmarshal(data, man); err != nil {
+				return nil, nil, nil, fmt.Errorf("%w: manifest: %v", ErrBundleInvalid, err)
+			}
+		case name == bundleHostKey:
+			hostKey = data
+		case strings.HasPrefix(name, bundleFixtures):
+			dev, rel, ok := strings.Cut(strings.TrimPrefix(name, bundleFixtures), "/")
+			if !ok || !ValidRecordName(dev) || rel == "" || path.IsAbs(rel) || rel == ".." || strings.HasPrefix(rel, "../") {
+				return nil, nil, nil, fmt.Errorf("%w: unsafe path %q", ErrBundleInvalid, h.Name)
+			}
+			if files[dev] == nil {
+				files[dev] = make(map[string][]byte)
+			}
+			files[dev][rel] = data
+		}
+	}
+	if man == nil {
+		return nil, nil, nil, fmt.Errorf("%w: %s missing", ErrBundleInvalid, bundleManifest)
+	}
+	if man.Version != bundleVersion {
+		return nil, nil, nil, fmt.Errorf("%w: unsupported version %d", ErrBundleInvalid, man.Version)
+	}
+	if _, ok := man.Config.Namespace[man.Namespace]; !ok {
+		return nil, nil, nil, fmt.Errorf("%w: namespace %q missing from manifest config", ErrBundleInvalid, man.Namespace)
+	}
+	return man, files, hostKey, nil
+}
+
+// mergeConfigFile 将配置片段合并写入 simulate.yaml：同名命名空间/设备类型/设备替换，
+// 同一命名空间、设备与命令的场景替换；按 yaml 节点修改以保留原有注释与顺序
+func mergeConfigFile(configPath string, frag *Config) error {
+	var doc yaml.Node
+	if bs, err := os.ReadFile(configPath); err == nil && len(bytes.TrimSpace(bs)) > 0 {
+		if err := yaml.Unmarshal(bs, &doc); err != nil {
+			return err
+		}
+	} else if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if doc.Kind == 0 {
+		doc = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode}}}
+	}
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return fmt.Errorf("%s: top level is not a mapping", configPath)
+	}
+	sections := []struct {
+		key   string
+		value interface{}
+	}{
+		{"namespace", frag.Namespace},
+		{"device_type", frag.DeviceType},
+		{"device_name", frag.DeviceName},
+	}
+	for _, sec := range sections {
+		var add yaml.Node
+		if err := add.Encode(sec.value); err != nil {
+			return err
+		}
+		m := mappingValue(root, sec.key)
+		for i := 0; i+1 < len(add.Content); i += 2 {
+			setMappingKey(m, add.Content[i].Value, add.Content[i+1])
+		}
+	}
+	if len(frag.Scenarios) > 0 {
+		var existing []ScenarioConfig
+		seq := sequenceValue(root, "scenarios")
+		if err := seq.Decode(&existing); err != nil {
+			return err
+		}
+		kept := seq.Content[:0]
+		for i, sc := range existing {
+			replaced := false
+			for _, a := range frag.Scenarios {
+				if sc.Namespace == a.Namespace && sc.DeviceName == a.DeviceName && sc.matches(a.Command) {
+					replaced = true
+					break
+				}
+			}
+			if !replaced {
+				kept = append(kept, seq.Content[i])
+			}
+		}
+		seq.Content = kept
+		for _, sc := range frag.Scenarios {
+			var n yaml.Node
+			if err := n.Encode(sc); err != nil {
+				return err
+			}
+			seq.Content = append(seq.Content, &n)
+		}
+	}
+	out, err := yaml.Marshal(&doc)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(configPath), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(configPath, out, 0o644)
+}
+
+// mappingValue 返回顶层 key 对应的 mapping 节点，不存在或为空时创建
+func mappingValue(root *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		if root.Content[i].Value == key {
+			v := root.Content[i+1]
+			if v.Kind != yaml.MappingNode {
+				*v = yaml.Node{Kind: yaml.MappingNode}
+			}
+			return v
+		}
+	}
+	v := &yaml.Node{Kind: yaml.MappingNode}
+	root.Content = append(root.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: key}, v)
+	return v
+}
+
+// sequenceValue 返回顶层 key 对应的 sequence 节点，不存在或为空时创建
+func sequenceValue(root *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		if root.Content[i].Value == key {
+			v := root.Content[i+1]
+			if v.Kind != yaml.SequenceNode {
+				*v = yaml.Node{Kind: yaml.SequenceNode}
+			}
+			return v
+		}
+	}
+	v := &yaml.Node{Kind: yaml.SequenceNode}
+	root.Content = append(root.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: key}, v)
+	return v
+}
+
+// setMappingKey 替换或追加 mapping 中的键
+func setMappingKey(m *yaml.Node, key string, value *yaml.Node) {
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			m.Content[i+1] = value
+			return
+		}
+	}
+	m.Content = append(m.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: key}, value)
+}
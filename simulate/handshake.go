@@ -0,0 +1,85 @@
+package simulate
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+
+	"github.com/sshcollectorpro/sshcollectorpro/pkg/logger"
+)
+
+// rsaHostKeyAlgorithms 模拟器 host key 为 RSA，可用的签名算法
+var rsaHostKeyAlgorithms = []string{ssh.KeyAlgoRSASHA512, ssh.KeyAlgoRSASHA256, ssh.KeyAlgoRSA}
+
+// ValidateNamespaceHandshake 校验 namespace 下的握手参数：版本串与算法名须为 x/crypto/ssh 已实现的算法（含不安全算法）
+func ValidateNamespaceHandshake(namespaces map[string]NamespaceConfig) error {
+	supported, insecure := ssh.SupportedAlgorithms(), ssh.InsecureAlgorithms()
+	kexes := append(supported.KeyExchanges, insecure.KeyExchanges...)
+	ciphers := append(supported.Ciphers, insecure.Ciphers...)
+	macs := append(supported.MACs, insecure.MACs...)
+	for name, n := range namespaces {
+		if strings.ContainsAny(n.ServerVersion, "\r\n") || len(n.ServerVersion) > 253 {
+			return fmt.Errorf("namespace %s: server_version must be a single line of at most 253 characters", name)
+		}
+		lists := []struct {
+			field string
+			got   []string
+			known []string
+		}{
+			{"key_exchanges", n.KeyExchanges, kexes},
+			{"ciphers", n.Ciphers, ciphers},
+			{"macs", n.MACs, macs},
+			{"host_key_algorithms", n.HostKeyAlgorithms, rsaHostKeyAlgorithms},
+		}
+		for _, l := range lists {
+			for _, a := range l.got {
+				if !slices.Contains(l.known, strings.TrimSpace(a)) {
+					return fmt.Errorf("namespace %s: unsupported %s entry %q (expected one of %s)", name, l.field, a, strings.Join(l.known, ", "))
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// serverVersion 握手版本串；未以 SSH- 开头时补 SSH-2.0- 前缀（保留 SSH-1.99- 等旧设备写法）
+func (c NamespaceConfig) serverVersion() string {
+	v := strings.TrimSpace(c.ServerVersion)
+	if v == "" || strings.HasPrefix(v, "SSH-") {
+		return v
+	}
+	return "SSH-2.0-" + v
+}
+
+// applyHandshake 按 namespace 配置设置版本串、kex/cipher/mac 列表与 host key 签名算法
+func (s *namespaceServer) applyHandshake(srvCfg *ssh.ServerConfig) {
+	srvCfg.ServerVersion = s.cfg.serverVersion()
+	srvCfg.KeyExchanges = trimAll(s.cfg.KeyExchanges)
+	srvCfg.Ciphers = trimAll(s.cfg.Ciphers)
+	srvCfg.MACs = trimAll(s.cfg.MACs)
+
+	hostKey := s.hostKey
+	if algos := trimAll(s.cfg.HostKeyAlgorithms); len(algos) > 0 {
+		if as, ok := hostKey.(ssh.AlgorithmSigner); ok {
+			if ms, err := ssh.NewSignerWithAlgorithms(as, algos); err == nil {
+				hostKey = ms
+			} else {
+				logger.Warn("Simulate: host_key_algorithms ignored", "namespace", s.nsName, "error", err)
+			}
+		}
+	}
+	srvCfg.AddHostKey(hostKey)
+}
+
+// trimAll 去除空白与空项；结果为空时返回 nil（使用 x/crypto/ssh 默认列表）
+func trimAll(in []string) []string {
+	var out []string
+	for _, v := range in {
+		if t := strings.TrimSpace(v); t != "" {
+			out = append(out, t)
+		}
+	}
+	return out
+}
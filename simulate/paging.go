@@ -0,0 +1,117 @@
+package simulate
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/sshcollectorpro/sshcollectorpro/pkg/logger"
+)
+
+// 默认分页提示（Cisco 风格）
+const defaultMorePrompt = " --More-- "
+
+// ConfirmPromptConfig 命令确认提示：命令（前缀匹配，忽略大小写与多余空白）回显前先输出 prompt 并等待应答
+type ConfirmPromptConfig struct {
+	Command string `mapstructure:"command" yaml:"command" json:"command"`
+	Prompt  string `mapstructure:"prompt" yaml:"prompt" json:"prompt"`                     // 如 "Proceed? [yes/no]:"
+	Expect  string `mapstructure:"expect" yaml:"expect,omitempty" json:"expect,omitempty"` // 期望应答（忽略大小写），为空接受任意应答
+	Abort   string `mapstructure:"abort" yaml:"abort,omitempty" json:"abort,omitempty"`    // 应答不符时的输出，默认 "Aborted."
+}
+
+// ValidateDeviceTypes 校验 device_type 下的分页与确认提示配置
+func ValidateDeviceTypes(types map[string]DeviceTypeConfig) error {
+	for name, t := range types {
+		if t.PageLines < 0 {
+			return fmt.Errorf("device_type %s: page_lines must not be negative", name)
+		}
+		for i, cp := range t.ConfirmPrompts {
+			if strings.TrimSpace(cp.Command) == "" || strings.TrimSpace(cp.Prompt) == "" {
+				return fmt.Errorf("device_type %s: confirm_prompts[%d] requires command and prompt", name, i)
+			}
+		}
+	}
+	return nil
+}
+
+// normalizeCommand 合并空白并转小写，用于命令匹配
+func normalizeCommand(cmd string) string {
+	return strings.ToLower(strings.Join(strings.Fields(cmd), " "))
+}
+
+// disablesPaging 命令是否为关闭分页的命令（如 terminal length 0）
+func (t DeviceTypeConfig) disablesPaging(cmd string) bool {
+	n := normalizeCommand(cmd)
+	for _, c := range t.PagingDisableCommands {
+		if n != "" && n == normalizeCommand(c) {
+			return true
+		}
+	}
+	return false
+}
+
+// confirmFor 匹配命令的确认提示
+func (t DeviceTypeConfig) confirmFor(cmd string) *ConfirmPromptConfig {
+	n := normalizeCommand(cmd)
+	for i := range t.ConfirmPrompts {
+		if strings.HasPrefix(n, normalizeCommand(t.ConfirmPrompts[i].Command)) {
+			return &t.ConfirmPrompts[i]
+		}
+	}
+	return nil
+}
+
+// confirm 输出确认提示并读取一行应答；应答不符时输出 abort 文案并返回 false
+func confirm(channel io.Writer, reader *bufio.Reader, cp *ConfirmPromptConfig) bool {
+	channel.Write([]byte(cp.Prompt))
+	ans, err := reader.ReadString('\n')
+	if err != nil && ans == "" {
+		return false
+	}
+	ans = strings.TrimSpace(cleanNewlines(ans))
+	channel.Write([]byte("\r\n"))
+	if cp.Expect != "" && !strings.EqualFold(ans, strings.TrimSpace(cp.Expect)) {
+		channel.Write([]byte(chooseNonEmpty(cp.Abort, "Aborted.") + "\r\n"))
+		return false
+	}
+	return true
+}
+
+// writePaged 按 page_lines 分页输出：每页后输出 More 提示并读取一个按键，
+// 空格翻页、回车/换行前进一行、q 结束；继续输出前换行，使 More 提示独占一行（可由 output_filter 过滤）
+func writePaged(channel io.Writer, reader *bufio.Reader, out string, pageLines int, morePrompt string) {
+	lines := strings.SplitAfter(out, "\n")
+	if pageLines <= 0 || len(lines) <= pageLines {
+		channel.Write([]byte(out))
+		return
+	}
+	more := chooseNonEmpty(morePrompt, defaultMorePrompt)
+	next := pageLines
+	for len(lines) > 0 {
+		n := next
+		if n > len(lines) {
+			n = len(lines)
+		}
+		channel.Write([]byte(strings.Join(lines[:n], "")))
+		lines = lines[n:]
+		if len(lines) == 0 || (len(lines) == 1 && lines[0] == "") {
+			return
+		}
+		channel.Write([]byte(more))
+		key, err := reader.ReadByte()
+		channel.Write([]byte("\r\n"))
+		if err != nil {
+			return
+		}
+		switch key {
+		case 'q', 'Q':
+			logger.Debug("Simulate: paging quit")
+			return
+		case '\r', '\n':
+			next = 1
+		default:
+			next = pageLines
+		}
+	}
+}
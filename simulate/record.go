@@ -0,0 +1,127 @@
+package simulate
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// RecordedCommand 录制的一条命令回显（输出应已脱敏）
+type RecordedCommand struct {
+	Command string
+	Output  string
+}
+
+// RecordResult 录制结果：路径均相对设备目录
+type RecordResult struct {
+	Dir       string   `json:"dir"`
+	Files     []string `json:"files"`
+	Scenarios []string `json:"scenarios,omitempty"` // 命令无法作为文件名时写入 scenario.yaml 的命令
+}
+
+// recordedDir 无法作为文件名的命令回显存放的子目录（回显查找只扫描设备目录下的 .txt，不受影响）
+const recordedDir = "recorded"
+
+var recordNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// ValidRecordName 命名空间与设备名仅允许字母数字与 . _ -，避免越出模拟目录
+func ValidRecordName(name string) bool {
+	return recordNamePattern.MatchString(name) && !strings.Contains(name, "..")
+}
+
+// DeviceDir 设备回显目录：simulate/namespace/<ns>/<device>
+func DeviceDir(ns, deviceName string) string {
+	return filepath.Join("simulate", "namespace", ns, deviceName)
+}
+
+// commandFileStem 命令对应的回显文件名（空格替换为下划线，与回显查找规则一致）；含路径或保留字符时返回 false
+func commandFileStem(cmd string) (string, bool) {
+	stem := strings.Join(strings.Fields(cmd), "_")
+	if stem == "" || strings.HasPrefix(stem, ".") || strings.ContainsAny(stem, `/\:*?"<>|`) {
+		return "", false
+	}
+	if strings.EqualFold(stem, "supported_commands") {
+		return "", false
+	}
+	return stem, true
+}
+
+// RecordOutputs 将命令回显写入设备目录：可作为文件名的命令写为 <命令>.txt，
+// 其余写入 recorded/ 并在设备 scenario.yaml 中登记（已存在的同名命令覆盖）
+func RecordOutputs(ns, deviceName string, cmds []RecordedCommand) (*RecordResult, error) {
+	if !ValidRecordName(ns) || !ValidRecordName(deviceName) {
+		return nil, fmt.Errorf("invalid namespace or device name: %q/%q", ns, deviceName)
+	}
+	base := DeviceDir(ns, deviceName)
+	if err := os.MkdirAll(base, 0o755); err != nil {
+		return nil, err
+	}
+	res := &RecordResult{Dir: base}
+	var scs []ScenarioConfig
+	for _, c := range cmds {
+		cmd := strings.Join(strings.Fields(c.Command), " ")
+		if cmd == "" {
+			continue
+		}
+		if stem, ok := commandFileStem(cmd); ok {
+			name := stem + ".txt"
+			if err := os.WriteFile(filepath.Join(base, name), []byte(c.Output), 0o644); err != nil {
+				return res, err
+			}
+			res.Files = append(res.Files, name)
+			continue
+		}
+		sum := sha1.Sum([]byte(cmd))
+		name := recordedDir + "/" + hex.EncodeToString(sum[:])[:12] + ".txt"
+		if err := os.MkdirAll(filepath.Join(base, recordedDir), 0o755); err != nil {
+			return res, err
+		}
+		if err := os.WriteFile(filepath.Join(base, filepath.FromSlash(name)), []byte(c.Output), 0o644); err != nil {
+			return res, err
+		}
+		res.Files = append(res.Files, name)
+		res.Scenarios = append(res.Scenarios, cmd)
+		scs = append(scs, ScenarioConfig{Command: cmd, Responses: []ScenarioResponse{{File: name}}})
+	}
+	if len(scs) > 0 {
+		if err := mergeDeviceScenarios(base, scs); err != nil {
+			return res, err
+		}
+	}
+	return res, nil
+}
+
+// mergeDeviceScenarios 合并写入设备 scenario.yaml：同一命令的已有场景被替换
+func mergeDeviceScenarios(base string, add []ScenarioConfig) error {
+	existing, err := loadDeviceScenarios(base)
+	if err != nil {
+		return fmt.Errorf("existing %s is invalid: %w", scenarioFile, err)
+	}
+	merged := make([]ScenarioConfig, 0, len(existing)+len(add))
+	for _, sc := range existing {
+		replaced := false
+		for _, a := range add {
+			if sc.matches(a.Command) {
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			merged = append(merged, sc)
+		}
+	}
+	merged = append(merged, add...)
+	bs, err := yaml.Marshal(struct {
+		Scenarios []ScenarioConfig `yaml:"scenarios"`
+	}{merged})
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(base, scenarioFile), bs, 0o644)
+}
@@ -0,0 +1,178 @@
+package simulate
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/sshcollectorpro/sshcollectorpro/pkg/logger"
+)
+
+// ScenarioConfig 场景化回显：同一命令按调用次数返回不同输出（如第一次接口 down，第二次 up）
+// 定义于 simulate.yaml 的 scenarios，或设备目录下的 scenario.yaml（仅作用于该设备）
+type ScenarioConfig struct {
+	Namespace  string             `mapstructure:"namespace" yaml:"namespace,omitempty"`     // 为空匹配所有命名空间
+	DeviceName string             `mapstructure:"device_name" yaml:"device_name,omitempty"` // 为空匹配所有设备
+	Command    string             `mapstructure:"command" yaml:"command"`
+	Responses  []ScenarioResponse `mapstructure:"responses" yaml:"responses"`
+	// Loop 响应用完后从头循环；默认停留在最后一条
+	Loop bool `mapstructure:"loop" yaml:"loop,omitempty"`
+}
+
+// ScenarioResponse 场景中的一次响应：output 直接给出回显，file 为设备目录下的文件
+type ScenarioResponse struct {
+	Output string `mapstructure:"output" yaml:"output,omitempty"`
+	File   string `mapstructure:"file" yaml:"file,omitempty"`
+	// Repeat 该响应连续返回的次数，默认 1
+	Repeat int `mapstructure:"repeat" yaml:"repeat,omitempty"`
+}
+
+// scenarioFile 设备级场景文件名：simulate/namespace/<ns>/<device>/scenario.yaml
+const scenarioFile = "scenario.yaml"
+
+// ResponseAt 第 n 次调用（从 0 开始）对应的响应
+func (sc ScenarioConfig) ResponseAt(n int) ScenarioResponse {
+	total := 0
+	for _, r := range sc.Responses {
+		total += r.times()
+	}
+	if total == 0 {
+		return ScenarioResponse{}
+	}
+	if n >= total {
+		if !sc.Loop {
+			return sc.Responses[len(sc.Responses)-1]
+		}
+		n %= total
+	}
+	for _, r := range sc.Responses {
+		if n < r.times() {
+			return r
+		}
+		n -= r.times()
+	}
+	return sc.Responses[len(sc.Responses)-1]
+}
+
+func (r ScenarioResponse) times() int {
+	if r.Repeat <= 0 {
+		return 1
+	}
+	return r.Repeat
+}
+
+// matches 命令匹配：忽略大小写与多余空白
+func (sc ScenarioConfig) matches(cmd string) bool {
+	return strings.EqualFold(strings.Join(strings.Fields(sc.Command), " "), strings.Join(strings.Fields(cmd), " "))
+}
+
+// validateScenarios 校验场景定义：命令非空，至少一条响应，output 与 file 二选一
+func validateScenarios(scs []ScenarioConfig) error {
+	for i, sc := range scs {
+		if strings.TrimSpace(sc.Command) == "" {
+			return fmt.Errorf("scenarios[%d]: command is required", i)
+		}
+		if len(sc.Responses) == 0 {
+			return fmt.Errorf("scenarios[%d] (%s): responses is empty", i, sc.Command)
+		}
+		for j, r := range sc.Responses {
+			if (r.Output == "") == (strings.TrimSpace(r.File) == "") {
+				return fmt.Errorf("scenarios[%d] (%s) responses[%d]: exactly one of output, file is required", i, sc.Command, j)
+			}
+			if r.Repeat < 0 {
+				return fmt.Errorf("scenarios[%d] (%s) responses[%d]: repeat must not be negative", i, sc.Command, j)
+			}
+		}
+	}
+	return nil
+}
+
+// scenarioState 场景调用计数：按命名空间、设备与场景来源计数，跨会话保留，配置重载时清零
+type scenarioState struct {
+	mu     sync.Mutex
+	counts map[string]int
+}
+
+// next 返回本次调用序号并递增
+func (st *scenarioState) next(key string) int {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if st.counts == nil {
+		st.counts = make(map[string]int)
+	}
+	n := st.counts[key]
+	st.counts[key] = n + 1
+	return n
+}
+
+func (st *scenarioState) reset() {
+	st.mu.Lock()
+	st.counts = nil
+	st.mu.Unlock()
+}
+
+// loadDeviceScenarios 读取设备目录下的 scenario.yaml（不存在时返回空）
+func loadDeviceScenarios(base string) ([]ScenarioConfig, error) {
+	bs, err := os.ReadFile(filepath.Join(base, scenarioFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var f struct {
+		Scenarios []ScenarioConfig `yaml:"scenarios"`
+	}
+	if err := yaml.Unmarshal(bs, &f); err != nil {
+		return nil, err
+	}
+	if err := validateScenarios(f.Scenarios); err != nil {
+		return nil, err
+	}
+	return f.Scenarios, nil
+}
+
+// scenarioOutput 按场景返回本次调用的回显；设备级场景优先于 simulate.yaml 中的场景
+func (s *namespaceServer) scenarioOutput(ns, deviceName, cmd string) (string, bool) {
+	base := filepath.Join("simulate", "namespace", ns, deviceName)
+	deviceScs, err := loadDeviceScenarios(base)
+	if err != nil {
+		logger.Warn("Simulate: invalid device scenario file", "namespace", ns, "device", deviceName, "error", err)
+	}
+	for _, sc := range deviceScs {
+		if sc.matches(cmd) {
+			return s.renderScenario(base, "device|"+ns+"|"+deviceName, sc, deviceName, cmd), true
+		}
+	}
+	for i, sc := range s.simCfg.Scenarios {
+		if sc.Namespace != "" && sc.Namespace != ns {
+			continue
+		}
+		if sc.DeviceName != "" && sc.DeviceName != deviceName {
+			continue
+		}
+		if sc.matches(cmd) {
+			return s.renderScenario(base, fmt.Sprintf("config#%d|%s|%s", i, ns, deviceName), sc, deviceName, cmd), true
+		}
+	}
+	return "", false
+}
+
+func (s *namespaceServer) renderScenario(base, source string, sc ScenarioConfig, deviceName, cmd string) string {
+	n := s.scenarios.next(source + "|" + strings.ToLower(strings.Join(strings.Fields(cmd), " ")))
+	r := sc.ResponseAt(n)
+	logger.Debug("Simulate: load out (scenario)", "namespace", s.nsName, "device", deviceName, "cmd", cmd, "invocation", n+1)
+	if r.File == "" {
+		return ensureCRLF(r.Output)
+	}
+	bs, err := os.ReadFile(filepath.Join(base, r.File))
+	if err != nil {
+		logger.Warn("Simulate: scenario file missing", "device", deviceName, "cmd", cmd, "file", r.File, "error", err)
+		return "unsupportted command\r\n"
+	}
+	return ensureCRLF(string(bs))
+}
@@ -0,0 +1,43 @@
+package simulate
+
+import (
+	"sort"
+	"sync/atomic"
+)
+
+// current 最近启动且未停止的模拟管理器（供健康检查读取）
+var current atomic.Pointer[Manager]
+
+// Current 返回当前运行的模拟管理器，未启动时为 nil
+func Current() *Manager {
+	return current.Load()
+}
+
+// NamespaceStatus 命名空间监听状态
+type NamespaceStatus struct {
+	Namespace   string `json:"namespace"`
+	Port        int    `json:"port"`
+	TelnetPort  int    `json:"telnet_port,omitempty"`
+	Listening   bool   `json:"listening"`
+	ActiveConns int    `json:"active_conns"`
+	MaxConn     int    `json:"max_conn"`
+}
+
+// Status 返回配置中各命名空间的监听状态（启动失败的命名空间 listening=false），按名称排序
+func (m *Manager) Status() []NamespaceStatus {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	out := make([]NamespaceStatus, 0, len(m.cfg.Namespace))
+	for ns, nsCfg := range m.cfg.Namespace {
+		st := NamespaceStatus{Namespace: ns, Port: nsCfg.Port, TelnetPort: nsCfg.TelnetPort, MaxConn: nsCfg.MaxConn}
+		if srv, ok := m.nsServers[ns]; ok {
+			srv.mu.Lock()
+			st.Listening = srv.listener != nil
+			st.ActiveConns = srv.active
+			srv.mu.Unlock()
+		}
+		out = append(out, st)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Namespace < out[j].Namespace })
+	return out
+}
@@ -0,0 +1,192 @@
+package simulate
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/sshcollectorpro/sshcollectorpro/pkg/logger"
+)
+
+// Telnet 协议字节（RFC 854）
+const (
+	telnetSE   = 240
+	telnetSB   = 250
+	telnetWILL = 251
+	telnetWONT = 252
+	telnetDO   = 253
+	telnetDONT = 254
+	telnetIAC  = 255
+)
+
+// telnetDefaultMaxTries 未配置 max_auth_tries 时 Telnet 登录失败的断开次数（与常见设备一致）
+const telnetDefaultMaxTries = 3
+
+// telnetConn 在 TCP 连接上剥离 Telnet 命令序列：拒绝客户端的选项协商，CR NUL 视为换行，输出中的 0xFF 转义
+type telnetConn struct {
+	net.Conn
+	state  int
+	verb   byte
+	prevCR bool
+}
+
+// Read 协议处理状态
+const (
+	tnData = iota
+	tnIAC
+	tnOption
+	tnSub
+	tnSubIAC
+)
+
+func (t *telnetConn) Read(p []byte) (int, error) {
+	buf := make([]byte, len(p))
+	for {
+		n, err := t.Conn.Read(buf)
+		out := 0
+		var reply []byte
+		for _, b := range buf[:n] {
+			switch t.state {
+			case tnData:
+				switch {
+				case b == telnetIAC:
+					t.state = tnIAC
+				case b == 0 && t.prevCR:
+					p[out] = '\n'
+					out++
+					t.prevCR = false
+				default:
+					p[out] = b
+					out++
+					t.prevCR = b == '\r'
+				}
+			case tnIAC:
+				switch b {
+				case telnetIAC:
+					p[out] = b
+					out++
+					t.state = tnData
+				case telnetWILL, telnetWONT, telnetDO, telnetDONT:
+					t.verb, t.state = b, tnOption
+				case telnetSB:
+					t.state = tnSub
+				default:
+					t.state = tnData
+				}
+			case tnOption:
+				switch t.verb {
+				case telnetDO:
+					reply = append(reply, telnetIAC, telnetWONT, b)
+				case telnetWILL:
+					reply = append(reply, telnetIAC, telnetDONT, b)
+				}
+				t.state = tnData
+			case tnSub:
+				if b == telnetIAC {
+					t.state = tnSubIAC
+				}
+			case tnSubIAC:
+				if b == telnetSE {
+					t.state = tnData
+				} else {
+					t.state = tnSub
+				}
+			}
+		}
+		if len(reply) > 0 {
+			_, _ = t.Conn.Write(reply)
+		}
+		if out > 0 || err != nil {
+			return out, err
+		}
+	}
+}
+
+func (t *telnetConn) Write(p []byte) (int, error) {
+	if bytes.IndexByte(p, telnetIAC) < 0 {
+		return t.Conn.Write(p)
+	}
+	esc := make([]byte, 0, len(p)+8)
+	for _, b := range p {
+		esc = append(esc, b)
+		if b == telnetIAC {
+			esc = append(esc, telnetIAC)
+		}
+	}
+	if _, err := t.Conn.Write(esc); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// handleTelnetConn Telnet 会话：Username/Password 登录（口令与失败模式同 SSH 设备认证），成功后进入与 SSH 相同的交互式 shell
+func (s *namespaceServer) handleTelnetConn(nc net.Conn) {
+	defer nc.Close()
+	tc := &telnetConn{Conn: nc}
+	reader := bufio.NewReader(tc)
+	readLine := func() (string, bool) {
+		line, err := reader.ReadString('\n')
+		if err != nil && line == "" {
+			return "", false
+		}
+		return strings.TrimSpace(cleanNewlines(line)), true
+	}
+	// 登录阶段按 idle_seconds 限时
+	if s.cfg.IdleSeconds > 0 {
+		_ = nc.SetReadDeadline(time.Now().Add(time.Duration(s.cfg.IdleSeconds) * time.Second))
+	}
+	if b := s.cfg.Banner; b != "" {
+		tc.Write([]byte(ensureCRLF(b)))
+	}
+
+	var user string
+	for failures := 0; ; {
+		tc.Write([]byte("\r\nUsername: "))
+		u, ok := readLine()
+		if !ok {
+			return
+		}
+		tc.Write([]byte("Password: "))
+		pwd, ok := readLine()
+		if !ok {
+			return
+		}
+		auth := s.resolveDeviceAuth(u)
+		switch {
+		case auth.failure == AuthFailureDisconnect:
+			logger.Debug("Simulate: telnet auth disconnect", "user", u)
+			return
+		case auth.failure != AuthFailureReject && pwd == auth.password:
+			user = u
+		}
+		if user != "" {
+			break
+		}
+		if auth.delay > 0 {
+			time.Sleep(auth.delay)
+		}
+		failures++
+		logger.Debug("Simulate: telnet auth failed", "user", u, "failures", failures)
+		tc.Write([]byte("\r\n% Login invalid\r\n"))
+		maxTries := auth.maxTries
+		if maxTries <= 0 {
+			maxTries = telnetDefaultMaxTries
+		}
+		if failures >= maxTries {
+			return
+		}
+	}
+	_ = nc.SetReadDeadline(time.Time{})
+	logger.Debug("Simulate: telnet auth success", "namespace", s.nsName, "user", user)
+
+	devType := s.resolveDeviceType(user)
+	tc.Write([]byte("\r\n"))
+	// 登录阶段已缓冲的输入交由 shell 继续读取
+	s.runInteractiveShell(struct {
+		io.Reader
+		io.Writer
+	}{reader, tc}, user, devType.PromptSuffix, devType.EnableModeRequired, devType.EnableModeSuffix, func() { _ = nc.Close() })
+}
@@ -0,0 +1,163 @@
+package integration
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sshcollectorpro/sshcollectorpro/api/handler"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/config"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/database"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/model"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type mapReader map[string]string
+
+func (m mapReader) Read(_ context.Context, uri string) ([]byte, error) {
+	if v, ok := m[uri]; ok {
+		return []byte(v), nil
+	}
+	return nil, service.ErrObjectNotFound
+}
+
+const anonymizeSample = `core-sw1#show running-config
+hostname core-sw1
+username netops privilege 15 secret 5 $1$abc
+snmp-server community s3cr3t RO
+interface Vlan10
+ ip address 10.20.30.1 255.255.255.0
+ ipv6 address 2001:470:1f0b::1/64
+ mac-address aabb.ccdd.eeff
+access-list 10 permit 10.20.30.0 0.0.0.255
+snmp-server host 10.20.30.5 traps version 2c s3cr3t
+! sysObjectID 1.3.6.1.4.1.9.1.516
+`
+
+// TestAnonymizeConsistentAndReversible 同一映射表内假名一致、原值加密存储并可还原
+func TestAnonymizeConsistentAndReversible(t *testing.T) {
+	dir := t.TempDir()
+	cfgPath := filepath.Join(dir, "config.yaml")
+	require.NoError(t, os.WriteFile(cfgPath, []byte("vault:\n  master_key: test-key\n"), 0o600))
+	cfg, err := config.Load(cfgPath)
+	require.NoError(t, err)
+	require.NoError(t, database.InitSQLite(config.SQLiteConfig{Path: filepath.Join(dir, "collector.db")}))
+	defer database.Close()
+
+	reader := mapReader{"file:///backups/core-sw1/run.txt": "Last login from 10.20.30.5 by netops\n"}
+	svc := service.NewAnonymizeService(cfg, handler.NewAnonymizeRepository(), reader)
+	ctx := context.Background()
+
+	resp, err := svc.Anonymize(ctx, &service.AnonymizeRequest{Text: anonymizeSample, URIs: []string{"file:///backups/core-sw1/run.txt"}})
+	require.NoError(t, err)
+	mapID := resp.MapID
+	assert.Regexp(t, `^am-[0-9a-f]{32}$`, mapID)
+	for _, secret := range []string{"core-sw1", "netops", "s3cr3t", "10.20.30.1", "10.20.30.5", "2001:470:1f0b::1", "aabb.ccdd.eeff"} {
+		assert.NotContains(t, resp.Text, secret)
+	}
+	assert.Contains(t, resp.Text, "host-0001#show running-config")
+	assert.Contains(t, resp.Text, "ip address 198.18.0.1 255.255.255.0")
+	assert.Contains(t, resp.Text, "0.0.0.255")
+	assert.Contains(t, resp.Text, "1.3.6.1.4.1.9.1.516")
+	assert.Contains(t, resp.Text, "mac-address 0200.0000.0001")
+	assert.Contains(t, resp.Text, "ipv6 address 2001:db8::1/64")
+	assert.Equal(t, 2, resp.Replaced[service.AnonymizeCommunity])
+	require.Len(t, resp.Objects, 1)
+	assert.Equal(t, "Last login from 198.18.0.3 by user-0001\n", resp.Objects[0].Content)
+
+	// 同一映射表：再次匿名化得到相同假名且不新增映射；重复匿名化结果不变
+	again, err := svc.Anonymize(ctx, &service.AnonymizeRequest{MapID: mapID, Text: anonymizeSample})
+	require.NoError(t, err)
+	assert.Equal(t, resp.Text, again.Text)
+	assert.Zero(t, again.NewMappings)
+	twice, err := svc.Anonymize(ctx, &service.AnonymizeRequest{MapID: mapID, Text: again.Text})
+	require.NoError(t, err)
+	assert.Equal(t, again.Text, twice.Text)
+
+	// 原值不以明文存储；团体字只保存摘要与假名
+	var rows []model.AnonymizeMapping
+	require.NoError(t, database.GetDB().Where("map_id = ?", mapID).Find(&rows).Error)
+	require.NotEmpty(t, rows)
+	for _, r := range rows {
+		if r.Kind == service.AnonymizeCommunity {
+			assert.Empty(t, r.OriginalEnc)
+			continue
+		}
+		assert.NotContains(t, r.OriginalEnc, "10.20.30")
+		assert.True(t, strings.HasPrefix(r.OriginalEnc, "v1:"))
+	}
+
+	// 还原厂商回复中引用的假名；团体字不可还原
+	back, err := svc.Deanonymize(ctx, &service.DeanonymizeRequest{MapID: mapID, Text: "Please check host-0001 port facing 198.18.0.3 (MAC 0200.0000.0001), 198.18.0.40 unknown, community-0001"})
+	require.NoError(t, err)
+	assert.Equal(t, "Please check core-sw1 port facing 10.20.30.5 (MAC aabb.ccdd.eeff), 198.18.0.40 unknown, community-0001", back.Text)
+	assert.Equal(t, 3, back.Restored)
+
+	// 其他映射表独立编号；仅处理指定类型
+	other, err := svc.Anonymize(ctx, &service.AnonymizeRequest{Text: "peer 10.20.30.5 on core-sw1", Kinds: []string{"ip"}})
+	require.NoError(t, err)
+	assert.Equal(t, "peer 198.18.0.1 on core-sw1", other.Text)
+	assert.NotEqual(t, mapID, other.MapID)
+
+	// 映射表只能由服务端创建：指定不存在的 map_id 返回未找到
+	_, err = svc.Anonymize(ctx, &service.AnonymizeRequest{MapID: "default", Text: "10.0.0.1"})
+	assert.ErrorIs(t, err, service.ErrAnonymizeMapNotFound)
+
+	_, err = svc.Anonymize(ctx, &service.AnonymizeRequest{Text: "x", Kinds: []string{"serial"}})
+	assert.ErrorIs(t, err, service.ErrValidation)
+
+	n, err := svc.DeleteMap(other.MapID)
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, n)
+	_, err = svc.Deanonymize(ctx, &service.DeanonymizeRequest{MapID: other.MapID, Text: "198.18.0.1"})
+	assert.ErrorIs(t, err, service.ErrAnonymizeMapNotFound)
+
+	// 未配置主密钥时拒绝执行（映射无法安全存储）
+	_, err = service.NewAnonymizeService(&config.Config{}, handler.NewAnonymizeRepository(), nil).Anonymize(ctx, &service.AnonymizeRequest{Text: "10.0.0.1"})
+	assert.ErrorIs(t, err, service.ErrVaultNotConfigured)
+}
+
+// TestAnonymizeReverseRequiresAdminToken 还原接口需要管理令牌
+func TestAnonymizeReverseRequiresAdminToken(t *testing.T) {
+	dir := t.TempDir()
+	cfgPath := filepath.Join(dir, "config.yaml")
+	require.NoError(t, os.WriteFile(cfgPath, []byte("vault:\n  master_key: test-key\ndebug:\n  token: admin-secret\n"), 0o600))
+	cfg, err := config.Load(cfgPath)
+	require.NoError(t, err)
+	require.NoError(t, database.InitSQLite(config.SQLiteConfig{Path: filepath.Join(dir, "collector.db")}))
+	defer database.Close()
+
+	svc := service.NewAnonymizeService(cfg, handler.NewAnonymizeRepository(), nil)
+	resp, err := svc.Anonymize(context.Background(), &service.AnonymizeRequest{Text: "peer 10.20.30.5"})
+	require.NoError(t, err)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/anonymize/reverse", handler.NewAnonymizeHandler(svc).Deanonymize)
+	reverse := func(token string) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(service.DeanonymizeRequest{MapID: resp.MapID, Text: "198.18.0.1"})
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/anonymize/reverse", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		if token != "" {
+			req.Header.Set("X-Admin-Token", token)
+		}
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	assert.Equal(t, http.StatusUnauthorized, reverse("").Code)
+	assert.Equal(t, http.StatusUnauthorized, reverse("wrong").Code)
+	w := reverse("admin-secret")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), "10.20.30.5")
+}
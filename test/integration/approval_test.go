@@ -0,0 +1,222 @@
+package integration
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sshcollectorpro/sshcollectorpro/api/handler"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/config"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/database"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/model"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestApprovalRequired 仅 exec 下发受审批约束；未启用审批时显式要求审批报错
+func TestApprovalRequired(t *testing.T) {
+	req := &service.DeployFastRequest{TaskType: "exec"}
+	ok, err := service.ApprovalRequired(&config.Config{}, req)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	req.RequireApproval = true
+	_, err = service.ApprovalRequired(&config.Config{}, req)
+	assert.Error(t, err)
+
+	cfg := &config.Config{Approval: config.ApprovalConfig{Enable: true, Secret: "s", BaseURL: "https://collector.example.com"}}
+	ok, err = service.ApprovalRequired(cfg, req)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	ok, _ = service.ApprovalRequired(cfg, &service.DeployFastRequest{TaskType: "dry_run", RequireApproval: true})
+	assert.False(t, ok)
+	cfg.Approval.DeployRequired = true
+	ok, _ = service.ApprovalRequired(cfg, &service.DeployFastRequest{TaskType: "exec"})
+	assert.True(t, ok)
+}
+
+// TestApprovalCards 卡片按钮为签名链接，钉钉加签参数附加在 webhook 上，errcode 非 0 记为推送失败
+func TestApprovalCards(t *testing.T) {
+	var wecom, ding map[string]interface{}
+	var dingQuery url.Values
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		if r.URL.Path == "/wecom" {
+			wecom = body
+			_, _ = w.Write([]byte(`{"errcode":0,"errmsg":"ok"}`))
+			return
+		}
+		ding, dingQuery = body, r.URL.Query()
+		_, _ = w.Write([]byte(`{"errcode":310000,"errmsg":"sign not match"}`))
+	}))
+	defer srv.Close()
+
+	cfg := config.ApprovalConfig{
+		Secret:   "approval-secret",
+		BaseURL:  "https://collector.example.com/",
+		WeCom:    config.ApprovalChannelConfig{WebhookURL: srv.URL + "/wecom"},
+		DingTalk: config.ApprovalChannelConfig{WebhookURL: srv.URL + "/ding?access_token=x", Secret: "SEC"},
+	}
+	req := &service.DeployFastRequest{TaskID: "t1", TaskName: "ntp", Devices: []service.DeployDevice{
+		{DeviceIP: "192.0.2.1", DevicePlatform: "cisco_ios", CliList: service.NewDeployCLIList("ntp server 10.0.0.1")},
+	}}
+	summary := service.SummarizeDeploy(&config.Config{}, req)
+	assert.Contains(t, summary, "ntp server 10.0.0.1")
+
+	expires := time.Now().Add(time.Hour)
+	events := service.SendApprovalCards(context.Background(), cfg, service.ApprovalCard{ID: "a1", Title: "下发审批: ntp", Summary: summary, ExpiresAt: expires})
+	require.Len(t, events, 2)
+	assert.Equal(t, "notified", events[0].Event)
+	assert.Equal(t, "notify_failed", events[1].Event)
+	assert.Contains(t, events[1].Detail, "310000")
+	assert.Equal(t, "x", dingQuery.Get("access_token"))
+	assert.NotEmpty(t, dingQuery.Get("sign"))
+	assert.Equal(t, "actionCard", ding["msgtype"])
+
+	card := wecom["template_card"].(map[string]interface{})
+	jump := card["jump_list"].([]interface{})[0].(map[string]interface{})
+	link, err := url.Parse(jump["url"].(string))
+	require.NoError(t, err)
+	assert.Equal(t, "/api/v1/deploy-approvals/a1/decision", link.Path)
+	q := link.Query()
+	assert.Equal(t, service.ApprovalActionApprove, q.Get("action"))
+	assert.Equal(t, service.ApprovalViaWeCom, q.Get("via"))
+	require.NoError(t, service.VerifyApproval(cfg.Secret, "a1", q.Get("action"), q.Get("via"), q.Get("expires"), q.Get("sig"), time.Now()))
+
+	// 篡改动作、过期或密钥不同均校验失败
+	assert.Error(t, service.VerifyApproval(cfg.Secret, "a1", service.ApprovalActionReject, q.Get("via"), q.Get("expires"), q.Get("sig"), time.Now()))
+	assert.Error(t, service.VerifyApproval(cfg.Secret, "a1", q.Get("action"), q.Get("via"), q.Get("expires"), q.Get("sig"), expires.Add(time.Minute)))
+	assert.Error(t, service.VerifyApproval("other", "a1", q.Get("action"), q.Get("via"), q.Get("expires"), q.Get("sig"), time.Now()))
+	assert.Equal(t, strconv.FormatInt(expires.Unix(), 10), q.Get("expires"))
+}
+
+// TestSummarizeDeployConfigDeploy 仅提供 config_deploy 或模板时摘要展示实际下发、回滚与检查点命令
+func TestSummarizeDeployConfigDeploy(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Collector.DeviceDefaults = map[string]config.PlatformDefaultsConfig{
+		"cisco_ios": {CheckpointCLI: []string{"archive config"}},
+	}
+	req := &service.DeployFastRequest{TaskID: "t2", TaskName: "acl", Checkpoint: true, Devices: []service.DeployDevice{{
+		DeviceIP:        "192.0.2.1",
+		DevicePlatform:  "cisco_ios",
+		ConfigDeploy:    "ip access-list extended BLOCK\r\n deny ip any any\n\nline 1\nline 2\nline 3\nline 4",
+		RollbackCliList: service.NewDeployCLIList("no ip access-list extended BLOCK"),
+	}}}
+	summary := service.SummarizeDeploy(cfg, req)
+	assert.Contains(t, summary, "    ip access-list extended BLOCK")
+	assert.Contains(t, summary, "    deny ip any any")
+	assert.NotContains(t, summary, "line 4")
+	assert.Contains(t, summary, "... 共 6 条")
+	assert.Contains(t, summary, "回滚命令:\n    no ip access-list extended BLOCK")
+	assert.Contains(t, summary, "检查点命令:\n    archive config")
+
+	// 模板按设备变量渲染后展示
+	tplReq := &service.DeployFastRequest{TaskID: "t3", ConfigTemplate: "hostname {{.hostname}}", Devices: []service.DeployDevice{{
+		DeviceIP: "192.0.2.2", DevicePlatform: "cisco_ios", Variables: map[string]interface{}{"hostname": "edge-01"},
+	}}}
+	summary = service.SummarizeDeploy(cfg, tplReq)
+	assert.Contains(t, summary, "    hostname edge-01")
+	assert.NotContains(t, summary, "检查点命令")
+}
+
+// loadApprovalConfig 启用审批（所有 exec 下发均需审批）并配置管理令牌
+func loadApprovalConfig(t *testing.T) *config.Config {
+	cfgPath := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(cfgPath, []byte(`
+debug:
+  token: admin-secret
+approval:
+  enable: true
+  deploy_required: true
+  secret: approval-secret
+  base_url: https://collector.example.com
+`), 0o600))
+	cfg, err := config.Load(cfgPath)
+	require.NoError(t, err)
+	return cfg
+}
+
+// TestDeployServiceEnforcesApproval 审批门控在下发服务内校验：gRPC/运行手册等直接调用服务的入口同样被拒绝，审批执行流程放行
+func TestDeployServiceEnforcesApproval(t *testing.T) {
+	cfg := loadApprovalConfig(t)
+	svc := service.NewDeployService(cfg, service.NewCollectorService(cfg))
+
+	_, err := svc.ExecuteFast(context.Background(), &service.DeployFastRequest{TaskID: "t1", TaskType: "exec"})
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, service.ErrApprovalRequired))
+	status, code := handler.ErrorStatus(err, "")
+	assert.Equal(t, http.StatusForbidden, status)
+	assert.Equal(t, "APPROVAL_REQUIRED", code)
+
+	_, err = svc.ExecuteFast(context.Background(), &service.DeployFastRequest{TaskID: "t1", TaskType: "dry_run"})
+	assert.NoError(t, err)
+	_, err = svc.ExecuteFast(service.WithApprovalGranted(context.Background(), "a1"), &service.DeployFastRequest{TaskID: "t1", TaskType: "exec"})
+	assert.NoError(t, err)
+}
+
+// TestApprovalDecisionRequiresCredential 接口审批须携带管理令牌或签名链接参数，审批人取自凭据而非请求体
+func TestApprovalDecisionRequiresCredential(t *testing.T) {
+	require.NoError(t, database.InitSQLite(config.SQLiteConfig{Path: filepath.Join(t.TempDir(), "collector.db")}))
+	defer database.Close()
+	loadApprovalConfig(t)
+	for _, id := range []string{"a1", "a2", "a3"} {
+		require.NoError(t, database.GetDB().Create(&model.DeployApproval{ID: id, Status: service.ApprovalPending, ExpiresAt: time.Now().Add(time.Hour)}).Error)
+	}
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	ah := handler.NewApprovalHandler(nil)
+	r.POST("/deploy-approvals/:id/reject", ah.Reject)
+	do := func(target, token string) (int, model.DeployApproval) {
+		req := httptest.NewRequest(http.MethodPost, target, bytes.NewReader([]byte(`{"approver":"mallory","comment":"no"}`)))
+		req.Header.Set("Content-Type", "application/json")
+		if token != "" {
+			req.Header.Set("X-Admin-Token", token)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		var row model.DeployApproval
+		_ = database.GetDB().Where("id = ?", path.Base(path.Dir(target))).First(&row).Error
+		return w.Code, row
+	}
+
+	code, row := do("/deploy-approvals/a1/reject", "")
+	assert.Equal(t, http.StatusUnauthorized, code)
+	assert.Equal(t, service.ApprovalPending, row.Status)
+	code, _ = do("/deploy-approvals/a1/reject", "wrong")
+	assert.Equal(t, http.StatusUnauthorized, code)
+
+	code, row = do("/deploy-approvals/a1/reject", "admin-secret")
+	require.Equal(t, http.StatusOK, code)
+	assert.Equal(t, service.ApprovalRejected, row.Status)
+	assert.Equal(t, "admin", row.DecidedBy)
+
+	// 签名链接参数：动作须与签名一致
+	exp := time.Now().Add(time.Hour).Unix()
+	q := url.Values{}
+	q.Set("via", service.ApprovalViaWeCom)
+	q.Set("expires", strconv.FormatInt(exp, 10))
+	q.Set("sig", service.SignApproval("approval-secret", "a2", service.ApprovalActionApprove, service.ApprovalViaWeCom, exp))
+	code, row = do("/deploy-approvals/a2/reject?"+q.Encode(), "")
+	assert.Equal(t, http.StatusForbidden, code)
+	assert.Equal(t, service.ApprovalPending, row.Status)
+
+	q.Set("sig", service.SignApproval("approval-secret", "a3", service.ApprovalActionReject, service.ApprovalViaWeCom, exp))
+	code, row = do("/deploy-approvals/a3/reject?"+q.Encode(), "")
+	require.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "wecom-link", row.DecidedBy)
+	assert.Equal(t, service.ApprovalViaWeCom, row.DecidedVia)
+}
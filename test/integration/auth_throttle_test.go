@@ -0,0 +1,148 @@
+package integration
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sshcollectorpro/sshcollectorpro/api/handler"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/config"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/service"
+	"github.com/sshcollectorpro/sshcollectorpro/simulate"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestAuthThrottlePausesCredential 同一凭据连续认证失败达到阈值后冷却：不再拨号、返回 CREDENTIAL_THROTTLED，
+// 状态在 stats 中可见并推送通知；其他凭据不受影响
+func TestAuthThrottlePausesCredential(t *testing.T) {
+	port := freePort(t)
+	mgr, err := simulate.Start(&simulate.Config{
+		Namespace:  map[string]simulate.NamespaceConfig{"t": {Port: port, IdleSeconds: 60, MaxConn: 10}},
+		DeviceType: map[string]simulate.DeviceTypeConfig{"cisco_ios": {PromptSuffix: "#"}},
+		DeviceName: map[string]simulate.DeviceNameConfig{"sw-01": {DeviceType: "cisco_ios"}},
+		Scenarios: []simulate.ScenarioConfig{
+			{DeviceName: "sw-01", Command: "show clock", Responses: []simulate.ScenarioResponse{{Output: "10:00:00 UTC"}}},
+		},
+	})
+	require.NoError(t, err)
+	t.Cleanup(mgr.Stop)
+
+	notified := make(chan service.CallbackPayload, 4)
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		var p service.CallbackPayload
+		if json.Unmarshal(body, &p) == nil {
+			notified <- p
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(hook.Close)
+
+	cfgPath := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(cfgPath, []byte(`
+ssh:
+  auth_throttle:
+    enable: true
+    max_failures: 2
+    cooldown: 1m
+    notify_url: `+hook.URL+`
+collector:
+  device_defaults:
+    cisco_ios:
+      prompt_suffixes: ["#"]
+      command_interval_ms: 10
+`), 0o600))
+	cfg, err := config.Load(cfgPath)
+	require.NoError(t, err)
+	svc := service.NewCollectorService(cfg)
+	require.NoError(t, svc.Start(context.Background()))
+	t.Cleanup(func() { svc.Stop() })
+
+	noRetry := 0
+	collect := func(id, password string) (*service.CollectResponse, error) {
+		return svc.ExecuteTask(context.Background(), &service.CollectRequest{
+			TaskID: id, DeviceIP: "127.0.0.1", Port: port, DevicePlatform: "cisco_ios", RetryFlag: &noRetry,
+			UserName: "sw-01", Password: password, CliList: service.NewCLIList("show clock"),
+		})
+	}
+
+	for i := 0; i < 2; i++ {
+		resp, err := collect("th-bad", "throttle-wrong")
+		require.NoError(t, err)
+		assert.False(t, resp.Success)
+		assert.Contains(t, resp.Error, "unable to authenticate")
+	}
+	resp, err := collect("th-bad", "throttle-wrong")
+	require.NoError(t, err)
+	assert.False(t, resp.Success)
+	assert.True(t, strings.HasPrefix(resp.Error, "CREDENTIAL_THROTTLED"), resp.Error)
+	status, code := handler.ErrorStatus(fmt.Errorf("collect: %w", service.ErrCredentialThrottled), "")
+	assert.Equal(t, http.StatusLocked, status)
+	assert.Equal(t, "CREDENTIAL_THROTTLED", code)
+
+	// 冷却期跨服务生效：批量备份同一凭据不建连
+	backup := service.NewBackupService(cfg)
+	require.NoError(t, backup.Start(context.Background()))
+	t.Cleanup(func() { backup.Stop() })
+	out, err := backup.ExecuteBatch(context.Background(), &service.BackupBatchRequest{
+		TaskID:  "th-bk",
+		SaveDir: t.TempDir(),
+		Devices: []service.BackupDevice{{DeviceIP: "127.0.0.1", Port: port, UserName: "sw-01", Password: "throttle-wrong", CliList: service.NewCLIList("show clock")}},
+	})
+	require.NoError(t, err)
+	require.Len(t, out.Data, 1)
+	assert.Equal(t, service.StatusCredentialThrottled, out.Data[0].Status)
+
+	// 正确凭据不受影响
+	resp, err = collect("th-ok", "nova")
+	require.NoError(t, err)
+	require.True(t, resp.Success, resp.Error)
+
+	stats := svc.GetStats()["auth_throttle"].(service.AuthThrottleStats)
+	assert.True(t, stats.Enable)
+	require.Equal(t, 1, stats.Locked)
+	var locked *service.AuthThrottleEntry
+	for i := range stats.Credentials {
+		if stats.Credentials[i].Locked {
+			locked = &stats.Credentials[i]
+		}
+	}
+	require.NotNil(t, locked)
+	assert.Equal(t, "sw-01", locked.Username)
+	assert.Equal(t, 2, locked.Failures)
+	assert.Equal(t, "127.0.0.1", locked.LastDevice)
+	require.NotNil(t, locked.LockedUntil)
+	assert.WithinDuration(t, time.Now().Add(time.Minute), *locked.LockedUntil, 10*time.Second)
+	// stats 只暴露用户名与设备，不含密码或可离线比对的密码摘要
+	raw, err := json.Marshal(stats)
+	require.NoError(t, err)
+	assert.NotContains(t, string(raw), "fingerprint")
+	wrongSum := sha256.Sum256([]byte("throttle-wrong"))
+	assert.NotContains(t, string(raw), hex.EncodeToString(wrongSum[:])[:12])
+
+	select {
+	case p := <-notified:
+		assert.Equal(t, "auth_throttle", p.TaskType)
+		assert.Equal(t, "CREDENTIAL_THROTTLED", p.Code)
+		assert.NotContains(t, p.Message, "throttle-wrong")
+		assert.NotContains(t, p.TaskID, hex.EncodeToString(wrongSum[:])[:12])
+	case <-time.After(5 * time.Second):
+		t.Fatal("throttle notification not delivered")
+	}
+
+	report, err := config.ValidateYAML([]byte("ssh:\n  auth_throttle:\n    enable: true\n    max_failures: 0\n"))
+	require.NoError(t, err)
+	require.NotEmpty(t, report.Errors())
+	assert.Equal(t, "ssh.auth_throttle.max_failures", report.Errors()[0].Path)
+}
@@ -0,0 +1,215 @@
+package integration
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sshcollectorpro/sshcollectorpro/api/handler"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/config"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const diffBaseConfig = `! Last configuration change at 02:00:00 UTC Thu Oct 15 2026
+hostname core-sw-01
+!
+interface Gi0/1
+ description uplink
+ shutdown
+!
+router ospf 1
+ network 10.0.0.0 0.0.0.255 area 0
+`
+
+const diffTargetConfig = `! Last configuration change at 02:00:00 UTC Fri Oct 16 2026
+hostname core-sw-01
+!
+interface Gi0/1
+ description uplink
+ no shutdown
+!
+router ospf 1
+ network 10.0.0.0 0.0.0.255 area 0
+ network 10.1.0.0 0.0.0.255 area 0
+`
+
+// diffDo 发送对比请求，返回状态码与响应体
+type diffDo func(body map[string]interface{}) (int, map[string]interface{})
+
+// backupDiffServer 本地备份目录与 MinIO 中各放置两份备份对象，返回对比请求函数与本地备份根目录
+func backupDiffServer(t *testing.T) (diffDo, string) {
+	fake := &fakeS3{objects: map[string][]byte{}, ctypes: map[string]string{}}
+	srv := httptest.NewServer(fake)
+	t.Cleanup(srv.Close)
+	host, port, err := net.SplitHostPort(strings.TrimPrefix(srv.URL, "http://"))
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	base := filepath.Join(dir, "backups")
+	for _, f := range []struct{ stamp, task, content string }{
+		{"20261015_020000", "task-1015", diffBaseConfig},
+		{"20261016_020000", "task-1016", diffTargetConfig},
+	} {
+		p := filepath.Join(base, "configs", "core-sw-01", f.stamp, f.task, "show_running-config.txt")
+		require.NoError(t, os.MkdirAll(filepath.Dir(p), 0o755))
+		require.NoError(t, os.WriteFile(p, []byte(f.content), 0o600))
+	}
+	other := filepath.Join(base, "configs", "core-sw-02", "20261016_020000", "task-1016", "show_running-config.txt")
+	require.NoError(t, os.MkdirAll(filepath.Dir(other), 0o755))
+	require.NoError(t, os.WriteFile(other, []byte(diffTargetConfig), 0o600))
+	fake.objects["ssh-collector/configs/core-sw-01/20261015_020000/task-1015/show_running-config.txt"] = []byte(diffBaseConfig)
+	fake.objects["ssh-collector/configs/core-sw-01/20261016_020000/task-1016/show_running-config.txt"] = []byte(diffTargetConfig)
+
+	cfgPath := filepath.Join(dir, "config.yaml")
+	require.NoError(t, os.WriteFile(cfgPath, []byte(`
+backup:
+  storage_backend: local
+  local:
+    base_dir: `+base+`
+storage:
+  minio:
+    host: `+host+`
+    port: `+port+`
+    access_key: AKIDTEST
+    secret_key: secret
+    bucket: ssh-collector
+`), 0o600))
+	cfg, err := config.Load(cfgPath)
+	require.NoError(t, err)
+	svc := service.NewBackupService(cfg)
+	require.NoError(t, svc.Start(context.Background()))
+	t.Cleanup(func() { svc.Stop() })
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(handler.ErrorMiddleware())
+	r.POST("/api/v1/backup/diff", handler.NewBackupHandler(svc).DiffBackup)
+	do := func(body map[string]interface{}) (int, map[string]interface{}) {
+		b, _ := json.Marshal(body)
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/backup/diff", bytes.NewReader(b))
+		req.Header.Set("Content-Type", "application/json")
+		r.ServeHTTP(w, req)
+		var out map[string]interface{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &out), w.Body.String())
+		return w.Code, out
+	}
+	return do, base
+}
+
+// TestBackupDiff 统一 diff 与变更摘要：增删行数、涉及的顶层配置段，忽略规则剔除易变行
+func TestBackupDiff(t *testing.T) {
+	do, base := backupDiffServer(t)
+	uri := func(stamp, task string) string {
+		return "file://" + filepath.Join(base, "configs", "core-sw-01", stamp, task, "show_running-config.txt")
+	}
+	baseURI, targetURI := uri("20261015_020000", "task-1015"), uri("20261016_020000", "task-1016")
+
+	code, body := do(map[string]interface{}{"base_uri": baseURI, "target_uri": targetURI})
+	require.Equal(t, http.StatusOK, code, body)
+	data := body["data"].(map[string]interface{})
+	assert.Equal(t, "core-sw-01", data["device"])
+	assert.Equal(t, "show_running-config", data["command"])
+	diff := data["diff"].(string)
+	assert.Contains(t, diff, "--- "+baseURI)
+	assert.Contains(t, diff, "+++ "+targetURI)
+	assert.Contains(t, diff, "\n- shutdown\n")
+	assert.Contains(t, diff, "\n+ no shutdown\n")
+	assert.Contains(t, diff, "\n+ network 10.1.0.0 0.0.0.255 area 0\n")
+	summary := data["summary"].(map[string]interface{})
+	assert.Equal(t, false, summary["identical"])
+	assert.Equal(t, float64(3), summary["lines_added"])
+	assert.Equal(t, float64(2), summary["lines_removed"])
+	// 变化的无缩进注释行自身计为一段
+	assert.Equal(t, []interface{}{
+		"! Last configuration change at 02:00:00 UTC Fri Oct 16 2026",
+		"! Last configuration change at 02:00:00 UTC Thu Oct 15 2026",
+		"interface Gi0/1",
+		"router ospf 1",
+	}, summary["sections_changed"])
+
+	// 忽略规则：只剩配置变化；context=0 时不输出上下文行
+	code, body = do(map[string]interface{}{"base_uri": baseURI, "target_uri": targetURI, "context": 0, "ignore_patterns": []string{"^! Last configuration change"}})
+	require.Equal(t, http.StatusOK, code, body)
+	data = body["data"].(map[string]interface{})
+	summary = data["summary"].(map[string]interface{})
+	assert.Equal(t, float64(2), summary["lines_added"])
+	assert.Equal(t, float64(1), summary["lines_removed"])
+	assert.Equal(t, []interface{}{"interface Gi0/1", "router ospf 1"}, summary["sections_changed"])
+	assert.NotContains(t, data["diff"], "Last configuration change")
+	assert.NotContains(t, data["diff"], " description uplink")
+
+	// 同一对象：无变化
+	code, body = do(map[string]interface{}{"base_uri": targetURI, "target_uri": targetURI})
+	require.Equal(t, http.StatusOK, code, body)
+	data = body["data"].(map[string]interface{})
+	assert.Equal(t, true, data["summary"].(map[string]interface{})["identical"])
+	assert.Empty(t, data["diff"])
+}
+
+// TestBackupDiffMinio MinIO 对象按配置的 bucket 读取
+func TestBackupDiffMinio(t *testing.T) {
+	do, _ := backupDiffServer(t)
+	code, body := do(map[string]interface{}{
+		"base_uri":   "minio://ssh-collector/configs/core-sw-01/20261015_020000/task-1015/show_running-config.txt",
+		"target_uri": "minio://ssh-collector/configs/core-sw-01/20261016_020000/task-1016/show_running-config.txt",
+	})
+	require.Equal(t, http.StatusOK, code, body)
+	summary := body["data"].(map[string]interface{})["summary"].(map[string]interface{})
+	assert.Equal(t, float64(3), summary["lines_added"])
+
+	// 其他 bucket 拒绝读取
+	code, body = do(map[string]interface{}{
+		"base_uri":   "minio://other/configs/core-sw-01/20261015_020000/task-1015/show_running-config.txt",
+		"target_uri": "minio://ssh-collector/configs/core-sw-01/20261016_020000/task-1016/show_running-config.txt",
+	})
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Equal(t, "INVALID_PARAMS", body["code"])
+}
+
+// TestBackupDiffErrors 参数错误返回 400，对象不存在返回 404
+func TestBackupDiffErrors(t *testing.T) {
+	do, base := backupDiffServer(t)
+	file := func(device, stamp, task string) string {
+		return "file://" + filepath.Join(base, "configs", device, stamp, task, "show_running-config.txt")
+	}
+	baseURI := file("core-sw-01", "20261015_020000", "task-1015")
+	otherDevice := file("core-sw-02", "20261016_020000", "task-1016")
+
+	cases := []struct {
+		name   string
+		body   map[string]interface{}
+		status int
+		code   string
+	}{
+		{"missing uri", map[string]interface{}{"base_uri": baseURI}, http.StatusBadRequest, "INVALID_PARAMS"},
+		{"not a backup path", map[string]interface{}{"base_uri": "file://x.txt", "target_uri": baseURI}, http.StatusBadRequest, "INVALID_PARAMS"},
+		{"different device", map[string]interface{}{"base_uri": baseURI, "target_uri": otherDevice}, http.StatusBadRequest, "INVALID_PARAMS"},
+		{"bad ignore pattern", map[string]interface{}{"base_uri": baseURI, "target_uri": baseURI, "ignore_patterns": []string{"("}}, http.StatusBadRequest, "INVALID_PARAMS"},
+		{"outside base dir", map[string]interface{}{"base_uri": "file:///etc/configs/core-sw-01/a/b/show_running-config.txt", "target_uri": baseURI}, http.StatusBadRequest, "INVALID_PARAMS"},
+		{"unsupported scheme", map[string]interface{}{"base_uri": "ftp://h/configs/core-sw-01/a/b/show_running-config.txt", "target_uri": baseURI}, http.StatusBadRequest, "INVALID_PARAMS"},
+		{"object not found", map[string]interface{}{"base_uri": file("core-sw-01", "20261001_020000", "task-1001"), "target_uri": baseURI}, http.StatusNotFound, "OBJECT_NOT_FOUND"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			code, body := do(tc.body)
+			assert.Equal(t, tc.status, code, body)
+			assert.Equal(t, tc.code, body["code"])
+		})
+	}
+
+	// allow_mismatch 允许跨设备对比
+	code, body := do(map[string]interface{}{"base_uri": baseURI, "target_uri": otherDevice, "allow_mismatch": true})
+	require.Equal(t, http.StatusOK, code, body)
+	assert.Equal(t, "core-sw-02", body["data"].(map[string]interface{})["device"])
+}
@@ -0,0 +1,91 @@
+package integration
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sshcollectorpro/sshcollectorpro/internal/config"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/service"
+	"github.com/sshcollectorpro/sshcollectorpro/simulate"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestBackupNamingFacts 命名模板引用的事实在同一会话中解析；事实命令结果不返回，平台未定义事实时回退到设备名
+func TestBackupNamingFacts(t *testing.T) {
+	port := freePort(t)
+	mgr, err := simulate.Start(&simulate.Config{
+		Namespace:  map[string]simulate.NamespaceConfig{"t": {Port: port, IdleSeconds: 60, MaxConn: 10}},
+		DeviceType: map[string]simulate.DeviceTypeConfig{"cisco_ios": {PromptSuffix: "#"}},
+		DeviceName: map[string]simulate.DeviceNameConfig{"sw-01": {DeviceType: "cisco_ios"}},
+		Scenarios: []simulate.ScenarioConfig{
+			{Command: "show version", Responses: []simulate.ScenarioResponse{{Output: "core-sw1 uptime is 3 weeks\nProcessor board ID FTX1234ABC\n"}}},
+			{Command: "show clock", Responses: []simulate.ScenarioResponse{{Output: "10:00:00.000 UTC Mon Oct 16 2026\n"}}},
+		},
+	})
+	require.NoError(t, err)
+	defer mgr.Stop()
+
+	base := t.TempDir()
+	cfgPath := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(cfgPath, []byte(`
+backup:
+  prefix: ""
+  path_template: "${hostname}/${task_id}"
+  filename_template: "${serial}_${command}"
+  aggregate:
+    enabled: false
+  local:
+    base_dir: `+base+`
+collector:
+  device_defaults:
+    cisco_ios:
+      prompt_suffixes: ["#"]
+      command_interval_ms: 10
+      facts:
+        - name: hostname
+          command: show version
+          pattern: '^(\S+) uptime is'
+        - name: serial
+          command: show version
+          pattern: 'Processor board ID (\S+)'
+    cisco_nxos:
+      prompt_suffixes: ["#"]
+      command_interval_ms: 10
+`), 0o600))
+	cfg, err := config.Load(cfgPath)
+	require.NoError(t, err)
+	svc := service.NewBackupService(cfg)
+	require.NoError(t, svc.Start(context.Background()))
+	defer svc.Stop()
+
+	backup := func(platform string) service.DeviceBackupResponse {
+		resp, err := svc.ExecuteBatch(context.Background(), &service.BackupBatchRequest{
+			TaskID: "bk-name-" + platform,
+			Devices: []service.BackupDevice{{
+				DeviceIP: "127.0.0.1", Port: port, DeviceName: "Edge-01", DevicePlatform: platform,
+				UserName: "sw-01", Password: "nova", CliList: service.NewCLIList("show clock"),
+			}},
+		})
+		require.NoError(t, err)
+		require.Len(t, resp.Data, 1)
+		return resp.Data[0]
+	}
+
+	r := backup("cisco_ios")
+	require.True(t, r.Success, r.Error)
+	require.Len(t, r.Results, 1, "fact command results are not returned")
+	require.Len(t, r.Results[0].StoredObjects, 1)
+	uri := filepath.ToSlash(r.Results[0].StoredObjects[0].URI)
+	assert.True(t, strings.HasSuffix(uri, "/core-sw1/bk-name-cisco_ios/ftx1234abc_show_clock.txt"), uri)
+
+	// 平台未定义事实：回退到设备名
+	r = backup("cisco_nxos")
+	require.True(t, r.Success, r.Error)
+	require.Len(t, r.Results[0].StoredObjects, 1)
+	uri = filepath.ToSlash(r.Results[0].StoredObjects[0].URI)
+	assert.True(t, strings.HasSuffix(uri, "/edge-01/bk-name-cisco_nxos/edge-01_show_clock.txt"), uri)
+}
@@ -0,0 +1,86 @@
+package integration
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sshcollectorpro/sshcollectorpro/internal/config"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/service"
+	"github.com/sshcollectorpro/sshcollectorpro/simulate"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestBackupSessionTranscript 请求开启 transcript 时会话收发字节随备份保存为 session.transcript；默认不保存
+func TestBackupSessionTranscript(t *testing.T) {
+	port := freePort(t)
+	mgr, err := simulate.Start(&simulate.Config{
+		Namespace:  map[string]simulate.NamespaceConfig{"t": {Port: port, IdleSeconds: 60, MaxConn: 10}},
+		DeviceType: map[string]simulate.DeviceTypeConfig{"cisco_ios": {PromptSuffix: "#"}},
+		DeviceName: map[string]simulate.DeviceNameConfig{"sw-01": {DeviceType: "cisco_ios"}},
+		Scenarios: []simulate.ScenarioConfig{
+			{Command: "show clock", Responses: []simulate.ScenarioResponse{{Output: "10:00:00.000 UTC Mon Oct 16 2026\n"}}},
+		},
+	})
+	require.NoError(t, err)
+	defer mgr.Stop()
+
+	base := t.TempDir()
+	cfgPath := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(cfgPath, []byte(`
+backup:
+  prefix: ""
+  aggregate:
+    enabled: false
+  local:
+    base_dir: `+base+`
+collector:
+  device_defaults:
+    cisco_ios:
+      prompt_suffixes: ["#"]
+      command_interval_ms: 10
+`), 0o600))
+	cfg, err := config.Load(cfgPath)
+	require.NoError(t, err)
+	svc := service.NewBackupService(cfg)
+	require.NoError(t, svc.Start(context.Background()))
+	defer svc.Stop()
+
+	backup := func(transcript *bool) service.DeviceBackupResponse {
+		resp, err := svc.ExecuteBatch(context.Background(), &service.BackupBatchRequest{
+			TaskID:     "bk-transcript",
+			Transcript: transcript,
+			Devices: []service.BackupDevice{{
+				DeviceIP: "127.0.0.1", Port: port, DeviceName: "sw-01", DevicePlatform: "cisco_ios",
+				UserName: "sw-01", Password: "nova", CliList: service.NewCLIList("show clock"),
+			}},
+		})
+		require.NoError(t, err)
+		require.Len(t, resp.Data, 1)
+		return resp.Data[0]
+	}
+
+	r := backup(nil)
+	require.True(t, r.Success, r.Error)
+	assert.Nil(t, r.TranscriptObject, "transcript is disabled by default")
+
+	on := true
+	r = backup(&on)
+	require.True(t, r.Success, r.Error)
+	require.NotNil(t, r.TranscriptObject)
+	require.Len(t, r.Results, 1)
+	require.Len(t, r.Results[0].StoredObjects, 1)
+	assert.Equal(t, filepath.Dir(r.Results[0].StoredObjects[0].URI), filepath.Dir(r.TranscriptObject.URI), "stored alongside the backup files")
+	assert.Equal(t, service.TranscriptObjectName, filepath.Base(r.TranscriptObject.URI))
+
+	data, err := os.ReadFile(strings.TrimPrefix(r.TranscriptObject.URI, "file://"))
+	require.NoError(t, err)
+	text := string(data)
+	assert.True(t, strings.HasPrefix(text, "# session 1 127.0.0.1:"), text)
+	assert.Contains(t, text, ` > "show clock`)
+	assert.Contains(t, text, `10:00:00.000 UTC Mon Oct 16 2026`)
+	assert.Contains(t, text, " < \"")
+}
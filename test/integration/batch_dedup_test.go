@@ -0,0 +1,95 @@
+package integration
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sshcollectorpro/sshcollectorpro/internal/config"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/service"
+	"github.com/sshcollectorpro/sshcollectorpro/simulate"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestBackupBatchDuplicateDevices 批内重复设备：copy 执行一次并复制结果，merge 只返回一次，reject 拒绝请求
+func TestBackupBatchDuplicateDevices(t *testing.T) {
+	port := freePort(t)
+	mgr, err := simulate.Start(&simulate.Config{
+		Namespace:  map[string]simulate.NamespaceConfig{"t": {Port: port, IdleSeconds: 60, MaxConn: 10}},
+		DeviceType: map[string]simulate.DeviceTypeConfig{"cisco_ios": {PromptSuffix: "#"}},
+		DeviceName: map[string]simulate.DeviceNameConfig{"sw-01": {DeviceType: "cisco_ios"}},
+		Scenarios: []simulate.ScenarioConfig{
+			{DeviceName: "sw-01", Command: "show clock", Responses: []simulate.ScenarioResponse{{Output: "10:00:00 UTC"}}},
+			{DeviceName: "sw-01", Command: "show version", Responses: []simulate.ScenarioResponse{{Output: "IOS 15.2"}}},
+		},
+	})
+	require.NoError(t, err)
+	t.Cleanup(mgr.Stop)
+
+	cfgPath := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(cfgPath, []byte(`
+backup:
+  aggregate:
+    enabled: false
+  local:
+    base_dir: `+t.TempDir()+`
+collector:
+  device_defaults:
+    cisco_ios:
+      prompt_suffixes: ["#"]
+      command_interval_ms: 10
+`), 0o600))
+	cfg, err := config.Load(cfgPath)
+	require.NoError(t, err)
+	backup := service.NewBackupService(cfg)
+	require.NoError(t, backup.Start(context.Background()))
+	t.Cleanup(func() { backup.Stop() })
+
+	device := func(name, cli string) service.BackupDevice {
+		return service.BackupDevice{DeviceIP: "127.0.0.1", Port: port, DeviceName: name, DevicePlatform: "cisco_ios",
+			UserName: "sw-01", Password: "nova", CliList: service.NewCLIList(cli)}
+	}
+	run := func(mode string) (*service.BackupBatchResponse, error) {
+		return backup.ExecuteBatch(context.Background(), &service.BackupBatchRequest{
+			TaskID: "dup-" + mode, DuplicateMode: mode,
+			Devices: []service.BackupDevice{device("a", "show clock"), device("b", "show version"), device("c", "show clock")},
+		})
+	}
+
+	out, err := run(service.DuplicateModeCopy)
+	require.NoError(t, err)
+	require.Len(t, out.Data, 3)
+	assert.Equal(t, 3, out.Total)
+	assert.Equal(t, "SUCCESS", out.Code)
+	require.Len(t, out.Duplicates, 2)
+	assert.Equal(t, service.DuplicateDevice{Index: 1, DuplicateOf: 0, DeviceIP: "127.0.0.1", Port: port, UserName: "sw-01"}, out.Duplicates[0])
+	assert.Nil(t, out.Data[0].DuplicateOf)
+	assert.Len(t, out.Data[0].Results, 2, "duplicate commands are merged into the first device")
+	for i, name := range []string{"b", "c"} {
+		r := out.Data[i+1]
+		require.NotNil(t, r.DuplicateOf)
+		assert.Equal(t, 0, *r.DuplicateOf)
+		assert.Equal(t, name, r.DeviceName)
+		assert.Equal(t, out.Data[0].Results, r.Results)
+	}
+
+	out, err = run(service.DuplicateModeMerge)
+	require.NoError(t, err)
+	require.Len(t, out.Data, 1)
+	assert.Len(t, out.Data[0].Results, 2)
+	assert.Len(t, out.Duplicates, 2)
+
+	_, err = run(service.DuplicateModeReject)
+	assert.ErrorIs(t, err, service.ErrDuplicateDevices)
+	assert.ErrorIs(t, err, service.ErrValidation)
+
+	_, err = run("skip")
+	assert.ErrorIs(t, err, service.ErrValidation)
+
+	report, err := config.ValidateYAML([]byte("collector:\n  duplicate_devices: ignore\n"))
+	require.NoError(t, err)
+	require.NotEmpty(t, report.Errors())
+	assert.Equal(t, "collector.duplicate_devices", report.Errors()[0].Path)
+}
@@ -0,0 +1,173 @@
+package integration
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sshcollectorpro/sshcollectorpro/api/handler"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/config"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/database"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/model"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/service"
+	"github.com/sshcollectorpro/sshcollectorpro/simulate"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// deviceSheetXLSX 构造共享字符串单元格的最小工作簿（模拟 Excel 保存的文件）
+func deviceSheetXLSX(t *testing.T, rows [][]string) []byte {
+	var shared, sheet strings.Builder
+	n := 0
+	for r, row := range rows {
+		sheet.WriteString(`<row r="` + strconv.Itoa(r+1) + `">`)
+		for c, v := range row {
+			if v == "" {
+				continue
+			}
+			ref := string(rune('A'+c)) + strconv.Itoa(r+1)
+			if _, err := strconv.Atoi(v); err == nil {
+				sheet.WriteString(`<c r="` + ref + `"><v>` + v + `</v></c>`)
+				continue
+			}
+			shared.WriteString(`<si><t>` + v + `</t></si>`)
+			sheet.WriteString(`<c r="` + ref + `" t="s"><v>` + strconv.Itoa(n) + `</v></c>`)
+			n++
+		}
+		sheet.WriteString(`</row>`)
+	}
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, body := range map[string]string{
+		"xl/sharedStrings.xml":     `<sst xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">` + shared.String() + `</sst>`,
+		"xl/worksheets/sheet1.xml": `<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>` + sheet.String() + `</sheetData></worksheet>`,
+	} {
+		w, err := zw.Create(name)
+		require.NoError(t, err)
+		_, err = w.Write([]byte(body))
+		require.NoError(t, err)
+	}
+	require.NoError(t, zw.Close())
+	return buf.Bytes()
+}
+
+// TestBatchDefinitionImportAndRun CSV 行级校验错误、XLSX 导入加密保存，并按定义执行自定义批量采集
+func TestBatchDefinitionImportAndRun(t *testing.T) {
+	port := freePort(t)
+	mgr, err := simulate.Start(&simulate.Config{
+		Namespace:  map[string]simulate.NamespaceConfig{"t": {Port: port, IdleSeconds: 60, MaxConn: 10}},
+		DeviceType: map[string]simulate.DeviceTypeConfig{"cisco_ios": {PromptSuffix: "#"}},
+		DeviceName: map[string]simulate.DeviceNameConfig{"sw-01": {DeviceType: "cisco_ios"}},
+		Scenarios: []simulate.ScenarioConfig{
+			{DeviceName: "sw-01", Command: "show clock", Responses: []simulate.ScenarioResponse{{Output: "10:00:00 UTC Fri Oct 16 2026"}}},
+		},
+	})
+	require.NoError(t, err)
+	t.Cleanup(mgr.Stop)
+
+	dir := t.TempDir()
+	cfgPath := filepath.Join(dir, "config.yaml")
+	require.NoError(t, os.WriteFile(cfgPath, []byte(`
+vault:
+  master_key: test-key
+collector:
+  device_defaults:
+    cisco_ios:
+      prompt_suffixes: ["#"]
+      command_interval_ms: 10
+`), 0o600))
+	cfg, err := config.Load(cfgPath)
+	require.NoError(t, err)
+	require.NoError(t, database.InitSQLite(config.SQLiteConfig{Path: filepath.Join(dir, "collector.db")}))
+	t.Cleanup(func() { database.Close() })
+	require.NoError(t, database.GetDB().Create(&model.Playbook{Name: "clock", Commands: `[{"cli":"show clock"}]`}).Error)
+
+	svc := service.NewCollectorService(cfg)
+	require.NoError(t, svc.Start(context.Background()))
+	t.Cleanup(func() { svc.Stop() })
+
+	h := handler.NewBatchDefinitionHandler(handler.NewCollectorHandler(svc), handler.NewBackupHandler(service.NewBackupService(cfg)))
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(handler.ErrorMiddleware())
+	r.POST("/batch-definitions/import", h.ImportBatchDefinition)
+	r.GET("/batch-definitions/:name", h.GetBatchDefinition)
+	r.POST("/batch-definitions/:name/run", h.RunBatchDefinition)
+
+	upload := func(filename string, data []byte, fields map[string]string) (int, map[string]interface{}) {
+		var body bytes.Buffer
+		mw := multipart.NewWriter(&body)
+		fw, err := mw.CreateFormFile("file", filename)
+		require.NoError(t, err)
+		_, err = fw.Write(data)
+		require.NoError(t, err)
+		for k, v := range fields {
+			require.NoError(t, mw.WriteField(k, v))
+		}
+		require.NoError(t, mw.Close())
+		req := httptest.NewRequest(http.MethodPost, "/batch-definitions/import", &body)
+		req.Header.Set("Content-Type", mw.FormDataContentType())
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		var out map[string]interface{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &out))
+		return w.Code, out
+	}
+
+	// CSV：每行错误按行号与列返回，不保存
+	csvData := "IP,Name,Platform,Port,Username,Password,Credential ID,CLI Profile\n" +
+		"10.0.0.1,a,cisco_ios,22,u,p,,clock\n" +
+		",b,cisco_ios,22,u,p,,\n" +
+		"10.0.0.3,c,cisco_ios,70000,u,p,,\n" +
+		"10.0.0.4,d,cisco_ios,,,,cred-missing,\n" +
+		"10.0.0.1,e,cisco_ios,22,u,p,,nope\n"
+	code, body := upload("devices.csv", []byte(csvData), map[string]string{"name": "bad"})
+	require.Equal(t, http.StatusBadRequest, code)
+	assert.Equal(t, "IMPORT_INVALID", body["code"])
+	data := body["data"].(map[string]interface{})
+	assert.EqualValues(t, 4, data["invalid_rows"])
+	assert.EqualValues(t, 1, data["valid"])
+	got := map[string]bool{}
+	for _, e := range data["errors"].([]interface{}) {
+		m := e.(map[string]interface{})
+		got[strconv.Itoa(int(m["row"].(float64)))+":"+m["column"].(string)] = true
+	}
+	assert.Equal(t, map[string]bool{"3:device_ip": true, "4:device_port": true, "5:credential_id": true, "6:device_ip": true}, got)
+
+	// XLSX：共享字符串与数值单元格，含明文密码的设备列表加密保存
+	xlsx := deviceSheetXLSX(t, [][]string{
+		{"device_ip", "device_name", "platform", "port", "user_name", "password", "credential_id", "cli_profile"},
+		{"127.0.0.1", "sw-01", "cisco_ios", strconv.Itoa(port), "sw-01", "nova", "", "clock"},
+	})
+	code, body = upload("devices.xlsx", xlsx, map[string]string{"name": "core"})
+	require.Equal(t, http.StatusCreated, code, body)
+	var row model.BatchDefinition
+	require.NoError(t, database.GetDB().Where("name = ?", "core").First(&row).Error)
+	assert.True(t, row.Encrypted)
+	assert.NotContains(t, row.Devices, "nova")
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/batch-definitions/core", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.NotContains(t, w.Body.String(), "nova")
+
+	// 再次导入同名定义需 overwrite
+	code, _ = upload("devices.xlsx", xlsx, map[string]string{"name": "core"})
+	assert.Equal(t, http.StatusConflict, code)
+
+	// 按定义执行：行 cli_profile 展开为命令列表
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/batch-definitions/core/run?kind=collect", strings.NewReader(`{"task_id":"def-1"}`)))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), "10:00:00 UTC Fri Oct 16 2026")
+}
@@ -0,0 +1,113 @@
+package integration
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sshcollectorpro/sshcollectorpro/api/handler"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/config"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/database"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestBatchOutcome 批量接口统一结果：全部成功/部分失败/全部失败
+func TestBatchOutcome(t *testing.T) {
+	cases := []struct {
+		name      string
+		total     int
+		succeeded int
+		code      string
+		message   string
+		status    int
+	}{
+		{"全部成功", 3, 3, service.BatchCodeSuccess, "批量备份任务执行完成", http.StatusOK},
+		{"部分失败", 3, 1, service.BatchCodePartialSuccess, "批量备份任务部分成功", http.StatusMultiStatus},
+		{"全部失败", 3, 0, service.BatchCodeFailed, "批量备份任务全部失败", http.StatusOK},
+		{"无设备", 0, 0, service.BatchCodeSuccess, "批量备份任务执行完成", http.StatusOK},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			o := service.NewBatchOutcome("批量备份任务", tc.total, tc.succeeded)
+			assert.Equal(t, tc.code, o.Code)
+			assert.Equal(t, tc.message, o.Message)
+			assert.Equal(t, tc.status, service.BatchHTTPStatus(o.Code))
+		})
+	}
+}
+
+// TestBatchOutcomeCallback 回调返回码与接口返回码一致
+func TestBatchOutcomeCallback(t *testing.T) {
+	req := &service.BackupBatchRequest{TaskID: "t1"}
+	build := func(success ...bool) *service.BackupBatchResponse {
+		resp := &service.BackupBatchResponse{}
+		for _, ok := range success {
+			resp.Data = append(resp.Data, service.DeviceBackupResponse{DeviceIP: "10.0.0.1", Success: ok})
+		}
+		return resp
+	}
+
+	assert.Equal(t, service.BatchCodeSuccess, service.NewBackupCallbackPayload(req, build(true, true)).Code)
+	assert.Equal(t, service.BatchCodePartialSuccess, service.NewBackupCallbackPayload(req, build(true, false)).Code)
+
+	p := service.NewBackupCallbackPayload(req, build(false, false))
+	assert.Equal(t, service.BatchCodeFailed, p.Code)
+	assert.Equal(t, 2, p.FailedCount)
+
+	dreq := &service.DeployFastRequest{TaskID: "t2"}
+	dresp := &service.DeployFastResponse{Results: []service.DeployDeviceResult{
+		{DeviceIP: "10.0.0.1", DeployLogExec: []service.CommandResult{{Command: "vlan 10", ExitCode: -1, Error: "Error: Unrecognized command"}}},
+		{DeviceIP: "10.0.0.2", Error: "login failed"},
+	}}
+	dp := service.NewDeployCallbackPayload(dreq, dresp)
+	assert.Equal(t, service.BatchCodeFailed, dp.Code)
+	assert.Equal(t, "vlan 10: Error: Unrecognized command", dp.Devices[0].Error)
+}
+
+// TestBatchBackupAllFailedReturns200 设备全部失败时返回 200，由响应体 code=FAILED 与逐设备结果表达
+func TestBatchBackupAllFailedReturns200(t *testing.T) {
+	dir := t.TempDir()
+	cfgPath := filepath.Join(dir, "config.yaml")
+	require.NoError(t, os.WriteFile(cfgPath, []byte(`
+backup:
+  storage_backend: local
+  local:
+    base_dir: `+filepath.Join(dir, "backups")+`
+    mkdir_if_missing: true
+`), 0o600))
+	cfg, err := config.Load(cfgPath)
+	require.NoError(t, err)
+	require.NoError(t, database.InitSQLite(config.SQLiteConfig{Path: filepath.Join(dir, "collector.db")}))
+	t.Cleanup(func() { database.Close() })
+
+	backup := service.NewBackupService(cfg)
+	require.NoError(t, backup.Start(context.Background()))
+	t.Cleanup(func() { backup.Stop() })
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(handler.ErrorMiddleware())
+	r.POST("/backup/batch", handler.NewBackupHandler(backup).BatchBackup)
+
+	retry, timeout := 0, 2
+	b, _ := json.Marshal(service.BackupBatchRequest{TaskID: "bk-failed", RetryFlag: &retry, Devices: []service.BackupDevice{{
+		DeviceIP: "127.0.0.1", Port: freePort(t), DevicePlatform: "cisco_ios", UserName: "u", Password: "p",
+		CliList: service.NewCLIList("show running-config"), DeviceTimeout: &timeout,
+	}}})
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/backup/batch", bytes.NewReader(b)))
+	assert.Equal(t, http.StatusOK, w.Code)
+	var body map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, service.BatchCodeFailed, body["code"])
+	require.Len(t, body["data"], 1)
+	assert.Equal(t, false, body["data"].([]interface{})[0].(map[string]interface{})["success"])
+}
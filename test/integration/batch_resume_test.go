@@ -0,0 +1,373 @@
+package integration
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sshcollectorpro/sshcollectorpro/api/handler"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/config"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/database"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/model"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestBatchJobRecoverAndResume 启动时标记中断任务，续跑仅执行未完成设备
+func TestBatchJobRecoverAndResume(t *testing.T) {
+	dir := t.TempDir()
+	cfgPath := filepath.Join(dir, "config.yaml")
+	require.NoError(t, os.WriteFile(cfgPath, []byte("vault:\n  master_key: test-key\nbatch_resume:\n  enable: true\n"), 0o600))
+	cfg, err := config.Load(cfgPath)
+	require.NoError(t, err)
+	require.NoError(t, database.InitSQLite(config.SQLiteConfig{Path: filepath.Join(dir, "collector.db")}))
+	defer database.Close()
+	service.SetBatchJobStore(handler.NewBatchJobRepository())
+	defer service.SetBatchJobStore(nil)
+
+	cc, err := service.NewCredentialCipher(cfg)
+	require.NoError(t, err)
+	req, err := json.Marshal(service.BackupBatchRequest{TaskID: "bk-1", Devices: []service.BackupDevice{
+		{DeviceIP: "10.0.0.1", UserName: "u", Password: "p"},
+		{DeviceIP: "10.0.0.2", Port: 2222, UserName: "u", Password: "p"},
+	}})
+	require.NoError(t, err)
+	enc, err := cc.Encrypt(string(req))
+	require.NoError(t, err)
+
+	db := database.GetDB()
+	require.NoError(t, db.Create(&model.BatchJob{ID: "bk-1", Kind: model.BatchJobKindBackup, Status: model.BatchJobRunning,
+		Request: enc, Total: 2, Completed: 2, Done: `["10.0.0.1:22","10.0.0.2:2222"]`}).Error)
+	require.NoError(t, db.Create(&model.BatchJob{ID: "bk-2", Kind: model.BatchJobKindBackup, Status: model.BatchJobCompleted, Total: 1, Completed: 1}).Error)
+
+	h := handler.NewBatchJobHandler(handler.NewCollectorHandler(nil), nil)
+	h.RecoverBatchJobs(false)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/batch-jobs", h.ListBatchJobs)
+	r.POST("/batch-jobs/:id/resume", h.ResumeBatchJob)
+	do := func(method, url string) (int, map[string]interface{}) {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(method, url, nil))
+		var body map[string]interface{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+		return w.Code, body
+	}
+
+	code, body := do(http.MethodGet, "/batch-jobs?status=interrupted")
+	assert.Equal(t, http.StatusOK, code)
+	jobs := body["data"].(map[string]interface{})["jobs"].([]interface{})
+	require.Len(t, jobs, 1)
+	assert.Equal(t, "bk-1", jobs[0].(map[string]interface{})["id"])
+
+	code, _ = do(http.MethodPost, "/batch-jobs/bk-2/resume")
+	assert.Equal(t, http.StatusConflict, code)
+	code, _ = do(http.MethodPost, "/batch-jobs/missing/resume")
+	assert.Equal(t, http.StatusNotFound, code)
+
+	// 全部设备均已完成：无需执行，续跑后标记完成并清除请求
+	code, body = do(http.MethodPost, "/batch-jobs/bk-1/resume")
+	require.Equal(t, http.StatusAccepted, code)
+	assert.EqualValues(t, 0, body["data"].(map[string]interface{})["remaining"])
+	require.Eventually(t, func() bool {
+		var job model.BatchJob
+		return db.Where("id = ?", "bk-1").First(&job).Error == nil && job.Status == model.BatchJobCompleted && job.Request == "" && job.Resumes == 1
+	}, 2*time.Second, 20*time.Millisecond)
+}
+
+// TestBatchJobRetryFailed 失败重试仅执行仍失败的设备，并以 retry_of 关联原任务
+func TestBatchJobRetryFailed(t *testing.T) {
+	dir := t.TempDir()
+	cfgPath := filepath.Join(dir, "config.yaml")
+	require.NoError(t, os.WriteFile(cfgPath, []byte("vault:\n  master_key: test-key\nbatch_resume:\n  enable: true\n"), 0o600))
+	cfg, err := config.Load(cfgPath)
+	require.NoError(t, err)
+	require.NoError(t, database.InitSQLite(config.SQLiteConfig{Path: filepath.Join(dir, "collector.db")}))
+	defer database.Close()
+	service.SetBatchJobStore(handler.NewBatchJobRepository())
+	defer service.SetBatchJobStore(nil)
+
+	cc, err := service.NewCredentialCipher(cfg)
+	require.NoError(t, err)
+	req, err := json.Marshal(service.BackupBatchRequest{TaskID: "bk-r", Devices: []service.BackupDevice{
+		{DeviceIP: "10.0.0.1", UserName: "u", Password: "p"},
+		{DeviceIP: "10.0.0.2", Port: 2222, UserName: "u", Password: "p"},
+		{DeviceIP: "10.0.0.3", UserName: "u", Password: "p"},
+	}})
+	require.NoError(t, err)
+	enc, err := cc.Encrypt(string(req))
+	require.NoError(t, err)
+
+	db := database.GetDB()
+	require.NoError(t, db.Create(&model.BatchJob{ID: "bk-r", Kind: model.BatchJobKindBackup, Status: model.BatchJobCompleted, Request: enc, Total: 3, Completed: 3,
+		Done: `["10.0.0.1:22","10.0.0.2:2222","10.0.0.3:22"]`, Failed: 2, FailedList: `["10.0.0.2:2222","10.0.0.3:22"]`}).Error)
+	// 第一次重试已修复 10.0.0.2
+	require.NoError(t, db.Create(&model.BatchJob{ID: "bk-r-retry-1", Kind: model.BatchJobKindBackup, Status: model.BatchJobCompleted, RetryOf: "bk-r", Total: 1, Completed: 1,
+		Done: `["10.0.0.2:2222"]`, FailedList: `[]`}).Error)
+	require.NoError(t, db.Create(&model.BatchJob{ID: "bk-ok", Kind: model.BatchJobKindBackup, Status: model.BatchJobCompleted, Total: 1, Completed: 1, Done: `["10.0.0.1:22"]`}).Error)
+
+	h := handler.NewBatchJobHandler(handler.NewCollectorHandler(nil), service.NewBackupService(cfg))
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/tasks/:id/retry-failed", h.RetryFailed)
+	do := func(url string) (int, map[string]interface{}) {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, url, nil))
+		var body map[string]interface{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+		return w.Code, body
+	}
+
+	code, _ := do("/tasks/missing/retry-failed")
+	assert.Equal(t, http.StatusNotFound, code)
+	code, body := do("/tasks/bk-ok/retry-failed")
+	assert.Equal(t, http.StatusConflict, code)
+	assert.Equal(t, "NO_FAILED_DEVICES", body["code"])
+
+	// 以重试任务 ID 调用同样按原任务计算失败设备
+	code, body = do("/tasks/bk-r-retry-1/retry-failed")
+	require.Equal(t, http.StatusAccepted, code)
+	data := body["data"].(map[string]interface{})
+	assert.Equal(t, "bk-r-retry-2", data["id"])
+	assert.Equal(t, "bk-r", data["retry_of"])
+	assert.EqualValues(t, 1, data["devices"])
+
+	// 备份服务未启动：重试任务登记后因执行失败标记为 interrupted，可再续跑
+	require.Eventually(t, func() bool {
+		var job model.BatchJob
+		return db.Where("id = ?", "bk-r-retry-2").First(&job).Error == nil && job.RetryOf == "bk-r" && job.Total == 1 && job.Status == model.BatchJobInterrupted
+	}, 2*time.Second, 20*time.Millisecond)
+}
+
+// memBatchJobStore 内存批量任务仓库
+type memBatchJobStore struct {
+	mu    sync.Mutex
+	jobs  map[string]model.BatchJob
+	delay time.Duration // 读取重试记录后的延迟，放大并发窗口
+}
+
+func (m *memBatchJobStore) ReplaceBatchJob(job *model.BatchJob) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.jobs[job.ID] = *job
+	return nil
+}
+
+func (m *memBatchJobStore) BatchJob(id string) (*model.BatchJob, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	job, ok := m.jobs[id]
+	if !ok {
+		return nil, service.ErrBatchJobNotFound
+	}
+	return &job, nil
+}
+
+func (m *memBatchJobStore) ListBatchJobs(status string, offset, limit int) ([]model.BatchJob, int64, error) {
+	return nil, 0, nil
+}
+
+func (m *memBatchJobStore) BatchJobRetries(rootID string) ([]model.BatchJob, error) {
+	m.mu.Lock()
+	var out []model.BatchJob
+	for _, j := range m.jobs {
+		if j.RetryOf == rootID {
+			out = append(out, j)
+		}
+	}
+	m.mu.Unlock()
+	time.Sleep(m.delay)
+	return out, nil
+}
+
+func (m *memBatchJobStore) update(id string, fn func(j *model.BatchJob)) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	j := m.jobs[id]
+	fn(&j)
+	m.jobs[id] = j
+	return nil
+}
+
+func (m *memBatchJobStore) MarkBatchJobResumed(id string) error {
+	return m.update(id, func(j *model.BatchJob) { j.Status = model.BatchJobRunning; j.Resumes++ })
+}
+
+func (m *memBatchJobStore) UpdateBatchJobProgress(id string, p service.BatchJobProgress) error {
+	return m.update(id, func(j *model.BatchJob) {
+		j.Done, j.Completed, j.FailedList, j.Failed = p.Done, p.Completed, p.FailedList, p.Failed
+	})
+}
+
+func (m *memBatchJobStore) SetBatchJobStatus(id, status string, clearRequest bool) error {
+	return m.update(id, func(j *model.BatchJob) {
+		j.Status = status
+		if clearRequest {
+			j.Request = ""
+		}
+	})
+}
+
+func (m *memBatchJobStore) InterruptRunningBatchJobs(exclude []string) (int64, error) {
+	return 0, nil
+}
+
+func (m *memBatchJobStore) InterruptedBatchJobs() ([]model.BatchJob, error) {
+	return nil, nil
+}
+
+// fakeBatchJob 记录执行设备的批量请求
+type fakeBatchJob struct {
+	TaskID  string   `json:"task_id"`
+	Devices []string `json:"devices"`
+	ran     chan []string
+	release chan struct{} // 非 nil 时执行前等待放行
+}
+
+func (f *fakeBatchJob) RetainDevices(keep func(key string) bool) int {
+	var out []string
+	for _, d := range f.Devices {
+		if keep(service.BatchDeviceKey(d, 0)) {
+			out = append(out, d)
+		}
+	}
+	f.Devices = out
+	return len(out)
+}
+func (f *fakeBatchJob) SetDeadline(*time.Time)    {}
+func (f *fakeBatchJob) ForRetry(taskID, _ string) { f.TaskID = taskID }
+func (f *fakeBatchJob) TaskName() string          { return "fake" }
+func (f *fakeBatchJob) Payload() interface{}      { return f }
+func (f *fakeBatchJob) Run(journal *service.BatchJournal, _ string) {
+	if f.release != nil {
+		<-f.release
+	}
+	for _, d := range f.Devices {
+		journal.DeviceDone(d, 0, d != "10.0.0.3")
+	}
+	f.ran <- f.Devices
+}
+
+// TestBatchJobServiceStore 批量任务服务经注入的仓库读写记录：续跑仅执行未完成设备，失败重试登记为新记录
+func TestBatchJobServiceStore(t *testing.T) {
+	cfgPath := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(cfgPath, []byte("vault:\n  master_key: test-key\nbatch_resume:\n  enable: true\n"), 0o600))
+	cfg, err := config.Load(cfgPath)
+	require.NoError(t, err)
+	store := &memBatchJobStore{jobs: map[string]model.BatchJob{}}
+	service.SetBatchJobStore(store)
+	defer service.SetBatchJobStore(nil)
+
+	ran := make(chan []string, 1)
+	svc := service.NewBatchJobService(nil)
+	svc.RegisterLoader("fake", func(enc string) (service.BatchJobRequest, error) {
+		req := &fakeBatchJob{ran: ran}
+		return req, service.DecryptBatchRequest(enc, req)
+	})
+	enc, err := service.EncryptBatchRequest(cfg, &fakeBatchJob{TaskID: "fk-1", Devices: []string{"10.0.0.1", "10.0.0.2", "10.0.0.3"}})
+	require.NoError(t, err)
+	require.NoError(t, store.ReplaceBatchJob(&model.BatchJob{ID: "fk-1", Kind: "fake", Status: model.BatchJobInterrupted, Request: enc,
+		Total: 3, Completed: 1, Done: `["10.0.0.1:22"]`, FailedList: `[]`}))
+
+	_, _, err = svc.Resume("missing", nil)
+	assert.ErrorIs(t, err, service.ErrNotFound)
+
+	job, remaining, err := svc.Resume("fk-1", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "fk-1", job.ID)
+	assert.Equal(t, 2, remaining)
+	assert.Equal(t, []string{"10.0.0.2", "10.0.0.3"}, <-ran)
+	require.Eventually(t, func() bool {
+		j, _ := store.BatchJob("fk-1")
+		return j.Status == model.BatchJobCompleted
+	}, 2*time.Second, 10*time.Millisecond)
+	j, _ := store.BatchJob("fk-1")
+	assert.Equal(t, 1, j.Resumes)
+	assert.Equal(t, `["10.0.0.3:22"]`, j.FailedList)
+	assert.NotEmpty(t, j.Request, "有失败设备时保留请求供失败重试")
+
+	// 记录状态先于进程内运行标记更新，运行标记释放前返回 ErrBatchJobRunning
+	var retry *service.BatchRetry
+	require.Eventually(t, func() bool {
+		retry, err = svc.RetryFailed("fk-1", "", nil)
+		return !errors.Is(err, service.ErrBatchJobRunning)
+	}, 2*time.Second, 10*time.Millisecond)
+	require.NoError(t, err)
+	assert.Equal(t, &service.BatchRetry{ID: "fk-1-retry-1", RetryOf: "fk-1", Kind: "fake", Devices: 1}, retry)
+	assert.Equal(t, []string{"10.0.0.3"}, <-ran)
+	require.Eventually(t, func() bool {
+		j, err := store.BatchJob("fk-1-retry-1")
+		return err == nil && j.Status == model.BatchJobCompleted && j.RetryOf == "fk-1"
+	}, 2*time.Second, 10*time.Millisecond)
+
+	require.Eventually(t, func() bool {
+		_, err = svc.RetryFailed("fk-1", "fk-1-retry-1", nil)
+		return !errors.Is(err, service.ErrBatchJobRunning)
+	}, 2*time.Second, 10*time.Millisecond)
+	assert.ErrorIs(t, err, service.ErrRetryTaskIDExists)
+}
+
+// TestBatchJobRetryFailedConcurrent 并发失败重试只启动一个重试任务，失败设备不被重复执行
+func TestBatchJobRetryFailedConcurrent(t *testing.T) {
+	cfgPath := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(cfgPath, []byte("vault:\n  master_key: test-key\nbatch_resume:\n  enable: true\n"), 0o600))
+	cfg, err := config.Load(cfgPath)
+	require.NoError(t, err)
+	store := &memBatchJobStore{jobs: map[string]model.BatchJob{}, delay: 20 * time.Millisecond}
+	service.SetBatchJobStore(store)
+	defer service.SetBatchJobStore(nil)
+
+	ran := make(chan []string, 8)
+	release := make(chan struct{})
+	svc := service.NewBatchJobService(nil)
+	svc.RegisterLoader("fake", func(enc string) (service.BatchJobRequest, error) {
+		req := &fakeBatchJob{ran: ran, release: release}
+		return req, service.DecryptBatchRequest(enc, req)
+	})
+	enc, err := service.EncryptBatchRequest(cfg, &fakeBatchJob{TaskID: "fk-c", Devices: []string{"10.0.0.1", "10.0.0.3"}})
+	require.NoError(t, err)
+	require.NoError(t, store.ReplaceBatchJob(&model.BatchJob{ID: "fk-c", Kind: "fake", Status: model.BatchJobCompleted, Request: enc,
+		Total: 2, Completed: 2, Failed: 1, Done: `["10.0.0.1:22","10.0.0.3:22"]`, FailedList: `["10.0.0.3:22"]`}))
+
+	const callers = 8
+	var wg sync.WaitGroup
+	errs := make([]error, callers)
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, errs[i] = svc.RetryFailed("fk-c", fmt.Sprintf("fk-c-r%d", i), nil)
+		}(i)
+	}
+	wg.Wait()
+	started := 0
+	for _, err := range errs {
+		if err == nil {
+			started++
+			continue
+		}
+		assert.ErrorIs(t, err, service.ErrBatchJobRunning)
+	}
+	assert.Equal(t, 1, started)
+	retries, err := store.BatchJobRetries("fk-c")
+	require.NoError(t, err)
+	assert.Len(t, retries, 1)
+
+	close(release)
+	assert.Equal(t, []string{"10.0.0.3"}, <-ran)
+	select {
+	case devices := <-ran:
+		t.Fatalf("failed devices retried twice: %v", devices)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
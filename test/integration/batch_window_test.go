@@ -0,0 +1,126 @@
+package integration
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sshcollectorpro/sshcollectorpro/internal/config"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/service"
+	"github.com/sshcollectorpro/sshcollectorpro/simulate"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestAcquireDispatchSlot 窗口已关闭或等待中到点返回 ErrWindowClosed；ctx 结束返回 ctx.Err()
+func TestAcquireDispatchSlot(t *testing.T) {
+	slots := make(chan struct{}, 1)
+	past := time.Now().Add(-time.Second)
+	assert.ErrorIs(t, service.AcquireDispatchSlot(context.Background(), slots, &past), service.ErrWindowClosed)
+	assert.Empty(t, slots, "窗口关闭时不占用令牌")
+
+	// 不限制截止时间
+	require.NoError(t, service.AcquireDispatchSlot(context.Background(), slots, nil))
+
+	// 令牌已满：等待中到点
+	deadline := time.Now().Add(100 * time.Millisecond)
+	start := time.Now()
+	assert.ErrorIs(t, service.AcquireDispatchSlot(context.Background(), slots, &deadline), service.ErrWindowClosed)
+	assert.GreaterOrEqual(t, time.Since(start), 90*time.Millisecond)
+
+	// 令牌已满：ctx 先结束
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	later := time.Now().Add(time.Minute)
+	assert.ErrorIs(t, service.AcquireDispatchSlot(ctx, slots, &later), context.DeadlineExceeded)
+
+	// 窗口内归还令牌后可获取
+	<-slots
+	require.NoError(t, service.AcquireDispatchSlot(context.Background(), slots, &later))
+	assert.Len(t, slots, 1)
+}
+
+// TestBatchBackupDeadline 单并发下截止时间到点：已派发设备执行完毕，其余设备标记 NOT_ATTEMPTED_WINDOW_CLOSED
+func TestBatchBackupDeadline(t *testing.T) {
+	port := freePort(t)
+	mgr, err := simulate.Start(&simulate.Config{
+		Namespace:  map[string]simulate.NamespaceConfig{"t": {Port: port, IdleSeconds: 60, MaxConn: 10, LatencyMS: 150}},
+		DeviceType: map[string]simulate.DeviceTypeConfig{"cisco_ios": {PromptSuffix: "#"}},
+		DeviceName: map[string]simulate.DeviceNameConfig{
+			"sw-01": {DeviceType: "cisco_ios"},
+			"sw-02": {DeviceType: "cisco_ios"},
+		},
+		Scenarios: []simulate.ScenarioConfig{
+			{Command: "show running-config", Responses: []simulate.ScenarioResponse{{Output: "hostname sw"}}},
+		},
+	})
+	require.NoError(t, err)
+	t.Cleanup(mgr.Stop)
+
+	dir := t.TempDir()
+	cfgPath := filepath.Join(dir, "config.yaml")
+	require.NoError(t, os.WriteFile(cfgPath, []byte(`
+backup:
+  storage_backend: local
+  local:
+    base_dir: `+filepath.Join(dir, "backups")+`
+    mkdir_if_missing: true
+collector:
+  concurrency_profile: ""
+  concurrent: 1
+  device_defaults:
+    cisco_ios:
+      prompt_suffixes: ["#"]
+      command_interval_ms: 10
+`), 0o600))
+	cfg, err := config.Load(cfgPath)
+	require.NoError(t, err)
+	backup := service.NewBackupService(cfg)
+	require.NoError(t, backup.Start(context.Background()))
+	t.Cleanup(func() { backup.Stop() })
+
+	retry, timeout := 0, 10
+	devices := func() []service.BackupDevice {
+		var out []service.BackupDevice
+		for _, name := range []string{"sw-01", "sw-02"} {
+			out = append(out, service.BackupDevice{DeviceIP: "127.0.0.1", Port: port, DeviceName: name, DevicePlatform: "cisco_ios",
+				UserName: name, Password: "nova", CliList: service.NewCLIList("show running-config")})
+		}
+		return out
+	}
+
+	deadline := time.Now().Add(100 * time.Millisecond)
+	resp, err := backup.ExecuteBatch(context.Background(), &service.BackupBatchRequest{
+		TaskID: "bk-window", RetryFlag: &retry, TaskTimeout: &timeout, Deadline: &deadline, Devices: devices(),
+	})
+	require.NoError(t, err)
+	require.Len(t, resp.Data, 2)
+	var done, skipped []service.DeviceBackupResponse
+	for _, d := range resp.Data {
+		if d.Status == service.StatusNotAttemptedWindowClosed {
+			skipped = append(skipped, d)
+		} else {
+			done = append(done, d)
+		}
+	}
+	require.Len(t, done, 1)
+	require.Len(t, skipped, 1)
+	assert.True(t, done[0].Success, "已派发设备在截止时间后继续执行完毕: %s", done[0].Error)
+	assert.False(t, skipped[0].Success)
+	assert.Empty(t, skipped[0].Results)
+	assert.Equal(t, service.BatchCodePartialSuccess, resp.Code)
+
+	// 截止时间已过：全部未派发
+	past := time.Now().Add(-time.Minute)
+	resp, err = backup.ExecuteBatch(context.Background(), &service.BackupBatchRequest{
+		TaskID: "bk-window-closed", RetryFlag: &retry, TaskTimeout: &timeout, Deadline: &past, Devices: devices(),
+	})
+	require.NoError(t, err)
+	require.Len(t, resp.Data, 2)
+	for _, d := range resp.Data {
+		assert.Equal(t, service.StatusNotAttemptedWindowClosed, d.Status)
+		assert.False(t, d.Success)
+	}
+}
@@ -0,0 +1,128 @@
+package integration
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sshcollectorpro/sshcollectorpro/api/handler"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/config"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/database"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/model"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestBootstrapRunsOnceAndLocks 初始化校验存储、补全平台、登记测试设备并生成管理令牌；完成后接口锁定，令牌可用于管理接口
+func TestBootstrapRunsOnceAndLocks(t *testing.T) {
+	require.NoError(t, database.InitSQLite(config.SQLiteConfig{Path: filepath.Join(t.TempDir(), "collector.db")}))
+	defer database.Close()
+
+	base := t.TempDir()
+	cfgPath := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(cfgPath, []byte(`
+backup:
+  storage_backend: local
+  local:
+    base_dir: `+base+`
+collector:
+  device_defaults:
+    cisco_ios:
+      prompt_suffixes: ["#"]
+    huawei:
+      prompt_suffixes: [">"]
+`), 0o600))
+	_, err := config.Load(cfgPath)
+	require.NoError(t, err)
+	require.NoError(t, database.GetDB().Create(&model.SSHPlatform{Type: "huawei", Params: "{}"}).Error)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(handler.ErrorMiddleware())
+	bh := handler.NewBootstrapHandler(service.NewBootstrapService(nil))
+	r.GET("/bootstrap", bh.GetBootstrap)
+	r.POST("/bootstrap", bh.RunBootstrap)
+	r.GET("/debug/bundle", handler.NewDebugHandler(nil).Bundle)
+
+	do := func(method, url string, body interface{}, token string) (int, map[string]interface{}) {
+		var rd *bytes.Reader
+		if body != nil {
+			b, _ := json.Marshal(body)
+			rd = bytes.NewReader(b)
+		} else {
+			rd = bytes.NewReader(nil)
+		}
+		req := httptest.NewRequest(method, url, rd)
+		if token != "" {
+			req.Header.Set("X-Admin-Token", token)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		var out map[string]interface{}
+		_ = json.Unmarshal(w.Body.Bytes(), &out)
+		return w.Code, out
+	}
+
+	code, body := do(http.MethodGet, "/bootstrap", nil, "")
+	require.Equal(t, http.StatusOK, code)
+	assert.Equal(t, true, body["data"].(map[string]interface{})["available"])
+
+	// 管理令牌未配置时诊断接口关闭
+	code, _ = do(http.MethodGet, "/debug/bundle", nil, "")
+	assert.Equal(t, http.StatusForbidden, code)
+
+	code, _ = do(http.MethodPost, "/bootstrap", service.BootstrapRequest{AdminToken: "short"}, "")
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	code, body = do(http.MethodPost, "/bootstrap", service.BootstrapRequest{
+		TestDevice: &service.BootstrapTestDevice{DeviceIP: "127.0.0.1", Port: freePort(t), DevicePlatform: "cisco_ios", UserName: "admin"},
+	}, "")
+	require.Equal(t, http.StatusOK, code, body)
+	data := body["data"].(map[string]interface{})
+	token, _ := data["admin_token"].(string)
+	require.Len(t, token, 48)
+	assert.NotEmpty(t, data["test_device_id"])
+	steps := map[string]string{}
+	for _, s := range data["steps"].([]interface{}) {
+		m := s.(map[string]interface{})
+		steps[m["name"].(string)] = m["status"].(string)
+	}
+	assert.Equal(t, map[string]string{"storage": "ok", "platforms": "ok", "test_device": "ok", "admin_token": "ok"}, steps)
+
+	var platforms []model.SSHPlatform
+	require.NoError(t, database.GetDB().Order("ssh_type").Find(&platforms).Error)
+	require.Len(t, platforms, 2)
+	assert.Equal(t, "cisco_ios", platforms[0].Type)
+	assert.Contains(t, platforms[0].Params, `"prompt_suffixes":["#"]`)
+	assert.Equal(t, "{}", platforms[1].Params, "existing platforms are not overwritten")
+
+	var probe string
+	_ = filepath.WalkDir(base, func(path string, d fs.DirEntry, err error) error {
+		if err == nil && d.Name() == "probe.txt" {
+			probe = path
+		}
+		return nil
+	})
+	assert.NotEmpty(t, probe, "storage probe written under the local base dir")
+
+	// 锁定
+	code, body = do(http.MethodPost, "/bootstrap", service.BootstrapRequest{}, "")
+	assert.Equal(t, http.StatusConflict, code)
+	assert.Equal(t, "BOOTSTRAP_LOCKED", body["code"])
+	code, body = do(http.MethodGet, "/bootstrap", nil, "")
+	require.Equal(t, http.StatusOK, code)
+	assert.Equal(t, false, body["data"].(map[string]interface{})["available"])
+
+	// 生成的令牌用于管理接口
+	code, _ = do(http.MethodGet, "/debug/bundle", nil, "wrong-token-0000000000")
+	assert.Equal(t, http.StatusUnauthorized, code)
+	assert.True(t, service.BootstrapAdminTokenValid(token))
+	assert.False(t, service.BootstrapAdminTokenValid("wrong-token-0000000000"))
+}
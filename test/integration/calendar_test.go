@@ -0,0 +1,91 @@
+package integration
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sshcollectorpro/sshcollectorpro/api/handler"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/config"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/database"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestCalendarEvaluate 封网 > 节假日 > 调休补班日 > 工作日
+func TestCalendarEvaluate(t *testing.T) {
+	cal := &service.Calendar{
+		Name:          "cn-prod",
+		Timezone:      "Asia/Shanghai",
+		WorkingDays:   []string{"mon", "tue", "wed", "thu", "fri"},
+		Holidays:      []service.CalendarDay{{Date: "2026-10-01", Name: "国庆节"}},
+		ExtraWorkdays: []service.CalendarDay{{Date: "2026-10-10", Name: "国庆调休"}},
+		Blackouts:     []service.CalendarRange{{From: "2026-10-12", To: "2026-10-13", Reason: "双十一封网"}},
+	}
+	require.NoError(t, cal.Validate())
+
+	day := func(v string) service.CalendarDecision {
+		d, err := cal.ParseDate(v)
+		require.NoError(t, err)
+		return cal.Evaluate(d)
+	}
+	assert.Equal(t, service.CalendarReasonHoliday, day("2026-10-01").Reason)
+	assert.True(t, day("2026-10-10").Allowed) // 周六补班
+	assert.Equal(t, service.CalendarReasonNonWorkingDay, day("2026-10-11").Reason)
+	assert.Equal(t, service.CalendarReasonBlackout, day("2026-10-12").Reason)
+	assert.True(t, day("2026-10-14").Allowed)
+
+	from, _ := cal.ParseDate("2026-10-11")
+	assert.Equal(t, "2026-10-14", cal.NextAllowed(from))
+	assert.Len(t, cal.Upcoming(from, 5), 5)
+
+	assert.Error(t, (&service.Calendar{Name: "x", WorkingDays: []string{"funday"}}).Validate())
+	assert.Error(t, (&service.Calendar{Name: "x", Blackouts: []service.CalendarRange{{From: "2026-10-02", To: "2026-10-01"}}}).Validate())
+}
+
+// TestBackupSkippedByCalendar 批量备份引用日历，封网日直接跳过
+func TestBackupSkippedByCalendar(t *testing.T) {
+	require.NoError(t, database.InitSQLite(config.SQLiteConfig{Path: filepath.Join(t.TempDir(), "collector.db")}))
+	defer database.Close()
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	ch := handler.NewCalendarHandler()
+	r.POST("/calendars", ch.CreateCalendar)
+	r.GET("/calendars/:name/evaluate", ch.EvaluateCalendar)
+	r.POST("/backup/batch", handler.NewBackupHandler(nil).BatchBackup)
+
+	post := func(url string, v interface{}) (int, map[string]interface{}) {
+		b, _ := json.Marshal(v)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, url, bytes.NewReader(b)))
+		var body map[string]interface{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+		return w.Code, body
+	}
+
+	now := time.Now()
+	code, _ := post("/calendars", service.Calendar{Name: "freeze", Blackouts: []service.CalendarRange{{
+		From: now.AddDate(0, 0, -1).Format("2006-01-02"), To: now.AddDate(0, 0, 1).Format("2006-01-02"), Reason: "变更冻结",
+	}}})
+	require.Equal(t, http.StatusCreated, code)
+
+	code, body := post("/backup/batch", service.BackupBatchRequest{TaskID: "bk-1", Calendar: "freeze", Devices: []service.BackupDevice{{DeviceIP: "10.0.0.1"}}})
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "SKIPPED_BY_CALENDAR", body["code"])
+	assert.Equal(t, now.AddDate(0, 0, 2).Format("2006-01-02"), body["next_allowed"])
+
+	code, _ = post("/backup/batch", service.BackupBatchRequest{TaskID: "bk-1", Calendar: "missing", Devices: []service.BackupDevice{{DeviceIP: "10.0.0.1"}}})
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/calendars/freeze/evaluate?days=3", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"reason":"blackout"`)
+}
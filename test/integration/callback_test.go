@@ -0,0 +1,153 @@
+package integration
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sshcollectorpro/sshcollectorpro/api/handler"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/config"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/database"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/service"
+	"github.com/sshcollectorpro/sshcollectorpro/simulate"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// callbackRecord 回调接收端记录的一次请求
+type callbackRecord struct {
+	timestamp string
+	signature string
+	body      []byte
+}
+
+// TestBatchBackupCallback 批次完成后异步推送设备摘要：失败按配置重试，带 HMAC 签名；非法回调地址直接拒绝
+func TestBatchBackupCallback(t *testing.T) {
+	port := freePort(t)
+	mgr, err := simulate.Start(&simulate.Config{
+		Namespace:  map[string]simulate.NamespaceConfig{"t": {Port: port, IdleSeconds: 60, MaxConn: 10}},
+		DeviceType: map[string]simulate.DeviceTypeConfig{"cisco_ios": {PromptSuffix: "#"}},
+		DeviceName: map[string]simulate.DeviceNameConfig{"sw-01": {DeviceType: "cisco_ios"}},
+		Scenarios: []simulate.ScenarioConfig{
+			{DeviceName: "sw-01", Command: "show running-config", Responses: []simulate.ScenarioResponse{{Output: "hostname sw-01"}}},
+		},
+	})
+	require.NoError(t, err)
+	t.Cleanup(mgr.Stop)
+
+	// 回调接收端：第一次返回 500，之后返回 200
+	var mu sync.Mutex
+	var records []callbackRecord
+	cb := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		records = append(records, callbackRecord{
+			timestamp: r.Header.Get(service.CallbackHeaderTimestamp),
+			signature: r.Header.Get(service.CallbackHeaderSignature),
+			body:      body,
+		})
+		n := len(records)
+		mu.Unlock()
+		if n == 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	t.Cleanup(cb.Close)
+
+	dir := t.TempDir()
+	cfgPath := filepath.Join(dir, "config.yaml")
+	require.NoError(t, os.WriteFile(cfgPath, []byte(`
+backup:
+  storage_backend: local
+  local:
+    base_dir: `+filepath.Join(dir, "backups")+`
+    mkdir_if_missing: true
+callback:
+  secret: s3cret
+  retries: 2
+  timeout: 2s
+  retry_delay: 50ms
+collector:
+  device_defaults:
+    cisco_ios:
+      prompt_suffixes: ["#"]
+      command_interval_ms: 10
+`), 0o600))
+	cfg, err := config.Load(cfgPath)
+	require.NoError(t, err)
+	require.NoError(t, database.InitSQLite(config.SQLiteConfig{Path: filepath.Join(dir, "collector.db")}))
+	t.Cleanup(func() { database.Close() })
+
+	backup := service.NewBackupService(cfg)
+	require.NoError(t, backup.Start(context.Background()))
+	t.Cleanup(func() { backup.Stop() })
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(handler.ErrorMiddleware())
+	r.POST("/backup/batch", handler.NewBackupHandler(backup).BatchBackup)
+	post := func(req service.BackupBatchRequest) *httptest.ResponseRecorder {
+		b, _ := json.Marshal(req)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/backup/batch", bytes.NewReader(b)))
+		return w
+	}
+
+	retry, timeout := 0, 2
+	devices := []service.BackupDevice{
+		{DeviceIP: "127.0.0.1", Port: port, DeviceName: "sw-01", DevicePlatform: "cisco_ios", UserName: "sw-01", Password: "nova",
+			CliList: service.NewCLIList("show running-config"), DeviceTimeout: &timeout},
+		{DeviceIP: "127.0.0.1", Port: freePort(t), DeviceName: "sw-down", DevicePlatform: "cisco_ios", UserName: "u", Password: "p",
+			CliList: service.NewCLIList("show running-config"), DeviceTimeout: &timeout},
+	}
+
+	// 非法回调地址：请求直接拒绝，不执行备份
+	w := post(service.BackupBatchRequest{TaskID: "bk-cb-bad", RetryFlag: &retry, CallbackURL: "ftp://example.com/hook", Devices: devices})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = post(service.BackupBatchRequest{TaskID: "bk-cb", TaskName: "nightly", RetryFlag: &retry, CallbackURL: cb.URL, Devices: devices})
+	require.Equal(t, http.StatusMultiStatus, w.Code, w.Body.String())
+
+	require.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(records) >= 2
+	}, 5*time.Second, 20*time.Millisecond, "回调应在失败后重试")
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.Len(t, records, 2, "成功后不再重试")
+	last := records[1]
+	assert.Equal(t, records[0].body, last.body, "重试推送相同内容")
+	assert.Equal(t, service.SignCallback("s3cret", last.timestamp, last.body), last.signature)
+
+	var payload service.CallbackPayload
+	require.NoError(t, json.Unmarshal(last.body, &payload))
+	assert.Equal(t, "bk-cb", payload.TaskID)
+	assert.Equal(t, "nightly", payload.TaskName)
+	assert.Equal(t, "backup", payload.TaskType)
+	assert.Equal(t, service.BatchCodePartialSuccess, payload.Code)
+	assert.Equal(t, 2, payload.Total)
+	assert.Equal(t, 1, payload.SuccessCount)
+	assert.Equal(t, 1, payload.FailedCount)
+	require.Len(t, payload.Devices, 2)
+	byName := map[string]service.CallbackDeviceSummary{}
+	for _, d := range payload.Devices {
+		byName[d.DeviceName] = d
+	}
+	assert.True(t, byName["sw-01"].Success)
+	assert.False(t, byName["sw-down"].Success)
+	assert.NotEmpty(t, byName["sw-down"].Error)
+	assert.NotContains(t, string(last.body), "nova", "回调不包含凭据")
+}
@@ -0,0 +1,116 @@
+package integration
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sshcollectorpro/sshcollectorpro/internal/config"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/service"
+	"github.com/sshcollectorpro/sshcollectorpro/simulate"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestPlatformCapabilities 能力标记解析（显式配置/引擎默认），单会话平台不复用连接，LF 行结束符可正常交互
+func TestPlatformCapabilities(t *testing.T) {
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(t.TempDir()))
+	defer os.Chdir(wd)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	port := ln.Addr().(*net.TCPAddr).Port
+	ln.Close()
+	mgr, err := simulate.Start(&simulate.Config{
+		Namespace:  map[string]simulate.NamespaceConfig{"t": {Port: port, IdleSeconds: 60, MaxConn: 10}},
+		DeviceType: map[string]simulate.DeviceTypeConfig{"cisco_ios": {PromptSuffix: "#"}},
+		DeviceName: map[string]simulate.DeviceNameConfig{"sw-01": {DeviceType: "cisco_ios"}},
+	})
+	require.NoError(t, err)
+	defer mgr.Stop()
+
+	cfgPath := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(cfgPath, []byte(`
+collector:
+  device_defaults:
+    cisco_ios:
+      prompt_suffixes: ["#"]
+      command_interval_ms: 10
+      capabilities:
+        supports_multisession: false
+        supports_commit: true
+        needs_crlf: false
+        wide_terminal: true
+`), 0o600))
+	cfg, err := config.Load(cfgPath)
+	require.NoError(t, err)
+
+	caps := service.CapabilitiesFor(cfg, "Cisco_IOS")
+	assert.True(t, caps.Configured)
+	assert.Equal(t, service.CapabilityFlag{Value: false, Source: service.CapabilitySourceConfigured}, caps.SupportsMultiSession)
+	assert.Equal(t, service.CapabilityFlag{Value: true, Source: service.CapabilitySourceConfigured}, caps.SupportsCommit)
+	assert.Equal(t, service.CapabilityFlag{Value: true, Source: service.CapabilitySourceDefault}, caps.SupportsExec)
+	assert.Equal(t, 830, caps.NetconfPort)
+	unknown := service.CapabilitiesFor(cfg, "unknown")
+	assert.False(t, unknown.Configured)
+	assert.True(t, unknown.NeedsCRLF.Value)
+	assert.False(t, unknown.SupportsCommit.Value)
+	require.Len(t, service.ListCapabilities(cfg), 1)
+
+	collector := service.NewCollectorService(cfg)
+	require.NoError(t, collector.Start(context.Background()))
+	defer collector.Stop()
+
+	collect := func(id string) *service.CollectResponse {
+		resp, err := collector.ExecuteTask(context.Background(), &service.CollectRequest{
+			TaskID: id, DeviceIP: "127.0.0.1", Port: port, DeviceName: "sw-01", DevicePlatform: "cisco_ios",
+			UserName: "sw-01", Password: "nova", CliList: service.NewCLIList("show version"),
+		})
+		require.NoError(t, err)
+		require.True(t, resp.Success, resp.Error)
+		require.NotNil(t, resp.Timings)
+		return resp
+	}
+	assert.False(t, collect("caps-1").Timings.ConnectionReused)
+	assert.False(t, collect("caps-2").Timings.ConnectionReused)
+}
+
+// TestPlatformLineEnding line_ending 优先于 capabilities.needs_crlf，无效取值加载失败
+func TestPlatformLineEnding(t *testing.T) {
+	dir := t.TempDir()
+	load := func(body string) (*config.Config, error) {
+		p := filepath.Join(dir, "config.yaml")
+		require.NoError(t, os.WriteFile(p, []byte(body), 0o600))
+		return config.Load(p)
+	}
+
+	cfg, err := load(`
+collector:
+  device_defaults:
+    linux:
+      line_ending: LF
+      capabilities:
+        needs_crlf: true
+    huawei:
+      capabilities:
+        needs_crlf: false
+`)
+	require.NoError(t, err)
+	linux := service.CapabilitiesFor(cfg, "linux")
+	assert.Equal(t, config.LineEndingLF, linux.LineEnding)
+	assert.False(t, linux.NeedsCRLF.Value)
+	assert.Equal(t, config.LineEndingLF, service.CapabilitiesFor(cfg, "huawei").LineEnding)
+	assert.Equal(t, config.LineEndingCRLF, service.CapabilitiesFor(cfg, "cisco_ios").LineEnding)
+
+	_, err = load(`
+collector:
+  device_defaults:
+    linux:
+      line_ending: cr
+`)
+	assert.ErrorContains(t, err, "line_ending")
+}
@@ -0,0 +1,167 @@
+package integration
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sshcollectorpro/sshcollectorpro/api/handler"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/config"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/service"
+	"github.com/sshcollectorpro/sshcollectorpro/simulate"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestDeviceCircuitBreaker 同一设备连续建连失败后熔断：不再拨号、返回 DEVICE_CIRCUIT_OPEN；
+// 冷却结束后放行试探连接，成功即恢复；熔断状态可通过接口查看与重置
+func TestDeviceCircuitBreaker(t *testing.T) {
+	port := freePort(t)
+	mgr, err := simulate.Start(&simulate.Config{
+		Namespace:  map[string]simulate.NamespaceConfig{"t": {Port: port, IdleSeconds: 60, MaxConn: 10}},
+		DeviceType: map[string]simulate.DeviceTypeConfig{"cisco_ios": {PromptSuffix: "#"}},
+		DeviceName: map[string]simulate.DeviceNameConfig{"sw-01": {DeviceType: "cisco_ios"}},
+		Scenarios: []simulate.ScenarioConfig{
+			{DeviceName: "sw-01", Command: "show clock", Responses: []simulate.ScenarioResponse{{Output: "10:00:00 UTC"}}},
+		},
+	})
+	require.NoError(t, err)
+	t.Cleanup(mgr.Stop)
+	t.Cleanup(func() { service.ResetCircuitBreaker("") })
+
+	cfgPath := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(cfgPath, []byte(`
+ssh:
+  circuit_breaker:
+    enable: true
+    failure_threshold: 2
+    cooldown: 500ms
+collector:
+  device_defaults:
+    cisco_ios:
+      prompt_suffixes: ["#"]
+      command_interval_ms: 10
+`), 0o600))
+	cfg, err := config.Load(cfgPath)
+	require.NoError(t, err)
+	svc := service.NewCollectorService(cfg)
+	require.NoError(t, svc.Start(context.Background()))
+	t.Cleanup(func() { svc.Stop() })
+
+	// 使用独立的回环地址，避免影响其他用例的 127.0.0.1
+	const deviceIP = "127.0.0.2"
+	noRetry := 0
+	collect := func(id string, devPort int) *service.CollectResponse {
+		resp, err := svc.ExecuteTask(context.Background(), &service.CollectRequest{
+			TaskID: id, DeviceIP: deviceIP, Port: devPort, DevicePlatform: "cisco_ios", RetryFlag: &noRetry,
+			UserName: "sw-01", Password: "nova", CliList: service.NewCLIList("show clock"),
+		})
+		require.NoError(t, err)
+		return resp
+	}
+
+	closed := freePort(t)
+	for i := 0; i < 2; i++ {
+		resp := collect(fmt.Sprintf("cb-dead-%d", i), closed)
+		assert.False(t, resp.Success)
+		assert.NotContains(t, resp.Error, "DEVICE_CIRCUIT_OPEN")
+	}
+	// 熔断期间即使端口可用也不拨号
+	resp := collect("cb-open", port)
+	assert.False(t, resp.Success)
+	assert.True(t, strings.HasPrefix(resp.Error, "DEVICE_CIRCUIT_OPEN"), resp.Error)
+	status, code := handler.ErrorStatus(fmt.Errorf("collect: %w", service.ErrDeviceCircuitOpen), "")
+	assert.Equal(t, http.StatusServiceUnavailable, status)
+	assert.Equal(t, "DEVICE_CIRCUIT_OPEN", code)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/circuit-breakers", handler.ListCircuitBreakers)
+	r.DELETE("/circuit-breakers", handler.ResetCircuitBreakers)
+	r.DELETE("/circuit-breakers/:ip", handler.ResetCircuitBreaker)
+	do := func(method, path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(method, path, nil))
+		return w
+	}
+
+	w := do(http.MethodGet, "/circuit-breakers")
+	require.Equal(t, http.StatusOK, w.Code)
+	var list struct {
+		Data service.CircuitBreakerStats `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &list))
+	assert.True(t, list.Data.Enable)
+	assert.Equal(t, 1, list.Data.Open)
+	require.Len(t, list.Data.Devices, 1)
+	assert.Equal(t, deviceIP, list.Data.Devices[0].DeviceIP)
+	assert.Equal(t, service.CircuitOpen, list.Data.Devices[0].State)
+	assert.Equal(t, 2, list.Data.Devices[0].Failures)
+
+	// 冷却结束后试探连接成功即恢复
+	time.Sleep(600 * time.Millisecond)
+	assert.Equal(t, service.CircuitHalfOpen, service.CircuitBreakerSnapshot().Devices[0].State)
+	resp = collect("cb-probe", port)
+	require.True(t, resp.Success, resp.Error)
+	assert.Empty(t, service.CircuitBreakerSnapshot().Devices)
+
+	// 重新熔断后通过接口重置
+	for i := 0; i < 2; i++ {
+		collect(fmt.Sprintf("cb-dead-again-%d", i), closed)
+	}
+	assert.Equal(t, 1, service.CircuitBreakerSnapshot().Open)
+	assert.Equal(t, http.StatusNotFound, do(http.MethodDelete, "/circuit-breakers/10.9.9.9").Code)
+	assert.Equal(t, http.StatusOK, do(http.MethodDelete, "/circuit-breakers/"+deviceIP).Code)
+	resp = collect("cb-after-reset", port)
+	require.True(t, resp.Success, resp.Error)
+	assert.Equal(t, http.StatusOK, do(http.MethodDelete, "/circuit-breakers").Code)
+
+	report, err := config.ValidateYAML([]byte("ssh:\n  circuit_breaker:\n    enable: true\n    failure_threshold: 0\n"))
+	require.NoError(t, err)
+	require.NotEmpty(t, report.Errors())
+	assert.Equal(t, "ssh.circuit_breaker.failure_threshold", report.Errors()[0].Path)
+}
+
+// TestCircuitBreakerContextErrors 调用方取消不计入熔断；上下文超时按建连超时计数，不归为设备不可达
+func TestCircuitBreakerContextErrors(t *testing.T) {
+	cfgPath := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(cfgPath, []byte(`
+ssh:
+  circuit_breaker:
+    enable: true
+    failure_threshold: 2
+    cooldown: 1m
+`), 0o600))
+	cfg, err := config.Load(cfgPath)
+	require.NoError(t, err)
+	t.Cleanup(func() { service.ResetCircuitBreaker("") })
+	verify := service.NewSSHLoginVerifier(cfg)
+	closed := freePort(t)
+
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	for i := 0; i < 3; i++ {
+		err := verify(canceled, "127.0.0.3", closed, "u", "p")
+		require.Error(t, err)
+		assert.ErrorIs(t, err, context.Canceled)
+	}
+	assert.Empty(t, service.CircuitBreakerSnapshot().Devices, "取消不计入")
+
+	expired, cancelExpired := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancelExpired()
+	for i := 0; i < 2; i++ {
+		require.Error(t, verify(expired, "127.0.0.4", closed, "u", "p"))
+	}
+	snap := service.CircuitBreakerSnapshot()
+	require.Len(t, snap.Devices, 1)
+	assert.Equal(t, "127.0.0.4", snap.Devices[0].DeviceIP)
+	assert.Equal(t, service.CircuitOpen, snap.Devices[0].State)
+}
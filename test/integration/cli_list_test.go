@@ -0,0 +1,22 @@
+package integration
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/sshcollectorpro/sshcollectorpro/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestCLIListIncludeRawBytes include_raw_bytes 仅对选中命令生效，序列化保持原格式
+func TestCLIListIncludeRawBytes(t *testing.T) {
+	var l service.CLIList
+	require.NoError(t, json.Unmarshal([]byte(`["show version", {"cli": "Show Run", "include_raw_bytes": true}]`), &l))
+	assert.Equal(t, map[string]bool{"show run": true}, l.RawCommands())
+	assert.Nil(t, service.NewCLIList("show version").RawCommands())
+
+	b, err := json.Marshal(l)
+	require.NoError(t, err)
+	assert.JSONEq(t, `["show version", {"cli": "Show Run", "include_raw_bytes": true}]`, string(b))
+}
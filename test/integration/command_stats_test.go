@@ -0,0 +1,100 @@
+package integration
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sshcollectorpro/sshcollectorpro/api/handler"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/config"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/database"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/service"
+	"github.com/sshcollectorpro/sshcollectorpro/simulate"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestCommandStats 采集命令按平台记入耗时与输出大小分布，输出为 Prometheus 直方图并按天汇总入库
+func TestCommandStats(t *testing.T) {
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(t.TempDir()))
+	defer os.Chdir(wd)
+	require.NoError(t, database.InitSQLite(config.SQLiteConfig{Path: filepath.Join(t.TempDir(), "collector.db")}))
+	defer database.Close()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	port := ln.Addr().(*net.TCPAddr).Port
+	ln.Close()
+	mgr, err := simulate.Start(&simulate.Config{
+		Namespace:  map[string]simulate.NamespaceConfig{"t": {Port: port, IdleSeconds: 60, MaxConn: 10, LatencyMS: 20}},
+		DeviceType: map[string]simulate.DeviceTypeConfig{"cisco_ios": {PromptSuffix: "#"}},
+		DeviceName: map[string]simulate.DeviceNameConfig{"sw-01": {DeviceType: "cisco_ios"}},
+	})
+	require.NoError(t, err)
+	defer mgr.Stop()
+
+	cfgPath := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(cfgPath, []byte(`
+collector:
+  device_defaults:
+    stats_ios:
+      prompt_suffixes: ["#"]
+      command_interval_ms: 10
+metrics:
+  persist_daily: true
+  flush_interval: 1h
+`), 0o600))
+	cfg, err := config.Load(cfgPath)
+	require.NoError(t, err)
+	service.InitCommandStats(cfg)
+	defer service.CloseCommandStats()
+
+	collector := service.NewCollectorService(cfg)
+	require.NoError(t, collector.Start(context.Background()))
+	defer collector.Stop()
+	resp, err := collector.ExecuteTask(context.Background(), &service.CollectRequest{
+		TaskID: "stats-1", DeviceIP: "127.0.0.1", Port: port, DeviceName: "sw-01", DevicePlatform: "stats_ios",
+		UserName: "sw-01", Password: "nova", CliList: service.NewCLIList("show version", "show clock"),
+	})
+	require.NoError(t, err)
+	require.True(t, resp.Success, resp.Error)
+
+	var ps *service.PlatformCommandStats
+	for _, s := range service.CommandStatsSnapshot() {
+		if s.Platform == "stats_ios" {
+			s := s
+			ps = &s
+		}
+	}
+	require.NotNil(t, ps)
+	assert.EqualValues(t, 2, ps.Commands)
+	assert.GreaterOrEqual(t, ps.DurationSeconds.Max, 0.02)
+	assert.Positive(t, ps.OutputBytes.Sum)
+	last := ps.DurationSeconds.Buckets[len(ps.DurationSeconds.Buckets)-1]
+	assert.Equal(t, "+Inf", last.Le)
+	assert.EqualValues(t, 2, last.Count)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/metrics", handler.Metrics)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "# TYPE sshcollector_command_duration_seconds histogram")
+	assert.Contains(t, w.Body.String(), `sshcollector_command_output_bytes_count{platform="stats_ios"} 2`)
+
+	// 停止时写入剩余增量
+	service.CloseCommandStats()
+	rows, err := service.ListDailyCommandStats(context.Background(), 1, "stats_ios")
+	require.NoError(t, err)
+	require.Len(t, rows, 1)
+	assert.EqualValues(t, 2, rows[0].Commands)
+	assert.Positive(t, rows[0].OutputBytesSum)
+}
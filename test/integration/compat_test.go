@@ -0,0 +1,74 @@
+package integration
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sshcollectorpro/sshcollectorpro/api/handler"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestLegacyFieldNames 旧字段名映射到现名并产生弃用提示，新旧同时出现时以新字段为准
+func TestLegacyFieldNames(t *testing.T) {
+	var req service.BackupBatchRequest
+	body := `{"task_id":"t1","timeout":30,"devices":[{"ip":"10.0.0.1","port":2222,"username":"admin","cli_list":["show version"]},{"device_ip":"10.0.0.2","ip":"10.0.0.9"}]}`
+	require.NoError(t, json.Unmarshal([]byte(body), &req))
+	require.NotNil(t, req.TaskTimeout)
+	assert.Equal(t, 30, *req.TaskTimeout)
+	require.Len(t, req.Devices, 2)
+	assert.Equal(t, "10.0.0.1", req.Devices[0].DeviceIP)
+	assert.Equal(t, 2222, req.Devices[0].Port)
+	assert.Equal(t, "admin", req.Devices[0].UserName)
+	assert.Equal(t, "10.0.0.2", req.Devices[1].DeviceIP)
+	assert.Len(t, req.DeprecationWarnings(), 4)
+	assert.Equal(t, service.WarnDeprecatedField, req.DeprecationWarnings()[0].Code)
+	assert.Equal(t, "devices[].ip", req.DeprecationWarnings()[0].Context["field"])
+
+	// 单设备请求：设备字段位于顶层，timeout 为任务超时
+	var cr service.CollectRequest
+	require.NoError(t, json.Unmarshal([]byte(`{"task_id":"t2","ip":"10.0.0.3","platform":"cisco_ios","timeout":10}`), &cr))
+	assert.Equal(t, "10.0.0.3", cr.DeviceIP)
+	assert.Equal(t, "cisco_ios", cr.DevicePlatform)
+	require.NotNil(t, cr.TaskTimeout)
+	assert.Nil(t, cr.DeviceTimeout)
+
+	// 未使用旧字段时不产生提示
+	var clean service.BackupBatchRequest
+	require.NoError(t, json.Unmarshal([]byte(`{"task_id":"t3","devices":[{"device_ip":"10.0.0.4"}]}`), &clean))
+	assert.Empty(t, clean.DeprecationWarnings())
+
+	assert.GreaterOrEqual(t, service.DeprecationUsage()["backup_batch.devices[].port"], int64(1))
+}
+
+// TestDeprecationWarningsResponse 使用旧字段的请求在 JSON 响应中附加 warnings
+func TestDeprecationWarningsResponse(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(handler.WarningsMiddleware())
+	r.Use(handler.ErrorMiddleware())
+	r.POST("/backup", handler.NewBackupHandler(nil).BatchBackup)
+
+	// 缺少 task_id：参数校验失败，但响应仍携带弃用提示
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/backup", strings.NewReader(`{"devices":[{"ip":"10.0.0.1"}]}`)))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	var resp struct {
+		Code     string            `json:"code"`
+		Warnings []service.Warning `json:"warnings"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, "INVALID_PARAMS", resp.Code)
+	require.Len(t, resp.Warnings, 1)
+	assert.Equal(t, service.WarnDeprecatedField, resp.Warnings[0].Code)
+	assert.Contains(t, resp.Warnings[0].Message, `use "devices[].device_ip"`)
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/backup", strings.NewReader(`{"devices":[{"device_ip":"10.0.0.1"}]}`)))
+	assert.NotContains(t, w.Body.String(), "warnings")
+}
@@ -0,0 +1,53 @@
+package integration
+
+import (
+	"testing"
+
+	"github.com/sshcollectorpro/sshcollectorpro/internal/service"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestEvaluateComplianceRule 三类规则的通过判定与证据行
+func TestEvaluateComplianceRule(t *testing.T) {
+	output := "hostname R1\r\nip ssh version 2\r\nline vty 0 4\r\n transport input telnet ssh\r\n"
+	cases := []struct {
+		name     string
+		rule     service.ComplianceRule
+		passed   bool
+		evidence []string
+	}{
+		{"正则命中", service.ComplianceRule{RuleType: service.RuleTypeRegex, Pattern: `^ip ssh version 2$`}, true, []string{"ip ssh version 2"}},
+		{"正则未命中", service.ComplianceRule{RuleType: service.RuleTypeRegex, Pattern: `^service password-encryption$`}, false, []string{}},
+		{"必须包含", service.ComplianceRule{RuleType: service.RuleTypeMustContain, Pattern: "hostname"}, true, []string{"hostname R1"}},
+		{"不得包含", service.ComplianceRule{RuleType: service.RuleTypeMustNotContain, Pattern: "telnet"}, false, []string{" transport input telnet ssh"}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			res := service.EvaluateRule(tc.rule, output)
+			assert.Equal(t, tc.passed, res.Passed)
+			assert.Equal(t, tc.evidence, res.Evidence)
+		})
+	}
+	assert.Error(t, service.ValidateComplianceRule(&service.ComplianceRule{Name: "x", Command: "c", RuleType: service.RuleTypeRegex, Pattern: "("}))
+}
+
+// TestCompileComplianceRules 预编译后评估沿用已编译正则；编译失败的规则评估时报告 invalid pattern
+func TestCompileComplianceRules(t *testing.T) {
+	rules := service.CompileRules([]service.ComplianceRule{
+		{RuleType: service.RuleTypeRegex, Pattern: `^ip ssh version 2$`},
+		{RuleType: service.RuleTypeRegex, Pattern: "("},
+		{RuleType: service.RuleTypeMustContain, Pattern: "hostname"},
+	})
+	// 修改模式不影响已编译的正则
+	rules[0].Pattern = "("
+	for _, output := range []string{"ip ssh version 2", "hostname R1\nip ssh version 2"} {
+		res := service.EvaluateRule(rules[0], output)
+		assert.Empty(t, res.Error)
+		assert.True(t, res.Passed)
+		assert.Equal(t, []string{"ip ssh version 2"}, res.Evidence)
+	}
+	bad := service.EvaluateRule(rules[1], "x")
+	assert.False(t, bad.Passed)
+	assert.Contains(t, bad.Error, "invalid pattern")
+	assert.True(t, service.EvaluateRule(rules[2], "hostname R1").Passed)
+}
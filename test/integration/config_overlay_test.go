@@ -0,0 +1,89 @@
+package integration
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sshcollectorpro/sshcollectorpro/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestConfigOverlays 基础配置依次叠加 config.<env>.yaml 与 config.local.yaml：映射按键深度合并，列表整体替换
+func TestConfigOverlays(t *testing.T) {
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(t.TempDir()))
+	defer os.Chdir(wd)
+	require.NoError(t, os.MkdirAll("configs", 0o755))
+
+	write := func(name, content string) {
+		require.NoError(t, os.WriteFile(filepath.Join("configs", name), []byte(content), 0o600))
+	}
+	write("config.yaml", `
+server:
+  port: 18000
+log:
+  level: info
+collector:
+  device_defaults:
+    huawei:
+      prompt_suffixes: [">", "]"]
+      enable_required: true
+      command_interval_ms: 100
+`)
+	write("config.prod.yaml", `
+log:
+  level: warn
+collector:
+  device_defaults:
+    huawei:
+      command_interval_ms: 300
+    h3c:
+      prompt_suffixes: [">"]
+`)
+	write("config.local.yaml", `
+server:
+  port: 18001
+collector:
+  device_defaults:
+    huawei:
+      prompt_suffixes: [">"]
+`)
+	t.Setenv(config.EnvName, "prod")
+
+	path := filepath.Join("configs", "config.yaml")
+	assert.Equal(t, []string{filepath.Join("configs", "config.prod.yaml"), filepath.Join("configs", "config.local.yaml")}, config.OverlayFiles(path))
+	cfg, err := config.Load(path)
+	require.NoError(t, err)
+
+	assert.Equal(t, 18001, cfg.Server.Port)
+	assert.Equal(t, "warn", cfg.Log.Level)
+	huawei := cfg.Collector.DeviceDefaults["huawei"]
+	assert.Equal(t, []string{">"}, huawei.PromptSuffixes)
+	assert.True(t, huawei.EnableRequired)
+	assert.Equal(t, 300, huawei.CommandIntervalMS)
+	assert.Equal(t, []string{">"}, cfg.Collector.DeviceDefaults["h3c"].PromptSuffixes)
+
+	// 未设置环境时仅叠加 config.local.yaml
+	t.Setenv(config.EnvName, "")
+	cfg, err = config.Load(path)
+	require.NoError(t, err)
+	assert.Equal(t, "info", cfg.Log.Level)
+	assert.Equal(t, 100, cfg.Collector.DeviceDefaults["huawei"].CommandIntervalMS)
+
+	// auto-ssh.yaml 整体替换主配置中的 device_defaults，叠加文件再合并到其上
+	write("auto-ssh.yaml", `
+device_defaults:
+  huawei:
+    prompt_suffixes: ["#"]
+    command_interval_ms: 50
+`)
+	cfg, err = config.Load(path)
+	require.NoError(t, err)
+	huawei = cfg.Collector.DeviceDefaults["huawei"]
+	assert.Equal(t, []string{">"}, huawei.PromptSuffixes)
+	assert.Equal(t, 50, huawei.CommandIntervalMS)
+	assert.False(t, huawei.EnableRequired)
+}
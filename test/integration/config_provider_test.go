@@ -0,0 +1,69 @@
+package integration
+
+import (
+	"testing"
+
+	"github.com/sshcollectorpro/sshcollectorpro/internal/config"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestConfigProviderUpdate 写时复制替换快照：旧快照保持不变，变更记录按配置键名输出字段路径
+func TestConfigProviderUpdate(t *testing.T) {
+	old := &config.Config{}
+	old.Log.Level = "info"
+	old.Collector.DeviceDefaults = map[string]config.PlatformDefaultsConfig{"huawei": {EnableRequired: false}}
+	p := config.NewProvider(old)
+	require.Same(t, old, p.Get())
+	assert.Empty(t, p.Changelog())
+
+	next := p.Update("runtime", func(c *config.Config) {
+		m := map[string]config.PlatformDefaultsConfig{}
+		for k, v := range c.Collector.DeviceDefaults {
+			m[k] = v
+		}
+		m["h3c"] = config.PlatformDefaultsConfig{EnableRequired: true}
+		c.Collector.DeviceDefaults = m
+	})
+	assert.Same(t, next, p.Get())
+	assert.Len(t, old.Collector.DeviceDefaults, 1)
+	assert.Len(t, next.Collector.DeviceDefaults, 2)
+
+	log := p.Changelog()
+	require.Len(t, log, 1)
+	assert.Equal(t, "runtime", log[0].Source)
+	assert.Equal(t, []string{"collector.device_defaults.h3c"}, log[0].Changed)
+}
+
+// TestConfigDiff 变更字段路径排序输出，不包含字段值
+func TestConfigDiff(t *testing.T) {
+	a, b := &config.Config{}, &config.Config{}
+	b.Log.Level = "debug"
+	b.Approval.Secret = "s3cret"
+	b.Collector.Concurrent = 8
+	changed := config.Diff(a, b)
+	assert.Equal(t, []string{"approval.secret", "collector.concurrent", "log.level"}, changed)
+	for _, c := range changed {
+		assert.NotContains(t, c, "s3cret")
+	}
+	assert.Empty(t, config.Diff(a, &config.Config{}))
+}
+
+// TestApplyReloadResizesWorkers 热更新后并发名额与连接池上限按新配置调整
+func TestApplyReloadResizesWorkers(t *testing.T) {
+	old := &config.Config{}
+	old.Collector.Concurrent = 2
+	collector := service.NewCollectorService(old)
+	backup := service.NewBackupService(old)
+	assert.Equal(t, 2, collector.WorkerUtilization().Max)
+
+	next := *old
+	next.Collector.Concurrent = 5
+	service.ApplyReload(old, &next, collector, backup)
+
+	assert.Equal(t, 5, collector.WorkerUtilization().Max)
+	assert.Equal(t, 5, backup.WorkerUtilization().Max)
+	pool := collector.GetStats()["ssh_pool"].(map[string]interface{})
+	assert.Equal(t, 5, pool["max_active"])
+}
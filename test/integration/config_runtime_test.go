@@ -0,0 +1,98 @@
+package integration
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sshcollectorpro/sshcollectorpro/api/handler"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestRuntimeConfigAPI 生效配置脱敏输出；运行时仅允许修改日志级别与并发档位
+func TestRuntimeConfigAPI(t *testing.T) {
+	prev := config.Get()
+	defer func() {
+		if prev != nil {
+			config.Default().Swap(prev, "file")
+		}
+	}()
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`
+debug:
+  token: admin-secret
+log:
+  level: info
+collector:
+  concurrency_profile: S
+storage:
+  minio:
+    host: 127.0.0.1
+    port: 9000
+    bucket: raw
+    access_key: AKIAEXAMPLE
+    secret_key: very-secret
+`), 0o600))
+	_, err := config.Load(path)
+	require.NoError(t, err)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	h := handler.NewConfigHandler()
+	r.GET("/api/v1/config", h.GetConfig)
+	r.PATCH("/api/v1/config", h.PatchConfig)
+
+	do := func(method, body string, token ...string) (*httptest.ResponseRecorder, map[string]interface{}) {
+		req := httptest.NewRequest(method, "/api/v1/config", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		for _, tok := range token {
+			req.Header.Set("X-Admin-Token", tok)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		var resp map[string]interface{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		return w, resp
+	}
+
+	w, resp := do(http.MethodGet, "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.NotContains(t, w.Body.String(), "very-secret")
+	assert.NotContains(t, w.Body.String(), "AKIAEXAMPLE")
+	data := resp["data"].(map[string]interface{})
+	minio := data["storage"].(map[string]interface{})["minio"].(map[string]interface{})
+	assert.Equal(t, "******", minio["secret_key"])
+	assert.Equal(t, "raw", minio["bucket"])
+	collector := data["collector"].(map[string]interface{})
+	assert.Equal(t, "S", collector["concurrency_profile"])
+	assert.IsType(t, "", data["ssh"].(map[string]interface{})["timeout"])
+
+	// 修改需管理令牌
+	w, _ = do(http.MethodPatch, `{"log.level": "debug"}`)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	w, _ = do(http.MethodPatch, `{"log.level": "debug"}`, "wrong")
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Equal(t, "info", config.Get().Log.Level)
+
+	w, _ = do(http.MethodPatch, `{"server.port": 1}`, "admin-secret")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w, _ = do(http.MethodPatch, `{"log.level": "debug", "collector.concurrency_profile": "XXL"}`, "admin-secret")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, "info", config.Get().Log.Level)
+
+	w, resp = do(http.MethodPatch, `{"log.level": "debug", "collector.concurrency_profile": "m"}`, "admin-secret")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	cfg := config.Get()
+	assert.Equal(t, "debug", cfg.Log.Level)
+	assert.Equal(t, "M", cfg.Collector.ConcurrencyProfile)
+	assert.Equal(t, cfg.Collector.ConcurrencyProfiles["M"].Concurrent, cfg.Collector.Concurrent)
+	assert.ElementsMatch(t, []interface{}{"collector.concurrency_profile", "log.level"}, resp["data"].(map[string]interface{})["changed"])
+}
@@ -0,0 +1,103 @@
+package integration
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sshcollectorpro/sshcollectorpro/api/handler"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const invalidConfigYAML = `
+collector:
+  concurrent: 4
+  concurrency_profile: XXL
+  device_defaults:
+    huawei:
+      promt_suffixes: [">"]
+      timeout:
+        dial_timeot: 5
+storage:
+  minio:
+    host: 127.0.0.1
+    port: 9000
+ssh:
+  keep_alive_interval: 30x
+`
+
+// TestConfigValidation 启动校验一次列出全部问题，未知键给出相近键名
+func TestConfigValidation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(invalidConfigYAML), 0o600))
+	_, err := config.Load(path)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "ssh.keep_alive_interval")
+
+	// 时长修正后报告其余问题
+	fixed := strings.Replace(invalidConfigYAML, "30x", "30s", 1)
+	require.NoError(t, os.WriteFile(path, []byte(fixed), 0o600))
+	_, err = config.Load(path)
+	require.Error(t, err)
+	for _, want := range []string{
+		`collector.device_defaults.huawei.promt_suffixes: unknown key "promt_suffixes" (did you mean "prompt_suffixes"?)`,
+		`collector.device_defaults.huawei.timeout.dial_timeot`,
+		`collector.concurrency_profile: unknown profile "XXL"`,
+		`storage.minio.bucket`,
+	} {
+		assert.Contains(t, err.Error(), want)
+	}
+
+	require.NoError(t, os.WriteFile(path, []byte(`
+collector:
+  concurrent: 4
+  device_defaults:
+    huawei:
+      enable_required: true
+`), 0o600))
+	_, err = config.Load(path)
+	require.NoError(t, err)
+	var paths []string
+	for _, w := range config.LastValidation().Warnings() {
+		paths = append(paths, w.Path)
+	}
+	assert.Contains(t, paths, "collector.concurrent")
+	assert.Contains(t, paths, "collector.device_defaults.huawei.prompt_suffixes")
+}
+
+// TestConfigValidateEndpoint 试运行校验不影响当前生效配置
+func TestConfigValidateEndpoint(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("server:\n  port: 18123\n"), 0o600))
+	_, err := config.Load(path)
+	require.NoError(t, err)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/config/validate", handler.ValidateConfig)
+	validate := func(body string) map[string]json.RawMessage {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/config/validate", strings.NewReader(body)))
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var resp struct {
+			Data map[string]json.RawMessage `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		return resp.Data
+	}
+
+	data := validate(invalidConfigYAML)
+	assert.Equal(t, "false", string(data["valid"]))
+	data = validate("server: [")
+	assert.Equal(t, "false", string(data["valid"]))
+	data = validate("server:\n  port: 18124\n")
+	assert.Equal(t, "true", string(data["valid"]))
+	assert.Equal(t, 18123, config.Get().Server.Port)
+}
@@ -0,0 +1,129 @@
+package integration
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sshcollectorpro/sshcollectorpro/api/handler"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/config"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/database"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/model"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestCredentialRotationWorkflow 验证分类、确认仅写入新密码可用的设备、回滚恢复原密码
+func TestCredentialRotationWorkflow(t *testing.T) {
+	dir := t.TempDir()
+	cfgPath := filepath.Join(dir, "config.yaml")
+	require.NoError(t, os.WriteFile(cfgPath, []byte("vault:\n  master_key: test-key\n"), 0o600))
+	cfg, err := config.Load(cfgPath)
+	require.NoError(t, err)
+	require.NoError(t, database.InitSQLite(config.SQLiteConfig{Path: filepath.Join(dir, "collector.db")}))
+	defer database.Close()
+
+	cc, err := service.NewCredentialCipher(cfg)
+	require.NoError(t, err)
+	oldEnc, err := cc.Encrypt("old-pass")
+	require.NoError(t, err)
+	db := database.GetDB()
+	require.NoError(t, db.Create(&model.Credential{ID: "cred-1", Name: "svc", Username: "ops", PasswordEnc: oldEnc}).Error)
+	for _, d := range []model.DeviceInfo{
+		{ID: "d1", IP: "10.0.0.1", Port: 22, Username: "ops", Password: "old-pass", Enabled: true},
+		{ID: "d2", IP: "10.0.0.2", Port: 22, Username: "ops", Password: "old-pass", Enabled: true},
+		{ID: "d3", IP: "10.0.0.3", Port: 22, Username: "ops", Password: "old-pass", Enabled: true},
+		{ID: "d4", IP: "10.0.0.4", Port: 22, Username: "ops", Password: "other", Enabled: true},
+	} {
+		require.NoError(t, db.Create(&d).Error)
+	}
+
+	// 10.0.0.1 已接受新密码；10.0.0.2 仍为旧密码；10.0.0.3 不可达
+	verify := func(ctx context.Context, ip string, port int, username, password string) error {
+		switch {
+		case ip == "10.0.0.3":
+			return errors.New("dial tcp 10.0.0.3:22: connect: connection refused")
+		case ip == "10.0.0.1" && password == "new-pass", ip == "10.0.0.2" && password == "old-pass":
+			return nil
+		}
+		return errors.New("ssh: handshake failed: ssh: unable to authenticate, attempted methods [none password]")
+	}
+	h := handler.NewCredentialRotationHandler(verify)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/rotations", h.CreateRotation)
+	r.GET("/rotations/:id", h.GetRotation)
+	r.POST("/rotations/:id/finalize", h.FinalizeRotation)
+	r.POST("/rotations/:id/rollback", h.RollbackRotation)
+	do := func(method, url string, body interface{}) (int, map[string]interface{}) {
+		var buf bytes.Buffer
+		if body != nil {
+			require.NoError(t, json.NewEncoder(&buf).Encode(body))
+		}
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(method, url, &buf)
+		req.Header.Set("Content-Type", "application/json")
+		r.ServeHTTP(w, req)
+		var out map[string]interface{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &out))
+		return w.Code, out
+	}
+
+	code, body := do(http.MethodPost, "/rotations", map[string]interface{}{"credential_id": "cred-1", "new_password": "new-pass", "concurrency": 2})
+	require.Equal(t, http.StatusAccepted, code, body)
+	data := body["data"].(map[string]interface{})
+	id := data["id"].(string)
+	assert.EqualValues(t, 3, data["total"])
+
+	require.Eventually(t, func() bool {
+		var rot model.CredentialRotation
+		return db.Where("id = ?", id).First(&rot).Error == nil && rot.Status == model.RotationVerified
+	}, 2*time.Second, 20*time.Millisecond)
+	code, body = do(http.MethodGet, "/rotations/"+id+"?result=requires_old", nil)
+	require.Equal(t, http.StatusOK, code)
+	data = body["data"].(map[string]interface{})
+	assert.EqualValues(t, 1, data["new_ok"])
+	assert.EqualValues(t, 1, data["requires_old"])
+	assert.EqualValues(t, 1, data["failed"])
+	targets := data["targets"].([]interface{})
+	require.Len(t, targets, 1)
+	assert.Equal(t, "10.0.0.2", targets[0].(map[string]interface{})["ip"])
+	assert.NotContains(t, targets[0], "old_password_enc")
+
+	password := func(id string) string {
+		var d model.DeviceInfo
+		require.NoError(t, db.Where("id = ?", id).First(&d).Error)
+		return d.Password
+	}
+	code, _ = do(http.MethodPost, "/rotations/"+id+"/finalize", nil)
+	require.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "new-pass", password("d1"))
+	assert.Equal(t, "old-pass", password("d2"))
+	assert.Equal(t, "other", password("d4"))
+	var cred model.Credential
+	require.NoError(t, db.Where("id = ?", "cred-1").First(&cred).Error)
+	plain, err := cc.Decrypt(cred.PasswordEnc)
+	require.NoError(t, err)
+	assert.Equal(t, "new-pass", plain)
+
+	code, _ = do(http.MethodPost, "/rotations/"+id+"/finalize", nil)
+	assert.Equal(t, http.StatusConflict, code)
+
+	code, _ = do(http.MethodPost, "/rotations/"+id+"/rollback", nil)
+	require.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "old-pass", password("d1"))
+	require.NoError(t, db.Where("id = ?", "cred-1").First(&cred).Error)
+	plain, err = cc.Decrypt(cred.PasswordEnc)
+	require.NoError(t, err)
+	assert.Equal(t, "old-pass", plain)
+}
@@ -0,0 +1,100 @@
+package integration
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sshcollectorpro/sshcollectorpro/api/handler"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/config"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/database"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/model"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestCredentialVault 凭据加密保存且不对外输出密码；创建与改名重名返回 409
+func TestCredentialVault(t *testing.T) {
+	dir := t.TempDir()
+	cfgPath := filepath.Join(dir, "config.yaml")
+	require.NoError(t, os.WriteFile(cfgPath, []byte("vault:\n  master_key: test-key\n"), 0o600))
+	cfg, err := config.Load(cfgPath)
+	require.NoError(t, err)
+	require.NoError(t, database.InitSQLite(config.SQLiteConfig{Path: filepath.Join(dir, "collector.db")}))
+	t.Cleanup(func() { database.Close() })
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	h := handler.NewCredentialHandler()
+	r.POST("/credentials", h.CreateCredential)
+	r.GET("/credentials/:id", h.GetCredential)
+	r.PUT("/credentials/:id", h.UpdateCredential)
+	do := func(method, path string, body interface{}) (int, map[string]interface{}, string) {
+		b, _ := json.Marshal(body)
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(method, path, bytes.NewReader(b))
+		req.Header.Set("Content-Type", "application/json")
+		r.ServeHTTP(w, req)
+		var out map[string]interface{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &out), w.Body.String())
+		return w.Code, out, w.Body.String()
+	}
+
+	code, body, raw := do(http.MethodPost, "/credentials", map[string]string{"name": "noc", "username": "ops", "password": "s3cret-pass", "enable_password": "en-pass"})
+	require.Equal(t, http.StatusCreated, code, raw)
+	assert.NotContains(t, raw, "s3cret-pass")
+	nocID := body["data"].(map[string]interface{})["id"].(string)
+	code, body, raw = do(http.MethodPost, "/credentials", map[string]string{"name": "backup", "username": "bk", "password": "bk-pass"})
+	require.Equal(t, http.StatusCreated, code, raw)
+	backupID := body["data"].(map[string]interface{})["id"].(string)
+
+	// 密文落库，可用主密钥解密
+	load := func(id string) model.Credential {
+		var cred model.Credential
+		require.NoError(t, database.GetDB().Where("id = ?", id).First(&cred).Error)
+		return cred
+	}
+	stored := load(nocID)
+	assert.NotContains(t, stored.PasswordEnc, "s3cret-pass")
+	cc, err := service.NewCredentialCipher(cfg)
+	require.NoError(t, err)
+	plain, err := cc.Decrypt(stored.PasswordEnc)
+	require.NoError(t, err)
+	assert.Equal(t, "s3cret-pass", plain)
+
+	code, _, raw = do(http.MethodGet, "/credentials/"+nocID, nil)
+	assert.Equal(t, http.StatusOK, code)
+	assert.NotContains(t, raw, "s3cret-pass")
+	assert.NotContains(t, raw, "password_enc")
+
+	// 创建重名
+	code, body, _ = do(http.MethodPost, "/credentials", map[string]string{"name": "noc", "username": "x", "password": "y"})
+	assert.Equal(t, http.StatusConflict, code)
+	assert.Equal(t, "CREDENTIAL_EXISTS", body["code"])
+
+	// 改名为其他凭据的名称
+	code, body, _ = do(http.MethodPut, "/credentials/"+backupID, map[string]string{"name": "noc"})
+	assert.Equal(t, http.StatusConflict, code)
+	assert.Equal(t, "CREDENTIAL_EXISTS", body["code"])
+	assert.Equal(t, "backup", load(backupID).Name, "冲突时不修改")
+
+	// 保持原名或改为未占用的名称
+	code, _, raw = do(http.MethodPut, "/credentials/"+nocID, map[string]string{"name": "noc", "remarks": "core"})
+	assert.Equal(t, http.StatusOK, code, raw)
+	code, body, raw = do(http.MethodPut, "/credentials/"+backupID, map[string]string{"name": "backup-v2", "password": "bk-pass-2"})
+	require.Equal(t, http.StatusOK, code, raw)
+	assert.Equal(t, "backup-v2", body["data"].(map[string]interface{})["name"])
+	plain, err = cc.Decrypt(load(backupID).PasswordEnc)
+	require.NoError(t, err)
+	assert.Equal(t, "bk-pass-2", plain)
+
+	code, body, _ = do(http.MethodPut, "/credentials/missing", map[string]string{"name": "x"})
+	assert.Equal(t, http.StatusNotFound, code)
+	assert.Equal(t, "CREDENTIAL_NOT_FOUND", body["code"])
+}
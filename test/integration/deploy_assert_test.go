@@ -0,0 +1,23 @@
+package integration
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/sshcollectorpro/sshcollectorpro/internal/service"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestValidateDeployAssertions 断言定义校验：阶段与规则类型
+func TestValidateDeployAssertions(t *testing.T) {
+	ok := []service.DeployDevice{{DeviceIP: "192.0.2.1", Assertions: []service.DeployAssertion{
+		{Name: "uplink-up", Phase: "post", Command: "show interface Gi0/48", RuleType: service.RuleTypeRegex, Pattern: `line protocol is up`},
+		{Command: "show vlan brief", RuleType: service.RuleTypeMustNotContain, Pattern: "VLAN0100"},
+	}}}
+	assert.NoError(t, service.ValidateDeployAssertions(ok))
+
+	bad := []service.DeployDevice{{DeviceIP: "192.0.2.1", Assertions: []service.DeployAssertion{{Phase: "during", Command: "show clock", RuleType: service.RuleTypeMustContain, Pattern: "UTC"}}}}
+	assert.True(t, errors.Is(service.ValidateDeployAssertions(bad), service.ErrValidation))
+	bad[0].Assertions[0] = service.DeployAssertion{Command: "show clock", RuleType: service.RuleTypeRegex, Pattern: "("}
+	assert.True(t, errors.Is(service.ValidateDeployAssertions(bad), service.ErrValidation))
+}
@@ -0,0 +1,80 @@
+package integration
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sshcollectorpro/sshcollectorpro/internal/config"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/service"
+	"github.com/sshcollectorpro/sshcollectorpro/simulate"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestDeployCheckpoint checkpoint=true 时下发前执行平台 checkpoint_cli 并记录结果；平台未配置时标记 UNSUPPORTED 并继续下发
+func TestDeployCheckpoint(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	port := ln.Addr().(*net.TCPAddr).Port
+	ln.Close()
+	mgr, err := simulate.Start(&simulate.Config{
+		Namespace:  map[string]simulate.NamespaceConfig{"t": {Port: port, IdleSeconds: 60, MaxConn: 10}},
+		DeviceType: map[string]simulate.DeviceTypeConfig{"cisco_ios": {PromptSuffix: "#"}},
+		DeviceName: map[string]simulate.DeviceNameConfig{"sw-01": {DeviceType: "cisco_ios"}},
+	})
+	require.NoError(t, err)
+	defer mgr.Stop()
+
+	cfgPath := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(cfgPath, []byte(`
+deploy:
+  deploy_wait_ms: 1
+collector:
+  device_defaults:
+    cisco_ios:
+      prompt_suffixes: ["#"]
+      command_interval_ms: 10
+      checkpoint_cli: ["archive config ${task_id}"]
+    cisco_nxos:
+      prompt_suffixes: ["#"]
+      command_interval_ms: 10
+`), 0o600))
+	cfg, err := config.Load(cfgPath)
+	require.NoError(t, err)
+	collector := service.NewCollectorService(cfg)
+	require.NoError(t, collector.Start(context.Background()))
+	defer collector.Stop()
+	svc := service.NewDeployService(cfg, collector)
+
+	deploy := func(platform string) service.DeployDeviceResult {
+		resp, err := svc.Deploy(context.Background(), &service.DeployFastRequest{
+			TaskID:      "ck-1",
+			TaskType:    "exec",
+			TaskTimeout: 10,
+			Checkpoint:  true,
+			Devices: []service.DeployDevice{{
+				DeviceIP: "127.0.0.1", DevicePort: port, DeviceName: "sw-01", DevicePlatform: platform,
+				UserName: "sw-01", Password: "nova",
+				CliList: service.NewDeployCLIList("ntp server 10.0.0.1"),
+			}},
+		})
+		require.NoError(t, err)
+		require.Len(t, resp.Results, 1)
+		return resp.Results[0]
+	}
+
+	r := deploy("cisco_ios")
+	assert.Empty(t, r.Error)
+	assert.Equal(t, service.CheckpointStatusSaved, r.CheckpointStatus)
+	require.Len(t, r.CheckpointLogExec, 1)
+	assert.Equal(t, "archive config ck-1", r.CheckpointLogExec[0].Command)
+	require.Len(t, r.DeployLogExec, 1)
+
+	r = deploy("cisco_nxos")
+	assert.Equal(t, service.CheckpointStatusUnsupported, r.CheckpointStatus)
+	assert.Empty(t, r.CheckpointLogExec)
+	assert.Len(t, r.DeployLogExec, 1)
+}
@@ -0,0 +1,24 @@
+package integration
+
+import (
+	"testing"
+
+	"github.com/sshcollectorpro/sshcollectorpro/internal/service"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestGenerateRollbackCommands 自动回滚：按块逆序、子视图重放、平台否定形式
+func TestGenerateRollbackCommands(t *testing.T) {
+	applied := []string{"vlan 100", "exit", "interface GigabitEthernet0/1", "switchport access vlan 100", "no shutdown", "exit", "ntp server 10.0.0.1"}
+	assert.Equal(t, []string{
+		"no ntp server 10.0.0.1",
+		"interface GigabitEthernet0/1", "shutdown", "no switchport access vlan 100", "exit",
+		"no vlan 100",
+	}, service.GenerateRollbackCommands("cisco_ios", applied))
+
+	assert.Equal(t, []string{"interface Vlanif10", "undo ip address 10.0.0.1 24", "quit"},
+		service.GenerateRollbackCommands("huawei_vrp", []string{"interface Vlanif10", "ip address 10.0.0.1 24"}))
+	assert.Equal(t, []string{"delete system ntp server 10.0.0.1"},
+		service.GenerateRollbackCommands("juniper_junos", []string{"set system ntp server 10.0.0.1", "commit"}))
+	assert.Empty(t, service.GenerateRollbackCommands("linux", []string{"echo 1"}))
+}
@@ -0,0 +1,49 @@
+package integration
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/sshcollectorpro/sshcollectorpro/internal/service"
+	"github.com/sshcollectorpro/sshcollectorpro/pkg/ssh"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestDeployCLIListScriptSteps 配置行与 send_raw/sleep_ms 步骤混排，按序解析并可还原
+func TestDeployCLIListScriptSteps(t *testing.T) {
+	var d service.DeployDevice
+	body := `{"device_ip":"192.0.2.1","cli_list":["interface gi0/1",{"sleep_ms":500},{"send_raw":"\\x1a"},{"cli":"end"}]}`
+	require.NoError(t, json.Unmarshal([]byte(body), &d))
+	require.Len(t, d.CliList, 4)
+	assert.Equal(t, "interface gi0/1", d.CliList[0].CLI)
+	require.NotNil(t, d.CliList[1].Step)
+	assert.Equal(t, ssh.SleepStep(500), *d.CliList[1].Step)
+	require.NotNil(t, d.CliList[2].Step)
+	assert.Equal(t, []byte{0x1a}, d.CliList[2].Step.Raw)
+	assert.Equal(t, "end", d.CliList[3].CLI)
+	assert.Equal(t, []string{"interface gi0/1", "<sleep_ms 500>", `<send_raw "\x1a">`, "end"}, d.CliList.Lines())
+
+	out, err := json.Marshal(d.CliList)
+	require.NoError(t, err)
+	assert.JSONEq(t, `["interface gi0/1",{"sleep_ms":500},{"send_raw":"\u001a"},"end"]`, string(out))
+
+	assert.Error(t, json.Unmarshal([]byte(`{"cli_list":[{"sleep_ms":0}]}`), &d))
+	assert.Error(t, json.Unmarshal([]byte(`{"cli_list":[{"send_raw":"\\x03","sleep_ms":5}]}`), &d))
+}
+
+// TestCLIListRejectsNULPrefix 以 NUL 开头的命令在 API 边界被拒绝，无法伪造脚本步骤
+func TestCLIListRejectsNULPrefix(t *testing.T) {
+	var collect struct {
+		CliList service.CLIList `json:"cli_list"`
+	}
+	assert.Error(t, json.Unmarshal([]byte(`{"cli_list":["\u0000step:sleep_ms:600000"]}`), &collect))
+	assert.Error(t, json.Unmarshal([]byte(`{"cli_list":[{"cli":"\u0000step:send_raw:\\x03"}]}`), &collect))
+	require.NoError(t, json.Unmarshal([]byte(`{"cli_list":["show version"]}`), &collect))
+
+	var d service.DeployDevice
+	assert.Error(t, json.Unmarshal([]byte(`{"cli_list":["\u0000step:sleep_ms:600000"]}`), &d))
+	assert.Error(t, json.Unmarshal([]byte(`{"cli_list":[{"cli":"\u0000x"}]}`), &d))
+	assert.Error(t, service.CheckCLIText("show clock", "\x00step:send_raw:\\x03"))
+	assert.NoError(t, service.CheckCLIText("show clock"))
+}
@@ -0,0 +1,47 @@
+package integration
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/sshcollectorpro/sshcollectorpro/internal/config"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestDeployConfigTemplateDryRun dry_run 仅渲染模板：设备变量覆盖公共变量，缺失变量仅该设备失败
+func TestDeployConfigTemplateDryRun(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Deploy.DeployWaitMS = 1
+	svc := service.NewDeployService(cfg, service.NewCollectorService(cfg))
+
+	req := &service.DeployFastRequest{
+		TaskID:         "tpl-1",
+		TaskType:       "dry_run",
+		ConfigTemplate: "hostname {{ upper .device.name }}\nntp server {{ .ntp }}\n",
+		Variables:      map[string]interface{}{"ntp": "10.0.0.1"},
+		Devices: []service.DeployDevice{
+			{DeviceIP: "192.0.2.1", DeviceName: "sw-01"},
+			{DeviceIP: "192.0.2.2", DeviceName: "sw-02", Variables: map[string]interface{}{"ntp": "10.0.0.2"}},
+		},
+	}
+	resp, err := svc.Deploy(context.Background(), req)
+	require.NoError(t, err)
+	require.Len(t, resp.Results, 2)
+	assert.Equal(t, "hostname SW-01\nntp server 10.0.0.1", resp.Results[0].RenderedConfig)
+	assert.Equal(t, "hostname SW-02\nntp server 10.0.0.2", resp.Results[1].RenderedConfig)
+	assert.Empty(t, resp.Results[0].DeployLogExec)
+
+	req.Variables = nil
+	req.Devices[0].Variables = nil
+	resp, err = svc.Deploy(context.Background(), req)
+	require.NoError(t, err)
+	assert.Contains(t, resp.Results[0].Error, "render config_template")
+	assert.Empty(t, resp.Results[1].Error)
+
+	req.ConfigTemplate = "hostname {{ .name"
+	_, err = svc.Deploy(context.Background(), req)
+	assert.True(t, errors.Is(err, service.ErrValidation))
+}
@@ -0,0 +1,56 @@
+package integration
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/sshcollectorpro/sshcollectorpro/internal/config"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeInventory map[string]map[string]string
+
+func (f fakeInventory) Attributes(ip string, port int) (map[string]string, bool) {
+	a, ok := f[ip]
+	return a, ok
+}
+
+// TestDeployVariableSubstitution ${var} 取自任务级、设备级变量与资产属性；未定义变量整个请求校验失败
+func TestDeployVariableSubstitution(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Deploy.DeployWaitMS = 1
+	svc := service.NewDeployService(cfg, service.NewCollectorService(cfg))
+	svc.SetInventory(fakeInventory{"192.0.2.1": {"vendor": "huawei"}})
+
+	req := &service.DeployFastRequest{
+		TaskID:    "vars-1",
+		TaskType:  "dry_run",
+		Variables: map[string]interface{}{"vlan": 100, "acl": "MGMT"},
+		Devices: []service.DeployDevice{
+			{
+				DeviceIP:        "192.0.2.1",
+				DeviceName:      "sw-01",
+				Variables:       map[string]interface{}{"vlan": 200},
+				CliList:         service.NewDeployCLIList("vlan ${vlan}", "description ${device.name}-${inventory.vendor}", "echo $${vlan}"),
+				RollbackCliList: service.NewDeployCLIList("undo vlan ${ vlan }"),
+			},
+			{DeviceIP: "192.0.2.2", ConfigDeploy: "acl name ${acl}\nvlan ${vlan}"},
+		},
+	}
+	_, err := svc.Deploy(context.Background(), req)
+	require.NoError(t, err)
+	assert.Equal(t, service.NewDeployCLIList("vlan 200", "description sw-01-huawei", "echo ${vlan}"), req.Devices[0].CliList)
+	assert.Equal(t, service.NewDeployCLIList("undo vlan 200"), req.Devices[0].RollbackCliList)
+	assert.Equal(t, "acl name MGMT\nvlan 100", req.Devices[1].ConfigDeploy)
+
+	req.Devices = []service.DeployDevice{
+		{DeviceIP: "192.0.2.3", CliList: service.NewDeployCLIList("interface ${port}", "vlan ${inventory.vendor}")},
+	}
+	_, err = svc.Deploy(context.Background(), req)
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, service.ErrValidation))
+	assert.Contains(t, err.Error(), "${inventory.vendor}, ${port}")
+}
@@ -0,0 +1,98 @@
+package integration
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sshcollectorpro/sshcollectorpro/internal/config"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/service"
+	"github.com/sshcollectorpro/sshcollectorpro/simulate"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const factsIOSVersion = `Cisco IOS Software, C3750E Software (C3750E-UNIVERSALK9-M), Version 15.2(4)E10, RELEASE SOFTWARE (fc2)
+core-sw1 uptime is 3 weeks, 2 days, 4 hours, 10 minutes
+cisco WS-C3750X-48P (PowerPC405) processor (revision A0) with 262144K bytes of memory.
+Processor board ID FDO1234X0AB
+`
+
+const factsVRPVersion = `Huawei Versatile Routing Platform Software
+VRP (R) software, Version 5.170 (S5720 V200R011C10SPC500)
+Copyright (C) 2000-2018 HUAWEI TECH CO., LTD
+HUAWEI S5720-28X-SI-AC Routing Switch uptime is 120 days, 3 hours, 5 minutes
+`
+
+// TestDiscoverFacts 未指定平台时按版本输出识别厂商并解析规范化事实
+func TestDiscoverFacts(t *testing.T) {
+	port := freePort(t)
+	mgr, err := simulate.Start(&simulate.Config{
+		Namespace:  map[string]simulate.NamespaceConfig{"t": {Port: port, IdleSeconds: 60, MaxConn: 10}},
+		DeviceType: map[string]simulate.DeviceTypeConfig{"cisco_ios": {PromptSuffix: "#"}, "huawei": {PromptSuffix: ">"}},
+		DeviceName: map[string]simulate.DeviceNameConfig{
+			"sw-01": {DeviceType: "cisco_ios"},
+			"sw-02": {DeviceType: "huawei"},
+			"sw-03": {DeviceType: "cisco_ios"},
+		},
+		Scenarios: []simulate.ScenarioConfig{
+			{DeviceName: "sw-01", Command: "show version", Responses: []simulate.ScenarioResponse{{Output: factsIOSVersion}}},
+			{DeviceName: "sw-02", Command: "display version", Responses: []simulate.ScenarioResponse{{Output: factsVRPVersion}}},
+		},
+	})
+	require.NoError(t, err)
+	defer mgr.Stop()
+
+	cfgPath := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(cfgPath, []byte(`
+collector:
+  device_defaults:
+    cisco_ios:
+      prompt_suffixes: ["#"]
+      command_interval_ms: 10
+    huawei:
+      prompt_suffixes: [">"]
+      command_interval_ms: 10
+`), 0o600))
+	cfg, err := config.Load(cfgPath)
+	require.NoError(t, err)
+	svc := service.NewCollectorService(cfg)
+	require.NoError(t, svc.Start(context.Background()))
+	defer svc.Stop()
+
+	discover := func(user, platform string) (*service.DeviceFacts, error) {
+		return svc.DiscoverFacts(context.Background(), &service.FactsRequest{
+			DeviceIP: "127.0.0.1", Port: port, DevicePlatform: platform, UserName: user, Password: "nova",
+		})
+	}
+
+	f, err := discover("sw-01", "")
+	require.NoError(t, err)
+	assert.True(t, f.PlatformDetected)
+	assert.Equal(t, "cisco_ios", f.DevicePlatform)
+	assert.Equal(t, "Cisco", f.Vendor)
+	assert.Equal(t, "WS-C3750X-48P", f.Model)
+	assert.Equal(t, "15.2(4)E10", f.OSVersion)
+	assert.Equal(t, "FDO1234X0AB", f.Serial)
+	assert.Equal(t, "3 weeks, 2 days, 4 hours, 10 minutes", f.Uptime)
+	assert.Equal(t, "core-sw1", f.Hostname)
+	assert.Equal(t, "show version", f.Command)
+
+	f, err = discover("sw-02", "")
+	require.NoError(t, err)
+	assert.Equal(t, "huawei", f.DevicePlatform)
+	assert.Equal(t, "S5720-28X-SI-AC", f.Model)
+	assert.Equal(t, "5.170 (S5720 V200R011C10SPC500)", f.OSVersion)
+	assert.Equal(t, "120 days, 3 hours, 5 minutes", f.Uptime)
+	assert.Empty(t, f.Serial)
+
+	// 已指定平台：仅执行该平台的版本命令
+	f, err = discover("sw-01", "cisco_ios")
+	require.NoError(t, err)
+	assert.False(t, f.PlatformDetected)
+	assert.Equal(t, "FDO1234X0AB", f.Serial)
+
+	_, err = discover("sw-03", "")
+	assert.ErrorIs(t, err, service.ErrFactsUnavailable)
+}
@@ -0,0 +1,55 @@
+package integration
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/sshcollectorpro/sshcollectorpro/api/handler"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/config"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestDiagnosticsHandlerAuth 未配置令牌时关闭，令牌错误拒绝，正确令牌可访问 runtime/pprof/expvar
+func TestDiagnosticsHandlerAuth(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Collector.Concurrent = 4
+	token := ""
+	h := handler.NewDiagnosticsHandler(func() string { return token }, service.NewBackupService(cfg), service.NewFormatService(cfg))
+
+	do := func(path, auth string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if auth != "" {
+			req.Header.Set("Authorization", "Bearer "+auth)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		return w
+	}
+
+	assert.Equal(t, http.StatusForbidden, do("/debug/runtime", "").Code)
+	token = "s3cret"
+	assert.Equal(t, http.StatusUnauthorized, do("/debug/runtime", "wrong").Code)
+
+	w := do("/debug/runtime", "s3cret")
+	require.Equal(t, http.StatusOK, w.Code)
+	var body struct {
+		Data struct {
+			Goroutines int                         `json:"goroutines"`
+			Workers    []service.WorkerUtilization `json:"workers"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Greater(t, body.Data.Goroutines, 0)
+	require.Len(t, body.Data.Workers, 2)
+	assert.Equal(t, "backup", body.Data.Workers[0].Service)
+	assert.Equal(t, 4, body.Data.Workers[0].Max)
+
+	assert.Equal(t, http.StatusOK, do("/debug/pprof/", "s3cret").Code)
+	w = do("/debug/vars", "s3cret")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"workers"`)
+}
@@ -0,0 +1,118 @@
+package integration
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sshcollectorpro/sshcollectorpro/api/handler"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/config"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/service"
+	"github.com/sshcollectorpro/sshcollectorpro/pkg/ssh"
+	"github.com/sshcollectorpro/sshcollectorpro/simulate"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestEgressPolicyRules deny 优先，allow 非空时须命中，租户规则与全局规则同时生效
+func TestEgressPolicyRules(t *testing.T) {
+	p := &ssh.EgressPolicy{
+		EgressRule: ssh.EgressRule{Allow: []string{"10.0.0.0/8", "127.0.0.1"}, Deny: []string{"10.0.99.0/24"}},
+		Tenants:    map[string]ssh.EgressRule{"campus": {Allow: []string{"10.20.0.0/16"}}},
+	}
+	ctx := context.Background()
+	assert.NoError(t, p.Check(ctx, "10.1.2.3"))
+	assert.NoError(t, p.Check(ctx, "localhost"))
+	assert.ErrorIs(t, p.Check(ctx, "10.0.99.7"), ssh.ErrEgressBlocked)
+	assert.ErrorIs(t, p.Check(ctx, "192.168.1.1"), ssh.ErrEgressBlocked)
+	assert.ErrorIs(t, p.Check(ctx, "no-such-host.invalid"), ssh.ErrEgressBlocked)
+
+	campus := ssh.WithEgressTenant(ctx, "Campus")
+	assert.NoError(t, p.Check(campus, "10.20.1.1"))
+	assert.ErrorIs(t, p.Check(campus, "10.1.2.3"), ssh.ErrEgressBlocked)
+	// 未配置的租户仅适用全局规则
+	assert.NoError(t, p.Check(ssh.WithEgressTenant(ctx, "other"), "10.1.2.3"))
+
+	var nilPolicy *ssh.EgressPolicy
+	assert.NoError(t, nilPolicy.Check(ctx, "192.168.1.1"))
+
+	report, err := config.ValidateYAML([]byte("ssh:\n  egress:\n    allow: [\"10.0.0.0/33\"]\n"))
+	require.NoError(t, err)
+	require.Len(t, report.Errors(), 1)
+	assert.Equal(t, "ssh.egress.allow[0]", report.Errors()[0].Path)
+}
+
+// TestEgressBlockedBeforeDial 范围外设备在派发前拒绝：采集返回 EGRESS_BLOCKED，批量备份设备状态为 EGRESS_BLOCKED
+func TestEgressBlockedBeforeDial(t *testing.T) {
+	port := freePort(t)
+	mgr, err := simulate.Start(&simulate.Config{
+		Namespace:  map[string]simulate.NamespaceConfig{"t": {Port: port, IdleSeconds: 60, MaxConn: 10}},
+		DeviceType: map[string]simulate.DeviceTypeConfig{"cisco_ios": {PromptSuffix: "#"}},
+		DeviceName: map[string]simulate.DeviceNameConfig{"sw-01": {DeviceType: "cisco_ios"}},
+		Scenarios: []simulate.ScenarioConfig{
+			{DeviceName: "sw-01", Command: "show clock", Responses: []simulate.ScenarioResponse{{Output: "10:00:00 UTC"}}},
+		},
+	})
+	require.NoError(t, err)
+	t.Cleanup(mgr.Stop)
+
+	cfgPath := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(cfgPath, []byte(`
+ssh:
+  egress:
+    allow: ["127.0.0.0/8"]
+    tenants:
+      lab:
+        deny: ["127.0.0.1"]
+collector:
+  device_defaults:
+    cisco_ios:
+      prompt_suffixes: ["#"]
+      command_interval_ms: 10
+`), 0o600))
+	cfg, err := config.Load(cfgPath)
+	require.NoError(t, err)
+	svc := service.NewCollectorService(cfg)
+	require.NoError(t, svc.Start(context.Background()))
+	t.Cleanup(func() { svc.Stop() })
+
+	collect := func(ctx context.Context, ip string) (*service.CollectResponse, error) {
+		return svc.ExecuteTask(ctx, &service.CollectRequest{
+			TaskID: "eg-" + ip, DeviceIP: ip, Port: port, DevicePlatform: "cisco_ios",
+			UserName: "sw-01", Password: "nova", CliList: service.NewCLIList("show clock"),
+		})
+	}
+
+	resp, err := collect(context.Background(), "127.0.0.1")
+	require.NoError(t, err)
+	require.True(t, resp.Success, resp.Error)
+
+	_, err = collect(context.Background(), "10.255.0.1")
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, service.ErrEgressBlocked))
+	assert.True(t, strings.HasPrefix(err.Error(), "EGRESS_BLOCKED"))
+	status, code := handler.ErrorStatus(err, "")
+	assert.Equal(t, http.StatusForbidden, status)
+	assert.Equal(t, "EGRESS_BLOCKED", code)
+
+	// 租户规则：池内已有到 127.0.0.1 的连接也不得复用
+	_, err = collect(ssh.WithEgressTenant(context.Background(), "lab"), "127.0.0.1")
+	assert.ErrorIs(t, err, service.ErrEgressBlocked)
+
+	backup := service.NewBackupService(cfg)
+	require.NoError(t, backup.Start(context.Background()))
+	t.Cleanup(func() { backup.Stop() })
+	out, err := backup.ExecuteBatch(context.Background(), &service.BackupBatchRequest{
+		TaskID:  "eg-bk",
+		SaveDir: t.TempDir(),
+		Devices: []service.BackupDevice{{DeviceIP: "10.255.0.1", Port: port, UserName: "sw-01", Password: "nova", CliList: service.NewCLIList("show clock")}},
+	})
+	require.NoError(t, err)
+	require.Len(t, out.Data, 1)
+	assert.False(t, out.Data[0].Success)
+	assert.Equal(t, service.StatusEgressBlocked, out.Data[0].Status)
+}
@@ -0,0 +1,46 @@
+package integration
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sshcollectorpro/sshcollectorpro/api/handler"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/service"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestErrorMapping 服务层错误经统一中间件映射为 HTTP 状态码与业务码
+func TestErrorMapping(t *testing.T) {
+	cases := []struct {
+		name   string
+		err    error
+		status int
+		code   string
+	}{
+		{"服务未运行", fmt.Errorf("wrap: %w", service.ErrServiceStopped), http.StatusServiceUnavailable, "SERVICE_NOT_READY"},
+		{"参数错误", fmt.Errorf("%w: task_id", service.ErrValidation), http.StatusBadRequest, "INVALID_PARAMS"},
+		{"任务不存在", fmt.Errorf("%w: t1", service.ErrTaskNotFound), http.StatusNotFound, "TASK_NOT_FOUND"},
+		{"设备不可达", fmt.Errorf("%w: dial", service.ErrDeviceUnreachable), http.StatusBadGateway, "DEVICE_UNREACHABLE"},
+		{"只读模式", service.ErrReadOnly, http.StatusForbidden, "READ_ONLY"},
+		{"登录超时", fmt.Errorf("%w: 设备登陆失败", service.ErrTimeout), http.StatusGatewayTimeout, "COMMAND_TIMEOUT"},
+		{"请求取消", fmt.Errorf("%w: client gone", service.ErrCanceled), 499, "REQUEST_CANCELED"},
+		{"未分类", errors.New("boom"), http.StatusInternalServerError, "EXEC_FAILED"},
+	}
+	gin.SetMode(gin.TestMode)
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := gin.New()
+			r.Use(handler.ErrorMiddleware())
+			r.GET("/x", func(c *gin.Context) { c.Error(tc.err).SetMeta("EXEC_FAILED") })
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/x", nil))
+			assert.Equal(t, tc.status, w.Code)
+			assert.Contains(t, w.Body.String(), `"code":"`+tc.code+`"`)
+			assert.Contains(t, w.Body.String(), tc.err.Error())
+		})
+	}
+}
@@ -0,0 +1,122 @@
+package integration
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sshcollectorpro/sshcollectorpro/internal/config"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/twmb/franz-go/pkg/kfake"
+	"github.com/twmb/franz-go/pkg/kgo"
+)
+
+// TestEventsKafkaPublish 备份事件写入配置的 topic（以设备 IP 为 key），未配置 topic 的事件类型不发布
+func TestEventsKafkaPublish(t *testing.T) {
+	cluster, err := kfake.NewCluster(kfake.NumBrokers(1), kfake.SeedTopics(2, "netops.backup"))
+	require.NoError(t, err)
+	defer cluster.Close()
+
+	cfg := &config.Config{}
+	cfg.Collector.ID = "collector-1"
+	cfg.Events = config.EventsConfig{
+		Enable:  true,
+		Backend: "kafka",
+		Brokers: cluster.ListenAddrs(),
+		Topics:  config.EventTopicsConfig{Backup: "netops.backup"},
+		Timeout: 2 * time.Second,
+	}
+	require.NoError(t, service.InitEvents(cfg))
+	service.PublishDeviceEvent(service.DeviceEvent{Type: service.EventTypeCollect, TaskID: "t0", DeviceIP: "10.0.0.9"})
+	service.PublishDeviceEvent(service.DeviceEvent{
+		Type:       service.EventTypeBackup,
+		TaskID:     "t1",
+		DeviceIP:   "10.0.0.1",
+		Status:     service.EventStatusSuccess,
+		ObjectURIs: []string{"s3://bucket/t1/show_run.txt"},
+	})
+	service.CloseEvents()
+
+	consumer, err := kgo.NewClient(kgo.SeedBrokers(cluster.ListenAddrs()...), kgo.ConsumeTopics("netops.backup"),
+		kgo.ConsumeResetOffset(kgo.NewOffset().AtStart()))
+	require.NoError(t, err)
+	defer consumer.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	var records []*kgo.Record
+	for len(records) == 0 && ctx.Err() == nil {
+		fetches := consumer.PollFetches(ctx)
+		records = append(records, fetches.Records()...)
+	}
+	require.Len(t, records, 1, "exactly one backup event produced")
+	assert.Equal(t, "10.0.0.1", string(records[0].Key))
+	var ev service.DeviceEvent
+	require.NoError(t, json.Unmarshal(records[0].Value, &ev))
+	assert.Equal(t, "t1", ev.TaskID)
+	assert.Equal(t, service.EventStatusSuccess, ev.Status)
+	assert.Equal(t, []string{"s3://bucket/t1/show_run.txt"}, ev.ObjectURIs)
+}
+
+// TestEventsNATSPublish 下发事件以 PUB 发送到配置的 subject
+func TestEventsNATSPublish(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer ln.Close()
+	pubs := make(chan string, 4)
+	go func() {
+		c, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer c.Close()
+		c.Write([]byte(`INFO {"server_id":"fake","version":"2.10.0","max_payload":1048576}` + "\r\n"))
+		r := bufio.NewReader(c)
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			switch {
+			case strings.HasPrefix(line, "PING"):
+				c.Write([]byte("PONG\r\n"))
+			case strings.HasPrefix(line, "PUB "):
+				f := strings.Fields(line)
+				n, _ := strconv.Atoi(f[len(f)-1])
+				payload := make([]byte, n+2)
+				if _, err := io.ReadFull(r, payload); err != nil {
+					return
+				}
+				pubs <- f[1] + " " + string(payload[:n])
+			}
+		}
+	}()
+
+	cfg := &config.Config{}
+	cfg.Events = config.EventsConfig{
+		Enable:  true,
+		Backend: "nats",
+		Brokers: []string{"nats://" + ln.Addr().String()},
+		Topics:  config.EventTopicsConfig{Deploy: "netops.deploy"},
+		Timeout: 2 * time.Second,
+	}
+	require.NoError(t, service.InitEvents(cfg))
+	service.PublishDeviceEvent(service.DeviceEvent{Type: service.EventTypeDeploy, TaskID: "d1", DeviceIP: "10.0.0.2", Status: service.EventStatusFailed})
+	service.CloseEvents()
+
+	select {
+	case p := <-pubs:
+		assert.True(t, strings.HasPrefix(p, "netops.deploy "), p)
+		assert.Contains(t, p, `"task_id":"d1"`)
+		assert.Contains(t, p, `"status":"failed"`)
+	case <-time.After(3 * time.Second):
+		t.Fatal("no message published")
+	}
+}
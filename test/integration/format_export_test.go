@@ -0,0 +1,238 @@
+package integration
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sshcollectorpro/sshcollectorpro/api/handler"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/config"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/service"
+	"github.com/sshcollectorpro/sshcollectorpro/simulate"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func zipEntries(t *testing.T, data []byte) map[string]string {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	require.NoError(t, err)
+	out := map[string]string{}
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		require.NoError(t, err)
+		b, err := io.ReadAll(rc)
+		rc.Close()
+		require.NoError(t, err)
+		out[f.Name] = string(b)
+	}
+	return out
+}
+
+// TestEncodeFormattedExport 按平台/命令展开：XLSX 多工作表，CSV 单表直出、多表打包 zip
+func TestEncodeFormattedExport(t *testing.T) {
+	parsed := func(recs ...map[string]interface{}) interface{} {
+		out := make([]interface{}, 0, len(recs))
+		for _, r := range recs {
+			out = append(out, r)
+		}
+		return map[string]interface{}{"parsed": out}
+	}
+	tables := []service.FormattedTable{
+		{Platform: "cisco_ios", Command: "show_version", Batches: []int{1, 2}, Items: []service.FormattedItem{
+			{DeviceName: "sw-01", InfoFormatted: parsed(map[string]interface{}{"VERSION": "15.2", "HOSTNAME": "sw-01"})},
+			{DeviceName: "sw-02", InfoFormatted: parsed(map[string]interface{}{"VERSION": "15.9", "SERIAL": []interface{}{"A1", "B2"}})},
+		}},
+		{Platform: "huawei", Command: "display_version", Batches: []int{1}, Items: []service.FormattedItem{
+			{DeviceName: "core-01", InfoFormatted: parsed(map[string]interface{}{"VRP_VERSION": "V200R019"})},
+		}},
+	}
+
+	one, err := service.EncodeFormattedExport("", "t1_formatted", tables[:1])
+	require.NoError(t, err)
+	assert.Equal(t, "t1_formatted.csv", one.Filename)
+	assert.Equal(t, 2, one.Rows)
+	rows, err := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(one.Data, []byte("\xEF\xBB\xBF")))).ReadAll()
+	require.NoError(t, err)
+	assert.Equal(t, [][]string{
+		{"device_name", "HOSTNAME", "SERIAL", "VERSION"},
+		{"sw-01", "sw-01", "", "15.2"},
+		{"sw-02", "", `["A1","B2"]`, "15.9"},
+	}, rows)
+
+	multi, err := service.EncodeFormattedExport("csv", "t1_formatted", tables)
+	require.NoError(t, err)
+	assert.Equal(t, "t1_formatted.zip", multi.Filename)
+	assert.Equal(t, "application/zip", multi.ContentType)
+	files := zipEntries(t, multi.Data)
+	assert.Contains(t, files, "cisco_ios/show_version.csv")
+	assert.Contains(t, files["huawei/display_version.csv"], "core-01,V200R019")
+
+	book, err := service.EncodeFormattedExport("XLSX", "t1_formatted", tables)
+	require.NoError(t, err)
+	assert.Equal(t, "t1_formatted.xlsx", book.Filename)
+	assert.Equal(t, 3, book.Rows)
+	// 复用导入解析读取工作簿：首个工作表为第一张表
+	sheet, err := service.ReadDeviceSheet(book.Filename, book.Data)
+	require.NoError(t, err)
+	require.Len(t, sheet, 3)
+	assert.Equal(t, []string{"device_name", "HOSTNAME", "SERIAL", "VERSION"}, sheet[0])
+	parts := zipEntries(t, book.Data)
+	assert.Contains(t, parts["xl/workbook.xml"], `name="cisco_ios-show_version"`)
+	assert.Contains(t, parts["xl/workbook.xml"], `name="huawei-display_version"`)
+	assert.Contains(t, parts["xl/worksheets/sheet2.xml"], "V200R019")
+
+	_, err = service.EncodeFormattedExport("json", "x", tables)
+	assert.ErrorIs(t, err, service.ErrValidation)
+}
+
+// TestFormatExportEndpointWithoutStorage 未配置 MinIO 时导出接口返回 503，参数错误返回 400
+func TestFormatExportEndpointWithoutStorage(t *testing.T) {
+	cfgPath := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(cfgPath, []byte("collector:\n  concurrent: 1\n"), 0o600))
+	cfg, err := config.Load(cfgPath)
+	require.NoError(t, err)
+	svc := service.NewFormatService(cfg)
+	require.NoError(t, svc.Start(context.Background()))
+	t.Cleanup(func() { svc.Stop() })
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(handler.ErrorMiddleware())
+	r.GET("/format/tasks/:task_id/export", handler.NewFormattedHandler(svc).ExportTask)
+
+	do := func(q string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/format/tasks/t1/export"+q, nil))
+		return w
+	}
+	w := do("?format=xlsx")
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code, w.Body.String())
+	w = do("?format=pdf")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = do("?batch=x")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.True(t, strings.Contains(w.Body.String(), "INVALID_PARAMS"))
+}
+
+// TestFormatBatchExportFormat 批量格式化附带 export_format：表格与 JSON 同目录同名写入对象存储，列顺序取模板变量定义顺序
+func TestFormatBatchExportFormat(t *testing.T) {
+	fake := &fakeS3{objects: map[string][]byte{}, ctypes: map[string]string{}}
+	srv := httptest.NewServer(fake)
+	t.Cleanup(srv.Close)
+	host, port, err := net.SplitHostPort(strings.TrimPrefix(srv.URL, "http://"))
+	require.NoError(t, err)
+
+	devPort := freePort(t)
+	mgr, err := simulate.Start(&simulate.Config{
+		Namespace:  map[string]simulate.NamespaceConfig{"t": {Port: devPort, IdleSeconds: 60, MaxConn: 10}},
+		DeviceType: map[string]simulate.DeviceTypeConfig{"cisco_ios": {PromptSuffix: "#"}},
+		DeviceName: map[string]simulate.DeviceNameConfig{"sw-01": {DeviceType: "cisco_ios"}},
+		Scenarios: []simulate.ScenarioConfig{
+			{DeviceName: "sw-01", Command: "show interfaces status", Responses: []simulate.ScenarioResponse{{Output: "Gi0/1 up\nGi0/2 down"}}},
+		},
+	})
+	require.NoError(t, err)
+	t.Cleanup(mgr.Stop)
+
+	cfgPath := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(cfgPath, []byte(`
+storage:
+  minio:
+    host: `+host+`
+    port: `+port+`
+    access_key: AKIDTEST
+    secret_key: secret
+    bucket: ssh-collector
+collector:
+  device_defaults:
+    cisco_ios:
+      prompt_suffixes: ["#"]
+      command_interval_ms: 10
+`), 0o600))
+	cfg, err := config.Load(cfgPath)
+	require.NoError(t, err)
+	svc := service.NewFormatService(cfg)
+	require.NoError(t, svc.Start(context.Background()))
+	t.Cleanup(func() { svc.Stop() })
+
+	// 变量定义顺序 STATUS 在前，与字母序相反
+	const tmpl = `Value STATUS (up|down)
+Value INTERFACE (\S+)
+
+Start
+  ^${INTERFACE}\s+${STATUS} -> Record
+`
+	run := func(exportFormat string) (*service.FormatBatchResponse, error) {
+		return svc.ExecuteBatch(context.Background(), &service.FormatBatchRequest{
+			TaskID:       "fmt-export",
+			TaskBatch:    2,
+			ExportFormat: exportFormat,
+			Devices: []service.FormatDevice{{
+				DeviceIP: "127.0.0.1", DevicePort: devPort, DeviceName: "sw-01", DevicePlatform: "cisco_ios",
+				UserName: "sw-01", Password: "nova", CliList: service.NewCLIList("show interfaces status"),
+			}},
+			FSMTemplates: []service.FSMTemplateDef{{DevicePlatform: "cisco_ios", TemplateValues: []service.FSMTemplateValue{
+				{CLIName: "show interfaces status", FSMValue: tmpl},
+			}}},
+		})
+	}
+	object := func(suffix string) ([]byte, string) {
+		fake.mu.Lock()
+		defer fake.mu.Unlock()
+		for k, v := range fake.objects {
+			if strings.HasSuffix(k, "/formatted/cisco_ios/show_interfaces_status/formatted_2"+suffix) {
+				return v, fake.ctypes[k]
+			}
+		}
+		return nil, ""
+	}
+	storedURIs := func(resp *service.FormatBatchResponse) []string {
+		var out []string
+		for _, so := range resp.Stored {
+			out = append(out, so.URI)
+		}
+		return out
+	}
+
+	_, err = run("pdf")
+	assert.ErrorIs(t, err, service.ErrValidation)
+
+	resp, err := run("CSV")
+	require.NoError(t, err)
+	data, ctype := object(".csv")
+	require.NotNil(t, data, "stored: %v", storedURIs(resp))
+	assert.Contains(t, ctype, "text/csv")
+	assert.True(t, bytes.HasPrefix(data, []byte("\xEF\xBB\xBF")), "CSV 带 UTF-8 BOM")
+	rows, err := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(data, []byte("\xEF\xBB\xBF")))).ReadAll()
+	require.NoError(t, err)
+	assert.Equal(t, [][]string{
+		{"device_name", "STATUS", "INTERFACE"},
+		{"sw-01", "up", "Gi0/1"},
+		{"sw-01", "down", "Gi0/2"},
+	}, rows)
+	jsonObj, _ := object(".json")
+	assert.NotNil(t, jsonObj, "JSON 照常写入")
+	assert.Len(t, resp.Stored, 2, "json + csv: %v", storedURIs(resp))
+
+	_, err = run("xlsx")
+	require.NoError(t, err)
+	data, _ = object(".xlsx")
+	require.NotNil(t, data)
+	sheet, err := service.ReadDeviceSheet("export.xlsx", data)
+	require.NoError(t, err)
+	assert.Equal(t, [][]string{
+		{"device_name", "STATUS", "INTERFACE"},
+		{"sw-01", "up", "Gi0/1"},
+		{"sw-01", "down", "Gi0/2"},
+	}, sheet)
+}
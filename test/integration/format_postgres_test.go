@@ -0,0 +1,266 @@
+package integration
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/sshcollectorpro/sshcollectorpro/internal/config"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/service"
+	"github.com/sshcollectorpro/sshcollectorpro/simulate"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakePostgres 最小 PostgreSQL 协议服务：接受简单查询与扩展协议（Parse/Bind/Execute），记录语句与插入参数
+type fakePostgres struct {
+	mu       sync.Mutex
+	queries  []string
+	inserts  [][]string
+	user     string
+	database string
+}
+
+func startFakePostgres(t *testing.T) (*fakePostgres, int) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { ln.Close() })
+	f := &fakePostgres{}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go f.serve(conn)
+		}
+	}()
+	return f, ln.Addr().(*net.TCPAddr).Port
+}
+
+func pgMsg(typ byte, body []byte) []byte {
+	out := []byte{typ, 0, 0, 0, 0}
+	binary.BigEndian.PutUint32(out[1:], uint32(len(body)+4))
+	return append(out, body...)
+}
+
+func pgCString(b []byte) (string, []byte) {
+	i := bytes.IndexByte(b, 0)
+	return string(b[:i]), b[i+1:]
+}
+
+func (f *fakePostgres) serve(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	var hdr [4]byte
+	if _, err := io.ReadFull(r, hdr[:]); err != nil {
+		return
+	}
+	startup := make([]byte, binary.BigEndian.Uint32(hdr[:])-4)
+	if _, err := io.ReadFull(r, startup); err != nil {
+		return
+	}
+	params := startup[4:]
+	for len(params) > 1 {
+		var k, v string
+		k, params = pgCString(params)
+		v, params = pgCString(params)
+		f.mu.Lock()
+		switch k {
+		case "user":
+			f.user = v
+		case "database":
+			f.database = v
+		}
+		f.mu.Unlock()
+	}
+	txStatus := byte('I')
+	ready := func() []byte { return pgMsg('Z', []byte{txStatus}) }
+	_, _ = conn.Write(append(pgMsg('R', []byte{0, 0, 0, 0}), ready()...))
+
+	stmts := map[string]string{}
+	var portal string
+	for {
+		typ, err := r.ReadByte()
+		if err != nil {
+			return
+		}
+		if _, err := io.ReadFull(r, hdr[:]); err != nil {
+			return
+		}
+		body := make([]byte, binary.BigEndian.Uint32(hdr[:])-4)
+		if _, err := io.ReadFull(r, body); err != nil {
+			return
+		}
+		var out []byte
+		switch typ {
+		case 'Q':
+			q, _ := pgCString(body)
+			f.mu.Lock()
+			f.queries = append(f.queries, q)
+			f.mu.Unlock()
+			fields := strings.Fields(strings.ToUpper(q))
+			tag := fields[0]
+			switch tag {
+			case "CREATE":
+				tag += " " + fields[1]
+			case "BEGIN":
+				txStatus = 'T'
+			case "COMMIT", "ROLLBACK":
+				txStatus = 'I'
+			}
+			out = append(pgMsg('C', append([]byte(tag), 0)), ready()...)
+		case 'P':
+			name, rest := pgCString(body)
+			q, _ := pgCString(rest)
+			stmts[name] = q
+			out = pgMsg('1', nil)
+		case 'D':
+			name, _ := pgCString(body[1:])
+			n := strings.Count(stmts[name], "$")
+			desc := make([]byte, 2+4*n)
+			binary.BigEndian.PutUint16(desc, uint16(n))
+			for i := 0; i < n; i++ {
+				binary.BigEndian.PutUint32(desc[2+4*i:], 25) // text
+			}
+			out = append(pgMsg('t', desc), pgMsg('n', nil)...)
+		case 'B':
+			_, rest := pgCString(body)
+			name, rest := pgCString(rest)
+			portal = stmts[name]
+			nf := int(binary.BigEndian.Uint16(rest))
+			rest = rest[2+2*nf:]
+			np := int(binary.BigEndian.Uint16(rest))
+			rest = rest[2:]
+			vals := make([]string, 0, np)
+			for i := 0; i < np; i++ {
+				l := int32(binary.BigEndian.Uint32(rest))
+				rest = rest[4:]
+				if l < 0 {
+					vals = append(vals, "")
+					continue
+				}
+				vals = append(vals, string(rest[:l]))
+				rest = rest[l:]
+			}
+			if strings.HasPrefix(portal, "INSERT") {
+				f.mu.Lock()
+				f.inserts = append(f.inserts, vals)
+				f.mu.Unlock()
+			}
+			out = pgMsg('2', nil)
+		case 'E':
+			tag := "SELECT 0"
+			if strings.HasPrefix(portal, "INSERT") {
+				tag = "INSERT 0 1"
+			}
+			out = pgMsg('C', append([]byte(tag), 0))
+		case 'C':
+			out = pgMsg('3', nil)
+		case 'S':
+			out = ready()
+		case 'X':
+			return
+		}
+		if len(out) > 0 {
+			if _, err := conn.Write(out); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// TestFormatBatchWritesPostgres 批量格式化的解析记录逐条写入 PostgreSQL：建表建索引、单事务插入、payload 为记录 JSON
+func TestFormatBatchWritesPostgres(t *testing.T) {
+	pg, pgPort := startFakePostgres(t)
+	port := freePort(t)
+	mgr, err := simulate.Start(&simulate.Config{
+		Namespace:  map[string]simulate.NamespaceConfig{"t": {Port: port, IdleSeconds: 60, MaxConn: 10}},
+		DeviceType: map[string]simulate.DeviceTypeConfig{"cisco_ios": {PromptSuffix: "#"}},
+		DeviceName: map[string]simulate.DeviceNameConfig{"sw-01": {DeviceType: "cisco_ios"}},
+		Scenarios: []simulate.ScenarioConfig{
+			{DeviceName: "sw-01", Command: "show interfaces status", Responses: []simulate.ScenarioResponse{{Output: "Gi0/1 up\nGi0/2 down"}}},
+		},
+	})
+	require.NoError(t, err)
+	t.Cleanup(mgr.Stop)
+
+	format := func(table string) *service.FormatBatchResponse {
+		cfgPath := filepath.Join(t.TempDir(), "config.yaml")
+		require.NoError(t, os.WriteFile(cfgPath, []byte(`
+storage:
+  postgres:
+    enable: true
+    host: 127.0.0.1
+    port: `+strconv.Itoa(pgPort)+`
+    username: nova
+    database: netops
+    table: `+table+`
+collector:
+  device_defaults:
+    cisco_ios:
+      prompt_suffixes: ["#"]
+      command_interval_ms: 10
+`), 0o600))
+		cfg, err := config.Load(cfgPath)
+		require.NoError(t, err)
+		svc := service.NewFormatService(cfg)
+		require.NoError(t, svc.Start(context.Background()))
+		defer svc.Stop()
+		resp, err := svc.ExecuteBatch(context.Background(), &service.FormatBatchRequest{
+			TaskID:    "fmt-pg",
+			TaskBatch: 3,
+			Devices: []service.FormatDevice{{
+				DeviceIP: "127.0.0.1", DevicePort: port, DeviceName: "sw-01", DevicePlatform: "cisco_ios",
+				UserName: "sw-01", Password: "nova", CliList: service.NewCLIList("show interfaces status"),
+			}},
+			FSMTemplates: []service.FSMTemplateDef{{DevicePlatform: "cisco_ios", TemplateValues: []service.FSMTemplateValue{
+				{CLIName: "show interfaces status", FSMValue: impactCurrentTemplate},
+			}}},
+		})
+		require.NoError(t, err)
+		return resp
+	}
+
+	resp := format("netops.if_status")
+	assert.Empty(t, resp.PostgresError)
+	assert.Equal(t, 2, resp.PostgresRows)
+
+	pg.mu.Lock()
+	assert.Equal(t, "nova", pg.user)
+	assert.Equal(t, "netops", pg.database)
+	all := strings.Join(pg.queries, "\n")
+	assert.Contains(t, all, "CREATE TABLE IF NOT EXISTS netops.if_status")
+	assert.Contains(t, all, "idx_netops_if_status_device_cli")
+	assert.Contains(t, all, "BEGIN")
+	assert.Contains(t, all, "COMMIT")
+	require.Len(t, pg.inserts, 2)
+	interfaces := map[string]string{}
+	for _, row := range pg.inserts {
+		require.Len(t, row, 8)
+		assert.Equal(t, []string{"fmt-pg", "3", "sw-01", "127.0.0.1", "cisco_ios", "show interfaces status"}, row[:6])
+		var rec map[string]interface{}
+		require.NoError(t, json.Unmarshal([]byte(row[6]), &rec))
+		interfaces[rec["INTERFACE"].(string)] = rec["STATUS"].(string)
+	}
+	assert.Equal(t, map[string]string{"Gi0/1": "up", "Gi0/2": "down"}, interfaces)
+	pg.mu.Unlock()
+
+	// 非法表名不执行任何语句，错误随响应返回
+	resp = format("if_status; DROP TABLE x")
+	assert.Contains(t, resp.PostgresError, "invalid postgres table name")
+	assert.Zero(t, resp.PostgresRows)
+	pg.mu.Lock()
+	assert.NotContains(t, strings.Join(pg.queries, "\n"), "DROP")
+	pg.mu.Unlock()
+}
@@ -0,0 +1,248 @@
+package integration
+
+import (
+	"context"
+	"net"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sshcollectorpro/sshcollectorpro/api/handler"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/config"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/database"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/service"
+	"github.com/sshcollectorpro/sshcollectorpro/simulate"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// freshnessFixture 模拟设备（show version 依次回显 v1、v2、v3…）、本地备份存储、MinIO 与结果索引
+type freshnessFixture struct {
+	port      int
+	backup    *service.BackupService
+	collector *service.CollectorService
+	format    *service.FormatService
+	s3        *fakeS3
+}
+
+func newFreshnessFixture(t *testing.T) *freshnessFixture {
+	fake := &fakeS3{objects: map[string][]byte{}, ctypes: map[string]string{}}
+	srv := httptest.NewServer(fake)
+	t.Cleanup(srv.Close)
+	host, s3Port, err := net.SplitHostPort(strings.TrimPrefix(srv.URL, "http://"))
+	require.NoError(t, err)
+
+	port := freePort(t)
+	mgr, err := simulate.Start(&simulate.Config{
+		Namespace:  map[string]simulate.NamespaceConfig{"t": {Port: port, IdleSeconds: 60, MaxConn: 10}},
+		DeviceType: map[string]simulate.DeviceTypeConfig{"cisco_ios": {PromptSuffix: "#"}},
+		DeviceName: map[string]simulate.DeviceNameConfig{"sw-01": {DeviceType: "cisco_ios"}},
+		Scenarios: []simulate.ScenarioConfig{
+			{DeviceName: "sw-01", Command: "show version", Responses: []simulate.ScenarioResponse{
+				{Output: "Version v1"}, {Output: "Version v2"}, {Output: "Version v3"}, {Output: "Version v4"},
+			}},
+			{DeviceName: "sw-01", Command: "show clock", Responses: []simulate.ScenarioResponse{{Output: "12:00:00 UTC"}}},
+		},
+	})
+	require.NoError(t, err)
+	t.Cleanup(mgr.Stop)
+
+	dir := t.TempDir()
+	cfgPath := filepath.Join(dir, "config.yaml")
+	require.NoError(t, os.WriteFile(cfgPath, []byte(`
+backup:
+  storage_backend: local
+  local:
+    base_dir: `+filepath.Join(dir, "backups")+`
+    mkdir_if_missing: true
+storage:
+  minio:
+    host: `+host+`
+    port: `+s3Port+`
+    access_key: AKIDTEST
+    secret_key: secret
+    bucket: ssh-collector
+collector:
+  device_defaults:
+    cisco_ios:
+      prompt_suffixes: ["#"]
+      command_interval_ms: 10
+`), 0o600))
+	cfg, err := config.Load(cfgPath)
+	require.NoError(t, err)
+	require.NoError(t, database.InitSQLite(config.SQLiteConfig{Path: filepath.Join(dir, "collector.db")}))
+	t.Cleanup(func() { database.Close() })
+
+	snapshots := handler.NewSnapshotIndex()
+	backup := service.NewBackupService(cfg)
+	backup.SetResultIndex(snapshots)
+	require.NoError(t, backup.Start(context.Background()))
+	t.Cleanup(func() { backup.Stop() })
+
+	collector := service.NewCollectorService(cfg)
+	collector.SetStorageWriter(backup.StorageWriter())
+	collector.SetResultIndex(snapshots)
+	require.NoError(t, collector.Start(context.Background()))
+	t.Cleanup(func() { collector.Stop() })
+
+	format := service.NewFormatService(cfg)
+	format.SetResultIndex(snapshots, backup.StorageReader())
+	require.NoError(t, format.Start(context.Background()))
+	t.Cleanup(func() { format.Stop() })
+
+	return &freshnessFixture{port: port, backup: backup, collector: collector, format: format, s3: fake}
+}
+
+func (f *freshnessFixture) collect(t *testing.T, taskID string, store bool, freshTTL int, cmds ...string) *service.CollectResponse {
+	retry := 0
+	resp, err := f.collector.ExecuteTask(context.Background(), &service.CollectRequest{
+		TaskID: taskID, DeviceIP: "127.0.0.1", Port: f.port, DeviceName: "sw-01", DevicePlatform: "cisco_ios",
+		UserName: "sw-01", Password: "nova", CliList: service.NewCLIList(cmds...), RetryFlag: &retry,
+		Store: store, FreshTTL: freshTTL,
+	})
+	require.NoError(t, err)
+	require.True(t, resp.Success, resp.Error)
+	return resp
+}
+
+// commandResult 按命令取采集结果
+func commandResult(t *testing.T, resp *service.CollectResponse, cmd string) *service.CommandResultView {
+	t.Helper()
+	for _, r := range resp.Results {
+		if r.Command == cmd {
+			return r
+		}
+	}
+	t.Fatalf("command %q not in results", cmd)
+	return nil
+}
+
+// TestCollectFreshTTL 采集落盘后记录结果；fresh_ttl 内的命令引用缓存对象，不再登录设备执行
+func TestCollectFreshTTL(t *testing.T) {
+	f := newFreshnessFixture(t)
+
+	first := f.collect(t, "col-fresh-1", true, 300, "show version")
+	v := commandResult(t, first, "show version")
+	assert.Contains(t, v.RawOutput, "Version v1")
+	require.NotEmpty(t, v.StoredObjects)
+	assert.Zero(t, first.SkippedFresh)
+
+	// 全部命令新鲜：不登录设备，结果引用第一次的落盘对象
+	second := f.collect(t, "col-fresh-2", true, 300, "show version")
+	assert.Equal(t, 1, second.SkippedFresh)
+	require.Len(t, second.Results, 1)
+	cached := second.Results[0]
+	assert.True(t, cached.SkippedFresh)
+	require.NotNil(t, cached.CollectedAt)
+	require.Len(t, cached.StoredObjects, 1)
+	assert.Equal(t, v.StoredObjects[0].URI, cached.StoredObjects[0].URI)
+	assert.Empty(t, cached.RawOutput)
+
+	// 部分新鲜：仅执行未记录的命令
+	mixed := f.collect(t, "col-fresh-3", true, 300, "show version", "show clock")
+	assert.Equal(t, 1, mixed.SkippedFresh)
+	assert.True(t, commandResult(t, mixed, "show version").SkippedFresh)
+	clock := commandResult(t, mixed, "show clock")
+	assert.False(t, clock.SkippedFresh)
+	assert.Contains(t, clock.RawOutput, "12:00:00 UTC")
+
+	// 未开启 store 时不跳过；未设置 fresh_ttl 时始终执行。设备此前只执行过一次 show version
+	plain := f.collect(t, "col-fresh-4", false, 300, "show version")
+	assert.Zero(t, plain.SkippedFresh)
+	assert.Contains(t, commandResult(t, plain, "show version").RawOutput, "Version v2")
+	again := f.collect(t, "col-fresh-5", true, 0, "show version")
+	assert.Contains(t, commandResult(t, again, "show version").RawOutput, "Version v3")
+
+	// 命令级 fresh_ttl 优先于任务级
+	list := service.NewCLIList("show version")
+	list[0].FreshTTL = 300
+	retry := 0
+	resp, err := f.collector.ExecuteTask(context.Background(), &service.CollectRequest{
+		TaskID: "col-fresh-6", DeviceIP: "127.0.0.1", Port: f.port, DeviceName: "sw-01", DevicePlatform: "cisco_ios",
+		UserName: "sw-01", Password: "nova", CliList: list, RetryFlag: &retry, Store: true,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 1, resp.SkippedFresh)
+}
+
+// TestFreshTTLSharedAcrossServices 备份记录的结果可被格式化复用（读取缓存对象解析）；格式化写入的原始数据同样记录
+func TestFreshTTLSharedAcrossServices(t *testing.T) {
+	f := newFreshnessFixture(t)
+	retry, timeout := 0, 10
+
+	bk, err := f.backup.ExecuteBatch(context.Background(), &service.BackupBatchRequest{
+		TaskID: "bk-fresh", RetryFlag: &retry, TaskTimeout: &timeout,
+		Devices: []service.BackupDevice{{DeviceIP: "127.0.0.1", Port: f.port, DeviceName: "sw-01", DevicePlatform: "cisco_ios",
+			UserName: "sw-01", Password: "nova", CliList: service.NewCLIList("show version")}},
+	})
+	require.NoError(t, err)
+	require.Len(t, bk.Data, 1)
+	require.True(t, bk.Data[0].Success, bk.Data[0].Error)
+
+	const tmpl = `Value VERSION (v\d+)
+
+Start
+  ^Version ${VERSION} -> Record
+`
+	runFormat := func(taskID string, freshTTL int) (*service.FormatBatchResponse, string) {
+		resp, err := f.format.ExecuteBatch(context.Background(), &service.FormatBatchRequest{
+			TaskID:   taskID,
+			FreshTTL: freshTTL,
+			Devices: []service.FormatDevice{{
+				DeviceIP: "127.0.0.1", DevicePort: f.port, DeviceName: "sw-01", DevicePlatform: "cisco_ios",
+				UserName: "sw-01", Password: "nova", CliList: service.NewCLIList("show version"),
+			}},
+			FSMTemplates: []service.FSMTemplateDef{{DevicePlatform: "cisco_ios", TemplateValues: []service.FSMTemplateValue{
+				{CLIName: "show version", FSMValue: tmpl},
+			}}},
+		})
+		require.NoError(t, err)
+		require.Equal(t, 1, resp.Stats.FullySuccess, "login: %v collect: %v", resp.LoginFailures, resp.CollectFailures)
+		f.s3.mu.Lock()
+		defer f.s3.mu.Unlock()
+		for k, v := range f.s3.objects {
+			if strings.Contains(k, "/"+taskID+"/formatted/") && strings.HasSuffix(k, ".json") {
+				return resp, string(v)
+			}
+		}
+		t.Fatalf("formatted JSON of %s not stored", taskID)
+		return nil, ""
+	}
+	rawObjects := func(taskID string) int {
+		f.s3.mu.Lock()
+		defer f.s3.mu.Unlock()
+		n := 0
+		for k := range f.s3.objects {
+			if strings.Contains(k, "/"+taskID+"/raw/") {
+				n++
+			}
+		}
+		return n
+	}
+
+	// 备份结果新鲜：读取本地备份对象解析，不登录设备、不重复写原始数据
+	resp, formatted := runFormat("fmt-fresh-1", 300)
+	require.Len(t, resp.SkippedFresh, 1)
+	assert.Equal(t, 1, resp.SkippedFresh[0].SkippedFresh)
+	assert.Equal(t, []string{"show version"}, resp.SkippedFresh[0].Commands)
+	assert.Contains(t, formatted, `"VERSION": "v1"`)
+	assert.Zero(t, rawObjects("fmt-fresh-1"))
+
+	// 不设置 fresh_ttl：执行并写入原始数据（第二次调用设备），记录覆盖为格式化的 MinIO 对象
+	resp, formatted = runFormat("fmt-fresh-2", 0)
+	assert.Empty(t, resp.SkippedFresh)
+	assert.Contains(t, formatted, `"VERSION": "v2"`)
+	assert.Equal(t, 1, rawObjects("fmt-fresh-2"))
+
+	// 读取格式化写入的 MinIO 原始数据复用
+	resp, formatted = runFormat("fmt-fresh-3", 300)
+	require.Len(t, resp.SkippedFresh, 1)
+	assert.Contains(t, formatted, `"VERSION": "v2"`)
+
+	// 采集同样复用格式化记录的对象
+	col := f.collect(t, "col-after-fmt", true, 300, "show version")
+	assert.Equal(t, 1, col.SkippedFresh)
+	assert.True(t, strings.HasPrefix(col.Results[0].StoredObjects[0].URI, "minio://ssh-collector/"), col.Results[0].StoredObjects[0].URI)
+}
@@ -0,0 +1,51 @@
+package integration
+
+import (
+	"testing"
+
+	"github.com/sshcollectorpro/sshcollectorpro/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const enginesRunningConfig = `interface GigabitEthernet0/1
+ description uplink to core
+ ip address 10.0.0.1 255.255.255.0
+!
+interface GigabitEthernet0/2
+ shutdown
+!
+interface Loopback0
+ ip address 1.1.1.1 255.255.255.255
+`
+
+// TestTTPTemplate 组内首行开始新记录，其余行填充字段；未出现的字段不输出
+func TestTTPTemplate(t *testing.T) {
+	tpl := `<group name="interfaces">
+interface {{ interface }}
+ description {{ description | ORPHRASE }}
+ ip address {{ ip | IP }} {{ mask | IP }}
+</group>`
+	recs, err := service.ParseWithTemplateType("ttp", tpl, enginesRunningConfig)
+	require.NoError(t, err)
+	require.Len(t, recs, 3)
+	assert.Equal(t, map[string]interface{}{"interface": "GigabitEthernet0/1", "description": "uplink to core", "ip": "10.0.0.1", "mask": "255.255.255.0"}, recs[0])
+	assert.Equal(t, map[string]interface{}{"interface": "GigabitEthernet0/2"}, recs[1])
+	assert.Equal(t, "1.1.1.1", recs[2]["ip"])
+
+	_, err = service.ParseWithTemplateType("ttp", "interface\n", enginesRunningConfig)
+	assert.Error(t, err)
+}
+
+// TestNamedRegexTemplate 整个模板为一条多行正则，每个匹配一条记录
+func TestNamedRegexTemplate(t *testing.T) {
+	recs, err := service.ParseWithTemplateType("regex", `^interface (?P<interface>\S+)\n ip address (?P<ip>\S+)`, enginesRunningConfig)
+	require.NoError(t, err)
+	require.Len(t, recs, 1)
+	assert.Equal(t, map[string]interface{}{"interface": "Loopback0", "ip": "1.1.1.1"}, recs[0])
+
+	_, err = service.ParseWithTemplateType("regex", `^interface (\S+)`, enginesRunningConfig)
+	assert.Error(t, err, "regex without named groups")
+	_, err = service.ParseWithTemplateType("jinja", "x", enginesRunningConfig)
+	assert.Error(t, err)
+}
@@ -0,0 +1,107 @@
+package integration
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sshcollectorpro/sshcollectorpro/api/handler"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/config"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/database"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const impactCurrentTemplate = `Value INTERFACE (\S+)
+Value STATUS (up|down)
+
+Start
+  ^${INTERFACE}\s+${STATUS} -> Record
+`
+
+const impactCandidateTemplate = `Value INTERFACE (\S+)
+Value STATUS (up|down|admin-down)
+Value SPEED (\d+)
+
+Start
+  ^${INTERFACE}\s+${STATUS}\s+${SPEED} -> Record
+`
+
+// TestTemplateImpact 新旧模板在历史原始输出上的记录数与字段差异
+func TestTemplateImpact(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, database.InitSQLite(config.SQLiteConfig{Path: filepath.Join(dir, "collector.db")}))
+	defer database.Close()
+	db := database.GetDB()
+
+	tplPath := filepath.Join(dir, "cisco_ios_show_interface_brief.textfsm")
+	require.NoError(t, os.WriteFile(tplPath, []byte(impactCurrentTemplate), 0o644))
+	tpl := model.FSMTemplate{Platform: "cisco_ios", Name: "cisco_ios_show_interface_brief.textfsm", Command: "show interface brief",
+		CommandPattern: "sh[[ow]] int[[erface]] br[[ief]]", FilePath: tplPath}
+	require.NoError(t, db.Create(&tpl).Error)
+
+	result := func(cmd, raw string) string {
+		b, _ := json.Marshal([]map[string]string{{"command": cmd, "raw_output": raw}})
+		return string(b)
+	}
+	now := time.Now()
+	for i, task := range []model.Task{
+		{ID: "t1", DeviceIP: "10.0.0.1", Metadata: `{"device_platform":"cisco_ios"}`, Result: result("sh int br", "Gi0/1 up 1000\nGi0/2 down 100\nGi0/3 admin-down 100\n")},
+		{ID: "t2", DeviceIP: "10.0.0.2", Metadata: `{"device_platform":"cisco_ios"}`, Result: result("show interface brief", "Gi0/1 up 1000\n")},
+		{ID: "t3", DeviceIP: "10.0.0.3", Metadata: `{"device_platform":"huawei_vrp"}`, Result: result("show interface brief", "Gi0/1 up 1000\n")},
+		{ID: "t4", DeviceIP: "10.0.0.4", Metadata: `{"device_platform":"cisco_ios"}`, Result: result("show version", "IOS 15.2\n")},
+	} {
+		task.CollectorID, task.Type, task.Username, task.Password, task.Commands = "c", model.TaskTypeSimple, "u", "p", "x"
+		task.Status = model.TaskStatusSuccess
+		task.CreatedAt = now.Add(time.Duration(i) * time.Second)
+		require.NoError(t, db.Create(&task).Error)
+	}
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/templates/:id/impact", handler.NewTemplateHandler().TemplateImpact)
+	body, _ := json.Marshal(map[string]interface{}{"content": impactCandidateTemplate, "samples": 5})
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/templates/1/impact", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	var resp struct {
+		Data struct {
+			Impact struct {
+				Samples          int            `json:"samples"`
+				Changed          int            `json:"changed"`
+				CurrentRecords   int            `json:"current_records"`
+				CandidateRecords int            `json:"candidate_records"`
+				AddedFields      []string       `json:"added_fields"`
+				ChangedFields    map[string]int `json:"changed_fields"`
+				Details          []struct {
+					TaskID           string `json:"task_id"`
+					CurrentRecords   int    `json:"current_records"`
+					CandidateRecords int    `json:"candidate_records"`
+					Changed          bool   `json:"changed"`
+				} `json:"details"`
+			} `json:"impact"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	impact := resp.Data.Impact
+	// 仅 cisco_ios 且命中命令（含缩写）的两条任务，最新在前
+	require.Equal(t, 2, impact.Samples)
+	assert.Equal(t, "t2", impact.Details[0].TaskID)
+	assert.Equal(t, "t1", impact.Details[1].TaskID)
+	assert.Equal(t, 2, impact.Details[1].CurrentRecords)
+	assert.Equal(t, 3, impact.Details[1].CandidateRecords)
+	assert.Equal(t, []string{"SPEED"}, impact.AddedFields)
+	assert.Equal(t, 2, impact.Changed)
+	assert.Equal(t, 3, impact.CurrentRecords)
+	assert.Equal(t, 4, impact.CandidateRecords)
+}
@@ -0,0 +1,50 @@
+package integration
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/sshcollectorpro/sshcollectorpro/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestFSMTemplateCacheConcurrentParse 同一模板并发解析结果一致，且只编译一次
+func TestFSMTemplateCacheConcurrentParse(t *testing.T) {
+	// 模板内容带唯一标记，避免与其他用例共享缓存条目
+	tpl := fmt.Sprintf("Value INTERFACE (\\S+)\nValue STATUS (up|down)\n\nStart\n  ^# cache-test-%s\n  ^${INTERFACE}\\s+${STATUS} -> Record\n", t.Name())
+	raw := "Gi0/1 up\nGi0/2 down\nGi0/3 up\n"
+
+	before := service.CurrentFSMCacheStats()
+	var wg sync.WaitGroup
+	results := make([][]map[string]interface{}, 16)
+	errs := make([]error, len(results))
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i], errs[i] = service.ParseWithTemplate(tpl, raw)
+		}(i)
+	}
+	wg.Wait()
+
+	for i := range results {
+		require.NoError(t, errs[i])
+		require.Len(t, results[i], 3)
+		assert.Equal(t, "Gi0/2", results[i][1]["INTERFACE"])
+		assert.Equal(t, "down", results[i][1]["STATUS"])
+	}
+
+	after := service.CurrentFSMCacheStats()
+	assert.Equal(t, before.Size+1, after.Size)
+	assert.Equal(t, uint64(len(results)), (after.Hits-before.Hits)+(after.Misses-before.Misses))
+
+	// 再次解析直接命中缓存
+	recs, err := service.ParseWithTemplate(tpl, raw)
+	require.NoError(t, err)
+	assert.Len(t, recs, 3)
+	final := service.CurrentFSMCacheStats()
+	assert.Equal(t, after.Hits+1, final.Hits)
+	assert.Equal(t, after.Misses, final.Misses)
+}
@@ -0,0 +1,251 @@
+package integration
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sshcollectorpro/sshcollectorpro/api/handler"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/config"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/database"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/service"
+	"github.com/sshcollectorpro/sshcollectorpro/simulate"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// parserFixture 模拟设备、格式化服务、外部解析登记接口与可切换行为的外部解析服务
+type parserFixture struct {
+	port   int
+	svc    *service.FormatService
+	router *gin.Engine
+	calls  atomic.Int32
+	// mode 外部服务行为：ok | slow | fail
+	mode atomic.Value
+}
+
+func setupParserFixture(t *testing.T) (*parserFixture, *httptest.Server) {
+	f := &parserFixture{port: freePort(t)}
+	f.mode.Store("ok")
+	mgr, err := simulate.Start(&simulate.Config{
+		Namespace:  map[string]simulate.NamespaceConfig{"t": {Port: f.port, IdleSeconds: 60, MaxConn: 10}},
+		DeviceType: map[string]simulate.DeviceTypeConfig{"cisco_ios": {PromptSuffix: "#"}},
+		DeviceName: map[string]simulate.DeviceNameConfig{"sw-01": {DeviceType: "cisco_ios"}},
+		Scenarios: []simulate.ScenarioConfig{
+			{DeviceName: "sw-01", Command: "show interfaces status", Responses: []simulate.ScenarioResponse{{Output: "Gi0/1 up\nGi0/2 down"}}},
+		},
+	})
+	require.NoError(t, err)
+	t.Cleanup(mgr.Stop)
+
+	ext := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		f.calls.Add(1)
+		body, _ := io.ReadAll(r.Body)
+		switch f.mode.Load().(string) {
+		case "slow":
+			select {
+			case <-r.Context().Done():
+			case <-time.After(2 * time.Second):
+			}
+			return
+		case "fail":
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		assert.Equal(t, "cisco_ios", r.Header.Get("X-Device-Platform"))
+		assert.Equal(t, "show interfaces status", r.Header.Get("X-Command"))
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = fmt.Fprintf(w, `{"parsed":[{"source":"external","bytes":%d}]}`, len(body))
+	}))
+	t.Cleanup(ext.Close)
+
+	dir := t.TempDir()
+	cfgPath := filepath.Join(dir, "config.yaml")
+	require.NoError(t, os.WriteFile(cfgPath, []byte(`
+data_format:
+  template_dir: `+filepath.Join(dir, "templates")+`
+  external_parser:
+    timeout: 200ms
+    retries: 0
+    failure_threshold: 2
+    cooldown: 400ms
+collector:
+  device_defaults:
+    cisco_ios:
+      prompt_suffixes: ["#"]
+      command_interval_ms: 10
+`), 0o600))
+	cfg, err := config.Load(cfgPath)
+	require.NoError(t, err)
+	require.NoError(t, database.InitSQLite(config.SQLiteConfig{Path: filepath.Join(dir, "collector.db")}))
+	t.Cleanup(func() { database.Close() })
+
+	f.svc = service.NewFormatService(cfg)
+	f.svc.SetTemplateStore(handler.NewTemplateRepository())
+	require.NoError(t, f.svc.Start(context.Background()))
+	t.Cleanup(func() { f.svc.Stop() })
+
+	gin.SetMode(gin.TestMode)
+	f.router = gin.New()
+	h := handler.NewTemplateHandler()
+	f.router.GET("/parsers", h.ListParserHooks)
+	f.router.POST("/parsers", h.CreateParserHook)
+	f.router.PUT("/parsers/:id", h.UpdateParserHook)
+	f.router.DELETE("/parsers/:id", h.DeleteParserHook)
+	return f, ext
+}
+
+func (f *parserFixture) do(t *testing.T, method, path string, body interface{}) (int, map[string]interface{}) {
+	var rd io.Reader
+	if body != nil {
+		b, _ := json.Marshal(body)
+		rd = bytes.NewReader(b)
+	}
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(method, path, rd)
+	req.Header.Set("Content-Type", "application/json")
+	f.router.ServeHTTP(w, req)
+	var out map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &out), w.Body.String())
+	return w.Code, out
+}
+
+// format 快速格式化单条命令，请求中附带本地模板用于回退
+func (f *parserFixture) format(t *testing.T) string {
+	resp, err := f.svc.ExecuteFast(context.Background(), &service.FormatFastRequest{
+		TaskID: "fmt-ext",
+		Device: []service.FormatFastDevice{{
+			DeviceIP: "127.0.0.1", DevicePort: f.port, DeviceName: "sw-01", DevicePlatform: "cisco_ios",
+			UserName: "sw-01", Password: "nova", Cli: "show interfaces status",
+		}},
+		FSMTemplates: []service.FSMTemplateDef{{DevicePlatform: "cisco_ios", TemplateValues: []service.FSMTemplateValue{
+			{CLIName: "show interfaces status", FSMValue: impactCurrentTemplate},
+		}}},
+	})
+	require.NoError(t, err)
+	b, _ := json.Marshal(resp.Formatted["show interfaces status"])
+	var out map[string][]map[string]interface{}
+	require.NoError(t, json.Unmarshal(b, &out))
+	require.NotEmpty(t, out["parsed"])
+	if out["parsed"][0]["source"] == "external" {
+		return "external"
+	}
+	return "local"
+}
+
+// TestParserHookCRUD 外部解析登记：校验地址、同平台同命令冲突、更新与删除
+func TestParserHookCRUD(t *testing.T) {
+	f, ext := setupParserFixture(t)
+
+	code, _ := f.do(t, http.MethodPost, "/parsers", map[string]interface{}{"platform": "cisco_ios", "command": "show version", "url": "ftp://x"})
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	code, body := f.do(t, http.MethodPost, "/parsers", map[string]interface{}{"platform": "Cisco_IOS", "command": "Show Version", "url": ext.URL})
+	require.Equal(t, http.StatusCreated, code, body)
+	hook := body["data"].(map[string]interface{})
+	assert.Equal(t, "cisco_ios", hook["platform"])
+	assert.Equal(t, "show version", hook["command"])
+
+	code, body = f.do(t, http.MethodPost, "/parsers", map[string]interface{}{"platform": "cisco_ios", "command": "show version", "url": ext.URL})
+	assert.Equal(t, http.StatusConflict, code)
+	assert.Equal(t, "PARSER_EXISTS", body["code"])
+
+	id := fmt.Sprint(hook["id"])
+	code, _ = f.do(t, http.MethodPut, "/parsers/"+id, map[string]interface{}{"url": "not a url"})
+	assert.Equal(t, http.StatusBadRequest, code)
+	code, _ = f.do(t, http.MethodPut, "/parsers/"+id, map[string]interface{}{"enabled": false})
+	assert.Equal(t, http.StatusOK, code)
+	_, found := handler.NewTemplateRepository().LookupParser("cisco_ios", "show version")
+	assert.False(t, found, "停用的登记不参与解析")
+
+	code, body = f.do(t, http.MethodGet, "/parsers?platform=cisco_ios", nil)
+	assert.Equal(t, http.StatusOK, code)
+	assert.EqualValues(t, 1, body["total"])
+
+	code, _ = f.do(t, http.MethodDelete, "/parsers/"+id, nil)
+	assert.Equal(t, http.StatusOK, code)
+	code, body = f.do(t, http.MethodDelete, "/parsers/"+id, nil)
+	assert.Equal(t, http.StatusNotFound, code)
+	assert.Equal(t, "PARSER_NOT_FOUND", body["code"])
+}
+
+// TestExternalParserTimeoutBreakerAndFallback 外部解析成功时使用其结果；超时与 5xx 回退本地模板；
+// 连续失败熔断后不再调用外部服务，冷却结束放行试探，成功即恢复；禁止回退时解析为空
+func TestExternalParserTimeoutBreakerAndFallback(t *testing.T) {
+	f, ext := setupParserFixture(t)
+	code, body := f.do(t, http.MethodPost, "/parsers", map[string]interface{}{"platform": "cisco_ios", "command": "show interfaces status", "url": ext.URL})
+	require.Equal(t, http.StatusCreated, code, body)
+	id := fmt.Sprint(body["data"].(map[string]interface{})["id"])
+
+	// 正常：使用外部解析结果
+	assert.Equal(t, "external", f.format(t))
+	assert.Equal(t, int32(1), f.calls.Load())
+
+	// 超时：按 external_parser.timeout 放弃并回退本地模板（第 1 次失败）
+	f.mode.Store("slow")
+	start := time.Now()
+	assert.Equal(t, "local", f.format(t))
+	assert.Less(t, time.Since(start), 2*time.Second)
+	assert.Equal(t, int32(2), f.calls.Load())
+
+	// 5xx：第 2 次失败，达到阈值后熔断
+	f.mode.Store("fail")
+	assert.Equal(t, "local", f.format(t))
+	assert.Equal(t, int32(3), f.calls.Load())
+
+	// 熔断期间：即使外部服务已恢复也不调用，直接回退
+	f.mode.Store("ok")
+	assert.Equal(t, "local", f.format(t))
+	assert.Equal(t, int32(3), f.calls.Load())
+
+	// 冷却结束（半开）：放行试探请求，成功后恢复
+	time.Sleep(450 * time.Millisecond)
+	assert.Equal(t, "external", f.format(t))
+	assert.Equal(t, int32(4), f.calls.Load())
+	assert.Equal(t, "external", f.format(t))
+	assert.Equal(t, int32(5), f.calls.Load())
+
+	// 半开试探失败：立即重新熔断
+	f.mode.Store("fail")
+	assert.Equal(t, "local", f.format(t))
+	assert.Equal(t, "local", f.format(t))
+	assert.Equal(t, int32(7), f.calls.Load())
+	f.mode.Store("ok")
+	assert.Equal(t, "local", f.format(t))
+	assert.Equal(t, int32(7), f.calls.Load())
+	time.Sleep(450 * time.Millisecond)
+	f.mode.Store("fail")
+	assert.Equal(t, "local", f.format(t))
+	assert.Equal(t, int32(8), f.calls.Load())
+	assert.Equal(t, "local", f.format(t))
+	assert.Equal(t, int32(8), f.calls.Load(), "试探失败后重新熔断")
+
+	// 禁止回退：外部解析失败时不使用本地模板
+	time.Sleep(450 * time.Millisecond)
+	code, _ = f.do(t, http.MethodPut, "/parsers/"+id, map[string]interface{}{"no_fallback": true})
+	require.Equal(t, http.StatusOK, code)
+	resp, err := f.svc.ExecuteFast(context.Background(), &service.FormatFastRequest{
+		TaskID: "fmt-ext",
+		Device: []service.FormatFastDevice{{
+			DeviceIP: "127.0.0.1", DevicePort: f.port, DeviceName: "sw-01", DevicePlatform: "cisco_ios",
+			UserName: "sw-01", Password: "nova", Cli: "show interfaces status",
+		}},
+		FSMTemplates: []service.FSMTemplateDef{{DevicePlatform: "cisco_ios", TemplateValues: []service.FSMTemplateValue{
+			{CLIName: "show interfaces status", FSMValue: impactCurrentTemplate},
+		}}},
+	})
+	require.NoError(t, err)
+	b, _ := json.Marshal(resp.Formatted["show interfaces status"])
+	assert.JSONEq(t, `{"parsed":[]}`, string(b))
+	assert.Equal(t, int32(9), f.calls.Load())
+}
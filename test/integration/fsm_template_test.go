@@ -0,0 +1,140 @@
+package integration
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sshcollectorpro/sshcollectorpro/api/handler"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/config"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/database"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// setupTemplateLibrary 临时数据库与模板目录，返回模板目录与路由
+func setupTemplateLibrary(t *testing.T) (string, *gin.Engine) {
+	dir := t.TempDir()
+	tplDir := filepath.Join(dir, "templates")
+	cfgPath := filepath.Join(dir, "config.yaml")
+	require.NoError(t, os.WriteFile(cfgPath, []byte("data_format:\n  template_dir: "+tplDir+"\n"), 0o600))
+	_, err := config.Load(cfgPath)
+	require.NoError(t, err)
+	require.NoError(t, database.InitSQLite(config.SQLiteConfig{Path: filepath.Join(dir, "collector.db")}))
+	t.Cleanup(func() { database.Close() })
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	h := handler.NewTemplateHandler()
+	r.POST("/templates", h.CreateTemplate)
+	r.POST("/templates/import", h.ImportTemplates)
+	return tplDir, r
+}
+
+// TestTemplateCreateRejectsTraversal 平台名与模板名含路径或非法字符时拒绝，不在模板目录外落盘
+func TestTemplateCreateRejectsTraversal(t *testing.T) {
+	tplDir, r := setupTemplateLibrary(t)
+	create := func(body map[string]string) *httptest.ResponseRecorder {
+		b, _ := json.Marshal(body)
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/templates", bytes.NewReader(b))
+		req.Header.Set("Content-Type", "application/json")
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	for _, tc := range []map[string]string{
+		{"platform": "cisco_ios", "name": "../../escape"},
+		{"platform": "cisco_ios", "name": "..%2fescape"},
+		{"platform": "cisco_ios", "name": "sub/escape.textfsm"},
+		{"platform": "cisco_ios", "name": "Upper.textfsm"},
+		{"platform": "..", "name": "escape"},
+		{"platform": "../x", "name": "escape"},
+	} {
+		tc["command"], tc["content"] = "show version", impactCurrentTemplate
+		w := create(tc)
+		assert.Equal(t, http.StatusBadRequest, w.Code, "%v: %s", tc, w.Body.String())
+	}
+	_, err := os.Stat(filepath.Join(filepath.Dir(tplDir), "escape.textfsm"))
+	assert.True(t, os.IsNotExist(err))
+
+	// 省略 name 时按平台与命令生成，命令中的非法字符被替换
+	w := create(map[string]string{"platform": "cisco_ios", "command": "show run | include ../x", "content": impactCurrentTemplate})
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var tpl model.FSMTemplate
+	require.NoError(t, database.GetDB().First(&tpl).Error)
+	assert.Equal(t, "cisco_ios_show_run_include_x.textfsm", tpl.Name)
+	assert.Equal(t, filepath.Join(tplDir, "cisco_ios", tpl.Name), tpl.FilePath)
+
+	// 显式名称补齐扩展名
+	w = create(map[string]string{"platform": "cisco_ios", "name": "my-tpl", "command": "show clock", "content": impactCurrentTemplate})
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	assert.FileExists(t, filepath.Join(tplDir, "cisco_ios", "my-tpl.textfsm"))
+}
+
+// TestTemplateImportZipValidatesNames zip 导入只接受 [a-z0-9_-]+.textfsm 文件名与合法平台名；不再支持服务器本地目录
+func TestTemplateImportZipValidatesNames(t *testing.T) {
+	tplDir, r := setupTemplateLibrary(t)
+
+	var archive bytes.Buffer
+	zw := zip.NewWriter(&archive)
+	files := map[string]string{
+		"templates/index": "Template, Hostname, Platform, Command\n" +
+			"cisco_ios_show_version.textfsm, .*, cisco_ios, sh[[ow]] ver[[sion]]\n" +
+			"../../evil.textfsm, .*, cisco_ios, sh[[ow]] cl[[ock]]\n" +
+			"cisco_ios_show_ip.txt, .*, cisco_ios, sh[[ow]] ip\n" +
+			"cisco_ios_show_arp.textfsm, .*, ../x, sh[[ow]] arp\n",
+		"templates/cisco_ios_show_version.textfsm": impactCurrentTemplate,
+		"evil.textfsm":                         impactCurrentTemplate,
+		"templates/cisco_ios_show_ip.txt":      impactCurrentTemplate,
+		"templates/cisco_ios_show_arp.textfsm": impactCurrentTemplate,
+	}
+	for name, content := range files {
+		f, err := zw.Create(name)
+		require.NoError(t, err)
+		_, err = f.Write([]byte(content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, zw.Close())
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	fw, err := mw.CreateFormFile("file", "ntc.zip")
+	require.NoError(t, err)
+	_, err = fw.Write(archive.Bytes())
+	require.NoError(t, err)
+	require.NoError(t, mw.Close())
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/templates/import", &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	r.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	var resp struct {
+		Data handler.TemplateImportResult `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, 1, resp.Data.Imported)
+	assert.Len(t, resp.Data.Errors, 3)
+	assert.FileExists(t, filepath.Join(tplDir, "cisco_ios", "cisco_ios_show_version.textfsm"))
+	_, err = os.Stat(filepath.Join(filepath.Dir(tplDir), "evil.textfsm"))
+	assert.True(t, os.IsNotExist(err))
+	_, err = os.Stat(filepath.Join(filepath.Dir(tplDir), "x"))
+	assert.True(t, os.IsNotExist(err))
+
+	// JSON 本地目录导入已移除
+	b, _ := json.Marshal(map[string]string{"path": filepath.Dir(tplDir)})
+	w = httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodPost, "/templates/import", bytes.NewReader(b))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
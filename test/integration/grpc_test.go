@@ -0,0 +1,79 @@
+package integration
+
+import (
+	"context"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/sshcollectorpro/sshcollectorpro/api/handler"
+	pb "github.com/sshcollectorpro/sshcollectorpro/api/proto/sshcollector/v1"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/config"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// TestGRPCDeployStream Deploy 逐设备推送结果；未定义变量与参数错误映射为 InvalidArgument
+func TestGRPCDeployStream(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Deploy.DeployWaitMS = 1
+	collector := service.NewCollectorService(cfg)
+	deploy := service.NewDeployService(cfg, collector)
+
+	lis := bufconn.Listen(1 << 20)
+	gs := grpc.NewServer()
+	handler.NewGRPCServer(collector, service.NewBackupService(cfg), nil, deploy).Register(gs)
+	go gs.Serve(lis)
+	defer gs.Stop()
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	defer conn.Close()
+	client := pb.NewDeployServiceClient(conn)
+
+	vars, _ := structpb.NewStruct(map[string]interface{}{"vlan": 100})
+	stream, err := client.Deploy(context.Background(), &pb.DeployRequest{
+		TaskId:    "grpc-1",
+		TaskType:  "dry_run",
+		Variables: vars,
+		Devices: []*pb.DeployDevice{
+			{DeviceIp: "192.0.2.1", DeviceName: "sw-01", ConfigDeploy: "vlan ${vlan}"},
+			{DeviceIp: "192.0.2.2", DeviceName: "sw-02", ConfigDeploy: "vlan ${vlan}"},
+		},
+	})
+	require.NoError(t, err)
+	got := map[string]*pb.DeployResult{}
+	for {
+		r, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		got[r.DeviceIp] = r
+	}
+	require.Len(t, got, 2)
+	assert.True(t, got["192.0.2.1"].Success)
+	assert.Equal(t, "sw-02", got["192.0.2.2"].DeviceName)
+
+	stream, err = client.Deploy(context.Background(), &pb.DeployRequest{
+		TaskId:   "grpc-2",
+		TaskType: "dry_run",
+		Devices:  []*pb.DeployDevice{{DeviceIp: "192.0.2.3", ConfigDeploy: "vlan ${missing}"}},
+	})
+	require.NoError(t, err)
+	_, err = stream.Recv()
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	assert.Contains(t, err.Error(), "${missing}")
+
+	_, err = pb.NewCollectorServiceClient(conn).Collect(context.Background(), &pb.CollectBatchRequest{})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
@@ -0,0 +1,132 @@
+package integration
+
+import (
+	"context"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/sshcollectorpro/sshcollectorpro/api/handler"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/config"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/database"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/model"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestHALeaderElection 同一租约仅一个实例为 leader；leader 停止释放租约后 follower 接管
+func TestHALeaderElection(t *testing.T) {
+	require.NoError(t, database.InitSQLite(config.SQLiteConfig{Path: filepath.Join(t.TempDir(), "collector.db")}))
+	defer database.Close()
+
+	for _, backend := range []string{"db", "file"} {
+		t.Run(backend, func(t *testing.T) {
+			hc := config.HAConfig{
+				Enable:        true,
+				Backend:       backend,
+				LeaseName:     "test-" + backend,
+				FilePath:      filepath.Join(t.TempDir(), "ha.lease"),
+				LeaseDuration: 600 * time.Millisecond,
+				RenewInterval: 100 * time.Millisecond,
+			}
+			hc.InstanceID = "node-a"
+			a, err := service.NewLeaderElector(hc)
+			require.NoError(t, err)
+			hc.InstanceID = "node-b"
+			b, err := service.NewLeaderElector(hc)
+			require.NoError(t, err)
+
+			a.Start(context.Background())
+			b.Start(context.Background())
+			defer b.Stop()
+			assert.True(t, a.IsLeader())
+			assert.False(t, b.IsLeader())
+			st := b.Status()
+			assert.Equal(t, service.HARoleFollower, st.Role)
+			assert.Equal(t, "node-a", st.Leader)
+
+			// 续约期间 leader 不变
+			time.Sleep(800 * time.Millisecond)
+			assert.True(t, a.IsLeader())
+			assert.False(t, b.IsLeader())
+
+			a.Stop()
+			assert.False(t, a.IsLeader())
+			assert.Eventually(t, b.IsLeader, 2*time.Second, 50*time.Millisecond)
+			assert.Equal(t, service.HARoleLeader, b.Status().Role)
+		})
+	}
+}
+
+// TestHAConfigValidation 续约间隔须小于租约有效期，后端须受支持
+func TestHAConfigValidation(t *testing.T) {
+	_, err := service.NewLeaderElector(config.HAConfig{LeaseDuration: time.Second, RenewInterval: 2 * time.Second})
+	assert.Error(t, err)
+	_, err = service.NewLeaderElector(config.HAConfig{Backend: "etcd"})
+	assert.Error(t, err)
+	assert.True(t, service.IsLeader())
+	assert.Equal(t, service.HARoleStandalone, service.CurrentHAStatus().Role)
+}
+
+// TestHARecoveryOnLeaderTransition 启动时为 follower 不执行中断恢复；接任 leader 后执行一次，接管原 leader 遗留的运行手册记录
+func TestHARecoveryOnLeaderTransition(t *testing.T) {
+	require.NoError(t, database.InitSQLite(config.SQLiteConfig{Path: filepath.Join(t.TempDir(), "collector.db")}))
+	defer database.Close()
+	require.NoError(t, database.GetDB().Create(&model.RunbookRun{ID: "rb-orphan", RunbookName: "upgrade", Status: service.RunbookRunRunning}).Error)
+
+	hc := config.HAConfig{
+		Enable:        true,
+		Backend:       "file",
+		LeaseName:     "recovery",
+		FilePath:      filepath.Join(t.TempDir(), "ha.lease"),
+		LeaseDuration: 600 * time.Millisecond,
+		RenewInterval: 100 * time.Millisecond,
+		InstanceID:    "node-a",
+	}
+	a, err := service.NewLeaderElector(hc)
+	require.NoError(t, err)
+	a.Start(context.Background())
+	defer a.Stop()
+	require.True(t, a.IsLeader())
+
+	cfg := &config.Config{HA: hc}
+	cfg.HA.InstanceID = "node-b"
+	require.NoError(t, service.InitHA(cfg))
+	defer service.CloseHA()
+
+	var runs atomic.Int32
+	unregister := service.OnLeaderElected(func() {
+		runs.Add(1)
+		handler.RecoverRunbookRuns()
+	})
+	defer unregister()
+	require.False(t, service.IsLeader())
+	assert.Zero(t, runs.Load(), "follower must not run recovery")
+
+	status := func() string {
+		var run model.RunbookRun
+		require.NoError(t, database.GetDB().First(&run, "id = ?", "rb-orphan").Error)
+		return run.Status
+	}
+	assert.Equal(t, service.RunbookRunRunning, status())
+
+	// 原 leader 停止，本实例接任后执行恢复
+	a.Stop()
+	assert.Eventually(t, func() bool { return runs.Load() == 1 }, 3*time.Second, 20*time.Millisecond)
+	assert.Equal(t, service.RunbookRunInterrupted, status())
+
+	// 同一任期内续约不重复执行
+	time.Sleep(300 * time.Millisecond)
+	assert.Equal(t, int32(1), runs.Load())
+	assert.True(t, service.IsLeader())
+}
+
+// TestLeaderHookStandalone 未启用高可用时注册即执行一次
+func TestLeaderHookStandalone(t *testing.T) {
+	var runs atomic.Int32
+	unregister := service.OnLeaderElected(func() { runs.Add(1) })
+	defer unregister()
+	assert.Equal(t, int32(1), runs.Load())
+}
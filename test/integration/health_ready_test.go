@@ -0,0 +1,84 @@
+package integration
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sshcollectorpro/sshcollectorpro/api/handler"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/config"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/database"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestHealthReady 关键依赖异常返回 503，全部正常返回 200 并附逐项耗时
+func TestHealthReady(t *testing.T) {
+	dir := t.TempDir()
+	cfgPath := filepath.Join(dir, "config.yaml")
+	require.NoError(t, os.WriteFile(cfgPath, []byte("storage:\n  minio:\n    host: \"\"\n"), 0o600))
+	cfg, err := config.Load(cfgPath)
+	require.NoError(t, err)
+
+	collector := service.NewCollectorService(cfg)
+	backup := service.NewBackupService(cfg)
+	format := service.NewFormatService(cfg)
+	deploy := service.NewDeployService(cfg, collector)
+	h := handler.NewHealthHandler(service.NewHealthChecker(collector, backup, format, deploy))
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/health/ready", h.Ready)
+	r.GET("/health/live", h.Live)
+	get := func(url string) (int, service.HealthReport) {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, url, nil))
+		var body struct {
+			Data service.HealthReport `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+		return w.Code, body.Data
+	}
+	status := func(rep service.HealthReport) map[string]string {
+		out := map[string]string{}
+		for _, c := range rep.Checks {
+			out[c.Name] = c.Status
+		}
+		return out
+	}
+
+	code, _ := get("/health/live")
+	assert.Equal(t, http.StatusOK, code)
+
+	// 服务未启动
+	code, rep := get("/health/ready")
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, service.HealthDown, rep.Status)
+	assert.Equal(t, service.DependencyDown, status(rep)["collector"])
+
+	require.NoError(t, database.InitSQLite(config.SQLiteConfig{Path: filepath.Join(dir, "collector.db")}))
+	defer database.Close()
+	ctx := context.Background()
+	require.NoError(t, collector.Start(ctx))
+	defer collector.Stop()
+	require.NoError(t, backup.Start(ctx))
+	defer backup.Stop()
+	require.NoError(t, format.Start(ctx))
+	defer format.Stop()
+
+	code, rep = get("/health/ready")
+	require.Equal(t, http.StatusOK, code)
+	assert.Equal(t, service.HealthOK, rep.Status)
+	st := status(rep)
+	for _, name := range []string{"collector", "backup", "format", "deploy", "sqlite", "ssh_pool"} {
+		assert.Equal(t, service.DependencyUp, st[name], name)
+	}
+	assert.Equal(t, service.DependencyDisabled, st["minio"])
+	assert.Equal(t, service.DependencyDisabled, st["simulate"])
+}
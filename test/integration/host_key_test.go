@@ -0,0 +1,121 @@
+package integration
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sshcollectorpro/sshcollectorpro/api/handler"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/config"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/database"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/model"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/service"
+	"github.com/sshcollectorpro/sshcollectorpro/pkg/ssh"
+	"github.com/sshcollectorpro/sshcollectorpro/simulate"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	xssh "golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// TestHostKeyVerification TOFU 首次记录指纹、指纹变化时拒绝；strict 拒绝未登记设备；known_hosts 文件校验；指纹可通过接口查看与删除
+func TestHostKeyVerification(t *testing.T) {
+	dir := t.TempDir()
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(dir))
+	defer os.Chdir(wd)
+	require.NoError(t, database.InitSQLite(config.SQLiteConfig{Path: filepath.Join(dir, "collector.db")}))
+	defer database.Close()
+
+	port := freePort(t)
+	mgr, err := simulate.Start(&simulate.Config{Namespace: map[string]simulate.NamespaceConfig{"t": {Port: port, IdleSeconds: 60, MaxConn: 10}}})
+	require.NoError(t, err)
+	defer mgr.Stop()
+
+	repo := handler.NewHostKeyRepository()
+	info := &ssh.ConnectionInfo{Host: "127.0.0.1", Port: port, Username: "sw-01", Password: "nova", Platform: "cisco_ios"}
+	connect := func(policy *ssh.HostKeyPolicy) error {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		client := ssh.NewClient(&ssh.Config{ConnectTimeout: 3 * time.Second, Timeout: 5 * time.Second, HostKey: policy})
+		err := client.Connect(ctx, info)
+		if err == nil {
+			client.Close()
+		}
+		return err
+	}
+
+	// TOFU：首次连接记录，再次连接校验通过
+	tofu := &ssh.HostKeyPolicy{Mode: ssh.HostKeyTOFU, Store: repo}
+	require.NoError(t, connect(tofu))
+	require.NoError(t, connect(tofu))
+	recs, err := repo.HostKeys("127.0.0.1", port)
+	require.NoError(t, err)
+	require.Len(t, recs, 1)
+	assert.Contains(t, recs[0].Fingerprint, "SHA256:")
+
+	// 记录的指纹与设备不一致时拒绝
+	require.NoError(t, database.GetDB().Model(&model.HostKey{}).Where("host = ?", "127.0.0.1").Update("fingerprint", "SHA256:tampered").Error)
+	err = connect(tofu)
+	require.Error(t, err)
+	assert.ErrorIs(t, err, ssh.ErrHostKeyRejected)
+	status, code := handler.ErrorStatus(fmt.Errorf("collect: %w", service.ErrHostKeyRejected), "")
+	assert.Equal(t, http.StatusBadGateway, status)
+	assert.Equal(t, "HOST_KEY_REJECTED", code)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/host-keys", handler.ListHostKeys)
+	r.DELETE("/host-keys", handler.DeleteHostKeys)
+	do := func(method, path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(method, path, nil))
+		return w
+	}
+	w := do(http.MethodGet, "/host-keys?host=127.0.0.1")
+	require.Equal(t, http.StatusOK, w.Code)
+	var list struct {
+		Data []model.HostKey `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &list))
+	require.Len(t, list.Data, 1)
+	assert.Equal(t, port, list.Data[0].Port)
+	assert.Equal(t, http.StatusBadRequest, do(http.MethodDelete, "/host-keys").Code)
+	assert.Equal(t, http.StatusOK, do(http.MethodDelete, "/host-keys?host=127.0.0.1&port="+strconv.Itoa(port)).Code)
+
+	// 按设备规则覆盖为 strict：未登记的设备拒绝
+	strict := &ssh.HostKeyPolicy{Mode: ssh.HostKeyTOFU, Store: repo, Rules: []ssh.HostKeyRule{{Match: "127.0.0.0/8", Mode: ssh.HostKeyStrict}}}
+	assert.ErrorIs(t, connect(strict), ssh.ErrHostKeyRejected)
+	// 删除后 TOFU 重新登记
+	require.NoError(t, connect(tofu))
+	recs, err = repo.HostKeys("127.0.0.1", port)
+	require.NoError(t, err)
+	require.Len(t, recs, 1)
+	require.NoError(t, connect(strict))
+
+	// known_hosts 文件
+	key, _, _, _, err := xssh.ParseAuthorizedKey([]byte(recs[0].PublicKey))
+	require.NoError(t, err)
+	khPath := filepath.Join(dir, "known_hosts")
+	addr := net.JoinHostPort("127.0.0.1", strconv.Itoa(port))
+	require.NoError(t, os.WriteFile(khPath, []byte(knownhosts.Line([]string{knownhosts.Normalize(addr)}, key)+"\n"), 0o600))
+	require.NoError(t, connect(&ssh.HostKeyPolicy{Mode: ssh.HostKeyKnownHosts, KnownHosts: []string{khPath}}))
+	other := filepath.Join(dir, "known_hosts_other")
+	require.NoError(t, os.WriteFile(other, []byte(knownhosts.Line([]string{"[127.0.0.1]:1"}, key)+"\n"), 0o600))
+	assert.ErrorIs(t, connect(&ssh.HostKeyPolicy{Mode: ssh.HostKeyKnownHosts, KnownHosts: []string{other}}), ssh.ErrHostKeyRejected)
+
+	report, err := config.ValidateYAML([]byte("ssh:\n  host_key:\n    mode: known_hosts\n"))
+	require.NoError(t, err)
+	require.NotEmpty(t, report.Errors())
+	assert.Equal(t, "ssh.host_key.known_hosts", report.Errors()[0].Path)
+}
@@ -0,0 +1,118 @@
+package integration
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/sshcollectorpro/sshcollectorpro/internal/config"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type memInventory map[string]service.InventoryRecord
+
+func (m memInventory) ListInventory() ([]service.InventoryRecord, error) {
+	out := make([]service.InventoryRecord, 0, len(m))
+	for _, r := range m {
+		out = append(out, r)
+	}
+	return out, nil
+}
+
+func (m memInventory) UpsertInventory(rec service.InventoryRecord) error {
+	if old, ok := m[rec.IP]; ok {
+		rec.Enabled = old.Enabled
+	}
+	m[rec.IP] = rec
+	return nil
+}
+
+func (m memInventory) DisableInventory(ip string, port int) error {
+	r := m[ip]
+	r.Enabled = false
+	m[ip] = r
+	return nil
+}
+
+// TestNetBoxInventorySync 分页拉取、平台/角色映射、差异报告、dry_run 与 disable_missing
+func TestNetBoxInventorySync(t *testing.T) {
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Token secret", r.Header.Get("Authorization"))
+		assert.Equal(t, "/api/dcim/devices/", r.URL.Path)
+		assert.Equal(t, "active", r.URL.Query().Get("status"))
+		page := map[string]interface{}{"count": 4}
+		if r.URL.Query().Get("offset") == "" {
+			next := srv.URL + "/api/dcim/devices/?status=active&limit=2&offset=2"
+			page["next"] = next
+			page["results"] = []map[string]interface{}{
+				{"id": 1, "name": "core-01", "platform": map[string]interface{}{"slug": "cisco-ios"},
+					"device_type": map[string]interface{}{"model": "C9300", "manufacturer": map[string]interface{}{"name": "Cisco"}},
+					"primary_ip":  map[string]interface{}{"address": "10.0.0.1/24"}},
+				{"id": 2, "name": "acc-02", "role": map[string]interface{}{"slug": "access"},
+					"primary_ip": map[string]interface{}{"address": "10.0.0.2/24"}},
+			}
+		} else {
+			page["results"] = []map[string]interface{}{
+				{"id": 3, "name": "no-ip", "platform": map[string]interface{}{"slug": "cisco-ios"}},
+				{"id": 4, "name": "fw-01", "platform": map[string]interface{}{"slug": "fortios"},
+					"primary_ip": map[string]interface{}{"address": "10.0.0.4/24"}},
+			}
+		}
+		json.NewEncoder(w).Encode(page)
+	}))
+	defer srv.Close()
+
+	cfg := &config.Config{}
+	cfg.NetBox = config.NetBoxConfig{
+		Enable:         true,
+		URL:            srv.URL,
+		Token:          "secret",
+		PageSize:       2,
+		Filters:        map[string]string{"status": "active"},
+		PlatformMap:    map[string]string{"cisco-ios": "cisco_ios"},
+		RoleMap:        map[string]string{"access": "huawei_vrp"},
+		DisableMissing: true,
+	}
+	store := memInventory{
+		"10.0.0.1": {IP: "10.0.0.1", Port: 22, Name: "old-name", Platform: "cisco_ios", Vendor: "Cisco", Model: "C9300", Enabled: true},
+		"10.0.0.9": {IP: "10.0.0.9", Port: 22, Name: "gone", Source: service.InventorySourceNetBox, Enabled: true},
+		"10.0.0.8": {IP: "10.0.0.8", Port: 22, Name: "manual", Enabled: true},
+	}
+	svc := service.NewInventorySyncService(cfg, store)
+
+	report, err := svc.Sync(context.Background(), true)
+	require.NoError(t, err)
+	assert.Equal(t, 4, report.Fetched)
+	assert.Equal(t, 1, report.Created)
+	assert.Equal(t, 1, report.Updated)
+	assert.Equal(t, 1, report.Disabled)
+	assert.Equal(t, 2, report.Skipped)
+	assert.Equal(t, "old-name", store["10.0.0.1"].Name, "dry_run must not write")
+
+	report, err = svc.Sync(context.Background(), false)
+	require.NoError(t, err)
+	assert.Same(t, report, svc.LastReport())
+	for _, c := range report.Changes {
+		switch c.IP {
+		case "10.0.0.1":
+			assert.Equal(t, service.InventoryActionUpdate, c.Action)
+			assert.Equal(t, service.FieldChange{Old: "old-name", New: "core-01"}, c.Fields["name"])
+		case "10.0.0.9":
+			assert.Equal(t, service.InventoryActionDisable, c.Action)
+		}
+	}
+	assert.Equal(t, "huawei_vrp", store["10.0.0.2"].Platform)
+	assert.True(t, store["10.0.0.2"].Enabled)
+	assert.False(t, store["10.0.0.9"].Enabled)
+	assert.True(t, store["10.0.0.8"].Enabled)
+
+	report, err = svc.Sync(context.Background(), false)
+	require.NoError(t, err)
+	assert.Equal(t, 2, report.Unchanged)
+	assert.Equal(t, 0, report.Created+report.Updated+report.Disabled)
+}
@@ -0,0 +1,77 @@
+package integration
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/sshcollectorpro/sshcollectorpro/internal/config"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestMonitorZabbixSender 设备事件按模板生成 host/key，满批后以 sender 协议推送
+func TestMonitorZabbixSender(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer ln.Close()
+	batches := make(chan []service.MonitorValue, 4)
+	go func() {
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			header := make([]byte, 13)
+			if _, err := io.ReadFull(c, header); err == nil && string(header[:5]) == "ZBXD\x01" {
+				body := make([]byte, binary.LittleEndian.Uint64(header[5:]))
+				io.ReadFull(c, body)
+				var req struct {
+					Request string                 `json:"request"`
+					Data    []service.MonitorValue `json:"data"`
+				}
+				json.Unmarshal(body, &req)
+				if req.Request == "sender data" {
+					batches <- req.Data
+				}
+				resp := []byte(`{"response":"success","info":"processed: 6; failed: 0; total: 6; seconds spent: 0.000055"}`)
+				out := append([]byte("ZBXD\x01"), make([]byte, 8)...)
+				binary.LittleEndian.PutUint64(out[5:], uint64(len(resp)))
+				c.Write(append(out, resp...))
+			}
+			c.Close()
+		}
+	}()
+
+	cfg := &config.Config{}
+	cfg.Monitor = config.MonitorConfig{
+		Enable:        true,
+		Backend:       "zabbix",
+		ZabbixServer:  ln.Addr().String(),
+		HostTemplate:  "{device_name}",
+		KeyTemplate:   "net.{type}[{metric}]",
+		BatchSize:     6,
+		FlushInterval: time.Minute,
+	}
+	require.NoError(t, service.InitMonitor(cfg))
+	defer service.CloseMonitor()
+	service.PublishDeviceEvent(service.DeviceEvent{Type: service.EventTypeBackup, DeviceIP: "10.0.0.1", DeviceName: "sw-01", Status: service.EventStatusSuccess, DurationMS: 120})
+	service.PublishDeviceEvent(service.DeviceEvent{Type: service.EventTypeCollect, DeviceIP: "10.0.0.2", Status: service.EventStatusFailed, Error: "connection refused"})
+
+	select {
+	case b := <-batches:
+		require.Len(t, b, 6)
+		assert.Equal(t, service.MonitorValue{Host: "sw-01", Key: "net.backup[status]", Value: "1", Clock: b[0].Clock}, b[0])
+		assert.Equal(t, "120", b[1].Value)
+		assert.Equal(t, "10.0.0.2", b[3].Host, "empty device name falls back to ip")
+		assert.Equal(t, "net.collect[status]", b[3].Key)
+		assert.Equal(t, "0", b[3].Value)
+		assert.Equal(t, "connection refused", b[5].Value)
+	case <-time.After(3 * time.Second):
+		t.Fatal("no batch received")
+	}
+}
@@ -0,0 +1,191 @@
+package integration
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sshcollectorpro/sshcollectorpro/internal/config"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/netconf"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeNetconfServer 在连接上模拟 NETCONF 1.1 设备：hello 使用 ]]>]]>，之后使用分块帧
+func fakeNetconfServer(t *testing.T, conn net.Conn, replies []string) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	hello := `<hello xmlns="urn:ietf:params:xml:ns:netconf:base:1.0"><capabilities>` +
+		`<capability>urn:ietf:params:netconf:base:1.1</capability></capabilities><session-id>42</session-id></hello>]]>]]>`
+	// net.Pipe 无缓冲：双方同时发送 hello，服务端写入放到独立协程
+	go io.WriteString(conn, hello)
+	// 读取客户端 hello
+	var buf strings.Builder
+	for !strings.HasSuffix(buf.String(), "]]>]]>") {
+		b, err := r.ReadByte()
+		if err != nil {
+			return
+		}
+		buf.WriteByte(b)
+	}
+	for _, reply := range replies {
+		// 读取一个分块请求
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			if line == "\n" {
+				continue
+			}
+			if line == "##\n" {
+				break
+			}
+			n, err := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(line, "#"), "\n"))
+			if err != nil {
+				t.Errorf("bad chunk header %q", line)
+				return
+			}
+			if _, err := io.CopyN(io.Discard, r, int64(n)); err != nil {
+				return
+			}
+		}
+		// 应答拆成两个分块发送
+		half := len(reply) / 2
+		fmt.Fprintf(conn, "\n#%d\n%s\n#%d\n%s\n##\n", half, reply[:half], len(reply)-half, reply[half:])
+	}
+}
+
+// TestNetconfSessionChunked hello 协商 1.1 后按分块帧收发，rpc-error 以 RPCError 返回
+func TestNetconfSessionChunked(t *testing.T) {
+	client, server := net.Pipe()
+	okReply := `<rpc-reply message-id="1" xmlns="urn:ietf:params:xml:ns:netconf:base:1.0"><data><system><host-name>core-1</host-name></system></data></rpc-reply>`
+	errReply := `<rpc-reply message-id="2" xmlns="urn:ietf:params:xml:ns:netconf:base:1.0"><rpc-error><error-type>protocol</error-type>` +
+		`<error-tag>operation-not-supported</error-tag><error-severity>error</error-severity><error-message>candidate not supported</error-message></rpc-error></rpc-reply>`
+	go fakeNetconfServer(t, server, []string{okReply, errReply})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	sess, err := netconf.NewSession(ctx, client, client, client)
+	require.NoError(t, err)
+	defer sess.Close()
+	assert.Equal(t, "42", sess.SessionID)
+	assert.True(t, sess.HasCapability(netconf.CapBase11))
+
+	reply, err := sess.GetConfig(ctx, "running", "<system/>")
+	require.NoError(t, err)
+	assert.Equal(t, okReply, reply)
+
+	_, err = sess.GetConfig(ctx, "candidate", "")
+	var rpcErr *netconf.RPCError
+	require.ErrorAs(t, err, &rpcErr)
+	assert.Equal(t, "operation-not-supported", rpcErr.Tag)
+	assert.Contains(t, rpcErr.Error(), "candidate not supported")
+}
+
+// TestNetconfBuildRPC 命令写法转换为 RPC 操作元素
+func TestNetconfBuildRPC(t *testing.T) {
+	body, err := netconf.BuildRPC("get-config")
+	require.NoError(t, err)
+	assert.Equal(t, "<get-config><source><running/></source></get-config>", body)
+
+	body, err = netconf.BuildRPC(`get-config candidate <interfaces xmlns="urn:x"/>`)
+	require.NoError(t, err)
+	assert.Equal(t, `<get-config><source><candidate/></source><filter type="subtree"><interfaces xmlns="urn:x"/></filter></get-config>`, body)
+
+	body, err = netconf.BuildRPC("get <system/>")
+	require.NoError(t, err)
+	assert.Equal(t, `<get><filter type="subtree"><system/></filter></get>`, body)
+
+	body, err = netconf.BuildRPC("<get-schema/>")
+	require.NoError(t, err)
+	assert.Equal(t, "<get-schema/>", body)
+
+	_, err = netconf.BuildRPC("show version")
+	assert.Error(t, err)
+	_, err = netconf.BuildRPC("get-config backup")
+	assert.Error(t, err)
+
+	body, err = netconf.BuildRPC("get-schema ietf-interfaces 2018-02-20")
+	require.NoError(t, err)
+	assert.Equal(t, `<get-schema xmlns="urn:ietf:params:xml:ns:yang:ietf-netconf-monitoring"><identifier>ietf-interfaces</identifier><version>2018-02-20</version></get-schema>`, body)
+}
+
+// TestNetconfBuildRPCRejectsWrites 采集仅允许只读操作：原样 XML 的写操作与经过滤拼接的额外操作均拒绝
+func TestNetconfBuildRPCRejectsWrites(t *testing.T) {
+	for _, cli := range []string{
+		`<edit-config><target><running/></target><config><system/></config></edit-config>`,
+		`<commit/>`,
+		`<delete-config><target><startup/></target></delete-config>`,
+		`<get/><commit/>`,
+		`get </filter></get><edit-config><target><running/></target></edit-config><get><filter>`,
+		`get-config running </filter></get-config><commit/><get-config><source><running/></source><filter>`,
+		`<get>`,
+		`edit-config`,
+	} {
+		_, err := netconf.BuildRPC(cli)
+		assert.Error(t, err, cli)
+	}
+
+	// 采集请求校验阶段即拒绝，返回参数错误
+	cfgPath := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(cfgPath, []byte(`
+collector:
+  device_defaults:
+    junos:
+      capabilities:
+        netconf: true
+`), 0o600))
+	cfg, err := config.Load(cfgPath)
+	require.NoError(t, err)
+	svc := service.NewCollectorService(cfg)
+	require.NoError(t, svc.Start(context.Background()))
+	defer svc.Stop()
+	retry := 0
+	_, err = svc.ExecuteTask(context.Background(), &service.CollectRequest{
+		TaskID: "nc-write", DeviceIP: "127.0.0.1", Port: freePort(t), DevicePlatform: "junos", CollectProtocol: "netconf",
+		UserName: "u", Password: "p", RetryFlag: &retry,
+		CliList: service.NewCLIList(`<edit-config><target><running/></target><config/></edit-config>`),
+	})
+	require.Error(t, err)
+	assert.ErrorIs(t, err, service.ErrValidation)
+	assert.Contains(t, err.Error(), "edit-config")
+}
+
+// TestNetconfXPathSubset 路径、后代、谓词与属性提取
+func TestNetconfXPathSubset(t *testing.T) {
+	doc, err := netconf.ParseDocument(`<rpc-reply><data><if:interfaces xmlns:if="urn:ietf">
+		<if:interface><if:name>ge-0/0/0</if:name><if:enabled>true</if:enabled><if:mtu unit="byte">1500</if:mtu></if:interface>
+		<if:interface><if:name>ge-0/0/1</if:name><if:enabled>false</if:enabled><if:mtu unit="byte">9000</if:mtu></if:interface>
+	</if:interfaces></data></rpc-reply>`)
+	require.NoError(t, err)
+
+	cases := map[string][]string{
+		"/rpc-reply/data/interfaces/interface/name":     {"ge-0/0/0", "ge-0/0/1"},
+		"//interface[2]/name":                           {"ge-0/0/1"},
+		"interface[name='ge-0/0/1']/mtu":                {"9000"},
+		"//if:interface[enabled='true']/if:name/text()": {"ge-0/0/0"},
+		"//mtu/@unit": {"byte", "byte"},
+		"/rpc-reply/*/interfaces/interface[mtu='1500']/name": {"ge-0/0/0"},
+		"//missing": {},
+	}
+	for expr, want := range cases {
+		x, err := netconf.CompileXPath(expr)
+		require.NoError(t, err, expr)
+		assert.Equal(t, want, x.Select(doc), expr)
+	}
+
+	for _, bad := range []string{"", "//a[", "//a[0]", "//a[b=c]", "//count(a)"} {
+		_, err := netconf.CompileXPath(bad)
+		assert.Error(t, err, bad)
+	}
+}
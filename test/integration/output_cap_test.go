@@ -0,0 +1,56 @@
+package integration
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/sshcollectorpro/sshcollectorpro/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func numberedLines(n int) string {
+	lines := make([]string, n)
+	for i := range lines {
+		lines[i] = fmt.Sprintf("line %d", i+1)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// TestOutputCapHeadTail 超出上限时保留首尾并插入省略标记
+func TestOutputCapHeadTail(t *testing.T) {
+	out, omitted := service.OutputCap{MaxLines: 5, TailLines: 2}.Apply(numberedLines(10))
+	assert.Equal(t, 5, omitted)
+	assert.Equal(t, "line 1\nline 2\nline 3\n... [5 lines omitted] ...\nline 9\nline 10", out)
+
+	// 未指定 tail 时首尾各一半
+	out, omitted = service.OutputCap{MaxLines: 4}.Apply(numberedLines(10))
+	assert.Equal(t, 6, omitted)
+	assert.Equal(t, "line 1\nline 2\n... [6 lines omitted] ...\nline 9\nline 10", out)
+
+	// 未超出或未启用时原样返回
+	in := numberedLines(5)
+	out, omitted = service.OutputCap{MaxLines: 5}.Apply(in)
+	assert.Equal(t, in, out)
+	assert.Zero(t, omitted)
+	out, _ = service.OutputCap{}.Apply(in)
+	assert.Equal(t, in, out)
+}
+
+// TestCLIListMaxLines max_lines/tail_lines 序列化往返与参数校验
+func TestCLIListMaxLines(t *testing.T) {
+	var l service.CLIList
+	require.NoError(t, json.Unmarshal([]byte(`["show version", {"cli": "show tech", "max_lines": 100, "tail_lines": 20}]`), &l))
+	require.Len(t, l, 2)
+	assert.Equal(t, 100, l[1].MaxLines)
+	assert.Equal(t, 20, l[1].TailLines)
+
+	b, err := json.Marshal(l)
+	require.NoError(t, err)
+	assert.JSONEq(t, `["show version", {"cli": "show tech", "max_lines": 100, "tail_lines": 20}]`, string(b))
+
+	assert.Error(t, json.Unmarshal([]byte(`[{"cli": "show tech", "max_lines": -1}]`), &l))
+	assert.Error(t, json.Unmarshal([]byte(`[{"cli": "show tech", "max_lines": 10, "tail_lines": 20}]`), &l))
+}
@@ -0,0 +1,26 @@
+package integration
+
+import (
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/service"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestOutputEncoding 控制字符的 strip / escape / base64 处理
+func TestOutputEncoding(t *testing.T) {
+	raw := "line1\r\n\x1b[32mok\x1b[0m\x7f"
+	assert.Equal(t, "line1\r\n[32mok[0m", service.EncodeControlChars(raw, service.OutputEncodingStrip))
+	assert.Equal(t, `line1`+"\r\n"+`\u001b[32mok\u001b[0m\u007f`, service.EncodeControlChars(raw, service.OutputEncodingEscape))
+	assert.Equal(t, "base64:YWIAAQ==", service.EncodeControlChars("ab\x00\x01", service.OutputEncodingBase64))
+	assert.Equal(t, "plain\ntext", service.EncodeControlChars("plain\ntext", service.OutputEncodingBase64))
+	assert.Error(t, service.ValidateOutputEncoding("hex"))
+
+	// 嵌套结构与 map 中的字符串均被处理
+	resp := &service.BackupBatchResponse{Data: []service.DeviceBackupResponse{{Results: []service.CommandBackupResult{{RawOutput: "a\x07b", RawOutputLines: []string{"a\x07b"}}}}}}
+	out := service.SanitizeOutput(gin.H{"data": resp, "msg": "x\x00"}, service.OutputEncodingStrip).(gin.H)
+	assert.Equal(t, "x", out["msg"])
+	assert.Equal(t, "ab", resp.Data[0].Results[0].RawOutput)
+	assert.Equal(t, []string{"ab"}, resp.Data[0].Results[0].RawOutputLines)
+}
@@ -0,0 +1,65 @@
+package integration
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sshcollectorpro/sshcollectorpro/internal/config"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestOutputNormalizers 平台规整流水线按顺序执行；平台族与 default 回退；无效步骤校验失败
+func TestOutputNormalizers(t *testing.T) {
+	dir := t.TempDir()
+	load := func(body string) (*config.Config, error) {
+		p := filepath.Join(dir, "config.yaml")
+		require.NoError(t, os.WriteFile(p, []byte(body), 0o600))
+		return config.Load(p)
+	}
+	cfg, err := load(`
+collector:
+  device_defaults:
+    cisco_ios:
+      prompt_suffixes: ["#"]
+      normalizers:
+        - type: strip_ansi
+        - type: drop_banner
+          pattern: '^\*+ AUTHORIZED'
+          end: '^\*+$'
+        - type: strip_timestamps
+        - type: collapse_blank_lines
+    default:
+      prompt_suffixes: ["#"]
+      normalizers:
+        - type: regex_replace
+          pattern: 'uptime is .*'
+          replace: 'uptime is <redacted>'
+`)
+	require.NoError(t, err)
+
+	raw := "\x1b[1m*** AUTHORIZED ACCESS ONLY\nviolators will be prosecuted\n***\x1b[0m\n\n" +
+		"Building configuration...\n\n\n! Last configuration change at 10:21:33 UTC Mon Mar 1 2021\nhostname sw-01\n\n"
+	assert.Equal(t, "Building configuration...\n\n! Last configuration change at \nhostname sw-01",
+		service.NormalizeOutput(cfg, "cisco_ios", raw))
+	// cisco_xe 回退到 cisco_ios，未知平台回退到 default
+	assert.Equal(t, "hostname sw-01", service.NormalizeOutput(cfg, "cisco_xe", "\x1b[32mhostname sw-01\x1b[0m"))
+	assert.Equal(t, "sw-01 uptime is <redacted>", service.NormalizeOutput(cfg, "arista_eos", "sw-01 uptime is 3 weeks, 2 days"))
+
+	assert.Error(t, service.ValidateNormalizers([]config.NormalizerConfig{{Type: "drop_banner"}}))
+	assert.Error(t, service.ValidateNormalizers([]config.NormalizerConfig{{Type: "nope"}}))
+	assert.NoError(t, service.ValidateNormalizers([]config.NormalizerConfig{{Type: "Strip_ANSI"}}))
+
+	_, err = load(`
+collector:
+  device_defaults:
+    huawei:
+      prompt_suffixes: [">"]
+      normalizers:
+        - type: regex_replace
+          pattern: '('
+`)
+	assert.ErrorContains(t, err, "collector.device_defaults.huawei.normalizers[0].pattern")
+}
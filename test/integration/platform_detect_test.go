@@ -0,0 +1,100 @@
+package integration
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sshcollectorpro/sshcollectorpro/internal/config"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/service"
+	"github.com/sshcollectorpro/sshcollectorpro/simulate"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestCollectPlatformAutoDetect device_platform 为 auto 或为空时登录探测平台并按探测结果执行
+func TestCollectPlatformAutoDetect(t *testing.T) {
+	port := freePort(t)
+	mgr, err := simulate.Start(&simulate.Config{
+		Namespace:  map[string]simulate.NamespaceConfig{"t": {Port: port, IdleSeconds: 60, MaxConn: 10}},
+		DeviceType: map[string]simulate.DeviceTypeConfig{"cisco_ios": {PromptSuffix: "#"}, "huawei": {PromptSuffix: ">"}},
+		DeviceName: map[string]simulate.DeviceNameConfig{
+			"sw-01": {DeviceType: "cisco_ios"},
+			"sw-02": {DeviceType: "huawei"},
+			"sw-03": {DeviceType: "cisco_ios"},
+			"sw-04": {DeviceType: "huawei"},
+		},
+		Scenarios: []simulate.ScenarioConfig{
+			{DeviceName: "sw-01", Command: "show version", Responses: []simulate.ScenarioResponse{{Output: factsIOSVersion}}},
+			{DeviceName: "sw-02", Command: "display version", Responses: []simulate.ScenarioResponse{{Output: factsVRPVersion}}},
+			{DeviceName: "sw-02", Command: "display clock", Responses: []simulate.ScenarioResponse{{Output: "2026-01-01 00:00:00"}}},
+		},
+	})
+	require.NoError(t, err)
+	t.Cleanup(mgr.Stop)
+
+	load := func(extra string) *service.CollectorService {
+		cfgPath := filepath.Join(t.TempDir(), "config.yaml")
+		require.NoError(t, os.WriteFile(cfgPath, []byte(`
+collector:
+  device_defaults:
+    cisco_ios:
+      prompt_suffixes: ["#"]
+      command_interval_ms: 10
+    huawei:
+      prompt_suffixes: [">"]
+      command_interval_ms: 10
+`+extra), 0o600))
+		cfg, err := config.Load(cfgPath)
+		require.NoError(t, err)
+		svc := service.NewCollectorService(cfg)
+		require.NoError(t, svc.Start(context.Background()))
+		t.Cleanup(func() { svc.Stop() })
+		return svc
+	}
+	collect := func(svc *service.CollectorService, ctx context.Context, user, platform string) *service.CollectResponse {
+		resp, err := svc.ExecuteTask(ctx, &service.CollectRequest{
+			TaskID: "detect-" + user, DeviceIP: "127.0.0.1", Port: port, DevicePlatform: platform,
+			UserName: user, Password: "nova", CliList: service.NewCLIList("display clock"),
+		})
+		require.NoError(t, err)
+		return resp
+	}
+
+	// 模拟器所有设备共用同一端口，关闭缓存以区分设备
+	svc := load(`
+  platform_detect:
+    enable: true
+    cache_ttl: 0
+`)
+	resp := collect(svc, context.Background(), "sw-02", "auto")
+	require.True(t, resp.Success, resp.Error)
+	assert.Equal(t, "huawei", resp.DetectedPlatform)
+	require.Len(t, resp.Results, 1)
+	assert.Contains(t, resp.Results[0].RawOutput, "2026-01-01")
+
+	resp = collect(svc, context.Background(), "sw-01", "")
+	assert.Equal(t, "cisco_ios", resp.DetectedPlatform)
+
+	// 已指定平台不探测
+	resp = collect(svc, context.Background(), "sw-02", "huawei")
+	assert.Empty(t, resp.DetectedPlatform)
+
+	// 未识别：告警并按 default 平台执行
+	ctx, warnings := service.WithWarnings(context.Background())
+	resp = collect(svc, ctx, "sw-03", "auto")
+	assert.Empty(t, resp.DetectedPlatform)
+	require.Equal(t, 1, warnings.Len())
+	assert.Equal(t, service.WarnPlatformUndetected, warnings.List()[0].Code)
+
+	// 关闭探测时空平台不探测，auto 仍探测；结果按 设备IP:端口 缓存（sw-04 无版本输出，命中缓存）
+	svc = load(`
+  platform_detect:
+    enable: false
+    cache_ttl: 1m
+`)
+	assert.Empty(t, collect(svc, context.Background(), "sw-02", "").DetectedPlatform)
+	assert.Equal(t, "huawei", collect(svc, context.Background(), "sw-02", "auto").DetectedPlatform)
+	assert.Equal(t, "huawei", collect(svc, context.Background(), "sw-04", "auto").DetectedPlatform)
+}
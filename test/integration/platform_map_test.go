@@ -0,0 +1,38 @@
+package integration
+
+import (
+	"testing"
+
+	"github.com/sshcollectorpro/sshcollectorpro/internal/config"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/service"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestNormalizePlatform 平台键优先，其次按顺序匹配映射规则；未命中返回小写原值
+func TestNormalizePlatform(t *testing.T) {
+	cfg := &config.Config{Collector: config.CollectorConfig{
+		DeviceDefaults: map[string]config.PlatformDefaultsConfig{"cisco_ios": {}, "huawei": {}, "cisco_nxos": {}},
+		PlatformMappings: []config.PlatformMappingConfig{
+			{Pattern: `nx-?os`, Platform: "cisco_nxos"},
+			{Pattern: `^cisco\s+ios(-xe)?\b`, Platform: "Cisco_IOS"},
+			{Pattern: `vrp|huawei`, Platform: "huawei"},
+		},
+	}}
+	cases := []struct {
+		raw, want string
+		ok        bool
+	}{
+		{"Cisco_IOS", "cisco_ios", true},
+		{"Cisco IOS-XE 17.3", "cisco_ios", true},
+		{"cisco ios 15.2", "cisco_ios", true},
+		{"Cisco NX-OS 9.3", "cisco_nxos", true},
+		{"Huawei VRP V200R019", "huawei", true},
+		{"", "", true},
+		{"Juniper JunOS 21.2", "juniper junos 21.2", false},
+	}
+	for _, c := range cases {
+		got, ok := service.NormalizePlatform(cfg, c.raw)
+		assert.Equal(t, c.want, got, c.raw)
+		assert.Equal(t, c.ok, ok, c.raw)
+	}
+}
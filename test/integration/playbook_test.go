@@ -0,0 +1,36 @@
+package integration
+
+import (
+	"testing"
+
+	"github.com/sshcollectorpro/sshcollectorpro/internal/service"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPlaybookValidate(t *testing.T) {
+	pb := &service.Playbook{Name: "daily-health-check", Commands: service.NewCLIList("show version")}
+	assert.NoError(t, pb.Validate())
+
+	assert.Error(t, (&service.Playbook{Name: "bad name", Commands: service.NewCLIList("show version")}).Validate())
+	assert.Error(t, (&service.Playbook{Name: "empty", Commands: service.NewCLIList(" ")}).Validate())
+	assert.Error(t, (&service.Playbook{Name: "empty-variant", Commands: service.NewCLIList("show version"),
+		Variants: map[string]service.CLIList{"huawei_vrp": {}}}).Validate())
+	// 仅提供平台变体也有效
+	assert.NoError(t, (&service.Playbook{Name: "vrp-only", Variants: map[string]service.CLIList{"huawei_vrp": service.NewCLIList("display version")}}).Validate())
+}
+
+// TestPlaybookExpand 平台变体优先；额外命令追加在后且跳过重复
+func TestPlaybookExpand(t *testing.T) {
+	pb := &service.Playbook{
+		Name:     "daily-health-check",
+		Commands: service.NewCLIList("show version", "show clock"),
+		Variants: map[string]service.CLIList{"huawei_vrp": service.NewCLIList("display version")},
+	}
+	assert.Equal(t, []string{"show version", "show clock"}, pb.Expand("cisco_ios", nil).Commands())
+	assert.Equal(t, []string{"display version"}, pb.Expand("Huawei_VRP", nil).Commands())
+
+	extra := service.CLIList{{CLI: "show clock"}, {CLI: "show logging", Timeout: 120}}
+	out := pb.Expand("cisco_ios", extra)
+	assert.Equal(t, []string{"show version", "show clock", "show logging"}, out.Commands())
+	assert.Equal(t, 120, out[2].Timeout)
+}
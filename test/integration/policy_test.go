@@ -0,0 +1,89 @@
+package integration
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sshcollectorpro/sshcollectorpro/api/handler"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/config"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestReadOnlyBlocksConfigModeAbbreviations 只读模式按规范化词序列拦截配置模式命令及厂商缩写
+func TestReadOnlyBlocksConfigModeAbbreviations(t *testing.T) {
+	service.SetReadOnly(true)
+	defer service.SetReadOnly(false)
+	cfg := &config.Config{Collector: config.CollectorConfig{DeviceDefaults: map[string]config.PlatformDefaultsConfig{
+		"olt_x": {ConfigModeCLIs: []string{"enter config"}},
+	}}}
+
+	blocked := []string{
+		"configure terminal", "conf t", "conf term", "configure t", "config terminal", "CONFIG  T",
+		"configure", "configure exclusive", "configure private", "  Conf   T ",
+		"system-view", "sys", "system", "system-v", "edit", "edit interfaces",
+	}
+	for _, c := range blocked {
+		err := service.CheckCommandsAllowed(cfg, "cisco_ios", []string{"show version", c})
+		assert.True(t, errors.Is(err, service.ErrReadOnly), "expected %q to be blocked", c)
+	}
+	// 平台 config_mode_clis 按词序列前缀匹配
+	assert.Error(t, service.CheckCommandsAllowed(cfg, "olt_x", []string{"Enter  Config mode"}))
+
+	allowed := []string{
+		"show running-config", "display current-configuration", "show configuration", "con",
+		"display system", "show system", "sysname", "exit", "enter",
+	}
+	for _, c := range allowed {
+		assert.NoError(t, service.CheckCommandsAllowed(cfg, "olt_x", []string{c}), "expected %q to be allowed", c)
+	}
+
+	// 命令内嵌换行在设备上逐行执行，逐行校验
+	for _, c := range []string{"show clock\nconfigure terminal\ninterface x\nshutdown", "show clock\r\nconf t", "show clock\rsys", "show version\n\n  Enter config"} {
+		err := service.CheckCommandsAllowed(cfg, "olt_x", []string{c})
+		assert.True(t, errors.Is(err, service.ErrReadOnly), "expected %q to be blocked", c)
+	}
+	assert.NoError(t, service.CheckCommandsAllowed(cfg, "olt_x", []string{"show clock\nshow version\r\n"}))
+
+	service.SetReadOnly(false)
+	assert.NoError(t, service.CheckCommandsAllowed(cfg, "cisco_ios", []string{"conf t"}))
+}
+
+// TestReadOnlyToggleRequiresAdminToken 只读开关的切换需管理令牌，查询不需要
+func TestReadOnlyToggleRequiresAdminToken(t *testing.T) {
+	service.SetReadOnly(true)
+	defer service.SetReadOnly(false)
+	cfgPath := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(cfgPath, []byte("debug:\n  token: admin-secret\n"), 0o600))
+	_, err := config.Load(cfgPath)
+	require.NoError(t, err)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	ah := handler.NewAdminHandler()
+	r.GET("/read-only", ah.GetReadOnly)
+	r.PUT("/read-only", ah.UpdateReadOnly)
+	do := func(method, token string) int {
+		req := httptest.NewRequest(method, "/read-only", bytes.NewReader([]byte(`{"read_only":false}`)))
+		if token != "" {
+			req.Header.Set("X-Admin-Token", token)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusOK, do(http.MethodGet, ""))
+	assert.Equal(t, http.StatusUnauthorized, do(http.MethodPut, ""))
+	assert.Equal(t, http.StatusUnauthorized, do(http.MethodPut, "guess"))
+	assert.True(t, service.IsReadOnly())
+	assert.Equal(t, http.StatusOK, do(http.MethodPut, "admin-secret"))
+	assert.False(t, service.IsReadOnly())
+}
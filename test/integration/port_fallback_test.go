@@ -0,0 +1,77 @@
+package integration
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sshcollectorpro/sshcollectorpro/internal/config"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/service"
+	"github.com/sshcollectorpro/sshcollectorpro/simulate"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakePortInventory struct {
+	candidates []int
+	recorded   [][3]interface{}
+}
+
+func (f *fakePortInventory) PortCandidates(ip string, port int) []int { return f.candidates }
+
+func (f *fakePortInventory) RecordConnectedPort(ip string, port, connected int) error {
+	f.recorded = append(f.recorded, [3]interface{}{ip, port, connected})
+	return nil
+}
+
+// TestPortCandidates 首选端口拒绝连接时按顺序尝试备用端口（请求与资产登记），记录并回写连接成功的端口
+func TestPortCandidates(t *testing.T) {
+	port := freePort(t)
+	mgr, err := simulate.Start(&simulate.Config{
+		Namespace:  map[string]simulate.NamespaceConfig{"t": {Port: port, IdleSeconds: 60, MaxConn: 10}},
+		DeviceType: map[string]simulate.DeviceTypeConfig{"cisco_ios": {PromptSuffix: "#"}},
+		DeviceName: map[string]simulate.DeviceNameConfig{"sw-01": {DeviceType: "cisco_ios"}},
+	})
+	require.NoError(t, err)
+	defer mgr.Stop()
+	refused, refused2 := freePort(t), freePort(t)
+
+	cfgPath := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(cfgPath, []byte(`
+collector:
+  device_defaults:
+    cisco_ios:
+      prompt_suffixes: ["#"]
+      command_interval_ms: 10
+`), 0o600))
+	cfg, err := config.Load(cfgPath)
+	require.NoError(t, err)
+	collector := service.NewCollectorService(cfg)
+	require.NoError(t, collector.Start(context.Background()))
+	defer collector.Stop()
+
+	collect := func(id string, candidates []int) *service.CollectResponse {
+		resp, err := collector.ExecuteTask(context.Background(), &service.CollectRequest{
+			TaskID: id, DeviceIP: "127.0.0.1", Port: refused, DeviceName: "sw-01", DevicePlatform: "cisco_ios",
+			UserName: "sw-01", Password: "nova", CliList: service.NewCLIList("show version"), PortCandidates: candidates,
+		})
+		require.NoError(t, err)
+		return resp
+	}
+
+	resp := collect("port-1", []int{refused2, port})
+	require.True(t, resp.Success, resp.Error)
+	assert.Equal(t, port, resp.ConnectedPort)
+
+	// 无备用端口时返回连接错误
+	assert.False(t, collect("port-2", nil).Success)
+
+	// 资产登记的备用端口在请求之后尝试，连接成功后回写
+	inv := &fakePortInventory{candidates: []int{port}}
+	collector.SetPortInventory(inv)
+	resp = collect("port-3", []int{refused2})
+	require.True(t, resp.Success, resp.Error)
+	assert.Equal(t, port, resp.ConnectedPort)
+	assert.Equal(t, [][3]interface{}{{"127.0.0.1", refused, port}}, inv.recorded)
+}
@@ -0,0 +1,27 @@
+package integration
+
+import (
+	"testing"
+
+	"github.com/sshcollectorpro/sshcollectorpro/pkg/ssh"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestPromptRegexOverridesSuffixes 配置正则后横幅中以 > / # 结尾的行不再被误判为提示符
+func TestPromptRegexOverridesSuffixes(t *testing.T) {
+	suffixes := []string{"#", ">", "]"}
+	banner := "Unauthorized access is prohibited ##########"
+	assert.True(t, ssh.MatchPrompt(nil, suffixes, banner))
+
+	regex := []string{`^[\w.-]+(\([\w-]+\))?[#>]$`}
+	assert.False(t, ssh.MatchPrompt(regex, suffixes, banner))
+	assert.False(t, ssh.MatchPrompt(regex, suffixes, "Press <Enter> to continue >"))
+	assert.True(t, ssh.MatchPrompt(regex, suffixes, "core-sw01#"))
+	assert.True(t, ssh.MatchPrompt(regex, suffixes, "core-sw01(config)#"))
+	assert.True(t, ssh.MatchPrompt(regex, suffixes, "  edge-r1>  "))
+}
+
+func TestValidatePromptRegex(t *testing.T) {
+	assert.NoError(t, ssh.ValidatePromptRegex([]string{`^<[\w-]+>$`}))
+	assert.Error(t, ssh.ValidatePromptRegex([]string{`^[unclosed`}))
+}
@@ -0,0 +1,99 @@
+package integration
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sshcollectorpro/sshcollectorpro/api/handler"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/config"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestRequestExamplesAndStrictJSON 规范示例均能通过严格校验；拼错的字段与类型错误以 JSON 指针返回
+func TestRequestExamplesAndStrictJSON(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/examples", handler.ListRequestExamples)
+	r.GET("/examples/:endpoint", handler.GetRequestExample)
+	r.POST("/examples/:endpoint/validate", handler.ValidateRequestExample)
+	collector := handler.NewCollectorHandler(service.NewCollectorService(&config.Config{}))
+	r.POST("/collector/fast", collector.FastCollect)
+	r.POST("/collector/batch/custom", collector.BatchExecuteCustomer)
+
+	do := func(method, path, body string, header map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		for k, v := range header {
+			req.Header.Set(k, v)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+	type errorBody struct {
+		Code   string               `json:"code"`
+		Errors []handler.FieldError `json:"errors"`
+	}
+	decodeErr := func(w *httptest.ResponseRecorder) errorBody {
+		var out errorBody
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &out), w.Body.String())
+		return out
+	}
+
+	w := do(http.MethodGet, "/examples", "", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	var list struct {
+		Data []handler.RequestExample `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &list))
+	require.NotEmpty(t, list.Data)
+	for _, ex := range list.Data {
+		assert.Empty(t, ex.Body, ex.Endpoint)
+		w := do(http.MethodGet, "/examples/"+ex.Endpoint, "", nil)
+		require.Equal(t, http.StatusOK, w.Code, ex.Endpoint)
+		var got struct {
+			Data handler.RequestExample `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &got))
+		require.NotEmpty(t, got.Data.Body, ex.Endpoint)
+		w = do(http.MethodPost, "/examples/"+ex.Endpoint+"/validate", string(got.Data.Body), nil)
+		assert.Equal(t, http.StatusOK, w.Code, "%s: %s", ex.Endpoint, w.Body.String())
+	}
+	assert.Equal(t, http.StatusNotFound, do(http.MethodGet, "/examples/nope", "", nil).Code)
+
+	// 拼错的列表字段与设备字段均被指出；旧字段名 ip 仍视为已知字段
+	w = do(http.MethodPost, "/examples/collector-batch-custom/validate",
+		`{"task_id": "t1", "devices_list": [], "devices": [{"ip": "10.0.0.1", "user_nmae": "admin", "device_port": "22"}]}`, nil)
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	body := decodeErr(w)
+	assert.Equal(t, "INVALID_PARAMS", body.Code)
+	assert.Equal(t, []handler.FieldError{
+		{Pointer: "/devices/0/device_port", Message: "expected integer, got string"},
+		{Pointer: "/devices/0/user_nmae", Message: "unknown field"},
+		{Pointer: "/devices_list", Message: "unknown field"},
+	}, body.Errors)
+
+	// 任务接口：默认忽略未知字段，请求头开启严格模式后拒绝
+	typo := `{"task_id": "t1", "devices_list": [{"device_ip": "10.0.0.1"}]}`
+	w = do(http.MethodPost, "/collector/batch/custom", typo, map[string]string{handler.StrictJSONHeader: "true"})
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, []handler.FieldError{{Pointer: "/devices_list", Message: "unknown field"}}, decodeErr(w).Errors)
+	w = do(http.MethodPost, "/collector/batch/custom", typo, nil)
+	assert.Empty(t, decodeErr(w).Errors, w.Body.String())
+
+	// 类型错误无论是否严格模式都返回字段指针
+	w = do(http.MethodPost, "/collector/fast", `{"device_ip": "10.0.0.1", "device_port": "22", "cli_list": ["show clock"]}`, nil)
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, []handler.FieldError{{Pointer: "/device_port", Message: "expected integer, got string"}}, decodeErr(w).Errors)
+	w = do(http.MethodPost, "/collector/fast", `{"device_ip": `, nil)
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	errs := decodeErr(w).Errors
+	require.Len(t, errs, 1)
+	assert.Empty(t, errs[0].Pointer)
+}
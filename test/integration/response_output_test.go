@@ -0,0 +1,55 @@
+package integration
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/sshcollectorpro/sshcollectorpro/internal/config"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestResponseOutputModes 批量响应原始输出的 omit / truncate / uri 处理
+func TestResponseOutputModes(t *testing.T) {
+	cfg := &config.Config{BatchResponse: config.BatchResponseConfig{RawOutputMode: "truncate", MaxOutputKB: 1}}
+
+	// 请求参数优先于配置，非法模式报错
+	ro, err := service.ResolveResponseOutput(cfg, "", 0)
+	require.NoError(t, err)
+	assert.Equal(t, service.ResponseOutput{Mode: service.RawOutputTruncate, MaxKB: 1}, ro)
+	ro, err = service.ResolveResponseOutput(cfg, "OMIT", 0)
+	require.NoError(t, err)
+	assert.True(t, ro.RequiresStore())
+	_, err = service.ResolveResponseOutput(cfg, "gzip", 0)
+	assert.Error(t, err)
+	ro, _ = service.ResolveResponseOutput(nil, "", 0)
+	assert.Equal(t, service.RawOutputFull, ro.Mode)
+
+	big := strings.Repeat("中", 500) // 1500 字节
+	views := []*service.CommandResultView{{RawOutput: big}, {RawOutput: "short"}}
+	service.ResponseOutput{Mode: service.RawOutputTruncate, MaxKB: 1}.ApplyCollect(views)
+	assert.True(t, views[0].RawOutputTruncated)
+	assert.Equal(t, 1500, views[0].RawOutputSize)
+	assert.True(t, strings.HasPrefix(views[0].RawOutput, strings.Repeat("中", 341)+"\n..."))
+	assert.False(t, views[1].RawOutputTruncated)
+	assert.Equal(t, "short", views[1].RawOutput)
+
+	// uri：有落盘对象时返回 URI，否则退化为截断
+	views = []*service.CommandResultView{
+		{RawOutput: big, StoredObjects: []service.StoredObject{{URI: "file:///data/a.txt"}}},
+		{RawOutput: big},
+	}
+	service.ResponseOutput{Mode: service.RawOutputURI, MaxKB: 1}.ApplyCollect(views)
+	assert.Equal(t, "", views[0].RawOutput)
+	assert.Equal(t, "file:///data/a.txt", views[0].RawOutputURI)
+	assert.True(t, views[1].RawOutputTruncated)
+
+	devices := []service.DeviceBackupResponse{{Results: []service.CommandBackupResult{{RawOutput: "a\nb", RawOutputLines: []string{"a", "b"}}}}}
+	service.ResponseOutput{Mode: service.RawOutputOmit, MaxKB: 1}.ApplyBackup(devices)
+	r := devices[0].Results[0]
+	assert.True(t, r.RawOutputOmitted)
+	assert.Equal(t, 3, r.RawOutputSize)
+	assert.Empty(t, r.RawOutput)
+	assert.Empty(t, r.RawOutputLines)
+}
@@ -0,0 +1,98 @@
+package integration
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sshcollectorpro/sshcollectorpro/internal/config"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/service"
+	"github.com/sshcollectorpro/sshcollectorpro/simulate"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestRetryPolicyBackoffAndRetryOn 指数退避按策略等待并记录尝试；retry_on=connect 时认证失败不重试；超出 max_elapsed 提前结束
+func TestRetryPolicyBackoffAndRetryOn(t *testing.T) {
+	port := freePort(t)
+	mgr, err := simulate.Start(&simulate.Config{
+		Namespace:  map[string]simulate.NamespaceConfig{"t": {Port: port, IdleSeconds: 60, MaxConn: 10}},
+		DeviceType: map[string]simulate.DeviceTypeConfig{"cisco_ios": {PromptSuffix: "#"}},
+		DeviceName: map[string]simulate.DeviceNameConfig{"sw-01": {DeviceType: "cisco_ios"}},
+		Scenarios: []simulate.ScenarioConfig{
+			{DeviceName: "sw-01", Command: "show clock", Responses: []simulate.ScenarioResponse{{Output: "10:00:00 UTC"}}},
+		},
+	})
+	require.NoError(t, err)
+	t.Cleanup(mgr.Stop)
+
+	cfgPath := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(cfgPath, []byte(`
+ssh:
+  connect_timeout: 2s
+collector:
+  retry_policy:
+    backoff: exponential
+    initial_delay: 20ms
+    multiplier: 3
+  device_defaults:
+    cisco_ios:
+      prompt_suffixes: ["#"]
+      command_interval_ms: 10
+`), 0o600))
+	cfg, err := config.Load(cfgPath)
+	require.NoError(t, err)
+	svc := service.NewCollectorService(cfg)
+	require.NoError(t, svc.Start(context.Background()))
+	t.Cleanup(func() { svc.Stop() })
+
+	retries := 2
+	collect := func(id string, devPort int, password string, policy *service.RetryPolicy) *service.CollectResponse {
+		resp, err := svc.ExecuteTask(context.Background(), &service.CollectRequest{
+			TaskID: id, DeviceIP: "127.0.0.1", Port: devPort, DevicePlatform: "cisco_ios", RetryFlag: &retries, RetryPolicy: policy,
+			UserName: "sw-01", Password: password, CliList: service.NewCLIList("show clock"),
+		})
+		require.NoError(t, err)
+		return resp
+	}
+
+	// 端口未监听：按配置的指数退避重试两次
+	closed := freePort(t)
+	resp := collect("rp-unreachable", closed, "nova", nil)
+	assert.False(t, resp.Success)
+	require.Len(t, resp.Attempts, 3)
+	assert.Equal(t, []int64{0, 20, 60}, []int64{resp.Attempts[0].DelayMS, resp.Attempts[1].DelayMS, resp.Attempts[2].DelayMS})
+	for i, a := range resp.Attempts {
+		assert.Equal(t, i+1, a.Attempt)
+		assert.NotEmpty(t, a.Error)
+	}
+
+	// 仅重试建连失败：认证失败只尝试一次
+	resp = collect("rp-auth", port, "retry-wrong", &service.RetryPolicy{RetryOn: "connect"})
+	assert.False(t, resp.Success)
+	require.Len(t, resp.Attempts, 1)
+	assert.Contains(t, resp.Attempts[0].Error, "unable to authenticate")
+
+	// 下一次等待将超出总时长上限时不再重试
+	resp = collect("rp-elapsed", closed, "nova", &service.RetryPolicy{Backoff: "constant", InitialDelayMS: 500, MaxElapsedMS: 100})
+	assert.False(t, resp.Success)
+	require.Len(t, resp.Attempts, 1)
+	assert.Contains(t, resp.Error, "max_elapsed")
+
+	// 首次即成功时不返回尝试记录
+	resp = collect("rp-ok", port, "nova", nil)
+	require.True(t, resp.Success, resp.Error)
+	assert.Nil(t, resp.Attempts)
+
+	_, err = svc.ExecuteTask(context.Background(), &service.CollectRequest{
+		TaskID: "rp-bad", DeviceIP: "127.0.0.1", Port: port, UserName: "sw-01", Password: "nova",
+		CliList: service.NewCLIList("show clock"), RetryPolicy: &service.RetryPolicy{RetryOn: "never"},
+	})
+	assert.ErrorIs(t, err, service.ErrValidation)
+
+	report, err := config.ValidateYAML([]byte("collector:\n  device_defaults:\n    cisco_ios:\n      prompt_suffixes: [\"#\"]\n      retry_policy:\n        jitter: 2\n"))
+	require.NoError(t, err)
+	require.NotEmpty(t, report.Errors())
+	assert.Equal(t, "collector.device_defaults.cisco_ios.retry_policy.jitter", report.Errors()[0].Path)
+}
@@ -0,0 +1,95 @@
+package integration
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/sshcollectorpro/sshcollectorpro/internal/config"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestRunbookValidate 步骤名唯一、条件只能引用前序步骤
+func TestRunbookValidate(t *testing.T) {
+	ok := []service.RunbookStep{
+		{Name: "collect", Type: "COLLECT", CliList: service.NewCLIList("show version")},
+		{Name: "check", Type: "compliance"},
+		{Name: "fix", Type: "deploy", When: &service.RunbookCondition{Outcome: "failed"}, Targets: "failed",
+			Deploy: &service.RunbookDeploy{ConfigTemplate: "ntp server 10.0.0.1"}},
+	}
+	require.NoError(t, service.ValidateRunbookSteps(ok))
+	assert.Equal(t, service.RunbookStepCollect, ok[0].Type)
+
+	bad := [][]service.RunbookStep{
+		nil,
+		{{Name: "a", Type: "collect"}},
+		{{Name: "a", Type: "reboot"}},
+		{{Name: "a", Type: "compliance"}, {Name: "a", Type: "compliance"}},
+		{{Name: "a", Type: "compliance", When: &service.RunbookCondition{Step: "b", Outcome: "failed"}}, {Name: "b", Type: "compliance"}},
+		{{Name: "a", Type: "compliance", When: &service.RunbookCondition{Outcome: "maybe"}}},
+		{{Name: "a", Type: "compliance", Targets: "some"}},
+		{{Name: "a", Type: "deploy"}},
+		{{Name: "a", Type: "notify", CallbackURL: "ftp://example.com"}},
+	}
+	for i, steps := range bad {
+		assert.Error(t, service.ValidateRunbookSteps(steps), i)
+	}
+}
+
+// TestRunbookExecute 条件跳过、按失败设备通知，已结束步骤续跑时不重复执行
+func TestRunbookExecute(t *testing.T) {
+	var got service.CallbackPayload
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&got)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	steps := []service.RunbookStep{
+		{Name: "collect", Type: "collect", CliList: service.NewCLIList("show clock")},
+		{Name: "fix", Type: "deploy", Deploy: &service.RunbookDeploy{CliList: service.NewDeployCLIList("ntp server 10.0.0.1")}},
+		{Name: "alert", Type: "notify", When: &service.RunbookCondition{Step: "collect", Outcome: "failed"}, Targets: "failed", CallbackURL: srv.URL},
+		{Name: "done", Type: "notify", When: &service.RunbookCondition{Outcome: "always"}, Targets: "succeeded", CallbackURL: srv.URL},
+	}
+	require.NoError(t, service.ValidateRunbookSteps(steps))
+	devices := []service.RunbookDevice{{DeviceIP: "192.0.2.1", DevicePlatform: "cisco_ios"}, {DeviceIP: "192.0.2.2", Port: 2222}}
+
+	// 未注入采集服务：采集步骤所有设备失败
+	svc := service.NewRunbookService(&config.Config{}, nil, nil, nil)
+	saves := 0
+	res := svc.Execute(context.Background(), "rb-1", "ntp", steps, devices, nil, func([]service.RunbookStepResult) { saves++ })
+	require.Len(t, res, 4)
+	assert.Equal(t, service.RunbookFailed, res[0].Status)
+	assert.Equal(t, 2, res[0].Failed)
+	assert.Equal(t, "rb-1-s1", res[0].TaskID)
+	assert.Equal(t, 2222, res[0].Devices[1].Port)
+	assert.Equal(t, service.RunbookSkipped, res[1].Status)
+	assert.Contains(t, res[1].Message, "condition not met")
+	assert.Equal(t, service.RunbookSucceeded, res[2].Status)
+	assert.Equal(t, service.RunbookSucceeded, res[3].Status)
+	assert.Equal(t, service.RunbookRunCompleted, service.RunbookRunStatus(res))
+	assert.Greater(t, saves, 4)
+
+	// 最后一次回调：引用上一步（alert，无设备结果），成功设备为空
+	assert.Equal(t, "runbook", got.TaskType)
+	assert.Equal(t, "rb-1-s4", got.TaskID)
+	assert.Empty(t, got.Devices)
+
+	// 续跑：仅执行未结束的步骤，按失败设备通知
+	res = service.NewRunbookResults(steps)
+	res[0] = service.RunbookStepResult{Name: "collect", Type: "collect", Status: service.RunbookFailed,
+		Devices: []service.RunbookDeviceOutcome{{DeviceIP: "192.0.2.1", Port: 22, Success: true}, {DeviceIP: "192.0.2.2", Port: 2222, Error: "timeout"}}}
+	res[1].Status = service.RunbookSkipped
+	res[3].Status = service.RunbookSkipped
+	assert.Equal(t, service.RunbookRunInterrupted, service.RunbookRunStatus(res))
+	res = svc.Execute(context.Background(), "rb-2", "ntp", steps, devices, res, nil)
+	assert.Equal(t, service.RunbookSucceeded, res[2].Status)
+	require.Len(t, got.Devices, 1)
+	assert.Equal(t, "192.0.2.2", got.Devices[0].DeviceIP)
+	assert.Equal(t, "timeout", got.Devices[0].Error)
+	assert.Equal(t, 1, got.FailedCount)
+}
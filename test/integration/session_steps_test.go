@@ -0,0 +1,127 @@
+package integration
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sshcollectorpro/sshcollectorpro/internal/config"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/service"
+	"github.com/sshcollectorpro/sshcollectorpro/simulate"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// startSessionStepsBackup 模拟设备与按给定平台配置启动的备份服务（会话转录用于核对实际发送的命令）
+func startSessionStepsBackup(t *testing.T, platformYAML string) (*service.BackupService, int) {
+	port := freePort(t)
+	mgr, err := simulate.Start(&simulate.Config{
+		Namespace:  map[string]simulate.NamespaceConfig{"t": {Port: port, IdleSeconds: 60, MaxConn: 10}},
+		DeviceType: map[string]simulate.DeviceTypeConfig{"zte_olt": {PromptSuffix: "#"}},
+		DeviceName: map[string]simulate.DeviceNameConfig{"olt-01": {DeviceType: "zte_olt"}},
+		Scenarios: []simulate.ScenarioConfig{
+			{Command: "show clock", Responses: []simulate.ScenarioResponse{{Output: "10:00:00 UTC Fri Oct 16 2026\n"}}},
+			{Command: "cls", Responses: []simulate.ScenarioResponse{{Output: "SCREEN CLEARED\n"}}},
+			{Command: "terminal no monitor", Responses: []simulate.ScenarioResponse{{Output: "MONITOR OFF\n"}}},
+		},
+	})
+	require.NoError(t, err)
+	t.Cleanup(mgr.Stop)
+
+	cfgPath := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(cfgPath, []byte(`
+backup:
+  prefix: ""
+  aggregate:
+    enabled: false
+  local:
+    base_dir: `+t.TempDir()+`
+collector:
+  device_defaults:
+    zte_olt:
+      prompt_suffixes: ["#"]
+      command_interval_ms: 10
+`+platformYAML), 0o600))
+	cfg, err := config.Load(cfgPath)
+	require.NoError(t, err)
+	svc := service.NewBackupService(cfg)
+	require.NoError(t, svc.Start(context.Background()))
+	t.Cleanup(func() { svc.Stop() })
+	return svc, port
+}
+
+func backupWithTranscript(t *testing.T, svc *service.BackupService, port int, cmds ...string) (service.DeviceBackupResponse, string) {
+	on := true
+	resp, err := svc.ExecuteBatch(context.Background(), &service.BackupBatchRequest{
+		TaskID:     "bk-steps",
+		Transcript: &on,
+		Devices: []service.BackupDevice{{
+			DeviceIP: "127.0.0.1", Port: port, DeviceName: "olt-01", DevicePlatform: "zte_olt",
+			UserName: "olt-01", Password: "nova", CliList: service.NewCLIList(cmds...),
+		}},
+	})
+	require.NoError(t, err)
+	require.Len(t, resp.Data, 1)
+	r := resp.Data[0]
+	if r.TranscriptObject == nil {
+		return r, ""
+	}
+	data, err := os.ReadFile(strings.TrimPrefix(r.TranscriptObject.URI, "file://"))
+	require.NoError(t, err)
+	return r, string(data)
+}
+
+// TestLoginSequence 登录序列在首个提示符后、用户命令前执行，其输出不计入命令结果
+func TestLoginSequence(t *testing.T) {
+	svc, port := startSessionStepsBackup(t, `      login_sequence:
+        - send: "cls"
+          expect: "screen cleared"
+`)
+
+	r, transcript := backupWithTranscript(t, svc, port, "show clock")
+	require.True(t, r.Success, r.Error)
+	require.Len(t, r.Results, 1)
+	assert.Contains(t, r.Results[0].RawOutput, "10:00:00 UTC")
+	assert.NotContains(t, r.Results[0].RawOutput, "SCREEN CLEARED")
+
+	login := strings.Index(transcript, ` > "cls`)
+	user := strings.Index(transcript, ` > "show clock`)
+	require.True(t, login >= 0 && user >= 0, transcript)
+	assert.Less(t, login, user)
+}
+
+// TestLoginSequenceStepTimeout 登录步骤等待期望文本超时则会话失败，错误包含步骤序号与期望文本
+func TestLoginSequenceStepTimeout(t *testing.T) {
+	svc, port := startSessionStepsBackup(t, `      login_sequence:
+        - send: "cls"
+          expect: "(config)#"
+          timeout_sec: 1
+`)
+	r, _ := backupWithTranscript(t, svc, port, "show clock")
+	assert.False(t, r.Success)
+	assert.Contains(t, r.Error, "login step 1")
+	assert.Contains(t, r.Error, "(config)#")
+}
+
+// TestPostCommands 清理命令在用户命令后执行，输出不计入结果；用户已显式执行的清理命令不重复发送
+func TestPostCommands(t *testing.T) {
+	svc, port := startSessionStepsBackup(t, `      post_commands: ["terminal no monitor"]
+`)
+
+	r, transcript := backupWithTranscript(t, svc, port, "show clock")
+	require.True(t, r.Success, r.Error)
+	require.Len(t, r.Results, 1)
+	assert.Equal(t, "show clock", r.Results[0].Command)
+	assert.NotContains(t, r.Results[0].RawOutput, "MONITOR OFF")
+
+	user := strings.Index(transcript, ` > "show clock`)
+	post := strings.Index(transcript, ` > "terminal no monitor`)
+	require.True(t, user >= 0 && post >= 0, transcript)
+	assert.Less(t, user, post)
+
+	r, transcript = backupWithTranscript(t, svc, port, "show clock", "terminal no monitor")
+	require.True(t, r.Success, r.Error)
+	assert.Equal(t, 1, strings.Count(transcript, ` > "terminal no monitor`), transcript)
+}
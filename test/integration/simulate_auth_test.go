@@ -0,0 +1,88 @@
+package integration
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sshcollectorpro/sshcollectorpro/pkg/ssh"
+	"github.com/sshcollectorpro/sshcollectorpro/simulate"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	xssh "golang.org/x/crypto/ssh"
+)
+
+// TestSimulateDeviceAuth 按设备配置口令、公钥登录与认证失败模式
+func TestSimulateDeviceAuth(t *testing.T) {
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	dir := t.TempDir()
+	require.NoError(t, os.Chdir(dir))
+	defer os.Chdir(wd)
+
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKCS8PrivateKey(priv)
+	require.NoError(t, err)
+	keyFile := filepath.Join(dir, "id_ed25519")
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0o600))
+	sshPub, err := xssh.NewPublicKey(pub)
+	require.NoError(t, err)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	port := ln.Addr().(*net.TCPAddr).Port
+	ln.Close()
+	mgr, err := simulate.Start(&simulate.Config{
+		Namespace: map[string]simulate.NamespaceConfig{"t": {Port: port, IdleSeconds: 60, MaxConn: 10}},
+		DeviceName: map[string]simulate.DeviceNameConfig{
+			"core-01":   {Password: "S3cret", MaxAuthTries: 1},
+			"key-01":    {AuthorizedKeys: []string{string(xssh.MarshalAuthorizedKey(sshPub))}},
+			"locked-01": {AuthFailure: simulate.AuthFailureReject},
+			"drop-01":   {AuthFailure: simulate.AuthFailureDisconnect},
+		},
+	})
+	require.NoError(t, err)
+	defer mgr.Stop()
+
+	connect := func(info ssh.ConnectionInfo) error {
+		info.Host, info.Port = "127.0.0.1", port
+		c := ssh.NewClient(&ssh.Config{ConnectTimeout: 3 * time.Second, Timeout: 5 * time.Second})
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		err := c.Connect(ctx, &info)
+		if err == nil {
+			c.Close()
+		}
+		return err
+	}
+
+	assert.NoError(t, connect(ssh.ConnectionInfo{Username: "core-01", Password: "S3cret"}))
+	err = connect(ssh.ConnectionInfo{Username: "core-01", Password: "nova"})
+	assert.Error(t, err)
+	// 未配置的设备仍使用默认口令
+	assert.NoError(t, connect(ssh.ConnectionInfo{Username: "other-01", Password: "nova"}))
+
+	assert.NoError(t, connect(ssh.ConnectionInfo{Username: "key-01", KeyFile: keyFile}))
+	assert.Error(t, connect(ssh.ConnectionInfo{Username: "core-01", KeyFile: keyFile}))
+
+	err = connect(ssh.ConnectionInfo{Username: "locked-01", Password: "nova"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "unable to authenticate")
+	assert.Error(t, connect(ssh.ConnectionInfo{Username: "drop-01", Password: "nova"}))
+}
+
+// TestSimulateLoadConfigAuthValidation 未知的 auth_failure 在加载时报错
+func TestSimulateLoadConfigAuthValidation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "simulate.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("device_name:\n  core-01:\n    device_type: cisco_ios\n    auth_failure: lockout\n"), 0o644))
+	_, err := simulate.LoadConfig(path)
+	assert.Error(t, err)
+}
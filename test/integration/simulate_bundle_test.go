@@ -0,0 +1,110 @@
+package integration
+
+import (
+	"bytes"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sshcollectorpro/sshcollectorpro/api/handler"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/config"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/database"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/model"
+	"github.com/sshcollectorpro/sshcollectorpro/simulate"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func freePort(t *testing.T) int {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer ln.Close()
+	return ln.Addr().(*net.TCPAddr).Port
+}
+
+// TestSimulateBundleExportImport 导出命名空间后改名导入，配置合并保留注释并热更新运行中的模拟服务
+func TestSimulateBundleExportImport(t *testing.T) {
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	dir := t.TempDir()
+	require.NoError(t, os.Chdir(dir))
+	defer os.Chdir(wd)
+	require.NoError(t, database.InitSQLite(config.SQLiteConfig{Path: filepath.Join(dir, "collector.db")}))
+	defer database.Close()
+	require.NoError(t, database.GetDB().Create(&model.SimDeviceCommand{Namespace: "lab", DeviceName: "sw-01", Command: "display ip", Output: "1.1.1.1"}).Error)
+
+	port, port2 := freePort(t), freePort(t)
+	cfgYAML := "# lab simulator\nnamespace:\n  lab:\n    port: " + strconv.Itoa(port) + "\n    max_conn: 5\n" +
+		"device_type:\n  huawei:\n    prompt_suffixe: \">\"\ndevice_name:\n  sw-01:\n    device_type: huawei\n" +
+		"scenarios:\n  - namespace: lab\n    device_name: sw-01\n    command: display version\n    responses:\n      - output: v1\n"
+	require.NoError(t, os.MkdirAll(simulate.DeviceDir("lab", "sw-01"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join("simulate", "simulate.yaml"), []byte(cfgYAML), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(simulate.DeviceDir("lab", "sw-01"), "display_clock.txt"), []byte("10:00\n"), 0o644))
+	cfg, err := simulate.LoadConfig(filepath.Join("simulate", "simulate.yaml"))
+	require.NoError(t, err)
+	mgr, err := simulate.Start(cfg)
+	require.NoError(t, err)
+	defer mgr.Stop()
+
+	h := handler.NewSimulateConfigHandler()
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/simulate/bundles/:namespace", h.ExportBundle)
+	r.POST("/simulate/bundles", h.ImportBundle)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/simulate/bundles/missing", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/simulate/bundles/lab", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	bundle := w.Body.Bytes()
+
+	post := func(query string) (int, map[string]interface{}) {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/simulate/bundles"+query, bytes.NewReader(bundle)))
+		var body map[string]interface{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+		return w.Code, body
+	}
+	// 原命名空间已存在；端口与现有命名空间冲突
+	code, _ := post("")
+	assert.Equal(t, http.StatusConflict, code)
+	code, _ = post("?namespace=lab2")
+	assert.Equal(t, http.StatusConflict, code)
+
+	code, body := post("?namespace=lab2&port=" + strconv.Itoa(port2) + "&device_map=sw-01=sw-02")
+	require.Equal(t, http.StatusOK, code, body)
+	assert.Equal(t, true, body["data"].(map[string]interface{})["reloaded"])
+
+	bs, err := os.ReadFile(filepath.Join(simulate.DeviceDir("lab2", "sw-02"), "display_clock.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "10:00\n", string(bs))
+	var rec model.SimDeviceCommand
+	require.NoError(t, database.GetDB().Where("namespace = ? AND device_name = ?", "lab2", "sw-02").First(&rec).Error)
+	assert.Equal(t, "1.1.1.1", rec.Output)
+	raw, err := os.ReadFile(filepath.Join("simulate", "simulate.yaml"))
+	require.NoError(t, err)
+	assert.Contains(t, string(raw), "# lab simulator")
+	merged, err := simulate.LoadConfig(filepath.Join("simulate", "simulate.yaml"))
+	require.NoError(t, err)
+	assert.Equal(t, port2, merged.Namespace["lab2"].Port)
+	assert.Equal(t, "huawei", merged.DeviceName["sw-02"].DeviceType)
+	require.Len(t, merged.Scenarios, 2)
+	assert.Equal(t, "lab2", merged.Scenarios[1].Namespace)
+	assert.Equal(t, "sw-02", merged.Scenarios[1].DeviceName)
+
+	var listening bool
+	for _, st := range mgr.Status() {
+		if st.Namespace == "lab2" {
+			listening = st.Listening
+		}
+	}
+	assert.True(t, listening)
+}
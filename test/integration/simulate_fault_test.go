@@ -0,0 +1,143 @@
+package integration
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sshcollectorpro/sshcollectorpro/api/handler"
+	"github.com/sshcollectorpro/sshcollectorpro/simulate"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	xssh "golang.org/x/crypto/ssh"
+)
+
+// startFaultSimulate 启动带故障注入参数的模拟命名空间，返回端口
+func startFaultSimulate(t *testing.T, ns simulate.NamespaceConfig) int {
+	ns.Port = freePort(t)
+	ns.IdleSeconds = 60
+	ns.MaxConn = 10
+	mgr, err := simulate.Start(&simulate.Config{
+		Namespace:  map[string]simulate.NamespaceConfig{"t": ns},
+		DeviceType: map[string]simulate.DeviceTypeConfig{"cisco_ios": {PromptSuffix: "#"}},
+		DeviceName: map[string]simulate.DeviceNameConfig{"sw-01": {DeviceType: "cisco_ios"}},
+		Scenarios: []simulate.ScenarioConfig{
+			{DeviceName: "sw-01", Command: "show clock", Responses: []simulate.ScenarioResponse{{Output: "10:00:00"}}},
+		},
+	})
+	require.NoError(t, err)
+	t.Cleanup(mgr.Stop)
+	return ns.Port
+}
+
+// execOnSimulate 以 exec 方式执行单条命令，返回输出、耗时与连接错误
+func execOnSimulate(t *testing.T, port int, cmd string) (string, time.Duration, error) {
+	client, err := xssh.Dial("tcp", net.JoinHostPort("127.0.0.1", strconv.Itoa(port)), &xssh.ClientConfig{
+		User: "sw-01", Auth: []xssh.AuthMethod{xssh.Password("nova")}, HostKeyCallback: xssh.InsecureIgnoreHostKey(), Timeout: 3 * time.Second,
+	})
+	require.NoError(t, err)
+	defer client.Close()
+	sess, err := client.NewSession()
+	require.NoError(t, err)
+	defer sess.Close()
+	start := time.Now()
+	out, err := sess.Output(cmd)
+	// 模拟服务的 exec 不回送退出码，视为正常结束
+	var missing *xssh.ExitMissingError
+	if errors.As(err, &missing) {
+		err = nil
+	}
+	return string(out), time.Since(start), err
+}
+
+// TestSimulateLatencyInjection 每条命令回显前等待 latency_ms ± jitter_ms
+func TestSimulateLatencyInjection(t *testing.T) {
+	port := startFaultSimulate(t, simulate.NamespaceConfig{LatencyMS: 300, JitterMS: 50})
+	for i := 0; i < 3; i++ {
+		out, elapsed, err := execOnSimulate(t, port, "show clock")
+		require.NoError(t, err)
+		assert.Contains(t, out, "10:00:00")
+		assert.GreaterOrEqual(t, elapsed, 250*time.Millisecond)
+		assert.Less(t, elapsed, 2*time.Second)
+	}
+
+	// 未配置故障注入时不等待
+	port = startFaultSimulate(t, simulate.NamespaceConfig{})
+	out, elapsed, err := execOnSimulate(t, port, "show clock")
+	require.NoError(t, err)
+	assert.Contains(t, out, "10:00:00")
+	assert.Less(t, elapsed, 250*time.Millisecond)
+}
+
+// TestSimulateDropInjection drop_rate=1 时每条命令直接断开连接，不返回回显
+func TestSimulateDropInjection(t *testing.T) {
+	port := startFaultSimulate(t, simulate.NamespaceConfig{DropRate: 1})
+	out, _, err := execOnSimulate(t, port, "show clock")
+	assert.Error(t, err)
+	assert.NotContains(t, out, "10:00:00")
+
+	// 交互式 shell：提示符正常输出，发送命令后连接断开
+	client, err := xssh.Dial("tcp", net.JoinHostPort("127.0.0.1", strconv.Itoa(port)), &xssh.ClientConfig{
+		User: "sw-01", Auth: []xssh.AuthMethod{xssh.Password("nova")}, HostKeyCallback: xssh.InsecureIgnoreHostKey(), Timeout: 3 * time.Second,
+	})
+	require.NoError(t, err)
+	defer client.Close()
+	sess, err := client.NewSession()
+	require.NoError(t, err)
+	stdin, err := sess.StdinPipe()
+	require.NoError(t, err)
+	buf := &shellBuffer{}
+	sess.Stdout = buf
+	require.NoError(t, sess.RequestPty("vt100", 80, 200, xssh.TerminalModes{}))
+	require.NoError(t, sess.Shell())
+	buf.waitFor(t, "sw-01#")
+	_, err = stdin.Write([]byte("show clock\r\n"))
+	require.NoError(t, err)
+
+	done := make(chan error, 1)
+	go func() { done <- client.Wait() }()
+	select {
+	case <-done:
+	case <-time.After(3 * time.Second):
+		t.Fatal("连接未被断开")
+	}
+	assert.NotContains(t, buf.waitFor(t, "sw-01#"), "10:00:00")
+}
+
+// TestSimulateConfigFaultValidation 保存配置时拒绝负数延迟与超出 0~1 的断连概率
+func TestSimulateConfigFaultValidation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/simulate/config", handler.NewSimulateConfigHandler().SaveSimulateConfig)
+
+	cases := map[string]handler.NamespaceConf{
+		"negative latency":  {Port: 22001, LatencyMS: -1},
+		"negative jitter":   {Port: 22001, JitterMS: -5},
+		"drop rate above 1": {Port: 22001, DropRate: 1.5},
+		"negative drop":     {Port: 22001, DropRate: -0.1},
+	}
+	for name, ns := range cases {
+		t.Run(name, func(t *testing.T) {
+			b, _ := json.Marshal(handler.SimulateConfig{
+				Namespace:  map[string]handler.NamespaceConf{"default": ns},
+				DeviceType: map[string]handler.DeviceTypeConf{"cisco_ios": {}},
+			})
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, "/simulate/config", bytes.NewReader(b))
+			req.Header.Set("Content-Type", "application/json")
+			r.ServeHTTP(w, req)
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+			var body map[string]interface{}
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+			assert.Equal(t, "INVALID_PARAMS", body["code"])
+			assert.Contains(t, body["message"], "default")
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package integration
+
+import (
+	"net"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/sshcollectorpro/sshcollectorpro/simulate"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	xssh "golang.org/x/crypto/ssh"
+)
+
+// TestSimulateHandshakeOptions 按命名空间配置版本串、kex 列表与认证前横幅
+func TestSimulateHandshakeOptions(t *testing.T) {
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(t.TempDir()))
+	defer os.Chdir(wd)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	port := ln.Addr().(*net.TCPAddr).Port
+	ln.Close()
+	ns := map[string]simulate.NamespaceConfig{"legacy": {
+		Port: port, IdleSeconds: 60, MaxConn: 10,
+		ServerVersion:     "Cisco-1.25",
+		Banner:            "Authorized access only",
+		KeyExchanges:      []string{"diffie-hellman-group1-sha1"},
+		HostKeyAlgorithms: []string{"ssh-rsa"},
+	}}
+	require.NoError(t, simulate.ValidateNamespaceHandshake(ns))
+	mgr, err := simulate.Start(&simulate.Config{Namespace: ns})
+	require.NoError(t, err)
+	defer mgr.Stop()
+
+	dial := func(cfg *xssh.ClientConfig) (*xssh.Client, string, error) {
+		var banner string
+		cfg.User = "sw-01"
+		cfg.Auth = []xssh.AuthMethod{xssh.Password("nova")}
+		cfg.HostKeyCallback = xssh.InsecureIgnoreHostKey()
+		cfg.BannerCallback = func(msg string) error { banner = msg; return nil }
+		cfg.Timeout = 3 * time.Second
+		c, err := xssh.Dial("tcp", net.JoinHostPort("127.0.0.1", strconv.Itoa(port)), cfg)
+		return c, banner, err
+	}
+
+	_, _, err = dial(&xssh.ClientConfig{})
+	require.Error(t, err, "default client kex must not match legacy-only server")
+
+	cfg := &xssh.ClientConfig{HostKeyAlgorithms: []string{xssh.KeyAlgoRSA}}
+	cfg.KeyExchanges = []string{"diffie-hellman-group1-sha1"}
+	c, banner, err := dial(cfg)
+	require.NoError(t, err)
+	defer c.Close()
+	assert.Equal(t, "SSH-2.0-Cisco-1.25", string(c.ServerVersion()))
+	assert.Equal(t, "Authorized access only\r\n", banner)
+
+	bad := map[string]simulate.NamespaceConfig{"x": {Port: 1, Ciphers: []string{"rot13"}}}
+	assert.ErrorContains(t, simulate.ValidateNamespaceHandshake(bad), "ciphers")
+	bad = map[string]simulate.NamespaceConfig{"x": {Port: 1, HostKeyAlgorithms: []string{"ssh-ed25519"}}}
+	assert.Error(t, simulate.ValidateNamespaceHandshake(bad))
+}
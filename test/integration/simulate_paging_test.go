@@ -0,0 +1,142 @@
+package integration
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sshcollectorpro/sshcollectorpro/simulate"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	xssh "golang.org/x/crypto/ssh"
+)
+
+// shellBuffer 并发读取 shell 输出，等待指定文本出现
+type shellBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *shellBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *shellBuffer) waitFor(t *testing.T, s string) string {
+	t.Helper()
+	var out string
+	require.Eventually(t, func() bool {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		out = b.buf.String()
+		return strings.Contains(out, s)
+	}, 3*time.Second, 10*time.Millisecond, "waiting for %q", s)
+	return out
+}
+
+func (b *shellBuffer) reset() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.buf.Reset()
+}
+
+// TestSimulatePagingAndConfirm 长回显按页输出 More 提示，关闭分页后一次输出；确认提示应答不符时不输出回显
+func TestSimulatePagingAndConfirm(t *testing.T) {
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(t.TempDir()))
+	defer os.Chdir(wd)
+
+	dir := filepath.Join("simulate", "namespace", "t", "sw-01")
+	require.NoError(t, os.MkdirAll(dir, 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "show_log.txt"), []byte("line1\nline2\nline3\nline4\nline5\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "clear_counters.txt"), []byte("counters cleared\n"), 0o644))
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	port := ln.Addr().(*net.TCPAddr).Port
+	ln.Close()
+	mgr, err := simulate.Start(&simulate.Config{
+		Namespace: map[string]simulate.NamespaceConfig{"t": {Port: port, IdleSeconds: 60, MaxConn: 10}},
+		DeviceType: map[string]simulate.DeviceTypeConfig{"cisco_ios": {
+			PromptSuffix: ">", PageLines: 2, MorePrompt: " --More-- ",
+			PagingDisableCommands: []string{"terminal length 0"},
+			ConfirmPrompts:        []simulate.ConfirmPromptConfig{{Command: "clear counters", Prompt: "Clear counters [yes/no]:", Expect: "yes"}},
+		}},
+		DeviceName: map[string]simulate.DeviceNameConfig{"sw-01": {DeviceType: "cisco_ios"}},
+	})
+	require.NoError(t, err)
+	defer mgr.Stop()
+
+	client, err := xssh.Dial("tcp", net.JoinHostPort("127.0.0.1", strconv.Itoa(port)), &xssh.ClientConfig{
+		User: "sw-01", Auth: []xssh.AuthMethod{xssh.Password("nova")}, HostKeyCallback: xssh.InsecureIgnoreHostKey(), Timeout: 3 * time.Second,
+	})
+	require.NoError(t, err)
+	defer client.Close()
+	sess, err := client.NewSession()
+	require.NoError(t, err)
+	defer sess.Close()
+	stdin, err := sess.StdinPipe()
+	require.NoError(t, err)
+	out := &shellBuffer{}
+	sess.Stdout = out
+	require.NoError(t, sess.RequestPty("vt100", 40, 120, xssh.TerminalModes{}))
+	require.NoError(t, sess.Shell())
+	out.waitFor(t, "sw-01>")
+	send := func(s string) { _, err := io.WriteString(stdin, s); require.NoError(t, err) }
+
+	// 第一页后出现 More 提示；空格翻页，回车前进一行
+	out.reset()
+	send("show log\n")
+	got := out.waitFor(t, "--More--")
+	assert.Contains(t, got, "line2")
+	assert.NotContains(t, got, "line3")
+	send(" ")
+	out.waitFor(t, "line4")
+	send("\r")
+	got = out.waitFor(t, "sw-01>")
+	assert.Contains(t, got, "line5")
+
+	// q 结束分页
+	out.reset()
+	send("show log\n")
+	out.waitFor(t, "--More--")
+	send("q")
+	got = out.waitFor(t, "sw-01>")
+	assert.NotContains(t, got, "line3")
+
+	// 关闭分页后一次输出
+	send("terminal length 0\n")
+	out.reset()
+	send("show log\n")
+	got = out.waitFor(t, "line5")
+	assert.NotContains(t, got, "--More--")
+
+	// 确认提示
+	out.reset()
+	send("clear counters\n")
+	out.waitFor(t, "[yes/no]:")
+	send("no\n")
+	got = out.waitFor(t, "Aborted.")
+	assert.NotContains(t, got, "counters cleared")
+	out.reset()
+	send("clear counters\n")
+	out.waitFor(t, "[yes/no]:")
+	send("yes\n")
+	out.waitFor(t, "counters cleared")
+}
+
+// TestSimulateDeviceTypeValidation 确认提示缺少 prompt 时加载失败
+func TestSimulateDeviceTypeValidation(t *testing.T) {
+	err := simulate.ValidateDeviceTypes(map[string]simulate.DeviceTypeConfig{"cisco_ios": {ConfirmPrompts: []simulate.ConfirmPromptConfig{{Command: "reload"}}}})
+	assert.Error(t, err)
+	assert.NoError(t, simulate.ValidateDeviceTypes(map[string]simulate.DeviceTypeConfig{"cisco_ios": {PageLines: 24}}))
+}
@@ -0,0 +1,68 @@
+package integration
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/sshcollectorpro/sshcollectorpro/internal/service"
+	"github.com/sshcollectorpro/sshcollectorpro/simulate"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestSanitizeRecordedOutput 登录口令原文、配置中的口令与密文、自定义规则均被替换
+func TestSanitizeRecordedOutput(t *testing.T) {
+	in := "enable secret 5 $1$abcd$xyz\n" +
+		"username admin privilege 15 password 7 0822455D0A16\n" +
+		"snmp-server community Publ1c RO\n" +
+		" local-user admin password irreversible-cipher $1c$Zx$\n" +
+		"banner motd Welcome-Pa55w0rd\n" +
+		"ntp authentication-key 1 md5 NtpK3y\n" +
+		"wpa-psk W1f1Secret\n" +
+		"interface Gi0/1\n"
+	out := service.SanitizeRecordedOutput(in, []string{"Pa55w0rd"}, []*regexp.Regexp{regexp.MustCompile(`(wpa-psk )\S+`)})
+	for _, leaked := range []string{"$1$abcd$xyz", "0822455D0A16", "Publ1c", "$1c$Zx$", "Pa55w0rd", "NtpK3y", "W1f1Secret"} {
+		assert.NotContains(t, out, leaked)
+	}
+	assert.Contains(t, out, "enable secret 5 ******")
+	assert.Contains(t, out, "snmp-server community ****** RO")
+	assert.Contains(t, out, "ntp authentication-key 1 md5 ******")
+	assert.Contains(t, out, "interface Gi0/1")
+}
+
+// TestSimulateRecordOutputs 命令写为设备目录下的 .txt；含 / 等字符的命令写入 recorded/ 并登记到 scenario.yaml
+func TestSimulateRecordOutputs(t *testing.T) {
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(t.TempDir()))
+	defer os.Chdir(wd)
+
+	_, err = simulate.RecordOutputs("default", "../etc", nil)
+	assert.Error(t, err)
+
+	res, err := simulate.RecordOutputs("default", "cisco-01", []simulate.RecordedCommand{
+		{Command: "show  version", Output: "Cisco IOS Software"},
+		{Command: "show interface Gi0/1", Output: "Gi0/1 is up"},
+	})
+	require.NoError(t, err)
+	base := simulate.DeviceDir("default", "cisco-01")
+	bs, err := os.ReadFile(filepath.Join(base, "show_version.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "Cisco IOS Software", string(bs))
+	assert.Equal(t, []string{"show interface Gi0/1"}, res.Scenarios)
+	require.Len(t, res.Files, 2)
+	bs, err = os.ReadFile(filepath.Join(base, filepath.FromSlash(res.Files[1])))
+	require.NoError(t, err)
+	assert.Equal(t, "Gi0/1 is up", string(bs))
+
+	// 再次录制同一命令时替换 scenario.yaml 中的已有条目
+	_, err = simulate.RecordOutputs("default", "cisco-01", []simulate.RecordedCommand{{Command: "show interface Gi0/1", Output: "Gi0/1 is down"}})
+	require.NoError(t, err)
+	sc, err := os.ReadFile(filepath.Join(base, "scenario.yaml"))
+	require.NoError(t, err)
+	assert.Equal(t, 1, strings.Count(string(sc), "command: "))
+	assert.Contains(t, string(sc), "command: show interface Gi0/1")
+}
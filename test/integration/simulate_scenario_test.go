@@ -0,0 +1,65 @@
+package integration
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sshcollectorpro/sshcollectorpro/simulate"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestSimulateScenario 按调用次数选择响应：repeat 连续返回，用完后停留在最后一条或循环
+func TestSimulateScenario(t *testing.T) {
+	sc := simulate.ScenarioConfig{Command: "show interface Gi0/1", Responses: []simulate.ScenarioResponse{
+		{Output: "down", Repeat: 2},
+		{Output: "up"},
+	}}
+	var got []string
+	for i := 0; i < 5; i++ {
+		got = append(got, sc.ResponseAt(i).Output)
+	}
+	assert.Equal(t, []string{"down", "down", "up", "up", "up"}, got)
+
+	sc.Loop = true
+	got = got[:0]
+	for i := 0; i < 5; i++ {
+		got = append(got, sc.ResponseAt(i).Output)
+	}
+	assert.Equal(t, []string{"down", "down", "up", "down", "down"}, got)
+}
+
+// TestSimulateScenarioConfig simulate.yaml 中的场景定义在加载时校验
+func TestSimulateScenarioConfig(t *testing.T) {
+	dir := t.TempDir()
+	good := filepath.Join(dir, "good.yaml")
+	require.NoError(t, os.WriteFile(good, []byte(`
+namespace:
+  default:
+    port: 22001
+scenarios:
+  - device_name: cisco-01
+    command: show interface Gi0/1
+    responses:
+      - output: "Gi0/1 is down"
+      - file: intf_up.txt
+        repeat: 3
+`), 0o644))
+	cfg, err := simulate.LoadConfig(good)
+	require.NoError(t, err)
+	require.Len(t, cfg.Scenarios, 1)
+	assert.Equal(t, "cisco-01", cfg.Scenarios[0].DeviceName)
+	assert.Equal(t, 3, cfg.Scenarios[0].Responses[1].Repeat)
+
+	bad := filepath.Join(dir, "bad.yaml")
+	require.NoError(t, os.WriteFile(bad, []byte(`
+scenarios:
+  - command: show clock
+    responses:
+      - output: "10:00"
+        file: clock.txt
+`), 0o644))
+	_, err = simulate.LoadConfig(bad)
+	assert.Error(t, err)
+}
@@ -0,0 +1,82 @@
+package integration
+
+import (
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/sshcollectorpro/sshcollectorpro/simulate"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestSimulateTelnetListener Telnet 监听与 SSH 共用设备回显与提示符；选项协商被拒绝，口令错误达到上限后断开
+func TestSimulateTelnetListener(t *testing.T) {
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(t.TempDir()))
+	defer os.Chdir(wd)
+
+	dir := filepath.Join("simulate", "namespace", "t", "sw-01")
+	require.NoError(t, os.MkdirAll(dir, 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "show_version.txt"), []byte("Cisco IOS Software, Version 15.2\n"), 0o644))
+
+	var ports []int
+	for i := 0; i < 2; i++ {
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		ports = append(ports, ln.Addr().(*net.TCPAddr).Port)
+		ln.Close()
+	}
+	mgr, err := simulate.Start(&simulate.Config{
+		Namespace:  map[string]simulate.NamespaceConfig{"t": {Port: ports[0], TelnetPort: ports[1], IdleSeconds: 60, MaxConn: 10, Banner: "Legacy lab"}},
+		DeviceType: map[string]simulate.DeviceTypeConfig{"cisco_ios": {PromptSuffix: "#"}},
+		DeviceName: map[string]simulate.DeviceNameConfig{"sw-01": {DeviceType: "cisco_ios", MaxAuthTries: 2}},
+	})
+	require.NoError(t, err)
+	defer mgr.Stop()
+	st := mgr.Status()
+	require.Len(t, st, 1)
+	assert.Equal(t, ports[1], st[0].TelnetPort)
+
+	dial := func() (net.Conn, *shellBuffer, <-chan struct{}) {
+		c, err := net.DialTimeout("tcp", net.JoinHostPort("127.0.0.1", strconv.Itoa(ports[1])), 3*time.Second)
+		require.NoError(t, err)
+		out, closed := &shellBuffer{}, make(chan struct{})
+		go func() {
+			io.Copy(out, c)
+			close(closed)
+		}()
+		return c, out, closed
+	}
+
+	c, out, _ := dial()
+	defer c.Close()
+	out.waitFor(t, "Legacy lab\r\n\r\nUsername: ")
+	// IAC DO ECHO 应被拒绝（IAC WONT ECHO），且不计入用户名
+	_, err = c.Write([]byte{255, 253, 1})
+	require.NoError(t, err)
+	out.waitFor(t, string([]byte{255, 252, 1}))
+	_, err = c.Write([]byte("sw-01\r\nnova\r\x00"))
+	require.NoError(t, err)
+	out.waitFor(t, "sw-01#\r\n")
+	out.reset()
+	_, err = c.Write([]byte("show version\r\n"))
+	require.NoError(t, err)
+	assert.Contains(t, out.waitFor(t, "sw-01#\r\n"), "Cisco IOS Software, Version 15.2")
+
+	bad, badOut, closed := dial()
+	defer bad.Close()
+	_, err = bad.Write([]byte("sw-01\r\nwrong\r\nsw-01\r\nwrong\r\n"))
+	require.NoError(t, err)
+	badOut.waitFor(t, "% Login invalid")
+	select {
+	case <-closed:
+	case <-time.After(3 * time.Second):
+		t.Fatal("connection should be closed after max_auth_tries")
+	}
+}
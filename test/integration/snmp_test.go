@@ -0,0 +1,189 @@
+package integration
+
+import (
+	"context"
+	"encoding/asn1"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sshcollectorpro/sshcollectorpro/internal/snmp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeVarBind struct {
+	OID   asn1.ObjectIdentifier
+	Value asn1.RawValue
+}
+
+type fakePDU struct {
+	RequestID int
+	A, B      int
+	VarBinds  []fakeVarBind
+}
+
+type fakeMessage struct {
+	Version   int
+	Community []byte
+	PDU       asn1.RawValue
+}
+
+func mustOID(s string) asn1.ObjectIdentifier {
+	var oid asn1.ObjectIdentifier
+	for _, p := range strings.Split(s, ".") {
+		n, _ := strconv.Atoi(p)
+		oid = append(oid, n)
+	}
+	return oid
+}
+
+func rawOf(v interface{}) asn1.RawValue {
+	b, _ := asn1.Marshal(v)
+	var rv asn1.RawValue
+	_, _ = asn1.Unmarshal(b, &rv)
+	return rv
+}
+
+// startFakeAgent 模拟 v2c agent：支持 Get 与 GetBulk，community 不符时不应答
+func startFakeAgent(t *testing.T, community string, mib map[string]asn1.RawValue) int {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	oids := make([]string, 0, len(mib))
+	for k := range mib {
+		oids = append(oids, k)
+	}
+	less := func(a, b string) bool {
+		pa, pb := mustOID(a), mustOID(b)
+		for i := 0; i < len(pa) && i < len(pb); i++ {
+			if pa[i] != pb[i] {
+				return pa[i] < pb[i]
+			}
+		}
+		return len(pa) < len(pb)
+	}
+	sort.Slice(oids, func(i, j int) bool { return less(oids[i], oids[j]) })
+
+	go func() {
+		buf := make([]byte, 65535)
+		for {
+			n, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			var msg fakeMessage
+			if _, err := asn1.Unmarshal(buf[:n], &msg); err != nil || string(msg.Community) != community {
+				continue
+			}
+			seq, _ := asn1.Marshal(asn1.RawValue{Tag: asn1.TagSequence, IsCompound: true, Bytes: msg.PDU.Bytes})
+			var req fakePDU
+			if _, err := asn1.Unmarshal(seq, &req); err != nil {
+				continue
+			}
+			resp := fakePDU{RequestID: req.RequestID}
+			for _, vb := range req.VarBinds {
+				key := vb.OID.String()
+				if msg.PDU.Tag == 0 { // Get
+					v, ok := mib[key]
+					if !ok {
+						v = asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0}
+					}
+					resp.VarBinds = append(resp.VarBinds, fakeVarBind{OID: vb.OID, Value: v})
+					continue
+				}
+				// GetBulk：返回 key 之后的 max-repetitions 个
+				count := 0
+				for _, o := range oids {
+					if less(key, o) && count < req.B {
+						resp.VarBinds = append(resp.VarBinds, fakeVarBind{OID: mustOID(o), Value: mib[o]})
+						count++
+					}
+				}
+				if count == 0 {
+					resp.VarBinds = append(resp.VarBinds, fakeVarBind{OID: vb.OID, Value: asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 2}})
+				}
+			}
+			body, _ := asn1.Marshal(resp)
+			var inner asn1.RawValue
+			_, _ = asn1.Unmarshal(body, &inner)
+			out, _ := asn1.Marshal(fakeMessage{Version: 1, Community: msg.Community,
+				PDU: asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 2, IsCompound: true, Bytes: inner.Bytes}})
+			_, _ = conn.WriteTo(out, addr)
+		}
+	}()
+	return conn.LocalAddr().(*net.UDPAddr).Port
+}
+
+// TestSNMPGetAndWalk v2c Get 标量与 GetBulk 遍历表列
+func TestSNMPGetAndWalk(t *testing.T) {
+	port := startFakeAgent(t, "s3cret", map[string]asn1.RawValue{
+		"1.3.6.1.2.1.1.3.0":     {Class: asn1.ClassApplication, Tag: 3, Bytes: []byte{0x01, 0x00}},
+		"1.3.6.1.2.1.1.5.0":     rawOf([]byte("core-1")),
+		"1.3.6.1.2.1.2.2.1.2.1": rawOf([]byte("GigabitEthernet0/0/1")),
+		"1.3.6.1.2.1.2.2.1.2.2": rawOf([]byte("GigabitEthernet0/0/2")),
+		"1.3.6.1.2.1.2.2.1.8.1": rawOf(1),
+		"1.3.6.1.2.1.2.2.1.8.2": rawOf(2),
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	client, err := snmp.Dial(ctx, snmp.Config{Target: "127.0.0.1", Port: port, Community: "s3cret", Timeout: time.Second, MaxRepetitions: 2})
+	require.NoError(t, err)
+	defer client.Close()
+
+	vars, err := client.Get(ctx, "1.3.6.1.2.1.1.5.0", ".1.3.6.1.2.1.1.3.0", "1.3.6.1.2.1.1.99.0")
+	require.NoError(t, err)
+	require.Len(t, vars, 3)
+	assert.Equal(t, "core-1", vars[0].Value)
+	assert.Equal(t, snmp.TypeTimeTicks, vars[1].Type)
+	assert.Equal(t, uint64(256), vars[1].Value)
+	assert.False(t, vars[2].Exists())
+
+	// 遍历在子树结束处停止，跨多次 GetBulk
+	vars, err = client.Walk(ctx, "1.3.6.1.2.1.2.2.1.2")
+	require.NoError(t, err)
+	require.Len(t, vars, 2)
+	assert.Equal(t, "GigabitEthernet0/0/2", vars[1].Value)
+	assert.Equal(t, "2", snmp.IndexOf(vars[1].OID, "1.3.6.1.2.1.2.2.1.2"))
+
+	vars, err = client.Walk(ctx, "1.3.6.1.2.1.2.2.1.8")
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{int64(1), int64(2)}, []interface{}{vars[0].Value, vars[1].Value})
+
+	// community 不符时超时
+	bad, err := snmp.Dial(ctx, snmp.Config{Target: "127.0.0.1", Port: port, Community: "wrong", Timeout: 200 * time.Millisecond})
+	require.NoError(t, err)
+	defer bad.Close()
+	_, err = bad.Get(ctx, "1.3.6.1.2.1.1.5.0")
+	assert.ErrorIs(t, err, snmp.ErrTimeout)
+}
+
+// TestSNMPConfigValidate 版本与 v3 安全参数校验
+func TestSNMPConfigValidate(t *testing.T) {
+	cases := []struct {
+		cfg snmp.Config
+		ok  bool
+	}{
+		{snmp.Config{Community: "public"}, true},
+		{snmp.Config{Version: "v2c"}, false},
+		{snmp.Config{Version: "v4", Community: "x"}, false},
+		{snmp.Config{Version: "v3"}, false},
+		{snmp.Config{Version: "v3", User: "ops"}, true},
+		{snmp.Config{Version: "v3", User: "ops", AuthProtocol: "sha", AuthPassword: "short"}, false},
+		{snmp.Config{Version: "v3", User: "ops", AuthProtocol: "sha", AuthPassword: "authpass1", PrivProtocol: "aes", PrivPassword: "privpass1"}, true},
+		{snmp.Config{Version: "v3", User: "ops", PrivProtocol: "aes", PrivPassword: "privpass1"}, false},
+		{snmp.Config{Version: "v3", User: "ops", AuthProtocol: "sha", AuthPassword: "authpass1", PrivProtocol: "3des", PrivPassword: "privpass1"}, false},
+	}
+	for i, c := range cases {
+		err := c.cfg.Validate()
+		if c.ok {
+			assert.NoError(t, err, i)
+		} else {
+			assert.Error(t, err, i)
+		}
+	}
+}
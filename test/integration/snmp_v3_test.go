@@ -0,0 +1,282 @@
+package integration
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/des"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/asn1"
+	"encoding/binary"
+	"encoding/hex"
+	"hash"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/sshcollectorpro/sshcollectorpro/internal/snmp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestSNMPLocalizeKeyRFC3414 RFC 3414 A.3.1 / A.3.2 口令本地化测试向量
+func TestSNMPLocalizeKeyRFC3414(t *testing.T) {
+	engineID, _ := hex.DecodeString("000000000000000000000002")
+	for _, tc := range []struct{ proto, want string }{
+		{"md5", "526f5eed9fcce26f8964c2930787d82b"},
+		{"sha", "6695febc9288e36282235fc7151f128497b38f3f"},
+	} {
+		key, err := snmp.LocalizeKey(tc.proto, "maplesyrup", engineID)
+		require.NoError(t, err, tc.proto)
+		assert.Equal(t, tc.want, hex.EncodeToString(key), tc.proto)
+	}
+	_, err := snmp.LocalizeKey("sha512", "maplesyrup", engineID)
+	assert.Error(t, err)
+}
+
+type v3Header struct {
+	MsgID    int
+	MaxSize  int
+	Flags    []byte
+	SecModel int
+}
+
+type v3Wire struct {
+	Version   int
+	Header    v3Header
+	SecParams []byte
+	Data      asn1.RawValue
+}
+
+type v3USMParams struct {
+	EngineID   []byte
+	Boots      int
+	Time       int
+	User       []byte
+	AuthParams []byte
+	PrivParams []byte
+}
+
+type v3Scoped struct {
+	ContextEngineID []byte
+	ContextName     []byte
+	PDU             asn1.RawValue
+}
+
+// fakeV3Agent 独立实现的 USM authPriv agent：校验请求摘要并解密，应答同样签名并加密
+type fakeV3Agent struct {
+	user, auth, priv string
+	engineID         []byte
+	authKey, privKey []byte
+	mib              map[string]asn1.RawValue
+	wrongDigests     atomic.Int32
+	tamperResponse   atomic.Bool
+}
+
+func (a *fakeV3Agent) hashFunc() (func() hash.Hash, int) {
+	switch a.auth {
+	case "md5":
+		return md5.New, 12
+	case "sha256":
+		return sha256.New, 24
+	default:
+		return sha1.New, 12
+	}
+}
+
+func (a *fakeV3Agent) sign(msg []byte) []byte {
+	h, n := a.hashFunc()
+	mac := hmac.New(h, a.authKey)
+	mac.Write(msg)
+	return mac.Sum(nil)[:n]
+}
+
+const fakeV3Boots, fakeV3Time = 7, 1200
+
+func (a *fakeV3Agent) handle(raw []byte) []byte {
+	var msg v3Wire
+	if _, err := asn1.Unmarshal(raw, &msg); err != nil || msg.Version != 3 {
+		return nil
+	}
+	var sp v3USMParams
+	if _, err := asn1.Unmarshal(msg.SecParams, &sp); err != nil {
+		return nil
+	}
+	// 引擎发现：以 usmStatsUnknownEngineIDs report 返回 engineID、boots、time
+	if len(sp.EngineID) == 0 {
+		return a.reply(msg.Header.MsgID, 8, msg.Header.MsgID, "1.3.6.1.6.3.15.1.1.4.0", asn1.RawValue{Class: asn1.ClassApplication, Tag: 1, Bytes: []byte{1}}, false)
+	}
+	if string(sp.User) != a.user || msg.Header.Flags[0]&0x03 != 0x03 || !bytes.Equal(sp.EngineID, a.engineID) {
+		return nil
+	}
+	zeroed := append([]byte(nil), raw...)
+	i := bytes.Index(zeroed, sp.AuthParams)
+	if i < 0 {
+		return nil
+	}
+	copy(zeroed[i:i+len(sp.AuthParams)], make([]byte, len(sp.AuthParams)))
+	if !hmac.Equal(sp.AuthParams, a.sign(zeroed)) {
+		a.wrongDigests.Add(1)
+		return a.reply(msg.Header.MsgID, 8, msg.Header.MsgID, "1.3.6.1.6.3.15.1.1.5.0", asn1.RawValue{Class: asn1.ClassApplication, Tag: 1, Bytes: []byte{1}}, false)
+	}
+	plain := a.crypt(msg.Data.Bytes, sp.PrivParams, sp.Boots, sp.Time, false)
+	var scoped v3Scoped
+	if _, err := asn1.Unmarshal(plain, &scoped); err != nil || scoped.PDU.Tag != 0 {
+		return nil
+	}
+	seq, _ := asn1.Marshal(asn1.RawValue{Tag: asn1.TagSequence, IsCompound: true, Bytes: scoped.PDU.Bytes})
+	var req fakePDU
+	if _, err := asn1.Unmarshal(seq, &req); err != nil || len(req.VarBinds) != 1 {
+		return nil
+	}
+	v, ok := a.mib[req.VarBinds[0].OID.String()]
+	if !ok {
+		v = asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0}
+	}
+	return a.reply(msg.Header.MsgID, 2, req.RequestID, req.VarBinds[0].OID.String(), v, true)
+}
+
+// reply 组装应答；secure 为 true 时加密 scopedPDU 并计算摘要
+func (a *fakeV3Agent) reply(msgID, pduTag, requestID int, oid string, value asn1.RawValue, secure bool) []byte {
+	body, _ := asn1.Marshal(fakePDU{RequestID: requestID, VarBinds: []fakeVarBind{{OID: mustOID(oid), Value: value}}})
+	var inner asn1.RawValue
+	_, _ = asn1.Unmarshal(body, &inner)
+	scoped, _ := asn1.Marshal(v3Scoped{ContextEngineID: a.engineID, ContextName: []byte{},
+		PDU: asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: pduTag, IsCompound: true, Bytes: inner.Bytes}})
+	sp := v3USMParams{EngineID: a.engineID, Boots: fakeV3Boots, Time: fakeV3Time, User: []byte{}, AuthParams: []byte{}, PrivParams: []byte{}}
+	flags := byte(0)
+	var data asn1.RawValue
+	_, _ = asn1.Unmarshal(scoped, &data)
+	if secure {
+		flags = 0x03
+		_, n := a.hashFunc()
+		sp.User, sp.AuthParams = []byte(a.user), make([]byte, n)
+		sp.PrivParams = make([]byte, 8)
+		_, _ = rand.Read(sp.PrivParams)
+		data = asn1.RawValue{Tag: asn1.TagOctetString, Bytes: a.crypt(scoped, sp.PrivParams, sp.Boots, sp.Time, true)}
+	}
+	build := func() []byte {
+		spBytes, _ := asn1.Marshal(sp)
+		out, _ := asn1.Marshal(v3Wire{Version: 3, Header: v3Header{MsgID: msgID, MaxSize: 65507, Flags: []byte{flags}, SecModel: 3}, SecParams: spBytes, Data: data})
+		return out
+	}
+	out := build()
+	if secure {
+		sp.AuthParams = a.sign(out)
+		if a.tamperResponse.Load() {
+			sp.AuthParams[0] ^= 0xff
+		}
+		out = build()
+	}
+	return out
+}
+
+// crypt DES-CBC（RFC 3414 8.1.1）或 AES-128-CFB（RFC 3826 3.1.2）
+func (a *fakeV3Agent) crypt(in, salt []byte, boots, etime int, encrypt bool) []byte {
+	if a.priv == "des" {
+		block, _ := des.NewCipher(a.privKey[:8])
+		iv := make([]byte, 8)
+		for i := range iv {
+			iv[i] = a.privKey[8+i] ^ salt[i]
+		}
+		if pad := len(in) % 8; pad != 0 {
+			in = append(append([]byte(nil), in...), make([]byte, 8-pad)...)
+		}
+		out := make([]byte, len(in))
+		if encrypt {
+			cipher.NewCBCEncrypter(block, iv).CryptBlocks(out, in)
+		} else {
+			cipher.NewCBCDecrypter(block, iv).CryptBlocks(out, in)
+		}
+		return out
+	}
+	block, _ := aes.NewCipher(a.privKey[:16])
+	iv := make([]byte, 16)
+	binary.BigEndian.PutUint32(iv[:4], uint32(boots))
+	binary.BigEndian.PutUint32(iv[4:8], uint32(etime))
+	copy(iv[8:], salt)
+	out := make([]byte, len(in))
+	if encrypt {
+		cipher.NewCFBEncrypter(block, iv).XORKeyStream(out, in)
+	} else {
+		cipher.NewCFBDecrypter(block, iv).XORKeyStream(out, in)
+	}
+	return out
+}
+
+func startFakeV3Agent(t *testing.T, a *fakeV3Agent) int {
+	var err error
+	a.authKey, err = snmp.LocalizeKey(a.auth, "authpass-123", a.engineID)
+	require.NoError(t, err)
+	a.privKey, err = snmp.LocalizeKey(a.auth, "privpass-456", a.engineID)
+	require.NoError(t, err)
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	go func() {
+		buf := make([]byte, 65535)
+		for {
+			n, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			if out := a.handle(append([]byte(nil), buf[:n]...)); out != nil {
+				_, _ = conn.WriteTo(out, addr)
+			}
+		}
+	}()
+	return conn.LocalAddr().(*net.UDPAddr).Port
+}
+
+// TestSNMPv3AuthPriv v3 authPriv：各认证/加密组合与独立实现的 agent 互通；口令错误与应答摘要被篡改时失败
+func TestSNMPv3AuthPriv(t *testing.T) {
+	engineID, _ := hex.DecodeString("80001f8880e9630000d61ff449")
+	for _, combo := range []struct{ auth, priv string }{
+		{"md5", "des"},
+		{"sha", "des"},
+		{"sha", "aes"},
+		{"sha256", "aes"},
+	} {
+		t.Run(combo.auth+"_"+combo.priv, func(t *testing.T) {
+			agent := &fakeV3Agent{user: "ops", auth: combo.auth, priv: combo.priv, engineID: engineID,
+				mib: map[string]asn1.RawValue{"1.3.6.1.2.1.1.5.0": rawOf([]byte("core-1"))}}
+			port := startFakeV3Agent(t, agent)
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			cfg := snmp.Config{Target: "127.0.0.1", Port: port, Version: snmp.Version3, Timeout: time.Second,
+				User: "ops", AuthProtocol: combo.auth, AuthPassword: "authpass-123", PrivProtocol: combo.priv, PrivPassword: "privpass-456"}
+
+			client, err := snmp.Dial(ctx, cfg)
+			require.NoError(t, err)
+			defer client.Close()
+			vars, err := client.Get(ctx, "1.3.6.1.2.1.1.5.0")
+			require.NoError(t, err)
+			require.Len(t, vars, 1)
+			assert.Equal(t, "core-1", vars[0].Value)
+			assert.Zero(t, agent.wrongDigests.Load())
+
+			// 应答摘要被篡改
+			agent.tamperResponse.Store(true)
+			_, err = client.Get(ctx, "1.3.6.1.2.1.1.5.0")
+			assert.ErrorIs(t, err, snmp.ErrWrongDigest)
+			agent.tamperResponse.Store(false)
+
+			// 认证口令错误：agent 以 wrongDigests report 拒绝
+			bad := cfg
+			bad.AuthPassword = "authpass-999"
+			bc, err := snmp.Dial(ctx, bad)
+			require.NoError(t, err)
+			defer bc.Close()
+			_, err = bc.Get(ctx, "1.3.6.1.2.1.1.5.0")
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), "wrong digest")
+			assert.Equal(t, int32(1), agent.wrongDigests.Load())
+		})
+	}
+}
@@ -0,0 +1,84 @@
+package integration
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sshcollectorpro/sshcollectorpro/api/handler"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/config"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/database"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestListPlatformsPagedSummary 平台列表按厂商过滤、分页，概要模式只返回参数字段统计
+func TestListPlatformsPagedSummary(t *testing.T) {
+	dir := t.TempDir()
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(dir))
+	defer os.Chdir(wd)
+	require.NoError(t, database.InitSQLite(config.SQLiteConfig{Path: filepath.Join(dir, "collector.db")}))
+	defer database.Close()
+
+	for _, p := range []model.SSHPlatform{
+		{Type: "default", Params: `{"output_filter":{},"interact":{}}`},
+		{Type: "huawei", Vendor: "Huawei", System: "VRP", Params: `{"prompt_suffixes":[">"],"timeout":{"timeout_all":45},"interact":{}}`},
+		{Type: "huawei_ce", Vendor: "huawei", System: "VRP8", Params: `{}`},
+		{Type: "cisco_ios", Vendor: "Cisco", System: "IOS", Params: `{"prompt_suffixes":["#"]}`},
+	} {
+		require.NoError(t, database.GetDB().Create(&p).Error)
+	}
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/platforms", handler.NewSSHAdapterHandler().ListPlatforms)
+	get := func(path string, out interface{}) {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), out))
+	}
+
+	// 未分页时保持完整数组
+	var all struct {
+		Data []model.SSHPlatform `json:"data"`
+	}
+	get("/platforms", &all)
+	require.Len(t, all.Data, 4)
+	assert.NotEmpty(t, all.Data[1].Params)
+
+	var paged struct {
+		Data struct {
+			Platforms  []handler.PlatformSummary `json:"platforms"`
+			Pagination struct {
+				Total int64 `json:"total"`
+				Pages int64 `json:"pages"`
+			} `json:"pagination"`
+		} `json:"data"`
+	}
+	get("/platforms?vendor=HUAWEI&summary=true&page=1&size=1", &paged)
+	assert.EqualValues(t, 2, paged.Data.Pagination.Total)
+	assert.EqualValues(t, 2, paged.Data.Pagination.Pages)
+	require.Len(t, paged.Data.Platforms, 1)
+	sum := paged.Data.Platforms[0]
+	assert.Equal(t, "huawei", sum.Type)
+	assert.Equal(t, 3, sum.ParamsFields)
+	assert.Equal(t, []string{"interact", "prompt_suffixes", "timeout"}, sum.ParamsKeys)
+	assert.False(t, sum.UpdatedAt.IsZero())
+
+	// 概要模式不含 params 正文
+	var raw struct {
+		Data []map[string]interface{} `json:"data"`
+	}
+	get("/platforms?system=ios&summary=true", &raw)
+	require.Len(t, raw.Data, 1)
+	assert.NotContains(t, raw.Data[0], "params")
+	assert.EqualValues(t, 1, raw.Data[0]["params_fields"])
+}
@@ -0,0 +1,142 @@
+package integration
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/config"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/service"
+	"github.com/sshcollectorpro/sshcollectorpro/pkg/logger"
+	"github.com/sshcollectorpro/sshcollectorpro/pkg/ssh"
+	"github.com/sshcollectorpro/sshcollectorpro/simulate"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// startCacheSimulator 启动仅接受口令 nova 的模拟设备
+func startCacheSimulator(t *testing.T) int {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	port := ln.Addr().(*net.TCPAddr).Port
+	ln.Close()
+	mgr, err := simulate.Start(&simulate.Config{
+		Namespace:  map[string]simulate.NamespaceConfig{"t": {Port: port, IdleSeconds: 60, MaxConn: 10}},
+		DeviceType: map[string]simulate.DeviceTypeConfig{"cisco_ios": {PromptSuffix: "#"}},
+		DeviceName: map[string]simulate.DeviceNameConfig{"sw-01": {DeviceType: "cisco_ios"}},
+	})
+	require.NoError(t, err)
+	t.Cleanup(mgr.Stop)
+	return port
+}
+
+// TestSSHPoolKeyHidesCredentials 池内连接按凭据隔离（口令不同不复用已认证连接），日志只记录 host:port@user
+func TestSSHPoolKeyHidesCredentials(t *testing.T) {
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(t.TempDir()))
+	defer os.Chdir(wd)
+	port := startCacheSimulator(t)
+
+	var logs bytes.Buffer
+	lg := logger.GetLogger()
+	prevOut, prevLevel := lg.Out, lg.GetLevel()
+	lg.SetOutput(&logs)
+	lg.SetLevel(logrus.DebugLevel)
+	defer func() {
+		lg.SetOutput(prevOut)
+		lg.SetLevel(prevLevel)
+	}()
+
+	pool := ssh.NewPool(&ssh.PoolConfig{
+		MaxIdle:     10,
+		MaxActive:   10,
+		IdleTimeout: time.Minute,
+		SSHConfig:   &ssh.Config{ConnectTimeout: 3 * time.Second, Timeout: 5 * time.Second},
+	})
+	defer pool.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	good := &ssh.ConnectionInfo{Host: "127.0.0.1", Port: port, Username: "sw-01", Password: "nova"}
+	_, err = pool.GetConnection(ctx, good)
+	require.NoError(t, err)
+	pool.ReleaseConnection(good)
+	_, err = pool.GetConnection(ctx, good)
+	require.NoError(t, err, "same credentials reuse the idle connection")
+	pool.ReleaseConnection(good)
+	require.Len(t, pool.Snapshot(), 1)
+
+	// 同一设备与用户、口令不同：不得复用已认证的空闲连接
+	bad := &ssh.ConnectionInfo{Host: "127.0.0.1", Port: port, Username: "sw-01", Password: "wrong"}
+	_, err = pool.GetConnection(ctx, bad)
+	assert.Error(t, err)
+
+	out := logs.String()
+	target := fmt.Sprintf("127.0.0.1:%d@sw-01", port)
+	assert.Contains(t, out, "target="+target)
+	assert.NotContains(t, out, target+"#")
+	assert.NotContains(t, out, "nova")
+}
+
+// TestSharedSSHPoolOwnedByProcess 共用连接池不随单个服务停止而关闭，由 CloseSharedSSHPool 统一关闭
+func TestSharedSSHPoolOwnedByProcess(t *testing.T) {
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(t.TempDir()))
+	defer os.Chdir(wd)
+	port := startCacheSimulator(t)
+
+	cfgPath := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(cfgPath, []byte(`
+ssh:
+  connection_cache:
+    enable: true
+    ttl: 1m
+collector:
+  device_defaults:
+    cisco_ios:
+      prompt_suffixes: ["#"]
+      command_interval_ms: 10
+`), 0o600))
+	cfg, err := config.Load(cfgPath)
+	require.NoError(t, err)
+	defer service.CloseSharedSSHPool()
+
+	collector := service.NewCollectorService(cfg)
+	require.NoError(t, collector.Start(context.Background()))
+	defer collector.Stop()
+	backup := service.NewBackupService(cfg)
+	require.NoError(t, backup.Start(context.Background()))
+	defer backup.Stop()
+
+	resp, err := collector.ExecuteTask(context.Background(), &service.CollectRequest{
+		TaskID: "cache-1", DeviceIP: "127.0.0.1", Port: port, DeviceName: "sw-01", DevicePlatform: "cisco_ios",
+		UserName: "sw-01", Password: "nova", CliList: service.NewCLIList("show version"),
+	})
+	require.NoError(t, err)
+	require.True(t, resp.Success, resp.Error)
+
+	sharedConns := func() int {
+		for _, p := range service.SSHPoolsSnapshot() {
+			if p.Shared {
+				return len(p.Connections)
+			}
+		}
+		return -1
+	}
+	require.Equal(t, 1, sharedConns())
+
+	// 任一服务停止不影响其他服务共用的缓存连接
+	require.NoError(t, backup.Stop())
+	assert.Equal(t, 1, sharedConns())
+
+	require.NoError(t, service.CloseSharedSSHPool())
+	assert.Equal(t, -1, sharedConns())
+}
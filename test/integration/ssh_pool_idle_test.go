@@ -0,0 +1,133 @@
+package integration
+
+import (
+	"context"
+	"net"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/sshcollectorpro/sshcollectorpro/pkg/ssh"
+	"github.com/sshcollectorpro/sshcollectorpro/simulate"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestMatchHostPattern 空闲规则主机匹配：IP、CIDR、主机名与通配
+func TestMatchHostPattern(t *testing.T) {
+	assert.True(t, ssh.MatchHostPattern("10.0.0.0/8", "10.1.2.3"))
+	assert.True(t, ssh.MatchHostPattern("10.1.2.3", "10.1.2.3"))
+	assert.True(t, ssh.MatchHostPattern("core-*", "CORE-SW01"))
+	assert.False(t, ssh.MatchHostPattern("core-*", "edge-sw01"))
+	assert.False(t, ssh.MatchHostPattern("10.0.0.0/8", "192.0.2.1"))
+}
+
+// TestSSHPoolHostIdleCap 按主机限制空闲连接：超出上限时关闭最久未用的连接，统计按主机输出占用
+func TestSSHPoolHostIdleCap(t *testing.T) {
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(t.TempDir()))
+	defer os.Chdir(wd)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	port := ln.Addr().(*net.TCPAddr).Port
+	ln.Close()
+	mgr, err := simulate.Start(&simulate.Config{Namespace: map[string]simulate.NamespaceConfig{"t": {Port: port, IdleSeconds: 60, MaxConn: 10}}})
+	require.NoError(t, err)
+	defer mgr.Stop()
+
+	one := 1
+	pool := ssh.NewPool(&ssh.PoolConfig{
+		MaxIdle:     10,
+		MaxActive:   10,
+		IdleTimeout: time.Minute,
+		SSHConfig:   &ssh.Config{ConnectTimeout: 3 * time.Second, Timeout: 5 * time.Second},
+		IdleRules:   []ssh.IdleRule{{Platform: "cisco_ios", Match: "127.0.0.0/8", MaxIdle: &one, IdleTimeout: 90 * time.Second}},
+	})
+	defer pool.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	a := &ssh.ConnectionInfo{Host: "127.0.0.1", Port: port, Username: "dev-a", Password: "nova", Platform: "cisco_ios"}
+	b := &ssh.ConnectionInfo{Host: "127.0.0.1", Port: port, Username: "dev-b", Password: "nova", Platform: "cisco_ios"}
+	_, err = pool.GetConnection(ctx, a)
+	require.NoError(t, err)
+	_, err = pool.GetConnection(ctx, b)
+	require.NoError(t, err)
+
+	hosts := pool.HostStats()
+	require.Len(t, hosts, 1)
+	assert.Equal(t, 2, hosts[0].InUse)
+	assert.Equal(t, 1, hosts[0].MaxIdle)
+	assert.Equal(t, 90, hosts[0].IdleTimeoutSec)
+
+	pool.ReleaseConnection(a)
+	pool.ReleaseConnection(b)
+	hosts = pool.HostStats()
+	require.Len(t, hosts, 1)
+	assert.Equal(t, 1, hosts[0].Idle)
+	assert.Equal(t, 1, hosts[0].Total)
+	// 保留最近释放的连接
+	snap := pool.Snapshot()
+	require.Len(t, snap, 1)
+	assert.Equal(t, "dev-b", snap[0].Username)
+}
+
+// TestSSHPoolReaper 清理协程回收空闲超时连接，超龄连接释放时关闭，统计按原因累计驱逐次数
+func TestSSHPoolReaper(t *testing.T) {
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(t.TempDir()))
+	defer os.Chdir(wd)
+
+	port := freePort(t)
+	mgr, err := simulate.Start(&simulate.Config{Namespace: map[string]simulate.NamespaceConfig{"t": {Port: port, IdleSeconds: 60, MaxConn: 10}}})
+	require.NoError(t, err)
+	defer mgr.Stop()
+
+	pool := ssh.NewPool(&ssh.PoolConfig{
+		MaxIdle:          10,
+		MaxActive:        10,
+		IdleTimeout:      150 * time.Millisecond,
+		CleanupInterval:  time.Hour,
+		MaxConnectionAge: 400 * time.Millisecond,
+		SSHConfig:        &ssh.Config{ConnectTimeout: 3 * time.Second, Timeout: 5 * time.Second},
+	})
+	defer pool.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	a := &ssh.ConnectionInfo{Host: "127.0.0.1", Port: port, Username: "dev-a", Password: "nova"}
+	b := &ssh.ConnectionInfo{Host: "127.0.0.1", Port: port, Username: "dev-b", Password: "nova"}
+	_, err = pool.GetConnection(ctx, a)
+	require.NoError(t, err)
+	pool.ReleaseConnection(a)
+	_, err = pool.GetConnection(ctx, b)
+	require.NoError(t, err)
+
+	// 空闲超时的连接被回收，使用中的连接保留
+	time.Sleep(200 * time.Millisecond)
+	assert.Equal(t, 1, pool.Reap())
+	snap := pool.Snapshot()
+	require.Len(t, snap, 1)
+	assert.Equal(t, "dev-b", snap[0].Username)
+
+	// 超龄的使用中连接在释放时关闭
+	time.Sleep(250 * time.Millisecond)
+	pool.ReleaseConnection(b)
+	assert.Empty(t, pool.Snapshot())
+
+	_, err = pool.GetConnection(ctx, a)
+	require.NoError(t, err)
+	pool.ReleaseConnection(a)
+	assert.Equal(t, 1, pool.Evict("", 0, false))
+
+	stats := pool.GetStats()
+	assert.Equal(t, map[string]int64{
+		ssh.EvictIdleTimeout: 1, ssh.EvictBroken: 0, ssh.EvictMaxAge: 1, ssh.EvictIdleCap: 0, ssh.EvictManual: 1,
+	}, stats["evictions"])
+	assert.EqualValues(t, 3, stats["evictions_total"])
+	assert.Equal(t, 0, stats["max_connection_age_sec"])
+	assert.Contains(t, stats, "last_cleanup")
+}
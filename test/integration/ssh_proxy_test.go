@@ -0,0 +1,57 @@
+package integration
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/sshcollectorpro/sshcollectorpro/pkg/ssh"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestSSHDialViaHTTPProxy 配置代理后 SSH 拨号经 HTTP CONNECT 发往代理，错误中不泄露代理口令
+func TestSSHDialViaHTTPProxy(t *testing.T) {
+	got := make(chan *http.Request, 1)
+	proxySrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got <- r
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer proxySrv.Close()
+
+	proxyURL := "http://ops:s3cret@" + proxySrv.Listener.Addr().String()
+	client := ssh.NewClient(&ssh.Config{
+		ConnectTimeout: 2 * time.Second,
+		Proxy: &ssh.ProxyConfig{
+			URL: "socks5://127.0.0.1:1",
+			// 设备级覆盖优先于全局代理
+			Overrides: []ssh.ProxyOverride{{Match: "192.0.2.0/24", URL: proxyURL}},
+		},
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	err := client.Connect(ctx, &ssh.ConnectionInfo{Host: "192.0.2.10", Port: 22, Username: "u", Password: "p"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "403")
+	assert.NotContains(t, err.Error(), "s3cret")
+
+	select {
+	case r := <-got:
+		assert.Equal(t, http.MethodConnect, r.Method)
+		assert.Equal(t, "192.0.2.10:22", r.Host)
+		assert.Equal(t, "Basic b3BzOnMzY3JldA==", r.Header.Get("Proxy-Authorization"))
+	default:
+		t.Fatal("proxy did not receive CONNECT")
+	}
+}
+
+// TestValidateProxyURL 仅支持 socks5/socks5h/http 代理
+func TestValidateProxyURL(t *testing.T) {
+	assert.NoError(t, ssh.ValidateProxyURL("socks5://bastion:1080"))
+	assert.NoError(t, ssh.ValidateProxyURL("http://proxy.local"))
+	assert.NoError(t, ssh.ValidateProxyURL("direct"))
+	assert.Error(t, ssh.ValidateProxyURL("https://proxy.local:443"))
+	assert.Error(t, ssh.ValidateProxyURL("socks5://"))
+}
@@ -0,0 +1,120 @@
+package integration
+
+import (
+	"context"
+	"encoding/base64"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/sshcollectorpro/sshcollectorpro/internal/config"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestAzureBlobStorageWriteAndRead 共享密钥签名写入 Blob，并可按 azure:// URI 读回
+func TestAzureBlobStorageWriteAndRead(t *testing.T) {
+	var mu sync.Mutex
+	blobs := map[string][]byte{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.Header.Get("Authorization"), "SharedKey acct:") || r.Header.Get("x-ms-date") == "" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		switch r.Method {
+		case http.MethodPut:
+			if r.Header.Get("x-ms-blob-type") != "BlockBlob" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			b, _ := io.ReadAll(r.Body)
+			blobs[r.URL.Path] = b
+			w.WriteHeader(http.StatusCreated)
+		case http.MethodGet:
+			b, ok := blobs[r.URL.Path]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			_, _ = w.Write(b)
+		}
+	}))
+	defer srv.Close()
+
+	cfg := &config.Config{}
+	cfg.Backup.Prefix = "configs"
+	cfg.Storage.Azure = config.AzureConfig{
+		AccountName: "acct",
+		AccountKey:  base64.StdEncoding.EncodeToString([]byte("secret-key")),
+		Container:   "backups",
+		Endpoint:    srv.URL,
+	}
+	sw := service.NewStorageWriter(cfg)
+
+	obj, err := sw.Write(context.Background(), service.StorageMeta{
+		DateYYYYMMDD: "20240101",
+		TimeHHMMSS:   "120000",
+		TaskID:       "t1",
+		DeviceName:   "sw-01",
+		CommandSlug:  "running",
+		Backend:      "azure",
+		SkipFilter:   true,
+	}, "hostname sw-01\n", "")
+	require.NoError(t, err)
+	assert.Equal(t, "azure://backups/configs/sw-01/20240101_120000/t1/running.txt", obj.URI)
+	assert.Contains(t, blobs, "/backups/configs/sw-01/20240101_120000/t1/running.txt")
+
+	data, err := sw.(service.StorageReader).Read(context.Background(), obj.URI)
+	require.NoError(t, err)
+	assert.Equal(t, "hostname sw-01\n", string(data))
+
+	_, err = sw.(service.StorageReader).Read(context.Background(), "azure://other/configs/x.txt")
+	assert.ErrorIs(t, err, service.ErrInvalidDiffRequest)
+}
+
+// TestAzureBlobStorageRetryHonorsCancel 写入持续失败时，取消上下文立即结束退避等待
+func TestAzureBlobStorageRetryHonorsCancel(t *testing.T) {
+	var puts atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		puts.Add(1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	cfg := &config.Config{}
+	cfg.Backup.Local.BaseDir = t.TempDir()
+	cfg.Backup.Local.MkdirIfMissing = true
+	cfg.Storage.Azure = config.AzureConfig{
+		AccountName: "acct",
+		AccountKey:  base64.StdEncoding.EncodeToString([]byte("secret-key")),
+		Container:   "backups",
+		Endpoint:    srv.URL,
+	}
+	sw := service.NewStorageWriter(cfg)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	obj, err := sw.Write(ctx, service.StorageMeta{
+		DateYYYYMMDD: "20240101",
+		TimeHHMMSS:   "120000",
+		TaskID:       "t1",
+		DeviceName:   "sw-01",
+		CommandSlug:  "running",
+		Backend:      "azure",
+		SkipFilter:   true,
+	}, "hostname sw-01\n", "")
+	assert.Less(t, time.Since(start), 900*time.Millisecond)
+	assert.Equal(t, int32(1), puts.Load())
+	// 远端失败时回退本地存储，并附带远端错误
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.True(t, strings.HasPrefix(obj.URI, "file://"), obj.URI)
+}
@@ -0,0 +1,146 @@
+package integration
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/sshcollectorpro/sshcollectorpro/internal/config"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeS3 路径风格的最小 S3 服务：HEAD bucket、bucket location、PUT/GET object，校验 SigV4 凭证
+type fakeS3 struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+	ctypes  map[string]string
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=AKIDTEST/") {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	key := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	if !strings.Contains(key, "/") {
+		// bucket 级请求：仅 ssh-collector 存在
+		if key != "ssh-collector" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if r.URL.Query().Has("location") {
+			w.Header().Set("Content-Type", "application/xml")
+			_, _ = io.WriteString(w, `<?xml version="1.0" encoding="UTF-8"?><LocationConstraint xmlns="http://s3.amazonaws.com/doc/2006-03-01/">us-east-1</LocationConstraint>`)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	switch r.Method {
+	case http.MethodPut:
+		b, _ := io.ReadAll(r.Body)
+		if strings.HasPrefix(r.Header.Get("X-Amz-Content-Sha256"), "STREAMING-") {
+			b = decodeAWSChunked(b)
+		}
+		f.objects[key] = b
+		f.ctypes[key] = r.Header.Get("Content-Type")
+		w.Header().Set("ETag", `"d41d8cd98f00b204e9800998ecf8427e"`)
+		w.WriteHeader(http.StatusOK)
+	case http.MethodGet, http.MethodHead:
+		b, ok := f.objects[key]
+		if !ok {
+			w.Header().Set("Content-Type", "application/xml")
+			w.WriteHeader(http.StatusNotFound)
+			if r.Method == http.MethodGet {
+				_, _ = io.WriteString(w, `<?xml version="1.0" encoding="UTF-8"?><Error><Code>NoSuchKey</Code><Message>The specified key does not exist.</Message></Error>`)
+			}
+			return
+		}
+		w.Header().Set("ETag", `"d41d8cd98f00b204e9800998ecf8427e"`)
+		w.Header().Set("Last-Modified", "Mon, 01 Jan 2024 12:00:00 GMT")
+		w.Header().Set("Content-Type", f.ctypes[key])
+		if r.Method == http.MethodGet {
+			_, _ = w.Write(b)
+		}
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// decodeAWSChunked 解开 SigV4 流式分块负载：{size-hex};chunk-signature=...\r\n{data}\r\n，以 0 长度块结束
+func decodeAWSChunked(b []byte) []byte {
+	var out []byte
+	for {
+		i := bytes.Index(b, []byte("\r\n"))
+		if i < 0 {
+			return out
+		}
+		hdr, _, _ := strings.Cut(string(b[:i]), ";")
+		n, err := strconv.ParseInt(hdr, 16, 64)
+		if err != nil || n == 0 || int64(len(b)) < int64(i+2)+n {
+			return out
+		}
+		out = append(out, b[i+2:int64(i+2)+n]...)
+		b = bytes.TrimPrefix(b[int64(i+2)+n:], []byte("\r\n"))
+	}
+}
+
+// TestS3StorageWriteAndRead 路径风格写入 S3 对象，并可按 s3:// URI 读回；不存在的键返回 ErrObjectNotFound
+func TestS3StorageWriteAndRead(t *testing.T) {
+	fake := &fakeS3{objects: map[string][]byte{}, ctypes: map[string]string{}}
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+
+	cfg := &config.Config{}
+	cfg.Backup.Prefix = "configs"
+	cfg.Storage.S3 = config.S3Config{
+		Endpoint:  strings.TrimPrefix(srv.URL, "http://"),
+		Region:    "ap-southeast-1",
+		Bucket:    "ssh-collector",
+		AccessKey: "AKIDTEST",
+		SecretKey: "secret",
+		PathStyle: true,
+	}
+	sw := service.NewStorageWriter(cfg)
+
+	obj, err := sw.Write(context.Background(), service.StorageMeta{
+		DateYYYYMMDD: "20240101",
+		TimeHHMMSS:   "120000",
+		TaskID:       "t1",
+		DeviceName:   "sw-01",
+		CommandSlug:  "running",
+		Backend:      "s3",
+		SkipFilter:   true,
+	}, "hostname sw-01\n", "")
+	require.NoError(t, err)
+	assert.Equal(t, "s3://ssh-collector/configs/sw-01/20240101_120000/t1/running.txt", obj.URI)
+	assert.Equal(t, int64(len("hostname sw-01\n")), obj.Size)
+
+	fake.mu.Lock()
+	stored, ok := fake.objects["ssh-collector/configs/sw-01/20240101_120000/t1/running.txt"]
+	ctype := fake.ctypes["ssh-collector/configs/sw-01/20240101_120000/t1/running.txt"]
+	fake.mu.Unlock()
+	require.True(t, ok)
+	assert.Equal(t, "hostname sw-01\n", string(stored))
+	assert.Equal(t, "text/plain; charset=utf-8", ctype)
+
+	data, err := sw.(service.StorageReader).Read(context.Background(), obj.URI)
+	require.NoError(t, err)
+	assert.Equal(t, "hostname sw-01\n", string(data))
+
+	_, err = sw.(service.StorageReader).Read(context.Background(), "s3://ssh-collector/configs/missing.txt")
+	assert.ErrorIs(t, err, service.ErrObjectNotFound)
+
+	_, err = sw.(service.StorageReader).Read(context.Background(), "s3://other/configs/x.txt")
+	assert.ErrorIs(t, err, service.ErrInvalidDiffRequest)
+}
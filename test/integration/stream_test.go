@@ -0,0 +1,188 @@
+package integration
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sshcollectorpro/sshcollectorpro/api/handler"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/config"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/service"
+	"github.com/sshcollectorpro/sshcollectorpro/simulate"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/websocket"
+)
+
+// startStreamServer 模拟设备与挂载流式采集接口的 HTTP 服务，返回服务地址与请求体
+func startStreamServer(t *testing.T, allowedOrigins string) (*httptest.Server, map[string]interface{}) {
+	port := freePort(t)
+	mgr, err := simulate.Start(&simulate.Config{
+		Namespace:  map[string]simulate.NamespaceConfig{"t": {Port: port, IdleSeconds: 60, MaxConn: 10}},
+		DeviceType: map[string]simulate.DeviceTypeConfig{"cisco_ios": {PromptSuffix: "#"}},
+		DeviceName: map[string]simulate.DeviceNameConfig{"sw-01": {DeviceType: "cisco_ios"}},
+		Scenarios: []simulate.ScenarioConfig{
+			{DeviceName: "sw-01", Command: "show tech-support", Responses: []simulate.ScenarioResponse{{Output: "line one\nline two\nline three"}}},
+		},
+	})
+	require.NoError(t, err)
+	t.Cleanup(mgr.Stop)
+
+	cfgPath := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(cfgPath, []byte(`
+server:
+  stream_allowed_origins: `+allowedOrigins+`
+collector:
+  device_defaults:
+    cisco_ios:
+      prompt_suffixes: ["#"]
+      command_interval_ms: 10
+`), 0o600))
+	cfg, err := config.Load(cfgPath)
+	require.NoError(t, err)
+	svc := service.NewCollectorService(cfg)
+	require.NoError(t, svc.Start(context.Background()))
+	t.Cleanup(func() { svc.Stop() })
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	h := handler.NewCollectorHandler(svc)
+	r.POST("/api/v1/collector/stream", h.StreamCollect)
+	r.GET("/api/v1/collector/stream", h.StreamCollect)
+	srv := httptest.NewServer(r)
+	t.Cleanup(srv.Close)
+
+	return srv, map[string]interface{}{
+		"device_ip": "127.0.0.1", "device_port": port, "device_name": "sw-01", "device_platform": "cisco_ios",
+		"user_name": "sw-01", "password": "nova", "cli_list": []string{"show tech-support"},
+	}
+}
+
+// assertStreamEvents 事件顺序：command_start、逐行 output、command_end，最后为 done；内部预命令不推送
+func assertStreamEvents(t *testing.T, events []handler.StreamEvent) {
+	t.Helper()
+	require.NotEmpty(t, events)
+	var types, lines []string
+	for _, ev := range events {
+		types = append(types, ev.Type)
+		if ev.Command != "" {
+			assert.Equal(t, "show tech-support", ev.Command)
+		}
+		if ev.Type == "output" {
+			lines = append(lines, strings.TrimSpace(ev.Line))
+		}
+	}
+	assert.Equal(t, "command_start", types[0])
+	assert.Equal(t, "command_end", types[len(types)-2])
+	assert.Equal(t, "done", types[len(types)-1])
+	assert.Subset(t, lines, []string{"line one", "line two", "line three"})
+
+	end := events[len(events)-2]
+	require.NotNil(t, end.ExitCode)
+	assert.Zero(t, *end.ExitCode)
+	done := events[len(events)-1]
+	require.NotNil(t, done.Success)
+	assert.True(t, *done.Success, done.Error)
+}
+
+// TestStreamCollectSSE POST 以 SSE 推送事件，事件名即 type
+func TestStreamCollectSSE(t *testing.T) {
+	srv, body := startStreamServer(t, "[]")
+	b, _ := json.Marshal(body)
+	resp, err := http.Post(srv.URL+"/api/v1/collector/stream", "application/json", bytes.NewReader(b))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Contains(t, resp.Header.Get("Content-Type"), "text/event-stream")
+
+	var events []handler.StreamEvent
+	var name string
+	sc := bufio.NewScanner(resp.Body)
+	for sc.Scan() {
+		line := sc.Text()
+		switch {
+		case strings.HasPrefix(line, "event:"):
+			name = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+		case strings.HasPrefix(line, "data:"):
+			var ev handler.StreamEvent
+			require.NoError(t, json.Unmarshal([]byte(strings.TrimPrefix(line, "data:")), &ev))
+			assert.Equal(t, name, ev.Type)
+			events = append(events, ev)
+		}
+	}
+	assertStreamEvents(t, events)
+
+	// 缺少 cli_list 等参数错误在建立事件流之前返回
+	resp2, err := http.Post(srv.URL+"/api/v1/collector/stream", "application/json", strings.NewReader(`{"device_ip":"127.0.0.1"}`))
+	require.NoError(t, err)
+	resp2.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, resp2.StatusCode)
+}
+
+// wsCollect 以指定 Origin 建立 WebSocket 并读取全部事件
+func wsCollect(t *testing.T, srv *httptest.Server, origin string, body map[string]interface{}) ([]handler.StreamEvent, error) {
+	wsURL := "ws" + strings.TrimPrefix(srv.URL, "http") + "/api/v1/collector/stream"
+	ws, err := websocket.Dial(wsURL, "", origin)
+	if err != nil {
+		return nil, err
+	}
+	defer ws.Close()
+	require.NoError(t, websocket.JSON.Send(ws, body))
+	require.NoError(t, ws.SetReadDeadline(time.Now().Add(10*time.Second)))
+	var events []handler.StreamEvent
+	for {
+		var ev handler.StreamEvent
+		if err := websocket.JSON.Receive(ws, &ev); err != nil {
+			break
+		}
+		events = append(events, ev)
+	}
+	return events, nil
+}
+
+// TestStreamCollectWebSocketOrigin 同源与配置允许的来源可建立连接，其他跨站来源握手返回 403
+func TestStreamCollectWebSocketOrigin(t *testing.T) {
+	srv, body := startStreamServer(t, `["https://noc.example.com"]`)
+
+	events, err := wsCollect(t, srv, srv.URL, body)
+	require.NoError(t, err)
+	assertStreamEvents(t, events)
+
+	events, err = wsCollect(t, srv, "https://noc.example.com", body)
+	require.NoError(t, err)
+	assertStreamEvents(t, events)
+
+	_, err = wsCollect(t, srv, "https://evil.example.com", body)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "bad status")
+
+	// 不带 Origin 的非浏览器客户端允许握手；跨站来源返回 403
+	handshake := func(origin string) string {
+		conn, err := net.Dial("tcp", strings.TrimPrefix(srv.URL, "http://"))
+		require.NoError(t, err)
+		defer conn.Close()
+		req := "GET /api/v1/collector/stream HTTP/1.1\r\nHost: " + strings.TrimPrefix(srv.URL, "http://") + "\r\n" +
+			"Upgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\nSec-WebSocket-Version: 13\r\n"
+		if origin != "" {
+			req += "Origin: " + origin + "\r\n"
+		}
+		_, err = conn.Write([]byte(req + "\r\n"))
+		require.NoError(t, err)
+		require.NoError(t, conn.SetReadDeadline(time.Now().Add(3*time.Second)))
+		status, err := bufio.NewReader(conn).ReadString('\n')
+		require.NoError(t, err)
+		return strings.TrimSpace(status)
+	}
+	assert.Equal(t, "HTTP/1.1 101 Switching Protocols", handshake(""))
+	assert.Equal(t, "HTTP/1.1 403 Forbidden", handshake("https://evil.example.com"))
+}
@@ -0,0 +1,87 @@
+package integration
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sshcollectorpro/sshcollectorpro/api/handler"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/config"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/database"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/model"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestCompareTasks 按设备与命令对齐两次批次任务，返回文本差异与解析字段差异
+func TestCompareTasks(t *testing.T) {
+	require.NoError(t, database.InitSQLite(config.SQLiteConfig{Path: filepath.Join(t.TempDir(), "collector.db")}))
+	defer database.Close()
+
+	repo := handler.NewTaskRepository()
+	for _, task := range []*model.Task{
+		{ID: "pre-1", DeviceIP: "10.0.0.1", Metadata: `{"batch_task_id":"pre"}`,
+			Result: `[{"command":"show version","raw_output":"Version 15.2(4)\nuptime 1d","format_output":[{"VERSION":"15.2(4)"}]},{"command":"show clock","raw_output":"10:00"}]`},
+		{ID: "pre-2", DeviceIP: "10.0.0.2", Metadata: `{"batch_task_id":"pre"}`,
+			Result: `[{"command":"show version","raw_output":"Version 16.1"}]`},
+		{ID: "pre-3", DeviceIP: "10.0.0.3", Metadata: `{"batch_task_id":"pre"}`, Result: `[]`},
+		{ID: "post-1", DeviceIP: "10.0.0.1", Metadata: `{"batch_task_id":"post"}`,
+			Result: `[{"command":"SHOW  version","raw_output":"Version 15.2(7)\nuptime 1d","format_output":[{"VERSION":"15.2(7)"}]},{"command":"show ip int brief","raw_output":"Gi0/1 up"}]`},
+		{ID: "post-2", DeviceIP: "10.0.0.2", Metadata: `{"batch_task_id":"post"}`,
+			Result: `[{"command":"show version","raw_output":"Version 16.1"}]`},
+		{ID: "post-4", DeviceIP: "10.0.0.4", Metadata: `{"batch_task_id":"post"}`, Result: `[]`},
+	} {
+		task.CollectorID, task.Type, task.Username, task.Status = "c1", model.TaskTypeSimple, "u", model.TaskStatusSuccess
+		require.NoError(t, repo.SaveTask(task))
+	}
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/tasks/compare", handler.NewTaskHandler().CompareTasks)
+	get := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w
+	}
+
+	w := get("/tasks/compare?base=pre&target=post")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var body struct {
+		Data service.TaskComparison `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	cmp := body.Data
+	assert.Equal(t, service.TaskCompareSummary{Devices: 4, Changed: 1, Unchanged: 1, OnlyBase: 1, OnlyTarget: 1, CommandsChanged: 3}, cmp.Summary)
+	require.Len(t, cmp.Devices, 4)
+	assert.Equal(t, "10.0.0.4", cmp.Devices[3].DeviceIP)
+	assert.Equal(t, service.CompareOnlyTarget, cmp.Devices[3].Status)
+
+	d := cmp.Devices[0]
+	assert.Equal(t, service.CompareChanged, d.Status)
+	assert.Equal(t, "pre-1", d.BaseTaskID)
+	assert.Equal(t, "post-1", d.TargetTaskID)
+	require.Len(t, d.Commands, 3)
+	ver := d.Commands[0]
+	assert.Equal(t, service.CompareChanged, ver.Status)
+	assert.Contains(t, ver.Diff, "-Version 15.2(4)")
+	assert.Contains(t, ver.Diff, "+Version 15.2(7)")
+	require.NotNil(t, ver.Summary)
+	assert.Equal(t, 1, ver.Summary.LinesAdded)
+	assert.Equal(t, []service.TaskFieldChange{{Path: "format_output[0].VERSION", Change: "changed", Base: "15.2(4)", Target: "15.2(7)"}}, ver.FieldChanges)
+	assert.Equal(t, service.CompareOnlyBase, d.Commands[1].Status)
+	assert.Equal(t, "show clock", d.Commands[1].Command)
+	assert.Equal(t, service.CompareOnlyTarget, d.Commands[2].Status)
+
+	// changed_only 省略未变化的设备
+	w = get("/tasks/compare?base=pre&target=post&changed_only=true")
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Len(t, body.Data.Devices, 3)
+	assert.Equal(t, 1, body.Data.Summary.Unchanged)
+
+	assert.Equal(t, http.StatusBadRequest, get("/tasks/compare?base=pre").Code)
+	assert.Equal(t, http.StatusNotFound, get("/tasks/compare?base=pre&target=missing").Code)
+}
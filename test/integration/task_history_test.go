@@ -0,0 +1,197 @@
+package integration
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sshcollectorpro/sshcollectorpro/api/handler"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/config"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/database"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/model"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/service"
+	"github.com/sshcollectorpro/sshcollectorpro/simulate"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestTaskHistoryQueryAndRetention 时间范围过滤、任务日志分页与超期清理
+func TestTaskHistoryQueryAndRetention(t *testing.T) {
+	require.NoError(t, database.InitSQLite(config.SQLiteConfig{Path: filepath.Join(t.TempDir(), "collector.db")}))
+	defer database.Close()
+
+	repo := handler.NewTaskRepository()
+	now := time.Now()
+	old := now.Add(-48 * time.Hour)
+	for _, task := range []*model.Task{
+		{ID: "old-1", CollectorID: "c1", Type: model.TaskTypeSimple, DeviceIP: "10.0.0.1", Username: "u", Commands: "show clock", Status: model.TaskStatusFailed, CreatedAt: old, UpdatedAt: old},
+		{ID: "new-1", CollectorID: "c1", Type: model.TaskTypeSimple, DeviceIP: "10.0.0.1", Username: "u", Commands: "show clock", Status: model.TaskStatusSuccess, CreatedAt: now, UpdatedAt: now},
+	} {
+		require.NoError(t, repo.SaveTask(task))
+	}
+	for i, lvl := range []string{"INFO", "WARN", "INFO"} {
+		require.NoError(t, repo.SaveTaskLog(&model.TaskLog{ID: fmt.Sprintf("new-log-%d", i), TaskID: "new-1", Level: lvl, Message: "m", CreatedAt: now.Add(time.Duration(i) * time.Second)}))
+	}
+	require.NoError(t, repo.SaveTaskLog(&model.TaskLog{ID: "old-log", TaskID: "old-1", Level: "ERROR", Message: "m", CreatedAt: old}))
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	h := handler.NewTaskHandler()
+	r.GET("/tasks", h.ListTasks)
+	r.GET("/tasks/:id/logs", h.GetTaskLogs)
+
+	get := func(url string) (int, map[string]interface{}) {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, url, nil))
+		var body map[string]interface{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+		return w.Code, body
+	}
+
+	code, body := get("/tasks?device=10.0.0.1&from=" + now.Add(-time.Hour).Format(time.RFC3339))
+	assert.Equal(t, http.StatusOK, code)
+	tasks := body["data"].(map[string]interface{})["tasks"].([]interface{})
+	require.Len(t, tasks, 1)
+	assert.Equal(t, "new-1", tasks[0].(map[string]interface{})["id"])
+
+	code, _ = get("/tasks?from=yesterday")
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	code, body = get("/tasks/new-1/logs?size=2")
+	assert.Equal(t, http.StatusOK, code)
+	data := body["data"].(map[string]interface{})
+	assert.Len(t, data["logs"], 2)
+	assert.EqualValues(t, 3, data["pagination"].(map[string]interface{})["total"])
+
+	code, body = get("/tasks/new-1/logs?level=warn")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Len(t, body["data"].(map[string]interface{})["logs"], 1)
+
+	code, _ = get("/tasks/missing/logs")
+	assert.Equal(t, http.StatusNotFound, code)
+
+	tasksDeleted, logsDeleted, err := repo.PurgeTasks(now.Add(-24 * time.Hour))
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, tasksDeleted)
+	assert.EqualValues(t, 1, logsDeleted)
+	code, _ = get("/tasks/old-1/logs")
+	assert.Equal(t, http.StatusNotFound, code)
+}
+
+// TestTaskPersistAcrossRestart 任务记录不含密码；重启后遗留的运行中任务标记为中断，
+// 以相同 task_id 重新下发后继续更新原记录，metadata 与标注保留
+func TestTaskPersistAcrossRestart(t *testing.T) {
+	port := freePort(t)
+	mgr, err := simulate.Start(&simulate.Config{
+		Namespace:  map[string]simulate.NamespaceConfig{"t": {Port: port, IdleSeconds: 60, MaxConn: 10}},
+		DeviceType: map[string]simulate.DeviceTypeConfig{"cisco_ios": {PromptSuffix: "#"}},
+		DeviceName: map[string]simulate.DeviceNameConfig{"sw-01": {DeviceType: "cisco_ios"}},
+		Scenarios: []simulate.ScenarioConfig{
+			{DeviceName: "sw-01", Command: "show clock", Responses: []simulate.ScenarioResponse{{Output: "10:00:00 UTC Fri Oct 16 2026"}}},
+		},
+	})
+	require.NoError(t, err)
+	t.Cleanup(mgr.Stop)
+
+	dir := t.TempDir()
+	cfgPath := filepath.Join(dir, "config.yaml")
+	require.NoError(t, os.WriteFile(cfgPath, []byte(`
+collector:
+  id: node-a
+  device_defaults:
+    cisco_ios:
+      prompt_suffixes: ["#"]
+      command_interval_ms: 10
+`), 0o600))
+	cfg, err := config.Load(cfgPath)
+	require.NoError(t, err)
+	dbPath := filepath.Join(dir, "collector.db")
+
+	start := func() *service.CollectorService {
+		require.NoError(t, database.InitSQLite(config.SQLiteConfig{Path: dbPath}))
+		svc := service.NewCollectorService(cfg)
+		svc.SetTaskStore(handler.NewTaskRepository())
+		require.NoError(t, svc.Start(context.Background()))
+		return svc
+	}
+	collect := func(svc *service.CollectorService) {
+		resp, err := svc.ExecuteTask(context.Background(), &service.CollectRequest{
+			TaskID: "restart-1", DeviceIP: "127.0.0.1", Port: port, DeviceName: "sw-01", DevicePlatform: "cisco_ios",
+			UserName: "sw-01", Password: "nova", CliList: service.NewCLIList("show clock"),
+			Metadata: map[string]interface{}{"site": "dc1", "collect_mode": "customer"},
+		})
+		require.NoError(t, err)
+		require.True(t, resp.Success, resp.Error)
+	}
+	storedPassword := func(id string) string {
+		var pw string
+		require.NoError(t, database.GetDB().Raw("SELECT password FROM tasks WHERE id = ?", id).Scan(&pw).Error)
+		return pw
+	}
+
+	svc := start()
+	collect(svc)
+	assert.Empty(t, storedPassword("restart-1"))
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	h := handler.NewTaskHandler()
+	r.GET("/tasks/:id", h.GetTask)
+	r.PATCH("/tasks/:id/annotations", h.UpdateAnnotations)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPatch, "/tasks/restart-1/annotations", strings.NewReader(`{"ticket":"CHG-1024"}`)))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	// 模拟进程在任务执行中退出：本节点与其他节点各遗留一条运行中记录
+	repo := handler.NewTaskRepository()
+	started := time.Now().Add(-time.Minute)
+	for _, task := range []*model.Task{
+		{ID: "running-a", CollectorID: "node-a", Type: model.TaskTypeSimple, DeviceIP: "10.0.0.1", Username: "u", Commands: "show clock", Status: model.TaskStatusRunning, StartTime: started},
+		{ID: "running-b", CollectorID: "node-b", Type: model.TaskTypeSimple, DeviceIP: "10.0.0.2", Username: "u", Commands: "show clock", Status: model.TaskStatusRunning, StartTime: started},
+	} {
+		require.NoError(t, repo.SaveTask(task))
+	}
+	require.NoError(t, svc.Stop())
+	database.Close()
+
+	// 重启
+	svc = start()
+	t.Cleanup(func() {
+		svc.Stop()
+		database.Close()
+	})
+	var rows []model.Task
+	require.NoError(t, database.GetDB().Order("id").Find(&rows).Error)
+	status := map[string]model.Task{}
+	for _, row := range rows {
+		status[row.ID] = row
+		assert.Empty(t, row.Password, row.ID)
+	}
+	assert.Equal(t, model.TaskStatusFailed, status["running-a"].Status)
+	assert.Contains(t, status["running-a"].ErrorMsg, "interrupted")
+	assert.Equal(t, model.TaskStatusRunning, status["running-b"].Status, "其他采集器的任务不受影响")
+	assert.Equal(t, model.TaskStatusSuccess, status["restart-1"].Status)
+
+	// 重新下发同一任务：原记录继续更新，标注保留，仍不落盘密码
+	collect(svc)
+	assert.Empty(t, storedPassword("restart-1"))
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/tasks/restart-1", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	var body struct {
+		Data handler.TaskView `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, model.TaskStatusSuccess, body.Data.Status)
+	assert.Equal(t, "dc1", body.Data.Metadata["site"])
+	assert.Equal(t, "CHG-1024", body.Data.Annotations["ticket"])
+	assert.NotContains(t, w.Body.String(), "nova")
+}
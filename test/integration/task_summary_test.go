@@ -0,0 +1,65 @@
+package integration
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sshcollectorpro/sshcollectorpro/api/handler"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/config"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/database"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/model"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestTaskSummary 按批次汇总状态、错误类别、最慢设备、存储量与命令失败排行
+func TestTaskSummary(t *testing.T) {
+	require.NoError(t, database.InitSQLite(config.SQLiteConfig{Path: filepath.Join(t.TempDir(), "collector.db")}))
+	defer database.Close()
+
+	repo := handler.NewTaskRepository()
+	meta := `{"batch_task_id":"b1"}`
+	for _, task := range []*model.Task{
+		{ID: "b1-1", DeviceIP: "10.0.0.1", Status: model.TaskStatusSuccess, Duration: 1200, Metadata: meta,
+			Result: `[{"command":"show version","error":"","stored_objects":[{"uri":"a","size":100}]},{"command":"show run","error":"timeout"}]`},
+		{ID: "b1-2", DeviceIP: "10.0.0.2", Status: model.TaskStatusSuccess, Duration: 3000, Metadata: meta,
+			Result: `[{"command":"show version","error":"","stored_objects":[{"uri":"b","size":50}]}]`},
+		{ID: "b1-3", DeviceIP: "10.0.0.3", Status: model.TaskStatusFailed, Duration: 500, Metadata: meta,
+			ErrorMsg: "failed to create SSH connection: ssh: unable to authenticate"},
+		{ID: "other-1", DeviceIP: "10.0.0.4", Status: model.TaskStatusFailed, Metadata: `{"batch_task_id":"b2"}`},
+	} {
+		task.CollectorID, task.Type, task.Username, task.Commands = "c1", model.TaskTypeSimple, "u", "show version"
+		require.NoError(t, repo.SaveTask(task))
+	}
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/tasks/:id/summary", handler.NewTaskHandler().GetTaskSummary)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/tasks/b1/summary?top=1", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	var body struct {
+		Data service.TaskSummary `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	sum := body.Data
+	assert.Equal(t, 3, sum.Total)
+	assert.Equal(t, 2, sum.Succeeded)
+	assert.Equal(t, 1, sum.Failed)
+	assert.Equal(t, map[string]int{service.ErrorClassAuth: 1, service.ErrorClassCommand: 1}, sum.ErrorClasses)
+	require.Len(t, sum.Failures, 1)
+	assert.Equal(t, "10.0.0.3", sum.Failures[0].DeviceIP)
+	assert.Equal(t, []service.DeviceDuration{{TaskID: "b1-2", DeviceIP: "10.0.0.2", DurationMS: 3000}}, sum.SlowestDevices)
+	assert.EqualValues(t, 150, sum.StoredBytes)
+	assert.Equal(t, []service.CommandFailureCount{{Command: "show run", Failures: 1}}, sum.CommandFailures)
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/tasks/missing/summary", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
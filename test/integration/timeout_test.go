@@ -85,7 +85,7 @@ func TestCollectorServiceTimeoutInterruption(t *testing.T) {
 		Port:            22,
 		UserName:        "testuser",
 		Password:        "testpass",
-		CliList:         []string{"show version", "show interfaces"},
+		CliList:         service.NewCLIList("show version", "show interfaces"),
 		TaskTimeout:     &[]int{2}[0], // 2秒超时
 	}
 
@@ -155,7 +155,7 @@ func TestTaskContextDeviceInteractionDuration(t *testing.T) {
 		Port:            22,
 		UserName:        "testuser",
 		Password:        "testpass",
-		CliList:         []string{"show version"},
+		CliList:         service.NewCLIList("show version"),
 		TaskTimeout:     &[]int{3}[0], // 3秒超时
 	}
 
@@ -0,0 +1,264 @@
+package integration
+
+import (
+	"context"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/klauspost/compress/snappy"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/config"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/service"
+	"github.com/sshcollectorpro/sshcollectorpro/simulate"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+const counterTemplate = `Value INTERFACE (\S+)
+Value IN_ERRORS ([\d,]+)
+Value STATUS (up|down)
+
+Start
+  ^${INTERFACE}\s+${IN_ERRORS}\s+${STATUS} -> Record
+`
+
+// tsRequest 时序库接收端记录的一次写入
+type tsRequest struct {
+	header http.Header
+	body   []byte
+}
+
+// timeseriesFixture 模拟设备与时序库接收端（以 status 应答写入），按后端类型创建格式化服务
+func timeseriesFixture(t *testing.T, backend string, status int) (func(metrics []service.MetricSelector) *service.FormatBatchResponse, func() []tsRequest) {
+	port := freePort(t)
+	mgr, err := simulate.Start(&simulate.Config{
+		Namespace:  map[string]simulate.NamespaceConfig{"t": {Port: port, IdleSeconds: 60, MaxConn: 10}},
+		DeviceType: map[string]simulate.DeviceTypeConfig{"cisco_ios": {PromptSuffix: "#"}},
+		DeviceName: map[string]simulate.DeviceNameConfig{"sw-01": {DeviceType: "cisco_ios"}},
+		Scenarios: []simulate.ScenarioConfig{
+			{DeviceName: "sw-01", Command: "show interfaces counters errors", Responses: []simulate.ScenarioResponse{{Output: "Gi0/1 1,024 up\nGi0/2 7 down"}}},
+		},
+	})
+	require.NoError(t, err)
+	t.Cleanup(mgr.Stop)
+
+	var mu sync.Mutex
+	var reqs []tsRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		reqs = append(reqs, tsRequest{header: r.Header.Clone(), body: b})
+		mu.Unlock()
+		if status >= 300 {
+			http.Error(w, "bucket not found", status)
+			return
+		}
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(srv.Close)
+
+	cfgPath := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(cfgPath, []byte(`
+data_format:
+  timeseries:
+    enable: true
+    backend: `+backend+`
+    url: `+srv.URL+`/write
+    token: ts-token
+collector:
+  device_defaults:
+    cisco_ios:
+      prompt_suffixes: ["#"]
+      command_interval_ms: 10
+`), 0o600))
+	cfg, err := config.Load(cfgPath)
+	require.NoError(t, err)
+	svc := service.NewFormatService(cfg)
+	require.NoError(t, svc.Start(context.Background()))
+	t.Cleanup(func() { svc.Stop() })
+
+	run := func(metrics []service.MetricSelector) *service.FormatBatchResponse {
+		resp, err := svc.ExecuteBatch(context.Background(), &service.FormatBatchRequest{
+			TaskID:  "fmt-ts",
+			Metrics: metrics,
+			Devices: []service.FormatDevice{{
+				DeviceIP: "127.0.0.1", DevicePort: port, DeviceName: "sw-01", DevicePlatform: "cisco_ios",
+				UserName: "sw-01", Password: "nova", CliList: service.NewCLIList("show interfaces counters errors"),
+			}},
+			FSMTemplates: []service.FSMTemplateDef{{DevicePlatform: "cisco_ios", TemplateValues: []service.FSMTemplateValue{
+				{CLIName: "show interfaces counters errors", FSMValue: counterTemplate},
+			}}},
+		})
+		require.NoError(t, err)
+		return resp
+	}
+	received := func() []tsRequest {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]tsRequest(nil), reqs...)
+	}
+	return run, received
+}
+
+var errorCounterMetrics = []service.MetricSelector{{
+	DevicePlatform: "Cisco_IOS",
+	CLIName:        "show interfaces counters errors",
+	Measurement:    "interface",
+	Fields:         []string{"IN_ERRORS", "STATUS"},
+	TagFields:      []string{"INTERFACE"},
+}}
+
+// TestFormatBatchTimeseriesInflux 选中数值字段按行协议写入 InfluxDB：千分位兼容、非数值字段跳过、标签含设备与接口
+func TestFormatBatchTimeseriesInflux(t *testing.T) {
+	run, received := timeseriesFixture(t, "influxdb", http.StatusNoContent)
+
+	resp := run(errorCounterMetrics)
+	assert.Empty(t, resp.MetricsError)
+	assert.Equal(t, 2, resp.MetricsWritten)
+
+	reqs := received()
+	require.Len(t, reqs, 1)
+	assert.Equal(t, "Token ts-token", reqs[0].header.Get("Authorization"))
+	lines := strings.Split(strings.TrimSpace(string(reqs[0].body)), "\n")
+	require.Len(t, lines, 2)
+	sort.Strings(lines)
+	var ts []string
+	for i, want := range []string{
+		"interface,device=sw-01,interface=Gi0/1,platform=cisco_ios in_errors=1024",
+		"interface,device=sw-01,interface=Gi0/2,platform=cisco_ios in_errors=7",
+	} {
+		fields := strings.Fields(lines[i])
+		require.Len(t, fields, 3, lines[i])
+		assert.Equal(t, want, fields[0]+" "+fields[1])
+		ts = append(ts, fields[2])
+	}
+	assert.Equal(t, ts[0], ts[1], "时间戳为批次开始时间")
+}
+
+// TestFormatBatchTimeseriesPrometheus remote-write 每个字段一条序列 {measurement}_{field}，请求体为 snappy 压缩的 protobuf
+func TestFormatBatchTimeseriesPrometheus(t *testing.T) {
+	run, received := timeseriesFixture(t, "prometheus", http.StatusNoContent)
+
+	resp := run(errorCounterMetrics)
+	assert.Empty(t, resp.MetricsError)
+	assert.Equal(t, 2, resp.MetricsWritten)
+
+	reqs := received()
+	require.Len(t, reqs, 1)
+	assert.Equal(t, "Bearer ts-token", reqs[0].header.Get("Authorization"))
+	assert.Equal(t, "snappy", reqs[0].header.Get("Content-Encoding"))
+	assert.Equal(t, "application/x-protobuf", reqs[0].header.Get("Content-Type"))
+	raw, err := snappy.Decode(nil, reqs[0].body)
+	require.NoError(t, err)
+
+	series := decodeRemoteWrite(t, raw)
+	require.Len(t, series, 2)
+	values := map[string]float64{}
+	for _, s := range series {
+		assert.Equal(t, "interface_in_errors", s.labels["__name__"])
+		assert.Equal(t, "sw-01", s.labels["device"])
+		assert.Equal(t, "cisco_ios", s.labels["platform"])
+		assert.True(t, sort.StringsAreSorted(s.names), "标签按名称排序: %v", s.names)
+		values[s.labels["interface"]] = s.value
+	}
+	assert.Equal(t, map[string]float64{"Gi0/1": 1024, "Gi0/2": 7}, values)
+}
+
+// TestFormatBatchTimeseriesValidation 未开启时序输出时携带 metrics 视为参数错误；写入失败不影响批次结果
+func TestFormatBatchTimeseriesValidation(t *testing.T) {
+	cfgPath := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(cfgPath, []byte("collector:\n  concurrent: 1\n"), 0o600))
+	cfg, err := config.Load(cfgPath)
+	require.NoError(t, err)
+	assert.ErrorIs(t, service.ValidateMetricSelectors(cfg, errorCounterMetrics), service.ErrValidation)
+	assert.NoError(t, service.ValidateMetricSelectors(cfg, nil))
+
+	cfg.DataFormat.Timeseries.Enable = true
+	cfg.DataFormat.Timeseries.URL = "http://127.0.0.1:1/write"
+	assert.NoError(t, service.ValidateMetricSelectors(cfg, errorCounterMetrics))
+	assert.ErrorIs(t, service.ValidateMetricSelectors(cfg, []service.MetricSelector{{DevicePlatform: "cisco_ios", CLIName: "x", Measurement: "m"}}), service.ErrValidation)
+	assert.ErrorIs(t, service.ValidateMetricSelectors(cfg, []service.MetricSelector{{Measurement: "m", Fields: []string{"A"}}}), service.ErrValidation)
+
+	// 时序库写入失败：设备与解析结果照常返回，错误在 metrics_error 中
+	run, received := timeseriesFixture(t, "influxdb", http.StatusNotFound)
+	resp := run(errorCounterMetrics)
+	assert.Len(t, received(), 1)
+	assert.Zero(t, resp.MetricsWritten)
+	assert.Contains(t, resp.MetricsError, "timeseries write status 404: bucket not found")
+	assert.Equal(t, 1, resp.Stats.FullySuccess)
+}
+
+// remoteWriteSeries 解码后的单条序列
+type remoteWriteSeries struct {
+	labels map[string]string
+	names  []string
+	value  float64
+}
+
+// decodeRemoteWrite 解析 prompb.WriteRequest：timeseries=1{labels=1{name=1,value=2}, samples=2{value=1,timestamp=2}}
+func decodeRemoteWrite(t *testing.T, b []byte) []remoteWriteSeries {
+	fieldsOf := func(b []byte) [][2]interface{} {
+		var out [][2]interface{}
+		for len(b) > 0 {
+			num, typ, n := protowire.ConsumeTag(b)
+			require.GreaterOrEqual(t, n, 0)
+			b = b[n:]
+			switch typ {
+			case protowire.BytesType:
+				v, n := protowire.ConsumeBytes(b)
+				require.GreaterOrEqual(t, n, 0)
+				out = append(out, [2]interface{}{num, v})
+				b = b[n:]
+			case protowire.Fixed64Type:
+				v, n := protowire.ConsumeFixed64(b)
+				require.GreaterOrEqual(t, n, 0)
+				out = append(out, [2]interface{}{num, v})
+				b = b[n:]
+			case protowire.VarintType:
+				v, n := protowire.ConsumeVarint(b)
+				require.GreaterOrEqual(t, n, 0)
+				out = append(out, [2]interface{}{num, v})
+				b = b[n:]
+			default:
+				t.Fatalf("unexpected wire type %d", typ)
+			}
+		}
+		return out
+	}
+	var series []remoteWriteSeries
+	for _, ts := range fieldsOf(b) {
+		require.Equal(t, protowire.Number(1), ts[0])
+		s := remoteWriteSeries{labels: map[string]string{}}
+		for _, f := range fieldsOf(ts[1].([]byte)) {
+			switch f[0] {
+			case protowire.Number(1):
+				var name, value string
+				for _, lf := range fieldsOf(f[1].([]byte)) {
+					if lf[0] == protowire.Number(1) {
+						name = string(lf[1].([]byte))
+					} else {
+						value = string(lf[1].([]byte))
+					}
+				}
+				s.labels[name] = value
+				s.names = append(s.names, name)
+			case protowire.Number(2):
+				for _, sf := range fieldsOf(f[1].([]byte)) {
+					if sf[0] == protowire.Number(1) {
+						s.value = math.Float64frombits(sf[1].(uint64))
+					}
+				}
+			}
+		}
+		series = append(series, s)
+	}
+	return series
+}
@@ -0,0 +1,76 @@
+package integration
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sshcollectorpro/sshcollectorpro/internal/config"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/service"
+	"github.com/sshcollectorpro/sshcollectorpro/simulate"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestCollectTimings 采集结果附带设备执行时间线：首次建连记录连接与认证耗时，命令耗时包含设备侧延迟，再次采集复用连接
+func TestCollectTimings(t *testing.T) {
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(t.TempDir()))
+	defer os.Chdir(wd)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	port := ln.Addr().(*net.TCPAddr).Port
+	ln.Close()
+	mgr, err := simulate.Start(&simulate.Config{
+		Namespace:  map[string]simulate.NamespaceConfig{"t": {Port: port, IdleSeconds: 60, MaxConn: 10, LatencyMS: 50}},
+		DeviceType: map[string]simulate.DeviceTypeConfig{"cisco_ios": {PromptSuffix: "#"}},
+		DeviceName: map[string]simulate.DeviceNameConfig{"sw-01": {DeviceType: "cisco_ios"}},
+	})
+	require.NoError(t, err)
+	defer mgr.Stop()
+
+	cfgPath := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(cfgPath, []byte(`
+collector:
+  device_defaults:
+    cisco_ios:
+      prompt_suffixes: ["#"]
+      command_interval_ms: 10
+`), 0o600))
+	cfg, err := config.Load(cfgPath)
+	require.NoError(t, err)
+	collector := service.NewCollectorService(cfg)
+	require.NoError(t, collector.Start(context.Background()))
+	defer collector.Stop()
+
+	collect := func(id string) *service.CollectResponse {
+		resp, err := collector.ExecuteTask(context.Background(), &service.CollectRequest{
+			TaskID: id, DeviceIP: "127.0.0.1", Port: port, DeviceName: "sw-01", DevicePlatform: "cisco_ios",
+			UserName: "sw-01", Password: "nova", CliList: service.NewCLIList("show version"),
+		})
+		require.NoError(t, err)
+		require.True(t, resp.Success, resp.Error)
+		require.NotNil(t, resp.Timings)
+		return resp
+	}
+
+	first := collect("timings-1").Timings
+	assert.False(t, first.ConnectionReused)
+	assert.Positive(t, first.ConnectMS+first.AuthMS)
+	var found bool
+	for _, c := range first.Commands {
+		if c.Command == "show version" {
+			found = true
+			assert.GreaterOrEqual(t, c.DurationMS, int64(50))
+		}
+	}
+	assert.True(t, found, "show version timing missing: %+v", first.Commands)
+
+	second := collect("timings-2").Timings
+	assert.True(t, second.ConnectionReused)
+	assert.Zero(t, second.AuthMS)
+}
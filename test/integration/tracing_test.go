@@ -0,0 +1,94 @@
+package integration
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sshcollectorpro/sshcollectorpro/internal/config"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/service"
+	"github.com/sshcollectorpro/sshcollectorpro/simulate"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+// TestDeployTracingSpans 下发流程按 批次 → 设备 → 连接/提示符等待/命令 生成 span，命令文本脱敏
+func TestDeployTracingSpans(t *testing.T) {
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(t.TempDir()))
+	defer os.Chdir(wd)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	port := ln.Addr().(*net.TCPAddr).Port
+	ln.Close()
+	mgr, err := simulate.Start(&simulate.Config{
+		Namespace:  map[string]simulate.NamespaceConfig{"t": {Port: port, IdleSeconds: 60, MaxConn: 10}},
+		DeviceType: map[string]simulate.DeviceTypeConfig{"cisco_ios": {PromptSuffix: "#"}},
+		DeviceName: map[string]simulate.DeviceNameConfig{"sw-01": {DeviceType: "cisco_ios"}},
+	})
+	require.NoError(t, err)
+	defer mgr.Stop()
+
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	defer otel.SetTracerProvider(noop.NewTracerProvider())
+
+	cfgPath := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(cfgPath, []byte(`
+deploy:
+  deploy_wait_ms: 1
+collector:
+  device_defaults:
+    cisco_ios:
+      prompt_suffixes: ["#"]
+      command_interval_ms: 10
+`), 0o600))
+	cfg, err := config.Load(cfgPath)
+	require.NoError(t, err)
+	collector := service.NewCollectorService(cfg)
+	require.NoError(t, collector.Start(context.Background()))
+	defer collector.Stop()
+	svc := service.NewDeployService(cfg, collector)
+
+	_, err = svc.Deploy(context.Background(), &service.DeployFastRequest{
+		TaskID:      "trace-1",
+		TaskType:    "exec",
+		TaskTimeout: 10,
+		Devices: []service.DeployDevice{{
+			DeviceIP: "127.0.0.1", DevicePort: port, DeviceName: "sw-01", DevicePlatform: "cisco_ios",
+			UserName: "sw-01", Password: "nova",
+			CliList: service.NewDeployCLIList("username ops password S3cret!"),
+		}},
+	})
+	require.NoError(t, err)
+
+	byName := map[string]sdktrace.ReadOnlySpan{}
+	var commands []string
+	for _, s := range recorder.Ended() {
+		byName[s.Name()] = s
+		if s.Name() == "ssh.command" {
+			for _, kv := range s.Attributes() {
+				if kv.Key == "ssh.command" {
+					commands = append(commands, kv.Value.AsString())
+				}
+			}
+		}
+	}
+	batch, device := byName["deploy.batch"], byName["deploy.device"]
+	require.NotNil(t, batch)
+	require.NotNil(t, device)
+	assert.Equal(t, batch.SpanContext().SpanID(), device.Parent().SpanID())
+	for _, name := range []string{"ssh.connect", "ssh.prompt_wait", "ssh.command"} {
+		require.Contains(t, byName, name)
+		assert.Equal(t, device.SpanContext().SpanID(), byName[name].Parent().SpanID(), name)
+	}
+	assert.Contains(t, commands, "username ops password ******")
+}
@@ -0,0 +1,25 @@
+package integration
+
+import (
+	"testing"
+
+	"github.com/sshcollectorpro/sshcollectorpro/pkg/ssh"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUnknownPromptDefaultPatterns(t *testing.T) {
+	p := ssh.DefaultUnknownPromptPatterns
+	assert.True(t, ssh.MatchUnknownPrompt(p, "Continue? [Y/N]:"))
+	assert.True(t, ssh.MatchUnknownPrompt(p, "Overwrite file (yes/no)?"))
+	assert.True(t, ssh.MatchUnknownPrompt(p, "Proceed with reload? [confirm]"))
+	assert.True(t, ssh.MatchUnknownPrompt(p, "Do you want to proceed?"))
+	assert.False(t, ssh.MatchUnknownPrompt(p, "<HUAWEI>"))
+	assert.False(t, ssh.MatchUnknownPrompt(p, "[~HUAWEI-GigabitEthernet0/0/1]"))
+	assert.False(t, ssh.MatchUnknownPrompt(p, "Router#"))
+}
+
+func TestUnknownPromptResponseBytes(t *testing.T) {
+	assert.Equal(t, []byte("N\r\n"), ssh.UnknownPromptResponseBytes(""))
+	assert.Equal(t, []byte("no\r\n"), ssh.UnknownPromptResponseBytes("no"))
+	assert.Equal(t, []byte{0x03}, ssh.UnknownPromptResponseBytes("ctrl-c"))
+}
@@ -0,0 +1,53 @@
+package integration
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sshcollectorpro/sshcollectorpro/api/handler"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/config"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestStorageFallbackWarning 远端存储未初始化时写入本地，响应 warnings 中给出回退说明
+func TestStorageFallbackWarning(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Backup.Local.BaseDir = t.TempDir()
+	cfg.Backup.Local.MkdirIfMissing = true
+	writer := service.NewStorageWriter(cfg)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(handler.WarningsMiddleware())
+	r.POST("/store", func(c *gin.Context) {
+		meta := service.StorageMeta{TaskID: "t1", DeviceIP: "10.0.0.1", CommandSlug: "show_version", Backend: "minio"}
+		for i := 0; i < 2; i++ {
+			obj, _ := writer.Write(c.Request.Context(), meta, "output", "text/plain")
+			require.NotEmpty(t, obj.URI)
+		}
+		c.JSON(http.StatusOK, gin.H{"code": "SUCCESS"})
+	})
+	r.GET("/plain", func(c *gin.Context) { c.JSON(http.StatusOK, gin.H{"code": "SUCCESS"}) })
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/store", nil))
+	var resp struct {
+		Code     string            `json:"code"`
+		Warnings []service.Warning `json:"warnings"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, "SUCCESS", resp.Code)
+	// 同类告警只保留一条
+	require.Len(t, resp.Warnings, 1)
+	assert.Equal(t, service.WarnStorageFallback, resp.Warnings[0].Code)
+	assert.Equal(t, "minio", resp.Warnings[0].Context["backend"])
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/plain", nil))
+	assert.JSONEq(t, `{"code":"SUCCESS"}`, w.Body.String())
+}
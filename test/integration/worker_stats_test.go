@@ -0,0 +1,109 @@
+package integration
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sshcollectorpro/sshcollectorpro/api/handler"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/config"
+	"github.com/sshcollectorpro/sshcollectorpro/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestWorkerQueueMetrics 名额被占满时后续任务排队并超时；统计与 /metrics 输出排队深度、等待分位数、超时计数与当前并发档位
+func TestWorkerQueueMetrics(t *testing.T) {
+	// 只接受连接、不回应 SSH 握手的端口：任务在建连阶段占住唯一的名额
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		var conns []net.Conn
+		defer func() {
+			for _, c := range conns {
+				c.Close()
+			}
+		}()
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			conns = append(conns, conn)
+		}
+	}()
+
+	cfgPath := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(cfgPath, []byte(`
+ssh:
+  connect_timeout: 2s
+collector:
+  concurrency_profile: S
+  concurrency_profiles:
+    S: {concurrent: 1}
+`), 0o600))
+	cfg, err := config.Load(cfgPath)
+	require.NoError(t, err)
+	require.Equal(t, 1, cfg.Collector.Concurrent)
+	svc := service.NewCollectorService(cfg)
+	require.NoError(t, svc.Start(context.Background()))
+	t.Cleanup(func() { svc.Stop() })
+
+	noRetry := 0
+	request := func(id string, timeout int) *service.CollectRequest {
+		return &service.CollectRequest{
+			TaskID: id, DeviceIP: "127.0.0.1", Port: ln.Addr().(*net.TCPAddr).Port, DevicePlatform: "cisco_ios",
+			UserName: "u", Password: "p", RetryFlag: &noRetry, TaskTimeout: &timeout, CliList: service.NewCLIList("show clock"),
+		}
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		_, _ = svc.ExecuteTask(context.Background(), request("wq-hold", 3))
+	}()
+	require.Eventually(t, func() bool { return svc.WorkerUtilization().Busy == 1 }, time.Second, 10*time.Millisecond)
+
+	queued := make(chan error, 1)
+	go func() {
+		_, err := svc.ExecuteTask(context.Background(), request("wq-wait", 1))
+		queued <- err
+	}()
+	require.Eventually(t, func() bool { return svc.WorkerUtilization().QueueDepth == 1 }, time.Second, 10*time.Millisecond)
+	assert.ErrorIs(t, <-queued, service.ErrTimeout)
+	wg.Wait()
+
+	u := svc.WorkerUtilization()
+	assert.Equal(t, "S", u.Profile)
+	assert.Equal(t, 0, u.QueueDepth)
+	assert.Equal(t, 1, u.PeakQueueDepth)
+	assert.EqualValues(t, 1, u.Acquired)
+	assert.EqualValues(t, 1, u.TimedOut)
+	assert.Zero(t, u.Rejected)
+	require.NotEmpty(t, u.QueueHistory)
+	assert.Equal(t, 1, u.QueueHistory[len(u.QueueHistory)-1].MaxQueueDepth)
+	assert.Equal(t, 1, u.QueueHistory[len(u.QueueHistory)-1].MaxBusy)
+
+	handler.SetWorkerReporters(svc)
+	t.Cleanup(func() { handler.SetWorkerReporters() })
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/metrics", handler.Metrics)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	body := w.Body.String()
+	assert.Contains(t, body, `sshcollector_worker_max{service="collector"} 1`)
+	assert.Contains(t, body, `sshcollector_worker_queue_wait_seconds_count{service="collector"} 1`)
+	assert.Contains(t, body, `sshcollector_worker_acquisitions_total{service="collector",result="timed_out"} 1`)
+	assert.Contains(t, body, `sshcollector_concurrency_profile{profile="S"} 1`)
+}